@@ -0,0 +1,273 @@
+// Package clairtest runs a complete Clair in-process, for integration tests
+// against its HTTP API.
+//
+// The indexer, matcher, and notifier run in "combo" mode against a database
+// provided by claircore's integration test harness, which downloads and runs
+// an embedded PostgreSQL engine when tests are built with the "integration"
+// tag. Without the tag (or the binaries it fetches), tests calling New are
+// skipped. Packages using this one need to arrange for the engine to be
+// started by calling Main from their TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(clairtest.Main(m))
+//	}
+//
+// Updaters are disabled; the matcher only knows about the vulnerabilities
+// provided in Options, or the canned vulnerabilities if none are.
+package clairtest // import "github.com/quay/clair/v4/clairtest"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+
+	"github.com/quay/clair/v4/httptransport"
+	"github.com/quay/clair/v4/httptransport/client"
+	"github.com/quay/clair/v4/initialize"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Routes served alongside Clair's API for the harness's own use.
+const (
+	layerPath   = `/clairtest/layer/`
+	webhookPath = `/clairtest/webhook`
+)
+
+// Main runs the tests in "m" with a database engine available, and returns
+// the exit code to pass to os.Exit.
+func Main(m *testing.M) int {
+	defer integration.DBSetup()()
+	return m.Run()
+}
+
+// Options configures a Clair started by New.
+type Options struct {
+	// Vulnerabilities are loaded into the matcher before New returns. If nil,
+	// the canned vulnerabilities returned by Vulnerabilities are used.
+	Vulnerabilities []*claircore.Vulnerability
+	// Config, if not nil, is called with the configuration before it's
+	// validated, to adjust it. Changing the mode or database connection
+	// strings is not supported.
+	Config func(*config.Config)
+}
+
+// Clair is a running Clair instance.
+//
+// All its services are shut down and its database is removed when the test
+// that created it completes.
+type Clair struct {
+	// URL is the base URL of Clair's HTTP API.
+	URL string
+	// Client is a client for Clair's API.
+	Client *client.HTTP
+
+	store datastore.MatcherStore
+	notes chan notifier.Callback
+
+	mu     sync.RWMutex
+	layers map[string][]byte
+}
+
+// New starts a Clair instance for the duration of the test "t".
+//
+// The test is skipped if there's no database engine available; see the
+// package documentation. A nil "opts" uses the defaults.
+func New(ctx context.Context, t testing.TB, opts *Options) *Clair {
+	t.Helper()
+	if opts == nil {
+		opts = &Options{}
+	}
+	integration.NeedDB(t)
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("clairtest: unable to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(context.Background(), t) })
+	pool, err := pgxpool.ConnectConfig(ctx, db.Config())
+	if err != nil {
+		t.Fatalf("clairtest: unable to connect to database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	c := &Clair{
+		notes:  make(chan notifier.Callback, 16),
+		layers: make(map[string][]byte),
+	}
+	// The configuration needs the server's address, so the API handler is
+	// added before the server is started.
+	mux := http.NewServeMux()
+	mux.HandleFunc(layerPath, c.serveLayer)
+	mux.HandleFunc(webhookPath, c.serveWebhook)
+	srv := httptest.NewUnstartedServer(mux)
+	t.Cleanup(srv.Close)
+	c.URL = "http://" + srv.Listener.Addr().String()
+
+	cfg := Config(connString(db.Config()), c.URL)
+	if opts.Config != nil {
+		opts.Config(&cfg)
+	}
+	if _, err := config.Validate(&cfg); err != nil {
+		t.Fatalf("clairtest: invalid configuration: %v", err)
+	}
+	svcs, err := initialize.Services(ctx, &cfg)
+	if err != nil {
+		t.Fatalf("clairtest: unable to start services: %v", err)
+	}
+	h, err := httptransport.New(ctx, cfg, svcs.Indexer, svcs.Matcher, svcs.Notifier)
+	if err != nil {
+		t.Fatalf("clairtest: unable to configure http transport: %v", err)
+	}
+	mux.Handle("/", h.Server.Handler)
+	srv.Start()
+
+	c.store, err = postgres.InitPostgresMatcherStore(ctx, pool, false)
+	if err != nil {
+		t.Fatalf("clairtest: unable to open matcher store: %v", err)
+	}
+	vs := opts.Vulnerabilities
+	if vs == nil {
+		vs = Vulnerabilities()
+	}
+	c.AddVulnerabilities(ctx, t, Updater, vs)
+
+	c.Client, err = client.NewHTTP(ctx, client.WithAddr(c.URL), client.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("clairtest: unable to create client: %v", err)
+	}
+	return c
+}
+
+// Config returns the configuration New uses, for a database at "connString"
+// and an API served at "url".
+func Config(connString, url string) config.Config {
+	return config.Config{
+		Mode:           config.ComboMode,
+		HTTPListenAddr: "127.0.0.1:0",
+		Indexer: config.Indexer{
+			ConnString: connString,
+			Migrations: true,
+		},
+		Matcher: config.Matcher{
+			ConnString:      connString,
+			Migrations:      true,
+			DisableUpdaters: true,
+		},
+		Notifier: config.Notifier{
+			ConnString:       connString,
+			Migrations:       true,
+			PollInterval:     config.Duration(time.Second),
+			DeliveryInterval: config.Duration(time.Second),
+			Webhook: &config.Webhook{
+				Target:   url + webhookPath,
+				Callback: url + httptransport.NotificationAPIPath,
+			},
+		},
+	}
+}
+
+// ConnString builds a connection string for "cfg", which the integration
+// harness constructs field-by-field.
+func connString(cfg *pgxpool.Config) string {
+	cc := cfg.ConnConfig
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cc.Host, cc.Port, cc.User, cc.Password, cc.Database)
+}
+
+// AddVulnerabilities adds "vs" to the matcher as a new update operation for
+// "updater", returning its reference. The notifier creates notifications for
+// manifests affected by the change the next time it polls.
+func (c *Clair) AddVulnerabilities(ctx context.Context, t testing.TB, updater string, vs []*claircore.Vulnerability) uuid.UUID {
+	t.Helper()
+	ref, err := c.store.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()), vs)
+	if err != nil {
+		t.Fatalf("clairtest: unable to add vulnerabilities: %v", err)
+	}
+	return ref
+}
+
+// Manifest returns a manifest consisting of the uncompressed tar archives
+// "layers", served by the harness so the indexer can fetch them.
+func (c *Clair) Manifest(t testing.TB, layers ...[]byte) *claircore.Manifest {
+	t.Helper()
+	m := &claircore.Manifest{}
+	h := sha256.New()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range layers {
+		sum := sha256.Sum256(b)
+		hexsum := hex.EncodeToString(sum[:])
+		d, err := claircore.ParseDigest("sha256:" + hexsum)
+		if err != nil {
+			t.Fatalf("clairtest: %v", err)
+		}
+		c.layers[hexsum] = b
+		h.Write(sum[:])
+		m.Layers = append(m.Layers, &claircore.Layer{
+			Hash: d,
+			URI:  c.URL + layerPath + hexsum,
+		})
+	}
+	d, err := claircore.NewDigest("sha256", h.Sum(nil))
+	if err != nil {
+		t.Fatalf("clairtest: %v", err)
+	}
+	m.Hash = d
+	return m
+}
+
+// Notifications returns a channel of the webhook callbacks the notifier
+// delivers.
+//
+// Callbacks are dropped if the channel isn't being read.
+func (c *Clair) Notifications() <-chan notifier.Callback {
+	return c.notes
+}
+
+func (c *Clair) serveLayer(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	b, ok := c.layers[strings.TrimPrefix(r.URL.Path, layerPath)]
+	c.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("content-type", "application/x-tar")
+	w.Write(b)
+}
+
+func (c *Clair) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var cb notifier.Callback
+	if err := json.NewDecoder(r.Body).Decode(&cb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	select {
+	case c.notes <- cb:
+	default:
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package clairtest_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/clairtest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(clairtest.Main(m))
+}
+
+func TestClair(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	c := clairtest.New(ctx, t, nil)
+	m := c.Manifest(t, clairtest.AlpineLayer())
+
+	ir, err := c.Client.Index(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatalf("index failed: %s", ir.Err)
+	}
+	vr, err := c.Client.Scan(ctx, ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range vr.Vulnerabilities {
+		names = append(names, v.Name)
+	}
+	if len(names) != 1 || names[0] != clairtest.Vulnerability {
+		t.Errorf("got: %v, want: [%s]", names, clairtest.Vulnerability)
+	}
+
+	t.Run("Notification", func(t *testing.T) {
+		vs := clairtest.Vulnerabilities()
+		nv := *vs[0]
+		nv.Name = "CVE-2000-0003"
+		vs = append(vs, &nv)
+		c.AddVulnerabilities(ctx, t, clairtest.Updater, vs)
+
+		timeout := time.After(time.Minute)
+		select {
+		case cb := <-c.Notifications():
+			t.Logf("notification: %v", cb.NotificationID)
+		case <-timeout:
+			t.Fatal("timed out waiting for notification")
+		}
+	})
+}
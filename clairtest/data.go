@@ -0,0 +1,106 @@
+package clairtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+// Updater is the updater name the vulnerabilities provided to New are
+// recorded under.
+const Updater = `clairtest`
+
+// Names in the canned data.
+const (
+	// VulnerablePackage is installed in AlpineLayer and affected by
+	// Vulnerability.
+	VulnerablePackage = `busybox`
+	// Vulnerability is the name of the canned vulnerability.
+	Vulnerability = `CVE-2000-0001`
+)
+
+// Alpine is the distribution of AlpineLayer, as the indexer reports it.
+var alpine = claircore.Distribution{
+	DID:        "alpine",
+	Name:       "Alpine Linux",
+	PrettyName: "Alpine Linux v3.18",
+}
+
+// Vulnerabilities returns the canned vulnerabilities: one affecting
+// VulnerablePackage as installed in AlpineLayer, and one fixed in an older
+// version of it.
+func Vulnerabilities() []*claircore.Vulnerability {
+	dist := alpine
+	return []*claircore.Vulnerability{
+		{
+			Updater:            Updater,
+			Name:               Vulnerability,
+			Description:        "A vulnerability affecting the canned layer.",
+			Issued:             time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			Severity:           "High",
+			NormalizedSeverity: claircore.High,
+			Package:            &claircore.Package{Name: VulnerablePackage, Kind: claircore.BINARY},
+			Dist:               &dist,
+			FixedInVersion:     "1.36.1-r5",
+		},
+		{
+			Updater:            Updater,
+			Name:               "CVE-2000-0002",
+			Description:        "A vulnerability already fixed in the canned layer.",
+			Issued:             time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			Severity:           "Low",
+			NormalizedSeverity: claircore.Low,
+			Package:            &claircore.Package{Name: VulnerablePackage, Kind: claircore.BINARY},
+			Dist:               &dist,
+			FixedInVersion:     "1.36.0-r0",
+		},
+	}
+}
+
+// AlpineLayer returns an uncompressed layer containing just enough of an
+// Alpine 3.18 system for the indexer to find the distribution and
+// VulnerablePackage.
+func AlpineLayer() []byte {
+	files := []struct {
+		Name, Body string
+	}{
+		{
+			Name: "etc/os-release",
+			Body: `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.18.4
+PRETTY_NAME="Alpine Linux v3.18"
+HOME_URL="https://alpinelinux.org/"
+`,
+		},
+		{
+			Name: "lib/apk/db/installed",
+			Body: `C:Q1AAAAAAAAAAAAAAAAAAAAAAAAAAA=
+P:busybox
+V:1.36.1-r2
+A:x86_64
+o:busybox
+
+`,
+		},
+	}
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	mod := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, f := range files {
+		h := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.Name,
+			Mode:     0o644,
+			Size:     int64(len(f.Body)),
+			ModTime:  mod,
+		}
+		// Writes to a bytes.Buffer can't fail.
+		w.WriteHeader(h)
+		w.Write([]byte(f.Body))
+	}
+	w.Close()
+	return buf.Bytes()
+}
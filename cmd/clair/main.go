@@ -10,6 +10,7 @@
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	"github.com/quay/clair/config"
@@ -22,12 +23,16 @@
 	"github.com/quay/clair/v4/httptransport"
 	"github.com/quay/clair/v4/initialize"
 	"github.com/quay/clair/v4/initialize/auto"
+	"github.com/quay/clair/v4/internal/listen"
+	"github.com/quay/clair/v4/internal/systemd"
 	"github.com/quay/clair/v4/introspection"
 )
 
 const (
-	envConfig = `CLAIR_CONF`
-	envMode   = `CLAIR_MODE`
+	envConfig  = `CLAIR_CONF`
+	envMode    = `CLAIR_MODE`
+	envProfile = `CLAIR_PROFILE`
+	envStrict  = `CLAIR_STRICT_CONFIG`
 )
 
 func main() {
@@ -35,39 +40,53 @@ func main() {
 	var conf config.Config
 	flag.String("conf", "", "The file system path to Clair's config file.")
 	flag.String("mode", "", "The operation mode for this server, will default to combo.")
+	flag.String("profile", "", "The profile from the config file to use, if any.")
+	strictDefault, _ := strconv.ParseBool(os.Getenv(envStrict))
+	strict := flag.Bool("strict-config", strictDefault, "Reject unknown config keys and report every config error, not just the first.")
 	flag.Parse()
-	flag.VisitAll(func(f *flag.Flag) {
-		fv := f.Value.(flag.Getter).Get().(string)
-		var key string
-		switch f.Name {
-		case "conf":
-			key = envConfig
-		case "mode":
-			key = envMode
-		}
-		v, ok := os.LookupEnv(key)
-		if fv == "" && !ok {
-			golog.Fatalf("must provide a -%s value or set %q in the environment", f.Name, key)
-		}
-		if fv == "" && ok {
-			fv = v
-		}
-		switch f.Name {
-		case "conf":
-			if err := cmd.LoadConfig(&conf, fv, true); err != nil {
-				golog.Fatalf("failed loading config: %v", err)
-			}
-		case "mode":
-			if fv == "" {
-				fv = "combo"
-			}
-			m, err := config.ParseMode(fv)
-			if err != nil {
-				golog.Fatalf("bad mode %q: %v", fv, err)
-			}
-			conf.Mode = m
+	// Flags take precedence over the environment.
+	get := func(name, key string) (string, bool) {
+		if v := flag.Lookup(name).Value.String(); v != "" {
+			return v, true
 		}
-	})
+		return os.LookupEnv(key)
+	}
+
+	path, ok := get("conf", envConfig)
+	if !ok {
+		golog.Fatalf("must provide a -%s value or set %q in the environment", "conf", envConfig)
+	}
+	profile, _ := get("profile", envProfile)
+	if *strict {
+		errs, err := cmd.CheckSchema(path)
+		if err != nil {
+			golog.Fatalf("failed checking config: %v", err)
+		}
+		for _, e := range errs {
+			golog.Print(e)
+		}
+		if len(errs) != 0 {
+			golog.Fatalf("config has %d problem(s)", len(errs))
+		}
+	}
+	profileMode, err := cmd.LoadProfile(&conf, path, profile, true)
+	if err != nil {
+		golog.Fatalf("failed loading config: %v", err)
+	}
+	// An explicit mode overrides the profile's.
+	switch v, ok := get("mode", envMode); {
+	case ok:
+		if v == "" {
+			v = "combo"
+		}
+		m, err := config.ParseMode(v)
+		if err != nil {
+			golog.Fatalf("bad mode %q: %v", v, err)
+		}
+		conf.Mode = m
+	case !profileMode:
+		golog.Fatalf("must provide a -%s value or set %q in the environment", "mode", envMode)
+	}
 
 	fail := false
 	defer func() {
@@ -77,9 +96,21 @@ func main() {
 	}()
 
 	// Grab the warnings to print after the logger is configured.
-	ws, err := config.Validate(&conf)
-	if err != nil {
-		golog.Fatalf("failed to validate config: %v", err)
+	var ws []config.Warning
+	if *strict {
+		var errs []error
+		ws, errs = config.ValidateAll(&conf)
+		for _, e := range errs {
+			golog.Printf("failed to validate config: %v", e)
+		}
+		if len(errs) != 0 {
+			golog.Fatalf("config has %d problem(s)", len(errs))
+		}
+	} else {
+		ws, err = config.Validate(&conf)
+		if err != nil {
+			golog.Fatalf("failed to validate config: %v", err)
+		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -112,7 +143,23 @@ func main() {
 			return
 		}
 		down.Add(i.Server)
-		if err := i.ListenAndServe(); err != http.ErrServerClosed {
+		l, err := listen.Listen(i.Addr, conf.UnixSocket)
+		if err != nil {
+			zlog.Warn(srvctx).
+				Err(err).Msg("introspection server failed to launch. continuing anyway")
+			return
+		}
+		if ic := conf.Introspection; ic != nil && ic.TLS != nil {
+			tlsConf, err := initialize.IntrospectionTLS(srvctx, ic.TLS)
+			if err != nil {
+				l.Close()
+				zlog.Warn(srvctx).
+					Err(err).Msg("introspection server tls configuration failed. continuing anyway")
+				return
+			}
+			l = tls.NewListener(l, tlsConf)
+		}
+		if err := i.Serve(l); err != http.ErrServerClosed {
 			zlog.Warn(srvctx).
 				Err(err).Msg("introspection server failed to launch. continuing anyway")
 		}
@@ -122,28 +169,61 @@ func main() {
 	// HTTP API server goroutine.
 	srvs.Go(func() error {
 		zlog.Info(srvctx).Msg("launching http transport")
-		srvs, err := initialize.Services(srvctx, &conf)
+		svcs, err := initialize.Services(srvctx, &conf)
 		if err != nil {
 			return fmt.Errorf("service initialization failed: %w", err)
 		}
-		h, err := httptransport.New(srvctx, conf, srvs.Indexer, srvs.Matcher, srvs.Notifier)
-		if err != nil {
-			return fmt.Errorf("http transport configuration failed: %w", err)
-		}
-		l, err := net.Listen("tcp", conf.HTTPListenAddr)
+		h, err := httptransport.New(srvctx, conf, svcs.Indexer, svcs.Matcher, svcs.Notifier)
 		if err != nil {
 			return fmt.Errorf("http transport configuration failed: %w", err)
 		}
+		var tlsConf *tls.Config
 		if conf.TLS != nil {
-			cfg, err := conf.TLS.Config()
+			tlsConf, err = initialize.ServerTLS(srvctx, conf.TLS)
 			if err != nil {
 				return fmt.Errorf("tls configuration failed: %w", err)
 			}
-			cfg.NextProtos = []string{"h2"}
-			l = tls.NewListener(l, cfg)
 		}
-		down.Add(h.Server)
+		announce := func(srv *http.Server) (net.Listener, error) {
+			l, err := listen.Listen(srv.Addr, conf.UnixSocket)
+			if err != nil {
+				return nil, err
+			}
+			if tlsConf != nil {
+				l = tls.NewListener(l, tlsConf)
+			}
+			down.Add(srv)
+			return l, nil
+		}
+		// Services with their own listeners.
+		for _, sl := range h.Listeners {
+			sl := sl
+			l, err := announce(sl.Server)
+			if err != nil {
+				return fmt.Errorf("%s listener failed to launch: %w", sl.Name, err)
+			}
+			srvs.Go(func() error {
+				zlog.Info(srvctx).
+					Str("service", sl.Name).
+					Str("address", sl.Addr).
+					Msg("launching separate listener")
+				if err := sl.Serve(l); err != http.ErrServerClosed {
+					return fmt.Errorf("%s listener failed to launch: %w", sl.Name, err)
+				}
+				return nil
+			})
+		}
+		l, err := announce(h.Server)
+		if err != nil {
+			return fmt.Errorf("http transport failed to launch: %w", err)
+		}
 		health.Ready()
+		if err := systemd.Notify("READY=1"); err != nil {
+			zlog.Warn(srvctx).Err(err).Msg("unable to notify service manager")
+		}
+		go systemd.Watchdog(srvctx, func(ctx context.Context) bool {
+			return health.Probe(ctx).Healthy
+		})
 		if err := h.Serve(l); err != http.ErrServerClosed {
 			return fmt.Errorf("http transport failed to launch: %w", err)
 		}
@@ -157,6 +237,9 @@ func main() {
 			// Note that we're using a background context here, so that we get a
 			// full timeout if the signal handler has fired.
 			tctx, done := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := systemd.Notify("STOPPING=1"); err != nil {
+				zlog.Warn(ctx).Err(err).Msg("unable to notify service manager")
+			}
 			err := down.Shutdown(tctx)
 			if err != nil {
 				zlog.Error(ctx).Err(err).Msg("error shutting down server")
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/quay/zlog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+// AttestationCmd is the "attestation" subcommand.
+var AttestationCmd = &cli.Command{
+	Name: "attestation",
+	Description: "Request and print an in-toto vulnerability attestation for the named container.\n\n" +
+		"The predicate is the one used by \"cosign attest --type vuln\", so the output of\n" +
+		"\"clairctl attestation --predicate\" can be passed as cosign's \"--predicate\" argument.",
+	Action:    attestationAction,
+	Usage:     "request a vulnerability attestation for the named container",
+	ArgsUsage: "container",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.BoolFlag{
+			Name:  "predicate",
+			Usage: "print only the predicate, for use with \"cosign attest\"",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "signed",
+			Usage: "request the attestation signed, in a DSSE envelope",
+			Value: false,
+		},
+	},
+}
+
+func attestationAction(c *cli.Context) error {
+	args := c.Args()
+	if args.Len() != 1 {
+		return errors.New("need exactly one container")
+	}
+	if c.Bool("predicate") && c.Bool("signed") {
+		return errors.New("only one of \"predicate\" and \"signed\" may be used")
+	}
+	ref := args.First()
+
+	fi, err := os.Stat(c.Path("config"))
+	useCfg := err == nil && !fi.IsDir()
+	ctx := zlog.ContextWithValues(c.Context, "ref", ref)
+	hc, err := httputil.NewClient(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	var s *httputil.Signer
+	if useCfg {
+		cfg, err := loadConfig(c.Path("config"))
+		if err != nil {
+			return err
+		}
+		s, err = httputil.NewSigner(ctx, cfg, commonClaim)
+		if err != nil {
+			return err
+		}
+		if err = s.Add(ctx, c.String("host")); err != nil {
+			return err
+		}
+	}
+	cc, err := NewClient(hc, c.String("host"), s)
+	if err != nil {
+		return err
+	}
+
+	d, err := resolveRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	ctx = zlog.ContextWithValues(ctx, "digest", d.String())
+	// Make sure the manifest is indexed, uploading it only if Clair hasn't
+	// seen it: an existing index report is good enough to attest to.
+	var m *IndexRequest
+	for ct := 1; ; ct++ {
+		if ct > 20 {
+			return errors.New("too many attempts")
+		}
+		err = cc.IndexReport(ctx, d, m)
+		switch {
+		case err == nil, errors.Is(err, errNeedManifest):
+		case errors.Is(err, errNovelManifest):
+			m, err = InspectRequest(ctx, ref)
+			if err != nil {
+				return err
+			}
+			continue
+		default:
+			return err
+		}
+		break
+	}
+	return cc.Attestation(ctx, os.Stdout, d, ref, c.Bool("predicate"), c.Bool("signed"))
+}
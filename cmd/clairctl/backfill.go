@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/matcher/backfill"
+)
+
+// BackfillCmd is the "backfill" subcommand.
+var BackfillCmd = &cli.Command{
+	Name: "backfill",
+	Description: "Re-run matching across all indexed manifests, at a limited rate and in resumable chunks. " +
+		"This is useful after a change to matcher logic, so that stored vulnerability reports " +
+		"are refreshed without waiting for clients to request them.",
+	Usage:    "manage re-match backfills",
+	Category: "Advanced",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "start",
+			Usage:  "start a backfill",
+			Action: backfillStartAction,
+			Flags: append([]cli.Flag{
+				&cli.IntFlag{
+					Name:  "rate",
+					Usage: "manifests re-matched per second, or 0 for the matcher's configured rate",
+				},
+				&cli.IntFlag{
+					Name:  "chunk-size",
+					Usage: "manifests re-matched between saves of progress, or 0 for the matcher's configured size",
+				},
+			}, hostFlags...),
+		},
+		{
+			Name: "status",
+			Description: "Report on a backfill, or list all backfills if no ID is given. " +
+				"With --follow, print the backfill's progress until it stops running.",
+			Usage:     "report backfill progress",
+			ArgsUsage: "[BACKFILL_ID]",
+			Action:    backfillStatusAction,
+			Flags: append([]cli.Flag{
+				&cli.BoolFlag{
+					Name:    "follow",
+					Aliases: []string{"f"},
+					Usage:   "print progress until the backfill stops running",
+				},
+				&cli.DurationFlag{
+					Name:  "interval",
+					Usage: "how often to check progress with --follow",
+					Value: 10 * time.Second,
+				},
+			}, hostFlags...),
+		},
+		{
+			Name:      "pause",
+			Usage:     "pause a running backfill",
+			ArgsUsage: "BACKFILL_ID",
+			Action:    backfillStateAction(backfill.StatePaused),
+			Flags:     hostFlags,
+		},
+		{
+			Name:      "resume",
+			Usage:     "resume a paused backfill",
+			ArgsUsage: "BACKFILL_ID",
+			Action:    backfillStateAction(backfill.StateRunning),
+			Flags:     hostFlags,
+		},
+		{
+			Name:      "cancel",
+			Usage:     "cancel a running or paused backfill",
+			ArgsUsage: "BACKFILL_ID",
+			Action:    backfillStateAction(backfill.StateCanceled),
+			Flags:     hostFlags,
+		},
+	},
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(v)
+}
+
+// BackfillID parses the single backfill ID argument.
+func backfillID(c *cli.Context) (uuid.UUID, error) {
+	if c.NArg() != 1 {
+		return uuid.Nil, errors.New("exactly one backfill ID is needed")
+	}
+	return uuid.Parse(c.Args().First())
+}
+
+func backfillStartAction(c *cli.Context) error {
+	cc, err := hostClient(c)
+	if err != nil {
+		return err
+	}
+	j, err := cc.StartBackfill(c.Context, c.Int("rate"), c.Int("chunk-size"))
+	if err != nil {
+		return err
+	}
+	return printJSON(j)
+}
+
+func backfillStatusAction(c *cli.Context) error {
+	ctx := c.Context
+	cc, err := hostClient(c)
+	if err != nil {
+		return err
+	}
+	if c.NArg() == 0 {
+		js, err := cc.Backfills(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(js)
+	}
+	id, err := backfillID(c)
+	if err != nil {
+		return err
+	}
+	j, err := cc.Backfill(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !c.Bool("follow") {
+		return printJSON(j)
+	}
+	tick := time.NewTicker(c.Duration("interval"))
+	defer tick.Stop()
+	for {
+		fmt.Fprintln(os.Stderr, progressLine(j))
+		if j.State != backfill.StateRunning {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+		if j, err = cc.Backfill(ctx, id); err != nil {
+			return err
+		}
+	}
+}
+
+// ProgressLine summarizes a backfill's progress on one line.
+func progressLine(j *backfill.Job) string {
+	s := fmt.Sprintf("%s: %5.1f%% (%d/%d, %d failed)",
+		j.State, j.Progress*100, j.Done, j.Total, j.Failed)
+	if j.ETA != nil {
+		s += fmt.Sprintf(", eta %s (%s)",
+			j.ETA.Local().Format(time.RFC3339), time.Until(*j.ETA).Round(time.Second))
+	}
+	return s
+}
+
+func backfillStateAction(s backfill.State) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		id, err := backfillID(c)
+		if err != nil {
+			return err
+		}
+		cc, err := hostClient(c)
+		if err != nil {
+			return err
+		}
+		j, err := cc.SetBackfillState(c.Context, id, s)
+		if err != nil {
+			return err
+		}
+		return printJSON(j)
+	}
+}
@@ -14,14 +14,19 @@
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/uuid"
 	"github.com/quay/claircore"
 	"github.com/quay/zlog"
 	"github.com/tomnomnom/linkheader"
 
 	"github.com/quay/clair/v4/cmd"
 	"github.com/quay/clair/v4/httptransport"
+	"github.com/quay/clair/v4/indexer"
 	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dsse"
 	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher/backfill"
+	"github.com/quay/clair/v4/notifier"
 )
 
 var (
@@ -105,7 +110,14 @@ func (c *Client) setValidator(ctx context.Context, path, v string) {
 	errNovelManifest = errors.New("manifest unknown to the system")
 )
 
-func (c *Client) IndexReport(ctx context.Context, id claircore.Digest, m *claircore.Manifest) error {
+// IndexRequest is the body of an index request: a manifest and, optionally,
+// where to fetch its image config.
+type IndexRequest struct {
+	*claircore.Manifest
+	Config *claircore.Layer `json:"config,omitempty"`
+}
+
+func (c *Client) IndexReport(ctx context.Context, id claircore.Digest, m *IndexRequest) error {
 	var (
 		req *http.Request
 		res *http.Response
@@ -189,6 +201,8 @@ func (c *Client) IndexReport(ctx context.Context, id claircore.Digest, m *clairc
 	case http.StatusOK:
 	case http.StatusCreated:
 		//
+	case http.StatusUnprocessableEntity:
+		return unsupportedPlatform(res.Body)
 	default:
 		return fmt.Errorf("unexpected return status: %d", res.StatusCode)
 	}
@@ -235,6 +249,79 @@ func (c *Client) IndexReport(ctx context.Context, id claircore.Digest, m *clairc
 	return nil
 }
 
+// UnsupportedPlatform returns the *indexer.UnsupportedPlatform described by
+// the indexer's "unsupported-platform" error response in "r", or a generic
+// error for any other unprocessable request.
+func unsupportedPlatform(r io.Reader) error {
+	var res struct {
+		Code     string                       `json:"code"`
+		Message  string                       `json:"message"`
+		Platform *indexer.UnsupportedPlatform `json:"platform"`
+	}
+	dec := codec.GetDecoder(io.LimitReader(r, 4096))
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&res); err != nil {
+		return fmt.Errorf("unexpected return status: %d", http.StatusUnprocessableEntity)
+	}
+	if res.Code != "unsupported-platform" || res.Platform == nil {
+		return fmt.Errorf("unprocessable request: %s", res.Message)
+	}
+	return res.Platform
+}
+
+// IndexDetails are the members of an index report that describe the image
+// rather than its packages.
+type IndexDetails struct {
+	ImageConfig *indexer.ImageConfig `json:"image_config"`
+	Unpackaged  *indexer.Unpackaged  `json:"unpackaged"`
+}
+
+// IndexDetails returns the image config and unpackaged contents the indexer
+// recorded for "id". Members the indexer didn't record are nil.
+func (c *Client) IndexDetails(ctx context.Context, id claircore.Digest) (*IndexDetails, error) {
+	u, err := c.host.Parse(path.Join(c.host.RequestURI(), httptransport.IndexReportAPIPath, id.String()))
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Msg("unable to construct index_report url")
+		return nil, err
+	}
+	req, err := c.request(ctx, u, http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	// The validator is for the index operation, but the whole report is
+	// needed here.
+	req.Header.Del("if-none-match")
+	res, err := c.client.Do(req)
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Stringer("url", req.URL).
+			Msg("request failed")
+		return nil, err
+	}
+	defer res.Body.Close()
+	zlog.Debug(ctx).
+		Str("method", res.Request.Method).
+		Str("path", res.Request.URL.Path).
+		Str("status", res.Status).
+		Send()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	var report IndexDetails
+	dec := codec.GetDecoder(res.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&report); err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Msg("unable to decode json payload")
+		return nil, err
+	}
+	return &report, nil
+}
+
 func (c *Client) VulnerabilityReport(ctx context.Context, id claircore.Digest) (*claircore.VulnerabilityReport, error) {
 	var (
 		req *http.Request
@@ -286,6 +373,59 @@ func (c *Client) VulnerabilityReport(ctx context.Context, id claircore.Digest) (
 	return &report, nil
 }
 
+// Attestation writes the in-toto attestation of the vulnerability report for
+// "id" to "w". The subject is called "name", if provided. If "predicate" is
+// set, only the predicate is written; if "signed" is set, the attestation is
+// requested in a DSSE envelope.
+func (c *Client) Attestation(ctx context.Context, w io.Writer, id claircore.Digest, name string, predicate, signed bool) error {
+	u, err := c.host.Parse(path.Join(c.host.RequestURI(), httptransport.AttestationAPIPath, id.String()))
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Msg("unable to construct attestation url")
+		return err
+	}
+	q := url.Values{}
+	if name != "" {
+		q.Set("name", name)
+	}
+	if predicate {
+		q.Set("predicate", "true")
+	}
+	u.RawQuery = q.Encode()
+	req, err := c.request(ctx, u, http.MethodGet)
+	if err != nil {
+		return err
+	}
+	if signed {
+		req.Header.Set("accept", dsse.MediaType)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Stringer("url", req.URL).
+			Msg("request failed")
+		return err
+	}
+	defer res.Body.Close()
+	zlog.Debug(ctx).
+		Str("method", res.Request.Method).
+		Str("path", res.Request.URL.Path).
+		Str("status", res.Status).
+		Send()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	if signed {
+		if ct := res.Header.Get("content-type"); ct != dsse.MediaType {
+			return fmt.Errorf("attestation not signed (content-type %q): is report signing configured?", ct)
+		}
+	}
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
 func (c *Client) DeleteIndexReports(ctx context.Context, ds []claircore.Digest) error {
 	var (
 		req *http.Request
@@ -323,6 +463,185 @@ func (c *Client) DeleteIndexReports(ctx context.Context, ds []claircore.Digest)
 	return nil
 }
 
+// NotifierSelfTest asks the notifier to send a test notification through its
+// configured deliverer.
+func (c *Client) NotifierSelfTest(ctx context.Context) (*notifier.SelfTestResult, error) {
+	u, err := c.host.Parse(path.Join(c.host.RequestURI(), httptransport.NotifierSelfTestAPIPath))
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.request(ctx, u, http.MethodPost)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Stringer("url", req.URL).
+			Msg("request failed")
+		return nil, err
+	}
+	defer res.Body.Close()
+	zlog.Debug(ctx).
+		Str("method", res.Request.Method).
+		Str("path", res.Request.URL.Path).
+		Str("status", res.Status).
+		Send()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errors.New("self-test not supported by server")
+	default:
+		return nil, fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	var out notifier.SelfTestResult
+	dec := codec.GetDecoder(res.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeliveryReport retrieves the notifier's record of delivering the
+// notification "id".
+func (c *Client) DeliveryReport(ctx context.Context, id uuid.UUID) (*notifier.DeliveryReport, error) {
+	u, err := c.host.Parse(path.Join(c.host.RequestURI(), httptransport.DeliveryReportAPIPath, id.String()))
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.request(ctx, u, http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Stringer("url", req.URL).
+			Msg("request failed")
+		return nil, err
+	}
+	defer res.Body.Close()
+	zlog.Debug(ctx).
+		Str("method", res.Request.Method).
+		Str("path", res.Request.URL.Path).
+		Str("status", res.Status).
+		Send()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("no delivery report for notification %v", id)
+	default:
+		return nil, fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	var out notifier.DeliveryReport
+	dec := codec.GetDecoder(res.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartBackfill starts a re-match backfill. A zero "rate" or "chunkSize" uses
+// the matcher's configured default.
+func (c *Client) StartBackfill(ctx context.Context, rate, chunkSize int) (*backfill.Job, error) {
+	var body interface{}
+	if rate != 0 || chunkSize != 0 {
+		body = struct {
+			Rate      int `json:"rate,omitempty"`
+			ChunkSize int `json:"chunk_size,omitempty"`
+		}{rate, chunkSize}
+	}
+	var out backfill.Job
+	if err := c.backfill(ctx, http.MethodPost, "", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Backfills lists the matcher's re-match backfills, newest first.
+func (c *Client) Backfills(ctx context.Context) ([]backfill.Job, error) {
+	var out []backfill.Job
+	if err := c.backfill(ctx, http.MethodGet, "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Backfill retrieves the re-match backfill "id".
+func (c *Client) Backfill(ctx context.Context, id uuid.UUID) (*backfill.Job, error) {
+	var out backfill.Job
+	if err := c.backfill(ctx, http.MethodGet, id.String(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetBackfillState pauses, resumes, or cancels the re-match backfill "id".
+func (c *Client) SetBackfillState(ctx context.Context, id uuid.UUID, s backfill.State) (*backfill.Job, error) {
+	body := struct {
+		State backfill.State `json:"state"`
+	}{s}
+	var out backfill.Job
+	if err := c.backfill(ctx, http.MethodPatch, id.String(), &body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Backfill makes a request to the backfill endpoint "elem", sending "body" if
+// it's not nil, and decodes the response into "out".
+func (c *Client) backfill(ctx context.Context, m, elem string, body, out interface{}) error {
+	u, err := c.host.Parse(path.Join(c.host.RequestURI(), httptransport.BackfillAPIPath, elem))
+	if err != nil {
+		return err
+	}
+	req, err := c.request(ctx, u, m)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Body = codec.JSONReader(body)
+		req.Header.Set("content-type", "application/json")
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Stringer("url", req.URL).
+			Msg("request failed")
+		return err
+	}
+	defer res.Body.Close()
+	zlog.Debug(ctx).
+		Str("method", res.Request.Method).
+		Str("path", res.Request.URL.Path).
+		Str("status", res.Status).
+		Send()
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusNotFound, http.StatusBadRequest, http.StatusConflict:
+		// These carry a message worth showing.
+		var e struct {
+			Message string `json:"message"`
+		}
+		dec := codec.GetDecoder(res.Body)
+		defer codec.PutDecoder(dec)
+		if err := dec.Decode(&e); err != nil || e.Message == "" {
+			return fmt.Errorf("unexpected return status: %d", res.StatusCode)
+		}
+		return errors.New(e.Message)
+	default:
+		return fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	dec := codec.GetDecoder(res.Body)
+	defer codec.PutDecoder(dec)
+	return dec.Decode(out)
+}
+
 func (c *Client) request(ctx context.Context, u *url.URL, m string) (*http.Request, error) {
 	req, err := httputil.NewRequestWithContext(ctx, m, u.String(), nil)
 	if err != nil {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+func TestUnsupportedPlatform(t *testing.T) {
+	const body = `{"code":"unsupported-platform",` +
+		`"message":"unsupported platform: windows 10.0.17763.5122",` +
+		`"platform":{"os":"windows","os_version":"10.0.17763.5122"}}`
+	err := unsupportedPlatform(strings.NewReader(body))
+	if !errors.Is(err, indexer.ErrUnsupportedPlatform) {
+		t.Fatalf("got: %v, want: %v", err, indexer.ErrUnsupportedPlatform)
+	}
+	var u *indexer.UnsupportedPlatform
+	if !errors.As(err, &u) {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if got, want := *u, (indexer.UnsupportedPlatform{OS: "windows", OSVersion: "10.0.17763.5122"}); got != want {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	err = unsupportedPlatform(strings.NewReader(`{"code":"internal-error","message":"oops"}`))
+	if err == nil || errors.Is(err, indexer.ErrUnsupportedPlatform) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
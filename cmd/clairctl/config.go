@@ -9,6 +9,8 @@
 	"github.com/quay/zlog"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v3"
+
+	"github.com/quay/clair/v4/cmd"
 )
 
 var CheckConfigCmd = &cli.Command{
@@ -16,6 +18,9 @@
 	Usage: "print a fully-resolved clair config",
 	Description: `Check-config can be used to check that drop-in config files are being merged correctly.
 
+With the "strict" flag, every file is also checked for unknown keys and values of
+the wrong type, and every problem is reported along with its line number.
+
 The output is not currently suitable to be fed back into Clair.`,
 	Action:    checkConfigAction,
 	ArgsUsage: "FILE[...]",
@@ -26,6 +31,10 @@
 			Usage:   "output format: json, yaml",
 			Value:   "json",
 		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "reject unknown keys and report every problem",
+		},
 	},
 }
 
@@ -63,6 +72,18 @@ func checkConfigAction(c *cli.Context) error {
 		if len(todo) > 1 {
 			fmt.Println("#", f)
 		}
+		if c.Bool("strict") {
+			errs, err := cmd.CheckSchema(f)
+			if err != nil {
+				return err
+			}
+			for _, e := range errs {
+				fmt.Fprintln(os.Stderr, e)
+			}
+			if len(errs) != 0 {
+				return fmt.Errorf("%s: %d problem(s)", f, len(errs))
+			}
+		}
 		cfg, err := loadConfig(f)
 		if err != nil {
 			return err
@@ -17,6 +17,7 @@
 	"github.com/urfave/cli/v2"
 
 	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher/feed"
 )
 
 // ExportCmd is the "export-updaters" subcommand.
@@ -122,7 +123,10 @@ func exportAction(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	cl.Transport = httputil.RateLimiter(cl.Transport)
+	cl.Transport, err = httputil.Signatures(httputil.Mirror(httputil.RateLimiter(cl.Transport), cfg.Updaters.Mirrors), cfg.Updaters.Signatures)
+	if err != nil {
+		return err
+	}
 
 	store, err := jsonblob.New()
 	if err != nil {
@@ -133,9 +137,14 @@ func exportAction(c *cli.Context) error {
 			fmt.Fprintln(os.Stderr, err)
 		}
 	}()
+	feeds, _, err := feed.Drivers(cfg.Updaters.Feeds, cl)
+	if err != nil {
+		return err
+	}
 	mgr, err := updates.NewManager(ctx, store, updates.NewLocalLockSource(), cl,
 		updates.WithConfigs(cfgs),
 		updates.WithEnabled(cfg.Updaters.Sets),
+		updates.WithOutOfTree(feeds),
 	)
 	if err != nil {
 		return err
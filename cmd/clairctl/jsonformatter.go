@@ -3,6 +3,9 @@
 import (
 	"io"
 
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
 	"github.com/quay/clair/v4/internal/codec"
 )
 
@@ -16,7 +19,27 @@ type jsonFormatter struct {
 }
 
 func (f *jsonFormatter) Format(r *Result) error {
-	return f.enc.Encode(r.Report)
+	if r.Unsupported != nil {
+		return f.enc.Encode(struct {
+			Name        string                       `json:"name"`
+			Unsupported *indexer.UnsupportedPlatform `json:"unsupported"`
+		}{
+			Name:        r.Name,
+			Unsupported: r.Unsupported,
+		})
+	}
+	if r.Config == nil && r.Unpackaged == nil {
+		return f.enc.Encode(r.Report)
+	}
+	return f.enc.Encode(struct {
+		*claircore.VulnerabilityReport
+		ImageConfig *indexer.ImageConfig `json:"image_config,omitempty"`
+		Unpackaged  *indexer.Unpackaged  `json:"unpackaged,omitempty"`
+	}{
+		VulnerabilityReport: r.Report,
+		ImageConfig:         r.Config,
+		Unpackaged:          r.Unpackaged,
+	})
 }
 func (f *jsonFormatter) Close() error {
 	codec.PutEncoder(f.enc)
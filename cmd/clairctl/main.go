@@ -25,6 +25,8 @@
 		Logger()
 
 	commonClaim = jwt.Claims{}
+	// ConfigProfile is the profile used when loading the configuration.
+	configProfile string
 )
 
 func main() {
@@ -48,15 +50,20 @@ func main() {
 			}
 			zlog.Set(&logout)
 			commonClaim.Issuer = c.String("issuer")
+			configProfile = c.String("profile")
 			return nil
 		},
 		Commands: []*cli.Command{
 			ManifestCmd,
 			ReportCmd,
+			AttestationCmd,
 			ExportCmd,
 			ImportCmd,
 			DeleteCmd,
+			NotifierTestCmd,
+			NotifierReceiptCmd,
 			CheckConfigCmd,
+			BackfillCmd,
 			AdminCmd,
 		},
 		Flags: []cli.Flag{
@@ -76,6 +83,11 @@ func main() {
 				TakesFile: true,
 				EnvVars:   []string{"CLAIR_CONF"},
 			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "profile from the clair configuration file to use",
+				EnvVars: []string{"CLAIR_PROFILE"},
+			},
 			&cli.StringFlag{
 				Name:    "issuer",
 				Aliases: []string{"iss"},
@@ -99,7 +111,7 @@ func main() {
 
 func loadConfig(n string) (*config.Config, error) {
 	var cfg config.Config
-	if err := cmd.LoadConfig(&cfg, n, false); err != nil {
+	if _, err := cmd.LoadProfile(&cfg, n, configProfile, false); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
@@ -10,6 +10,7 @@
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/quay/claircore"
 	"github.com/quay/zlog"
@@ -75,6 +76,20 @@ func manifestAction(c *cli.Context) error {
 }
 
 func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
+	req, err := inspect(ctx, r, false)
+	if err != nil {
+		return nil, err
+	}
+	return req.Manifest, nil
+}
+
+// InspectRequest is like Inspect, but returns an index request that also
+// describes where to fetch the image config.
+func InspectRequest(ctx context.Context, r string) (*IndexRequest, error) {
+	return inspect(ctx, r, true)
+}
+
+func inspect(ctx context.Context, r string, wantConfig bool) (*IndexRequest, error) {
 	rt, err := rt(ctx, r)
 	if err != nil {
 		return nil, err
@@ -124,11 +139,9 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 		Transport: rt,
 	}
 
-	for _, l := range ls {
-		d, err := l.Digest()
-		if err != nil {
-			return nil, err
-		}
+	// Request the first byte of every blob to follow any redirects and
+	// capture the authorization Clair needs to fetch it.
+	locate := func(d v1.Hash) (*claircore.Layer, error) {
 		ccd, err := claircore.ParseDigest(d.String())
 		if err != nil {
 			return nil, err
@@ -150,12 +163,33 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 
 		res.Request.Header.Del("User-Agent")
 		res.Request.Header.Del("Range")
-		out.Layers = append(out.Layers, &claircore.Layer{
+		return &claircore.Layer{
 			Hash:    ccd,
 			URI:     res.Request.URL.String(),
 			Headers: res.Request.Header,
-		})
+		}, nil
+	}
+	for _, l := range ls {
+		d, err := l.Digest()
+		if err != nil {
+			return nil, err
+		}
+		cl, err := locate(d)
+		if err != nil {
+			return nil, err
+		}
+		out.Layers = append(out.Layers, cl)
+	}
+	req := IndexRequest{Manifest: &out}
+	if wantConfig {
+		d, err := img.ConfigName()
+		if err != nil {
+			return nil, err
+		}
+		if req.Config, err = locate(d); err != nil {
+			return nil, err
+		}
 	}
 
-	return &out, nil
+	return &req, nil
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+var hostFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "host",
+		Usage:   "URL for the clairv4 v1 API.",
+		Value:   "http://localhost:6060/",
+		EnvVars: []string{"CLAIR_API"},
+	},
+}
+
+var NotifierTestCmd = &cli.Command{
+	Name: "notifier-test",
+	Description: "Send a synthetic notification through the notifier's configured deliverer " +
+		"and report the probe and delivery results. " +
+		"Exits non-zero if delivery failed.",
+	Action: notifierTestAction,
+	Usage:  "sends a test notification",
+	Flags:  hostFlags,
+}
+
+var NotifierReceiptCmd = &cli.Command{
+	Name: "notifier-receipt",
+	Description: "Report the delivery status of a notification: its receipt status, " +
+		"the number of delivery attempts, the last error, and when it was delivered.",
+	Action:    notifierReceiptAction,
+	Usage:     "reports on the delivery of a notification",
+	ArgsUsage: "NOTIFICATION_ID",
+	Flags:     hostFlags,
+}
+
+// HostClient returns a Client for the "host" flag, signing requests if a
+// configuration file is present.
+func hostClient(c *cli.Context) (*Client, error) {
+	fi, err := os.Stat(c.Path("config"))
+	useCfg := err == nil && !fi.IsDir()
+	ctx := c.Context
+	hc, err := httputil.NewClient(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *httputil.Signer
+	if useCfg {
+		cfg, err := loadConfig(c.Path("config"))
+		if err != nil {
+			return nil, err
+		}
+		s, err = httputil.NewSigner(ctx, cfg, commonClaim)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.Add(ctx, c.String("host")); err != nil {
+			return nil, err
+		}
+	}
+	return NewClient(hc, c.String("host"), s)
+}
+
+func notifierTestAction(c *cli.Context) error {
+	ctx := c.Context
+	cc, err := hostClient(c)
+	if err != nil {
+		return err
+	}
+	res, err := cc.NotifierSelfTest(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(res); err != nil {
+		return err
+	}
+	if !res.OK {
+		return errors.New("self-test failed")
+	}
+	return nil
+}
+
+func notifierReceiptAction(c *cli.Context) error {
+	ctx := c.Context
+	if c.NArg() != 1 {
+		return errors.New("exactly one notification ID is needed")
+	}
+	id, err := uuid.Parse(c.Args().First())
+	if err != nil {
+		return err
+	}
+	cc, err := hostClient(c)
+	if err != nil {
+		return err
+	}
+	res, err := cc.DeliveryReport(ctx, id)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(res)
+}
@@ -8,6 +8,7 @@
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
@@ -16,6 +17,7 @@
 	"github.com/urfave/cli/v2"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/quay/clair/v4/indexer"
 	"github.com/quay/clair/v4/internal/codec"
 	"github.com/quay/clair/v4/internal/httputil"
 )
@@ -52,6 +54,11 @@
 			Usage: "only upload novel manifests",
 			Value: false,
 		},
+		&cli.BoolFlag{
+			Name:  "history",
+			Usage: "include the image config recorded by the indexer and attribute findings to the instruction that introduced them",
+			Value: false,
+		},
 	},
 }
 
@@ -113,8 +120,76 @@ type Formatter interface {
 // Users should examine Err first to determine if the request succeeded.
 type Result struct {
 	Report *claircore.VulnerabilityReport
-	Err    error
-	Name   string
+	Config *indexer.ImageConfig
+	// Unpackaged is populated if the indexer looks for contents no package
+	// manager accounts for.
+	Unpackaged *indexer.Unpackaged
+	Err        error
+	Name       string
+	// Unsupported is populated, along with Err, if the indexer refused the
+	// image because it can't index its operating system.
+	Unsupported *indexer.UnsupportedPlatform
+}
+
+// CreatedBy reports the image history instruction that introduced the
+// package with the provided ID, if known.
+func (r *Result) CreatedBy(pkgID string) string {
+	if r.Config == nil || r.Report == nil {
+		return ""
+	}
+	for _, env := range r.Report.Environments[pkgID] {
+		if s := r.Config.CreatedBy(env.IntroducedIn); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// NoPackageManager reports whether the image has no packages because the
+// indexer found no package manager metadata, meaning its contents couldn't be
+// inventoried rather than being clean.
+func (r *Result) NoPackageManager() bool {
+	return r.Report != nil && len(r.Report.Packages) == 0 &&
+		r.Unpackaged != nil && !r.Unpackaged.PackageManager
+}
+
+// UnpackagedSummary describes the files found in the image that no package
+// manager accounts for, such as "2 static binaries, busybox, 1 CA bundle".
+func (r *Result) UnpackagedSummary() string {
+	if r.Unpackaged == nil {
+		return ""
+	}
+	var static, bundles int
+	var busybox bool
+	for _, f := range r.Unpackaged.Files {
+		switch f.Kind {
+		case indexer.UnpackagedStaticBinary:
+			static++
+		case indexer.UnpackagedCABundle:
+			bundles++
+		case indexer.UnpackagedBusybox:
+			busybox = true
+		}
+	}
+	var out []string
+	switch static {
+	case 0:
+	case 1:
+		out = append(out, "1 static binary")
+	default:
+		out = append(out, fmt.Sprintf("%d static binaries", static))
+	}
+	if busybox {
+		out = append(out, "busybox")
+	}
+	switch bundles {
+	case 0:
+	case 1:
+		out = append(out, "1 CA bundle")
+	default:
+		out = append(out, fmt.Sprintf("%d CA bundles", bundles))
+	}
+	return strings.Join(out, ", ")
 }
 
 func reportAction(c *cli.Context) error {
@@ -190,7 +265,7 @@ func reportAction(c *cli.Context) error {
 			// prevents us from generating one.
 			//
 			// If we need the manifest, populate the manifest and jump to Again.
-			var m *claircore.Manifest
+			var m *IndexRequest
 			ct := 1
 		Again:
 			if ct > 20 {
@@ -200,8 +275,15 @@ func reportAction(c *cli.Context) error {
 				Int("attempt", ct).
 				Msg("requesting index_report")
 			err = cc.IndexReport(ctx, d, m)
+			var u *indexer.UnsupportedPlatform
 			switch {
 			case err == nil:
+			case errors.As(err, &u):
+				zlog.Debug(ctx).
+					Err(err).
+					Msg("skipping image")
+				result <- &Result{Name: ref, Err: err, Unsupported: u}
+				return nil
 			case errors.Is(err, errNeedManifest):
 				if c.Bool("novel") {
 					zlog.Debug(ctx).
@@ -210,7 +292,7 @@ func reportAction(c *cli.Context) error {
 				}
 				fallthrough
 			case errors.Is(err, errNovelManifest):
-				m, err = Inspect(ctx, ref)
+				m, err = InspectRequest(ctx, ref)
 				if err != nil {
 					zlog.Debug(ctx).
 						Err(err).
@@ -242,6 +324,26 @@ func reportAction(c *cli.Context) error {
 			if r.Err != nil {
 				r.Err = fmt.Errorf("%s(%v): %w", ref, d, r.Err)
 			}
+			if r.Err == nil {
+				details, err := cc.IndexDetails(ctx, d)
+				switch {
+				case err != nil:
+					// The report is still useful without the details.
+					zlog.Info(ctx).
+						Err(err).
+						Msg("unable to fetch index report details")
+				default:
+					r.Unpackaged = details.Unpackaged
+					if !c.Bool("history") {
+						break
+					}
+					r.Config = details.ImageConfig
+					if r.Config == nil {
+						zlog.Info(ctx).
+							Msg("indexer has no image config recorded")
+					}
+				}
+			}
 			result <- &r
 			return nil
 		})
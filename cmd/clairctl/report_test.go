@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/codec"
+)
+
+// NoPackageManager returns a Result for an image without a package manager.
+func noPackageManager() *Result {
+	return &Result{
+		Name: "test",
+		Report: &claircore.VulnerabilityReport{
+			Hash:     claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+			Packages: map[string]*claircore.Package{},
+		},
+		Unpackaged: &indexer.Unpackaged{
+			Files: []indexer.UnpackagedFile{
+				{Kind: indexer.UnpackagedBusybox, Path: "bin/busybox"},
+			},
+			BusyboxApplets: []string{"sh"},
+		},
+	}
+}
+
+func TestJSONNoPackageManager(t *testing.T) {
+	var buf bytes.Buffer
+	f := &jsonFormatter{enc: codec.GetEncoder(&buf), c: nopCloser{&buf}}
+	if err := f.Format(noPackageManager()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Unpackaged *indexer.Unpackaged `json:"unpackaged"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Unpackaged == nil || got.Unpackaged.PackageManager || len(got.Unpackaged.Files) != 1 {
+		t.Errorf("got: %s", buf.String())
+	}
+}
+
+func TestXMLNoPackageManager(t *testing.T) {
+	var buf bytes.Buffer
+	f := &xmlFormatter{enc: xml.NewEncoder(&buf), c: nopCloser{&buf}}
+	if err := f.Format(noPackageManager()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	t.Logf("got: %s", got)
+	for _, want := range []string{
+		`<property name="package_manager" value="false"></property>`,
+		`<skipped>no package manager found; contents could not be inventoried (found: busybox)</skipped>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want: %q", want)
+		}
+	}
+}
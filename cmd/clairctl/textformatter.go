@@ -39,6 +39,15 @@ func newTextFormatter(w io.WriteCloser) (*textFormatter, error) {
 {{- define "ok" -}}
 {{base .Name}}	ok
 {{end}}
+{{- define "empty" -}}
+{{base .Name}}	no packages found
+{{end}}
+{{- define "unpackaged" -}}
+{{base .Name}}	no package manager{{with .UnpackagedSummary}}	{{.}}{{end}}
+{{end}}
+{{- define "unsupported" -}}
+{{base .Name}}	unsupported	{{.Unsupported.OS}}{{with .Unsupported.OSVersion}} {{.}}{{end}}
+{{end}}
 {{- define "err" -}}
 {{base .Name}}	error	{{.Err}}
 {{end}}
@@ -47,10 +56,14 @@ func newTextFormatter(w io.WriteCloser) (*textFormatter, error) {
 {{base $r.Name}}	found	{{with index $r.Report.Packages $id}}{{.Name}}	{{.Version}}{{end}}
 	{{- with index $r.Report.Vulnerabilities $d}}	{{.Name}}
 	{{- with .FixedInVersion}}	(fixed: {{.}}){{end}}{{end}}
+	{{- with $r.CreatedBy $id}}	(from: {{.}}){{end}}
 {{end}}{{end}}{{end}}{{end}}
 {{- /* The following is the actual bit of the template that runs per item. */ -}}
-{{if .Err}}{{template "err" .}}
+{{if .Unsupported}}{{template "unsupported" .}}
+{{- else if .Err}}{{template "err" .}}
 {{- else if ne (len .Report.PackageVulnerabilities) 0}}{{template "found" .}}
+{{- else if .NoPackageManager}}{{template "unpackaged" .}}
+{{- else if eq (len .Report.Packages) 0}}{{template "empty" .}}
 {{- else}}{{template "ok" .}}
 {{- end}}`
 
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestTextFormatter(t *testing.T) {
+	tt := []struct {
+		Name       string
+		Report     *claircore.VulnerabilityReport
+		Unpackaged *indexer.Unpackaged
+		Want       string
+	}{
+		{
+			Name: "Empty",
+			Report: &claircore.VulnerabilityReport{
+				Packages: map[string]*claircore.Package{},
+			},
+			Want: "no packages found",
+		},
+		{
+			Name: "NoPackageManager",
+			Report: &claircore.VulnerabilityReport{
+				Packages: map[string]*claircore.Package{},
+			},
+			Unpackaged: &indexer.Unpackaged{
+				Files: []indexer.UnpackagedFile{
+					{Kind: indexer.UnpackagedStaticBinary, Path: "app/server"},
+					{Kind: indexer.UnpackagedCABundle, Path: "etc/ssl/certs/ca-certificates.crt"},
+				},
+			},
+			Want: "no package manager 1 static binary, 1 CA bundle",
+		},
+		{
+			Name: "EmptyWithPackageManager",
+			Report: &claircore.VulnerabilityReport{
+				Packages: map[string]*claircore.Package{},
+			},
+			Unpackaged: &indexer.Unpackaged{PackageManager: true},
+			Want:       "no packages found",
+		},
+		{
+			Name: "Clean",
+			Report: &claircore.VulnerabilityReport{
+				Packages: map[string]*claircore.Package{
+					"1": {Name: "pkg", Version: "1"},
+				},
+			},
+			Want: "ok",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f, err := newTextFormatter(nopCloser{&buf})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Format(&Result{Name: "test", Report: tc.Report, Unpackaged: tc.Unpackaged}); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			t.Logf("got: %q", got)
+			if !strings.Contains(got, tc.Want) {
+				t.Errorf("want: %q", tc.Want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -64,6 +65,14 @@ func (j *junitTestsuite) Init(r *Result) error {
 	j.Errors = 0
 	j.Failures = 0
 	j.NumTests = 0
+	if u := r.Unsupported; u != nil {
+		j.Props.Props = []junitProp{{Name: "os", Value: u.OS}}
+		j.Tests = []junitTestcase{
+			{Name: r.Name, Skipped: u.Error()},
+		}
+		j.NumTests++
+		return nil
+	}
 	j.Props.Props = make([]junitProp, 1)
 	j.Props.Props[0].Name = "manifest"
 	j.Props.Props[0].Value = r.Report.Hash.String()
@@ -77,6 +86,25 @@ func (j *junitTestsuite) Init(r *Result) error {
 		return nil
 	}
 
+	if u := r.Unpackaged; u != nil {
+		j.Props.Props = append(j.Props.Props, junitProp{
+			Name:  "package_manager",
+			Value: strconv.FormatBool(u.PackageManager),
+		})
+	}
+	if r.NoPackageManager() {
+		// Nothing could be checked, which shouldn't read as passing.
+		msg := "no package manager found; contents could not be inventoried"
+		if s := r.UnpackagedSummary(); s != "" {
+			msg += " (found: " + s + ")"
+		}
+		j.Tests = []junitTestcase{
+			{Name: r.Name, Skipped: msg},
+		}
+		j.NumTests++
+		return nil
+	}
+
 	j.Tests = make([]junitTestcase, len(r.Report.Packages))
 	j.NumTests = len(r.Report.Packages)
 	i := 0
@@ -113,4 +141,5 @@ type junitTestcase struct {
 	Name    string   `xml:"name,attr"`
 	Error   string   `xml:"error,omitempty"`
 	Failure string   `xml:"failure,omitempty"`
+	Skipped string   `xml:"skipped,omitempty"`
 }
@@ -8,6 +8,7 @@
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
@@ -37,7 +38,38 @@
 // The "strict" argument controls whether the function returns on the first
 // error, or runs the full routine and returns all accumulated errors at the
 // end.
+//
+// Any profiles defined in the configuration are ignored; see LoadProfile.
 func LoadConfig(cfg *config.Config, name string, strict bool) error {
+	_, err := LoadProfile(cfg, name, "", strict)
+	return err
+}
+
+// LoadProfile is like LoadConfig, but additionally merges the named profile
+// into the configuration.
+//
+// Profiles are defined in a top-level "profiles" object, mapping names to
+// configuration snippets. After any drop-ins are applied, the "profiles"
+// object is removed and, if "profile" is not empty, the named snippet is
+// merged into the configuration in the same way as a drop-in. A profile may
+// also have a "mode" member, which sets the Mode of "cfg"; the returned bool
+// reports whether it did.
+//
+// For example, given the configuration:
+//
+//	http_listen_addr: ":6060"
+//	matcher:
+//	  indexer_addr: http://clair-indexer:6060/
+//	profiles:
+//	  dev:
+//	    mode: combo
+//	    log_level: debug
+//	  matcher:
+//	    mode: matcher
+//
+// Loading the "dev" profile returns a combo mode configuration logging at
+// the debug level.
+func LoadProfile(cfg *config.Config, name, profile string, strict bool) (bool, error) {
 	// This function would probably benefit from some logging, but the logging
 	// configuration is specified _inside_ the configuration, so it's hard to
 	// say what should be done here.
@@ -47,14 +79,14 @@ func LoadConfig(cfg *config.Config, name string, strict bool) error {
 	case ".yaml": // OK
 	case ".json": // OK
 	default:
-		return fmt.Errorf("unknown config kind %q", ext)
+		return false, fmt.Errorf("unknown config kind %q", ext)
 	}
 	var errs []error
 
 	b, err := loadAsJSON(name)
 	if err != nil {
 		if strict {
-			return err
+			return false, err
 		}
 		errs = append(errs, err)
 	}
@@ -112,7 +144,7 @@ func LoadConfig(cfg *config.Config, name string, strict bool) error {
 	case errors.Is(err, nil):
 	case errors.Is(err, fs.ErrNotExist): // OK
 	case strict:
-		return err
+		return false, err
 	default:
 		errs = append(errs, err)
 	}
@@ -120,10 +152,21 @@ func LoadConfig(cfg *config.Config, name string, strict bool) error {
 	if len(b) == 0 {
 		err := fmt.Errorf("error load config %q: empty document after merges", name)
 		if strict {
-			return err
+			return false, err
 		}
 		errs = append(errs, err)
 	}
+	var setMode bool
+	if len(b) != 0 {
+		b, setMode, err = applyProfile(cfg, b, profile)
+		if err != nil {
+			err := fmt.Errorf("error load config %q: %w", name, err)
+			if strict {
+				return setMode, err
+			}
+			errs = append(errs, err)
+		}
+	}
 	dec := json.NewDecoder(bytes.NewReader(b))
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(cfg); err != nil {
@@ -131,11 +174,74 @@ func LoadConfig(cfg *config.Config, name string, strict bool) error {
 		// confuse people.
 		err := fmt.Errorf("error decoding config %q: %s", name, strings.TrimPrefix(err.Error(), `json: `))
 		if strict {
-			return err
+			return setMode, err
 		}
 		errs = append(errs, err)
 	}
-	return errors.Join(errs...)
+	return setMode, errors.Join(errs...)
+}
+
+// ApplyProfile removes the "profiles" member from the JSON object "b" and
+// merges the named profile, if any, into it. A "mode" member in the profile
+// sets the Mode of "cfg", and the returned bool reports whether it did.
+func applyProfile(cfg *config.Config, b []byte, profile string) ([]byte, bool, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		// Let decoding into the Config report this.
+		return b, false, nil
+	}
+	raw, ok := doc["profiles"]
+	switch {
+	case !ok && profile == "":
+		return b, false, nil
+	case !ok:
+		return b, false, fmt.Errorf("profile %q requested, but no profiles defined", profile)
+	}
+	delete(doc, "profiles")
+	base, err := json.Marshal(doc)
+	if err != nil {
+		return b, false, err
+	}
+	if profile == "" {
+		return base, false, nil
+	}
+
+	var ps map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &ps); err != nil {
+		return base, false, fmt.Errorf("malformed profiles: %s", strings.TrimPrefix(err.Error(), `json: `))
+	}
+	p, ok := ps[profile]
+	if !ok {
+		names := make([]string, 0, len(ps))
+		for n := range ps {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return base, false, fmt.Errorf("unknown profile %q (defined: %s)", profile, strings.Join(names, ", "))
+	}
+	var setMode bool
+	if m, ok := p["mode"]; ok {
+		var s string
+		if err := json.Unmarshal(m, &s); err != nil {
+			return base, false, fmt.Errorf("profile %q: mode must be a string", profile)
+		}
+		mode, err := config.ParseMode(s)
+		if err != nil {
+			return base, false, fmt.Errorf("profile %q: %w", profile, err)
+		}
+		cfg.Mode = mode
+		setMode = true
+		delete(p, "mode")
+	}
+	patch, err := json.Marshal(p)
+	if err != nil {
+		return base, setMode, err
+	}
+	out, err := jsonpatch.MergePatch(base, patch)
+	if err != nil {
+		return base, setMode, fmt.Errorf("error merging profile %q: %w", profile, err)
+	}
+	return out, setMode, nil
 }
 
 func loadAsJSON(path string) ([]byte, error) {
@@ -58,3 +58,64 @@ func TestLoadConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadProfile(t *testing.T) {
+	const path = `testdata/Profiles/config.yaml`
+	tt := []struct {
+		Profile string
+		// Want is the expected configuration, or empty if an error is
+		// expected.
+		Want string
+		// Mode is the mode the profile sets, if any.
+		Mode *config.Mode
+	}{
+		{Profile: "dev", Want: "want-dev.json", Mode: modePtr(config.ComboMode)},
+		{Profile: "matcher", Want: "want.json", Mode: modePtr(config.MatcherMode)},
+		{Profile: "plain", Want: "want-plain.json"},
+		{Profile: "missing"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Profile, func(t *testing.T) {
+			got := config.Config{Mode: config.NotifierMode}
+			setMode, err := cmd.LoadProfile(&got, path, tc.Profile, true)
+			if tc.Want == "" {
+				t.Log(err)
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			wf, err := os.Open(filepath.Join(filepath.Dir(path), tc.Want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer wf.Close()
+			want := config.Config{Mode: config.NotifierMode}
+			if err := json.NewDecoder(wf).Decode(&want); err != nil {
+				t.Error(err)
+			}
+			if tc.Mode != nil {
+				want.Mode = *tc.Mode
+			}
+			if got, want := setMode, tc.Mode != nil; got != want {
+				t.Errorf("set mode: got: %v, want: %v", got, want)
+			}
+			if !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		})
+	}
+	t.Run("NoProfiles", func(t *testing.T) {
+		var got config.Config
+		_, err := cmd.LoadProfile(&got, `testdata/SimpleYAML/config.yaml`, "dev", true)
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func modePtr(m config.Mode) *config.Mode { return &m }
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/quay/clair/config"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaError is a problem found by CheckSchema.
+type SchemaError struct {
+	// File is the file the problem is in.
+	File string
+	// Line and Column locate the problem in File, starting at 1.
+	Line, Column int
+	// Path is the json-schema style path of the problem.
+	Path string
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s (at %s)", e.File, e.Line, e.Column, e.Msg, e.Path)
+}
+
+// CheckSchema checks the named config file and its drop-ins against the
+// configuration schema, returning every problem found.
+//
+// Unlike LoadConfig, which stops at the first problem, every key in every
+// document is checked. Keys must exactly match the documented names, and
+// values must have the right type. Patch drop-ins aren't checked, as they
+// only make sense once applied.
+//
+// The "profiles" object is checked as well: each profile must itself be a
+// valid configuration snippet, optionally with a "mode" member.
+func CheckSchema(name string) ([]*SchemaError, error) {
+	name = filepath.Clean(name)
+	ext := filepath.Ext(name)
+	switch ext {
+	case ".yaml": // OK
+	case ".json": // OK
+	default:
+		return nil, fmt.Errorf("unknown config kind %q", ext)
+	}
+	files := []string{name}
+	dropinDir := name + ".d"
+	err := filepath.WalkDir(dropinDir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case path == dropinDir:
+			return nil
+		case !errors.Is(err, nil):
+			return fmt.Errorf("error walking filesystem: %w", err)
+		case d.IsDir():
+			return fs.SkipDir
+		}
+		if filepath.Ext(path) == ext {
+			files = append(files, path)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, fs.ErrNotExist): // OK
+	default:
+		return nil, err
+	}
+
+	var out []*SchemaError
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %q: %w", f, err)
+		}
+		// JSON documents are YAML documents, so this handles both.
+		var doc yaml.Node
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("malformed file %q: %v", f, strings.TrimPrefix(err.Error(), `yaml: `))
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		s := schemaCheck{file: f}
+		s.root(doc.Content[0])
+		out = append(out, s.errs...)
+	}
+	return out, nil
+}
+
+var (
+	configType        = reflect.TypeOf(config.Config{})
+	textUnmarshalType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// SchemaCheck accumulates the problems in a single file.
+type schemaCheck struct {
+	file string
+	errs []*SchemaError
+}
+
+func (s *schemaCheck) errorf(n *yaml.Node, path, format string, args ...interface{}) {
+	s.errs = append(s.errs, &SchemaError{
+		File:   s.file,
+		Line:   n.Line,
+		Column: n.Column,
+		Path:   path,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// Root checks a top-level document, which may have a "profiles" member.
+func (s *schemaCheck) root(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		s.check(n, "$", configType)
+		return
+	}
+	rest := *n
+	rest.Content = nil
+	for i := 0; i < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Value != "profiles" {
+			rest.Content = append(rest.Content, k, v)
+			continue
+		}
+		if v.Kind != yaml.MappingNode {
+			s.errorf(v, "$.profiles", "expected an object of profiles")
+			continue
+		}
+		for j := 0; j < len(v.Content); j += 2 {
+			pn, p := v.Content[j], v.Content[j+1]
+			path := "$.profiles." + pn.Value
+			if p.Kind != yaml.MappingNode {
+				s.errorf(p, path, "expected an object")
+				continue
+			}
+			prest := *p
+			prest.Content = nil
+			for l := 0; l < len(p.Content); l += 2 {
+				pk, pv := p.Content[l], p.Content[l+1]
+				if pk.Value != "mode" {
+					prest.Content = append(prest.Content, pk, pv)
+					continue
+				}
+				if pv.Kind != yaml.ScalarNode || pv.Tag != "!!str" {
+					s.errorf(pv, path+".mode", "expected a string")
+				} else if _, err := config.ParseMode(pv.Value); err != nil {
+					s.errorf(pv, path+".mode", "%v", err)
+				}
+			}
+			s.check(&prest, path, configType)
+		}
+	}
+	s.check(&rest, "$", configType)
+}
+
+// Check checks that the node "n" can be decoded into a value of type "t".
+func (s *schemaCheck) check(n *yaml.Node, path string, t reflect.Type) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		// Decoding null leaves the value as-is.
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	pt := reflect.PtrTo(t)
+	switch {
+	case pt.Implements(jsonUnmarshalType):
+		// Can't know what the type accepts.
+		return
+	case pt.Implements(textUnmarshalType):
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!str" {
+			s.errorf(n, path, "expected a string")
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		// Anything goes.
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			s.errorf(n, path, "expected an object")
+			return
+		}
+		fields := structFields(t)
+		for i := 0; i < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			p := path + "." + k.Value
+			ft, ok := fields[k.Value]
+			if !ok {
+				if c := closest(k.Value, fields); c != "" {
+					s.errorf(k, p, "unknown key %q, did you mean %q?", k.Value, c)
+				} else {
+					s.errorf(k, p, "unknown key %q", k.Value)
+				}
+				continue
+			}
+			s.check(v, p, ft)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			s.errorf(n, path, "expected an object")
+			return
+		}
+		for i := 0; i < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			s.check(v, fmt.Sprintf("%s.[%s]", path, k.Value), t.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		if n.Kind != yaml.SequenceNode {
+			s.errorf(n, path, "expected a list")
+			return
+		}
+		for i, v := range n.Content {
+			s.check(v, fmt.Sprintf("%s.[%d]", path, i), t.Elem())
+		}
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!str" {
+			s.errorf(n, path, "expected a string")
+		}
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+			s.errorf(n, path, "expected a boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!int" {
+			s.errorf(n, path, "expected an integer")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!int" || strings.HasPrefix(n.Value, "-") {
+			s.errorf(n, path, "expected a non-negative integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if n.Kind != yaml.ScalarNode || (n.Tag != "!!int" && n.Tag != "!!float") {
+			s.errorf(n, path, "expected a number")
+		}
+	}
+}
+
+// StructFields returns the types of the fields of "t", keyed by the names
+// they're decoded from.
+func structFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		n := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if i := strings.IndexByte(tag, ','); i != -1 {
+				tag = tag[:i]
+			}
+			if tag != "" {
+				n = tag
+			}
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range structFields(f.Type) {
+				out[k] = v
+			}
+			continue
+		}
+		out[n] = f.Type
+	}
+	return out
+}
+
+// Closest returns the key in "fields" most similar to "k", if any is close
+// enough to be a likely typo.
+func closest(k string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for n := range fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	best, dist := "", 3
+	for _, n := range names {
+		if d := editDistance(k, n); d < dist {
+			best, dist = n, d
+		}
+	}
+	return best
+}
+
+// EditDistance returns the Damerau-Levenshtein distance between "a" and "b",
+// counting a transposition of adjacent characters as one edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
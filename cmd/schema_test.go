@@ -0,0 +1,57 @@
+package cmd_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/clair/v4/cmd"
+)
+
+func TestCheckSchema(t *testing.T) {
+	ms, err := filepath.Glob(`testdata/*/config.*[^d]`)
+	if err != nil {
+		panic("programmer error")
+	}
+	for _, m := range ms {
+		name := filepath.Base(filepath.Dir(m))
+		t.Run(name, func(t *testing.T) {
+			errs, err := cmd.CheckSchema(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range errs {
+				t.Error(e)
+			}
+		})
+	}
+
+	t.Run("Typos", func(t *testing.T) {
+		const (
+			file   = `testdata/Schema/typos.yaml`
+			dropin = `testdata/Schema/typos.yaml.d/dropin.yaml`
+		)
+		errs, err := cmd.CheckSchema(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(errs))
+		for i, e := range errs {
+			got[i] = e.Error()
+		}
+		want := []string{
+			file + `:17:11: unknown mode "bogus" (at $.profiles.dev.mode)`,
+			file + `:18:5: unknown key "log_lvl", did you mean "log_level"? (at $.profiles.dev.log_lvl)`,
+			file + `:5:19: expected an integer (at $.indexer.scanlock_retry)`,
+			file + `:8:7: unknown key "concurency", did you mean "concurrency"? (at $.indexer.layer_fetch_hosts.[0].concurency)`,
+			file + `:10:3: unknown key "peroid", did you mean "period"? (at $.matcher.peroid)`,
+			file + `:12:15: expected a boolean (at $.matcher.migrations)`,
+			file + `:14:18: expected a string (at $.notifier.poll_interval)`,
+			dropin + `:2:3: unknown key "nmae", did you mean "name"? (at $.trace.nmae)`,
+		}
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+}
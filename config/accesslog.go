@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// AccessLog configures logging of requests to the HTTP API.
+//
+// A request is logged if it's sampled, slower than "SlowThreshold", or
+// responded to with a status of at least "ErrorStatus".
+type AccessLog struct {
+	// The fraction of requests to log, between 0 and 1.
+	Sample float64 `yaml:"sample,omitempty" json:"sample,omitempty"`
+	// Requests taking at least this long are always logged. If 0, requests
+	// are not logged based on their duration.
+	SlowThreshold Duration `yaml:"slow_threshold,omitempty" json:"slow_threshold,omitempty"`
+	// Responses with at least this status code are always logged. If 0, the
+	// default of 500 is used.
+	ErrorStatus int `yaml:"error_status,omitempty" json:"error_status,omitempty"`
+}
+
+func (a *AccessLog) validate(_ Mode) ([]Warning, error) {
+	if a.Sample < 0 || a.Sample > 1 {
+		return nil, errors.New("sample must be between 0 and 1")
+	}
+	if a.ErrorStatus == 0 {
+		a.ErrorStatus = DefaultAccessLogErrorStatus
+	}
+	return a.lint()
+}
+
+func (a *AccessLog) lint() (ws []Warning, err error) {
+	if a.SlowThreshold < 0 {
+		ws = append(ws, Warning{
+			path: ".slow_threshold",
+			msg:  "negative durations disable slow request logging",
+		})
+	}
+	if t := time.Duration(a.SlowThreshold); t > 0 && t < 10*time.Millisecond {
+		ws = append(ws, Warning{
+			path: ".slow_threshold",
+			msg:  "very small thresholds will log most requests",
+		})
+	}
+	return ws, nil
+}
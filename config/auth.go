@@ -41,11 +41,24 @@ func (b *Base64) UnmarshalText(in []byte) error {
 type Auth struct {
 	PSK       *AuthPSK       `yaml:"psk,omitempty" json:"psk,omitempty"`
 	Keyserver *AuthKeyserver `yaml:"keyserver,omitempty" json:"keyserver,omitempty"`
+	// Plugins holds configuration blocks for authentication plugins compiled
+	// into the binary, keyed by plugin name. Each named plugin is enabled.
+	Plugins map[string]interface{} `yaml:"plugins,omitempty" json:"plugins,omitempty"`
 }
 
 // Any reports whether any sort of authentication is configured.
 func (a Auth) Any() bool {
-	return a.PSK != nil
+	return a.PSK != nil || len(a.Plugins) != 0
+}
+
+// Validate checks that services running in separate processes can
+// authenticate to each other: plugins only verify callers, so they need a PSK
+// to sign intraservice requests with.
+func (a *Auth) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && len(a.Plugins) != 0 && a.PSK == nil {
+		return nil, fmt.Errorf(`"psk" is required with "plugins" in %v mode, to authenticate requests between services`, mode)
+	}
+	return nil, nil
 }
 
 func (a *Auth) lint() ([]Warning, error) {
@@ -78,6 +91,12 @@ func (a *AuthKeyserver) lint() ([]Warning, error) {
 type AuthPSK struct {
 	Key    Base64   `yaml:"key" json:"key"`
 	Issuer []string `yaml:"iss" json:"iss"`
+	// ScopedIntraservice restricts the tokens Clair services mint for
+	// requests to each other to the service they're meant for and the
+	// requests that service needs to make.
+	//
+	// Services always mint scoped tokens; this enables enforcing the scopes.
+	ScopedIntraservice bool `yaml:"scoped_intraservice,omitempty" json:"scoped_intraservice,omitempty"`
 }
 
 func (a *AuthPSK) validate(_ Mode) ([]Warning, error) {
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Azure configures the notifier to send notifications to an Azure Service Bus
+// queue or topic, or to an Azure Event Hub.
+//
+// Every notification is sent as its own message. Messages are sent in
+// batches of consecutive notifications sharing a session ID and partition
+// key.
+type Azure struct {
+	// The fully-qualified namespace, such as
+	// "example.servicebus.windows.net".
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// The queue, topic, or event hub messages are sent to.
+	Entity string `yaml:"entity" json:"entity"`
+	// Whether the entity is an event hub rather than a Service Bus queue or
+	// topic.
+	EventHub bool `yaml:"event_hub,omitempty" json:"event_hub,omitempty"`
+	// Authenticate using a managed identity.
+	//
+	// Exactly one of "managed_identity" and "shared_access_key" must be
+	// provided.
+	ManagedIdentity *AzureManagedIdentity `yaml:"managed_identity,omitempty" json:"managed_identity,omitempty"`
+	// Authenticate using a shared access policy.
+	SharedAccessKey *AzureSharedAccessKey `yaml:"shared_access_key,omitempty" json:"shared_access_key,omitempty"`
+	// A Go text/template for the session ID of a message, executed with the
+	// notification. Required if the queue or subscription has sessions
+	// enabled. Not allowed for event hubs.
+	SessionID string `yaml:"session_id,omitempty" json:"session_id,omitempty"`
+	// A Go text/template for the partition key of a message, executed with
+	// the notification.
+	//
+	// If empty, the session ID is used if there is one, and otherwise the
+	// manifest digest, so notifications about a manifest stay in order.
+	PartitionKey string `yaml:"partition_key,omitempty" json:"partition_key,omitempty"`
+	// The maximum number of messages sent in a single request.
+	// If 0, the default of 100 is used.
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`
+}
+
+// AzureManagedIdentity configures authenticating with a managed identity.
+type AzureManagedIdentity struct {
+	// The client ID of a user-assigned identity. If empty, the
+	// system-assigned identity is used.
+	ClientID string `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+}
+
+// AzureSharedAccessKey configures authenticating with a shared access policy.
+type AzureSharedAccessKey struct {
+	// The name of the policy.
+	Name string `yaml:"name" json:"name"`
+	// The primary or secondary key of the policy.
+	Key string `yaml:"key" json:"key"`
+}
+
+func (a *Azure) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	switch {
+	case a.Namespace == "":
+		return nil, errors.New("namespace is required")
+	case strings.Contains(a.Namespace, "/"):
+		return nil, fmt.Errorf("namespace %q must be a hostname", a.Namespace)
+	case a.Entity == "":
+		return nil, errors.New("entity is required")
+	case a.ManagedIdentity == nil && a.SharedAccessKey == nil:
+		return nil, errors.New("one of managed_identity or shared_access_key is required")
+	case a.ManagedIdentity != nil && a.SharedAccessKey != nil:
+		return nil, errors.New("only one of managed_identity or shared_access_key may be provided")
+	case a.EventHub && a.SessionID != "":
+		return nil, errors.New("session_id is not supported for event hubs")
+	case a.BatchSize < 0:
+		return nil, errors.New("batch_size must not be negative")
+	}
+	switch {
+	case a.PartitionKey != "":
+	case a.SessionID != "":
+		a.PartitionKey = a.SessionID
+	default:
+		a.PartitionKey = DefaultAzurePartitionKey
+	}
+	if a.BatchSize == 0 {
+		a.BatchSize = DefaultAzureBatchSize
+	}
+	for _, t := range []struct {
+		name, text string
+	}{
+		{"session_id", a.SessionID},
+		{"partition_key", a.PartitionKey},
+	} {
+		if _, err := template.New(t.name).Parse(t.text); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", t.name, err)
+		}
+	}
+	return a.lint()
+}
+
+func (a *Azure) lint() (ws []Warning, err error) {
+	if a.SessionID != "" && a.PartitionKey != "" && a.PartitionKey != a.SessionID {
+		ws = append(ws, Warning{
+			path: ".partition_key",
+			msg:  "Service Bus rejects messages whose partition key differs from their session ID",
+		})
+	}
+	return ws, nil
+}
+
+func (s *AzureSharedAccessKey) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	switch {
+	case s.Name == "":
+		return nil, errors.New("name is required")
+	case s.Key == "":
+		return nil, errors.New("key is required")
+	}
+	return nil, nil
+}
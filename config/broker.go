@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Broker overrides connection settings for a single message broker.
+type Broker struct {
+	// URI is the broker these settings apply to. It must exactly match an
+	// entry in the containing configuration's "uris" list.
+	URI string `yaml:"uri" json:"uri"`
+	// TLS configures the TLS connection to this broker. If not provided, the
+	// containing configuration's TLS configuration is used.
+	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// DialTimeout bounds how long establishing a network connection to the
+	// broker may take.
+	//
+	// A time.ParseDuration parsable string. If zero,
+	// DefaultBrokerDialTimeout is used.
+	DialTimeout Duration `yaml:"dial_timeout,omitempty" json:"dial_timeout,omitempty"`
+	// HandshakeTimeout bounds how long the TLS and protocol handshakes may
+	// take once a network connection is established.
+	//
+	// A time.ParseDuration parsable string. If zero,
+	// DefaultBrokerHandshakeTimeout is used.
+	HandshakeTimeout Duration `yaml:"handshake_timeout,omitempty" json:"handshake_timeout,omitempty"`
+}
+
+func (b *Broker) validate(_ Mode) ([]Warning, error) {
+	if b.URI == "" {
+		return nil, errors.New("broker settings missing URI")
+	}
+	var ws []Warning
+	for _, t := range []struct {
+		d    *Duration
+		def  time.Duration
+		path string
+	}{
+		{&b.DialTimeout, DefaultBrokerDialTimeout, ".dial_timeout"},
+		{&b.HandshakeTimeout, DefaultBrokerHandshakeTimeout, ".handshake_timeout"},
+	} {
+		if *t.d < 0 {
+			ws = append(ws, Warning{
+				path: t.path,
+				msg:  "negative values are treated as the default",
+			})
+		}
+		if *t.d <= 0 {
+			*t.d = Duration(t.def)
+		}
+	}
+	return ws, nil
+}
+
+// CheckBrokers reports an error if "bs" names a broker not in "uris", or names
+// a broker more than once.
+func checkBrokers(uris []string, bs []Broker) error {
+	known := make(map[string]bool, len(uris))
+	for _, u := range uris {
+		known[u] = false
+	}
+	for _, b := range bs {
+		seen, ok := known[b.URI]
+		switch {
+		case !ok:
+			return fmt.Errorf("broker settings for unknown URI %q", b.URI)
+		case seen:
+			return fmt.Errorf("duplicate broker settings for URI %q", b.URI)
+		}
+		known[b.URI] = true
+	}
+	return nil
+}
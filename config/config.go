@@ -2,7 +2,6 @@
 
 import (
 	"fmt"
-	"net"
 	"time"
 )
 
@@ -21,25 +20,78 @@ type Config struct {
 	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
 	// Sets which mode the clair instance will run.
 	Mode Mode `yaml:"-" json:"-"`
-	// A string in <host>:<port> format where <host> can be an empty string.
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
 	//
 	// exposes Clair node's functionality to the network.
 	// see /openapi/v1 for api spec.
 	HTTPListenAddr string `yaml:"http_listen_addr" json:"http_listen_addr"`
-	// A string in <host>:<port> format where <host> can be an empty string.
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
 	//
 	// exposes Clair's metrics and health endpoints.
 	IntrospectionAddr string `yaml:"introspection_addr" json:"introspection_addr"`
+	// Configures TLS and authentication for the introspection server. If
+	// unset, the introspection server is served over plaintext without
+	// authentication.
+	Introspection *Introspection `yaml:"introspection,omitempty" json:"introspection,omitempty"`
+	// Configures separate listeners for individual services in combo mode.
+	// If unset, all services are served on the "http_listen_addr".
+	Listeners *Listeners `yaml:"listeners,omitempty" json:"listeners,omitempty"`
+	// Configures the permissions of Unix domain sockets Clair listens on. If
+	// unset, sockets are created with the default permissions.
+	UnixSocket *UnixSocket `yaml:"unix_socket,omitempty" json:"unix_socket,omitempty"`
 	// Set the logging level.
 	LogLevel LogLevel `yaml:"log_level" json:"log_level"`
-	Indexer  Indexer  `yaml:"indexer,omitempty" json:"indexer,omitempty"`
-	Matcher  Matcher  `yaml:"matcher,omitempty" json:"matcher,omitempty"`
-	Matchers Matchers `yaml:"matchers,omitempty" json:"matchers,omitempty"`
-	Updaters Updaters `yaml:"updaters,omitempty" json:"updaters,omitempty"`
-	Notifier Notifier `yaml:"notifier,omitempty" json:"notifier,omitempty"`
-	Auth     Auth     `yaml:"auth,omitempty" json:"auth,omitempty"`
-	Trace    Trace    `yaml:"trace,omitempty" json:"trace,omitempty"`
-	Metrics  Metrics  `yaml:"metrics,omitempty" json:"metrics,omitempty"`
+	// Configures where logs are written. If unset, logs are written to
+	// stderr.
+	LogOutput *LogOutput `yaml:"log_output,omitempty" json:"log_output,omitempty"`
+	// Configures logging of HTTP API requests. If unset, requests are not
+	// logged.
+	AccessLog *AccessLog `yaml:"access_log,omitempty" json:"access_log,omitempty"`
+	// Configures sending audit events for HTTP API requests to a syslog
+	// collector. If unset, requests are not audited.
+	AuditLog *AuditLog `yaml:"audit_log,omitempty" json:"audit_log,omitempty"`
+	Indexer  Indexer   `yaml:"indexer,omitempty" json:"indexer,omitempty"`
+	Matcher  Matcher   `yaml:"matcher,omitempty" json:"matcher,omitempty"`
+	Matchers Matchers  `yaml:"matchers,omitempty" json:"matchers,omitempty"`
+	Updaters Updaters  `yaml:"updaters,omitempty" json:"updaters,omitempty"`
+	Notifier Notifier  `yaml:"notifier,omitempty" json:"notifier,omitempty"`
+	Auth     Auth      `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Trace    Trace     `yaml:"trace,omitempty" json:"trace,omitempty"`
+	Metrics  Metrics   `yaml:"metrics,omitempty" json:"metrics,omitempty"`
+	// Configures automatic profile capture under resource pressure. If
+	// unset, profiles are only available from the introspection server.
+	ProfileWatchdog *ProfileWatchdog `yaml:"profile_watchdog,omitempty" json:"profile_watchdog,omitempty"`
+	// Configures recording of database query latencies. If unset, queries
+	// are not instrumented.
+	QueryStats *QueryStats `yaml:"query_stats,omitempty" json:"query_stats,omitempty"`
+	// Configures a shared cache for indexer lookups. If unset, lookups
+	// always go to the indexer.
+	Cache *Cache `yaml:"cache,omitempty" json:"cache,omitempty"`
+	// Configures proxies for outbound HTTP requests. If unset, the standard
+	// proxy environment variables are used.
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Configures trust stores and TLS restrictions for outgoing
+	// connections, per subsystem. If unset, the system trust store is used.
+	OutboundTLS *OutboundTLS `yaml:"outbound_tls,omitempty" json:"outbound_tls,omitempty"`
+	// Configures connection reuse and HTTP/2 for outgoing HTTP requests, per
+	// subsystem. If unset, Go's defaults are used.
+	ClientTransport *ClientTransport `yaml:"client_transport,omitempty" json:"client_transport,omitempty"`
+	// Configures signing of served reports. If unset, reports can't be
+	// requested signed.
+	ReportSigning *ReportSigning `yaml:"report_signing,omitempty" json:"report_signing,omitempty"`
+	// Configures removing fields from served reports depending on the
+	// caller. If unset, reports are served in full.
+	Redaction *Redaction `yaml:"redaction,omitempty" json:"redaction,omitempty"`
+	// Configures how long HTTP API requests may run, per endpoint. If unset,
+	// requests run until they finish or the client goes away.
+	RequestTimeouts *RequestTimeouts `yaml:"request_timeouts,omitempty" json:"request_timeouts,omitempty"`
+	// Runs a combo process as a warm standby, which holds its background work
+	// until it's promoted.
+	Standby *Standby `yaml:"standby,omitempty" json:"standby,omitempty"`
 }
 
 func (c *Config) validate(mode Mode) ([]Warning, error) {
@@ -55,9 +107,14 @@ func (c *Config) validate(mode Mode) ([]Warning, error) {
 	default:
 		return nil, fmt.Errorf("unknown mode: %q", mode)
 	}
-	if _, _, err := net.SplitHostPort(c.HTTPListenAddr); err != nil {
+	if err := checkListenAddr(c.HTTPListenAddr); err != nil {
 		return nil, err
 	}
+	if c.IntrospectionAddr != "" {
+		if err := checkListenAddr(c.IntrospectionAddr); err != nil {
+			return nil, fmt.Errorf("introspection address: %w", err)
+		}
+	}
 	return c.lint()
 }
 
@@ -3,6 +3,7 @@
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/quay/clair/config"
@@ -74,6 +75,101 @@ func TestValidateFailure(t *testing.T) {
 				},
 				Check: shouldFail,
 			},
+			{
+				Name: "FreshnessStaleAfter",
+				Conf: config.Config{
+					Mode:           config.MatcherMode,
+					HTTPListenAddr: "localhost:8080",
+					Matcher: config.Matcher{
+						IndexerAddr: "http://example.com/",
+						Freshness:   &config.MatcherFreshness{Readiness: true},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "TrendsRetention",
+				Conf: config.Config{
+					Mode:           config.MatcherMode,
+					HTTPListenAddr: "localhost:8080",
+					Matcher: config.Matcher{
+						IndexerAddr: "http://example.com/",
+						Trends:      &config.MatcherTrends{Retention: -1},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "ChangelogRetention",
+				Conf: config.Config{
+					Mode:           config.MatcherMode,
+					HTTPListenAddr: "localhost:8080",
+					Matcher: config.Matcher{
+						IndexerAddr: "http://example.com/",
+						Changelog:   &config.MatcherChangelog{Retention: -1},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "SubscriptionsAllowedHosts",
+				Conf: config.Config{
+					Mode:           config.MatcherMode,
+					HTTPListenAddr: "localhost:8080",
+					Matcher: config.Matcher{
+						IndexerAddr:   "http://example.com/",
+						Subscriptions: &config.MatcherSubscriptions{AllowedHosts: []string{"*.*"}},
+					},
+				},
+				Check: shouldFail,
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.Name, tc.Run)
+		}
+	})
+
+	t.Run("OutboundTLS", func(t *testing.T) {
+		tt := []ValidateTestcase{
+			{
+				Name: "MinVersion",
+				Conf: config.Config{
+					Mode: config.MatcherMode,
+					Matcher: config.Matcher{
+						IndexerAddr: "http://example.com/",
+					},
+					OutboundTLS: &config.OutboundTLS{
+						Updaters: &config.TLSPolicy{MinVersion: "1.4"},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "CipherSuite",
+				Conf: config.Config{
+					Mode: config.MatcherMode,
+					Matcher: config.Matcher{
+						IndexerAddr: "http://example.com/",
+					},
+					OutboundTLS: &config.OutboundTLS{
+						Updaters: &config.TLSPolicy{CipherSuites: []string{"TLS_NOT_A_SUITE"}},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "NoRoots",
+				Conf: config.Config{
+					Mode: config.MatcherMode,
+					Matcher: config.Matcher{
+						IndexerAddr: "http://example.com/",
+					},
+					OutboundTLS: &config.OutboundTLS{
+						Database: &config.TLSPolicy{ExcludeSystemRoots: true},
+					},
+				},
+				Check: shouldFail,
+			},
 		}
 		for _, tc := range tt {
 			t.Run(tc.Name, tc.Run)
@@ -110,6 +206,48 @@ func TestValidateFailure(t *testing.T) {
 		}
 	})
 
+	t.Run("Introspection", func(t *testing.T) {
+		tt := []ValidateTestcase{
+			{
+				Name: "BadPSKKey",
+				Conf: config.Config{
+					Mode: config.IndexerMode,
+					Introspection: &config.Introspection{
+						PSK: &config.AuthPSK{},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "TLSWithoutCert",
+				Conf: config.Config{
+					Mode: config.IndexerMode,
+					Introspection: &config.Introspection{
+						TLS: &config.TLS{},
+					},
+				},
+				Check: shouldFail,
+			},
+			{
+				Name: "RelativePath",
+				Conf: config.Config{
+					Mode: config.IndexerMode,
+					Introspection: &config.Introspection{
+						PSK: &config.AuthPSK{
+							Key:    config.Base64([]byte{0xde, 0xad, 0xbe, 0xef}),
+							Issuer: []string{"prometheus"},
+						},
+						Unauthenticated: []string{"healthz"},
+					},
+				},
+				Check: shouldFail,
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.Name, tc.Run)
+		}
+	})
+
 	t.Run("Notifier", func(t *testing.T) {
 		tt := []ValidateTestcase{
 			{
@@ -159,6 +297,54 @@ func TestValidateFailure(t *testing.T) {
 					},
 					Check: shouldFail,
 				},
+				{
+					Name: "RetryAttempts",
+					Conf: config.Config{
+						Mode: config.NotifierMode,
+						Notifier: config.Notifier{
+							IndexerAddr: "http://example.com/",
+							MatcherAddr: "http://example.com/",
+							Webhook: &config.Webhook{
+								Target:   "http://example.com/",
+								Callback: "http://example.com/",
+								Retry:    &config.WebhookRetry{MaxAttempts: -1},
+							},
+						},
+					},
+					Check: shouldFail,
+				},
+				{
+					Name: "RetryStatusCodes",
+					Conf: config.Config{
+						Mode: config.NotifierMode,
+						Notifier: config.Notifier{
+							IndexerAddr: "http://example.com/",
+							MatcherAddr: "http://example.com/",
+							Webhook: &config.Webhook{
+								Target:   "http://example.com/",
+								Callback: "http://example.com/",
+								Retry:    &config.WebhookRetry{StatusCodes: []int{200}},
+							},
+						},
+					},
+					Check: shouldFail,
+				},
+				{
+					Name: "AllowedNetworks",
+					Conf: config.Config{
+						Mode: config.NotifierMode,
+						Notifier: config.Notifier{
+							IndexerAddr: "http://example.com/",
+							MatcherAddr: "http://example.com/",
+							Webhook: &config.Webhook{
+								Target:          "http://example.com/",
+								Callback:        "http://example.com/",
+								AllowedNetworks: []string{"example.com"},
+							},
+						},
+					},
+					Check: shouldFail,
+				},
 			}
 			for _, tc := range tt {
 				t.Run(tc.Name, tc.Run)
@@ -273,6 +459,38 @@ func TestValidateFailure(t *testing.T) {
 					},
 					Check: shouldFail,
 				},
+				{
+					Name: "Failover",
+					Conf: config.Config{
+						Mode: config.NotifierMode,
+						Notifier: config.Notifier{
+							IndexerAddr: "http://example.com/",
+							MatcherAddr: "http://example.com/",
+							STOMP: &config.STOMP{
+								URIs:     []string{"stomp:567"},
+								Callback: "http://example.com/",
+								Failover: "least-loaded",
+							},
+						},
+					},
+					Check: shouldFail,
+				},
+				{
+					Name: "UnknownBroker",
+					Conf: config.Config{
+						Mode: config.NotifierMode,
+						Notifier: config.Notifier{
+							IndexerAddr: "http://example.com/",
+							MatcherAddr: "http://example.com/",
+							STOMP: &config.STOMP{
+								URIs:     []string{"stomp:567"},
+								Callback: "http://example.com/",
+								Brokers:  []config.Broker{{URI: "other:567"}},
+							},
+						},
+					},
+					Check: shouldFail,
+				},
 			}
 			for _, tc := range tt {
 				t.Run(tc.Name, tc.Run)
@@ -425,3 +643,1349 @@ func TestDisableUpdaters(t *testing.T) {
 		t.Run(tc.Name, tc.Run)
 	}
 }
+
+func TestNotifierThrottle(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			if got, want := c.Notifier.Throttle.ChunkSize, config.DefaultNotifierAffectedChunk; got != want {
+				t.Errorf("chunk size: got: %d, want: %d", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.NotifierThrottle
+		OK   bool
+	}{
+		{Name: "Zero", OK: true},
+		{Name: "Windows", In: config.NotifierThrottle{Windows: []string{"22:00-06:00", "12:00 - 13:30"}, Timezone: "UTC"}, OK: true},
+		{Name: "NoSeparator", In: config.NotifierThrottle{Windows: []string{"22:00"}}},
+		{Name: "BadTime", In: config.NotifierThrottle{Windows: []string{"22:00-25:00"}}},
+		{Name: "Empty", In: config.NotifierThrottle{Windows: []string{"06:00-06:00"}}},
+		{Name: "Timezone", In: config.NotifierThrottle{Timezone: "Nowhere/Special"}},
+		{Name: "Negative", In: config.NotifierThrottle{Concurrency: -1}},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Throttle: c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestNotifierPages(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			if got, want := c.Notifier.Pages.CacheSize, config.DefaultNotifierPageCacheSize; got != want {
+				t.Errorf("cache size: got: %d, want: %d", got, want)
+			}
+			if c.Notifier.Pages.Workers < 1 {
+				t.Errorf("workers: got: %d", c.Notifier.Pages.Workers)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.NotifierPages
+		OK   bool
+	}{
+		{Name: "Zero", OK: true},
+		{Name: "Hot", In: config.NotifierPages{Workers: 1, HotThreshold: 3}, OK: true},
+		{Name: "NegativeWorkers", In: config.NotifierPages{Workers: -1}},
+		{Name: "NegativeTTL", In: config.NotifierPages{TTL: -1}},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Pages: c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestNotifierRetry(t *testing.T) {
+	check := func(backoff time.Duration) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &c.Notifier.Retry
+			if got, want := time.Duration(r.Backoff), backoff; got != want {
+				t.Errorf("backoff: got: %v, want: %v", got, want)
+			}
+			if got, want := time.Duration(r.MaxBackoff), config.DefaultNotifierRetryMaxBackoff; got != want {
+				t.Errorf("max backoff: got: %v, want: %v", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.NotifierRetry
+		Want time.Duration
+	}{
+		{Name: "Zero", Want: config.DefaultNotifierRetryBackoff},
+		{Name: "Set", In: config.NotifierRetry{Backoff: config.Duration(time.Minute)}, Want: time.Minute},
+		{Name: "Disabled", In: config.NotifierRetry{Backoff: -1}, Want: -1},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Retry: c.In,
+				},
+			},
+			Check: check(c.Want),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestIndexerWebhook(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			if got, want := c.Indexer.Webhook.QueueSize, config.DefaultIndexerWebhookQueueSize; got != want {
+				t.Errorf("queue size: got: %d, want: %d", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.IndexerWebhook
+		OK   bool
+	}{
+		{Name: "Addr", In: config.IndexerWebhook{ListenAddr: ":6070"}, OK: true},
+		{Name: "Unix", In: config.IndexerWebhook{ListenAddr: "unix:/run/clair/webhook.sock"}, OK: true},
+		{Name: "NoAddr", In: config.IndexerWebhook{}},
+		{Name: "BadAddr", In: config.IndexerWebhook{ListenAddr: "6070"}},
+		{Name: "Negative", In: config.IndexerWebhook{ListenAddr: ":6070", Concurrency: -1}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					Webhook: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestIndexerSecrets(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			if got, want := c.Indexer.Secrets.MaxFileSize, int64(config.DefaultIndexerSecretsMaxFileSize); got != want {
+				t.Errorf("max file size: got: %d, want: %d", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.IndexerSecrets
+		OK   bool
+	}{
+		{Name: "Default", In: config.IndexerSecrets{}, OK: true},
+		{Name: "Ignore", In: config.IndexerSecrets{IgnorePaths: []string{"usr/share/doc/*", "etc/ssl/private/*.pem"}}, OK: true},
+		{Name: "BadPattern", In: config.IndexerSecrets{IgnorePaths: []string{"etc/["}}},
+		{Name: "Negative", In: config.IndexerSecrets{MaxFileSize: -1}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					Secrets: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestLicensePolicy(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.LicensePolicy
+		OK   bool
+	}{
+		{Name: "Deny", In: config.LicensePolicy{Deny: []string{"AGPL-3.0-only", "SSPL-1.0"}}, OK: true},
+		{Name: "Allow", In: config.LicensePolicy{Allow: []string{"MIT", "Apache-2.0"}}, OK: true},
+		{Name: "Empty", In: config.LicensePolicy{}, OK: true},
+		{Name: "Blank", In: config.LicensePolicy{Deny: []string{""}}},
+		{Name: "Expression", In: config.LicensePolicy{Allow: []string{"MIT OR Apache-2.0"}}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					Licenses: &config.IndexerLicenses{Policy: &c.In},
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestUpdaterFeeds(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   []config.UpdaterFeed
+		OK   bool
+	}{
+		{
+			Name: "OVAL",
+			In:   []config.UpdaterFeed{{Name: "internal", URL: "https://example.com/oval.xml.bz2", Format: "oval", Namespace: "rhel:9", VersionScheme: "rpm"}},
+			OK:   true,
+		},
+		{
+			Name: "CSAF",
+			In:   []config.UpdaterFeed{{Name: "internal", URL: "https://example.com/csaf.json", Format: "CSAF", Namespace: "repo:pypi", VersionScheme: "pep440"}},
+			OK:   true,
+		},
+		{
+			Name: "OVALScheme",
+			In:   []config.UpdaterFeed{{Name: "internal", URL: "https://example.com/oval.xml", Format: "oval", Namespace: "alpine", VersionScheme: "apk"}},
+		},
+		{
+			Name: "Format",
+			In:   []config.UpdaterFeed{{Name: "internal", URL: "https://example.com/osv.json", Format: "osv", Namespace: "alpine", VersionScheme: "apk"}},
+		},
+		{
+			Name: "Namespace",
+			In:   []config.UpdaterFeed{{Name: "internal", URL: "https://example.com/csaf.json", Format: "csaf", Namespace: "repo:", VersionScheme: "semver"}},
+		},
+		{
+			Name: "URL",
+			In:   []config.UpdaterFeed{{Name: "internal", URL: "file:///tmp/csaf.json", Format: "csaf", Namespace: "debian:12", VersionScheme: "dpkg"}},
+		},
+		{
+			Name: "Duplicate",
+			In: []config.UpdaterFeed{
+				{Name: "internal", URL: "https://example.com/a.json", Format: "csaf", Namespace: "debian:12", VersionScheme: "dpkg"},
+				{Name: "internal", URL: "https://example.com/b.json", Format: "csaf", Namespace: "debian:12", VersionScheme: "dpkg"},
+			},
+		},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Updaters: config.Updaters{
+					Feeds: c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestUpdaterRuntimeEOL(t *testing.T) {
+	check := func(ok bool, want string) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Fatalf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			if got := c.Updaters.RuntimeEOL.URL; got != want {
+				t.Errorf("url: got: %q, want: %q", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.UpdaterRuntimeEOL
+		OK   bool
+		Want string
+	}{
+		{Name: "Default", OK: true, Want: config.DefaultRuntimeEOLURL},
+		{Name: "Mirror", In: config.UpdaterRuntimeEOL{URL: "https://mirror.example.com/eol"}, OK: true, Want: "https://mirror.example.com/eol/"},
+		{Name: "URL", In: config.UpdaterRuntimeEOL{URL: "file:///srv/eol/"}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Updaters: config.Updaters{
+					RuntimeEOL: &c.In,
+				},
+			},
+			Check: check(c.OK, c.Want),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestUpdaterBudgets(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   []config.UpdaterBudget
+		OK   bool
+	}{
+		{
+			Name: "Budgets",
+			In: []config.UpdaterBudget{
+				{Updater: "nvd", Time: config.Duration(10 * time.Minute), Bytes: 1 << 30},
+				{Time: config.Duration(5 * time.Minute)},
+			},
+			OK: true,
+		},
+		{
+			Name: "NegativeTime",
+			In:   []config.UpdaterBudget{{Updater: "nvd", Time: config.Duration(-time.Minute)}},
+		},
+		{
+			Name: "NegativeBytes",
+			In:   []config.UpdaterBudget{{Updater: "nvd", Bytes: -1}},
+		},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Updaters: config.Updaters{
+					Budgets: c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestScannerSelection(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.ScannerConfig
+		OK   bool
+	}{
+		{Name: "None", In: config.ScannerConfig{}, OK: true},
+		{Name: "Enable", In: config.ScannerConfig{Enable: []string{"os"}}, OK: true},
+		{Name: "Disable", In: config.ScannerConfig{Disable: []string{"python", "java"}}, OK: true},
+		{Name: "Both", In: config.ScannerConfig{Enable: []string{"os"}, Disable: []string{"python"}}},
+		{Name: "Empty", In: config.ScannerConfig{Disable: []string{""}}},
+		{Name: "ExcludeVendored", In: config.ScannerConfig{Vendored: config.ScannerExclude}, OK: true},
+		{Name: "IncludeCaches", In: config.ScannerConfig{Caches: config.ScannerInclude}, OK: true},
+		{Name: "UnknownCaches", In: config.ScannerConfig{Caches: "drop"}},
+		{Name: "Lockfiles", In: config.ScannerConfig{Lockfiles: []string{config.LockfileComposer, config.LockfileSwift, config.LockfileCocoaPods, config.LockfileConan}}, OK: true},
+		{Name: "UnknownLockfiles", In: config.ScannerConfig{Lockfiles: []string{"pip"}}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					Scanner: c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestLayerFetchRetries(t *testing.T) {
+	want := func(n int) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Indexer.LayerFetchRetries; got != n {
+				t.Errorf("got: %d, want: %d", got, n)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   int
+		Want int
+	}{
+		{Name: "Default", In: 0, Want: config.DefaultLayerFetchRetries},
+		{Name: "Set", In: 5, Want: 5},
+		{Name: "Disabled", In: -1, Want: -1},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					LayerFetchRetries: c.In,
+				},
+			},
+			Check: want(c.Want),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestLayerFetchHosts(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   []config.LayerFetchHost
+		OK   bool
+	}{
+		{Name: "None", OK: true},
+		{
+			Name: "Hosts",
+			In: []config.LayerFetchHost{
+				{Host: "quay.io", Concurrency: 4, Bandwidth: 64 * 1024 * 1024},
+				{Host: "*", Concurrency: 2},
+			},
+			OK: true,
+		},
+		{Name: "MissingHost", In: []config.LayerFetchHost{{Concurrency: 1}}},
+		{Name: "URL", In: []config.LayerFetchHost{{Host: "https://quay.io/", Concurrency: 1}}},
+		{
+			Name: "Duplicate",
+			In: []config.LayerFetchHost{
+				{Host: "quay.io", Concurrency: 4},
+				{Host: "quay.io", Concurrency: 2},
+			},
+		},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					LayerFetchHosts: c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestIndexerResolve(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.RegistryCredential
+		OK   bool
+	}{
+		{Name: "Basic", In: config.RegistryCredential{Registry: "quay.io", Username: "robot", Password: "hunter2"}, OK: true},
+		{Name: "Token", In: config.RegistryCredential{Registry: "quay.io", Token: "t0ken"}, OK: true},
+		{Name: "MissingRegistry", In: config.RegistryCredential{Token: "t0ken"}},
+		{Name: "URL", In: config.RegistryCredential{Registry: "https://quay.io/", Token: "t0ken"}},
+		{Name: "Both", In: config.RegistryCredential{Registry: "quay.io", Username: "robot", Token: "t0ken"}},
+		{Name: "Neither", In: config.RegistryCredential{Registry: "quay.io", Password: "hunter2"}},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					Resolve: &config.IndexerResolve{
+						Registries:  []string{"quay.io"},
+						Credentials: map[string]config.RegistryCredential{"cred": c.In},
+					},
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	tt = append(tt, ValidateTestcase{
+		Name: "NoRegistries",
+		Conf: config.Config{
+			Mode:           config.ComboMode,
+			HTTPListenAddr: "localhost:8080",
+			Indexer: config.Indexer{
+				Resolve: &config.IndexerResolve{},
+			},
+		},
+		Check: check(false),
+	})
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestRequestTimeouts(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.RequestTimeouts
+		OK   bool
+	}{
+		{
+			Name: "Endpoints",
+			In: config.RequestTimeouts{
+				Default: config.Duration(time.Minute),
+				Endpoints: map[string]config.Duration{
+					"/matcher/api/v1/vulnerability_report/": config.Duration(30 * time.Second),
+					"/indexer/api/v1/index_report":          config.Duration(-1),
+				},
+			},
+			OK: true,
+		},
+		{
+			Name: "NegativeDefault",
+			In:   config.RequestTimeouts{Default: config.Duration(-time.Second)},
+		},
+		{
+			Name: "RelativePath",
+			In: config.RequestTimeouts{
+				Endpoints: map[string]config.Duration{"matcher/api/v1/": config.Duration(time.Minute)},
+			},
+		},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:            config.ComboMode,
+				HTTPListenAddr:  "localhost:8080",
+				RequestTimeouts: &c.In,
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestStandby(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	tt := []ValidateTestcase{
+		{
+			Name: "Defaults",
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Standby:        &config.Standby{},
+			},
+			Check: func(t *testing.T, c *config.Config, err error) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := c.Standby.Promotion, config.StandbyPromotionLock; got != want {
+					t.Errorf("promotion: got: %q, want: %q", got, want)
+				}
+				if got, want := time.Duration(c.Standby.PollInterval), config.DefaultStandbyPollInterval; got != want {
+					t.Errorf("poll interval: got: %v, want: %v", got, want)
+				}
+			},
+		},
+		{
+			Name: "API",
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Standby:        &config.Standby{Promotion: config.StandbyPromotionAPI},
+			},
+			Check: check(true),
+		},
+		{
+			Name: "UnknownPromotion",
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Standby:        &config.Standby{Promotion: "vote"},
+			},
+			Check: check(false),
+		},
+		{
+			Name: "NegativeInterval",
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Standby:        &config.Standby{PollInterval: config.Duration(-time.Second)},
+			},
+			Check: check(false),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   []config.RedactionRule
+		OK   bool
+	}{
+		{
+			Name: "Claim",
+			In:   []config.RedactionRule{{Name: "vendors", Claim: "roles", Values: []string{"vendor"}, Fields: []string{"file_paths", "repository_urls"}}},
+			OK:   true,
+		},
+		{
+			Name: "Everyone",
+			In:   []config.RedactionRule{{Name: "all", Fields: []string{"labels"}}},
+			OK:   true,
+		},
+		{
+			Name: "UnknownField",
+			In:   []config.RedactionRule{{Name: "vendors", Subjects: []string{"acme"}, Fields: []string{"vulnerabilities"}}},
+		},
+		{
+			Name: "ClaimWithoutValues",
+			In:   []config.RedactionRule{{Name: "vendors", Claim: "roles", Fields: []string{"labels"}}},
+		},
+		{
+			Name: "MissingName",
+			In:   []config.RedactionRule{{Fields: []string{"labels"}}},
+		},
+		{
+			Name: "Duplicate",
+			In: []config.RedactionRule{
+				{Name: "vendors", Subjects: []string{"acme"}, Fields: []string{"labels"}},
+				{Name: "vendors", Subjects: []string{"initech"}, Fields: []string{"secrets"}},
+			},
+		},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Redaction:      &config.Redaction{Rules: c.In},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	c := config.Config{
+		Mode:           config.ComboMode,
+		HTTPListenAddr: "localhost:8080",
+		Indexer: config.Indexer{
+			LayerFetchHosts: []config.LayerFetchHost{{Concurrency: 1}},
+		},
+		UnixSocket: &config.UnixSocket{Mode: "0990"},
+	}
+	_, errs := config.ValidateAll(&c)
+	if got, want := len(errs), 2; got != want {
+		t.Fatalf("got: %d errors, want: %d: %v", got, want, errs)
+	}
+	for _, err := range errs {
+		t.Log(err)
+	}
+	if _, err := config.Validate(&c); err == nil {
+		t.Error("Validate: unexpected success")
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		Addr string
+		In   *config.UnixSocket
+		OK   bool
+	}{
+		{Name: "TCP", Addr: "http://clair-indexer:6060/", OK: true},
+		{Name: "Socket", Addr: "unix:/run/clair/indexer.sock", OK: true},
+		{Name: "NoPath", Addr: "unix:"},
+		{Name: "Mode", Addr: "unix:/run/clair/indexer.sock", In: &config.UnixSocket{Mode: "0660", Group: "clair"}, OK: true},
+		{Name: "NotOctal", Addr: "unix:/run/clair/indexer.sock", In: &config.UnixSocket{Mode: "0990"}},
+		{Name: "TooLarge", Addr: "unix:/run/clair/indexer.sock", In: &config.UnixSocket{Mode: "4660"}},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.MatcherMode,
+				HTTPListenAddr: "unix:/run/clair/matcher.sock",
+				UnixSocket:     c.In,
+				Matcher: config.Matcher{
+					ConnString:  "postgres://localhost/",
+					IndexerAddr: c.Addr,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestClientTransport(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   *config.HTTPTransport
+		OK   bool
+	}{
+		{Name: "None", OK: true},
+		{
+			Name: "Tuned",
+			In: &config.HTTPTransport{
+				DisableHTTP2:        true,
+				MaxIdleConnsPerHost: 64,
+				IdleConnTimeout:     config.Duration(5 * time.Minute),
+			},
+			OK: true,
+		},
+		{Name: "NegativeConns", In: &config.HTTPTransport{MaxIdleConnsPerHost: -1}},
+		{Name: "NegativeTimeout", In: &config.HTTPTransport{IdleConnTimeout: config.Duration(-time.Second)}},
+	} {
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				ClientTransport: &config.ClientTransport{
+					Intraservice: c.In,
+					Notifier:     c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestRepoCPE(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.IndexerRepoCPE
+		OK   bool
+	}{
+		{Name: "Sources", In: config.IndexerRepoCPE{Sources: []string{"/etc/clair/repo2cpe.json"}}, OK: true},
+		{Name: "Overrides", In: config.IndexerRepoCPE{Overrides: map[string][]string{"internal-rpms": {"cpe:/o:example:linux:8"}}}, OK: true},
+		{Name: "None", In: config.IndexerRepoCPE{}},
+		{Name: "EmptySource", In: config.IndexerRepoCPE{Sources: []string{""}}},
+		{Name: "EmptyID", In: config.IndexerRepoCPE{Overrides: map[string][]string{"": nil}}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					RepoCPE: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestDependencyTrack(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			d := c.Notifier.DependencyTrack
+			if got, want := d.ProjectLabel, config.DefaultDependencyTrackProjectLabel; got != want {
+				t.Errorf("project label: got: %q, want: %q", got, want)
+			}
+			if got, want := d.VersionLabel, config.DefaultDependencyTrackVersionLabel; got != want {
+				t.Errorf("version label: got: %q, want: %q", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.DependencyTrack
+		OK   bool
+	}{
+		{Name: "OK", In: config.DependencyTrack{URL: "https://dtrack.example.com", APIKey: "key"}, OK: true},
+		{Name: "NoKey", In: config.DependencyTrack{URL: "https://dtrack.example.com"}},
+		{Name: "NoURL", In: config.DependencyTrack{APIKey: "key"}},
+		{Name: "RelativeURL", In: config.DependencyTrack{URL: "/api", APIKey: "key"}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					DependencyTrack: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestMQTT(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			m := c.Notifier.MQTT
+			if m.Topic == "" {
+				t.Error("topic: unset")
+			}
+			if got, want := m.DialTimeout, config.Duration(config.DefaultBrokerDialTimeout); got != want {
+				t.Errorf("dial timeout: got: %v, want: %v", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.MQTT
+		OK   bool
+	}{
+		{Name: "Plain", In: config.MQTT{Broker: "mqtt://broker.example.com"}, OK: true},
+		{Name: "TLS", In: config.MQTT{Broker: "mqtts://broker.example.com:8884", Username: "clair", Password: "secret"}, OK: true},
+		{Name: "Topic", In: config.MQTT{Broker: "tcp://broker:1883", Topic: "vulns/{{.Manifest}}"}, OK: true},
+		{Name: "NoBroker", In: config.MQTT{}},
+		{Name: "BadScheme", In: config.MQTT{Broker: "http://broker.example.com"}},
+		{Name: "BadTemplate", In: config.MQTT{Broker: "mqtt://broker", Topic: "{{.Manifest"}},
+		{Name: "PasswordOnly", In: config.MQTT{Broker: "mqtt://broker", Password: "secret"}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					MQTT: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestAzure(t *testing.T) {
+	sak := &config.AzureSharedAccessKey{Name: "send", Key: "secret"}
+	check := func(ok bool, key string) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			a := c.Notifier.Azure
+			if got, want := a.PartitionKey, key; got != want {
+				t.Errorf("partition key: got: %q, want: %q", got, want)
+			}
+			if got, want := a.BatchSize, config.DefaultAzureBatchSize; got != want {
+				t.Errorf("batch size: got: %d, want: %d", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.Azure
+		OK   bool
+		Key  string
+	}{
+		{Name: "SharedAccessKey", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair", SharedAccessKey: sak}, OK: true, Key: config.DefaultAzurePartitionKey},
+		{Name: "ManagedIdentity", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair", ManagedIdentity: &config.AzureManagedIdentity{}}, OK: true, Key: config.DefaultAzurePartitionKey},
+		{Name: "Session", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair", SharedAccessKey: sak, SessionID: "{{.Manifest}}"}, OK: true, Key: "{{.Manifest}}"},
+		{Name: "NoCredentials", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair"}},
+		{Name: "BothCredentials", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair", SharedAccessKey: sak, ManagedIdentity: &config.AzureManagedIdentity{}}},
+		{Name: "NoEntity", In: config.Azure{Namespace: "example.servicebus.windows.net", SharedAccessKey: sak}},
+		{Name: "URLNamespace", In: config.Azure{Namespace: "https://example.servicebus.windows.net/", Entity: "clair", SharedAccessKey: sak}},
+		{Name: "EventHubSession", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair", EventHub: true, SharedAccessKey: sak, SessionID: "x"}},
+		{Name: "NoKey", In: config.Azure{Namespace: "example.servicebus.windows.net", Entity: "clair", SharedAccessKey: &config.AzureSharedAccessKey{Name: "send"}}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Azure: &c.In,
+				},
+			},
+			Check: check(c.OK, c.Key),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			s := c.Notifier.Syslog
+			if s.Facility == "" || s.AppName == "" {
+				t.Errorf("defaults not set: %+v", s)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.Syslog
+		OK   bool
+	}{
+		{Name: "TLS", In: config.Syslog{Address: "tls://siem.example.com"}, OK: true},
+		{Name: "Mapping", In: config.Syslog{Address: "tcp://siem.example.com:601", Facility: "local3", Severities: map[string]string{"Critical": "alert"}}, OK: true},
+		{Name: "NoAddress", In: config.Syslog{}},
+		{Name: "BadScheme", In: config.Syslog{Address: "https://siem.example.com"}},
+		{Name: "BadFacility", In: config.Syslog{Address: "udp://siem", Facility: "local9"}},
+		{Name: "BadSeverity", In: config.Syslog{Address: "udp://siem", Severities: map[string]string{"High": "loud"}}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Syslog: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestSplunk(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			s := c.Notifier.Splunk
+			if s.Sourcetype == "" || s.Source == "" {
+				t.Errorf("defaults not set: %+v", s)
+			}
+			if got, want := s.BatchSize, config.DefaultSplunkBatchSize; got != want {
+				t.Errorf("batch size: got: %d, want: %d", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.Splunk
+		OK   bool
+	}{
+		{Name: "Defaults", In: config.Splunk{URL: "https://splunk.example.com:8088", Token: "token"}, OK: true},
+		{Name: "Templates", In: config.Splunk{URL: "https://splunk.example.com:8088", Token: "token", Index: "clair_{{.Reason}}", Sourcetype: "clair:{{.Reason}}"}, OK: true},
+		{Name: "NoToken", In: config.Splunk{URL: "https://splunk.example.com:8088"}},
+		{Name: "RelativeURL", In: config.Splunk{URL: "splunk:8088", Token: "token"}},
+		{Name: "BadTemplate", In: config.Splunk{URL: "https://splunk.example.com:8088", Token: "token", Index: "{{.Reason"}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Splunk: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestElasticsearch(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			e := c.Notifier.Elasticsearch
+			if e.Index == "" {
+				t.Error("index not set")
+			}
+			if got, want := e.BatchSize, config.DefaultElasticsearchBatchSize; got != want {
+				t.Errorf("batch size: got: %d, want: %d", got, want)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.Elasticsearch
+		OK   bool
+	}{
+		{Name: "Defaults", In: config.Elasticsearch{URL: "https://es.example.com:9200"}, OK: true},
+		{Name: "APIKey", In: config.Elasticsearch{URL: "https://es.example.com:9200", APIKey: "key", Index: "clair-{{.Reason}}"}, OK: true},
+		{Name: "Basic", In: config.Elasticsearch{URL: "https://es.example.com:9200", Username: "clair", Password: "secret"}, OK: true},
+		{Name: "BothCredentials", In: config.Elasticsearch{URL: "https://es.example.com:9200", APIKey: "key", Username: "clair"}},
+		{Name: "PasswordOnly", In: config.Elasticsearch{URL: "https://es.example.com:9200", Password: "secret"}},
+		{Name: "NoURL", In: config.Elasticsearch{}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Elasticsearch: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestIssues(t *testing.T) {
+	jira := &config.IssuesJira{URL: "https://example.atlassian.net", Project: "SEC", Token: "token"}
+	dojo := &config.IssuesDefectDojo{URL: "https://dojo.example.com", APIKey: "key", Test: 1}
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if !ok {
+				return
+			}
+			i := c.Notifier.Issues
+			if got, want := i.Title, config.DefaultIssuesTitle; got != want {
+				t.Errorf("title: got: %q, want: %q", got, want)
+			}
+			if i.Jira != nil && i.Jira.IssueType != config.DefaultIssuesJiraIssueType {
+				t.Errorf("issue type: got: %q, want: %q", i.Jira.IssueType, config.DefaultIssuesJiraIssueType)
+			}
+		}
+	}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		In   config.Issues
+		OK   bool
+	}{
+		{Name: "Jira", In: config.Issues{Jira: jira}, OK: true},
+		{Name: "DefectDojo", In: config.Issues{DefectDojo: dojo}, OK: true},
+		{Name: "None", In: config.Issues{}},
+		{Name: "Both", In: config.Issues{Jira: jira, DefectDojo: dojo}},
+		{Name: "BadTemplate", In: config.Issues{Jira: jira, Description: "{{.Image"}},
+		{Name: "NoProject", In: config.Issues{Jira: &config.IssuesJira{URL: jira.URL, Token: "token"}}},
+		{Name: "NoTest", In: config.Issues{DefectDojo: &config.IssuesDefectDojo{URL: dojo.URL, APIKey: "key"}}},
+	} {
+		c := c
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					Issues: &c.In,
+				},
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestMatcherVulnstore(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	vs := &config.MatcherVulnstore{Addr: "http://vulnstore.example.com/"}
+	var tt []ValidateTestcase
+	for _, c := range []struct {
+		Name string
+		Mode config.Mode
+		In   config.Matcher
+		OK   bool
+	}{
+		{Name: "OK", Mode: config.MatcherMode, In: config.Matcher{Vulnstore: vs}, OK: true},
+		{Name: "NoAddr", Mode: config.MatcherMode, In: config.Matcher{Vulnstore: &config.MatcherVulnstore{}}},
+		{Name: "Trends", Mode: config.MatcherMode, In: config.Matcher{Vulnstore: vs, Trends: &config.MatcherTrends{}}},
+		{Name: "GC", Mode: config.MatcherMode, In: config.Matcher{Vulnstore: vs, GC: &config.MatcherGC{}}},
+		{Name: "Combo", Mode: config.ComboMode, In: config.Matcher{Vulnstore: vs}},
+	} {
+		c := c
+		c.In.IndexerAddr = "http://example.com/"
+		tt = append(tt, ValidateTestcase{
+			Name: c.Name,
+			Conf: config.Config{
+				Mode:           c.Mode,
+				HTTPListenAddr: "localhost:8080",
+				Matcher:        c.In,
+			},
+			Check: check(c.OK),
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestAuthPlugins(t *testing.T) {
+	check := func(ok bool) func(*testing.T, *config.Config, error) {
+		return func(t *testing.T, c *config.Config, err error) {
+			if got, want := err == nil, ok; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		}
+	}
+	plugins := map[string]interface{}{"sso": map[string]interface{}{}}
+	psk := &config.AuthPSK{Key: []byte("deadbeef"), Issuer: []string{"clair"}}
+	tt := []ValidateTestcase{
+		{
+			Name: "Combo",
+			Conf: config.Config{
+				Mode:           config.ComboMode,
+				HTTPListenAddr: "localhost:8080",
+				Auth:           config.Auth{Plugins: plugins},
+			},
+			Check: check(true),
+		},
+		{
+			Name: "MatcherNoPSK",
+			Conf: config.Config{
+				Mode:           config.MatcherMode,
+				HTTPListenAddr: "localhost:8080",
+				Matcher:        config.Matcher{IndexerAddr: "http://example.com/"},
+				Auth:           config.Auth{Plugins: plugins},
+			},
+			Check: check(false),
+		},
+		{
+			Name: "MatcherPSK",
+			Conf: config.Config{
+				Mode:           config.MatcherMode,
+				HTTPListenAddr: "localhost:8080",
+				Matcher:        config.Matcher{IndexerAddr: "http://example.com/"},
+				Auth:           config.Auth{Plugins: plugins, PSK: psk},
+			},
+			Check: check(true),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
@@ -22,6 +22,197 @@
 	// the notifier's delivery interval. The notifier will attempt to deliver
 	// outstanding notifications at this rate.
 	DefaultNotifierDeliveryInterval = 5 * time.Second
+	// DefaultNotifierDeliveredRetention is the default age past which
+	// delivered notifications are garbage collected.
+	DefaultNotifierDeliveredRetention = 14 * 24 * time.Hour
+	// DefaultNotifierGCInterval is the default interval for garbage
+	// collecting notifications.
+	DefaultNotifierGCInterval = time.Hour
+	// DefaultNotifierDeliveryBatch is the default maximum number of
+	// notification sets attempted in a single delivery run.
+	DefaultNotifierDeliveryBatch = 100
+	// DefaultNotifierDeliveryLatency is the default average delivery latency
+	// past which the notifier shrinks delivery batches.
+	DefaultNotifierDeliveryLatency = 5 * time.Second
+	// DefaultNotifierRetryBackoff is the default delay before a notification
+	// that failed to be delivered is attempted again.
+	DefaultNotifierRetryBackoff = 30 * time.Second
+	// DefaultNotifierRetryMaxBackoff is the default maximum delay between
+	// attempts to deliver a notification.
+	DefaultNotifierRetryMaxBackoff = time.Hour
+	// DefaultNotifierMaxPending is the default number of undelivered
+	// notification sets past which the notifier pauses polling.
+	DefaultNotifierMaxPending = 1000
+	// DefaultNotifierAffectedChunk is the default number of vulnerabilities
+	// sent in a single request for affected manifests.
+	DefaultNotifierAffectedChunk = 1000
+	// DefaultNotifierPageWorkers is the default number of goroutines
+	// decoding a page of notifications.
+	DefaultNotifierPageWorkers = 4
+	// DefaultNotifierPageCacheSize is the default number of materialized
+	// notification pages kept.
+	DefaultNotifierPageCacheSize = 64
+	// DefaultNotifierPageTTL is the default length of time materialized
+	// notification pages are kept.
+	DefaultNotifierPageTTL = 5 * time.Minute
+	// DefaultIntrospectionHealthPath and DefaultIntrospectionReadyPath are
+	// the health and readiness endpoints of the introspection server, which
+	// are served without authentication by default.
+	DefaultIntrospectionHealthPath = "/healthz"
+	DefaultIntrospectionReadyPath  = "/readyz"
+	// DefaultWebhookRetryAttempts is the default number of requests made for
+	// a webhook delivery when retries are configured.
+	DefaultWebhookRetryAttempts = 3
+	// DefaultWebhookRetryBackoff is the default delay before the first retry
+	// of a webhook delivery.
+	DefaultWebhookRetryBackoff = time.Second
+	// DefaultWebhookRetryMaxBackoff is the default cap on the delay between
+	// webhook delivery attempts.
+	DefaultWebhookRetryMaxBackoff = 30 * time.Second
+	// DefaultDependencyTrackProjectLabel is the default manifest label
+	// naming the Dependency-Track project a manifest is published to.
+	DefaultDependencyTrackProjectLabel = "repository"
+	// DefaultDependencyTrackVersionLabel is the default manifest label
+	// naming the Dependency-Track project version a manifest is published
+	// to.
+	DefaultDependencyTrackVersionLabel = "tag"
+	// DefaultIssuesImageLabel is the default manifest label naming the image
+	// issues are opened for.
+	DefaultIssuesImageLabel = "repository"
+	// DefaultIssuesJiraIssueType is the default type of Jira issue opened.
+	DefaultIssuesJiraIssueType = "Bug"
+	// DefaultBrokerDialTimeout is the default timeout for connecting to an
+	// AMQP or STOMP broker.
+	DefaultBrokerDialTimeout = 30 * time.Second
+	// DefaultBrokerHandshakeTimeout is the default timeout for the TLS and
+	// protocol handshakes with an AMQP or STOMP broker.
+	DefaultBrokerHandshakeTimeout = 30 * time.Second
+	// DefaultSTOMPFailureBackoff is the default length of time a STOMP broker
+	// that couldn't be connected to is skipped for.
+	DefaultSTOMPFailureBackoff = 30 * time.Second
+	// DefaultMQTTTopic is the default template for the topic notifications
+	// are published to over MQTT.
+	DefaultMQTTTopic = `clair/notifications/{{.Vulnerability.Severity}}/{{.Manifest}}`
+	// DefaultAzurePartitionKey is the default template for the partition key
+	// of messages sent to Azure Service Bus or Event Hubs.
+	DefaultAzurePartitionKey = `{{.Manifest}}`
+	// DefaultSyslogFacility is the default facility of syslog messages.
+	DefaultSyslogFacility = "local0"
+	// DefaultSyslogAppName is the default APP-NAME of syslog messages.
+	DefaultSyslogAppName = "clair"
+	// DefaultAzureBatchSize is the default number of messages sent to Azure
+	// Service Bus or Event Hubs in a single request.
+	DefaultAzureBatchSize = 100
+	// DefaultSplunkSourcetype is the default template for the sourcetype of
+	// events sent to a Splunk HTTP Event Collector.
+	DefaultSplunkSourcetype = "clair:notification"
+	// DefaultSplunkSource is the default source of events sent to a Splunk
+	// HTTP Event Collector.
+	DefaultSplunkSource = "clair"
+	// DefaultSplunkBatchSize is the default number of events sent to a Splunk
+	// HTTP Event Collector in a single request.
+	DefaultSplunkBatchSize = 100
+	// DefaultElasticsearchIndex is the default template for the index
+	// notifications are written to.
+	DefaultElasticsearchIndex = "clair-notifications"
+	// DefaultElasticsearchBatchSize is the default number of documents sent
+	// to Elasticsearch in a single bulk request.
+	DefaultElasticsearchBatchSize = 500
+	// DefaultMatcherTrendsRetention is the default length of time recorded
+	// finding counts are kept.
+	DefaultMatcherTrendsRetention = 90 * 24 * time.Hour
+	// DefaultMatcherChangelogRetention is the default length of time
+	// changelog events are kept.
+	DefaultMatcherChangelogRetention = 365 * 24 * time.Hour
+	// DefaultMatcherGCInterval is the default interval for evaluating update
+	// operation GC policies.
+	DefaultMatcherGCInterval = time.Hour
+	// DefaultSubscriptionMinInterval is the default shortest interval a
+	// re-scan subscription may request.
+	DefaultSubscriptionMinInterval = time.Hour
+	// DefaultBackfillRate is the default number of manifests a re-match
+	// backfill processes per second.
+	DefaultBackfillRate = 10
+	// DefaultBackfillChunkSize is the default number of manifests a re-match
+	// backfill processes between saves of its progress.
+	DefaultBackfillChunkSize = 100
+	// DefaultVacuumWindow is the default length of the matcher's vacuum
+	// window.
+	DefaultVacuumWindow = 2 * time.Hour
+	// DefaultCacheKeyPrefix is the default prefix for keys in the shared
+	// cache.
+	DefaultCacheKeyPrefix = "clair:"
+	// DefaultCacheIndexReportTTL is the default lifetime of index reports in
+	// the shared cache.
+	DefaultCacheIndexReportTTL = time.Hour
+	// DefaultCacheAffectedManifestsTTL is the default lifetime of affected
+	// manifest results in the shared cache.
+	DefaultCacheAffectedManifestsTTL = 5 * time.Minute
+	// DefaultIndexerQueueLease is the default length of a lease on a manifest
+	// claimed from the shared indexer queue.
+	DefaultIndexerQueueLease = 5 * time.Minute
+	// DefaultIndexerQueuePollInterval is the default interval for checking
+	// whether a manifest claimed by another indexer has been released.
+	DefaultIndexerQueuePollInterval = 2 * time.Second
+	// DefaultIndexerIdempotencyTTL is the default length of time a response
+	// to an index request carrying an idempotency key is kept for replay.
+	DefaultIndexerIdempotencyTTL = time.Hour
+	// DefaultIndexerIdempotencyMaxEntries is the default number of responses
+	// kept for replay.
+	DefaultIndexerIdempotencyMaxEntries = 1000
+	// DefaultIndexerSecretsMaxFileSize is the default size of the largest
+	// file examined for embedded credentials.
+	DefaultIndexerSecretsMaxFileSize = 64 * 1024
+	// DefaultLayerFetchRetries is the default number of times an interrupted
+	// layer download is resumed.
+	DefaultLayerFetchRetries = 3
+	// DefaultIndexerWebhookConcurrency is the default number of pushed
+	// images the webhook listener indexes at once.
+	DefaultIndexerWebhookConcurrency = 4
+	// DefaultIndexerWebhookQueueSize is the default number of pushed images
+	// that may wait to be indexed.
+	DefaultIndexerWebhookQueueSize = 1000
+	// DefaultAccessLogErrorStatus is the default status code at and above
+	// which requests are always logged, if access logging is enabled.
+	DefaultAccessLogErrorStatus = 500
+	// DefaultProfileWatchdogInterval is the default interval for checking
+	// resource thresholds.
+	DefaultProfileWatchdogInterval = 10 * time.Second
+	// DefaultProfileWatchdogCooldown is the default minimum time between
+	// profile captures.
+	DefaultProfileWatchdogCooldown = 5 * time.Minute
+	// DefaultProfileWatchdogRetain is the default number of profile captures
+	// to keep.
+	DefaultProfileWatchdogRetain = 10
+	// DefaultExplainCooldown is the default minimum time between query plan
+	// captures for the same query.
+	DefaultExplainCooldown = 10 * time.Minute
+	// DefaultSignatureSuffix is the default suffix for locating the detached
+	// signature of an updater download.
+	DefaultSignatureSuffix = ".asc"
+	// DefaultStandbyPollInterval is the default interval for a standby to try
+	// the active lock.
+	DefaultStandbyPollInterval = 500 * time.Millisecond
+	// DefaultRuntimeEOLURL is the default base URL for language runtime
+	// end-of-life schedules.
+	DefaultRuntimeEOLURL = "https://endoflife.date/api/"
+)
+
+// These are the default templates for issues opened by the notifier. They're
+// executed with the vulnerability, the image, and the affected manifests.
+const (
+	DefaultIssuesTitle       = `{{.Vulnerability.Name}} in {{.Image}}`
+	DefaultIssuesDescription = `{{.Vulnerability.Name}} ({{.Vulnerability.Severity}}) affects {{with .Vulnerability.Package}}{{.Name}} {{.Version}} in {{end}}{{.Image}}.
+{{with .Vulnerability.FixedInVersion}}
+Fixed in version {{.}}.
+{{end}}{{with .Vulnerability.Description}}
+{{.}}
+{{end}}{{with .Vulnerability.Links}}
+References: {{.}}
+{{end}}
+Affected manifests:
+{{range .Manifests}}- {{.}}
+{{end}}`
 )
 
 // BUG(hank) The DefaultNotifierPollInterval is absurdly low.
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// Elasticsearch configures the notifier to index notifications into
+// Elasticsearch or OpenSearch using the bulk API.
+//
+// Every notification is indexed as its own document, with the notification
+// ID as the document ID. Documents are sent in batches.
+type Elasticsearch struct {
+	// The base URL of the cluster, such as "https://es.example.com:9200".
+	URL string `yaml:"url" json:"url"`
+	// A Go text/template for the index or data stream of a document, executed
+	// with the notification. The result is lowercased.
+	// If empty, the default of "clair-notifications" is used.
+	Index string `yaml:"index,omitempty" json:"index,omitempty"`
+	// An ingest pipeline to run documents through.
+	Pipeline string `yaml:"pipeline,omitempty" json:"pipeline,omitempty"`
+	// An encoded API key.
+	//
+	// At most one of "api_key" and "username" may be provided.
+	APIKey string `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	// A username for basic authentication.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	// A password for basic authentication.
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// The maximum number of documents sent in a single request.
+	// If 0, the default of 500 is used.
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`
+}
+
+func (e *Elasticsearch) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	u, err := url.Parse(e.URL)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	case !u.IsAbs() || u.Host == "":
+		return nil, fmt.Errorf("url %q must be absolute", e.URL)
+	case e.APIKey != "" && e.Username != "":
+		return nil, errors.New("only one of api_key or username may be provided")
+	case e.Password != "" && e.Username == "":
+		return nil, errors.New("password provided without username")
+	case e.BatchSize < 0:
+		return nil, errors.New("batch_size must not be negative")
+	}
+	if e.Index == "" {
+		e.Index = DefaultElasticsearchIndex
+	}
+	if e.BatchSize == 0 {
+		e.BatchSize = DefaultElasticsearchBatchSize
+	}
+	if _, err := template.New("index").Parse(e.Index); err != nil {
+		return nil, fmt.Errorf("invalid index template: %w", err)
+	}
+	return e.lint()
+}
+
+func (e *Elasticsearch) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(e.URL); err == nil && u.Scheme == "http" &&
+		(e.APIKey != "" || e.Password != "") {
+		ws = append(ws, Warning{
+			path: ".url",
+			msg:  "credentials will be sent unencrypted",
+		})
+	}
+	return ws, nil
+}
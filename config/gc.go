@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// MatcherGC configures the garbage collection policy engine for update
+// operations.
+//
+// The policy engine runs in addition to the collection controlled by
+// "update_retention", which acts as an upper bound on the number of update
+// operations kept for every updater. Vulnerabilities only referenced by
+// removed update operations are cleaned up by that collection, so it should
+// not be disabled when using policies.
+type MatcherGC struct {
+	// A time.ParseDuration parsable string
+	//
+	// The frequency at which policies are evaluated.
+	// If 0, the default of 1 hour is used.
+	Interval Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// DryRun reports what would be deleted without deleting anything.
+	DryRun bool `yaml:"dry_run,omitempty" json:"dry_run,omitempty"`
+	// Policies is checked in order, and the first policy matching an
+	// updater's name applies. Updaters without a matching policy are only
+	// subject to "update_retention".
+	Policies []GCPolicy `yaml:"policies,omitempty" json:"policies,omitempty"`
+	// Vacuum configures a daily window for vacuuming the matcher's tables.
+	// If unset, tables are left to autovacuum.
+	Vacuum *VacuumWindow `yaml:"vacuum,omitempty" json:"vacuum,omitempty"`
+}
+
+func (g *MatcherGC) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if g.Interval <= 0 {
+		g.Interval = Duration(DefaultMatcherGCInterval)
+	}
+	return g.lint()
+}
+
+func (g *MatcherGC) lint() (ws []Warning, err error) {
+	if len(g.Policies) == 0 && g.Vacuum == nil {
+		ws = append(ws, Warning{
+			path: ".policies",
+			msg:  "no policies or vacuum window configured: nothing to do",
+		})
+	}
+	if g.Vacuum != nil && g.Vacuum.Duration < g.Interval {
+		ws = append(ws, Warning{
+			path: ".vacuum.duration",
+			msg:  "vacuum window is shorter than the interval and may be missed",
+		})
+	}
+	return ws, nil
+}
+
+// GCPolicy is a retention policy for the update operations of some set of
+// updaters.
+//
+// The two most recent update operations of an updater are always kept, so
+// that changes can be reported by the notifier.
+type GCPolicy struct {
+	// Updaters is a glob pattern, in the syntax of path.Match, selecting
+	// updaters by name. Required; use "*" to match every updater.
+	Updaters string `yaml:"updaters" json:"updaters"`
+	// The number of update operations to keep.
+	// If 0, there is no limit.
+	Keep int `yaml:"keep,omitempty" json:"keep,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// Update operations older than this are removed.
+	// If 0, there is no limit.
+	MaxAge Duration `yaml:"max_age,omitempty" json:"max_age,omitempty"`
+}
+
+func (p *GCPolicy) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if p.Updaters == "" {
+		return nil, fmt.Errorf("gc policy: updaters pattern must be provided")
+	}
+	if _, err := path.Match(p.Updaters, ""); err != nil {
+		return nil, fmt.Errorf("gc policy: bad updaters pattern %q: %w", p.Updaters, err)
+	}
+	if p.Keep < 0 {
+		return nil, fmt.Errorf("gc policy: keep must not be negative")
+	}
+	if p.MaxAge < 0 {
+		return nil, fmt.Errorf("gc policy: max_age must not be negative")
+	}
+	return p.lint()
+}
+
+func (p *GCPolicy) lint() (ws []Warning, err error) {
+	if p.Keep == 1 {
+		ws = append(ws, Warning{
+			path: ".keep",
+			msg:  "two update operations are always kept",
+		})
+	}
+	if p.Keep == 0 && p.MaxAge == 0 {
+		ws = append(ws, Warning{
+			msg: "policy sets no limits",
+		})
+	}
+	return ws, nil
+}
+
+// VacuumWindow is a daily period, in UTC, when the matcher's tables are
+// vacuumed.
+//
+// Vacuuming happens at most once per window, on the first evaluation of the
+// GC policies inside it.
+type VacuumWindow struct {
+	// Start is the beginning of the window, as "HH:MM" in UTC. Required.
+	Start string `yaml:"start" json:"start"`
+	// A time.ParseDuration parsable string
+	//
+	// The length of the window.
+	// If 0, the default of 2 hours is used.
+	Duration Duration `yaml:"duration,omitempty" json:"duration,omitempty"`
+}
+
+func (v *VacuumWindow) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if _, err := v.Offset(); err != nil {
+		return nil, err
+	}
+	switch {
+	case v.Duration <= 0:
+		v.Duration = Duration(DefaultVacuumWindow)
+	case v.Duration > Duration(24*time.Hour):
+		return nil, fmt.Errorf("vacuum window: duration must be at most 24h")
+	}
+	return nil, nil
+}
+
+// Offset reports the start of the window as an offset from midnight UTC.
+func (v *VacuumWindow) Offset() (time.Duration, error) {
+	t, err := time.Parse("15:04", v.Start)
+	if err != nil {
+		return 0, fmt.Errorf("vacuum window: bad start %q: %w", v.Start, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -1,6 +1,14 @@
 package config
 
-import "runtime"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
 
 // Indexer provides Clair Indexer node configuration
 type Indexer struct {
@@ -23,6 +31,39 @@ type Indexer struct {
 	// Indexers will index a Manifest's layers concurrently.
 	// This value tunes the number of layers an Indexer will scan in parallel.
 	LayerScanConcurrency int `yaml:"layer_scan_concurrency,omitempty" json:"layer_scan_concurrency,omitempty"`
+	// A positive value representing quantity.
+	//
+	// This value limits the number of layers an Indexer will download at
+	// once, across all Manifests being indexed. A value of 0 means
+	// "unlimited."
+	LayerFetchConcurrency int `yaml:"layer_fetch_concurrency,omitempty" json:"layer_fetch_concurrency,omitempty"`
+	// A positive value representing bytes per second.
+	//
+	// This value caps the total bandwidth used for downloading layers, shared
+	// by all concurrent fetches. A value of 0 means "unlimited."
+	LayerFetchBandwidth int64 `yaml:"layer_fetch_bandwidth,omitempty" json:"layer_fetch_bandwidth,omitempty"`
+	// A positive value representing quantity.
+	//
+	// This value is the number of times a layer download that fails partway
+	// through is resumed with a range request before giving up. A value of 0
+	// means the default, and a negative value disables resuming.
+	LayerFetchRetries int `yaml:"layer_fetch_retries,omitempty" json:"layer_fetch_retries,omitempty"`
+	// Per-registry limits on layer downloads.
+	//
+	// Each entry limits downloads from one registry host. An entry for the
+	// host "*" applies separately to every host without its own entry. These
+	// limits apply in addition to the global ones.
+	LayerFetchHosts []LayerFetchHost `yaml:"layer_fetch_hosts,omitempty" json:"layer_fetch_hosts,omitempty"`
+	// LayerFetchers holds configuration blocks for layer fetcher plugins
+	// compiled into the binary, keyed by plugin name. Each named plugin is
+	// asked for layers, in name order, before the registry.
+	LayerFetchers map[string]interface{} `yaml:"layer_fetchers,omitempty" json:"layer_fetchers,omitempty"`
+	// A URL used to check that layers can be fetched.
+	//
+	// If set, the URL is requested with a HEAD request using the Indexer's
+	// HTTP client when a detailed health check is performed. Any response counts
+	// as success; only transport errors are reported.
+	EgressProbe string `yaml:"egress_probe,omitempty" json:"egress_probe,omitempty"`
 	// Rate limits the number if index report creation requests.
 	//
 	// Setting this to 0 will attempt to auto-size this value. Setting a
@@ -46,6 +87,632 @@ type Indexer struct {
 	// [RFC 1918]: https://datatracker.ietf.org/doc/html/rfc1918
 	// [RFC 4193]: https://datatracker.ietf.org/doc/html/rfc4193
 	Airgap bool `yaml:"airgap,omitempty" json:"airgap,omitempty"`
+	// Limits sets admission limits for submitted manifests.
+	Limits IndexerLimits `yaml:"limits,omitempty" json:"limits,omitempty"`
+	// Scratch, if provided, has the indexer keep layers in a scratch
+	// directory of its own while they're scanned, and limit how much space
+	// they may use. If not provided, layers are kept in the system's
+	// temporary directory.
+	Scratch *IndexerScratch `yaml:"scratch,omitempty" json:"scratch,omitempty"`
+	// BatchLane, if provided, gives batch index report creation requests
+	// their own concurrency budget, separate from the one configured by
+	// IndexReportRequestConcurrency.
+	BatchLane *IndexerBatchLane `yaml:"batch_lane,omitempty" json:"batch_lane,omitempty"`
+	// Queue, if provided, coordinates indexing across indexer processes
+	// sharing a database, so that a manifest is only indexed by one process
+	// at a time.
+	Queue *IndexerQueue `yaml:"queue,omitempty" json:"queue,omitempty"`
+	// Resolve, if provided, enables the endpoint that resolves image
+	// references into manifests and indexes them, so clients don't need to
+	// talk to registries themselves.
+	Resolve *IndexerResolve `yaml:"resolve,omitempty" json:"resolve,omitempty"`
+	// Idempotency, if provided, enables the "Idempotency-Key" header on index
+	// requests, so that a client retrying a request gets the original
+	// response rather than starting the work again.
+	Idempotency *IndexerIdempotency `yaml:"idempotency,omitempty" json:"idempotency,omitempty"`
+	// A "true" or "false" value
+	//
+	// Dedup stores a single index report for manifests with identical
+	// layers, such as images differing only in their config blobs. Other
+	// manifests reference the stored report, which is removed once no
+	// manifest references it.
+	Dedup bool `yaml:"dedup,omitempty" json:"dedup,omitempty"`
+	// A "true" or "false" value
+	//
+	// Labels enables storing the labels clients submit with manifests, and
+	// echoing them in index reports, vulnerability reports, and
+	// notifications.
+	Labels bool `yaml:"labels,omitempty" json:"labels,omitempty"`
+	// Artifacts, if provided, enables indexing OCI artifacts that aren't
+	// container images, such as Helm charts and WASM modules.
+	Artifacts *IndexerArtifacts `yaml:"artifacts,omitempty" json:"artifacts,omitempty"`
+	// Webhook, if provided, serves a listener accepting registry push
+	// webhooks and indexes the pushed images.
+	Webhook *IndexerWebhook `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+	// RepoCPE, if provided, adds to and overrides the mapping of RHEL
+	// repositories to CPEs used by the RHEL repository scanner.
+	RepoCPE *IndexerRepoCPE `yaml:"repo_cpe,omitempty" json:"repo_cpe,omitempty"`
+	// Secrets, if provided, enables looking for credentials embedded in
+	// images and reporting them in index reports.
+	Secrets *IndexerSecrets `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	// Licenses, if provided, enables recording the licenses packages declare
+	// and reporting them in index reports.
+	Licenses *IndexerLicenses `yaml:"licenses,omitempty" json:"licenses,omitempty"`
+	// FileOwners enables recording the files installed by distribution
+	// packages, so file ownership lookups can attribute them.
+	FileOwners bool `yaml:"file_owners,omitempty" json:"file_owners,omitempty"`
+	// ImageConfig enables recording the image configs clients submit with
+	// manifests and including them in index reports. Manifests whose config
+	// names an operating system other than Linux are refused.
+	ImageConfig bool `yaml:"image_config,omitempty" json:"image_config,omitempty"`
+	// Unpackaged enables looking for the contents of images that no package
+	// manager accounts for, such as statically linked executables, and
+	// reporting them, along with whether there's a package manager at all,
+	// in index reports.
+	Unpackaged bool `yaml:"unpackaged,omitempty" json:"unpackaged,omitempty"`
+}
+
+// IndexerRepoCPE is the configuration for additional repository-to-CPE
+// mapping data.
+//
+// The mapping the RHEL repository scanner is configured with is still
+// fetched as usual, then the Sources are merged into it and the Overrides
+// applied on top.
+type IndexerRepoCPE struct {
+	// Sources are URLs or file paths of additional mapping files, in the
+	// format of Red Hat's "repository-to-cpe.json". A repository in more than
+	// one mapping file maps to all of the CPEs listed for it.
+	Sources []string `yaml:"sources,omitempty" json:"sources,omitempty"`
+	// Overrides maps repository IDs (the content sets listed in an image's
+	// content manifest) to the CPEs they map to, replacing any mapping from
+	// the mapping files. An empty list removes the repository's mapping.
+	Overrides map[string][]string `yaml:"overrides,omitempty" json:"overrides,omitempty"`
+}
+
+func (r *IndexerRepoCPE) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if len(r.Sources) == 0 && len(r.Overrides) == 0 {
+		return nil, errors.New(`one of "sources" or "overrides" is required`)
+	}
+	for _, src := range r.Sources {
+		if src == "" {
+			return nil, errors.New("sources must not be empty")
+		}
+	}
+	for id := range r.Overrides {
+		if id == "" {
+			return nil, errors.New("override repository IDs must not be empty")
+		}
+	}
+	return r.lint()
+}
+
+func (r *IndexerRepoCPE) lint() (ws []Warning, err error) {
+	for _, src := range r.Sources {
+		if u, err := url.Parse(src); err == nil && u.Scheme == "http" {
+			ws = append(ws, Warning{
+				path: ".sources",
+				msg:  fmt.Sprintf("%q will be fetched unencrypted", src),
+			})
+		}
+	}
+	return ws, nil
+}
+
+// IndexerWebhook is the configuration for the registry webhook listener.
+//
+// Pushed images are resolved with the credentials in the Docker
+// configuration file named by $DOCKER_CONFIG (default
+// ~/.docker/config.json).
+type IndexerWebhook struct {
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
+	//
+	// The webhook listener is always separate from the API listeners.
+	ListenAddr string `yaml:"listen_addr" json:"listen_addr"`
+	// Secret, if provided, must be presented by webhook senders, either as a
+	// bearer token or as the "secret" query parameter.
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+	// Registries, if provided, are the only registry hosts pushed images are
+	// accepted from.
+	Registries []string `yaml:"registries,omitempty" json:"registries,omitempty"`
+	// Concurrency is the number of pushed images resolved and indexed at
+	// once.
+	//
+	// The default is 4.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// QueueSize is the number of pushed images that may wait to be indexed.
+	// Webhooks received while the queue is full are answered with "503
+	// Service Unavailable".
+	//
+	// The default is 1000.
+	QueueSize int `yaml:"queue_size,omitempty" json:"queue_size,omitempty"`
+}
+
+func (h *IndexerWebhook) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if h.ListenAddr == "" {
+		return nil, errors.New(`"listen_addr" is required`)
+	}
+	if err := checkListenAddr(h.ListenAddr); err != nil {
+		return nil, fmt.Errorf("listen_addr: %w", err)
+	}
+	if h.Concurrency == 0 {
+		h.Concurrency = DefaultIndexerWebhookConcurrency
+	}
+	if h.QueueSize == 0 {
+		h.QueueSize = DefaultIndexerWebhookQueueSize
+	}
+	return h.lint()
+}
+
+func (h *IndexerWebhook) lint() (ws []Warning, err error) {
+	if h.Concurrency < 0 || h.QueueSize < 0 {
+		return nil, fmt.Errorf("negative values are invalid: concurrency %d, queue_size %d",
+			h.Concurrency, h.QueueSize)
+	}
+	if h.Secret == "" {
+		ws = append(ws, Warning{
+			path: ".secret",
+			msg:  `anyone who can reach the listener can cause images to be indexed`,
+		})
+	}
+	if len(h.Registries) == 0 {
+		ws = append(ws, Warning{
+			path: ".registries",
+			msg:  `images will be fetched from any registry named in a webhook`,
+		})
+	}
+	return ws, nil
+}
+
+// IndexerArtifacts is the configuration for indexing non-image OCI artifacts.
+//
+// Artifacts are indexed by scanners selected by the artifact type supplied
+// with the manifest, and their reports are stored in the indexer's database.
+type IndexerArtifacts struct {
+	// Scanners names the artifact scanners to enable. If empty, all
+	// registered scanners are used.
+	Scanners []string `yaml:"scanners,omitempty" json:"scanners,omitempty"`
+}
+
+// IndexerSecrets is the configuration for detecting credentials embedded in
+// images.
+//
+// Layers are examined for well-known credential files and private keys, and
+// the environment supplied with the manifest for variables that look like
+// they hold credentials. Findings are stored in the indexer's database.
+type IndexerSecrets struct {
+	// MaxFileSize is the size in bytes of the largest file examined. Larger
+	// files are skipped.
+	//
+	// The default is 64 KiB.
+	MaxFileSize int64 `yaml:"max_file_size,omitempty" json:"max_file_size,omitempty"`
+	// IgnorePaths is a list of patterns, in the syntax of path.Match,
+	// for files that are never reported. Paths are relative to the root of
+	// the image, without a leading slash.
+	//
+	// The image's environment is always examined.
+	IgnorePaths []string `yaml:"ignore_paths,omitempty" json:"ignore_paths,omitempty"`
+}
+
+func (s *IndexerSecrets) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if s.MaxFileSize == 0 {
+		s.MaxFileSize = DefaultIndexerSecretsMaxFileSize
+	}
+	return s.lint()
+}
+
+func (s *IndexerSecrets) lint() (ws []Warning, err error) {
+	if s.MaxFileSize < 0 {
+		return nil, fmt.Errorf("negative max_file_size is invalid: %d", s.MaxFileSize)
+	}
+	for _, p := range s.IgnorePaths {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("ignore_paths: pattern %q: %w", p, err)
+		}
+		if strings.HasPrefix(p, "/") {
+			ws = append(ws, Warning{
+				path: ".ignore_paths",
+				msg:  fmt.Sprintf("pattern %q has a leading slash and will never match", p),
+			})
+		}
+	}
+	return ws, nil
+}
+
+// IndexerLicenses is the configuration for recording package licenses.
+//
+// Licenses are read from package metadata in each layer: the RPM and apk
+// databases, Debian machine-readable copyright files, Python package
+// metadata, and RubyGems specifications. They're stored in the indexer's
+// database.
+type IndexerLicenses struct {
+	// Policy, if provided, enables the endpoint checking a manifest's
+	// packages against it.
+	Policy *LicensePolicy `yaml:"policy,omitempty" json:"policy,omitempty"`
+}
+
+// LicensePolicy lists acceptable and unacceptable licenses, by SPDX license
+// identifier. Identifiers are compared without regard to case.
+//
+// A license that's denied is a violation. If Allow isn't empty, a license
+// that isn't allowed is also a violation. For license expressions, a choice
+// of licenses is acceptable if any of the choices is.
+type LicensePolicy struct {
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty" json:"deny,omitempty"`
+}
+
+func (p *LicensePolicy) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	return p.lint()
+}
+
+func (p *LicensePolicy) lint() (ws []Warning, err error) {
+	for _, l := range [][]string{p.Allow, p.Deny} {
+		for _, id := range l {
+			if strings.TrimSpace(id) == "" || strings.ContainsAny(id, " ()") {
+				return nil, fmt.Errorf("invalid license identifier %q", id)
+			}
+		}
+	}
+	if len(p.Allow) == 0 && len(p.Deny) == 0 {
+		ws = append(ws, Warning{
+			msg: `empty policy: every license is acceptable`,
+		})
+	}
+	return ws, nil
+}
+
+// IndexerBatchLane is the configuration for the batch lane of index report
+// creation requests.
+//
+// Requests are in the batch lane if they carry a "Clair-Priority: batch"
+// header or are authenticated with one of the listed issuers. All other
+// requests are in the interactive lane, so large backfills can't starve
+// interactive clients.
+type IndexerBatchLane struct {
+	// Concurrency limits the number of concurrent batch requests.
+	//
+	// Setting this to 0 will attempt to auto-size this value. Setting a
+	// negative value means "unlimited."
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// Issuers is a list of JWT issuers whose requests are always in the batch
+	// lane.
+	Issuers []string `yaml:"issuers,omitempty" json:"issuers,omitempty"`
+}
+
+func (b *IndexerBatchLane) validate(mode Mode) (ws []Warning, err error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if b.Concurrency == 0 {
+		// Batch work is assumed to be heavier, so hand out fewer slots.
+		b.Concurrency = runtime.GOMAXPROCS(0)
+		ws = append(ws, Warning{
+			path: ".concurrency",
+			msg:  `automatically sizing number of concurrent requests`,
+		})
+	}
+	return ws, nil
+}
+
+// IndexerResolve is the configuration for resolving image references on
+// behalf of clients.
+//
+// Requests may name one of the configured Credentials to authenticate with.
+// Otherwise, registries are accessed anonymously.
+type IndexerResolve struct {
+	// Registries are the only registry hosts references are resolved from.
+	// At least one is required.
+	Registries []string `yaml:"registries,omitempty" json:"registries,omitempty"`
+	// Credentials are registry credentials requests may refer to by name.
+	Credentials map[string]RegistryCredential `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+// RegistryCredential is a credential for a single registry.
+//
+// One of Token or Username and Password must be provided.
+type RegistryCredential struct {
+	// Registry is the registry host the credential is for. It's only ever
+	// presented to this host.
+	Registry string `yaml:"registry" json:"registry"`
+	// Username and Password are presented using basic authentication.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// Token is presented as a bearer token.
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
+func (r *IndexerResolve) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if len(r.Registries) == 0 {
+		return nil, errors.New("at least one entry in \"registries\" is required")
+	}
+	for n, c := range r.Credentials {
+		switch {
+		case n == "":
+			return nil, errors.New("credential names must not be empty")
+		case c.Registry == "":
+			return nil, fmt.Errorf("credential %q: \"registry\" is required", n)
+		case strings.Contains(c.Registry, "/"):
+			return nil, fmt.Errorf("credential %q: \"registry\" must be a host, not %q", n, c.Registry)
+		case c.Token != "" && (c.Username != "" || c.Password != ""):
+			return nil, fmt.Errorf("credential %q: only one of \"token\" or \"username\" and \"password\" may be provided", n)
+		case c.Token == "" && c.Username == "":
+			return nil, fmt.Errorf("credential %q: one of \"token\" or \"username\" is required", n)
+		}
+	}
+	return r.lint()
+}
+
+func (r *IndexerResolve) lint() (ws []Warning, err error) {
+	allow := make(map[string]struct{}, len(r.Registries))
+	for _, h := range r.Registries {
+		allow[h] = struct{}{}
+	}
+	for n, c := range r.Credentials {
+		if _, ok := allow[c.Registry]; !ok {
+			ws = append(ws, Warning{
+				path: ".credentials." + n,
+				msg:  fmt.Sprintf("registry %q isn't allowed: credential will never be used", c.Registry),
+			})
+		}
+	}
+	return ws, nil
+}
+
+// IndexerIdempotency is the configuration for idempotency keys on index
+// requests.
+//
+// Responses are kept in the memory of the process that served them, so a
+// retry only gets the original response if it reaches the same process.
+type IndexerIdempotency struct {
+	// TTL is how long a response is kept for replay.
+	//
+	// The default is 1 hour.
+	TTL Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	// MaxEntries is the number of responses kept. Once full, the oldest
+	// response is discarded to make room.
+	//
+	// The default is 1000.
+	MaxEntries int `yaml:"max_entries,omitempty" json:"max_entries,omitempty"`
+}
+
+func (i *IndexerIdempotency) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if i.TTL == 0 {
+		i.TTL = Duration(DefaultIndexerIdempotencyTTL)
+	}
+	if i.MaxEntries == 0 {
+		i.MaxEntries = DefaultIndexerIdempotencyMaxEntries
+	}
+	return i.lint()
+}
+
+func (i *IndexerIdempotency) lint() (ws []Warning, err error) {
+	if i.TTL < 0 || i.MaxEntries < 0 {
+		return nil, fmt.Errorf("negative values are invalid: ttl %v, max_entries %d",
+			time.Duration(i.TTL), i.MaxEntries)
+	}
+	if i.TTL != 0 && i.TTL < Duration(time.Minute) {
+		ws = append(ws, Warning{
+			path: ".ttl",
+			msg:  `short TTLs may expire before a client retries`,
+		})
+	}
+	return ws, nil
+}
+
+// IndexerQueue is the configuration for the shared indexer queue.
+//
+// The queue is stored in the indexer's database. An indexer claims a lease on
+// a manifest before indexing it and renews the lease while the work
+// continues; if the indexer goes away, the lease expires and another indexer
+// may claim the manifest.
+type IndexerQueue struct {
+	// Lease is the length of a claim on a manifest.
+	//
+	// The default is 5 minutes.
+	Lease Duration `yaml:"lease,omitempty" json:"lease,omitempty"`
+	// PollInterval is how often a waiting indexer checks whether a manifest
+	// has been released.
+	//
+	// The default is 2 seconds.
+	PollInterval Duration `yaml:"poll_interval,omitempty" json:"poll_interval,omitempty"`
+}
+
+func (q *IndexerQueue) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if q.Lease == 0 {
+		q.Lease = Duration(DefaultIndexerQueueLease)
+	}
+	if q.PollInterval == 0 {
+		q.PollInterval = Duration(DefaultIndexerQueuePollInterval)
+	}
+	return q.lint()
+}
+
+func (q *IndexerQueue) lint() (ws []Warning, err error) {
+	if q.Lease < 0 || q.PollInterval < 0 {
+		return nil, fmt.Errorf("negative durations are invalid: lease %v, poll_interval %v",
+			time.Duration(q.Lease), time.Duration(q.PollInterval))
+	}
+	if q.Lease != 0 && q.Lease < Duration(30*time.Second) {
+		ws = append(ws, Warning{
+			path: ".lease",
+			msg:  `short leases cause frequent renewals and may expire during slow fetches`,
+		})
+	}
+	if q.Lease != 0 && q.PollInterval >= q.Lease {
+		ws = append(ws, Warning{
+			path: ".poll_interval",
+			msg:  `poll interval longer than the lease: waiting indexers will be slow to notice released manifests`,
+		})
+	}
+	return ws, nil
+}
+
+// LayerFetchHost limits layer downloads from a single registry host.
+type LayerFetchHost struct {
+	// Host is the host (and port, if not the default) of the registry, as it
+	// appears in layer URLs, or "*".
+	Host string `yaml:"host" json:"host"`
+	// Concurrency limits the number of layers downloaded from the host at
+	// once. A value of 0 means "unlimited."
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// Bandwidth caps the bandwidth, in bytes per second, used for downloading
+	// layers from the host. A value of 0 means "unlimited."
+	Bandwidth int64 `yaml:"bandwidth,omitempty" json:"bandwidth,omitempty"`
+}
+
+func (h *LayerFetchHost) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if h.Host == "" {
+		return nil, errors.New("layer fetch host: missing host")
+	}
+	if strings.Contains(h.Host, "/") {
+		return nil, fmt.Errorf("layer fetch host: %q is not a host", h.Host)
+	}
+	return h.lint()
+}
+
+func (h *LayerFetchHost) lint() (ws []Warning, err error) {
+	if h.Concurrency < 0 {
+		ws = append(ws, Warning{
+			path: ".concurrency",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	switch {
+	case h.Bandwidth < 0:
+		ws = append(ws, Warning{
+			path: ".bandwidth",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	case h.Bandwidth > 0 && h.Bandwidth < 1024*1024:
+		ws = append(ws, Warning{
+			path: ".bandwidth",
+			msg:  `small values will greatly increase latency`,
+		})
+	}
+	if h.Concurrency == 0 && h.Bandwidth == 0 {
+		ws = append(ws, Warning{
+			msg: `no limits set`,
+		})
+	}
+	return ws, nil
+}
+
+// IndexerLimits is the configuration for rejecting manifests that are too
+// large to be indexed.
+//
+// A value of 0 for any member means "unlimited."
+type IndexerLimits struct {
+	// MaxLayers is the maximum number of layers a submitted manifest may
+	// have. Manifests over this limit are rejected at submission.
+	MaxLayers int `yaml:"max_layers,omitempty" json:"max_layers,omitempty"`
+	// MaxLayerSize is the maximum size, in bytes, of a single compressed
+	// layer.
+	MaxLayerSize int64 `yaml:"max_layer_size,omitempty" json:"max_layer_size,omitempty"`
+	// MaxManifestSize is the maximum number of bytes fetched for all the
+	// layers of a single manifest.
+	MaxManifestSize int64 `yaml:"max_manifest_size,omitempty" json:"max_manifest_size,omitempty"`
+}
+
+// IndexerScratch is the configuration for the indexer's scratch space.
+//
+// Each process keeps its layers in its own directory under Dir, and removes
+// any directories left there by processes that have exited, such as after a
+// crash.
+type IndexerScratch struct {
+	// Dir is the directory scratch directories are created in. It's
+	// required, and should be set aside for the indexer: scratch directories
+	// left in it by exited processes are removed on startup.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// ManifestQuota is the maximum number of bytes of scratch space the
+	// decompressed layers of a single manifest may use. A value of 0 means
+	// "unlimited."
+	ManifestQuota int64 `yaml:"manifest_quota,omitempty" json:"manifest_quota,omitempty"`
+}
+
+func (s *IndexerScratch) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if s.Dir == "" {
+		return nil, errors.New(`"dir" is required`)
+	}
+	return s.lint()
+}
+
+func (s *IndexerScratch) lint() (ws []Warning, err error) {
+	if s.Dir != "" && !path.IsAbs(s.Dir) {
+		ws = append(ws, Warning{
+			path: ".dir",
+			msg:  `relative paths are resolved against the working directory`,
+		})
+	}
+	if s.ManifestQuota < 0 {
+		ws = append(ws, Warning{
+			path: ".manifest_quota",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	if s.ManifestQuota > 0 && s.ManifestQuota < 64*1024*1024 {
+		ws = append(ws, Warning{
+			path: ".manifest_quota",
+			msg:  `small values will cause most manifests to fail indexing`,
+		})
+	}
+	return ws, nil
+}
+
+func (l *IndexerLimits) lint() (ws []Warning, err error) {
+	if l.MaxLayers < 0 {
+		ws = append(ws, Warning{
+			path: ".max_layers",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	if l.MaxLayerSize < 0 {
+		ws = append(ws, Warning{
+			path: ".max_layer_size",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	if l.MaxManifestSize < 0 {
+		ws = append(ws, Warning{
+			path: ".max_manifest_size",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	if l.MaxManifestSize > 0 && l.MaxLayerSize > l.MaxManifestSize {
+		ws = append(ws, Warning{
+			path: ".max_layer_size",
+			msg:  `larger than "max_manifest_size": the latter will be the effective limit`,
+		})
+	}
+	return ws, nil
 }
 
 func (i *Indexer) validate(mode Mode) (ws []Warning, err error) {
@@ -56,6 +723,16 @@ func (i *Indexer) validate(mode Mode) (ws []Warning, err error) {
 	if i.ScanLockRetry == 0 {
 		i.ScanLockRetry = DefaultScanLockRetry
 	}
+	if i.LayerFetchRetries == 0 {
+		i.LayerFetchRetries = DefaultLayerFetchRetries
+	}
+	seen := make(map[string]struct{}, len(i.LayerFetchHosts))
+	for _, h := range i.LayerFetchHosts {
+		if _, ok := seen[h.Host]; ok {
+			return ws, fmt.Errorf("duplicate layer_fetch_hosts entry for %q", h.Host)
+		}
+		seen[h.Host] = struct{}{}
+	}
 	if i.IndexReportRequestConcurrency == 0 {
 		// GOMAXPROCS should be set to the number of cores available.
 		gmp := runtime.GOMAXPROCS(0)
@@ -98,6 +775,38 @@ func (i *Indexer) lint() (ws []Warning, err error) {
 			msg:  `large values may exceed resource quotas`,
 		})
 	}
+	if i.LayerFetchConcurrency < 0 {
+		ws = append(ws, Warning{
+			path: ".layer_fetch_concurrency",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	switch {
+	case i.LayerFetchBandwidth < 0:
+		ws = append(ws, Warning{
+			path: ".layer_fetch_bandwidth",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	case i.LayerFetchBandwidth > 0 && i.LayerFetchBandwidth < 1024*1024:
+		ws = append(ws, Warning{
+			path: ".layer_fetch_bandwidth",
+			msg:  `small values will greatly increase latency`,
+		})
+	}
+	if i.LayerFetchRetries > 10 { // Guess at what a "large" value is here.
+		ws = append(ws, Warning{
+			path: ".layer_fetch_retries",
+			msg:  `large values may hold layer fetch slots for a long time`,
+		})
+	}
+	if i.EgressProbe != "" {
+		if _, err := url.Parse(i.EgressProbe); err != nil {
+			ws = append(ws, Warning{
+				path:  ".egress_probe",
+				inner: err,
+			})
+		}
+	}
 
 	return ws, nil
 }
@@ -108,4 +817,84 @@ type ScannerConfig struct {
 	Package map[string]interface{} `yaml:"package,omitempty" json:"package,omitempty"`
 	Dist    map[string]interface{} `yaml:"dist,omitempty" json:"dist,omitempty"`
 	Repo    map[string]interface{} `yaml:"repo,omitempty" json:"repo,omitempty"`
+	// Enable, if not empty, names the only package scanners to run. The
+	// group names "os" and "language" stand for the scanners of distribution
+	// packages and of language packages, respectively.
+	//
+	// Distribution and repository scanners run if any package scanner they
+	// support does.
+	Enable []string `yaml:"enable,omitempty" json:"enable,omitempty"`
+	// Disable names package scanners not to run, in the same way as Enable.
+	Disable []string `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// Vendored controls packages found in vendored dependency directories,
+	// like "vendor" or "node_modules". These are often copies of packages
+	// reported elsewhere in the image.
+	//
+	// "include" reports them, marked as vendored, and "exclude" drops them.
+	// The default is "include".
+	Vendored string `yaml:"vendored,omitempty" json:"vendored,omitempty"`
+	// Caches controls packages found in package manager caches, like
+	// "~/.m2/repository" or "~/.cache/pip", in the same way as Vendored.
+	Caches string `yaml:"caches,omitempty" json:"caches,omitempty"`
+	// Runtimes enables detecting language runtimes, such as the Python
+	// interpreter or a JDK, installed outside of the distribution's package
+	// manager. They're reported as packages in the "runtime" repository.
+	Runtimes bool `yaml:"runtimes,omitempty" json:"runtimes,omitempty"`
+	// Lockfiles names the package managers whose files are read for packages
+	// claircore has no scanner for: "composer" for PHP, "swift" for the Swift
+	// Package Manager, "cocoapods", and "conan" for C and C++. The packages
+	// are matched against OSV advisories.
+	Lockfiles []string `yaml:"lockfiles,omitempty" json:"lockfiles,omitempty"`
+}
+
+// These are the recognized values for ScannerConfig.Vendored and
+// ScannerConfig.Caches.
+const (
+	ScannerInclude = "include"
+	ScannerExclude = "exclude"
+)
+
+// These are the recognized values for ScannerConfig.Lockfiles.
+const (
+	LockfileComposer  = "composer"
+	LockfileSwift     = "swift"
+	LockfileCocoaPods = "cocoapods"
+	LockfileConan     = "conan"
+)
+
+func (s *ScannerConfig) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != IndexerMode {
+		return nil, nil
+	}
+	if len(s.Enable) != 0 && len(s.Disable) != 0 {
+		return nil, errors.New(`only one of "enable" or "disable" may be provided`)
+	}
+	for _, n := range append(s.Enable, s.Disable...) {
+		if n == "" {
+			return nil, errors.New("scanner names must not be empty")
+		}
+	}
+	for _, v := range []struct {
+		name string
+		v    *string
+	}{
+		{"vendored", &s.Vendored},
+		{"caches", &s.Caches},
+	} {
+		switch *v.v {
+		case "":
+			*v.v = ScannerInclude
+		case ScannerInclude, ScannerExclude:
+		default:
+			return nil, fmt.Errorf("unknown %s setting: %q", v.name, *v.v)
+		}
+	}
+	for _, n := range s.Lockfiles {
+		switch n {
+		case LockfileComposer, LockfileSwift, LockfileCocoaPods, LockfileConan:
+		default:
+			return nil, fmt.Errorf("unknown lockfiles package manager: %q", n)
+		}
+	}
+	return nil, nil
 }
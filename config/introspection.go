@@ -1,29 +1,88 @@
 package config
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Trace specifies how to configure Clair's tracing support.
 //
 // The "Name" key must match the provider to use.
 //
-// Currently, only "jaeger" is supported.
+// Currently, "jaeger" and "otlp" are supported.
 type Trace struct {
 	Name        string   `yaml:"name" json:"name"`
 	Probability *float64 `yaml:"probability,omitempty" json:"probability,omitempty"`
-	Jaeger      Jaeger   `yaml:"jaeger,omitempty" json:"jaeger,omitempty"`
+	// Sampler selects the sampling strategy, using the names from the
+	// OpenTelemetry "OTEL_TRACES_SAMPLER" environment variable:
+	// "always_on", "always_off", "traceidratio", "parentbased_always_on",
+	// "parentbased_always_off", and "parentbased_traceidratio".
+	//
+	// The ratio-based samplers use the "Probability" member. If unset, the
+	// sampler is chosen based on the log level and "Probability".
+	Sampler string `yaml:"sampler,omitempty" json:"sampler,omitempty"`
+	Jaeger  Jaeger `yaml:"jaeger,omitempty" json:"jaeger,omitempty"`
+	OTLP    OTLP   `yaml:"otlp,omitempty" json:"otlp,omitempty"`
 }
 
-func (t *Trace) lint() ([]Warning, error) {
+func (t *Trace) lint() (ws []Warning, err error) {
 	switch t.Name {
 	case "":
 	case "jaeger":
+	case "otlp":
 	default:
-		return []Warning{{
+		ws = append(ws, Warning{
 			path: ".name",
 			msg:  fmt.Sprintf(`unrecognized trace provider: %q`, t.Name),
-		}}, nil
+		})
 	}
-	return nil, nil
+	switch t.Sampler {
+	case "", "always_on", "always_off", "parentbased_always_on", "parentbased_always_off":
+	case "traceidratio", "parentbased_traceidratio":
+		if t.Probability == nil {
+			ws = append(ws, Warning{
+				path: ".probability",
+				msg:  fmt.Sprintf(`sampler %q without a probability: all traces will be sampled`, t.Sampler),
+			})
+		}
+	default:
+		return ws, fmt.Errorf("unrecognized trace sampler: %q", t.Sampler)
+	}
+	return ws, nil
+}
+
+// OTLP specific distributed tracing configuration.
+type OTLP struct {
+	// Headers are sent with every export request.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Protocol is one of "grpc" or "http/protobuf". The default is "grpc".
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	// Endpoint is the "host:port" of the collector. If unset, the
+	// OpenTelemetry environment variables or the protocol's default are
+	// used.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// URLPath overrides the path used for the "http/protobuf" protocol.
+	URLPath string `yaml:"url_path,omitempty" json:"url_path,omitempty"`
+	// Insecure disables TLS when talking to the collector.
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+}
+
+func (o *OTLP) lint() (ws []Warning, err error) {
+	switch o.Protocol {
+	case "", "grpc":
+		if o.URLPath != "" {
+			ws = append(ws, Warning{
+				path: ".url_path",
+				msg:  `ignored for the "grpc" protocol`,
+			})
+		}
+	case "http/protobuf":
+	default:
+		return nil, fmt.Errorf("unrecognized otlp protocol: %q", o.Protocol)
+	}
+	return ws, nil
 }
 
 // Jaeger specific distributed tracing configuration.
@@ -69,3 +128,140 @@ type Prometheus struct {
 	// Endpoint is a URL path where Prometheus metrics will be hosted.
 	Endpoint *string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
 }
+
+// ProfileWatchdog configures automatic capture of profiles when the process is
+// under resource pressure.
+//
+// When the heap or goroutine count crosses its threshold, heap and goroutine
+// profiles are written to "Directory". At least one threshold should be set.
+type ProfileWatchdog struct {
+	// Directory to write profiles to. Required.
+	Directory string `yaml:"directory" json:"directory"`
+	// HeapThreshold is the size of the live heap, in bytes, that triggers a
+	// capture. If 0, heap size is not checked.
+	HeapThreshold int64 `yaml:"heap_threshold,omitempty" json:"heap_threshold,omitempty"`
+	// GoroutineThreshold is the number of goroutines that triggers a capture.
+	// If 0, the goroutine count is not checked.
+	GoroutineThreshold int `yaml:"goroutine_threshold,omitempty" json:"goroutine_threshold,omitempty"`
+	// Interval is how often the thresholds are checked.
+	Interval Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Cooldown is the minimum time between captures.
+	Cooldown Duration `yaml:"cooldown,omitempty" json:"cooldown,omitempty"`
+	// Retain is the number of captures to keep. Older captures are removed.
+	Retain int `yaml:"retain,omitempty" json:"retain,omitempty"`
+}
+
+func (w *ProfileWatchdog) validate(_ Mode) ([]Warning, error) {
+	if w.Directory == "" {
+		return nil, errors.New("profile watchdog directory must be provided")
+	}
+	if w.Interval <= 0 {
+		w.Interval = Duration(DefaultProfileWatchdogInterval)
+	}
+	if w.Cooldown <= 0 {
+		w.Cooldown = Duration(DefaultProfileWatchdogCooldown)
+	}
+	if w.Retain <= 0 {
+		w.Retain = DefaultProfileWatchdogRetain
+	}
+	return w.lint()
+}
+
+func (w *ProfileWatchdog) lint() (ws []Warning, err error) {
+	if w.HeapThreshold <= 0 && w.GoroutineThreshold <= 0 {
+		ws = append(ws, Warning{
+			msg: "no thresholds set: profiles will never be captured",
+		})
+	}
+	return ws, nil
+}
+
+// QueryStats configures instrumentation of the database connections used by
+// the indexer, matcher, and notifier.
+//
+// Query latencies are recorded in a histogram labeled by pool and by a
+// fingerprint of the query text. Read queries slower than the threshold have
+// their plan captured with EXPLAIN and logged; this doesn't require the
+// "auto_explain" module or any elevated privileges.
+type QueryStats struct {
+	// ExplainThreshold is the latency above which a read query's plan is
+	// captured. If 0, plans are never captured.
+	ExplainThreshold Duration `yaml:"explain_threshold,omitempty" json:"explain_threshold,omitempty"`
+	// ExplainCooldown is the minimum time between plan captures for the same
+	// query.
+	ExplainCooldown Duration `yaml:"explain_cooldown,omitempty" json:"explain_cooldown,omitempty"`
+}
+
+func (q *QueryStats) validate(_ Mode) ([]Warning, error) {
+	if q.ExplainThreshold < 0 {
+		return nil, errors.New("query stats: explain_threshold must not be negative")
+	}
+	if q.ExplainCooldown <= 0 {
+		q.ExplainCooldown = Duration(DefaultExplainCooldown)
+	}
+	return q.lint()
+}
+
+func (q *QueryStats) lint() (ws []Warning, err error) {
+	if q.ExplainThreshold > 0 && q.ExplainThreshold < Duration(100*time.Millisecond) {
+		ws = append(ws, Warning{
+			path: ".explain_threshold",
+			msg:  "threshold is very low: plans will be captured for routine queries",
+		})
+	}
+	return ws, nil
+}
+
+// Introspection configures access to the introspection server.
+//
+// The introspection server exposes metrics and profiling endpoints, so
+// deployments that can't firewall its port should require authentication.
+// If neither a client CA nor a PSK is configured, every endpoint is served
+// without authentication.
+type Introspection struct {
+	// TLS configures HTTPS for the introspection server.
+	//
+	// If "client_ca" is set, a request presenting a client certificate signed
+	// by one of the CAs is authenticated. Unlike the API server, clients
+	// without a certificate can still connect, so that the unauthenticated
+	// endpoints remain reachable.
+	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// PSK configures authentication using JWTs signed with a pre-shared key.
+	//
+	// This should be a different key than the one used for the API.
+	PSK *AuthPSK `yaml:"psk,omitempty" json:"psk,omitempty"`
+	// Unauthenticated is a list of paths served without authentication. If
+	// unset, the health and readiness endpoints are not authenticated.
+	Unauthenticated []string `yaml:"unauthenticated,omitempty" json:"unauthenticated,omitempty"`
+}
+
+func (i *Introspection) validate(_ Mode) ([]Warning, error) {
+	if i.TLS != nil && i.TLS.Cert == "" {
+		return nil, errors.New("introspection tls requires a cert and key")
+	}
+	if i.Unauthenticated == nil {
+		i.Unauthenticated = []string{DefaultIntrospectionHealthPath, DefaultIntrospectionReadyPath}
+	}
+	for _, p := range i.Unauthenticated {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("introspection: unauthenticated path %q must be absolute", p)
+		}
+	}
+	return i.lint()
+}
+
+func (i *Introspection) lint() (ws []Warning, err error) {
+	mtls := i.TLS != nil && i.TLS.ClientCA != ""
+	if !mtls && i.PSK == nil {
+		ws = append(ws, Warning{
+			msg: "no authentication configured: all endpoints are unauthenticated",
+		})
+	}
+	if i.PSK != nil && i.TLS == nil {
+		ws = append(ws, Warning{
+			path: ".psk",
+			msg:  "tokens will be sent over plaintext connections",
+		})
+	}
+	return ws, nil
+}
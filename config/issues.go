@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// Issues configures the notifier to open and update issues in an issue
+// tracker for the vulnerabilities mentioned in notifications.
+//
+// Exactly one of the trackers must be provided.
+type Issues struct {
+	// Configures opening Jira issues.
+	Jira *IssuesJira `yaml:"jira,omitempty" json:"jira,omitempty"`
+	// Configures opening DefectDojo findings.
+	DefectDojo *IssuesDefectDojo `yaml:"defectdojo,omitempty" json:"defectdojo,omitempty"`
+	// The manifest label naming the image a manifest belongs to.
+	//
+	// Issues are deduplicated by vulnerability and image, so every manifest
+	// with the same label value shares an issue for a given vulnerability.
+	// If empty, the default of "repository" is used. Manifests without the
+	// label are treated as their own image, named by the manifest digest.
+	ImageLabel string `yaml:"image_label,omitempty" json:"image_label,omitempty"`
+	// A Go text/template for the issue title.
+	//
+	// If empty, DefaultIssuesTitle is used.
+	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+	// A Go text/template for the issue description.
+	//
+	// If empty, DefaultIssuesDescription is used.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+func (i *Issues) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	switch {
+	case i.Jira == nil && i.DefectDojo == nil:
+		return nil, errors.New("one of jira or defectdojo is required")
+	case i.Jira != nil && i.DefectDojo != nil:
+		return nil, errors.New("only one of jira or defectdojo may be provided")
+	}
+	if i.ImageLabel == "" {
+		i.ImageLabel = DefaultIssuesImageLabel
+	}
+	if i.Title == "" {
+		i.Title = DefaultIssuesTitle
+	}
+	if i.Description == "" {
+		i.Description = DefaultIssuesDescription
+	}
+	for _, t := range []struct {
+		name, text string
+	}{
+		{"title", i.Title},
+		{"description", i.Description},
+	} {
+		if _, err := template.New(t.name).Parse(t.text); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", t.name, err)
+		}
+	}
+	return nil, nil
+}
+
+// IssuesJira configures opening Jira issues.
+type IssuesJira struct {
+	// The base URL of the Jira server.
+	URL string `yaml:"url" json:"url"`
+	// The key of the project issues are opened in.
+	Project string `yaml:"project" json:"project"`
+	// The name of the issue type to open.
+	// If empty, the default of "Bug" is used.
+	IssueType string `yaml:"issue_type,omitempty" json:"issue_type,omitempty"`
+	// The user to authenticate as, with Token as the password. This is
+	// needed for Jira Cloud API tokens.
+	// If empty, Token is used as a bearer token, as for Jira Server and Data
+	// Center personal access tokens.
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+	// An API token or personal access token.
+	Token string `yaml:"token" json:"token"`
+}
+
+func (j *IssuesJira) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if err := checkTrackerURL(j.URL); err != nil {
+		return nil, err
+	}
+	switch {
+	case j.Project == "":
+		return nil, errors.New("project is required")
+	case j.Token == "":
+		return nil, errors.New("token is required")
+	}
+	if j.IssueType == "" {
+		j.IssueType = DefaultIssuesJiraIssueType
+	}
+	return j.lint()
+}
+
+func (j *IssuesJira) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(j.URL); err == nil && u.Scheme == "http" {
+		ws = append(ws, Warning{
+			path: ".url",
+			msg:  "token will be sent unencrypted",
+		})
+	}
+	return ws, nil
+}
+
+// IssuesDefectDojo configures opening DefectDojo findings.
+type IssuesDefectDojo struct {
+	// The base URL of the DefectDojo server.
+	URL string `yaml:"url" json:"url"`
+	// An API v2 key.
+	APIKey string `yaml:"api_key" json:"api_key"`
+	// The ID of the test findings are opened in.
+	Test int `yaml:"test" json:"test"`
+	// The IDs of the test types recorded as having found the findings.
+	FoundBy []int `yaml:"found_by,omitempty" json:"found_by,omitempty"`
+}
+
+func (d *IssuesDefectDojo) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if err := checkTrackerURL(d.URL); err != nil {
+		return nil, err
+	}
+	switch {
+	case d.APIKey == "":
+		return nil, errors.New("api_key is required")
+	case d.Test <= 0:
+		return nil, errors.New("test is required")
+	}
+	return d.lint()
+}
+
+func (d *IssuesDefectDojo) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(d.URL); err == nil && u.Scheme == "http" {
+		ws = append(ws, Warning{
+			path: ".url",
+			msg:  "API key will be sent unencrypted",
+		})
+	}
+	if len(d.FoundBy) == 0 {
+		ws = append(ws, Warning{
+			path: ".found_by",
+			msg:  "some DefectDojo versions require at least one test type",
+		})
+	}
+	return ws, nil
+}
+
+// CheckTrackerURL reports an error if "u" isn't an absolute URL.
+func checkTrackerURL(u string) error {
+	p, err := url.Parse(u)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to parse url: %w", err)
+	case !p.IsAbs() || p.Host == "":
+		return fmt.Errorf("url %q must be absolute", u)
+	}
+	return nil
+}
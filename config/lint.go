@@ -53,6 +53,10 @@ func (w *Warning) Error() string {
 
 func (w *Warning) Unwrap() error { return w.inner }
 
+// Path reports the json-schema style path of the setting the Warning is
+// about.
+func (w *Warning) Path() string { return w.path }
+
 // These are some common kinds of Warnings.
 var (
 	ErrDeprecated = errors.New("setting will be removed in a future release")
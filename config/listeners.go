@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// These prefixes mark listen addresses that aren't <host>:<port> pairs.
+const (
+	// UnixPrefix marks the remainder of the address as a Unix domain socket
+	// path.
+	UnixPrefix = "unix:"
+	// SystemdPrefix marks the remainder of the address as the name of a
+	// socket passed by systemd socket activation, as set by
+	// FileDescriptorName= in the socket unit.
+	SystemdPrefix = "systemd:"
+)
+
+// Listeners configures separate listeners for individual services.
+//
+// A service with an address configured here is served only on that address
+// instead of the "http_listen_addr". Services without an address continue to
+// be served on the "http_listen_addr".
+//
+// Listeners are only used in combo mode.
+type Listeners struct {
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
+	//
+	// Exposes the Indexer API.
+	Indexer string `yaml:"indexer,omitempty" json:"indexer,omitempty"`
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
+	//
+	// Exposes the Matcher API.
+	Matcher string `yaml:"matcher,omitempty" json:"matcher,omitempty"`
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
+	//
+	// Exposes the Notifier API.
+	Notifier string `yaml:"notifier,omitempty" json:"notifier,omitempty"`
+}
+
+func (l *Listeners) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode {
+		return []Warning{{
+			msg: "listeners are only used in combo mode",
+		}}, nil
+	}
+	for _, a := range []struct {
+		name, addr string
+	}{
+		{"indexer", l.Indexer},
+		{"matcher", l.Matcher},
+		{"notifier", l.Notifier},
+	} {
+		if a.addr == "" {
+			continue
+		}
+		if err := checkListenAddr(a.addr); err != nil {
+			return nil, fmt.Errorf("%s listener: %w", a.name, err)
+		}
+	}
+	return l.lint()
+}
+
+func (l *Listeners) lint() (ws []Warning, err error) {
+	seen := make(map[string]string)
+	for _, a := range []struct {
+		name, addr string
+	}{
+		{"indexer", l.Indexer},
+		{"matcher", l.Matcher},
+		{"notifier", l.Notifier},
+	} {
+		if a.addr == "" {
+			continue
+		}
+		if prev, ok := seen[a.addr]; ok {
+			ws = append(ws, Warning{
+				path: "." + a.name,
+				msg:  fmt.Sprintf("address is the same as the %s listener", prev),
+			})
+		}
+		seen[a.addr] = a.name
+	}
+	return ws, nil
+}
+
+// CheckListenAddr reports whether "addr" is usable as a listen address.
+func checkListenAddr(addr string) error {
+	for _, pfx := range []string{UnixPrefix, SystemdPrefix} {
+		if strings.HasPrefix(addr, pfx) {
+			if addr == pfx {
+				return fmt.Errorf("%q: missing socket name", addr)
+			}
+			return nil
+		}
+	}
+	_, _, err := net.SplitHostPort(addr)
+	return err
+}
+
+// CheckServiceAddr reports whether "addr" is usable as the address of another
+// Clair service: either a URL or "unix:" followed by a socket path.
+func checkServiceAddr(addr string) error {
+	if p := strings.TrimPrefix(addr, UnixPrefix); p != addr {
+		if p == "" {
+			return fmt.Errorf("%q: missing socket path", addr)
+		}
+		return nil
+	}
+	_, err := url.Parse(addr)
+	return err
+}
+
+// UnixSocket configures the Unix domain sockets Clair listens on.
+type UnixSocket struct {
+	// Mode is the permission bits for sockets, as an octal string such as
+	// "0660". If unset, the process's umask determines the permissions.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// Group is the name or numeric ID of the group to own sockets. If unset,
+	// sockets are owned by the process's group.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
+}
+
+// FileMode returns the permission bits described by Mode, or 0 if Mode is
+// unset or invalid.
+func (u *UnixSocket) FileMode() fs.FileMode {
+	m, err := parseSocketMode(u.Mode)
+	if err != nil {
+		return 0
+	}
+	return m
+}
+
+func parseSocketMode(s string) (fs.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("unix socket: bad mode %q: must be octal permission bits", s)
+	}
+	return fs.FileMode(m), nil
+}
+
+func (u *UnixSocket) validate(_ Mode) ([]Warning, error) {
+	if _, err := parseSocketMode(u.Mode); err != nil {
+		return nil, err
+	}
+	return u.lint()
+}
+
+func (u *UnixSocket) lint() (ws []Warning, err error) {
+	m := u.FileMode()
+	if m&0o002 != 0 {
+		ws = append(ws, Warning{
+			path: ".mode",
+			msg:  "sockets will be writable by any user",
+		})
+	}
+	if u.Mode != "" && m&0o600 != 0o600 {
+		ws = append(ws, Warning{
+			path: ".mode",
+			msg:  "sockets will not be usable by the owner",
+		})
+	}
+	return ws, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LogOutput configures where logs are written.
+//
+// By default, logs are written to stderr. At most one member may be set.
+type LogOutput struct {
+	// File writes logs to a file, rotating it as needed.
+	File *LogFile `yaml:"file,omitempty" json:"file,omitempty"`
+	// Syslog writes logs to a syslog daemon.
+	Syslog *LogSyslog `yaml:"syslog,omitempty" json:"syslog,omitempty"`
+}
+
+func (o *LogOutput) validate(_ Mode) ([]Warning, error) {
+	if o.File != nil && o.Syslog != nil {
+		return nil, errors.New("only one of file or syslog may be configured")
+	}
+	return nil, nil
+}
+
+// LogFile configures writing logs to a file.
+type LogFile struct {
+	// The path to write logs to. Required.
+	Path string `yaml:"path" json:"path"`
+	// The size, in megabytes, a file may reach before it's rotated.
+	//
+	// If 0, the default of 100 is used.
+	MaxSize int `yaml:"max_size,omitempty" json:"max_size,omitempty"`
+	// The number of rotated files to keep.
+	//
+	// If 0, all files are kept, subject to "MaxAge".
+	MaxBackups int `yaml:"max_backups,omitempty" json:"max_backups,omitempty"`
+	// The number of days to keep rotated files.
+	//
+	// If 0, files are not removed based on age.
+	MaxAge int `yaml:"max_age,omitempty" json:"max_age,omitempty"`
+	// Compress rotated files with gzip.
+	Compress bool `yaml:"compress,omitempty" json:"compress,omitempty"`
+}
+
+func (f *LogFile) validate(_ Mode) ([]Warning, error) {
+	if f.Path == "" {
+		return nil, errors.New("log file path must be provided")
+	}
+	return f.lint()
+}
+
+func (f *LogFile) lint() (ws []Warning, _ error) {
+	if f.MaxSize < 0 || f.MaxBackups < 0 || f.MaxAge < 0 {
+		ws = append(ws, Warning{
+			msg: "negative values are treated as 0",
+		})
+	}
+	return ws, nil
+}
+
+// LogSyslog configures writing logs to a syslog daemon.
+type LogSyslog struct {
+	// The network to use to connect to the daemon: "udp", "tcp", or "unix".
+	//
+	// If unset along with "Address", the local syslog daemon is used.
+	Network string `yaml:"network,omitempty" json:"network,omitempty"`
+	// The address of the syslog daemon.
+	Address string `yaml:"address,omitempty" json:"address,omitempty"`
+	// The tag to use for messages. If unset, "clair" is used.
+	Tag string `yaml:"tag,omitempty" json:"tag,omitempty"`
+}
+
+func (s *LogSyslog) validate(_ Mode) ([]Warning, error) {
+	switch s.Network {
+	case "", "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unknown syslog network: %q", s.Network)
+	}
+	if (s.Network == "") != (s.Address == "") {
+		return nil, errors.New("syslog network and address must be set together")
+	}
+	return nil, nil
+}
@@ -2,7 +2,8 @@
 
 import (
 	"fmt"
-	"net/url"
+	"strings"
+	"time"
 )
 
 // Matcher is the configuration for the matcher service.
@@ -31,6 +32,9 @@ type Matcher struct {
 	//
 	// A value of 0 disables GC.
 	UpdateRetention int `yaml:"update_retention" json:"update_retention"`
+	// GC configures the policy engine for garbage collecting update
+	// operations. If unset, only "update_retention" applies.
+	GC *MatcherGC `yaml:"gc,omitempty" json:"gc,omitempty"`
 	// A positive integer
 	//
 	// Clair allows for a custom connection pool size.  This number will
@@ -57,6 +61,323 @@ type Matcher struct {
 	// This should be toggled on if vulnerabilities are being provided by
 	// another mechanism.
 	DisableUpdaters bool `yaml:"disable_updaters,omitempty" json:"disable_updaters,omitempty"`
+	// LeaderElection restricts running updaters to a single Matcher process
+	// at a time, elected using a lock in the database.
+	//
+	// If the elected process exits or loses its database connection, another
+	// process takes over.
+	LeaderElection bool `yaml:"leader_election,omitempty" json:"leader_election,omitempty"`
+	// ScanConcurrency is the maximum number of vulnerability reports this
+	// process builds at once. Each report already runs its matchers in
+	// parallel, so this bounds CPU and memory use under load. Requests over
+	// the limit wait for a slot.
+	//
+	// A value of 0 means "unlimited."
+	ScanConcurrency int `yaml:"scan_concurrency,omitempty" json:"scan_concurrency,omitempty"`
+	// Limits sets per-request limits for building vulnerability reports.
+	Limits MatcherLimits `yaml:"limits,omitempty" json:"limits,omitempty"`
+	// Freshness configures alarms for updaters that haven't successfully
+	// updated recently. If unset, updater status is reported but never
+	// considered stale.
+	Freshness *MatcherFreshness `yaml:"freshness,omitempty" json:"freshness,omitempty"`
+	// Trends, if provided, records per-severity finding counts each time a
+	// manifest's vulnerability report is built, so they can be charted over
+	// time.
+	Trends *MatcherTrends `yaml:"trends,omitempty" json:"trends,omitempty"`
+	// Changelog, if provided, records an event log for each manifest of the
+	// changes in its vulnerability report: when it was indexed or
+	// re-indexed, and the findings gained and lost.
+	Changelog *MatcherChangelog `yaml:"changelog,omitempty" json:"changelog,omitempty"`
+	// Subscriptions enables scheduled re-scan subscriptions, which re-match
+	// a manifest periodically and send the resulting vulnerability report to
+	// a callback URL. If unset, subscriptions can't be created.
+	Subscriptions *MatcherSubscriptions `yaml:"subscriptions,omitempty" json:"subscriptions,omitempty"`
+	// Backfill enables re-match backfills, which re-run matching across all
+	// indexed manifests after a matcher logic change. If unset, backfills
+	// can't be started.
+	Backfill *MatcherBackfill `yaml:"backfill,omitempty" json:"backfill,omitempty"`
+	// Harbor, if provided, serves the Harbor Scanner Adapter API on its own
+	// listener, so Harbor can use Clair as a scanner.
+	Harbor *MatcherHarbor `yaml:"harbor,omitempty" json:"harbor,omitempty"`
+	// BaseImages lists the manifest digests of base images. Findings in
+	// vulnerability reports are attributed to a base image if the layer that
+	// introduced the package is one that introduced packages in any of
+	// these manifests, which must be indexed.
+	BaseImages []string `yaml:"base_images,omitempty" json:"base_images,omitempty"`
+	// Vulnstore, if provided, has this matcher query another matcher for
+	// vulnerability data instead of keeping a database of its own. Only
+	// supported in matcher mode.
+	Vulnstore *MatcherVulnstore `yaml:"vulnstore,omitempty" json:"vulnstore,omitempty"`
+}
+
+// MatcherVulnstore is the configuration for querying a central matcher's
+// vulnerability store.
+type MatcherVulnstore struct {
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// or "unix:" followed by a socket path.
+	//
+	// Addr is the address of the matcher to query. That matcher runs the
+	// updaters and answers queries from its own database.
+	Addr string `yaml:"addr" json:"addr"`
+}
+
+func (v *MatcherVulnstore) validate(mode Mode) ([]Warning, error) {
+	switch mode {
+	case MatcherMode:
+	case ComboMode:
+		return nil, fmt.Errorf("vulnstore: only supported in matcher mode")
+	default:
+		return nil, nil
+	}
+	if v.Addr == "" {
+		return nil, fmt.Errorf(`vulnstore: "addr" is required`)
+	}
+	if err := checkServiceAddr(v.Addr); err != nil {
+		return nil, fmt.Errorf("vulnstore: addr: %w", err)
+	}
+	return nil, nil
+}
+
+// MatcherHarbor is the configuration for the Harbor Scanner Adapter API.
+type MatcherHarbor struct {
+	// A string in <host>:<port> format where <host> can be an empty string,
+	// "unix:" followed by a socket path, or "systemd:" followed by the name of
+	// an activated socket.
+	ListenAddr string `yaml:"listen_addr" json:"listen_addr"`
+	// Secret, if provided, must be presented by Harbor, either as a bearer
+	// token or as an API key. Configure the scanner registration in Harbor
+	// with the "Bearer" or "APIKey" authorization type and this value.
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+	// Registries, if provided, are the only registry hosts artifacts are
+	// accepted from.
+	Registries []string `yaml:"registries,omitempty" json:"registries,omitempty"`
+}
+
+func (h *MatcherHarbor) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if h.ListenAddr == "" {
+		return nil, fmt.Errorf(`harbor: "listen_addr" is required`)
+	}
+	if err := checkListenAddr(h.ListenAddr); err != nil {
+		return nil, fmt.Errorf("harbor: listen_addr: %w", err)
+	}
+	return h.lint()
+}
+
+func (h *MatcherHarbor) lint() (ws []Warning, err error) {
+	if h.Secret == "" {
+		ws = append(ws, Warning{
+			path: ".secret",
+			msg:  `anyone who can reach the listener can request scans`,
+		})
+	}
+	if len(h.Registries) == 0 {
+		ws = append(ws, Warning{
+			path: ".registries",
+			msg:  `artifacts will be fetched from any registry named in a scan request`,
+		})
+	}
+	return ws, nil
+}
+
+// MatcherSubscriptions is the configuration for scheduled re-scan
+// subscriptions.
+type MatcherSubscriptions struct {
+	// A time.ParseDuration parsable string
+	//
+	// MinInterval is the shortest interval a subscription may request.
+	//
+	// The default is 1 hour.
+	MinInterval Duration `yaml:"min_interval,omitempty" json:"min_interval,omitempty"`
+	// AllowedHosts restricts the hosts callbacks may be sent to.
+	//
+	// Entries are hostnames or IP addresses, or "*." followed by a domain to
+	// allow any subdomain. If empty, any host is allowed.
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty" json:"allowed_hosts,omitempty"`
+	// AllowedNetworks restricts the addresses callbacks may be sent to.
+	//
+	// Entries are CIDR blocks or single IP addresses. If empty, any address
+	// is allowed except link-local, multicast, and cloud metadata addresses.
+	AllowedNetworks []string `yaml:"allowed_networks,omitempty" json:"allowed_networks,omitempty"`
+}
+
+func (s *MatcherSubscriptions) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if s.MinInterval <= 0 {
+		s.MinInterval = Duration(DefaultSubscriptionMinInterval)
+	}
+	if err := checkAllowed(s.AllowedHosts, s.AllowedNetworks); err != nil {
+		return nil, fmt.Errorf("subscriptions: %w", err)
+	}
+	return s.lint()
+}
+
+func (s *MatcherSubscriptions) lint() (ws []Warning, err error) {
+	if len(s.AllowedHosts) == 0 && len(s.AllowedNetworks) == 0 {
+		ws = append(ws, Warning{
+			msg: "callbacks may be sent to any host: consider setting allowed_hosts or allowed_networks",
+		})
+	}
+	return ws, nil
+}
+
+// MatcherBackfill is the configuration for re-match backfills.
+type MatcherBackfill struct {
+	// Rate is the default number of manifests re-matched per second, used
+	// when a backfill is started without one.
+	//
+	// The default is 10.
+	Rate int `yaml:"rate,omitempty" json:"rate,omitempty"`
+	// ChunkSize is the default number of manifests re-matched between
+	// saves of a backfill's progress, used when a backfill is started
+	// without one.
+	//
+	// The default is 100.
+	ChunkSize int `yaml:"chunk_size,omitempty" json:"chunk_size,omitempty"`
+}
+
+func (b *MatcherBackfill) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	switch {
+	case b.Rate < 0:
+		return nil, fmt.Errorf("backfill: rate %d is negative", b.Rate)
+	case b.Rate == 0:
+		b.Rate = DefaultBackfillRate
+	}
+	switch {
+	case b.ChunkSize < 0:
+		return nil, fmt.Errorf("backfill: chunk_size %d is negative", b.ChunkSize)
+	case b.ChunkSize == 0:
+		b.ChunkSize = DefaultBackfillChunkSize
+	}
+	return nil, nil
+}
+
+// MatcherFreshness is the configuration for detecting stale vulnerability
+// data.
+type MatcherFreshness struct {
+	// A time.ParseDuration parsable string
+	//
+	// StaleAfter is how long after an updater's last successful run its data
+	// is considered stale. Required.
+	StaleAfter Duration `yaml:"stale_after" json:"stale_after"`
+	// Readiness causes the matcher to report itself as not ready while any
+	// updater is stale.
+	Readiness bool `yaml:"readiness,omitempty" json:"readiness,omitempty"`
+}
+
+func (f *MatcherFreshness) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if f.StaleAfter <= 0 {
+		return nil, fmt.Errorf("freshness: stale_after must be positive")
+	}
+	return nil, nil
+}
+
+// MatcherTrends is the configuration for recording finding trends.
+type MatcherTrends struct {
+	// A time.ParseDuration parsable string
+	//
+	// Retention is how long recorded counts are kept.
+	//
+	// The default is 90 days.
+	Retention Duration `yaml:"retention,omitempty" json:"retention,omitempty"`
+}
+
+func (t *MatcherTrends) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if t.Retention == 0 {
+		t.Retention = Duration(DefaultMatcherTrendsRetention)
+	}
+	return t.lint()
+}
+
+func (t *MatcherTrends) lint() (ws []Warning, err error) {
+	if t.Retention < 0 {
+		return nil, fmt.Errorf("trends: retention must not be negative")
+	}
+	if t.Retention != 0 && t.Retention < Duration(24*time.Hour) {
+		ws = append(ws, Warning{
+			path: ".retention",
+			msg:  `retention under a day may not show a useful trend`,
+		})
+	}
+	return ws, nil
+}
+
+// MatcherChangelog is the configuration for recording manifest changelogs.
+type MatcherChangelog struct {
+	// A time.ParseDuration parsable string
+	//
+	// Retention is how long events are kept. A manifest whose report isn't
+	// built for this long starts a new log.
+	//
+	// The default is 365 days.
+	Retention Duration `yaml:"retention,omitempty" json:"retention,omitempty"`
+}
+
+func (c *MatcherChangelog) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != MatcherMode {
+		return nil, nil
+	}
+	if c.Retention == 0 {
+		c.Retention = Duration(DefaultMatcherChangelogRetention)
+	}
+	return c.lint()
+}
+
+func (c *MatcherChangelog) lint() (ws []Warning, err error) {
+	if c.Retention < 0 {
+		return nil, fmt.Errorf("changelog: retention must not be negative")
+	}
+	if c.Retention != 0 && c.Retention < Duration(7*24*time.Hour) {
+		ws = append(ws, Warning{
+			path: ".retention",
+			msg:  `retention under a week loses the history of rarely scanned manifests`,
+		})
+	}
+	return ws, nil
+}
+
+// MatcherLimits is the configuration for rejecting vulnerability report
+// requests that would use too many resources.
+//
+// A value of 0 for any member means "unlimited."
+type MatcherLimits struct {
+	// MaxReportMemory is the approximate number of bytes a single
+	// vulnerability report, including the index report it's built from, may
+	// occupy in memory. Requests over this limit are rejected.
+	MaxReportMemory int64 `yaml:"max_report_memory,omitempty" json:"max_report_memory,omitempty"`
+	// MaxBatchReports is the maximum number of manifests a single batch
+	// vulnerability report request may name. Requests over this limit are
+	// rejected.
+	MaxBatchReports int `yaml:"max_batch_reports,omitempty" json:"max_batch_reports,omitempty"`
+}
+
+func (l *MatcherLimits) lint() (ws []Warning, err error) {
+	if l.MaxReportMemory < 0 {
+		ws = append(ws, Warning{
+			path: ".max_report_memory",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	if l.MaxBatchReports < 0 {
+		ws = append(ws, Warning{
+			path: ".max_batch_reports",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	return ws, nil
 }
 
 func (m *Matcher) validate(mode Mode) ([]Warning, error) {
@@ -83,23 +404,47 @@ func (m *Matcher) validate(mode Mode) ([]Warning, error) {
 		if m.IndexerAddr == "" {
 			return nil, fmt.Errorf("matcher mode requires a remote Indexer address")
 		}
-		_, err := url.Parse(m.IndexerAddr)
-		if err != nil {
+		if err := checkServiceAddr(m.IndexerAddr); err != nil {
 			return nil, fmt.Errorf("failed to parse matcher mode IndexerAddr string: %v", err)
 		}
 	default:
 		panic("programmer error")
 	}
+	for _, d := range m.BaseImages {
+		if i := strings.IndexByte(d, ':'); i < 1 || i == len(d)-1 {
+			return nil, fmt.Errorf("base_images: %q is not a digest", d)
+		}
+	}
+	if m.Vulnstore != nil {
+		// These all keep their state in the matcher's database.
+		switch {
+		case m.GC != nil:
+			return nil, fmt.Errorf(`vulnstore: "gc" requires a matcher database`)
+		case m.Trends != nil:
+			return nil, fmt.Errorf(`vulnstore: "trends" requires a matcher database`)
+		case m.Subscriptions != nil:
+			return nil, fmt.Errorf(`vulnstore: "subscriptions" requires a matcher database`)
+		case m.Backfill != nil:
+			return nil, fmt.Errorf(`vulnstore: "backfill" requires a matcher database`)
+		case m.Freshness != nil:
+			return nil, fmt.Errorf(`vulnstore: "freshness" requires a matcher database`)
+		case m.Changelog != nil:
+			return nil, fmt.Errorf(`vulnstore: "changelog" requires a matcher database`)
+		}
+	}
 	return m.lint()
 }
 
 func (m *Matcher) lint() (ws []Warning, err error) {
-	ws, err = checkDSN(m.ConnString)
-	if err != nil {
-		return ws, err
-	}
-	for i := range ws {
-		ws[i].path = ".connstring"
+	// A matcher querying a vulnstore doesn't connect to a database.
+	if m.Vulnstore == nil {
+		ws, err = checkDSN(m.ConnString)
+		if err != nil {
+			return ws, err
+		}
+		for i := range ws {
+			ws[i].path = ".connstring"
+		}
 	}
 
 	if m.Period < Duration(DefaultMatcherPeriod) {
@@ -126,6 +471,18 @@ func (m *Matcher) lint() (ws []Warning, err error) {
 			msg:  "this parameter will be ignored in a future release",
 		})
 	}
+	if m.ScanConcurrency < 0 {
+		ws = append(ws, Warning{
+			path: ".scan_concurrency",
+			msg:  `negative values are treated as "unlimited"`,
+		})
+	}
+	if f := m.Freshness; f != nil && f.StaleAfter > 0 && f.StaleAfter < m.Period {
+		ws = append(ws, Warning{
+			path: ".freshness.stale_after",
+			msg:  "shorter than the updater period: data will be reported stale between runs",
+		})
+	}
 
 	return ws, nil
 }
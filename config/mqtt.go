@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// MQTT configures the notifier to publish notifications to an MQTT 5 broker.
+//
+// Every notification is published as its own message, with QoS 1, to a topic
+// built from the notification.
+type MQTT struct {
+	// The broker to connect to, as a URL.
+	//
+	// The scheme must be "mqtt" or "tcp" for plain connections, or "mqtts"
+	// or "ssl" for TLS. If the port is omitted, 1883 or 8883 is used.
+	Broker string `yaml:"broker" json:"broker"`
+	// Optional TLS configuration, used for "mqtts" and "ssl" brokers.
+	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// The client identifier to connect with.
+	//
+	// If empty, the broker assigns one. Notifier processes must not share a
+	// client identifier: the broker disconnects the older connection.
+	ClientID string `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+	// Optional credentials to connect with.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// A Go text/template for the topic a notification is published to. It's
+	// executed with the notification, so the severity and manifest digest
+	// are available as ".Vulnerability.Severity" and ".Manifest".
+	//
+	// If empty, DefaultMQTTTopic is used.
+	Topic string `yaml:"topic,omitempty" json:"topic,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The timeout for connecting to the broker, including the TLS and MQTT
+	// handshakes. If 0, DefaultBrokerDialTimeout is used.
+	DialTimeout Duration `yaml:"dial_timeout,omitempty" json:"dial_timeout,omitempty"`
+	// Serializer selects how messages are encoded. If nil, they're JSON.
+	Serializer *Serializer `yaml:"serializer,omitempty" json:"serializer,omitempty"`
+}
+
+func (m *MQTT) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	u, err := url.Parse(m.Broker)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to parse broker: %w", err)
+	case u.Host == "":
+		return nil, fmt.Errorf("broker %q must include a host", m.Broker)
+	}
+	switch u.Scheme {
+	case "mqtt", "tcp", "mqtts", "ssl":
+	default:
+		return nil, fmt.Errorf("broker %q: unknown scheme %q", m.Broker, u.Scheme)
+	}
+	if m.Password != "" && m.Username == "" {
+		return nil, errors.New("password requires a username")
+	}
+	if m.Topic == "" {
+		m.Topic = DefaultMQTTTopic
+	}
+	if _, err := template.New("topic").Parse(m.Topic); err != nil {
+		return nil, fmt.Errorf("invalid topic template: %w", err)
+	}
+	if m.DialTimeout <= 0 {
+		m.DialTimeout = Duration(DefaultBrokerDialTimeout)
+	}
+	return m.lint()
+}
+
+func (m *MQTT) lint() (ws []Warning, err error) {
+	u, err := url.Parse(m.Broker)
+	if err != nil {
+		return nil, nil
+	}
+	plain := u.Scheme == "mqtt" || u.Scheme == "tcp"
+	if plain && m.Password != "" {
+		ws = append(ws, Warning{
+			path: ".password",
+			msg:  "password will be sent unencrypted",
+		})
+	}
+	if plain && m.TLS != nil {
+		ws = append(ws, Warning{
+			path: ".tls",
+			msg:  "tls configured for a plain broker: it will be ignored",
+		})
+	}
+	return ws, nil
+}
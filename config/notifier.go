@@ -20,6 +20,22 @@ type Notifier struct {
 	AMQP *AMQP `yaml:"amqp,omitempty" json:"amqp,omitempty"`
 	// Configures the notifier for STOMP delivery.
 	STOMP *STOMP `yaml:"stomp,omitempty" json:"stomp,omitempty"`
+	// Configures the notifier for MQTT delivery.
+	MQTT *MQTT `yaml:"mqtt,omitempty" json:"mqtt,omitempty"`
+	// Configures the notifier for Azure Service Bus or Event Hubs delivery.
+	Azure *Azure `yaml:"azure,omitempty" json:"azure,omitempty"`
+	// Configures the notifier to send notifications to a syslog collector.
+	Syslog *Syslog `yaml:"syslog,omitempty" json:"syslog,omitempty"`
+	// Configures the notifier to send notifications to a Splunk HTTP Event
+	// Collector.
+	Splunk *Splunk `yaml:"splunk,omitempty" json:"splunk,omitempty"`
+	// Configures the notifier to index notifications into Elasticsearch.
+	Elasticsearch *Elasticsearch `yaml:"elasticsearch,omitempty" json:"elasticsearch,omitempty"`
+	// Configures the notifier to publish affected manifests to
+	// Dependency-Track.
+	DependencyTrack *DependencyTrack `yaml:"dependency_track,omitempty" json:"dependency_track,omitempty"`
+	// Configures the notifier to open and update issues in an issue tracker.
+	Issues *Issues `yaml:"issues,omitempty" json:"issues,omitempty"`
 	// A Postgres connection string.
 	//
 	// Formats:
@@ -43,6 +59,13 @@ type Notifier struct {
 	// If a value smaller then 1 second is provided it will be replaced with the
 	// default 5 second poll interval.
 	PollInterval Duration `yaml:"poll_interval,omitempty" json:"poll_interval,omitempty"`
+	// A Postgres connection string for the Matcher's database.
+	//
+	// If provided, the notifier listens for update operations being recorded
+	// in the Matcher's database and looks for new ones immediately, instead of
+	// waiting for the next poll. Polling continues as a fallback. The Matcher
+	// installs the needed trigger as part of its migrations.
+	MatcherConnString string `yaml:"matcher_connstring,omitempty" json:"matcher_connstring,omitempty"`
 	// A time.ParseDuration parsable string
 	//
 	// The frequency at which the notifier attempt delivery of created or previously failed
@@ -60,10 +83,43 @@ type Notifier struct {
 	// For a machine-consumption use case, it may be easier to instead have the
 	// notifier push all the data.
 	DisableSummary bool `yaml:"disable_summary,omitempty" json:"disable_summary,omitempty"`
+	// Retention configures how long notifications are kept before being
+	// garbage collected.
+	Retention NotifierRetention `yaml:"retention,omitempty" json:"retention,omitempty"`
+	// Backpressure configures how many notifications are attempted per
+	// delivery run and when polling pauses because delivery is falling
+	// behind.
+	Backpressure NotifierBackpressure `yaml:"backpressure,omitempty" json:"backpressure,omitempty"`
+	// Retry configures how long notifications that failed to be delivered
+	// wait before being attempted again.
+	Retry NotifierRetry `yaml:"retry,omitempty" json:"retry,omitempty"`
+	// Throttle configures how the notifier paces its requests for the
+	// manifests affected by an update.
+	Throttle NotifierThrottle `yaml:"throttle,omitempty" json:"throttle,omitempty"`
+	// Pages configures how pages of notifications are assembled for
+	// clients fetching them.
+	Pages NotifierPages `yaml:"pages,omitempty" json:"pages,omitempty"`
 	// A "true" or "false" value
 	//
 	// Whether Notifier nodes handle migrations to their database.
 	Migrations bool `yaml:"migrations,omitempty" json:"migrations,omitempty"`
+	// LeaderElection restricts polling for updates and garbage collection
+	// to a single Notifier process at a time, elected using a lock in the
+	// database.
+	//
+	// If the elected process exits or loses its database connection, another
+	// process takes over. Delivery still happens on every process.
+	LeaderElection bool `yaml:"leader_election,omitempty" json:"leader_election,omitempty"`
+	// A "true" or "false" value
+	//
+	// OrderedDelivery delivers the notification sets mentioning a manifest in
+	// the order they were created, so a consumer never sees a vulnerability
+	// removed before it was added. Sets mentioning different manifests are
+	// still delivered concurrently.
+	//
+	// A set that fails to be delivered holds back every later set sharing
+	// a manifest with it until it's delivered or garbage collected.
+	OrderedDelivery bool `yaml:"ordered_delivery,omitempty" json:"ordered_delivery,omitempty"`
 }
 
 func (n *Notifier) validate(mode Mode) ([]Warning, error) {
@@ -85,6 +141,12 @@ func (n *Notifier) validate(mode Mode) ([]Warning, error) {
 		if n.MatcherAddr == "" {
 			return nil, fmt.Errorf("notifier mode requires a remote Matcher")
 		}
+		if err := checkServiceAddr(n.IndexerAddr); err != nil {
+			return nil, fmt.Errorf("failed to parse notifier mode IndexerAddr string: %v", err)
+		}
+		if err := checkServiceAddr(n.MatcherAddr); err != nil {
+			return nil, fmt.Errorf("failed to parse notifier mode MatcherAddr string: %v", err)
+		}
 	default:
 		panic("programmer error")
 	}
@@ -106,9 +168,30 @@ func (n *Notifier) lint() (ws []Warning, err error) {
 	if n.STOMP != nil {
 		got++
 	}
+	if n.MQTT != nil {
+		got++
+	}
+	if n.Azure != nil {
+		got++
+	}
+	if n.Syslog != nil {
+		got++
+	}
+	if n.Splunk != nil {
+		got++
+	}
+	if n.Elasticsearch != nil {
+		got++
+	}
 	if n.Webhook != nil {
 		got++
 	}
+	if n.DependencyTrack != nil {
+		got++
+	}
+	if n.Issues != nil {
+		got++
+	}
 	switch {
 	case got == 0 && !reflect.ValueOf(n).Elem().IsZero():
 		ws = append(ws, Warning{
@@ -120,6 +203,16 @@ func (n *Notifier) lint() (ws []Warning, err error) {
 		})
 	}
 
+	if n.MatcherConnString != "" {
+		mws, err := checkDSN(n.MatcherConnString)
+		if err != nil {
+			return ws, err
+		}
+		for i := range mws {
+			mws[i].path = ".matcher_connstring"
+		}
+		ws = append(ws, mws...)
+	}
 	if n.PollInterval < Duration(DefaultNotifierPollInterval) {
 		ws = append(ws, Warning{
 			path: ".poll_interval",
@@ -142,6 +235,303 @@ func (n *Notifier) lint() (ws []Warning, err error) {
 	return ws, nil
 }
 
+// NotifierRetention configures garbage collection of notifications.
+//
+// Notifications deleted via the API are always collected.
+type NotifierRetention struct {
+	// A time.ParseDuration parsable string
+	//
+	// Notifications delivered longer than this ago are removed.
+	// If 0, the default of 14 days is used. If negative, delivered
+	// notifications are kept until deleted.
+	Delivered Duration `yaml:"delivered,omitempty" json:"delivered,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// Notifications that have not been delivered and were created or last
+	// attempted longer than this ago are removed.
+	// If 0 or negative, undelivered notifications are kept.
+	Undelivered Duration `yaml:"undelivered,omitempty" json:"undelivered,omitempty"`
+	// The maximum number of notification sets to keep, regardless of
+	// status. The oldest, by creation time, are removed first.
+	// If 0, there is no limit.
+	MaxCount int `yaml:"max_count,omitempty" json:"max_count,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The frequency at which notifications are garbage collected.
+	// If 0, the default of 1 hour is used.
+	Interval Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
+
+func (r *NotifierRetention) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if r.MaxCount < 0 {
+		return nil, fmt.Errorf("max_count must not be negative")
+	}
+	if r.Delivered == 0 {
+		r.Delivered = Duration(DefaultNotifierDeliveredRetention)
+	}
+	if r.Interval <= 0 {
+		r.Interval = Duration(DefaultNotifierGCInterval)
+	}
+	return r.lint()
+}
+
+func (r *NotifierRetention) lint() (ws []Warning, err error) {
+	if r.Undelivered < 0 {
+		ws = append(ws, Warning{
+			path: ".undelivered",
+			msg:  "negative durations keep undelivered notifications",
+		})
+	}
+	if r.Undelivered > 0 && time.Duration(r.Undelivered) < time.Hour {
+		ws = append(ws, Warning{
+			path: ".undelivered",
+			msg:  "short retention may remove notifications before delivery is retried",
+		})
+	}
+	if r.Interval > 0 && time.Duration(r.Interval) < time.Minute {
+		ws = append(ws, Warning{
+			path: ".interval",
+			msg:  "interval is very fast: may result in increased workload",
+		})
+	}
+	return ws, nil
+}
+
+// NotifierBackpressure configures how the notifier slows down when
+// notifications can't be delivered as quickly as they're created.
+type NotifierBackpressure struct {
+	// The maximum number of notification sets attempted in a single delivery
+	// run. Fewer are attempted while deliveries are slow.
+	// If 0, the default of 100 is used. If negative, every pending
+	// notification set is attempted.
+	MaxBatch int `yaml:"max_batch,omitempty" json:"max_batch,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// Deliveries averaging longer than this shrink the delivery batch.
+	// If 0, the default of 5 seconds is used.
+	TargetLatency Duration `yaml:"target_latency,omitempty" json:"target_latency,omitempty"`
+	// Polling for new update operations pauses while more than this many
+	// notification sets are awaiting delivery.
+	// If 0, the default of 1000 is used. If negative, polling never pauses.
+	MaxPending int `yaml:"max_pending,omitempty" json:"max_pending,omitempty"`
+}
+
+func (b *NotifierBackpressure) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if b.MaxBatch == 0 {
+		b.MaxBatch = DefaultNotifierDeliveryBatch
+	}
+	if b.TargetLatency <= 0 {
+		b.TargetLatency = Duration(DefaultNotifierDeliveryLatency)
+	}
+	if b.MaxPending == 0 {
+		b.MaxPending = DefaultNotifierMaxPending
+	}
+	return b.lint()
+}
+
+func (b *NotifierBackpressure) lint() (ws []Warning, err error) {
+	if b.MaxBatch < 0 {
+		ws = append(ws, Warning{
+			path: ".max_batch",
+			msg:  "unlimited delivery batches may load every pending notification at once",
+		})
+	}
+	if b.MaxBatch > 0 && b.MaxPending > 0 && b.MaxPending < b.MaxBatch {
+		ws = append(ws, Warning{
+			path: ".max_pending",
+			msg:  "smaller than max_batch: polling may pause while delivery is keeping up",
+		})
+	}
+	return ws, nil
+}
+
+// NotifierRetry configures how the notifier backs off from notifications that
+// failed to be delivered.
+//
+// The time of the next attempt is stored with the notification, so the
+// schedule survives restarts.
+type NotifierRetry struct {
+	// A time.ParseDuration parsable string
+	//
+	// The delay before the first retry, doubled for each subsequent one.
+	// If 0, the default of 30 seconds is used. If negative, failed
+	// notifications are attempted on every delivery run.
+	Backoff Duration `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The maximum delay between attempts.
+	// If 0, the default of 1 hour is used.
+	MaxBackoff Duration `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+}
+
+func (r *NotifierRetry) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if r.Backoff == 0 {
+		r.Backoff = Duration(DefaultNotifierRetryBackoff)
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = Duration(DefaultNotifierRetryMaxBackoff)
+	}
+	return r.lint()
+}
+
+func (r *NotifierRetry) lint() (ws []Warning, err error) {
+	if r.Backoff > 0 && r.MaxBackoff > 0 && r.MaxBackoff < r.Backoff {
+		ws = append(ws, Warning{
+			path: ".max_backoff",
+			msg:  "smaller than backoff: every retry waits max_backoff",
+		})
+	}
+	return ws, nil
+}
+
+// NotifierThrottle configures how the notifier paces the requests for
+// manifests affected by an update, which can load the indexer's database for
+// a long time after a large update.
+type NotifierThrottle struct {
+	// The number of vulnerabilities sent in a single request.
+	// If 0, the default of 1000 is used.
+	ChunkSize int `yaml:"chunk_size,omitempty" json:"chunk_size,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The delay between requests made for a single kind of change.
+	// If 0, there is no delay.
+	Delay Duration `yaml:"delay,omitempty" json:"delay,omitempty"`
+	// The maximum number of requests made at once for an update.
+	// If 0, there is no limit.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// Daily periods, in "HH:MM-HH:MM" form, when requests may be made.
+	// A period ending before it starts wraps past midnight.
+	// If empty, requests may be made at any time.
+	Windows []string `yaml:"windows,omitempty" json:"windows,omitempty"`
+	// The IANA time zone Windows are interpreted in.
+	// If empty, UTC is used.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+func (t *NotifierThrottle) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if t.ChunkSize < 0 {
+		return nil, fmt.Errorf("chunk_size must not be negative")
+	}
+	if t.ChunkSize == 0 {
+		t.ChunkSize = DefaultNotifierAffectedChunk
+	}
+	if t.Delay < 0 {
+		return nil, fmt.Errorf("delay must not be negative")
+	}
+	if t.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must not be negative")
+	}
+	for _, w := range t.Windows {
+		i := strings.IndexByte(w, '-')
+		if i == -1 {
+			return nil, fmt.Errorf("invalid window %q: missing \"-\"", w)
+		}
+		start, end := w[:i], w[i+1:]
+		s, err := time.Parse("15:04", strings.TrimSpace(start))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", w, err)
+		}
+		e, err := time.Parse("15:04", strings.TrimSpace(end))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", w, err)
+		}
+		if s.Equal(e) {
+			return nil, fmt.Errorf("invalid window %q: empty", w)
+		}
+	}
+	if t.Timezone != "" {
+		if _, err := time.LoadLocation(t.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	return t.lint()
+}
+
+func (t *NotifierThrottle) lint() (ws []Warning, err error) {
+	if t.Timezone != "" && len(t.Windows) == 0 {
+		ws = append(ws, Warning{
+			path: ".timezone",
+			msg:  "timezone has no effect without windows",
+		})
+	}
+	if t.ChunkSize > 0 && t.ChunkSize < 100 && t.Delay > 0 {
+		ws = append(ws, Warning{
+			path: ".chunk_size",
+			msg:  "small chunks with a delay may take a very long time to create notifications",
+		})
+	}
+	return ws, nil
+}
+
+// NotifierPages configures how pages of notifications are assembled for
+// clients fetching them after a callback.
+type NotifierPages struct {
+	// The number of goroutines decoding the notifications in a page.
+	// If 0, the default of 4 is used. If 1, pages are decoded serially.
+	Workers int `yaml:"workers,omitempty" json:"workers,omitempty"`
+	// A notification whose pages are requested this many times within "ttl"
+	// is considered hot: the pages served for it are kept, and the page
+	// after each one served is assembled before it's requested.
+	// If 0, pages are never kept.
+	HotThreshold int `yaml:"hot_threshold,omitempty" json:"hot_threshold,omitempty"`
+	// The maximum number of pages kept for hot notifications.
+	// If 0, the default of 64 is used.
+	CacheSize int `yaml:"cache_size,omitempty" json:"cache_size,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// How long pages are kept, and the window requests are counted in.
+	// If 0, the default of 5 minutes is used.
+	TTL Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+func (p *NotifierPages) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	switch {
+	case p.Workers < 0:
+		return nil, fmt.Errorf("workers must not be negative")
+	case p.HotThreshold < 0:
+		return nil, fmt.Errorf("hot_threshold must not be negative")
+	case p.CacheSize < 0:
+		return nil, fmt.Errorf("cache_size must not be negative")
+	case p.TTL < 0:
+		return nil, fmt.Errorf("ttl must not be negative")
+	}
+	if p.Workers == 0 {
+		p.Workers = DefaultNotifierPageWorkers
+	}
+	if p.CacheSize == 0 {
+		p.CacheSize = DefaultNotifierPageCacheSize
+	}
+	if p.TTL == 0 {
+		p.TTL = Duration(DefaultNotifierPageTTL)
+	}
+	return p.lint()
+}
+
+func (p *NotifierPages) lint() (ws []Warning, err error) {
+	if p.HotThreshold == 1 {
+		ws = append(ws, Warning{
+			path: ".hot_threshold",
+			msg:  "every notification is hot: pages are assembled ahead for notifications nobody pages through",
+		})
+	}
+	return ws, nil
+}
+
 // Webhook configures the "webhook" notification mechanism.
 type Webhook struct {
 	// any HTTP headers necessary for the request to Target
@@ -155,6 +545,114 @@ type Webhook struct {
 	// if true webhooks will be sent with a jwt signed by
 	// the notifier's private key.
 	Signed bool `yaml:"signed,omitempty" json:"signed,omitempty"`
+	// Direct configures the notifier to POST the notifications themselves
+	// to the target, instead of a callback.
+	//
+	// If true "Callback" is ignored.
+	Direct bool `yaml:"direct,omitempty" json:"direct,omitempty"`
+	// Specifies the maximum number of notifications delivered in a single
+	// request when Direct is true.
+	//
+	// Ignored if Direct is not true.
+	// If 0, all notifications are delivered in a single request.
+	PageSize int `yaml:"page_size,omitempty" json:"page_size,omitempty"`
+	// Retry configures retrying a failed request within a single delivery
+	// attempt.
+	//
+	// If not provided, a single request is made and a failed delivery is
+	// retried at the next delivery interval.
+	Retry *WebhookRetry `yaml:"retry,omitempty" json:"retry,omitempty"`
+	// AllowedHosts restricts the hosts webhooks may be delivered to.
+	//
+	// Entries are hostnames or IP addresses, or "*." followed by a domain to
+	// allow any subdomain. If empty, any host is allowed.
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty" json:"allowed_hosts,omitempty"`
+	// AllowedNetworks restricts the addresses webhooks may be delivered to.
+	//
+	// Entries are CIDR blocks or single IP addresses. If empty, any address
+	// is allowed except link-local, multicast, and cloud metadata addresses.
+	AllowedNetworks []string `yaml:"allowed_networks,omitempty" json:"allowed_networks,omitempty"`
+}
+
+// WebhookRetry configures the retry schedule for webhook requests.
+//
+// Requests that fail without a response, or with one of the listed status
+// codes, are retried with an exponential backoff.
+type WebhookRetry struct {
+	// The number of requests made for a delivery, including the first.
+	// If 0, the default of 3 is used.
+	MaxAttempts int `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The delay before the first retry, doubled for each subsequent one.
+	// If 0, the default of 1 second is used.
+	Backoff Duration `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The maximum delay between requests.
+	// If 0, the default of 30 seconds is used.
+	MaxBackoff Duration `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+	// The response status codes that are retried.
+	// If empty, 408, 429, 500, 502, 503, and 504 are retried.
+	StatusCodes []int `yaml:"status_codes,omitempty" json:"status_codes,omitempty"`
+	// Disables waiting for the delay requested by a Retry-After header.
+	//
+	// By default, a Retry-After delay is used in place of the backoff, and
+	// a delay longer than MaxBackoff ends the delivery attempt.
+	IgnoreRetryAfter bool `yaml:"ignore_retry_after,omitempty" json:"ignore_retry_after,omitempty"`
+}
+
+func (r *WebhookRetry) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	if r.MaxAttempts < 0 {
+		return nil, fmt.Errorf("max_attempts must not be negative")
+	}
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = DefaultWebhookRetryAttempts
+	}
+	if r.Backoff <= 0 {
+		r.Backoff = Duration(DefaultWebhookRetryBackoff)
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = Duration(DefaultWebhookRetryMaxBackoff)
+	}
+	if len(r.StatusCodes) == 0 {
+		r.StatusCodes = []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	for _, c := range r.StatusCodes {
+		if c < 100 || c > 599 {
+			return nil, fmt.Errorf("invalid status code: %d", c)
+		}
+		if c == http.StatusOK {
+			return nil, fmt.Errorf("status code %d indicates a successful delivery", c)
+		}
+	}
+	return r.lint()
+}
+
+func (r *WebhookRetry) lint() (ws []Warning, err error) {
+	if r.Backoff > r.MaxBackoff {
+		ws = append(ws, Warning{
+			path: ".backoff",
+			msg:  "backoff is longer than max_backoff: max_backoff will be used",
+		})
+	}
+	if r.MaxAttempts > 1 && time.Duration(r.MaxBackoff) > time.Minute {
+		ws = append(ws, Warning{
+			path: ".max_backoff",
+			msg:  "long delays hold up delivery of other notifications",
+		})
+	}
+	return ws, nil
 }
 
 // Validate will return a copy of the Config on success.
@@ -168,17 +666,22 @@ func (w *Webhook) validate(mode Mode) ([]Warning, error) {
 		return nil, fmt.Errorf("failed to parse target url: %w", err)
 	}
 
-	// Require trailing slash so url.Parse() can easily append notification id.
-	if !strings.HasSuffix(w.Callback, "/") {
-		w.Callback = w.Callback + "/"
-		ws = append(ws, Warning{
-			path: ".callback",
-			msg:  `URL should end in a "/"`,
-		})
-	}
+	if !w.Direct {
+		// Require trailing slash so url.Parse() can easily append notification id.
+		if !strings.HasSuffix(w.Callback, "/") {
+			w.Callback = w.Callback + "/"
+			ws = append(ws, Warning{
+				path: ".callback",
+				msg:  `URL should end in a "/"`,
+			})
+		}
 
-	if _, err := url.Parse(w.Callback); err != nil {
-		return nil, fmt.Errorf("failed to parse callback url: %w", err)
+		if _, err := url.Parse(w.Callback); err != nil {
+			return nil, fmt.Errorf("failed to parse callback url: %w", err)
+		}
+	}
+	if err := checkAllowed(w.AllowedHosts, w.AllowedNetworks); err != nil {
+		return nil, err
 	}
 	ls, err := w.lint()
 	ws = append(ws, ls...)
@@ -188,14 +691,51 @@ func (w *Webhook) validate(mode Mode) ([]Warning, error) {
 	return ws, nil
 }
 
-func (w *Webhook) lint() ([]Warning, error) {
+// CheckAllowed reports an error if any of the "hosts" or "networks" used to
+// restrict outgoing requests are malformed.
+func checkAllowed(hosts, networks []string) error {
+	for _, h := range hosts {
+		d := strings.TrimPrefix(h, "*.")
+		if d == "" || (strings.ContainsAny(d, "*/:") && net.ParseIP(d) == nil) {
+			return fmt.Errorf("invalid allowed host %q", h)
+		}
+	}
+	for _, n := range networks {
+		if net.ParseIP(n) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(n); err != nil {
+			return fmt.Errorf("invalid allowed network %q: %w", n, err)
+		}
+	}
+	return nil
+}
+
+func (w *Webhook) lint() (ws []Warning, err error) {
 	if w.Signed {
-		return []Warning{{
+		ws = append(ws, Warning{
 			path:  ".signed",
 			inner: ErrDeprecated,
-		}}, nil
+		})
 	}
-	return nil, nil
+	if w.Direct && w.Callback != "" {
+		ws = append(ws, Warning{
+			msg: "`Callback` and `Direct` set: `Callback` will be ignored",
+		})
+	}
+	if !w.Direct && w.PageSize != 0 {
+		ws = append(ws, Warning{
+			path: ".page_size",
+			msg:  "`PageSize` set without `Direct`: `PageSize` will be ignored",
+		})
+	}
+	if w.PageSize < 0 {
+		ws = append(ws, Warning{
+			path: ".page_size",
+			msg:  "negative values are treated as 0",
+		})
+	}
+	return ws, nil
 }
 
 // Exchange are the required fields necessary to check
@@ -224,6 +764,23 @@ func (e *Exchange) validate(_ Mode) ([]Warning, error) {
 	return nil, nil
 }
 
+// Serializer selects the serializer plugin encoding a message-queue
+// deliverer's messages.
+type Serializer struct {
+	// Name is the name the serializer is registered as. The "json"
+	// serializer is built in.
+	Name string `yaml:"name" json:"name"`
+	// Config is the serializer's configuration block, if it takes one.
+	Config interface{} `yaml:"config,omitempty" json:"config,omitempty"`
+}
+
+func (s *Serializer) validate(_ Mode) ([]Warning, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("serializer name required")
+	}
+	return nil, nil
+}
+
 // AMQP configures the AMQP notification mechanism.
 type AMQP struct {
 	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
@@ -242,6 +799,9 @@ type AMQP struct {
 	//
 	// If "amqps://" broker URI schemas are provided the TLS configuration below is required.
 	URIs []string `yaml:"uris" json:"uris"`
+	// Brokers overrides connection settings for individual entries in
+	// "URIs".
+	Brokers []Broker `yaml:"brokers,omitempty" json:"brokers,omitempty"`
 	// Specifies the number of notifications delivered in single AMQP message
 	// when Direct is true.
 	//
@@ -256,6 +816,8 @@ type AMQP struct {
 	// If false a notifier.Callback is delivered to the queue and clients
 	// utilize the pagination API to retrieve.
 	Direct bool `yaml:"direct,omitempty" json:"direct,omitempty"`
+	// Serializer selects how messages are encoded. If nil, they're JSON.
+	Serializer *Serializer `yaml:"serializer,omitempty" json:"serializer,omitempty"`
 }
 
 // Validate confirms configuration is valid.
@@ -275,6 +837,9 @@ func (c *AMQP) validate(mode Mode) ([]Warning, error) {
 			return nil, fmt.Errorf("invalid URI %q: %w", uri, err)
 		}
 	}
+	if err := checkBrokers(c.URIs, c.Brokers); err != nil {
+		return nil, err
+	}
 
 	if !c.Direct {
 		if !strings.HasSuffix(c.Callback, "/") {
@@ -327,10 +892,26 @@ type STOMP struct {
 	// the destination messages will be delivered to
 	Destination string `yaml:"destination" json:"destination"`
 	// a list of URIs to send messages to.
-	// a linear search of this list is always performed.
+	// The order they're attempted in is controlled by "Failover".
 	//
 	// Note that "URI" is a misnomer, this must be host:port pairs.
 	URIs []string `yaml:"uris" json:"uris"`
+	// Brokers overrides connection settings for individual entries in
+	// "URIs".
+	Brokers []Broker `yaml:"brokers,omitempty" json:"brokers,omitempty"`
+	// Failover is the strategy used to pick a broker from "URIs". It must be
+	// one of the Failover* constants.
+	//
+	// If empty, FailoverPriority is used.
+	Failover string `yaml:"failover,omitempty" json:"failover,omitempty"`
+	// FailureBackoff is how long a broker that couldn't be connected to is
+	// skipped for. Skipped brokers are still attempted if no other broker
+	// can be connected to.
+	//
+	// A time.ParseDuration parsable string. If zero,
+	// DefaultSTOMPFailureBackoff is used. Negative values disable the
+	// backoff.
+	FailureBackoff Duration `yaml:"failure_backoff,omitempty" json:"failure_backoff,omitempty"`
 	// Specifies the number of notifications delivered in single STOMP message
 	// when Direct is true.
 	//
@@ -345,8 +926,24 @@ type STOMP struct {
 	// If false a notifier.Callback is delivered to the queue and clients
 	// utilize the pagination API to retrieve.
 	Direct bool `yaml:"direct,omitempty" json:"direct,omitempty"`
+	// Serializer selects how messages are encoded. If nil, they're JSON.
+	Serializer *Serializer `yaml:"serializer,omitempty" json:"serializer,omitempty"`
 }
 
+// These are the failover strategies for STOMP delivery.
+const (
+	// FailoverPriority attempts brokers in configuration order.
+	FailoverPriority = "priority"
+	// FailoverRandom attempts brokers in a random order.
+	FailoverRandom = "random"
+	// FailoverRoundRobin starts each attempt with the broker after the one the
+	// previous attempt started with.
+	FailoverRoundRobin = "round-robin"
+	// FailoverSticky starts each attempt with the broker that was most
+	// recently connected to.
+	FailoverSticky = "sticky"
+)
+
 func (c *STOMP) validate(mode Mode) ([]Warning, error) {
 	if mode != ComboMode && mode != NotifierMode {
 		return nil, nil
@@ -360,6 +957,19 @@ func (c *STOMP) validate(mode Mode) ([]Warning, error) {
 			return nil, fmt.Errorf("bad host:port %q: %w", u, err)
 		}
 	}
+	if err := checkBrokers(c.URIs, c.Brokers); err != nil {
+		return nil, err
+	}
+	switch c.Failover {
+	case "":
+		c.Failover = FailoverPriority
+	case FailoverPriority, FailoverRandom, FailoverRoundRobin, FailoverSticky:
+	default:
+		return nil, fmt.Errorf("unknown failover strategy %q", c.Failover)
+	}
+	if c.FailureBackoff == 0 {
+		c.FailureBackoff = Duration(DefaultSTOMPFailureBackoff)
+	}
 	if !c.Direct {
 		if !strings.HasSuffix(c.Callback, "/") {
 			c.Callback = c.Callback + "/"
@@ -393,3 +1003,61 @@ func (c *STOMP) lint() (w []Warning, err error) {
 	}
 	return w, nil
 }
+
+// DependencyTrack configures the notifier to publish a CycloneDX SBOM,
+// including Clair's findings, to a Dependency-Track server for every manifest
+// mentioned in a notification.
+type DependencyTrack struct {
+	// The base URL of the Dependency-Track API server.
+	URL string `yaml:"url" json:"url"`
+	// An API key for a team with the BOM_UPLOAD and PROJECT_CREATION_UPLOAD
+	// permissions.
+	APIKey string `yaml:"api_key" json:"api_key"`
+	// The manifest label used as the project name.
+	// If empty, the default of "repository" is used. Manifests without the
+	// label are published to a project named by the manifest digest.
+	ProjectLabel string `yaml:"project_label,omitempty" json:"project_label,omitempty"`
+	// The manifest label used as the project version.
+	// If empty, the default of "tag" is used. Manifests without the label are
+	// published to a version named by the manifest digest.
+	VersionLabel string `yaml:"version_label,omitempty" json:"version_label,omitempty"`
+}
+
+func (d *DependencyTrack) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	u, err := url.Parse(d.URL)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	case !u.IsAbs() || u.Host == "":
+		return nil, fmt.Errorf("url %q must be absolute", d.URL)
+	}
+	if d.APIKey == "" {
+		return nil, fmt.Errorf("api_key is required")
+	}
+	if d.ProjectLabel == "" {
+		d.ProjectLabel = DefaultDependencyTrackProjectLabel
+	}
+	if d.VersionLabel == "" {
+		d.VersionLabel = DefaultDependencyTrackVersionLabel
+	}
+	return d.lint()
+}
+
+func (d *DependencyTrack) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(d.URL); err == nil && u.Scheme == "http" {
+		ws = append(ws, Warning{
+			path: ".url",
+			msg:  "API key will be sent unencrypted",
+		})
+	}
+	if d.ProjectLabel == d.VersionLabel {
+		ws = append(ws, Warning{
+			path: ".version_label",
+			msg:  "project and version use the same label",
+		})
+	}
+	return ws, nil
+}
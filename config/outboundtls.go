@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// OutboundTLS configures how TLS connections made by Clair are verified, per
+// class of server. Classes without a policy use the system trust store and Go's
+// defaults.
+type OutboundTLS struct {
+	// Database applies to connections to PostgreSQL, for all services.
+	Database *TLSPolicy `yaml:"database,omitempty" json:"database,omitempty"`
+	// Registry applies to the indexer's requests, including layer fetches.
+	Registry *TLSPolicy `yaml:"registry,omitempty" json:"registry,omitempty"`
+	// Updaters applies to updaters' requests for vulnerability data.
+	Updaters *TLSPolicy `yaml:"updaters,omitempty" json:"updaters,omitempty"`
+	// Notifier applies to webhook deliveries and re-scan subscription
+	// callbacks. AMQP and STOMP deliveries are configured in their own
+	// sections.
+	Notifier *TLSPolicy `yaml:"notifier,omitempty" json:"notifier,omitempty"`
+}
+
+// TLSPolicy describes the trust store and protocol restrictions for
+// outgoing TLS connections.
+type TLSPolicy struct {
+	// RootCAs is a list of filesystem paths to PEM encoded CA certificates
+	// to trust, in addition to the system trust store.
+	RootCAs []string `yaml:"root_cas,omitempty" json:"root_cas,omitempty"`
+	// ExcludeSystemRoots causes only "RootCAs" to be trusted.
+	ExcludeSystemRoots bool `yaml:"exclude_system_roots,omitempty" json:"exclude_system_roots,omitempty"`
+	// MinVersion is the minimum TLS version to negotiate: one of "1.0",
+	// "1.1", "1.2", or "1.3". If empty, Go's default is used.
+	MinVersion string `yaml:"min_version,omitempty" json:"min_version,omitempty"`
+	// CipherSuites restricts the cipher suites used for TLS 1.2 and earlier,
+	// by their IANA names. TLS 1.3 cipher suites are not configurable.
+	CipherSuites []string `yaml:"cipher_suites,omitempty" json:"cipher_suites,omitempty"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Apply modifies "c" according to the policy.
+//
+// If the *TLSPolicy is nil, "c" is left unmodified.
+func (p *TLSPolicy) Apply(c *tls.Config) error {
+	if p == nil {
+		return nil
+	}
+	if len(p.RootCAs) != 0 || p.ExcludeSystemRoots {
+		pool := x509.NewCertPool()
+		if !p.ExcludeSystemRoots {
+			var err error
+			pool, err = x509.SystemCertPool()
+			if err != nil {
+				return err
+			}
+		}
+		for _, f := range p.RootCAs {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("failed to read root ca: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return fmt.Errorf("no certificates found in %q", f)
+			}
+		}
+		c.RootCAs = pool
+	}
+	if p.MinVersion != "" {
+		v, ok := tlsVersions[p.MinVersion]
+		if !ok {
+			return fmt.Errorf("unknown tls version %q", p.MinVersion)
+		}
+		c.MinVersion = v
+	}
+	if len(p.CipherSuites) != 0 {
+		ids, err := cipherSuites(p.CipherSuites)
+		if err != nil {
+			return err
+		}
+		c.CipherSuites = ids
+	}
+	return nil
+}
+
+// Config returns a tls.Config modified according to the policy.
+//
+// If the *TLSPolicy is nil, nil is returned.
+func (p *TLSPolicy) Config() (*tls.Config, error) {
+	if p == nil {
+		return nil, nil
+	}
+	var c tls.Config
+	if err := p.Apply(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func cipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, len(names))
+	for i, n := range names {
+		id, ok := known[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", n)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func (p *TLSPolicy) validate(_ Mode) ([]Warning, error) {
+	if p.ExcludeSystemRoots && len(p.RootCAs) == 0 {
+		return nil, errors.New("exclude_system_roots set without root_cas: no server could be trusted")
+	}
+	for _, f := range p.RootCAs {
+		if _, err := os.Stat(f); err != nil {
+			return nil, fmt.Errorf(`error accessing %q: %w`, f, err)
+		}
+	}
+	if _, ok := tlsVersions[p.MinVersion]; p.MinVersion != "" && !ok {
+		return nil, fmt.Errorf("unknown tls version %q", p.MinVersion)
+	}
+	if _, err := cipherSuites(p.CipherSuites); err != nil {
+		return nil, err
+	}
+	return p.lint()
+}
+
+func (p *TLSPolicy) lint() (ws []Warning, err error) {
+	insecure := make(map[string]bool)
+	for _, s := range tls.InsecureCipherSuites() {
+		insecure[s.Name] = true
+	}
+	for _, n := range p.CipherSuites {
+		if insecure[n] {
+			ws = append(ws, Warning{
+				path: ".cipher_suites",
+				msg:  fmt.Sprintf("cipher suite %q has known security issues", n),
+			})
+		}
+	}
+	if v := p.MinVersion; v == "1.0" || v == "1.1" {
+		ws = append(ws, Warning{
+			path: ".min_version",
+			msg:  fmt.Sprintf("TLS %s is deprecated", v),
+		})
+	}
+	return ws, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Proxy configures proxies for outbound HTTP requests: updater fetches, layer
+// downloads, and webhook deliveries. Requests between Clair services do not
+// use it.
+//
+// Proxy credentials may be provided as the userinfo portion of a proxy URL.
+type Proxy struct {
+	// URL is the proxy used for destinations not matched by "NoProxy" or
+	// "Rules". The "http", "https", and "socks5" schemes are supported.
+	//
+	// If empty, the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment
+	// variables are consulted.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// NoProxy lists destinations that are connected to directly. Entries
+	// are in the same format as the NO_PROXY environment variable: host
+	// names, domain suffixes, IP addresses, or CIDR ranges, with optional
+	// ports.
+	NoProxy []string `yaml:"no_proxy,omitempty" json:"no_proxy,omitempty"`
+	// Rules selects proxies for specific destinations. Rules are checked in
+	// order, before "NoProxy" and "URL".
+	Rules []ProxyRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// ProxyRule selects a proxy for a set of destinations.
+type ProxyRule struct {
+	// Hosts lists the destinations this rule applies to, in the same format
+	// as Proxy.NoProxy.
+	Hosts []string `yaml:"hosts" json:"hosts"`
+	// URL is the proxy to use for these destinations. If empty, they are
+	// connected to directly.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+}
+
+func (p *Proxy) validate(_ Mode) ([]Warning, error) {
+	if err := checkProxyURL(p.URL); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (r *ProxyRule) validate(_ Mode) ([]Warning, error) {
+	if len(r.Hosts) == 0 {
+		return nil, errors.New("proxy rule missing hosts")
+	}
+	if err := checkProxyURL(r.URL); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func checkProxyURL(s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("proxy: bad url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("proxy: unsupported url scheme %q", u.Scheme)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Redaction configures removing fields from the index and vulnerability
+// reports Clair serves, depending on the caller.
+//
+// This is meant for sharing reports with parties that shouldn't learn about
+// internal structure, such as external vendors. Every rule matching the
+// caller applies, so the fields removed are the union of their fields.
+// Reports are redacted before they're signed.
+type Redaction struct {
+	Rules []RedactionRule `yaml:"rules" json:"rules"`
+}
+
+func (r *Redaction) validate(_ Mode) ([]Warning, error) {
+	seen := make(map[string]struct{}, len(r.Rules))
+	for _, rule := range r.Rules {
+		if _, ok := seen[rule.Name]; ok {
+			return nil, fmt.Errorf("rule: duplicate name %q", rule.Name)
+		}
+		seen[rule.Name] = struct{}{}
+	}
+	return r.lint()
+}
+
+func (r *Redaction) lint() (ws []Warning, err error) {
+	if len(r.Rules) == 0 {
+		ws = append(ws, Warning{
+			path: ".rules",
+			msg:  "no rules: reports are served in full",
+		})
+	}
+	return ws, nil
+}
+
+// RedactionRule removes fields from reports served to matching callers.
+//
+// Callers are identified by the authentication plugin or client
+// certificate that accepted the request. All of the criteria that are set
+// must match; a rule with none matches every caller, including
+// unauthenticated ones.
+type RedactionRule struct {
+	// Name identifies the rule. It must be unique.
+	Name string `yaml:"name" json:"name"`
+	// Subjects, if not empty, matches callers with one of these subjects.
+	Subjects []string `yaml:"subjects,omitempty" json:"subjects,omitempty"`
+	// Issuers, if not empty, matches callers whose identity was issued by
+	// one of these.
+	Issuers []string `yaml:"issuers,omitempty" json:"issuers,omitempty"`
+	// Claim, if set, matches callers whose claim of this name is one of
+	// "Values", or is a list containing one of them. This is how roles or
+	// groups are usually matched.
+	Claim  string   `yaml:"claim,omitempty" json:"claim,omitempty"`
+	Values []string `yaml:"values,omitempty" json:"values,omitempty"`
+	// Fields lists what's removed:
+	//
+	//   - "file_paths": package database paths and the paths of secrets and
+	//     unpackaged files
+	//   - "repository_urls": repository URIs and keys
+	//   - "package_metadata": source packages, modules, architectures, and CPEs
+	//   - "labels": manifest labels
+	//   - "annotations": triage annotations
+	//   - "secrets": secret findings
+	//   - "image_config": the recorded image config
+	Fields []string `yaml:"fields" json:"fields"`
+}
+
+// RedactionFields are the known values for RedactionRule.Fields.
+var redactionFields = map[string]struct{}{
+	"file_paths":       {},
+	"repository_urls":  {},
+	"package_metadata": {},
+	"labels":           {},
+	"annotations":      {},
+	"secrets":          {},
+	"image_config":     {},
+}
+
+func (r *RedactionRule) validate(_ Mode) ([]Warning, error) {
+	if r.Name == "" {
+		return nil, errors.New("rule: missing name")
+	}
+	if (r.Claim == "") != (len(r.Values) == 0) {
+		return nil, fmt.Errorf("rule %q: claim and values must be set together", r.Name)
+	}
+	for _, f := range r.Fields {
+		if _, ok := redactionFields[f]; !ok {
+			return nil, fmt.Errorf("rule %q: unknown field %q", r.Name, f)
+		}
+	}
+	return r.lint()
+}
+
+func (r *RedactionRule) lint() (ws []Warning, err error) {
+	if len(r.Fields) == 0 {
+		ws = append(ws, Warning{
+			path: ".fields",
+			msg:  fmt.Sprintf("rule %q removes nothing", r.Name),
+		})
+	}
+	return ws, nil
+}
@@ -8,13 +8,32 @@
 
 type walkFunc func(interface{}) ([]Warning, error)
 
+// ErrFunc is called with the errors reported by a walkFunc and their path. If
+// it returns an error, the walk stops.
+type errFunc func(path string, err error) error
+
 func forEach(i interface{}, f walkFunc) ([]Warning, error) {
 	var ws []Warning
 	v := reflect.ValueOf(i)
-	return ws, walk(&ws, "$", v, f)
+	return ws, walk(&ws, "$", v, f, stop)
 }
 
-func walk(ws *[]Warning, path string, v reflect.Value, wf walkFunc) error {
+// ForEachAll is like forEach, but visits every value regardless of errors,
+// returning all of them.
+func forEachAll(i interface{}, f walkFunc) ([]Warning, []error) {
+	var ws []Warning
+	var errs []error
+	v := reflect.ValueOf(i)
+	walk(&ws, "$", v, f, func(path string, err error) error {
+		errs = append(errs, fmt.Errorf("%w (at %s)", err, path))
+		return nil
+	})
+	return ws, errs
+}
+
+func stop(_ string, err error) error { return err }
+
+func walk(ws *[]Warning, path string, v reflect.Value, wf walkFunc, ef errFunc) error {
 	t := v.Type()
 	var vi interface{}
 	// Figure out if we should take the address to do the interface
@@ -29,7 +48,9 @@ func walk(ws *[]Warning, path string, v reflect.Value, wf walkFunc) error {
 	if vi != nil {
 		w, err := wf(vi)
 		if err != nil {
-			return err
+			if err := ef(path, err); err != nil {
+				return err
+			}
 		}
 		for i := range w {
 			// Adjust the path here, so that the lint method doesn't need to
@@ -60,7 +81,7 @@ func walk(ws *[]Warning, path string, v reflect.Value, wf walkFunc) error {
 				n = t
 			}
 			p := fmt.Sprintf(`%s.%s`, path, n)
-			if err := walk(ws, p, v.Field(i), wf); err != nil {
+			if err := walk(ws, p, v.Field(i), wf, ef); err != nil {
 				return err
 			}
 		}
@@ -68,7 +89,7 @@ func walk(ws *[]Warning, path string, v reflect.Value, wf walkFunc) error {
 		i := v.MapRange()
 		for i.Next() {
 			p := fmt.Sprintf(`%s.[%s]`, path, i.Key().String())
-			if err := walk(ws, p, i.Value(), wf); err != nil {
+			if err := walk(ws, p, i.Value(), wf, ef); err != nil {
 				return err
 			}
 
@@ -76,7 +97,7 @@ func walk(ws *[]Warning, path string, v reflect.Value, wf walkFunc) error {
 	case reflect.Slice:
 		for i, lim := 0, v.Len(); i < lim; i++ {
 			p := fmt.Sprintf(`%s.[%d]`, path, i)
-			if err := walk(ws, p, v.Index(i), wf); err != nil {
+			if err := walk(ws, p, v.Index(i), wf, ef); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ReportSigning configures signing of the index and vulnerability reports
+// Clair serves.
+//
+// Signed reports are returned as DSSE envelopes when a client requests the
+// "application/vnd.dsse.envelope.v1+json" media type. Reports are otherwise
+// unchanged.
+type ReportSigning struct {
+	// Key is a filesystem path to an unencrypted PEM encoded private key:
+	// ECDSA, Ed25519, or RSA, in PKCS#8, SEC 1, or PKCS#1 form.
+	Key string `yaml:"key" json:"key"`
+	// KeyID is the identifier recorded in signatures. If empty, the hex
+	// encoded SHA-256 digest of the DER encoded public key is used.
+	KeyID string `yaml:"key_id,omitempty" json:"key_id,omitempty"`
+}
+
+func (s *ReportSigning) validate(_ Mode) ([]Warning, error) {
+	if s.Key == "" {
+		return nil, errors.New("report signing requires a key")
+	}
+	if _, err := os.Stat(s.Key); err != nil {
+		return nil, fmt.Errorf(`error accessing %q: %w`, s.Key, err)
+	}
+	return s.lint()
+}
+
+func (s *ReportSigning) lint() (ws []Warning, err error) {
+	fi, err := os.Stat(s.Key)
+	if err != nil {
+		return nil, nil
+	}
+	if fi.Mode().Perm()&0o077 != 0 {
+		ws = append(ws, Warning{
+			path: ".key",
+			msg:  "private key is accessible by other users",
+		})
+	}
+	return ws, nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// Splunk configures the notifier to send notifications to a Splunk HTTP Event
+// Collector.
+//
+// Every notification is sent as its own event. Events are sent in batches.
+type Splunk struct {
+	// The base URL of the HTTP Event Collector, such as
+	// "https://splunk.example.com:8088".
+	URL string `yaml:"url" json:"url"`
+	// An HTTP Event Collector token.
+	Token string `yaml:"token" json:"token"`
+	// A Go text/template for the index of an event, executed with the
+	// notification. If empty, the token's default index is used.
+	Index string `yaml:"index,omitempty" json:"index,omitempty"`
+	// A Go text/template for the sourcetype of an event, executed with the
+	// notification.
+	// If empty, the default of "clair:notification" is used.
+	Sourcetype string `yaml:"sourcetype,omitempty" json:"sourcetype,omitempty"`
+	// The source of events.
+	// If empty, the default of "clair" is used.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+	// The host of events. If empty, the collector fills it in.
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+	// The maximum number of events sent in a single request.
+	// If 0, the default of 100 is used.
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`
+}
+
+func (s *Splunk) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode && mode != NotifierMode {
+		return nil, nil
+	}
+	u, err := url.Parse(s.URL)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	case !u.IsAbs() || u.Host == "":
+		return nil, fmt.Errorf("url %q must be absolute", s.URL)
+	case s.Token == "":
+		return nil, errors.New("token is required")
+	case s.BatchSize < 0:
+		return nil, errors.New("batch_size must not be negative")
+	}
+	if s.Sourcetype == "" {
+		s.Sourcetype = DefaultSplunkSourcetype
+	}
+	if s.Source == "" {
+		s.Source = DefaultSplunkSource
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = DefaultSplunkBatchSize
+	}
+	for _, t := range []struct {
+		name, text string
+	}{
+		{"index", s.Index},
+		{"sourcetype", s.Sourcetype},
+	} {
+		if _, err := template.New(t.name).Parse(t.text); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", t.name, err)
+		}
+	}
+	return s.lint()
+}
+
+func (s *Splunk) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(s.URL); err == nil && u.Scheme == "http" {
+		ws = append(ws, Warning{
+			path: ".url",
+			msg:  "token will be sent unencrypted",
+		})
+	}
+	return ws, nil
+}
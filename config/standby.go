@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Standby configures a combo process as a warm standby.
+//
+// A standby serves API requests and keeps its caches and matcher state warm,
+// but doesn't run its background work (updaters, garbage collection,
+// subscription re-matching, notification delivery) until it's promoted. If
+// the active process goes away, a standby can take over in about the poll
+// interval, rather than the time it takes to start a new process.
+type Standby struct {
+	// How the process is promoted.
+	//
+	// "lock" promotes whichever process holds a lock in the matcher database,
+	// so a standby takes over as soon as the active process exits or loses
+	// its connection. "api" waits for a request to the standby endpoint.
+	//
+	// The default is "lock".
+	Promotion string `yaml:"promotion,omitempty" json:"promotion,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// How often a standby tries the lock when the promotion is "lock".
+	// If 0, the default of 500 milliseconds is used.
+	PollInterval Duration `yaml:"poll_interval,omitempty" json:"poll_interval,omitempty"`
+}
+
+// These are the recognized values for Standby.Promotion.
+const (
+	StandbyPromotionLock = "lock"
+	StandbyPromotionAPI  = "api"
+)
+
+func (s *Standby) validate(mode Mode) ([]Warning, error) {
+	if mode != ComboMode {
+		return []Warning{{
+			msg: "standby is only used in combo mode",
+		}}, nil
+	}
+	switch s.Promotion {
+	case "":
+		s.Promotion = StandbyPromotionLock
+	case StandbyPromotionLock, StandbyPromotionAPI:
+	default:
+		return nil, fmt.Errorf("unknown promotion: %q", s.Promotion)
+	}
+	if s.PollInterval < 0 {
+		return nil, fmt.Errorf("negative poll_interval is invalid: %v", time.Duration(s.PollInterval))
+	}
+	if s.PollInterval == 0 {
+		s.PollInterval = Duration(DefaultStandbyPollInterval)
+	}
+	return s.lint()
+}
+
+func (s *Standby) lint() (ws []Warning, err error) {
+	if s.Promotion == StandbyPromotionLock && time.Duration(s.PollInterval) > time.Minute {
+		ws = append(ws, Warning{
+			path: ".poll_interval",
+			msg:  fmt.Sprintf("long poll interval (%v) delays failover", time.Duration(s.PollInterval)),
+		})
+	}
+	return ws, nil
+}
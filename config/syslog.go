@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Syslog configures sending RFC 5424 syslog messages to a collector.
+type Syslog struct {
+	// The collector to send messages to, as a URL.
+	//
+	// The scheme must be "tls" for RFC 5425 syslog over TLS, "tcp" for
+	// plain TCP, or "udp". TCP and TLS messages are framed by octet
+	// counting. If the port is omitted, 6514, 601, or 514 is used.
+	Address string `yaml:"address" json:"address"`
+	// Optional TLS configuration, used for "tls" collectors.
+	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// The facility messages are sent with, by name: "kern", "user",
+	// "daemon", "auth", "authpriv", "local0" through "local7", and so on.
+	// If empty, "local0" is used.
+	Facility string `yaml:"facility,omitempty" json:"facility,omitempty"`
+	// Severities maps kinds of event to the syslog severity, by name ("crit",
+	// "err", "warning", "notice", "info", and so on), they're sent with.
+	//
+	// For notifications, the kinds are vulnerability severities:
+	// "Critical", "High", "Medium", "Low", "Negligible", and "Unknown". For
+	// audit events, they're "success", "denied", and "error". Kinds not
+	// mentioned keep their default severity.
+	Severities map[string]string `yaml:"severities,omitempty" json:"severities,omitempty"`
+	// The APP-NAME of messages. If empty, "clair" is used.
+	AppName string `yaml:"app_name,omitempty" json:"app_name,omitempty"`
+	// The HOSTNAME of messages. If empty, the system hostname is used.
+	Hostname string `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// The timeout for connecting to the collector. If 0,
+	// DefaultBrokerDialTimeout is used.
+	DialTimeout Duration `yaml:"dial_timeout,omitempty" json:"dial_timeout,omitempty"`
+}
+
+// These are the syslog facility and severity names, as used by syslog.conf(5).
+var (
+	syslogFacilities = []string{
+		"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+		"uucp", "cron", "authpriv", "ftp", "ntp", "audit", "alert", "clock",
+		"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+	}
+	syslogSeverities = []string{
+		"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+	}
+)
+
+func (s *Syslog) validate(_ Mode) ([]Warning, error) {
+	u, err := url.Parse(s.Address)
+	switch {
+	case s.Address == "":
+		return nil, errors.New("address is required")
+	case err != nil:
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	case u.Host == "":
+		return nil, fmt.Errorf("address %q must include a host", s.Address)
+	}
+	switch u.Scheme {
+	case "tls", "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("address %q: unknown scheme %q", s.Address, u.Scheme)
+	}
+	if s.Facility == "" {
+		s.Facility = DefaultSyslogFacility
+	}
+	if !contains(syslogFacilities, s.Facility) {
+		return nil, fmt.Errorf("unknown facility %q", s.Facility)
+	}
+	for k, v := range s.Severities {
+		if !contains(syslogSeverities, v) {
+			return nil, fmt.Errorf("severities: %q: unknown severity %q", k, v)
+		}
+	}
+	if s.AppName == "" {
+		s.AppName = DefaultSyslogAppName
+	}
+	if s.DialTimeout <= 0 {
+		s.DialTimeout = Duration(DefaultBrokerDialTimeout)
+	}
+	return s.lint()
+}
+
+func (s *Syslog) lint() (ws []Warning, err error) {
+	u, err := url.Parse(s.Address)
+	if err != nil {
+		return nil, nil
+	}
+	switch {
+	case u.Scheme == "udp":
+		ws = append(ws, Warning{
+			path: ".address",
+			msg:  "messages sent over UDP may be lost or truncated, and aren't encrypted",
+		})
+	case u.Scheme == "tcp":
+		ws = append(ws, Warning{
+			path: ".address",
+			msg:  "messages will be sent unencrypted",
+		})
+	}
+	if u.Scheme != "tls" && s.TLS != nil {
+		ws = append(ws, Warning{
+			path: ".tls",
+			msg:  "tls configured for a plain collector: it will be ignored",
+		})
+	}
+	return ws, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditLog configures sending audit events for HTTP API requests to a syslog
+// collector.
+//
+// Requests that may change Clair's state, and requests refused for lack of
+// authorization, are audited.
+type AuditLog struct {
+	// The collector audit events are sent to.
+	Syslog Syslog `yaml:"syslog" json:"syslog"`
+	// Audit every request, including reads.
+	AllRequests bool `yaml:"all_requests,omitempty" json:"all_requests,omitempty"`
+}
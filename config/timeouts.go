@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RequestTimeouts configures how long HTTP API requests may run.
+//
+// When a request runs out of time, its context is canceled, which stops the
+// database queries and layer fetches it started, in the same way as when the
+// client goes away.
+type RequestTimeouts struct {
+	// Default is the timeout for requests not covered by "Endpoints". If 0,
+	// those requests aren't bounded.
+	Default Duration `yaml:"default,omitempty" json:"default,omitempty"`
+	// Endpoints maps path prefixes, such as
+	// "/matcher/api/v1/vulnerability_report/", to the timeout for requests
+	// under them. The longest matching prefix is used. A negative timeout
+	// means requests aren't bounded.
+	Endpoints map[string]Duration `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+}
+
+func (t *RequestTimeouts) validate(_ Mode) ([]Warning, error) {
+	if t.Default < 0 {
+		return nil, fmt.Errorf("negative default is invalid: %v", time.Duration(t.Default))
+	}
+	for p := range t.Endpoints {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("endpoint %q: must be an absolute path", p)
+		}
+	}
+	return t.lint()
+}
+
+func (t *RequestTimeouts) lint() (ws []Warning, err error) {
+	const index = "/indexer/api/v1/index_report"
+	if t.Default > 0 {
+		covered := false
+		for p := range t.Endpoints {
+			if strings.HasPrefix(index, p) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			ws = append(ws, Warning{
+				path: ".default",
+				msg:  fmt.Sprintf("default also bounds indexing, which can take minutes; consider an entry for %q", index),
+			})
+		}
+	}
+	for p, v := range t.Endpoints {
+		if d := time.Duration(v); d > 0 && d < time.Second {
+			ws = append(ws, Warning{
+				path: ".endpoints",
+				msg:  fmt.Sprintf("endpoint %q: very short timeout (%v) will fail most requests", p, d),
+			})
+		}
+	}
+	return ws, nil
+}
@@ -10,7 +10,8 @@
 
 // TLS describes some TLS settings.
 //
-// These are currently only used in the Notifier. Using the environment
+// These are used for serving the HTTP API and for connecting to notification
+// brokers. Using the environment
 // variables "SSL_CERT_DIR" or "SSL_CERT_FILE" or modifying the system's trust
 // store are the ways to modify root CAs for all outgoing TLS connections.
 type TLS struct {
@@ -24,6 +25,12 @@ type TLS struct {
 	Cert string `yaml:"cert" json:"cert"`
 	// The filesystem path where a tls private key can be read.
 	Key string `yaml:"key" json:"key"`
+	// The filesystem path where CA certificates for verifying client
+	// certificates can be read.
+	//
+	// If provided, clients are required to present a certificate signed by
+	// one of these CAs. This is only used when serving TLS.
+	ClientCA string `yaml:"client_ca,omitempty" json:"client_ca,omitempty"`
 }
 
 // Config returns a tls.Config modified according to the TLS struct.
@@ -73,7 +80,10 @@ func (t *TLS) validate(_ Mode) ([]Warning, error) {
 	if (t.Cert != "" || t.Key != "") && (t.Cert == "" || t.Key == "") {
 		return nil, errors.New("both tls cert and key are required")
 	}
-	for _, n := range []string{t.RootCA, t.Cert, t.Key} {
+	if t.ClientCA != "" && t.Cert == "" {
+		return nil, errors.New("tls client ca requires a cert and key")
+	}
+	for _, n := range []string{t.RootCA, t.Cert, t.Key, t.ClientCA} {
 		if n == "" {
 			continue
 		}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// ClientTransport configures connection handling for HTTP clients Clair uses,
+// per class of server. Classes without settings use Go's defaults.
+type ClientTransport struct {
+	// Intraservice applies to requests to other Clair services: Matchers'
+	// and Notifiers' requests to an Indexer, and Notifiers' requests to a
+	// Matcher.
+	Intraservice *HTTPTransport `yaml:"intraservice,omitempty" json:"intraservice,omitempty"`
+	// Notifier applies to webhook deliveries and re-scan subscription
+	// callbacks.
+	Notifier *HTTPTransport `yaml:"notifier,omitempty" json:"notifier,omitempty"`
+}
+
+// HTTPTransport describes how an HTTP client manages its connections.
+type HTTPTransport struct {
+	// DisableHTTP2 prevents negotiating HTTP/2, so every request uses an
+	// HTTP/1.1 connection.
+	DisableHTTP2 bool `yaml:"disable_http2,omitempty" json:"disable_http2,omitempty"`
+	// MaxIdleConnsPerHost is the number of idle connections kept open to each
+	// host for reuse. If unset, Go's default of 2 is used.
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host,omitempty" json:"max_idle_conns_per_host,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// IdleConnTimeout is how long an idle connection is kept open. If unset,
+	// Go's default of 90 seconds is used.
+	IdleConnTimeout Duration `yaml:"idle_conn_timeout,omitempty" json:"idle_conn_timeout,omitempty"`
+}
+
+func (t *HTTPTransport) validate(_ Mode) ([]Warning, error) {
+	if t.MaxIdleConnsPerHost < 0 {
+		return nil, errors.New("max_idle_conns_per_host must not be negative")
+	}
+	if t.IdleConnTimeout < 0 {
+		return nil, errors.New("idle_conn_timeout must not be negative")
+	}
+	return t.lint()
+}
+
+func (t *HTTPTransport) lint() (ws []Warning, err error) {
+	if t.IdleConnTimeout != 0 && t.IdleConnTimeout < Duration(time.Second) {
+		ws = append(ws, Warning{
+			path: ".idle_conn_timeout",
+			msg:  "idle connections will be closed almost immediately; connections are unlikely to be reused",
+		})
+	}
+	return ws, nil
+}
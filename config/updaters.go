@@ -1,5 +1,12 @@
 package config
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
 // Updaters configures updater behavior.
 type Updaters struct {
 	// Filter is a regexp that disallows updaters that do not match from
@@ -29,4 +36,268 @@ type Updaters struct {
 	// "suse"
 	// "ubuntu"
 	Sets []string `yaml:"sets,omitempty" json:"sets,omitempty"`
+	// Mirrors rewrites updater requests to internal mirrors of upstream
+	// data sources. The first entry with a matching "From" prefix is used.
+	Mirrors []UpdaterMirror `yaml:"mirrors,omitempty" json:"mirrors,omitempty"`
+	// Signatures verifies updater downloads against detached OpenPGP
+	// signatures published by the data source. The first entry with a
+	// matching "URL" prefix is used.
+	Signatures []UpdaterSignature `yaml:"signatures,omitempty" json:"signatures,omitempty"`
+	// Feeds configures additional updaters ingesting OVAL or CSAF advisory
+	// feeds, such as an organization's internal advisories.
+	Feeds []UpdaterFeed `yaml:"feeds,omitempty" json:"feeds,omitempty"`
+	// RuntimeEOL configures an updater reporting language runtimes that have
+	// reached the end of their support. If unset, runtimes aren't checked
+	// against end-of-life schedules.
+	RuntimeEOL *UpdaterRuntimeEOL `yaml:"runtime_eol,omitempty" json:"runtime_eol,omitempty"`
+	// Budgets bounds the time and bytes each updater may use in one update
+	// run. The first entry with a matching "Updater" prefix is used.
+	Budgets []UpdaterBudget `yaml:"budgets,omitempty" json:"budgets,omitempty"`
+}
+
+func (u *Updaters) validate(_ Mode) ([]Warning, error) {
+	seen := make(map[string]struct{}, len(u.Feeds))
+	for _, f := range u.Feeds {
+		if _, ok := seen[f.Name]; ok {
+			return nil, fmt.Errorf("feed: duplicate name %q", f.Name)
+		}
+		seen[f.Name] = struct{}{}
+	}
+	return u.lint()
+}
+
+func (u *Updaters) lint() (ws []Warning, err error) {
+	for i, b := range u.Budgets {
+		for _, prev := range u.Budgets[:i] {
+			if strings.HasPrefix(b.Updater, prev.Updater) {
+				ws = append(ws, Warning{
+					path: ".budgets",
+					msg:  fmt.Sprintf("budget %q is never used: %q comes first", b.Updater, prev.Updater),
+				})
+				break
+			}
+		}
+	}
+	return ws, nil
+}
+
+// UpdaterMirror maps an upstream URL prefix to a mirror.
+type UpdaterMirror struct {
+	// From is a URL prefix, such as "https://security.access.redhat.com/data/".
+	From string `yaml:"from" json:"from"`
+	// To is the prefix substituted for "From".
+	To string `yaml:"to" json:"to"`
+	// ChecksumSuffix, if set, causes downloads from the mirror to be verified.
+	// The suffix is appended to the rewritten URL to locate a file containing
+	// the SHA-256 digest of the response body, in the format written by
+	// sha256sum(1).
+	ChecksumSuffix string `yaml:"checksum_suffix,omitempty" json:"checksum_suffix,omitempty"`
+}
+
+func (m *UpdaterMirror) validate(_ Mode) ([]Warning, error) {
+	for _, s := range []string{m.From, m.To} {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("mirror: bad url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("mirror: url %q must be http or https", s)
+		}
+	}
+	return m.lint()
+}
+
+func (m *UpdaterMirror) lint() (ws []Warning, err error) {
+	if strings.HasSuffix(m.From, "/") != strings.HasSuffix(m.To, "/") {
+		ws = append(ws, Warning{
+			msg: "`from` and `to` disagree on a trailing \"/\"",
+		})
+	}
+	return ws, nil
+}
+
+// UpdaterSignature configures signature verification for the downloads under
+// a URL prefix.
+type UpdaterSignature struct {
+	// Name identifies the entry in logs and errors. It's usually the name of
+	// the updater fetching from "URL".
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// URL is a URL prefix, such as "https://security.access.redhat.com/data/".
+	URL string `yaml:"url" json:"url"`
+	// Keyring is the path to a file of trusted OpenPGP public keys, either
+	// armored or binary.
+	Keyring string `yaml:"keyring" json:"keyring"`
+	// Suffix is appended to a download's URL to locate its detached
+	// signature. Defaults to ".asc".
+	Suffix string `yaml:"suffix,omitempty" json:"suffix,omitempty"`
+	// Strict refuses downloads that don't have a published signature. By
+	// default, they're allowed with a warning.
+	Strict bool `yaml:"strict,omitempty" json:"strict,omitempty"`
+}
+
+func (s *UpdaterSignature) validate(_ Mode) ([]Warning, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("signature: bad url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("signature: url %q must be http or https", s.URL)
+	}
+	if s.Keyring == "" {
+		return nil, fmt.Errorf("signature: %q: keyring must be provided", s.URL)
+	}
+	if s.Suffix == "" {
+		s.Suffix = DefaultSignatureSuffix
+	}
+	return s.lint()
+}
+
+func (s *UpdaterSignature) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(s.URL); err == nil && u.Scheme == "http" && !s.Strict {
+		ws = append(ws, Warning{
+			msg: "signatures fetched over http without `strict` can be stripped in transit",
+		})
+	}
+	return ws, nil
+}
+
+// UpdaterFeed configures an updater for an OVAL or CSAF advisory feed.
+type UpdaterFeed struct {
+	// Name identifies the feed. The updater is named "feed-" followed by
+	// this, and it must be unique.
+	Name string `yaml:"name" json:"name"`
+	// URL is where the feed is fetched from.
+	URL string `yaml:"url" json:"url"`
+	// Format is the format of the feed: "oval" or "csaf".
+	Format string `yaml:"format" json:"format"`
+	// Namespace names the packages the feed's advisories are about: either a
+	// distribution, as its os-release "ID" optionally followed by a colon
+	// and its "VERSION_ID" (for example, "rhel:9" or "alpine"), or a
+	// language package repository as "repo:" followed by its name (for
+	// example, "repo:pypi").
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// VersionScheme is how package versions are compared: "rpm", "dpkg",
+	// "apk", "semver", or "pep440". OVAL feeds must use "rpm" or "dpkg".
+	VersionScheme string `yaml:"version_scheme" json:"version_scheme"`
+	// Compression is the compression of the feed: "gzip", "bzip2", "zstd",
+	// or "none". By default, it's guessed from the response.
+	Compression string `yaml:"compression,omitempty" json:"compression,omitempty"`
+}
+
+func (f *UpdaterFeed) validate(_ Mode) ([]Warning, error) {
+	if f.Name == "" || strings.ContainsAny(f.Name, " \t/") {
+		return nil, fmt.Errorf("feed: bad name %q", f.Name)
+	}
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return nil, fmt.Errorf("feed %q: bad url: %w", f.Name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("feed %q: url %q must be http or https", f.Name, f.URL)
+	}
+	f.Format = strings.ToLower(f.Format)
+	f.VersionScheme = strings.ToLower(f.VersionScheme)
+	switch f.VersionScheme {
+	case "rpm", "dpkg", "apk", "semver", "pep440":
+	default:
+		return nil, fmt.Errorf("feed %q: unknown version scheme %q", f.Name, f.VersionScheme)
+	}
+	switch f.Format {
+	case "oval":
+		if f.VersionScheme != "rpm" && f.VersionScheme != "dpkg" {
+			return nil, fmt.Errorf("feed %q: oval feeds must use the rpm or dpkg version scheme", f.Name)
+		}
+	case "csaf":
+	default:
+		return nil, fmt.Errorf("feed %q: unknown format %q", f.Name, f.Format)
+	}
+	kind, name, _ := strings.Cut(f.Namespace, ":")
+	if kind == "" || (kind == "repo" && name == "") {
+		return nil, fmt.Errorf("feed %q: bad namespace %q", f.Name, f.Namespace)
+	}
+	switch f.Compression {
+	case "", "auto", "none", "gz", "gzip", "bz2", "bzip2", "zstd":
+	default:
+		return nil, fmt.Errorf("feed %q: unknown compression %q", f.Name, f.Compression)
+	}
+	return f.lint()
+}
+
+func (f *UpdaterFeed) lint() (ws []Warning, err error) {
+	if u, err := url.Parse(f.URL); err == nil && u.Scheme == "http" {
+		ws = append(ws, Warning{
+			msg: "feed fetched over http can be modified in transit; consider configuring a signature",
+		})
+	}
+	return ws, nil
+}
+
+// UpdaterRuntimeEOL configures the updater for language runtime end-of-life
+// schedules.
+type UpdaterRuntimeEOL struct {
+	// URL is the base of an API serving schedules in the format of
+	// endoflife.date, such as a mirror of it. A product's schedule is
+	// fetched from the product's name followed by ".json".
+	//
+	// The default is "https://endoflife.date/api/".
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+}
+
+func (e *UpdaterRuntimeEOL) validate(_ Mode) ([]Warning, error) {
+	if e.URL == "" {
+		e.URL = DefaultRuntimeEOLURL
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return nil, fmt.Errorf("runtime_eol: bad url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("runtime_eol: url %q must be http or https", e.URL)
+	}
+	if !strings.HasSuffix(e.URL, "/") {
+		e.URL += "/"
+	}
+	return nil, nil
+}
+
+// UpdaterBudget bounds the updaters with names starting with a prefix.
+//
+// An updater exceeding its budget is stopped for the rest of the update run,
+// so one slow source can't hold up the others. Its download is kept and
+// resumed on the next run, if the source supports range requests.
+type UpdaterBudget struct {
+	// Updater is an updater name prefix, such as "osv/". The empty prefix
+	// matches every updater.
+	Updater string `yaml:"updater,omitempty" json:"updater,omitempty"`
+	// A time.ParseDuration parsable string
+	//
+	// Time is how long the updater may run. If 0, it's not bounded.
+	Time Duration `yaml:"time,omitempty" json:"time,omitempty"`
+	// Bytes is how much the updater may download. If 0, it's not bounded.
+	Bytes int64 `yaml:"bytes,omitempty" json:"bytes,omitempty"`
+}
+
+func (b *UpdaterBudget) validate(_ Mode) ([]Warning, error) {
+	if b.Time < 0 {
+		return nil, fmt.Errorf("budget %q: negative time is invalid: %v", b.Updater, time.Duration(b.Time))
+	}
+	if b.Bytes < 0 {
+		return nil, fmt.Errorf("budget %q: negative bytes is invalid: %d", b.Updater, b.Bytes)
+	}
+	return b.lint()
+}
+
+func (b *UpdaterBudget) lint() (ws []Warning, err error) {
+	if b.Time == 0 && b.Bytes == 0 {
+		ws = append(ws, Warning{
+			msg: fmt.Sprintf("budget %q: neither time nor bytes set; updaters it matches are unbounded", b.Updater),
+		})
+	}
+	if d := time.Duration(b.Time); d > 0 && d < time.Minute {
+		ws = append(ws, Warning{
+			path: ".time",
+			msg:  fmt.Sprintf("budget %q: very short time (%v) may stop updaters from ever finishing", b.Updater, d),
+		})
+	}
+	return ws, nil
 }
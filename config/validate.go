@@ -11,6 +11,18 @@ func Validate(c *Config) ([]Warning, error) {
 	})
 }
 
+// ValidateAll is like Validate, but doesn't stop at the first error. Every
+// error found is returned, annotated with where in the configuration it was
+// found.
+func ValidateAll(c *Config) ([]Warning, []error) {
+	return forEachAll(c, func(i interface{}) ([]Warning, error) {
+		if v, ok := i.(validator); ok {
+			return v.validate(c.Mode)
+		}
+		return nil, nil
+	})
+}
+
 // Types that want complex defaults or to fail validation can implement the
 // validator interface.
 type validator interface {
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProbeTimeout is the amount of time a single probe is allowed to run.
+const ProbeTimeout = 5 * time.Second
+
+// Check reports whether a dependency is usable, returning a non-nil error if
+// it's not.
+//
+// Checks should be cheap and honor the passed Context.
+type Check func(context.Context) error
+
+var probes = struct {
+	sync.RWMutex
+	m map[string]Check
+}{
+	m: make(map[string]Check),
+}
+
+// Register adds a Check to be run by the ProbeHandler, replacing any Check
+// previously registered with the same name.
+//
+// Names are conventionally of the form "<component>/<dependency>", e.g.
+// "indexer/database".
+func Register(name string, c Check) {
+	probes.Lock()
+	defer probes.Unlock()
+	probes.m[name] = c
+}
+
+// ProbeReport is the response body of the ProbeHandler.
+type ProbeReport struct {
+	Probes  []ProbeResult `json:"probes"`
+	Healthy bool          `json:"healthy"`
+}
+
+// ProbeResult is the outcome of a single Check.
+type ProbeResult struct {
+	Name    string `json:"name"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+	Healthy bool   `json:"healthy"`
+}
+
+// Probe runs all registered Checks concurrently and reports the results,
+// sorted by name.
+func Probe(ctx context.Context) *ProbeReport {
+	probes.RLock()
+	res := make([]ProbeResult, 0, len(probes.m))
+	cs := make([]Check, 0, len(probes.m))
+	for n, c := range probes.m {
+		res = append(res, ProbeResult{Name: n})
+		cs = append(cs, c)
+	}
+	probes.RUnlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(cs))
+	for i := range cs {
+		go func(r *ProbeResult, c Check) {
+			defer wg.Done()
+			ctx, done := context.WithTimeout(ctx, ProbeTimeout)
+			defer done()
+			start := time.Now()
+			err := c(ctx)
+			r.Latency = time.Since(start).String()
+			r.Healthy = err == nil
+			if err != nil {
+				r.Error = err.Error()
+			}
+		}(&res[i], cs[i])
+	}
+	wg.Wait()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	rep := ProbeReport{Probes: res, Healthy: true}
+	for _, r := range res {
+		rep.Healthy = rep.Healthy && r.Healthy
+	}
+	return &rep
+}
+
+// ProbeHandler runs all registered Checks and responds with a JSON-encoded
+// ProbeReport.
+//
+// The response status is 200 OK if every Check succeeded and 503 "Service
+// Unavailable" otherwise.
+func ProbeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Cache-Control", "no-store")
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		rep := Probe(r.Context())
+		h.Set("Content-Type", "application/json")
+		if !rep.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(rep)
+	})
+}
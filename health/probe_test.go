@@ -0,0 +1,58 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/clair/v4/health"
+)
+
+func TestProbeHandler(t *testing.T) {
+	srv := httptest.NewServer(health.ProbeHandler())
+	defer srv.Close()
+	get := func(t *testing.T) (*http.Response, *health.ProbeReport) {
+		t.Helper()
+		res, err := srv.Client().Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var rep health.ProbeReport
+		if err := json.NewDecoder(res.Body).Decode(&rep); err != nil {
+			t.Fatal(err)
+		}
+		return res, &rep
+	}
+
+	health.Register("test/ok", func(context.Context) error { return nil })
+	res, rep := get(t)
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("status: got: %d, want: %d", got, want)
+	}
+	if !rep.Healthy {
+		t.Errorf("expected healthy report: %+v", rep)
+	}
+
+	health.Register("test/bad", func(context.Context) error { return errors.New("down") })
+	res, rep = get(t)
+	if got, want := res.StatusCode, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status: got: %d, want: %d", got, want)
+	}
+	if rep.Healthy {
+		t.Errorf("expected unhealthy report: %+v", rep)
+	}
+	if got, want := len(rep.Probes), 2; got != want {
+		t.Fatalf("probes: got: %d, want: %d", got, want)
+	}
+	// Results are sorted by name.
+	if p := rep.Probes[0]; p.Name != "test/bad" || p.Healthy || p.Error != "down" {
+		t.Errorf("unexpected result: %+v", p)
+	}
+	if p := rep.Probes[1]; p.Name != "test/ok" || !p.Healthy {
+		t.Errorf("unexpected result: %+v", p)
+	}
+}
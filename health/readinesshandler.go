@@ -2,11 +2,42 @@
 
 import (
 	"net/http"
+	"sync"
 	"sync/atomic"
 )
 
 var ready *uint32 = new(uint32)
 
+var blocks = struct {
+	sync.RWMutex
+	m map[string]struct{}
+}{
+	m: make(map[string]struct{}),
+}
+
+// Block causes the ReadinessHandler to serve 503 Service Unavailable until
+// Unblock is called with the same name, regardless of calls to Ready.
+//
+// Names follow the same convention as Register.
+func Block(name string) {
+	blocks.Lock()
+	defer blocks.Unlock()
+	blocks.m[name] = struct{}{}
+}
+
+// Unblock removes a block added by Block.
+func Unblock(name string) {
+	blocks.Lock()
+	defer blocks.Unlock()
+	delete(blocks.m, name)
+}
+
+func blocked() bool {
+	blocks.RLock()
+	defer blocks.RUnlock()
+	return len(blocks.m) != 0
+}
+
 // Ready instructs the ReadinessHandler to begin serving 200 OK status.
 func Ready() {
 	atomic.StoreUint32(ready, uint32(1))
@@ -19,7 +50,8 @@ func Unready() {
 }
 
 // ReadinessHandler will return a 200 OK or 503 "Service Unavailable" status
-// depending on whether the Ready or Unready functions have been called.
+// depending on whether the Ready or Unready functions have been called, and
+// whether any blocks are in place.
 //
 // The Ready() method must be called to begin returning 200 OK.
 func ReadinessHandler() http.Handler {
@@ -34,7 +66,7 @@ func ReadinessHandler() http.Handler {
 			return
 		}
 
-		if atomic.LoadUint32(ready) != 1 {
+		if atomic.LoadUint32(ready) != 1 || blocked() {
 			w.WriteHeader(http.StatusServiceUnavailable)
 		}
 	})
@@ -38,6 +38,24 @@ func TestReadinessHandler(t *testing.T) {
 		t.Fatalf("expected %d got %d", http.StatusOK, resp.StatusCode)
 	}
 
+	// a block should return StatusServiceUnavailable until removed
+	health.Block("test/block")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to do request: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	health.Unblock("test/block")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to do request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
 	// signal to handler that process is unready. should return StatusServiceUnavailable
 	health.Unready()
 	resp, err = client.Do(req)
@@ -0,0 +1,100 @@
+package httptransport
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	rr "github.com/ldelossa/responserecorder"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+// AccessLog returns an http.Handler wrapping the provided Handler that logs
+// requests as described by the provided AccessLog.
+func accessLog(cfg *config.AccessLog, next http.Handler) http.Handler {
+	return &accessLogger{
+		next:   next,
+		sample: cfg.Sample,
+		slow:   time.Duration(cfg.SlowThreshold),
+		status: cfg.ErrorStatus,
+	}
+}
+
+// AccessLogger is the handler returned by accessLog.
+type accessLogger struct {
+	next   http.Handler
+	sample float64
+	slow   time.Duration
+	status int
+}
+
+// AccessEntry holds values discovered by handlers that should be included in
+// the access log.
+type accessEntry struct {
+	digest claircore.Digest
+}
+
+type accessEntryKey struct{}
+
+// NoteDigest records the manifest digest a request is about, for inclusion in
+// the access log.
+func noteDigest(ctx context.Context, d claircore.Digest) {
+	if e, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		e.digest = d
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (l *accessLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var e accessEntry
+	ctx := context.WithValue(r.Context(), accessEntryKey{}, &e)
+	rec := rr.NewResponseRecorder(w)
+	start := time.Now()
+	l.next.ServeHTTP(rec, r.WithContext(ctx))
+	dur := time.Since(start)
+	code := rec.StatusCode()
+	if code == 0 {
+		// Handler never called WriteHeader.
+		code = http.StatusOK
+	}
+
+	reason := l.reason(code, dur)
+	if reason == "" {
+		return
+	}
+
+	ctx = zlog.ContextWithValues(r.Context(), "component", "httptransport/accessLogger.ServeHTTP")
+	ev := zlog.Info(ctx).
+		Str("reason", reason).
+		Str("remote_addr", r.RemoteAddr).
+		Str("method", r.Method).
+		Str("request_uri", r.RequestURI).
+		Int("status", code).
+		Int("length", rec.ContentLength()).
+		Dur("duration", dur)
+	if cl, ok := bearerClaims(r); ok {
+		ev = ev.Str("issuer", cl.Issuer).
+			Str("subject", cl.Subject)
+	}
+	if e.digest.String() != "" {
+		ev = ev.Stringer("manifest", e.digest)
+	}
+	ev.Msg("handled request")
+}
+
+// Reason reports why a request should be logged, or the empty string if it
+// shouldn't be.
+func (l *accessLogger) reason(code int, dur time.Duration) string {
+	switch {
+	case code >= l.status:
+		return "error"
+	case l.slow > 0 && dur >= l.slow:
+		return "slow"
+	case l.sample > 0 && rand.Float64() < l.sample:
+		return "sampled"
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package httptransport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+)
+
+func TestAccessLogReason(t *testing.T) {
+	l := accessLog(&config.AccessLog{
+		SlowThreshold: config.Duration(time.Second),
+		ErrorStatus:   500,
+	}, nil).(*accessLogger)
+	tt := []struct {
+		Name string
+		Code int
+		Dur  time.Duration
+		Want string
+	}{
+		{Name: "Fast", Code: http.StatusOK, Dur: time.Millisecond, Want: ""},
+		{Name: "Slow", Code: http.StatusOK, Dur: 2 * time.Second, Want: "slow"},
+		{Name: "Error", Code: http.StatusBadGateway, Dur: time.Millisecond, Want: "error"},
+		{Name: "SlowError", Code: http.StatusInternalServerError, Dur: 2 * time.Second, Want: "error"},
+		{Name: "ClientError", Code: http.StatusNotFound, Dur: time.Millisecond, Want: ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got, want := l.reason(tc.Code, tc.Dur), tc.Want; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		})
+	}
+
+	l.sample = 1
+	if got, want := l.reason(http.StatusOK, 0), "sampled"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestAccessLogDigest(t *testing.T) {
+	const d = `sha256:0000000000000000000000000000000000000000000000000000000000000000`
+	want, err := claircore.ParseDigest(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got claircore.Digest
+	h := accessLog(&config.AccessLog{ErrorStatus: 500}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := getDigest(w, r); err != nil {
+			t.Error(err)
+		}
+		got = r.Context().Value(accessEntryKey{}).(*accessEntry).digest
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, IndexReportAPIPath+d, nil))
+	if got.String() != want.String() {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,289 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/registry"
+	"github.com/quay/clair/v4/matcher/admission"
+	"github.com/quay/clair/v4/matcher/policy"
+)
+
+const (
+	// MaxAdmissionSize is the largest admission request accepted.
+	maxAdmissionSize = 4 << 20
+	// AdmissionConcurrency is the number of images checked at once for a
+	// single request.
+	admissionConcurrency = 4
+	// AdmissionIndexTimeout bounds the background indexing of images that
+	// haven't been scanned.
+	admissionIndexTimeout = 10 * time.Minute
+	// AdmissionMaxMessages is the number of violations listed per image in
+	// an AdmissionReview denial.
+	admissionMaxMessages = 5
+)
+
+// AdmissionImage is the outcome of checking a single image.
+type admissionImage struct {
+	Image      string             `json:"image"`
+	Manifest   *claircore.Digest  `json:"manifest_hash,omitempty"`
+	Scanned    bool               `json:"scanned"`
+	Pass       bool               `json:"pass"`
+	Violations []policy.Violation `json:"violations"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// AdmissionResult is the response to an image list.
+type admissionResult struct {
+	Policy  string           `json:"policy"`
+	Allowed bool             `json:"allowed"`
+	Images  []admissionImage `json:"images"`
+}
+
+// AdmissionReview checks the images in a Kubernetes AdmissionReview, or a
+// plain list of images, against the policy named by the "policy" query
+// parameter.
+//
+// Images that haven't been indexed are submitted for indexing in the
+// background and fail the check, unless "allow_unscanned" is set. An
+// AdmissionReview is answered with an AdmissionReview, so the endpoint can
+// be used directly by a validating webhook.
+func (h *MatcherV1) admissionReview(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.admissionReview")
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	q := r.URL.Query()
+	pname := strings.TrimSpace(q.Get("policy"))
+	if pname == "" {
+		apiError(ctx, w, http.StatusBadRequest, `missing "policy" query param`)
+		return
+	}
+	allowUnscanned := false
+	if v := q.Get("allow_unscanned"); v != "" {
+		var err error
+		allowUnscanned, err = strconv.ParseBool(v)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "bad allow_unscanned value: %v", err)
+			return
+		}
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionSize+1))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "unable to read request: %v", err)
+		return
+	}
+	if len(b) > maxAdmissionSize {
+		apiError(ctx, w, http.StatusRequestEntityTooLarge, "request over %d bytes", maxAdmissionSize)
+		return
+	}
+	in, err := admission.Decode(b)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	images, err := in.Images()
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	s := h.policies(ctx, w)
+	if s == nil {
+		return
+	}
+	p, ok, err := s.Policy(ctx, pname)
+	switch {
+	case err != nil:
+		apiError(ctx, w, http.StatusInternalServerError, "could not get policy: %v", err)
+		return
+	case !ok:
+		apiError(ctx, w, http.StatusNotFound, "policy %q not found", pname)
+		return
+	}
+
+	res := admissionResult{
+		Policy:  p.Name,
+		Allowed: true,
+		Images:  make([]admissionImage, len(images)),
+	}
+	now := time.Now()
+	eg, ectx := errgroup.WithContext(ctx)
+	eg.SetLimit(admissionConcurrency)
+	for i, ref := range images {
+		i, ref := i, ref
+		eg.Go(func() error {
+			res.Images[i] = h.admitImage(ectx, p, ref, now)
+			return nil
+		})
+	}
+	eg.Wait()
+	for _, img := range res.Images {
+		switch {
+		case img.Scanned && !img.Pass:
+			res.Allowed = false
+		case !img.Scanned && !allowUnscanned:
+			res.Allowed = false
+		}
+	}
+	zlog.Debug(ctx).
+		Str("policy", p.Name).
+		Int("images", len(images)).
+		Bool("allowed", res.Allowed).
+		Msg("checked images")
+
+	var out interface{} = &res
+	if in.Review != nil {
+		out = admissionResponse(in.Review, &res)
+	}
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(out)
+}
+
+// AdmitImage checks a single image against "p".
+func (h *MatcherV1) admitImage(ctx context.Context, p *policy.Policy, ref string, now time.Time) admissionImage {
+	ctx = zlog.ContextWithValues(ctx, "image", ref)
+	out := admissionImage{Image: ref, Violations: []policy.Violation{}}
+	pr, err := name.ParseReference(ref)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	var d claircore.Digest
+	indexed := false
+	if pd, ok := pr.(name.Digest); ok {
+		// A pinned image may already be known, which saves contacting the
+		// registry. It may also be an image index, so it's resolved if not.
+		if d, err = claircore.ParseDigest(pd.DigestStr()); err == nil {
+			indexed, err = h.indexed(ctx, d)
+		}
+	}
+	if !indexed && err == nil {
+		if d, err = registry.Digest(ctx, nil, ref); err == nil {
+			indexed, err = h.indexed(ctx, d)
+		}
+	}
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	out.Manifest = &d
+	if !indexed {
+		h.indexLater(ref)
+		return out
+	}
+	vr, _, err := h.report(ctx, d)
+	switch {
+	case err != nil:
+		out.Error = err.Error()
+		return out
+	case vr == nil: // Matcher not initialized yet.
+		return out
+	}
+	pres := p.Evaluate(vr, now)
+	out.Scanned = true
+	out.Pass = pres.Pass
+	out.Violations = pres.Violations
+	return out
+}
+
+// Indexed reports whether the indexer has a successful index report for
+// "d".
+func (h *MatcherV1) indexed(ctx context.Context, d claircore.Digest) (bool, error) {
+	ir, ok, err := h.indexerSrv.IndexReport(ctx, d)
+	if err != nil {
+		return false, err
+	}
+	return ok && ir.Success, nil
+}
+
+// IndexLater resolves and indexes "ref" in the background, unless that's
+// already happening.
+func (h *MatcherV1) indexLater(ref string) {
+	if _, loaded := h.admitting.LoadOrStore(ref, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer h.admitting.Delete(ref)
+		ctx, done := context.WithTimeout(context.Background(), admissionIndexTimeout)
+		defer done()
+		ctx = zlog.ContextWithValues(ctx,
+			"component", "httptransport/MatcherV1.indexLater",
+			"image", ref)
+		m, err := registry.Resolve(ctx, nil, ref)
+		if err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to resolve image")
+			return
+		}
+		if _, err := h.indexerSrv.Index(ctx, m); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to index image")
+			return
+		}
+		zlog.Info(ctx).Stringer("manifest", m.Hash).Msg("indexed image for admission")
+	}()
+}
+
+// AdmissionResponse builds the AdmissionReview answering "rev".
+func admissionResponse(rev *admission.Review, res *admissionResult) *admission.Review {
+	out := admission.Review{
+		APIVersion: rev.APIVersion,
+		Kind:       "AdmissionReview",
+		Response: &admission.Response{
+			UID:     rev.Request.UID,
+			Allowed: res.Allowed,
+		},
+	}
+	if out.APIVersion == "" {
+		out.APIVersion = "admission.k8s.io/v1"
+	}
+	var msgs []string
+	for _, img := range res.Images {
+		var m string
+		switch {
+		case img.Error != "":
+			m = fmt.Sprintf("%s: not scanned: %s", img.Image, img.Error)
+		case !img.Scanned:
+			m = fmt.Sprintf("%s: not scanned yet", img.Image)
+		case !img.Pass:
+			vs := make([]string, 0, admissionMaxMessages)
+			for i, v := range img.Violations {
+				if i == admissionMaxMessages {
+					vs = append(vs, fmt.Sprintf("and %d more", len(img.Violations)-i))
+					break
+				}
+				vs = append(vs, v.Message)
+			}
+			m = fmt.Sprintf("%s: violates policy %q: %s", img.Image, res.Policy, strings.Join(vs, "; "))
+		default:
+			continue
+		}
+		if res.Allowed {
+			// Only unscanned images can get here when allowed.
+			out.Response.Warnings = append(out.Response.Warnings, m)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	if !res.Allowed {
+		out.Response.Status = &admission.Status{
+			Code:    http.StatusForbidden,
+			Message: strings.Join(msgs, "\n"),
+		}
+	}
+	return &out
+}
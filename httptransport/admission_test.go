@@ -0,0 +1,130 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/admission"
+	"github.com/quay/clair/v4/matcher/policy"
+)
+
+func TestAdmissionReview(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+		image  = "quay.io/org/repo@" + digest
+	)
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{
+				Hash:     d,
+				Packages: map[string]*claircore.Package{"1": {ID: "1", Name: "openssl"}},
+				Success:  true,
+			}, true, nil
+		},
+	}
+	m := &policyMock{
+		Mock: &matcher.Mock{
+			Initialized_: func(context.Context) (bool, error) { return true, nil },
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{
+					Hash:     ir.Hash,
+					Packages: ir.Packages,
+					Vulnerabilities: map[string]*claircore.Vulnerability{
+						"1": {ID: "1", Name: "CVE-2023-0001", NormalizedSeverity: claircore.Critical},
+					},
+					PackageVulnerabilities: map[string][]string{"1": {"1"}},
+				}, nil
+			},
+		},
+		m: map[string]policy.Policy{
+			"strict":  {Name: "strict", MaxSeverity: "high"},
+			"lenient": {Name: "lenient"},
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	post := func(t *testing.T, query, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/admission_review?"+query, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		return res
+	}
+	review := `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"abc",` +
+		`"kind":{"group":"","version":"v1","kind":"Pod"},` +
+		`"object":{"spec":{"containers":[{"name":"app","image":"` + image + `"}]}}}}`
+
+	t.Run("Deny", func(t *testing.T) {
+		res := post(t, "policy=strict", review, http.StatusOK)
+		defer res.Body.Close()
+		var got admission.Review
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Response == nil || got.Response.UID != "abc" {
+			t.Fatalf("bad response: %+v", got.Response)
+		}
+		if got.Response.Allowed || got.Response.Status == nil {
+			t.Errorf("got: %+v, want denial", got.Response)
+		}
+		if !strings.Contains(got.Response.Status.Message, "CVE-2023-0001") {
+			t.Errorf("message missing violation: %q", got.Response.Status.Message)
+		}
+	})
+	t.Run("Allow", func(t *testing.T) {
+		res := post(t, "policy=lenient", review, http.StatusOK)
+		defer res.Body.Close()
+		var got admission.Review
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Response.Allowed {
+			t.Errorf("got: %+v, want allowed", got.Response)
+		}
+	})
+	t.Run("List", func(t *testing.T) {
+		res := post(t, "policy=strict", `{"images":["`+image+`","`+image+`"]}`, http.StatusOK)
+		defer res.Body.Close()
+		var got admissionResult
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed || len(got.Images) != 1 {
+			t.Fatalf("got: %+v", got)
+		}
+		if img := got.Images[0]; !img.Scanned || img.Pass || len(img.Violations) != 1 {
+			t.Errorf("got: %+v", img)
+		}
+	})
+	t.Run("MissingPolicy", func(t *testing.T) {
+		post(t, "policy=missing", review, http.StatusNotFound).Body.Close()
+		post(t, "", review, http.StatusBadRequest).Body.Close()
+	})
+}
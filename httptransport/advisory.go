@@ -0,0 +1,151 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/advisory"
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// Advisories returns the matcher's internal advisory store, writing an error
+// response and returning nil if it doesn't have one.
+func (h *MatcherV1) advisories(ctx context.Context, w http.ResponseWriter) matcher.Advisories {
+	s, ok := h.srv.(matcher.Advisories)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "internal advisories not supported")
+		return nil
+	}
+	return s
+}
+
+// AdvisoryList lists the internal advisories, including withdrawn ones.
+func (h *MatcherV1) advisoryList(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.advisoryList")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	s := h.advisories(ctx, w)
+	if s == nil {
+		return
+	}
+	as, err := s.Advisories(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not list advisories: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(as)
+}
+
+// AdvisoryHandler serves a single internal advisory: GET returns it, PUT
+// creates or replaces it, and DELETE withdraws it.
+//
+// Changing advisories changes every report, so PUT and DELETE are only
+// allowed if the server requires authentication.
+func (h *MatcherV1) advisoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.advisoryHandler")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodDelete:
+		if !h.authenticated {
+			apiError(ctx, w, http.StatusForbidden, "changing advisories requires authentication to be configured")
+			return
+		}
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	s := h.advisories(ctx, w)
+	if s == nil {
+		return
+	}
+	name := path.Base(r.URL.Path)
+	ctx = zlog.ContextWithValues(ctx, "advisory", name)
+
+	var a *advisory.Advisory
+	switch r.Method {
+	case http.MethodGet:
+		var ok bool
+		var err error
+		a, ok, err = s.Advisory(ctx, name)
+		switch {
+		case err != nil:
+			apiError(ctx, w, http.StatusInternalServerError, "could not get advisory: %v", err)
+			return
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "advisory %q not found", name)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+	case http.MethodPut:
+		a = new(advisory.Advisory)
+		dec := codec.GetDecoder(r.Body)
+		err := dec.Decode(a)
+		codec.PutDecoder(dec)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not deserialize advisory: %v", err)
+			return
+		}
+		if a.Name == "" {
+			a.Name = name
+		}
+		if a.Name != name {
+			apiError(ctx, w, http.StatusBadRequest, "advisory name %q does not match path", a.Name)
+			return
+		}
+		a.UpdatedBy = ""
+		if id, ok := auth.IdentityFromContext(ctx); ok {
+			a.UpdatedBy = id.Subject
+		}
+		created, err := s.PutAdvisory(ctx, a)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, advisory.ErrInvalid):
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
+			return
+		default:
+			apiError(ctx, w, http.StatusInternalServerError, "could not store advisory: %v", err)
+			return
+		}
+		zlog.Info(ctx).
+			Str("by", a.UpdatedBy).
+			Bool("created", created).
+			Msg("advisory stored")
+		w.Header().Set("content-type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodDelete:
+		ok, err := s.WithdrawAdvisory(ctx, name)
+		switch {
+		case err != nil:
+			apiError(ctx, w, http.StatusInternalServerError, "could not withdraw advisory: %v", err)
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "advisory %q not found", name)
+		default:
+			zlog.Info(ctx).Msg("advisory withdrawn")
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	var err error
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(a)
+}
@@ -0,0 +1,121 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/advisory"
+)
+
+type advisoryMock struct {
+	*matcher.Mock
+	m map[string]advisory.Advisory
+}
+
+func (p *advisoryMock) Advisory(_ context.Context, name string) (*advisory.Advisory, bool, error) {
+	v, ok := p.m[name]
+	return &v, ok, nil
+}
+
+func (p *advisoryMock) Advisories(context.Context) ([]advisory.Advisory, error) {
+	out := []advisory.Advisory{}
+	for _, v := range p.m {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *advisoryMock) PutAdvisory(_ context.Context, v *advisory.Advisory) (bool, error) {
+	if err := v.Validate(); err != nil {
+		return false, err
+	}
+	_, ok := p.m[v.Name]
+	v.Withdrawn = nil
+	p.m[v.Name] = *v
+	return !ok, nil
+}
+
+func (p *advisoryMock) WithdrawAdvisory(_ context.Context, name string) (bool, error) {
+	v, ok := p.m[name]
+	if ok {
+		now := time.Now()
+		v.Withdrawn = &now
+		p.m[name] = v
+	}
+	return ok, nil
+}
+
+func TestAdvisoryHandler(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	m := &advisoryMock{
+		Mock: &matcher.Mock{},
+		m:    make(map[string]advisory.Advisory),
+	}
+	v1 := NewMatcherV1(ctx, "", m, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(method, path, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+	const body = `{"severity":"high","affected":[{"package":"acme-client","namespace":"repo:pypi","version_scheme":"pep440","fixed":"1.4.2"}]}`
+
+	do(http.MethodPut, "/advisory/ACME-1", body, http.StatusForbidden).Body.Close()
+	do(http.MethodDelete, "/advisory/ACME-1", "", http.StatusForbidden).Body.Close()
+	v1.authenticated = true
+
+	do(http.MethodPut, "/advisory/ACME-1", `{"severity":"urgent","affected":[]}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/advisory/ACME-1", `{"name":"ACME-2"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/advisory/ACME-1", body, http.StatusCreated).Body.Close()
+	do(http.MethodPut, "/advisory/ACME-1", body, http.StatusOK).Body.Close()
+	do(http.MethodGet, "/advisory/missing", "", http.StatusNotFound).Body.Close()
+	do(http.MethodDelete, "/advisory/ACME-1", "", http.StatusNoContent).Body.Close()
+	do(http.MethodDelete, "/advisory/missing", "", http.StatusNotFound).Body.Close()
+
+	res := do(http.MethodGet, "/advisory/ACME-1", "", http.StatusOK)
+	defer res.Body.Close()
+	var got advisory.Advisory
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Withdrawn == nil {
+		t.Errorf("advisory not withdrawn: %+v", got)
+	}
+	res = do(http.MethodGet, "/advisory", "", http.StatusOK)
+	defer res.Body.Close()
+	var as []advisory.Advisory
+	if err := json.NewDecoder(res.Body).Decode(&as); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(as), 1; got != want {
+		t.Errorf("got: %d advisories, want: %d", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"path"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+	oteltrace "go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
+
+	"github.com/quay/clair/v4/cmd"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dsse"
+	"github.com/quay/clair/v4/internal/intoto"
+)
+
+// Attestation serves an in-toto Statement attesting to the vulnerability
+// report for a manifest.
+//
+// The "name" query parameter sets the subject name, and the report filter
+// parameters are honored. With "predicate=true" only the predicate is
+// returned, suitable for "cosign attest --type vuln --predicate". Otherwise,
+// clients that ask for a DSSE envelope get the Statement signed, if signing
+// is configured.
+func (h *MatcherV1) attestation(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.attestation")
+
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+	ctx = httptrace.WithClientTrace(ctx, oteltrace.NewClientTrace(ctx))
+
+	manifest, err := claircore.ParseDigest(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	q := r.URL.Query()
+	filter, err := parseReportFilter(q)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	schema, err := reportSchema(w, r)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	bases, err := h.baseImages(q)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	start := time.Now()
+	vulnReport, ok := h.buildReport(ctx, w, manifest)
+	if !ok {
+		return
+	}
+	if filter != nil {
+		vulnReport = filter.apply(vulnReport)
+	}
+	out := annotatedVulnerabilityReport{
+		VulnerabilityReport: vulnReport,
+		Labels:              labelsFor(ctx, h.indexerSrv, manifest),
+		Annotations:         annotationsFor(ctx, h.srv, vulnReport),
+		Confidence:          assess(vulnReport),
+		Errata:              errata(vulnReport),
+	}
+	if hasAttribution(schema) {
+		out.Attribution = attribute(vulnReport, h.baseLayers(ctx, bases))
+	}
+	h.redact.fields(ctx).vulnerabilityReport(&out)
+	opts := intoto.VulnOpts{
+		Name:     q.Get("name"),
+		Version:  cmd.Version,
+		Started:  start,
+		Finished: time.Now(),
+	}
+	if ref, err := h.srv.LatestUpdateOperation(ctx, driver.VulnerabilityKind); err == nil {
+		opts.DBVersion = ref.String()
+	} else {
+		zlog.Info(ctx).Err(err).Msg("unable to determine latest update operation")
+	}
+	st := intoto.VulnStatement(manifest, out.in(schema), &opts)
+
+	var body interface{} = st
+	w.Header().Set("content-type", intoto.MediaType)
+	switch {
+	case q.Get("predicate") == "true":
+		body = st.Predicate
+		w.Header().Set("content-type", "application/json")
+	case h.signer != nil && wantEnvelope(r):
+		body, err = signReport(h.signer, intoto.MediaType, st)
+		if err != nil {
+			apiError(ctx, w, http.StatusInternalServerError, "unable to sign attestation: %v", err)
+			return
+		}
+		w.Header().Set("content-type", dsse.MediaType)
+	}
+	setCacheControl(w, h.Cache)
+
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(body)
+}
@@ -0,0 +1,96 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/internal/intoto"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestAttestation(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+		name   = "quay.io/projectquay/clair:nightly"
+	)
+	ref := uuid.New()
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+		LatestUpdateOperation_: func(context.Context, driver.UpdateKind) (uuid.UUID, error) { return ref, nil },
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/attestation/"+digest+"?name="+name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	if got, want := res.Header.Get("content-type"), intoto.MediaType; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	var st struct {
+		intoto.Statement
+		Predicate struct {
+			intoto.VulnPredicate
+			Scanner struct {
+				intoto.Scanner
+				Result claircore.VulnerabilityReport `json:"result"`
+			} `json:"scanner"`
+		} `json:"predicate"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.PredicateType, intoto.VulnPredicateType; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if len(st.Subject) != 1 {
+		t.Fatalf("got: %d subjects, want: 1", len(st.Subject))
+	}
+	if got, want := st.Subject[0].Name, name; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := st.Subject[0].Digest["sha256"], digest[len("sha256:"):]; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := st.Predicate.Scanner.DB.Version, ref.String(); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := st.Predicate.Scanner.Result.Hash.String(), digest; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
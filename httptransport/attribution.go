@@ -0,0 +1,102 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+// These are the values of attribution's "origin" member.
+const (
+	// OriginBase is a package introduced by a layer of a base image.
+	originBase = `base`
+	// OriginImage is a package introduced by a layer added on top of any
+	// base images.
+	originImage = `image`
+)
+
+// Attribution reports where a package with findings came from.
+type attribution struct {
+	// IntroducedIn is the layer that introduced the package.
+	IntroducedIn claircore.Digest `json:"introduced_in"`
+	// Origin is "base" if the layer also introduced packages in a base
+	// image, or "image" if not. It's empty if no base images are known.
+	Origin string `json:"origin,omitempty"`
+}
+
+// BaseImages returns the base image manifests to attribute findings to: the
+// configured ones, followed by any named in the repeatable "base_image" query
+// parameter.
+func (h *MatcherV1) baseImages(q url.Values) ([]claircore.Digest, error) {
+	out := append([]claircore.Digest(nil), h.bases...)
+	for _, s := range q["base_image"] {
+		d, err := claircore.ParseDigest(s)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q query param: %w", "base_image", err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// BaseLayers returns the set of layers that introduced packages in the
+// manifests "bases".
+//
+// Base images that aren't indexed are logged and skipped, so findings are
+// attributed to the bases that are. If none are, nil is returned.
+func (h *MatcherV1) baseLayers(ctx context.Context, bases []claircore.Digest) map[string]struct{} {
+	if len(bases) == 0 {
+		return nil
+	}
+	out := make(map[string]struct{})
+	for _, b := range bases {
+		ir, ok, err := h.indexerSrv.IndexReport(ctx, b)
+		switch {
+		case err != nil:
+			zlog.Warn(ctx).Err(err).Stringer("base_image", b).Msg("unable to fetch base image index report")
+			continue
+		case !ok:
+			zlog.Warn(ctx).Stringer("base_image", b).Msg("base image not indexed")
+			continue
+		}
+		for _, envs := range ir.Environments {
+			for _, e := range envs {
+				out[e.IntroducedIn.String()] = struct{}{}
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// Attribute returns the attribution of every package in "vr" with findings,
+// keyed by package ID. If "base" is nil, no origin is reported.
+//
+// Packages found in more than one place are attributed to the first layer
+// they were found in.
+func attribute(vr *claircore.VulnerabilityReport, base map[string]struct{}) map[string]attribution {
+	if len(vr.PackageVulnerabilities) == 0 {
+		return nil
+	}
+	out := make(map[string]attribution, len(vr.PackageVulnerabilities))
+	for id, vs := range vr.PackageVulnerabilities {
+		envs := vr.Environments[id]
+		if len(vs) == 0 || len(envs) == 0 || envs[0] == nil {
+			continue
+		}
+		a := attribution{IntroducedIn: envs[0].IntroducedIn}
+		if base != nil {
+			a.Origin = originImage
+			if _, ok := base[a.IntroducedIn.String()]; ok {
+				a.Origin = originBase
+			}
+		}
+		out[id] = a
+	}
+	return out
+}
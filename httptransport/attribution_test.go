@@ -0,0 +1,207 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// AttributedTo is how TestAttribution compares attributions.
+func attributedTo(layer, origin string) string {
+	return layer + " " + origin
+}
+
+func TestAttribution(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const (
+		image     = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+		base      = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		unindexed = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		baseLayer = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		topLayer  = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	)
+	env := func(l string) []*claircore.Environment {
+		return []*claircore.Environment{{IntroducedIn: claircore.MustParseDigest(l)}}
+	}
+	reports := map[string]*claircore.IndexReport{
+		image: {
+			Hash: claircore.MustParseDigest(image),
+			Packages: map[string]*claircore.Package{
+				"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+				"2": {ID: "2", Name: "requests", Version: "2.25.0"},
+				"3": {ID: "3", Name: "bash", Version: "5.1"},
+			},
+			Environments: map[string][]*claircore.Environment{
+				"1": env(baseLayer),
+				"2": env(topLayer),
+				"3": env(baseLayer),
+			},
+			Success: true,
+		},
+		base: {
+			Hash: claircore.MustParseDigest(base),
+			Packages: map[string]*claircore.Package{
+				"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+			},
+			Environments: map[string][]*claircore.Environment{
+				"1": env(baseLayer),
+			},
+			Success: true,
+		},
+	}
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			ir, ok := reports[d.String()]
+			return ir, ok, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{
+				Hash:         ir.Hash,
+				Packages:     ir.Packages,
+				Environments: ir.Environments,
+				Vulnerabilities: map[string]*claircore.Vulnerability{
+					"1": {ID: "1", Name: "CVE-2023-0001"},
+					"2": {ID: "2", Name: "CVE-2023-0002"},
+				},
+				// Package "3" has no findings, so isn't attributed.
+				PackageVulnerabilities: map[string][]string{
+					"1": {"1"},
+					"2": {"2"},
+				},
+			}, nil
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	get := func(t *testing.T, query string) (*http.Response, map[string]json.RawMessage) {
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/vulnerability_report/"+image+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return res, nil
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return res, body
+	}
+	// Attributed returns the attribution member as "layer origin" strings, as
+	// Digests can't be compared directly.
+	attributed := func(t *testing.T, body map[string]json.RawMessage) map[string]string {
+		var as map[string]attribution
+		if raw, ok := body["attribution"]; ok {
+			if err := json.Unmarshal(raw, &as); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got := make(map[string]string, len(as))
+		for id, a := range as {
+			got[id] = attributedTo(a.IntroducedIn.String(), a.Origin)
+		}
+		return got
+	}
+
+	t.Run("NoBase", func(t *testing.T) {
+		res, body := get(t, "")
+		if got, want := res.Header.Get(SchemaHeader), currentSchema; got != want {
+			t.Errorf("schema: got: %q, want: %q", got, want)
+		}
+		want := map[string]string{
+			"1": attributedTo(baseLayer, ""),
+			"2": attributedTo(topLayer, ""),
+		}
+		if got := attributed(t, body); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Query", func(t *testing.T) {
+		_, body := get(t, "?base_image="+base+"&base_image="+unindexed)
+		want := map[string]string{
+			"1": attributedTo(baseLayer, originBase),
+			"2": attributedTo(topLayer, originImage),
+		}
+		if got := attributed(t, body); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Configured", func(t *testing.T) {
+		v1.bases = []claircore.Digest{claircore.MustParseDigest(base)}
+		defer func() { v1.bases = nil }()
+		_, body := get(t, "")
+		want := map[string]string{
+			"1": attributedTo(baseLayer, originBase),
+			"2": attributedTo(topLayer, originImage),
+		}
+		if got := attributed(t, body); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Unindexed", func(t *testing.T) {
+		_, body := get(t, "?base_image="+unindexed)
+		want := map[string]string{
+			"1": attributedTo(baseLayer, ""),
+			"2": attributedTo(topLayer, ""),
+		}
+		if got := attributed(t, body); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("SchemaV2", func(t *testing.T) {
+		_, body := get(t, "?schema=v2&base_image="+base)
+		if _, ok := body["attribution"]; ok {
+			t.Error("unexpected attribution member")
+		}
+		if _, ok := body["vulnerabilities"]; !ok {
+			t.Error("missing vulnerabilities member")
+		}
+	})
+	t.Run("Confidence", func(t *testing.T) {
+		_, body := get(t, "")
+		var cs map[string]confidence
+		if err := json.Unmarshal(body["confidence"], &cs); err != nil {
+			t.Fatal(err)
+		}
+		if len(cs) != 2 || cs["1"].Level == "" || cs["2"].Level == "" {
+			t.Errorf("got: %+v", cs)
+		}
+		_, body = get(t, "?schema=v6")
+		if _, ok := body["confidence"]; ok {
+			t.Error("unexpected confidence member")
+		}
+	})
+	t.Run("BadDigest", func(t *testing.T) {
+		res, _ := get(t, "?base_image=nope")
+		if got, want := res.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
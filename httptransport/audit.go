@@ -0,0 +1,153 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	rr "github.com/ldelossa/responserecorder"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/syslog"
+)
+
+var auditEventsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "http",
+		Name:      "audit_events_total",
+		Help:      "Total number of audit events, by whether they were sent, failed to send, or were dropped because sending fell behind.",
+	},
+	[]string{"result"},
+)
+
+// AuditQueueSize is the number of audit events that may be waiting to be
+// sent before new ones are dropped.
+const auditQueueSize = 1024
+
+// AuditLog returns an http.Handler wrapping the provided Handler that sends
+// audit events for requests to the configured syslog collector.
+//
+// Events are sent in the background, so a slow or unreachable collector
+// doesn't hold up requests. The background sender exits when "ctx" is
+// canceled.
+func auditLog(ctx context.Context, cfg *config.AuditLog, next http.Handler) (http.Handler, error) {
+	w, err := syslog.New(&cfg.Syslog)
+	if err != nil {
+		return nil, err
+	}
+	a := &auditor{
+		next: next,
+		w:    w,
+		all:  cfg.AllRequests,
+		ch:   make(chan syslog.Message, auditQueueSize),
+	}
+	go a.run(zlog.ContextWithValues(ctx, "component", "httptransport/auditor.run"))
+	return a, nil
+}
+
+// Auditor is the handler returned by auditLog.
+type auditor struct {
+	next http.Handler
+	w    *syslog.Writer
+	all  bool
+	ch   chan syslog.Message
+}
+
+// ServeHTTP implements http.Handler.
+func (a *auditor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := rr.NewResponseRecorder(w)
+	start := time.Now()
+	a.next.ServeHTTP(rec, r)
+	code := rec.StatusCode()
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	kind, def := "success", syslog.Notice
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		kind, def = "denied", syslog.Warning
+	case code >= 400:
+		kind, def = "error", syslog.Err
+	}
+	if !a.all && kind != "denied" && isSafeMethod(r.Method) {
+		return
+	}
+
+	ps := []syslog.Param{
+		{Name: "outcome", Value: kind},
+		{Name: "method", Value: r.Method},
+		{Name: "uri", Value: r.RequestURI},
+		{Name: "status", Value: strconv.Itoa(code)},
+		{Name: "remote_addr", Value: r.RemoteAddr},
+	}
+	// The claims are only verified if the request was let through, but
+	// the claimed identity is still useful for refused requests.
+	if cl, ok := bearerClaims(r); ok {
+		ps = append(ps,
+			syslog.Param{Name: "issuer", Value: cl.Issuer},
+			syslog.Param{Name: "subject", Value: cl.Subject},
+		)
+	}
+	m := syslog.Message{
+		Time:     start,
+		Severity: a.w.Severity(kind, def),
+		MsgID:    "request",
+		Data:     []syslog.Element{{ID: "audit@32473", Params: ps}},
+		Msg:      fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, code),
+	}
+	select {
+	case a.ch <- m:
+	default:
+		auditEventsCounter.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Run sends queued events until "ctx" is canceled, sending whatever has
+// accumulated together.
+func (a *auditor) run(ctx context.Context) {
+	defer a.w.Close()
+	var batch []syslog.Message
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-a.ch:
+			batch = append(batch[:0], m)
+		}
+	drain:
+		for len(batch) < 100 {
+			select {
+			case m := <-a.ch:
+				batch = append(batch, m)
+			default:
+				break drain
+			}
+		}
+		if err := a.w.Write(ctx, batch...); err != nil {
+			auditEventsCounter.WithLabelValues("failed").Add(float64(len(batch)))
+			zlog.Warn(ctx).
+				Err(err).
+				Int("count", len(batch)).
+				Msg("unable to send audit events")
+			continue
+		}
+		auditEventsCounter.WithLabelValues("sent").Add(float64(len(batch)))
+	}
+}
+
+// IsSafeMethod reports whether "m" is a request method that doesn't change
+// state.
+func isSafeMethod(m string) bool {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package httptransport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(zlog.Test(context.Background(), t))
+	defer cancel()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	h, err := auditLog(ctx, &config.AuditLog{
+		Syslog: config.Syslog{
+			Address:  "udp://" + pc.LocalAddr().String(),
+			Facility: "auth",
+		},
+	}, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/indexer/api/v1/index_state", nil),
+		httptest.NewRequest(http.MethodDelete, "/indexer/api/v1/index_report", nil),
+		httptest.NewRequest(http.MethodGet, "/secret", nil),
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// Auth is facility 4: notice is <37> and warning is <36>. The read isn't
+	// audited.
+	for _, want := range []string{
+		`<37>1 .* request [audit@32473 outcome="success" method="DELETE"`,
+		`<36>1 .* request [audit@32473 outcome="denied" method="GET"`,
+	} {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 4096)
+		n, _, err := pc.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(b[:n])
+		pre, post, _ := strings.Cut(want, " .* ")
+		if !strings.HasPrefix(got, pre) || !strings.Contains(got, post) {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package httptransport
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"sort"
 
 	"github.com/quay/clair/config"
 
@@ -11,15 +14,26 @@
 
 // AuthHandler returns an http.Handler wrapping the provided Handler, as
 // described by the provided Config.
-func authHandler(cfg *config.Config, next http.Handler) (http.Handler, error) {
+func authHandler(ctx context.Context, cfg *config.Config, next http.Handler) (http.Handler, error) {
 	var checks []auth.Checker
 
 	// Keep this ordered "best" to "worst".
 	switch {
 	case cfg.Auth.PSK != nil:
+		mode := cfg.Mode
 		cfg := cfg.Auth.PSK
 		issuers := make([]string, 0, 1+len(cfg.Issuer))
-		issuers = append(issuers, IntraserviceIssuer)
+		if cfg.ScopedIntraservice {
+			// Intraservice tokens are only accepted for their audience and
+			// scopes.
+			s, err := auth.NewScoped(cfg.Key, IntraserviceIssuer, audiences(mode), scopeGrants)
+			if err != nil {
+				return nil, err
+			}
+			checks = append(checks, s)
+		} else {
+			issuers = append(issuers, IntraserviceIssuer)
+		}
 		issuers = append(issuers, cfg.Issuer...)
 
 		psk, err := auth.NewPSK(cfg.Key, issuers)
@@ -29,9 +43,31 @@ func authHandler(cfg *config.Config, next http.Handler) (http.Handler, error) {
 		checks = append(checks, psk)
 	case cfg.Auth.Keyserver != nil:
 		return nil, errors.New("quay keyserver support has been removed")
-	default:
-		return next, nil
 	}
 
+	// Plugins are tried after the built-in methods, in name order.
+	names := make([]string, 0, len(cfg.Auth.Plugins))
+	for n := range cfg.Auth.Plugins {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		node := cfg.Auth.Plugins[n]
+		p, err := auth.NewPlugin(ctx, n, func(v interface{}) error {
+			b, err := json.Marshal(node)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(b, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, p)
+	}
+
+	if len(checks) == 0 {
+		return next, nil
+	}
 	return auth.Handler(next, checks...), nil
 }
@@ -50,7 +50,7 @@ func (tc *authTestcase) Run(ctx context.Context) func(*testing.T) {
 		})
 
 		// Create a handler that has auth according to the config.
-		h, err := authHandler(&tc.Config, next)
+		h, err := authHandler(ctx, &tc.Config, next)
 		if err != nil {
 			t.Error(err)
 		}
@@ -175,3 +175,118 @@ func TestAuth(t *testing.T) {
 		t.Run(tc.Name, tc.Run(ctx))
 	}
 }
+
+// TestScopedAuth tests that intraservice tokens are limited to their audience
+// and scopes when scoped intraservice auth is enabled.
+func TestScopedAuth(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	cfg := config.Config{
+		Mode: config.IndexerMode,
+		Auth: config.Auth{
+			PSK: &config.AuthPSK{
+				Issuer:             []string{`sweet-bro`},
+				Key:                []byte("deadbeef"),
+				ScopedIntraservice: true,
+			},
+		},
+	}
+	h, err := authHandler(ctx, &cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+	cfg.Matcher.IndexerAddr = srv.URL
+
+	report := IndexReportAPIPath + "sha256:" + hex.EncodeToString(make([]byte, 32))
+	tt := []struct {
+		Name   string
+		Claims jwt.Claims
+		Scopes []string
+		Method string
+		Path   string
+		Status int
+	}{
+		{
+			Name:   "Scoped",
+			Claims: jwt.Claims{Issuer: IntraserviceIssuer, Audience: jwt.Audience{IndexerAudience}},
+			Scopes: []string{ScopeIndexReport},
+			Method: http.MethodGet,
+			Path:   report,
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "OutOfScopeMethod",
+			Claims: jwt.Claims{Issuer: IntraserviceIssuer, Audience: jwt.Audience{IndexerAudience}},
+			Scopes: []string{ScopeIndexReport},
+			Method: http.MethodDelete,
+			Path:   report,
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name:   "OutOfScopePath",
+			Claims: jwt.Claims{Issuer: IntraserviceIssuer, Audience: jwt.Audience{IndexerAudience}},
+			Scopes: []string{ScopeIndexReport},
+			Method: http.MethodPost,
+			Path:   IndexAPIPath,
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name:   "DotDot",
+			Claims: jwt.Claims{Issuer: IntraserviceIssuer, Audience: jwt.Audience{IndexerAudience}},
+			Scopes: []string{ScopeIndexReport},
+			Method: http.MethodGet,
+			Path:   IndexReportAPIPath + "../index_state",
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name:   "Unscoped",
+			Claims: jwt.Claims{Issuer: IntraserviceIssuer},
+			Method: http.MethodGet,
+			Path:   report,
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name:   "WrongAudience",
+			Claims: jwt.Claims{Issuer: IntraserviceIssuer, Audience: jwt.Audience{MatcherAudience}},
+			Scopes: []string{ScopeIndexReport},
+			Method: http.MethodGet,
+			Path:   report,
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name:   "Client",
+			Claims: jwt.Claims{Issuer: `sweet-bro`},
+			Method: http.MethodPost,
+			Path:   IndexAPIPath,
+			Status: http.StatusOK,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			s, err := httputil.NewSigner(ctx, &cfg, tc.Claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Scope(tc.Scopes...)
+			req, err := httputil.NewRequestWithContext(ctx, tc.Method, srv.URL+tc.Path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Sign(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if got, want := res.StatusCode, tc.Status; got != want {
+				t.Errorf("got: %d, want: %d", got, want)
+			}
+		})
+	}
+}
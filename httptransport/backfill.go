@@ -0,0 +1,175 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/backfill"
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// Backfills returns the matcher's backfill manager, writing an error response
+// and returning nil if it doesn't have one.
+func (h *MatcherV1) backfills(ctx context.Context, w http.ResponseWriter) matcher.Backfills {
+	b, ok := h.srv.(matcher.Backfills)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "re-match backfills not supported")
+		return nil
+	}
+	return b
+}
+
+// BackfillError writes the response for an error from a backfill method.
+func backfillError(ctx context.Context, w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, backfill.ErrDisabled):
+		apiError(ctx, w, http.StatusNotFound, "re-match backfills not configured")
+	case errors.Is(err, backfill.ErrInvalid):
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+	case errors.Is(err, backfill.ErrActive):
+		apiError(ctx, w, http.StatusConflict, "%v", err)
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not %s: %v", action, err)
+	}
+}
+
+// BackfillList lists the backfills in response to GET requests and starts
+// one in response to POST requests.
+//
+// A POST body is optional: it may set the "rate" and "chunk_size" to use
+// instead of the configured defaults.
+func (h *MatcherV1) backfillList(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.backfillList")
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	b := h.backfills(ctx, w)
+	if b == nil {
+		return
+	}
+
+	var out interface{}
+	switch r.Method {
+	case http.MethodGet:
+		js, err := b.Backfills(ctx)
+		if err != nil {
+			backfillError(ctx, w, err, "list backfills")
+			return
+		}
+		out = js
+		w.Header().Set("content-type", "application/json")
+	case http.MethodPost:
+		var req struct {
+			Rate      int `json:"rate"`
+			ChunkSize int `json:"chunk_size"`
+		}
+		if r.ContentLength != 0 {
+			dec := codec.GetDecoder(r.Body)
+			err := dec.Decode(&req)
+			codec.PutDecoder(dec)
+			if err != nil {
+				apiError(ctx, w, http.StatusBadRequest, "could not deserialize request: %v", err)
+				return
+			}
+		}
+		j := &backfill.Job{Rate: req.Rate, ChunkSize: req.ChunkSize}
+		if err := b.StartBackfill(ctx, j); err != nil {
+			backfillError(ctx, w, err, "start backfill")
+			return
+		}
+		ev := zlog.Info(ctx).
+			Stringer("backfill", j.ID).
+			Int64("total", j.Total).
+			Int("rate", j.Rate)
+		if id, ok := auth.IdentityFromContext(ctx); ok {
+			ev = ev.Str("by", id.Subject)
+		}
+		ev.Msg("started backfill")
+		out = j
+		w.Header().Set("content-type", "application/json")
+		w.Header().Set("location", path.Join(r.URL.Path, j.ID.String()))
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	var err error
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(out)
+}
+
+// BackfillHandler serves a single backfill: GET returns it, with its
+// progress and ETA, and PATCH changes its "state" to pause, resume, or cancel
+// it.
+func (h *MatcherV1) backfillHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.backfillHandler")
+	switch r.Method {
+	case http.MethodGet, http.MethodPatch:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	b := h.backfills(ctx, w)
+	if b == nil {
+		return
+	}
+	id, err := uuid.Parse(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "could not parse backfill id: %v", err)
+		return
+	}
+
+	var j *backfill.Job
+	var ok bool
+	switch r.Method {
+	case http.MethodGet:
+		j, ok, err = b.Backfill(ctx, id)
+		if err != nil {
+			backfillError(ctx, w, err, "get backfill")
+			return
+		}
+	case http.MethodPatch:
+		var req struct {
+			State backfill.State `json:"state"`
+		}
+		dec := codec.GetDecoder(r.Body)
+		err = dec.Decode(&req)
+		codec.PutDecoder(dec)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not deserialize request: %v", err)
+			return
+		}
+		j, ok, err = b.SetBackfillState(ctx, id, req.State)
+		if err != nil {
+			backfillError(ctx, w, err, "update backfill")
+			return
+		}
+		if ok {
+			zlog.Info(ctx).
+				Stringer("backfill", id).
+				Str("state", string(req.State)).
+				Msg("changed backfill state")
+		}
+	}
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "backfill %v not found", id)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(j)
+}
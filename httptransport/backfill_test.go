@@ -0,0 +1,133 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/backfill"
+)
+
+type backfillMock struct {
+	*matcher.Mock
+	m map[uuid.UUID]backfill.Job
+}
+
+func (b *backfillMock) Backfills(context.Context) ([]backfill.Job, error) {
+	out := []backfill.Job{}
+	for _, v := range b.m {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (b *backfillMock) Backfill(_ context.Context, id uuid.UUID) (*backfill.Job, bool, error) {
+	v, ok := b.m[id]
+	return &v, ok, nil
+}
+
+func (b *backfillMock) StartBackfill(_ context.Context, j *backfill.Job) error {
+	for _, v := range b.m {
+		if v.State == backfill.StateRunning || v.State == backfill.StatePaused {
+			return backfill.ErrActive
+		}
+	}
+	if j.Rate == 0 {
+		j.Rate = 10
+	}
+	if j.ChunkSize == 0 {
+		j.ChunkSize = 100
+	}
+	if err := j.Validate(); err != nil {
+		return err
+	}
+	j.ID, j.State = uuid.New(), backfill.StateRunning
+	b.m[j.ID] = *j
+	return nil
+}
+
+func (b *backfillMock) SetBackfillState(_ context.Context, id uuid.UUID, s backfill.State) (*backfill.Job, bool, error) {
+	v, ok := b.m[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if v.State == backfill.StateCanceled {
+		return nil, true, fmt.Errorf("%w: backfill is canceled", backfill.ErrInvalid)
+	}
+	v.State = s
+	b.m[id] = v
+	return &v, true, nil
+}
+
+func TestBackfillHandler(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	m := &backfillMock{
+		Mock: &matcher.Mock{},
+		m:    make(map[uuid.UUID]backfill.Job),
+	}
+	v1 := NewMatcherV1(ctx, "", m, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(method, path, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+
+	do(http.MethodPost, "/internal/backfill", `{"chunk_size":100000}`, http.StatusBadRequest).Body.Close()
+	res := do(http.MethodPost, "/internal/backfill", "", http.StatusCreated)
+	var got backfill.Job
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got.ID == uuid.Nil || got.Rate != 10 || res.Header.Get("location") != "/internal/backfill/"+got.ID.String() {
+		t.Errorf("got: %+v, location: %q", got, res.Header.Get("location"))
+	}
+	do(http.MethodPost, "/internal/backfill", `{"rate":5}`, http.StatusConflict).Body.Close()
+
+	p := "/internal/backfill/" + got.ID.String()
+	do(http.MethodGet, "/internal/backfill", "", http.StatusOK).Body.Close()
+	do(http.MethodGet, p, "", http.StatusOK).Body.Close()
+	do(http.MethodGet, "/internal/backfill/bad", "", http.StatusBadRequest).Body.Close()
+	do(http.MethodGet, "/internal/backfill/"+uuid.New().String(), "", http.StatusNotFound).Body.Close()
+	do(http.MethodPatch, p, `{"state":"canceled"}`, http.StatusOK).Body.Close()
+	do(http.MethodPatch, p, `{"state":"running"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodDelete, p, "", http.StatusMethodNotAllowed).Body.Close()
+
+	// A matcher without backfills reports them as unsupported.
+	v1 = NewMatcherV1(ctx, "", &matcher.Mock{}, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	rec := httptest.NewRecorder()
+	v1.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/backfill", nil).WithContext(ctx))
+	if got, want := rec.Code, http.StatusNotFound; got != want {
+		t.Errorf("unsupported: got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	oteltrace "go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
+
+	"github.com/quay/clair/v4/internal/codec"
+)
+
+// BatchReportRequest is the request body for the batch vulnerability report
+// endpoint.
+type batchReportRequest struct {
+	Manifests []claircore.Digest `json:"manifests"`
+}
+
+// BatchReportEntry is one line of the batch vulnerability report response:
+// the report for a manifest, or the reason there isn't one.
+type batchReportEntry struct {
+	Manifest claircore.Digest `json:"manifest_hash"`
+	Report   interface{}      `json:"report,omitempty"`
+	Error    *batchReportErr  `json:"error,omitempty"`
+}
+
+// BatchReportErr mirrors the body apiError writes, for a single manifest.
+type batchReportErr struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// VulnerabilityReports streams the vulnerability reports for the manifests
+// named in the request body, one JSON object per line, in the order they were
+// requested.
+//
+// Failing to build one manifest's report is reported in its line and doesn't
+// stop the others. The query parameters accepted by vulnerabilityReport apply
+// to every report.
+func (h *MatcherV1) vulnerabilityReports(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnerabilityReports")
+
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+	ctx = httptrace.WithClientTrace(ctx, oteltrace.NewClientTrace(ctx))
+
+	var req batchReportRequest
+	dec := codec.GetDecoder(r.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&req); err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "failed to deserialize manifests: %v", err)
+		return
+	}
+	if len(req.Manifests) == 0 {
+		apiError(ctx, w, http.StatusBadRequest, "no manifests provided")
+		return
+	}
+	if max := h.limits.MaxBatchReports; max > 0 && len(req.Manifests) > max {
+		apiError(ctx, w, http.StatusRequestEntityTooLarge,
+			"%d manifests requested, over limit of %d", len(req.Manifests), max)
+		return
+	}
+	filter, err := parseReportFilter(r.URL.Query())
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	schema, err := reportSchema(w, r)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	bases, err := h.baseImages(r.URL.Query())
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	initd, err := h.srv.Initialized(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !initd {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var base map[string]struct{}
+	if hasAttribution(schema) {
+		base = h.baseLayers(ctx, bases)
+	}
+	redact := h.redact.fields(ctx)
+
+	// Headers can't be changed once the first line is written, so the stale
+	// updaters are reported up front.
+	h.setStaleHeader(w)
+	w.Header().Set("content-type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flush := func() {}
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	for _, m := range req.Manifests {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		e := batchReportEntry{Manifest: m}
+		vr, code, rErr := h.report(ctx, m)
+		switch {
+		case rErr != nil:
+			e.Error = &batchReportErr{Code: errorCode(code), Message: rErr.Error()}
+		case vr == nil:
+			// The matcher can't become uninitialized, but be defensive.
+			e.Error = &batchReportErr{Code: errorCode(http.StatusServiceUnavailable)}
+		default:
+			if filter != nil {
+				vr = filter.apply(vr)
+			}
+			out := annotatedVulnerabilityReport{
+				VulnerabilityReport: vr,
+				Labels:              labelsFor(ctx, h.indexerSrv, m),
+				Annotations:         annotationsFor(ctx, h.srv, vr),
+				Confidence:          assess(vr),
+				Errata:              errata(vr),
+			}
+			if hasAttribution(schema) {
+				out.Attribution = attribute(vr, base)
+			}
+			redact.vulnerabilityReport(&out)
+			e.Report = out.in(schema)
+		}
+		if err = enc.Encode(&e); err != nil {
+			return
+		}
+		if _, err = w.Write([]byte{'\n'}); err != nil {
+			return
+		}
+		flush()
+	}
+}
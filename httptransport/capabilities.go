@@ -0,0 +1,248 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer/artifact"
+	"github.com/quay/clair/v4/indexer/ecosystem"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dsse"
+	"github.com/quay/clair/v4/matcher"
+	intromw "github.com/quay/clair/v4/middleware/introspection"
+)
+
+// Capabilities describes what this deployment of Clair does, so tooling
+// working with a fleet of differently configured or versioned instances can
+// find out before choosing request formats.
+type capabilities struct {
+	// Mode is the mode Clair is running in.
+	Mode string `json:"mode"`
+	// APIs are the APIs served.
+	APIs []capabilityAPI `json:"apis"`
+	// ReportSchemas are the schema versions reports can be requested in,
+	// oldest first, and ReportSchema is the default.
+	ReportSchemas []string `json:"report_schemas,omitempty"`
+	ReportSchema  string   `json:"report_schema,omitempty"`
+	// ReportFormats are the media types each kind of report can be served
+	// as.
+	ReportFormats map[string][]string `json:"report_formats,omitempty"`
+	// Scanners are the scanners the indexer runs.
+	Scanners []ecosystem.Scanner `json:"scanners,omitempty"`
+	// Updaters are the updaters that have recorded data.
+	Updaters []capabilityUpdater `json:"updaters,omitempty"`
+	// Deliverers are the notification delivery mechanisms configured.
+	Deliverers []string `json:"deliverers,omitempty"`
+	// Deprecations are the deprecated settings in use.
+	Deprecations []capabilityDeprecation `json:"deprecations"`
+}
+
+// CapabilityAPI is an API served.
+type capabilityAPI struct {
+	Service string `json:"service"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// CapabilityUpdater is an updater known to the matcher.
+type capabilityUpdater struct {
+	Name     string `json:"name"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// CapabilityDeprecation is a deprecated setting in use.
+type capabilityDeprecation struct {
+	Setting string `json:"setting"`
+	Message string `json:"message"`
+}
+
+// ConfigureCapabilities serves the description of this deployment's
+// capabilities.
+//
+// Everything but the updaters is fixed by the configuration, so it's only
+// worked out once.
+func (t *Server) configureCapabilities(ctx context.Context) error {
+	c := capabilities{
+		Mode:         t.conf.Mode.String(),
+		Deprecations: []capabilityDeprecation{},
+	}
+	api := func(svc, root string) {
+		c.APIs = append(c.APIs, capabilityAPI{Service: svc, Version: "v1", Path: root + apiRoot})
+	}
+	hasIndexer := t.indexer != nil && (t.conf.Mode == config.ComboMode || t.conf.Mode == config.IndexerMode)
+	hasMatcher := t.matcher != nil && (t.conf.Mode == config.ComboMode || t.conf.Mode == config.MatcherMode)
+	hasNotifier := t.notifier != nil && (t.conf.Mode == config.ComboMode || t.conf.Mode == config.NotifierMode)
+	if hasIndexer || hasMatcher {
+		c.ReportSchemas = schemas
+		c.ReportSchema = currentSchema
+		c.ReportFormats = make(map[string][]string)
+	}
+	if hasIndexer {
+		api("indexer", indexerRoot)
+		c.ReportFormats["index_report"] = []string{"application/vnd.clair.indexreport.v1+json", "application/json", codec.CBORMediaType}
+		var err error
+		c.Scanners, err = configuredScanners(ctx, &t.conf.Indexer)
+		if err != nil {
+			return err
+		}
+	}
+	if hasMatcher {
+		api("matcher", matcherRoot)
+		c.ReportFormats["vulnerability_report"] = []string{"application/json"}
+	}
+	if hasNotifier {
+		api("notifier", notifierRoot)
+		if d := deliverer(&t.conf.Notifier); d != "" {
+			c.Deliverers = []string{d}
+		}
+	}
+	if t.signer != nil {
+		for k, v := range c.ReportFormats {
+			c.ReportFormats[k] = append(v, dsse.MediaType)
+		}
+	}
+	ws, err := config.Lint(&t.conf)
+	var lw *config.Warning
+	switch {
+	case err == nil:
+	case errors.As(err, &lw):
+		// Some lints report deprecations as errors, stopping the lint.
+		ws = append(ws, *lw)
+	default:
+		return err
+	}
+	for i := range ws {
+		w := &ws[i]
+		if errors.Is(w, config.ErrDeprecated) {
+			c.Deprecations = append(c.Deprecations, capabilityDeprecation{
+				Setting: w.Path(),
+				Message: w.Unwrap().Error(),
+			})
+		}
+	}
+
+	var srv matcher.Service
+	if hasMatcher {
+		srv = t.matcher
+	}
+	t.Handle(CapabilitiesPath,
+		intromw.InstrumentedHandler(CapabilitiesPath, t.traceOpt, capabilitiesHandler(&c, srv)))
+	return nil
+}
+
+// ConfiguredScanners returns the scanners the indexer configuration "cfg"
+// enables, including artifact scanners.
+func configuredScanners(ctx context.Context, cfg *config.Indexer) ([]ecosystem.Scanner, error) {
+	es, err := ecosystem.Configured(ctx, &cfg.Scanner)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ecosystem.Scanners(ctx, es)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Artifacts != nil {
+		as, err := artifact.Scanners(cfg.Artifacts.Scanners)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range as {
+			out = append(out, ecosystem.Scanner{Name: s.Name(), Version: s.Version(), Kind: "artifact"})
+		}
+	}
+	return out, nil
+}
+
+// Deliverer returns the name of the notification delivery mechanism "cfg"
+// configures, if any, with the same precedence as the notifier.
+func deliverer(cfg *config.Notifier) string {
+	switch {
+	case cfg.Webhook != nil:
+		return "webhook"
+	case cfg.AMQP != nil:
+		return "amqp"
+	case cfg.STOMP != nil:
+		return "stomp"
+	case cfg.MQTT != nil:
+		return "mqtt"
+	case cfg.Azure != nil:
+		return "azure"
+	case cfg.Syslog != nil:
+		return "syslog"
+	case cfg.Splunk != nil:
+		return "splunk"
+	case cfg.Elasticsearch != nil:
+		return "elasticsearch"
+	case cfg.DependencyTrack != nil:
+		return "dependency_track"
+	case cfg.Issues != nil:
+		return "issues"
+	}
+	return ""
+}
+
+// CapabilitiesHandler serves "c", along with the updaters known to "srv" if
+// it's not nil.
+func capabilitiesHandler(c *capabilities, srv matcher.Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := zlog.ContextWithValues(r.Context(),
+			"component", "httptransport/capabilitiesHandler")
+		if r.Method != http.MethodGet {
+			apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+			return
+		}
+		out := *c
+		if srv != nil {
+			var err error
+			out.Updaters, err = updaters(ctx, srv)
+			if err != nil {
+				apiError(ctx, w, http.StatusInternalServerError, "could not list updaters: %v", err)
+				return
+			}
+		}
+
+		w.Header().Set("content-type", "application/json")
+		var err error
+		defer writerError(w, &err)()
+		enc := codec.GetEncoder(w)
+		defer codec.PutEncoder(enc)
+		err = enc.Encode(&out)
+	})
+}
+
+// Updaters returns the updaters that have recorded update operations, noting
+// the ones disabled at runtime, ordered by name.
+func updaters(ctx context.Context, srv matcher.Service) ([]capabilityUpdater, error) {
+	seen := make(map[string]struct{})
+	for _, k := range []driver.UpdateKind{driver.VulnerabilityKind, driver.EnrichmentKind} {
+		ops, err := srv.LatestUpdateOperations(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		for n := range ops {
+			seen[n] = struct{}{}
+		}
+	}
+	disabled := make(map[string]bool)
+	if uc, ok := srv.(matcher.UpdaterControl); ok {
+		ds, err := uc.DisabledUpdaters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range ds {
+			disabled[d.Updater] = true
+		}
+	}
+	out := make([]capabilityUpdater, 0, len(seen))
+	for n := range seen {
+		out = append(out, capabilityUpdater{Name: n, Disabled: disabled[n]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+	othttp "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestCapabilities(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := &matcher.Mock{
+		LatestUpdateOperations_: func(_ context.Context, k driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+			if k == driver.EnrichmentKind {
+				return map[string][]driver.UpdateOperation{"clair.cvss": nil}, nil
+			}
+			return map[string][]driver.UpdateOperation{"rhel-vex": nil, "alpine-main-v3.18": nil}, nil
+		},
+	}
+	s := &Server{
+		conf: config.Config{
+			Mode: config.MatcherMode,
+			TLS:  &config.TLS{RootCA: "/etc/clair/ca.pem"},
+		},
+		matcher:  m,
+		ServeMux: http.NewServeMux(),
+		traceOpt: othttp.WithTracerProvider(otel.GetTracerProvider()),
+	}
+	if err := s.configureCapabilities(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(s)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	res, err := srv.Client().Get(srv.URL + CapabilitiesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	var got capabilities
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Mode != "matcher" {
+		t.Errorf("mode: got: %q", got.Mode)
+	}
+	if len(got.APIs) != 1 || got.APIs[0].Service != "matcher" {
+		t.Errorf("apis: got: %+v", got.APIs)
+	}
+	if got.ReportSchema != currentSchema || len(got.ReportSchemas) != len(schemas) {
+		t.Errorf("schemas: got: %q %q", got.ReportSchema, got.ReportSchemas)
+	}
+	if _, ok := got.ReportFormats["index_report"]; ok {
+		t.Errorf("formats: got: %v", got.ReportFormats)
+	}
+	want := []string{"alpine-main-v3.18", "clair.cvss", "rhel-vex"}
+	if len(got.Updaters) != len(want) {
+		t.Fatalf("updaters: got: %+v, want: %q", got.Updaters, want)
+	}
+	for i, u := range got.Updaters {
+		if u.Name != want[i] {
+			t.Errorf("updaters: got: %+v, want: %q", got.Updaters, want)
+		}
+	}
+	if len(got.Deprecations) != 1 || got.Deprecations[0].Setting != "$.tls.root_ca" {
+		t.Errorf("deprecations: got: %+v", got.Deprecations)
+	}
+}
@@ -0,0 +1,77 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/changelog"
+)
+
+// ChangelogResponse is the body of a changelog response.
+type changelogResponse struct {
+	Manifest claircore.Digest  `json:"manifest_hash"`
+	Page     changelog.Page    `json:"page"`
+	Events   []changelog.Event `json:"events"`
+}
+
+// Changelog reports a page of the events recorded for a manifest, selected
+// by the optional "page_size" and "next" query parameters.
+func (h *MatcherV1) changelog(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.changelog")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	c, ok := h.srv.(matcher.Changelogs)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "changelogs not supported")
+		return
+	}
+	manifest, err := claircore.ParseDigest(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	var page changelog.Page
+	q := r.URL.Query()
+	if s := q.Get("page_size"); s != "" {
+		page.Size, err = strconv.Atoi(s)
+		if err != nil || page.Size < 1 {
+			apiError(ctx, w, http.StatusBadRequest, "%q query param must be a positive integer", "page_size")
+			return
+		}
+	}
+	if s := q.Get("next"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not parse %q query param into integer", "next")
+			return
+		}
+		page.Next = &n
+	}
+
+	evs, next, err := c.Changelog(ctx, manifest, &page)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, changelog.ErrDisabled):
+		apiError(ctx, w, http.StatusNotFound, "%v", err)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not get changelog: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(&changelogResponse{Manifest: manifest, Page: next, Events: evs})
+}
@@ -0,0 +1,104 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/changelog"
+)
+
+type changelogMock struct {
+	*matcher.Mock
+	disabled bool
+	page     changelog.Page
+}
+
+func (m *changelogMock) Changelog(_ context.Context, _ claircore.Digest, page *changelog.Page) ([]changelog.Event, changelog.Page, error) {
+	if m.disabled {
+		return nil, changelog.Page{}, changelog.ErrDisabled
+	}
+	m.page = *page
+	cur := uuid.New()
+	next := int64(3)
+	return []changelog.Event{
+			{ID: 1, Kind: changelog.KindIndexed, Cursor: cur},
+			{ID: 2, Kind: changelog.KindFindingsAdded, Cursor: cur, Reason: changelog.ReasonIndex, Findings: []changelog.Finding{
+				{Vulnerability: "CVE-1", Package: "openssl", Version: "1"},
+			}},
+		},
+		changelog.Page{Size: 2, Next: &next},
+		nil
+}
+
+func TestChangelog(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = `sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`
+	run := func(t *testing.T, svc matcher.Service) func(string, int) *http.Response {
+		v1 := NewMatcherV1(ctx, "", svc, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(path string, want int) *http.Response {
+			t.Helper()
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { res.Body.Close() })
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s: got: %d, want: %d", path, got, want)
+			}
+			return res
+		}
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		do := run(t, &matcher.Mock{})
+		do("/changelog/"+digest, http.StatusNotFound)
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		do := run(t, &changelogMock{Mock: &matcher.Mock{}, disabled: true})
+		do("/changelog/"+digest, http.StatusNotFound)
+	})
+	t.Run("Changelog", func(t *testing.T) {
+		m := &changelogMock{Mock: &matcher.Mock{}}
+		do := run(t, m)
+		do("/changelog/sha256:bad", http.StatusBadRequest)
+		do("/changelog/"+digest+"?page_size=0", http.StatusBadRequest)
+		do("/changelog/"+digest+"?next=first", http.StatusBadRequest)
+
+		res := do("/changelog/"+digest+"?page_size=2&next=1", http.StatusOK)
+		var got changelogResponse
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Manifest.String() != digest || len(got.Events) != 2 || got.Events[1].Findings[0].Vulnerability != "CVE-1" {
+			t.Errorf("got: %+v", got)
+		}
+		if got.Page.Next == nil || *got.Page.Next != 3 {
+			t.Errorf("next page: got: %+v", got.Page)
+		}
+		if m.page.Size != 2 || m.page.Next == nil || *m.page.Next != 1 {
+			t.Errorf("requested page: got: %+v", m.page)
+		}
+	})
+}
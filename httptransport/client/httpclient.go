@@ -2,12 +2,15 @@
 
 import (
 	"context"
+	"mime"
 	"net/http"
 	"net/url"
 	"sync"
 	"sync/atomic"
 
 	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/internal/codec"
 )
 
 // UoCache caches an UpdateOperation map when the server provides a conditional
@@ -136,3 +139,21 @@ func (s *HTTP) sign(ctx context.Context, req *http.Request) error {
 	}
 	return s.signer.Sign(ctx, req)
 }
+
+// AcceptCBOR is the "Accept" header for requests whose responses can be large.
+//
+// Servers that predate CBOR support respond with JSON.
+const acceptCBOR = codec.CBORMediaType + `, application/json;q=0.9`
+
+// DecodeResponse decodes the body of "res" into "v": as CBOR if its
+// "Content-Type" header says so, and as JSON otherwise.
+func decodeResponse(res *http.Response, v interface{}) error {
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("content-type")); mt == codec.CBORMediaType {
+		dec := codec.GetCBORDecoder(res.Body)
+		defer codec.PutCBORDecoder(dec)
+		return dec.Decode(v)
+	}
+	dec := codec.GetDecoder(res.Body)
+	defer codec.PutDecoder(dec)
+	return dec.Decode(v)
+}
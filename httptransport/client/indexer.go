@@ -5,7 +5,9 @@
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
+	"strconv"
 
 	"github.com/quay/claircore"
 
@@ -16,7 +18,12 @@
 	"github.com/quay/clair/v4/internal/httputil"
 )
 
-var _ indexer.Service = (*HTTP)(nil)
+var (
+	_ indexer.Service        = (*HTTP)(nil)
+	_ indexer.Labeler        = (*HTTP)(nil)
+	_ indexer.UsageReporter  = (*HTTP)(nil)
+	_ indexer.ManifestLister = (*HTTP)(nil)
+)
 
 func (s *HTTP) AffectedManifests(ctx context.Context, v []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
 	u, err := s.addr.Parse(httptransport.AffectedManifestAPIPath)
@@ -36,6 +43,7 @@ func (s *HTTP) AffectedManifests(ctx context.Context, v []claircore.Vulnerabilit
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("content-type", `application/json`)
+	req.Header.Set("accept", acceptCBOR)
 	resp, err := s.c.Do(req)
 	if err != nil {
 		return nil, err
@@ -49,19 +57,60 @@ func (s *HTTP) AffectedManifests(ctx context.Context, v []claircore.Vulnerabilit
 	}
 
 	var a claircore.AffectedManifests
-	switch ct := req.Header.Get("content-type"); ct {
-	case "", `application/json`:
-		dec := codec.GetDecoder(resp.Body)
-		defer codec.PutDecoder(dec)
-		if err := dec.Decode(&a); err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("unrecognized content-type %q", ct)
+	if err := decodeResponse(resp, &a); err != nil {
+		return nil, err
 	}
 	return &a, nil
 }
 
+// Labels implements indexer.Labeler.
+//
+// Indexers that predate labels report none.
+func (s *HTTP) Labels(ctx context.Context, ds ...claircore.Digest) (map[string]map[string]string, error) {
+	u, err := s.addr.Parse(httptransport.ManifestLabelsAPIPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse api address: %v", err)
+	}
+	rd := codec.JSONReader(struct {
+		M []claircore.Digest `json:"manifests"`
+	}{
+		ds,
+	})
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, u.String(), rd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := s.sign(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("content-type", `application/json`)
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return map[string]map[string]string{}, nil
+	default:
+		return nil, &clairerror.ErrRequestFail{
+			Code:   resp.StatusCode,
+			Status: resp.Status,
+		}
+	}
+
+	var out struct {
+		Labels map[string]map[string]string `json:"labels"`
+	}
+	dec := codec.GetDecoder(resp.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out.Labels, nil
+}
+
 // Index receives a Manifest and returns a IndexReport providing the indexed
 // items in the resulting image.
 //
@@ -122,6 +171,7 @@ func (s *HTTP) IndexReport(ctx context.Context, manifest claircore.Digest) (*cla
 	if err := s.sign(ctx, req); err != nil {
 		return nil, false, fmt.Errorf("failed to create request: %v", err)
 	}
+	req.Header.Set("accept", acceptCBOR)
 	resp, err := s.c.Do(req)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to do request: %v", err)
@@ -141,9 +191,7 @@ func (s *HTTP) IndexReport(ctx context.Context, manifest claircore.Digest) (*cla
 	}
 
 	ir := &claircore.IndexReport{}
-	dec := codec.GetDecoder(resp.Body)
-	defer codec.PutDecoder(dec)
-	if err := dec.Decode(ir); err != nil {
+	if err := decodeResponse(resp, ir); err != nil {
 		return nil, false, &clairerror.ErrBadIndexReport{E: err}
 	}
 	return ir, true, nil
@@ -205,3 +253,79 @@ func (s *HTTP) DeleteManifests(ctx context.Context, d ...claircore.Digest) ([]cl
 	}
 	return ret, nil
 }
+
+// Usage implements indexer.UsageReporter.
+func (s *HTTP) Usage(ctx context.Context, tenantLabel string) (*indexer.Usage, error) {
+	u, err := s.addr.Parse(httptransport.IndexerUsageAPIPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse api address: %v", err)
+	}
+	if tenantLabel != "" {
+		u.RawQuery = url.Values{"tenant_label": {tenantLabel}}.Encode()
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := s.sign(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &clairerror.ErrRequestFail{
+			Code:   resp.StatusCode,
+			Status: resp.Status,
+		}
+	}
+	var out indexer.Usage
+	dec := codec.GetDecoder(resp.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &out, nil
+}
+
+// Manifests implements indexer.ManifestLister.
+func (s *HTTP) Manifests(ctx context.Context, after string, limit int) ([]claircore.Digest, error) {
+	u, err := s.addr.Parse(httptransport.IndexerManifestsAPIPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse api address: %v", err)
+	}
+	v := url.Values{"limit": {strconv.Itoa(limit)}}
+	if after != "" {
+		v.Set("after", after)
+	}
+	u.RawQuery = v.Encode()
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := s.sign(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &clairerror.ErrRequestFail{
+			Code:   resp.StatusCode,
+			Status: resp.Status,
+		}
+	}
+	var out struct {
+		Manifests []claircore.Digest `json:"manifests"`
+	}
+	dec := codec.GetDecoder(resp.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return out.Manifests, nil
+}
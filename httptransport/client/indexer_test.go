@@ -0,0 +1,81 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/httptransport"
+	"github.com/quay/clair/v4/httptransport/client"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/codec"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// TestIndexReportCBOR checks that index reports are fetched as CBOR from an
+// indexer that supports it.
+func TestIndexReportCBOR(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	d := claircore.MustParseDigest(`sha256:0000000000000000000000000000000000000000000000000000000000000000`)
+	want := &claircore.IndexReport{
+		Hash:    d,
+		State:   "IndexFinished",
+		Success: true,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k", Kind: claircore.BINARY},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{PackageDB: "var/lib/rpm", IntroducedIn: d}},
+		},
+	}
+	v1, err := httptransport.NewIndexerV1(ctx, "/indexer/api/v1", &indexer.Mock{
+		State_: func(context.Context) (string, error) { return "state", nil },
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return want, true, nil
+		},
+	}, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(v1)
+	defer srv.Close()
+
+	var ct string
+	tr := srv.Client().Transport
+	hc := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		res, err := tr.RoundTrip(r)
+		if err == nil {
+			ct = res.Header.Get("content-type")
+		}
+		return res, err
+	})}
+	c, err := client.NewHTTP(ctx, client.WithAddr(srv.URL), client.WithClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := c.IndexReport(ctx, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("index report not found")
+	}
+	if ct != codec.CBORMediaType {
+		t.Errorf("content-type: got: %q, want: %q", ct, codec.CBORMediaType)
+	}
+	opts := []cmp.Option{cmpopts.EquateEmpty(), cmp.AllowUnexported(claircore.Digest{})}
+	if !cmp.Equal(got, want, opts...) {
+		t.Error(cmp.Diff(got, want, opts...))
+	}
+}
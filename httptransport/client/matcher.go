@@ -243,6 +243,7 @@ func (c *HTTP) UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.Upd
 	if err := c.sign(ctx, req); err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
+	req.Header.Set("accept", acceptCBOR)
 
 	res, err := c.c.Do(req)
 	if err != nil {
@@ -253,9 +254,7 @@ func (c *HTTP) UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.Upd
 		return nil, fmt.Errorf("%v: unexpected status: %s", u.Path, res.Status)
 	}
 	d := driver.UpdateDiff{}
-	dec := codec.GetDecoder(res.Body)
-	defer codec.PutDecoder(dec)
-	if err := dec.Decode(&d); err != nil {
+	if err := decodeResponse(res, &d); err != nil {
 		return nil, err
 	}
 	return &d, nil
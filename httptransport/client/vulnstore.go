@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/libvuln/driver"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/httptransport"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+)
+
+var _ matcher.Vulnstore = (*HTTP)(nil)
+
+// Vulnerabilities implements matcher.Vulnstore.
+func (c *HTTP) Vulnerabilities(ctx context.Context, records []*claircore.IndexRecord, opts datastore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	rd := codec.JSONReader(struct {
+		Records          []*claircore.IndexRecord `json:"records"`
+		Matchers         []driver.MatchConstraint `json:"matchers"`
+		VersionFiltering bool                     `json:"version_filtering"`
+	}{
+		records,
+		opts.Matchers,
+		opts.VersionFiltering,
+	})
+	m := make(map[string][]*claircore.Vulnerability)
+	if err := c.vulnstore(ctx, httptransport.VulnstoreVulnerabilitiesPath, rd, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Enrichments implements matcher.Vulnstore.
+func (c *HTTP) Enrichments(ctx context.Context, kind string, tags []string) ([]driver.EnrichmentRecord, error) {
+	rd := codec.JSONReader(struct {
+		Kind string   `json:"kind"`
+		Tags []string `json:"tags"`
+	}{
+		kind,
+		tags,
+	})
+	var rs []driver.EnrichmentRecord
+	if err := c.vulnstore(ctx, httptransport.VulnstoreEnrichmentsPath, rd, &rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Vulnstore implements the common bits of the vulnerability store queries:
+// it posts "body" to "p" and decodes the response into "v".
+func (c *HTTP) vulnstore(ctx context.Context, p string, body io.Reader, v interface{}) error {
+	u, err := c.addr.Parse(p)
+	if err != nil {
+		return err
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := c.sign(ctx, req); err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("content-type", `application/json`)
+	req.Header.Set("accept", acceptCBOR)
+	res, err := c.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &clairerror.ErrRequestFail{
+			Code:   res.StatusCode,
+			Status: res.Status,
+		}
+	}
+	return decodeResponse(res, v)
+}
@@ -0,0 +1,127 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/httptransport"
+	"github.com/quay/clair/v4/httptransport/client"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+type vulnstoreMock struct {
+	*matcher.Mock
+	t *testing.T
+}
+
+func (m *vulnstoreMock) Vulnerabilities(_ context.Context, records []*claircore.IndexRecord, opts datastore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	if got, want := opts.Matchers, []driver.MatchConstraint{driver.PackageName}; !cmp.Equal(got, want) {
+		m.t.Errorf("matchers: got: %v, want: %v", got, want)
+	}
+	if !opts.VersionFiltering {
+		m.t.Error("version filtering not passed along")
+	}
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range records {
+		out[r.Package.ID] = []*claircore.Vulnerability{{
+			ID:             "1",
+			Name:           "CVE-2000-0001",
+			Updater:        "test",
+			Package:        r.Package,
+			FixedInVersion: "2",
+		}}
+	}
+	return out, nil
+}
+
+func (m *vulnstoreMock) Enrichments(_ context.Context, kind string, tags []string) ([]driver.EnrichmentRecord, error) {
+	if kind != "cvss" {
+		return nil, errors.New("unknown kind")
+	}
+	return []driver.EnrichmentRecord{
+		{Tags: tags, Enrichment: json.RawMessage(`{"score":9.8}`)},
+	}, nil
+}
+
+func TestVulnstore(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	serve := func(t *testing.T, svc matcher.Service) *client.HTTP {
+		t.Helper()
+		v1 := httptransport.NewMatcherV1(ctx, "/matcher/api/v1/", svc, &indexer.Mock{}, time.Second,
+			otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		srv := httptest.NewServer(v1)
+		t.Cleanup(srv.Close)
+		c, err := client.NewHTTP(ctx, client.WithAddr(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	t.Run("Vulnerabilities", func(t *testing.T) {
+		c := serve(t, &vulnstoreMock{Mock: &matcher.Mock{}, t: t})
+		rs := []*claircore.IndexRecord{
+			{Package: &claircore.Package{ID: "10", Name: "openssl", Version: "1"}},
+			{Package: &claircore.Package{ID: "11", Name: "zlib", Version: "1"}},
+		}
+		got, err := c.Vulnerabilities(ctx, rs, datastore.GetOpts{
+			Matchers:         []driver.MatchConstraint{driver.PackageName},
+			VersionFiltering: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(rs) {
+			t.Fatalf("got %d packages, want %d", len(got), len(rs))
+		}
+		for _, r := range rs {
+			vs := got[r.Package.ID]
+			if len(vs) != 1 {
+				t.Errorf("%s: got %d vulnerabilities, want 1", r.Package.Name, len(vs))
+				continue
+			}
+			if got, want := vs[0].Package.Name, r.Package.Name; got != want {
+				t.Errorf("package: got: %q, want: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("Enrichments", func(t *testing.T) {
+		c := serve(t, &vulnstoreMock{Mock: &matcher.Mock{}, t: t})
+		tags := []string{"CVE-2000-0001"}
+		got, err := c.Enrichments(ctx, "cvss", tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []driver.EnrichmentRecord{
+			{Tags: tags, Enrichment: json.RawMessage(`{"score":9.8}`)},
+		}
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		c := serve(t, &matcher.Mock{})
+		_, err := c.Enrichments(ctx, "cvss", nil)
+		var rf *clairerror.ErrRequestFail
+		if !errors.As(err, &rf) || rf.Code != http.StatusNotFound {
+			t.Errorf("got: %v, want: a 404", err)
+		}
+	})
+}
@@ -19,6 +19,8 @@
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/codec"
 )
 
 // GetDigest removes the last path element and parses it as a digest.
@@ -27,7 +29,11 @@ func getDigest(_ http.ResponseWriter, r *http.Request) (d claircore.Digest, err
 	if dStr == "" {
 		return d, errors.New("provide a single manifest hash")
 	}
-	return claircore.ParseDigest(dStr)
+	d, err = claircore.ParseDigest(dStr)
+	if err == nil {
+		noteDigest(r.Context(), d)
+	}
+	return d, err
 }
 
 // PickContentType sets the response's "Content-Type" header.
@@ -115,6 +121,20 @@ func (a *accept) Match(mt string) bool {
 	return a.Type == t && (a.Subtype == s || a.Subtype == "*")
 }
 
+// ResponseEncoder returns an Encoder for the media type chosen by
+// pickContentType and a function to return it to its pool.
+//
+// Endpoints other Clair services call list codec.CBORMediaType last in their
+// allowed media types, so that it's only used when explicitly requested.
+func responseEncoder(w http.ResponseWriter) (*codec.Encoder, func()) {
+	if w.Header().Get("content-type") == codec.CBORMediaType {
+		enc := codec.GetCBOREncoder(w)
+		return enc, func() { codec.PutCBOREncoder(enc) }
+	}
+	enc := codec.GetEncoder(w)
+	return enc, func() { codec.PutEncoder(enc) }
+}
+
 var idPool = sync.Pool{
 	New: func() interface{} {
 		b := make([]byte, 8)
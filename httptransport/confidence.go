@@ -0,0 +1,119 @@
+package httptransport
+
+import (
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// These are the confidence levels of a finding, from least to most
+// confident.
+const (
+	// ConfidenceLow is a package identified by its file name alone.
+	confidenceLow = `low`
+	// ConfidenceMedium is a package identified from incidental metadata,
+	// such as a jar manifest or image labels.
+	confidenceMedium = `medium`
+	// ConfidenceHigh is a package recorded by a package manager, or whose
+	// build embeds its identity.
+	confidenceHigh = `high`
+)
+
+// ConfidenceRank orders the confidence levels.
+var confidenceRank = map[string]int{
+	confidenceLow:    1,
+	confidenceMedium: 2,
+	confidenceHigh:   3,
+}
+
+// ParseConfidence returns the confidence level named "s", ignoring case.
+func parseConfidence(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	_, ok := confidenceRank[s]
+	return s, ok
+}
+
+// Confidence reports how sure Clair is of the findings for a package, based
+// on how the package was found.
+type confidence struct {
+	// Level is "high", "medium", or "low".
+	Level string `json:"level"`
+	// Basis describes how the package was identified.
+	Basis string `json:"basis"`
+}
+
+// PackageConfidence returns the confidence of findings for the package with
+// the ID "id", judging by the package database it was found in.
+//
+// Packages found in more than one place take the most confident.
+func packageConfidence(vr *claircore.VulnerabilityReport, id string) confidence {
+	if p, ok := vr.Packages[id]; ok && p.RepositoryHint == "rhcc" {
+		// Red Hat container images are identified by their labels.
+		return confidence{Level: confidenceMedium, Basis: "image labels"}
+	}
+	var out confidence
+	for _, env := range vr.Environments[id] {
+		if env == nil {
+			continue
+		}
+		if c := dbConfidence(env.PackageDB); confidenceRank[c.Level] > confidenceRank[out.Level] {
+			out = c
+		}
+	}
+	if out.Level == "" {
+		out = confidence{Level: confidenceMedium, Basis: "unknown"}
+	}
+	return out
+}
+
+// DbConfidence maps the package database recorded by a claircore scanner to
+// a confidence.
+//
+// Scanners that can find packages more than one way prefix the database
+// with how: "maven:", "jar:", or "file:" for Java; and "bdb:", "ndb:", or
+// "sqlite:" for RPM.
+func dbConfidence(db string) confidence {
+	var (
+		distro   = confidence{Level: confidenceHigh, Basis: "distribution package database"}
+		language = confidence{Level: confidenceHigh, Basis: "language package metadata"}
+	)
+	scheme, _, _ := strings.Cut(db, ":")
+	switch scheme {
+	case "bdb", "ndb", "sqlite":
+		return distro
+	case "maven", "python":
+		return language
+	case "go":
+		return confidence{Level: confidenceHigh, Basis: "binary build information"}
+	case "jar":
+		return confidence{Level: confidenceMedium, Basis: "jar manifest"}
+	case "file":
+		return confidence{Level: confidenceLow, Basis: "file name"}
+	}
+	switch {
+	case strings.HasPrefix(db, "var/lib/dpkg/"), db == "lib/apk/db/installed":
+		return distro
+	case strings.HasSuffix(db, ".gemspec"):
+		return language
+	case path.Base(db) == "pkgconfig":
+		return confidence{Level: confidenceMedium, Basis: "pkg-config file"}
+	}
+	return confidence{Level: confidenceMedium, Basis: "unknown"}
+}
+
+// Assess returns the confidence of every package in "vr" with findings, keyed
+// by package ID.
+func assess(vr *claircore.VulnerabilityReport) map[string]confidence {
+	if len(vr.PackageVulnerabilities) == 0 {
+		return nil
+	}
+	out := make(map[string]confidence, len(vr.PackageVulnerabilities))
+	for id, vs := range vr.PackageVulnerabilities {
+		if len(vs) == 0 {
+			continue
+		}
+		out[id] = packageConfidence(vr, id)
+	}
+	return out
+}
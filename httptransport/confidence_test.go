@@ -0,0 +1,51 @@
+package httptransport
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestAssess(t *testing.T) {
+	vr := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"rhcc": {ID: "rhcc", Name: "ubi8-container", RepositoryHint: "rhcc"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"rpm":    {{PackageDB: "bdb:var/lib/rpm"}},
+			"deb":    {{PackageDB: "var/lib/dpkg/status"}},
+			"apk":    {{PackageDB: "lib/apk/db/installed"}},
+			"python": {{PackageDB: "python:usr/lib/python3.9/site-packages/requests-2.25.0.dist-info/METADATA"}},
+			"gem":    {{PackageDB: "usr/share/gems/specifications/rack-2.2.3.gemspec"}},
+			"go":     {{PackageDB: "go:usr/bin/app"}},
+			"jar":    {{PackageDB: "jar:opt/app/lib/thing.jar"}},
+			"both":   {{PackageDB: "file:opt/app/lib/thing.jar"}, {PackageDB: "maven:opt/app/app.jar"}},
+			"file":   {{PackageDB: "file:opt/app/lib/guessed-1.0.jar"}},
+			"rhcc":   {{PackageDB: "root/buildinfo/Dockerfile-ubi8-8.4"}},
+			"clean":  {{PackageDB: "bdb:var/lib/rpm"}},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"rpm": {"1"}, "deb": {"1"}, "apk": {"1"}, "python": {"1"}, "gem": {"1"},
+			"go": {"1"}, "jar": {"1"}, "both": {"1"}, "file": {"1"}, "rhcc": {"1"},
+			"none": {"1"},
+		},
+	}
+	got := assess(vr)
+	want := map[string]confidence{
+		"rpm":    {Level: "high", Basis: "distribution package database"},
+		"deb":    {Level: "high", Basis: "distribution package database"},
+		"apk":    {Level: "high", Basis: "distribution package database"},
+		"python": {Level: "high", Basis: "language package metadata"},
+		"gem":    {Level: "high", Basis: "language package metadata"},
+		"go":     {Level: "high", Basis: "binary build information"},
+		"jar":    {Level: "medium", Basis: "jar manifest"},
+		"both":   {Level: "high", Basis: "language package metadata"},
+		"file":   {Level: "low", Basis: "file name"},
+		"rhcc":   {Level: "medium", Basis: "image labels"},
+		"none":   {Level: "medium", Basis: "unknown"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,152 @@
+package httptransport
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Erratum is the vendor advisory for a vulnerability on the distribution it
+// was found in.
+type erratum struct {
+	// Vendor names the distribution vendor: "redhat", "debian", "ubuntu", or
+	// "amazon".
+	Vendor string `json:"vendor"`
+	// ID is the vendor's advisory ID, such as "RHSA-2023:1234" or
+	// "USN-6000-1". It's empty if the vulnerability has no advisory yet, in
+	// which case URL is the vendor's page for the CVE.
+	ID string `json:"id,omitempty"`
+	// URL is the vendor's page for the advisory.
+	URL string `json:"url"`
+}
+
+// A vendor recognizes its advisory IDs and builds links to its advisories.
+type vendor struct {
+	name string
+	// Dists are the os-release IDs of the vendor's distributions.
+	dists []string
+	// ID matches the vendor's advisory IDs.
+	id *regexp.Regexp
+	// Advisory returns the URL of the advisory "id".
+	advisory func(id string) string
+	// CVE returns the URL of the vendor's page for the CVE "id".
+	cve func(id string) string
+}
+
+var vendors = []vendor{
+	{
+		name:     "redhat",
+		dists:    []string{"rhel", "centos", "rhcos"},
+		id:       regexp.MustCompile(`\bRH[SBE]A-[0-9]{4}:[0-9]+\b`),
+		advisory: func(id string) string { return "https://access.redhat.com/errata/" + id },
+		cve:      func(id string) string { return "https://access.redhat.com/security/cve/" + id },
+	},
+	{
+		name:     "debian",
+		dists:    []string{"debian"},
+		id:       regexp.MustCompile(`\bD[SL]A-[0-9]+(-[0-9]+)?\b`),
+		advisory: func(id string) string { return "https://security-tracker.debian.org/tracker/" + id },
+		cve:      func(id string) string { return "https://security-tracker.debian.org/tracker/" + id },
+	},
+	{
+		name:     "ubuntu",
+		dists:    []string{"ubuntu"},
+		id:       regexp.MustCompile(`\bUSN-[0-9]+-[0-9]+\b`),
+		advisory: func(id string) string { return "https://ubuntu.com/security/notices/" + id },
+		cve:      func(id string) string { return "https://ubuntu.com/security/" + id },
+	},
+	{
+		name:  "amazon",
+		dists: []string{"amzn"},
+		id:    regexp.MustCompile(`\bALAS(2|2022|2023)?-[0-9]{4}-[0-9]+\b`),
+		advisory: func(id string) string {
+			// Each Amazon Linux release has its own bulletin.
+			switch {
+			case strings.HasPrefix(id, "ALAS2-"):
+				return "https://alas.aws.amazon.com/AL2/" + id + ".html"
+			case strings.HasPrefix(id, "ALAS2022-"):
+				return "https://alas.aws.amazon.com/AL2022/" + id + ".html"
+			case strings.HasPrefix(id, "ALAS2023-"):
+				return "https://alas.aws.amazon.com/AL2023/" + id + ".html"
+			}
+			return "https://alas.aws.amazon.com/" + id + ".html"
+		},
+		cve: func(id string) string { return "https://explore.alas.aws.amazon.com/" + id + ".html" },
+	},
+}
+
+var cveID = regexp.MustCompile(`\bCVE-[0-9]{4}-[0-9]+\b`)
+
+// VulnerabilityErratum returns the vendor advisory for "v", if its updater
+// names one, or else the vendor's page for its CVE if it's about a
+// distribution package.
+//
+// Advisory IDs are looked for in the vulnerability's name and links, and a
+// link naming the advisory is preferred over the vendor's usual URL, so
+// mirrors of the vendor's data keep their links.
+func vulnerabilityErratum(v *claircore.Vulnerability) (erratum, bool) {
+	links := strings.Fields(v.Links)
+	for i := range vendors {
+		vd := &vendors[i]
+		id := vd.id.FindString(v.Name)
+		if id == "" {
+			for _, l := range links {
+				if id = vd.id.FindString(l); id != "" {
+					break
+				}
+			}
+		}
+		if id == "" {
+			continue
+		}
+		e := erratum{Vendor: vd.name, ID: id, URL: vd.advisory(id)}
+		for _, l := range links {
+			if strings.HasPrefix(l, "https://") && strings.Contains(l, id) {
+				e.URL = l
+				break
+			}
+		}
+		return e, true
+	}
+	if v.Dist == nil || v.Dist.DID == "" {
+		return erratum{}, false
+	}
+	cve := cveID.FindString(v.Name)
+	if cve == "" {
+		cve = cveID.FindString(v.Links)
+	}
+	if cve == "" {
+		return erratum{}, false
+	}
+	for i := range vendors {
+		vd := &vendors[i]
+		for _, d := range vd.dists {
+			if d == v.Dist.DID {
+				return erratum{Vendor: vd.name, URL: vd.cve(cve)}, true
+			}
+		}
+	}
+	return erratum{}, false
+}
+
+// Errata returns the vendor advisory for every vulnerability in "vr" that
+// has one, keyed by vulnerability ID.
+func errata(vr *claircore.VulnerabilityReport) map[string]erratum {
+	if len(vr.Vulnerabilities) == 0 {
+		return nil
+	}
+	out := make(map[string]erratum)
+	for id, v := range vr.Vulnerabilities {
+		if v == nil {
+			continue
+		}
+		if e, ok := vulnerabilityErratum(v); ok {
+			out[id] = e
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
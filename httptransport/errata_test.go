@@ -0,0 +1,75 @@
+package httptransport
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestErrata(t *testing.T) {
+	dist := func(id string) *claircore.Distribution { return &claircore.Distribution{DID: id} }
+	vr := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"rhsa": {
+				Name:  "RHSA-2023:1405: openssl security update (Important)",
+				Links: "https://access.redhat.com/errata/RHSA-2023:1405 https://access.redhat.com/security/cve/CVE-2023-0286",
+				Dist:  dist("rhel"),
+			},
+			"rhsa-mirror": {
+				Name:  "RHBA-2023:0100: tzdata bug fix",
+				Links: "https://errata.example.com/RHBA-2023:0100",
+				Dist:  dist("rhel"),
+			},
+			"dsa": {
+				Name:  "CVE-2023-0286",
+				Links: "https://security-tracker.debian.org/tracker/CVE-2023-0286 https://security-tracker.debian.org/tracker/DSA-5343-1",
+				Dist:  dist("debian"),
+			},
+			"debian-cve": {
+				Name:  "CVE-2023-0464",
+				Links: "https://security-tracker.debian.org/tracker/CVE-2023-0464",
+				Dist:  dist("debian"),
+			},
+			"usn": {
+				Name:  "CVE-2023-0286 on Ubuntu 22.04 LTS (jammy) - high.",
+				Links: "https://ubuntu.com/security/notices/USN-5844-1 https://ubuntu.com/security/CVE-2023-0286",
+				Dist:  dist("ubuntu"),
+			},
+			"alas": {
+				Name:  "ALAS2-2023-1935",
+				Links: "",
+				Dist:  dist("amzn"),
+			},
+			"alas2023": {
+				Name: "ALAS2023-2023-081",
+				Dist: dist("amzn"),
+			},
+			"language": {
+				Name:  "GHSA-jfhm-5ghh-2f97",
+				Links: "https://github.com/advisories/GHSA-jfhm-5ghh-2f97 https://nvd.nist.gov/vuln/detail/CVE-2022-42889",
+			},
+			"unknown-dist": {
+				Name: "CVE-2023-0286",
+				Dist: dist("photon"),
+			},
+		},
+	}
+	got := errata(vr)
+	want := map[string]erratum{
+		"rhsa":        {Vendor: "redhat", ID: "RHSA-2023:1405", URL: "https://access.redhat.com/errata/RHSA-2023:1405"},
+		"rhsa-mirror": {Vendor: "redhat", ID: "RHBA-2023:0100", URL: "https://errata.example.com/RHBA-2023:0100"},
+		"dsa":         {Vendor: "debian", ID: "DSA-5343-1", URL: "https://security-tracker.debian.org/tracker/DSA-5343-1"},
+		"debian-cve":  {Vendor: "debian", URL: "https://security-tracker.debian.org/tracker/CVE-2023-0464"},
+		"usn":         {Vendor: "ubuntu", ID: "USN-5844-1", URL: "https://ubuntu.com/security/notices/USN-5844-1"},
+		"alas":        {Vendor: "amazon", ID: "ALAS2-2023-1935", URL: "https://alas.aws.amazon.com/AL2/ALAS2-2023-1935.html"},
+		"alas2023":    {Vendor: "amazon", ID: "ALAS2023-2023-081", URL: "https://alas.aws.amazon.com/AL2023/ALAS2023-2023-081.html"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if got := errata(&claircore.VulnerabilityReport{}); got != nil {
+		t.Errorf("empty report: got: %v, want: nil", got)
+	}
+}
@@ -8,11 +8,32 @@
 	"net/http"
 
 	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
 )
 
 // ApiError writes an untyped (that is, "application/json") error with the
 // provided HTTP status code and message.
+//
+// Server errors for requests that ran out of their configured time are
+// reported as "504 Gateway Timeout", whatever "code" is.
 func apiError(ctx context.Context, w http.ResponseWriter, code int, f string, v ...interface{}) {
+	if code >= http.StatusInternalServerError && timedOut(ctx) {
+		code = http.StatusGatewayTimeout
+	}
+	writeError(ctx, w, code, errorCode(code), "", nil, f, v...)
+}
+
+// PlatformError writes a "422 Unprocessable Entity" error response for a
+// manifest refused because of its platform. Alongside the usual members, the
+// response has a "platform" member describing it.
+func platformError(ctx context.Context, w http.ResponseWriter, u *indexer.UnsupportedPlatform) {
+	writeError(ctx, w, http.StatusUnprocessableEntity, "unsupported-platform", "platform", u, "%v", u)
+}
+
+// WriteError writes an error response with the HTTP status "code" and the
+// "code" member "name". If "key" isn't empty, "val" is added as that member.
+func writeError(ctx context.Context, w http.ResponseWriter, code int, name, key string, val interface{}, f string, v ...interface{}) {
 	const errheader = `Clair-Error`
 	h := w.Header()
 	h.Del("link")
@@ -29,24 +50,18 @@ func apiError(ctx context.Context, w http.ResponseWriter, code int, f string, v
 
 	var buf bytes.Buffer
 	buf.WriteString(`{"code":"`)
-	switch code {
-	case http.StatusBadRequest:
-		buf.WriteString("bad-request")
-	case http.StatusMethodNotAllowed:
-		buf.WriteString("method-not-allowed")
-	case http.StatusNotFound:
-		buf.WriteString("not-found")
-	case http.StatusTooManyRequests:
-		buf.WriteString("too-many-requests")
-	default:
-		buf.WriteString("internal-error")
-	}
+	buf.WriteString(name)
 	buf.WriteByte('"')
 	if f != "" {
 		buf.WriteString(`,"message":`)
 		b, _ := json.Marshal(fmt.Sprintf(f, v...)) // OK use of encoding/json.
 		buf.Write(b)
 	}
+	if key != "" {
+		buf.WriteString(`,"` + key + `":`)
+		b, _ := json.Marshal(val) // OK use of encoding/json.
+		buf.Write(b)
+	}
 	buf.WriteByte('}')
 
 	if _, err := buf.WriteTo(w); err != nil {
@@ -56,3 +71,24 @@ func apiError(ctx context.Context, w http.ResponseWriter, code int, f string, v
 		f.Flush()
 	}
 }
+
+// ErrorCode returns the "code" member of an error response for the HTTP
+// status "code".
+func errorCode(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "bad-request"
+	case http.StatusMethodNotAllowed:
+		return "method-not-allowed"
+	case http.StatusNotFound:
+		return "not-found"
+	case http.StatusTooManyRequests:
+		return "too-many-requests"
+	case http.StatusRequestEntityTooLarge:
+		return "too-large"
+	case http.StatusGatewayTimeout:
+		return "timeout"
+	default:
+		return "internal-error"
+	}
+}
@@ -0,0 +1,54 @@
+package httptransport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// StaleUpdatersHeader lists the updaters whose data was stale when a
+// vulnerability report was built. It's omitted if there are none.
+const staleUpdatersHeader = "Clair-Stale-Updaters"
+
+// SetStaleHeader adds the stale updaters header, if the matcher tracks
+// freshness and any updaters are stale.
+func (h *MatcherV1) setStaleHeader(w http.ResponseWriter) {
+	f, ok := h.srv.(matcher.Freshness)
+	if !ok {
+		return
+	}
+	if s := f.StaleUpdaters(); len(s) != 0 {
+		w.Header().Set(staleUpdatersHeader, strings.Join(s, ", "))
+	}
+}
+
+// UpdaterStatus reports when each updater last ran and whether its data is
+// stale.
+func (h *MatcherV1) updaterStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.updaterStatus")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	f, ok := h.srv.(matcher.Freshness)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "updater status not supported")
+		return
+	}
+	ss, err := f.UpdaterStatus(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not get updater status: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(ss)
+}
@@ -0,0 +1,114 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/freshness"
+)
+
+type freshnessMock struct {
+	*matcher.Mock
+	s []freshness.Status
+}
+
+func (f *freshnessMock) UpdaterStatus(context.Context) ([]freshness.Status, error) {
+	return f.s, nil
+}
+
+func (f *freshnessMock) StaleUpdaters() []string {
+	var out []string
+	for _, s := range f.s {
+		if s.Stale {
+			out = append(out, s.Updater)
+		}
+	}
+	return out
+}
+
+func TestUpdaterStatus(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	now := time.Now().UTC().Truncate(time.Second)
+	i := &indexer.Mock{
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: claircore.MustParseDigest(digest), Success: true}, true, nil
+		},
+	}
+	m := &freshnessMock{
+		Mock: &matcher.Mock{
+			Initialized_: func(context.Context) (bool, error) { return true, nil },
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+			},
+		},
+		s: []freshness.Status{
+			{Updater: "alpine", LastSuccess: &now, LastRunSucceeded: true},
+			{Updater: "debian", LastSuccess: &now, Stale: true},
+			{Updater: "ubuntu", Stale: true},
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(method, path string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+
+	do(http.MethodPost, "/updater_status", http.StatusMethodNotAllowed).Body.Close()
+	res := do(http.MethodGet, "/updater_status", http.StatusOK)
+	var got []freshness.Status
+	err := json.NewDecoder(res.Body).Decode(&got)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || !got[0].LastSuccess.Equal(now) || got[2].LastSuccess != nil {
+		t.Errorf("got: %+v", got)
+	}
+
+	res = do(http.MethodGet, "/vulnerability_report/"+digest, http.StatusOK)
+	res.Body.Close()
+	if got, want := res.Header.Get(staleUpdatersHeader), "debian, ubuntu"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// A matcher without freshness tracking has no endpoint and no header.
+	v1 = NewMatcherV1(ctx, "", m.Mock, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv.Config.Handler = v1
+	do(http.MethodGet, "/updater_status", http.StatusNotFound).Body.Close()
+	res = do(http.MethodGet, "/vulnerability_report/"+digest, http.StatusOK)
+	res.Body.Close()
+	if got := res.Header.Get(staleUpdatersHeader); got != "" {
+		t.Errorf("unexpected header: %q", got)
+	}
+}
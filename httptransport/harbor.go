@@ -0,0 +1,562 @@
+package httptransport
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/cmd"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/registry"
+)
+
+// The Harbor Scanner Adapter API, version 1.0.
+//
+// See https://github.com/goharbor/pluggable-scanner-spec.
+const (
+	harborRoot = "/api/v1/"
+
+	harborMetadataType = `application/vnd.scanner.adapter.metadata+json; version=1.0`
+	harborScanReqType  = `application/vnd.scanner.adapter.scan.request+json; version=1.0`
+	harborScanRespType = `application/vnd.scanner.adapter.scan.response+json; version=1.0`
+	harborReportType   = `application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0`
+	harborErrorType    = `application/vnd.scanner.adapter.error+json; version=1.0`
+
+	// HarborRefreshAfter is the number of seconds Harbor is told to wait
+	// before asking again for a report that isn't ready.
+	harborRefreshAfter = "5"
+	// HarborScanTimeout bounds fetching and indexing a single artifact.
+	harborScanTimeout = 30 * time.Minute
+	// HarborScanRetain is how long the state of a scan request is kept after
+	// it's done.
+	harborScanRetain = time.Hour
+)
+
+// Manifest media types Clair can index.
+var harborConsumes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+type harborScanner struct {
+	Name    string `json:"name"`
+	Vendor  string `json:"vendor"`
+	Version string `json:"version"`
+}
+
+var harborClair = harborScanner{
+	Name:    "Clair",
+	Vendor:  "Project Quay",
+	Version: cmd.Version,
+}
+
+type harborMetadata struct {
+	Scanner      harborScanner      `json:"scanner"`
+	Capabilities []harborCapability `json:"capabilities"`
+	Properties   map[string]string  `json:"properties"`
+}
+
+type harborCapability struct {
+	ConsumesMIMETypes []string `json:"consumes_mime_types"`
+	ProducesMIMETypes []string `json:"produces_mime_types"`
+}
+
+type harborScanRequest struct {
+	Registry struct {
+		URL           string `json:"url"`
+		Authorization string `json:"authorization"`
+	} `json:"registry"`
+	Artifact harborArtifact `json:"artifact"`
+}
+
+type harborArtifact struct {
+	Repository string `json:"repository,omitempty"`
+	Digest     string `json:"digest"`
+	Tag        string `json:"tag,omitempty"`
+	MIMEType   string `json:"mime_type,omitempty"`
+}
+
+type harborScanResponse struct {
+	ID string `json:"id"`
+}
+
+type harborReport struct {
+	GeneratedAt     time.Time             `json:"generated_at"`
+	Artifact        harborArtifact        `json:"artifact"`
+	Scanner         harborScanner         `json:"scanner"`
+	Severity        string                `json:"severity"`
+	Vulnerabilities []harborVulnerability `json:"vulnerabilities"`
+}
+
+type harborVulnerability struct {
+	ID          string   `json:"id"`
+	Package     string   `json:"package"`
+	Version     string   `json:"version"`
+	FixVersion  string   `json:"fix_version,omitempty"`
+	Severity    string   `json:"severity"`
+	Description string   `json:"description"`
+	Links       []string `json:"links"`
+}
+
+type harborError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// HarborScan is the state of a scan request made through the adapter.
+type harborScan struct {
+	artifact harborArtifact
+	done     time.Time
+	err      error
+}
+
+// HarborV1 serves the Harbor Scanner Adapter API.
+//
+// Scan request IDs are the artifact digests, so reports for artifacts that
+// were indexed some other way, or before a restart, can still be retrieved.
+type HarborV1 struct {
+	reports *MatcherV1
+	secret  string
+	allow   map[string]struct{}
+	resolve func(context.Context, name.Reference, authn.Authenticator) (*claircore.Manifest, error)
+	mu      sync.Mutex
+	scans   map[string]*harborScan
+	mux     *http.ServeMux
+	baseCtx context.Context
+}
+
+var _ http.Handler = (*HarborV1)(nil)
+
+// ConfigureHarbor sets up the Harbor Scanner Adapter listener, if
+// configured.
+func (t *Server) configureHarbor(ctx context.Context) {
+	cfg := t.conf.Matcher.Harbor
+	if cfg == nil || t.matcher == nil || t.indexer == nil {
+		return
+	}
+	mv1 := &MatcherV1{
+		srv:        t.matcher,
+		indexerSrv: t.indexer,
+		limits:     t.conf.Matcher.Limits,
+	}
+	var h http.Handler = newHarborV1(ctx, mv1, cfg.Secret, cfg.Registries)
+	if t.conf.AccessLog != nil {
+		h = accessLog(t.conf.AccessLog, h)
+	}
+	t.Listeners = append(t.Listeners, &Listener{
+		Name: "harbor",
+		Server: &http.Server{
+			Addr:        cfg.ListenAddr,
+			BaseContext: t.Server.BaseContext,
+			Handler:     h,
+		},
+	})
+	zlog.Info(ctx).
+		Str("address", cfg.ListenAddr).
+		Msg("harbor scanner adapter configured")
+}
+
+// NewHarborV1 returns a HarborV1 building reports with "reports". Background
+// scans are canceled along with "ctx".
+func newHarborV1(ctx context.Context, reports *MatcherV1, secret string, registries []string) *HarborV1 {
+	h := &HarborV1{
+		reports: reports,
+		secret:  secret,
+		resolve: func(ctx context.Context, r name.Reference, auth authn.Authenticator) (*claircore.Manifest, error) {
+			return registry.ResolveReference(ctx, nil, r, auth)
+		},
+		scans:   make(map[string]*harborScan),
+		mux:     http.NewServeMux(),
+		baseCtx: ctx,
+	}
+	if len(registries) != 0 {
+		h.allow = make(map[string]struct{}, len(registries))
+		for _, r := range registries {
+			h.allow[r] = struct{}{}
+		}
+	}
+	h.mux.HandleFunc(harborRoot+"metadata", h.metadata)
+	h.mux.HandleFunc(harborRoot+"scan", h.scan)
+	h.mux.HandleFunc(harborRoot+"scan/", h.report)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *HarborV1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.secret != "" && !h.authorized(r) {
+		h.error(w, http.StatusUnauthorized, "missing or incorrect credentials")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// Authorized reports whether the request carries the secret as a bearer
+// token or API key.
+func (h *HarborV1) authorized(r *http.Request) bool {
+	got := r.Header.Get("x-scanneradapter-api-key")
+	if v := r.Header.Get("authorization"); v != "" {
+		got = strings.TrimPrefix(v, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(h.secret)) == 1
+}
+
+// Error writes an error response in the format the specification requires.
+func (h *HarborV1) error(w http.ResponseWriter, code int, f string, v ...interface{}) {
+	var e harborError
+	e.Error.Message = fmt.Sprintf(f, v...)
+	w.Header().Set("content-type", harborErrorType)
+	w.WriteHeader(code)
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	enc.Encode(&e)
+}
+
+func (h *HarborV1) metadata(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/HarborV1.metadata")
+	if r.Method != http.MethodGet {
+		h.error(w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	md := harborMetadata{
+		Scanner: harborClair,
+		Capabilities: []harborCapability{{
+			ConsumesMIMETypes: harborConsumes,
+			ProducesMIMETypes: []string{harborReportType},
+		}},
+		Properties: map[string]string{
+			"harbor.scanner-adapter/scanner-type": "os-package-vulnerability",
+		},
+	}
+	if latest, err := latestUpdate(ctx, h.reports); err == nil && !latest.IsZero() {
+		md.Properties["harbor.scanner-adapter/vulnerability-database-updated-at"] = latest.UTC().Format(time.RFC3339)
+	}
+	var err error
+	w.Header().Set("content-type", harborMetadataType)
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(&md)
+}
+
+// LatestUpdate reports the time of the most recent vulnerability update
+// operation.
+func latestUpdate(ctx context.Context, h *MatcherV1) (time.Time, error) {
+	var latest time.Time
+	uos, err := h.srv.LatestUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		return latest, err
+	}
+	for _, us := range uos {
+		for _, u := range us {
+			if u.Date.After(latest) {
+				latest = u.Date
+			}
+		}
+	}
+	return latest, nil
+}
+
+func (h *HarborV1) scan(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/HarborV1.scan")
+	if r.Method != http.MethodPost {
+		h.error(w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	if ct := r.Header.Get("content-type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		want, _, _ := mime.ParseMediaType(harborScanReqType)
+		if err != nil || (mt != want && mt != "application/json") {
+			h.error(w, http.StatusUnsupportedMediaType, "unsupported content-type %q", ct)
+			return
+		}
+	}
+	var req harborScanRequest
+	dec := codec.GetDecoder(r.Body)
+	err := dec.Decode(&req)
+	codec.PutDecoder(dec)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "unable to decode scan request: %v", err)
+		return
+	}
+	ref, auth, err := h.parseScanRequest(&req)
+	if err != nil {
+		h.error(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	id := req.Artifact.Digest
+	ctx = zlog.ContextWithValues(ctx, "artifact", ref.String())
+
+	if ok, err := h.reports.indexed(ctx, claircore.MustParseDigest(id)); err == nil && ok {
+		zlog.Debug(ctx).Msg("artifact already indexed")
+		h.mu.Lock()
+		h.scans[id] = &harborScan{artifact: req.Artifact, done: time.Now()}
+		h.mu.Unlock()
+	} else if h.start(id, req.Artifact) {
+		go h.run(id, ref, auth)
+	}
+
+	w.Header().Set("content-type", harborScanRespType)
+	w.WriteHeader(http.StatusAccepted)
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(&harborScanResponse{ID: id})
+}
+
+// ParseScanRequest checks a scan request and returns the artifact reference
+// and registry credentials from it.
+func (h *HarborV1) parseScanRequest(req *harborScanRequest) (name.Reference, authn.Authenticator, error) {
+	if _, err := claircore.ParseDigest(req.Artifact.Digest); err != nil {
+		return nil, nil, fmt.Errorf("bad artifact digest: %w", err)
+	}
+	if mt := req.Artifact.MIMEType; mt != "" {
+		ok := false
+		for _, c := range harborConsumes {
+			ok = ok || c == mt
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported artifact type %q", mt)
+		}
+	}
+	u, err := url.Parse(req.Registry.URL)
+	if err != nil || u.Host == "" {
+		return nil, nil, fmt.Errorf("bad registry url %q", req.Registry.URL)
+	}
+	if h.allow != nil {
+		if _, ok := h.allow[u.Host]; !ok {
+			return nil, nil, fmt.Errorf("registry %q not allowed", u.Host)
+		}
+	}
+	var opts []name.Option
+	if u.Scheme == "http" {
+		opts = append(opts, name.Insecure)
+	}
+	ref, err := name.NewDigest(u.Host+"/"+req.Artifact.Repository+"@"+req.Artifact.Digest, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var auth authn.Authenticator = authn.Anonymous
+	switch a := req.Registry.Authorization; {
+	case a == "":
+	case strings.HasPrefix(a, "Basic "):
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(a, "Basic "))
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad registry authorization: %w", err)
+		}
+		user, pass, ok := strings.Cut(string(b), ":")
+		if !ok {
+			return nil, nil, errors.New("bad registry authorization: missing password")
+		}
+		auth = &authn.Basic{Username: user, Password: pass}
+	case strings.HasPrefix(a, "Bearer "):
+		auth = &authn.Bearer{Token: strings.TrimPrefix(a, "Bearer ")}
+	default:
+		return nil, nil, errors.New("unsupported registry authorization scheme")
+	}
+	return ref, auth, nil
+}
+
+// Start records a scan of "id" as started, reporting false if one already
+// is. Old finished scans are forgotten.
+func (h *HarborV1) start(id string, a harborArtifact) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	for k, s := range h.scans {
+		if !s.done.IsZero() && now.Sub(s.done) > harborScanRetain {
+			delete(h.scans, k)
+		}
+	}
+	if s, ok := h.scans[id]; ok && s.done.IsZero() {
+		return false
+	}
+	h.scans[id] = &harborScan{artifact: a}
+	return true
+}
+
+// Run fetches and indexes an artifact.
+func (h *HarborV1) run(id string, ref name.Reference, auth authn.Authenticator) {
+	ctx, done := context.WithTimeout(h.baseCtx, harborScanTimeout)
+	defer done()
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "httptransport/HarborV1.run",
+		"artifact", ref.String())
+	m, err := h.resolve(ctx, ref, auth)
+	if err == nil && m.Hash.String() != id {
+		err = fmt.Errorf("registry returned manifest %v, requested %s", m.Hash, id)
+	}
+	if err == nil {
+		_, err = h.reports.indexerSrv.Index(ctx, m)
+	}
+	if err != nil {
+		zlog.Warn(ctx).Err(err).Msg("scan failed")
+	} else {
+		zlog.Info(ctx).Msg("scan finished")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.scans[id]; ok {
+		s.done = time.Now()
+		s.err = err
+	}
+}
+
+func (h *HarborV1) report(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/HarborV1.report")
+	if r.Method != http.MethodGet {
+		h.error(w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	if path.Base(r.URL.Path) != "report" {
+		h.error(w, http.StatusNotFound, "not found")
+		return
+	}
+	if !harborAccepts(r) {
+		h.error(w, http.StatusBadRequest, "only %q reports are supported", harborReportType)
+		return
+	}
+	id := path.Base(path.Dir(r.URL.Path))
+	d, err := claircore.ParseDigest(id)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "unknown scan request %q", id)
+		return
+	}
+
+	h.mu.Lock()
+	var s harborScan
+	sp, known := h.scans[id]
+	if known {
+		s = *sp
+	}
+	h.mu.Unlock()
+	switch {
+	case known && s.done.IsZero():
+		harborNotReady(w)
+		return
+	case known && s.err != nil:
+		h.error(w, http.StatusInternalServerError, "scan failed: %v", s.err)
+		return
+	}
+
+	ir, ok, err := h.reports.indexerSrv.IndexReport(ctx, d)
+	switch {
+	case err != nil:
+		h.error(w, http.StatusInternalServerError, "%v", err)
+		return
+	case !ok:
+		h.error(w, http.StatusNotFound, "unknown scan request %q", id)
+		return
+	case ir.Err != "":
+		h.error(w, http.StatusInternalServerError, "scan failed: %s", ir.Err)
+		return
+	case !ir.Success:
+		harborNotReady(w)
+		return
+	}
+	vr, code, err := h.reports.report(ctx, d)
+	switch {
+	case err != nil:
+		h.error(w, code, "%v", err)
+		return
+	case vr == nil:
+		harborNotReady(w)
+		return
+	}
+	a := s.artifact
+	a.Digest = id
+
+	w.Header().Set("content-type", harborReportType)
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(harborReportFrom(vr, a, time.Now()))
+}
+
+// HarborNotReady tells the client to ask again later.
+func harborNotReady(w http.ResponseWriter) {
+	w.Header().Set("refresh-after", harborRefreshAfter)
+	w.WriteHeader(http.StatusFound)
+}
+
+// HarborAccepts reports whether the request accepts the Harbor report type.
+func harborAccepts(r *http.Request) bool {
+	want, _, _ := mime.ParseMediaType(harborReportType)
+	hs := r.Header["Accept"]
+	if len(hs) == 0 {
+		return true
+	}
+	for _, h := range hs {
+		for _, s := range strings.Split(h, ",") {
+			mt, _, err := mime.ParseMediaType(strings.TrimSpace(s))
+			if err == nil && (mt == want || mt == "*/*") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HarborReportFrom converts a vulnerability report.
+func harborReportFrom(vr *claircore.VulnerabilityReport, a harborArtifact, now time.Time) *harborReport {
+	out := harborReport{
+		GeneratedAt:     now.UTC(),
+		Artifact:        a,
+		Scanner:         harborClair,
+		Severity:        claircore.Unknown.String(),
+		Vulnerabilities: []harborVulnerability{},
+	}
+	max := claircore.Unknown
+	pkgs := make([]string, 0, len(vr.PackageVulnerabilities))
+	for id := range vr.PackageVulnerabilities {
+		pkgs = append(pkgs, id)
+	}
+	sort.Strings(pkgs)
+	for _, pid := range pkgs {
+		pkg, ok := vr.Packages[pid]
+		if !ok || pkg == nil {
+			continue
+		}
+		for _, vid := range vr.PackageVulnerabilities[pid] {
+			v, ok := vr.Vulnerabilities[vid]
+			if !ok || v == nil {
+				continue
+			}
+			if v.NormalizedSeverity > max {
+				max = v.NormalizedSeverity
+			}
+			out.Vulnerabilities = append(out.Vulnerabilities, harborVulnerability{
+				ID:          v.Name,
+				Package:     pkg.Name,
+				Version:     pkg.Version,
+				FixVersion:  v.FixedInVersion,
+				Severity:    v.NormalizedSeverity.String(),
+				Description: v.Description,
+				Links:       append([]string{}, strings.Fields(v.Links)...),
+			})
+		}
+	}
+	if len(out.Vulnerabilities) != 0 {
+		out.Severity = max.String()
+	}
+	return &out
+}
@@ -0,0 +1,200 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestHarborV1(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	var (
+		mu      sync.Mutex
+		indexed = make(map[string]*claircore.IndexReport)
+	)
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ir, ok := indexed[d.String()]
+			return ir, ok, nil
+		},
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			ir := &claircore.IndexReport{
+				Hash:     m.Hash,
+				Packages: map[string]*claircore.Package{"1": {ID: "1", Name: "openssl", Version: "1.1.1k"}},
+				Success:  true,
+			}
+			mu.Lock()
+			indexed[m.Hash.String()] = ir
+			mu.Unlock()
+			return ir, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{
+				Hash:     ir.Hash,
+				Packages: ir.Packages,
+				Vulnerabilities: map[string]*claircore.Vulnerability{
+					"1": {ID: "1", Name: "CVE-2023-0001", FixedInVersion: "1.1.1t", Links: "https://a https://b", NormalizedSeverity: claircore.High},
+				},
+				PackageVulnerabilities: map[string][]string{"1": {"1"}},
+			}, nil
+		},
+		LatestUpdateOperations_: func(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+			return map[string][]driver.UpdateOperation{
+				"test": {{Date: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}},
+			}, nil
+		},
+	}
+	release := make(chan struct{})
+	var gotAuth authn.Authenticator
+	h := newHarborV1(ctx, &MatcherV1{srv: m, indexerSrv: i}, "sekrit", []string{"harbor.example.com"})
+	h.resolve = func(_ context.Context, r name.Reference, auth authn.Authenticator) (*claircore.Manifest, error) {
+		<-release
+		gotAuth = auth
+		return &claircore.Manifest{Hash: claircore.MustParseDigest(r.Identifier())}, nil
+	}
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(t *testing.T, method, path, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("authorization", "Bearer sekrit")
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Fatalf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		res, err := srv.Client().Get(srv.URL + "/api/v1/metadata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusUnauthorized; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		if got, want := res.Header.Get("content-type"), harborErrorType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/api/v1/metadata", "", http.StatusOK)
+		defer res.Body.Close()
+		var md harborMetadata
+		if err := json.NewDecoder(res.Body).Decode(&md); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := md.Capabilities[0].ProducesMIMETypes[0], harborReportType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := md.Properties["harbor.scanner-adapter/vulnerability-database-updated-at"], "2000-01-01T00:00:00Z"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+	t.Run("DisallowedRegistry", func(t *testing.T) {
+		do(t, http.MethodPost, "/api/v1/scan",
+			`{"registry":{"url":"https://elsewhere.example.com"},"artifact":{"repository":"library/repo","digest":"`+digest+`"}}`,
+			http.StatusUnprocessableEntity).Body.Close()
+	})
+	t.Run("Scan", func(t *testing.T) {
+		res := do(t, http.MethodPost, "/api/v1/scan",
+			`{"registry":{"url":"https://harbor.example.com","authorization":"Basic cm9ib3Q6cGFzcw=="},`+
+				`"artifact":{"repository":"library/repo","digest":"`+digest+`","tag":"latest","mime_type":"application/vnd.docker.distribution.manifest.v2+json"}}`,
+			http.StatusAccepted)
+		var sr harborScanResponse
+		err := json.NewDecoder(res.Body).Decode(&sr)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sr.ID != digest {
+			t.Errorf("got: %q, want: %q", sr.ID, digest)
+		}
+		report := "/api/v1/scan/" + sr.ID + "/report"
+		res = do(t, http.MethodGet, report, "", http.StatusFound)
+		res.Body.Close()
+		if got, want := res.Header.Get("refresh-after"), harborRefreshAfter; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+
+		close(release)
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+report, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("authorization", "Bearer sekrit")
+			req.Header.Set("accept", harborReportType)
+			res, err = srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusFound {
+				break
+			}
+			res.Body.Close()
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for report")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		defer res.Body.Close()
+		if b, ok := gotAuth.(*authn.Basic); !ok || b.Username != "robot" || b.Password != "pass" {
+			t.Errorf("unexpected registry auth: %#v", gotAuth)
+		}
+		var got harborReport
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Severity != "High" || len(got.Vulnerabilities) != 1 {
+			t.Fatalf("got: %+v", got)
+		}
+		v := got.Vulnerabilities[0]
+		if v.ID != "CVE-2023-0001" || v.Package != "openssl" || v.FixVersion != "1.1.1t" || len(v.Links) != 2 {
+			t.Errorf("got: %+v", v)
+		}
+		if got.Artifact.Repository != "library/repo" {
+			t.Errorf("got: %+v", got.Artifact)
+		}
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		do(t, http.MethodGet, "/api/v1/scan/sha256:"+strings.Repeat("1", 64)+"/report", "", http.StatusNotFound).Body.Close()
+		do(t, http.MethodGet, "/api/v1/scan/garbage/report", "", http.StatusNotFound).Body.Close()
+	})
+}
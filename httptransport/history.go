@@ -0,0 +1,53 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/matcher/history"
+)
+
+// Headers describing a report rewound with the "as_of" query parameter.
+const (
+	asOfHeader       = "Clair-As-Of"
+	unretainedHeader = "Clair-Unretained-Updaters"
+)
+
+// ParseAsOf parses the "as_of" query parameter, returning nil if it's not
+// present.
+func parseAsOf(q url.Values) (*history.Point, error) {
+	s := q.Get("as_of")
+	if s == "" {
+		return nil, nil
+	}
+	p, err := history.ParsePoint(s)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Rewind reconstructs "vr" as of "p". If it returns false, a response has
+// already been written.
+func (h *MatcherV1) rewind(ctx context.Context, w http.ResponseWriter, vr *claircore.VulnerabilityReport, p *history.Point) (*claircore.VulnerabilityReport, bool) {
+	res, err := history.Rewind(ctx, h.srv, h.indexerSrv, vr, *p)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, history.ErrNotRetained):
+		apiError(ctx, w, http.StatusNotFound, "update operation %q not retained", p.String())
+		return nil, false
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "unable to reconstruct report: %v", err)
+		return nil, false
+	}
+	w.Header().Set(asOfHeader, p.String())
+	if len(res.Unretained) != 0 {
+		w.Header().Set(unretainedHeader, strings.Join(res.Unretained, ", "))
+	}
+	return res.VulnerabilityReport, true
+}
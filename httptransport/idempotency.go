@@ -0,0 +1,243 @@
+package httptransport
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+var idempotencyCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: metricSubsystem,
+		Name:      "idempotency_total",
+		Help:      "Total number of requests carrying an idempotency key, by outcome.",
+	},
+	[]string{"result"},
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying an idempotency
+	// key.
+	idempotencyKeyHeader = `Idempotency-Key`
+	// IdempotentReplayedHeader is set on responses replayed for a repeated
+	// idempotency key.
+	idempotentReplayedHeader = `Idempotent-Replayed`
+	// MaxIdempotencyKey is the longest idempotency key accepted.
+	maxIdempotencyKey = 255
+)
+
+// IdempotencyCache remembers the responses to requests carrying an
+// idempotency key, so that a client retrying a request (say, after a network
+// timeout) gets the original response instead of starting the work again.
+//
+// Only successful responses are kept; a failed request may be retried with
+// the same key. A key reused with a different request is rejected.
+type idempotencyCache struct {
+	ttl time.Duration
+	max int
+
+	mu sync.Mutex
+	m  map[string]*list.Element
+	// Order holds the entries oldest first. Entries all live for the same
+	// TTL, so this is also expiry order.
+	order list.List
+}
+
+type idempotentEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	expires     time.Time
+	// Done is set once the response has been recorded.
+	done   bool
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newIdempotencyCache(ttl time.Duration, max int) *idempotencyCache {
+	return &idempotencyCache{
+		ttl: ttl,
+		max: max,
+		m:   make(map[string]*list.Element),
+	}
+}
+
+// Begin looks up "key". If it isn't present, an entry is reserved and
+// returned with "fresh" set.
+func (c *idempotencyCache) begin(key string, fp [sha256.Size]byte, now time.Time) (e idempotentEntry, fresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for f := c.order.Front(); f != nil && !now.Before(f.Value.(*idempotentEntry).expires); f = c.order.Front() {
+		c.evict(f)
+	}
+	if el, ok := c.m[key]; ok {
+		return *el.Value.(*idempotentEntry), false
+	}
+	for c.order.Len() >= c.max {
+		c.evict(c.order.Front())
+	}
+	ne := &idempotentEntry{key: key, fingerprint: fp, expires: now.Add(c.ttl)}
+	c.m[key] = c.order.PushBack(ne)
+	return *ne, true
+}
+
+// Evict removes the entry at "el". The caller must hold the lock.
+func (c *idempotencyCache) evict(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.m, el.Value.(*idempotentEntry).key)
+}
+
+// Finish records the response for "key", or forgets the key if the response
+// shouldn't be replayed.
+func (c *idempotencyCache) finish(key string, status int, header http.Header, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.m[key]
+	if !ok { // Evicted while in flight.
+		return
+	}
+	if status < 200 || status > 299 {
+		c.evict(el)
+		return
+	}
+	e := el.Value.(*idempotentEntry)
+	e.done = true
+	e.status = status
+	e.header = header
+	e.body = body
+}
+
+// Handler returns a Handler that applies idempotency keys to requests before
+// passing them to "next". Requests without a key are passed through.
+func (c *idempotencyCache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			apiError(ctx, w, http.StatusBadRequest, "malformed %s header", idempotencyKeyHeader)
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "unable to read request: %v", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		h := sha256.New()
+		io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+		h.Write(b)
+		var fp [sha256.Size]byte
+		h.Sum(fp[:0])
+		// Keys are only unique per client, so scope them to the caller if
+		// it's known.
+		if id, ok := auth.IdentityFromContext(ctx); ok {
+			key = id.Issuer + "\x00" + id.Subject + "\x00" + key
+		}
+
+		e, fresh := c.begin(key, fp, time.Now())
+		switch {
+		case fresh:
+		case e.fingerprint != fp:
+			idempotencyCounter.WithLabelValues("mismatch").Inc()
+			apiError(ctx, w, http.StatusUnprocessableEntity, "%s reused for a different request", idempotencyKeyHeader)
+			return
+		case !e.done:
+			idempotencyCounter.WithLabelValues("conflict").Inc()
+			w.Header().Set("retry-after", "1")
+			apiError(ctx, w, http.StatusConflict, "request with this %s is still in progress", idempotencyKeyHeader)
+			return
+		default:
+			idempotencyCounter.WithLabelValues("replayed").Inc()
+			zlog.Debug(ctx).
+				Int("status", e.status).
+				Msg("replaying response for idempotency key")
+			hdr := w.Header()
+			for k, v := range e.header {
+				hdr[k] = v
+			}
+			hdr.Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(e.status)
+			w.Write(e.body)
+			return
+		}
+
+		idempotencyCounter.WithLabelValues("new").Inc()
+		rec := &recordingWriter{ResponseWriter: w}
+		returned := false
+		defer func() {
+			// If "next" panicked, the status is left as 0 and the key is
+			// forgotten.
+			status := rec.status
+			if status == 0 && returned {
+				status = http.StatusOK
+			}
+			if rec.header == nil {
+				rec.header = w.Header().Clone()
+			}
+			c.finish(key, status, rec.header, rec.body.Bytes())
+		}()
+		next.ServeHTTP(rec, r)
+		returned = true
+	})
+}
+
+// ValidIdempotencyKey reports whether "k" is an acceptable key: printable
+// ASCII and not too long.
+func validIdempotencyKey(k string) bool {
+	if len(k) > maxIdempotencyKey {
+		return false
+	}
+	for i := 0; i < len(k); i++ {
+		if k[i] < 0x20 || k[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordingWriter passes a response through, keeping a copy.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
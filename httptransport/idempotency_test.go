@@ -0,0 +1,129 @@
+package httptransport
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+)
+
+func TestIdempotency(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var calls int32
+	fail := make(map[string]bool)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		b, _ := io.ReadAll(r.Body)
+		switch string(b) {
+		case "block":
+			close(started)
+			<-block
+		case "fail":
+			if !fail["done"] {
+				fail["done"] = true
+				http.Error(w, "oops", http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("location", "/report")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, string(b)+strings.Repeat("!", int(n)))
+	})
+	h := newIdempotencyCache(time.Hour, 10).Handler(next)
+	do := func(key, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/index_report", strings.NewReader(body)).WithContext(ctx)
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do("a", "one")
+	if got, want := first.Code, http.StatusCreated; got != want {
+		t.Errorf("first: got: %d, want: %d", got, want)
+	}
+	replay := do("a", "one")
+	if got, want := replay.Code, http.StatusCreated; got != want {
+		t.Errorf("replay: got: %d, want: %d", got, want)
+	}
+	if got, want := replay.Body.String(), first.Body.String(); got != want {
+		t.Errorf("replay body: got: %q, want: %q", got, want)
+	}
+	if got, want := replay.Header().Get("location"), "/report"; got != want {
+		t.Errorf("replay location: got: %q, want: %q", got, want)
+	}
+	if got := replay.Header().Get(idempotentReplayedHeader); got != "true" {
+		t.Errorf("replay header: got: %q", got)
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(1); got != want {
+		t.Errorf("calls: got: %d, want: %d", got, want)
+	}
+
+	if got, want := do("a", "two").Code, http.StatusUnprocessableEntity; got != want {
+		t.Errorf("mismatch: got: %d, want: %d", got, want)
+	}
+	if got, want := do("", "one").Code, http.StatusCreated; got != want {
+		t.Errorf("no key: got: %d, want: %d", got, want)
+	}
+	if got, want := do("bad\nkey", "one").Code, http.StatusBadRequest; got != want {
+		t.Errorf("bad key: got: %d, want: %d", got, want)
+	}
+
+	if got, want := do("f", "fail").Code, http.StatusInternalServerError; got != want {
+		t.Errorf("fail: got: %d, want: %d", got, want)
+	}
+	if got, want := do("f", "fail").Code, http.StatusCreated; got != want {
+		t.Errorf("retry after fail: got: %d, want: %d", got, want)
+	}
+
+	done := make(chan int)
+	go func() {
+		done <- do("b", "block").Code
+	}()
+	<-started
+	if got, want := do("b", "block").Code, http.StatusConflict; got != want {
+		t.Errorf("in flight: got: %d, want: %d", got, want)
+	}
+	close(block)
+	if got, want := <-done, http.StatusCreated; got != want {
+		t.Errorf("blocked: got: %d, want: %d", got, want)
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	c := newIdempotencyCache(time.Minute, 2)
+	var fp [sha256.Size]byte
+	now := time.Now()
+	for _, k := range []string{"a", "b"} {
+		if _, fresh := c.begin(k, fp, now); !fresh {
+			t.Fatalf("%s: not fresh", k)
+		}
+		c.finish(k, http.StatusOK, nil, nil)
+	}
+	if _, fresh := c.begin("a", fp, now); fresh {
+		t.Error("a: unexpectedly fresh")
+	}
+	// Full: adding "c" evicts "a".
+	if _, fresh := c.begin("c", fp, now); !fresh {
+		t.Error("c: not fresh")
+	}
+	if _, fresh := c.begin("a", fp, now); !fresh {
+		t.Error("a: not evicted")
+	}
+	// Everything expires.
+	if _, fresh := c.begin("c", fp, now.Add(2*time.Minute)); !fresh {
+		t.Error("c: not expired")
+	}
+}
@@ -6,16 +6,26 @@
 	"fmt"
 	"net/http"
 	"path"
+	"sort"
 	"time"
 
 	"github.com/ldelossa/responserecorder"
+	"github.com/quay/clair/config"
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/pkg/tarfs"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/artifact"
+	"github.com/quay/clair/v4/indexer/ecosystem"
+	"github.com/quay/clair/v4/indexer/imageconfig"
+	"github.com/quay/clair/v4/indexer/labels"
+	"github.com/quay/clair/v4/indexer/licenses"
+	"github.com/quay/clair/v4/indexer/secrets"
 	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dsse"
+	"github.com/quay/clair/v4/internal/httputil"
 )
 
 // NewIndexerV1 returns an http.Handler serving the Indexer V1 API rooted at
@@ -33,21 +43,48 @@ func NewIndexerV1(_ context.Context, prefix string, srv indexer.Service, topt ot
 		srv: srv,
 	}
 	p := path.Join(prefix, "index_report")
-	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.indexReport))
+	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.idempotent(h.indexReport)))
 	p += "/"
 	m.Handle(p, indexerv1wrapper.wrapFunc(path.Join(p, ":digest"), h.indexReportOne))
+	p = path.Join(prefix, "index_reference")
+	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.idempotent(h.indexReference)))
 	p = path.Join(prefix, "index_state")
 	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.indexState))
+	p = path.Join(prefix, "file_owners") + "/"
+	m.Handle(p, indexerv1wrapper.wrapFunc(path.Join(p, ":digest"), h.fileOwners))
+	p = path.Join(prefix, "license_check") + "/"
+	m.Handle(p, indexerv1wrapper.wrapFunc(path.Join(p, ":digest"), h.licenseCheck))
 	p = path.Join(prefix, "internal", "affected_manifest") + "/"
 	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.affectedManifests))
+	p = path.Join(prefix, "internal", "manifest_labels")
+	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.manifestLabels))
+	p = path.Join(prefix, "internal", "usage")
+	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.usage))
+	p = path.Join(prefix, "internal", "manifests")
+	m.Handle(p, indexerv1wrapper.wrapFunc(p, h.manifests))
 
 	return &h, nil
 }
 
 // IndexerV1 is a consolidated Indexer endpoint.
 type IndexerV1 struct {
-	inner http.Handler
-	srv   indexer.Service
+	inner  http.Handler
+	srv    indexer.Service
+	limits config.IndexerLimits
+	// Signer signs reports for clients that request them signed, if not nil.
+	signer *dsse.Signer
+	// Scanners are the scanners the indexer runs, reported in index reports.
+	scanners []ecosystem.Scanner
+	// Idem replays responses to index requests with repeated idempotency
+	// keys, if not nil.
+	idem *idempotencyCache
+	// Resolver resolves image references for clients, if not nil.
+	resolver *refResolver
+	// Policy is the license policy manifests are checked against, if not
+	// nil.
+	policy *licenses.Policy
+	// Redact decides which report fields to remove for each caller.
+	redact *redactor
 }
 
 var _ http.Handler = (*IndexerV1)(nil)
@@ -72,6 +109,147 @@ func (h *IndexerV1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.inner.ServeHTTP(wr, r)
 }
 
+// Idempotent applies idempotency keys to POST requests handled by "next", if
+// configured.
+func (h *IndexerV1) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.idem == nil || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		h.idem.Handler(next).ServeHTTP(w, r)
+	}
+}
+
+// IndexRequest is the body of an index request: a Manifest, optionally
+// annotated with the artifact type it describes, labels to store with it, the
+// environment from its image config, and a descriptor for the image config
+// blob itself.
+type indexRequest struct {
+	claircore.Manifest
+	ArtifactType string            `json:"artifact_type,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Env          []string          `json:"env,omitempty"`
+	Config       *claircore.Layer  `json:"config,omitempty"`
+}
+
+// Labels returns the stored labels for the manifest "d", if the indexer
+// stores labels.
+//
+// Labels are auxiliary, so errors are logged rather than failing the request.
+func labelsFor(ctx context.Context, srv indexer.Service, d claircore.Digest) map[string]string {
+	l, ok := srv.(indexer.Labeler)
+	if !ok {
+		return nil
+	}
+	ls, err := l.Labels(ctx, d)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		return nil
+	default:
+		zlog.Warn(ctx).Err(err).Stringer("manifest", d).Msg("unable to look up labels")
+		return nil
+	}
+	return ls[d.String()]
+}
+
+// SupportsLabels reports whether the indexer stores labels.
+func supportsLabels(ctx context.Context, srv indexer.Service) bool {
+	l, ok := srv.(indexer.Labeler)
+	if !ok {
+		return false
+	}
+	// Asking for no manifests is free, and only fails if labels aren't
+	// supported.
+	_, err := l.Labels(ctx)
+	return !errors.Is(err, indexer.ErrUnsupported)
+}
+
+// SecretsFor returns the credentials found embedded in the manifest "d", if
+// the indexer looks for them.
+//
+// Like labels, errors are logged rather than failing the request.
+func secretsFor(ctx context.Context, srv indexer.Service, d claircore.Digest) []indexer.Secret {
+	sr, ok := srv.(indexer.SecretReporter)
+	if !ok {
+		return nil
+	}
+	ss, _, err := sr.Secrets(ctx, d)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		return nil
+	default:
+		zlog.Warn(ctx).Err(err).Stringer("manifest", d).Msg("unable to look up secrets")
+		return nil
+	}
+	return ss
+}
+
+// LicensesFor returns the licenses declared by the packages in "report", if
+// the indexer records them.
+//
+// Like labels, errors are logged rather than failing the request.
+func licensesFor(ctx context.Context, srv indexer.Service, report *claircore.IndexReport) map[string]string {
+	lr, ok := srv.(indexer.LicenseReporter)
+	if !ok || report == nil {
+		return nil
+	}
+	ls, err := lr.Licenses(ctx, report)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		return nil
+	default:
+		zlog.Warn(ctx).Err(err).Stringer("manifest", report.Hash).Msg("unable to look up licenses")
+		return nil
+	}
+	return ls
+}
+
+// ImageConfigFor returns the image config recorded for the manifest "d", if
+// the indexer records them.
+//
+// Like labels, errors are logged rather than failing the request.
+func imageConfigFor(ctx context.Context, srv indexer.Service, d claircore.Digest) *indexer.ImageConfig {
+	cr, ok := srv.(indexer.ImageConfigReporter)
+	if !ok {
+		return nil
+	}
+	c, _, err := cr.ImageConfig(ctx, d)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		return nil
+	default:
+		zlog.Warn(ctx).Err(err).Stringer("manifest", d).Msg("unable to look up image config")
+		return nil
+	}
+	return c
+}
+
+// UnpackagedFor returns the unpackaged contents found in the manifest "d",
+// if the indexer looks for them.
+//
+// Like labels, errors are logged rather than failing the request.
+func unpackagedFor(ctx context.Context, srv indexer.Service, d claircore.Digest) *indexer.Unpackaged {
+	ur, ok := srv.(indexer.UnpackagedReporter)
+	if !ok {
+		return nil
+	}
+	u, _, err := ur.Unpackaged(ctx, d)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		return nil
+	default:
+		zlog.Warn(ctx).Err(err).Stringer("manifest", d).Msg("unable to look up unpackaged contents")
+		return nil
+	}
+	return u
+}
+
 func (h *IndexerV1) indexReport(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	switch r.Method {
@@ -91,45 +269,17 @@ func (h *IndexerV1) indexReport(w http.ResponseWriter, r *http.Request) {
 			apiError(ctx, w, http.StatusInternalServerError, "could not retrieve indexer state: %v", err)
 			return
 		}
-		var m claircore.Manifest
-		if err := dec.Decode(&m); err != nil {
-			apiError(ctx, w, http.StatusBadRequest, "failed to deserialize manifest: %v", err)
-			return
-		}
-		if m.Hash.String() == "" || len(m.Layers) == 0 {
-			apiError(ctx, w, http.StatusBadRequest, "bogus manifest")
-			return
-		}
-		next := path.Join(r.URL.Path, m.Hash.String())
-
-		w.Header().Add("link", fmt.Sprintf(linkIndex, next))
-		w.Header().Add("link", fmt.Sprintf(linkReport, path.Join(VulnerabilityReportPath, m.Hash.String())))
-		validator := `"` + state + `"`
-		if unmodified(r, validator) {
-			w.WriteHeader(http.StatusPreconditionFailed)
+		schema, err := reportSchema(w, r)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
 			return
 		}
-
-		// TODO Do we need some sort of background context embedded in the HTTP
-		// struct?
-		report, err := h.srv.Index(ctx, &m)
-		switch {
-		case errors.Is(err, nil):
-		case errors.Is(err, tarfs.ErrFormat):
-			apiError(ctx, w, http.StatusBadRequest, "failed to start scan: %v", err)
-			return
-		default:
-			apiError(ctx, w, http.StatusInternalServerError, "failed to start scan: %v", err)
+		var req indexRequest
+		if err := dec.Decode(&req); err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "failed to deserialize manifest: %v", err)
 			return
 		}
-
-		w.Header().Set("etag", validator)
-		w.Header().Set("location", next)
-		defer writerError(w, &err)()
-		w.WriteHeader(http.StatusCreated)
-		enc := codec.GetEncoder(w)
-		defer codec.PutEncoder(enc)
-		err = enc.Encode(report)
+		h.index(w, r, &req, state, schema, r.URL.Path)
 	case http.MethodDelete:
 		var ds []claircore.Digest
 		if err := dec.Decode(&ds); err != nil {
@@ -152,6 +302,96 @@ func (h *IndexerV1) indexReport(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Index indexes the manifest in "req" and writes the index report. The
+// report's location is "base" joined with the manifest digest.
+func (h *IndexerV1) index(w http.ResponseWriter, r *http.Request, req *indexRequest, state, schema, base string) {
+	ctx := r.Context()
+	m := req.Manifest
+	if m.Hash.String() == "" || len(m.Layers) == 0 {
+		apiError(ctx, w, http.StatusBadRequest, "bogus manifest")
+		return
+	}
+	noteDigest(ctx, m.Hash)
+	if max := h.limits.MaxLayers; max > 0 && len(m.Layers) > max {
+		apiError(ctx, w, http.StatusRequestEntityTooLarge,
+			"manifest has %d layers, over limit of %d", len(m.Layers), max)
+		return
+	}
+	next := path.Join(base, m.Hash.String())
+
+	w.Header().Add("link", fmt.Sprintf(linkIndex, next))
+	w.Header().Add("link", fmt.Sprintf(linkReport, path.Join(VulnerabilityReportPath, m.Hash.String())))
+	validator := `"` + state + `"`
+	if unmodified(r, validator) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	// TODO Do we need some sort of background context embedded in the HTTP
+	// struct?
+	ictx := httputil.WithByteBudget(ctx, h.limits.MaxManifestSize)
+	if req.ArtifactType != "" {
+		ictx = artifact.WithType(ictx, req.ArtifactType)
+	}
+	if len(req.Labels) != 0 {
+		if !supportsLabels(ctx, h.srv) {
+			apiError(ctx, w, http.StatusBadRequest, "labels are not enabled on this indexer")
+			return
+		}
+		if err := labels.Validate(req.Labels); err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "invalid labels: %v", err)
+			return
+		}
+		ictx = labels.WithLabels(ictx, req.Labels)
+	}
+	if req.Env != nil {
+		ictx = secrets.WithEnv(ictx, req.Env)
+	}
+	if c := req.Config; c != nil {
+		if c.Hash.Checksum() == nil || c.URI == "" {
+			apiError(ctx, w, http.StatusBadRequest, "bogus image config descriptor")
+			return
+		}
+		ictx = imageconfig.WithConfig(ictx, c)
+	}
+	report, err := h.srv.Index(ictx, &m)
+	var unsupported *indexer.UnsupportedPlatform
+	switch {
+	case errors.Is(err, nil):
+	case errors.As(err, &unsupported):
+		platformError(ctx, w, unsupported)
+		return
+	case errors.Is(err, tarfs.ErrFormat):
+		apiError(ctx, w, http.StatusBadRequest, "failed to start scan: %v", err)
+		return
+	case errors.Is(err, httputil.ErrTooLarge):
+		apiError(ctx, w, http.StatusRequestEntityTooLarge, "failed to start scan: %v", err)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "failed to start scan: %v", err)
+		return
+	}
+
+	w.Header().Set("etag", validator)
+	w.Header().Set("location", next)
+	defer writerError(w, &err)()
+	w.WriteHeader(http.StatusCreated)
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	out := labeledIndexReport{
+		IndexReport: report,
+		Labels:      labelsFor(ctx, h.srv, m.Hash),
+		Scanners:    h.scanners,
+		Secrets:     secretsFor(ctx, h.srv, m.Hash),
+		Licenses:    licensesFor(ctx, h.srv, report),
+		Origins:     ecosystem.Origins(report),
+		ImageConfig: imageConfigFor(ctx, h.srv, m.Hash),
+		Unpackaged:  unpackagedFor(ctx, h.srv, m.Hash),
+	}
+	h.redact.fields(ctx).indexReport(&out)
+	err = enc.Encode(out.in(schema))
+}
+
 const (
 	linkIndex  = `<%s>; rel="https://projectquay.io/clair/v1/index_report"`
 	linkReport = `<%s>; rel="https://projectquay.io/clair/v1/vulnerability_report"`
@@ -173,14 +413,26 @@ func (h *IndexerV1) indexReportOne(w http.ResponseWriter, r *http.Request) {
 	}
 	switch r.Method {
 	case http.MethodGet:
-		allow := []string{"application/vnd.clair.indexreport.v1+json", "application/json"}
-		switch err := pickContentType(w, r, allow); {
-		case errors.Is(err, nil): // OK
-		case errors.Is(err, ErrMediaType):
-			apiError(ctx, w, http.StatusUnsupportedMediaType, "unable to negotiate common media type for %v", allow)
-			return
-		default:
-			apiError(ctx, w, http.StatusBadRequest, "malformed request: %v", err)
+		// A signed report is only sent when asked for by name, so it's
+		// negotiated separately.
+		signed := h.signer != nil && wantEnvelope(r)
+		allow := []string{"application/vnd.clair.indexreport.v1+json", "application/json", codec.CBORMediaType}
+		if signed {
+			w.Header().Set("content-type", dsse.MediaType)
+		} else {
+			switch err := pickContentType(w, r, allow); {
+			case errors.Is(err, nil): // OK
+			case errors.Is(err, ErrMediaType):
+				apiError(ctx, w, http.StatusUnsupportedMediaType, "unable to negotiate common media type for %v", allow)
+				return
+			default:
+				apiError(ctx, w, http.StatusBadRequest, "malformed request: %v", err)
+				return
+			}
+		}
+		schema, err := reportSchema(w, r)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
 			return
 		}
 
@@ -205,11 +457,30 @@ func (h *IndexerV1) indexReportOne(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		out := labeledIndexReport{
+			IndexReport: report,
+			Labels:      labelsFor(ctx, h.srv, d),
+			Scanners:    h.scanners,
+			Secrets:     secretsFor(ctx, h.srv, d),
+			Licenses:    licensesFor(ctx, h.srv, report),
+			Origins:     ecosystem.Origins(report),
+			ImageConfig: imageConfigFor(ctx, h.srv, d),
+			Unpackaged:  unpackagedFor(ctx, h.srv, d),
+		}
+		h.redact.fields(ctx).indexReport(&out)
+		body := out.in(schema)
+		if signed {
+			body, err = signReport(h.signer, indexReportPayloadType, body)
+			if err != nil {
+				apiError(ctx, w, http.StatusInternalServerError, "unable to sign report: %v", err)
+				return
+			}
+		}
 		w.Header().Add("etag", validator)
 		defer writerError(w, &err)()
-		enc := codec.GetEncoder(w)
-		defer codec.PutEncoder(enc)
-		err = enc.Encode(report)
+		enc, put := responseEncoder(w)
+		defer put()
+		err = enc.Encode(body)
 	case http.MethodDelete:
 		if _, err := h.srv.DeleteManifests(ctx, d); err != nil {
 			apiError(ctx, w, http.StatusInternalServerError, "unable to delete manifest: %v", err)
@@ -241,22 +512,223 @@ func (h *IndexerV1) indexState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag := `"` + s + `"`
-	w.Header().Add("etag", tag)
+	// If a manifest is named, report on its progress as well. The progress
+	// changes independently of the indexer state, so the response can't be
+	// cached.
+	var progress *indexer.Progress
+	if m := r.URL.Query().Get("manifest"); m != "" {
+		d, err := claircore.ParseDigest(m)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "malformed manifest digest: %v", err)
+			return
+		}
+		report, ok, err := h.srv.IndexReport(ctx, d)
+		if err != nil {
+			apiError(ctx, w, http.StatusInternalServerError, "could not retrieve index report: %v", err)
+			return
+		}
+		if !ok {
+			apiError(ctx, w, http.StatusNotFound, "index report not found")
+			return
+		}
+		p := indexer.ReportProgress(report)
+		progress = &p
+		w.Header().Set("cache-control", "no-store")
+	} else {
+		tag := `"` + s + `"`
+		w.Header().Add("etag", tag)
+
+		if unmodified(r, tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	defer writerError(w, &err)()
+	// TODO(hank) Don't use an encoder to write out like 40 bytes of json.
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(struct {
+		State    string            `json:"state"`
+		Progress *indexer.Progress `json:"progress,omitempty"`
+	}{
+		State:    s,
+		Progress: progress,
+	})
+}
+
+func (h *IndexerV1) fileOwners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	d, err := getDigest(w, r)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		apiError(ctx, w, http.StatusBadRequest, "missing required query parameter: path")
+		return
+	}
+	allow := []string{"application/vnd.clair.fileowners.v1+json", "application/json"}
+	switch err := pickContentType(w, r, allow); {
+	case errors.Is(err, nil): // OK
+	case errors.Is(err, ErrMediaType):
+		apiError(ctx, w, http.StatusUnsupportedMediaType, "unable to negotiate common media type for %v", allow)
+		return
+	default:
+		apiError(ctx, w, http.StatusBadRequest, "malformed request: %v", err)
+		return
+	}
 
-	if unmodified(r, tag) {
+	state, err := h.srv.State(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not retrieve indexer state: %v", err)
+		return
+	}
+	validator := `"` + state + `"`
+	if unmodified(r, validator) {
 		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
+	report, ok, err := h.srv.IndexReport(ctx, d)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "index report not found")
+		return
+	}
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not retrieve index report: %v", err)
+		return
+	}
+
+	owners := indexer.FileOwners(report, p)
+	if fr, ok := h.srv.(indexer.FileOwnerReporter); ok {
+		rec, err := fr.FileOwners(ctx, report, p)
+		switch {
+		case errors.Is(err, nil):
+			owners = indexer.MergeFileOwners(owners, rec)
+		case errors.Is(err, indexer.ErrUnsupported):
+		default:
+			apiError(ctx, w, http.StatusInternalServerError, "could not retrieve file owners: %v", err)
+			return
+		}
+	}
+
+	w.Header().Add("etag", validator)
 	defer writerError(w, &err)()
-	// TODO(hank) Don't use an encoder to write out like 40 bytes of json.
 	enc := codec.GetEncoder(w)
 	defer codec.PutEncoder(enc)
 	err = enc.Encode(struct {
-		State string `json:"state"`
+		Hash   claircore.Digest    `json:"manifest_hash"`
+		Path   string              `json:"path"`
+		Owners []indexer.FileOwner `json:"owners"`
 	}{
-		State: s,
+		Hash:   d,
+		Path:   p,
+		Owners: owners,
+	})
+}
+
+// LicenseViolation is a package whose declared license the policy doesn't
+// accept.
+type licenseViolation struct {
+	PackageID string `json:"package_id"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	License   string `json:"license"`
+	Reason    string `json:"reason"`
+}
+
+func (h *IndexerV1) licenseCheck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	lr, ok := h.srv.(indexer.LicenseReporter)
+	if h.policy == nil || !ok {
+		apiError(ctx, w, http.StatusNotFound, "license policy not configured")
+		return
+	}
+	d, err := getDigest(w, r)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	allow := []string{"application/vnd.clair.licensecheck.v1+json", "application/json"}
+	switch err := pickContentType(w, r, allow); {
+	case errors.Is(err, nil): // OK
+	case errors.Is(err, ErrMediaType):
+		apiError(ctx, w, http.StatusUnsupportedMediaType, "unable to negotiate common media type for %v", allow)
+		return
+	default:
+		apiError(ctx, w, http.StatusBadRequest, "malformed request: %v", err)
+		return
+	}
+
+	report, ok, err := h.srv.IndexReport(ctx, d)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "index report not found")
+		return
+	}
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not retrieve index report: %v", err)
+		return
+	}
+	ls, err := lr.Licenses(ctx, report)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		apiError(ctx, w, http.StatusNotFound, "license policy not configured")
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not retrieve licenses: %v", err)
+		return
+	}
+
+	// Packages are reported in ID order, so the response is stable.
+	ids := make([]string, 0, len(report.Packages))
+	for id := range report.Packages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	violations := []licenseViolation{}
+	unknown := []string{}
+	for _, id := range ids {
+		l, ok := ls[id]
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		if ok, reason := h.policy.Check(l); !ok {
+			p := report.Packages[id]
+			violations = append(violations, licenseViolation{
+				PackageID: id,
+				Name:      p.Name,
+				Version:   p.Version,
+				License:   l,
+				Reason:    reason,
+			})
+		}
+	}
+
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(struct {
+		Hash       claircore.Digest   `json:"manifest_hash"`
+		Compliant  bool               `json:"compliant"`
+		Violations []licenseViolation `json:"violations"`
+		Unknown    []string           `json:"unknown"`
+	}{
+		Hash:       d,
+		Compliant:  len(violations) == 0,
+		Violations: violations,
+		Unknown:    unknown,
 	})
 }
 
@@ -266,7 +738,7 @@ func (h *IndexerV1) affectedManifests(w http.ResponseWriter, r *http.Request) {
 		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
 		return
 	}
-	allow := []string{"application/vnd.clair.affectedmanifests.v1+json", "application/json"}
+	allow := []string{"application/vnd.clair.affectedmanifests.v1+json", "application/json", codec.CBORMediaType}
 	switch err := pickContentType(w, r, allow); {
 	case errors.Is(err, nil): // OK
 	case errors.Is(err, ErrMediaType):
@@ -293,10 +765,55 @@ func (h *IndexerV1) affectedManifests(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	defer writerError(w, &err)
+	defer writerError(w, &err)()
+	enc, put := responseEncoder(w)
+	defer put()
+	err = enc.Encode(affected)
+}
+
+// ManifestLabels reports the labels stored for a set of manifests.
+//
+// If the indexer doesn't store labels, the result is always empty, so that
+// callers don't need to know how the indexer is configured.
+func (h *IndexerV1) manifestLabels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	var req struct {
+		Manifests []claircore.Digest `json:"manifests"`
+	}
+	dec := codec.GetDecoder(r.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&req); err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "failed to deserialize manifests: %v", err)
+		return
+	}
+
+	out := make(map[string]map[string]string)
+	if l, ok := h.srv.(indexer.Labeler); ok && len(req.Manifests) != 0 {
+		var err error
+		out, err = l.Labels(ctx, req.Manifests...)
+		if errors.Is(err, indexer.ErrUnsupported) {
+			out, err = make(map[string]map[string]string), nil
+		}
+		if err != nil {
+			apiError(ctx, w, http.StatusInternalServerError, "could not retrieve labels: %v", err)
+			return
+		}
+	}
+
+	var err error
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
 	enc := codec.GetEncoder(w)
 	defer codec.PutEncoder(enc)
-	err = enc.Encode(affected)
+	err = enc.Encode(struct {
+		Labels map[string]map[string]string `json:"labels"`
+	}{
+		Labels: out,
+	})
 }
 
 func init() {
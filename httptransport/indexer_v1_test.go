@@ -3,6 +3,7 @@
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -11,6 +12,7 @@
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/pkg/tarfs"
 	"github.com/quay/zlog"
@@ -18,6 +20,8 @@
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/ecosystem"
+	"github.com/quay/clair/v4/indexer/licenses"
 	"github.com/quay/clair/v4/internal/httputil"
 )
 
@@ -64,6 +68,93 @@ func TestIndexReportBadLayer(t *testing.T) {
 	})
 }
 
+func TestIndexReportUnsupportedPlatform(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	i := &indexer.Mock{
+		State_: func(ctx context.Context) (string, error) {
+			return `deadbeef`, nil
+		},
+		Index_: func(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return nil, fmt.Errorf("wrapped: %w", &indexer.UnsupportedPlatform{OS: "windows", OSVersion: "10.0.17763.5122"})
+		},
+	}
+	v1, err := NewIndexerV1(ctx, "", i, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const body = `{"hash":"sha256:0000000000000000000000000000000000000000000000000000000000000000",` +
+		`"layers":[{}]}`
+	req := httptest.NewRequest(http.MethodPost, "/index_report", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	v1.ServeHTTP(rec, req.WithContext(ctx))
+	if got, want := rec.Code, http.StatusUnprocessableEntity; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	const want = `{"code":"unsupported-platform",` +
+		`"message":"unsupported platform: windows 10.0.17763.5122",` +
+		`"platform":{"os":"windows","os_version":"10.0.17763.5122"}}`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestIndexReportLimits(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+
+	i := &indexer.Mock{
+		State_: func(ctx context.Context) (string, error) {
+			return `deadbeef`, nil
+		},
+		Index_: func(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			if len(m.Layers) == 1 {
+				return nil, fmt.Errorf("fetch: %w", httputil.ErrTooLarge)
+			}
+			return new(claircore.IndexReport), nil
+		},
+	}
+	v1, err := NewIndexerV1(ctx, "", i, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1.limits.MaxLayers = 2
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	tt := []struct {
+		Name   string
+		Layers string
+		Want   int
+	}{
+		{Name: "OK", Layers: `{},{}`, Want: http.StatusCreated},
+		{Name: "TooManyLayers", Layers: `{},{},{}`, Want: http.StatusRequestEntityTooLarge},
+		{Name: "TooLarge", Layers: `{}`, Want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			body := `{"hash":"sha256:0000000000000000000000000000000000000000000000000000000000000000",` +
+				`"layers":[` + tc.Layers + `]}`
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, srv.URL+`/index_report`, strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			got, want := res.StatusCode, tc.Want
+			t.Logf("got: %d, want: %d", got, want)
+			if got != want {
+				t.Error()
+			}
+		})
+	}
+}
+
 func TestIndexerV1(t *testing.T) {
 	ctx := context.Background()
 	ctx = zlog.Test(ctx, t)
@@ -122,6 +213,44 @@ func TestIndexerV1(t *testing.T) {
 				t.Error()
 			}
 		})
+		t.Run("Progress", func(t *testing.T) {
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path+"?manifest="+digest.String(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			got, want := res.StatusCode, http.StatusOK
+			t.Logf("got: %d, want: %d", got, want)
+			if got != want {
+				t.Error()
+			}
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, res.Body); err != nil {
+				t.Error(err)
+			}
+			if !strings.Contains(buf.String(), `"progress":`) {
+				t.Errorf("missing progress: %q", buf.String())
+			}
+		})
+		t.Run("ProgressBadDigest", func(t *testing.T) {
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path+"?manifest=bogus", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, want := res.StatusCode, http.StatusBadRequest
+			t.Logf("got: %d, want: %d", got, want)
+			if got != want {
+				t.Error()
+			}
+		})
 	})
 	t.Run("Report", func(t *testing.T) {
 		ctx := zlog.Test(ctx, t)
@@ -239,3 +368,238 @@ func TestIndexerV1(t *testing.T) {
 		})
 	})
 }
+
+// LabelIndexer is an indexer.Service with labels stored in memory.
+type labelIndexer struct {
+	*indexer.Mock
+	labels map[string]map[string]string
+}
+
+func (i *labelIndexer) Labels(_ context.Context, ds ...claircore.Digest) (map[string]map[string]string, error) {
+	out := make(map[string]map[string]string)
+	for _, d := range ds {
+		if l, ok := i.labels[d.String()]; ok {
+			out[d.String()] = l
+		}
+	}
+	return out, nil
+}
+
+// SecretIndexer is an indexer.Service reporting a fixed set of secrets.
+type secretIndexer struct {
+	*indexer.Mock
+	secrets []indexer.Secret
+}
+
+func (i *secretIndexer) Secrets(context.Context, claircore.Digest) ([]indexer.Secret, bool, error) {
+	return i.secrets, true, nil
+}
+
+// LicenseIndexer is an indexer.Service reporting a fixed set of licenses.
+type licenseIndexer struct {
+	*indexer.Mock
+	licenses map[string]string
+}
+
+func (i *licenseIndexer) Licenses(context.Context, *claircore.IndexReport) (map[string]string, error) {
+	return i.licenses, nil
+}
+
+func TestIndexReportLabels(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = `sha256:0000000000000000000000000000000000000000000000000000000000000000`
+	m := &indexer.Mock{
+		State_: func(context.Context) (string, error) {
+			return `deadbeef`, nil
+		},
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash}, nil
+		},
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: d}, true, nil
+		},
+	}
+	labeled := &labelIndexer{
+		Mock: m,
+		labels: map[string]map[string]string{
+			digest: {"repository": "quay.io/example/app", "team": "payments"},
+		},
+	}
+	serve := func(t *testing.T, svc indexer.Service) *httptest.Server {
+		v1, err := NewIndexerV1(ctx, "", svc, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	do := func(t *testing.T, srv *httptest.Server, method, path, body string) (int, []byte) {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, b
+	}
+	submit := func(labels string) string {
+		return `{"hash":"` + digest + `","layers":[{}],"labels":` + labels + `}`
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		srv := serve(t, m)
+		if got, _ := do(t, srv, http.MethodPost, "/index_report", submit(`{"team":"payments"}`)); got != http.StatusBadRequest {
+			t.Errorf("got: %d, want: %d", got, http.StatusBadRequest)
+		}
+		got, b := do(t, srv, http.MethodPost, "/internal/manifest_labels", `{"manifests":["`+digest+`"]}`)
+		if got != http.StatusOK || !bytes.Contains(b, []byte(`"labels":{}`)) {
+			t.Errorf("got: %d %s", got, b)
+		}
+	})
+	t.Run("Enabled", func(t *testing.T) {
+		srv := serve(t, labeled)
+		if got, b := do(t, srv, http.MethodPost, "/index_report", submit(`{"team":"payments"}`)); got != http.StatusCreated {
+			t.Errorf("got: %d %s", got, b)
+		}
+		var many strings.Builder
+		many.WriteByte('{')
+		for i := 0; i < 100; i++ {
+			if i != 0 {
+				many.WriteByte(',')
+			}
+			fmt.Fprintf(&many, `"k%d":"v"`, i)
+		}
+		many.WriteByte('}')
+		if got, _ := do(t, srv, http.MethodPost, "/index_report", submit(many.String())); got != http.StatusBadRequest {
+			t.Errorf("too many labels: got: %d, want: %d", got, http.StatusBadRequest)
+		}
+		got, b := do(t, srv, http.MethodGet, "/index_report/"+digest, "")
+		if got != http.StatusOK || !bytes.Contains(b, []byte(`"team":"payments"`)) {
+			t.Errorf("index report: got: %d %s", got, b)
+		}
+		got, b = do(t, srv, http.MethodPost, "/internal/manifest_labels", `{"manifests":["`+digest+`"]}`)
+		if got != http.StatusOK || !bytes.Contains(b, []byte(`"repository":"quay.io/example/app"`)) {
+			t.Errorf("manifest labels: got: %d %s", got, b)
+		}
+	})
+	t.Run("Schema", func(t *testing.T) {
+		srv := serve(t, labeled)
+		got, b := do(t, srv, http.MethodGet, "/index_report/"+digest+"?schema=v1", "")
+		if got != http.StatusOK || bytes.Contains(b, []byte(`"labels"`)) {
+			t.Errorf("v1: got: %d %s", got, b)
+		}
+		got, b = do(t, srv, http.MethodGet, "/index_report/"+digest+"?schema=v2", "")
+		if got != http.StatusOK || !bytes.Contains(b, []byte(`"labels"`)) {
+			t.Errorf("v2: got: %d %s", got, b)
+		}
+		if got, _ := do(t, srv, http.MethodGet, "/index_report/"+digest+"?schema=v0", ""); got != http.StatusBadRequest {
+			t.Errorf("unknown: got: %d, want: %d", got, http.StatusBadRequest)
+		}
+		if got, _ := do(t, srv, http.MethodPost, "/index_report?schema=v0", submit(`{}`)); got != http.StatusBadRequest {
+			t.Errorf("unknown: got: %d, want: %d", got, http.StatusBadRequest)
+		}
+	})
+	t.Run("Scanners", func(t *testing.T) {
+		v1, err := NewIndexerV1(ctx, "", m, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v1.scanners = []ecosystem.Scanner{{Name: "dpkg", Version: "4", Kind: "package"}}
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		defer srv.Close()
+		want := []byte(`"scanners":[{"name":"dpkg","version":"4","kind":"package"}]`)
+		got, b := do(t, srv, http.MethodGet, "/index_report/"+digest, "")
+		if got != http.StatusOK || !bytes.Contains(b, want) {
+			t.Errorf("v4: got: %d %s", got, b)
+		}
+		got, b = do(t, srv, http.MethodGet, "/index_report/"+digest+"?schema=v3", "")
+		if got != http.StatusOK || bytes.Contains(b, []byte(`"scanners"`)) {
+			t.Errorf("v3: got: %d %s", got, b)
+		}
+	})
+	t.Run("Secrets", func(t *testing.T) {
+		srv := serve(t, &secretIndexer{
+			Mock:    m,
+			secrets: []indexer.Secret{{Kind: indexer.SecretNetrc, Path: "root/.netrc", Description: "password in netrc file"}},
+		})
+		want := []byte(`"secrets":[{"kind":"netrc","path":"root/.netrc","description":"password in netrc file"}]`)
+		body := `{"hash":"` + digest + `","layers":[{}],"env":["PATH=/usr/bin"]}`
+		if got, b := do(t, srv, http.MethodPost, "/index_report", body); got != http.StatusCreated || !bytes.Contains(b, want) {
+			t.Errorf("index: got: %d %s", got, b)
+		}
+		got, b := do(t, srv, http.MethodGet, "/index_report/"+digest, "")
+		if got != http.StatusOK || !bytes.Contains(b, want) {
+			t.Errorf("v5: got: %d %s", got, b)
+		}
+		got, b = do(t, srv, http.MethodGet, "/index_report/"+digest+"?schema=v4", "")
+		if got != http.StatusOK || bytes.Contains(b, []byte(`"secrets"`)) {
+			t.Errorf("v4: got: %d %s", got, b)
+		}
+	})
+	t.Run("Licenses", func(t *testing.T) {
+		pm := *m
+		pm.IndexReport_ = func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{
+				Hash: d,
+				Packages: map[string]*claircore.Package{
+					"1": {ID: "1", Name: "musl", Version: "1.2.4-r2"},
+					"2": {ID: "2", Name: "readline", Version: "8.2.1-r0"},
+					"3": {ID: "3", Name: "ca-certificates", Version: "20230506-r0"},
+				},
+			}, true, nil
+		}
+		li := &licenseIndexer{
+			Mock:     &pm,
+			licenses: map[string]string{"1": "MIT", "2": "GPL-3.0-or-later"},
+		}
+		srv := serve(t, li)
+		got, b := do(t, srv, http.MethodGet, "/index_report/"+digest, "")
+		// Map members aren't encoded in any particular order.
+		var v6 struct {
+			Licenses map[string]string `json:"licenses"`
+		}
+		if err := json.Unmarshal(b, &v6); err != nil {
+			t.Fatal(err)
+		}
+		if got != http.StatusOK || !cmp.Equal(v6.Licenses, li.licenses) {
+			t.Errorf("v6: got: %d %s", got, b)
+		}
+		got, b = do(t, srv, http.MethodGet, "/index_report/"+digest+"?schema=v5", "")
+		if got != http.StatusOK || bytes.Contains(b, []byte(`"licenses"`)) {
+			t.Errorf("v5: got: %d %s", got, b)
+		}
+		if got, _ := do(t, srv, http.MethodGet, "/license_check/"+digest, ""); got != http.StatusNotFound {
+			t.Errorf("no policy: got: %d, want: %d", got, http.StatusNotFound)
+		}
+
+		v1, err := NewIndexerV1(ctx, "", li, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v1.policy = licenses.NewPolicy(nil, []string{"GPL-3.0-or-later"})
+		psrv := httptest.NewUnstartedServer(v1)
+		psrv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		psrv.Start()
+		defer psrv.Close()
+		want := `{"manifest_hash":"` + digest + `","compliant":false,` +
+			`"violations":[{"package_id":"2","name":"readline","version":"8.2.1-r0","license":"GPL-3.0-or-later","reason":"GPL-3.0-or-later is denied"}],` +
+			`"unknown":["3"]}`
+		got, b = do(t, psrv, http.MethodGet, "/license_check/"+digest, "")
+		if got != http.StatusOK || strings.TrimSpace(string(b)) != want {
+			t.Errorf("license check: got: %d %s", got, b)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package httptransport
+
+import (
+	"net/http"
+	"strings"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// PriorityHeader is the request header clients may use to place an index
+// report creation request in a lane.
+const PriorityHeader = `Clair-Priority`
+
+// Request lanes.
+const (
+	laneInteractive = `interactive`
+	laneBatch       = `batch`
+)
+
+// RequestLane reports which lane the request belongs in.
+//
+// The issuer is read from the bearer token without verifying it; this is only
+// meaningful because the authentication middleware has already run by the
+// time requests are sorted into lanes.
+func requestLane(r *http.Request, issuers []string) string {
+	if strings.EqualFold(r.Header.Get(PriorityHeader), laneBatch) {
+		return laneBatch
+	}
+	if len(issuers) == 0 {
+		return laneInteractive
+	}
+	cl, ok := bearerClaims(r)
+	if !ok {
+		return laneInteractive
+	}
+	for _, iss := range issuers {
+		if iss == cl.Issuer {
+			return laneBatch
+		}
+	}
+	return laneInteractive
+}
+
+// BearerClaims returns the claims in the request's bearer token, if any.
+//
+// The token is not verified.
+func bearerClaims(r *http.Request) (cl jwt.Claims, ok bool) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return cl, false
+	}
+	tok, err := jwt.ParseSigned(strings.TrimPrefix(h, "Bearer "))
+	if err != nil {
+		return cl, false
+	}
+	if err := tok.UnsafeClaimsWithoutVerification(&cl); err != nil {
+		return cl, false
+	}
+	return cl, true
+}
@@ -0,0 +1,46 @@
+package httptransport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestRequestLane(t *testing.T) {
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("deadbeefdeadbeef")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(iss string) string {
+		tok, err := jwt.Signed(sig).Claims(jwt.Claims{Issuer: iss}).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + tok
+	}
+	issuers := []string{"backfill"}
+
+	tt := []struct {
+		Name   string
+		Header http.Header
+		Want   string
+	}{
+		{Name: "Default", Header: http.Header{}, Want: laneInteractive},
+		{Name: "Header", Header: http.Header{PriorityHeader: {"Batch"}}, Want: laneBatch},
+		{Name: "Issuer", Header: http.Header{"Authorization": {token("backfill")}}, Want: laneBatch},
+		{Name: "OtherIssuer", Header: http.Header{"Authorization": {token("quay")}}, Want: laneInteractive},
+		{Name: "Garbage", Header: http.Header{"Authorization": {"Bearer xyz"}}, Want: laneInteractive},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, IndexAPIPath, nil)
+			r.Header = tc.Header
+			if got, want := requestLane(r, issuers), tc.Want; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
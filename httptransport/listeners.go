@@ -0,0 +1,87 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/quay/zlog"
+)
+
+// Listener is an http.Server for a single service that's been configured to
+// use its own address.
+type Listener struct {
+	*http.Server
+	// Name is the name of the service, e.g. "indexer".
+	Name string
+}
+
+// ConfigureListeners splits services with their own configured address out
+// of the main Server and into a Listener.
+//
+// Every Listener shares the main Server's handler chain, so authentication and
+// access logging work the same everywhere; requests for services that aren't
+// served on a given address are answered with "404 Not Found". The OpenAPI
+// discovery endpoint is served on every address.
+//
+// Must be run after the handler chain is configured.
+func (t *Server) configureListeners(ctx context.Context) {
+	ls := t.conf.Listeners
+	all := t.Server.Handler
+	var moved []string
+	for _, s := range []struct {
+		name, addr, root string
+		skip             bool
+	}{
+		{name: "indexer", addr: ls.Indexer, root: indexerRoot + "/"},
+		{name: "matcher", addr: ls.Matcher, root: matcherRoot + "/"},
+		{name: "notifier", addr: ls.Notifier, root: notifierRoot + "/", skip: t.notifier == nil},
+	} {
+		if s.addr == "" || s.skip {
+			continue
+		}
+		moved = append(moved, s.root)
+		t.Listeners = append(t.Listeners, &Listener{
+			Name: s.name,
+			Server: &http.Server{
+				Addr:        s.addr,
+				BaseContext: t.Server.BaseContext,
+				Handler:     onlyPrefix(all, s.root),
+			},
+		})
+		zlog.Info(ctx).
+			Str("service", s.name).
+			Str("address", s.addr).
+			Msg("serving on separate listener")
+	}
+	if len(moved) != 0 {
+		t.Server.Handler = exceptPrefix(all, moved)
+	}
+}
+
+// OnlyPrefix serves requests for paths under "root" and the OpenAPI discovery
+// document with "next".
+func onlyPrefix(next http.Handler, root string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if !strings.HasPrefix(p, root) && p != OpenAPIV1Path {
+			apiError(r.Context(), w, http.StatusNotFound, "%q is not served on this address", p)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ExceptPrefix serves requests for paths not under any of "roots" with "next".
+func exceptPrefix(next http.Handler, roots []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		for _, root := range roots {
+			if strings.HasPrefix(p, root) {
+				apiError(r.Context(), w, http.StatusNotFound, "%q is not served on this address", p)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,54 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+func TestListeners(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	s := &Server{
+		conf: config.Config{
+			Listeners: &config.Listeners{
+				Indexer:  ":6061",
+				Notifier: ":6063",
+			},
+		},
+		Server: &http.Server{Handler: ok},
+	}
+	s.configureListeners(ctx)
+	// The notifier is skipped, because there's no notifier service.
+	if got, want := len(s.Listeners), 1; got != want {
+		t.Fatalf("listeners: got: %d, want: %d", got, want)
+	}
+	indexer := s.Listeners[0]
+	if got, want := indexer.Name, "indexer"; got != want {
+		t.Errorf("name: got: %q, want: %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		handler http.Handler
+		path    string
+		want    int
+	}{
+		{s.Server.Handler, IndexAPIPath, http.StatusNotFound},
+		{s.Server.Handler, VulnerabilityReportPath, http.StatusOK},
+		{s.Server.Handler, OpenAPIV1Path, http.StatusOK},
+		{indexer.Handler, IndexAPIPath, http.StatusOK},
+		{indexer.Handler, VulnerabilityReportPath, http.StatusNotFound},
+		{indexer.Handler, OpenAPIV1Path, http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil).WithContext(ctx)
+		tc.handler.ServeHTTP(rec, req)
+		if got := rec.Code; got != tc.want {
+			t.Errorf("%s: got: %d, want: %d", tc.path, got, tc.want)
+		}
+	}
+}
@@ -2,15 +2,19 @@
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptrace"
 	"path"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ldelossa/responserecorder"
+	"github.com/quay/clair/config"
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/zlog"
@@ -19,6 +23,7 @@
 
 	"github.com/quay/clair/v4/indexer"
 	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dsse"
 	"github.com/quay/clair/v4/matcher"
 )
 
@@ -40,12 +45,66 @@ func NewMatcherV1(_ context.Context, prefix string, srv matcher.Service, indexer
 	}
 	p := path.Join(prefix, "vulnerability_report") + "/"
 	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnerabilityReport))
+	p = path.Join(prefix, "attestation") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.attestation))
+	p = path.Join(prefix, "vulnerability_reports")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnerabilityReports))
+	p = path.Join(prefix, "vulnerability_summary") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnerabilitySummary))
+	p = path.Join(prefix, "vulnerability_trend") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnerabilityTrend))
+	p = path.Join(prefix, "changelog") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.changelog))
 	p = path.Join(prefix, "internal", "update_operation")
 	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.updateOperationHandlerGet))
 	p = path.Join(prefix, "internal", "update_operation") + "/"
 	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.updateOperationHandlerDelete))
 	p = path.Join(prefix, "internal", "update_diff")
 	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.updateDiffHandler))
+	p = path.Join(prefix, "internal", "gc")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.gcHandler))
+	p = path.Join(prefix, "internal", "usage")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.usage))
+	p = path.Join(prefix, "internal", "vulnerability_stats")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnerabilityStats))
+	p = path.Join(prefix, "internal", "vulnerability_diff")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnerabilityDiff))
+	p = path.Join(prefix, "internal", "disabled_updaters")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.disabledUpdaters))
+	p = path.Join(prefix, "internal", "disabled_updaters") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(path.Join(p, ":updater"), h.disabledUpdaterHandler))
+	p = path.Join(prefix, "internal", "vulnstore", "vulnerabilities")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnstoreVulnerabilities))
+	p = path.Join(prefix, "internal", "vulnstore", "enrichments")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.vulnstoreEnrichments))
+	p = path.Join(prefix, "internal", "standby")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.standbyHandler))
+	p = path.Join(prefix, "policy")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.policyList))
+	p = path.Join(prefix, "policy") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.policyHandler))
+	p = path.Join(prefix, "policy_report") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.policyReport))
+	p = path.Join(prefix, "advisory")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.advisoryList))
+	p = path.Join(prefix, "advisory") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.advisoryHandler))
+	p = path.Join(prefix, "admission_review")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.admissionReview))
+	p = path.Join(prefix, "workload_report")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.workloadReport))
+	p = path.Join(prefix, "annotation") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.annotationHandler))
+	p = path.Join(prefix, "subscription")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.subscriptionList))
+	p = path.Join(prefix, "subscription") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(path.Join(p, ":id"), h.subscriptionHandler))
+	p = path.Join(prefix, "internal", "backfill")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.backfillList))
+	p = path.Join(prefix, "internal", "backfill") + "/"
+	m.Handle(p, matcherv1wrapper.wrapFunc(path.Join(p, ":id"), h.backfillHandler))
+	p = path.Join(prefix, "updater_status")
+	m.Handle(p, matcherv1wrapper.wrapFunc(p, h.updaterStatus))
 
 	return &h
 }
@@ -56,6 +115,20 @@ type MatcherV1 struct {
 	srv        matcher.Service
 	indexerSrv indexer.Service
 	Cache      time.Duration
+	limits     config.MatcherLimits
+	// Bases are the configured base image manifests findings are attributed
+	// to.
+	bases []claircore.Digest
+	// Signer signs reports for clients that request them signed, if not nil.
+	signer *dsse.Signer
+	// Authenticated reports whether the server requires authentication,
+	// which changing internal advisories needs.
+	authenticated bool
+	// Redact decides which report fields to remove for each caller.
+	redact *redactor
+	// Admitting tracks the images being indexed in the background for
+	// admission requests.
+	admitting sync.Map
 }
 
 var _ http.Handler = (*MatcherV1)(nil)
@@ -102,42 +175,127 @@ func (h *MatcherV1) vulnerabilityReport(w http.ResponseWriter, r *http.Request)
 		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
 		return
 	}
-
-	initd, err := h.srv.Initialized(ctx)
+	filter, err := parseReportFilter(r.URL.Query())
 	if err != nil {
-		apiError(ctx, w, http.StatusInternalServerError, err.Error())
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
 		return
 	}
-	if !initd {
-		w.WriteHeader(http.StatusAccepted)
+	schema, err := reportSchema(w, r)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
 		return
 	}
-
-	indexReport, ok, err := h.indexerSrv.IndexReport(ctx, manifest)
-	// check err first
+	asOf, err := parseAsOf(r.URL.Query())
 	if err != nil {
-		apiError(ctx, w, http.StatusInternalServerError, "experienced a server side error: %v", err)
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
 		return
 	}
-	// now check bool only after confirming no err
-	if !ok {
-		apiError(ctx, w, http.StatusNotFound, "index report for manifest %q not found", manifest.String())
+	bases, err := h.baseImages(r.URL.Query())
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
 		return
 	}
 
-	vulnReport, err := h.srv.Scan(ctx, indexReport)
-	if err != nil {
-		apiError(ctx, w, http.StatusInternalServerError, "failed to start scan: %v", err)
+	vulnReport, ok := h.buildReport(ctx, w, manifest)
+	if !ok {
 		return
 	}
+	if asOf != nil {
+		vulnReport, ok = h.rewind(ctx, w, vulnReport, asOf)
+		if !ok {
+			return
+		}
+	}
+	if filter != nil {
+		vulnReport = filter.apply(vulnReport)
+	}
 
+	out := annotatedVulnerabilityReport{
+		VulnerabilityReport: vulnReport,
+		Labels:              labelsFor(ctx, h.indexerSrv, manifest),
+		Annotations:         annotationsFor(ctx, h.srv, vulnReport),
+		Confidence:          assess(vulnReport),
+		Errata:              errata(vulnReport),
+	}
+	if hasAttribution(schema) {
+		out.Attribution = attribute(vulnReport, h.baseLayers(ctx, bases))
+	}
+	h.redact.fields(ctx).vulnerabilityReport(&out)
+	body := out.in(schema)
 	w.Header().Set("content-type", "application/json")
+	if h.signer != nil && wantEnvelope(r) {
+		body, err = signReport(h.signer, vulnerabilityReportPayloadType, body)
+		if err != nil {
+			apiError(ctx, w, http.StatusInternalServerError, "unable to sign report: %v", err)
+			return
+		}
+		w.Header().Set("content-type", dsse.MediaType)
+	}
 	setCacheControl(w, h.Cache)
 
 	defer writerError(w, &err)()
 	enc := codec.GetEncoder(w)
 	defer codec.PutEncoder(enc)
-	err = enc.Encode(vulnReport)
+	err = enc.Encode(body)
+}
+
+// BuildReport creates the vulnerability report for "manifest". If it returns
+// false, a response has already been written.
+func (h *MatcherV1) buildReport(ctx context.Context, w http.ResponseWriter, manifest claircore.Digest) (*claircore.VulnerabilityReport, bool) {
+	vulnReport, code, err := h.report(ctx, manifest)
+	switch {
+	case err != nil:
+		apiError(ctx, w, code, "%v", err)
+		return nil, false
+	case vulnReport == nil:
+		w.WriteHeader(code)
+		return nil, false
+	}
+	h.setStaleHeader(w)
+	return vulnReport, true
+}
+
+// Report creates the vulnerability report for "manifest", returning the
+// status code describing the outcome. If the matcher isn't initialized yet,
+// neither a report nor an error is returned.
+func (h *MatcherV1) report(ctx context.Context, manifest claircore.Digest) (*claircore.VulnerabilityReport, int, error) {
+	initd, err := h.srv.Initialized(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if !initd {
+		return nil, http.StatusAccepted, nil
+	}
+
+	indexReport, ok, err := h.indexerSrv.IndexReport(ctx, manifest)
+	// check err first
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("experienced a server side error: %w", err)
+	}
+	// now check bool only after confirming no err
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("index report for manifest %q not found", manifest.String())
+	}
+
+	budget := h.limits.MaxReportMemory
+	size := indexReportSize(indexReport)
+	if budget > 0 && size > budget {
+		return nil, http.StatusRequestEntityTooLarge,
+			fmt.Errorf("index report needs ~%d bytes, over report memory limit of %d", size, budget)
+	}
+
+	vulnReport, err := h.srv.Scan(ctx, indexReport)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to start scan: %w", err)
+	}
+	if budget > 0 {
+		size += vulnerabilityReportSize(vulnReport)
+		if size > budget {
+			return nil, http.StatusRequestEntityTooLarge,
+				fmt.Errorf("vulnerability report needs ~%d bytes, over report memory limit of %d", size, budget)
+		}
+	}
+	return vulnReport, http.StatusOK, nil
 }
 
 func (h *MatcherV1) updateDiffHandler(w http.ResponseWriter, r *http.Request) {
@@ -171,6 +329,17 @@ func (h *MatcherV1) updateDiffHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	allow := []string{"application/json", codec.CBORMediaType}
+	switch err := pickContentType(w, r, allow); {
+	case errors.Is(err, nil): // OK
+	case errors.Is(err, ErrMediaType):
+		apiError(ctx, w, http.StatusUnsupportedMediaType, "unable to negotiate common media type for %v", allow)
+		return
+	default:
+		apiError(ctx, w, http.StatusBadRequest, "malformed request: %v", err)
+		return
+	}
+
 	diff, err := h.srv.UpdateDiff(ctx, prev, cur)
 	if err != nil {
 		apiError(ctx, w, http.StatusInternalServerError, "could not get update operations: %v", err)
@@ -178,8 +347,8 @@ func (h *MatcherV1) updateDiffHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer writerError(w, &err)()
-	enc := codec.GetEncoder(w)
-	defer codec.PutEncoder(enc)
+	enc, put := responseEncoder(w)
+	defer put()
 	err = enc.Encode(&diff)
 }
 
@@ -261,6 +430,48 @@ func (h *MatcherV1) updateOperationHandlerDelete(w http.ResponseWriter, r *http.
 	}
 }
 
+// GcHandler reports what the GC policies would delete in response to GET
+// requests and applies them in response to POST requests, unless the
+// "dry_run" parameter is set.
+func (h *MatcherV1) gcHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.gcHandler")
+	dryRun := true
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				apiError(ctx, w, http.StatusBadRequest, "bad dry_run value: %q", v)
+				return
+			}
+			dryRun = b
+		} else {
+			dryRun = false
+		}
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	c, ok := h.srv.(matcher.Collector)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "garbage collection policies not configured")
+		return
+	}
+
+	rep, err := c.CollectGarbage(ctx, dryRun)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not collect garbage: %v", err)
+		return
+	}
+
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(rep)
+}
+
 func init() {
 	matcherv1wrapper.init("matcherv1")
 }
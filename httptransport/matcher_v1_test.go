@@ -1,7 +1,9 @@
 package httptransport
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -9,6 +11,7 @@
 	"net/http/httptest"
 	"net/url"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +19,8 @@
 	"github.com/quay/clair/v4/indexer"
 	"github.com/quay/clair/v4/internal/httputil"
 	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/gc"
+	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -417,3 +422,223 @@ func testUpdateOperationHandlerGet(t *testing.T) {
 		t.Fatalf("got: %v, want: %v", etag, id.String())
 	}
 }
+
+type collectorMock struct {
+	*matcher.Mock
+	dryRun []bool
+}
+
+func (m *collectorMock) CollectGarbage(_ context.Context, dryRun bool) (*gc.Report, error) {
+	m.dryRun = append(m.dryRun, dryRun)
+	return &gc.Report{DryRun: dryRun}, nil
+}
+
+// TestGCHandler confirms the GC handler only applies policies in response to
+// POST requests without a dry_run parameter.
+func TestGCHandler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tp := otelhttp.WithTracerProvider(trace.NewNoopTracerProvider())
+	m := &collectorMock{Mock: &matcher.Mock{}}
+	srv := httptest.NewServer(NewMatcherV1(ctx, "", m, &indexer.Mock{}, time.Second*10, tp))
+	defer srv.Close()
+	u := srv.URL + path.Join("/", "internal", "gc")
+
+	tt := []struct {
+		Method string
+		Query  string
+		Status int
+	}{
+		{Method: http.MethodGet, Status: http.StatusOK},
+		{Method: http.MethodPost, Status: http.StatusOK},
+		{Method: http.MethodPost, Query: "?dry_run=true", Status: http.StatusOK},
+		{Method: http.MethodPost, Query: "?dry_run=maybe", Status: http.StatusBadRequest},
+		{Method: http.MethodDelete, Status: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tt {
+		req, err := httputil.NewRequestWithContext(ctx, tc.Method, u+tc.Query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, tc.Status; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", tc.Method, tc.Query, got, want)
+		}
+	}
+	if got, want := fmt.Sprint(m.dryRun), "[true false true]"; got != want {
+		t.Errorf("dry runs: got: %s, want: %s", got, want)
+	}
+
+	// Services without the policy engine.
+	srv2 := httptest.NewServer(NewMatcherV1(ctx, "", &matcher.Mock{}, &indexer.Mock{}, time.Second*10, tp))
+	defer srv2.Close()
+	res, err := srv2.Client().Get(srv2.URL + path.Join("/", "internal", "gc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("unconfigured: got: %d, want: %d", got, want)
+	}
+}
+
+func TestVulnerabilityReportLimits(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(digest),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+		},
+		Success: true,
+	}
+	i := &indexer.Mock{
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return ir, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{
+				Hash:     ir.Hash,
+				Packages: ir.Packages,
+				Vulnerabilities: map[string]*claircore.Vulnerability{
+					"1": {ID: "1", Name: "CVE-2023-0001", Description: strings.Repeat("x", 4096)},
+				},
+				PackageVulnerabilities: map[string][]string{"1": {"1"}},
+			}, nil
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	sz := indexReportSize(ir)
+	tt := []struct {
+		Name   string
+		Budget int64
+		Want   int
+	}{
+		{Name: "Unlimited", Budget: 0, Want: http.StatusOK},
+		{Name: "IndexReport", Budget: sz - 1, Want: http.StatusRequestEntityTooLarge},
+		{Name: "VulnerabilityReport", Budget: sz + 1, Want: http.StatusRequestEntityTooLarge},
+		{Name: "OK", Budget: sz + 8192, Want: http.StatusOK},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			v1.limits.MaxReportMemory = tc.Budget
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/vulnerability_report/"+digest, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			got, want := res.StatusCode, tc.Want
+			t.Logf("got: %d, want: %d", got, want)
+			if got != want {
+				t.Error()
+			}
+		})
+	}
+}
+
+func TestVulnerabilityReports(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const (
+		found   = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+		missing = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	)
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			if d.String() != found {
+				return nil, false, nil
+			}
+			return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	post := func(t *testing.T, body string) *http.Response {
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/vulnerability_reports", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	t.Run("Stream", func(t *testing.T) {
+		res := post(t, `{"manifests":["`+found+`","`+missing+`"]}`)
+		defer res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		type entry struct {
+			Manifest string          `json:"manifest_hash"`
+			Report   json.RawMessage `json:"report"`
+			Error    *batchReportErr `json:"error"`
+		}
+		var got []entry
+		s := bufio.NewScanner(res.Body)
+		for s.Scan() {
+			var e entry
+			if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+				t.Fatalf("line %d: %v", len(got)+1, err)
+			}
+			got = append(got, e)
+		}
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got: %d lines, want: 2", len(got))
+		}
+		if e := got[0]; e.Manifest != found || e.Report == nil || e.Error != nil {
+			t.Errorf("line 1: unexpected entry: %+v", e)
+		}
+		if e := got[1]; e.Manifest != missing || e.Report != nil || e.Error == nil || e.Error.Code != "not-found" {
+			t.Errorf("line 2: unexpected entry: %+v", e)
+		}
+	})
+	t.Run("Limit", func(t *testing.T) {
+		v1.limits.MaxBatchReports = 1
+		defer func() { v1.limits.MaxBatchReports = 0 }()
+		res := post(t, `{"manifests":["`+found+`","`+missing+`"]}`)
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusRequestEntityTooLarge; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("Empty", func(t *testing.T) {
+		res := post(t, `{"manifests":[]}`)
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
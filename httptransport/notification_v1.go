@@ -3,7 +3,9 @@
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"path"
 	"path/filepath"
 	"strconv"
@@ -14,7 +16,9 @@
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/severity"
 	"github.com/quay/clair/v4/notifier"
 )
 
@@ -49,6 +53,12 @@ func NewNotificationV1(_ context.Context, prefix string, srv notifier.Service, t
 	}
 	p := path.Join(prefix, "notification") + "/"
 	m.Handle(p, notificationv1wrapper.wrapFunc(path.Join(p, ":id"), h.serveHTTP))
+	p = path.Join(prefix, "internal", "self_test")
+	m.Handle(p, notificationv1wrapper.wrapFunc(p, h.selfTest))
+	p = path.Join(prefix, "internal", "delivery_report") + "/"
+	m.Handle(p, notificationv1wrapper.wrapFunc(path.Join(p, ":id"), h.deliveryReport))
+	p = path.Join(prefix, "internal", "usage")
+	m.Handle(p, notificationv1wrapper.wrapFunc(p, h.usage))
 	return &h, nil
 }
 
@@ -132,7 +142,7 @@ func (h *NotificationV1) get(w http.ResponseWriter, r *http.Request) {
 	if param := r.URL.Query().Get("next"); param != "" {
 		n, err := uuid.Parse(param)
 		if err != nil {
-			apiError(ctx, w, http.StatusBadRequest, "could not parse %q query param into integer", "next")
+			apiError(ctx, w, http.StatusBadRequest, "could not parse %q query param into uuid", "next")
 			return
 		}
 		if n != uuid.Nil {
@@ -140,6 +150,12 @@ func (h *NotificationV1) get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	filter, err := notificationFilter(r.URL.Query())
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
 	allow := []string{"application/vnd.clair.notification.v1+json", "application/json"}
 	switch err := pickContentType(w, r, allow); {
 	case errors.Is(err, nil): // OK
@@ -152,8 +168,9 @@ func (h *NotificationV1) get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	inP := &notifier.Page{
-		Size: pageSize,
-		Next: next,
+		Size:   pageSize,
+		Next:   next,
+		Filter: filter,
 	}
 	notifications, outP, err := h.serv.Notifications(ctx, notificationID, inP)
 	if err != nil {
@@ -172,6 +189,115 @@ func (h *NotificationV1) get(w http.ResponseWriter, r *http.Request) {
 	err = enc.Encode(&response)
 }
 
+// SelfTest sends a test notification through the configured deliverer in
+// response to POST requests and reports the result.
+func (h *NotificationV1) selfTest(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/NotificationV1.selfTest")
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	t, ok := h.serv.(notifier.SelfTester)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "self-test not supported")
+		return
+	}
+
+	res, err := t.SelfTest(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not run self-test: %v", err)
+		return
+	}
+	zlog.Info(ctx).
+		Str("deliverer", res.Deliverer).
+		Stringer("notification_id", res.NotificationID).
+		Bool("ok", res.OK).
+		Msg("notifier self-test")
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
+
+// DeliveryReport reports on the delivery of the notification named in the
+// request path.
+func (h *NotificationV1) deliveryReport(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/NotificationV1.deliveryReport")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	dr, ok := h.serv.(notifier.DeliveryReporter)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "delivery reports not supported")
+		return
+	}
+	notificationID, err := uuid.Parse(filepath.Base(r.URL.Path))
+	if err != nil {
+		zlog.Warn(ctx).Err(err).Msg("could not parse notification id")
+		apiError(ctx, w, http.StatusBadRequest, "could not parse notification id: %v", err)
+		return
+	}
+
+	res, err := dr.DeliveryReport(ctx, notificationID)
+	var errNoReceipt *clairerror.ErrNoReceipt
+	switch {
+	case errors.Is(err, nil):
+	case errors.As(err, &errNoReceipt):
+		apiError(ctx, w, http.StatusNotFound, "unknown notification: %v", notificationID)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not retrieve delivery report: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
+
+// NotificationFilter constructs a notifier.Filter from the optional
+// "min_severity", "distribution", "fixed", and "manifest" query parameters.
+// It returns nil if none are present.
+func notificationFilter(q url.Values) (*notifier.Filter, error) {
+	var f notifier.Filter
+	var set bool
+	if param := q.Get("min_severity"); param != "" {
+		s, ok := severity.Parse(param)
+		if !ok {
+			return nil, fmt.Errorf("could not parse %q query param into severity", "min_severity")
+		}
+		f.Severity = s
+		set = true
+	}
+	if param := q.Get("distribution"); param != "" {
+		f.Distribution = param
+		set = true
+	}
+	if param := q.Get("fixed"); param != "" {
+		b, err := strconv.ParseBool(param)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q query param into boolean", "fixed")
+		}
+		f.FixedOnly = b
+		set = true
+	}
+	if param := q.Get("manifest"); param != "" {
+		f.ManifestPrefix = param
+		set = true
+	}
+	if !set {
+		return nil, nil
+	}
+	return &f, nil
+}
+
 func init() {
 	notificationv1wrapper.init("notificationv1")
 }
@@ -8,13 +8,16 @@
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
+	"github.com/quay/claircore"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/trace"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/internal/httputil"
 	"github.com/quay/clair/v4/notifier"
 	"github.com/quay/clair/v4/notifier/service"
@@ -26,7 +29,10 @@ func TestNotificationsHandler(t *testing.T) {
 	t.Run("Methods", testNotificationsHandlerMethods(ctx))
 	t.Run("Get", testNotificationHandlerGet(ctx))
 	t.Run("GetParams", testNotificationHandlerGetParams(ctx))
+	t.Run("GetFilter", testNotificationHandlerGetFilter(ctx))
 	t.Run("Delete", testNotificationHandlerDelete(ctx))
+	t.Run("SelfTest", testNotificationHandlerSelfTest(ctx))
+	t.Run("DeliveryReport", testNotificationHandlerDeliveryReport(ctx))
 }
 
 var notifierTraceOpt = otelhttp.WithTracerProvider(trace.NewNoopTracerProvider())
@@ -192,6 +198,60 @@ func testNotificationHandlerGetParams(ctx context.Context) func(*testing.T) {
 	}
 }
 
+// testNotificationHandlerGetFilter confirms the Get handler passes filter
+// parameters through and rejects malformed ones.
+func testNotificationHandlerGetFilter(ctx context.Context) func(*testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+		ctx := zlog.Test(ctx, t)
+		var (
+			noteID     = uuid.New()
+			inPageWant = notifier.Page{
+				Size: 500,
+				Filter: &notifier.Filter{
+					Severity:       claircore.High,
+					Distribution:   "debian",
+					FixedOnly:      true,
+					ManifestPrefix: "sha256:ab",
+				},
+			}
+		)
+
+		nm := &service.Mock{
+			Notifications_: func(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error) {
+				if !cmp.Equal(page, &inPageWant) {
+					t.Fatalf("got: %v, wanted: %v", page, inPageWant)
+				}
+				return []notifier.Notification{}, notifier.Page{Size: page.Size}, nil
+			},
+		}
+
+		h, err := NewNotificationV1(ctx, `/notifier/api/v1/`, nm, notifierTraceOpt)
+		if err != nil {
+			t.Error(err)
+		}
+		for _, tc := range []struct {
+			query string
+			want  int
+		}{
+			{query: "min_severity=high&distribution=debian&fixed=true&manifest=sha256:ab", want: http.StatusOK},
+			{query: "min_severity=bad", want: http.StatusBadRequest},
+			{query: "fixed=maybe", want: http.StatusBadRequest},
+		} {
+			rr := httptest.NewRecorder()
+			u := "http://clair-notifier/notifier/api/v1/notification/" + noteID.String() + "?" + tc.query
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+			if err != nil {
+				t.Error(err)
+			}
+			h.get(rr, req)
+			if got, want := rr.Result().StatusCode, tc.want; got != want {
+				t.Errorf("%s: got: %v, wanted: %v", tc.query, got, want)
+			}
+		}
+	}
+}
+
 func testNotificationsHandlerMethods(ctx context.Context) func(*testing.T) {
 	return func(t *testing.T) {
 		t.Parallel()
@@ -230,3 +290,119 @@ func testNotificationsHandlerMethods(ctx context.Context) func(*testing.T) {
 		}
 	}
 }
+
+type selfTestMock struct {
+	*service.Mock
+	res *notifier.SelfTestResult
+}
+
+func (m *selfTestMock) SelfTest(context.Context) (*notifier.SelfTestResult, error) {
+	return m.res, nil
+}
+
+func testNotificationHandlerSelfTest(ctx context.Context) func(*testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+		ctx := zlog.Test(ctx, t)
+		want := &notifier.SelfTestResult{
+			Deliverer:      "webhook",
+			NotificationID: uuid.New(),
+			Delivery:       &notifier.SelfTestStep{OK: true, Duration: "1ms"},
+			OK:             true,
+		}
+		do := func(srv notifier.Service, m string, status int) *http.Response {
+			t.Helper()
+			h, err := NewNotificationV1(ctx, `/notifier/api/v1/`, srv, notifierTraceOpt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			req, err := httputil.NewRequestWithContext(ctx, m, "http://clair-notifier/notifier/api/v1/internal/self_test", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.ServeHTTP(rr, req)
+			res := rr.Result()
+			if got := res.StatusCode; got != status {
+				t.Errorf("%s: got: %d, want: %d", m, got, status)
+			}
+			return res
+		}
+
+		do(&service.Mock{}, http.MethodPost, http.StatusNotFound)
+		srv := &selfTestMock{Mock: &service.Mock{}, res: want}
+		do(srv, http.MethodGet, http.StatusMethodNotAllowed)
+		res := do(srv, http.MethodPost, http.StatusOK)
+		var got notifier.SelfTestResult
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(&got, want) {
+			t.Error(cmp.Diff(&got, want))
+		}
+	}
+}
+
+type deliveryReportMock struct {
+	*service.Mock
+	res *notifier.DeliveryReport
+}
+
+func (m *deliveryReportMock) DeliveryReport(_ context.Context, id uuid.UUID) (*notifier.DeliveryReport, error) {
+	if id != m.res.NotificationID {
+		return nil, &clairerror.ErrNoReceipt{NotificationID: id}
+	}
+	return m.res, nil
+}
+
+func testNotificationHandlerDeliveryReport(ctx context.Context) func(*testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+		ctx := zlog.Test(ctx, t)
+		ts := time.Now().UTC().Truncate(time.Second)
+		want := &notifier.DeliveryReport{
+			NotificationID: uuid.New(),
+			UpdateID:       uuid.New(),
+			Status:         notifier.Delivered,
+			StatusUpdated:  ts,
+			Deliverer:      "webhook",
+			Destination:    "https://example.com/notify",
+			Attempts:       2,
+			LastAttempt:    &ts,
+			Delivered:      &ts,
+		}
+		do := func(srv notifier.Service, m, id string, status int) *http.Response {
+			t.Helper()
+			h, err := NewNotificationV1(ctx, `/notifier/api/v1/`, srv, notifierTraceOpt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			req, err := httputil.NewRequestWithContext(ctx, m, "http://clair-notifier/notifier/api/v1/internal/delivery_report/"+id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.ServeHTTP(rr, req)
+			res := rr.Result()
+			if got := res.StatusCode; got != status {
+				t.Errorf("%s %s: got: %d, want: %d", m, id, got, status)
+			}
+			return res
+		}
+
+		id := want.NotificationID.String()
+		do(&service.Mock{}, http.MethodGet, id, http.StatusNotFound)
+		srv := &deliveryReportMock{Mock: &service.Mock{}, res: want}
+		do(srv, http.MethodPost, id, http.StatusMethodNotAllowed)
+		do(srv, http.MethodGet, "not-a-uuid", http.StatusBadRequest)
+		do(srv, http.MethodGet, uuid.NewString(), http.StatusNotFound)
+		res := do(srv, http.MethodGet, id, http.StatusOK)
+		var got notifier.DeliveryReport
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(&got, want) {
+			t.Error(cmp.Diff(&got, want))
+		}
+	}
+}
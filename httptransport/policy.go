@@ -0,0 +1,181 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/policy"
+)
+
+// Policies returns the matcher's policy store, writing an error response and
+// returning nil if it doesn't have one.
+func (h *MatcherV1) policies(ctx context.Context, w http.ResponseWriter) matcher.Policies {
+	p, ok := h.srv.(matcher.Policies)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "scan policies not supported")
+		return nil
+	}
+	return p
+}
+
+// PolicyList lists the stored policies.
+func (h *MatcherV1) policyList(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.policyList")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	s := h.policies(ctx, w)
+	if s == nil {
+		return
+	}
+	ps, err := s.Policies(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not list policies: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(ps)
+}
+
+// PolicyHandler serves a single policy: GET returns it, PUT creates or
+// replaces it, and DELETE removes it.
+func (h *MatcherV1) policyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.policyHandler")
+	switch r.Method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	s := h.policies(ctx, w)
+	if s == nil {
+		return
+	}
+	name := path.Base(r.URL.Path)
+
+	var p *policy.Policy
+	switch r.Method {
+	case http.MethodGet:
+		var ok bool
+		var err error
+		p, ok, err = s.Policy(ctx, name)
+		switch {
+		case err != nil:
+			apiError(ctx, w, http.StatusInternalServerError, "could not get policy: %v", err)
+			return
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "policy %q not found", name)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+	case http.MethodPut:
+		p = new(policy.Policy)
+		dec := codec.GetDecoder(r.Body)
+		err := dec.Decode(p)
+		codec.PutDecoder(dec)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not deserialize policy: %v", err)
+			return
+		}
+		if p.Name == "" {
+			p.Name = name
+		}
+		if p.Name != name {
+			apiError(ctx, w, http.StatusBadRequest, "policy name %q does not match path", p.Name)
+			return
+		}
+		created, err := s.PutPolicy(ctx, p)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, policy.ErrInvalid):
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
+			return
+		default:
+			apiError(ctx, w, http.StatusInternalServerError, "could not store policy: %v", err)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodDelete:
+		ok, err := s.DeletePolicy(ctx, name)
+		switch {
+		case err != nil:
+			apiError(ctx, w, http.StatusInternalServerError, "could not delete policy: %v", err)
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "policy %q not found", name)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	var err error
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(p)
+}
+
+// PolicyReport evaluates a manifest's vulnerability report against the policy
+// named by the "policy" query parameter.
+func (h *MatcherV1) policyReport(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.policyReport")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	manifest, err := claircore.ParseDigest(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	name := strings.TrimSpace(r.URL.Query().Get("policy"))
+	if name == "" {
+		apiError(ctx, w, http.StatusBadRequest, `missing "policy" query param`)
+		return
+	}
+	s := h.policies(ctx, w)
+	if s == nil {
+		return
+	}
+	p, ok, err := s.Policy(ctx, name)
+	switch {
+	case err != nil:
+		apiError(ctx, w, http.StatusInternalServerError, "could not get policy: %v", err)
+		return
+	case !ok:
+		apiError(ctx, w, http.StatusNotFound, "policy %q not found", name)
+		return
+	}
+
+	vr, ok := h.buildReport(ctx, w, manifest)
+	if !ok {
+		return
+	}
+	res := p.Evaluate(vr, time.Now())
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
@@ -0,0 +1,138 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/policy"
+)
+
+type policyMock struct {
+	*matcher.Mock
+	m map[string]policy.Policy
+}
+
+func (p *policyMock) Policy(_ context.Context, name string) (*policy.Policy, bool, error) {
+	v, ok := p.m[name]
+	return &v, ok, nil
+}
+
+func (p *policyMock) Policies(context.Context) ([]policy.Policy, error) {
+	out := []policy.Policy{}
+	for _, v := range p.m {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *policyMock) PutPolicy(_ context.Context, v *policy.Policy) (bool, error) {
+	if err := v.Validate(); err != nil {
+		return false, err
+	}
+	_, ok := p.m[v.Name]
+	p.m[v.Name] = *v
+	return !ok, nil
+}
+
+func (p *policyMock) DeletePolicy(_ context.Context, name string) (bool, error) {
+	_, ok := p.m[name]
+	delete(p.m, name)
+	return ok, nil
+}
+
+func TestPolicyHandler(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(digest),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+		},
+		Success: true,
+	}
+	i := &indexer.Mock{
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return ir, true, nil
+		},
+	}
+	m := &policyMock{
+		Mock: &matcher.Mock{
+			Initialized_: func(context.Context) (bool, error) { return true, nil },
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{
+					Hash:     ir.Hash,
+					Packages: ir.Packages,
+					Vulnerabilities: map[string]*claircore.Vulnerability{
+						"1": {ID: "1", Name: "CVE-2023-0001", NormalizedSeverity: claircore.Critical},
+					},
+					PackageVulnerabilities: map[string][]string{"1": {"1"}},
+				}, nil
+			},
+		},
+		m: make(map[string]policy.Policy),
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(method, path, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+
+	do(http.MethodPut, "/policy/prod", `{"max_severity":"urgent"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/policy/prod", `{"name":"other"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/policy/prod", `{"max_severity":"high"}`, http.StatusCreated).Body.Close()
+	do(http.MethodPut, "/policy/prod", `{"max_severity":"critical"}`, http.StatusOK).Body.Close()
+	do(http.MethodGet, "/policy/missing", "", http.StatusNotFound).Body.Close()
+	do(http.MethodGet, "/policy", "", http.StatusOK).Body.Close()
+
+	check := func(max string, pass bool) {
+		t.Helper()
+		do(http.MethodPut, "/policy/prod", `{"max_severity":"`+max+`"}`, http.StatusOK).Body.Close()
+		res := do(http.MethodGet, "/policy_report/"+digest+"?policy=prod", "", http.StatusOK)
+		defer res.Body.Close()
+		var got policy.Result
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Pass != pass {
+			t.Errorf("max %s: got: %+v, want pass: %v", max, got, pass)
+		}
+	}
+	check("critical", true)
+	check("high", false)
+
+	do(http.MethodGet, "/policy_report/"+digest, "", http.StatusBadRequest).Body.Close()
+	do(http.MethodDelete, "/policy/prod", "", http.StatusNoContent).Body.Close()
+	do(http.MethodDelete, "/policy/prod", "", http.StatusNotFound).Body.Close()
+	do(http.MethodGet, "/policy_report/"+digest+"?policy=prod", "", http.StatusNotFound).Body.Close()
+}
@@ -0,0 +1,254 @@
+package httptransport
+
+import (
+	"context"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// Redaction is a set of report fields to remove.
+type redaction uint8
+
+const (
+	redactFilePaths redaction = 1 << iota
+	redactRepositoryURLs
+	redactPackageMetadata
+	redactLabels
+	redactAnnotations
+	redactSecrets
+	redactImageConfig
+)
+
+var redactionNames = map[string]redaction{
+	"file_paths":       redactFilePaths,
+	"repository_urls":  redactRepositoryURLs,
+	"package_metadata": redactPackageMetadata,
+	"labels":           redactLabels,
+	"annotations":      redactAnnotations,
+	"secrets":          redactSecrets,
+	"image_config":     redactImageConfig,
+}
+
+// Redactor decides which report fields to remove for a caller.
+//
+// A nil *redactor removes nothing.
+type redactor struct {
+	rules []redactionRule
+}
+
+type redactionRule struct {
+	subjects map[string]struct{}
+	issuers  map[string]struct{}
+	claim    string
+	values   map[string]struct{}
+	fields   redaction
+}
+
+// NewRedactor returns a redactor for the rules in "cfg", or nil if there are
+// none.
+func newRedactor(cfg *config.Redaction) *redactor {
+	if cfg == nil || len(cfg.Rules) == 0 {
+		return nil
+	}
+	set := func(ss []string) map[string]struct{} {
+		if len(ss) == 0 {
+			return nil
+		}
+		m := make(map[string]struct{}, len(ss))
+		for _, s := range ss {
+			m[s] = struct{}{}
+		}
+		return m
+	}
+	r := &redactor{rules: make([]redactionRule, len(cfg.Rules))}
+	for i, c := range cfg.Rules {
+		rule := &r.rules[i]
+		rule.subjects = set(c.Subjects)
+		rule.issuers = set(c.Issuers)
+		rule.claim = c.Claim
+		rule.values = set(c.Values)
+		for _, f := range c.Fields {
+			rule.fields |= redactionNames[f]
+		}
+	}
+	return r
+}
+
+// Fields returns the fields to remove for the caller making the request in
+// "ctx".
+func (r *redactor) fields(ctx context.Context) (f redaction) {
+	if r == nil {
+		return 0
+	}
+	id, _ := auth.IdentityFromContext(ctx)
+	for i := range r.rules {
+		if rule := &r.rules[i]; rule.matches(id) {
+			f |= rule.fields
+		}
+	}
+	return f
+}
+
+// Matches reports whether the rule applies to the caller "id", which is nil
+// for unauthenticated callers.
+func (rule *redactionRule) matches(id *auth.Identity) bool {
+	if rule.subjects == nil && rule.issuers == nil && rule.claim == "" {
+		return true
+	}
+	if id == nil {
+		return false
+	}
+	if rule.subjects != nil {
+		if _, ok := rule.subjects[id.Subject]; !ok {
+			return false
+		}
+	}
+	if rule.issuers != nil {
+		if _, ok := rule.issuers[id.Issuer]; !ok {
+			return false
+		}
+	}
+	if rule.claim != "" {
+		return rule.claimMatches(id.Claims[rule.claim])
+	}
+	return true
+}
+
+// ClaimMatches reports whether the claim value "v", as decoded from JSON, is
+// one of the rule's values or a list containing one.
+func (rule *redactionRule) claimMatches(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		_, ok := rule.values[v]
+		return ok
+	case []string:
+		for _, s := range v {
+			if _, ok := rule.values[s]; ok {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				if _, ok := rule.values[s]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// VulnerabilityReport removes the fields in "f" from "out".
+//
+// Reports may be shared, so anything changed is copied first.
+func (f redaction) vulnerabilityReport(out *annotatedVulnerabilityReport) {
+	if f == 0 {
+		return
+	}
+	if f&redactLabels != 0 {
+		out.Labels = nil
+	}
+	if f&redactAnnotations != 0 {
+		out.Annotations = nil
+	}
+	vr := *out.VulnerabilityReport
+	vr.Packages, vr.Repositories, vr.Environments = f.contents(vr.Packages, vr.Repositories, vr.Environments)
+	out.VulnerabilityReport = &vr
+}
+
+// IndexReport removes the fields in "f" from "out".
+//
+// Reports may be shared, so anything changed is copied first.
+func (f redaction) indexReport(out *labeledIndexReport) {
+	if f == 0 {
+		return
+	}
+	if f&redactLabels != 0 {
+		out.Labels = nil
+	}
+	if f&redactImageConfig != 0 {
+		out.ImageConfig = nil
+	}
+	switch {
+	case f&redactSecrets != 0:
+		out.Secrets = nil
+	case f&redactFilePaths != 0 && len(out.Secrets) != 0:
+		ss := make([]indexer.Secret, len(out.Secrets))
+		for i, s := range out.Secrets {
+			s.Path = ""
+			ss[i] = s
+		}
+		out.Secrets = ss
+	}
+	if f&redactFilePaths != 0 && out.Unpackaged != nil {
+		u := *out.Unpackaged
+		u.Files = make([]indexer.UnpackagedFile, len(out.Unpackaged.Files))
+		for i, uf := range out.Unpackaged.Files {
+			uf.Path = ""
+			u.Files[i] = uf
+		}
+		out.Unpackaged = &u
+	}
+	ir := *out.IndexReport
+	ir.Packages, ir.Repositories, ir.Environments = f.contents(ir.Packages, ir.Repositories, ir.Environments)
+	out.IndexReport = &ir
+}
+
+// Contents returns copies of the packages, repositories, and environments
+// common to both kinds of report with the fields in "f" removed. Maps without
+// anything to remove are returned as-is.
+func (f redaction) contents(
+	pkgs map[string]*claircore.Package,
+	repos map[string]*claircore.Repository,
+	envs map[string][]*claircore.Environment,
+) (map[string]*claircore.Package, map[string]*claircore.Repository, map[string][]*claircore.Environment) {
+	if f&(redactFilePaths|redactPackageMetadata) != 0 && pkgs != nil {
+		m := make(map[string]*claircore.Package, len(pkgs))
+		for id, p := range pkgs {
+			c := *p
+			if f&redactFilePaths != 0 {
+				c.PackageDB = ""
+				c.Filepath = ""
+			}
+			if f&redactPackageMetadata != 0 {
+				c.Source = nil
+				c.Module = ""
+				c.Arch = ""
+				c.CPE = cpe.WFN{}
+				c.RepositoryHint = ""
+			}
+			m[id] = &c
+		}
+		pkgs = m
+	}
+	if f&redactRepositoryURLs != 0 && repos != nil {
+		m := make(map[string]*claircore.Repository, len(repos))
+		for id, r := range repos {
+			c := *r
+			c.URI = ""
+			c.Key = ""
+			m[id] = &c
+		}
+		repos = m
+	}
+	if f&redactFilePaths != 0 && envs != nil {
+		m := make(map[string][]*claircore.Environment, len(envs))
+		for id, es := range envs {
+			cs := make([]*claircore.Environment, len(es))
+			for i, e := range es {
+				c := *e
+				c.PackageDB = ""
+				cs[i] = &c
+			}
+			m[id] = cs
+		}
+		envs = m
+	}
+	return pkgs, repos, envs
+}
@@ -0,0 +1,118 @@
+package httptransport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+func TestRedactorFields(t *testing.T) {
+	r := newRedactor(&config.Redaction{Rules: []config.RedactionRule{
+		{Name: "all", Fields: []string{"labels"}},
+		{Name: "vendors", Claim: "roles", Values: []string{"vendor"}, Fields: []string{"file_paths", "repository_urls"}},
+		{Name: "acme", Subjects: []string{"acme"}, Issuers: []string{"https://sso.example.com"}, Fields: []string{"secrets"}},
+	}})
+	tt := []struct {
+		Name string
+		ID   *auth.Identity
+		Want redaction
+	}{
+		{Name: "Anonymous", Want: redactLabels},
+		{
+			Name: "Role",
+			ID:   &auth.Identity{Subject: "initech", Claims: map[string]interface{}{"roles": []interface{}{"reader", "vendor"}}},
+			Want: redactLabels | redactFilePaths | redactRepositoryURLs,
+		},
+		{
+			Name: "Subject",
+			ID:   &auth.Identity{Subject: "acme", Issuer: "https://sso.example.com", Claims: map[string]interface{}{"roles": "vendor"}},
+			Want: redactLabels | redactFilePaths | redactRepositoryURLs | redactSecrets,
+		},
+		{
+			Name: "WrongIssuer",
+			ID:   &auth.Identity{Subject: "acme", Issuer: "https://evil.example.com"},
+			Want: redactLabels,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.ID != nil {
+				ctx = auth.WithIdentity(ctx, tc.ID)
+			}
+			if got, want := r.fields(ctx), tc.Want; got != want {
+				t.Errorf("got: %06b, want: %06b", got, want)
+			}
+		})
+	}
+
+	var none *redactor
+	if got := none.fields(context.Background()); got != 0 {
+		t.Errorf("nil redactor: got: %06b", got)
+	}
+}
+
+func TestRedactReports(t *testing.T) {
+	pkgs := map[string]*claircore.Package{
+		"1": {ID: "1", Name: "openssl", Arch: "x86_64", Module: "openssl:3", Source: &claircore.Package{Name: "openssl-src"}},
+	}
+	repos := map[string]*claircore.Repository{
+		"1": {ID: "1", Name: "internal", URI: "https://repo.corp.example.com/el9", Key: "corp"},
+	}
+	envs := map[string][]*claircore.Environment{
+		"1": {{PackageDB: "var/lib/rpm", DistributionID: "1"}},
+	}
+	f := redactFilePaths | redactRepositoryURLs | redactPackageMetadata | redactLabels | redactAnnotations
+
+	vr := &claircore.VulnerabilityReport{Packages: pkgs, Repositories: repos, Environments: envs}
+	out := annotatedVulnerabilityReport{
+		VulnerabilityReport: vr,
+		Labels:              map[string]string{"team": "payments"},
+	}
+	f.vulnerabilityReport(&out)
+	if out.Labels != nil {
+		t.Errorf("labels: got: %v", out.Labels)
+	}
+	p := out.Packages["1"]
+	if p.Name != "openssl" || p.Arch != "" || p.Module != "" || p.Source != nil {
+		t.Errorf("package: got: %+v", p)
+	}
+	if r := out.Repositories["1"]; r.Name != "internal" || r.URI != "" || r.Key != "" {
+		t.Errorf("repository: got: %+v", r)
+	}
+	if e := out.Environments["1"][0]; e.PackageDB != "" || e.DistributionID != "1" {
+		t.Errorf("environment: got: %+v", e)
+	}
+	// The original report must be untouched.
+	if pkgs["1"].Arch != "x86_64" || repos["1"].URI == "" || envs["1"][0].PackageDB == "" || vr.Packages["1"] != pkgs["1"] {
+		t.Error("original report modified")
+	}
+
+	ir := labeledIndexReport{
+		IndexReport: &claircore.IndexReport{Packages: pkgs},
+		Secrets:     []indexer.Secret{{Kind: "aws", Path: "root/.aws/credentials"}},
+		Unpackaged: &indexer.Unpackaged{
+			Files: []indexer.UnpackagedFile{{Kind: indexer.UnpackagedStaticBinary, Path: "app/server"}},
+		},
+	}
+	u := ir.Unpackaged
+	redactFilePaths.indexReport(&ir)
+	if len(ir.Secrets) != 1 || ir.Secrets[0].Path != "" || ir.Secrets[0].Kind != "aws" {
+		t.Errorf("secrets: got: %+v", ir.Secrets)
+	}
+	if fs := ir.Unpackaged.Files; len(fs) != 1 || fs[0].Path != "" || fs[0].Kind != indexer.UnpackagedStaticBinary {
+		t.Errorf("unpackaged: got: %+v", fs)
+	}
+	if u.Files[0].Path == "" {
+		t.Error("original unpackaged contents modified")
+	}
+	redactSecrets.indexReport(&ir)
+	if ir.Secrets != nil {
+		t.Errorf("secrets: got: %+v", ir.Secrets)
+	}
+}
@@ -0,0 +1,130 @@
+package httptransport
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/severity"
+)
+
+// ReportFilter describes the vulnerabilities to keep in a vulnerability report.
+type reportFilter struct {
+	// Severities, if not empty, is the set of normalized severities to keep.
+	severities map[claircore.Severity]struct{}
+	// FixedOnly drops vulnerabilities without a fixed version.
+	fixedOnly bool
+	// MinConfidence, if not empty, drops findings for packages identified
+	// with less confidence.
+	minConfidence string
+}
+
+// ParseReportFilter constructs a reportFilter from the optional "fixed",
+// "severity", and "min_confidence" query parameters. The "severity"
+// parameter may be repeated or contain a comma-separated list. It returns nil
+// if none are present.
+func parseReportFilter(q url.Values) (*reportFilter, error) {
+	var f reportFilter
+	var set bool
+	if param := q.Get("fixed"); param != "" {
+		b, err := strconv.ParseBool(param)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q query param into boolean", "fixed")
+		}
+		f.fixedOnly = b
+		set = b
+	}
+	for _, param := range q["severity"] {
+		for _, s := range strings.Split(param, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			sev, ok := severity.Parse(s)
+			if !ok {
+				return nil, fmt.Errorf("could not parse %q query param: unknown severity %q", "severity", s)
+			}
+			if f.severities == nil {
+				f.severities = make(map[claircore.Severity]struct{})
+			}
+			f.severities[sev] = struct{}{}
+			set = true
+		}
+	}
+	if param := q.Get("min_confidence"); param != "" {
+		l, ok := parseConfidence(param)
+		if !ok {
+			return nil, fmt.Errorf("could not parse %q query param: unknown confidence %q", "min_confidence", param)
+		}
+		f.minConfidence = l
+		set = true
+	}
+	if !set {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// Keep reports whether the vulnerability passes the filter.
+func (f *reportFilter) keep(v *claircore.Vulnerability) bool {
+	if f.fixedOnly && v.FixedInVersion == "" {
+		return false
+	}
+	if len(f.severities) != 0 {
+		if _, ok := f.severities[v.NormalizedSeverity]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns a copy of the report containing only the vulnerabilities
+// passing the filter. Packages are left in place, but their entries in
+// PackageVulnerabilities are removed if no vulnerabilities remain or the
+// package is identified with less than the minimum confidence. When
+// filtering by confidence, vulnerabilities no longer affecting any package
+// are removed as well.
+//
+// Enrichments are passed through unmodified.
+func (f *reportFilter) apply(r *claircore.VulnerabilityReport) *claircore.VulnerabilityReport {
+	out := *r
+	out.Vulnerabilities = make(map[string]*claircore.Vulnerability, len(r.Vulnerabilities))
+	for id, v := range r.Vulnerabilities {
+		if f.keep(v) {
+			out.Vulnerabilities[id] = v
+		}
+	}
+	out.PackageVulnerabilities = make(map[string][]string, len(r.PackageVulnerabilities))
+	for pkg, ids := range r.PackageVulnerabilities {
+		if f.minConfidence != "" &&
+			confidenceRank[packageConfidence(r, pkg).Level] < confidenceRank[f.minConfidence] {
+			continue
+		}
+		var keep []string
+		for _, id := range ids {
+			if _, ok := out.Vulnerabilities[id]; ok {
+				keep = append(keep, id)
+			}
+		}
+		if len(keep) != 0 {
+			out.PackageVulnerabilities[pkg] = keep
+		}
+	}
+	if f.minConfidence != "" {
+		affecting := make(map[string]struct{}, len(out.Vulnerabilities))
+		for _, ids := range out.PackageVulnerabilities {
+			for _, id := range ids {
+				affecting[id] = struct{}{}
+			}
+		}
+		for id := range out.Vulnerabilities {
+			if _, ok := affecting[id]; !ok {
+				delete(out.Vulnerabilities, id)
+			}
+		}
+	}
+	return &out
+}
@@ -0,0 +1,103 @@
+package httptransport
+
+import (
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestReportFilter(t *testing.T) {
+	r := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {NormalizedSeverity: claircore.Critical, FixedInVersion: "1.0"},
+			"2": {NormalizedSeverity: claircore.Critical},
+			"3": {NormalizedSeverity: claircore.Low, FixedInVersion: "1.0"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"a": {"1", "2"},
+			"b": {"3"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"a": {{PackageDB: "file:app/lib/guessed-1.0.jar"}},
+			"b": {{PackageDB: "sqlite:var/lib/rpm"}},
+		},
+	}
+	for _, tc := range []struct {
+		query    string
+		wantVuln []string
+		wantPkg  map[string][]string
+	}{
+		{
+			query:    "fixed=true",
+			wantVuln: []string{"1", "3"},
+			wantPkg:  map[string][]string{"a": {"1"}, "b": {"3"}},
+		},
+		{
+			query:    "severity=critical",
+			wantVuln: []string{"1", "2"},
+			wantPkg:  map[string][]string{"a": {"1", "2"}},
+		},
+		{
+			query:    "fixed=true&severity=Critical,High",
+			wantVuln: []string{"1"},
+			wantPkg:  map[string][]string{"a": {"1"}},
+		},
+		{
+			query:    "severity=low&severity=critical",
+			wantVuln: []string{"1", "2", "3"},
+			wantPkg:  r.PackageVulnerabilities,
+		},
+		{
+			query:    "min_confidence=medium",
+			wantVuln: []string{"3"},
+			wantPkg:  map[string][]string{"b": {"3"}},
+		},
+		{
+			query:    "min_confidence=LOW",
+			wantVuln: []string{"1", "2", "3"},
+			wantPkg:  r.PackageVulnerabilities,
+		},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := parseReportFilter(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.apply(r)
+			var ids []string
+			for id := range got.Vulnerabilities {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			if !cmp.Equal(ids, tc.wantVuln) {
+				t.Error(cmp.Diff(ids, tc.wantVuln))
+			}
+			if !cmp.Equal(got.PackageVulnerabilities, tc.wantPkg) {
+				t.Error(cmp.Diff(got.PackageVulnerabilities, tc.wantPkg))
+			}
+		})
+	}
+	if len(r.Vulnerabilities) != 3 {
+		t.Error("original report modified")
+	}
+
+	for _, q := range []string{"", "fixed=false"} {
+		v, _ := url.ParseQuery(q)
+		if f, err := parseReportFilter(v); err != nil || f != nil {
+			t.Errorf("%q: got: %v, %v; want no filter", q, f, err)
+		}
+	}
+	for _, q := range []string{"fixed=maybe", "severity=loud", "min_confidence=certain"} {
+		v, _ := url.ParseQuery(q)
+		if _, err := parseReportFilter(v); err == nil {
+			t.Errorf("%q: expected error", q)
+		}
+	}
+}
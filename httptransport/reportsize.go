@@ -0,0 +1,98 @@
+package httptransport
+
+import (
+	"github.com/quay/claircore"
+)
+
+// These are rough per-object costs for the fixed-size parts of the claircore
+// types and the map entries pointing at them. They don't need to be exact:
+// the estimate is only used to compare against a configured budget.
+const (
+	objectOverhead = 128
+	entryOverhead  = 48
+)
+
+// IndexReportSize estimates the number of bytes "ir" occupies in memory.
+func indexReportSize(ir *claircore.IndexReport) int64 {
+	n := int64(objectOverhead)
+	for id, p := range ir.Packages {
+		n += entryOverhead + int64(len(id)) + packageSize(p)
+	}
+	for id, d := range ir.Distributions {
+		n += entryOverhead + int64(len(id)) + distSize(d)
+	}
+	for id, r := range ir.Repositories {
+		n += entryOverhead + int64(len(id)) + repoSize(r)
+	}
+	for id, es := range ir.Environments {
+		n += entryOverhead + int64(len(id))
+		for _, e := range es {
+			if e == nil {
+				continue
+			}
+			n += objectOverhead + int64(len(e.PackageDB)+len(e.IntroducedIn.String())+len(e.DistributionID))
+			for _, r := range e.RepositoryIDs {
+				n += int64(len(r))
+			}
+		}
+	}
+	return n
+}
+
+// VulnerabilityReportSize estimates the number of bytes "vr" occupies in
+// memory, excluding the members shared with the index report it was built
+// from.
+func vulnerabilityReportSize(vr *claircore.VulnerabilityReport) int64 {
+	n := int64(objectOverhead)
+	for id, v := range vr.Vulnerabilities {
+		n += entryOverhead + int64(len(id))
+		if v == nil {
+			continue
+		}
+		n += objectOverhead + int64(len(v.ID)+len(v.Updater)+len(v.Name)+
+			len(v.Description)+len(v.Links)+len(v.Severity)+len(v.FixedInVersion))
+		n += packageSize(v.Package) + distSize(v.Dist) + repoSize(v.Repo)
+	}
+	for id, vs := range vr.PackageVulnerabilities {
+		n += entryOverhead + int64(len(id))
+		for _, v := range vs {
+			n += 16 + int64(len(v))
+		}
+	}
+	for k, es := range vr.Enrichments {
+		n += entryOverhead + int64(len(k))
+		for _, e := range es {
+			n += 24 + int64(len(e))
+		}
+	}
+	return n
+}
+
+func packageSize(p *claircore.Package) int64 {
+	if p == nil {
+		return 0
+	}
+	n := int64(objectOverhead + len(p.ID) + len(p.Name) + len(p.Version) +
+		len(p.Kind) + len(p.PackageDB) + len(p.Filepath) + len(p.RepositoryHint) +
+		len(p.Module) + len(p.Arch))
+	if p.Source != nil && p.Source != p {
+		n += packageSize(p.Source)
+	}
+	return n
+}
+
+func distSize(d *claircore.Distribution) int64 {
+	if d == nil {
+		return 0
+	}
+	return int64(objectOverhead + len(d.ID) + len(d.DID) + len(d.Name) +
+		len(d.Version) + len(d.VersionCodeName) + len(d.VersionID) +
+		len(d.Arch) + len(d.PrettyName))
+}
+
+func repoSize(r *claircore.Repository) int64 {
+	if r == nil {
+		return 0
+	}
+	return int64(objectOverhead + len(r.ID) + len(r.Name) + len(r.Key) + len(r.URI))
+}
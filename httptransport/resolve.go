@@ -0,0 +1,140 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"path"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/internal/registry"
+)
+
+// IndexReferenceRequest is the body of an index reference request: an image
+// reference, optionally naming a configured credential, along with the
+// annotations an index request may carry.
+type indexReferenceRequest struct {
+	Reference    string            `json:"reference"`
+	Credentials  string            `json:"credentials,omitempty"`
+	ArtifactType string            `json:"artifact_type,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// RefResolver resolves image references on behalf of clients.
+type refResolver struct {
+	// Allow holds the registry hosts references may name.
+	allow   map[string]struct{}
+	creds   map[string]config.RegistryCredential
+	resolve func(context.Context, name.Reference, authn.Authenticator) (*claircore.Manifest, *claircore.Layer, error)
+}
+
+// NewRefResolver returns a refResolver for the configuration in
+// "cfg.Indexer.Resolve".
+//
+// References are supplied by API clients, so requests go through a guarded
+// client that refuses link-local and metadata service addresses.
+func newRefResolver(ctx context.Context, cfg *config.Config) (*refResolver, error) {
+	rc := cfg.Indexer.Resolve
+	var tp *config.TLSPolicy
+	if cfg.OutboundTLS != nil {
+		tp = cfg.OutboundTLS.Registry
+	}
+	c, err := httputil.NewGuardedClient(ctx, &httputil.Guard{}, cfg.Proxy, tp, nil)
+	if err != nil {
+		return nil, err
+	}
+	rr := &refResolver{
+		allow: make(map[string]struct{}, len(rc.Registries)),
+		creds: rc.Credentials,
+		resolve: func(ctx context.Context, r name.Reference, auth authn.Authenticator) (*claircore.Manifest, *claircore.Layer, error) {
+			return registry.ResolveConfig(ctx, c.Transport, r, auth)
+		},
+	}
+	for _, r := range rc.Registries {
+		rr.allow[r] = struct{}{}
+	}
+	return rr, nil
+}
+
+// IndexReference resolves the image reference in the request body into a
+// manifest and indexes it, answering as the index report endpoint does.
+func (h *IndexerV1) indexReference(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/IndexerV1.indexReference")
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	rr := h.resolver
+	if rr == nil {
+		apiError(ctx, w, http.StatusNotFound, "reference resolution not enabled")
+		return
+	}
+	state, err := h.srv.State(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not retrieve indexer state: %v", err)
+		return
+	}
+	schema, err := reportSchema(w, r)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	var req indexReferenceRequest
+	dec := codec.GetDecoder(r.Body)
+	err = dec.Decode(&req)
+	codec.PutDecoder(dec)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "failed to deserialize request: %v", err)
+		return
+	}
+	ref, err := name.ParseReference(req.Reference)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed reference: %v", err)
+		return
+	}
+	host := ref.Context().RegistryStr()
+	if _, ok := rr.allow[host]; !ok {
+		apiError(ctx, w, http.StatusForbidden, "registry %q not allowed", host)
+		return
+	}
+	// Never lend the server's own credentials to a client.
+	auth := authn.Anonymous
+	if n := req.Credentials; n != "" {
+		c, ok := rr.creds[n]
+		switch {
+		case !ok:
+			apiError(ctx, w, http.StatusBadRequest, "unknown credentials %q", n)
+			return
+		case c.Registry != host:
+			apiError(ctx, w, http.StatusBadRequest, "credentials %q are not for registry %q", n, host)
+			return
+		case c.Token != "":
+			auth = &authn.Bearer{Token: c.Token}
+		default:
+			auth = &authn.Basic{Username: c.Username, Password: c.Password}
+		}
+	}
+	ctx = zlog.ContextWithValues(ctx, "reference", ref.String())
+	m, cfg, err := rr.resolve(ctx, ref, auth)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadGateway, "unable to resolve reference: %v", err)
+		return
+	}
+	zlog.Debug(ctx).
+		Stringer("manifest", m.Hash).
+		Msg("resolved reference")
+	ir := indexRequest{
+		Manifest:     *m,
+		ArtifactType: req.ArtifactType,
+		Labels:       req.Labels,
+		Config:       cfg,
+	}
+	h.index(w, r.WithContext(ctx), &ir, state, schema, path.Join(path.Dir(r.URL.Path), "index_report"))
+}
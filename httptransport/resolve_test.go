@@ -0,0 +1,112 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+func TestIndexReference(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = `sha256:0000000000000000000000000000000000000000000000000000000000000000`
+	var gotAuth authn.Authenticator
+	i := &indexer.Mock{
+		State_: func(context.Context) (string, error) {
+			return `deadbeef`, nil
+		},
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+	}
+	run := func(t *testing.T, rr *refResolver) func(string, int) *http.Response {
+		v1, err := NewIndexerV1(ctx, "", i, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v1.resolver = rr
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(body string, want int) *http.Response {
+			t.Helper()
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/index_reference", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s: got: %d, want: %d", body, got, want)
+			}
+			return res
+		}
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		do := run(t, nil)
+		do(`{"reference":"quay.io/example/app:latest"}`, http.StatusNotFound).Body.Close()
+	})
+	t.Run("Enabled", func(t *testing.T) {
+		rr, err := newRefResolver(ctx, &config.Config{
+			Indexer: config.Indexer{
+				Resolve: &config.IndexerResolve{
+					Registries: []string{"quay.io"},
+					Credentials: map[string]config.RegistryCredential{
+						"robot": {Registry: "quay.io", Username: "robot", Password: "hunter2"},
+						"other": {Registry: "registry.example.com", Token: "t0ken"},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr.resolve = func(_ context.Context, r name.Reference, auth authn.Authenticator) (*claircore.Manifest, *claircore.Layer, error) {
+			gotAuth = auth
+			if r.Identifier() == "missing" {
+				return nil, nil, errors.New("not found")
+			}
+			return &claircore.Manifest{
+				Hash:   claircore.MustParseDigest(digest),
+				Layers: []*claircore.Layer{{Hash: claircore.MustParseDigest(digest)}},
+			}, nil, nil
+		}
+		do := run(t, rr)
+		do(`{`, http.StatusBadRequest).Body.Close()
+		do(`{"reference":"Not A Reference"}`, http.StatusBadRequest).Body.Close()
+		do(`{"reference":"docker.io/library/alpine:latest"}`, http.StatusForbidden).Body.Close()
+		do(`{"reference":"quay.io/example/app:latest","credentials":"nobody"}`, http.StatusBadRequest).Body.Close()
+		do(`{"reference":"quay.io/example/app:latest","credentials":"other"}`, http.StatusBadRequest).Body.Close()
+		do(`{"reference":"quay.io/example/app:missing"}`, http.StatusBadGateway).Body.Close()
+		if gotAuth != authn.Anonymous {
+			t.Errorf("auth: got: %#v, want anonymous", gotAuth)
+		}
+
+		res := do(`{"reference":"quay.io/example/app:latest","credentials":"robot"}`, http.StatusCreated)
+		res.Body.Close()
+		if got, want := res.Header.Get("location"), "/index_report/"+digest; got != want {
+			t.Errorf("location: got: %q, want: %q", got, want)
+		}
+		if b, ok := gotAuth.(*authn.Basic); !ok || b.Username != "robot" {
+			t.Errorf("auth: got: %#v", gotAuth)
+		}
+	})
+}
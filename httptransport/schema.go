@@ -0,0 +1,176 @@
+package httptransport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/ecosystem"
+	"github.com/quay/clair/v4/matcher/triage"
+)
+
+// These are the schema versions index and vulnerability reports can be
+// served in.
+//
+// Reports are served in the current schema unless the "schema" query
+// parameter requests an older one. When the structure of a report changes,
+// a new version is added here along with a case in the report types' "in"
+// methods converting back to the previous version.
+const (
+	// SchemaV1 is claircore's report types, as-is.
+	SchemaV1 = `v1`
+	// SchemaV2 adds the "labels" member to index reports and the "labels"
+	// and "annotations" members to vulnerability reports.
+	SchemaV2 = `v2`
+	// SchemaV3 adds the "attribution" member to vulnerability reports.
+	SchemaV3 = `v3`
+	// SchemaV4 adds the "scanners" member to index reports.
+	SchemaV4 = `v4`
+	// SchemaV5 adds the "secrets" member to index reports.
+	SchemaV5 = `v5`
+	// SchemaV6 adds the "licenses" member to index reports.
+	SchemaV6 = `v6`
+	// SchemaV7 adds the "confidence" member to vulnerability reports.
+	SchemaV7 = `v7`
+	// SchemaV8 adds the "origins" member to index reports.
+	SchemaV8 = `v8`
+	// SchemaV9 adds the "errata" member to vulnerability reports.
+	SchemaV9 = `v9`
+	// SchemaV10 adds the "image_config" member to index reports.
+	SchemaV10 = `v10`
+	// SchemaV11 adds the "unpackaged" member to index reports.
+	SchemaV11 = `v11`
+
+	currentSchema = SchemaV11
+)
+
+// Schemas lists every schema version, oldest first.
+var schemas = []string{SchemaV1, SchemaV2, SchemaV3, SchemaV4, SchemaV5, SchemaV6, SchemaV7, SchemaV8, SchemaV9, SchemaV10, SchemaV11}
+
+// SchemaHeader is the response header reporting the schema version a report
+// was served in.
+const SchemaHeader = `Clair-Report-Schema`
+
+// ReportSchema returns the schema version requested by "r" and sets the
+// response header reporting it.
+func reportSchema(w http.ResponseWriter, r *http.Request) (string, error) {
+	s := r.URL.Query().Get("schema")
+	switch s {
+	case "":
+		s = currentSchema
+	case SchemaV1, SchemaV2, SchemaV3, SchemaV4, SchemaV5, SchemaV6, SchemaV7, SchemaV8, SchemaV9, SchemaV10, SchemaV11:
+	default:
+		return "", fmt.Errorf("unknown report schema %q", s)
+	}
+	w.Header().Set(SchemaHeader, s)
+	return s, nil
+}
+
+// HasAttribution reports whether vulnerability reports in the named schema
+// version include attribution, so it's only computed when needed.
+func hasAttribution(schema string) bool {
+	return schema != SchemaV1 && schema != SchemaV2
+}
+
+// LabeledIndexReport is an IndexReport along with the labels stored for its
+// manifest, the scanners the indexer runs, any credentials found embedded in
+// the manifest, the licenses its packages declare, which packages were found
+// vendored or in package manager caches, its recorded image config, and the
+// contents no package manager accounts for.
+type labeledIndexReport struct {
+	*claircore.IndexReport
+	Labels      map[string]string    `json:"labels,omitempty"`
+	Scanners    []ecosystem.Scanner  `json:"scanners,omitempty"`
+	Secrets     []indexer.Secret     `json:"secrets,omitempty"`
+	Licenses    map[string]string    `json:"licenses,omitempty"`
+	Origins     map[string]string    `json:"origins,omitempty"`
+	ImageConfig *indexer.ImageConfig `json:"image_config,omitempty"`
+	Unpackaged  *indexer.Unpackaged  `json:"unpackaged,omitempty"`
+}
+
+// In returns the report in the named schema version.
+func (r *labeledIndexReport) in(schema string) interface{} {
+	switch schema {
+	case SchemaV1:
+		return r.IndexReport
+	case SchemaV2, SchemaV3:
+		v2 := *r
+		v2.Scanners = nil
+		v2.Secrets = nil
+		v2.Licenses = nil
+		v2.Origins = nil
+		v2.ImageConfig = nil
+		v2.Unpackaged = nil
+		return &v2
+	case SchemaV4:
+		v4 := *r
+		v4.Secrets = nil
+		v4.Licenses = nil
+		v4.Origins = nil
+		v4.ImageConfig = nil
+		v4.Unpackaged = nil
+		return &v4
+	case SchemaV5:
+		v5 := *r
+		v5.Licenses = nil
+		v5.Origins = nil
+		v5.ImageConfig = nil
+		v5.Unpackaged = nil
+		return &v5
+	case SchemaV6, SchemaV7:
+		v6 := *r
+		v6.Origins = nil
+		v6.ImageConfig = nil
+		v6.Unpackaged = nil
+		return &v6
+	case SchemaV8, SchemaV9:
+		v8 := *r
+		v8.ImageConfig = nil
+		v8.Unpackaged = nil
+		return &v8
+	case SchemaV10:
+		v10 := *r
+		v10.Unpackaged = nil
+		return &v10
+	}
+	return r
+}
+
+// AnnotatedVulnerabilityReport is a VulnerabilityReport along with the labels
+// stored for its manifest, the triage annotations in effect, where the
+// packages with findings came from, how confident the findings are, and the
+// vendor advisories for the vulnerabilities.
+type annotatedVulnerabilityReport struct {
+	*claircore.VulnerabilityReport
+	Labels      map[string]string            `json:"labels,omitempty"`
+	Annotations map[string]triage.Annotation `json:"annotations,omitempty"`
+	Attribution map[string]attribution       `json:"attribution,omitempty"`
+	Confidence  map[string]confidence        `json:"confidence,omitempty"`
+	Errata      map[string]erratum           `json:"errata,omitempty"`
+}
+
+// In returns the report in the named schema version.
+func (r *annotatedVulnerabilityReport) in(schema string) interface{} {
+	switch schema {
+	case SchemaV1:
+		return r.VulnerabilityReport
+	case SchemaV2:
+		v2 := *r
+		v2.Attribution = nil
+		v2.Confidence = nil
+		v2.Errata = nil
+		return &v2
+	case SchemaV3, SchemaV4, SchemaV5, SchemaV6:
+		v3 := *r
+		v3.Confidence = nil
+		v3.Errata = nil
+		return &v3
+	case SchemaV7, SchemaV8:
+		v7 := *r
+		v7.Errata = nil
+		return &v7
+	}
+	return r
+}
@@ -0,0 +1,73 @@
+package httptransport
+
+import (
+	"net/http"
+
+	"github.com/quay/clair/config"
+
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// These are the audiences for intraservice tokens, naming the service the
+// token is meant for.
+const (
+	IndexerAudience  = `clair-indexer`
+	MatcherAudience  = `clair-matcher`
+	NotifierAudience = `clair-notifier`
+)
+
+// These are the scopes granted to intraservice tokens. Each names the only
+// requests a token carrying it may make.
+const (
+	// ScopeIndexReport allows fetching index reports.
+	ScopeIndexReport = `index_report:read`
+	// ScopeIndexManifest allows submitting manifests to be indexed.
+	ScopeIndexManifest = `index_report:write`
+	// ScopeAffectedManifest allows querying affected manifests.
+	ScopeAffectedManifest = `affected_manifest:read`
+	// ScopeManifestLabels allows looking up manifest labels.
+	ScopeManifestLabels = `manifest_labels:read`
+	// ScopeManifestList allows listing the indexed manifests.
+	ScopeManifestList = `manifest:list`
+	// ScopeUpdateOperation allows listing update operations.
+	ScopeUpdateOperation = `update_operation:read`
+	// ScopeUpdateDiff allows fetching update diffs.
+	ScopeUpdateDiff = `update_diff:read`
+	// ScopeVulnerabilityReport allows creating vulnerability reports for
+	// submitted index reports.
+	ScopeVulnerabilityReport = `vulnerability_report:read`
+	// ScopeVulnstore allows querying the vulnerability store.
+	ScopeVulnstore = `vulnstore:read`
+)
+
+// ScopeGrants maps scopes to the routes they grant access to.
+//
+// None of these allow deleting manifests or update operations. Submitting
+// manifests is only requested by matchers with re-scan subscriptions
+// configured, listing them only by matchers with backfills configured, and
+// fetching reports only by notifiers that publish them.
+var scopeGrants = map[string]auth.Route{
+	ScopeIndexReport:         {Method: http.MethodGet, Prefix: IndexReportAPIPath},
+	ScopeIndexManifest:       {Method: http.MethodPost, Prefix: IndexAPIPath},
+	ScopeAffectedManifest:    {Method: http.MethodPost, Prefix: AffectedManifestAPIPath},
+	ScopeManifestLabels:      {Method: http.MethodPost, Prefix: ManifestLabelsAPIPath},
+	ScopeManifestList:        {Method: http.MethodGet, Prefix: IndexerManifestsAPIPath},
+	ScopeUpdateOperation:     {Method: http.MethodGet, Prefix: UpdateOperationAPIPath},
+	ScopeUpdateDiff:          {Method: http.MethodGet, Prefix: UpdateDiffAPIPath},
+	ScopeVulnerabilityReport: {Method: http.MethodPost, Prefix: VulnerabilityReportPath},
+	ScopeVulnstore:           {Method: http.MethodPost, Prefix: VulnstoreAPIPath},
+}
+
+// Audiences returns the audiences a server running in "mode" accepts
+// intraservice tokens for.
+func audiences(mode config.Mode) []string {
+	switch mode {
+	case config.IndexerMode:
+		return []string{IndexerAudience}
+	case config.MatcherMode:
+		return []string{MatcherAudience}
+	case config.NotifierMode:
+		return []string{NotifierAudience}
+	}
+	return []string{IndexerAudience, MatcherAudience, NotifierAudience}
+}
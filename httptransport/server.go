@@ -8,6 +8,7 @@
 	"time"
 
 	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
 	"github.com/quay/zlog"
 	othttp "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -15,6 +16,9 @@
 
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/ecosystem"
+	"github.com/quay/clair/v4/indexer/licenses"
+	"github.com/quay/clair/v4/internal/dsse"
 	"github.com/quay/clair/v4/matcher"
 	intromw "github.com/quay/clair/v4/middleware/introspection"
 	"github.com/quay/clair/v4/notifier"
@@ -28,16 +32,35 @@
 	internalRoot                 = apiRoot + "internal/"
 	IndexAPIPath                 = indexerRoot + apiRoot + "index_report"
 	IndexReportAPIPath           = indexerRoot + apiRoot + "index_report/"
+	IndexReferenceAPIPath        = indexerRoot + apiRoot + "index_reference"
 	IndexStateAPIPath            = indexerRoot + apiRoot + "index_state"
+	FileOwnersAPIPath            = indexerRoot + apiRoot + "file_owners/"
+	LicenseCheckAPIPath          = indexerRoot + apiRoot + "license_check/"
 	AffectedManifestAPIPath      = indexerRoot + internalRoot + "affected_manifest/"
+	ManifestLabelsAPIPath        = indexerRoot + internalRoot + "manifest_labels"
+	IndexerUsageAPIPath          = indexerRoot + internalRoot + "usage"
+	IndexerManifestsAPIPath      = indexerRoot + internalRoot + "manifests"
 	VulnerabilityReportPath      = matcherRoot + apiRoot + "vulnerability_report/"
+	VulnerabilityReportsPath     = matcherRoot + apiRoot + "vulnerability_reports"
+	AttestationAPIPath           = matcherRoot + apiRoot + "attestation/"
+	AdmissionReviewAPIPath       = matcherRoot + apiRoot + "admission_review"
 	UpdateOperationAPIPath       = matcherRoot + internalRoot + "update_operation"
 	UpdateOperationDeleteAPIPath = matcherRoot + internalRoot + "update_operation/"
 	UpdateDiffAPIPath            = matcherRoot + internalRoot + "update_diff"
+	MatcherUsageAPIPath          = matcherRoot + internalRoot + "usage"
+	VulnstoreAPIPath             = matcherRoot + internalRoot + "vulnstore/"
+	BackfillAPIPath              = matcherRoot + internalRoot + "backfill"
+	VulnstoreVulnerabilitiesPath = VulnstoreAPIPath + "vulnerabilities"
+	VulnstoreEnrichmentsPath     = VulnstoreAPIPath + "enrichments"
 	NotificationAPIPath          = notifierRoot + apiRoot + "notification/"
+	NotifierSelfTestAPIPath      = notifierRoot + internalRoot + "self_test"
+	DeliveryReportAPIPath        = notifierRoot + internalRoot + "delivery_report/"
+	NotifierUsageAPIPath         = notifierRoot + internalRoot + "usage"
 	KeysAPIPath                  = notifierRoot + apiRoot + "services/notifier/keys"
 	KeyByIDAPIPath               = notifierRoot + apiRoot + "services/notifier/keys/"
 	OpenAPIV1Path                = "/openapi/v1"
+	SigningKeyV1Path             = "/signing/v1/key"
+	CapabilitiesPath             = "/capabilities"
 )
 
 // Server is the primary http server Clair exposes its functionality on.
@@ -52,6 +75,13 @@ type Server struct {
 	matcher  matcher.Service
 	notifier notifier.Service
 	traceOpt othttp.Option
+	// Signer signs reports for clients that request them signed, if report
+	// signing is configured.
+	signer *dsse.Signer
+	// Listeners are servers for services configured to use their own
+	// address. Services present here are not served by the embedded
+	// http.Server.
+	Listeners []*Listener
 }
 
 func New(ctx context.Context, conf config.Config, indexer indexer.Service, matcher matcher.Service, notifier notifier.Service) (*Server, error) {
@@ -73,6 +103,9 @@ func New(ctx context.Context, conf config.Config, indexer indexer.Service, match
 	}
 	ctx = zlog.ContextWithValues(ctx, "component", "httptransport/New")
 
+	if err := t.configureSigning(ctx); err != nil {
+		return nil, err
+	}
 	if err := t.configureDiscovery(ctx); err != nil {
 		zlog.Warn(ctx).Err(err).Msg("configuring openapi discovery failed")
 	} else {
@@ -103,20 +136,45 @@ func New(ctx context.Context, conf config.Config, indexer indexer.Service, match
 		}
 	}
 
+	if err := t.configureCapabilities(ctx); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("configuring capabilities discovery failed")
+	}
+
 	// attach HttpTransport to server, this works because we embed http.ServeMux
 	t.Server.Handler = t
+	if conf.RequestTimeouts != nil {
+		t.Server.Handler = requestTimeouts(conf.RequestTimeouts, t.Server.Handler)
+	}
 
 	// Add endpoint authentication if configured to add auth. Must happen after
 	// mux was configured for given mode.
 	if conf.Auth.Any() {
-		err := t.configureWithAuth(ctx)
+		// Serving without the configured authentication would leave every
+		// endpoint open, so this is fatal.
+		if err := t.configureWithAuth(ctx); err != nil {
+			return nil, fmt.Errorf("auth configuration: %w", err)
+		}
+	}
+
+	// Access logging wraps everything, so that rejected requests are logged
+	// as well.
+	if conf.AccessLog != nil {
+		t.Server.Handler = accessLog(conf.AccessLog, t.Server.Handler)
+	}
+	if conf.AuditLog != nil {
+		h, err := auditLog(ctx, conf.AuditLog, t.Server.Handler)
 		if err != nil {
-			zlog.Warn(ctx).
-				Err(err).
-				Msg("received error configuring auth middleware")
+			return nil, clairerror.ErrNotInitialized{Msg: "could not configure audit log: " + err.Error()}
 		}
+		t.Server.Handler = h
 	}
 
+	if conf.Mode == config.ComboMode && conf.Listeners != nil {
+		t.configureListeners(ctx)
+	}
+	t.configureWebhook(ctx)
+	t.configureHarbor(ctx)
+
 	return t, nil
 }
 
@@ -128,6 +186,27 @@ func (t *Server) configureDiscovery(_ context.Context) error {
 	return nil
 }
 
+// ConfigureSigning loads the report signing key, if configured, and serves
+// its public half.
+func (t *Server) configureSigning(ctx context.Context) error {
+	cfg := t.conf.ReportSigning
+	if cfg == nil {
+		return nil
+	}
+	s, err := dsse.LoadSigner(cfg.Key, cfg.KeyID)
+	if err != nil {
+		return clairerror.ErrNotInitialized{Msg: "could not load report signing key: " + err.Error()}
+	}
+	t.signer = s
+	t.Handle(SigningKeyV1Path,
+		intromw.InstrumentedHandler(SigningKeyV1Path, t.traceOpt, signingKeyHandler(s)))
+	zlog.Info(ctx).
+		Str("keyid", s.KeyID()).
+		Str("algorithm", s.Algorithm()).
+		Msg("report signing configured")
+	return nil
+}
+
 // ConfigureComboMode configures the HttpTransport for ComboMode.
 //
 // This mode runs both Indexer and Matcher in a single process.
@@ -170,15 +249,43 @@ func (t *Server) configureIndexerMode(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("indexer configuration: %w", err)
 	}
-	var sem *semaphore.Weighted
+	v1.limits = t.conf.Indexer.Limits
+	v1.signer = t.signer
+	v1.redact = newRedactor(t.conf.Redaction)
+	if t.conf.Indexer.Resolve != nil {
+		if v1.resolver, err = newRefResolver(ctx, &t.conf); err != nil {
+			return fmt.Errorf("indexer configuration: %w", err)
+		}
+	}
+	if ic := t.conf.Indexer.Idempotency; ic != nil {
+		v1.idem = newIdempotencyCache(time.Duration(ic.TTL), ic.MaxEntries)
+	}
+	if lc := t.conf.Indexer.Licenses; lc != nil && lc.Policy != nil {
+		v1.policy = licenses.NewPolicy(lc.Policy.Allow, lc.Policy.Deny)
+	}
+	es, err := ecosystem.Configured(ctx, &t.conf.Indexer.Scanner)
+	if err != nil {
+		return fmt.Errorf("indexer configuration: %w", err)
+	}
+	if v1.scanners, err = ecosystem.Scanners(ctx, es); err != nil {
+		return fmt.Errorf("indexer configuration: %w", err)
+	}
+	var sem, batchSem *semaphore.Weighted
 	if ct := t.conf.Indexer.IndexReportRequestConcurrency; ct > 0 {
 		sem = semaphore.NewWeighted(int64(ct))
 	}
+	batch := t.conf.Indexer.BatchLane
+	if batch != nil && batch.Concurrency > 0 {
+		batchSem = semaphore.NewWeighted(int64(batch.Concurrency))
+	}
 	rl := &limitHandler{
 		Check: func(r *http.Request) (*semaphore.Weighted, string) {
 			if r.Method != http.MethodPost && r.URL.Path != IndexAPIPath {
 				return nil, ""
 			}
+			if batch != nil && requestLane(r, batch.Issuers) == laneBatch {
+				return batchSem, IndexAPIPath
+			}
 			// Nil if the relevant config option isn't set.
 			return sem, IndexAPIPath
 		},
@@ -199,6 +306,17 @@ func (t *Server) configureMatcherMode(ctx context.Context) error {
 	}
 	prefix := matcherRoot + apiRoot
 	v1 := NewMatcherV1(ctx, prefix, t.matcher, t.indexer, time.Duration(t.conf.Matcher.CacheAge), t.traceOpt)
+	v1.limits = t.conf.Matcher.Limits
+	v1.signer = t.signer
+	v1.authenticated = t.conf.Auth.Any()
+	v1.redact = newRedactor(t.conf.Redaction)
+	for _, b := range t.conf.Matcher.BaseImages {
+		d, err := claircore.ParseDigest(b)
+		if err != nil {
+			return fmt.Errorf("matcher configuration: base image: %w", err)
+		}
+		v1.bases = append(v1.bases, d)
+	}
 
 	t.Handle(prefix, v1)
 	return nil
@@ -229,8 +347,8 @@ func (t *Server) configureNotifierMode(ctx context.Context) error {
 // middleware handler.
 //
 // Must be ran after the config*Mode method of choice.
-func (t *Server) configureWithAuth(_ context.Context) error {
-	h, err := authHandler(&t.conf, t.Server.Handler)
+func (t *Server) configureWithAuth(ctx context.Context) error {
+	h, err := authHandler(ctx, &t.conf, t.Server.Handler)
 	if err != nil {
 		return err
 	}
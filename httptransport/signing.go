@@ -0,0 +1,81 @@
+package httptransport
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dsse"
+)
+
+// Payload types of signed reports.
+const (
+	indexReportPayloadType         = `application/vnd.clair.indexreport.v1+json`
+	vulnerabilityReportPayloadType = `application/vnd.clair.vulnerabilityreport.v1+json`
+)
+
+// WantEnvelope reports whether the request names the DSSE envelope media
+// type in its "Accept" header. Wildcards don't count, so clients only get an
+// envelope when they ask for one.
+func wantEnvelope(r *http.Request) bool {
+	for _, part := range r.Header["Accept"] {
+		for _, s := range strings.Split(part, ",") {
+			mt, _, err := mime.ParseMediaType(strings.TrimSpace(s))
+			if err == nil && mt == dsse.MediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SignReport returns an envelope containing "v", encoded as JSON, signed by
+// "s".
+func signReport(s *dsse.Signer, typ string, v interface{}) (*dsse.Envelope, error) {
+	var buf bytes.Buffer
+	enc := codec.GetEncoder(&buf)
+	defer codec.PutEncoder(enc)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return s.Sign(typ, buf.Bytes())
+}
+
+// SigningKey is the verification material for signed reports.
+type signingKey struct {
+	KeyID     string `json:"keyid"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+// SigningKeyHandler serves the public key that signed reports can be
+// verified with.
+func signingKeyHandler(s *dsse.Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := zlog.ContextWithValues(r.Context(),
+			"component", "httptransport/signingKeyHandler")
+		if r.Method != http.MethodGet {
+			apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+			return
+		}
+		if r.URL.Query().Get("format") == "pem" {
+			w.Header().Set("content-type", "application/x-pem-file")
+			w.Write(s.PublicKeyPEM())
+			return
+		}
+		var err error
+		defer writerError(w, &err)()
+		w.Header().Set("content-type", "application/json")
+		enc := codec.GetEncoder(w)
+		defer codec.PutEncoder(enc)
+		err = enc.Encode(&signingKey{
+			KeyID:     s.KeyID(),
+			Algorithm: s.Algorithm(),
+			PublicKey: string(s.PublicKeyPEM()),
+		})
+	})
+}
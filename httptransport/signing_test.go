@@ -0,0 +1,101 @@
+package httptransport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/dsse"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestSignedVulnerabilityReport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := dsse.NewSigner(k, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	v1.signer = s
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	get := func(t *testing.T, accept string) *http.Response {
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/vulnerability_report/"+digest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("accept", accept)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	t.Run("Signed", func(t *testing.T) {
+		res := get(t, dsse.MediaType+", application/json;q=0.5")
+		defer res.Body.Close()
+		if got, want := res.Header.Get("content-type"), dsse.MediaType; got != want {
+			t.Fatalf("got: %q, want: %q", got, want)
+		}
+		var e dsse.Envelope
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := e.PayloadType, vulnerabilityReportPayloadType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if err := dsse.Verify(&e, &k.PublicKey); err != nil {
+			t.Error(err)
+		}
+		var vr claircore.VulnerabilityReport
+		if err := json.Unmarshal(e.Payload, &vr); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vr.Hash.String(), digest; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+	t.Run("Wildcard", func(t *testing.T) {
+		res := get(t, "*/*")
+		res.Body.Close()
+		if got, want := res.Header.Get("content-type"), "application/json"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/standby"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// StandbyHandler reports whether the process is active or on standby in
+// response to GET requests, and promotes it in response to POST requests.
+func (h *MatcherV1) standbyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.standbyHandler")
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	sb, ok := h.srv.(matcher.Standby)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "standby not supported")
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		ok, err := sb.Promote(ctx)
+		if err != nil {
+			standbyError(ctx, w, err, "promote")
+			return
+		}
+		if ok {
+			zlog.Info(ctx).Msg("promoted by request")
+		}
+	}
+	s, err := sb.StandbyStatus(ctx)
+	if err != nil {
+		standbyError(ctx, w, err, "get standby status")
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(&s)
+}
+
+// StandbyError writes the response for an error from a matcher.Standby
+// method.
+func standbyError(ctx context.Context, w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, standby.ErrDisabled):
+		apiError(ctx, w, http.StatusNotFound, "standby not configured")
+	case errors.Is(err, standby.ErrLockPromotion):
+		apiError(ctx, w, http.StatusConflict, "%v", err)
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not %s: %v", action, err)
+	}
+}
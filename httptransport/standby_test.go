@@ -0,0 +1,106 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/internal/standby"
+	"github.com/quay/clair/v4/matcher"
+)
+
+type standbyMock struct {
+	*matcher.Mock
+	gate   *standby.Gate
+	byLock bool
+}
+
+func (m *standbyMock) StandbyStatus(_ context.Context) (standby.Status, error) {
+	if m.gate == nil {
+		return standby.Status{}, standby.ErrDisabled
+	}
+	return m.gate.Status(), nil
+}
+
+func (m *standbyMock) Promote(_ context.Context) (bool, error) {
+	switch {
+	case m.gate == nil:
+		return false, standby.ErrDisabled
+	case m.byLock:
+		return false, standby.ErrLockPromotion
+	}
+	return m.gate.Promote("api"), nil
+}
+
+func TestStandby(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	run := func(t *testing.T, svc matcher.Service) func(string, int) *http.Response {
+		v1 := NewMatcherV1(ctx, "", svc, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(method string, want int) *http.Response {
+			t.Helper()
+			req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+"/internal/standby", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { res.Body.Close() })
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s: got: %d, want: %d", method, got, want)
+			}
+			return res
+		}
+	}
+	status := func(t *testing.T, res *http.Response) standby.Status {
+		t.Helper()
+		var s standby.Status
+		if err := json.NewDecoder(res.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		do := run(t, &matcher.Mock{})
+		do(http.MethodGet, http.StatusNotFound)
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		do := run(t, &standbyMock{Mock: &matcher.Mock{}})
+		do(http.MethodGet, http.StatusNotFound)
+		do(http.MethodPost, http.StatusNotFound)
+	})
+	t.Run("Lock", func(t *testing.T) {
+		do := run(t, &standbyMock{Mock: &matcher.Mock{}, gate: standby.New(ctx), byLock: true})
+		if s := status(t, do(http.MethodGet, http.StatusOK)); s.Active {
+			t.Errorf("got: %+v, want standby", s)
+		}
+		do(http.MethodPost, http.StatusConflict)
+		do(http.MethodDelete, http.StatusMethodNotAllowed)
+	})
+	t.Run("API", func(t *testing.T) {
+		do := run(t, &standbyMock{Mock: &matcher.Mock{}, gate: standby.New(ctx)})
+		if s := status(t, do(http.MethodPost, http.StatusOK)); !s.Active || s.Reason != "api" {
+			t.Errorf("got: %+v, want active", s)
+		}
+		// Promoting an active process is fine.
+		if s := status(t, do(http.MethodPost, http.StatusOK)); !s.Active {
+			t.Errorf("got: %+v, want active", s)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/subscription"
+)
+
+// Subscriptions returns the matcher's subscription manager, writing an error
+// response and returning nil if it doesn't have one.
+func (h *MatcherV1) subscriptions(ctx context.Context, w http.ResponseWriter) matcher.Subscriptions {
+	s, ok := h.srv.(matcher.Subscriptions)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "re-scan subscriptions not supported")
+		return nil
+	}
+	return s
+}
+
+// SubscriptionError writes the response for an error from a subscription
+// method.
+func subscriptionError(ctx context.Context, w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, subscription.ErrDisabled):
+		apiError(ctx, w, http.StatusNotFound, "re-scan subscriptions not configured")
+	case errors.Is(err, subscription.ErrInvalid):
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not %s: %v", action, err)
+	}
+}
+
+// SubscriptionList lists the stored subscriptions in response to GET
+// requests and creates one in response to POST requests.
+func (h *MatcherV1) subscriptionList(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.subscriptionList")
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	s := h.subscriptions(ctx, w)
+	if s == nil {
+		return
+	}
+
+	var out interface{}
+	switch r.Method {
+	case http.MethodGet:
+		ss, err := s.Subscriptions(ctx)
+		if err != nil {
+			subscriptionError(ctx, w, err, "list subscriptions")
+			return
+		}
+		out = ss
+		w.Header().Set("content-type", "application/json")
+	case http.MethodPost:
+		sub := new(subscription.Subscription)
+		dec := codec.GetDecoder(r.Body)
+		err := dec.Decode(sub)
+		codec.PutDecoder(dec)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not deserialize subscription: %v", err)
+			return
+		}
+		if err := s.AddSubscription(ctx, sub); err != nil {
+			subscriptionError(ctx, w, err, "store subscription")
+			return
+		}
+		zlog.Info(ctx).
+			Stringer("subscription", sub.ID).
+			Stringer("manifest", sub.ManifestHash).
+			Msg("created subscription")
+		out = sub
+		w.Header().Set("content-type", "application/json")
+		w.Header().Set("location", path.Join(r.URL.Path, sub.ID.String()))
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	var err error
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(out)
+}
+
+// SubscriptionHandler serves a single subscription: GET returns it and DELETE
+// removes it.
+func (h *MatcherV1) subscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.subscriptionHandler")
+	switch r.Method {
+	case http.MethodGet, http.MethodDelete:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	s := h.subscriptions(ctx, w)
+	if s == nil {
+		return
+	}
+	id, err := uuid.Parse(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "could not parse subscription id: %v", err)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		ok, err := s.DeleteSubscription(ctx, id)
+		switch {
+		case err != nil:
+			subscriptionError(ctx, w, err, "delete subscription")
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "subscription %v not found", id)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	sub, ok, err := s.Subscription(ctx, id)
+	switch {
+	case err != nil:
+		subscriptionError(ctx, w, err, "get subscription")
+		return
+	case !ok:
+		apiError(ctx, w, http.StatusNotFound, "subscription %v not found", id)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(sub)
+}
@@ -0,0 +1,111 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/subscription"
+)
+
+type subscriptionMock struct {
+	*matcher.Mock
+	disabled bool
+	m        map[uuid.UUID]subscription.Subscription
+}
+
+func (s *subscriptionMock) Subscriptions(context.Context) ([]subscription.Subscription, error) {
+	if s.disabled {
+		return nil, subscription.ErrDisabled
+	}
+	out := []subscription.Subscription{}
+	for _, v := range s.m {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (s *subscriptionMock) Subscription(_ context.Context, id uuid.UUID) (*subscription.Subscription, bool, error) {
+	v, ok := s.m[id]
+	return &v, ok, nil
+}
+
+func (s *subscriptionMock) AddSubscription(_ context.Context, v *subscription.Subscription) error {
+	if err := v.Validate(time.Hour); err != nil {
+		return err
+	}
+	v.ID = uuid.New()
+	s.m[v.ID] = *v
+	return nil
+}
+
+func (s *subscriptionMock) DeleteSubscription(_ context.Context, id uuid.UUID) (bool, error) {
+	_, ok := s.m[id]
+	delete(s.m, id)
+	return ok, nil
+}
+
+func TestSubscriptionHandler(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	m := &subscriptionMock{
+		Mock: &matcher.Mock{},
+		m:    make(map[uuid.UUID]subscription.Subscription),
+	}
+	v1 := NewMatcherV1(ctx, "", m, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(method, path, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+
+	do(http.MethodPost, "/subscription", `{"manifest_hash":"`+digest+`","interval":"1m","callback":"https://example.com/"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPost, "/subscription", `{"manifest_hash":"`+digest+`","interval":"24h","callback":"/relative"}`, http.StatusBadRequest).Body.Close()
+	res := do(http.MethodPost, "/subscription", `{"manifest_hash":"`+digest+`","interval":"24h","callback":"https://example.com/"}`, http.StatusCreated)
+	var got subscription.Subscription
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got.ID == uuid.Nil || res.Header.Get("location") != "/subscription/"+got.ID.String() {
+		t.Errorf("got: %+v, location: %q", got, res.Header.Get("location"))
+	}
+
+	do(http.MethodGet, "/subscription", "", http.StatusOK).Body.Close()
+	do(http.MethodGet, "/subscription/"+got.ID.String(), "", http.StatusOK).Body.Close()
+	do(http.MethodGet, "/subscription/bad", "", http.StatusBadRequest).Body.Close()
+	do(http.MethodDelete, "/subscription/"+got.ID.String(), "", http.StatusNoContent).Body.Close()
+	do(http.MethodGet, "/subscription/"+got.ID.String(), "", http.StatusNotFound).Body.Close()
+
+	m.disabled = true
+	do(http.MethodGet, "/subscription", "", http.StatusNotFound).Body.Close()
+}
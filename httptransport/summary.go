@@ -0,0 +1,180 @@
+package httptransport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher/trend"
+)
+
+const (
+	// DefaultSummaryTop is the number of vulnerabilities listed in a summary
+	// when the "top" query parameter isn't provided.
+	defaultSummaryTop = 5
+	// MaxSummaryTop is the most vulnerabilities a summary will list.
+	maxSummaryTop = 50
+)
+
+// VulnerabilitySummary is the body of a vulnerability summary response: the
+// parts of a vulnerability report a listing needs, reduced server-side.
+type vulnerabilitySummary struct {
+	Manifest claircore.Digest `json:"manifest_hash"`
+	Counts   trend.Counts     `json:"counts"`
+	Total    int              `json:"total"`
+	// Fixable counts the vulnerabilities with a fixed version, by severity.
+	Fixable      trend.Counts `json:"fixable"`
+	FixableTotal int          `json:"fixable_total"`
+	// AffectedPackages is the number of packages with any vulnerability.
+	AffectedPackages int              `json:"affected_packages"`
+	Top              []summaryFinding `json:"top"`
+}
+
+// SummaryFinding is one of the most severe vulnerabilities in a summary.
+type summaryFinding struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Severity       string `json:"normalized_severity"`
+	Package        string `json:"package,omitempty"`
+	FixedInVersion string `json:"fixed_in_version,omitempty"`
+}
+
+// Summarize reduces "vr" to a summary listing at most "top" vulnerabilities.
+//
+// The listed vulnerabilities are the most severe, preferring ones with a fix
+// available, and each name is listed once even if it's reported against
+// multiple packages.
+func summarize(vr *claircore.VulnerabilityReport, top int) *vulnerabilitySummary {
+	s := vulnerabilitySummary{
+		Manifest: vr.Hash,
+		Counts:   trend.Summarize(vr),
+		Top:      []summaryFinding{},
+	}
+	s.Total = s.Counts.Total()
+	vs := make([]*claircore.Vulnerability, 0, len(vr.Vulnerabilities))
+	fixable := claircore.VulnerabilityReport{
+		Vulnerabilities: make(map[string]*claircore.Vulnerability),
+	}
+	for id, v := range vr.Vulnerabilities {
+		vs = append(vs, v)
+		if v.FixedInVersion != "" {
+			fixable.Vulnerabilities[id] = v
+		}
+	}
+	s.Fixable = trend.Summarize(&fixable)
+	s.FixableTotal = s.Fixable.Total()
+	for _, ids := range vr.PackageVulnerabilities {
+		if len(ids) != 0 {
+			s.AffectedPackages++
+		}
+	}
+
+	sort.Slice(vs, func(i, j int) bool {
+		a, b := vs[i], vs[j]
+		switch {
+		case a.NormalizedSeverity != b.NormalizedSeverity:
+			return a.NormalizedSeverity > b.NormalizedSeverity
+		case (a.FixedInVersion != "") != (b.FixedInVersion != ""):
+			return a.FixedInVersion != ""
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	seen := make(map[string]struct{}, top)
+	for _, v := range vs {
+		if len(s.Top) == top {
+			break
+		}
+		if _, ok := seen[v.Name]; ok {
+			continue
+		}
+		seen[v.Name] = struct{}{}
+		f := summaryFinding{
+			ID:             v.ID,
+			Name:           v.Name,
+			Severity:       v.NormalizedSeverity.String(),
+			FixedInVersion: v.FixedInVersion,
+		}
+		if v.Package != nil {
+			f.Package = v.Package.Name
+		}
+		s.Top = append(s.Top, f)
+	}
+	return &s
+}
+
+// VulnerabilitySummary reports the severity counts, fixability, and most
+// severe vulnerabilities for a manifest, so that clients listing many
+// manifests don't need to fetch and reduce full vulnerability reports.
+//
+// The optional "top" query parameter sets how many vulnerabilities are
+// listed. The report filter parameters are also accepted. The response has an
+// ETag derived from its contents, so clients can poll with conditional
+// requests.
+func (h *MatcherV1) vulnerabilitySummary(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnerabilitySummary")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	manifest, err := claircore.ParseDigest(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	q := r.URL.Query()
+	top := defaultSummaryTop
+	if s := q.Get("top"); s != "" {
+		top, err = strconv.Atoi(s)
+		if err != nil || top < 0 {
+			apiError(ctx, w, http.StatusBadRequest, "could not parse %q query param: %q", "top", s)
+			return
+		}
+		if top > maxSummaryTop {
+			top = maxSummaryTop
+		}
+	}
+	filter, err := parseReportFilter(q)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	vr, ok := h.buildReport(ctx, w, manifest)
+	if !ok {
+		return
+	}
+	if filter != nil {
+		vr = filter.apply(vr)
+	}
+
+	var buf bytes.Buffer
+	enc := codec.GetEncoder(&buf)
+	err = enc.Encode(summarize(vr, top))
+	codec.PutEncoder(enc)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not encode summary: %v", err)
+		return
+	}
+	validator := fmt.Sprintf(`"%x"`, sha256.Sum256(buf.Bytes()))
+	setCacheControl(w, h.Cache)
+	w.Header().Set("etag", validator)
+	if unmodified(r, validator) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	_, err = buf.WriteTo(w)
+}
@@ -0,0 +1,138 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/trend"
+)
+
+// SummaryReport returns a report for "d" with the same CVE reported against
+// two packages, so summaries should list it once.
+func summaryReport(d claircore.Digest) *claircore.VulnerabilityReport {
+	openssl := &claircore.Package{ID: "1", Name: "openssl"}
+	libssl := &claircore.Package{ID: "2", Name: "libssl"}
+	zlib := &claircore.Package{ID: "3", Name: "zlib"}
+	return &claircore.VulnerabilityReport{
+		Hash: d,
+		Packages: map[string]*claircore.Package{
+			"1": openssl, "2": libssl, "3": zlib,
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"a": {ID: "a", Name: "CVE-2024-0001", NormalizedSeverity: claircore.Critical, Package: openssl},
+			"b": {ID: "b", Name: "CVE-2024-0001", NormalizedSeverity: claircore.Critical, Package: libssl},
+			"c": {ID: "c", Name: "CVE-2024-0002", NormalizedSeverity: claircore.Critical, Package: zlib, FixedInVersion: "1.3"},
+			"d": {ID: "d", Name: "CVE-2024-0003", NormalizedSeverity: claircore.Low, Package: zlib, FixedInVersion: "1.3"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"a"}, "2": {"b"}, "3": {"c", "d"},
+		},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	d := claircore.MustParseDigest(`sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`)
+	got := summarize(summaryReport(d), 5)
+	want := &vulnerabilitySummary{
+		Manifest:         d,
+		Counts:           trend.Counts{Low: 1, Critical: 3},
+		Total:            4,
+		Fixable:          trend.Counts{Low: 1, Critical: 1},
+		FixableTotal:     2,
+		AffectedPackages: 3,
+		Top: []summaryFinding{
+			{ID: "c", Name: "CVE-2024-0002", Severity: "Critical", Package: "zlib", FixedInVersion: "1.3"},
+			{ID: "a", Name: "CVE-2024-0001", Severity: "Critical", Package: "openssl"},
+			{ID: "d", Name: "CVE-2024-0003", Severity: "Low", Package: "zlib", FixedInVersion: "1.3"},
+		},
+	}
+	digestCmp := cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+	if !cmp.Equal(got, want, digestCmp) {
+		t.Error(cmp.Diff(got, want, digestCmp))
+	}
+
+	if got := summarize(summaryReport(d), 1); len(got.Top) != 1 || got.Top[0].ID != "c" {
+		t.Errorf("top 1: got: %+v", got.Top)
+	}
+	if got := summarize(summaryReport(d), 0); got.Top == nil || len(got.Top) != 0 {
+		t.Errorf("top 0: got: %#v", got.Top)
+	}
+}
+
+func TestVulnerabilitySummary(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = `sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return summaryReport(ir.Hash), nil
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(path, etag string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag != "" {
+			req.Header.Set("if-none-match", etag)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s: got: %d, want: %d", path, got, want)
+		}
+		return res
+	}
+
+	do("/vulnerability_summary/sha256:bad", "", http.StatusBadRequest)
+	do("/vulnerability_summary/"+digest+"?top=-1", "", http.StatusBadRequest)
+	do("/vulnerability_summary/"+digest+"?severity=bogus", "", http.StatusBadRequest)
+
+	res := do("/vulnerability_summary/"+digest+"?top=2", "", http.StatusOK)
+	var got vulnerabilitySummary
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Total != 4 || got.FixableTotal != 2 || len(got.Top) != 2 {
+		t.Errorf("got: %+v", got)
+	}
+	etag := res.Header.Get("etag")
+	if etag == "" {
+		t.Fatal("missing etag")
+	}
+	if res.Header.Get("cache-control") == "" {
+		t.Error("missing cache-control")
+	}
+	do("/vulnerability_summary/"+digest+"?top=2", etag, http.StatusNotModified)
+	// A different summary has a different validator.
+	do("/vulnerability_summary/"+digest+"?fixed=true", etag, http.StatusOK)
+}
@@ -0,0 +1,100 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+var timedOutCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: metricSubsystem,
+		Name:      "timedout_total",
+		Help:      "Total number of requests that ran out of time.",
+	},
+	[]string{"endpoint", "method"},
+)
+
+// RequestTimeouts returns an http.Handler wrapping "next" that bounds each
+// request by the timeout configured for its path.
+//
+// Handlers see the deadline on the request's Context, and everything that
+// respects the Context is canceled along with it: database queries are
+// canceled on the server and layer fetches are aborted.
+func requestTimeouts(cfg *config.RequestTimeouts, next http.Handler) http.Handler {
+	h := &timeoutHandler{
+		next: next,
+		def:  time.Duration(cfg.Default),
+	}
+	for p, d := range cfg.Endpoints {
+		h.endpoints = append(h.endpoints, timeoutEndpoint{prefix: p, timeout: time.Duration(d)})
+	}
+	// Longest prefix first, so the first match is the most specific.
+	sort.Slice(h.endpoints, func(i, j int) bool {
+		return len(h.endpoints[i].prefix) > len(h.endpoints[j].prefix)
+	})
+	return h
+}
+
+// TimeoutHandler is the handler returned by requestTimeouts.
+type timeoutHandler struct {
+	next      http.Handler
+	def       time.Duration
+	endpoints []timeoutEndpoint
+}
+
+type timeoutEndpoint struct {
+	prefix  string
+	timeout time.Duration
+}
+
+type timeoutKey struct{}
+
+// Timeout returns the timeout for requests to "p", and the prefix it was
+// configured for. A non-positive timeout means no timeout.
+func (h *timeoutHandler) timeout(p string) (time.Duration, string) {
+	for _, e := range h.endpoints {
+		if strings.HasPrefix(p, e.prefix) {
+			return e.timeout, e.prefix
+		}
+	}
+	return h.def, "default"
+}
+
+// ServeHTTP implements http.Handler.
+func (h *timeoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d, endpt := h.timeout(r.URL.Path)
+	if d <= 0 {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	ctx = context.WithValue(ctx, timeoutKey{}, d)
+	h.next.ServeHTTP(w, r.WithContext(ctx))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		timedOutCounter.WithLabelValues(endpt, r.Method).Add(1)
+		ctx = zlog.ContextWithValues(r.Context(), "component", "httptransport/timeoutHandler.ServeHTTP")
+		zlog.Info(ctx).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Dur("timeout", d).
+			Msg("request timed out")
+	}
+}
+
+// TimedOut reports whether the request "ctx" belongs to ran out of its
+// configured time.
+func timedOut(ctx context.Context) bool {
+	_, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	return ok && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
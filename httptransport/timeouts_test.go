@@ -0,0 +1,70 @@
+package httptransport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+func TestRequestTimeouts(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	cfg := &config.RequestTimeouts{
+		Default: config.Duration(time.Hour),
+		Endpoints: map[string]config.Duration{
+			"/matcher/":                   config.Duration(10 * time.Millisecond),
+			"/matcher/api/v1/unbounded/":  config.Duration(-1),
+			"/matcher/api/v1/long_query/": config.Duration(time.Minute),
+		},
+	}
+	// The handler reports its deadline, or waits for it and fails the way a
+	// canceled query would.
+	h := requestTimeouts(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		dl, ok := ctx.Deadline()
+		if r.URL.Query().Get("wait") == "" {
+			if !ok {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("x-timeout", time.Until(dl).Round(time.Minute).String())
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		<-ctx.Done()
+		apiError(ctx, w, http.StatusInternalServerError, "query failed: %v", ctx.Err())
+	}))
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	tt := []struct {
+		Path    string
+		Code    int
+		Timeout string
+	}{
+		{Path: "/matcher/api/v1/vulnerability_report/x?wait=1", Code: http.StatusGatewayTimeout},
+		{Path: "/matcher/api/v1/unbounded/x", Code: http.StatusNoContent},
+		{Path: "/matcher/api/v1/long_query/x", Code: http.StatusOK, Timeout: "1m0s"},
+		{Path: "/indexer/api/v1/index_report", Code: http.StatusOK, Timeout: "1h0m0s"},
+	}
+	for _, tc := range tt {
+		res, err := srv.Client().Get(srv.URL + tc.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, tc.Code; got != want {
+			t.Errorf("%s: got: %d, want: %d", tc.Path, got, want)
+		}
+		if got, want := res.Header.Get("x-timeout"), tc.Timeout; got != want {
+			t.Errorf("%s: timeout: got: %q, want: %q", tc.Path, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/trend"
+)
+
+// TrendResponse is the body of a vulnerability trend response.
+type trendResponse struct {
+	Manifest claircore.Digest `json:"manifest_hash"`
+	Points   []trend.Point    `json:"points"`
+}
+
+// VulnerabilityTrend reports the finding counts recorded for a manifest,
+// limited by the optional "since" and "until" query parameters.
+func (h *MatcherV1) vulnerabilityTrend(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnerabilityTrend")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	t, ok := h.srv.(matcher.Trends)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "vulnerability trends not supported")
+		return
+	}
+	manifest, err := claircore.ParseDigest(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+	var since, until time.Time
+	q := r.URL.Query()
+	if s := q.Get("since"); s != "" {
+		since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "malformed since: %v", err)
+			return
+		}
+	}
+	if s := q.Get("until"); s != "" {
+		until, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "malformed until: %v", err)
+			return
+		}
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		apiError(ctx, w, http.StatusBadRequest, "until is before since")
+		return
+	}
+
+	ps, err := t.Trend(ctx, manifest, since, until)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, trend.ErrDisabled):
+		apiError(ctx, w, http.StatusNotFound, "%v", err)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not get vulnerability trend: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(&trendResponse{Manifest: manifest, Points: ps})
+}
@@ -0,0 +1,95 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/trend"
+)
+
+type trendMock struct {
+	*matcher.Mock
+	disabled     bool
+	since, until time.Time
+}
+
+func (m *trendMock) Trend(_ context.Context, _ claircore.Digest, since, until time.Time) ([]trend.Point, error) {
+	if m.disabled {
+		return nil, trend.ErrDisabled
+	}
+	m.since, m.until = since, until
+	return []trend.Point{
+		{Cursor: uuid.New(), Counts: trend.Counts{High: 2}, Total: 2},
+		{Cursor: uuid.New(), Counts: trend.Counts{High: 1}, Total: 1},
+	}, nil
+}
+
+func TestVulnerabilityTrend(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = `sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`
+	run := func(t *testing.T, svc matcher.Service) func(string, int) *http.Response {
+		v1 := NewMatcherV1(ctx, "", svc, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(path string, want int) *http.Response {
+			t.Helper()
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { res.Body.Close() })
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s: got: %d, want: %d", path, got, want)
+			}
+			return res
+		}
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		do := run(t, &matcher.Mock{})
+		do("/vulnerability_trend/"+digest, http.StatusNotFound)
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		do := run(t, &trendMock{Mock: &matcher.Mock{}, disabled: true})
+		do("/vulnerability_trend/"+digest, http.StatusNotFound)
+	})
+	t.Run("Trend", func(t *testing.T) {
+		m := &trendMock{Mock: &matcher.Mock{}}
+		do := run(t, m)
+		do("/vulnerability_trend/sha256:bad", http.StatusBadRequest)
+		do("/vulnerability_trend/"+digest+"?since=yesterday", http.StatusBadRequest)
+		do("/vulnerability_trend/"+digest+"?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z", http.StatusBadRequest)
+
+		res := do("/vulnerability_trend/"+digest+"?since=2024-01-01T00:00:00Z", http.StatusOK)
+		var got trendResponse
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Manifest.String() != digest || len(got.Points) != 2 || got.Points[1].Total != 1 {
+			t.Errorf("got: %+v", got)
+		}
+		if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !m.since.Equal(want) || !m.until.IsZero() {
+			t.Errorf("bounds: got: (%v, %v)", m.since, m.until)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/triage"
+)
+
+// AnnotationsFor returns the triage annotations applying to the findings in
+// "vr", keyed by vulnerability ID. Errors are logged and otherwise ignored, so
+// that reports are still served if annotations are unavailable.
+func annotationsFor(ctx context.Context, srv matcher.Service, vr *claircore.VulnerabilityReport) map[string]triage.Annotation {
+	t, ok := srv.(matcher.Triage)
+	if !ok {
+		return nil
+	}
+	as, err := t.Annotations(ctx, vr.Hash)
+	if err != nil {
+		zlog.Warn(ctx).Err(err).Stringer("manifest", vr.Hash).Msg("unable to look up annotations")
+		return nil
+	}
+	return triage.Apply(vr, as, time.Now())
+}
+
+// AnnotationHandler serves the triage annotations on a manifest: GET lists
+// them, PUT creates or replaces one, and DELETE removes the one named by the
+// "vulnerability" and "package" query parameters.
+func (h *MatcherV1) annotationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.annotationHandler")
+	switch r.Method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	t, ok := h.srv.(matcher.Triage)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "triage annotations not supported")
+		return
+	}
+	manifest, err := claircore.ParseDigest(path.Base(r.URL.Path))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: %v", err)
+		return
+	}
+
+	var out interface{}
+	switch r.Method {
+	case http.MethodGet:
+		as, err := t.Annotations(ctx, manifest)
+		if err != nil {
+			apiError(ctx, w, http.StatusInternalServerError, "could not list annotations: %v", err)
+			return
+		}
+		out = as
+		w.Header().Set("content-type", "application/json")
+	case http.MethodPut:
+		a := new(triage.Annotation)
+		dec := codec.GetDecoder(r.Body)
+		err := dec.Decode(a)
+		codec.PutDecoder(dec)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not deserialize annotation: %v", err)
+			return
+		}
+		created, err := t.PutAnnotation(ctx, manifest, a)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, triage.ErrInvalid):
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
+			return
+		default:
+			apiError(ctx, w, http.StatusInternalServerError, "could not store annotation: %v", err)
+			return
+		}
+		out = a
+		w.Header().Set("content-type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodDelete:
+		q := r.URL.Query()
+		vuln := strings.TrimSpace(q.Get("vulnerability"))
+		if vuln == "" {
+			apiError(ctx, w, http.StatusBadRequest, `missing "vulnerability" query param`)
+			return
+		}
+		ok, err := t.DeleteAnnotation(ctx, manifest, vuln, q.Get("package"))
+		switch {
+		case err != nil:
+			apiError(ctx, w, http.StatusInternalServerError, "could not delete annotation: %v", err)
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "annotation for %q not found", vuln)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(out)
+}
@@ -0,0 +1,141 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/triage"
+)
+
+type triageMock struct {
+	*matcher.Mock
+	m map[string]triage.Annotation
+}
+
+func (t *triageMock) Annotations(context.Context, claircore.Digest) ([]triage.Annotation, error) {
+	out := []triage.Annotation{}
+	for _, a := range t.m {
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (t *triageMock) PutAnnotation(_ context.Context, _ claircore.Digest, a *triage.Annotation) (bool, error) {
+	if err := a.Validate(time.Now()); err != nil {
+		return false, err
+	}
+	k := strings.ToLower(a.Vulnerability) + "/" + a.Package
+	_, ok := t.m[k]
+	a.Updated = time.Now()
+	t.m[k] = *a
+	return !ok, nil
+}
+
+func (t *triageMock) DeleteAnnotation(_ context.Context, _ claircore.Digest, vuln, pkg string) (bool, error) {
+	k := strings.ToLower(vuln) + "/" + pkg
+	_, ok := t.m[k]
+	delete(t.m, k)
+	return ok, nil
+}
+
+func TestAnnotationHandler(t *testing.T) {
+	ctx := context.Background()
+	ctx = zlog.Test(ctx, t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(digest),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+		},
+		Success: true,
+	}
+	i := &indexer.Mock{
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return ir, true, nil
+		},
+	}
+	m := &triageMock{
+		Mock: &matcher.Mock{
+			Initialized_: func(context.Context) (bool, error) { return true, nil },
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{
+					Hash:     ir.Hash,
+					Packages: ir.Packages,
+					Vulnerabilities: map[string]*claircore.Vulnerability{
+						"1": {ID: "1", Name: "CVE-2023-0001", NormalizedSeverity: claircore.Critical},
+					},
+					PackageVulnerabilities: map[string][]string{"1": {"1"}},
+				}, nil
+			},
+		},
+		m: make(map[string]triage.Annotation),
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	do := func(method, path, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+		}
+		return res
+	}
+	report := func() map[string]triage.Annotation {
+		t.Helper()
+		res := do(http.MethodGet, "/vulnerability_report/"+digest, "", http.StatusOK)
+		defer res.Body.Close()
+		var got struct {
+			Annotations map[string]triage.Annotation `json:"annotations"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Annotations
+	}
+
+	do(http.MethodPut, "/annotation/bad", `{}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/annotation/"+digest, `{"vulnerability":"CVE-2023-0001","state":"ignored"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/annotation/"+digest, `{"vulnerability":"CVE-2023-0001","state":"risk_accepted"}`, http.StatusBadRequest).Body.Close()
+	do(http.MethodPut, "/annotation/"+digest, `{"vulnerability":"CVE-2023-0001","state":"acknowledged"}`, http.StatusCreated).Body.Close()
+	if got := report(); got["1"].State != triage.Acknowledged {
+		t.Errorf("report: got: %+v", got)
+	}
+	exp := time.Now().Add(time.Hour).Format(time.RFC3339)
+	do(http.MethodPut, "/annotation/"+digest, `{"vulnerability":"CVE-2023-0001","state":"risk_accepted","expires":"`+exp+`"}`, http.StatusOK).Body.Close()
+	if got := report(); got["1"].State != triage.RiskAccepted {
+		t.Errorf("report: got: %+v", got)
+	}
+	do(http.MethodGet, "/annotation/"+digest, "", http.StatusOK).Body.Close()
+
+	do(http.MethodDelete, "/annotation/"+digest, "", http.StatusBadRequest).Body.Close()
+	do(http.MethodDelete, "/annotation/"+digest+"?vulnerability=CVE-2023-0001", "", http.StatusNoContent).Body.Close()
+	do(http.MethodDelete, "/annotation/"+digest+"?vulnerability=CVE-2023-0001", "", http.StatusNotFound).Body.Close()
+	if got := report(); len(got) != 0 {
+		t.Errorf("report: got: %+v", got)
+	}
+}
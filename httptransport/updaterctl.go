@@ -0,0 +1,119 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/updaterctl"
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// DisabledUpdaters lists the updaters disabled at runtime.
+func (h *MatcherV1) disabledUpdaters(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.disabledUpdaters")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	uc, ok := h.srv.(matcher.UpdaterControl)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "updater control not supported")
+		return
+	}
+	ds, err := uc.DisabledUpdaters(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not list disabled updaters: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(ds)
+}
+
+// DisabledUpdaterHandler disables (PUT) or re-enables (DELETE) the updater
+// named by the rest of the path. Updater names may contain slashes.
+func (h *MatcherV1) disabledUpdaterHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.disabledUpdaterHandler")
+	switch r.Method {
+	case http.MethodPut, http.MethodDelete:
+	default:
+		apiError(ctx, w, http.StatusMethodNotAllowed, "method disallowed: %s", r.Method)
+		return
+	}
+	uc, ok := h.srv.(matcher.UpdaterControl)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "updater control not supported")
+		return
+	}
+	_, name, _ := strings.Cut(r.URL.Path, "/internal/disabled_updaters/")
+	name = path.Clean("/" + name)[1:]
+	if name == "" {
+		apiError(ctx, w, http.StatusBadRequest, "malformed path: missing updater name")
+		return
+	}
+	ctx = zlog.ContextWithValues(ctx, "updater", name)
+
+	if r.Method == http.MethodDelete {
+		ok, err := uc.EnableUpdater(ctx, name)
+		switch {
+		case err != nil:
+			apiError(ctx, w, http.StatusInternalServerError, "could not enable updater: %v", err)
+		case !ok:
+			apiError(ctx, w, http.StatusNotFound, "updater %q not disabled", name)
+		default:
+			zlog.Info(ctx).Msg("updater enabled")
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	d := new(updaterctl.Disabled)
+	if r.ContentLength != 0 {
+		dec := codec.GetDecoder(r.Body)
+		err := dec.Decode(d)
+		codec.PutDecoder(dec)
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not deserialize request: %v", err)
+			return
+		}
+	}
+	d.Updater = name
+	d.By = ""
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		d.By = id.Subject
+	}
+	created, err := uc.DisableUpdater(ctx, d)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, updaterctl.ErrInvalid):
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not disable updater: %v", err)
+		return
+	}
+	zlog.Info(ctx).
+		Str("reason", d.Reason).
+		Str("by", d.By).
+		Msg("updater disabled")
+
+	w.Header().Set("content-type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(d)
+}
@@ -0,0 +1,132 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/updaterctl"
+)
+
+type updaterctlMock struct {
+	*matcher.Mock
+	mu       sync.Mutex
+	disabled map[string]updaterctl.Disabled
+}
+
+func (m *updaterctlMock) DisabledUpdaters(context.Context) ([]updaterctl.Disabled, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []updaterctl.Disabled{}
+	for _, d := range m.disabled {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Updater < out[j].Updater })
+	return out, nil
+}
+
+func (m *updaterctlMock) DisableUpdater(_ context.Context, d *updaterctl.Disabled) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exists := m.disabled[d.Updater]
+	d.Since = time.Unix(0, 0).UTC()
+	m.disabled[d.Updater] = *d
+	return !exists, nil
+}
+
+func (m *updaterctlMock) EnableUpdater(_ context.Context, name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exists := m.disabled[name]
+	delete(m.disabled, name)
+	return exists, nil
+}
+
+func TestUpdaterControl(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	run := func(t *testing.T, svc matcher.Service) func(string, string, string, int) *http.Response {
+		v1 := NewMatcherV1(ctx, "", svc, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(method, path, body string, want int) *http.Response {
+			t.Helper()
+			var rd io.Reader
+			if body != "" {
+				rd = strings.NewReader(body)
+			}
+			req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, rd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+			}
+			return res
+		}
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		do := run(t, &matcher.Mock{})
+		do(http.MethodGet, "/internal/disabled_updaters", "", http.StatusNotFound).Body.Close()
+		do(http.MethodPut, "/internal/disabled_updaters/alpine", "", http.StatusNotFound).Body.Close()
+	})
+	t.Run("Control", func(t *testing.T) {
+		m := &updaterctlMock{
+			Mock:     &matcher.Mock{},
+			disabled: make(map[string]updaterctl.Disabled),
+		}
+		do := run(t, m)
+		do(http.MethodPost, "/internal/disabled_updaters", "", http.StatusMethodNotAllowed).Body.Close()
+		do(http.MethodGet, "/internal/disabled_updaters/osv/npm", "", http.StatusMethodNotAllowed).Body.Close()
+		do(http.MethodPut, "/internal/disabled_updaters/", "", http.StatusBadRequest).Body.Close()
+		do(http.MethodPut, "/internal/disabled_updaters/osv/npm", "{", http.StatusBadRequest).Body.Close()
+
+		do(http.MethodPut, "/internal/disabled_updaters/osv/npm", `{"reason":"feed truncated"}`, http.StatusCreated).Body.Close()
+		do(http.MethodPut, "/internal/disabled_updaters/alpine", "", http.StatusCreated).Body.Close()
+		res := do(http.MethodPut, "/internal/disabled_updaters/osv/npm", `{"reason":"still truncated","disabled_by":"nobody"}`, http.StatusOK)
+		var d updaterctl.Disabled
+		err := json.NewDecoder(res.Body).Decode(&d)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Updater != "osv/npm" || d.Reason != "still truncated" || d.By != "" {
+			t.Errorf("disable: got: %+v", d)
+		}
+
+		res = do(http.MethodGet, "/internal/disabled_updaters", "", http.StatusOK)
+		var ds []updaterctl.Disabled
+		err = json.NewDecoder(res.Body).Decode(&ds)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ds) != 2 || ds[0].Updater != "alpine" || ds[1].Updater != "osv/npm" {
+			t.Errorf("list: got: %+v", ds)
+		}
+
+		do(http.MethodDelete, "/internal/disabled_updaters/osv/npm", "", http.StatusNoContent).Body.Close()
+		do(http.MethodDelete, "/internal/disabled_updaters/osv/npm", "", http.StatusNotFound).Body.Close()
+	})
+}
@@ -0,0 +1,159 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/usage"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Usage reports the indexer's storage usage, counting manifests by the value
+// of the label named in the optional "tenant_label" query parameter.
+func (h *IndexerV1) usage(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/IndexerV1.usage")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	u, ok := h.srv.(indexer.UsageReporter)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "usage statistics not supported")
+		return
+	}
+	res, err := u.Usage(ctx, r.URL.Query().Get("tenant_label"))
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		apiError(ctx, w, http.StatusNotFound, "usage statistics not supported")
+		return
+	case errors.Is(err, usage.ErrNoLabels):
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not get usage statistics: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
+
+// These bound the pages returned by the manifest listing endpoint.
+const (
+	defaultManifestPage = 100
+	maxManifestPage     = 1000
+)
+
+// Manifests lists a page of the indexed manifests, in digest order, starting
+// after the digest in the optional "after" query parameter. The optional
+// "limit" parameter sets the page size.
+func (h *IndexerV1) manifests(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/IndexerV1.manifests")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	l, ok := h.srv.(indexer.ManifestLister)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "manifest listing not supported")
+		return
+	}
+	q := r.URL.Query()
+	limit := defaultManifestPage
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apiError(ctx, w, http.StatusBadRequest, "bad limit: %q", v)
+			return
+		}
+		if n > maxManifestPage {
+			n = maxManifestPage
+		}
+		limit = n
+	}
+	ms, err := l.Manifests(ctx, q.Get("after"), limit)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, indexer.ErrUnsupported):
+		apiError(ctx, w, http.StatusNotFound, "manifest listing not supported")
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not list manifests: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(struct {
+		Manifests []claircore.Digest `json:"manifests"`
+	}{
+		Manifests: ms,
+	})
+}
+
+// Usage reports the matcher's storage usage.
+func (h *MatcherV1) usage(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.usage")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	u, ok := h.srv.(matcher.UsageReporter)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "usage statistics not supported")
+		return
+	}
+	res, err := u.Usage(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not get usage statistics: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
+
+// Usage reports the notifier's storage usage.
+func (h *NotificationV1) usage(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/NotificationV1.usage")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	u, ok := h.serv.(notifier.UsageReporter)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "usage statistics not supported")
+		return
+	}
+	res, err := u.Usage(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not get usage statistics: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
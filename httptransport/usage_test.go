@@ -0,0 +1,154 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/usage"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+type usageMock struct {
+	*indexer.Mock
+}
+
+func (usageMock) Usage(_ context.Context, label string) (*indexer.Usage, error) {
+	u := indexer.Usage{Manifests: 3, Layers: 5, Packages: 100, DatabaseBytes: 4096}
+	switch label {
+	case "":
+	case "team":
+		u.Tenants = map[string]int64{"payments": 2, "": 1}
+	default:
+		return nil, usage.ErrNoLabels
+	}
+	return &u, nil
+}
+
+type manifestsMock struct {
+	*indexer.Mock
+	after string
+	limit int
+}
+
+// Manifests lists "limit" copies of a digest, recording the request.
+func (m *manifestsMock) Manifests(_ context.Context, after string, limit int) ([]claircore.Digest, error) {
+	m.after, m.limit = after, limit
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	out := make([]claircore.Digest, limit)
+	for i := range out {
+		out[i] = d
+	}
+	return out, nil
+}
+
+func TestIndexerUsage(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	run := func(t *testing.T, svc indexer.Service) func(string, string, int) *http.Response {
+		v1, err := NewIndexerV1(ctx, "", svc, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(method, path string, want int) *http.Response {
+			t.Helper()
+			req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+			}
+			return res
+		}
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		do := run(t, &indexer.Mock{})
+		do(http.MethodGet, "/internal/usage", http.StatusNotFound).Body.Close()
+	})
+	t.Run("Supported", func(t *testing.T) {
+		do := run(t, usageMock{Mock: &indexer.Mock{}})
+		do(http.MethodPost, "/internal/usage", http.StatusMethodNotAllowed).Body.Close()
+		do(http.MethodGet, "/internal/usage?tenant_label=bad", http.StatusBadRequest).Body.Close()
+
+		res := do(http.MethodGet, "/internal/usage?tenant_label=team", http.StatusOK)
+		defer res.Body.Close()
+		var got indexer.Usage
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := indexer.Usage{
+			Manifests:     3,
+			Layers:        5,
+			Packages:      100,
+			DatabaseBytes: 4096,
+			Tenants:       map[string]int64{"payments": 2, "": 1},
+		}
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+}
+
+func TestIndexerManifests(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := &manifestsMock{Mock: &indexer.Mock{}}
+	v1, err := NewIndexerV1(ctx, "", m, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string, want int) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		v1.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+		if got := rec.Code; got != want {
+			t.Errorf("%s: got: %d, want: %d", path, got, want)
+		}
+		return rec
+	}
+
+	get("/internal/manifests?limit=0", http.StatusBadRequest)
+	rec := get("/internal/manifests?after=sha256:01&limit=2", http.StatusOK)
+	var got struct {
+		Manifests []claircore.Digest `json:"manifests"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Manifests) != 2 || m.after != "sha256:01" {
+		t.Errorf("got: %v, after: %q", got.Manifests, m.after)
+	}
+	get("/internal/manifests", http.StatusOK)
+	if got, want := m.limit, defaultManifestPage; got != want {
+		t.Errorf("default limit: got: %d, want: %d", got, want)
+	}
+	get("/internal/manifests?limit=100000", http.StatusOK)
+	if got, want := m.limit, maxManifestPage; got != want {
+		t.Errorf("max limit: got: %d, want: %d", got, want)
+	}
+
+	v1, err = NewIndexerV1(ctx, "", &indexer.Mock{}, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get("/internal/manifests", http.StatusNotFound)
+}
@@ -0,0 +1,102 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/vulnstats"
+)
+
+// VulnerabilityStats reports the size of each updater's most recent update
+// operations.
+func (h *MatcherV1) vulnerabilityStats(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnerabilityStats")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	vs, ok := h.srv.(matcher.VulnerabilityStats)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "vulnerability statistics not supported")
+		return
+	}
+	res, err := vs.VulnerabilityStats(ctx)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not get vulnerability statistics: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
+
+// VulnerabilityDiff reports the advisories that changed between the update
+// operations named by the "prev" and "cur" query parameters. The "prev"
+// parameter is optional, as is "limit", which caps the number of advisory
+// names listed per kind of change.
+func (h *MatcherV1) vulnerabilityDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnerabilityDiff")
+	if r.Method != http.MethodGet {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows GET")
+		return
+	}
+	vs, ok := h.srv.(matcher.VulnerabilityStats)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "vulnerability statistics not supported")
+		return
+	}
+	q := r.URL.Query()
+	var prev, cur uuid.UUID
+	var err error
+	if p := q.Get("prev"); p != "" {
+		if prev, err = uuid.Parse(p); err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "could not parse \"prev\" query param into uuid")
+			return
+		}
+	}
+	if p := q.Get("cur"); p == "" {
+		apiError(ctx, w, http.StatusBadRequest, "\"cur\" query param is required")
+		return
+	} else if cur, err = uuid.Parse(p); err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "could not parse \"cur\" query param into uuid")
+		return
+	}
+	limit := vulnstats.DefaultLimit
+	if p := q.Get("limit"); p != "" {
+		if limit, err = strconv.Atoi(p); err != nil || limit < 1 {
+			apiError(ctx, w, http.StatusBadRequest, "\"limit\" query param must be a positive integer")
+			return
+		}
+	}
+
+	res, err := vs.VulnerabilityDiff(ctx, prev, cur, limit)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, vulnstats.ErrNotFound):
+		apiError(ctx, w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, vulnstats.ErrMismatch):
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		apiError(ctx, w, http.StatusInternalServerError, "could not compare update operations: %v", err)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(res)
+}
@@ -0,0 +1,128 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/vulnstats"
+)
+
+type vulnstatsMock struct {
+	*matcher.Mock
+	prev, cur, other uuid.UUID
+}
+
+func (m *vulnstatsMock) VulnerabilityStats(context.Context) ([]vulnstats.Source, error) {
+	return []vulnstats.Source{
+		{
+			Updater:  "alpine",
+			Latest:   vulnstats.Snapshot{Ref: m.cur, Vulnerabilities: 10, Advisories: 4},
+			Previous: &vulnstats.Snapshot{Ref: m.prev, Vulnerabilities: 20, Advisories: 8},
+		},
+	}, nil
+}
+
+func (m *vulnstatsMock) VulnerabilityDiff(_ context.Context, prev, cur uuid.UUID, limit int) (*vulnstats.Diff, error) {
+	switch {
+	case cur != m.cur:
+		return nil, fmt.Errorf("%w: %v", vulnstats.ErrNotFound, cur)
+	case prev == m.other:
+		return nil, vulnstats.ErrMismatch
+	}
+	d := vulnstats.Diff{
+		Updater: "alpine",
+		Prev:    vulnstats.Snapshot{Ref: m.prev, Vulnerabilities: 20, Advisories: 8},
+		Cur:     vulnstats.Snapshot{Ref: m.cur, Vulnerabilities: 10, Advisories: 4},
+		Removed: vulnstats.Changes{Count: 4, Advisories: []string{"CVE-1", "CVE-2", "CVE-3", "CVE-4"}},
+	}
+	if len(d.Removed.Advisories) > limit {
+		d.Removed.Advisories = d.Removed.Advisories[:limit]
+	}
+	return &d, nil
+}
+
+func TestVulnerabilityStats(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := &vulnstatsMock{
+		Mock:  &matcher.Mock{},
+		prev:  uuid.New(),
+		cur:   uuid.New(),
+		other: uuid.New(),
+	}
+	run := func(t *testing.T, svc matcher.Service) func(string, string, int) *http.Response {
+		v1 := NewMatcherV1(ctx, "", svc, &indexer.Mock{}, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+		srv := httptest.NewUnstartedServer(v1)
+		srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+		srv.Start()
+		t.Cleanup(srv.Close)
+		return func(method, path string, want int) *http.Response {
+			t.Helper()
+			req, err := httputil.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.StatusCode; got != want {
+				t.Errorf("%s %s: got: %d, want: %d", method, path, got, want)
+			}
+			return res
+		}
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		do := run(t, &matcher.Mock{})
+		do(http.MethodGet, "/internal/vulnerability_stats", http.StatusNotFound).Body.Close()
+		do(http.MethodGet, "/internal/vulnerability_diff?cur="+m.cur.String(), http.StatusNotFound).Body.Close()
+	})
+	t.Run("Stats", func(t *testing.T) {
+		do := run(t, m)
+		do(http.MethodPost, "/internal/vulnerability_stats", http.StatusMethodNotAllowed).Body.Close()
+		res := do(http.MethodGet, "/internal/vulnerability_stats", http.StatusOK)
+		defer res.Body.Close()
+		var got []vulnstats.Source
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := m.VulnerabilityStats(ctx)
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Diff", func(t *testing.T) {
+		do := run(t, m)
+		do(http.MethodGet, "/internal/vulnerability_diff", http.StatusBadRequest).Body.Close()
+		do(http.MethodGet, "/internal/vulnerability_diff?cur=bad", http.StatusBadRequest).Body.Close()
+		do(http.MethodGet, "/internal/vulnerability_diff?cur="+m.cur.String()+"&limit=0", http.StatusBadRequest).Body.Close()
+		do(http.MethodGet, "/internal/vulnerability_diff?cur="+uuid.NewString(), http.StatusNotFound).Body.Close()
+		do(http.MethodGet, "/internal/vulnerability_diff?cur="+m.cur.String()+"&prev="+m.other.String(), http.StatusBadRequest).Body.Close()
+
+		res := do(http.MethodGet, "/internal/vulnerability_diff?cur="+m.cur.String()+"&limit=2", http.StatusOK)
+		defer res.Body.Close()
+		var got vulnstats.Diff
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := vulnstats.Changes{Count: 4, Advisories: []string{"CVE-1", "CVE-2"}}
+		if !cmp.Equal(got.Removed, want) {
+			t.Error(cmp.Diff(got.Removed, want))
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// VulnstoreVulnerabilities answers a vulnerability store query for the
+// records in the request body, for matchers that don't have a database of
+// their own.
+func (h *MatcherV1) vulnstoreVulnerabilities(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnstoreVulnerabilities")
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	vs, ok := h.srv.(matcher.Vulnstore)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "vulnerability store queries not supported")
+		return
+	}
+	var req struct {
+		Records          []*claircore.IndexRecord `json:"records"`
+		Matchers         []driver.MatchConstraint `json:"matchers"`
+		VersionFiltering bool                     `json:"version_filtering"`
+	}
+	dec := codec.GetDecoder(r.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&req); err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "failed to deserialize query: %v", err)
+		return
+	}
+	if !pickVulnstoreType(ctx, w, r) {
+		return
+	}
+
+	res, err := vs.Vulnerabilities(ctx, req.Records, datastore.GetOpts{
+		Matchers:         req.Matchers,
+		VersionFiltering: req.VersionFiltering,
+	})
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not get vulnerabilities: %v", err)
+		return
+	}
+
+	defer writerError(w, &err)()
+	enc, put := responseEncoder(w)
+	defer put()
+	err = enc.Encode(res)
+}
+
+// VulnstoreEnrichments answers an enrichment query for the kind and tags in
+// the request body, for matchers that don't have a database of their own.
+func (h *MatcherV1) vulnstoreEnrichments(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.vulnstoreEnrichments")
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	vs, ok := h.srv.(matcher.Vulnstore)
+	if !ok {
+		apiError(ctx, w, http.StatusNotFound, "vulnerability store queries not supported")
+		return
+	}
+	var req struct {
+		Kind string   `json:"kind"`
+		Tags []string `json:"tags"`
+	}
+	dec := codec.GetDecoder(r.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(&req); err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "failed to deserialize query: %v", err)
+		return
+	}
+	if req.Kind == "" {
+		apiError(ctx, w, http.StatusBadRequest, "\"kind\" is required")
+		return
+	}
+	if !pickVulnstoreType(ctx, w, r) {
+		return
+	}
+
+	res, err := vs.Enrichments(ctx, req.Kind, req.Tags)
+	if err != nil {
+		apiError(ctx, w, http.StatusInternalServerError, "could not get enrichments: %v", err)
+		return
+	}
+
+	defer writerError(w, &err)()
+	enc, put := responseEncoder(w)
+	defer put()
+	err = enc.Encode(res)
+}
+
+// PickVulnstoreType negotiates the response type for a vulnerability store
+// query, writing an error and reporting false if there's no common type.
+func pickVulnstoreType(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
+	allow := []string{"application/json", codec.CBORMediaType}
+	switch err := pickContentType(w, r, allow); {
+	case errors.Is(err, nil): // OK
+	case errors.Is(err, ErrMediaType):
+		apiError(ctx, w, http.StatusUnsupportedMediaType, "unable to negotiate common media type for %v", allow)
+		return false
+	default:
+		apiError(ctx, w, http.StatusBadRequest, "malformed request: %v", err)
+		return false
+	}
+	return true
+}
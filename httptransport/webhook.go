@@ -0,0 +1,116 @@
+package httptransport
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer/webhook"
+)
+
+// WebhookV1Path is the root of the registry webhook endpoints, served on the
+// webhook listener. The last path element names the payload format.
+const WebhookV1Path = "/webhook/v1/"
+
+// MaxWebhookSize is the largest webhook payload accepted.
+const maxWebhookSize = 1 << 20
+
+// ConfigureWebhook sets up the registry webhook listener, if configured.
+func (t *Server) configureWebhook(ctx context.Context) {
+	cfg := t.conf.Indexer.Webhook
+	if cfg == nil || t.indexer == nil {
+		return
+	}
+	ing := webhook.New(ctx, t.indexer, &webhook.Options{
+		Registries:  cfg.Registries,
+		Concurrency: cfg.Concurrency,
+		QueueSize:   cfg.QueueSize,
+	})
+	mux := http.NewServeMux()
+	mux.Handle(WebhookV1Path, webhookHandler(ing, cfg.Secret))
+	var h http.Handler = mux
+	if t.conf.AccessLog != nil {
+		h = accessLog(t.conf.AccessLog, h)
+	}
+	t.Listeners = append(t.Listeners, &Listener{
+		Name: "webhook",
+		Server: &http.Server{
+			Addr:        cfg.ListenAddr,
+			BaseContext: t.Server.BaseContext,
+			Handler:     h,
+		},
+	})
+	zlog.Info(ctx).
+		Str("address", cfg.ListenAddr).
+		Msg("registry webhook listener configured")
+}
+
+// WebhookHandler accepts registry push webhooks and submits the pushed
+// images to "ing".
+func webhookHandler(ing *webhook.Ingester, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := zlog.ContextWithValues(r.Context(),
+			"component", "httptransport/webhookHandler")
+		if r.Method != http.MethodPost {
+			apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+			return
+		}
+		if secret != "" && !webhookAuthorized(r, secret) {
+			apiError(ctx, w, http.StatusUnauthorized, "missing or incorrect webhook secret")
+			return
+		}
+		format := path.Base(r.URL.Path)
+		b, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize+1))
+		if err != nil {
+			apiError(ctx, w, http.StatusBadRequest, "unable to read payload: %v", err)
+			return
+		}
+		if len(b) > maxWebhookSize {
+			apiError(ctx, w, http.StatusRequestEntityTooLarge, "payload over %d bytes", maxWebhookSize)
+			return
+		}
+		refs, err := webhook.Parse(format, b)
+		switch {
+		case errors.Is(err, webhook.ErrFormat):
+			apiError(ctx, w, http.StatusNotFound, "%v", err)
+			return
+		case err != nil:
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		switch err := ing.Submit(format, refs); {
+		case errors.Is(err, webhook.ErrNotAllowed):
+			apiError(ctx, w, http.StatusForbidden, "%v", err)
+			return
+		case errors.Is(err, webhook.ErrQueueFull):
+			w.Header().Set("retry-after", "60")
+			apiError(ctx, w, http.StatusServiceUnavailable, "%v", err)
+			return
+		case err != nil:
+			apiError(ctx, w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		zlog.Debug(ctx).
+			Str("format", format).
+			Strs("refs", refs).
+			Msg("queued pushed images")
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// WebhookAuthorized reports whether the request presents "secret" as a
+// bearer token or as the "secret" query parameter. Not every registry can
+// set headers on its webhooks, hence the latter.
+func webhookAuthorized(r *http.Request, secret string) bool {
+	got := r.URL.Query().Get("secret")
+	if h := r.Header.Get("authorization"); h != "" {
+		got = strings.TrimPrefix(h, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(secret)) == 1
+}
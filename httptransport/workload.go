@@ -0,0 +1,219 @@
+package httptransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/matcher/trend"
+	"github.com/quay/clair/v4/matcher/workload"
+)
+
+// MaxWorkloadSize is the largest workload report request accepted. Cluster
+// dumps are larger than single admission requests.
+const maxWorkloadSize = 16 << 20
+
+// WorkloadImage is the outcome of looking up a single image.
+type workloadImage struct {
+	Image    string            `json:"image"`
+	Manifest *claircore.Digest `json:"manifest_hash,omitempty"`
+	// Pinned reports whether the image is referenced by digest. Only pinned
+	// images are looked up.
+	Pinned  bool          `json:"pinned"`
+	Indexed bool          `json:"indexed"`
+	Counts  *trend.Counts `json:"counts,omitempty"`
+	Error   string        `json:"error,omitempty"`
+
+	// Vulns is the normalized severity of each vulnerability found, by name.
+	vulns map[string]claircore.Severity
+}
+
+// WorkloadFindings is the aggregated findings for a single workload.
+type workloadFindings struct {
+	workload.Workload
+	Images []workloadImage `json:"images"`
+	// Counts is the number of distinct vulnerabilities, by name, across the
+	// workload's indexed images.
+	Counts trend.Counts `json:"counts"`
+	// Complete reports whether there are findings for every image, so the
+	// counts cover the whole workload.
+	Complete bool `json:"complete"`
+}
+
+// WorkloadResult is the response to a workload report request.
+type workloadResult struct {
+	Workloads []workloadFindings `json:"workloads"`
+	// Unindexed lists the distinct images not known to be indexed, including
+	// unpinned ones.
+	Unindexed []string `json:"unindexed"`
+}
+
+// WorkloadReport reports the findings for the workloads in Kubernetes
+// manifests, or a list of images with their namespaces.
+//
+// Only images pinned by digest and already indexed are reported on; this
+// endpoint doesn't contact registries or index anything, so it's cheap to
+// run against a whole cluster. Other images are flagged.
+func (h *MatcherV1) workloadReport(w http.ResponseWriter, r *http.Request) {
+	ctx := zlog.ContextWithValues(r.Context(),
+		"component", "httptransport/MatcherV1.workloadReport")
+	if r.Method != http.MethodPost {
+		apiError(ctx, w, http.StatusMethodNotAllowed, "endpoint only allows POST")
+		return
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxWorkloadSize+1))
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "unable to read request: %v", err)
+		return
+	}
+	if len(b) > maxWorkloadSize {
+		apiError(ctx, w, http.StatusRequestEntityTooLarge, "request over %d bytes", maxWorkloadSize)
+		return
+	}
+	ws, err := workload.Decode(b)
+	if err != nil {
+		apiError(ctx, w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	// Workloads commonly share images, so each is only looked up once.
+	imgs := make(map[string]*workloadImage)
+	var refs []string
+	for _, wl := range ws {
+		for _, ref := range wl.Images {
+			if _, ok := imgs[ref]; !ok {
+				imgs[ref] = nil
+				refs = append(refs, ref)
+			}
+		}
+	}
+	found := make([]workloadImage, len(refs))
+	eg, ectx := errgroup.WithContext(ctx)
+	eg.SetLimit(admissionConcurrency)
+	for i, ref := range refs {
+		i, ref := i, ref
+		eg.Go(func() error {
+			found[i] = h.workloadImage(ectx, ref)
+			return nil
+		})
+	}
+	eg.Wait()
+
+	res := workloadResult{
+		Workloads: make([]workloadFindings, len(ws)),
+		Unindexed: []string{},
+	}
+	for i := range found {
+		img := &found[i]
+		imgs[img.Image] = img
+		if !img.Indexed && img.Error == "" {
+			res.Unindexed = append(res.Unindexed, img.Image)
+		}
+	}
+	for i, wl := range ws {
+		f := &res.Workloads[i]
+		f.Workload = wl
+		f.Images = make([]workloadImage, len(wl.Images))
+		f.Complete = true
+		vulns := make(map[string]claircore.Severity)
+		for j, ref := range wl.Images {
+			img := imgs[ref]
+			f.Images[j] = *img
+			f.Complete = f.Complete && img.Counts != nil
+			for n, sev := range img.vulns {
+				if cur, ok := vulns[n]; !ok || sev > cur {
+					vulns[n] = sev
+				}
+			}
+		}
+		f.Counts = countSeverities(vulns)
+	}
+	zlog.Debug(ctx).
+		Int("workloads", len(ws)).
+		Int("images", len(refs)).
+		Int("unindexed", len(res.Unindexed)).
+		Msg("reported on workloads")
+
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	enc := codec.GetEncoder(w)
+	defer codec.PutEncoder(enc)
+	err = enc.Encode(&res)
+}
+
+// WorkloadImage looks up the findings for a single image.
+func (h *MatcherV1) workloadImage(ctx context.Context, ref string) workloadImage {
+	ctx = zlog.ContextWithValues(ctx, "image", ref)
+	out := workloadImage{Image: ref}
+	pr, err := name.ParseReference(ref)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	pd, ok := pr.(name.Digest)
+	if !ok {
+		return out
+	}
+	out.Pinned = true
+	d, err := claircore.ParseDigest(pd.DigestStr())
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	out.Manifest = &d
+	indexed, err := h.indexed(ctx, d)
+	switch {
+	case err != nil:
+		out.Error = err.Error()
+		return out
+	case !indexed:
+		return out
+	}
+	out.Indexed = true
+	vr, _, err := h.report(ctx, d)
+	switch {
+	case err != nil:
+		out.Error = err.Error()
+		return out
+	case vr == nil:
+		out.Error = "matcher not initialized yet"
+		return out
+	}
+	out.vulns = make(map[string]claircore.Severity, len(vr.Vulnerabilities))
+	for _, v := range vr.Vulnerabilities {
+		if sev, ok := out.vulns[v.Name]; !ok || v.NormalizedSeverity > sev {
+			out.vulns[v.Name] = v.NormalizedSeverity
+		}
+	}
+	c := countSeverities(out.vulns)
+	out.Counts = &c
+	return out
+}
+
+// CountSeverities counts the vulnerabilities in "vs" by normalized severity.
+func countSeverities(vs map[string]claircore.Severity) trend.Counts {
+	var c trend.Counts
+	for _, sev := range vs {
+		switch sev {
+		case claircore.Negligible:
+			c.Negligible++
+		case claircore.Low:
+			c.Low++
+		case claircore.Medium:
+			c.Medium++
+		case claircore.High:
+			c.High++
+		case claircore.Critical:
+			c.Critical++
+		default:
+			c.Unknown++
+		}
+	}
+	return c
+}
@@ -0,0 +1,141 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestWorkloadReport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		app     = "quay.io/org/app@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+		sidecar = "quay.io/org/sidecar@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		missing = "quay.io/org/new@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		tagged  = "quay.io/org/app:latest"
+	)
+	known := map[string]bool{
+		"sha256:0000000000000000000000000000000000000000000000000000000000000000": true,
+		"sha256:1111111111111111111111111111111111111111111111111111111111111111": true,
+	}
+	i := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			if !known[d.String()] {
+				return nil, false, nil
+			}
+			return &claircore.IndexReport{
+				Hash:     d,
+				Packages: map[string]*claircore.Package{"1": {ID: "1", Name: "openssl"}},
+				Success:  true,
+			}, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Initialized_: func(context.Context) (bool, error) { return true, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			// Both images share a vulnerability, with a different record
+			// each.
+			vr := &claircore.VulnerabilityReport{
+				Hash:     ir.Hash,
+				Packages: ir.Packages,
+				Vulnerabilities: map[string]*claircore.Vulnerability{
+					"1": {ID: "1", Name: "CVE-2023-0001", NormalizedSeverity: claircore.Critical},
+				},
+				PackageVulnerabilities: map[string][]string{"1": {"1"}},
+			}
+			if strings.HasPrefix(ir.Hash.String(), "sha256:1") {
+				vr.Vulnerabilities = map[string]*claircore.Vulnerability{
+					"2": {ID: "2", Name: "CVE-2023-0001", NormalizedSeverity: claircore.High},
+					"3": {ID: "3", Name: "CVE-2023-0002", NormalizedSeverity: claircore.Low},
+				}
+				vr.PackageVulnerabilities = map[string][]string{"1": {"2", "3"}}
+			}
+			return vr, nil
+		},
+	}
+	v1 := NewMatcherV1(ctx, "", m, i, time.Second, otelhttp.WithTracerProvider(trace.NewNoopTracerProvider()))
+	srv := httptest.NewUnstartedServer(v1)
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	post := func(t *testing.T, body string, want int) *http.Response {
+		t.Helper()
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/workload_report", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		return res
+	}
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  template:
+    spec:
+      containers:
+      - image: ` + app + `
+      - image: ` + sidecar + `
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: canary
+  namespace: prod
+spec:
+  template:
+    spec:
+      containers:
+      - image: ` + missing + `
+      - image: ` + tagged + `
+      - image: ` + sidecar + `
+`
+
+	res := post(t, manifests, http.StatusOK)
+	defer res.Body.Close()
+	var got workloadResult
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Workloads) != 2 {
+		t.Fatalf("got: %+v", got)
+	}
+	web, canary := got.Workloads[0], got.Workloads[1]
+	if !web.Complete || web.Counts.Critical != 1 || web.Counts.Low != 1 || web.Counts.Total() != 2 {
+		t.Errorf("web: got: %+v", web)
+	}
+	if canary.Complete || canary.Counts.High != 1 || canary.Counts.Total() != 2 {
+		t.Errorf("canary: got: %+v", canary)
+	}
+	if img := canary.Images[1]; img.Pinned || img.Indexed {
+		t.Errorf("tagged image: got: %+v", img)
+	}
+	if got, want := strings.Join(got.Unindexed, " "), missing+" "+tagged; got != want {
+		t.Errorf("unindexed: got: %q, want: %q", got, want)
+	}
+
+	post(t, `{"kind":"ConfigMap"}`, http.StatusBadRequest).Body.Close()
+}
@@ -0,0 +1,114 @@
+// Package artifact indexes OCI artifacts that aren't container images.
+//
+// Registries store more than images: Helm charts, WASM modules, and other
+// content distinguished by the artifact type of their manifests. Claircore
+// only understands image layers, so an Indexer wraps an indexer.Service and
+// routes manifests with a non-image artifact type to Scanners, which examine
+// each blob directly. The resulting reports are stored in this package's
+// table in the indexer database.
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/quay/claircore"
+)
+
+// Scanner finds packages in the blobs of an artifact.
+type Scanner interface {
+	// Name and Version identify the Scanner. The Version should change
+	// whenever the Scanner may report something different for the same blob.
+	Name() string
+	Version() string
+	// ArtifactTypes reports the artifact types the Scanner handles.
+	ArtifactTypes() []string
+	// Scan examines a single blob. Blobs the Scanner doesn't understand
+	// should be reported as having no packages, not as an error.
+	Scan(ctx context.Context, blob io.Reader) ([]*claircore.Package, error)
+}
+
+var registry = struct {
+	sync.Mutex
+	m map[string]Scanner
+}{
+	m: make(map[string]Scanner),
+}
+
+// Register makes a Scanner available to Scanners.
+//
+// It panics if a Scanner with the same name is already registered.
+func Register(s Scanner) {
+	registry.Lock()
+	defer registry.Unlock()
+	n := s.Name()
+	if _, ok := registry.m[n]; ok {
+		panic(fmt.Sprintf("artifact: scanner %q registered twice", n))
+	}
+	registry.m[n] = s
+}
+
+// Scanners returns the named registered Scanners, or every registered Scanner
+// if "names" is empty.
+func Scanners(names []string) ([]Scanner, error) {
+	registry.Lock()
+	defer registry.Unlock()
+	var out []Scanner
+	if len(names) == 0 {
+		for _, s := range registry.m {
+			out = append(out, s)
+		}
+	}
+	for _, n := range names {
+		s, ok := registry.m[n]
+		if !ok {
+			return nil, fmt.Errorf("artifact: unknown scanner %q", n)
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
+
+// State reports an opaque identifier for the set of Scanners.
+//
+// Reports created with a different state are re-created when the manifest is
+// submitted again.
+func state(ss []Scanner) string {
+	h := sha256.New()
+	for _, s := range ss {
+		fmt.Fprintf(h, "%s\x00%s\n", s.Name(), s.Version())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type typeKey struct{}
+
+// WithType returns a Context carrying the artifact type of the manifest being
+// indexed. The type is the "artifactType" of an OCI manifest, or its config
+// media type if that's absent.
+func WithType(ctx context.Context, t string) context.Context {
+	return context.WithValue(ctx, typeKey{}, t)
+}
+
+func typeFrom(ctx context.Context) string {
+	t, _ := ctx.Value(typeKey{}).(string)
+	return t
+}
+
+// IsImage reports whether the artifact type "t" denotes a container image.
+// The empty string is treated as an image.
+func IsImage(t string) bool {
+	switch t {
+	case "",
+		"application/vnd.oci.image.config.v1+json",
+		"application/vnd.docker.container.image.v1+json":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register(helm{})
+}
+
+// HelmConfigType is the artifact type of Helm charts stored in OCI
+// registries.
+const HelmConfigType = "application/vnd.cncf.helm.config.v1+json"
+
+// Helm reports a chart and the subcharts vendored into it, from the
+// Chart.yaml files in the chart archive.
+type helm struct{}
+
+var _ Scanner = helm{}
+
+// Name implements Scanner.
+func (helm) Name() string { return "helm" }
+
+// Version implements Scanner.
+func (helm) Version() string { return "1" }
+
+// ArtifactTypes implements Scanner.
+func (helm) ArtifactTypes() []string { return []string{HelmConfigType} }
+
+// Scan implements Scanner.
+//
+// Blobs that aren't gzipped tarballs, such as the provenance file, are
+// skipped.
+func (helm) Scan(ctx context.Context, blob io.Reader) ([]*claircore.Package, error) {
+	br := bufio.NewReader(blob)
+	if magic, err := br.Peek(2); err != nil || !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return nil, nil
+	}
+	return helmArchive(ctx, br, "", 0)
+}
+
+// MaxChartDepth bounds recursion into packed subcharts.
+const maxChartDepth = 8
+
+// HelmArchive reads the Chart.yaml files from a chart archive. Packed
+// subcharts in the archive are read recursively, and their paths are
+// reported relative to "prefix".
+func helmArchive(ctx context.Context, r io.Reader, prefix string, depth int) ([]*claircore.Package, error) {
+	if depth > maxChartDepth {
+		return nil, errors.New("helm: subcharts nested too deeply")
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("helm: %w", err)
+	}
+	defer gz.Close()
+	var out []*claircore.Package
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, io.EOF):
+			return out, nil
+		default:
+			return nil, fmt.Errorf("helm: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(h.Name)
+		p := path.Join(prefix, name)
+		switch {
+		case path.Base(name) == "Chart.yaml" && isChartDir(path.Dir(name)):
+			pkg, err := chartYAML(io.LimitReader(tr, 1<<20), p)
+			if err != nil {
+				return nil, err
+			}
+			if pkg != nil {
+				out = append(out, pkg)
+			}
+		case strings.HasSuffix(name, ".tgz") && path.Base(path.Dir(name)) == "charts":
+			ps, err := helmArchive(ctx, tr, p, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ps...)
+		}
+	}
+}
+
+// IsChartDir reports whether "dir" is the root of a chart: the top-level
+// directory of the archive, or a directory in a "charts" directory.
+func isChartDir(dir string) bool {
+	ds := strings.Split(dir, "/")
+	if len(ds)%2 != 1 {
+		return false
+	}
+	for i := 1; i < len(ds); i += 2 {
+		if ds[i] != "charts" {
+			return false
+		}
+	}
+	return true
+}
+
+// ChartYAML returns the package described by a Chart.yaml, or nil if it
+// doesn't name a chart.
+func chartYAML(r io.Reader, p string) (*claircore.Package, error) {
+	var c struct {
+		Name       string `yaml:"name"`
+		Version    string `yaml:"version"`
+		AppVersion string `yaml:"appVersion"`
+	}
+	if err := yaml.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("helm: %s: %w", p, err)
+	}
+	if c.Name == "" {
+		return nil, nil
+	}
+	pkg := claircore.Package{
+		Name:      c.Name,
+		Version:   c.Version,
+		Kind:      claircore.BINARY,
+		PackageDB: "helm:" + p,
+		Filepath:  p,
+	}
+	if c.AppVersion != "" {
+		// The application the chart deploys.
+		pkg.Source = &claircore.Package{
+			Name:    c.Name,
+			Version: c.AppVersion,
+			Kind:    claircore.SOURCE,
+		}
+	}
+	return &pkg, nil
+}
@@ -0,0 +1,224 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+var indexCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_artifact",
+		Name:      "index_total",
+		Help:      "Total number of artifacts indexed, by artifact type and whether a scanner handled it",
+	},
+	[]string{"artifact_type", "supported"},
+)
+
+// Indexer wraps an indexer.Service so that manifests of non-image artifacts
+// are indexed by Scanners instead.
+//
+// Manifests submitted without an artifact type, or with an image type, are
+// passed through.
+type Indexer struct {
+	indexer.Service
+	store  *Store
+	client *http.Client
+	byType map[string][]Scanner
+	state  string
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer storing reports in "s" and fetching blobs with
+// "c".
+func NewIndexer(svc indexer.Service, s *Store, c *http.Client, ss []Scanner) *Indexer {
+	i := Indexer{
+		Service: svc,
+		store:   s,
+		client:  c,
+		byType:  make(map[string][]Scanner),
+		state:   state(ss),
+	}
+	for _, sc := range ss {
+		for _, t := range sc.ArtifactTypes() {
+			i.byType[t] = append(i.byType[t], sc)
+		}
+	}
+	return &i
+}
+
+// Index implements indexer.Indexer.
+//
+// The artifact type is taken from the Context; see WithType. A stored
+// successful report created by the current Scanners is returned without
+// fetching anything.
+// Artifacts no Scanner handles get an unsuccessful report explaining so.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	t := typeFrom(ctx)
+	if IsImage(t) {
+		return i.Service.Index(ctx, m)
+	}
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/artifact/Indexer.Index",
+		"manifest", m.Hash.String(),
+		"artifact_type", t)
+	prev, err := i.store.Get(ctx, m.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && prev.State == i.state && prev.ArtifactType == t && prev.Report.Success {
+		zlog.Debug(ctx).Msg("using stored report")
+		return prev.Report, nil
+	}
+
+	ss := i.byType[t]
+	indexCounter.WithLabelValues(t, strconv.FormatBool(len(ss) != 0)).Inc()
+	r := &claircore.IndexReport{
+		Hash:          m.Hash,
+		State:         "IndexFinished",
+		Packages:      make(map[string]*claircore.Package),
+		Distributions: make(map[string]*claircore.Distribution),
+		Repositories:  make(map[string]*claircore.Repository),
+		Environments:  make(map[string][]*claircore.Environment),
+		Success:       true,
+	}
+	switch {
+	case len(ss) == 0:
+		r.State = "IndexError"
+		r.Success = false
+		r.Err = fmt.Sprintf("unsupported artifact type %q", t)
+	default:
+		if err := i.scan(ctx, ss, m, r); err != nil {
+			var se *scanError
+			if !errors.As(err, &se) {
+				return nil, err
+			}
+			// The artifact itself is bad, so there's no point retrying:
+			// record the failure.
+			r.State = "IndexError"
+			r.Success = false
+			r.Err = err.Error()
+		}
+	}
+	zlog.Debug(ctx).
+		Bool("success", r.Success).
+		Int("packages", len(r.Packages)).
+		Msg("indexed artifact")
+	if err := i.store.Put(ctx, &Entry{ArtifactType: t, State: i.state, Report: r}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ScanError is a Scanner failing on a blob.
+type scanError struct {
+	scanner string
+	layer   claircore.Digest
+	err     error
+}
+
+func (e *scanError) Error() string {
+	if e.scanner == "" {
+		return fmt.Sprintf("blob %v: %v", e.layer, e.err)
+	}
+	return fmt.Sprintf("scanner %q: blob %v: %v", e.scanner, e.layer, e.err)
+}
+
+func (e *scanError) Unwrap() error {
+	return e.err
+}
+
+// Scan runs the Scanners over every blob of "m", adding what they find to "r".
+func (i *Indexer) scan(ctx context.Context, ss []Scanner, m *claircore.Manifest, r *claircore.IndexReport) error {
+	for _, l := range m.Layers {
+		b, err := i.fetch(ctx, l)
+		if err != nil {
+			return err
+		}
+		for _, s := range ss {
+			ps, err := s.Scan(ctx, bytes.NewReader(b))
+			if err != nil {
+				return &scanError{scanner: s.Name(), layer: l.Hash, err: err}
+			}
+			for _, p := range ps {
+				p.ID = strconv.Itoa(len(r.Packages))
+				r.Packages[p.ID] = p
+				r.Environments[p.ID] = []*claircore.Environment{{
+					PackageDB:     p.PackageDB,
+					IntroducedIn:  l.Hash,
+					RepositoryIDs: []string{},
+				}}
+			}
+		}
+	}
+	return nil
+}
+
+// Fetch retrieves the blob for "l", checking its digest.
+func (i *Indexer) fetch(ctx context.Context, l *claircore.Layer) ([]byte, error) {
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, l.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: fetching %v: %w", l.Hash, err)
+	}
+	for k, vs := range l.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	res, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: fetching %v: %w", l.Hash, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("artifact: fetching %v: unexpected status: %s", l.Hash, res.Status)
+	}
+	h := l.Hash.Hash()
+	if h == nil {
+		return nil, &scanError{layer: l.Hash, err: fmt.Errorf("unsupported digest algorithm %q", l.Hash.Algorithm())}
+	}
+	b, err := io.ReadAll(io.TeeReader(res.Body, h))
+	if err != nil {
+		return nil, fmt.Errorf("artifact: fetching %v: %w", l.Hash, err)
+	}
+	if !bytes.Equal(h.Sum(nil), l.Hash.Checksum()) {
+		return nil, &scanError{layer: l.Hash, err: errors.New("digest mismatch")}
+	}
+	return b, nil
+}
+
+// IndexReport implements indexer.Reporter.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	e, err := i.store.Get(ctx, d)
+	switch {
+	case err != nil:
+		return nil, false, err
+	case e != nil:
+		return e.Report, true, nil
+	}
+	return i.Service.IndexReport(ctx, d)
+}
+
+// DeleteManifests implements indexer.Indexer.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	out, err := i.store.Delete(ctx, ds...)
+	if err != nil {
+		return nil, err
+	}
+	rm, err := i.Service.DeleteManifests(ctx, ds...)
+	return append(out, rm...), err
+}
@@ -0,0 +1,119 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return NewStore(pool)
+}
+
+func TestIndexer(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := TestingStore(ctx, t)
+
+	chart := tgz(t, "app/Chart.yaml", "name: app\nversion: 1.2.3\n")
+	sum := sha256.Sum256(chart)
+	blob := claircore.MustParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(chart)
+	}))
+	defer srv.Close()
+
+	var passed int
+	svc := &indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			passed++
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return nil, false, nil
+		},
+		DeleteManifests_: func(context.Context, ...claircore.Digest) ([]claircore.Digest, error) {
+			return nil, nil
+		},
+	}
+	ss, err := Scanners(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := NewIndexer(svc, s, srv.Client(), ss)
+	m := &claircore.Manifest{
+		Hash:   claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32))),
+		Layers: []*claircore.Layer{{Hash: blob, URI: srv.URL + "/blob"}},
+	}
+
+	if _, err := i.Index(ctx, m); err != nil || passed != 1 {
+		t.Errorf("image: got: (%d, %v)", passed, err)
+	}
+	actx := WithType(ctx, HelmConfigType)
+	for n := 0; n < 2; n++ {
+		r, err := i.Index(actx, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Success || len(r.Packages) != 1 || r.Packages["0"].Name != "app" {
+			t.Errorf("chart: got: %+v", r)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("stored report not reused: %d fetches", fetches)
+	}
+	if r, ok, err := i.IndexReport(ctx, m.Hash); err != nil || !ok || !r.Success {
+		t.Errorf("report: got: (%v, %v, %v)", r, ok, err)
+	}
+
+	r, err := i.Index(WithType(ctx, "application/vnd.example.unknown+json"), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Success || r.Err == "" {
+		t.Errorf("unsupported: got: %+v", r)
+	}
+
+	ds, err := i.DeleteManifests(ctx, m.Hash)
+	if err != nil || len(ds) != 1 {
+		t.Errorf("delete: got: (%v, %v)", ds, err)
+	}
+	if _, ok, err := i.IndexReport(ctx, m.Hash); err != nil || ok {
+		t.Errorf("deleted report: got: (%v, %v)", ok, err)
+	}
+}
@@ -0,0 +1,154 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+// Tgz builds a gzipped tarball from the name and contents pairs.
+func tgz(t testing.TB, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i < len(files); i += 2 {
+		b := files[i+1]
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     files[i],
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(b)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type pkg struct{ Name, Version, DB string }
+
+func summarize(ps []*claircore.Package) []pkg {
+	out := make([]pkg, len(ps))
+	for i, p := range ps {
+		out[i] = pkg{p.Name, p.Version, p.PackageDB}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DB < out[j].DB })
+	return out
+}
+
+func TestHelm(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	sub := tgz(t, "redis/Chart.yaml", "name: redis\nversion: 17.3.2\n")
+	chart := tgz(t,
+		"app/Chart.yaml", "apiVersion: v2\nname: app\nversion: 1.2.3\nappVersion: \"2.0\"\n",
+		"app/templates/Chart.yaml", "name: not-a-chart\n",
+		"app/charts/common/Chart.yaml", "name: common\nversion: 2.1.0\n",
+		"app/charts/redis-17.3.2.tgz", string(sub),
+	)
+
+	ps, err := helm{}.Scan(ctx, bytes.NewReader(chart))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := summarize(ps)
+	want := []pkg{
+		{"app", "1.2.3", "helm:app/Chart.yaml"},
+		{"common", "2.1.0", "helm:app/charts/common/Chart.yaml"},
+		{"redis", "17.3.2", "helm:app/charts/redis-17.3.2.tgz/redis/Chart.yaml"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for _, p := range ps {
+		if p.Name == "app" && (p.Source == nil || p.Source.Version != "2.0") {
+			t.Errorf("missing appVersion: %+v", p.Source)
+		}
+	}
+
+	ps, err = helm{}.Scan(ctx, strings.NewReader("-----BEGIN PGP SIGNED MESSAGE-----"))
+	if err != nil || len(ps) != 0 {
+		t.Errorf("provenance file: got: (%v, %v)", ps, err)
+	}
+}
+
+func TestWASM(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	uv := func(b *bytes.Buffer, n int) {
+		var tmp [binary.MaxVarintLen64]byte
+		b.Write(tmp[:binary.PutUvarint(tmp[:], uint64(n))])
+	}
+	str := func(b *bytes.Buffer, s string) {
+		uv(b, len(s))
+		b.WriteString(s)
+	}
+	section := func(b *bytes.Buffer, id byte, contents []byte) {
+		b.WriteByte(id)
+		uv(b, len(contents))
+		b.Write(contents)
+	}
+
+	var prod bytes.Buffer
+	str(&prod, "producers")
+	uv(&prod, 2)
+	str(&prod, "language")
+	uv(&prod, 1)
+	str(&prod, "Rust")
+	str(&prod, "")
+	str(&prod, "processed-by")
+	uv(&prod, 2)
+	str(&prod, "rustc")
+	str(&prod, "1.70.0")
+	str(&prod, "wasm-bindgen")
+	str(&prod, "0.2.87")
+
+	var other bytes.Buffer
+	str(&other, "name")
+	other.WriteString("ignored")
+
+	var mod bytes.Buffer
+	mod.WriteString("\x00asm\x01\x00\x00\x00")
+	section(&mod, 1, []byte{0x01, 0x60, 0x00, 0x00}) // A type section.
+	section(&mod, 0, other.Bytes())
+	section(&mod, 0, prod.Bytes())
+
+	ps, err := wasm{}.Scan(ctx, bytes.NewReader(mod.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := summarize(ps)
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	want := []pkg{
+		{"rustc", "1.70.0", "wasm:producers/processed-by"},
+		{"wasm-bindgen", "0.2.87", "wasm:producers/processed-by"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if _, err := (wasm{}).Scan(ctx, bytes.NewReader(mod.Bytes()[:mod.Len()-3])); err == nil {
+		t.Error("truncated module: unexpected success")
+	}
+	ps, err = wasm{}.Scan(ctx, strings.NewReader("{}"))
+	if err != nil || len(ps) != 0 {
+		t.Errorf("config blob: got: (%v, %v)", ps, err)
+	}
+}
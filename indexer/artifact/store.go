@@ -0,0 +1,104 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_artifact", "the artifact report store")
+
+// Store persists index reports for artifacts.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Entry is the stored report for a single artifact.
+type Entry struct {
+	ArtifactType string
+	// State is the state of the Scanners that created the Report.
+	State  string
+	Report *claircore.IndexReport
+}
+
+// Get returns the Entry for the manifest, or nil if there isn't one.
+func (s *Store) Get(ctx context.Context, d claircore.Digest) (_ *Entry, err error) {
+	const query = `SELECT artifact_type, state, report FROM indexer_artifact WHERE manifest = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var e Entry
+	var b []byte
+	err = s.pool.QueryRow(ctx, query, d.String()).Scan(&e.ArtifactType, &e.State, &b)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("artifact: unable to look up %v: %w", d, err)
+	}
+	e.Report = new(claircore.IndexReport)
+	if err = json.Unmarshal(b, e.Report); err != nil {
+		return nil, fmt.Errorf("artifact: bad stored report for %v: %w", d, err)
+	}
+	return &e, nil
+}
+
+// Put stores the Entry, replacing any previous one for the same manifest.
+func (s *Store) Put(ctx context.Context, e *Entry) (err error) {
+	const query = `INSERT INTO indexer_artifact (manifest, artifact_type, state, report)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (manifest) DO UPDATE
+SET artifact_type = EXCLUDED.artifact_type, state = EXCLUDED.state,
+	report = EXCLUDED.report, updated = now();`
+	defer queryMetrics.Observe("put", &err)()
+	b, err := json.Marshal(e.Report)
+	if err != nil {
+		return fmt.Errorf("artifact: unable to encode report: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, query, e.Report.Hash.String(), e.ArtifactType, e.State, b); err != nil {
+		return fmt.Errorf("artifact: unable to store %v: %w", e.Report.Hash, err)
+	}
+	return nil
+}
+
+// Delete removes the reports for the manifests, returning the ones that were
+// present.
+func (s *Store) Delete(ctx context.Context, ds ...claircore.Digest) (_ []claircore.Digest, err error) {
+	const query = `DELETE FROM indexer_artifact WHERE manifest = ANY($1::text[]) RETURNING manifest;`
+	defer queryMetrics.Observe("delete", &err)()
+	in := make([]string, len(ds))
+	for i, d := range ds {
+		in[i] = d.String()
+	}
+	rows, err := s.pool.Query(ctx, query, in)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: unable to delete: %w", err)
+	}
+	defer rows.Close()
+	var out []claircore.Digest
+	for rows.Next() {
+		var d claircore.Digest
+		if err = rows.Scan(&d); err != nil {
+			return nil, fmt.Errorf("artifact: unable to delete: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("artifact: unable to delete: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,175 @@
+package artifact
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/quay/claircore"
+)
+
+func init() {
+	Register(wasm{})
+}
+
+// These are the artifact types used for WASM modules stored in OCI
+// registries.
+const (
+	// WASMConfigType is from the CNCF TAG Runtime WASM artifact layout.
+	WASMConfigType = "application/vnd.wasm.config.v0+json"
+	// WASMLegacyConfigType is written by wasm-to-oci.
+	WASMLegacyConfigType = "application/vnd.module.wasm.config.v1+json"
+)
+
+// WASM reports the toolchain recorded in a WASM module's "producers" custom
+// section: the compilers and tools that processed it, and the SDK it was
+// built with.
+type wasm struct{}
+
+var _ Scanner = wasm{}
+
+// Name implements Scanner.
+func (wasm) Name() string { return "wasm" }
+
+// Version implements Scanner.
+func (wasm) Version() string { return "1" }
+
+// ArtifactTypes implements Scanner.
+func (wasm) ArtifactTypes() []string {
+	return []string{WASMConfigType, WASMLegacyConfigType}
+}
+
+// MaxProducersSize bounds the size of a "producers" section that's read.
+const maxProducersSize = 1 << 20
+
+// Scan implements Scanner.
+//
+// Both core modules and components are understood. Blobs without the WASM
+// magic number are skipped.
+func (wasm) Scan(ctx context.Context, blob io.Reader) ([]*claircore.Package, error) {
+	br := bufio.NewReader(blob)
+	hdr, err := br.Peek(8)
+	if err != nil || !bytes.Equal(hdr[:4], []byte("\x00asm")) {
+		return nil, nil
+	}
+	br.Discard(8)
+
+	var out []*claircore.Package
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id, err := br.ReadByte()
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, io.EOF):
+			return out, nil
+		default:
+			return nil, fmt.Errorf("wasm: %w", err)
+		}
+		sz, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("wasm: malformed section: %w", err)
+		}
+		if id != 0 {
+			if _, err := br.Discard(int(sz)); err != nil {
+				return nil, fmt.Errorf("wasm: truncated section: %w", err)
+			}
+			continue
+		}
+		// Custom section: check the name before reading the contents.
+		sec := io.LimitReader(br, int64(sz))
+		name, err := wasmName(sec)
+		if err != nil {
+			return nil, err
+		}
+		if name != "producers" || sz > maxProducersSize {
+			if _, err := io.Copy(io.Discard, sec); err != nil {
+				return nil, fmt.Errorf("wasm: truncated section: %w", err)
+			}
+			continue
+		}
+		b, err := io.ReadAll(sec)
+		if err != nil {
+			return nil, fmt.Errorf("wasm: truncated section: %w", err)
+		}
+		ps, err := producers(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ps...)
+	}
+}
+
+// Producers reads the fields of a "producers" section, skipping the
+// "language" field.
+func producers(r *bytes.Reader) ([]*claircore.Package, error) {
+	var out []*claircore.Package
+	nf, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: malformed producers section: %w", err)
+	}
+	for i := uint64(0); i < nf; i++ {
+		field, err := wasmName(r)
+		if err != nil {
+			return nil, err
+		}
+		nv, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("wasm: malformed producers section: %w", err)
+		}
+		for j := uint64(0); j < nv; j++ {
+			name, err := wasmName(r)
+			if err != nil {
+				return nil, err
+			}
+			version, err := wasmName(r)
+			if err != nil {
+				return nil, err
+			}
+			if field == "language" {
+				continue
+			}
+			out = append(out, &claircore.Package{
+				Name:      name,
+				Version:   version,
+				Kind:      claircore.BINARY,
+				PackageDB: "wasm:producers/" + field,
+			})
+		}
+	}
+	return out, nil
+}
+
+// WasmName reads a length-prefixed UTF-8 string.
+func wasmName(r io.Reader) (string, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &byteReader{r}
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return "", fmt.Errorf("wasm: malformed name: %w", err)
+	}
+	if n > 4096 {
+		return "", fmt.Errorf("wasm: name too long: %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf("wasm: malformed name: %w", err)
+	}
+	return string(b), nil
+}
+
+// ByteReader adapts an io.Reader to an io.ByteReader, one byte at a time.
+type byteReader struct{ io.Reader }
+
+func (b *byteReader) ReadByte() (byte, error) {
+	var p [1]byte
+	_, err := io.ReadFull(b.Reader, p[:])
+	return p[0], err
+}
@@ -0,0 +1,305 @@
+// Package cache implements a shared cache in front of an indexer.Service.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/codec"
+)
+
+var requestCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_cache",
+		Name:      "requests_total",
+		Help:      "Total number of cache lookups, by kind and result.",
+	},
+	[]string{"kind", "result"},
+)
+
+const (
+	// LockTTL bounds how long a process may hold the fill lock for a key.
+	lockTTL = 30 * time.Second
+	// LockWait is how long a process waits for another process to fill a
+	// key before doing the work itself.
+	lockWait = 5 * time.Second
+	// LockPoll is the interval for checking whether a key has been filled.
+	lockPoll = 50 * time.Millisecond
+)
+
+// ErrMiss is returned by a Client when a key isn't present.
+var ErrMiss = errors.New("cache: miss")
+
+// Client is the storage API used by the Indexer.
+type Client interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, v []byte, ttl time.Duration) error
+	// SetNX sets "key" only if it doesn't exist, reporting whether it was
+	// set.
+	SetNX(ctx context.Context, key string, v []byte, ttl time.Duration) (bool, error)
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Redis returns a Client using the provided Redis client.
+func Redis(c redis.UniversalClient) Client {
+	return &redisClient{c: c}
+}
+
+type redisClient struct {
+	c redis.UniversalClient
+}
+
+func (r *redisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := r.c.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return b, err
+}
+
+func (r *redisClient) Set(ctx context.Context, key string, v []byte, ttl time.Duration) error {
+	return r.c.Set(ctx, key, v, ttl).Err()
+}
+
+func (r *redisClient) SetNX(ctx context.Context, key string, v []byte, ttl time.Duration) (bool, error) {
+	return r.c.SetNX(ctx, key, v, ttl).Result()
+}
+
+func (r *redisClient) Del(ctx context.Context, keys ...string) error {
+	return r.c.Del(ctx, keys...).Err()
+}
+
+// Options configures an Indexer.
+type Options struct {
+	// KeyPrefix is prepended to every key.
+	KeyPrefix string
+	// IndexReportTTL is how long index reports are cached.
+	IndexReportTTL time.Duration
+	// AffectedManifestsTTL is how long affected manifest results are cached.
+	AffectedManifestsTTL time.Duration
+}
+
+// Indexer wraps an indexer.Service so that IndexReport and AffectedManifests
+// calls are served from a Client when possible.
+//
+// Concurrent misses for the same key are collapsed, both within a process and
+// across processes sharing the Client, so that an expired entry doesn't cause
+// a stampede of identical requests to the wrapped Service. Errors from the
+// Client are logged and otherwise ignored.
+type Indexer struct {
+	indexer.Service
+	c    Client
+	opts Options
+	sf   singleflight.Group
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer caching results from "svc" in "c".
+func NewIndexer(svc indexer.Service, c Client, opts *Options) *Indexer {
+	return &Indexer{
+		Service: svc,
+		c:       c,
+		opts:    *opts,
+	}
+}
+
+func (i *Indexer) reportKey(d claircore.Digest) string {
+	return i.opts.KeyPrefix + "index_report:" + d.String()
+}
+
+// Index implements indexer.Indexer.
+//
+// Any cached report for the manifest is invalidated.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	r, err := i.Service.Index(ctx, m)
+	i.invalidate(ctx, i.reportKey(m.Hash))
+	return r, err
+}
+
+// DeleteManifests implements indexer.Indexer.
+//
+// Any cached reports for the manifests are invalidated.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	out, err := i.Service.DeleteManifests(ctx, ds...)
+	keys := make([]string, len(ds))
+	for j, d := range ds {
+		keys[j] = i.reportKey(d)
+	}
+	i.invalidate(ctx, keys...)
+	return out, err
+}
+
+// IndexReport implements indexer.Reporter.
+//
+// Only completed reports are cached.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/cache/Indexer.IndexReport",
+		"manifest", d.String())
+	var r *claircore.IndexReport
+	err := i.load(ctx, "index_report", i.reportKey(d), i.opts.IndexReportTTL, &r, func(ctx context.Context) (interface{}, bool, error) {
+		r, ok, err := i.Service.IndexReport(ctx, d)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		return r, r.Success, nil
+	})
+	if err != nil || r == nil {
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+// AffectedManifests implements indexer.Affected.
+func (i *Indexer) AffectedManifests(ctx context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/cache/Indexer.AffectedManifests")
+	var buf bytes.Buffer
+	enc := codec.GetEncoder(&buf)
+	err := enc.Encode(vs)
+	codec.PutEncoder(enc)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	key := i.opts.KeyPrefix + "affected_manifests:" + hex.EncodeToString(sum[:])
+
+	var a *claircore.AffectedManifests
+	err = i.load(ctx, "affected_manifests", key, i.opts.AffectedManifestsTTL, &a, func(ctx context.Context) (interface{}, bool, error) {
+		a, err := i.Service.AffectedManifests(ctx, vs)
+		return a, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Load decodes the value at "key" into "dst", calling "fetch" on a miss.
+//
+// The fetch function returns the value, whether it should be cached, and an
+// error. If the value is nil, "dst" is left untouched.
+//
+// Concurrent callers share a single fetch, run with the first caller's
+// Context. Callers stop waiting for it when their own Context is done.
+func (i *Indexer) load(ctx context.Context, kind, key string, ttl time.Duration, dst interface{}, fetch func(context.Context) (interface{}, bool, error)) error {
+	if i.get(ctx, kind, key, dst) {
+		return nil
+	}
+	ch := i.sf.DoChan(key, func() (interface{}, error) {
+		locked, err := i.c.SetNX(ctx, key+":lock", []byte{'1'}, lockTTL)
+		if err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to take cache fill lock")
+		}
+		if err == nil && !locked {
+			// Another process is filling this key; give it a chance
+			// before doing the same work.
+			if b, ok := i.wait(ctx, key); ok {
+				return b, nil
+			}
+		}
+		if locked {
+			defer i.invalidate(ctx, key+":lock")
+		}
+		v, cache, err := fetch(ctx)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		enc := codec.GetEncoder(&buf)
+		err = enc.Encode(v)
+		codec.PutEncoder(enc)
+		if err != nil {
+			return nil, err
+		}
+		if !cache {
+			return buf.Bytes(), nil
+		}
+		if err := i.c.Set(ctx, key, buf.Bytes(), ttl); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to fill cache")
+		}
+		return buf.Bytes(), nil
+	})
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res = <-ch:
+	}
+	if res.Err != nil || res.Val == nil {
+		return res.Err
+	}
+	return decode(res.Val.([]byte), dst)
+}
+
+// Get reports whether "key" was found and decoded into "dst".
+func (i *Indexer) get(ctx context.Context, kind, key string, dst interface{}) bool {
+	b, err := i.c.Get(ctx, key)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrMiss):
+		requestCounter.WithLabelValues(kind, "miss").Inc()
+		return false
+	default:
+		zlog.Warn(ctx).Err(err).Msg("cache lookup failed")
+		requestCounter.WithLabelValues(kind, "error").Inc()
+		return false
+	}
+	if err := decode(b, dst); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to decode cached value")
+		requestCounter.WithLabelValues(kind, "error").Inc()
+		return false
+	}
+	requestCounter.WithLabelValues(kind, "hit").Inc()
+	return true
+}
+
+// Wait polls for "key" to be filled by another process, for up to lockWait.
+func (i *Indexer) wait(ctx context.Context, key string) ([]byte, bool) {
+	t := time.NewTicker(lockPoll)
+	defer t.Stop()
+	timeout := time.After(lockWait)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-timeout:
+			return nil, false
+		case <-t.C:
+		}
+		b, err := i.c.Get(ctx, key)
+		if err == nil {
+			return b, true
+		}
+		if !errors.Is(err, ErrMiss) {
+			return nil, false
+		}
+	}
+}
+
+// Invalidate removes "keys", logging any error.
+func (i *Indexer) invalidate(ctx context.Context, keys ...string) {
+	if err := i.c.Del(ctx, keys...); err != nil {
+		zlog.Warn(ctx).Err(err).Strs("keys", keys).Msg("unable to invalidate cache")
+	}
+}
+
+func decode(b []byte, dst interface{}) error {
+	dec := codec.GetDecoder(bytes.NewReader(b))
+	defer codec.PutDecoder(dec)
+	return dec.Decode(dst)
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+type memClient struct {
+	sync.Mutex
+	m map[string][]byte
+}
+
+func (c *memClient) Get(_ context.Context, key string) ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	b, ok := c.m[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	return b, nil
+}
+
+func (c *memClient) Set(_ context.Context, key string, v []byte, _ time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+	c.m[key] = append([]byte(nil), v...)
+	return nil
+}
+
+func (c *memClient) SetNX(_ context.Context, key string, v []byte, _ time.Duration) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.m[key]; ok {
+		return false, nil
+	}
+	c.m[key] = v
+	return true, nil
+}
+
+func (c *memClient) Del(_ context.Context, keys ...string) error {
+	c.Lock()
+	defer c.Unlock()
+	for _, k := range keys {
+		delete(c.m, k)
+	}
+	return nil
+}
+
+func TestIndexReport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	d := claircore.MustParseDigest("sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	var calls int64
+	success := false
+	m := &indexer.Mock{
+		IndexReport_: func(_ context.Context, got claircore.Digest) (*claircore.IndexReport, bool, error) {
+			atomic.AddInt64(&calls, 1)
+			// Slow enough that the concurrent callers overlap.
+			time.Sleep(10 * time.Millisecond)
+			return &claircore.IndexReport{Hash: got, Success: success}, true, nil
+		},
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash}, nil
+		},
+	}
+	c := &memClient{m: make(map[string][]byte)}
+	i := NewIndexer(m, c, &Options{KeyPrefix: "test:", IndexReportTTL: time.Minute})
+
+	// Incomplete reports aren't cached.
+	for n := 0; n < 2; n++ {
+		if _, ok, err := i.IndexReport(ctx, d); err != nil || !ok {
+			t.Fatalf("got: (%v, %v)", ok, err)
+		}
+	}
+	if got, want := atomic.LoadInt64(&calls), int64(2); got != want {
+		t.Errorf("incomplete: calls: got: %d, want: %d", got, want)
+	}
+
+	success = true
+	atomic.StoreInt64(&calls, 0)
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, ok, err := i.IndexReport(ctx, d)
+			if err != nil || !ok || r.Hash.String() != d.String() {
+				t.Errorf("got: (%v, %v, %v)", r, ok, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, _, err := i.IndexReport(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt64(&calls), int64(1); got != want {
+		t.Errorf("complete: calls: got: %d, want: %d", got, want)
+	}
+
+	// Indexing invalidates.
+	if _, err := i.Index(ctx, &claircore.Manifest{Hash: d}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := i.IndexReport(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt64(&calls), int64(2); got != want {
+		t.Errorf("invalidated: calls: got: %d, want: %d", got, want)
+	}
+}
+
+func TestIndexReportCanceled(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	d := claircore.MustParseDigest("sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m := &indexer.Mock{
+		IndexReport_: func(_ context.Context, got claircore.Digest) (*claircore.IndexReport, bool, error) {
+			close(started)
+			<-release
+			return &claircore.IndexReport{Hash: got, Success: true}, true, nil
+		},
+	}
+	c := &memClient{m: make(map[string][]byte)}
+	i := NewIndexer(m, c, &Options{KeyPrefix: "test:", IndexReportTTL: time.Minute})
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := i.IndexReport(ctx, d)
+		errCh <- err
+	}()
+	<-started
+
+	// A caller waiting on the shared fetch gives up when its request does.
+	wctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := i.IndexReport(wctx, d); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter: got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := <-errCh; err != nil {
+		t.Errorf("first caller: got: %v", err)
+	}
+}
+
+func TestAffectedManifests(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var calls int
+	m := &indexer.Mock{
+		AffectedManifests_: func(_ context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			calls++
+			a := claircore.NewAffectedManifests()
+			return &a, nil
+		},
+	}
+	c := &memClient{m: make(map[string][]byte)}
+	i := NewIndexer(m, c, &Options{AffectedManifestsTTL: time.Minute})
+	a := []claircore.Vulnerability{{ID: "1", Name: "CVE-2023-0001"}}
+	b := []claircore.Vulnerability{{ID: "2", Name: "CVE-2023-0002"}}
+	for _, vs := range [][]claircore.Vulnerability{a, b, a, b} {
+		if _, err := i.AffectedManifests(ctx, vs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("calls: got: %d, want: %d", got, want)
+	}
+}
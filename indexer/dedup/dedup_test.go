@@ -0,0 +1,179 @@
+package dedup
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return NewStore(pool)
+}
+
+func digest(c byte) claircore.Digest {
+	b := make([]byte, 64)
+	for i := range b {
+		b[i] = c
+	}
+	return claircore.MustParseDigest("sha256:" + string(b))
+}
+
+func TestContentHash(t *testing.T) {
+	l := func(c byte) *claircore.Layer { return &claircore.Layer{Hash: digest(c)} }
+	a := &claircore.Manifest{Hash: digest('a'), Layers: []*claircore.Layer{l('1'), l('2')}}
+	b := &claircore.Manifest{Hash: digest('b'), Layers: []*claircore.Layer{l('1'), l('2')}}
+	c := &claircore.Manifest{Hash: digest('c'), Layers: []*claircore.Layer{l('2'), l('1')}}
+	if contentHash(a) != contentHash(b) {
+		t.Error("identical layers hashed differently")
+	}
+	if contentHash(a) == contentHash(c) {
+		t.Error("layer order ignored")
+	}
+}
+
+// Fake is a minimal index report store standing in for claircore.
+type fake struct {
+	indexed []string
+	reports map[string]*claircore.IndexReport
+	state   string
+}
+
+func (f *fake) Mock() *indexer.Mock {
+	return &indexer.Mock{
+		State_: func(context.Context) (string, error) { return f.state, nil },
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			f.indexed = append(f.indexed, m.Hash.String())
+			r := &claircore.IndexReport{Hash: m.Hash, Success: true}
+			f.reports[m.Hash.String()] = r
+			return r, nil
+		},
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			r, ok := f.reports[d.String()]
+			return r, ok, nil
+		},
+		DeleteManifests_: func(_ context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+			for _, d := range ds {
+				delete(f.reports, d.String())
+			}
+			return ds, nil
+		},
+		AffectedManifests_: func(context.Context, []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			a := claircore.NewAffectedManifests()
+			for d := range f.reports {
+				a.VulnerableManifests[d] = []string{"1"}
+			}
+			return &a, nil
+		},
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	f := &fake{reports: make(map[string]*claircore.IndexReport), state: "1"}
+	i := NewIndexer(f.Mock(), TestingStore(ctx, t))
+	layers := []*claircore.Layer{{Hash: digest('1')}, {Hash: digest('2')}}
+	a := &claircore.Manifest{Hash: digest('a'), Layers: layers}
+	b := &claircore.Manifest{Hash: digest('b'), Layers: layers}
+	c := &claircore.Manifest{Hash: digest('c'), Layers: layers[:1]}
+
+	for _, m := range []*claircore.Manifest{a, b, c} {
+		r, err := i.Index(ctx, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Hash.String(), m.Hash.String(); got != want {
+			t.Errorf("report hash: got: %s, want: %s", got, want)
+		}
+	}
+	if got, want := len(f.indexed), 2; got != want {
+		t.Errorf("indexed: got: %v, want: %d entries", f.indexed, want)
+	}
+
+	affected := func(want ...string) {
+		t.Helper()
+		am, err := i.AffectedManifests(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for d := range am.VulnerableManifests {
+			got = append(got, d)
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if len(got) != len(want) {
+			t.Fatalf("affected: got: %v, want: %v", got, want)
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatalf("affected: got: %v, want: %v", got, want)
+			}
+		}
+	}
+	affected(a.Hash.String(), b.Hash.String(), c.Hash.String())
+
+	// Deleting the canonical manifest keeps its data for "b".
+	if _, err := i.DeleteManifests(ctx, a.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := i.IndexReport(ctx, a.Hash); err != nil || ok {
+		t.Errorf("deleted report: got: (%v, %v)", ok, err)
+	}
+	r, ok, err := i.IndexReport(ctx, b.Hash)
+	if err != nil || !ok {
+		t.Fatalf("referencing report: got: (%v, %v)", ok, err)
+	}
+	if got, want := r.Hash.String(), b.Hash.String(); got != want {
+		t.Errorf("report hash: got: %s, want: %s", got, want)
+	}
+	affected(b.Hash.String(), c.Hash.String())
+
+	// Removing the last reference removes the data.
+	if _, err := i.DeleteManifests(ctx, b.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.reports[a.Hash.String()]; ok {
+		t.Error("unreferenced report not removed")
+	}
+	affected(c.Hash.String())
+
+	// A new indexer state means the content is indexed again.
+	f.state = "2"
+	if _, err := i.Index(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(f.indexed), 3; got != want {
+		t.Errorf("indexed: got: %v, want: %d entries", f.indexed, want)
+	}
+}
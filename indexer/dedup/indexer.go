@@ -0,0 +1,244 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+var indexCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_dedup",
+		Name:      "index_total",
+		Help:      "Total number of Index calls, by whether an existing report for identical content was used",
+	},
+	[]string{"deduplicated"},
+)
+
+// Indexer wraps an indexer.Service so that manifests with identical content
+// share a single stored index report.
+type Indexer struct {
+	indexer.Service
+	store *Store
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer recording references in "s".
+func NewIndexer(svc indexer.Service, s *Store) *Indexer {
+	return &Indexer{
+		Service: svc,
+		store:   s,
+	}
+}
+
+// ContentHash identifies the content of a manifest: the digests of its
+// layers, in order.
+func contentHash(m *claircore.Manifest) string {
+	h := sha256.New()
+	for _, l := range m.Layers {
+		h.Write([]byte(l.Hash.String()))
+		h.Write([]byte{'\n'})
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Index implements indexer.Indexer.
+//
+// If a canonical manifest with the same content was indexed with the current
+// indexer state, its report is returned for "m" without indexing anything.
+// Otherwise, "m" is indexed and becomes the canonical manifest for its
+// content.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/dedup/Indexer.Index",
+		"manifest", m.Hash.String())
+	state, err := i.Service.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	content := contentHash(m)
+	prev, err := i.store.Lookup(ctx, m.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil || !prev.IsCanonical() {
+		c, ok, err := i.store.Canonical(ctx, content, state)
+		if err != nil {
+			return nil, err
+		}
+		if ok && c.String() != m.Hash.String() {
+			r, ok, err := i.Service.IndexReport(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+			if ok && r.Success {
+				ok, err := i.store.Put(ctx, m.Hash, content, state, c)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					indexCounter.WithLabelValues("true").Inc()
+					zlog.Debug(ctx).
+						Stringer("canonical", c).
+						Msg("using report for identical content")
+					i.release(ctx, prev)
+					return rehash(r, m.Hash), nil
+				}
+			}
+		}
+	}
+
+	indexCounter.WithLabelValues("false").Inc()
+	r, err := i.Service.Index(ctx, m)
+	if err != nil || !r.Success {
+		return r, err
+	}
+	if _, err := i.store.Put(ctx, m.Hash, content, state, m.Hash); err != nil {
+		return nil, err
+	}
+	i.release(ctx, prev)
+	return r, nil
+}
+
+// Release removes the data for the canonical manifest "prev" referenced, if
+// it's been deleted and "m" was its last reference.
+//
+// Errors are logged; at worst, a report is kept longer than needed.
+func (i *Indexer) release(ctx context.Context, prev *Entry) {
+	if prev == nil || prev.IsCanonical() {
+		return
+	}
+	unreferenced, err := i.store.Release(ctx, prev.Canonical)
+	if err == nil && unreferenced {
+		err = i.remove(ctx, prev.Canonical)
+	}
+	if err != nil {
+		zlog.Warn(ctx).Err(err).
+			Stringer("canonical", prev.Canonical).
+			Msg("unable to release previous report")
+	}
+}
+
+// IndexReport implements indexer.Reporter.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	e, err := i.store.Lookup(ctx, d)
+	switch {
+	case err != nil:
+		return nil, false, err
+	case e == nil:
+		return i.Service.IndexReport(ctx, d)
+	case e.IsCanonical() && e.Deleted:
+		return nil, false, nil
+	case e.IsCanonical():
+		return i.Service.IndexReport(ctx, d)
+	}
+	r, ok, err := i.Service.IndexReport(ctx, e.Canonical)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return rehash(r, d), true, nil
+}
+
+// DeleteManifests implements indexer.Indexer.
+//
+// A canonical manifest's data is only removed once no manifest references
+// it.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/dedup/Indexer.DeleteManifests")
+	var pass, out []claircore.Digest
+	for _, d := range ds {
+		e, err := i.store.Lookup(ctx, d)
+		if err != nil {
+			return out, err
+		}
+		if e == nil {
+			pass = append(pass, d)
+			continue
+		}
+		if e.IsCanonical() && e.Deleted {
+			// Already deleted.
+			continue
+		}
+		if err := i.purge(ctx, e); err != nil {
+			return out, err
+		}
+		out = append(out, d)
+	}
+	if len(pass) == 0 {
+		return out, nil
+	}
+	rm, err := i.Service.DeleteManifests(ctx, pass...)
+	return append(out, rm...), err
+}
+
+// Purge removes the entry, then the canonical manifest's data if it's no
+// longer referenced.
+func (i *Indexer) purge(ctx context.Context, e *Entry) error {
+	unreferenced, err := i.store.Remove(ctx, e)
+	if err != nil || !unreferenced {
+		return err
+	}
+	return i.remove(ctx, e.Canonical)
+}
+
+// Remove deletes the data for an unreferenced canonical manifest.
+func (i *Indexer) remove(ctx context.Context, c claircore.Digest) error {
+	zlog.Debug(ctx).
+		Stringer("canonical", c).
+		Msg("removing unreferenced report")
+	_, err := i.Service.DeleteManifests(ctx, c)
+	return err
+}
+
+// AffectedManifests implements indexer.Affected.
+//
+// Results for canonical manifests are expanded to every manifest referencing
+// them.
+func (i *Indexer) AffectedManifests(ctx context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+	a, err := i.Service.AffectedManifests(ctx, vs)
+	if err != nil || len(a.VulnerableManifests) == 0 {
+		return a, err
+	}
+	cs := make([]string, 0, len(a.VulnerableManifests))
+	for d := range a.VulnerableManifests {
+		cs = append(cs, d)
+	}
+	refs, err := i.store.References(ctx, cs)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return a, nil
+	}
+	out := make(map[string][]string, len(a.VulnerableManifests))
+	for d, ids := range a.VulnerableManifests {
+		ms, ok := refs[d]
+		if !ok {
+			// Not deduplicated.
+			out[d] = ids
+			continue
+		}
+		for _, m := range ms {
+			out[m] = ids
+		}
+	}
+	a.VulnerableManifests = out
+	return a, nil
+}
+
+// Rehash returns a shallow copy of "r" describing the manifest "d".
+func rehash(r *claircore.IndexReport, d claircore.Digest) *claircore.IndexReport {
+	cp := *r
+	cp.Hash = d
+	return &cp
+}
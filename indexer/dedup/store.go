@@ -0,0 +1,237 @@
+// Package dedup implements storing a single index report for manifests with
+// identical content.
+//
+// Claircore indexes a manifest's layers, so manifests listing the same layers
+// in the same order produce the same index report. The first such manifest
+// indexed becomes the canonical manifest; later ones are recorded as
+// references to it and are served its report. The canonical manifest's data
+// is kept until no manifest references it.
+package dedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_dedup", "index report deduplication")
+
+// Store records which manifests reference which canonical manifest.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Entry is the record for a single manifest.
+type Entry struct {
+	Manifest  claircore.Digest
+	Canonical claircore.Digest
+	// Deleted is set on a canonical manifest that's been deleted while still
+	// referenced.
+	Deleted bool
+}
+
+// IsCanonical reports whether the Entry is for a canonical manifest.
+func (e *Entry) IsCanonical() bool {
+	return e.Manifest.String() == e.Canonical.String()
+}
+
+// Lookup returns the Entry for the manifest, or nil if there isn't one.
+func (s *Store) Lookup(ctx context.Context, d claircore.Digest) (_ *Entry, err error) {
+	const query = `SELECT canonical, deleted FROM indexer_dedup WHERE manifest = $1;`
+	defer queryMetrics.Observe("lookup", &err)()
+	e := Entry{Manifest: d}
+	var c string
+	err = s.pool.QueryRow(ctx, query, d.String()).Scan(&c, &e.Deleted)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("dedup: unable to look up %v: %w", d, err)
+	}
+	if e.Canonical, err = claircore.ParseDigest(c); err != nil {
+		return nil, fmt.Errorf("dedup: bad canonical digest for %v: %w", d, err)
+	}
+	return &e, nil
+}
+
+// Canonical returns the canonical manifest for the content hash and indexer
+// state, if any.
+func (s *Store) Canonical(ctx context.Context, content, state string) (_ claircore.Digest, ok bool, err error) {
+	const query = `SELECT canonical FROM indexer_dedup
+WHERE content = $1 AND state = $2 AND manifest = canonical
+LIMIT 1;`
+	defer queryMetrics.Observe("canonical", &err)()
+	var c string
+	err = s.pool.QueryRow(ctx, query, content, state).Scan(&c)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return claircore.Digest{}, false, nil
+	default:
+		return claircore.Digest{}, false, fmt.Errorf("dedup: unable to find canonical manifest: %w", err)
+	}
+	d, err := claircore.ParseDigest(c)
+	if err != nil {
+		return claircore.Digest{}, false, fmt.Errorf("dedup: bad canonical digest: %w", err)
+	}
+	return d, true, nil
+}
+
+// Put records "m" as referencing "canonical", which may be "m" itself. It
+// reports false if "canonical" is no longer recorded, in which case nothing
+// is changed.
+func (s *Store) Put(ctx context.Context, m claircore.Digest, content, state string, canonical claircore.Digest) (ok bool, err error) {
+	// The lock on the canonical row keeps a concurrent Remove from purging
+	// it out from under the new reference.
+	const query = `WITH c AS (
+	SELECT 1 FROM indexer_dedup WHERE manifest = $4 FOR SHARE
+)
+INSERT INTO indexer_dedup (manifest, content, state, canonical)
+SELECT $1, $2, $3, $4
+WHERE $1 = $4 OR EXISTS (SELECT 1 FROM c)
+ON CONFLICT (manifest) DO UPDATE
+SET content = EXCLUDED.content, state = EXCLUDED.state,
+	canonical = EXCLUDED.canonical, deleted = false;`
+	defer queryMetrics.Observe("put", &err)()
+	tag, err := s.pool.Exec(ctx, query, m.String(), content, state, canonical.String())
+	if err != nil {
+		return false, fmt.Errorf("dedup: unable to record %v: %w", m, err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+const (
+	lockCanonical = `SELECT deleted FROM indexer_dedup WHERE manifest = $1 FOR UPDATE;`
+	countRefs     = `SELECT count(*) FROM indexer_dedup WHERE canonical = $1 AND NOT deleted;`
+	purge         = `DELETE FROM indexer_dedup WHERE canonical = $1 OR manifest = $1;`
+)
+
+// Remove deletes the record for a manifest referencing another manifest, or
+// marks it deleted if it's canonical. It reports whether the canonical
+// manifest is now unreferenced, in which case its records are gone and its
+// data should be removed from the indexer.
+func (s *Store) Remove(ctx context.Context, e *Entry) (unreferenced bool, err error) {
+	const (
+		remove    = `DELETE FROM indexer_dedup WHERE manifest = $1;`
+		tombstone = `UPDATE indexer_dedup SET deleted = true WHERE manifest = $1;`
+	)
+	defer queryMetrics.Observe("remove", &err)()
+	err = s.pool.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		present, deleted, err := lock(ctx, tx, e.Canonical)
+		if err != nil {
+			return err
+		}
+		q := remove
+		if e.IsCanonical() {
+			q, deleted = tombstone, true
+		}
+		if _, err := tx.Exec(ctx, q, e.Manifest.String()); err != nil {
+			return err
+		}
+		if present && !deleted {
+			return nil
+		}
+		unreferenced, err = collect(ctx, tx, e.Canonical)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("dedup: unable to remove %v: %w", e.Manifest, err)
+	}
+	return unreferenced, nil
+}
+
+// Release is called after a reference to "c" has been replaced. It reports
+// whether "c" was deleted and is now unreferenced, in which case its records
+// are gone and its data should be removed from the indexer.
+func (s *Store) Release(ctx context.Context, c claircore.Digest) (unreferenced bool, err error) {
+	defer queryMetrics.Observe("release", &err)()
+	err = s.pool.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		present, deleted, err := lock(ctx, tx, c)
+		if err != nil {
+			return err
+		}
+		if !present || !deleted {
+			return nil
+		}
+		unreferenced, err = collect(ctx, tx, c)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("dedup: unable to release %v: %w", c, err)
+	}
+	return unreferenced, nil
+}
+
+// Lock locks the row for the canonical manifest "c", reporting whether it
+// exists and whether it's marked deleted.
+//
+// The row is only missing if it was purged concurrently.
+func lock(ctx context.Context, tx pgx.Tx, c claircore.Digest) (present, deleted bool, err error) {
+	err = tx.QueryRow(ctx, lockCanonical, c.String()).Scan(&deleted)
+	switch {
+	case err == nil:
+		return true, deleted, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		return false, false, nil
+	default:
+		return false, false, err
+	}
+}
+
+// Collect purges the records for "c" if nothing references it, reporting
+// whether it did.
+func collect(ctx context.Context, tx pgx.Tx, c claircore.Digest) (bool, error) {
+	var n int64
+	if err := tx.QueryRow(ctx, countRefs, c.String()).Scan(&n); err != nil {
+		return false, err
+	}
+	if n != 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, purge, c.String()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// References returns the manifests referencing each of the provided
+// canonical manifests, keyed by canonical manifest. Deleted canonical
+// manifests are omitted.
+func (s *Store) References(ctx context.Context, cs []string) (_ map[string][]string, err error) {
+	const query = `SELECT manifest, canonical FROM indexer_dedup
+WHERE canonical = ANY($1) AND NOT deleted;`
+	defer queryMetrics.Observe("references", &err)()
+	rows, err := s.pool.Query(ctx, query, cs)
+	if err != nil {
+		return nil, fmt.Errorf("dedup: unable to find references: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string][]string)
+	for rows.Next() {
+		var m, c string
+		if err = rows.Scan(&m, &c); err != nil {
+			return nil, fmt.Errorf("dedup: unable to find references: %w", err)
+		}
+		out[c] = append(out[c], m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("dedup: unable to find references: %w", err)
+	}
+	return out, nil
+}
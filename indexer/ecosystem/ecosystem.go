@@ -0,0 +1,170 @@
+// Package ecosystem selects the claircore ecosystems an indexer runs.
+//
+// Ecosystems bundle the package, distribution, and repository scanners for a
+// kind of package along with the coalescer that puts their results together.
+// Selection works on package scanner names: an ecosystem left without any
+// package scanners is dropped entirely, so its other scanners don't run
+// either.
+package ecosystem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/dpkg"
+	"github.com/quay/claircore/gobin"
+	"github.com/quay/claircore/indexer"
+	"github.com/quay/claircore/java"
+	"github.com/quay/claircore/python"
+	"github.com/quay/claircore/rhel"
+	"github.com/quay/claircore/rhel/rhcc"
+	"github.com/quay/claircore/rpm"
+	"github.com/quay/claircore/ruby"
+)
+
+// These are the group names that can be used in place of package scanner
+// names.
+const (
+	// GroupOS is the scanners of distribution packages.
+	GroupOS = `os`
+	// GroupLanguage is the scanners of language packages.
+	GroupLanguage = `language`
+)
+
+var groups = map[string][]string{
+	GroupOS:       {"dpkg", "dpkg-distroless", "apk", "rpm"},
+	GroupLanguage: {"python", "java", "gobin", "ruby"},
+}
+
+// Default returns the ecosystems libindex uses when none are provided.
+func Default(ctx context.Context) []*indexer.Ecosystem {
+	return []*indexer.Ecosystem{
+		dpkg.NewEcosystem(ctx),
+		alpine.NewEcosystem(ctx),
+		rhel.NewEcosystem(ctx),
+		rpm.NewEcosystem(ctx),
+		python.NewEcosystem(ctx),
+		java.NewEcosystem(ctx),
+		rhcc.NewEcosystem(ctx),
+		gobin.NewEcosystem(ctx),
+		ruby.NewEcosystem(ctx),
+	}
+}
+
+// Select returns the default ecosystems, keeping only the package scanners
+// named in "enable" if it's not empty, and then removing the package scanners
+// named in "disable". Group names expand to their members.
+//
+// Naming a package scanner that isn't in any default ecosystem is an error.
+func Select(ctx context.Context, enable, disable []string) ([]*indexer.Ecosystem, error) {
+	all := Default(ctx)
+	if len(enable) == 0 && len(disable) == 0 {
+		return all, nil
+	}
+	known := make(map[string]struct{})
+	for _, e := range all {
+		ps, err := e.PackageScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ps {
+			known[s.Name()] = struct{}{}
+		}
+	}
+	expand := func(names []string) (map[string]struct{}, error) {
+		out := make(map[string]struct{}, len(names))
+		for _, n := range names {
+			if g, ok := groups[n]; ok {
+				for _, n := range g {
+					out[n] = struct{}{}
+				}
+				continue
+			}
+			if _, ok := known[n]; !ok {
+				return nil, fmt.Errorf("unknown package scanner %q (known: %s)", n, strings.Join(sortedKeys(known), ", "))
+			}
+			out[n] = struct{}{}
+		}
+		return out, nil
+	}
+	en, err := expand(enable)
+	if err != nil {
+		return nil, err
+	}
+	dis, err := expand(disable)
+	if err != nil {
+		return nil, err
+	}
+	keep := func(n string) bool {
+		if len(en) != 0 {
+			if _, ok := en[n]; !ok {
+				return false
+			}
+		}
+		_, ok := dis[n]
+		return !ok
+	}
+
+	var out []*indexer.Ecosystem
+	for _, e := range all {
+		ps, err := e.PackageScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var kept []indexer.PackageScanner
+		for _, s := range ps {
+			if keep(s.Name()) {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		f := *e
+		f.PackageScanners = func(context.Context) ([]indexer.PackageScanner, error) {
+			return kept, nil
+		}
+		out = append(out, &f)
+	}
+	return out, nil
+}
+
+// Scanner identifies a scanner an indexer runs.
+type Scanner struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// Scanners returns the scanners the ecosystems "es" run, sorted by kind and
+// name.
+func Scanners(ctx context.Context, es []*indexer.Ecosystem) ([]Scanner, error) {
+	ps, ds, rs, fs, err := indexer.EcosystemsToScanners(ctx, es)
+	if err != nil {
+		return nil, err
+	}
+	vs := indexer.MergeVS(ps, ds, rs, fs)
+	out := make([]Scanner, len(vs))
+	for i, v := range vs {
+		out[i] = Scanner{Name: v.Name(), Version: v.Version(), Kind: v.Kind()}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
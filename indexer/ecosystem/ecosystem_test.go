@@ -0,0 +1,91 @@
+package ecosystem
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+)
+
+func TestSelect(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name    string
+		Enable  []string
+		Disable []string
+		// Want is the package scanners expected, or nil if an error is
+		// expected.
+		Want []string
+		// Also is a scanner of another kind expected to be present.
+		Also string
+	}{
+		{
+			Name: "Default",
+			Want: []string{"apk", "dpkg", "dpkg-distroless", "gobin", "java", "python", "rhel_containerscanner", "rpm", "ruby"},
+			Also: "pip",
+		},
+		{
+			Name:   "OS",
+			Enable: []string{GroupOS},
+			Want:   []string{"apk", "dpkg", "dpkg-distroless", "rpm"},
+			Also:   "rhel-repository-scanner",
+		},
+		{
+			Name:    "NoPython",
+			Disable: []string{"python"},
+			Want:    []string{"apk", "dpkg", "dpkg-distroless", "gobin", "java", "rhel_containerscanner", "rpm", "ruby"},
+			Also:    "maven",
+		},
+		{
+			Name:    "NoLanguage",
+			Disable: []string{GroupLanguage, "rhel_containerscanner"},
+			Want:    []string{"apk", "dpkg", "dpkg-distroless", "rpm"},
+		},
+		{
+			Name:   "Unknown",
+			Enable: []string{"cobol"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			es, err := Select(ctx, tc.Enable, tc.Disable)
+			if tc.Want == nil {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				t.Log(err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ss, err := Scanners(ctx, es)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			all := make(map[string]bool)
+			for _, s := range ss {
+				all[s.Name] = true
+				if s.Kind == "package" {
+					got = append(got, s.Name)
+				}
+			}
+			sort.Strings(got)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+			if tc.Also != "" && !all[tc.Also] {
+				t.Errorf("missing scanner %q", tc.Also)
+			}
+			// Python's repository scanner should only run with the python
+			// package scanner.
+			if got, want := all["pip"], all["python"]; got != want {
+				t.Errorf("pip scanner: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
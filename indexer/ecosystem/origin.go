@@ -0,0 +1,243 @@
+package ecosystem
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/clair/v4/indexer/lockfiles"
+	"github.com/quay/clair/v4/indexer/runtimes"
+)
+
+// These are the origins a package can be classified as, besides an ordinary
+// install.
+const (
+	// OriginVendored is a package found in a dependency directory bundled
+	// with another project, like "vendor" or "node_modules".
+	OriginVendored = `vendored`
+	// OriginCache is a package found in a package manager's download cache,
+	// like "~/.m2/repository" or "~/.cache/pip".
+	OriginCache = `cache`
+)
+
+// VendorDirs are the directory names that hold vendored dependencies.
+var vendorDirs = map[string]struct{}{
+	"vendor":           {},
+	"_vendor":          {},
+	"node_modules":     {},
+	"bower_components": {},
+}
+
+// CacheDirs are the path fragments that hold package manager caches.
+var cacheDirs = []string{
+	".m2/repository/",
+	".gradle/caches/",
+	".ivy2/cache/",
+	".cache/pip/",
+	".cache/yarn/",
+	".npm/_cacache/",
+	".yarn/cache/",
+	".bundle/cache/",
+	".gem/cache/",
+	"go/pkg/mod/",
+	".cargo/registry/",
+}
+
+// Origin classifies the package database "db" of a package, returning
+// OriginVendored, OriginCache, or "" for an ordinary install.
+//
+// Vendored dependencies and caches are recognized by the directories they're
+// kept in, so a package cache inside a vendored project counts as a cache.
+func Origin(db string) string {
+	if i := strings.IndexByte(db, ':'); i != -1 && !strings.Contains(db[:i], "/") {
+		db = db[i+1:]
+	}
+	if db == "" {
+		return ""
+	}
+	p := strings.TrimPrefix(path.Clean("/"+db), "/")
+	for _, c := range cacheDirs {
+		if strings.HasPrefix(p, c) || strings.Contains(p, "/"+c) {
+			return OriginCache
+		}
+	}
+	for _, seg := range strings.Split(path.Dir(p), "/") {
+		if _, ok := vendorDirs[seg]; ok {
+			return OriginVendored
+		}
+	}
+	return ""
+}
+
+// Origins returns the origin of every package in "ir" that isn't an ordinary
+// install, keyed by package ID. A package that's also installed ordinarily
+// somewhere isn't reported; otherwise, the first place it was found decides.
+func Origins(ir *claircore.IndexReport) map[string]string {
+	out := make(map[string]string)
+	for id, envs := range ir.Environments {
+		if len(envs) == 0 {
+			continue
+		}
+		o := Origin(envs[0].PackageDB)
+		for _, env := range envs[1:] {
+			if o == "" {
+				break
+			}
+			if Origin(env.PackageDB) == "" {
+				o = ""
+			}
+		}
+		if o != "" {
+			out[id] = o
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// Exclude returns the ecosystems "es" with their package scanners changed to
+// drop packages with any of the origins "origins".
+//
+// The returned scanners report a different version than the ones they wrap,
+// so layers already scanned are scanned again with the exclusion applied.
+func Exclude(ctx context.Context, es []*indexer.Ecosystem, origins ...string) ([]*indexer.Ecosystem, error) {
+	if len(origins) == 0 {
+		return es, nil
+	}
+	drop := make(map[string]struct{}, len(origins))
+	for _, o := range origins {
+		drop[o] = struct{}{}
+	}
+	names := sortedKeys(drop)
+	out := make([]*indexer.Ecosystem, len(es))
+	for i, e := range es {
+		ps, err := e.PackageScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		wrapped := make([]indexer.PackageScanner, len(ps))
+		for j, s := range ps {
+			wrapped[j] = exclude(&excluding{
+				PackageScanner: s,
+				drop:           drop,
+				version:        s.Version() + "+exclude=" + strings.Join(names, ","),
+			})
+		}
+		f := *e
+		f.PackageScanners = func(context.Context) ([]indexer.PackageScanner, error) {
+			return wrapped, nil
+		}
+		out[i] = &f
+	}
+	return out, nil
+}
+
+// Exclude returns "s" as a type implementing the same optional interfaces as
+// the scanner it wraps.
+func exclude(s *excluding) indexer.PackageScanner {
+	switch inner := s.PackageScanner.(type) {
+	case indexer.RPCScanner:
+		return &excludingRPC{excluding: s, rpc: inner}
+	case indexer.ConfigurableScanner:
+		return &excludingConfigurable{excluding: s, cfg: inner}
+	case indexer.DefaultRepoScanner:
+		return &excludingDefaultRepo{excluding: s, repo: inner}
+	}
+	return s
+}
+
+// Excluding is a package scanner that drops packages by origin.
+type excluding struct {
+	indexer.PackageScanner
+	drop    map[string]struct{}
+	version string
+}
+
+// Version implements indexer.VersionedScanner.
+func (s *excluding) Version() string { return s.version }
+
+// Scan implements indexer.PackageScanner.
+func (s *excluding) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Package, error) {
+	ps, err := s.PackageScanner.Scan(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	out := ps[:0]
+	for _, p := range ps {
+		if _, ok := s.drop[Origin(p.PackageDB)]; ok {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+type excludingRPC struct {
+	*excluding
+	rpc indexer.RPCScanner
+}
+
+// Configure implements indexer.RPCScanner.
+func (s *excludingRPC) Configure(ctx context.Context, f indexer.ConfigDeserializer, c *http.Client) error {
+	return s.rpc.Configure(ctx, f, c)
+}
+
+type excludingConfigurable struct {
+	*excluding
+	cfg indexer.ConfigurableScanner
+}
+
+// Configure implements indexer.ConfigurableScanner.
+func (s *excludingConfigurable) Configure(ctx context.Context, f indexer.ConfigDeserializer) error {
+	return s.cfg.Configure(ctx, f)
+}
+
+type excludingDefaultRepo struct {
+	*excluding
+	repo indexer.DefaultRepoScanner
+}
+
+// DefaultRepository implements indexer.DefaultRepoScanner.
+func (s *excludingDefaultRepo) DefaultRepository(ctx context.Context) *claircore.Repository {
+	return s.repo.DefaultRepository(ctx)
+}
+
+// Excluded returns the origins the scanner configuration "sc" excludes.
+func Excluded(sc *config.ScannerConfig) []string {
+	var out []string
+	if sc.Vendored == config.ScannerExclude {
+		out = append(out, OriginVendored)
+	}
+	if sc.Caches == config.ScannerExclude {
+		out = append(out, OriginCache)
+	}
+	return out
+}
+
+// Configured returns the ecosystems the scanner configuration "sc" selects,
+// along with the runtime and lockfile ecosystems it enables, with any
+// excluded origins dropped.
+func Configured(ctx context.Context, sc *config.ScannerConfig) ([]*indexer.Ecosystem, error) {
+	es, err := Select(ctx, sc.Enable, sc.Disable)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Runtimes {
+		es = append(es, runtimes.NewEcosystem(ctx))
+	}
+	for _, n := range sc.Lockfiles {
+		e, err := lockfiles.NewEcosystem(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+	return Exclude(ctx, es, Excluded(sc)...)
+}
@@ -0,0 +1,140 @@
+package ecosystem
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer"
+	"github.com/quay/zlog"
+)
+
+func TestOrigin(t *testing.T) {
+	tt := []struct {
+		DB   string
+		Want string
+	}{
+		{DB: "var/lib/dpkg/status", Want: ""},
+		{DB: "python:usr/lib/python3.9/site-packages", Want: ""},
+		{DB: "python:app/vendor/lib/python3.9/site-packages", Want: OriginVendored},
+		{DB: "jar:srv/app/node_modules/thing/lib/x.jar", Want: OriginVendored},
+		{DB: "maven:root/.m2/repository/org/example/x/1.0/x-1.0.jar", Want: OriginCache},
+		{DB: "file:home/user/.gradle/caches/modules-2/files-2.1/x.jar", Want: OriginCache},
+		{DB: "go:root/go/pkg/mod/example.com/tool@v1.0.0/tool", Want: OriginCache},
+		{DB: "usr/share/gems/specifications/rake.gemspec", Want: ""},
+		// Only directories count, not the file itself.
+		{DB: "srv/app/vendor", Want: ""},
+		{DB: "", Want: ""},
+	}
+	for _, tc := range tt {
+		if got := Origin(tc.DB); got != tc.Want {
+			t.Errorf("%q: got: %q, want: %q", tc.DB, got, tc.Want)
+		}
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{
+			"1": {{PackageDB: "python:usr/lib/python3/site-packages"}},
+			"2": {{PackageDB: "python:app/vendor/site-packages"}},
+			"3": {
+				{PackageDB: "python:app/vendor/site-packages"},
+				{PackageDB: "python:usr/lib/python3/site-packages"},
+			},
+			"4": {
+				{PackageDB: "python:root/.cache/pip/x"},
+				{PackageDB: "python:app/vendor/site-packages"},
+			},
+		},
+	}
+	want := map[string]string{"2": OriginVendored, "4": OriginCache}
+	if got := Origins(ir); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+type fakeScanner struct{ pkgs []*claircore.Package }
+
+func (fakeScanner) Name() string    { return "fake" }
+func (fakeScanner) Version() string { return "1" }
+func (fakeScanner) Kind() string    { return "package" }
+
+func (s fakeScanner) Scan(context.Context, *claircore.Layer) ([]*claircore.Package, error) {
+	return append([]*claircore.Package(nil), s.pkgs...), nil
+}
+
+func TestExclude(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+
+	t.Run("Scan", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s := fakeScanner{pkgs: []*claircore.Package{
+			{Name: "installed", PackageDB: "python:usr/lib/python3/site-packages"},
+			{Name: "vendored", PackageDB: "python:app/vendor/site-packages"},
+			{Name: "cached", PackageDB: "maven:root/.m2/repository/x.jar"},
+		}}
+		es, err := Exclude(ctx, []*indexer.Ecosystem{{
+			PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+				return []indexer.PackageScanner{s}, nil
+			},
+		}}, OriginVendored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps, err := es[0].PackageScanners(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ps[0].Version(), "1+exclude=vendored"; got != want {
+			t.Errorf("version: got: %q, want: %q", got, want)
+		}
+		pkgs, err := ps[0].Scan(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range pkgs {
+			got = append(got, p.Name)
+		}
+		if want := []string{"installed", "cached"}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+
+	t.Run("Interfaces", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		es, err := Exclude(ctx, Default(ctx), OriginVendored, OriginCache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make(map[string]bool)
+		for _, e := range es {
+			ps, err := e.PackageScanners(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range ps {
+				seen[s.Name()] = true
+				if !strings.HasSuffix(s.Version(), "+exclude=cache,vendored") {
+					t.Errorf("%s: unexpected version %q", s.Name(), s.Version())
+				}
+				switch s.Name() {
+				case "java":
+					if _, ok := s.(indexer.RPCScanner); !ok {
+						t.Error("java: not an RPCScanner")
+					}
+				case "gobin":
+					if _, ok := s.(indexer.DefaultRepoScanner); !ok {
+						t.Error("gobin: not a DefaultRepoScanner")
+					}
+				}
+			}
+		}
+		if !seen["java"] || !seen["gobin"] {
+			t.Errorf("missing scanners: %v", seen)
+		}
+	})
+}
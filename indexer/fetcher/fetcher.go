@@ -0,0 +1,198 @@
+// Package fetcher lets downstream builds supply layer contents from sources
+// other than the registry, such as an internal content-addressable store or a
+// peer-to-peer distribution system.
+//
+// A plugin implements Fetcher and registers a Factory for it under a name from
+// an init function. The plugins named in the indexer's configuration are asked
+// for each layer, in name order, before the layer is requested from the
+// registry. Layers are still verified against their digest, so a plugin can't
+// substitute different contents.
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+var fetchCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer",
+		Name:      "layer_fetcher_total",
+		Help:      "Total number of layers requested from fetcher plugins.",
+	},
+	[]string{"fetcher", "result"},
+)
+
+// Layer describes a layer being fetched.
+type Layer struct {
+	// Digest is the layer's digest, e.g. "sha256:...".
+	Digest string
+	// URL is where the layer would be fetched from in the registry.
+	URL *url.URL
+	// Header holds the headers the registry request would carry, including
+	// any credentials.
+	Header http.Header
+}
+
+// Fetcher is the interface implemented by fetcher plugins.
+type Fetcher interface {
+	// Fetch returns the contents of the layer, exactly as the registry would
+	// serve them (that is, still compressed).
+	//
+	// A nil ReadCloser and nil error means the Fetcher doesn't have the
+	// layer. An error means the Fetcher couldn't be asked. In either case,
+	// the next plugin, and finally the registry, is tried.
+	Fetch(ctx context.Context, l *Layer) (io.ReadCloser, error)
+}
+
+// Factory constructs a Fetcher. The "cfg" function decodes the plugin's
+// configuration block into its argument.
+type Factory func(ctx context.Context, cfg func(interface{}) error) (Fetcher, error)
+
+var registry struct {
+	sync.Mutex
+	m map[string]Factory
+}
+
+// Register makes the Factory "f" available as the plugin "name".
+//
+// Register is meant to be called from the init function of a package
+// compiled into a downstream build. It panics if "name" is registered twice.
+func Register(name string, f Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.m == nil {
+		registry.m = make(map[string]Factory)
+	}
+	if _, ok := registry.m[name]; ok {
+		panic(fmt.Sprintf("fetcher: plugin %q registered twice", name))
+	}
+	registry.m[name] = f
+}
+
+// Plugins returns the names of the registered plugins.
+func Plugins() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	out := make([]string, 0, len(registry.m))
+	for n := range registry.m {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Source is a Fetcher constructed by a plugin.
+type Source struct {
+	name string
+	f    Fetcher
+}
+
+// New returns a Source using the Fetcher constructed by the plugin "name".
+func New(ctx context.Context, name string, cfg func(interface{}) error) (*Source, error) {
+	registry.Lock()
+	f, ok := registry.m[name]
+	registry.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fetcher: unknown plugin %q", name)
+	}
+	ft, err := f(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("fetcher: plugin %q: %w", name, err)
+	}
+	return &Source{name: name, f: ft}, nil
+}
+
+// Transport wraps the provided RoundTripper so that layer requests are
+// offered to each Source in turn before being sent to "next".
+//
+// Layer requests are GET requests for a registry blob path, i.e. ones ending
+// in "/blobs/<digest>". Other requests are sent to "next" unmodified.
+func Transport(next http.RoundTripper, srcs ...*Source) http.RoundTripper {
+	if len(srcs) == 0 {
+		return next
+	}
+	return &transport{next: next, srcs: srcs}
+}
+
+type transport struct {
+	next http.RoundTripper
+	srcs []*Source
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d, ok := layerDigest(req)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	ctx := zlog.ContextWithValues(req.Context(),
+		"component", "indexer/fetcher/transport.RoundTrip",
+		"layer", d)
+	l := &Layer{
+		Digest: d,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+	}
+	for _, s := range t.srcs {
+		rc, err := s.f.Fetch(ctx, l)
+		switch {
+		case err != nil:
+			fetchCounter.WithLabelValues(s.name, "error").Inc()
+			zlog.Warn(ctx).
+				Err(err).
+				Str("fetcher", s.name).
+				Msg("fetcher plugin failed, trying next source")
+			continue
+		case rc == nil:
+			fetchCounter.WithLabelValues(s.name, "miss").Inc()
+			continue
+		}
+		fetchCounter.WithLabelValues(s.name, "hit").Inc()
+		zlog.Debug(ctx).
+			Str("fetcher", s.name).
+			Msg("layer supplied by fetcher plugin")
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			// Let the fetch arena detect the compression.
+			Header:        http.Header{"Content-Type": {"application/octet-stream"}},
+			Body:          rc,
+			ContentLength: -1,
+			Request:       req,
+		}, nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// LayerDigest reports the digest named by a layer request's path.
+func layerDigest(req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet {
+		return "", false
+	}
+	p := req.URL.Path
+	if path.Base(path.Dir(p)) != "blobs" {
+		return "", false
+	}
+	d := path.Base(p)
+	alg, sum, ok := strings.Cut(d, ":")
+	if !ok || alg == "" || sum == "" {
+		return "", false
+	}
+	return d, true
+}
@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Cas is a test Fetcher serving a fixed set of blobs.
+type cas struct {
+	blobs map[string]string
+}
+
+func (c *cas) Fetch(_ context.Context, l *Layer) (io.ReadCloser, error) {
+	b, ok := c.blobs[l.Digest]
+	switch {
+	case !ok:
+		return nil, nil
+	case b == "broken":
+		return nil, errors.New("store unavailable")
+	}
+	return io.NopCloser(strings.NewReader(b)), nil
+}
+
+func init() {
+	Register("test-cas", func(_ context.Context, cfg func(interface{}) error) (Fetcher, error) {
+		var c struct {
+			Blobs map[string]string `json:"blobs"`
+		}
+		if err := cfg(&c); err != nil {
+			return nil, err
+		}
+		if c.Blobs == nil {
+			return nil, errors.New("blobs required")
+		}
+		return &cas{blobs: c.Blobs}, nil
+	})
+}
+
+func TestTransport(t *testing.T) {
+	ctx := context.Background()
+	blobs := func(m map[string]string) func(interface{}) error {
+		return func(v interface{}) error {
+			v.(*struct {
+				Blobs map[string]string `json:"blobs"`
+			}).Blobs = m
+			return nil
+		}
+	}
+
+	if _, err := New(ctx, "missing", blobs(nil)); err == nil {
+		t.Error("unknown plugin: unexpected success")
+	}
+	if _, err := New(ctx, "test-cas", blobs(nil)); err == nil {
+		t.Error("bad config: unexpected success")
+	}
+	src, err := New(ctx, "test-cas", blobs(map[string]string{
+		"sha256:aaaa": "from cas",
+		"sha256:bbbb": "broken",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "from registry")
+	}))
+	defer srv.Close()
+	c := srv.Client()
+	c.Transport = Transport(c.Transport, src)
+
+	tt := []struct {
+		Name   string
+		Method string
+		Path   string
+		Want   string
+	}{
+		{Name: "Hit", Method: http.MethodGet, Path: "/v2/repo/blobs/sha256:aaaa", Want: "from cas"},
+		{Name: "Miss", Method: http.MethodGet, Path: "/v2/repo/blobs/sha256:cccc", Want: "from registry"},
+		{Name: "Error", Method: http.MethodGet, Path: "/v2/repo/blobs/sha256:bbbb", Want: "from registry"},
+		{Name: "NotBlob", Method: http.MethodGet, Path: "/v2/repo/manifests/sha256:aaaa", Want: "from registry"},
+		{Name: "NotGet", Method: http.MethodPost, Path: "/v2/repo/blobs/sha256:aaaa", Want: "from registry"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tc.Method, srv.URL+tc.Path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Errorf("status: got: %d, want: %d", got, want)
+			}
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(b), tc.Want; got != want {
+				t.Errorf("body: got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// FileOwnerReporter is implemented by indexers that record the files
+// installed by packages.
+type FileOwnerReporter interface {
+	// FileOwners returns the packages in the report recorded as installing
+	// the path.
+	FileOwners(ctx context.Context, report *claircore.IndexReport, path string) ([]FileOwner, error)
+}
+
+// FileOwner is a package found to own a given path in a manifest.
+type FileOwner struct {
+	Package     *claircore.Package     `json:"package"`
+	Environment *claircore.Environment `json:"environment"`
+	// Exact reports whether the package owns the path itself, because it
+	// installed the path or the path is its package database, as opposed to
+	// a directory containing it.
+	Exact bool `json:"exact"`
+}
+
+// FileOwners reports the packages in the IndexReport that own the provided
+// path.
+//
+// Ownership is determined by the package database recorded in each
+// package's environment: language packages record the archive or directory
+// they were found in, so lookups for those are precise. Distribution
+// packages only record the distribution's package database, so a path is
+// only attributed to them if the path names the database itself; a
+// FileOwnerReporter can attribute the files they installed.
+func FileOwners(ir *claircore.IndexReport, p string) []FileOwner {
+	want := cleanPath(p)
+	if want == "" {
+		return nil
+	}
+	var out []FileOwner
+	for id, envs := range ir.Environments {
+		pkg, ok := ir.Packages[id]
+		if !ok {
+			continue
+		}
+		for _, env := range envs {
+			db := cleanPath(env.PackageDB)
+			switch {
+			case db == "":
+			case db == want:
+				out = append(out, FileOwner{Package: pkg, Environment: env, Exact: true})
+			case strings.HasPrefix(want, db+"/"):
+				out = append(out, FileOwner{Package: pkg, Environment: env})
+			}
+		}
+	}
+	sortOwners(out)
+	return out
+}
+
+// MergeFileOwners combines the owners in "a" and "b", listing each package
+// once and preferring an exact match.
+func MergeFileOwners(a, b []FileOwner) []FileOwner {
+	out := make([]FileOwner, 0, len(a)+len(b))
+	idx := make(map[string]int, len(a)+len(b))
+	for _, list := range [][]FileOwner{a, b} {
+		for _, o := range list {
+			i, ok := idx[o.Package.ID]
+			switch {
+			case !ok:
+				idx[o.Package.ID] = len(out)
+				out = append(out, o)
+			case o.Exact && !out[i].Exact:
+				out[i] = o
+			}
+		}
+	}
+	sortOwners(out)
+	return out
+}
+
+func sortOwners(fo []FileOwner) {
+	sort.Slice(fo, func(i, j int) bool {
+		if fo[i].Exact != fo[j].Exact {
+			return fo[i].Exact
+		}
+		return fo[i].Package.ID < fo[j].Package.ID
+	})
+}
+
+// CleanPath normalizes a path or package database string for comparison.
+//
+// Some scanners prefix the package database with a tag denoting the kind
+// of database, like "python:".
+func cleanPath(p string) string {
+	if i := strings.IndexByte(p, ':'); i != -1 && !strings.Contains(p[:i], "/") {
+		p = p[i+1:]
+	}
+	if p == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
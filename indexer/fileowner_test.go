@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestFileOwners(t *testing.T) {
+	ir := &claircore.IndexReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "requests"},
+			"2": {ID: "2", Name: "app"},
+			"3": {ID: "3", Name: "bash"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{PackageDB: "python:usr/lib/python3/site-packages/requests"}},
+			"2": {{PackageDB: "go:usr/local/bin/app"}},
+			"3": {{PackageDB: "var/lib/dpkg/status"}},
+		},
+	}
+	tt := []struct {
+		Path  string
+		Want  string
+		Exact bool
+	}{
+		{Path: "/usr/local/bin/app", Want: "app", Exact: true},
+		{Path: "usr/lib/python3/site-packages/requests/api.py", Want: "requests"},
+		{Path: "/var/lib/dpkg/status", Want: "bash", Exact: true},
+		{Path: "/usr/bin/bash"},
+		{Path: "/usr/local/bin/app2"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Path, func(t *testing.T) {
+			got := FileOwners(ir, tc.Path)
+			if tc.Want == "" {
+				if len(got) != 0 {
+					t.Errorf("unexpected owners: %+v", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d owners, want 1", len(got))
+			}
+			if got, want := got[0].Package.Name, tc.Want; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if got, want := got[0].Exact, tc.Exact; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeFileOwners(t *testing.T) {
+	a := &claircore.Package{ID: "1", Name: "bash"}
+	b := &claircore.Package{ID: "2", Name: "coreutils"}
+	got := MergeFileOwners(
+		[]FileOwner{{Package: b}, {Package: a}},
+		[]FileOwner{{Package: a, Exact: true}},
+	)
+	if len(got) != 2 {
+		t.Fatalf("got %d owners, want 2", len(got))
+	}
+	if got[0].Package != a || !got[0].Exact {
+		t.Errorf("got: %+v, want exact owner %q first", got[0], a.Name)
+	}
+	if got[1].Package != b || got[1].Exact {
+		t.Errorf("got: %+v", got[1])
+	}
+}
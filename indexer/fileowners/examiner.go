@@ -0,0 +1,196 @@
+package fileowners
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var examineCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_file_owners",
+		Name:      "examine_total",
+		Help:      "Total number of layers examined for package file lists, by whether examining failed",
+	},
+	[]string{"error"},
+)
+
+// Examiner records the files installed by the packages in the layers of
+// manifests.
+//
+// Examining a layer never fails an index request: errors are logged, and
+// the layer is examined again the next time a manifest containing it is
+// submitted.
+type Examiner struct {
+	store *Store
+}
+
+var (
+	_ layerwalk.Examiner        = (*Examiner)(nil)
+	_ indexer.FileOwnerReporter = (*Examiner)(nil)
+)
+
+// NewExaminer returns an Examiner storing file lists in "s".
+func NewExaminer(s *Store) *Examiner {
+	return &Examiner{store: s}
+}
+
+// Name implements layerwalk.Examiner.
+func (x *Examiner) Name() string { return "fileowners" }
+
+// Examine implements layerwalk.Examiner.
+//
+// Only layers that haven't been examined by the current version of the walk
+// are walked.
+func (x *Examiner) Examine(ctx context.Context, m *claircore.Manifest) (layerwalk.Examination, error) {
+	ds := make([]claircore.Digest, len(m.Layers))
+	for n, l := range m.Layers {
+		ds[n] = l.Hash
+	}
+	states, err := x.store.States(ctx, ds...)
+	if err != nil {
+		return nil, fmt.Errorf("fileowners: unable to look up examined layers: %w", err)
+	}
+	return &examination{store: x.store, states: states}, nil
+}
+
+// Examination is the examination of a single manifest.
+type examination struct {
+	store  *Store
+	states map[string]string
+}
+
+// Layer implements layerwalk.Examination.
+func (e *examination) Layer(_ context.Context, l *claircore.Layer) layerwalk.Visitor {
+	if e.states[l.Hash.String()] == version {
+		return nil
+	}
+	return &visitor{store: e.store, digest: l.Hash, out: newLayer()}
+}
+
+// Done implements layerwalk.Examination.
+func (e *examination) Done(_ context.Context) {}
+
+// Visitor reads the package file lists in a single layer.
+type visitor struct {
+	store  *Store
+	digest claircore.Digest
+	out    *layer
+}
+
+// Visit implements layerwalk.Visitor.
+func (v *visitor) Visit(ctx context.Context, e *layerwalk.Entry) error {
+	h := e.Header
+	if h.Typeflag != tar.TypeReg || h.Size == 0 {
+		return nil
+	}
+	switch k := kindOf(e.Name); k {
+	case kindNone:
+	case kindRPM:
+		ps, err := e.RPM(ctx)
+		if err != nil {
+			return fmt.Errorf("fileowners: %s: %w", e.Name, err)
+		}
+		for _, p := range ps {
+			evr := p.EVR()
+			for _, f := range p.Files() {
+				v.out.add(f, p.Name, evr)
+			}
+		}
+	default:
+		if h.Size > maxMetadataSize {
+			return nil
+		}
+		b, err := e.Bytes(h.Size)
+		if err != nil {
+			return fmt.Errorf("fileowners: %s: %w", e.Name, err)
+		}
+		v.out.read(k, e.Name, b)
+	}
+	return nil
+}
+
+// Done implements layerwalk.Visitor.
+//
+// The file lists are only stored if the whole layer was read.
+func (v *visitor) Done(ctx context.Context, err error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/fileowners/visitor.Done",
+		"layer", v.digest.String())
+	var fs []File
+	if err == nil {
+		fs = v.out.Files()
+		err = v.store.Put(ctx, v.digest, fs)
+	}
+	examineCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+	if err != nil {
+		zlog.Warn(ctx).Err(err).
+			Msg("unable to examine layer for package file lists")
+		return
+	}
+	zlog.Debug(ctx).
+		Int("files", len(fs)).
+		Msg("examined")
+}
+
+// FileOwners implements indexer.FileOwnerReporter.
+//
+// Each package's files are looked up in the layer that introduced it.
+func (x *Examiner) FileOwners(ctx context.Context, report *claircore.IndexReport, p string) ([]indexer.FileOwner, error) {
+	p = cleanPath(p)
+	if p == "" {
+		return nil, nil
+	}
+	seen := make(map[string]struct{})
+	var ds []claircore.Digest
+	for _, envs := range report.Environments {
+		for _, env := range envs {
+			k := env.IntroducedIn.String()
+			if _, ok := seen[k]; ok || env.IntroducedIn.Checksum() == nil {
+				continue
+			}
+			seen[k] = struct{}{}
+			ds = append(ds, env.IntroducedIn)
+		}
+	}
+	layers, err := x.store.Lookup(ctx, p, ds...)
+	if err != nil {
+		return nil, err
+	}
+	var out []indexer.FileOwner
+	for id, envs := range report.Environments {
+		pkg, ok := report.Packages[id]
+		if !ok {
+			continue
+		}
+	Env:
+		for _, env := range envs {
+			for _, f := range layers[env.IntroducedIn.String()] {
+				if f.Package == pkg.Name && (f.Version == "" || f.Version == pkg.Version) {
+					out = append(out, indexer.FileOwner{Package: pkg, Environment: env, Exact: true})
+					break Env
+				}
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Package.ID < out[j].Package.ID })
+	return out, nil
+}
+
+// Delete implements layerwalk.Examiner.
+//
+// Files for layers no longer referenced by any manifest are removed.
+func (x *Examiner) Delete(ctx context.Context, _ ...claircore.Digest) error {
+	return x.store.Prune(ctx)
+}
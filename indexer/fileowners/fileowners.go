@@ -0,0 +1,216 @@
+// Package fileowners records which package installed each file.
+//
+// Claircore records the package database a package was found in, but not the
+// files the package installed, so an Examiner, run by a layerwalk.Indexer
+// once a manifest is indexed, reads the file lists kept by the distribution
+// package managers in any of its layers it hasn't seen before. File lists are
+// stored by layer, and matched to the packages in an index report by the
+// layer that introduced each package.
+//
+// The dpkg file lists (including the "status.d" layout used by distroless
+// images), the apk installed database, and RPM databases are read.
+package fileowners
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/quay/clair/v4/indexer/layerwalk"
+)
+
+// Version is the version of the layer walk. It should change whenever the
+// walk may record something different for the same layer.
+const version = "1"
+
+// MaxMetadataSize is the size of the largest metadata file read, other than
+// RPM databases.
+const maxMetadataSize = 16 * 1024 * 1024
+
+// File is a path installed by a package.
+//
+// Paths are relative to the root of the image, without a leading slash.
+// Packages whose metadata doesn't say which version installed the path are
+// recorded with the empty version.
+type File struct {
+	Path    string
+	Package string
+	Version string
+}
+
+// These are the kinds of metadata files the walk reads.
+type kind int
+
+const (
+	kindNone kind = iota
+	kindRPM
+	kindAPK
+	kindDpkgStatus
+	kindDpkgList
+	kindDistrolessStatus
+	kindDistrolessSums
+)
+
+// KindOf reports what kind of metadata the file "name" holds, judging by its
+// path.
+func kindOf(name string) kind {
+	dir, base := path.Split(name)
+	dir = path.Clean(dir)
+	switch {
+	case layerwalk.RPMDatabase(name):
+		return kindRPM
+	case name == "lib/apk/db/installed":
+		return kindAPK
+	case name == "var/lib/dpkg/status":
+		return kindDpkgStatus
+	case dir == "var/lib/dpkg/info" && strings.HasSuffix(base, ".list"):
+		return kindDpkgList
+	case dir == "var/lib/dpkg/status.d" && strings.HasSuffix(base, ".md5sums"):
+		return kindDistrolessSums
+	case dir == "var/lib/dpkg/status.d":
+		return kindDistrolessStatus
+	}
+	return kindNone
+}
+
+// Layer accumulates the file lists found in a layer.
+//
+// Dpkg keeps file lists and versions in separate files, which may appear in
+// either order, so they're joined once the whole layer has been read.
+type layer struct {
+	files []File
+	// Dpkg file lists and versions, keyed by package name.
+	dpkgFiles    map[string][]string
+	dpkgVersions map[string]string
+}
+
+func newLayer() *layer {
+	return &layer{
+		dpkgFiles:    make(map[string][]string),
+		dpkgVersions: make(map[string]string),
+	}
+}
+
+func (l *layer) add(p, name, version string) {
+	if p = cleanPath(p); p == "" || name == "" {
+		return
+	}
+	l.files = append(l.files, File{Path: p, Package: name, Version: version})
+}
+
+// Files returns every file recorded in the layer.
+func (l *layer) Files() []File {
+	out := l.files
+	for name, ps := range l.dpkgFiles {
+		v := l.dpkgVersions[name]
+		for _, p := range ps {
+			out = append(out, File{Path: p, Package: name, Version: v})
+		}
+	}
+	return out
+}
+
+// Read records the file lists in the metadata file "name" of kind "k". RPM
+// databases are handled separately.
+func (l *layer) read(k kind, name string, b []byte) {
+	switch k {
+	case kindAPK:
+		l.apk(b)
+	case kindDpkgStatus, kindDistrolessStatus:
+		l.dpkgStatus(b)
+	case kindDpkgList:
+		// Multi-arch packages' lists are named "pkg:arch.list".
+		pkg := strings.TrimSuffix(path.Base(name), ".list")
+		pkg, _, _ = strings.Cut(pkg, ":")
+		l.dpkgList(pkg, b, false)
+	case kindDistrolessSums:
+		l.dpkgList(strings.TrimSuffix(path.Base(name), ".md5sums"), b, true)
+	}
+}
+
+// Apk records the files in an apk installed database, whose records are
+// separated by blank lines and made of single-letter keyed lines. Each "R"
+// line names a file in the directory of the preceding "F" line.
+func (l *layer) apk(b []byte) {
+	var name, ver, dir string
+	var files []string
+	flush := func() {
+		for _, f := range files {
+			l.add(f, name, ver)
+		}
+		name, ver, dir, files = "", "", "", files[:0]
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		ln := s.Text()
+		if ln == "" {
+			flush()
+			continue
+		}
+		k, v, ok := strings.Cut(ln, ":")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "P":
+			name = v
+		case "V":
+			ver = v
+		case "F":
+			dir = v
+		case "R":
+			files = append(files, path.Join(dir, v))
+		}
+	}
+	flush()
+}
+
+// DpkgStatus records the package versions in a dpkg status file, whose
+// stanzas are separated by blank lines.
+func (l *layer) dpkgStatus(b []byte) {
+	var name, ver string
+	flush := func() {
+		if name != "" {
+			l.dpkgVersions[name] = ver
+		}
+		name, ver = "", ""
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		ln := s.Text()
+		switch {
+		case ln == "":
+			flush()
+		case strings.HasPrefix(ln, "Package:"):
+			name = strings.TrimSpace(strings.TrimPrefix(ln, "Package:"))
+		case strings.HasPrefix(ln, "Version:"):
+			ver = strings.TrimSpace(strings.TrimPrefix(ln, "Version:"))
+		}
+	}
+	flush()
+}
+
+// DpkgList records the files in a dpkg file list, one path per line. If
+// "sums" is set, each line is instead an MD5 sum followed by the path.
+func (l *layer) dpkgList(pkg string, b []byte, sums bool) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		p := s.Text()
+		if sums {
+			_, p, _ = strings.Cut(p, " ")
+		}
+		if p = cleanPath(strings.TrimSpace(p)); p != "" {
+			l.dpkgFiles[pkg] = append(l.dpkgFiles[pkg], p)
+		}
+	}
+}
+
+// CleanPath normalizes a path to be relative to the root of the image.
+func cleanPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
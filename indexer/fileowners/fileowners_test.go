@@ -0,0 +1,109 @@
+package fileowners
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/clair/v4/indexer/layerwalk"
+)
+
+// Archive returns a tar archive of the named files.
+func archive(t testing.TB, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     files[i],
+			Mode:     0o644,
+			Size:     int64(len(files[i+1])),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	apkDB = `C:Q1x
+P:musl
+V:1.2.4-r2
+F:lib
+R:ld-musl-x86_64.so.1
+R:libc.musl-x86_64.so.1
+
+P:busybox
+V:1.36.1-r5
+F:bin
+R:busybox
+F:etc
+R:securetty
+`
+	dpkgStatus = `Package: bash
+Status: install ok installed
+Version: 5.2.15-2+b2
+Description: GNU Bourne Again SHell
+ Bash is an sh-compatible command language interpreter.
+
+Package: libc6
+Status: install ok installed
+Version: 2.36-9+deb12u4
+`
+	bashList = `/.
+/bin
+/bin/bash
+/usr/share/doc/bash/copyright
+`
+	libcList = `/lib/x86_64-linux-gnu/libc.so.6
+`
+	distrolessStatus = `Package: tzdata
+Version: 2024a-0+deb12u1
+`
+	distrolessSums = `3c2a4b0e0d1c4b58d29b5b8d1e1e0a4c  usr/share/zoneinfo/UTC
+`
+)
+
+func TestWalk(t *testing.T) {
+	ctx := context.Background()
+	tarball := archive(t,
+		"var/lib/dpkg/info/bash.list", bashList,
+		"var/lib/dpkg/info/libc6:amd64.list", libcList,
+		"var/lib/dpkg/status", dpkgStatus,
+		"var/lib/dpkg/status.d/tzdata", distrolessStatus,
+		"var/lib/dpkg/status.d/tzdata.md5sums", distrolessSums,
+		"./lib/apk/db/installed", apkDB,
+		"etc/os-release", "ID=debian\n",
+	)
+	v := &visitor{out: newLayer()}
+	if err := layerwalk.Walk(ctx, t.TempDir(), tar.NewReader(bytes.NewReader(tarball)), v); err != nil {
+		t.Fatal(err)
+	}
+	got := v.out.Files()
+	want := []File{
+		{Path: "bin", Package: "bash", Version: "5.2.15-2+b2"},
+		{Path: "bin/bash", Package: "bash", Version: "5.2.15-2+b2"},
+		{Path: "bin/busybox", Package: "busybox", Version: "1.36.1-r5"},
+		{Path: "etc/securetty", Package: "busybox", Version: "1.36.1-r5"},
+		{Path: "lib/ld-musl-x86_64.so.1", Package: "musl", Version: "1.2.4-r2"},
+		{Path: "lib/libc.musl-x86_64.so.1", Package: "musl", Version: "1.2.4-r2"},
+		{Path: "lib/x86_64-linux-gnu/libc.so.6", Package: "libc6", Version: "2.36-9+deb12u4"},
+		{Path: "usr/share/doc/bash/copyright", Package: "bash", Version: "5.2.15-2+b2"},
+		{Path: "usr/share/zoneinfo/UTC", Package: "tzdata", Version: "2024a-0+deb12u1"},
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,130 @@
+package fileowners
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_file_owners", "the file owner store")
+
+// Store persists the files installed by the packages in layers.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+func digestStrings(ds []claircore.Digest) []string {
+	out := make([]string, len(ds))
+	for i, d := range ds {
+		out[i] = d.String()
+	}
+	return out
+}
+
+// States returns the version of the walk each of the layers was examined
+// with, keyed by digest. Layers not yet examined are omitted.
+func (s *Store) States(ctx context.Context, ds ...claircore.Digest) (_ map[string]string, err error) {
+	const query = `SELECT layer, state FROM indexer_file_owners_layer WHERE layer = ANY($1::text[]);`
+	defer queryMetrics.Observe("states", &err)()
+	rows, err := s.pool.Query(ctx, query, digestStrings(ds))
+	if err != nil {
+		return nil, fmt.Errorf("fileowners: unable to look up layers: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]string, len(ds))
+	for rows.Next() {
+		var l, st string
+		if err = rows.Scan(&l, &st); err != nil {
+			return nil, fmt.Errorf("fileowners: unable to look up layers: %w", err)
+		}
+		out[l] = st
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("fileowners: unable to look up layers: %w", err)
+	}
+	return out, nil
+}
+
+// Lookup returns the files recorded at path "p" in each of the layers, keyed
+// by digest. Layers without the path are omitted.
+func (s *Store) Lookup(ctx context.Context, p string, ds ...claircore.Digest) (_ map[string][]File, err error) {
+	const query = `SELECT layer, package, version FROM indexer_file_owners WHERE path = $1 AND layer = ANY($2::text[]);`
+	defer queryMetrics.Observe("lookup", &err)()
+	out := make(map[string][]File)
+	if len(ds) == 0 {
+		return out, nil
+	}
+	rows, err := s.pool.Query(ctx, query, p, digestStrings(ds))
+	if err != nil {
+		return nil, fmt.Errorf("fileowners: unable to look up %q: %w", p, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l string
+		f := File{Path: p}
+		if err = rows.Scan(&l, &f.Package, &f.Version); err != nil {
+			return nil, fmt.Errorf("fileowners: unable to look up %q: %w", p, err)
+		}
+		out[l] = append(out[l], f)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("fileowners: unable to look up %q: %w", p, err)
+	}
+	return out, nil
+}
+
+// Put stores the files installed in the layer, replacing any previous ones.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, fs []File) (err error) {
+	const (
+		upsert = `INSERT INTO indexer_file_owners_layer (layer, state)
+VALUES ($1, $2)
+ON CONFLICT (layer) DO UPDATE
+SET state = EXCLUDED.state, updated = now();`
+		clear = `DELETE FROM indexer_file_owners WHERE layer = $1;`
+	)
+	defer queryMetrics.Observe("put", &err)()
+	layer := d.String()
+	err = s.pool.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, upsert, layer, version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, clear, layer); err != nil {
+			return err
+		}
+		_, err := tx.CopyFrom(ctx,
+			pgx.Identifier{"indexer_file_owners"},
+			[]string{"layer", "path", "package", "version"},
+			pgx.CopyFromSlice(len(fs), func(i int) ([]interface{}, error) {
+				f := &fs[i]
+				return []interface{}{layer, f.Path, f.Package, f.Version}, nil
+			}))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fileowners: unable to store files for %v: %w", d, err)
+	}
+	return nil
+}
+
+// Prune removes the files for layers the indexer no longer knows about.
+func (s *Store) Prune(ctx context.Context) (err error) {
+	const query = `DELETE FROM indexer_file_owners_layer l
+WHERE NOT EXISTS (SELECT FROM layer WHERE layer.hash = l.layer);`
+	defer queryMetrics.Observe("prune", &err)()
+	if _, err = s.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("fileowners: unable to prune: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+// ImageConfigReporter is an optional interface for Services that record the
+// image configs of submitted manifests.
+type ImageConfigReporter interface {
+	// ImageConfig returns the image config recorded for the manifest, and
+	// whether there is one.
+	ImageConfig(ctx context.Context, manifest claircore.Digest) (*ImageConfig, bool, error)
+}
+
+// ImageConfig is the subset of an OCI image config that's useful for
+// explaining where findings came from.
+type ImageConfig struct {
+	OS           string            `json:"os,omitempty"`
+	OSVersion    string            `json:"os_version,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Env          []string          `json:"env,omitempty"`
+	ExposedPorts []string          `json:"exposed_ports,omitempty"`
+	History      []LayerHistory    `json:"history,omitempty"`
+}
+
+// LayerHistory is a single entry in an image's history.
+//
+// Layer is only populated for history entries that created a layer.
+type LayerHistory struct {
+	Layer     *claircore.Digest `json:"layer,omitempty"`
+	Created   *time.Time        `json:"created,omitempty"`
+	CreatedBy string            `json:"created_by,omitempty"`
+	Comment   string            `json:"comment,omitempty"`
+}
+
+// CreatedBy reports the instruction that created the named layer, if known.
+func (c *ImageConfig) CreatedBy(layer claircore.Digest) string {
+	if c == nil {
+		return ""
+	}
+	want := layer.String()
+	for _, h := range c.History {
+		if h.Layer != nil && h.Layer.String() == want {
+			return h.CreatedBy
+		}
+	}
+	return ""
+}
+
+// ErrUnsupportedPlatform is reported for manifests built for an operating
+// system Clair can't index.
+var ErrUnsupportedPlatform = errors.New("unsupported platform")
+
+// UnsupportedPlatform describes a manifest that wasn't indexed because its
+// image config names an operating system other than Linux.
+//
+// Windows images are the common case: their contents aren't recognized by
+// any scanner, so indexing them would at best produce an empty report that
+// looks clean.
+type UnsupportedPlatform struct {
+	OS        string `json:"os"`
+	OSVersion string `json:"os_version,omitempty"`
+}
+
+// Error implements error.
+func (u *UnsupportedPlatform) Error() string {
+	os := u.OS
+	if u.OSVersion != "" {
+		os += " " + u.OSVersion
+	}
+	return fmt.Sprintf("%v: %s", ErrUnsupportedPlatform, os)
+}
+
+// Is reports whether "target" is ErrUnsupportedPlatform.
+func (u *UnsupportedPlatform) Is(target error) bool {
+	return target == ErrUnsupportedPlatform
+}
+
+// Supported returns an *UnsupportedPlatform if the config names an operating
+// system other than Linux. Configs that don't name one are assumed to be for
+// Linux.
+func (c *ImageConfig) Supported() error {
+	if c == nil {
+		return nil
+	}
+	switch c.OS {
+	case "", "linux":
+		return nil
+	}
+	return &UnsupportedPlatform{OS: c.OS, OSVersion: c.OSVersion}
+}
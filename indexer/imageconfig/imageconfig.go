@@ -0,0 +1,82 @@
+// Package imageconfig records the image configs of submitted manifests.
+//
+// A manifest only lists layers, so clients may also submit a descriptor for
+// the image config blob. An Indexer fetches the blob and records the parts
+// useful for explaining findings: the platform, labels, environment, exposed
+// ports, and history, with each history entry that created a layer matched to
+// that layer's digest.
+//
+// The blob is fetched before the manifest is indexed, so that images for an
+// operating system other than Linux can be refused with an
+// *indexer.UnsupportedPlatform rather than indexed to an empty report.
+package imageconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// MaxConfigSize is the size of the largest image config read.
+const maxConfigSize = 4 * 1024 * 1024
+
+type configKey struct{}
+
+// WithConfig returns a Context carrying the descriptor for the image config
+// of the manifest being indexed.
+//
+// The descriptor is a claircore.Layer, as it's fetched the same way.
+func WithConfig(ctx context.Context, c *claircore.Layer) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+func configFrom(ctx context.Context) *claircore.Layer {
+	c, _ := ctx.Value(configKey{}).(*claircore.Layer)
+	return c
+}
+
+// Parse summarizes the image config "b" for the manifest with layers "ls".
+//
+// History entries that don't create a layer are marked "empty", so the
+// remaining entries line up with the layers in order.
+func parse(b []byte, ls []*claircore.Layer) (*indexer.ImageConfig, error) {
+	cf, err := v1.ParseConfigFile(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("imageconfig: %w", err)
+	}
+	out := indexer.ImageConfig{
+		OS:        cf.OS,
+		OSVersion: cf.OSVersion,
+		Labels:    cf.Config.Labels,
+		Env:       cf.Config.Env,
+	}
+	for p := range cf.Config.ExposedPorts {
+		out.ExposedPorts = append(out.ExposedPorts, p)
+	}
+	sort.Strings(out.ExposedPorts)
+
+	next := 0
+	for _, h := range cf.History {
+		lh := indexer.LayerHistory{
+			CreatedBy: h.CreatedBy,
+			Comment:   h.Comment,
+		}
+		if !h.Created.IsZero() {
+			t := h.Created.Time
+			lh.Created = &t
+		}
+		if !h.EmptyLayer && next < len(ls) {
+			d := ls[next].Hash
+			lh.Layer = &d
+			next++
+		}
+		out.History = append(out.History, lh)
+	}
+	return &out, nil
+}
@@ -0,0 +1,228 @@
+package imageconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+// TestImage returns the raw config and layers of a small image with an
+// empty layer in its history.
+func testImage(t *testing.T) ([]byte, []*claircore.Layer) {
+	t.Helper()
+	l1, err := random.Layer(64, types.DockerLayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2, err := random.Layer(64, types.DockerLayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image,
+		mutate.Addendum{Layer: l1, History: v1.History{CreatedBy: "ADD rootfs.tar /"}},
+		mutate.Addendum{History: v1.History{CreatedBy: "ENV A=b", EmptyLayer: true}},
+		mutate.Addendum{Layer: l2, History: v1.History{CreatedBy: "RUN install stuff"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = mutate.Config(img, v1.Config{
+		Env:          []string{"A=b"},
+		ExposedPorts: map[string]struct{}{"8080/tcp": {}, "443/tcp": {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := img.RawConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ls []*claircore.Layer
+	for _, l := range []v1.Layer{l1, l2} {
+		d, err := l.Digest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls = append(ls, &claircore.Layer{Hash: claircore.MustParseDigest(d.String())})
+	}
+	return b, ls
+}
+
+func TestParse(t *testing.T) {
+	b, ls := testImage(t)
+	cfg, err := parse(b, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Supported(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := len(cfg.History), 3; got != want {
+		t.Fatalf("got: %d history entries, want: %d", got, want)
+	}
+	if cfg.History[1].Layer != nil {
+		t.Error("empty layer attributed to a digest")
+	}
+	for i, want := range []string{"ADD rootfs.tar /", "RUN install stuff"} {
+		if got := cfg.CreatedBy(ls[i].Hash); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+	if got, want := cfg.ExposedPorts, []string{"443/tcp", "8080/tcp"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	b, ls := testImage(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	sum := sha256.Sum256(b)
+	desc := &claircore.Layer{
+		Hash:    claircore.MustParseDigest("sha256:" + hex.EncodeToString(sum[:])),
+		URI:     srv.URL,
+		Headers: http.Header{"Authorization": {"Bearer token"}},
+	}
+
+	m := &claircore.Manifest{
+		Hash:   claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32))),
+		Layers: ls,
+	}
+	i := NewIndexer(&indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash}, nil
+		},
+		DeleteManifests_: func(_ context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+			return ds, nil
+		},
+	}, NewStore(pool), srv.Client())
+	check := func(t *testing.T, want bool) {
+		t.Helper()
+		c, ok, err := i.ImageConfig(ctx, m.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Fatalf("got: %v, want: %v", ok, want)
+		}
+		if !ok {
+			return
+		}
+		if got, want := c.CreatedBy(ls[1].Hash), "RUN install stuff"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	check(t, false)
+	if _, err := i.Index(WithConfig(ctx, desc), m); err != nil {
+		t.Fatal(err)
+	}
+	check(t, true)
+
+	// A config that doesn't match its digest isn't recorded, and doesn't
+	// replace the recorded one.
+	bad := *desc
+	bad.Hash = claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32)))
+	if _, err := i.Index(WithConfig(ctx, &bad), m); err != nil {
+		t.Fatal(err)
+	}
+	check(t, true)
+
+	if _, err := i.DeleteManifests(ctx, m.Hash); err != nil {
+		t.Fatal(err)
+	}
+	check(t, false)
+}
+
+func TestUnsupportedPlatform(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	img, err := mutate.ConfigFile(empty.Image, &v1.ConfigFile{
+		OS:        "windows",
+		OSVersion: "10.0.17763.5122",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := img.RawConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	sum := sha256.Sum256(b)
+	desc := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + hex.EncodeToString(sum[:])),
+		URI:  srv.URL,
+	}
+	m := &claircore.Manifest{
+		Hash: claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32))),
+	}
+	i := NewIndexer(&indexer.Mock{
+		Index_: func(context.Context, *claircore.Manifest) (*claircore.IndexReport, error) {
+			t.Error("unsupported manifest indexed")
+			return nil, nil
+		},
+	}, nil, srv.Client())
+
+	_, err = i.Index(WithConfig(ctx, desc), m)
+	if !errors.Is(err, indexer.ErrUnsupportedPlatform) {
+		t.Fatalf("got: %v, want: %v", err, indexer.ErrUnsupportedPlatform)
+	}
+	var u *indexer.UnsupportedPlatform
+	if !errors.As(err, &u) {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if got, want := *u, (indexer.UnsupportedPlatform{OS: "windows", OSVersion: "10.0.17763.5122"}); got != want {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package imageconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+// Indexer wraps an indexer.Service so that the image configs submitted with
+// manifests are recorded, and manifests for unsupported platforms refused.
+//
+// Otherwise, recording a config never fails an index request: errors are
+// logged, and the config is fetched again the next time the manifest is
+// submitted with it.
+type Indexer struct {
+	indexer.Service
+	store  *Store
+	client *http.Client
+}
+
+var (
+	_ indexer.Service             = (*Indexer)(nil)
+	_ indexer.ImageConfigReporter = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer storing image configs in "s" and fetching
+// them with "c".
+func NewIndexer(svc indexer.Service, s *Store, c *http.Client) *Indexer {
+	return &Indexer{
+		Service: svc,
+		store:   s,
+		client:  c,
+	}
+}
+
+// Index implements indexer.Indexer.
+//
+// The config descriptor is taken from the Context; see WithConfig. If
+// present, the config is fetched first: if it names an operating system other
+// than Linux, the manifest isn't indexed and an *indexer.UnsupportedPlatform
+// is returned. Otherwise, the config replaces the manifest's recorded one
+// once the index operation has started successfully. Manifests submitted
+// without a descriptor keep any recorded config.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	desc := configFrom(ctx)
+	if desc == nil {
+		return i.Service.Index(ctx, m)
+	}
+	lctx := zlog.ContextWithValues(ctx,
+		"component", "indexer/imageconfig/Indexer.Index",
+		"manifest", m.Hash.String(),
+		"config", desc.Hash.String())
+	c, err := i.fetch(lctx, desc, m.Layers)
+	if err != nil {
+		zlog.Warn(lctx).Err(err).Msg("unable to fetch image config")
+	}
+	if err := c.Supported(); err != nil {
+		zlog.Info(lctx).Err(err).Msg("refusing manifest")
+		return nil, err
+	}
+	r, err := i.Service.Index(ctx, m)
+	if err != nil || c == nil {
+		return r, err
+	}
+	if err := i.store.Put(lctx, m.Hash, c); err != nil {
+		zlog.Warn(lctx).Err(err).Msg("unable to record image config")
+		return r, nil
+	}
+	zlog.Debug(lctx).
+		Int("history", len(c.History)).
+		Msg("recorded image config")
+	return r, nil
+}
+
+// Fetch retrieves the config blob "desc" describes, checks its digest, and
+// summarizes it.
+func (i *Indexer) fetch(ctx context.Context, desc *claircore.Layer, ls []*claircore.Layer) (*indexer.ImageConfig, error) {
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, desc.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("imageconfig: fetching %v: %w", desc.Hash, err)
+	}
+	for k, vs := range desc.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	res, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("imageconfig: fetching %v: %w", desc.Hash, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("imageconfig: fetching %v: unexpected status: %s", desc.Hash, res.Status)
+	}
+	h := desc.Hash.Hash()
+	if h == nil {
+		return nil, fmt.Errorf("imageconfig: config %v: unsupported digest algorithm %q", desc.Hash, desc.Hash.Algorithm())
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxConfigSize+1))
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("imageconfig: fetching %v: %w", desc.Hash, err)
+	case len(b) > maxConfigSize:
+		return nil, fmt.Errorf("imageconfig: config %v: over size limit of %d bytes", desc.Hash, maxConfigSize)
+	}
+	h.Write(b)
+	if !bytes.Equal(h.Sum(nil), desc.Hash.Checksum()) {
+		return nil, fmt.Errorf("imageconfig: config %v: digest mismatch", desc.Hash)
+	}
+	return parse(b, ls)
+}
+
+// ImageConfig implements indexer.ImageConfigReporter.
+func (i *Indexer) ImageConfig(ctx context.Context, d claircore.Digest) (*indexer.ImageConfig, bool, error) {
+	return i.store.Get(ctx, d)
+}
+
+// DeleteManifests implements indexer.Indexer.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	rm, err := i.Service.DeleteManifests(ctx, ds...)
+	if err != nil {
+		return rm, err
+	}
+	if len(rm) != 0 {
+		if err := i.store.Delete(ctx, rm...); err != nil {
+			return nil, err
+		}
+	}
+	return rm, nil
+}
@@ -0,0 +1,80 @@
+package imageconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_image_config", "the image config store")
+
+// Store persists the image configs of manifests.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Get returns the image config recorded for the manifest, and whether there
+// is one.
+func (s *Store) Get(ctx context.Context, d claircore.Digest) (_ *indexer.ImageConfig, _ bool, err error) {
+	const query = `SELECT config FROM indexer_image_config WHERE manifest = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var b []byte
+	switch err = s.pool.QueryRow(ctx, query, d.String()).Scan(&b); {
+	case errors.Is(err, nil):
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("imageconfig: unable to look up %v: %w", d, err)
+	}
+	var c indexer.ImageConfig
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, false, fmt.Errorf("imageconfig: bad stored config for %v: %w", d, err)
+	}
+	return &c, true, nil
+}
+
+// Put stores the image config for the manifest, replacing any previous one.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, c *indexer.ImageConfig) (err error) {
+	const query = `INSERT INTO indexer_image_config (manifest, config)
+VALUES ($1, $2)
+ON CONFLICT (manifest) DO UPDATE
+SET config = EXCLUDED.config, updated = now();`
+	defer queryMetrics.Observe("put", &err)()
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("imageconfig: unable to encode config: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, query, d.String(), b); err != nil {
+		return fmt.Errorf("imageconfig: unable to store config for %v: %w", d, err)
+	}
+	return nil
+}
+
+// Delete removes the image configs for the manifests.
+func (s *Store) Delete(ctx context.Context, ds ...claircore.Digest) (err error) {
+	const query = `DELETE FROM indexer_image_config WHERE manifest = ANY($1::text[]);`
+	defer queryMetrics.Observe("delete", &err)()
+	in := make([]string, len(ds))
+	for i, d := range ds {
+		in[i] = d.String()
+	}
+	if _, err = s.pool.Exec(ctx, query, in); err != nil {
+		return fmt.Errorf("imageconfig: unable to delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package labels
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// These are the limits on the labels submitted with a manifest.
+const (
+	MaxLabels      = 64
+	MaxKeyLength   = 128
+	MaxValueLength = 1024
+)
+
+// Validate reports an error if "l" is over any of the limits or contains
+// invalid UTF-8 or empty keys.
+func Validate(l map[string]string) error {
+	if len(l) > MaxLabels {
+		return fmt.Errorf("%d labels, over limit of %d", len(l), MaxLabels)
+	}
+	for k, v := range l {
+		switch {
+		case k == "":
+			return fmt.Errorf("empty label key")
+		case len(k) > MaxKeyLength:
+			return fmt.Errorf("label key %.32q...: over length limit of %d", k, MaxKeyLength)
+		case len(v) > MaxValueLength:
+			return fmt.Errorf("label %q: value over length limit of %d", k, MaxValueLength)
+		case !utf8.ValidString(k) || !utf8.ValidString(v):
+			return fmt.Errorf("label %q: invalid UTF-8", k)
+		}
+	}
+	return nil
+}
+
+type labelsKey struct{}
+
+// WithLabels returns a Context carrying the labels submitted with the
+// manifest being indexed.
+func WithLabels(ctx context.Context, l map[string]string) context.Context {
+	return context.WithValue(ctx, labelsKey{}, l)
+}
+
+func labelsFrom(ctx context.Context) map[string]string {
+	l, _ := ctx.Value(labelsKey{}).(map[string]string)
+	return l
+}
+
+// Indexer wraps an indexer.Service so that labels submitted with manifests
+// are stored.
+type Indexer struct {
+	indexer.Service
+	store *Store
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ indexer.Labeler = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer storing labels in "s".
+func NewIndexer(svc indexer.Service, s *Store) *Indexer {
+	return &Indexer{
+		Service: svc,
+		store:   s,
+	}
+}
+
+// Index implements indexer.Indexer.
+//
+// Labels are taken from the Context; see WithLabels. If any are present, they
+// replace the manifest's stored labels once the index operation has started
+// successfully. Manifests submitted without labels keep any stored ones.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	r, err := i.Service.Index(ctx, m)
+	if err != nil {
+		return r, err
+	}
+	if l := labelsFrom(ctx); len(l) != 0 {
+		if err := i.store.Put(ctx, m.Hash, l); err != nil {
+			return nil, err
+		}
+		zlog.Debug(ctx).
+			Str("component", "indexer/labels/Indexer.Index").
+			Str("manifest", m.Hash.String()).
+			Int("count", len(l)).
+			Msg("stored labels")
+	}
+	return r, nil
+}
+
+// DeleteManifests implements indexer.Indexer.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	rm, err := i.Service.DeleteManifests(ctx, ds...)
+	if err != nil {
+		return rm, err
+	}
+	if len(rm) != 0 {
+		if err := i.store.Delete(ctx, rm...); err != nil {
+			return nil, err
+		}
+	}
+	return rm, nil
+}
+
+// Labels implements indexer.Labeler.
+func (i *Indexer) Labels(ctx context.Context, ds ...claircore.Digest) (map[string]map[string]string, error) {
+	return i.store.Get(ctx, ds...)
+}
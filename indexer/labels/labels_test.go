@@ -0,0 +1,116 @@
+package labels
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestValidate(t *testing.T) {
+	many := make(map[string]string)
+	for i := 0; i <= MaxLabels; i++ {
+		many[strings.Repeat("k", i+1)] = "v"
+	}
+	tt := []struct {
+		Name   string
+		Labels map[string]string
+		OK     bool
+	}{
+		{Name: "OK", Labels: map[string]string{"team": "payments", "env": "prod"}, OK: true},
+		{Name: "EmptyValue", Labels: map[string]string{"team": ""}, OK: true},
+		{Name: "EmptyKey", Labels: map[string]string{"": "payments"}},
+		{Name: "TooMany", Labels: many},
+		{Name: "LongKey", Labels: map[string]string{strings.Repeat("k", MaxKeyLength+1): "v"}},
+		{Name: "LongValue", Labels: map[string]string{"k": strings.Repeat("v", MaxValueLength+1)}},
+		{Name: "BadUTF8", Labels: map[string]string{"k": "\xff"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := Validate(tc.Labels)
+			if got, want := err == nil, tc.OK; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		})
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	d := claircore.MustParseDigest(`sha256:` + strings.Repeat("a", 64))
+	i := NewIndexer(&indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash}, nil
+		},
+		DeleteManifests_: func(_ context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+			return ds, nil
+		},
+	}, NewStore(pool))
+	check := func(t *testing.T, want map[string]map[string]string) {
+		t.Helper()
+		got, err := i.Labels(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	}
+
+	want := map[string]string{"team": "payments"}
+	if _, err := i.Index(WithLabels(ctx, want), &claircore.Manifest{Hash: d}); err != nil {
+		t.Fatal(err)
+	}
+	check(t, map[string]map[string]string{d.String(): want})
+
+	// Submitting without labels keeps the stored ones.
+	if _, err := i.Index(ctx, &claircore.Manifest{Hash: d}); err != nil {
+		t.Fatal(err)
+	}
+	check(t, map[string]map[string]string{d.String(): want})
+
+	n, err := i.store.Count(ctx, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, map[string]int64{"payments": 1}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if _, err := i.DeleteManifests(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	check(t, map[string]map[string]string{})
+}
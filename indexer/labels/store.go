@@ -0,0 +1,126 @@
+// Package labels implements storing client-supplied labels for manifests.
+//
+// Labels are opaque key-value pairs, such as a repository name or owning team,
+// submitted along with a manifest. They're echoed in index reports,
+// vulnerability reports, and notifications so that consumers can route
+// findings without keeping their own mapping of digests to owners.
+package labels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_labels", "the manifest label store")
+
+// Store persists labels for manifests.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Get returns the labels for each of the manifests that has any, keyed by
+// manifest digest.
+func (s *Store) Get(ctx context.Context, ds ...claircore.Digest) (_ map[string]map[string]string, err error) {
+	const query = `SELECT manifest, labels FROM indexer_labels WHERE manifest = ANY($1::text[]);`
+	defer queryMetrics.Observe("get", &err)()
+	if len(ds) == 0 {
+		return map[string]map[string]string{}, nil
+	}
+	in := make([]string, len(ds))
+	for i, d := range ds {
+		in[i] = d.String()
+	}
+	rows, err := s.pool.Query(ctx, query, in)
+	if err != nil {
+		return nil, fmt.Errorf("labels: unable to look up labels: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]map[string]string)
+	for rows.Next() {
+		var m string
+		var b []byte
+		if err = rows.Scan(&m, &b); err != nil {
+			return nil, fmt.Errorf("labels: unable to look up labels: %w", err)
+		}
+		var l map[string]string
+		if err = json.Unmarshal(b, &l); err != nil {
+			return nil, fmt.Errorf("labels: bad stored labels for %v: %w", m, err)
+		}
+		out[m] = l
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("labels: unable to look up labels: %w", err)
+	}
+	return out, nil
+}
+
+// Count returns how many manifests have each value of the named label.
+// Manifests without the label aren't counted.
+func (s *Store) Count(ctx context.Context, key string) (_ map[string]int64, err error) {
+	const query = `SELECT labels->>$1, count(*) FROM indexer_labels
+WHERE labels->>$1 IS NOT NULL
+GROUP BY 1;`
+	defer queryMetrics.Observe("count", &err)()
+	rows, err := s.pool.Query(ctx, query, key)
+	if err != nil {
+		return nil, fmt.Errorf("labels: unable to count labels: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]int64)
+	for rows.Next() {
+		var v string
+		var n int64
+		if err = rows.Scan(&v, &n); err != nil {
+			return nil, fmt.Errorf("labels: unable to count labels: %w", err)
+		}
+		out[v] = n
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("labels: unable to count labels: %w", err)
+	}
+	return out, nil
+}
+
+// Put stores the labels for the manifest, replacing any previous ones.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, l map[string]string) (err error) {
+	const query = `INSERT INTO indexer_labels (manifest, labels)
+VALUES ($1, $2)
+ON CONFLICT (manifest) DO UPDATE
+SET labels = EXCLUDED.labels, updated = now();`
+	defer queryMetrics.Observe("put", &err)()
+	b, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("labels: unable to encode labels: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, query, d.String(), b); err != nil {
+		return fmt.Errorf("labels: unable to store labels for %v: %w", d, err)
+	}
+	return nil
+}
+
+// Delete removes the labels for the manifests.
+func (s *Store) Delete(ctx context.Context, ds ...claircore.Digest) (err error) {
+	const query = `DELETE FROM indexer_labels WHERE manifest = ANY($1::text[]);`
+	defer queryMetrics.Observe("delete", &err)()
+	in := make([]string, len(ds))
+	for i, d := range ds {
+		in[i] = d.String()
+	}
+	if _, err = s.pool.Exec(ctx, query, in); err != nil {
+		return fmt.Errorf("labels: unable to delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package layerwalk
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/dbutil"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+var fetchCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_layerwalk",
+		Name:      "fetch_total",
+		Help:      "Total number of layers fetched to be walked, by whether fetching or walking failed",
+	},
+	[]string{"error"},
+)
+
+// Indexer wraps an indexer.Service so that the layers of manifests are
+// walked for its Examiners once they're indexed.
+//
+// Examining a manifest never fails an index request: Examiners are told
+// about errors and deal with them as they see fit.
+type Indexer struct {
+	indexer.Service
+	client *http.Client
+	tmp    string
+	ex     []Examiner
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer fetching layers with "c" for "ex". Temporary
+// files are created in "tmp", or the system's temporary directory if it's
+// empty.
+func NewIndexer(svc indexer.Service, c *http.Client, tmp string, ex ...Examiner) *Indexer {
+	return &Indexer{
+		Service: svc,
+		client:  c,
+		tmp:     tmp,
+		ex:      ex,
+	}
+}
+
+// Index implements indexer.Indexer.
+//
+// Layers are only walked after a successful index, and only if an Examiner
+// asks for them.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	r, err := i.Service.Index(ctx, m)
+	if err != nil || r == nil || !r.Success {
+		return r, err
+	}
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/layerwalk/Indexer.Index",
+		"manifest", m.Hash.String())
+	i.examine(ctx, m)
+	return r, nil
+}
+
+// Examine runs every Examiner over "m".
+func (i *Indexer) examine(ctx context.Context, m *claircore.Manifest) {
+	xs := make([]Examination, 0, len(i.ex))
+	for _, e := range i.ex {
+		x, err := e.Examine(ctx, m)
+		if err != nil {
+			zlog.Warn(ctx).Err(err).
+				Str("examiner", e.Name()).
+				Msg("unable to begin examination")
+			continue
+		}
+		if x != nil {
+			xs = append(xs, x)
+		}
+	}
+	defer func() {
+		for _, x := range xs {
+			x.Done(ctx)
+		}
+	}()
+	vs := make([]Visitor, 0, len(xs))
+	for _, l := range m.Layers {
+		vs = vs[:0]
+		for _, x := range xs {
+			if v := x.Layer(ctx, l); v != nil {
+				vs = append(vs, v)
+			}
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		errs, err := i.layer(ctx, l, vs)
+		fetchCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+		for n, v := range vs {
+			verr := err
+			if verr == nil {
+				verr = errs[n]
+			}
+			v.Done(ctx, verr)
+		}
+	}
+}
+
+// Layer fetches "l" and walks it with "vs", checking the layer's digest. It
+// returns the errors that stopped each Visitor, by index, and the error
+// fetching or walking the layer, if any.
+func (i *Indexer) layer(ctx context.Context, l *claircore.Layer, vs []Visitor) ([]error, error) {
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, l.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("layerwalk: fetching %v: %w", l.Hash, err)
+	}
+	for k, vs := range l.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	res, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("layerwalk: fetching %v: %w", l.Hash, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("layerwalk: fetching %v: unexpected status: %s", l.Hash, res.Status)
+	}
+	h := l.Hash.Hash()
+	if h == nil {
+		return nil, fmt.Errorf("layerwalk: layer %v: unsupported digest algorithm %q", l.Hash, l.Hash.Algorithm())
+	}
+	body := io.TeeReader(res.Body, h)
+	rd, err := decompress(body)
+	if err != nil {
+		return nil, fmt.Errorf("layerwalk: layer %v: %w", l.Hash, err)
+	}
+	defer rd.Close()
+	errs, err := walk(ctx, i.tmp, tar.NewReader(rd), vs)
+	if err != nil {
+		return nil, fmt.Errorf("layerwalk: layer %v: %w", l.Hash, err)
+	}
+	// Make sure the whole blob went through the hash.
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return nil, fmt.Errorf("layerwalk: fetching %v: %w", l.Hash, err)
+	}
+	if !bytes.Equal(h.Sum(nil), l.Hash.Checksum()) {
+		return nil, fmt.Errorf("layerwalk: layer %v: digest mismatch", l.Hash)
+	}
+	return errs, nil
+}
+
+// Decompress returns a reader for the tar stream in "r", which may be gzip or
+// zstd compressed.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return io.NopCloser(br), nil
+}
+
+// DeleteManifests implements indexer.Indexer.
+//
+// Each Examiner is told about the manifests that were deleted.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	rm, err := i.Service.DeleteManifests(ctx, ds...)
+	if err != nil {
+		return rm, err
+	}
+	if len(rm) == 0 {
+		return rm, nil
+	}
+	for _, e := range i.ex {
+		if err := e.Delete(ctx, rm...); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+	}
+	return rm, nil
+}
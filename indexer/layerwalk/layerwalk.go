@@ -0,0 +1,194 @@
+// Package layerwalk walks the layers of indexed manifests on behalf of the
+// optional indexers that examine layer contents.
+//
+// Claircore doesn't keep layers around once a manifest is indexed, so
+// anything wanting to look inside them has to fetch them again. Rather than
+// each of those fetching every layer for itself, an Indexer wraps an
+// indexer.Service and, once a manifest is indexed, asks each of its
+// Examiners which layers they need, fetches each of those layers once, and
+// hands every entry in it to each Examiner interested in the layer.
+package layerwalk
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Examiner records something about the layers of manifests.
+type Examiner interface {
+	// Name identifies the Examiner in logs and errors.
+	Name() string
+	// Examine begins examining the indexed manifest "m". A nil Examination
+	// means there's nothing to do.
+	Examine(ctx context.Context, m *claircore.Manifest) (Examination, error)
+	// Delete is called with the manifests the indexer deleted.
+	Delete(ctx context.Context, ds ...claircore.Digest) error
+}
+
+// Examination is an Examiner's examination of a single manifest.
+type Examination interface {
+	// Layer returns a Visitor for the entries of "l", or nil if the layer
+	// doesn't need to be walked. It's called for each layer in manifest
+	// order, after the previous layer's Visitor is done.
+	Layer(ctx context.Context, l *claircore.Layer) Visitor
+	// Done is called once every layer has been walked.
+	Done(ctx context.Context)
+}
+
+// Visitor is handed the entries of a layer.
+type Visitor interface {
+	// Visit is called for each entry in the layer, in order. An error stops
+	// the Visitor from seeing the rest of the layer.
+	Visit(ctx context.Context, e *Entry) error
+	// Done is called once the layer has been walked and its digest checked,
+	// with the error that stopped the walk or the Visitor, if any.
+	Done(ctx context.Context, err error)
+}
+
+// Entry is an entry in a layer.
+//
+// Entries are only valid during the call to Visit, and the same Entry is
+// handed to every Visitor: whatever's read of the contents is read from the
+// layer once and shared.
+type Entry struct {
+	// Name is the entry's path, cleaned and without a leading slash.
+	Name string
+	// Header is the entry's tar header.
+	Header *tar.Header
+
+	tmp   string
+	tr    *tar.Reader
+	buf   []byte
+	spool *os.File
+	err   error
+	rpm   *rpmResult
+}
+
+// Bytes returns the first "n" bytes of the entry's contents, or all of them
+// if there are fewer. The returned slice must not be retained.
+func (e *Entry) Bytes(n int64) ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if n > e.Header.Size {
+		n = e.Header.Size
+	}
+	have := int64(len(e.buf))
+	if have >= n {
+		return e.buf[:n], nil
+	}
+	if int64(cap(e.buf)) < n {
+		b := make([]byte, have, n)
+		copy(b, e.buf)
+		e.buf = b
+	}
+	// Once spooled, the rest of the contents is only in the spool file.
+	var r io.Reader = e.tr
+	if e.spool != nil {
+		r = io.NewSectionReader(e.spool, have, n-have)
+	}
+	m, err := io.ReadFull(r, e.buf[have:n])
+	e.buf = e.buf[:have+int64(m)]
+	if err != nil {
+		e.err = err
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// File returns a temporary file holding the entry's contents, for readers
+// that need random access. The file is removed once every Visitor has seen
+// the entry.
+func (e *Entry) File() (*os.File, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.spool != nil {
+		return e.spool, nil
+	}
+	f, err := os.CreateTemp(e.tmp, "layerwalk.")
+	if err != nil {
+		e.err = err
+		return nil, err
+	}
+	e.spool = f
+	if _, err := f.Write(e.buf); err != nil {
+		e.err = err
+		return nil, err
+	}
+	if _, err := io.Copy(f, e.tr); err != nil {
+		e.err = err
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reset readies "e" for the next entry, "h".
+func (e *Entry) reset(h *tar.Header) {
+	e.release()
+	e.Name = path.Clean(strings.TrimPrefix(h.Name, "/"))
+	e.Header = h
+	e.buf = e.buf[:0]
+	e.err = nil
+	e.rpm = nil
+}
+
+// Release removes the entry's spool file, if any.
+func (e *Entry) release() {
+	if e.spool != nil {
+		e.spool.Close()
+		os.Remove(e.spool.Name())
+		e.spool = nil
+	}
+}
+
+// Walk hands each entry in "tr" to "v", creating any temporary files in
+// "tmp", and returns the error that stopped the walk or the Visitor, if any.
+// Unlike an Indexer, it doesn't call the Visitor's Done method.
+func Walk(ctx context.Context, tmp string, tr *tar.Reader, v Visitor) error {
+	errs, err := walk(ctx, tmp, tr, []Visitor{v})
+	if err != nil {
+		return err
+	}
+	return errs[0]
+}
+
+// Walk hands each entry in "tr" to each of "vs". It returns the errors that
+// stopped each Visitor, by index, and the error reading "tr", if any.
+func walk(ctx context.Context, tmp string, tr *tar.Reader, vs []Visitor) ([]error, error) {
+	errs := make([]error, len(vs))
+	e := Entry{tmp: tmp, tr: tr}
+	defer e.release()
+	for {
+		h, err := tr.Next()
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, io.EOF):
+			return errs, nil
+		default:
+			return errs, err
+		}
+		e.reset(h)
+		live := 0
+		for n, v := range vs {
+			if errs[n] != nil {
+				continue
+			}
+			live++
+			errs[n] = v.Visit(ctx, &e)
+		}
+		if live == 0 {
+			return errs, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return errs, err
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package layerwalk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Archive returns a tar archive of the named files.
+func archive(t testing.TB, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     files[i],
+			Mode:     0o644,
+			Size:     int64(len(files[i+1])),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// ReadVisitor reads up to "n" bytes of each entry, or spools it if "spool"
+// is set, and records what it saw.
+type readVisitor struct {
+	n     int64
+	spool bool
+	fail  string
+	got   map[string]string
+	done  int
+	err   error
+}
+
+func (v *readVisitor) Visit(_ context.Context, e *Entry) error {
+	if e.Name == v.fail {
+		return errors.New("failed")
+	}
+	if v.got == nil {
+		v.got = make(map[string]string)
+	}
+	if v.spool {
+		f, err := e.File()
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(io.NewSectionReader(f, 0, e.Header.Size))
+		if err != nil {
+			return err
+		}
+		v.got[e.Name] = string(b)
+		return nil
+	}
+	b, err := e.Bytes(v.n)
+	if err != nil {
+		return err
+	}
+	v.got[e.Name] = string(b)
+	return nil
+}
+
+func (v *readVisitor) Done(_ context.Context, err error) {
+	v.done++
+	v.err = err
+}
+
+func TestWalk(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tarball := archive(t,
+		"/etc/os-release", "ID=test\n",
+		"./a", "0123456789",
+		"b", "abc",
+	)
+	vs := []*readVisitor{
+		{n: 2},
+		{n: 5},
+		{spool: true},
+		{n: 100, fail: "a"},
+	}
+	errs, err := walk(ctx, t.TempDir(), tar.NewReader(bytes.NewReader(tarball)),
+		[]Visitor{vs[0], vs[1], vs[2], vs[3]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, want := range []map[string]string{
+		{"etc/os-release": "ID", "a": "01", "b": "ab"},
+		{"etc/os-release": "ID=te", "a": "01234", "b": "abc"},
+		{"etc/os-release": "ID=test\n", "a": "0123456789", "b": "abc"},
+		{"etc/os-release": "ID=test\n"},
+	} {
+		got := vs[n].got
+		if len(got) != len(want) {
+			t.Errorf("%d: got: %q, want: %q", n, got, want)
+			continue
+		}
+		for k, w := range want {
+			if got[k] != w {
+				t.Errorf("%d: %s: got: %q, want: %q", n, k, got[k], w)
+			}
+		}
+	}
+	for n, err := range errs {
+		if (err != nil) != (n == 3) {
+			t.Errorf("%d: unexpected error: %v", n, err)
+		}
+	}
+}
+
+// Examiner is an Examiner wanting every layer but "skip".
+type examiner struct {
+	skip claircore.Digest
+	vs   []*readVisitor
+	done int
+}
+
+func (x *examiner) Name() string { return "test" }
+
+func (x *examiner) Examine(context.Context, *claircore.Manifest) (Examination, error) {
+	return x, nil
+}
+
+func (x *examiner) Delete(context.Context, ...claircore.Digest) error { return nil }
+
+func (x *examiner) Layer(_ context.Context, l *claircore.Layer) Visitor {
+	if l.Hash.String() == x.skip.String() {
+		return nil
+	}
+	v := &readVisitor{n: 100}
+	x.vs = append(x.vs, v)
+	return v
+}
+
+func (x *examiner) Done(context.Context) { x.done++ }
+
+func TestIndexer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	blobs := map[string][]byte{
+		"/good": archive(t, "a", "good"),
+		"/bad":  archive(t, "a", "bad"),
+	}
+	digest := func(b []byte) claircore.Digest {
+		sum := sha256.Sum256(b)
+		return claircore.MustParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	}
+	fetches := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches[r.URL.Path]++
+		w.Write(blobs[r.URL.Path])
+	}))
+	defer srv.Close()
+	good, other := digest(blobs["/good"]), digest([]byte("other"))
+
+	svc := &indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+	}
+	// The second layer's digest doesn't match what's served.
+	m := &claircore.Manifest{
+		Hash: claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32))),
+		Layers: []*claircore.Layer{
+			{Hash: good, URI: srv.URL + "/good"},
+			{Hash: other, URI: srv.URL + "/bad"},
+		},
+	}
+	x1, x2 := &examiner{}, &examiner{skip: other}
+	i := NewIndexer(svc, srv.Client(), t.TempDir(), x1, x2)
+	if _, err := i.Index(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fetches["/good"], 1; got != want {
+		t.Errorf("good layer fetched %d times, want %d", got, want)
+	}
+	if got, want := fetches["/bad"], 1; got != want {
+		t.Errorf("bad layer fetched %d times, want %d", got, want)
+	}
+	if len(x1.vs) != 2 || len(x2.vs) != 1 {
+		t.Fatalf("got %d and %d visitors", len(x1.vs), len(x2.vs))
+	}
+	for _, v := range []*readVisitor{x1.vs[0], x2.vs[0]} {
+		if v.done != 1 || v.err != nil || v.got["a"] != "good" {
+			t.Errorf("good layer: done %d times, error %v, got %q", v.done, v.err, v.got)
+		}
+	}
+	if v := x1.vs[1]; v.done != 1 || v.err == nil {
+		t.Errorf("bad layer: done %d times, error %v", v.done, v.err)
+	}
+	if x1.done != 1 || x2.done != 1 {
+		t.Errorf("examinations done %d and %d times", x1.done, x2.done)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	tarball := archive(t, "a", "contents")
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(tarball)
+	w.Close()
+	var zs bytes.Buffer
+	enc, err := zstd.NewWriter(&zs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.Write(tarball)
+	enc.Close()
+
+	for name, b := range map[string][]byte{"tar": tarball, "gzip": gz.Bytes(), "zstd": zs.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			rd, err := decompress(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rd.Close()
+			got, err := io.ReadAll(rd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tarball) {
+				t.Error("decompressed layer differs")
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package layerwalk
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/quay/claircore/rpm/bdb"
+	"github.com/quay/claircore/rpm/ndb"
+	"github.com/quay/claircore/rpm/sqlite"
+)
+
+// These are the RPM header tags read.
+const (
+	tagName       = 1000
+	tagVersion    = 1001
+	tagRelease    = 1002
+	tagEpoch      = 1003
+	tagLicense    = 1014
+	tagDirIndexes = 1116
+	tagBasenames  = 1117
+	tagDirnames   = 1118
+)
+
+// These are the RPM header data types used by those tags.
+const (
+	typeInt32       = 4
+	typeString      = 6
+	typeStringArray = 8
+	typeI18N        = 9
+)
+
+// These are the kinds of RPM database.
+type rpmKind int
+
+const (
+	rpmNone rpmKind = iota
+	rpmBDB
+	rpmNDB
+	rpmSQLite
+)
+
+// RpmKindOf reports what kind of RPM database the file "name" is, judging by
+// its path.
+func rpmKindOf(name string) rpmKind {
+	dir, base := path.Split(name)
+	if path.Base(path.Clean(dir)) != "rpm" {
+		return rpmNone
+	}
+	switch base {
+	case "Packages":
+		return rpmBDB
+	case "Packages.db":
+		return rpmNDB
+	case "rpmdb.sqlite":
+		return rpmSQLite
+	}
+	return rpmNone
+}
+
+// RPMDatabase reports whether "name" is an RPM database, judging by its path.
+func RPMDatabase(name string) bool {
+	return rpmKindOf(name) != rpmNone
+}
+
+// RPMPackage is the subset of an RPM header read from RPM databases.
+type RPMPackage struct {
+	Name, Version, Release, License string
+	Epoch                           int32
+	// The file list is stored as the distinct directories, the base names,
+	// and the index of each base name's directory.
+	Dirnames   []string
+	Basenames  []string
+	DirIndexes []int32
+}
+
+// EVR returns the package's version as claircore constructs it:
+// "epoch:version-release", with the epoch omitted if zero.
+func (p *RPMPackage) EVR() string {
+	v := p.Version + "-" + p.Release
+	if p.Epoch != 0 {
+		v = strconv.Itoa(int(p.Epoch)) + ":" + v
+	}
+	return v
+}
+
+// Files returns the paths of the package's files.
+func (p *RPMPackage) Files() []string {
+	out := make([]string, 0, len(p.Basenames))
+	for i, b := range p.Basenames {
+		if i >= len(p.DirIndexes) {
+			break
+		}
+		d := p.DirIndexes[i]
+		if d < 0 || int(d) >= len(p.Dirnames) {
+			continue
+		}
+		out = append(out, p.Dirnames[d]+b)
+	}
+	return out
+}
+
+// RpmResult is the outcome of reading an entry's RPM database.
+type rpmResult struct {
+	pkgs []RPMPackage
+	err  error
+}
+
+// RPM returns the packages in the entry, which should be an RPM database
+// according to RPMDatabase. The database is only read once, however many
+// Visitors ask. Entries that aren't RPM databases, or have the wrong magic
+// number, have no packages.
+func (e *Entry) RPM(ctx context.Context) ([]RPMPackage, error) {
+	if e.rpm == nil {
+		ps, err := e.readRPM(ctx)
+		e.rpm = &rpmResult{pkgs: ps, err: err}
+	}
+	return e.rpm.pkgs, e.rpm.err
+}
+
+func (e *Entry) readRPM(ctx context.Context) ([]RPMPackage, error) {
+	k := rpmKindOf(e.Name)
+	if k == rpmNone {
+		return nil, nil
+	}
+	f, err := e.File()
+	if err != nil {
+		return nil, err
+	}
+	var hs []io.ReaderAt
+	switch k {
+	case rpmBDB:
+		if !bdb.CheckMagic(ctx, io.NewSectionReader(f, 0, 16)) {
+			return nil, nil
+		}
+		var db bdb.PackageDB
+		if err := db.Parse(f); err != nil {
+			return nil, err
+		}
+		hs, err = db.AllHeaders(ctx)
+	case rpmNDB:
+		if !ndb.CheckMagic(ctx, io.NewSectionReader(f, 0, 16)) {
+			return nil, nil
+		}
+		var db ndb.PackageDB
+		if err := db.Parse(f); err != nil {
+			return nil, err
+		}
+		hs, err = db.AllHeaders(ctx)
+	case rpmSQLite:
+		var db *sqlite.RPMDB
+		db, err = sqlite.Open(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		hs, err = db.AllHeaders(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RPMPackage, 0, len(hs))
+	for _, h := range hs {
+		p, err := rpmHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		if p.Name == "gpg-pubkey" {
+			continue
+		}
+		out = append(out, *p)
+	}
+	return out, nil
+}
+
+// RpmHeader reads the tags of interest from an RPM header blob, as stored in
+// the RPM database: an index of 16-byte entries followed by a data store.
+func rpmHeader(r io.ReaderAt) (*RPMPackage, error) {
+	const maxSize = 256 * 1024 * 1024
+	var pre [8]byte
+	if _, err := r.ReadAt(pre[:], 0); err != nil {
+		return nil, fmt.Errorf("rpm header: %w", err)
+	}
+	il := int64(binary.BigEndian.Uint32(pre[0:]))
+	dl := int64(binary.BigEndian.Uint32(pre[4:]))
+	if il > 0xffff || 8+il*16+dl > maxSize {
+		return nil, errors.New("rpm header: size out of range")
+	}
+	b := make([]byte, il*16+dl)
+	if _, err := r.ReadAt(b, 8); err != nil {
+		return nil, fmt.Errorf("rpm header: %w", err)
+	}
+	data := b[il*16:]
+	var p RPMPackage
+	for i := int64(0); i < il; i++ {
+		e := b[i*16 : i*16+16]
+		tag := binary.BigEndian.Uint32(e[0:])
+		typ := binary.BigEndian.Uint32(e[4:])
+		off := int64(binary.BigEndian.Uint32(e[8:]))
+		ct := int64(binary.BigEndian.Uint32(e[12:]))
+		if off >= dl {
+			continue
+		}
+		switch tag {
+		case tagName, tagVersion, tagRelease, tagLicense:
+			// For I18N strings, the first is the untranslated one.
+			if typ != typeString && typ != typeI18N {
+				continue
+			}
+			s := cStrings(data[off:], 1)[0]
+			switch tag {
+			case tagName:
+				p.Name = s
+			case tagVersion:
+				p.Version = s
+			case tagRelease:
+				p.Release = s
+			case tagLicense:
+				p.License = s
+			}
+		case tagEpoch:
+			if typ == typeInt32 && off+4 <= dl {
+				p.Epoch = int32(binary.BigEndian.Uint32(data[off:]))
+			}
+		case tagDirnames, tagBasenames:
+			if typ != typeStringArray || ct > dl {
+				continue
+			}
+			ss := cStrings(data[off:], int(ct))
+			if tag == tagDirnames {
+				p.Dirnames = ss
+			} else {
+				p.Basenames = ss
+			}
+		case tagDirIndexes:
+			if typ != typeInt32 || off+ct*4 > dl {
+				continue
+			}
+			p.DirIndexes = make([]int32, ct)
+			for n := range p.DirIndexes {
+				p.DirIndexes[n] = int32(binary.BigEndian.Uint32(data[off+int64(n)*4:]))
+			}
+		}
+	}
+	if p.Name == "" {
+		return nil, errors.New("rpm header: missing name")
+	}
+	return &p, nil
+}
+
+// CStrings reads "n" consecutive NUL-terminated strings from "b". Missing
+// strings are returned empty.
+func cStrings(b []byte, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		end := bytes.IndexByte(b, 0)
+		if end == -1 {
+			out[i] = string(b)
+			break
+		}
+		out[i] = string(b[:end])
+		b = b[end+1:]
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package layerwalk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// RpmBlob builds an RPM header blob with the passed epoch, string tags, and
+// file list.
+func rpmBlob(epoch int32, tags map[uint32]string, dirs, bases []string, idxs []int32) []byte {
+	var idx, data bytes.Buffer
+	entry := func(tag, typ uint32, ct int) {
+		binary.Write(&idx, binary.BigEndian, [4]uint32{tag, typ, uint32(data.Len()), uint32(ct)})
+	}
+	str := func(tag uint32, typ uint32, vs ...string) {
+		entry(tag, typ, len(vs))
+		for _, v := range vs {
+			data.WriteString(v)
+			data.WriteByte(0)
+		}
+	}
+	for tag, v := range tags {
+		str(tag, typeString, v)
+	}
+	if len(bases) != 0 {
+		str(tagDirnames, typeStringArray, dirs...)
+		str(tagBasenames, typeStringArray, bases...)
+	}
+	for data.Len()%4 != 0 {
+		data.WriteByte(0)
+	}
+	if epoch != 0 {
+		entry(tagEpoch, typeInt32, 1)
+		binary.Write(&data, binary.BigEndian, epoch)
+	}
+	if len(idxs) != 0 {
+		entry(tagDirIndexes, typeInt32, len(idxs))
+		binary.Write(&data, binary.BigEndian, idxs)
+	}
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, [2]uint32{uint32(idx.Len() / 16), uint32(data.Len())})
+	out.Write(idx.Bytes())
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+func TestRPMHeader(t *testing.T) {
+	b := rpmBlob(1, map[uint32]string{
+		tagName:    "openssl-libs",
+		tagVersion: "3.0.7",
+		tagRelease: "25.el9",
+		tagLicense: "ASL 2.0",
+	}, nil, nil, nil)
+	p, err := rpmHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RPMPackage{Name: "openssl-libs", Version: "3.0.7", Release: "25.el9", License: "ASL 2.0", Epoch: 1}
+	if !cmp.Equal(*p, want) {
+		t.Error(cmp.Diff(*p, want))
+	}
+	if got, want := p.EVR(), "1:3.0.7-25.el9"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	b = rpmBlob(0, map[uint32]string{
+		tagName:    "bash",
+		tagVersion: "1.0",
+		tagRelease: "1.el9",
+	},
+		[]string{"/usr/bin/", "/etc/skel/"},
+		[]string{"bash", ".bashrc", "sh"},
+		[]int32{0, 1, 0})
+	p, err = rpmHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.EVR(), "1.0-1.el9"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := p.Files(), []string{"/usr/bin/bash", "/etc/skel/.bashrc", "/usr/bin/sh"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// Indexes past the directory list are skipped.
+	b = rpmBlob(0, map[uint32]string{tagName: "bad"}, []string{"/"}, []string{"a", "b"}, []int32{0, 3})
+	p, err = rpmHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Files(), []string{"/a"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if _, err := rpmHeader(bytes.NewReader(rpmBlob(0, map[uint32]string{tagLicense: "MIT"}, nil, nil, nil))); err == nil {
+		t.Error("expected error for header without a name")
+	}
+	if _, err := rpmHeader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})); err == nil {
+		t.Error("expected error for oversized header")
+	}
+}
@@ -0,0 +1,15 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// LicenseReporter is an optional interface for Services that record the
+// licenses packages declare.
+type LicenseReporter interface {
+	// Licenses returns the declared license of each package in the report
+	// that has one, keyed by package ID.
+	Licenses(ctx context.Context, report *claircore.IndexReport) (map[string]string, error)
+}
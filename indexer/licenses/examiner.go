@@ -0,0 +1,182 @@
+package licenses
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var examineCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_licenses",
+		Name:      "examine_total",
+		Help:      "Total number of layers examined for declared licenses, by whether examining failed",
+	},
+	[]string{"error"},
+)
+
+// Examiner records the licenses declared by the packages in the layers of
+// manifests.
+//
+// Examining a layer never fails an index request: errors are logged, and
+// the layer is examined again the next time a manifest containing it is
+// submitted.
+type Examiner struct {
+	store *Store
+}
+
+var (
+	_ layerwalk.Examiner      = (*Examiner)(nil)
+	_ indexer.LicenseReporter = (*Examiner)(nil)
+)
+
+// NewExaminer returns an Examiner storing licenses in "s".
+func NewExaminer(s *Store) *Examiner {
+	return &Examiner{store: s}
+}
+
+// Name implements layerwalk.Examiner.
+func (x *Examiner) Name() string { return "licenses" }
+
+// Examine implements layerwalk.Examiner.
+//
+// Only layers that haven't been examined by the current version of the walk
+// are walked.
+func (x *Examiner) Examine(ctx context.Context, m *claircore.Manifest) (layerwalk.Examination, error) {
+	ds := make([]claircore.Digest, len(m.Layers))
+	for n, l := range m.Layers {
+		ds[n] = l.Hash
+	}
+	states, err := x.store.States(ctx, ds...)
+	if err != nil {
+		return nil, fmt.Errorf("licenses: unable to look up examined layers: %w", err)
+	}
+	return &examination{store: x.store, states: states}, nil
+}
+
+// Examination is the examination of a single manifest.
+type examination struct {
+	store  *Store
+	states map[string]string
+}
+
+// Layer implements layerwalk.Examination.
+func (e *examination) Layer(_ context.Context, l *claircore.Layer) layerwalk.Visitor {
+	if e.states[l.Hash.String()] == version {
+		return nil
+	}
+	return &visitor{store: e.store, layer: l.Hash, out: make(Declared)}
+}
+
+// Done implements layerwalk.Examination.
+func (e *examination) Done(_ context.Context) {}
+
+// Visitor reads the package metadata in a single layer.
+type visitor struct {
+	store *Store
+	layer claircore.Digest
+	out   Declared
+}
+
+// Visit implements layerwalk.Visitor.
+func (v *visitor) Visit(ctx context.Context, e *layerwalk.Entry) error {
+	h := e.Header
+	if h.Typeflag != tar.TypeReg || h.Size == 0 {
+		return nil
+	}
+	switch k := kindOf(e.Name); k {
+	case kindNone:
+	case kindRPM:
+		ps, err := e.RPM(ctx)
+		if err != nil {
+			return fmt.Errorf("licenses: %s: %w", e.Name, err)
+		}
+		for _, p := range ps {
+			v.out.add(p.Name, p.EVR(), p.License)
+		}
+	default:
+		if h.Size > maxMetadataSize {
+			return nil
+		}
+		b, err := e.Bytes(h.Size)
+		if err != nil {
+			return fmt.Errorf("licenses: %s: %w", e.Name, err)
+		}
+		v.out.read(k, e.Name, b)
+	}
+	return nil
+}
+
+// Done implements layerwalk.Visitor.
+//
+// The licenses are only stored if the whole layer was read.
+func (v *visitor) Done(ctx context.Context, err error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/licenses/visitor.Done",
+		"layer", v.layer.String())
+	if err == nil {
+		err = v.store.Put(ctx, v.layer, v.out)
+	}
+	examineCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+	if err != nil {
+		zlog.Warn(ctx).Err(err).
+			Msg("unable to examine layer for declared licenses")
+		return
+	}
+	zlog.Debug(ctx).
+		Int("packages", len(v.out)).
+		Msg("examined")
+}
+
+// Licenses implements indexer.LicenseReporter.
+//
+// Each package's license is looked up in the layer that introduced it.
+func (x *Examiner) Licenses(ctx context.Context, report *claircore.IndexReport) (map[string]string, error) {
+	seen := make(map[string]struct{})
+	var ds []claircore.Digest
+	for _, envs := range report.Environments {
+		for _, env := range envs {
+			k := env.IntroducedIn.String()
+			if _, ok := seen[k]; ok || env.IntroducedIn.Checksum() == nil {
+				continue
+			}
+			seen[k] = struct{}{}
+			ds = append(ds, env.IntroducedIn)
+		}
+	}
+	layers, err := x.store.Get(ctx, ds...)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	for id, envs := range report.Environments {
+		p, ok := report.Packages[id]
+		if !ok {
+			continue
+		}
+		for _, env := range envs {
+			if l := layers[env.IntroducedIn.String()].Lookup(p.Name, p.Version); l != "" {
+				out[id] = l
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// Delete implements layerwalk.Examiner.
+//
+// Licenses for layers no longer referenced by any manifest are removed.
+func (x *Examiner) Delete(ctx context.Context, _ ...claircore.Digest) error {
+	return x.store.Prune(ctx)
+}
@@ -0,0 +1,119 @@
+package licenses
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) (*Store, *pgxpool.Pool) {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return NewStore(pool), pool
+}
+
+func TestIndexer(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s, pool := TestingStore(ctx, t)
+	// Stand in for the indexer's own layer table, which pruning consults.
+	if _, err := pool.Exec(ctx, `CREATE TABLE layer (hash text PRIMARY KEY);`); err != nil {
+		t.Fatal(err)
+	}
+
+	blob := layer(t, "lib/apk/db/installed", apkDB)
+	sum := sha256.Sum256(blob)
+	ld := claircore.MustParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(blob)
+	}))
+	defer srv.Close()
+
+	svc := &indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+		DeleteManifests_: func(_ context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+			return ds, nil
+		},
+	}
+	x := NewExaminer(s)
+	i := layerwalk.NewIndexer(svc, srv.Client(), t.TempDir(), x)
+	m := &claircore.Manifest{
+		Hash:   claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32))),
+		Layers: []*claircore.Layer{{Hash: ld, URI: srv.URL + "/blob"}},
+	}
+	report := &claircore.IndexReport{
+		Hash: m.Hash,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.2.4-r2"},
+			"2": {ID: "2", Name: "busybox", Version: "1.36.1-r5"},
+			"3": {ID: "3", Name: "zlib", Version: "1.3-r2"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{IntroducedIn: ld}},
+			"2": {{IntroducedIn: ld}},
+			"3": {{IntroducedIn: ld}},
+		},
+	}
+
+	if got, err := x.Licenses(ctx, report); err != nil || len(got) != 0 {
+		t.Errorf("unexamined: got: (%v, %v)", got, err)
+	}
+	for n := 0; n < 2; n++ {
+		if _, err := i.Index(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("examined layer fetched again: %d fetches", fetches)
+	}
+	got, err := x.Licenses(ctx, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["1"] != "MIT" || got["2"] != "GPL-2.0-only" {
+		t.Errorf("got: %v", got)
+	}
+
+	if _, err := i.DeleteManifests(ctx, m.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := x.Licenses(ctx, report); err != nil || len(got) != 0 {
+		t.Errorf("deleted: got: (%v, %v)", got, err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package licenses records the licenses packages declare.
+//
+// Claircore finds packages but not their licenses, so an Examiner, run by a
+// layerwalk.Indexer once a manifest is indexed, reads the package metadata
+// that carries license information in any of its layers it hasn't seen
+// before. Licenses are stored by layer, and matched to the packages in an
+// index report by the layer that introduced each package.
+//
+// Licenses are recorded as declared: RPM and apk databases, RubyGems
+// specifications, and recent Python metadata generally use SPDX license
+// expressions, but older packages may use free-form names.
+package licenses
+
+import (
+	"bufio"
+	"bytes"
+	"net/textproto"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/quay/clair/v4/indexer/layerwalk"
+)
+
+// Version is the version of the layer walk. It should change whenever the
+// walk may record something different for the same layer.
+const version = "1"
+
+// MaxMetadataSize is the size of the largest metadata file read, other than
+// RPM databases.
+const maxMetadataSize = 1024 * 1024
+
+// Declared is the licenses declared by the packages in a layer, keyed by
+// package name and then version.
+//
+// Package names are lower-cased. Packages whose metadata doesn't say which
+// version it's for are recorded with the empty version.
+type Declared map[string]map[string]string
+
+func (d Declared) add(name, version, license string) {
+	license = strings.TrimSpace(license)
+	if name == "" || license == "" {
+		return
+	}
+	name = strings.ToLower(name)
+	vs, ok := d[name]
+	if !ok {
+		vs = make(map[string]string)
+		d[name] = vs
+	}
+	vs[version] = license
+}
+
+// Lookup returns the license declared by the named package, falling back to
+// one recorded without a version.
+func (d Declared) Lookup(name, version string) string {
+	vs := d[strings.ToLower(name)]
+	if l, ok := vs[version]; ok {
+		return l
+	}
+	return vs[""]
+}
+
+// These are the kinds of metadata files the walk reads.
+type kind int
+
+const (
+	kindNone kind = iota
+	kindRPM
+	kindAPK
+	kindCopyright
+	kindPython
+	kindGem
+)
+
+// KindOf reports what kind of metadata the file "name" holds, judging by its
+// path.
+func kindOf(name string) kind {
+	dir, base := path.Split(name)
+	dir = path.Clean(dir)
+	switch {
+	case layerwalk.RPMDatabase(name):
+		return kindRPM
+	case name == "lib/apk/db/installed":
+		return kindAPK
+	case base == "copyright" && path.Dir(dir) == "usr/share/doc":
+		return kindCopyright
+	case base == "METADATA" && strings.HasSuffix(dir, ".dist-info"),
+		base == "PKG-INFO" && strings.HasSuffix(dir, ".egg-info"):
+		return kindPython
+	case strings.HasSuffix(base, ".gemspec") && path.Base(dir) == "specifications":
+		return kindGem
+	}
+	return kindNone
+}
+
+// Read records the licenses in the metadata file "name" of kind "k". RPM
+// databases are handled separately.
+func (d Declared) read(k kind, name string, b []byte) {
+	switch k {
+	case kindAPK:
+		apk(d, b)
+	case kindCopyright:
+		// The directory is named for the binary package.
+		if l := copyright(b); l != "" {
+			d.add(path.Base(path.Dir(name)), "", l)
+		}
+	case kindPython:
+		python(d, b)
+	case kindGem:
+		gem(d, b)
+	}
+}
+
+// Apk records the licenses in an apk installed database, whose records are
+// separated by blank lines and made of single-letter keyed lines.
+func apk(d Declared, b []byte) {
+	var name, ver, lic string
+	flush := func() {
+		d.add(name, ver, lic)
+		name, ver, lic = "", "", ""
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := s.Text()
+		if l == "" {
+			flush()
+			continue
+		}
+		k, v, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "P":
+			name = v
+		case "V":
+			ver = v
+		case "L":
+			lic = v
+		}
+	}
+	flush()
+}
+
+// Copyright returns the license of the files in a machine-readable Debian
+// copyright file: that of the "Files: *" paragraph, or of the header
+// paragraph if there isn't one.
+//
+// Files not in the machine-readable format are ignored.
+func copyright(b []byte) string {
+	if !bytes.HasPrefix(b, []byte("Format:")) {
+		return ""
+	}
+	var header, all string
+	for i, para := range bytes.Split(b, []byte("\n\n")) {
+		var files, lic string
+		for _, l := range strings.Split(string(para), "\n") {
+			k, v, ok := strings.Cut(l, ":")
+			if !ok || strings.HasPrefix(l, " ") {
+				continue
+			}
+			switch k {
+			case "Files":
+				files = strings.TrimSpace(v)
+			case "License":
+				lic = strings.TrimSpace(v)
+			}
+		}
+		switch {
+		case i == 0:
+			header = lic
+		case files == "*":
+			all = lic
+		}
+	}
+	if all != "" {
+		return all
+	}
+	return header
+}
+
+// Python records the license in Python core metadata, preferring the
+// "License-Expression" field, then a short "License" field, then license
+// classifiers.
+func python(d Declared, b []byte) {
+	// Only the header block is needed; the body is the long description.
+	if i := bytes.Index(b, []byte("\n\n")); i != -1 {
+		b = b[:i+2]
+	}
+	hdr, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(b))).ReadMIMEHeader()
+	if err != nil && len(hdr) == 0 {
+		return
+	}
+	var lic string
+	switch l := hdr.Get("License"); {
+	case hdr.Get("License-Expression") != "":
+		lic = hdr.Get("License-Expression")
+	case l != "" && l != "UNKNOWN" && len(l) <= 100:
+		lic = l
+	default:
+		var cs []string
+		for _, c := range hdr.Values("Classifier") {
+			if !strings.HasPrefix(c, "License ::") {
+				continue
+			}
+			parts := strings.Split(c, "::")
+			cs = append(cs, strings.TrimSpace(parts[len(parts)-1]))
+		}
+		lic = strings.Join(cs, " OR ")
+	}
+	d.add(hdr.Get("Name"), hdr.Get("Version"), lic)
+}
+
+var (
+	gemName     = regexp.MustCompile(`^\S+\.\s*name\s*=\s*(\S+)$`)
+	gemVersion  = regexp.MustCompile(`^\S+\.\s*version\s*=\s*(\S+)$`)
+	gemLicenses = regexp.MustCompile(`^\S+\.\s*licenses?\s*=\s*(.+)$`)
+	gemString   = regexp.MustCompile(`["']([^"']+)["']`)
+)
+
+// Gem records the license in a RubyGems specification. A gem declaring
+// several licenses may be used under any of them.
+func gem(d Declared, b []byte) {
+	var name, ver string
+	var lics []string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if m := gemName.FindStringSubmatch(l); m != nil {
+			name = gemTrim(m[1])
+		}
+		if m := gemVersion.FindStringSubmatch(l); m != nil {
+			ver = gemTrim(m[1])
+		}
+		if m := gemLicenses.FindStringSubmatch(l); m != nil {
+			for _, q := range gemString.FindAllStringSubmatch(m[1], -1) {
+				lics = append(lics, q[1])
+			}
+		}
+	}
+	d.add(name, ver, strings.Join(lics, " OR "))
+}
+
+func gemTrim(s string) string {
+	s = strings.TrimSuffix(s, ".freeze")
+	return strings.Trim(s, `'"`)
+}
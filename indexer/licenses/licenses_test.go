@@ -0,0 +1,147 @@
+package licenses
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/quay/clair/v4/indexer/layerwalk"
+)
+
+// Layer returns a tar archive of the named files.
+func layer(t testing.TB, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     files[i],
+			Mode:     0o644,
+			Size:     int64(len(files[i+1])),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	apkDB = `C:Q1x
+P:musl
+V:1.2.4-r2
+L:MIT
+
+P:busybox
+V:1.36.1-r5
+L:GPL-2.0-only
+`
+	debCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: zlib
+License: Zlib
+
+Files: *
+Copyright: 1995-2013 Jean-loup Gailly and Mark Adler
+License: Zlib
+
+Files: debian/*
+License: GPL-2+
+
+License: Zlib
+ This software is provided 'as-is', without any express or implied warranty.
+`
+	pyMetadata = `Metadata-Version: 2.1
+Name: requests
+Version: 2.31.0
+License: Apache 2.0
+Classifier: License :: OSI Approved :: Apache Software License
+
+A long description.
+License: not a header
+`
+	pyClassifiers = `Metadata-Version: 2.1
+Name: six
+Version: 1.16.0
+License: UNKNOWN
+Classifier: License :: OSI Approved :: MIT License
+Classifier: Programming Language :: Python :: 3
+`
+	gemspec = `# -*- encoding: utf-8 -*-
+Gem::Specification.new do |s|
+  s.name = "rack".freeze
+  s.version = "3.0.8"
+  s.licenses = ["MIT".freeze, "Ruby".freeze]
+end
+`
+)
+
+func TestWalk(t *testing.T) {
+	ctx := context.Background()
+	tarball := layer(t,
+		"lib/apk/db/installed", apkDB,
+		"usr/share/doc/zlib1g/copyright", debCopyright,
+		"usr/share/doc/old/copyright", "This is free software.\n",
+		"./usr/lib/python3/site-packages/requests-2.31.0.dist-info/METADATA", pyMetadata,
+		"usr/lib/python3/site-packages/six.egg-info/PKG-INFO", pyClassifiers,
+		"usr/lib/ruby/gems/3.2.0/specifications/rack-3.0.8.gemspec", gemspec,
+		"etc/os-release", "ID=alpine\n",
+	)
+	v := &visitor{out: make(Declared)}
+	if err := layerwalk.Walk(ctx, t.TempDir(), tar.NewReader(bytes.NewReader(tarball)), v); err != nil {
+		t.Fatal(err)
+	}
+	got := v.out
+	for _, tc := range []struct {
+		Name, Version, License string
+	}{
+		{"musl", "1.2.4-r2", "MIT"},
+		{"busybox", "1.36.1-r5", "GPL-2.0-only"},
+		{"busybox", "1.36.1-r6", ""},
+		{"zlib1g", "1:1.2.13.dfsg-1", "Zlib"},
+		{"old", "", ""},
+		{"Requests", "2.31.0", "Apache 2.0"},
+		{"six", "1.16.0", "MIT License"},
+		{"rack", "3.0.8", "MIT OR Ruby"},
+	} {
+		if l := got.Lookup(tc.Name, tc.Version); l != tc.License {
+			t.Errorf("%s %s: got: %q, want: %q", tc.Name, tc.Version, l, tc.License)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	deny := NewPolicy(nil, []string{"AGPL-3.0-only", "SSPL-1.0"})
+	allow := NewPolicy([]string{"MIT", "Apache-2.0", "BSD-3-Clause", "GPL-2.0-only"}, []string{"GPL-2.0-only"})
+	tt := []struct {
+		Policy *Policy
+		Expr   string
+		OK     bool
+		Reason string
+	}{
+		{deny, "MIT", true, ""},
+		{deny, "agpl-3.0-only", false, "agpl-3.0-only is denied"},
+		{deny, "MIT AND SSPL-1.0", false, "SSPL-1.0 is denied"},
+		{deny, "MIT OR SSPL-1.0", true, ""},
+		{deny, "(AGPL-3.0-only or SSPL-1.0) and MIT", false, "AGPL-3.0-only is denied"},
+		{allow, "MIT", true, ""},
+		{allow, "Zlib", false, "Zlib is not allowed"},
+		{allow, "GPL-2.0-only", false, "GPL-2.0-only is denied"},
+		{allow, "Apache-2.0 WITH LLVM-exception", true, ""},
+		{allow, "(MIT OR Zlib) AND (BSD-3-Clause OR GPL-2.0-only)", true, ""},
+		{allow, "MIT AND (Zlib OR GPL-2.0-only)", false, "Zlib is not allowed"},
+		{allow, "MIT) Zlib", false, "Zlib is not allowed"},
+	}
+	for _, tc := range tt {
+		ok, reason := tc.Policy.Check(tc.Expr)
+		if ok != tc.OK || reason != tc.Reason {
+			t.Errorf("%q: got: (%v, %q), want: (%v, %q)", tc.Expr, ok, reason, tc.OK, tc.Reason)
+		}
+	}
+}
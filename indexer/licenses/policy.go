@@ -0,0 +1,118 @@
+package licenses
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy decides whether licenses are acceptable.
+type Policy struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// NewPolicy returns a Policy denying the licenses in "deny" and, if "allow"
+// isn't empty, any license not in it. Identifiers are compared without
+// regard to case.
+func NewPolicy(allow, deny []string) *Policy {
+	p := Policy{
+		allow: make(map[string]struct{}, len(allow)),
+		deny:  make(map[string]struct{}, len(deny)),
+	}
+	for _, id := range allow {
+		p.allow[strings.ToLower(id)] = struct{}{}
+	}
+	for _, id := range deny {
+		p.deny[strings.ToLower(id)] = struct{}{}
+	}
+	return &p
+}
+
+// Check reports whether the declared license "expr" is acceptable and, if
+// not, why.
+//
+// The license is treated as an SPDX license expression: every license
+// joined by "AND" must be acceptable, at least one joined by "OR" must be,
+// and exceptions named by "WITH" are ignored. The operators are matched
+// without regard to case, as RPM spells them in lower case.
+func (p *Policy) Check(expr string) (ok bool, reason string) {
+	e := exprParser{toks: tokenize(expr)}
+	ok, reason = e.or(p)
+	if ok && e.pos < len(e.toks) {
+		// Trailing garbage, such as an unbalanced parenthesis: check the
+		// rest as well rather than ignoring it.
+		e.pos++
+		return e.or(p)
+	}
+	return ok, reason
+}
+
+func (p *Policy) id(id string) (bool, string) {
+	k := strings.ToLower(id)
+	if _, ok := p.deny[k]; ok {
+		return false, fmt.Sprintf("%s is denied", id)
+	}
+	if _, ok := p.allow[k]; len(p.allow) != 0 && !ok {
+		return false, fmt.Sprintf("%s is not allowed", id)
+	}
+	return true, ""
+}
+
+func tokenize(s string) []string {
+	s = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)
+	return strings.Fields(s)
+}
+
+// ExprParser evaluates a license expression against a Policy as it parses.
+type exprParser struct {
+	toks []string
+	pos  int
+}
+
+func (e *exprParser) peek() string {
+	if e.pos < len(e.toks) {
+		return e.toks[e.pos]
+	}
+	return ""
+}
+
+func (e *exprParser) or(p *Policy) (bool, string) {
+	ok, reason := e.and(p)
+	for strings.EqualFold(e.peek(), "or") {
+		e.pos++
+		if alt, _ := e.and(p); alt {
+			ok, reason = true, ""
+		}
+	}
+	return ok, reason
+}
+
+func (e *exprParser) and(p *Policy) (bool, string) {
+	ok, reason := e.term(p)
+	for strings.EqualFold(e.peek(), "and") {
+		e.pos++
+		if next, r := e.term(p); !next && ok {
+			ok, reason = false, r
+		}
+	}
+	return ok, reason
+}
+
+func (e *exprParser) term(p *Policy) (bool, string) {
+	t := e.peek()
+	e.pos++
+	switch t {
+	case "":
+		return true, ""
+	case "(":
+		ok, reason := e.or(p)
+		if e.peek() == ")" {
+			e.pos++
+		}
+		return ok, reason
+	}
+	if strings.EqualFold(e.peek(), "with") {
+		e.pos += 2
+	}
+	return p.id(t)
+}
@@ -0,0 +1,122 @@
+package licenses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_licenses", "the license store")
+
+// Store persists the licenses declared in layers.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+func digestStrings(ds []claircore.Digest) []string {
+	out := make([]string, len(ds))
+	for i, d := range ds {
+		out[i] = d.String()
+	}
+	return out
+}
+
+// States returns the version of the walk each of the layers was examined
+// with, keyed by digest. Layers not yet examined are omitted.
+func (s *Store) States(ctx context.Context, ds ...claircore.Digest) (_ map[string]string, err error) {
+	const query = `SELECT layer, state FROM indexer_licenses WHERE layer = ANY($1::text[]);`
+	defer queryMetrics.Observe("states", &err)()
+	rows, err := s.pool.Query(ctx, query, digestStrings(ds))
+	if err != nil {
+		return nil, fmt.Errorf("licenses: unable to look up layers: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]string, len(ds))
+	for rows.Next() {
+		var l, st string
+		if err = rows.Scan(&l, &st); err != nil {
+			return nil, fmt.Errorf("licenses: unable to look up layers: %w", err)
+		}
+		out[l] = st
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("licenses: unable to look up layers: %w", err)
+	}
+	return out, nil
+}
+
+// Get returns the licenses declared in each of the layers, keyed by digest.
+// Layers not yet examined are omitted.
+func (s *Store) Get(ctx context.Context, ds ...claircore.Digest) (_ map[string]Declared, err error) {
+	const query = `SELECT layer, licenses FROM indexer_licenses WHERE layer = ANY($1::text[]);`
+	defer queryMetrics.Observe("get", &err)()
+	out := make(map[string]Declared, len(ds))
+	if len(ds) == 0 {
+		return out, nil
+	}
+	rows, err := s.pool.Query(ctx, query, digestStrings(ds))
+	if err != nil {
+		return nil, fmt.Errorf("licenses: unable to look up layers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l string
+		var b []byte
+		if err = rows.Scan(&l, &b); err != nil {
+			return nil, fmt.Errorf("licenses: unable to look up layers: %w", err)
+		}
+		var d Declared
+		if err = json.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("licenses: bad stored licenses for %s: %w", l, err)
+		}
+		out[l] = d
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("licenses: unable to look up layers: %w", err)
+	}
+	return out, nil
+}
+
+// Put stores the licenses declared in the layer, replacing any previous
+// ones.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, l Declared) (err error) {
+	const query = `INSERT INTO indexer_licenses (layer, state, licenses)
+VALUES ($1, $2, $3)
+ON CONFLICT (layer) DO UPDATE
+SET state = EXCLUDED.state, licenses = EXCLUDED.licenses, updated = now();`
+	defer queryMetrics.Observe("put", &err)()
+	if l == nil {
+		l = Declared{}
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("licenses: unable to encode licenses: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, query, d.String(), version, b); err != nil {
+		return fmt.Errorf("licenses: unable to store licenses for %v: %w", d, err)
+	}
+	return nil
+}
+
+// Prune removes the licenses for layers the indexer no longer knows about.
+func (s *Store) Prune(ctx context.Context) (err error) {
+	const query = `DELETE FROM indexer_licenses l
+WHERE NOT EXISTS (SELECT FROM layer WHERE layer.hash = l.layer);`
+	defer queryMetrics.Observe("prune", &err)()
+	if _, err = s.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("licenses: unable to prune: %w", err)
+	}
+	return nil
+}
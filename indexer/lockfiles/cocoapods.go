@@ -0,0 +1,56 @@
+package lockfiles
+
+import (
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MatchCocoaPods recognizes CocoaPods' lockfile, "Podfile.lock".
+func matchCocoaPods(p string) string {
+	if path.Base(p) == "Podfile.lock" {
+		return p
+	}
+	return ""
+}
+
+// ParseCocoaPods reads "Podfile.lock". Its "PODS" list has an entry of the
+// form "Name (version)" for every pod, which is a mapping to the pod's own
+// dependencies if it has any. Subspecs, like "Firebase/Core", are reported as
+// the pod they belong to.
+func parseCocoaPods(b []byte) ([]dep, error) {
+	var doc struct {
+		Pods []yaml.Node `yaml:"PODS"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(doc.Pods))
+	out := make([]dep, 0, len(doc.Pods))
+	for _, n := range doc.Pods {
+		var s string
+		switch n.Kind {
+		case yaml.ScalarNode:
+			s = n.Value
+		case yaml.MappingNode:
+			if len(n.Content) == 0 {
+				continue
+			}
+			s = n.Content[0].Value
+		default:
+			continue
+		}
+		name, v, ok := strings.Cut(s, " (")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "/")
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, dep{name: name, version: strings.TrimSuffix(v, ")")})
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package lockfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+)
+
+// MatchComposer recognizes Composer's lockfile, "composer.lock", and the
+// record of installed packages, "vendor/composer/installed.json".
+//
+// Installed packages are reported as being in the vendor directory, rather
+// than the file inside it, so they aren't taken for vendored copies.
+func matchComposer(p string) string {
+	switch path.Base(p) {
+	case "composer.lock":
+		return p
+	case "installed.json":
+		if dir := path.Dir(p); path.Base(dir) == "composer" {
+			return path.Dir(dir)
+		}
+	}
+	return ""
+}
+
+// ComposerPackage is the part of a package entry read from Composer's files.
+type composerPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ParseComposer reads "composer.lock" or "installed.json". Composer 1 wrote
+// the latter as a bare list of packages.
+func parseComposer(b []byte) ([]dep, error) {
+	var ps []composerPackage
+	if b = bytes.TrimSpace(b); bytes.HasPrefix(b, []byte("[")) {
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return nil, err
+		}
+	} else {
+		var doc struct {
+			Packages    []composerPackage `json:"packages"`
+			PackagesDev []composerPackage `json:"packages-dev"`
+		}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		ps = append(doc.Packages, doc.PackagesDev...)
+	}
+	out := make([]dep, len(ps))
+	for i, p := range ps {
+		out[i] = dep{name: p.Name, version: p.Version}
+	}
+	return out, nil
+}
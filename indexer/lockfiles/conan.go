@@ -0,0 +1,62 @@
+package lockfiles
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MatchConan recognizes Conan's lockfile, "conan.lock".
+func matchConan(p string) string {
+	if path.Base(p) == "conan.lock" {
+		return p
+	}
+	return ""
+}
+
+// ParseConan reads "conan.lock". Conan 2 lists the references of the
+// requirements; Conan 1 has a graph of nodes, each with a reference. Build
+// requirements aren't reported, as they're not part of what's built.
+func parseConan(b []byte) ([]dep, error) {
+	var doc struct {
+		Requires  []string `json:"requires"`
+		GraphLock struct {
+			Nodes map[string]struct {
+				Ref string `json:"ref"`
+			} `json:"nodes"`
+		} `json:"graph_lock"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	refs := doc.Requires
+	ids := make([]string, 0, len(doc.GraphLock.Nodes))
+	for id := range doc.GraphLock.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		refs = append(refs, doc.GraphLock.Nodes[id].Ref)
+	}
+	out := make([]dep, 0, len(refs))
+	for _, r := range refs {
+		if d, ok := conanRef(r); ok {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// ConanRef parses a reference of the form
+// "name/version[@user/channel][#revision[%timestamp]]".
+func conanRef(r string) (dep, bool) {
+	if i := strings.IndexAny(r, "@#"); i != -1 {
+		r = r[:i]
+	}
+	name, v, ok := strings.Cut(r, "/")
+	if !ok || strings.Contains(v, "/") {
+		return dep{}, false
+	}
+	return dep{name: name, version: v}, true
+}
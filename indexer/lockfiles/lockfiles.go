@@ -0,0 +1,341 @@
+// Package lockfiles detects the dependencies recorded by language package
+// managers claircore has no scanner for, such as PHP's Composer or the Swift
+// Package Manager, and reports them as packages.
+//
+// Each package manager is its own ecosystem, with a package scanner named
+// after it. Its packages are in the repository OSV publishes the manager's
+// advisories under, such as "packagist", and have a source package named by
+// the package URL OSV records for them, which is how the lockfiles matcher
+// finds their advisories.
+package lockfiles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer"
+	"github.com/quay/claircore/pkg/tarfs"
+	"github.com/quay/zlog"
+)
+
+// These are the names of the supported package managers.
+const (
+	Composer  = `composer`
+	Swift     = `swift`
+	CocoaPods = `cocoapods`
+	Conan     = `conan`
+)
+
+// MaxSize is the size of the largest lockfile read.
+const maxSize = 32 * 1024 * 1024
+
+// A manager describes a package manager's lockfiles.
+type manager struct {
+	name string
+	// Repo is the repository the manager's packages are in.
+	repo claircore.Repository
+	// Purl is the package URL type of the manager's packages.
+	purl string
+	// Match reports the package database for the path, or "" if the path
+	// isn't one of the manager's lockfiles.
+	match func(p string) string
+	// Parse returns the dependencies recorded in the lockfile.
+	parse func(b []byte) ([]dep, error)
+}
+
+// Dep is a dependency recorded in a lockfile.
+type dep struct {
+	name, version string
+}
+
+var managers = map[string]*manager{
+	Composer: {
+		name: Composer,
+		repo: claircore.Repository{
+			Name: "packagist",
+			URI:  "https://packagist.org/",
+		},
+		purl:  "composer",
+		match: matchComposer,
+		parse: parseComposer,
+	},
+	Swift: {
+		name:  Swift,
+		repo:  claircore.Repository{Name: "swifturl"},
+		purl:  "swift",
+		match: matchSwift,
+		parse: parseSwift,
+	},
+	// OSV doesn't publish CocoaPods advisories, but the packages can be
+	// matched by advisory feeds.
+	CocoaPods: {
+		name: CocoaPods,
+		repo: claircore.Repository{
+			Name: "cocoapods",
+			URI:  "https://cocoapods.org/",
+		},
+		purl:  "cocoapods",
+		match: matchCocoaPods,
+		parse: parseCocoaPods,
+	},
+	Conan: {
+		name: Conan,
+		repo: claircore.Repository{
+			Name: "conancenter",
+			URI:  "https://conan.io/center/",
+		},
+		purl:  "conan",
+		match: matchConan,
+		parse: parseConan,
+	},
+}
+
+// Names returns the names of the supported package managers, sorted.
+func Names() []string {
+	out := make([]string, 0, len(managers))
+	for n := range managers {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// IsRepository reports whether "name" is the name of a repository packages
+// found in lockfiles are in.
+func IsRepository(name string) bool {
+	for _, m := range managers {
+		if m.repo.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Find reports the packages recorded in the manager's lockfiles in "sys".
+func (m *manager) find(ctx context.Context, sys fs.FS) ([]*claircore.Package, error) {
+	var out []*claircore.Package
+	err := fs.WalkDir(sys, ".", func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case !d.Type().IsRegular():
+			return nil
+		case strings.HasPrefix(path.Base(p), ".wh."):
+			return nil
+		}
+		db := m.match(p)
+		if db == "" {
+			return nil
+		}
+		ds, err := m.read(sys, p)
+		if err != nil {
+			zlog.Info(ctx).
+				Err(err).
+				Str("path", p).
+				Msg("unable to read lockfile, skipping")
+			return nil
+		}
+		zlog.Debug(ctx).
+			Str("path", p).
+			Int("count", len(ds)).
+			Msg("found lockfile")
+		for _, d := range ds {
+			out = append(out, &claircore.Package{
+				Name:           d.name,
+				Version:        d.version,
+				Kind:           claircore.BINARY,
+				PackageDB:      m.name + ":" + db,
+				RepositoryHint: m.repo.Name,
+				Source: &claircore.Package{
+					Name: "pkg:" + m.purl + "/" + d.name,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (m *manager) read(sys fs.FS, p string) ([]dep, error) {
+	f, err := sys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	switch {
+	case err != nil:
+		return nil, err
+	case len(b) > maxSize:
+		return nil, fmt.Errorf("over size limit of %d bytes", maxSize)
+	}
+	ds, err := m.parse(b)
+	if err != nil {
+		return nil, err
+	}
+	out := ds[:0]
+	for _, d := range ds {
+		if d.name != "" && d.version != "" {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Open returns the filesystem of the layer.
+func open(layer *claircore.Layer) (fs.FS, func() error, error) {
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, nil, err
+	}
+	sys, err := tarfs.New(r)
+	if err != nil {
+		r.Close()
+		return nil, nil, fmt.Errorf("lockfiles: unable to open tar: %w", err)
+	}
+	return sys, r.Close, nil
+}
+
+var (
+	_ indexer.PackageScanner    = (*Scanner)(nil)
+	_ indexer.RepositoryScanner = (*RepoScanner)(nil)
+)
+
+// Scanner reports the packages recorded in a package manager's lockfiles.
+type Scanner struct {
+	m *manager
+}
+
+// Name implements indexer.VersionedScanner.
+func (s *Scanner) Name() string { return s.m.name }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Scan implements indexer.PackageScanner.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/lockfiles/Scanner.Scan",
+		"manager", s.m.name,
+		"version", s.Version(),
+		"layer", layer.Hash.String())
+	sys, done, err := open(layer)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ps, err := s.m.find(ctx, sys)
+	if err != nil {
+		return nil, fmt.Errorf("lockfiles: unable to find %s lockfiles: %w", s.m.name, err)
+	}
+	return ps, nil
+}
+
+// RepoScanner reports a package manager's repository for layers with its
+// lockfiles.
+type RepoScanner struct {
+	m *manager
+}
+
+// Name implements indexer.VersionedScanner.
+func (s *RepoScanner) Name() string { return s.m.name }
+
+// Version implements indexer.VersionedScanner.
+func (*RepoScanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*RepoScanner) Kind() string { return "repository" }
+
+// Scan implements indexer.RepositoryScanner.
+func (s *RepoScanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Repository, error) {
+	defer trace.StartRegion(ctx, "RepoScanner.Scan").End()
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/lockfiles/RepoScanner.Scan",
+		"manager", s.m.name,
+		"version", s.Version(),
+		"layer", layer.Hash.String())
+	sys, done, err := open(layer)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ps, err := s.m.find(ctx, sys)
+	if err != nil {
+		return nil, fmt.Errorf("lockfiles: unable to find %s lockfiles: %w", s.m.name, err)
+	}
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	r := s.m.repo
+	return []*claircore.Repository{&r}, nil
+}
+
+// Coalescer puts the packages found in each layer into an IndexReport.
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// A package recorded in more than one lockfile has an environment for each.
+func (*coalescer) Coalesce(_ context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+		Repositories: map[string]*claircore.Repository{},
+	}
+	for _, l := range ls {
+		if len(l.Repos) == 0 {
+			continue
+		}
+		rs := make([]string, len(l.Repos))
+		for i, r := range l.Repos {
+			rs[i] = r.ID
+			ir.Repositories[r.ID] = r
+		}
+		for _, pkg := range l.Pkgs {
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = append(ir.Environments[pkg.ID], &claircore.Environment{
+				PackageDB:     pkg.PackageDB,
+				IntroducedIn:  l.Hash,
+				RepositoryIDs: rs,
+			})
+		}
+	}
+	return ir, nil
+}
+
+// NewEcosystem returns the ecosystem of scanners for the package manager
+// "name", which must be one of Names.
+func NewEcosystem(_ context.Context, name string) (*indexer.Ecosystem, error) {
+	m, ok := managers[name]
+	if !ok {
+		return nil, fmt.Errorf("lockfiles: unknown package manager %q (known: %s)", name, strings.Join(Names(), ", "))
+	}
+	return &indexer.Ecosystem{
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{&Scanner{m: m}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) {
+			return nil, nil
+		},
+		RepositoryScanners: func(context.Context) ([]indexer.RepositoryScanner, error) {
+			return []indexer.RepositoryScanner{&RepoScanner{m: m}}, nil
+		},
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return &coalescer{}, nil
+		},
+	}, nil
+}
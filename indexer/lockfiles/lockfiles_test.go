@@ -0,0 +1,157 @@
+package lockfiles
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+func TestComposer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	sys := fstest.MapFS{
+		"var/www/html/composer.lock": file(`{
+			"packages": [{"name": "symfony/http-kernel", "version": "v5.4.20"}],
+			"packages-dev": [{"name": "phpunit/phpunit", "version": "9.6.3"}]
+		}`),
+		// Composer 2.
+		"var/www/html/vendor/composer/installed.json": file(`{
+			"packages": [{"name": "symfony/http-kernel", "version": "v5.4.20"}],
+			"dev": false
+		}`),
+		// Composer 1.
+		"srv/app/vendor/composer/installed.json": file(`[{"name": "guzzlehttp/guzzle", "version": "6.5.5"}]`),
+		// Missing a version.
+		"opt/tool/composer.lock": file(`{"packages": [{"name": "a/b"}]}`),
+		// Not JSON.
+		"opt/broken/composer.lock": file(`<<<`),
+		// Removed in this layer.
+		"opt/old/.wh.composer.lock": file(""),
+	}
+	got, err := managers[Composer].find(ctx, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := func(name, v, db string) *claircore.Package {
+		return &claircore.Package{
+			Name:           name,
+			Version:        v,
+			Kind:           claircore.BINARY,
+			PackageDB:      "composer:" + db,
+			RepositoryHint: "packagist",
+			Source:         &claircore.Package{Name: "pkg:composer/" + name},
+		}
+	}
+	want := []*claircore.Package{
+		pkg("guzzlehttp/guzzle", "6.5.5", "srv/app/vendor"),
+		pkg("symfony/http-kernel", "v5.4.20", "var/www/html/composer.lock"),
+		pkg("phpunit/phpunit", "9.6.3", "var/www/html/composer.lock"),
+		pkg("symfony/http-kernel", "v5.4.20", "var/www/html/vendor"),
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		Name  string
+		Parse func([]byte) ([]dep, error)
+		In    string
+		Want  []dep
+	}{
+		{
+			Name:  "SwiftV1",
+			Parse: parseSwift,
+			In: `{"object": {"pins": [
+				{"package": "swift-nio", "repositoryURL": "https://github.com/apple/swift-nio.git",
+				 "state": {"branch": null, "revision": "abc", "version": "2.40.0"}},
+				{"package": "local", "repositoryURL": "https://example.com/local.git",
+				 "state": {"branch": "main", "revision": "def", "version": null}}
+			]}, "version": 1}`,
+			Want: []dep{{"github.com/apple/swift-nio", "2.40.0"}, {"example.com/local", ""}},
+		},
+		{
+			Name:  "SwiftV2",
+			Parse: parseSwift,
+			In: `{"pins": [
+				{"identity": "swift-log", "kind": "remoteSourceControl",
+				 "location": "git@github.com:apple/swift-log.git",
+				 "state": {"revision": "abc", "version": "1.5.3"}}
+			], "version": 2}`,
+			Want: []dep{{"github.com/apple/swift-log", "1.5.3"}},
+		},
+		{
+			Name:  "CocoaPods",
+			Parse: parseCocoaPods,
+			In: `PODS:
+  - Alamofire (5.6.4)
+  - Firebase/Core (10.3.0):
+    - Firebase/CoreOnly
+    - FirebaseAnalytics (~> 10.3.0)
+  - Firebase/CoreOnly (10.3.0):
+    - FirebaseCore (= 10.3.0)
+
+DEPENDENCIES:
+  - Alamofire (~> 5.6)
+  - Firebase/Core
+
+COCOAPODS: 1.11.3
+`,
+			Want: []dep{{"Alamofire", "5.6.4"}, {"Firebase", "10.3.0"}},
+		},
+		{
+			Name:  "Conan2",
+			Parse: parseConan,
+			In: `{"version": "0.5",
+				"requires": ["zlib/1.2.13#97d5730b529b4224045fe7090592d4c1%1692672717.68", "openssl/3.1.2@corp/stable"],
+				"build_requires": ["cmake/3.27.4#1"]}`,
+			Want: []dep{{"zlib", "1.2.13"}, {"openssl", "3.1.2"}},
+		},
+		{
+			Name:  "Conan1",
+			Parse: parseConan,
+			In: `{"version": "0.4", "graph_lock": {"nodes": {
+				"0": {"ref": "conanfile.txt"},
+				"1": {"ref": "zlib/1.2.11#rev"},
+				"2": {"ref": "bzip2/1.0.8@user/channel"}
+			}}}`,
+			Want: []dep{{"zlib", "1.2.11"}, {"bzip2", "1.0.8"}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := tc.Parse([]byte(tc.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want, cmp.AllowUnexported(dep{})) {
+				t.Error(cmp.Diff(got, tc.Want, cmp.AllowUnexported(dep{})))
+			}
+		})
+	}
+}
+
+func TestNewEcosystem(t *testing.T) {
+	ctx := context.Background()
+	for _, n := range Names() {
+		e, err := NewEcosystem(ctx, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps, err := e.PackageScanners(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ps[0].Name(); got != n {
+			t.Errorf("got: %q, want: %q", got, n)
+		}
+	}
+	if _, err := NewEcosystem(ctx, "pip"); err == nil {
+		t.Error("expected error for unknown package manager")
+	}
+}
@@ -0,0 +1,66 @@
+package lockfiles
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// MatchSwift recognizes the Swift Package Manager's lockfile,
+// "Package.resolved".
+func matchSwift(p string) string {
+	if path.Base(p) == "Package.resolved" {
+		return p
+	}
+	return ""
+}
+
+// SwiftPin is a pinned package in "Package.resolved". Version 1 of the
+// format calls the location "repositoryURL".
+type swiftPin struct {
+	Location      string `json:"location"`
+	RepositoryURL string `json:"repositoryURL"`
+	State         struct {
+		Version string `json:"version"`
+	} `json:"state"`
+}
+
+// ParseSwift reads "Package.resolved". Packages are named by their location,
+// as OSV does: the host and path, without a scheme or ".git" suffix. Pins to
+// a branch or revision rather than a version are skipped.
+func parseSwift(b []byte) ([]dep, error) {
+	var doc struct {
+		Pins   []swiftPin `json:"pins"`
+		Object struct {
+			Pins []swiftPin `json:"pins"`
+		} `json:"object"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	pins := append(doc.Pins, doc.Object.Pins...)
+	out := make([]dep, 0, len(pins))
+	for _, p := range pins {
+		loc := p.Location
+		if loc == "" {
+			loc = p.RepositoryURL
+		}
+		out = append(out, dep{name: swiftName(loc), version: p.State.Version})
+	}
+	return out, nil
+}
+
+// SwiftName returns the package name for the repository location "loc",
+// which may be a URL or an scp-style "git@host:path".
+func swiftName(loc string) string {
+	var name string
+	if u, err := url.Parse(loc); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	} else if at, colon := strings.IndexByte(loc, '@'), strings.IndexByte(loc, ':'); colon != -1 && at < colon {
+		name = loc[at+1:colon] + "/" + strings.TrimPrefix(loc[colon+1:], "/")
+	} else {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(name, "/"), ".git")
+}
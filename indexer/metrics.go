@@ -0,0 +1,35 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+)
+
+var indexInFlight = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "clair",
+		Subsystem: "indexer",
+		Name:      "index_in_flight",
+		Help:      "Number of Index calls currently in progress.",
+	},
+)
+
+// Instrument wraps the provided Service to report the number of Index calls in
+// flight.
+func Instrument(s Service) Service {
+	return &instrumented{Service: s}
+}
+
+type instrumented struct {
+	Service
+}
+
+// Index implements Indexer.
+func (i *instrumented) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	indexInFlight.Inc()
+	defer indexInFlight.Dec()
+	return i.Service.Index(ctx, m)
+}
@@ -0,0 +1,9 @@
+--- a relation holding leases on manifests being indexed
+CREATE TABLE IF NOT EXISTS indexer_queue (
+    manifest text PRIMARY KEY,
+    owner text NOT NULL,
+    claimed timestamptz NOT NULL DEFAULT now(),
+    expiry timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS indexer_queue_expiry_idx ON indexer_queue (expiry);
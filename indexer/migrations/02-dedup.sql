@@ -0,0 +1,15 @@
+-- a relation mapping manifests to the manifest whose index report is stored
+-- for the same content
+CREATE TABLE IF NOT EXISTS indexer_dedup (
+    manifest text PRIMARY KEY,
+    -- hash of the manifest's layer digests, in order
+    content text NOT NULL,
+    -- indexer state the canonical report was created with
+    state text NOT NULL,
+    canonical text NOT NULL,
+    -- set on a canonical manifest that's been deleted while still referenced
+    deleted boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS indexer_dedup_content_idx ON indexer_dedup (content, state);
+CREATE INDEX IF NOT EXISTS indexer_dedup_canonical_idx ON indexer_dedup (canonical);
@@ -0,0 +1,9 @@
+-- index reports for manifests of non-image artifacts
+CREATE TABLE IF NOT EXISTS indexer_artifact (
+    manifest text PRIMARY KEY,
+    artifact_type text NOT NULL,
+    -- combined name and version of the scanners the report was created with
+    state text NOT NULL,
+    report jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
@@ -0,0 +1,6 @@
+-- client-supplied labels for submitted manifests
+CREATE TABLE IF NOT EXISTS indexer_labels (
+    manifest text PRIMARY KEY,
+    labels jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
@@ -0,0 +1,9 @@
+-- credentials found embedded in submitted manifests
+CREATE TABLE IF NOT EXISTS indexer_secrets (
+    manifest text PRIMARY KEY,
+    -- identifies the rules and options the layers were examined with
+    state text NOT NULL,
+    files jsonb NOT NULL,
+    env jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
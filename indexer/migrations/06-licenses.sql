@@ -0,0 +1,8 @@
+-- licenses declared by the packages in indexed layers
+CREATE TABLE IF NOT EXISTS indexer_licenses (
+    layer text PRIMARY KEY,
+    -- identifies the walk the layer was examined with
+    state text NOT NULL,
+    licenses jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
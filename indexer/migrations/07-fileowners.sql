@@ -0,0 +1,16 @@
+-- layers examined for package file lists
+CREATE TABLE IF NOT EXISTS indexer_file_owners_layer (
+    layer text PRIMARY KEY,
+    -- identifies the walk the layer was examined with
+    state text NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
+-- the files each package in a layer's package databases installed
+CREATE TABLE IF NOT EXISTS indexer_file_owners (
+    layer text NOT NULL REFERENCES indexer_file_owners_layer (layer) ON DELETE CASCADE,
+    path text NOT NULL,
+    package text NOT NULL,
+    version text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS indexer_file_owners_path_idx ON indexer_file_owners (path, layer);
+CREATE INDEX IF NOT EXISTS indexer_file_owners_layer_idx ON indexer_file_owners (layer);
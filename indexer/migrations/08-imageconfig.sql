@@ -0,0 +1,6 @@
+-- image configs of submitted manifests
+CREATE TABLE IF NOT EXISTS indexer_image_config (
+    manifest text PRIMARY KEY,
+    config jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
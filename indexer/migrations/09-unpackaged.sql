@@ -0,0 +1,8 @@
+-- contents no package manager accounts for, found in submitted manifests
+CREATE TABLE IF NOT EXISTS indexer_unpackaged (
+    manifest text PRIMARY KEY,
+    -- identifies the walk the layers were examined with
+    state text NOT NULL,
+    contents jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
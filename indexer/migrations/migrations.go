@@ -0,0 +1,70 @@
+// Package migrations holds the schema of the tables Clair's optional indexer
+// features keep alongside claircore's, in the indexer database.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+//go:embed *.sql
+var fs embed.FS
+
+func runFile(n string) func(*sql.Tx) error {
+	return dbutil.RunFile(fs, n)
+}
+
+// MigrationTable is the table the indexer migrations are recorded in.
+const MigrationTable = "clair_indexer_migrations"
+
+// Migrations creates the tables for every optional indexer feature, whether or
+// not it's enabled.
+var Migrations = []migrate.Migration{
+	{
+		ID: 1,
+		Up: runFile("01-queue.sql"),
+	},
+	{
+		ID: 2,
+		Up: runFile("02-dedup.sql"),
+	},
+	{
+		ID: 3,
+		Up: runFile("03-artifact.sql"),
+	},
+	{
+		ID: 4,
+		Up: runFile("04-labels.sql"),
+	},
+	{
+		ID: 5,
+		Up: runFile("05-secrets.sql"),
+	},
+	{
+		ID: 6,
+		Up: runFile("06-licenses.sql"),
+	},
+	{
+		ID: 7,
+		Up: runFile("07-fileowners.sql"),
+	},
+	{
+		ID: 8,
+		Up: runFile("08-imageconfig.sql"),
+	},
+	{
+		ID: 9,
+		Up: runFile("09-unpackaged.sql"),
+	},
+}
+
+// Init performs the indexer migrations on the database described by "cfg".
+func Init(ctx context.Context, cfg *pgx.ConnConfig) error {
+	return dbutil.Migrate(ctx, cfg, MigrationTable, Migrations...)
+}
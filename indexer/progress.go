@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer/controller"
+)
+
+// Progress is a summary of how far along the indexing of a manifest is.
+//
+// It's computed from the (possibly partial) IndexReport persisted by the
+// indexer as it moves through its states.
+type Progress struct {
+	// State is the name of the indexer state the manifest is currently in.
+	State string `json:"state"`
+	// Step and Steps describe the position of State in the sequence of
+	// states an index operation moves through.
+	Step  int `json:"step"`
+	Steps int `json:"steps"`
+	// Finished reports whether the indexer has stopped working on the
+	// manifest, successfully or not.
+	Finished bool   `json:"finished"`
+	Success  bool   `json:"success"`
+	Err      string `json:"err,omitempty"`
+	// The number of layers that have contributed packages and the number of
+	// packages, distributions, and repositories found so far.
+	Layers        int `json:"layers"`
+	Packages      int `json:"packages"`
+	Distributions int `json:"distributions"`
+	Repositories  int `json:"repositories"`
+}
+
+// ProgressSteps is the ordered list of states an index operation moves
+// through.
+var progressSteps = []controller.State{
+	controller.CheckManifest,
+	controller.FetchLayers,
+	controller.ScanLayers,
+	controller.Coalesce,
+	controller.IndexManifest,
+	controller.IndexFinished,
+}
+
+// ReportProgress summarizes the provided IndexReport.
+func ReportProgress(ir *claircore.IndexReport) Progress {
+	p := Progress{
+		State:         ir.State,
+		Steps:         len(progressSteps),
+		Success:       ir.Success,
+		Err:           ir.Err,
+		Packages:      len(ir.Packages),
+		Distributions: len(ir.Distributions),
+		Repositories:  len(ir.Repositories),
+	}
+	p.Finished = ir.Success || ir.Err != "" ||
+		ir.State == controller.IndexFinished.String() ||
+		ir.State == controller.IndexError.String()
+	for i, s := range progressSteps {
+		if s.String() == ir.State {
+			p.Step = i + 1
+			break
+		}
+	}
+	if p.Success {
+		p.Step = p.Steps
+	}
+	seen := make(map[string]struct{})
+	for _, envs := range ir.Environments {
+		for _, env := range envs {
+			seen[env.IntroducedIn.String()] = struct{}{}
+		}
+	}
+	p.Layers = len(seen)
+	return p
+}
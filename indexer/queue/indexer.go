@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+var (
+	waitCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_queue",
+			Name:      "claims_total",
+			Help:      "Total number of manifest claims, by whether the claim had to wait on another indexer",
+		},
+		[]string{"waited"},
+	)
+	waitDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_queue",
+			Name:      "wait_duration_seconds",
+			Help:      "Time spent waiting to claim a manifest",
+		},
+	)
+)
+
+// Indexer wraps an indexer.Service so that calls to Index are coordinated
+// through a Queue.
+type Indexer struct {
+	indexer.Service
+	queue *Queue
+	owner string
+	lease time.Duration
+	poll  time.Duration
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer claiming manifests as "owner".
+//
+// The owner name must be unique among the processes sharing the Queue.
+func NewIndexer(svc indexer.Service, q *Queue, owner string, lease, poll time.Duration) *Indexer {
+	return &Indexer{
+		Service: svc,
+		queue:   q,
+		owner:   owner,
+		lease:   lease,
+		poll:    poll,
+	}
+}
+
+// Index implements indexer.Indexer.
+//
+// If another process holds the lease on the manifest, Index waits for the
+// lease to be released or to expire. The wrapped Index method then either
+// returns the stored result or does the work.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/queue/Indexer.Index",
+		"manifest", m.Hash.String())
+	start := time.Now()
+	waited := false
+	for {
+		ok, err := i.queue.Claim(ctx, m.Hash, i.owner, i.lease)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if !waited {
+			zlog.Debug(ctx).Msg("manifest claimed elsewhere, waiting")
+			waited = true
+		}
+		t := time.NewTimer(i.poll)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+	if waited {
+		waitCounter.WithLabelValues("true").Inc()
+		waitDuration.Observe(time.Since(start).Seconds())
+	} else {
+		waitCounter.WithLabelValues("false").Inc()
+	}
+	if _, err := i.queue.Depth(ctx); err != nil {
+		zlog.Debug(ctx).Err(err).Msg("unable to update queue depth")
+	}
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		i.renew(ctx, m.Hash, done)
+	}()
+	defer func() {
+		close(done)
+		<-renewed
+		// Use a detached context so the lease is released even if the
+		// request was canceled.
+		rctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := i.queue.Release(rctx, m.Hash, i.owner); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to release lease, it will expire")
+		}
+	}()
+	return i.Service.Index(ctx, m)
+}
+
+// Renew extends the lease on "d" until "done" is closed.
+func (i *Indexer) renew(ctx context.Context, d claircore.Digest, done <-chan struct{}) {
+	t := time.NewTicker(i.lease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		switch err := i.queue.Renew(ctx, d, i.owner, i.lease); {
+		case errors.Is(err, nil):
+		case errors.Is(err, ErrLeaseLost):
+			// Another indexer decided this one was dead. The work will
+			// be duplicated, but the store handles that.
+			zlog.Warn(ctx).Msg("lease lost while indexing")
+			return
+		default:
+			zlog.Info(ctx).Err(err).Msg("unable to renew lease")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package queue implements coordination of indexing work between indexer
+// processes sharing a database.
+//
+// Manifests are claimed with a lease before being indexed. A lease is renewed
+// while the work continues and released when it's done; if the holder goes
+// away, the lease expires and the manifest may be claimed by another process.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var (
+	queryMetrics = dbutil.NewQueryMetrics("indexer_queue", "the indexer queue")
+	depthGauge   = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_queue",
+			Name:      "depth",
+			Help:      "Number of manifests currently leased by any indexer, as last observed",
+		},
+	)
+)
+
+// Queue is a Postgres-backed lease table.
+type Queue struct {
+	pool *pgxpool.Pool
+}
+
+// New returns a Queue using the passed-in Pool.
+//
+// The caller should close the Pool once the Queue is no longer needed.
+func New(pool *pgxpool.Pool) *Queue {
+	return &Queue{pool: pool}
+}
+
+// ErrLeaseLost is returned by Renew if the lease is no longer held by the
+// named owner.
+var ErrLeaseLost = errors.New("lease lost")
+
+// Claim attempts to take a lease on the manifest for "owner" for the duration
+// "lease". It reports false if another owner holds an unexpired lease.
+//
+// Claiming a manifest already leased by the same owner extends the lease.
+func (q *Queue) Claim(ctx context.Context, d claircore.Digest, owner string, lease time.Duration) (ok bool, err error) {
+	const query = `INSERT INTO indexer_queue (manifest, owner, expiry)
+VALUES ($1, $2, now() + $3::interval)
+ON CONFLICT (manifest) DO UPDATE
+SET owner = EXCLUDED.owner, claimed = now(), expiry = EXCLUDED.expiry
+WHERE indexer_queue.expiry < now() OR indexer_queue.owner = EXCLUDED.owner
+RETURNING owner;`
+	defer queryMetrics.Observe("claim", &err)()
+	var got string
+	err = q.pool.QueryRow(ctx, query, d.String(), owner, lease).Scan(&got)
+	switch {
+	case errors.Is(err, nil):
+		return true, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return false, nil
+	default:
+		return false, fmt.Errorf("queue: unable to claim %v: %w", d, err)
+	}
+}
+
+// Renew extends a lease held by "owner". ErrLeaseLost is returned if the
+// lease has been claimed by another owner.
+func (q *Queue) Renew(ctx context.Context, d claircore.Digest, owner string, lease time.Duration) (err error) {
+	const query = `UPDATE indexer_queue SET expiry = now() + $3::interval
+WHERE manifest = $1 AND owner = $2;`
+	defer queryMetrics.Observe("renew", &err)()
+	tag, err := q.pool.Exec(ctx, query, d.String(), owner, lease)
+	if err != nil {
+		return fmt.Errorf("queue: unable to renew %v: %w", d, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release gives up a lease held by "owner". It's not an error to release a
+// lease that's expired or held by someone else.
+func (q *Queue) Release(ctx context.Context, d claircore.Digest, owner string) (err error) {
+	const query = `DELETE FROM indexer_queue WHERE manifest = $1 AND owner = $2;`
+	defer queryMetrics.Observe("release", &err)()
+	if _, err = q.pool.Exec(ctx, query, d.String(), owner); err != nil {
+		return fmt.Errorf("queue: unable to release %v: %w", d, err)
+	}
+	return nil
+}
+
+// Depth reports the number of unexpired leases, and updates the exported
+// metric.
+func (q *Queue) Depth(ctx context.Context) (n int64, err error) {
+	const query = `SELECT count(*) FROM indexer_queue WHERE expiry > now();`
+	defer queryMetrics.Observe("depth", &err)()
+	if err = q.pool.QueryRow(ctx, query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("queue: unable to count leases: %w", err)
+	}
+	depthGauge.Set(float64(n))
+	return n, nil
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingQueue(ctx context.Context, t testing.TB) *Queue {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return New(pool)
+}
+
+func TestLease(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	q := TestingQueue(ctx, t)
+	d := claircore.MustParseDigest("sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	const lease = time.Minute
+
+	check := func(ok bool, err error, want bool) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Errorf("got: %v, want: %v", ok, want)
+		}
+	}
+
+	ok, err := q.Claim(ctx, d, "a", lease)
+	check(ok, err, true)
+	ok, err = q.Claim(ctx, d, "b", lease)
+	check(ok, err, false)
+	ok, err = q.Claim(ctx, d, "a", lease)
+	check(ok, err, true)
+	if err := q.Renew(ctx, d, "b", lease); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n, err := q.Depth(ctx); err != nil || n != 1 {
+		t.Errorf("got: %d, %v; want: 1", n, err)
+	}
+	if err := q.Release(ctx, d, "a"); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = q.Claim(ctx, d, "b", lease)
+	check(ok, err, true)
+
+	// An expired lease can be taken over.
+	if err := q.Renew(ctx, d, "b", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = q.Claim(ctx, d, "a", lease)
+	check(ok, err, true)
+	if err := q.Renew(ctx, d, "b", lease); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
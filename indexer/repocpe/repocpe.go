@@ -0,0 +1,243 @@
+// Package repocpe adds to and overrides the repository-to-CPE mapping used by
+// claircore's RHEL repository scanner.
+//
+// The scanner only knows how to fetch a single mapping file, so an Overlay
+// is installed in the indexer's HTTP client and the scanner is pointed at
+// URL. Requests for URL are answered with the configured upstream mapping,
+// with the additional mapping files merged in and the overrides applied.
+// The scanner refreshes its mapping periodically, so changes to the
+// additional mapping files are picked up without a restart.
+package repocpe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quay/claircore/pkg/cpe"
+	"github.com/quay/claircore/rhel"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+// URL is the mapping URL the scanner is pointed at. It's never requested
+// from the network.
+const URL = `http://repo2cpe.clair.invalid/repository-to-cpe.json`
+
+// ScannerName is the name of the scanner consuming the mapping, as used to
+// key its configuration.
+const ScannerName = `rhel-repository-scanner`
+
+// MappingFile is the format of the mapping file.
+type mappingFile struct {
+	Data map[string]mappingRepo `json:"data"`
+}
+
+type mappingRepo struct {
+	CPEs []string `json:"cpes"`
+}
+
+// Overlay is an http.RoundTripper serving the merged mapping at URL and
+// passing all other requests through.
+type Overlay struct {
+	next      http.RoundTripper
+	c         *http.Client
+	upstream  string
+	sources   []string
+	overrides map[string][]string
+
+	// Last is the most recently fetched upstream mapping, used if fetching
+	// it fails.
+	mu   sync.Mutex
+	last *mappingFile
+}
+
+var _ http.RoundTripper = (*Overlay)(nil)
+
+// NewOverlay returns an Overlay merging "sources" into the mapping at
+// "upstream" and then applying "overrides". The upstream and the sources are
+// URLs or file paths; URLs are fetched using "next".
+//
+// Every CPE in "overrides" must be valid.
+func NewOverlay(next http.RoundTripper, upstream string, sources []string, overrides map[string][]string) (*Overlay, error) {
+	for id, cs := range overrides {
+		for _, c := range cs {
+			if _, err := cpe.Unbind(c); err != nil {
+				return nil, fmt.Errorf("override %q: invalid CPE %q: %w", id, c, err)
+			}
+		}
+	}
+	return &Overlay{
+		next:      next,
+		c:         &http.Client{Transport: next},
+		upstream:  upstream,
+		sources:   sources,
+		overrides: overrides,
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (o *Overlay) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.String() != URL {
+		return o.next.RoundTrip(req)
+	}
+	b, err := o.merged(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":   {"application/json"},
+			"Content-Length": {strconv.Itoa(len(b))},
+			"Last-Modified":  {time.Now().UTC().Format(http.TimeFormat)},
+		},
+		ContentLength: int64(len(b)),
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		Request:       req,
+	}, nil
+}
+
+// Merged returns the encoded merged mapping.
+//
+// Failing to load the upstream mapping falls back to the last one loaded, and
+// failing to load a source skips it, so the overrides are always served.
+func (o *Overlay) merged(ctx context.Context) ([]byte, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "indexer/repocpe/Overlay.merged")
+	out := mappingFile{Data: make(map[string]mappingRepo)}
+
+	up, err := o.load(ctx, o.upstream)
+	o.mu.Lock()
+	switch {
+	case err == nil:
+		o.last = up
+	case o.last != nil:
+		zlog.Warn(ctx).Err(err).Str("upstream", o.upstream).Msg("unable to load mapping, using previous copy")
+		up = o.last
+	default:
+		zlog.Warn(ctx).Err(err).Str("upstream", o.upstream).Msg("unable to load mapping")
+	}
+	o.mu.Unlock()
+	if up != nil {
+		for id, r := range up.Data {
+			out.Data[id] = r
+		}
+	}
+
+	for _, src := range o.sources {
+		m, err := o.load(ctx, src)
+		if err != nil {
+			zlog.Warn(ctx).Err(err).Str("source", src).Msg("unable to load mapping source, skipping")
+			continue
+		}
+		for id, r := range m.Data {
+			out.Data[id] = mappingRepo{CPEs: union(out.Data[id].CPEs, r.CPEs)}
+		}
+	}
+	for id, cs := range o.overrides {
+		if len(cs) == 0 {
+			delete(out.Data, id)
+			continue
+		}
+		out.Data[id] = mappingRepo{CPEs: cs}
+	}
+	zlog.Debug(ctx).
+		Int("repositories", len(out.Data)).
+		Int("sources", len(o.sources)).
+		Int("overrides", len(o.overrides)).
+		Msg("serving merged mapping")
+	return json.Marshal(&out)
+}
+
+// Load reads the mapping file at "src", which is either a URL or a file path.
+func (o *Overlay) load(ctx context.Context, src string) (*mappingFile, error) {
+	var rd io.Reader
+	if u, err := url.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		res, err := o.c.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, &clairerror.ErrRequestFail{Code: res.StatusCode, Status: res.Status}
+		}
+		rd = res.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		rd = f
+	}
+	var m mappingFile
+	if err := json.NewDecoder(rd).Decode(&m); err != nil {
+		return nil, fmt.Errorf("unable to decode mapping: %w", err)
+	}
+	return &m, nil
+}
+
+// Union returns the sorted union of "a" and "b".
+func union(a, b []string) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	out := make([]string, 0, len(a)+len(b))
+	for _, s := range append(append([]string(nil), a...), b...) {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Upstream returns where the scanner configuration "conf" says to load the
+// mapping from: the mapping file if provided, otherwise the mapping URL or
+// claircore's default.
+func Upstream(conf *rhel.RepositoryScannerConfig) string {
+	switch {
+	case conf.Repo2CPEMappingFile != "":
+		return conf.Repo2CPEMappingFile
+	case conf.Repo2CPEMappingURL != "":
+		return conf.Repo2CPEMappingURL
+	}
+	return rhel.DefaultRepo2CPEMappingURL
+}
+
+// Configure wraps the RHEL repository scanner's configuration function
+// "prev", which may be nil, to point the scanner at URL.
+func Configure(prev func(interface{}) error) func(interface{}) error {
+	return func(v interface{}) error {
+		if prev != nil {
+			if err := prev(v); err != nil {
+				return err
+			}
+		}
+		if c, ok := v.(*rhel.RepositoryScannerConfig); ok {
+			c.Repo2CPEMappingURL = URL
+			c.Repo2CPEMappingFile = ""
+		}
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package repocpe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore/rhel"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+func TestOverlay(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		baseos = "cpe:/o:redhat:enterprise_linux:8::baseos"
+		appstr = "cpe:/a:redhat:enterprise_linux:8::appstream"
+		local  = "cpe:/o:example:internal_linux:8"
+	)
+	upstreamOK := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !upstreamOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":{` +
+			`"rhel-8-for-x86_64-baseos-rpms":{"cpes":["` + baseos + `"]},` +
+			`"rhel-8-for-x86_64-appstream-rpms":{"cpes":["` + appstr + `"]},` +
+			`"removed-rpms":{"cpes":["` + baseos + `"]}` +
+			`}}`))
+	}))
+	defer srv.Close()
+	src := filepath.Join(t.TempDir(), "internal.json")
+	if err := os.WriteFile(src, []byte(`{"data":{`+
+		`"internal-baseos-rpms":{"cpes":["`+local+`"]},`+
+		`"rhel-8-for-x86_64-appstream-rpms":{"cpes":["`+local+`"]}`+
+		`}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ov, err := NewOverlay(srv.Client().Transport, srv.URL, []string{src, filepath.Join(t.TempDir(), "missing.json")},
+		map[string][]string{
+			"rhel-8-for-x86_64-baseos-rpms": {local},
+			"removed-rpms":                  {},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: ov}
+	get := func(t *testing.T) map[string][]string {
+		req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status: %s", res.Status)
+		}
+		var m mappingFile
+		if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[string][]string, len(m.Data))
+		for id, r := range m.Data {
+			out[id] = r.CPEs
+		}
+		return out
+	}
+	want := map[string][]string{
+		"rhel-8-for-x86_64-baseos-rpms":    {local},
+		"rhel-8-for-x86_64-appstream-rpms": {appstr, local},
+		"internal-baseos-rpms":             {local},
+	}
+
+	t.Run("Merge", func(t *testing.T) {
+		if got := get(t); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("UpstreamDown", func(t *testing.T) {
+		upstreamOK = false
+		defer func() { upstreamOK = true }()
+		if got := get(t); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Passthrough", func(t *testing.T) {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status: %s", res.Status)
+		}
+	})
+	t.Run("BadOverride", func(t *testing.T) {
+		_, err := NewOverlay(http.DefaultTransport, srv.URL, nil, map[string][]string{"x": {"not a cpe"}})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	prev := func(v interface{}) error {
+		return json.Unmarshal([]byte(`{"repo2cpe_mapping_file":"/etc/clair/repo2cpe.json","timeout":1000}`), v)
+	}
+	var got rhel.RepositoryScannerConfig
+	if err := prev(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Upstream(&got), "/etc/clair/repo2cpe.json"; got != want {
+		t.Errorf("upstream: got: %q, want: %q", got, want)
+	}
+	got = rhel.RepositoryScannerConfig{}
+	if err := Configure(prev)(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := rhel.RepositoryScannerConfig{Repo2CPEMappingURL: URL, Timeout: 1000}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := Upstream(&rhel.RepositoryScannerConfig{}), rhel.DefaultRepo2CPEMappingURL; got != want {
+		t.Errorf("upstream: got: %q, want: %q", got, want)
+	}
+}
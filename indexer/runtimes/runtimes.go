@@ -0,0 +1,360 @@
+// Package runtimes detects language runtimes installed in container layers,
+// such as the Python interpreter or a JDK, and reports them as packages.
+//
+// Only runtimes installed outside of the distribution's package manager are
+// detected, by looking under "/usr/local" and "/opt": a runtime installed
+// from a distribution package is already reported, and matched, as that
+// package.
+//
+// The packages are named "python", "node", "java", and "ruby", and are in
+// the "runtime" repository, so advisory feeds and matchers can select them.
+package runtimes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"runtime/trace"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer"
+	"github.com/quay/claircore/pkg/tarfs"
+	"github.com/quay/zlog"
+)
+
+// These are the names of the runtime packages.
+const (
+	Python = `python`
+	Node   = `node`
+	Java   = `java`
+	Ruby   = `ruby`
+)
+
+// RepositoryName is the name of the repository runtime packages are in.
+const RepositoryName = `runtime`
+
+// Repository is the repository runtime packages are in.
+var Repository = claircore.Repository{
+	Name: RepositoryName,
+	URI:  "https://endoflife.date/",
+}
+
+// Roots are the directories runtimes are looked for in.
+var roots = []string{"usr/local/", "opt/"}
+
+// A detector recognizes the file recording a runtime's version and reads the
+// version from it.
+type detector struct {
+	name string
+	// Match reports whether the path is the runtime's version file.
+	match func(p string) bool
+	// Read returns the version recorded in the file, or "" if there isn't
+	// one.
+	read func(s *bufio.Scanner) string
+}
+
+var (
+	pythonHeader = regexp.MustCompile(`(^|/)include/python[23]\.[0-9]+m?/patchlevel\.h$`)
+	pythonLine   = regexp.MustCompile(`^#define\s+PY_VERSION\s+"([^"]+)"`)
+	nodeHeader   = regexp.MustCompile(`(^|/)include/node/node_version\.h$`)
+	nodeLine     = regexp.MustCompile(`^#define\s+NODE_(MAJOR|MINOR|PATCH)_VERSION\s+([0-9]+)`)
+	javaLine     = regexp.MustCompile(`^JAVA_VERSION="([^"]+)"`)
+	rubyConfig   = regexp.MustCompile(`(^|/)lib/ruby/[0-9.]+/[^/]+/rbconfig\.rb$`)
+	rubyLine     = regexp.MustCompile(`CONFIG\["RUBY_PROGRAM_VERSION"\]\s*=\s*"([^"]+)"`)
+)
+
+var detectors = []detector{
+	{
+		name:  Python,
+		match: pythonHeader.MatchString,
+		read:  firstMatch(pythonLine),
+	},
+	{
+		name:  Node,
+		match: nodeHeader.MatchString,
+		read: func(s *bufio.Scanner) string {
+			v := make(map[string]string, 3)
+			for s.Scan() {
+				if m := nodeLine.FindStringSubmatch(s.Text()); m != nil {
+					v[m[1]] = m[2]
+				}
+			}
+			if len(v) != 3 {
+				return ""
+			}
+			return v["MAJOR"] + "." + v["MINOR"] + "." + v["PATCH"]
+		},
+	},
+	{
+		// Every JDK and JRE build has a "release" file at its root.
+		name:  Java,
+		match: func(p string) bool { return path.Base(p) == "release" },
+		read:  firstMatch(javaLine),
+	},
+	{
+		name:  Ruby,
+		match: rubyConfig.MatchString,
+		read:  firstMatch(rubyLine),
+	},
+}
+
+// FirstMatch returns a read function reporting the first submatch of "re"
+// in the file.
+func firstMatch(re *regexp.Regexp) func(*bufio.Scanner) string {
+	return func(s *bufio.Scanner) string {
+		for s.Scan() {
+			if m := re.FindStringSubmatch(s.Text()); m != nil {
+				return m[1]
+			}
+		}
+		return ""
+	}
+}
+
+// Find reports the runtimes installed in "sys".
+func find(ctx context.Context, sys fs.FS) ([]*claircore.Package, error) {
+	var out []*claircore.Package
+	for _, root := range roots {
+		dir := strings.TrimSuffix(root, "/")
+		if _, err := fs.Stat(sys, dir); err != nil {
+			continue
+		}
+		err := fs.WalkDir(sys, dir, func(p string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case !d.Type().IsRegular():
+				return nil
+			case strings.HasPrefix(path.Base(p), ".wh."):
+				return nil
+			}
+			for i := range detectors {
+				det := &detectors[i]
+				if !det.match(p) {
+					continue
+				}
+				v, err := readVersion(sys, p, det)
+				if err != nil {
+					return err
+				}
+				if v == "" {
+					zlog.Debug(ctx).
+						Str("path", p).
+						Str("runtime", det.name).
+						Msg("no version found, skipping")
+					continue
+				}
+				zlog.Debug(ctx).
+					Str("path", p).
+					Str("runtime", det.name).
+					Str("version", v).
+					Msg("found runtime")
+				out = append(out, &claircore.Package{
+					Name:           det.name,
+					Version:        v,
+					Kind:           claircore.BINARY,
+					PackageDB:      det.name + ":" + p,
+					RepositoryHint: RepositoryName,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func readVersion(sys fs.FS, p string, det *detector) (string, error) {
+	f, err := sys.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	v := det.read(s)
+	if err := s.Err(); err != nil {
+		return "", fmt.Errorf("runtimes: unable to read %q: %w", p, err)
+	}
+	return v, nil
+}
+
+// Open returns the filesystem of the layer.
+func open(layer *claircore.Layer) (fs.FS, func() error, error) {
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, nil, err
+	}
+	sys, err := tarfs.New(r)
+	if err != nil {
+		r.Close()
+		return nil, nil, fmt.Errorf("runtimes: unable to open tar: %w", err)
+	}
+	return sys, r.Close, nil
+}
+
+var (
+	_ indexer.PackageScanner    = (*Scanner)(nil)
+	_ indexer.RepositoryScanner = (*RepoScanner)(nil)
+)
+
+// Scanner reports the runtimes installed in a layer.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "runtime" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Scan implements indexer.PackageScanner.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/runtimes/Scanner.Scan",
+		"version", s.Version(),
+		"layer", layer.Hash.String())
+	sys, done, err := open(layer)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ps, err := find(ctx, sys)
+	if err != nil {
+		return nil, fmt.Errorf("runtimes: unable to find runtimes: %w", err)
+	}
+	return ps, nil
+}
+
+// RepoScanner reports the runtime repository for layers with runtimes
+// installed.
+//
+// The zero value is ready to use.
+type RepoScanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*RepoScanner) Name() string { return "runtime" }
+
+// Version implements indexer.VersionedScanner.
+func (*RepoScanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*RepoScanner) Kind() string { return "repository" }
+
+// Scan implements indexer.RepositoryScanner.
+func (s *RepoScanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Repository, error) {
+	defer trace.StartRegion(ctx, "RepoScanner.Scan").End()
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/runtimes/RepoScanner.Scan",
+		"version", s.Version(),
+		"layer", layer.Hash.String())
+	sys, done, err := open(layer)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ps, err := find(ctx, sys)
+	if err != nil {
+		return nil, fmt.Errorf("runtimes: unable to find runtimes: %w", err)
+	}
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	r := Repository
+	return []*claircore.Repository{&r}, nil
+}
+
+// Coalescer puts the runtimes found in each layer into an IndexReport.
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+func (*coalescer) Coalesce(_ context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+		Repositories: map[string]*claircore.Repository{},
+	}
+	for _, l := range ls {
+		if len(l.Repos) == 0 {
+			continue
+		}
+		rs := make([]string, len(l.Repos))
+		for i, r := range l.Repos {
+			rs[i] = r.ID
+			ir.Repositories[r.ID] = r
+		}
+		for _, pkg := range l.Pkgs {
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = []*claircore.Environment{
+				{
+					PackageDB:     pkg.PackageDB,
+					IntroducedIn:  l.Hash,
+					RepositoryIDs: rs,
+				},
+			}
+		}
+	}
+	return ir, nil
+}
+
+// NewEcosystem returns the ecosystem of runtime scanners.
+func NewEcosystem(_ context.Context) *indexer.Ecosystem {
+	return &indexer.Ecosystem{
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{&Scanner{}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) {
+			return nil, nil
+		},
+		RepositoryScanners: func(context.Context) ([]indexer.RepositoryScanner, error) {
+			return []indexer.RepositoryScanner{&RepoScanner{}}, nil
+		},
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return &coalescer{}, nil
+		},
+	}
+}
+
+// Cycle returns the release cycle the version "v" of the runtime "name"
+// belongs to, which is what end-of-life schedules are published for: the
+// major version of Node.js and Java, and the minor version of Python and
+// Ruby. It returns "" if the version can't be understood.
+func Cycle(name, v string) string {
+	// Strip build metadata and Java's update number, as in "1.8.0_392" or
+	// "17.0.9+9".
+	if i := strings.IndexAny(v, "+_-"); i != -1 {
+		v = v[:i]
+	}
+	f := strings.Split(v, ".")
+	for _, p := range f {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return ""
+		}
+	}
+	switch name {
+	case Node:
+		return f[0]
+	case Java:
+		// Versions before 9 are "1.major".
+		if f[0] == "1" && len(f) > 1 {
+			return f[1]
+		}
+		return f[0]
+	case Python, Ruby:
+		if len(f) < 2 {
+			return ""
+		}
+		return f[0] + "." + f[1]
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package runtimes
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+func TestFind(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	sys := fstest.MapFS{
+		"usr/local/include/python3.12/patchlevel.h": file("#define PY_MAJOR_VERSION 3\n#define PY_VERSION \"3.12.1\"\n"),
+		"usr/local/include/node/node_version.h": file(
+			"#define NODE_MAJOR_VERSION 20\n#define NODE_MINOR_VERSION 10\n#define NODE_PATCH_VERSION 0\n"),
+		"opt/java/openjdk/release":                          file("IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\"17.0.9\"\n"),
+		"usr/local/lib/ruby/3.2.0/x86_64-linux/rbconfig.rb": file("  CONFIG[\"RUBY_PROGRAM_VERSION\"] = \"3.2.2\"\n"),
+		// Not a JDK.
+		"opt/app/release": file("v1\n"),
+		// Installed from a distribution package.
+		"usr/include/python3.11/patchlevel.h": file("#define PY_VERSION \"3.11.2\"\n"),
+		// Removed in this layer.
+		"usr/local/include/node/.wh.node_version.h": file(""),
+	}
+	got, err := find(ctx, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := func(name, v, p string) *claircore.Package {
+		return &claircore.Package{
+			Name:           name,
+			Version:        v,
+			Kind:           claircore.BINARY,
+			PackageDB:      name + ":" + p,
+			RepositoryHint: RepositoryName,
+		}
+	}
+	want := []*claircore.Package{
+		pkg(Node, "20.10.0", "usr/local/include/node/node_version.h"),
+		pkg(Python, "3.12.1", "usr/local/include/python3.12/patchlevel.h"),
+		pkg(Ruby, "3.2.2", "usr/local/lib/ruby/3.2.0/x86_64-linux/rbconfig.rb"),
+		pkg(Java, "17.0.9", "opt/java/openjdk/release"),
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestCycle(t *testing.T) {
+	tt := []struct {
+		Name, Version, Want string
+	}{
+		{Python, "3.12.1", "3.12"},
+		{Python, "2.7.18", "2.7"},
+		{Python, "3", ""},
+		{Ruby, "3.2.2", "3.2"},
+		{Node, "20.10.0", "20"},
+		{Java, "17.0.9", "17"},
+		{Java, "17.0.9+9", "17"},
+		{Java, "1.8.0_392", "8"},
+		{Java, "21", "21"},
+		{Java, "jdk-21", ""},
+		{"perl", "5.36.0", ""},
+	}
+	for _, tc := range tt {
+		if got := Cycle(tc.Name, tc.Version); got != tc.Want {
+			t.Errorf("%s %q: got: %q, want: %q", tc.Name, tc.Version, got, tc.Want)
+		}
+	}
+}
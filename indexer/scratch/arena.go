@@ -0,0 +1,308 @@
+package scratch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer"
+	"github.com/quay/claircore/pkg/tarfs"
+	"github.com/quay/zlog"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	_ indexer.FetchArena = (*Arena)(nil)
+	_ indexer.Realizer   = (*realizer)(nil)
+)
+
+// Arena is an indexer.FetchArena that fetches layers into a scratch
+// directory, holding each manifest to a quota.
+//
+// It works like libindex's RemoteFetchArena: layers are fetched once no
+// matter how many manifests are waiting on them, and removed once none are.
+// A layer's decompressed size is charged to the manifest that fetched it as
+// it's written, so a manifest over the quota fails as soon as it's over
+// rather than once the disk is full.
+type Arena struct {
+	wc    *http.Client
+	dir   *Dir
+	quota int64
+	sf    singleflight.Group
+
+	mu sync.Mutex
+	rc map[string]int // reference counts, keyed by layer digest
+}
+
+// NewArena returns an Arena fetching layers with "wc" into "dir". A "quota"
+// of 0 or less means "unlimited."
+//
+// Closing the Arena closes "dir".
+func NewArena(wc *http.Client, dir *Dir, quota int64) *Arena {
+	return &Arena{
+		wc:    wc,
+		dir:   dir,
+		quota: quota,
+		rc:    make(map[string]int),
+	}
+}
+
+// Realizer implements indexer.FetchArena.
+//
+// Libindex asks for a Realizer for each manifest it indexes, so the quota is
+// tracked per Realizer.
+func (a *Arena) Realizer(_ context.Context) indexer.Realizer {
+	return &realizer{a: a}
+}
+
+// Close implements indexer.FetchArena.
+//
+// Any layers still in use are removed out from under their users.
+func (a *Arena) Close(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.rc) != 0 {
+		zlog.Warn(ctx).
+			Str("component", "indexer/scratch/Arena.Close").
+			Int("count", len(a.rc)).
+			Msg("seem to have active fetchers")
+	}
+	for d := range a.rc {
+		delete(a.rc, d)
+		a.sf.Forget(d)
+	}
+	return a.dir.Close()
+}
+
+// Forget drops a reference to the layer "digest", removing it if it was the
+// last.
+func (a *Arena) forget(digest string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ct, ok := a.rc[digest]
+	if !ok {
+		return nil
+	}
+	ct--
+	if ct == 0 {
+		delete(a.rc, digest)
+		a.sf.Forget(digest)
+		return os.Remove(filepath.Join(a.dir.Path(), digest))
+	}
+	a.rc[digest] = ct
+	return nil
+}
+
+// FetchOne fetches "l" if it isn't already, charging "r" if it's fetched on
+// its behalf, and takes a reference to it.
+func (a *Arena) fetchOne(ctx context.Context, r *realizer, l *claircore.Layer) func() error {
+	var do func() error
+	do = func() error {
+		h := l.Hash.String()
+		tgt := filepath.Join(a.dir.Path(), h)
+		var tmp string
+		select {
+		case res := <-a.sf.DoChan(h, func() (interface{}, error) {
+			return a.fetch(ctx, r, l)
+		}):
+			if err := res.Err; err != nil {
+				return fmt.Errorf("error realizing layer %s: %w", h, err)
+			}
+			tmp = res.Val.(string)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		a.mu.Lock()
+		ct, ok := a.rc[h]
+		if !ok {
+			// The file may have been removed while waiting on the lock.
+			if _, err := os.Stat(tmp); errors.Is(err, os.ErrNotExist) {
+				a.mu.Unlock()
+				return do()
+			}
+			if err := os.Rename(tmp, tgt); err != nil {
+				a.mu.Unlock()
+				return err
+			}
+		}
+		a.rc[h] = ct + 1
+		a.mu.Unlock()
+		l.SetLocal(tgt)
+		return nil
+	}
+	return do
+}
+
+// Fetch downloads and decompresses "l" into a temporary file in the scratch
+// directory, returning the file's name.
+func (a *Arena) fetch(ctx context.Context, r *realizer, l *claircore.Layer) (string, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/scratch/Arena.fetch",
+		"layer", l.Hash.String())
+	if l.URI == "" {
+		return "", fmt.Errorf("empty uri for layer %v", l.Hash)
+	}
+	u, err := url.ParseRequestURI(l.URI)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse remote path uri: %w", err)
+	}
+	if l.Hash.Checksum() == nil {
+		return "", errors.New("digest is empty")
+	}
+	vh := l.Hash.Hash()
+	want := l.Hash.Checksum()
+
+	rm := true
+	fd, err := os.CreateTemp(a.dir.Path(), "fetch.*")
+	if err != nil {
+		return "", fmt.Errorf("unable to create file: %w", err)
+	}
+	name := fd.Name()
+	defer func() {
+		if err := fd.Close(); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to close layer file")
+		}
+		if rm {
+			if err := os.Remove(name); err != nil {
+				zlog.Warn(ctx).Err(err).Msg("unable to remove unsuccessful layer fetch")
+			}
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if l.Headers != nil {
+		req.Header = http.Header(l.Headers).Clone()
+	}
+	res, err := a.wc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		// The start of the body often says what went wrong.
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+		return "", fmt.Errorf("unexpected status code: %s (body starts: %q)", res.Status, b)
+	}
+
+	br := bufio.NewReader(io.TeeReader(res.Body, vh))
+	var rd io.Reader
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1F, 0x8B, 0x08}):
+		g, err := gzip.NewReader(br)
+		if err != nil {
+			return "", err
+		}
+		defer g.Close()
+		rd = g
+	case bytes.HasPrefix(magic, []byte{0x28, 0xB5, 0x2F, 0xFD}):
+		z, err := zstd.NewReader(br)
+		if err != nil {
+			return "", err
+		}
+		defer z.Close()
+		rd = z
+	default:
+		rd = br
+	}
+
+	w := bufio.NewWriter(&quotaWriter{w: fd, r: r})
+	if _, err := io.Copy(w, rd); err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	// Drain anything after the end of the archive, so it's hashed.
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return "", err
+	}
+	if got := vh.Sum(nil); !bytes.Equal(got, want) {
+		return "", fmt.Errorf("validation failed: got %q, expected %q",
+			hex.EncodeToString(got), hex.EncodeToString(want))
+	}
+	if _, err := tarfs.New(fd); err != nil {
+		return "", err
+	}
+	rm = false
+	return name, nil
+}
+
+// Realizer tracks the layers fetched for one manifest.
+type realizer struct {
+	a     *Arena
+	used  int64 // bytes written on behalf of this manifest
+	over  int32 // set once the quota's exceeded
+	clean []string
+}
+
+// Realize implements indexer.Realizer.
+func (r *realizer) Realize(ctx context.Context, ls []*claircore.Layer) error {
+	g, ctx := errgroup.WithContext(ctx)
+	r.clean = make([]string, len(ls))
+	for i, l := range ls {
+		r.clean[i] = l.Hash.String()
+		g.Go(r.a.fetchOne(ctx, r, l))
+	}
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("encountered error while fetching a layer: %w", err)
+	}
+	return nil
+}
+
+// Close implements indexer.Realizer.
+//
+// Layers no other manifest is using are removed.
+func (r *realizer) Close() error {
+	var errs []error
+	for _, d := range r.clean {
+		if err := r.a.forget(d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Charge records "n" more bytes written, reporting ErrQuota if that's over
+// the quota.
+func (r *realizer) charge(n int) error {
+	used := atomic.AddInt64(&r.used, int64(n))
+	if r.a.quota <= 0 || used <= r.a.quota {
+		return nil
+	}
+	if atomic.CompareAndSwapInt32(&r.over, 0, 1) {
+		quotaCounter.Inc()
+	}
+	return fmt.Errorf("%w: %d bytes", ErrQuota, r.a.quota)
+}
+
+// QuotaWriter charges everything written through it to a Realizer.
+type quotaWriter struct {
+	w io.Writer
+	r *realizer
+}
+
+// Write implements io.Writer.
+func (q *quotaWriter) Write(b []byte) (int, error) {
+	if err := q.r.charge(len(b)); err != nil {
+		return 0, err
+	}
+	return q.w.Write(b)
+}
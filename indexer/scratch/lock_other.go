@@ -0,0 +1,12 @@
+//go:build !unix
+
+package scratch
+
+import "os"
+
+// Lock always fails on systems without flock(2), so the janitor never removes
+// another process's directory. A process's own directory is still removed
+// when it exits cleanly.
+func lock(_ *os.File) error {
+	return errNoLock
+}
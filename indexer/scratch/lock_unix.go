@@ -0,0 +1,14 @@
+//go:build unix
+
+package scratch
+
+import (
+	"os"
+	"syscall"
+)
+
+// Lock takes an exclusive lock on "f" without blocking. The lock is released
+// when "f" is closed or the process exits.
+func lock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
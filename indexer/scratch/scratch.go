@@ -0,0 +1,179 @@
+// Package scratch manages the indexer's scratch space: the directory layers
+// are fetched into while they're scanned.
+//
+// Each process keeps its files in a directory of its own, which it holds a
+// lock on for as long as it runs. When a process starts, it removes any
+// scratch directories whose owners have exited without cleaning up, so a
+// crash or a killed pod doesn't leak layers onto the node.
+package scratch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+var (
+	reclaimedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_scratch",
+			Name:      "reclaimed_bytes_total",
+			Help:      "Total number of bytes removed from scratch directories left by exited processes.",
+		},
+	)
+	quotaCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_scratch",
+			Name:      "quota_exceeded_total",
+			Help:      "Total number of manifests whose layers exceeded the scratch quota.",
+		},
+	)
+)
+
+// ErrQuota is reported when a manifest's layers use more scratch space than
+// allowed. It wraps httputil.ErrTooLarge, so it's reported the same way as
+// the other size limits.
+var ErrQuota = fmt.Errorf("scratch: manifest over quota: %w", httputil.ErrTooLarge)
+
+// Prefix is the name prefix of scratch directories. Only directories with
+// this prefix are ever removed by the janitor.
+const prefix = `clair-scratch-`
+
+// ErrNoLock is reported by lock on systems where it's unsupported.
+var errNoLock = errors.New("scratch: locking unsupported")
+
+// LockFile is the file in a scratch directory its owner holds a lock on.
+const lockFile = `.lock`
+
+// Dir is a process's scratch directory.
+type Dir struct {
+	path string
+	lock *os.File
+}
+
+// Open removes the scratch directories in "root" left by processes that have
+// exited, then creates and locks one for this process.
+//
+// The sweep removes anything named like a scratch directory, so "root"
+// should be a directory set aside for them rather than a shared one like the
+// system's temporary directory.
+func Open(ctx context.Context, root string) (*Dir, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "indexer/scratch/Open")
+	if root == "" {
+		return nil, errors.New("scratch: no directory provided")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("scratch: %w", err)
+	}
+	if err := sweep(ctx, root); err != nil {
+		// Leftovers are a nuisance, not a reason not to start.
+		zlog.Warn(ctx).Err(err).Str("root", root).Msg("unable to remove old scratch directories")
+	}
+	p, err := os.MkdirTemp(root, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("scratch: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(p, lockFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err == nil {
+		if err = lock(f); errors.Is(err, errNoLock) {
+			err = nil
+		}
+		if err != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		os.RemoveAll(p)
+		return nil, fmt.Errorf("scratch: unable to lock %q: %w", p, err)
+	}
+	fmt.Fprintln(f, strconv.Itoa(os.Getpid()))
+	zlog.Debug(ctx).Str("dir", p).Msg("created scratch directory")
+	return &Dir{path: p, lock: f}, nil
+}
+
+// Path reports the directory's path.
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// Close removes the directory and everything in it.
+func (d *Dir) Close() error {
+	err := os.RemoveAll(d.path)
+	d.lock.Close()
+	return err
+}
+
+// Sweep removes the unlocked scratch directories in "root".
+func sweep(ctx context.Context, root string) error {
+	ms, err := filepath.Glob(filepath.Join(root, prefix+"*"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, p := range ms {
+		fi, err := os.Lstat(p)
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+		f, err := os.OpenFile(filepath.Join(p, lockFile), os.O_RDWR, 0)
+		switch {
+		case errors.Is(err, nil):
+			err = lock(f)
+			if err != nil {
+				// Still in use.
+				f.Close()
+				continue
+			}
+		case errors.Is(err, os.ErrNotExist):
+			// The owner may not have taken the lock yet, or exited before
+			// it did.
+			if time.Since(fi.ModTime()) < time.Minute {
+				continue
+			}
+		default:
+			errs = append(errs, err)
+			continue
+		}
+		n := usage(p)
+		err = os.RemoveAll(p)
+		if f != nil {
+			f.Close()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reclaimedCounter.Add(float64(n))
+		zlog.Info(ctx).
+			Str("dir", p).
+			Int64("bytes", n).
+			Msg("removed scratch directory left by an exited process")
+	}
+	return errors.Join(errs...)
+}
+
+// Usage reports the bytes used by the regular files under "p".
+func usage(p string) (n int64) {
+	filepath.WalkDir(p, func(_ string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			n += fi.Size()
+		}
+		return nil
+	})
+	return n
+}
@@ -0,0 +1,174 @@
+package scratch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+func TestSweep(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	root := t.TempDir()
+
+	live, err := Open(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+	// A directory whose owner exited: the lock file is there, but unlocked.
+	dead := filepath.Join(root, prefix+"dead")
+	if err := os.Mkdir(dead, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dead, lockFile), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dead, "fetch.1"), make([]byte, 1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A directory whose owner hasn't locked it yet.
+	fresh := filepath.Join(root, prefix+"fresh")
+	if err := os.Mkdir(fresh, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A directory whose owner never locked it.
+	stale := filepath.Join(root, prefix+"stale")
+	if err := os.Mkdir(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+	// Something that isn't a scratch directory at all.
+	other := filepath.Join(root, "other")
+	if err := os.Mkdir(other, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for p, want := range map[string]bool{
+		live.Path(): true,
+		d.Path():    true,
+		dead:        false,
+		fresh:       true,
+		stale:       false,
+		other:       true,
+	} {
+		_, err := os.Stat(p)
+		if got := err == nil; got != want {
+			t.Errorf("%s: exists: got: %v, want: %v", filepath.Base(p), got, want)
+		}
+	}
+
+	if err := d.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(d.Path()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("directory not removed on close: %v", err)
+	}
+}
+
+func TestArena(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// A layer of one 64 KiB file.
+	var raw bytes.Buffer
+	gz := gzip.NewWriter(&raw)
+	tw := tar.NewWriter(gz)
+	content := make([]byte, 64*1024)
+	if err := tw.WriteHeader(&tar.Header{Name: "file", Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(raw.Bytes())
+	digest, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(raw.Bytes())
+	}))
+	defer srv.Close()
+
+	tt := []struct {
+		Name  string
+		Quota int64
+		Err   error
+	}{
+		{Name: "Unlimited"},
+		{Name: "UnderQuota", Quota: 1024 * 1024},
+		{Name: "OverQuota", Quota: 16 * 1024, Err: ErrQuota},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			d, err := Open(ctx, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewArena(srv.Client(), d, tc.Quota)
+			defer a.Close(ctx)
+
+			l := &claircore.Layer{Hash: digest, URI: srv.URL}
+			r := a.Realizer(ctx)
+			err = r.Realize(ctx, []*claircore.Layer{l})
+			if tc.Err != nil {
+				if !errors.Is(err, tc.Err) {
+					t.Fatalf("got: %v, want: %v", err, tc.Err)
+				}
+				if !errors.Is(err, httputil.ErrTooLarge) {
+					t.Errorf("%v isn't a size limit error", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				fi, err := os.Stat(filepath.Join(d.Path(), digest.String()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Size() < int64(len(content)) {
+					t.Errorf("layer file too small: %d bytes", fi.Size())
+				}
+			}
+			r.Close()
+			// Nothing should be left once the manifest's done, successful
+			// or not.
+			es, err := os.ReadDir(d.Path())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range es {
+				if e.Name() != lockFile {
+					t.Errorf("left behind: %s", e.Name())
+				}
+			}
+		})
+	}
+}
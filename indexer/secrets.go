@@ -0,0 +1,39 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// SecretReporter is an optional interface for Services that look for
+// credentials embedded in images.
+type SecretReporter interface {
+	// Secrets returns the findings for the manifest, and whether the
+	// manifest has been examined.
+	Secrets(ctx context.Context, manifest claircore.Digest) ([]Secret, bool, error)
+}
+
+// These are the kinds of Secret.
+const (
+	SecretPrivateKey     = "private_key"
+	SecretDockerConfig   = "docker_config"
+	SecretNetrc          = "netrc"
+	SecretGitCredentials = "git_credentials"
+	SecretEnvironment    = "environment"
+)
+
+// Secret is a credential found embedded in an image.
+//
+// The credential itself is never included; a Secret only says where to look.
+type Secret struct {
+	Kind string `json:"kind"`
+	// Path is the file the credential was found in, for findings in layers.
+	Path string `json:"path,omitempty"`
+	// Variable is the name of the environment variable the credential was
+	// found in, for findings in the image's environment.
+	Variable string `json:"variable,omitempty"`
+	// Layer is the layer that added the file.
+	Layer       *claircore.Digest `json:"layer,omitempty"`
+	Description string            `json:"description"`
+}
@@ -0,0 +1,211 @@
+package secrets
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var (
+	examineCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_secrets",
+			Name:      "examine_total",
+			Help:      "Total number of manifests examined for embedded credentials, by whether examining failed",
+		},
+		[]string{"error"},
+	)
+	findingCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "indexer_secrets",
+			Name:      "findings_total",
+			Help:      "Total number of embedded credentials found, by kind",
+		},
+		[]string{"kind"},
+	)
+)
+
+// Examiner looks for credentials embedded in manifests.
+//
+// Examining a manifest never fails an index request: errors are logged, and
+// the manifest is examined again the next time it's submitted.
+type Examiner struct {
+	store *Store
+	opts  Options
+	state string
+}
+
+var (
+	_ layerwalk.Examiner     = (*Examiner)(nil)
+	_ indexer.SecretReporter = (*Examiner)(nil)
+)
+
+// NewExaminer returns an Examiner storing findings in "s".
+func NewExaminer(s *Store, opts *Options) *Examiner {
+	return &Examiner{
+		store: s,
+		opts:  *opts,
+		state: opts.state(),
+	}
+}
+
+// Name implements layerwalk.Examiner.
+func (x *Examiner) Name() string { return "secrets" }
+
+// Examine implements layerwalk.Examiner.
+//
+// Layers are only walked if the manifest hasn't been examined with the
+// current rules and options. The environment is taken from the Context; see
+// WithEnv. If present, it replaces the findings for the manifest's previous
+// environment; manifests submitted without one keep any stored findings.
+func (x *Examiner) Examine(ctx context.Context, m *claircore.Manifest) (layerwalk.Examination, error) {
+	prev, err := x.store.Get(ctx, m.Hash)
+	if err != nil {
+		examineCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+		return nil, err
+	}
+	env, haveEnv := envFrom(ctx)
+	e := examination{
+		x:      x,
+		digest: m.Hash,
+		entry:  Entry{State: x.state},
+	}
+	if prev != nil {
+		e.entry.Env = prev.Env
+	}
+	if haveEnv {
+		e.entry.Env = checkEnv(env)
+	}
+	switch {
+	case prev != nil && prev.State == x.state && !haveEnv:
+		zlog.Debug(ctx).Msg("already examined")
+		return nil, nil
+	case prev != nil && prev.State == x.state:
+		e.entry.Files = prev.Files
+	default:
+		e.walk = true
+	}
+	return &e, nil
+}
+
+// Examination is the examination of a single manifest.
+type examination struct {
+	x      *Examiner
+	digest claircore.Digest
+	entry  Entry
+	// Walk is set if the layers need to be walked, and err to the first
+	// error walking them.
+	walk bool
+	err  error
+}
+
+// Layer implements layerwalk.Examination.
+//
+// Once a layer fails, the rest aren't walked.
+func (e *examination) Layer(_ context.Context, l *claircore.Layer) layerwalk.Visitor {
+	if !e.walk || e.err != nil {
+		return nil
+	}
+	return &visitor{e: e, opts: &e.x.opts, digest: l.Hash}
+}
+
+// Done implements layerwalk.Examination.
+//
+// The findings are only stored if every layer was walked.
+func (e *examination) Done(ctx context.Context) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/secrets/examination.Done")
+	err := e.err
+	if err == nil {
+		err = e.store(ctx)
+	}
+	examineCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+	if err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to examine manifest for embedded credentials")
+	}
+}
+
+func (e *examination) store(ctx context.Context) error {
+	if e.walk {
+		for _, s := range e.entry.Files {
+			findingCounter.WithLabelValues(s.Kind).Inc()
+		}
+	}
+	for _, s := range e.entry.Env {
+		findingCounter.WithLabelValues(s.Kind).Inc()
+	}
+	zlog.Debug(ctx).
+		Int("files", len(e.entry.Files)).
+		Int("env", len(e.entry.Env)).
+		Msg("examined")
+	return e.x.store.Put(ctx, e.digest, &e.entry)
+}
+
+// Visitor examines the regular files in a single layer.
+type visitor struct {
+	e      *examination
+	opts   *Options
+	digest claircore.Digest
+	out    []indexer.Secret
+}
+
+// Visit implements layerwalk.Visitor.
+func (v *visitor) Visit(_ context.Context, e *layerwalk.Entry) error {
+	h := e.Header
+	if h.Typeflag != tar.TypeReg || h.Size == 0 || h.Size > v.opts.MaxFileSize {
+		return nil
+	}
+	if v.opts.ignored(e.Name) {
+		return nil
+	}
+	b, err := e.Bytes(h.Size)
+	if err != nil {
+		return fmt.Errorf("secrets: %s: %w", e.Name, err)
+	}
+	if kind, desc := checkFile(e.Name, b); kind != "" {
+		v.out = append(v.out, indexer.Secret{
+			Kind:        kind,
+			Path:        e.Name,
+			Layer:       &v.digest,
+			Description: desc,
+		})
+	}
+	return nil
+}
+
+// Done implements layerwalk.Visitor.
+func (v *visitor) Done(_ context.Context, err error) {
+	if err != nil {
+		v.e.err = err
+		return
+	}
+	v.e.entry.Files = append(v.e.entry.Files, v.out...)
+}
+
+// Secrets implements indexer.SecretReporter.
+func (x *Examiner) Secrets(ctx context.Context, d claircore.Digest) ([]indexer.Secret, bool, error) {
+	e, err := x.store.Get(ctx, d)
+	if err != nil || e == nil {
+		return nil, false, err
+	}
+	out := make([]indexer.Secret, 0, len(e.Files)+len(e.Env))
+	out = append(out, e.Files...)
+	out = append(out, e.Env...)
+	return out, true, nil
+}
+
+// Delete implements layerwalk.Examiner.
+func (x *Examiner) Delete(ctx context.Context, ds ...claircore.Digest) error {
+	return x.store.Delete(ctx, ds...)
+}
@@ -0,0 +1,229 @@
+// Package secrets finds credentials embedded in container images.
+//
+// Credentials baked into an image are readable by anyone who can pull it, and
+// deleting them in a later layer doesn't help: the layer that added them is
+// still in the image. An Examiner, run by a layerwalk.Indexer once a
+// manifest is indexed, looks through its layers for well-known credential
+// files and private keys, and examines the environment supplied with the
+// manifest. Findings only say where a credential is; the credential itself
+// is never stored or reported.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Version is the version of the rules. It should change whenever the rules
+// may report something different for the same image.
+const version = "1"
+
+type envKey struct{}
+
+// WithEnv returns a Context carrying the environment of the image being
+// indexed, as "NAME=value" strings like the "Env" member of an image config.
+func WithEnv(ctx context.Context, env []string) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+func envFrom(ctx context.Context) ([]string, bool) {
+	env, ok := ctx.Value(envKey{}).([]string)
+	return env, ok
+}
+
+// Options configures an Indexer.
+type Options struct {
+	// MaxFileSize is the size of the largest file examined.
+	MaxFileSize int64
+	// IgnorePaths are path.Match patterns for files that are never
+	// reported.
+	IgnorePaths []string
+}
+
+// State reports an opaque identifier for the rules and options.
+//
+// Layers are examined again when a manifest examined with a different state
+// is submitted.
+func (o *Options) state() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\n", version, o.MaxFileSize)
+	for _, p := range o.IgnorePaths {
+		fmt.Fprintf(h, "%s\n", p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (o *Options) ignored(name string) bool {
+	for _, p := range o.IgnorePaths {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// These are substrings of environment variable names that suggest the value
+// is a credential.
+var envNames = []string{
+	"PASSWORD", "PASSWD", "SECRET", "TOKEN", "API_KEY", "APIKEY",
+	"ACCESS_KEY", "PRIVATE_KEY", "CREDENTIAL",
+}
+
+// These are suffixes of environment variable names that refer to a
+// credential kept elsewhere, rather than holding one.
+var envRefs = []string{"_FILE", "_PATH", "_DIR"}
+
+// ValuePatterns match well-known token formats, regardless of the name of the
+// variable holding them.
+var valuePatterns = []struct {
+	re   *regexp.Regexp
+	desc string
+}{
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "AWS access key ID"},
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), "GitHub token"},
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "Slack token"},
+}
+
+// CheckEnv examines an image's environment, as "NAME=value" strings.
+func checkEnv(env []string) []indexer.Secret {
+	var out []indexer.Secret
+	for _, kv := range env {
+		name, v, ok := strings.Cut(kv, "=")
+		if !ok || v == "" {
+			continue
+		}
+		if desc := envValue(name, v); desc != "" {
+			out = append(out, indexer.Secret{
+				Kind:        indexer.SecretEnvironment,
+				Variable:    name,
+				Description: desc,
+			})
+		}
+	}
+	return out
+}
+
+func envValue(name, v string) string {
+	for _, p := range valuePatterns {
+		if p.re.MatchString(v) {
+			return p.desc
+		}
+	}
+	if u, err := url.Parse(v); err == nil && u.Host != "" {
+		if p, ok := u.User.Password(); ok && p != "" {
+			return "URL with an embedded password"
+		}
+	}
+	n := strings.ToUpper(name)
+	for _, s := range envRefs {
+		if strings.HasSuffix(n, s) {
+			return ""
+		}
+	}
+	if strings.HasPrefix(v, "/") {
+		return ""
+	}
+	for _, s := range envNames {
+		if strings.Contains(n, s) {
+			return "variable name suggests a credential"
+		}
+	}
+	return ""
+}
+
+// PrivateKey matches the header of a PEM-encoded or armored private key.
+var privateKey = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+
+// CheckFile examines the contents of the file "name", returning the kind of
+// credential found and a description, or empty strings if there's nothing.
+func checkFile(name string, b []byte) (kind, desc string) {
+	switch base := path.Base(name); {
+	case base == "config.json" && path.Base(path.Dir(name)) == ".docker":
+		if dockerConfig(b) {
+			return indexer.SecretDockerConfig, "registry credentials in Docker client configuration"
+		}
+	case base == ".dockercfg":
+		var cfg map[string]dockerAuth
+		if json.Unmarshal(b, &cfg) == nil && anyAuth(cfg) {
+			return indexer.SecretDockerConfig, "registry credentials in legacy Docker client configuration"
+		}
+	case base == ".netrc" || base == "_netrc":
+		if netrc(b) {
+			return indexer.SecretNetrc, "password in netrc file"
+		}
+	case base == ".git-credentials":
+		if gitCredentials(b) {
+			return indexer.SecretGitCredentials, "password in Git credential store"
+		}
+	}
+	if privateKey.Match(b) {
+		return indexer.SecretPrivateKey, "PEM-encoded private key"
+	}
+	return "", ""
+}
+
+type dockerAuth struct {
+	Auth          string `json:"auth"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+func anyAuth(m map[string]dockerAuth) bool {
+	for _, a := range m {
+		if a.Auth != "" || a.Password != "" || a.IdentityToken != "" || a.RegistryToken != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// DockerConfig reports whether "b" is a Docker client configuration holding
+// credentials. References to credential helpers don't count.
+func dockerConfig(b []byte) bool {
+	var cfg struct {
+		Auths map[string]dockerAuth `json:"auths"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return false
+	}
+	return anyAuth(cfg.Auths)
+}
+
+// Netrc reports whether "b" is a netrc file with a non-empty password.
+func netrc(b []byte) bool {
+	fs := strings.Fields(string(b))
+	for i, f := range fs {
+		if f == "password" && i+1 < len(fs) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitCredentials reports whether "b" is a Git credential store with a
+// password in any of its URLs.
+func gitCredentials(b []byte) bool {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		u, err := url.Parse(strings.TrimSpace(s.Text()))
+		if err != nil {
+			continue
+		}
+		if p, ok := u.User.Password(); ok && p != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_secrets", "the embedded credential store")
+
+// Store persists embedded credential findings for manifests.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Entry is the stored findings for a single manifest.
+type Entry struct {
+	// State is the state of the rules and options the layers were examined
+	// with.
+	State string
+	// Files are the findings in the manifest's layers.
+	Files []indexer.Secret
+	// Env are the findings in the environment last submitted with the
+	// manifest.
+	Env []indexer.Secret
+}
+
+// Get returns the Entry for the manifest, or nil if there isn't one.
+func (s *Store) Get(ctx context.Context, d claircore.Digest) (_ *Entry, err error) {
+	const query = `SELECT state, files, env FROM indexer_secrets WHERE manifest = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var e Entry
+	var fs, env []byte
+	err = s.pool.QueryRow(ctx, query, d.String()).Scan(&e.State, &fs, &env)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("secrets: unable to look up %v: %w", d, err)
+	}
+	if err = json.Unmarshal(fs, &e.Files); err != nil {
+		return nil, fmt.Errorf("secrets: bad stored findings for %v: %w", d, err)
+	}
+	if err = json.Unmarshal(env, &e.Env); err != nil {
+		return nil, fmt.Errorf("secrets: bad stored findings for %v: %w", d, err)
+	}
+	return &e, nil
+}
+
+// Put stores the Entry for the manifest, replacing any previous one.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, e *Entry) (err error) {
+	const query = `INSERT INTO indexer_secrets (manifest, state, files, env)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (manifest) DO UPDATE
+SET state = EXCLUDED.state, files = EXCLUDED.files, env = EXCLUDED.env,
+	updated = now();`
+	defer queryMetrics.Observe("put", &err)()
+	fs, err := json.Marshal(nonNil(e.Files))
+	if err != nil {
+		return fmt.Errorf("secrets: unable to encode findings: %w", err)
+	}
+	env, err := json.Marshal(nonNil(e.Env))
+	if err != nil {
+		return fmt.Errorf("secrets: unable to encode findings: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, query, d.String(), e.State, fs, env); err != nil {
+		return fmt.Errorf("secrets: unable to store findings for %v: %w", d, err)
+	}
+	return nil
+}
+
+func nonNil(s []indexer.Secret) []indexer.Secret {
+	if s == nil {
+		return []indexer.Secret{}
+	}
+	return s
+}
+
+// Delete removes the findings for the manifests.
+func (s *Store) Delete(ctx context.Context, ds ...claircore.Digest) (err error) {
+	const query = `DELETE FROM indexer_secrets WHERE manifest = ANY($1::text[]);`
+	defer queryMetrics.Observe("delete", &err)()
+	in := make([]string, len(ds))
+	for i, d := range ds {
+		in[i] = d.String()
+	}
+	if _, err = s.pool.Exec(ctx, query, in); err != nil {
+		return fmt.Errorf("secrets: unable to delete: %w", err)
+	}
+	return nil
+}
@@ -2,10 +2,16 @@
 
 import (
 	"context"
+	"errors"
 
 	"github.com/quay/claircore"
 )
 
+// ErrUnsupported is reported by the methods of the optional interfaces when
+// a Service implements an interface but doesn't provide its functionality,
+// such as when labels aren't enabled.
+var ErrUnsupported = errors.New("indexer: not supported")
+
 // Service is an aggregate interface wrapping claircore.Libindex functionality.
 //
 // Implementation may use a local instance of claircore.Libindex or a remote
@@ -51,3 +57,11 @@ type Stater interface {
 type Affected interface {
 	AffectedManifests(ctx context.Context, vulns []claircore.Vulnerability) (*claircore.AffectedManifests, error)
 }
+
+// Labeler is an optional interface for Services that store the labels clients
+// submit along with manifests.
+type Labeler interface {
+	// Labels returns the labels for each of the named manifests that has
+	// any, keyed by manifest digest.
+	Labels(ctx context.Context, manifests ...claircore.Digest) (map[string]map[string]string, error)
+}
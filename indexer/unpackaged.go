@@ -0,0 +1,44 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// UnpackagedReporter is an optional interface for Services that look for
+// the contents of images that no package manager accounts for.
+type UnpackagedReporter interface {
+	// Unpackaged returns the findings for the manifest, and whether the
+	// manifest has been examined.
+	Unpackaged(ctx context.Context, manifest claircore.Digest) (*Unpackaged, bool, error)
+}
+
+// These are the kinds of UnpackagedFile.
+const (
+	UnpackagedStaticBinary = "static_binary"
+	UnpackagedBusybox      = "busybox"
+	UnpackagedCABundle     = "ca_bundle"
+)
+
+// Unpackaged describes the contents of an image that vulnerability matching
+// can't see, so that an image with nothing to match can be told apart from
+// one with nothing vulnerable.
+type Unpackaged struct {
+	// PackageManager reports whether any package manager metadata was found.
+	// If it wasn't, an index report without packages means the image's
+	// contents couldn't be inventoried, not that there's nothing in it.
+	PackageManager bool `json:"package_manager"`
+	// Files are the notable files not installed by a package manager.
+	Files []UnpackagedFile `json:"files,omitempty"`
+	// BusyboxApplets are the commands provided by links to busybox.
+	BusyboxApplets []string `json:"busybox_applets,omitempty"`
+}
+
+// UnpackagedFile is a notable file found in an image.
+type UnpackagedFile struct {
+	Kind string `json:"kind"`
+	Path string `json:"path,omitempty"`
+	// Layer is the layer that added the file.
+	Layer *claircore.Digest `json:"layer,omitempty"`
+}
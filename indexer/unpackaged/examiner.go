@@ -0,0 +1,162 @@
+package unpackaged
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var examineCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "indexer_unpackaged",
+		Name:      "examine_total",
+		Help:      "Total number of manifests examined for unpackaged contents, by whether examining failed",
+	},
+	[]string{"error"},
+)
+
+// Examiner looks for the unpackaged contents of manifests.
+//
+// Examining a manifest never fails an index request: errors are logged, and
+// the manifest is examined again the next time it's submitted.
+type Examiner struct {
+	store *Store
+}
+
+var (
+	_ layerwalk.Examiner         = (*Examiner)(nil)
+	_ indexer.UnpackagedReporter = (*Examiner)(nil)
+)
+
+// NewExaminer returns an Examiner storing findings in "s".
+func NewExaminer(s *Store) *Examiner {
+	return &Examiner{store: s}
+}
+
+// Name implements layerwalk.Examiner.
+func (x *Examiner) Name() string { return "unpackaged" }
+
+// Examine implements layerwalk.Examiner.
+//
+// Layers are only walked if the manifest hasn't been examined by the current
+// version of the walk.
+func (x *Examiner) Examine(ctx context.Context, m *claircore.Manifest) (layerwalk.Examination, error) {
+	prev, err := x.store.Get(ctx, m.Hash)
+	if err != nil {
+		examineCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+		return nil, err
+	}
+	if prev != nil && prev.State == version {
+		zlog.Debug(ctx).Msg("already examined")
+		return nil, nil
+	}
+	return &examination{store: x.store, digest: m.Hash, c: newContents()}, nil
+}
+
+// Examination is the examination of a single manifest.
+type examination struct {
+	store  *Store
+	digest claircore.Digest
+	c      *contents
+	// Err is the first error walking a layer.
+	err error
+}
+
+// Layer implements layerwalk.Examination.
+//
+// Once a layer fails, the rest aren't walked.
+func (e *examination) Layer(_ context.Context, l *claircore.Layer) layerwalk.Visitor {
+	if e.err != nil {
+		return nil
+	}
+	return &visitor{e: e, out: newLayer(l.Hash)}
+}
+
+// Done implements layerwalk.Examination.
+//
+// The findings are only stored if every layer was walked.
+func (e *examination) Done(ctx context.Context) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "indexer/unpackaged/examination.Done")
+	err := e.err
+	if err == nil {
+		ent := Entry{State: version, Contents: e.c.Unpackaged()}
+		zlog.Debug(ctx).
+			Bool("package_manager", ent.Contents.PackageManager).
+			Int("files", len(ent.Contents.Files)).
+			Msg("examined")
+		err = e.store.Put(ctx, e.digest, &ent)
+	}
+	examineCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+	if err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to examine manifest for unpackaged contents")
+	}
+}
+
+// Visitor examines the files in a single layer.
+type visitor struct {
+	e   *examination
+	out *layer
+}
+
+// Visit implements layerwalk.Visitor.
+func (v *visitor) Visit(_ context.Context, e *layerwalk.Entry) error {
+	h := e.Header
+	if v.out.whiteout(e.Name) {
+		return nil
+	}
+	switch h.Typeflag {
+	case tar.TypeSymlink, tar.TypeLink:
+		v.out.link(e.Name, h.Linkname)
+		return nil
+	case tar.TypeReg:
+	default:
+		return nil
+	}
+	if packageDB(e.Name) {
+		v.out.pkgDBs[e.Name] = struct{}{}
+		return nil
+	}
+	if h.Size == 0 {
+		return nil
+	}
+	b, err := e.Bytes(maxHeaderSize)
+	if err != nil {
+		return fmt.Errorf("unpackaged: %s: %w", e.Name, err)
+	}
+	v.out.file(e.Name, h.FileInfo().Mode()&0o111 != 0, b)
+	return nil
+}
+
+// Done implements layerwalk.Visitor.
+func (v *visitor) Done(_ context.Context, err error) {
+	if err != nil {
+		v.e.err = err
+		return
+	}
+	v.e.c.add(v.out)
+}
+
+// Unpackaged implements indexer.UnpackagedReporter.
+func (x *Examiner) Unpackaged(ctx context.Context, d claircore.Digest) (*indexer.Unpackaged, bool, error) {
+	e, err := x.store.Get(ctx, d)
+	if err != nil || e == nil {
+		return nil, false, err
+	}
+	return &e.Contents, true, nil
+}
+
+// Delete implements layerwalk.Examiner.
+func (x *Examiner) Delete(ctx context.Context, ds ...claircore.Digest) error {
+	return x.store.Delete(ctx, ds...)
+}
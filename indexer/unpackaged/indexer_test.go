@@ -0,0 +1,100 @@
+package unpackaged
+
+import (
+	"context"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/indexer/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestIndexer(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	blob := archive(t, entry{Name: "server", Mode: 0o755, Body: elfBinary(t, elf.ET_EXEC, elf.PT_LOAD)})
+	sum := sha256.Sum256(blob)
+	ld := claircore.MustParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(blob)
+	}))
+	defer srv.Close()
+
+	x := NewExaminer(NewStore(pool))
+	i := layerwalk.NewIndexer(&indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+		DeleteManifests_: func(_ context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+			return ds, nil
+		},
+	}, srv.Client(), t.TempDir(), x)
+	m := &claircore.Manifest{
+		Hash:   claircore.MustParseDigest("sha256:" + hex.EncodeToString(make([]byte, 32))),
+		Layers: []*claircore.Layer{{Hash: ld, URI: srv.URL + "/blob"}},
+	}
+
+	if _, ok, err := x.Unpackaged(ctx, m.Hash); err != nil || ok {
+		t.Errorf("unexamined: got: (%v, %v)", ok, err)
+	}
+	for n := 0; n < 2; n++ {
+		if _, err := i.Index(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := fetches, 1; got != want {
+		t.Errorf("got: %d fetches, want: %d", got, want)
+	}
+	got, ok, err := x.Unpackaged(ctx, m.Hash)
+	if err != nil || !ok {
+		t.Fatalf("got: (%v, %v)", ok, err)
+	}
+	if got.PackageManager {
+		t.Error("unexpected package manager")
+	}
+	if len(got.Files) != 1 || got.Files[0].Kind != indexer.UnpackagedStaticBinary {
+		t.Errorf("unexpected files: %+v", got.Files)
+	}
+
+	if _, err := i.DeleteManifests(ctx, m.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := x.Unpackaged(ctx, m.Hash); err != nil || ok {
+		t.Errorf("deleted: got: (%v, %v)", ok, err)
+	}
+}
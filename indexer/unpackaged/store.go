@@ -0,0 +1,88 @@
+package unpackaged
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_unpackaged", "the unpackaged contents store")
+
+// Store persists the unpackaged contents found in manifests.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Entry is the stored findings for a single manifest.
+type Entry struct {
+	// State is the version of the walk the layers were examined with.
+	State    string
+	Contents indexer.Unpackaged
+}
+
+// Get returns the Entry for the manifest, or nil if there isn't one.
+func (s *Store) Get(ctx context.Context, d claircore.Digest) (_ *Entry, err error) {
+	const query = `SELECT state, contents FROM indexer_unpackaged WHERE manifest = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var e Entry
+	var b []byte
+	err = s.pool.QueryRow(ctx, query, d.String()).Scan(&e.State, &b)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unpackaged: unable to look up %v: %w", d, err)
+	}
+	if err = json.Unmarshal(b, &e.Contents); err != nil {
+		return nil, fmt.Errorf("unpackaged: bad stored findings for %v: %w", d, err)
+	}
+	return &e, nil
+}
+
+// Put stores the Entry for the manifest, replacing any previous one.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, e *Entry) (err error) {
+	const query = `INSERT INTO indexer_unpackaged (manifest, state, contents)
+VALUES ($1, $2, $3)
+ON CONFLICT (manifest) DO UPDATE
+SET state = EXCLUDED.state, contents = EXCLUDED.contents, updated = now();`
+	defer queryMetrics.Observe("put", &err)()
+	b, err := json.Marshal(&e.Contents)
+	if err != nil {
+		return fmt.Errorf("unpackaged: unable to encode findings: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, query, d.String(), e.State, b); err != nil {
+		return fmt.Errorf("unpackaged: unable to store findings for %v: %w", d, err)
+	}
+	return nil
+}
+
+// Delete removes the findings for the manifests.
+func (s *Store) Delete(ctx context.Context, ds ...claircore.Digest) (err error) {
+	const query = `DELETE FROM indexer_unpackaged WHERE manifest = ANY($1::text[]);`
+	defer queryMetrics.Observe("delete", &err)()
+	in := make([]string, len(ds))
+	for i, d := range ds {
+		in[i] = d.String()
+	}
+	if _, err = s.pool.Exec(ctx, query, in); err != nil {
+		return fmt.Errorf("unpackaged: unable to delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Package unpackaged looks for the contents of images that no package manager
+// accounts for.
+//
+// Distroless and "scratch" images often have no package manager metadata at
+// all, so an index report for one has no packages and a vulnerability report
+// has no findings, which reads the same as a clean image. An Examiner, run by
+// a layerwalk.Indexer once a manifest is indexed, looks through its layers
+// and records whether any package manager metadata is present, along with the
+// statically linked executables, busybox and its applets, and CA certificate
+// bundles it finds.
+//
+// These are heuristics: an executable is considered statically linked if it
+// has no program interpreter and no dynamic section, so static PIE
+// executables aren't found.
+package unpackaged
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Version is the version of the layer walk. It should change whenever the
+// walk may record something different for the same layers.
+const version = "1"
+
+// MaxHeaderSize is the size of the prefix of each file examined.
+const maxHeaderSize = 64 * 1024
+
+// MaxStaticBinaries is the most statically linked executables reported for a
+// manifest.
+const maxStaticBinaries = 256
+
+// PemCertificate is the header of a PEM-encoded certificate.
+var pemCertificate = []byte("-----BEGIN CERTIFICATE-----")
+
+// CABundles are the names of CA certificate bundles, wherever they're found.
+var caBundles = map[string]struct{}{
+	"ca-certificates.crt": {},
+	"ca-bundle.crt":       {},
+	"ca-bundle.pem":       {},
+	"cacert.pem":          {},
+	"tls-ca-bundle.pem":   {},
+}
+
+// PackageDB reports whether "name" is the metadata of a distribution package
+// manager.
+func packageDB(name string) bool {
+	dir, base := path.Split(name)
+	dir = path.Clean(dir)
+	switch {
+	case path.Base(dir) == "rpm" && (base == "Packages" || base == "Packages.db" || base == "rpmdb.sqlite"):
+	case name == "lib/apk/db/installed":
+	case name == "var/lib/dpkg/status":
+	case dir == "var/lib/dpkg/status.d":
+	default:
+		return false
+	}
+	return true
+}
+
+// Layer is what's found in a single layer.
+type layer struct {
+	digest claircore.Digest
+	// Whiteouts are the paths removed from the layers below, and opaque the
+	// directories whose contents are.
+	whiteouts []string
+	opaque    []string
+	pkgDBs    map[string]struct{}
+	files     map[string]string
+	// Applets maps links to busybox to the command they provide.
+	applets map[string]string
+}
+
+func newLayer(d claircore.Digest) *layer {
+	return &layer{
+		digest:  d,
+		pkgDBs:  make(map[string]struct{}),
+		files:   make(map[string]string),
+		applets: make(map[string]string),
+	}
+}
+
+// Whiteout records the whiteout file "name", if it is one, and reports
+// whether it was.
+func (l *layer) whiteout(name string) bool {
+	dir, base := path.Split(name)
+	switch {
+	case base == ".wh..wh..opq":
+		l.opaque = append(l.opaque, path.Clean(dir))
+	case strings.HasPrefix(base, ".wh."):
+		l.whiteouts = append(l.whiteouts, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+	default:
+		return false
+	}
+	return true
+}
+
+// Link records the link "name" to "target", if it's a busybox applet.
+func (l *layer) link(name, target string) {
+	if path.Base(target) == "busybox" && path.Base(name) != "busybox" {
+		l.applets[name] = path.Base(name)
+	}
+}
+
+// File records the regular file "name", of which "b" is the prefix, if it's
+// of interest.
+func (l *layer) file(name string, executable bool, b []byte) {
+	base := path.Base(name)
+	switch {
+	case executable && base == "busybox" && bytes.HasPrefix(b, []byte(elf.ELFMAG)):
+		l.files[name] = indexer.UnpackagedBusybox
+	case executable && staticELF(b):
+		l.files[name] = indexer.UnpackagedStaticBinary
+	case !executable && isCABundle(name, base) && bytes.Contains(b, pemCertificate):
+		l.files[name] = indexer.UnpackagedCABundle
+	}
+}
+
+func isCABundle(name, base string) bool {
+	if _, ok := caBundles[base]; ok {
+		return true
+	}
+	return name == "etc/ssl/cert.pem"
+}
+
+// StaticELF reports whether "b", the prefix of a file, is an ELF executable
+// with no program interpreter and no dynamic section.
+//
+// If the program headers aren't in "b", the file isn't reported.
+func staticELF(b []byte) bool {
+	if len(b) < elf.EI_NIDENT || !bytes.HasPrefix(b, []byte(elf.ELFMAG)) {
+		return false
+	}
+	var bo binary.ByteOrder
+	switch elf.Data(b[elf.EI_DATA]) {
+	case elf.ELFDATA2LSB:
+		bo = binary.LittleEndian
+	case elf.ELFDATA2MSB:
+		bo = binary.BigEndian
+	default:
+		return false
+	}
+	var typ elf.Type
+	var phoff uint64
+	var phentsize, phnum uint64
+	switch elf.Class(b[elf.EI_CLASS]) {
+	case elf.ELFCLASS64:
+		var h elf.Header64
+		if err := binary.Read(bytes.NewReader(b), bo, &h); err != nil {
+			return false
+		}
+		typ, phoff, phentsize, phnum = elf.Type(h.Type), h.Phoff, uint64(h.Phentsize), uint64(h.Phnum)
+	case elf.ELFCLASS32:
+		var h elf.Header32
+		if err := binary.Read(bytes.NewReader(b), bo, &h); err != nil {
+			return false
+		}
+		typ, phoff, phentsize, phnum = elf.Type(h.Type), uint64(h.Phoff), uint64(h.Phentsize), uint64(h.Phnum)
+	default:
+		return false
+	}
+	if typ != elf.ET_EXEC && typ != elf.ET_DYN || phnum == 0 || phentsize < 4 {
+		return false
+	}
+	// The program header type is the first member in both classes.
+	for i := uint64(0); i < phnum; i++ {
+		off := phoff + i*phentsize
+		if off+4 > uint64(len(b)) {
+			return false
+		}
+		switch elf.ProgType(bo.Uint32(b[off:])) {
+		case elf.PT_INTERP, elf.PT_DYNAMIC:
+			return false
+		}
+	}
+	return true
+}
+
+// Contents accumulates layers, in order, into the image's contents.
+type contents struct {
+	pkgDBs  map[string]struct{}
+	files   map[string]indexer.UnpackagedFile
+	applets map[string]string
+}
+
+func newContents() *contents {
+	return &contents{
+		pkgDBs:  make(map[string]struct{}),
+		files:   make(map[string]indexer.UnpackagedFile),
+		applets: make(map[string]string),
+	}
+}
+
+// Add applies the layer "l" on top of the current contents.
+func (c *contents) add(l *layer) {
+	for _, p := range l.opaque {
+		c.remove(p, false)
+	}
+	for _, p := range l.whiteouts {
+		c.remove(p, true)
+	}
+	for p := range l.pkgDBs {
+		c.pkgDBs[p] = struct{}{}
+	}
+	for p, k := range l.files {
+		d := l.digest
+		c.files[p] = indexer.UnpackagedFile{Kind: k, Path: p, Layer: &d}
+	}
+	for p, a := range l.applets {
+		c.applets[p] = a
+	}
+}
+
+// Remove removes everything under "p", and "p" itself if "self" is set.
+func (c *contents) remove(p string, self bool) {
+	under := func(n string) bool {
+		return (self && n == p) || p == "." || strings.HasPrefix(n, p+"/")
+	}
+	for n := range c.pkgDBs {
+		if under(n) {
+			delete(c.pkgDBs, n)
+		}
+	}
+	for n := range c.files {
+		if under(n) {
+			delete(c.files, n)
+		}
+	}
+	for n := range c.applets {
+		if under(n) {
+			delete(c.applets, n)
+		}
+	}
+}
+
+// Unpackaged returns the findings for the contents.
+//
+// Busybox applets are only reported if busybox itself is present.
+func (c *contents) Unpackaged() indexer.Unpackaged {
+	out := indexer.Unpackaged{PackageManager: len(c.pkgDBs) != 0}
+	var busybox bool
+	static := 0
+	for _, f := range c.files {
+		switch f.Kind {
+		case indexer.UnpackagedBusybox:
+			busybox = true
+		case indexer.UnpackagedStaticBinary:
+			static++
+		}
+		out.Files = append(out.Files, f)
+	}
+	sort.Slice(out.Files, func(i, j int) bool { return out.Files[i].Path < out.Files[j].Path })
+	if static > maxStaticBinaries {
+		fs := out.Files[:0]
+		n := 0
+		for _, f := range out.Files {
+			if f.Kind == indexer.UnpackagedStaticBinary {
+				if n == maxStaticBinaries {
+					continue
+				}
+				n++
+			}
+			fs = append(fs, f)
+		}
+		out.Files = fs
+	}
+	if busybox {
+		seen := make(map[string]struct{}, len(c.applets))
+		for _, a := range c.applets {
+			if _, ok := seen[a]; ok {
+				continue
+			}
+			seen[a] = struct{}{}
+			out.BusyboxApplets = append(out.BusyboxApplets, a)
+		}
+		sort.Strings(out.BusyboxApplets)
+	}
+	return out
+}
@@ -0,0 +1,193 @@
+package unpackaged
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+)
+
+// ElfBinary returns the start of a little-endian ELF64 executable with a
+// program header of each of the types "ps".
+func elfBinary(t testing.TB, typ elf.Type, ps ...elf.ProgType) []byte {
+	t.Helper()
+	h := elf.Header64{
+		Type:      uint16(typ),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     64,
+		Ehsize:    64,
+		Phentsize: 56,
+		Phnum:     uint16(len(ps)),
+	}
+	copy(h.Ident[:], elf.ELFMAG)
+	h.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	h.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	h.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, &h); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range ps {
+		if err := binary.Write(&buf, binary.LittleEndian, &elf.Prog64{Type: uint32(p)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestStaticELF(t *testing.T) {
+	tt := []struct {
+		Name string
+		In   []byte
+		Want bool
+	}{
+		{Name: "Static", In: elfBinary(t, elf.ET_EXEC, elf.PT_LOAD, elf.PT_NOTE), Want: true},
+		{Name: "Dynamic", In: elfBinary(t, elf.ET_DYN, elf.PT_LOAD, elf.PT_INTERP, elf.PT_DYNAMIC)},
+		{Name: "SharedObject", In: elfBinary(t, elf.ET_DYN, elf.PT_LOAD, elf.PT_DYNAMIC)},
+		{Name: "Relocatable", In: elfBinary(t, elf.ET_REL, elf.PT_LOAD)},
+		{Name: "Truncated", In: elfBinary(t, elf.ET_EXEC, elf.PT_LOAD)[:66]},
+		{Name: "Script", In: []byte("#!/bin/sh\necho hello\n")},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got, want := staticELF(tc.In), tc.Want; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+// Entry is a file to put in a test layer.
+type entry struct {
+	Name     string
+	Mode     int64
+	Typeflag byte
+	Linkname string
+	Body     []byte
+}
+
+func archive(t testing.TB, es ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range es {
+		if e.Typeflag == 0 {
+			e.Typeflag = tar.TypeReg
+		}
+		if e.Mode == 0 {
+			e.Mode = 0o644
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     e.Name,
+			Mode:     e.Mode,
+			Size:     int64(len(e.Body)),
+			Typeflag: e.Typeflag,
+			Linkname: e.Linkname,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Walk returns what's found in "b", an archive of the layer "d".
+func walk(b []byte, d claircore.Digest) (*layer, error) {
+	v := &visitor{out: newLayer(d)}
+	err := layerwalk.Walk(context.Background(), "", tar.NewReader(bytes.NewReader(b)), v)
+	return v.out, err
+}
+
+func TestWalk(t *testing.T) {
+	static := elfBinary(t, elf.ET_EXEC, elf.PT_LOAD)
+	dynamic := elfBinary(t, elf.ET_DYN, elf.PT_LOAD, elf.PT_INTERP)
+	bundle := []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")
+	d1 := claircore.MustParseDigest("sha256:" + string(bytes.Repeat([]byte("a"), 64)))
+	d2 := claircore.MustParseDigest("sha256:" + string(bytes.Repeat([]byte("b"), 64)))
+
+	t.Run("Distroless", func(t *testing.T) {
+		c := newContents()
+		for _, l := range []struct {
+			d  claircore.Digest
+			es []entry
+		}{
+			{d: d1, es: []entry{
+				{Name: "bin/busybox", Mode: 0o755, Body: static},
+				{Name: "bin/sh", Typeflag: tar.TypeSymlink, Linkname: "busybox"},
+				{Name: "bin/ls", Typeflag: tar.TypeLink, Linkname: "bin/busybox"},
+				{Name: "etc/ssl/certs/ca-certificates.crt", Body: bundle},
+				{Name: "usr/bin/old", Mode: 0o755, Body: static},
+			}},
+			{d: d2, es: []entry{
+				{Name: "app/server", Mode: 0o755, Body: static},
+				{Name: "app/plugin", Mode: 0o755, Body: dynamic},
+				{Name: "app/notes.txt", Body: []byte("hello")},
+				{Name: "usr/bin/.wh.old", Body: nil},
+			}},
+		} {
+			ly, err := walk(archive(t, l.es...), l.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.add(ly)
+		}
+		want := indexer.Unpackaged{
+			Files: []indexer.UnpackagedFile{
+				{Kind: indexer.UnpackagedStaticBinary, Path: "app/server", Layer: &d2},
+				{Kind: indexer.UnpackagedBusybox, Path: "bin/busybox", Layer: &d1},
+				{Kind: indexer.UnpackagedCABundle, Path: "etc/ssl/certs/ca-certificates.crt", Layer: &d1},
+			},
+			BusyboxApplets: []string{"ls", "sh"},
+		}
+		opt := cmp.AllowUnexported(claircore.Digest{})
+		if got := c.Unpackaged(); !cmp.Equal(got, want, opt) {
+			t.Error(cmp.Diff(got, want, opt))
+		}
+	})
+
+	t.Run("PackageManager", func(t *testing.T) {
+		c := newContents()
+		ly, err := walk(archive(t,
+			entry{Name: "var/lib/dpkg/status", Body: []byte("Package: base-files\n")},
+			entry{Name: "bin/sh", Typeflag: tar.TypeSymlink, Linkname: "/bin/busybox"},
+		), d1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.add(ly)
+		got := c.Unpackaged()
+		if !got.PackageManager {
+			t.Error("package manager not found")
+		}
+		// Without busybox itself, the link is dangling.
+		if len(got.BusyboxApplets) != 0 {
+			t.Errorf("unexpected applets: %v", got.BusyboxApplets)
+		}
+
+		// An opaque directory hides the package database.
+		ly, err = walk(archive(t,
+			entry{Name: "var/lib/dpkg/.wh..wh..opq"},
+		), d2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.add(ly)
+		if c.Unpackaged().PackageManager {
+			t.Error("package manager found after removal")
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// UsageReporter is an optional interface for Services that can report how
+// much they're storing.
+type UsageReporter interface {
+	// Usage reports the indexer's storage usage. If "tenantLabel" is not
+	// empty, manifests are also counted by the value of that label.
+	Usage(ctx context.Context, tenantLabel string) (*Usage, error)
+}
+
+// Usage is a snapshot of an indexer's storage usage.
+type Usage struct {
+	Manifests int64 `json:"manifests"`
+	Layers    int64 `json:"layers"`
+	// Packages is the number of distinct packages, not the number of
+	// package occurrences in layers.
+	Packages int64 `json:"packages"`
+	// DatabaseBytes is the size of the indexer's database.
+	DatabaseBytes int64 `json:"database_bytes"`
+	// Tenants counts manifests by the value of the requested tenant label.
+	// Manifests without the label are counted under the empty string.
+	Tenants map[string]int64 `json:"tenants,omitempty"`
+}
+
+// ManifestLister is an optional interface for Services that can enumerate the
+// manifests they've indexed.
+type ManifestLister interface {
+	// Manifests returns up to "limit" manifest digests that sort after
+	// "after", in order. Passing the last digest returned as "after" gets the
+	// next page; an empty "after" starts from the beginning.
+	Manifests(ctx context.Context, after string, limit int) ([]claircore.Digest, error)
+}
@@ -0,0 +1,105 @@
+// Package usage reports how much an indexer is storing, for capacity
+// planning and for checking that garbage collection is keeping up.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/labels"
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("indexer_usage", "the usage reporter")
+
+// ErrNoLabels is returned when a tenant label is requested but manifest
+// labels aren't stored.
+var ErrNoLabels = errors.New("usage: manifest labels are not stored")
+
+// Reporter reports an indexer's storage usage.
+type Reporter struct {
+	pool   *pgxpool.Pool
+	labels *labels.Store
+}
+
+var (
+	_ indexer.UsageReporter  = (*Reporter)(nil)
+	_ indexer.ManifestLister = (*Reporter)(nil)
+)
+
+// New returns a Reporter using the provided pool, which must be connected to
+// the indexer's database. The label Store is used to count manifests by
+// tenant and may be nil if labels aren't stored.
+func New(pool *pgxpool.Pool, l *labels.Store) *Reporter {
+	return &Reporter{pool: pool, labels: l}
+}
+
+// Usage implements indexer.UsageReporter.
+//
+// The counts are exact, so this scans the manifest, layer, and package
+// tables.
+func (r *Reporter) Usage(ctx context.Context, tenantLabel string) (_ *indexer.Usage, err error) {
+	const query = `SELECT
+	(SELECT count(*) FROM manifest),
+	(SELECT count(*) FROM layer),
+	(SELECT count(*) FROM package),
+	pg_database_size(current_database());`
+	if tenantLabel != "" && r.labels == nil {
+		return nil, ErrNoLabels
+	}
+	var u indexer.Usage
+	err = func() (err error) {
+		defer queryMetrics.Observe("counts", &err)()
+		return r.pool.QueryRow(ctx, query).
+			Scan(&u.Manifests, &u.Layers, &u.Packages, &u.DatabaseBytes)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("usage: unable to count rows: %w", err)
+	}
+	if tenantLabel == "" {
+		return &u, nil
+	}
+
+	u.Tenants, err = r.labels.Count(ctx, tenantLabel)
+	if err != nil {
+		return nil, err
+	}
+	var labeled int64
+	for _, n := range u.Tenants {
+		labeled += n
+	}
+	// The counts come from different queries, so don't report a negative
+	// number if manifests were deleted in between.
+	if rest := u.Manifests - labeled; rest > 0 {
+		u.Tenants[""] += rest
+	}
+	return &u, nil
+}
+
+// Manifests implements indexer.ManifestLister.
+func (r *Reporter) Manifests(ctx context.Context, after string, limit int) (_ []claircore.Digest, err error) {
+	const query = `SELECT hash FROM manifest WHERE hash > $1 ORDER BY hash LIMIT $2;`
+	defer queryMetrics.Observe("manifests", &err)()
+	rows, err := r.pool.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("usage: unable to list manifests: %w", err)
+	}
+	defer rows.Close()
+	out := make([]claircore.Digest, 0, limit)
+	for rows.Next() {
+		var d claircore.Digest
+		if err = rows.Scan(&d); err != nil {
+			return nil, fmt.Errorf("usage: unable to list manifests: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("usage: unable to list manifests: %w", err)
+	}
+	return out, nil
+}
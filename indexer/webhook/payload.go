@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Payload formats understood by Parse.
+const (
+	Quay      = "quay"
+	Harbor    = "harbor"
+	DockerHub = "dockerhub"
+)
+
+// ErrFormat is returned by Parse for unknown payload formats.
+var ErrFormat = errors.New("webhook: unknown payload format")
+
+// Parse returns the image references pushed according to the webhook
+// payload "b" in the named format. Payloads for events other than pushes
+// return no references.
+func Parse(format string, b []byte) ([]string, error) {
+	var refs []string
+	var err error
+	switch format {
+	case Quay:
+		refs, err = parseQuay(b)
+	case Harbor:
+		refs, err = parseHarbor(b)
+	case DockerHub:
+		refs, err = parseDockerHub(b)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("webhook: %s payload: %w", format, err)
+	}
+	for _, ref := range refs {
+		if _, err := name.ParseReference(ref); err != nil {
+			return nil, fmt.Errorf("webhook: %s payload: %w", format, err)
+		}
+	}
+	return refs, nil
+}
+
+// ParseQuay handles Quay's "Push to Repository" notification.
+func parseQuay(b []byte) ([]string, error) {
+	var p struct {
+		DockerURL   string   `json:"docker_url"`
+		UpdatedTags []string `json:"updated_tags"`
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	if p.DockerURL == "" {
+		return nil, errors.New(`missing "docker_url"`)
+	}
+	refs := make([]string, 0, len(p.UpdatedTags))
+	for _, t := range p.UpdatedTags {
+		refs = append(refs, p.DockerURL+":"+t)
+	}
+	return refs, nil
+}
+
+// ParseHarbor handles Harbor's "PUSH_ARTIFACT" webhook event. Artifacts are
+// referred to by digest, so a push of several tags of the same artifact is
+// only indexed once.
+func parseHarbor(b []byte) ([]string, error) {
+	var p struct {
+		Type      string `json:"type"`
+		EventData struct {
+			Resources []struct {
+				Digest      string `json:"digest"`
+				ResourceURL string `json:"resource_url"`
+			} `json:"resources"`
+		} `json:"event_data"`
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	if p.Type != "PUSH_ARTIFACT" {
+		return nil, nil
+	}
+	var refs []string
+	seen := make(map[string]struct{})
+	for _, r := range p.EventData.Resources {
+		if r.ResourceURL == "" {
+			return nil, errors.New(`missing "resource_url"`)
+		}
+		ref := r.ResourceURL
+		if r.Digest != "" {
+			pr, err := name.ParseReference(r.ResourceURL)
+			if err != nil {
+				return nil, err
+			}
+			ref = pr.Context().Digest(r.Digest).String()
+		}
+		if _, ok := seen[ref]; ok {
+			continue
+		}
+		seen[ref] = struct{}{}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// ParseDockerHub handles Docker Hub's repository webhook.
+func parseDockerHub(b []byte) ([]string, error) {
+	var p struct {
+		PushData struct {
+			Tag string `json:"tag"`
+		} `json:"push_data"`
+		Repository struct {
+			RepoName string `json:"repo_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	if p.Repository.RepoName == "" {
+		return nil, errors.New(`missing "repository.repo_name"`)
+	}
+	if p.PushData.Tag == "" {
+		return nil, nil
+	}
+	return []string{"docker.io/" + p.Repository.RepoName + ":" + p.PushData.Tag}, nil
+}
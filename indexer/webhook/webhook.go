@@ -0,0 +1,187 @@
+// Package webhook indexes images pushed to a registry, as announced by the
+// registry's push webhooks.
+//
+// Webhook senders generally don't wait long for a response, so references are
+// queued and resolved and indexed in the background.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/registry"
+)
+
+// Errors returned by Submit.
+var (
+	ErrNotAllowed = errors.New("webhook: registry not allowed")
+	ErrQueueFull  = errors.New("webhook: queue full")
+)
+
+var (
+	eventsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "webhook",
+			Name:      "events_total",
+			Help:      "Total number of webhook events received, by payload format.",
+		},
+		[]string{"format"},
+	)
+	manifestsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "webhook",
+			Name:      "manifests_total",
+			Help:      "Total number of pushed images processed, by result.",
+		},
+		[]string{"result"},
+	)
+	queueGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "webhook",
+			Name:      "queued",
+			Help:      "Number of pushed images waiting to be indexed.",
+		},
+	)
+)
+
+// Indexer is the subset of indexer.Service needed to index pushed images.
+type Indexer interface {
+	Index(context.Context, *claircore.Manifest) (*claircore.IndexReport, error)
+}
+
+// Options configures an Ingester.
+type Options struct {
+	// Registries, if not empty, are the only registry hosts images are
+	// accepted from.
+	Registries []string
+	// Concurrency is the number of images resolved and indexed at once.
+	Concurrency int
+	// QueueSize is the number of images that may wait to be indexed.
+	QueueSize int
+	// Transport is used for registry requests. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+// Ingester resolves and indexes pushed images.
+type Ingester struct {
+	srv     Indexer
+	allow   map[string]struct{}
+	queue   chan string
+	resolve func(context.Context, string) (*claircore.Manifest, error)
+	wg      sync.WaitGroup
+}
+
+// New returns an Ingester indexing images with "srv". Its workers stop when
+// "ctx" is canceled.
+func New(ctx context.Context, srv Indexer, opts *Options) *Ingester {
+	i := &Ingester{
+		srv:   srv,
+		queue: make(chan string, opts.QueueSize),
+		resolve: func(ctx context.Context, ref string) (*claircore.Manifest, error) {
+			return registry.Resolve(ctx, opts.Transport, ref)
+		},
+	}
+	if len(opts.Registries) != 0 {
+		i.allow = make(map[string]struct{}, len(opts.Registries))
+		for _, r := range opts.Registries {
+			i.allow[r] = struct{}{}
+		}
+	}
+	n := opts.Concurrency
+	if n < 1 {
+		n = 1
+	}
+	i.start(ctx, n)
+	return i
+}
+
+func (i *Ingester) start(ctx context.Context, n int) {
+	ctx = zlog.ContextWithValues(ctx, "component", "indexer/webhook/Ingester.worker")
+	for w := 0; w < n; w++ {
+		i.wg.Add(1)
+		go func() {
+			defer i.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case ref := <-i.queue:
+					queueGauge.Dec()
+					i.index(ctx, ref)
+				}
+			}
+		}()
+	}
+}
+
+// Index resolves and indexes a single image.
+func (i *Ingester) index(ctx context.Context, ref string) {
+	ctx = zlog.ContextWithValues(ctx, "ref", ref)
+	m, err := i.resolve(ctx, ref)
+	if err != nil {
+		manifestsCounter.WithLabelValues("resolve_error").Inc()
+		zlog.Warn(ctx).Err(err).Msg("unable to resolve pushed image")
+		return
+	}
+	ctx = zlog.ContextWithValues(ctx, "manifest", m.Hash.String())
+	if _, err := i.srv.Index(ctx, m); err != nil {
+		manifestsCounter.WithLabelValues("index_error").Inc()
+		zlog.Warn(ctx).Err(err).Msg("unable to index pushed image")
+		return
+	}
+	manifestsCounter.WithLabelValues("indexed").Inc()
+	zlog.Info(ctx).Msg("indexed pushed image")
+}
+
+// Submit queues the references parsed from a webhook payload in format
+// "format" for indexing.
+//
+// ErrNotAllowed is returned, and nothing queued, if any reference names a
+// registry that isn't allowed. ErrQueueFull is returned if there isn't room
+// for every reference.
+func (i *Ingester) Submit(format string, refs []string) error {
+	eventsCounter.WithLabelValues(format).Inc()
+	if i.allow != nil {
+		for _, ref := range refs {
+			host, err := registry.Registry(ref)
+			if err != nil {
+				return err
+			}
+			if _, ok := i.allow[host]; !ok {
+				return fmt.Errorf("%w: %q", ErrNotAllowed, host)
+			}
+		}
+	}
+	if len(refs) > cap(i.queue)-len(i.queue) {
+		manifestsCounter.WithLabelValues("dropped").Add(float64(len(refs)))
+		return ErrQueueFull
+	}
+	for n, ref := range refs {
+		select {
+		case i.queue <- ref:
+			queueGauge.Inc()
+		default:
+			// Lost a race with another Submit call.
+			manifestsCounter.WithLabelValues("dropped").Add(float64(len(refs) - n))
+			return ErrQueueFull
+		}
+	}
+	return nil
+}
+
+// Wait blocks until the Ingester's workers have stopped.
+func (i *Ingester) Wait() {
+	i.wg.Wait()
+}
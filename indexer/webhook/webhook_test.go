@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Format string
+		In     string
+		Want   []string
+		Err    bool
+	}{
+		{
+			Name:   "Quay",
+			Format: Quay,
+			In:     `{"repository":"org/repo","namespace":"org","name":"repo","docker_url":"quay.io/org/repo","homepage":"https://quay.io/repository/org/repo","updated_tags":["latest","v1"]}`,
+			Want:   []string{"quay.io/org/repo:latest", "quay.io/org/repo:v1"},
+		},
+		{
+			Name:   "QuayMissingURL",
+			Format: Quay,
+			In:     `{"updated_tags":["latest"]}`,
+			Err:    true,
+		},
+		{
+			Name:   "Harbor",
+			Format: Harbor,
+			In: `{"type":"PUSH_ARTIFACT","occur_at":1680000000,"operator":"admin","event_data":{"resources":[` +
+				`{"digest":"sha256:0000000000000000000000000000000000000000000000000000000000000000","tag":"latest","resource_url":"harbor.example.com/library/repo:latest"},` +
+				`{"digest":"sha256:0000000000000000000000000000000000000000000000000000000000000000","tag":"v1","resource_url":"harbor.example.com/library/repo:v1"}` +
+				`],"repository":{"name":"repo","namespace":"library","repo_full_name":"library/repo","repo_type":"private"}}}`,
+			Want: []string{"harbor.example.com/library/repo@sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+		},
+		{
+			Name:   "HarborDelete",
+			Format: Harbor,
+			In:     `{"type":"DELETE_ARTIFACT","event_data":{"resources":[{"resource_url":"harbor.example.com/library/repo:latest"}]}}`,
+		},
+		{
+			Name:   "DockerHub",
+			Format: DockerHub,
+			In:     `{"callback_url":"https://registry.hub.docker.com/u/org/repo/hook/1/","push_data":{"pushed_at":1680000000,"pusher":"someone","tag":"latest"},"repository":{"name":"repo","namespace":"org","repo_name":"org/repo"}}`,
+			Want:   []string{"docker.io/org/repo:latest"},
+		},
+		{
+			Name:   "Garbage",
+			Format: DockerHub,
+			In:     `[]`,
+			Err:    true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := Parse(tc.Format, []byte(tc.In))
+			if (err != nil) != tc.Err {
+				t.Fatalf("got: %v, want error: %v", err, tc.Err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+	if _, err := Parse("gitlab", []byte(`{}`)); !errors.Is(err, ErrFormat) {
+		t.Errorf("got: %v, want: %v", err, ErrFormat)
+	}
+}
+
+func TestIngester(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	indexed := make(chan claircore.Digest)
+	srv := &indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			indexed <- m.Hash
+			return &claircore.IndexReport{Hash: m.Hash}, nil
+		},
+	}
+	i := &Ingester{
+		srv:   srv,
+		allow: map[string]struct{}{"quay.io": {}},
+		queue: make(chan string, 1),
+		resolve: func(_ context.Context, ref string) (*claircore.Manifest, error) {
+			return &claircore.Manifest{Hash: claircore.MustParseDigest(digest)}, nil
+		},
+	}
+
+	if err := i.Submit(Quay, []string{"docker.io/org/repo:latest"}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("got: %v, want: %v", err, ErrNotAllowed)
+	}
+	if err := i.Submit(Quay, []string{"quay.io/org/repo:latest", "quay.io/org/repo:v1"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got: %v, want: %v", err, ErrQueueFull)
+	}
+	if err := i.Submit(Quay, []string{"quay.io/org/repo:latest"}); err != nil {
+		t.Fatal(err)
+	}
+	i.start(ctx, 1)
+	select {
+	case got := <-indexed:
+		if got.String() != digest {
+			t.Errorf("got: %v, want: %v", got, digest)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for index")
+	}
+	done()
+	i.Wait()
+}
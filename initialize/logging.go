@@ -3,38 +3,52 @@
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/quay/clair/config"
 	"github.com/quay/zlog"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/quay/clair/v4/internal/loglevel"
 )
 
 // Logging configures zlog according to the provided configuration.
+//
+// The configured level can be changed at runtime via the loglevel package.
 func Logging(ctx context.Context, cfg *config.Config) error {
-	l := zerolog.New(os.Stderr)
+	out, err := logOutput(cfg.LogOutput)
+	if err != nil {
+		return err
+	}
+	var lvl zerolog.Level
 	switch cfg.LogLevel {
 	case config.DebugColorLog:
-		// set logger to use ConsoleWriter for colorized output
-		l = l.Level(zerolog.DebugLevel).
-			Output(zerolog.ConsoleWriter{Out: os.Stderr})
+		lvl = zerolog.DebugLevel
+		// set logger to use ConsoleWriter for colorized output, if writing to
+		// a terminal-ish output
+		if cfg.LogOutput == nil {
+			out = zerolog.ConsoleWriter{Out: os.Stderr}
+		}
 	case config.DebugLog:
-		l = l.Level(zerolog.DebugLevel)
+		lvl = zerolog.DebugLevel
 	case config.InfoLog:
-		l = l.Level(zerolog.InfoLevel)
+		lvl = zerolog.InfoLevel
 	case config.WarnLog:
-		l = l.Level(zerolog.WarnLevel)
+		lvl = zerolog.WarnLevel
 	case config.ErrorLog:
-		l = l.Level(zerolog.ErrorLevel)
+		lvl = zerolog.ErrorLevel
 	case config.FatalLog:
-		l = l.Level(zerolog.FatalLevel)
+		lvl = zerolog.FatalLevel
 	case config.PanicLog:
-		l = l.Level(zerolog.PanicLevel)
+		lvl = zerolog.PanicLevel
 	default:
 		return fmt.Errorf("unknown log level: %v", cfg.LogLevel)
 	}
-	l = l.With().
+	loglevel.Set(lvl, nil)
+	l := zerolog.New(loglevel.Writer(out)).With().
 		Timestamp().
 		Logger()
 	zlog.Set(&l)
@@ -42,3 +56,28 @@ func Logging(ctx context.Context, cfg *config.Config) error {
 	zlog.Debug(ctx).Str("component", "initialize/Logging").Msg("logging initialized")
 	return nil
 }
+
+// LogOutput returns the Writer described by the configuration.
+func logOutput(cfg *config.LogOutput) (io.Writer, error) {
+	switch {
+	case cfg == nil:
+		return os.Stderr, nil
+	case cfg.File != nil:
+		f := cfg.File
+		return &lumberjack.Logger{
+			Filename:   f.Path,
+			MaxSize:    f.MaxSize,
+			MaxBackups: f.MaxBackups,
+			MaxAge:     f.MaxAge,
+			Compress:   f.Compress,
+		}, nil
+	case cfg.Syslog != nil:
+		w, err := syslogWriter(cfg.Syslog)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to syslog: %w", err)
+		}
+		return w, nil
+	default:
+		return os.Stderr, nil
+	}
+}
@@ -2,32 +2,83 @@
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/cookiejar"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
 	"github.com/quay/claircore/datastore/postgres"
 	"github.com/quay/claircore/enricher/cvss"
 	"github.com/quay/claircore/libindex"
 	"github.com/quay/claircore/libvuln"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/updates"
 	"github.com/quay/claircore/pkg/ctxlock"
+	"github.com/quay/claircore/rhel"
 	"github.com/quay/zlog"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/net/publicsuffix"
 	"gopkg.in/square/go-jose.v2/jwt"
 
 	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/health"
 	"github.com/quay/clair/v4/httptransport"
 	"github.com/quay/clair/v4/httptransport/client"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/artifact"
+	"github.com/quay/clair/v4/indexer/cache"
+	"github.com/quay/clair/v4/indexer/dedup"
+	"github.com/quay/clair/v4/indexer/ecosystem"
+	"github.com/quay/clair/v4/indexer/fetcher"
+	"github.com/quay/clair/v4/indexer/fileowners"
+	"github.com/quay/clair/v4/indexer/imageconfig"
+	"github.com/quay/clair/v4/indexer/labels"
+	"github.com/quay/clair/v4/indexer/layerwalk"
+	"github.com/quay/clair/v4/indexer/licenses"
+	indexermigrations "github.com/quay/clair/v4/indexer/migrations"
+	"github.com/quay/clair/v4/indexer/queue"
+	"github.com/quay/clair/v4/indexer/repocpe"
+	"github.com/quay/clair/v4/indexer/scratch"
+	"github.com/quay/clair/v4/indexer/secrets"
+	"github.com/quay/clair/v4/indexer/unpackaged"
+	indexerusage "github.com/quay/clair/v4/indexer/usage"
 	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/internal/leader"
+	"github.com/quay/clair/v4/internal/poolstats"
+	"github.com/quay/clair/v4/internal/querystats"
+	"github.com/quay/clair/v4/internal/standby"
 	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/advisory"
+	"github.com/quay/clair/v4/matcher/backfill"
+	"github.com/quay/clair/v4/matcher/budget"
+	"github.com/quay/clair/v4/matcher/changelog"
+	"github.com/quay/clair/v4/matcher/eol"
+	"github.com/quay/clair/v4/matcher/feed"
+	"github.com/quay/clair/v4/matcher/freshness"
+	"github.com/quay/clair/v4/matcher/gc"
+	"github.com/quay/clair/v4/matcher/lockfiles"
+	matchermigrations "github.com/quay/clair/v4/matcher/migrations"
+	"github.com/quay/clair/v4/matcher/policy"
+	"github.com/quay/clair/v4/matcher/subscription"
+	"github.com/quay/clair/v4/matcher/trend"
+	"github.com/quay/clair/v4/matcher/triage"
+	"github.com/quay/clair/v4/matcher/updatenotify"
+	"github.com/quay/clair/v4/matcher/updaterctl"
+	"github.com/quay/clair/v4/matcher/usage"
+	"github.com/quay/clair/v4/matcher/vulnstats"
+	"github.com/quay/clair/v4/matcher/vulnstore"
 	"github.com/quay/clair/v4/notifier"
 	notifierpg "github.com/quay/clair/v4/notifier/postgres"
 	"github.com/quay/clair/v4/notifier/service"
@@ -39,10 +90,15 @@
 	NotifierIssuer = `clair-notifier`
 )
 
-var (
-	intraserviceClaim = jwt.Claims{Issuer: httptransport.IntraserviceIssuer}
-	notifierClaim     = jwt.Claims{Issuer: NotifierIssuer}
-)
+var notifierClaim = jwt.Claims{Issuer: NotifierIssuer}
+
+// IntraserviceClaim returns the claims for requests to the service "aud".
+func intraserviceClaim(aud string) jwt.Claims {
+	return jwt.Claims{
+		Issuer:   httptransport.IntraserviceIssuer,
+		Audience: jwt.Audience{aud},
+	}
+}
 
 // Srv is a bundle of configured Services.
 //
@@ -65,15 +121,25 @@ func Services(ctx context.Context, cfg *config.Config) (*Srv, error) {
 	var err error
 	switch cfg.Mode {
 	case config.ComboMode:
+		// A standby serves requests but holds background work until it's
+		// promoted.
+		var gate *standby.Gate
+		if cfg.Standby != nil {
+			gate = standby.New(ctx)
+		}
 		srv.Indexer, err = localIndexer(ctx, cfg)
 		if err != nil {
 			return nil, err
 		}
-		srv.Matcher, err = localMatcher(ctx, cfg)
+		srv.Indexer, err = cachedIndexer(ctx, cfg, srv.Indexer)
 		if err != nil {
 			return nil, err
 		}
-		srv.Notifier, err = localNotifier(ctx, cfg, srv.Indexer, srv.Matcher)
+		srv.Matcher, err = localMatcher(ctx, cfg, srv.Indexer, gate)
+		if err != nil {
+			return nil, err
+		}
+		srv.Notifier, err = localNotifier(ctx, cfg, srv.Indexer, srv.Matcher, gate)
 		if err != nil {
 			return nil, err
 		}
@@ -82,25 +148,57 @@ func Services(ctx context.Context, cfg *config.Config) (*Srv, error) {
 		if err != nil {
 			return nil, err
 		}
+		srv.Indexer, err = cachedIndexer(ctx, cfg, srv.Indexer)
+		if err != nil {
+			return nil, err
+		}
 	case config.MatcherMode:
-		srv.Matcher, err = localMatcher(ctx, cfg)
+		scopes := []string{httptransport.ScopeIndexReport, httptransport.ScopeManifestLabels}
+		if cfg.Matcher.Subscriptions != nil {
+			// Subscriptions may re-submit manifests.
+			scopes = append(scopes, httptransport.ScopeIndexManifest)
+		}
+		if cfg.Matcher.Backfill != nil {
+			// Backfills enumerate the indexed manifests.
+			scopes = append(scopes, httptransport.ScopeManifestList)
+		}
+		srv.Indexer, err = remoteIndexer(ctx, cfg, cfg.Matcher.IndexerAddr, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		srv.Indexer, err = cachedIndexer(ctx, cfg, srv.Indexer)
 		if err != nil {
 			return nil, err
 		}
-		srv.Indexer, err = remoteIndexer(ctx, cfg, cfg.Matcher.IndexerAddr)
+		if cfg.Matcher.Vulnstore != nil {
+			srv.Matcher, err = vulnstoreMatcher(ctx, cfg)
+		} else {
+			srv.Matcher, err = localMatcher(ctx, cfg, srv.Indexer, nil)
+		}
 		if err != nil {
 			return nil, err
 		}
 	case config.NotifierMode:
-		srv.Indexer, err = remoteIndexer(ctx, cfg, cfg.Notifier.IndexerAddr)
+		iscopes := []string{httptransport.ScopeAffectedManifest, httptransport.ScopeManifestLabels}
+		mscopes := []string{httptransport.ScopeUpdateOperation, httptransport.ScopeUpdateDiff}
+		if cfg.Notifier.DependencyTrack != nil {
+			// Publishing to Dependency-Track builds vulnerability reports.
+			iscopes = append(iscopes, httptransport.ScopeIndexReport)
+			mscopes = append(mscopes, httptransport.ScopeVulnerabilityReport)
+		}
+		srv.Indexer, err = remoteIndexer(ctx, cfg, cfg.Notifier.IndexerAddr, iscopes...)
 		if err != nil {
 			return nil, err
 		}
-		srv.Matcher, err = remoteMatcher(ctx, cfg, cfg.Notifier.MatcherAddr)
+		srv.Indexer, err = cachedIndexer(ctx, cfg, srv.Indexer)
 		if err != nil {
 			return nil, err
 		}
-		srv.Notifier, err = localNotifier(ctx, cfg, srv.Indexer, srv.Matcher)
+		srv.Matcher, err = remoteMatcher(ctx, cfg, cfg.Notifier.MatcherAddr, mscopes...)
+		if err != nil {
+			return nil, err
+		}
+		srv.Notifier, err = localNotifier(ctx, cfg, srv.Indexer, srv.Matcher, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -114,20 +212,114 @@ func Services(ctx context.Context, cfg *config.Config) (*Srv, error) {
 // BUG(hank) The various resources (database connections, lock services)
 // constructed in some internal functions are not properly cleaned up.
 
+// Connect returns a pool for "connString". It's equivalent to
+// postgres.Connect, with query instrumentation labeled "name" added if
+// configured. In that case, claircore's pool metrics aren't registered; the
+// callers register Clair's own.
+func connect(ctx context.Context, cfg *config.Config, connString, appName, name string) (*pgxpool.Pool, error) {
+	ql := queryLogger(cfg, name)
+	tp := outboundTLS(cfg).Database
+	if ql == nil && tp == nil {
+		return postgres.Connect(ctx, connString, appName)
+	}
+	poolcfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ConnString: %v", err)
+	}
+	if err := databaseTLS(poolcfg, tp); err != nil {
+		return nil, err
+	}
+	poolcfg.MaxConns = 30
+	const appnameKey = `application_name`
+	if _, ok := poolcfg.ConnConfig.RuntimeParams[appnameKey]; !ok {
+		poolcfg.ConnConfig.RuntimeParams[appnameKey] = appName
+	}
+	if ql != nil {
+		ql.Configure(poolcfg)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, poolcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ConnPool: %v", err)
+	}
+	if ql != nil {
+		ql.Attach(pool)
+	}
+	return pool, nil
+}
+
+// OutboundTLS returns the configured outbound TLS policies. The returned value
+// is never nil; absent policies are nil.
+func outboundTLS(cfg *config.Config) *config.OutboundTLS {
+	if cfg.OutboundTLS == nil {
+		return &config.OutboundTLS{}
+	}
+	return cfg.OutboundTLS
+}
+
+// ClientTransport returns the configured client transport settings. The
+// returned value is never nil; absent settings are nil.
+func clientTransport(cfg *config.Config) *config.ClientTransport {
+	if cfg.ClientTransport == nil {
+		return &config.ClientTransport{}
+	}
+	return cfg.ClientTransport
+}
+
+// DatabaseTLS applies "tp" to every TLS config the connection string produced.
+// Connections the connection string doesn't use TLS for are unaffected.
+func databaseTLS(poolcfg *pgxpool.Config, tp *config.TLSPolicy) error {
+	cc := poolcfg.ConnConfig
+	tcs := []*tls.Config{cc.TLSConfig}
+	for _, fb := range cc.Fallbacks {
+		tcs = append(tcs, fb.TLSConfig)
+	}
+	for _, tc := range tcs {
+		if tc == nil {
+			continue
+		}
+		if err := tp.Apply(tc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryLogger returns the instrumentation for the pool "name", or nil if
+// it's not configured.
+func queryLogger(cfg *config.Config, name string) *querystats.Logger {
+	qs := cfg.QueryStats
+	if qs == nil {
+		return nil
+	}
+	return querystats.New(name, &querystats.Options{
+		ExplainThreshold: time.Duration(qs.ExplainThreshold),
+		ExplainCooldown:  time.Duration(qs.ExplainCooldown),
+	})
+}
+
 func localIndexer(ctx context.Context, cfg *config.Config) (indexer.Service, error) {
 	const msg = "failed to initialize indexer: "
 	mkErr := func(err error) *clairerror.ErrNotInitialized {
 		return &clairerror.ErrNotInitialized{msg + err.Error()}
 	}
 
-	pool, err := postgres.Connect(ctx, cfg.Indexer.ConnString, "libindex")
+	pool, err := connect(ctx, cfg, cfg.Indexer.ConnString, "libindex", "indexer")
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	health.Register("indexer/database", pool.Ping)
+	if err := poolstats.Register("indexer", pool); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to register pool metrics")
+	}
 	store, err := postgres.InitPostgresIndexerStore(ctx, pool, cfg.Indexer.Migrations)
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	if cfg.Indexer.Migrations {
+		if err := indexermigrations.Init(ctx, pool.Config().ConnConfig); err != nil {
+			return nil, mkErr(err)
+		}
+	}
 	locker, err := ctxlock.New(ctx, pool)
 	if err != nil {
 		return nil, mkErr(err)
@@ -139,6 +331,21 @@ func localIndexer(ctx context.Context, cfg *config.Config) (indexer.Service, err
 		ScanLockRetry:        time.Duration(cfg.Indexer.ScanLockRetry) * time.Second,
 		LayerScanConcurrency: cfg.Indexer.LayerScanConcurrency,
 	}
+	if sc := &cfg.Indexer.Scanner; len(sc.Enable) != 0 || len(sc.Disable) != 0 || len(ecosystem.Excluded(sc)) != 0 || sc.Runtimes || len(sc.Lockfiles) != 0 {
+		opts.Ecosystems, err = ecosystem.Configured(ctx, sc)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		ss, err := ecosystem.Scanners(ctx, opts.Ecosystems)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		names := make([]string, len(ss))
+		for i, sc := range ss {
+			names[i] = sc.Kind + "/" + sc.Name
+		}
+		zlog.Info(ctx).Strs("scanners", names).Msg("using selected scanners")
+	}
 	if cfg.Indexer.Scanner.Package != nil {
 		opts.ScannerConfig.Package = make(map[string]func(interface{}) error, len(cfg.Indexer.Scanner.Package))
 		for name, node := range cfg.Indexer.Scanner.Package {
@@ -178,34 +385,367 @@ func localIndexer(ctx context.Context, cfg *config.Config) (indexer.Service, err
 			}
 		}
 	}
-	c, err := httputil.NewClient(ctx, cfg.Indexer.Airgap)
+	c, err := httputil.NewOutboundClient(ctx, cfg.Indexer.Airgap, cfg.Proxy, outboundTLS(cfg).Registry)
 	if err != nil {
 		return nil, mkErr(err)
 	}
 
-	opts.FetchArena = libindex.NewRemoteFetchArena(c, os.TempDir())
+	// Layer fetches get their own copy of the client so the limits don't
+	// apply to any requests the scanners themselves make.
+	fc := *c
+	fc.Transport, err = httputil.Faults(ctx, c.Transport, httputil.FaultLayers)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	// Resume below the metrics and limits, so they see one stitched-together
+	// body per layer.
+	fc.Transport = httputil.Resumer(fc.Transport, cfg.Indexer.LayerFetchRetries)
+	// Plugins go inside the metrics and limits, so layers they supply are
+	// counted and limited like any other.
+	srcs, err := layerFetchers(ctx, cfg)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	fc.Transport = fetcher.Transport(fc.Transport, srcs...)
+	fc.Transport = httputil.FetchMetrics(fc.Transport)
+	fc.Transport = httputil.SizeLimiter(fc.Transport, cfg.Indexer.Limits.MaxLayerSize)
+	fc.Transport = httputil.FetchLimiter(fc.Transport,
+		cfg.Indexer.LayerFetchConcurrency, cfg.Indexer.LayerFetchBandwidth)
+	// Per-host limits go outside the global ones, so a busy host doesn't
+	// hold global slots while waiting.
+	fc.Transport = httputil.HostLimiter(fc.Transport, cfg.Indexer.LayerFetchHosts)
+	// Tmp is where the optional indexers below create their temporary files.
+	// The empty string means the system's temporary directory.
+	var tmp string
+	if sc := cfg.Indexer.Scratch; sc != nil {
+		dir, err := scratch.Open(ctx, sc.Dir)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		tmp = dir.Path()
+		opts.FetchArena = scratch.NewArena(&fc, dir, sc.ManifestQuota)
+	} else {
+		opts.FetchArena = libindex.NewRemoteFetchArena(&fc, os.TempDir())
+	}
+	if rc := cfg.Indexer.RepoCPE; rc != nil {
+		// The scanner's own configuration says where the upstream mapping
+		// is, so decode it the same way the scanner will.
+		var sc rhel.RepositoryScannerConfig
+		prev := opts.ScannerConfig.Repo[repocpe.ScannerName]
+		if prev != nil {
+			if err := prev(&sc); err != nil {
+				return nil, mkErr(fmt.Errorf("%s configuration: %w", repocpe.ScannerName, err))
+			}
+		}
+		ov, err := repocpe.NewOverlay(c.Transport, repocpe.Upstream(&sc), rc.Sources, rc.Overrides)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		c.Transport = ov
+		if opts.ScannerConfig.Repo == nil {
+			opts.ScannerConfig.Repo = make(map[string]func(interface{}) error)
+		}
+		opts.ScannerConfig.Repo[repocpe.ScannerName] = repocpe.Configure(prev)
+		zlog.Info(ctx).
+			Str("upstream", repocpe.Upstream(&sc)).
+			Int("sources", len(rc.Sources)).
+			Int("overrides", len(rc.Overrides)).
+			Msg("overlaying repository-to-CPE mapping")
+	}
+	if u := cfg.Indexer.EgressProbe; u != "" {
+		health.Register("indexer/egress", func(ctx context.Context) error {
+			req, err := httputil.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+			if err != nil {
+				return err
+			}
+			res, err := c.Do(req)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			return nil
+		})
+	}
 
-	s, err := libindex.New(ctx, &opts, c)
+	li, err := libindex.New(ctx, &opts, c)
 	if err != nil {
 		return nil, mkErr(err)
 	}
-	return s, nil
+	var s indexer.Service = li
+	if cfg.Indexer.Dedup {
+		zlog.Info(ctx).Msg("deduplicating index reports")
+		s = dedup.NewIndexer(s, dedup.NewStore(pool))
+	}
+	// Examiners of layer contents share a single walk of each layer.
+	var xs []layerwalk.Examiner
+	var sr indexer.SecretReporter
+	if sc := cfg.Indexer.Secrets; sc != nil {
+		zlog.Info(ctx).Msg("looking for embedded credentials")
+		sx := secrets.NewExaminer(secrets.NewStore(pool), &secrets.Options{
+			MaxFileSize: sc.MaxFileSize,
+			IgnorePaths: sc.IgnorePaths,
+		})
+		xs, sr = append(xs, sx), sx
+	}
+	var lr indexer.LicenseReporter
+	if cfg.Indexer.Licenses != nil {
+		zlog.Info(ctx).Msg("recording declared licenses")
+		lx := licenses.NewExaminer(licenses.NewStore(pool))
+		xs, lr = append(xs, lx), lx
+	}
+	var fr indexer.FileOwnerReporter
+	if cfg.Indexer.FileOwners {
+		zlog.Info(ctx).Msg("recording package file lists")
+		fx := fileowners.NewExaminer(fileowners.NewStore(pool))
+		xs, fr = append(xs, fx), fx
+	}
+	var ur indexer.UnpackagedReporter
+	if cfg.Indexer.Unpackaged {
+		zlog.Info(ctx).Msg("looking for unpackaged contents")
+		ux := unpackaged.NewExaminer(unpackaged.NewStore(pool))
+		xs, ur = append(xs, ux), ux
+	}
+	if len(xs) != 0 {
+		s = layerwalk.NewIndexer(s, &fc, tmp, xs...)
+	}
+	if a := cfg.Indexer.Artifacts; a != nil {
+		ss, err := artifact.Scanners(a.Scanners)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		names := make([]string, len(ss))
+		for i, sc := range ss {
+			names[i] = sc.Name()
+		}
+		zlog.Info(ctx).Strs("scanners", names).Msg("indexing artifacts")
+		s = artifact.NewIndexer(s, artifact.NewStore(pool), &fc, ss)
+	}
+	if q := cfg.Indexer.Queue; q != nil {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		owner := host + "/" + uuid.New().String()
+		zlog.Info(ctx).
+			Str("owner", owner).
+			Msg("using shared indexer queue")
+		s = queue.NewIndexer(s, queue.New(pool), owner,
+			time.Duration(q.Lease), time.Duration(q.PollInterval))
+	}
+	s = indexer.Instrument(s)
+	var cr indexer.ImageConfigReporter
+	if cfg.Indexer.ImageConfig {
+		zlog.Info(ctx).Msg("recording image configs")
+		cx := imageconfig.NewIndexer(s, imageconfig.NewStore(pool), &fc)
+		s, cr = cx, cx
+	}
+	var ls *labels.Store
+	var l indexer.Labeler
+	if cfg.Indexer.Labels {
+		zlog.Info(ctx).Msg("storing manifest labels")
+		ls = labels.NewStore(pool)
+		lx := labels.NewIndexer(s, ls)
+		s, l = lx, lx
+	}
+	return withOptional(s, l, indexerusage.New(pool, ls), sr, lr, fr, cr, ur), nil
+}
+
+// Inventory is implemented by indexers that can both count and list the
+// manifests they've indexed.
+type inventory interface {
+	indexer.UsageReporter
+	indexer.ManifestLister
+}
+
+// WithOptional adds the optional indexer interfaces to "svc", forwarding to
+// the non-nil delegates.
+//
+// The wrappers in the indexer packages only implement indexer.Service, so
+// this is needed to keep the optional interfaces available after wrapping.
+func withOptional(svc indexer.Service, l indexer.Labeler, u inventory, sr indexer.SecretReporter, lr indexer.LicenseReporter, fr indexer.FileOwnerReporter, cr indexer.ImageConfigReporter, ur indexer.UnpackagedReporter) indexer.Service {
+	return &optionalIndexer{
+		Service:     svc,
+		labeler:     l,
+		inventory:   u,
+		secrets:     sr,
+		licenses:    lr,
+		fileOwners:  fr,
+		imageConfig: cr,
+		unpackaged:  ur,
+	}
+}
+
+// OptionalIndexer implements every optional indexer interface. Methods
+// without a delegate report indexer.ErrUnsupported.
+type optionalIndexer struct {
+	indexer.Service
+	labeler     indexer.Labeler
+	inventory   inventory
+	secrets     indexer.SecretReporter
+	licenses    indexer.LicenseReporter
+	fileOwners  indexer.FileOwnerReporter
+	imageConfig indexer.ImageConfigReporter
+	unpackaged  indexer.UnpackagedReporter
+}
+
+var (
+	_ indexer.Labeler             = (*optionalIndexer)(nil)
+	_ inventory                   = (*optionalIndexer)(nil)
+	_ indexer.SecretReporter      = (*optionalIndexer)(nil)
+	_ indexer.LicenseReporter     = (*optionalIndexer)(nil)
+	_ indexer.FileOwnerReporter   = (*optionalIndexer)(nil)
+	_ indexer.ImageConfigReporter = (*optionalIndexer)(nil)
+	_ indexer.UnpackagedReporter  = (*optionalIndexer)(nil)
+)
+
+// Labels implements indexer.Labeler.
+func (o *optionalIndexer) Labels(ctx context.Context, ds ...claircore.Digest) (map[string]map[string]string, error) {
+	if o.labeler == nil {
+		return nil, indexer.ErrUnsupported
+	}
+	return o.labeler.Labels(ctx, ds...)
+}
+
+// Usage implements indexer.UsageReporter.
+func (o *optionalIndexer) Usage(ctx context.Context, tenantLabel string) (*indexer.Usage, error) {
+	if o.inventory == nil {
+		return nil, indexer.ErrUnsupported
+	}
+	return o.inventory.Usage(ctx, tenantLabel)
+}
+
+// Manifests implements indexer.ManifestLister.
+func (o *optionalIndexer) Manifests(ctx context.Context, after string, limit int) ([]claircore.Digest, error) {
+	if o.inventory == nil {
+		return nil, indexer.ErrUnsupported
+	}
+	return o.inventory.Manifests(ctx, after, limit)
+}
+
+// Secrets implements indexer.SecretReporter.
+func (o *optionalIndexer) Secrets(ctx context.Context, d claircore.Digest) ([]indexer.Secret, bool, error) {
+	if o.secrets == nil {
+		return nil, false, indexer.ErrUnsupported
+	}
+	return o.secrets.Secrets(ctx, d)
+}
+
+// Licenses implements indexer.LicenseReporter.
+func (o *optionalIndexer) Licenses(ctx context.Context, r *claircore.IndexReport) (map[string]string, error) {
+	if o.licenses == nil {
+		return nil, indexer.ErrUnsupported
+	}
+	return o.licenses.Licenses(ctx, r)
+}
+
+// FileOwners implements indexer.FileOwnerReporter.
+func (o *optionalIndexer) FileOwners(ctx context.Context, r *claircore.IndexReport, p string) ([]indexer.FileOwner, error) {
+	if o.fileOwners == nil {
+		return nil, indexer.ErrUnsupported
+	}
+	return o.fileOwners.FileOwners(ctx, r, p)
+}
+
+// ImageConfig implements indexer.ImageConfigReporter.
+func (o *optionalIndexer) ImageConfig(ctx context.Context, d claircore.Digest) (*indexer.ImageConfig, bool, error) {
+	if o.imageConfig == nil {
+		return nil, false, indexer.ErrUnsupported
+	}
+	return o.imageConfig.ImageConfig(ctx, d)
 }
 
-func remoteIndexer(ctx context.Context, cfg *config.Config, addr string) (indexer.Service, error) {
+// Unpackaged implements indexer.UnpackagedReporter.
+func (o *optionalIndexer) Unpackaged(ctx context.Context, d claircore.Digest) (*indexer.Unpackaged, bool, error) {
+	if o.unpackaged == nil {
+		return nil, false, indexer.ErrUnsupported
+	}
+	return o.unpackaged.Unpackaged(ctx, d)
+}
+
+// CachedIndexer wraps "svc" with the shared cache, if configured.
+func cachedIndexer(ctx context.Context, cfg *config.Config, svc indexer.Service) (indexer.Service, error) {
+	cc := cfg.Cache
+	if cc == nil {
+		return svc, nil
+	}
+	opts, err := redis.ParseURL(cc.URL)
+	if err != nil {
+		return nil, &clairerror.ErrNotInitialized{Msg: "failed to initialize cache: " + err.Error()}
+	}
+	rc := redis.NewClient(opts)
+	// Not registered as a health check: the cache is an optimization and
+	// lookups fall back to the indexer when it's unavailable.
+	zlog.Info(ctx).
+		Str("addr", opts.Addr).
+		Msg("using shared cache")
+	var out indexer.Service = cache.NewIndexer(svc, cache.Redis(rc), &cache.Options{
+		KeyPrefix:            cc.KeyPrefix,
+		IndexReportTTL:       time.Duration(cc.IndexReportTTL),
+		AffectedManifestsTTL: time.Duration(cc.AffectedManifestsTTL),
+	})
+	// Labels, usage, manifest listing, secrets, licenses, file owners, image
+	// configs, and unpackaged contents aren't cached, but keep them
+	// available.
+	if o, ok := svc.(*optionalIndexer); ok {
+		w := *o
+		w.Service = out
+		out = &w
+	}
+	return out, nil
+}
+
+// LayerFetchers constructs the configured layer fetcher plugins, in name order.
+func layerFetchers(ctx context.Context, cfg *config.Config) ([]*fetcher.Source, error) {
+	names := make([]string, 0, len(cfg.Indexer.LayerFetchers))
+	for n := range cfg.Indexer.LayerFetchers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	srcs := make([]*fetcher.Source, 0, len(names))
+	for _, n := range names {
+		node := cfg.Indexer.LayerFetchers[n]
+		s, err := fetcher.New(ctx, n, func(v interface{}) error {
+			b, err := json.Marshal(node)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(b, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, s)
+	}
+	if len(names) != 0 {
+		zlog.Info(ctx).Strs("fetchers", names).Msg("using layer fetcher plugins")
+	}
+	return srcs, nil
+}
+
+// RemoteIndexer returns a client for the indexer at "addr", using tokens
+// limited to "scopes" if authentication is configured.
+func remoteIndexer(ctx context.Context, cfg *config.Config, addr string, scopes ...string) (indexer.Service, error) {
 	const msg = "failed to initialize indexer client: "
 	mkErr := func(err error) *clairerror.ErrNotInitialized {
 		return &clairerror.ErrNotInitialized{msg + err.Error()}
 	}
-	rc, err := remoteClient(ctx, cfg, intraserviceClaim, addr)
+	rc, err := remoteClient(ctx, cfg, intraserviceClaim(httptransport.IndexerAudience), addr, scopes)
 	if err != nil {
 		return nil, mkErr(err)
 	}
 	return rc, nil
 }
 
-func remoteClient(ctx context.Context, cfg *config.Config, claim jwt.Claims, addr string) (*client.HTTP, error) {
-	c, err := httputil.NewClient(ctx, false) // ???
+func remoteClient(ctx context.Context, cfg *config.Config, claim jwt.Claims, addr string, scopes []string) (*client.HTTP, error) {
+	var c *http.Client
+	var err error
+	if p := strings.TrimPrefix(addr, config.UnixPrefix); p != addr {
+		// The host is only used for display; every request goes to the socket.
+		c, err = httputil.NewUnixClient(ctx, p, clientTransport(cfg).Intraservice)
+		addr = "http://localhost/"
+	} else {
+		c, err = httputil.NewServiceClient(ctx, clientTransport(cfg).Intraservice)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -215,12 +755,15 @@ func remoteClient(ctx context.Context, cfg *config.Config, claim jwt.Claims, add
 		if err != nil {
 			return nil, err
 		}
+		s.Scope(scopes...)
 		opts = append(opts, client.WithSigner(s))
 	}
 	return client.NewHTTP(ctx, opts...)
 }
 
-func localMatcher(ctx context.Context, cfg *config.Config) (matcher.Service, error) {
+// LocalMatcher constructs a matcher backed by the configured database. If
+// "gate" is not nil, background work waits for it to be promoted.
+func localMatcher(ctx context.Context, cfg *config.Config, i indexer.Service, gate *standby.Gate) (matcher.Service, error) {
 	const msg = "failed to initialize matcher: "
 	mkErr := func(err error) *clairerror.ErrNotInitialized {
 		return &clairerror.ErrNotInitialized{
@@ -232,15 +775,38 @@ func localMatcher(ctx context.Context, cfg *config.Config) (matcher.Service, err
 	// Some servers return weak validators when the Content-Encoding is not
 	// "identity". Setting this prevents automatically negotiating up to "gzip".
 	tr.DisableCompression = true
+	proxy, err := httputil.ProxyFunc(cfg.Proxy)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	tr.Proxy = proxy
+	if tr.TLSClientConfig, err = outboundTLS(cfg).Updaters.Config(); err != nil {
+		return nil, mkErr(err)
+	}
 	jar, err := cookiejar.New(&cookiejar.Options{
 		PublicSuffixList: publicsuffix.List,
 	})
 	if err != nil {
 		return nil, err
 	}
+	faulty, err := httputil.Faults(ctx, tr, httputil.FaultUpdaters)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	budgets, err := budget.New(cfg.Updaters.Budgets)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	// Budgets are charged inside signature verification, so a download
+	// resumed from an earlier run is verified as a whole.
+	metered := budgets.Transport(httputil.Mirror(httputil.RateLimiter(faulty), cfg.Updaters.Mirrors))
+	verified, err := httputil.Signatures(metered, cfg.Updaters.Signatures)
+	if err != nil {
+		return nil, mkErr(err)
+	}
 	cl := &http.Client{
 		Jar:       jar,
-		Transport: httputil.RateLimiter(tr),
+		Transport: otelhttp.NewTransport(verified),
 	}
 	updaterConfigs := make(map[string]driver.ConfigUnmarshaler)
 	for name, node := range cfg.Updaters.Config {
@@ -264,52 +830,550 @@ func localMatcher(ctx context.Context, cfg *config.Config) (matcher.Service, err
 			return json.Unmarshal(b, v)
 		}
 	}
-	pool, err := postgres.Connect(ctx, cfg.Matcher.ConnString, "libvuln")
+	pool, err := connect(ctx, cfg, cfg.Matcher.ConnString, "libvuln", "matcher")
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	health.Register("matcher/database", pool.Ping)
+	if err := poolstats.Register("matcher", pool); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to register pool metrics")
+	}
 	store, err := postgres.InitPostgresMatcherStore(ctx, pool, cfg.Matcher.Migrations)
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	// Clair's own tables need to exist before updates can run, and go after
+	// claircore's because the update notification trigger is on one of them.
+	if cfg.Matcher.Migrations {
+		if err := matchermigrations.Init(ctx, pool.Config().ConnConfig); err != nil {
+			return nil, mkErr(err)
+		}
+	}
 	locker, err := ctxlock.New(ctx, pool)
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	ctl := updaterctl.NewStore(pool)
+	advisories := advisory.NewStore(pool, store)
+	go advisories.Run(ctx, advisoryInterval)
+	feedUpdaters, feedMatchers, err := feed.Drivers(cfg.Updaters.Feeds, cl)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	feedMatchers = append(feedMatchers, advisories.Matcher(), &lockfiles.Matcher{})
+	if e := cfg.Updaters.RuntimeEOL; e != nil {
+		u, m, err := eol.New(e, cl)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		feedUpdaters = append(feedUpdaters, u)
+		feedMatchers = append(feedMatchers, m)
+	}
+	if cfg.Matcher.DisableUpdaters {
+		feedUpdaters = nil
+	}
 
 	s, err := libvuln.New(ctx, &libvuln.Options{
 		Store:           store,
-		Locker:          locker,
+		Locker:          budgets.Locker(ctl.Locker(locker)),
 		UpdaterSets:     cfg.Updaters.Sets,
 		UpdateInterval:  time.Duration(cfg.Matcher.Period),
 		UpdaterConfigs:  updaterConfigs,
 		UpdateRetention: cfg.Matcher.UpdateRetention,
+		Updaters:        feedUpdaters,
 		MatcherNames:    cfg.Matchers.Names,
 		MatcherConfigs:  matcherConfigs,
+		Matchers:        feedMatchers,
 		Client:          cl,
 		Enrichers: []driver.Enricher{
 			&cvss.Enricher{},
 		},
+		DisableBackgroundUpdates: cfg.Matcher.LeaderElection || gate != nil,
 	})
 	if err != nil {
 		return nil, mkErr(err)
 	}
-	return s, nil
+	period := time.Duration(cfg.Matcher.Period)
+	switch {
+	case cfg.Matcher.LeaderElection:
+		gate.Go(ctx, func(ctx context.Context) {
+			leader.Run(ctx, locker, "matcher-updaters", leader.Periodic(period, s.FetchUpdates))
+		})
+	case gate != nil:
+		gate.Go(ctx, leader.Periodic(period, s.FetchUpdates))
+	}
+	var svc matcher.Service = matcher.Limit(s, cfg.Matcher.ScanConcurrency)
+	var trends *trend.Store
+	if tc := cfg.Matcher.Trends; tc != nil {
+		trends = trend.NewStore(pool)
+		// Wrapping the Service means reports built for subscriptions are
+		// recorded, too.
+		svc = &trendingMatcher{Service: svc, trends: trends}
+		gate.Go(ctx, func(ctx context.Context) {
+			trends.Run(ctx, trendPruneInterval, time.Duration(tc.Retention))
+		})
+	}
+	var changelogs *changelog.Store
+	if cc := cfg.Matcher.Changelog; cc != nil {
+		changelogs = changelog.NewStore(pool)
+		svc = &changelogMatcher{Service: svc, changelogs: changelogs}
+		gate.Go(ctx, func(ctx context.Context) {
+			changelogs.Run(ctx, changelogPruneInterval, time.Duration(cc.Retention))
+		})
+	}
+	srv := &dbMatcher{
+		Service:       svc,
+		Store:         policy.NewStore(pool),
+		Triage:        triage.NewStore(pool),
+		Reporter:      usage.New(pool),
+		Stats:         vulnstats.New(pool),
+		Updaters:      ctl,
+		Trends:        trends,
+		Changelogs:    changelogs,
+		AdvisoryStore: advisories,
+		Vulnstore:     store,
+		Gate:          gate,
+		LockPromotion: gate != nil && cfg.Standby.Promotion == config.StandbyPromotionLock,
+	}
+	if sc := cfg.Matcher.Subscriptions; sc != nil {
+		srv.Manager, err = matcherSubscriptions(ctx, cfg, sc, pool, i, srv.Service)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		m := srv.Manager
+		gate.Go(ctx, func(ctx context.Context) {
+			m.Run(ctx, subscriptionInterval)
+		})
+	}
+	if bc := cfg.Matcher.Backfill; bc != nil {
+		bi, ok := i.(backfill.Indexer)
+		if !ok {
+			return nil, mkErr(errors.New("backfill: indexer can't list manifests"))
+		}
+		// Using the wrapped Service means re-matched reports are recorded
+		// in trends and changelogs, too.
+		m := backfill.New(pool, &backfill.Options{
+			Indexer:   bi,
+			Scanner:   srv.Service,
+			Rate:      bc.Rate,
+			ChunkSize: bc.ChunkSize,
+		})
+		srv.BackfillManager = m
+		gate.Go(ctx, func(ctx context.Context) {
+			m.Run(ctx, backfillInterval)
+		})
+	}
+	fopts := freshness.Options{}
+	if f := cfg.Matcher.Freshness; f != nil {
+		fopts.StaleAfter = time.Duration(f.StaleAfter)
+		fopts.Readiness = f.Readiness
+	}
+	srv.Tracker = freshness.NewTracker(pool, &fopts)
+	if fopts.StaleAfter > 0 {
+		go srv.Tracker.Run(ctx, freshnessInterval)
+	}
+	if srv.LockPromotion {
+		go gate.Elect(ctx, locker, time.Duration(cfg.Standby.PollInterval))
+	}
+	if cfg.Matcher.GC == nil {
+		return srv, nil
+	}
+	e, err := matcherGC(cfg.Matcher.GC, s, pool)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	gcCfg := cfg.Matcher.GC
+	collect := leader.Periodic(time.Duration(gcCfg.Interval), func(ctx context.Context) error {
+		_, err := e.Collect(ctx, gcCfg.DryRun)
+		return err
+	})
+	if cfg.Matcher.LeaderElection {
+		gate.Go(ctx, func(ctx context.Context) {
+			leader.Run(ctx, locker, "matcher-gc", collect)
+		})
+	} else {
+		gate.Go(ctx, collect)
+	}
+	return &collectingMatcher{dbMatcher: srv, gc: e}, nil
+}
+
+// MatcherGC constructs the GC policy engine described by "cfg".
+func matcherGC(cfg *config.MatcherGC, s gc.Store, pool *pgxpool.Pool) (*gc.Engine, error) {
+	opts := gc.Options{
+		Policies: make([]gc.Policy, len(cfg.Policies)),
+		DB:       pool,
+	}
+	for i, p := range cfg.Policies {
+		opts.Policies[i] = gc.Policy{
+			Updaters: p.Updaters,
+			Keep:     p.Keep,
+			MaxAge:   time.Duration(p.MaxAge),
+		}
+	}
+	if v := cfg.Vacuum; v != nil {
+		off, err := v.Offset()
+		if err != nil {
+			return nil, err
+		}
+		opts.Vacuum = &gc.Window{
+			Start:  off,
+			Length: time.Duration(v.Duration),
+		}
+	}
+	return gc.New(s, &opts), nil
+}
+
+// MatcherSubscriptions constructs the re-scan subscription manager described
+// by "sc".
+func matcherSubscriptions(ctx context.Context, cfg *config.Config, sc *config.MatcherSubscriptions, pool *pgxpool.Pool, i indexer.Service, s matcher.Scanner) (*subscription.Manager, error) {
+	// Callback URLs are supplied by API clients, so restrict where the
+	// matcher can send requests.
+	g, err := httputil.NewGuard(sc.AllowedHosts, sc.AllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	c, err := httputil.NewGuardedClient(ctx, g, cfg.Proxy, outboundTLS(cfg).Notifier, clientTransport(cfg).Notifier)
+	if err != nil {
+		return nil, err
+	}
+	return subscription.New(pool, &subscription.Options{
+		Indexer:     i,
+		Scanner:     s,
+		Client:      c,
+		CheckURL:    g.CheckURL,
+		MinInterval: time.Duration(sc.MinInterval),
+	}), nil
 }
 
-func remoteMatcher(ctx context.Context, cfg *config.Config, addr string) (matcher.Service, error) {
+// SubscriptionInterval is how often due re-scan subscriptions are checked
+// for, if subscriptions are configured.
+const subscriptionInterval = time.Minute
+
+// BackfillInterval is how often a running re-match backfill is checked for,
+// if backfills are configured.
+const backfillInterval = time.Minute
+
+// AdvisoryInterval is how often the internal advisories are reloaded, to
+// pick up changes made through other matchers.
+const advisoryInterval = time.Minute
+
+// FreshnessInterval is how often updater status is checked, if a staleness
+// threshold is configured.
+const freshnessInterval = time.Minute
+
+// TrendPruneInterval is how often expired finding counts are removed, if
+// trends are configured.
+const trendPruneInterval = time.Hour
+
+// TrendingMatcher records finding counts for every vulnerability report it
+// builds.
+type trendingMatcher struct {
+	matcher.Service
+	trends *trend.Store
+}
+
+// Scan implements matcher.Scanner.
+func (m *trendingMatcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.Service.Scan(ctx, ir)
+	if err != nil {
+		return nil, err
+	}
+	// A failure to record shouldn't fail the report.
+	if err := m.trends.Observe(ctx, m.Service, vr); err != nil {
+		zlog.Warn(ctx).Err(err).Stringer("manifest", vr.Hash).Msg("unable to record finding counts")
+	}
+	return vr, nil
+}
+
+// ChangelogPruneInterval is how often expired changelog events are removed,
+// if changelogs are configured.
+const changelogPruneInterval = time.Hour
+
+// ChangelogMatcher records the changes in every vulnerability report it
+// builds.
+type changelogMatcher struct {
+	matcher.Service
+	changelogs *changelog.Store
+}
+
+// Scan implements matcher.Scanner.
+func (m *changelogMatcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.Service.Scan(ctx, ir)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.changelogs.Observe(ctx, m.Service, vr); err != nil {
+		zlog.Warn(ctx).Err(err).Stringer("manifest", vr.Hash).Msg("unable to record changelog")
+	}
+	return vr, nil
+}
+
+// DbMatcher is a local matcher that stores scan policies, triage
+// annotations, re-scan subscriptions, re-match backfills, finding trends,
+// changelogs, disabled updaters, and internal advisories in, and reads
+// updater status and usage statistics from, its database.
+//
+// The subscription Manager, BackfillManager, Trends, and Changelogs are nil
+// if subscriptions, backfills, trends, or changelogs aren't configured.
+type dbMatcher struct {
+	matcher.Service
+	*policy.Store
+	*freshness.Tracker
+	matcher.Triage
+	*subscription.Manager
+	*usage.Reporter
+	Stats         *vulnstats.Reporter
+	Updaters      *updaterctl.Store
+	Trends        *trend.Store
+	Changelogs    *changelog.Store
+	AdvisoryStore *advisory.Store
+	// BackfillManager returns backfill.ErrDisabled from every method if
+	// it's nil.
+	BackfillManager *backfill.Manager
+	// Vulnstore answers queries from matchers without a database of their
+	// own.
+	Vulnstore datastore.MatcherStore
+	// Gate holds background work while on standby, and LockPromotion
+	// reports whether it's promoted by lock rather than by request.
+	Gate          *standby.Gate
+	LockPromotion bool
+}
+
+// StandbyStatus implements matcher.Standby.
+func (m *dbMatcher) StandbyStatus(_ context.Context) (standby.Status, error) {
+	if m.Gate == nil {
+		return standby.Status{}, standby.ErrDisabled
+	}
+	return m.Gate.Status(), nil
+}
+
+// Promote implements matcher.Standby.
+func (m *dbMatcher) Promote(_ context.Context) (bool, error) {
+	switch {
+	case m.Gate == nil:
+		return false, standby.ErrDisabled
+	case m.LockPromotion:
+		return false, standby.ErrLockPromotion
+	}
+	return m.Gate.Promote("api"), nil
+}
+
+// Trend implements matcher.Trends.
+func (m *dbMatcher) Trend(ctx context.Context, d claircore.Digest, since, until time.Time) ([]trend.Point, error) {
+	if m.Trends == nil {
+		return nil, trend.ErrDisabled
+	}
+	return m.Trends.Trend(ctx, d, since, until)
+}
+
+// Changelog implements matcher.Changelogs.
+func (m *dbMatcher) Changelog(ctx context.Context, d claircore.Digest, page *changelog.Page) ([]changelog.Event, changelog.Page, error) {
+	if m.Changelogs == nil {
+		return nil, changelog.Page{}, changelog.ErrDisabled
+	}
+	return m.Changelogs.Changelog(ctx, d, page)
+}
+
+// Backfills implements matcher.Backfills.
+func (m *dbMatcher) Backfills(ctx context.Context) ([]backfill.Job, error) {
+	return m.BackfillManager.Jobs(ctx)
+}
+
+// Backfill implements matcher.Backfills.
+func (m *dbMatcher) Backfill(ctx context.Context, id uuid.UUID) (*backfill.Job, bool, error) {
+	return m.BackfillManager.Job(ctx, id)
+}
+
+// StartBackfill implements matcher.Backfills.
+func (m *dbMatcher) StartBackfill(ctx context.Context, j *backfill.Job) error {
+	return m.BackfillManager.Start(ctx, j)
+}
+
+// SetBackfillState implements matcher.Backfills.
+func (m *dbMatcher) SetBackfillState(ctx context.Context, id uuid.UUID, st backfill.State) (*backfill.Job, bool, error) {
+	return m.BackfillManager.SetState(ctx, id, st)
+}
+
+// VulnerabilityStats implements matcher.VulnerabilityStats.
+func (m *dbMatcher) VulnerabilityStats(ctx context.Context) ([]vulnstats.Source, error) {
+	return m.Stats.VulnerabilityStats(ctx)
+}
+
+// VulnerabilityDiff implements matcher.VulnerabilityStats.
+func (m *dbMatcher) VulnerabilityDiff(ctx context.Context, prev, cur uuid.UUID, limit int) (*vulnstats.Diff, error) {
+	return m.Stats.VulnerabilityDiff(ctx, prev, cur, limit)
+}
+
+// DisabledUpdaters implements matcher.UpdaterControl.
+func (m *dbMatcher) DisabledUpdaters(ctx context.Context) ([]updaterctl.Disabled, error) {
+	return m.Updaters.DisabledUpdaters(ctx)
+}
+
+// DisableUpdater implements matcher.UpdaterControl.
+func (m *dbMatcher) DisableUpdater(ctx context.Context, d *updaterctl.Disabled) (bool, error) {
+	return m.Updaters.DisableUpdater(ctx, d)
+}
+
+// EnableUpdater implements matcher.UpdaterControl.
+func (m *dbMatcher) EnableUpdater(ctx context.Context, name string) (bool, error) {
+	return m.Updaters.EnableUpdater(ctx, name)
+}
+
+// Vulnerabilities implements matcher.Vulnstore.
+func (m *dbMatcher) Vulnerabilities(ctx context.Context, records []*claircore.IndexRecord, opts datastore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	return m.Vulnstore.Get(ctx, records, opts)
+}
+
+// Enrichments implements matcher.Vulnstore.
+func (m *dbMatcher) Enrichments(ctx context.Context, kind string, tags []string) ([]driver.EnrichmentRecord, error) {
+	return m.Vulnstore.GetEnrichment(ctx, kind, tags)
+}
+
+// Advisory implements matcher.Advisories.
+func (m *dbMatcher) Advisory(ctx context.Context, name string) (*advisory.Advisory, bool, error) {
+	return m.AdvisoryStore.Advisory(ctx, name)
+}
+
+// Advisories implements matcher.Advisories.
+func (m *dbMatcher) Advisories(ctx context.Context) ([]advisory.Advisory, error) {
+	return m.AdvisoryStore.Advisories(ctx)
+}
+
+// PutAdvisory implements matcher.Advisories.
+func (m *dbMatcher) PutAdvisory(ctx context.Context, a *advisory.Advisory) (bool, error) {
+	return m.AdvisoryStore.PutAdvisory(ctx, a)
+}
+
+// WithdrawAdvisory implements matcher.Advisories.
+func (m *dbMatcher) WithdrawAdvisory(ctx context.Context, name string) (bool, error) {
+	return m.AdvisoryStore.WithdrawAdvisory(ctx, name)
+}
+
+var (
+	_ matcher.Policies           = (*dbMatcher)(nil)
+	_ matcher.Freshness          = (*dbMatcher)(nil)
+	_ matcher.Triage             = (*dbMatcher)(nil)
+	_ matcher.Subscriptions      = (*dbMatcher)(nil)
+	_ matcher.Backfills          = (*dbMatcher)(nil)
+	_ matcher.UsageReporter      = (*dbMatcher)(nil)
+	_ matcher.VulnerabilityStats = (*dbMatcher)(nil)
+	_ matcher.UpdaterControl     = (*dbMatcher)(nil)
+	_ matcher.Trends             = (*dbMatcher)(nil)
+	_ matcher.Changelogs         = (*dbMatcher)(nil)
+	_ matcher.Advisories         = (*dbMatcher)(nil)
+	_ matcher.Standby            = (*dbMatcher)(nil)
+	_ matcher.Vulnstore          = (*dbMatcher)(nil)
+)
+
+// CollectingMatcher is a local matcher with the GC policy engine enabled.
+type collectingMatcher struct {
+	*dbMatcher
+	gc *gc.Engine
+}
+
+var _ matcher.Collector = (*collectingMatcher)(nil)
+
+// CollectGarbage implements matcher.Collector.
+func (m *collectingMatcher) CollectGarbage(ctx context.Context, dryRun bool) (*gc.Report, error) {
+	return m.gc.Collect(ctx, dryRun)
+}
+
+// RemoteMatcher returns a client for the matcher at "addr", using tokens
+// limited to "scopes" if authentication is configured.
+func remoteMatcher(ctx context.Context, cfg *config.Config, addr string, scopes ...string) (matcher.Service, error) {
 	const msg = "failed to initialize matcher client: "
 	mkErr := func(err error) *clairerror.ErrNotInitialized {
 		return &clairerror.ErrNotInitialized{msg + err.Error()}
 	}
-	rc, err := remoteClient(ctx, cfg, intraserviceClaim, addr)
+	rc, err := remoteClient(ctx, cfg, intraserviceClaim(httptransport.MatcherAudience), addr, scopes)
 	if err != nil {
 		return nil, mkErr(err)
 	}
 	return rc, nil
 }
 
-func localNotifier(ctx context.Context, cfg *config.Config, i indexer.Service, m matcher.Service) (notifier.Service, error) {
+// VulnstoreMatcher constructs a matcher that queries the configured vulnstore
+// instead of a database of its own. It runs no updaters, and none of the
+// features that keep state in the matcher's database are available.
+func vulnstoreMatcher(ctx context.Context, cfg *config.Config) (matcher.Service, error) {
+	const msg = "failed to initialize matcher: "
+	mkErr := func(err error) *clairerror.ErrNotInitialized {
+		return &clairerror.ErrNotInitialized{
+			Msg: msg + err.Error(),
+		}
+	}
+
+	rc, err := remoteClient(ctx, cfg, intraserviceClaim(httptransport.MatcherAudience), cfg.Matcher.Vulnstore.Addr,
+		[]string{httptransport.ScopeVulnstore, httptransport.ScopeUpdateOperation, httptransport.ScopeUpdateDiff})
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	proxy, err := httputil.ProxyFunc(cfg.Proxy)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	tr.Proxy = proxy
+	if tr.TLSClientConfig, err = outboundTLS(cfg).Updaters.Config(); err != nil {
+		return nil, mkErr(err)
+	}
+	cl := &http.Client{
+		Transport: otelhttp.NewTransport(tr),
+	}
+	matcherConfigs := make(map[string]driver.MatcherConfigUnmarshaler)
+	for name, node := range cfg.Matchers.Config {
+		node := node
+		matcherConfigs[name] = func(v interface{}) error {
+			b, err := json.Marshal(node)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(b, v)
+		}
+	}
+	// Vulnerabilities from configured feeds and runtime schedules, and OSV
+	// advisories for lockfile packages, need their matchers to be matched,
+	// even though the central matcher runs the updaters.
+	_, feedMatchers, err := feed.Drivers(cfg.Updaters.Feeds, cl)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	feedMatchers = append(feedMatchers, &lockfiles.Matcher{})
+	if e := cfg.Updaters.RuntimeEOL; e != nil {
+		_, m, err := eol.New(e, cl)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		feedMatchers = append(feedMatchers, m)
+	}
+
+	s, err := libvuln.New(ctx, &libvuln.Options{
+		Store:  vulnstore.New(rc),
+		Locker: updates.NewLocalLockSource(),
+		// An empty list, rather than nil, constructs no updaters.
+		UpdaterSets:    []string{},
+		MatcherNames:   cfg.Matchers.Names,
+		MatcherConfigs: matcherConfigs,
+		Matchers:       feedMatchers,
+		Client:         cl,
+		Enrichers: []driver.Enricher{
+			&cvss.Enricher{},
+		},
+		DisableBackgroundUpdates: true,
+	})
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	zlog.Info(ctx).
+		Str("vulnstore", cfg.Matcher.Vulnstore.Addr).
+		Msg("querying remote vulnerability store")
+	return matcher.Limit(s, cfg.Matcher.ScanConcurrency), nil
+}
+
+// LocalNotifier constructs a notifier backed by the configured database. If
+// "gate" is not nil, the notifier's background work waits for it to be
+// promoted.
+func localNotifier(ctx context.Context, cfg *config.Config, i indexer.Service, m matcher.Service, gate *standby.Gate) (notifier.Service, error) {
 	const msg = "failed to initialize notifier: "
 	mkErr := func(err error) *clairerror.ErrNotInitialized {
 		return &clairerror.ErrNotInitialized{
@@ -317,7 +1381,21 @@ func localNotifier(ctx context.Context, cfg *config.Config, i indexer.Service, m
 		}
 	}
 
-	c, err := httputil.NewClient(ctx, false) // No airgap flag.
+	// Webhook targets come from configuration that may be templated, so
+	// restrict where the notifier can send requests.
+	var hosts, nets []string
+	if wh := cfg.Notifier.Webhook; wh != nil {
+		hosts, nets = wh.AllowedHosts, wh.AllowedNetworks
+	}
+	g, err := httputil.NewGuard(hosts, nets)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	c, err := httputil.NewGuardedClient(ctx, g, cfg.Proxy, outboundTLS(cfg).Notifier, clientTransport(cfg).Notifier) // No airgap flag.
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	c.Transport, err = httputil.Faults(ctx, c.Transport, httputil.FaultNotifier)
 	if err != nil {
 		return nil, mkErr(err)
 	}
@@ -331,21 +1409,52 @@ func localNotifier(ctx context.Context, cfg *config.Config, i indexer.Service, m
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	if err := databaseTLS(poolcfg, outboundTLS(cfg).Database); err != nil {
+		return nil, mkErr(err)
+	}
 	if cfg.Notifier.Migrations {
 		if err := notifierpg.Init(ctx, poolcfg.ConnConfig); err != nil {
 			return nil, mkErr(err)
 		}
 	}
+	ql := queryLogger(cfg, "notifier")
+	if ql != nil {
+		ql.Configure(poolcfg)
+	}
 	pool, err := pgxpool.ConnectConfig(ctx, poolcfg)
 	if err != nil {
 		return nil, mkErr(err)
 	}
+	if ql != nil {
+		ql.Attach(pool)
+	}
+	health.Register("notifier/database", pool.Ping)
+	if err := poolstats.Register("notifier", pool); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to register pool metrics")
+	}
 	store := notifierpg.NewStore(pool)
+	store.PageWorkers = cfg.Notifier.Pages.Workers
 	locks, err := ctxlock.New(ctx, pool)
 	if err != nil {
 		return nil, mkErr(err)
 	}
 
+	throttle, err := notifierThrottle(&cfg.Notifier.Throttle)
+	if err != nil {
+		return nil, mkErr(err)
+	}
+	var wake chan struct{}
+	if dsn := cfg.Notifier.MatcherConnString; dsn != "" {
+		mcfg, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			return nil, mkErr(err)
+		}
+		if err := databaseTLS(mcfg, outboundTLS(cfg).Database); err != nil {
+			return nil, mkErr(err)
+		}
+		wake = make(chan struct{}, 1)
+		go updatenotify.Listen(ctx, mcfg.ConnConfig, wake)
+	}
 	s, err := service.New(ctx, store, locks, service.Opts{
 		DeliveryInterval: time.Duration(cfg.Notifier.DeliveryInterval),
 		Indexer:          i,
@@ -353,10 +1462,26 @@ func localNotifier(ctx context.Context, cfg *config.Config, i indexer.Service, m
 		Client:           c,
 		Signer:           signer,
 		PollInterval:     time.Duration(cfg.Notifier.PollInterval),
+		Wake:             wake,
 		DisableSummary:   cfg.Notifier.DisableSummary,
 		Webhook:          cfg.Notifier.Webhook,
 		AMQP:             cfg.Notifier.AMQP,
 		STOMP:            cfg.Notifier.STOMP,
+		MQTT:             cfg.Notifier.MQTT,
+		Azure:            cfg.Notifier.Azure,
+		Syslog:           cfg.Notifier.Syslog,
+		Splunk:           cfg.Notifier.Splunk,
+		Elasticsearch:    cfg.Notifier.Elasticsearch,
+		DependencyTrack:  cfg.Notifier.DependencyTrack,
+		Issues:           cfg.Notifier.Issues,
+		GCInterval:       time.Duration(cfg.Notifier.Retention.Interval),
+		LeaderElection:   cfg.Notifier.LeaderElection,
+		OrderedDelivery:  cfg.Notifier.OrderedDelivery,
+		Retention:        notifierRetention(&cfg.Notifier.Retention),
+		Backpressure:     notifierBackpressure(&cfg.Notifier.Backpressure),
+		Retry:            notifierRetry(&cfg.Notifier.Retry),
+		Throttle:         throttle,
+		Pages:            notifierPages(&cfg.Notifier.Pages),
 	})
 	switch {
 	case err == nil:
@@ -366,10 +1491,76 @@ func localNotifier(ctx context.Context, cfg *config.Config, i indexer.Service, m
 	default:
 		return nil, mkErr(err)
 	}
-	go func() {
+	gate.Go(ctx, func(ctx context.Context) {
 		if err := s.Run(ctx); err != context.Canceled {
 			zlog.Error(ctx).Err(err).Msg("unexpected notifier error")
 		}
-	}()
+	})
 	return s, nil
 }
+
+// NotifierRetention translates the retention configuration into the options
+// used by the notifier's garbage collector. Negative durations disable their
+// criterion.
+func notifierRetention(cfg *config.NotifierRetention) notifier.CollectOpts {
+	var opts notifier.CollectOpts
+	if d := time.Duration(cfg.Delivered); d > 0 {
+		opts.DeliveredAge = d
+	}
+	if d := time.Duration(cfg.Undelivered); d > 0 {
+		opts.UndeliveredAge = d
+	}
+	opts.MaxCount = cfg.MaxCount
+	return opts
+}
+
+// NotifierBackpressure constructs the notifier's delivery backpressure from
+// its configuration. Negative limits disable them.
+func notifierBackpressure(cfg *config.NotifierBackpressure) *notifier.Backpressure {
+	return notifier.NewBackpressure(cfg.MaxBatch, time.Duration(cfg.TargetLatency), cfg.MaxPending)
+}
+
+// NotifierRetry translates the retry configuration into the notifier's retry
+// schedule, or nil if failed notifications are attempted on every delivery
+// run.
+func notifierRetry(cfg *config.NotifierRetry) *notifier.Retry {
+	if cfg.Backoff < 0 {
+		return nil
+	}
+	return notifier.NewRetry(time.Duration(cfg.Backoff), time.Duration(cfg.MaxBackoff))
+}
+
+// NotifierPages translates the page configuration into the options used by the
+// notifier's page cache, or nil if pages aren't kept.
+func notifierPages(cfg *config.NotifierPages) *notifier.PageOptions {
+	if cfg.HotThreshold < 1 {
+		return nil
+	}
+	return &notifier.PageOptions{
+		Hot:  cfg.HotThreshold,
+		Size: cfg.CacheSize,
+		TTL:  time.Duration(cfg.TTL),
+	}
+}
+
+// NotifierThrottle constructs the notifier's affected manifests throttle from
+// its configuration.
+func notifierThrottle(cfg *config.NotifierThrottle) (*notifier.Throttle, error) {
+	ws := make([]notifier.Window, len(cfg.Windows))
+	for i, w := range cfg.Windows {
+		var err error
+		ws[i], err = notifier.ParseWindow(w)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var loc *time.Location
+	if cfg.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notifier.NewThrottle(cfg.ChunkSize, time.Duration(cfg.Delay), cfg.Concurrency, loc, ws), nil
+}
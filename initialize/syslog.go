@@ -0,0 +1,24 @@
+//go:build !windows && !plan9
+
+package initialize
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/quay/clair/config"
+	"github.com/rs/zerolog"
+)
+
+// SyslogWriter connects to the configured syslog daemon.
+func syslogWriter(cfg *config.LogSyslog) (io.Writer, error) {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "clair"
+	}
+	w, err := syslog.Dial(cfg.Network, cfg.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return zerolog.SyslogLevelWriter(w), nil
+}
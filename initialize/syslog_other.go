@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package initialize
+
+import (
+	"errors"
+	"io"
+
+	"github.com/quay/clair/config"
+)
+
+// SyslogWriter reports an error on this platform.
+func syslogWriter(_ *config.LogSyslog) (io.Writer, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,155 @@
+package initialize
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+// TLSReloadInterval is how often the files backing a server TLS configuration
+// are checked for changes.
+var TLSReloadInterval = time.Minute
+
+// ServerTLS returns a tls.Config for serving the HTTP API, according to the
+// provided configuration.
+//
+// The certificate, key, and client CA files are checked for changes
+// periodically until the Context is canceled, and reloaded if modified. If
+// reloading fails, the previously loaded files continue to be used.
+//
+// If a client CA is configured, clients are required to present a certificate
+// signed by it.
+func ServerTLS(ctx context.Context, cfg *config.TLS) (*tls.Config, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "initialize/ServerTLS")
+	return serverTLS(ctx, cfg, tls.RequireAndVerifyClientCert)
+}
+
+// IntrospectionTLS returns a tls.Config for serving the introspection server,
+// according to the provided configuration.
+//
+// This is like ServerTLS, except that client certificates are only verified
+// if presented. Requests without one are left to the introspection server's
+// authentication.
+func IntrospectionTLS(ctx context.Context, cfg *config.TLS) (*tls.Config, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "initialize/IntrospectionTLS")
+	return serverTLS(ctx, cfg, tls.VerifyClientCertIfGiven)
+}
+
+// ServerTLS is the common implementation of ServerTLS and IntrospectionTLS.
+// The "auth" policy is used if a client CA is configured.
+func serverTLS(ctx context.Context, cfg *config.TLS, auth tls.ClientAuthType) (*tls.Config, error) {
+	r := &certReloader{cfg: cfg}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	out := &tls.Config{
+		NextProtos: []string{"h2"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.cert.Load(), nil
+		},
+	}
+	if cfg.ClientCA != "" {
+		out.ClientAuth = auth
+		out.ClientCAs = r.pool.Load()
+		base := out.Clone()
+		out.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := base.Clone()
+			c.ClientCAs = r.pool.Load()
+			return c, nil
+		}
+	}
+	go r.watch(ctx, TLSReloadInterval)
+	return out, nil
+}
+
+// CertReloader holds the current certificate and client CA pool.
+type certReloader struct {
+	cfg  *config.TLS
+	cert atomic.Pointer[tls.Certificate]
+	pool atomic.Pointer[x509.CertPool]
+	// Modified is the latest modification time of the backing files as of
+	// the last successful load. Only accessed by the watch goroutine after
+	// the initial load.
+	modified time.Time
+}
+
+// Load reads all the configured files.
+func (r *certReloader) load() error {
+	mod, err := r.mtime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.Cert, r.cfg.Key)
+	if err != nil {
+		return fmt.Errorf("failed to read x509 cert and key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.cfg.ClientCA != "" {
+		b, err := os.ReadFile(r.cfg.ClientCA)
+		if err != nil {
+			return fmt.Errorf("failed to read tls client ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.New("unable to add client ca certificates to pool")
+		}
+	}
+	r.cert.Store(&cert)
+	if pool != nil {
+		r.pool.Store(pool)
+	}
+	r.modified = mod
+	return nil
+}
+
+// Mtime reports the latest modification time of the configured files.
+func (r *certReloader) mtime() (time.Time, error) {
+	var latest time.Time
+	for _, n := range []string{r.cfg.Cert, r.cfg.Key, r.cfg.ClientCA} {
+		if n == "" {
+			continue
+		}
+		fi, err := os.Stat(n)
+		if err != nil {
+			return latest, err
+		}
+		if m := fi.ModTime(); m.After(latest) {
+			latest = m
+		}
+	}
+	return latest, nil
+}
+
+// Watch reloads the files when they change, until the Context is canceled.
+func (r *certReloader) watch(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		mod, err := r.mtime()
+		switch {
+		case err != nil:
+			zlog.Warn(ctx).Err(err).Msg("unable to check tls files")
+			continue
+		case !mod.After(r.modified):
+			continue
+		}
+		if err := r.load(); err != nil {
+			zlog.Error(ctx).Err(err).Msg("unable to reload tls files; continuing with previous certificate")
+			continue
+		}
+		zlog.Info(ctx).Msg("reloaded tls certificate")
+	}
+}
@@ -0,0 +1,113 @@
+package initialize
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+func writeCert(t *testing.T, dir, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "cert.pem"), cert, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), cert, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	k := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), k, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerTLSReload(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	prev := TLSReloadInterval
+	TLSReloadInterval = 10 * time.Millisecond
+	t.Cleanup(func() { TLSReloadInterval = prev })
+
+	dir := t.TempDir()
+	writeCert(t, dir, "first")
+	cfg := &config.TLS{
+		Cert:     filepath.Join(dir, "cert.pem"),
+		Key:      filepath.Join(dir, "key.pem"),
+		ClientCA: filepath.Join(dir, "ca.pem"),
+	}
+	tc, err := ServerTLS(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tc.ClientAuth, tls.RequireAndVerifyClientCert; got != want {
+		t.Errorf("client auth: got: %v, want: %v", got, want)
+	}
+	cn := func() string {
+		c, err := tc.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x.Subject.CommonName
+	}
+	if got, want := cn(), "first"; got != want {
+		t.Fatalf("got: %q, want: %q", got, want)
+	}
+
+	writeCert(t, dir, "second")
+	// Make sure the modification time moves forward on coarse filesystems.
+	future := time.Now().Add(time.Minute)
+	for _, n := range []string{cfg.Cert, cfg.Key, cfg.ClientCA} {
+		if err := os.Chtimes(n, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for cn() != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c, err := tc.GetConfigForClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ClientCAs == nil {
+		t.Error("missing client CAs")
+	}
+}
@@ -0,0 +1,120 @@
+package codec
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+	"github.com/ugorji/go/codec"
+)
+
+// CBORMediaType is the media type for CBOR-encoded bodies.
+//
+// CBOR is only used between Clair services, and only when the client asks
+// for it, so the encoding of particular types only needs to agree between
+// Clair versions.
+const CBORMediaType = `application/cbor`
+
+var cborHandle codec.CborHandle
+
+// TextTypes are the types in reports that encode themselves as text for the
+// JSON encoding, but have no binary encoding. Without an extension, they
+// would be encoded as structs of their (unexported) fields.
+//
+// The tags are part of the wire format: don't change or reuse them.
+var textTypes = []struct {
+	tag uint64
+	typ reflect.Type
+}{
+	{tag: 0xc1a1_0001, typ: reflect.TypeOf(claircore.Digest{})},
+	{tag: 0xc1a1_0002, typ: reflect.TypeOf(claircore.Version{})},
+	{tag: 0xc1a1_0003, typ: reflect.TypeOf(claircore.Severity(0))},
+	{tag: 0xc1a1_0004, typ: reflect.TypeOf(claircore.ArchOp(0))},
+	{tag: 0xc1a1_0005, typ: reflect.TypeOf(cpe.WFN{})},
+}
+
+func init() {
+	cborHandle.WriterBufferSize = 4096
+	cborHandle.ReaderBufferSize = 4096
+	for _, t := range textTypes {
+		if err := cborHandle.SetInterfaceExt(t.typ, t.tag, textExt{}); err != nil {
+			panic(fmt.Sprintf("programmer error: %v", err))
+		}
+	}
+}
+
+// TextExt is a codec.InterfaceExt for types implementing
+// encoding.TextMarshaler and encoding.TextUnmarshaler, possibly with pointer
+// receivers.
+type textExt struct{}
+
+// ConvertExt implements codec.InterfaceExt.
+func (textExt) ConvertExt(v interface{}) interface{} {
+	m, ok := v.(encoding.TextMarshaler)
+	if !ok {
+		// Make an addressable copy, for pointer receivers.
+		p := reflect.New(reflect.TypeOf(v))
+		p.Elem().Set(reflect.ValueOf(v))
+		m = p.Interface().(encoding.TextMarshaler)
+	}
+	b, err := m.MarshalText()
+	if err != nil {
+		panic(err) // Recovered by the Encoder and returned as an error.
+	}
+	return string(b)
+}
+
+// UpdateExt implements codec.InterfaceExt.
+func (textExt) UpdateExt(dst interface{}, src interface{}) {
+	s, ok := src.(string)
+	if !ok {
+		panic(fmt.Errorf("codec: unexpected %T for %T", src, dst))
+	}
+	if err := dst.(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+		panic(err) // Recovered by the Decoder and returned as an error.
+	}
+}
+
+// CBOR encoder and decoder pools, to reuse if possible.
+var (
+	cborEncPool = sync.Pool{
+		New: func() interface{} {
+			return codec.NewEncoder(nil, &cborHandle)
+		},
+	}
+	cborDecPool = sync.Pool{
+		New: func() interface{} {
+			return codec.NewDecoder(nil, &cborHandle)
+		},
+	}
+)
+
+// GetCBOREncoder returns a CBOR encoder configured to write to w.
+func GetCBOREncoder(w io.Writer) *Encoder {
+	e := cborEncPool.Get().(*Encoder)
+	e.Reset(w)
+	return e
+}
+
+// PutCBOREncoder returns a CBOR encoder to the pool.
+func PutCBOREncoder(e *Encoder) {
+	e.Reset(nil)
+	cborEncPool.Put(e)
+}
+
+// GetCBORDecoder returns a CBOR decoder configured to read from r.
+func GetCBORDecoder(r io.Reader) *Decoder {
+	d := cborDecPool.Get().(*Decoder)
+	d.Reset(r)
+	return d
+}
+
+// PutCBORDecoder returns a CBOR decoder to the pool.
+func PutCBORDecoder(d *Decoder) {
+	d.Reset(nil)
+	cborDecPool.Put(d)
+}
@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	digest := claircore.MustParseDigest(`sha256:` + `0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`)
+	wfn := cpe.MustUnbind(`cpe:2.3:o:redhat:enterprise_linux:8:*:*:*:*:*:*:*`)
+	pkg := &claircore.Package{
+		ID:                "1",
+		Name:              "openssl",
+		Version:           "1.1.1k-5.el8_5",
+		Kind:              claircore.BINARY,
+		Source:            &claircore.Package{ID: "2", Name: "openssl", Kind: claircore.SOURCE},
+		NormalizedVersion: claircore.Version{Kind: "semver", V: [...]int32{0, 1, 1, 1, 0, 0, 0, 0, 0, 0}},
+		Arch:              "x86_64",
+		CPE:               wfn,
+	}
+	dist := &claircore.Distribution{ID: "3", Name: "Red Hat Enterprise Linux", Version: "8", CPE: wfn}
+	vuln := &claircore.Vulnerability{
+		ID:                 "4",
+		Updater:            "rhel",
+		Name:               "CVE-2022-0778",
+		Issued:             time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC),
+		NormalizedSeverity: claircore.High,
+		Package:            pkg,
+		Dist:               dist,
+		FixedInVersion:     "1:1.1.1k-6.el8_5",
+		Range: &claircore.Range{
+			Lower: claircore.Version{Kind: "semver"},
+			Upper: claircore.Version{Kind: "semver", V: [10]int32{0, 1, 1, 2}},
+		},
+		ArchOperation: claircore.OpEquals,
+	}
+	tt := []struct {
+		Name string
+		In   interface{}
+		Out  interface{}
+	}{
+		{
+			Name: "VulnerabilityReport",
+			In: &claircore.VulnerabilityReport{
+				Hash:          digest,
+				Packages:      map[string]*claircore.Package{pkg.ID: pkg},
+				Distributions: map[string]*claircore.Distribution{dist.ID: dist},
+				Environments: map[string][]*claircore.Environment{
+					pkg.ID: {{PackageDB: "var/lib/rpm", IntroducedIn: digest, DistributionID: dist.ID}},
+				},
+				Vulnerabilities:        map[string]*claircore.Vulnerability{vuln.ID: vuln},
+				PackageVulnerabilities: map[string][]string{pkg.ID: {vuln.ID}},
+				Enrichments:            map[string][]json.RawMessage{"cvss": {json.RawMessage(`{"4":[]}`)}},
+			},
+			Out: new(claircore.VulnerabilityReport),
+		},
+		{
+			Name: "UpdateDiff",
+			In: &driver.UpdateDiff{
+				Prev:  driver.UpdateOperation{Ref: uuid.New(), Updater: "rhel", Date: vuln.Issued, Kind: driver.VulnerabilityKind},
+				Cur:   driver.UpdateOperation{Ref: uuid.New(), Updater: "rhel", Date: vuln.Issued, Kind: driver.VulnerabilityKind},
+				Added: []claircore.Vulnerability{*vuln},
+			},
+			Out: new(driver.UpdateDiff),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := GetCBOREncoder(&buf)
+			err := enc.Encode(tc.In)
+			PutCBOREncoder(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := GetCBORDecoder(&buf)
+			err = dec.Decode(tc.Out)
+			PutCBORDecoder(dec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(tc.Out, tc.In, cmpopts.EquateEmpty(), cmp.AllowUnexported(claircore.Digest{})) {
+				t.Error(cmp.Diff(tc.Out, tc.In, cmpopts.EquateEmpty(), cmp.AllowUnexported(claircore.Digest{})))
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+// Package cyclonedx builds CycloneDX software bills of materials from Clair's
+// results.
+//
+// Only the parts of the specification needed to describe a container image's
+// packages and the vulnerabilities affecting them are implemented.
+//
+// See https://cyclonedx.org/docs/1.4/json/ for the specification.
+package cyclonedx
+
+import (
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+)
+
+// MediaType is the media type of a serialized BOM.
+const MediaType = `application/vnd.cyclonedx+json; version=1.4`
+
+// BOM is a CycloneDX bill of materials.
+type BOM struct {
+	BOMFormat       string          `json:"bomFormat"`
+	SpecVersion     string          `json:"specVersion"`
+	SerialNumber    string          `json:"serialNumber"`
+	Version         int             `json:"version"`
+	Metadata        Metadata        `json:"metadata"`
+	Components      []Component     `json:"components"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// Metadata describes the BOM itself and the component it's about.
+type Metadata struct {
+	Timestamp time.Time `json:"timestamp"`
+	Tools     []Tool    `json:"tools"`
+	Component Component `json:"component"`
+}
+
+// Tool is the tool that created a BOM.
+type Tool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Component is a piece of software.
+type Component struct {
+	BOMRef   string          `json:"bom-ref"`
+	Type     string          `json:"type"`
+	Name     string          `json:"name"`
+	Version  string          `json:"version,omitempty"`
+	PURL     string          `json:"purl,omitempty"`
+	Hashes   []Hash          `json:"hashes,omitempty"`
+	Licenses []LicenseChoice `json:"licenses,omitempty"`
+	Props    []Property      `json:"properties,omitempty"`
+}
+
+// Hash is a checksum of a component.
+type Hash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// LicenseChoice is a component's license: either a single named license or
+// an SPDX license expression.
+type LicenseChoice struct {
+	License    *License `json:"license,omitempty"`
+	Expression string   `json:"expression,omitempty"`
+}
+
+// License is a single license, by name.
+type License struct {
+	Name string `json:"name"`
+}
+
+// Property is a name-value pair for data the specification has no field for.
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Vulnerability is a vulnerability affecting some of a BOM's components.
+type Vulnerability struct {
+	BOMRef         string     `json:"bom-ref"`
+	ID             string     `json:"id"`
+	Source         *Source    `json:"source,omitempty"`
+	Ratings        []Rating   `json:"ratings,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Recommendation string     `json:"recommendation,omitempty"`
+	Advisories     []Advisory `json:"advisories,omitempty"`
+	Published      *time.Time `json:"published,omitempty"`
+	Affects        []Affects  `json:"affects"`
+}
+
+// Source is where a vulnerability's data came from.
+type Source struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Rating is a severity assigned to a vulnerability.
+type Rating struct {
+	Source   *Source `json:"source,omitempty"`
+	Severity string  `json:"severity"`
+	Method   string  `json:"method,omitempty"`
+}
+
+// Advisory is a link to more information about a vulnerability.
+type Advisory struct {
+	URL string `json:"url"`
+}
+
+// Affects names a component a vulnerability affects.
+type Affects struct {
+	Ref string `json:"ref"`
+}
+
+// Opts describes the image a BOM is built for.
+type Opts struct {
+	// Name and Version name the image, typically its repository and tag. If
+	// Name is empty, the manifest digest is used.
+	Name, Version string
+	// Tool is the Clair version.
+	Tool string
+	// Licenses are the declared licenses of the packages, keyed by package
+	// ID, if known.
+	Licenses map[string]string
+}
+
+// FromReport returns a BOM listing the packages in "vr" and the
+// vulnerabilities affecting them.
+//
+// Components are ordered by package name and version and vulnerabilities by
+// ID, so reports with the same contents produce the same BOM apart from the
+// serial number and timestamp.
+func FromReport(vr *claircore.VulnerabilityReport, opts *Opts) *BOM {
+	name := opts.Name
+	if name == "" {
+		name = vr.Hash.String()
+	}
+	b := BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + uuid.New().String(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC(),
+			Tools:     []Tool{{Vendor: "Clair", Name: "clair", Version: opts.Tool}},
+			Component: Component{
+				BOMRef:  vr.Hash.String(),
+				Type:    "container",
+				Name:    name,
+				Version: opts.Version,
+				Hashes:  hashes(vr.Hash),
+			},
+		},
+		Components: make([]Component, 0, len(vr.Packages)),
+	}
+
+	for id, p := range vr.Packages {
+		c := Component{
+			BOMRef:  pkgRef(id),
+			Type:    "library",
+			Name:    p.Name,
+			Version: p.Version,
+			PURL:    purl(vr, id, p),
+		}
+		if l, ok := opts.Licenses[id]; ok {
+			c.Licenses = []LicenseChoice{license(l)}
+		}
+		if p.Arch != "" {
+			c.Props = append(c.Props, Property{Name: "clair:package:arch", Value: p.Arch})
+		}
+		if p.Source != nil && p.Source.Name != "" && p.Source.Name != p.Name {
+			c.Props = append(c.Props, Property{Name: "clair:package:source", Value: p.Source.Name})
+		}
+		b.Components = append(b.Components, c)
+	}
+	sort.Slice(b.Components, func(i, j int) bool {
+		x, y := &b.Components[i], &b.Components[j]
+		if x.Name != y.Name {
+			return x.Name < y.Name
+		}
+		if x.Version != y.Version {
+			return x.Version < y.Version
+		}
+		return x.BOMRef < y.BOMRef
+	})
+
+	affects := make(map[string][]Affects, len(vr.Vulnerabilities))
+	for pkg, ids := range vr.PackageVulnerabilities {
+		for _, id := range ids {
+			affects[id] = append(affects[id], Affects{Ref: pkgRef(pkg)})
+		}
+	}
+	for id, v := range vr.Vulnerabilities {
+		a := affects[id]
+		if len(a) == 0 {
+			continue
+		}
+		sort.Slice(a, func(i, j int) bool { return a[i].Ref < a[j].Ref })
+		out := Vulnerability{
+			BOMRef:      "vulnerability-" + id,
+			ID:          v.Name,
+			Description: v.Description,
+			Affects:     a,
+			Ratings: []Rating{{
+				Severity: severity(v.NormalizedSeverity),
+				Method:   "other",
+			}},
+		}
+		if v.Updater != "" {
+			out.Source = &Source{Name: v.Updater}
+			out.Ratings[0].Source = out.Source
+		}
+		if v.FixedInVersion != "" {
+			out.Recommendation = "Upgrade to " + v.FixedInVersion + " or later."
+		}
+		for _, l := range strings.Fields(v.Links) {
+			if u, err := url.Parse(l); err == nil && u.IsAbs() {
+				out.Advisories = append(out.Advisories, Advisory{URL: l})
+			}
+		}
+		if !v.Issued.IsZero() {
+			t := v.Issued.UTC()
+			out.Published = &t
+		}
+		b.Vulnerabilities = append(b.Vulnerabilities, out)
+	}
+	sort.Slice(b.Vulnerabilities, func(i, j int) bool {
+		x, y := &b.Vulnerabilities[i], &b.Vulnerabilities[j]
+		if x.ID != y.ID {
+			return x.ID < y.ID
+		}
+		return x.BOMRef < y.BOMRef
+	})
+	return &b
+}
+
+// PkgRef is the bom-ref of the package with the ID "id". These aren't package
+// URLs, because those aren't unique within a report.
+func pkgRef(id string) string { return "package-" + id }
+
+// License returns the LicenseChoice for the declared license "l": an
+// expression if it combines licenses, and a name otherwise, as a lone
+// declared license isn't necessarily an SPDX identifier.
+func license(l string) LicenseChoice {
+	for _, op := range []string{" AND ", " OR ", " WITH "} {
+		if strings.Contains(l, op) {
+			return LicenseChoice{Expression: l}
+		}
+	}
+	return LicenseChoice{License: &License{Name: l}}
+}
+
+func hashes(d claircore.Digest) []Hash {
+	var alg string
+	switch d.Algorithm() {
+	case "sha256":
+		alg = "SHA-256"
+	case "sha512":
+		alg = "SHA-512"
+	default:
+		return nil
+	}
+	return []Hash{{Alg: alg, Content: hex.EncodeToString(d.Checksum())}}
+}
+
+// Severity maps a normalized severity onto the CycloneDX severities.
+func severity(s claircore.Severity) string {
+	switch s {
+	case claircore.Critical:
+		return "critical"
+	case claircore.High:
+		return "high"
+	case claircore.Medium:
+		return "medium"
+	case claircore.Low:
+		return "low"
+	case claircore.Negligible:
+		return "info"
+	}
+	return "unknown"
+}
+
+// These map the ecosystems Clair knows about to package URL types.
+var (
+	repoTypes = map[string]string{
+		"pypi":     "pypi",
+		"maven":    "maven",
+		"go":       "golang",
+		"rubygems": "gem",
+	}
+	distTypes = map[string]string{
+		"alpine":    "apk",
+		"debian":    "deb",
+		"ubuntu":    "deb",
+		"rhel":      "rpm",
+		"centos":    "rpm",
+		"fedora":    "rpm",
+		"ol":        "rpm",
+		"amzn":      "rpm",
+		"rocky":     "rpm",
+		"almalinux": "rpm",
+		"photon":    "rpm",
+		"suse":      "rpm",
+		"sles":      "rpm",
+		"opensuse":  "rpm",
+	}
+)
+
+// Purl returns a package URL for the package "p", or an empty string if the
+// ecosystem it belongs to isn't known.
+//
+// Language packages are identified by the repository they were found in, and
+// distribution packages by the distribution of the layer they were found in.
+func purl(vr *claircore.VulnerabilityReport, id string, p *claircore.Package) string {
+	var repos []string
+	var dist *claircore.Distribution
+	for _, env := range vr.Environments[id] {
+		repos = append(repos, env.RepositoryIDs...)
+		if d, ok := vr.Distributions[env.DistributionID]; ok && dist == nil {
+			dist = d
+		}
+	}
+	for _, rid := range repos {
+		r, ok := vr.Repositories[rid]
+		if !ok {
+			continue
+		}
+		typ, ok := repoTypes[r.Name]
+		if !ok {
+			continue
+		}
+		name := p.Name
+		if typ == "maven" {
+			// Maven packages are named "group:artifact".
+			name = strings.Replace(name, ":", "/", 1)
+		} else {
+			name = escape(name)
+		}
+		return "pkg:" + typ + "/" + name + "@" + url.PathEscape(p.Version)
+	}
+	if dist == nil {
+		return ""
+	}
+	typ, ok := distTypes[dist.DID]
+	if !ok {
+		return ""
+	}
+	q := url.Values{}
+	if p.Arch != "" {
+		q.Set("arch", p.Arch)
+	}
+	if dist.VersionID != "" {
+		q.Set("distro", dist.DID+"-"+dist.VersionID)
+	}
+	s := "pkg:" + typ + "/" + dist.DID + "/" + escape(p.Name) + "@" + url.PathEscape(p.Version)
+	if len(q) != 0 {
+		s += "?" + q.Encode()
+	}
+	return s
+}
+
+// Escape percent-encodes a package URL name segment. Slashes are allowed, as
+// they separate a namespace from the name.
+func escape(s string) string {
+	parts := strings.Split(s, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
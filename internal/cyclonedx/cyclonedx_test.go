@@ -0,0 +1,92 @@
+package cyclonedx
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestFromReport(t *testing.T) {
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	vr := &claircore.VulnerabilityReport{
+		Hash: claircore.MustParseDigest(digest),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k-1", Arch: "x86_64"},
+			"2": {ID: "2", Name: "requests", Version: "2.25.0"},
+			"3": {ID: "3", Name: "mystery", Version: "1"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "rhel", VersionID: "8"},
+		},
+		Repositories: map[string]*claircore.Repository{
+			"1": {ID: "1", Name: "pypi"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+			"2": {{RepositoryIDs: []string{"1"}}},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": {
+				ID:                 "10",
+				Name:               "CVE-2021-3711",
+				Updater:            "rhel-vex",
+				NormalizedSeverity: claircore.High,
+				FixedInVersion:     "1.1.1k-5",
+				Links:              "https://access.redhat.com/security/cve/CVE-2021-3711 not-a-link",
+			},
+			"11": {ID: "11", Name: "CVE-2000-0000", NormalizedSeverity: claircore.Negligible},
+		},
+		PackageVulnerabilities: map[string][]string{"1": {"10"}},
+	}
+	b := FromReport(vr, &Opts{
+		Name:     "quay.io/example/app",
+		Version:  "latest",
+		Tool:     "v4",
+		Licenses: map[string]string{"1": "Apache-2.0", "2": "Apache-2.0 OR MIT"},
+	})
+
+	if got, want := b.Metadata.Component.Name, "quay.io/example/app"; got != want {
+		t.Errorf("name: got: %q, want: %q", got, want)
+	}
+	if got, want := b.Metadata.Component.Hashes, []Hash{{Alg: "SHA-256", Content: digest[7:]}}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	var purls []string
+	for _, c := range b.Components {
+		purls = append(purls, c.PURL)
+	}
+	want := []string{
+		"",
+		"pkg:rpm/rhel/openssl@1.1.1k-1?arch=x86_64&distro=rhel-8",
+		"pkg:pypi/requests@2.25.0",
+	}
+	if !cmp.Equal(purls, want) {
+		t.Error(cmp.Diff(purls, want))
+	}
+	var lics [][]LicenseChoice
+	for _, c := range b.Components {
+		lics = append(lics, c.Licenses)
+	}
+	wantLics := [][]LicenseChoice{
+		nil,
+		{{License: &License{Name: "Apache-2.0"}}},
+		{{Expression: "Apache-2.0 OR MIT"}},
+	}
+	if !cmp.Equal(lics, wantLics) {
+		t.Error(cmp.Diff(lics, wantLics))
+	}
+	// The vulnerability affecting no packages is left out.
+	wantVulns := []Vulnerability{{
+		BOMRef:         "vulnerability-10",
+		ID:             "CVE-2021-3711",
+		Source:         &Source{Name: "rhel-vex"},
+		Ratings:        []Rating{{Source: &Source{Name: "rhel-vex"}, Severity: "high", Method: "other"}},
+		Recommendation: "Upgrade to 1.1.1k-5 or later.",
+		Advisories:     []Advisory{{URL: "https://access.redhat.com/security/cve/CVE-2021-3711"}},
+		Affects:        []Affects{{Ref: "package-1"}},
+	}}
+	if !cmp.Equal(b.Vulnerabilities, wantVulns) {
+		t.Error(cmp.Diff(b.Vulnerabilities, wantVulns))
+	}
+}
@@ -0,0 +1,108 @@
+// Package dbutil holds the migration and metrics helpers shared by Clair's own
+// database stores.
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"github.com/remind101/migrate"
+)
+
+// Migrate performs the migrations "ms", recording them in the table "table",
+// on the database described by "cfg".
+func Migrate(ctx context.Context, cfg *pgx.ConnConfig, table string, ms ...migrate.Migration) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "internal/dbutil/Migrate",
+		"table", table)
+	db, err := sql.Open("pgx", stdlib.RegisterConnConfig(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to open db: %w", err)
+	}
+	defer db.Close()
+
+	zlog.Info(ctx).Msg("performing migrations")
+	migrator := migrate.NewPostgresMigrator(db)
+	migrator.Table = table
+	if err := migrator.Exec(migrate.Up, ms...); err != nil {
+		return fmt.Errorf("failed to perform migrations: %w", err)
+	}
+	return nil
+}
+
+// RunFile returns a migration step executing the SQL file "name" in "sys".
+func RunFile(sys fs.FS, name string) func(*sql.Tx) error {
+	b, err := fs.ReadFile(sys, name)
+	return func(tx *sql.Tx) error {
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(string(b)); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// ErrLabel returns the value of an "error" metric label for "err".
+func ErrLabel(err error) string {
+	if err == nil {
+		return `false`
+	}
+	return `true`
+}
+
+// QueryMetrics counts and times a store's database queries, by query name and
+// whether the query failed.
+type QueryMetrics struct {
+	count *prometheus.CounterVec
+	dur   *prometheus.HistogramVec
+}
+
+// NewQueryMetrics registers the "clair_<subsystem>_query_total" and
+// "clair_<subsystem>_query_duration_seconds" metrics with the default
+// Prometheus registry. The "issuer" describes what issues the queries, such as
+// "the label store".
+func NewQueryMetrics(subsystem, issuer string) *QueryMetrics {
+	return &QueryMetrics{
+		count: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "clair",
+				Subsystem: subsystem,
+				Name:      "query_total",
+				Help:      "Total number of database queries issued by " + issuer,
+			},
+			[]string{"query", "error"},
+		),
+		dur: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "clair",
+				Subsystem: subsystem,
+				Name:      "query_duration_seconds",
+				Help:      "Duration of database queries issued by " + issuer,
+			},
+			[]string{"query", "error"},
+		),
+	}
+}
+
+// Observe starts timing the query "name", returning a function that records it
+// with the error "err" points to. It's meant to be deferred:
+//
+//	defer m.Observe("lookup", &err)()
+func (m *QueryMetrics) Observe(name string, err *error) func() {
+	start := time.Now()
+	return func() {
+		l := ErrLabel(*err)
+		m.count.WithLabelValues(name, l).Inc()
+		m.dur.WithLabelValues(name, l).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,43 @@
+package dbutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRunFile(t *testing.T) {
+	// A missing file is reported when the migration runs, before the
+	// transaction is used.
+	if err := RunFile(fstest.MapFS{}, "01-missing.sql")(nil); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestQueryMetrics(t *testing.T) {
+	m := NewQueryMetrics("dbutil_test", "the test")
+	run := func(name string, fail error) {
+		var err error
+		defer m.Observe(name, &err)()
+		err = fail
+	}
+	run("lookup", nil)
+	run("lookup", nil)
+	run("lookup", errors.New("oops"))
+
+	const want = `
+# HELP clair_dbutil_test_query_total Total number of database queries issued by the test
+# TYPE clair_dbutil_test_query_total counter
+clair_dbutil_test_query_total{error="false",query="lookup"} 2
+clair_dbutil_test_query_total{error="true",query="lookup"} 1
+`
+	if err := testutil.CollectAndCompare(m.count, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.CollectAndCount(m.dur); got != 2 {
+		t.Errorf("got %d duration series, want 2", got)
+	}
+}
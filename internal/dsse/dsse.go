@@ -0,0 +1,188 @@
+// Package dsse implements signing and verification of Dead Simple Signing
+// Envelopes, as used by in-toto and sigstore.
+//
+// See https://github.com/secure-systems-lab/dsse for the specification.
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// MediaType is the media type of a serialized Envelope.
+const MediaType = `application/vnd.dsse.envelope.v1+json`
+
+// ErrVerify is returned when no signature in an Envelope verifies.
+var ErrVerify = errors.New("dsse: no valid signature")
+
+// Envelope is a signed payload.
+//
+// The byte slices are base64 encoded when serialized as JSON, as the
+// specification requires.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature over an Envelope's payload.
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// PAE returns the pre-authentication encoding of "payload" with type "typ",
+// which is what's actually signed.
+func PAE(typ string, payload []byte) []byte {
+	b := make([]byte, 0, 32+len(typ)+len(payload))
+	b = append(b, "DSSEv1 "...)
+	b = strconv.AppendInt(b, int64(len(typ)), 10)
+	b = append(b, ' ')
+	b = append(b, typ...)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(len(payload)), 10)
+	b = append(b, ' ')
+	b = append(b, payload...)
+	return b
+}
+
+// Signer signs payloads with a private key.
+//
+// ECDSA and RSA (PKCS #1 v1.5) signatures are made over the SHA-256 digest of
+// the pre-authentication encoding; Ed25519 signatures over the encoding
+// itself. ECDSA signatures are ASN.1 encoded.
+type Signer struct {
+	key crypto.Signer
+	id  string
+	alg string
+	pub []byte // DER encoded
+}
+
+// NewSigner returns a Signer using "key", which must be an ECDSA, Ed25519,
+// or RSA private key. If "keyID" is empty, the hex encoded SHA-256 digest of
+// the DER encoded public key is used.
+func NewSigner(key crypto.Signer, keyID string) (*Signer, error) {
+	s := Signer{key: key, id: keyID}
+	switch k := key.Public().(type) {
+	case *ecdsa.PublicKey:
+		s.alg = "ecdsa-" + k.Curve.Params().Name + "-sha256"
+	case ed25519.PublicKey:
+		s.alg = "ed25519"
+	case *rsa.PublicKey:
+		s.alg = "rsa-pkcs1v15-sha256"
+	default:
+		return nil, fmt.Errorf("dsse: unsupported key type %T", k)
+	}
+	var err error
+	s.pub, err = x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("dsse: %w", err)
+	}
+	if s.id == "" {
+		sum := sha256.Sum256(s.pub)
+		s.id = hex.EncodeToString(sum[:])
+	}
+	return &s, nil
+}
+
+// LoadSigner reads an unencrypted PEM encoded private key from the file
+// "path" and returns a Signer using it.
+func LoadSigner(path, keyID string) (*Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("dsse: %w", err)
+	}
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, fmt.Errorf("dsse: no PEM data in %q", path)
+	}
+	var key interface{}
+	switch blk.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(blk.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(blk.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(blk.Bytes)
+	default:
+		return nil, fmt.Errorf("dsse: unsupported PEM block %q in %q", blk.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dsse: %q: %w", path, err)
+	}
+	cs, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("dsse: unsupported key type %T", key)
+	}
+	return NewSigner(cs, keyID)
+}
+
+// KeyID reports the identifier recorded in signatures.
+func (s *Signer) KeyID() string { return s.id }
+
+// Algorithm reports the signature algorithm, as a short descriptive string
+// such as "ecdsa-P-256-sha256".
+func (s *Signer) Algorithm() string { return s.alg }
+
+// PublicKeyPEM returns the PEM encoded public key corresponding to the
+// signing key.
+func (s *Signer) PublicKeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: s.pub})
+}
+
+// Sign returns an Envelope containing "payload" of type "typ", signed by the
+// Signer.
+func (s *Signer) Sign(typ string, payload []byte) (*Envelope, error) {
+	msg := PAE(typ, payload)
+	var sig []byte
+	var err error
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		sig, err = s.key.Sign(rand.Reader, msg, crypto.Hash(0))
+	} else {
+		sum := sha256.Sum256(msg)
+		sig, err = s.key.Sign(rand.Reader, sum[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dsse: %w", err)
+	}
+	return &Envelope{
+		PayloadType: typ,
+		Payload:     payload,
+		Signatures:  []Signature{{KeyID: s.id, Sig: sig}},
+	}, nil
+}
+
+// Verify reports ErrVerify if none of the signatures in "e" verify with
+// "pub."
+func Verify(e *Envelope, pub crypto.PublicKey) error {
+	msg := PAE(e.PayloadType, e.Payload)
+	sum := sha256.Sum256(msg)
+	for _, s := range e.Signatures {
+		var ok bool
+		switch k := pub.(type) {
+		case *ecdsa.PublicKey:
+			ok = ecdsa.VerifyASN1(k, sum[:], s.Sig)
+		case ed25519.PublicKey:
+			ok = ed25519.Verify(k, msg, s.Sig)
+		case *rsa.PublicKey:
+			ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], s.Sig) == nil
+		default:
+			return fmt.Errorf("dsse: unsupported key type %T", k)
+		}
+		if ok {
+			return nil
+		}
+	}
+	return ErrVerify
+}
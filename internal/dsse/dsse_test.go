@@ -0,0 +1,104 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPAE(t *testing.T) {
+	// Test vector from the specification.
+	got := string(PAE("http://example.com/HelloWorld", []byte("hello world")))
+	want := "DSSEv1 29 http://example.com/HelloWorld 11 hello world"
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const typ = "application/vnd.test+json"
+	payload := []byte(`{"test":true}`)
+	for name, k := range map[string]crypto.Signer{"ECDSA": ec, "Ed25519": ed, "RSA": rk} {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSigner(k, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := s.Sign(typ, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Signatures[0].KeyID; got != s.KeyID() || got == "" {
+				t.Errorf("keyid: got: %q, want: %q", got, s.KeyID())
+			}
+			// Round-trip through JSON, as a client would see it.
+			b, err := json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Envelope
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			blk, _ := pem.Decode(s.PublicKeyPEM())
+			pub, err := x509.ParsePKIXPublicKey(blk.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Verify(&got, pub); err != nil {
+				t.Error(err)
+			}
+			got.Payload = []byte(`{"test":false}`)
+			if err := Verify(&got, pub); !errors.Is(err, ErrVerify) {
+				t.Errorf("tampered: got: %v, want: %v", err, ErrVerify)
+			}
+		})
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(f, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSigner(f, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.KeyID(), "test"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := s.Algorithm(), "ecdsa-P-256-sha256"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
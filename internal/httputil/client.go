@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -11,8 +12,11 @@
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/quay/clair/config"
 	"github.com/quay/clair/v4/cmd"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/net/publicsuffix"
 )
 
@@ -21,14 +25,54 @@
 //
 // If disallowed, the reported error will be a [*net.AddrError] with the "Err"
 // value of "disallowed by policy".
+//
+// The returned client propagates trace context on outgoing requests.
 func NewClient(ctx context.Context, localOnly bool) (*http.Client, error) {
-	tr := http.DefaultTransport.(*http.Transport).Clone()
-	dialer := &net.Dialer{}
+	return NewOutboundClient(ctx, localOnly, nil, nil)
+}
+
+// NewServiceClient constructs an [http.Client] for requests to other Clair
+// services, managing connections as described by "t", which may be nil.
+//
+// The returned client propagates trace context on outgoing requests.
+func NewServiceClient(ctx context.Context, t *config.HTTPTransport) (*http.Client, error) {
+	return newOutboundClient(ctx, nil, nil, nil, t)
+}
+
+// NewOutboundClient is like [NewClient], but sends requests through proxies as
+// described by "p" and verifies servers according to "tp". Either may be nil.
+//
+// If localOnly is set, "p" is ignored: sending requests through a proxy would
+// defeat the restriction.
+func NewOutboundClient(ctx context.Context, localOnly bool, p *config.Proxy, tp *config.TLSPolicy) (*http.Client, error) {
 	// Set a control function if we're restricting subnets.
 	if localOnly {
-		dialer.Control = ctlLocalOnly
+		return newOutboundClient(ctx, ctlLocalOnly, nil, tp, nil)
+	}
+	return newOutboundClient(ctx, nil, p, tp, nil)
+}
+
+// NewOutboundClient builds a client whose connections are checked by "ctl",
+// if not nil.
+func newOutboundClient(_ context.Context, ctl func(string, string, syscall.RawConn) error, p *config.Proxy, tp *config.TLSPolicy, t *config.HTTPTransport) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	applyTransport(tr, t)
+	if tp != nil {
+		tc, err := tp.Config()
+		if err != nil {
+			return nil, err
+		}
+		tr.TLSClientConfig = tc
 	}
+	dialer := &net.Dialer{Control: ctl}
 	tr.DialContext = dialer.DialContext
+	if p != nil {
+		f, err := ProxyFunc(p)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = f
+	}
 
 	jar, err := cookiejar.New(&cookiejar.Options{
 		PublicSuffixList: publicsuffix.List,
@@ -37,11 +81,59 @@ func NewClient(ctx context.Context, localOnly bool) (*http.Client, error) {
 		return nil, err
 	}
 	return &http.Client{
-		Transport: tr,
+		Transport: otelhttp.NewTransport(tr),
 		Jar:       jar,
 	}, nil
 }
 
+// NewUnixClient constructs an [http.Client] that sends every request over the
+// Unix domain socket at "path", regardless of the request's host. Connections
+// are managed as described by "t", which may be nil.
+//
+// The returned client propagates trace context on outgoing requests.
+func NewUnixClient(_ context.Context, path string, t *config.HTTPTransport) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	applyTransport(tr, t)
+	tr.Proxy = nil
+	dialer := &net.Dialer{}
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+
+	jar, err := cookiejar.New(&cookiejar.Options{
+		PublicSuffixList: publicsuffix.List,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: otelhttp.NewTransport(tr),
+		Jar:       jar,
+	}, nil
+}
+
+// ApplyTransport modifies "tr" according to "t". If "t" is nil, "tr" is left
+// unmodified.
+func applyTransport(tr *http.Transport, t *config.HTTPTransport) {
+	if t == nil {
+		return
+	}
+	if t.DisableHTTP2 {
+		// A non-nil, empty map disables the automatic HTTP/2 upgrade.
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	if n := t.MaxIdleConnsPerHost; n > 0 {
+		tr.MaxIdleConnsPerHost = n
+		if tr.MaxIdleConns != 0 && tr.MaxIdleConns < n {
+			tr.MaxIdleConns = n
+		}
+	}
+	if d := time.Duration(t.IdleConnTimeout); d > 0 {
+		tr.IdleConnTimeout = d
+	}
+}
+
 func ctlLocalOnly(network, address string, _ syscall.RawConn) error {
 	// Future-proof for QUIC by allowing UDP here.
 	if !strings.HasPrefix(network, "tcp") && !strings.HasPrefix(network, "udp") {
@@ -1,9 +1,16 @@
 package httputil
 
 import (
+	"context"
 	"errors"
+	"io"
 	"net"
+	"net/http"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
 )
 
 func TestLocalOnly(t *testing.T) {
@@ -55,3 +62,69 @@ func TestLocalOnly(t *testing.T) {
 		}
 	}
 }
+
+func TestUnixClient(t *testing.T) {
+	ctx := context.Background()
+	p := filepath.Join(t.TempDir(), "test.sock")
+	l, err := net.Listen("unix", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, r.URL.Path)
+		}),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Serve(l)
+	}()
+	defer func() { <-done }()
+	defer srv.Close()
+
+	c, err := NewUnixClient(ctx, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Get("http://localhost/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "/ok"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestApplyTransport(t *testing.T) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	applyTransport(tr, &config.HTTPTransport{
+		DisableHTTP2:        true,
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     config.Duration(5 * time.Minute),
+	})
+	if tr.ForceAttemptHTTP2 || tr.TLSNextProto == nil {
+		t.Error("HTTP/2 not disabled")
+	}
+	if got, want := tr.MaxIdleConnsPerHost, 200; got != want {
+		t.Errorf("MaxIdleConnsPerHost: got: %d, want: %d", got, want)
+	}
+	if got, want := tr.MaxIdleConns, 200; got < want {
+		t.Errorf("MaxIdleConns: got: %d, want: >=%d", got, want)
+	}
+	if got, want := tr.IdleConnTimeout, 5*time.Minute; got != want {
+		t.Errorf("IdleConnTimeout: got: %v, want: %v", got, want)
+	}
+
+	def := http.DefaultTransport.(*http.Transport)
+	tr = def.Clone()
+	applyTransport(tr, nil)
+	if tr.MaxIdleConnsPerHost != def.MaxIdleConnsPerHost || tr.ForceAttemptHTTP2 != def.ForceAttemptHTTP2 {
+		t.Error("nil transport settings modified transport")
+	}
+}
@@ -0,0 +1,201 @@
+package httputil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+// FaultsEnv is the environment variable read for fault injection rules.
+//
+// Its value is a semicolon-separated list of rules. A rule is a target
+// followed by a colon and comma-separated key=value pairs:
+//
+//	layers:latency=2s,error=0.1;updaters:reset=0.5
+//
+// The keys are "latency", a delay applied to every request; "error", the
+// probability of responding "503 Service Unavailable" without making the
+// request; and "reset", the probability of failing the request as if the
+// connection was reset.
+//
+// It's only consulted in binaries built with the "faultinject" tag.
+const FaultsEnv = `CLAIR_FAULTS`
+
+// Fault injection targets.
+const (
+	FaultLayers   = `layers`
+	FaultUpdaters = `updaters`
+	FaultNotifier = `notifier`
+)
+
+var faultCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "http",
+		Name:      "injected_faults_total",
+		Help:      "Total number of faults injected into outgoing requests.",
+	},
+	[]string{"target", "fault"},
+)
+
+var faultRules struct {
+	sync.Once
+	rules map[string]faultRule
+	err   error
+}
+
+// Faults wraps the provided RoundTripper so that requests fail according to
+// the rule for "target" in the FaultsEnv environment variable.
+//
+// In binaries built without the "faultinject" tag, or if there's no rule for
+// "target", "next" is returned unchanged. An error is reported if the rules
+// can't be parsed.
+func Faults(ctx context.Context, next http.RoundTripper, target string) (http.RoundTripper, error) {
+	if !faultInjection {
+		return next, nil
+	}
+	faultRules.Do(func() {
+		faultRules.rules, faultRules.err = parseFaults(os.Getenv(FaultsEnv))
+	})
+	if err := faultRules.err; err != nil {
+		return nil, err
+	}
+	r, ok := faultRules.rules[target]
+	if !ok {
+		return next, nil
+	}
+	zlog.Warn(ctx).
+		Str("target", target).
+		Stringer("latency", r.Latency).
+		Float64("error", r.Error).
+		Float64("reset", r.Reset).
+		Msg("FAULT INJECTION ENABLED. REQUESTS WILL FAIL DELIBERATELY")
+	return &faults{
+		rt:     next,
+		target: target,
+		rule:   r,
+		rand:   rand.Float64,
+	}, nil
+}
+
+// FaultRule describes the faults injected for a target.
+type faultRule struct {
+	Latency time.Duration
+	Error   float64
+	Reset   float64
+}
+
+// ParseFaults parses the rules described by FaultsEnv.
+func parseFaults(spec string) (map[string]faultRule, error) {
+	rules := make(map[string]faultRule)
+	for _, s := range strings.Split(spec, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		target, kvs, ok := strings.Cut(s, ":")
+		if !ok || target == "" {
+			return nil, fmt.Errorf("httputil: bad fault rule %q: missing target", s)
+		}
+		var r faultRule
+		for _, kv := range strings.Split(kvs, ",") {
+			k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+			if !ok {
+				return nil, fmt.Errorf("httputil: bad fault rule %q: bad pair %q", s, kv)
+			}
+			var err error
+			switch k {
+			case "latency":
+				r.Latency, err = time.ParseDuration(v)
+			case "error":
+				r.Error, err = parseProbability(v)
+			case "reset":
+				r.Reset, err = parseProbability(v)
+			default:
+				err = fmt.Errorf("unknown fault %q", k)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("httputil: bad fault rule %q: %w", s, err)
+			}
+		}
+		rules[target] = r
+	}
+	return rules, nil
+}
+
+func parseProbability(v string) (float64, error) {
+	p, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("probability %v out of range [0, 1]", p)
+	}
+	return p, nil
+}
+
+type faults struct {
+	rt     http.RoundTripper
+	target string
+	rule   faultRule
+	rand   func() float64
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *faults) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if d := f.rule.Latency; d > 0 {
+		faultCounter.WithLabelValues(f.target, "latency").Inc()
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, context.Cause(ctx)
+		case <-t.C:
+		}
+	}
+	p := f.rand()
+	if p < f.rule.Reset+f.rule.Error && req.Body != nil {
+		req.Body.Close()
+	}
+	switch {
+	case p < f.rule.Reset:
+		faultCounter.WithLabelValues(f.target, "reset").Inc()
+		return nil, &net.OpError{
+			Op:  "read",
+			Net: "tcp",
+			Err: os.NewSyscallError("read", syscall.ECONNRESET),
+		}
+	case p < f.rule.Reset+f.rule.Error:
+		faultCounter.WithLabelValues(f.target, "error").Inc()
+		const msg = "injected fault\n"
+		return &http.Response{
+			Status:        "503 Service Unavailable",
+			StatusCode:    http.StatusServiceUnavailable,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			Body:          io.NopCloser(strings.NewReader(msg)),
+			ContentLength: int64(len(msg)),
+			Request:       req,
+		}, nil
+	}
+	return f.rt.RoundTrip(req)
+}
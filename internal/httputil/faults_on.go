@@ -0,0 +1,6 @@
+//go:build faultinject
+
+package httputil
+
+// FaultInjection reports whether Faults consults FaultsEnv.
+const faultInjection = true
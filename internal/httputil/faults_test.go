@@ -0,0 +1,115 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFaults(t *testing.T) {
+	got, err := parseFaults(" layers:latency=2s,error=0.1 ; updaters:reset=1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]faultRule{
+		"layers":   {Latency: 2 * time.Second, Error: 0.1},
+		"updaters": {Reset: 1},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	for _, bad := range []string{
+		"latency=2s",
+		"layers:latency",
+		"layers:latency=soon",
+		"layers:error=2",
+		"layers:explode=1",
+	} {
+		if _, err := parseFaults(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	do := func(t *testing.T, r faultRule, p float64) (*http.Response, error) {
+		rt := &faults{
+			rt:     srv.Client().Transport,
+			target: "test",
+			rule:   r,
+			rand:   func() float64 { return p },
+		}
+		req, err := NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := rt.RoundTrip(req)
+		if err == nil {
+			res.Body.Close()
+		}
+		return res, err
+	}
+	rule := faultRule{Reset: 0.2, Error: 0.3}
+
+	t.Run("Reset", func(t *testing.T) {
+		_, err := do(t, rule, 0.1)
+		if !errors.Is(err, syscall.ECONNRESET) {
+			t.Errorf("got: %v, want: %v", err, syscall.ECONNRESET)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		res, err := do(t, rule, 0.4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := res.StatusCode, http.StatusServiceUnavailable; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("Pass", func(t *testing.T) {
+		res, err := do(t, rule, 0.6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := res.StatusCode, http.StatusNoContent; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("Latency", func(t *testing.T) {
+		const d = 50 * time.Millisecond
+		start := time.Now()
+		if _, err := do(t, faultRule{Latency: d}, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got := time.Since(start); got < d {
+			t.Errorf("got: %v, want: >=%v", got, d)
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		if faultInjection {
+			t.Skip("built with fault injection")
+		}
+		t.Setenv(FaultsEnv, "test:reset=1")
+		next := srv.Client().Transport
+		rt, err := Faults(ctx, next, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rt != next {
+			t.Error("expected unwrapped RoundTripper")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package httputil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// FetchLimiter wraps the provided RoundTripper with limits suitable for
+// downloading large blobs.
+//
+// At most "concurrency" requests are allowed to be in flight at once, where a
+// request is considered in flight until its response body is closed. All
+// response bodies share a single bandwidth budget of "bps" bytes per second.
+// A non-positive value for either disables that limit.
+func FetchLimiter(next http.RoundTripper, concurrency int, bps int64) http.RoundTripper {
+	if concurrency <= 0 && bps <= 0 {
+		return next
+	}
+	f := &fetchlimiter{rt: next, lim: bandwidthLimiter(bps)}
+	if concurrency > 0 {
+		f.sem = make(chan struct{}, concurrency)
+	}
+	return f
+}
+
+// FetchChunk is the largest read a limited body will issue at once.
+const fetchChunk = 64 * 1024
+
+// Fetchlimiter implements the limiting by using a channel as a semaphore and a
+// shared Limiter.
+type fetchlimiter struct {
+	rt  http.RoundTripper
+	sem chan struct{}
+	lim *rate.Limiter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *fetchlimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	release := func() {}
+	if f.sem != nil {
+		select {
+		case f.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-f.sem }) }
+	}
+	res, err := f.rt.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &limitedBody{
+		ctx:     ctx,
+		rc:      res.Body,
+		lim:     f.lim,
+		release: release,
+	}
+	return res, nil
+}
+
+// LimitedBody throttles reads according to a shared Limiter and releases its
+// concurrency slot on Close.
+type limitedBody struct {
+	ctx     context.Context
+	rc      io.ReadCloser
+	lim     *rate.Limiter
+	release func()
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.lim == nil {
+		return b.rc.Read(p)
+	}
+	if max := b.lim.Burst(); len(p) > max {
+		p = p[:max]
+	}
+	n, err := b.rc.Read(p)
+	if n > 0 {
+		if werr := b.lim.WaitN(b.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *limitedBody) Close() error {
+	defer b.release()
+	return b.rc.Close()
+}
@@ -0,0 +1,103 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchLimiterConcurrency(t *testing.T) {
+	const (
+		nReq  = 10
+		limit = 2
+	)
+	var cur, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		defer atomic.AddInt32(&cur, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	cl := srv.Client()
+	cl.Transport = FetchLimiter(cl.Transport, limit, 0)
+
+	var wg sync.WaitGroup
+	wg.Add(nReq)
+	for i := 0; i < nReq; i++ {
+		go func() {
+			defer wg.Done()
+			res, err := cl.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	t.Logf("peak: %d", peak)
+	if peak > limit {
+		t.Error("concurrency exceeded limit")
+	}
+}
+
+func TestFetchLimiterBandwidth(t *testing.T) {
+	const (
+		size = 64 * 1024
+		bps  = 128 * 1024
+	)
+	payload := bytes.Repeat([]byte{'x'}, size)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(payload)
+	}))
+	defer srv.Close()
+	cl := srv.Client()
+	cl.Transport = FetchLimiter(cl.Transport, 0, bps)
+
+	var wg sync.WaitGroup
+	const nReq = 4
+	wg.Add(nReq)
+	begin := time.Now()
+	for i := 0; i < nReq; i++ {
+		go func() {
+			defer wg.Done()
+			res, err := cl.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer res.Body.Close()
+			n, err := io.Copy(io.Discard, res.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if n != size {
+				t.Errorf("short read: %d", n)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(begin)
+
+	// The first burst is free, so the remaining bytes should take at least
+	// this long at the configured rate.
+	floor := time.Duration(float64(nReq*size-fetchChunk) / bps * float64(time.Second))
+	t.Logf("elapsed: %v, floor: %v", elapsed, floor)
+	if elapsed < floor-50*time.Millisecond {
+		t.Error("bandwidth exceeded limit")
+	}
+}
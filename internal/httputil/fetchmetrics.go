@@ -0,0 +1,90 @@
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fetchBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "indexer",
+			Name:      "layer_fetch_bytes_total",
+			Help:      "Total number of bytes read from layer fetches.",
+		},
+		[]string{"host"},
+	)
+	fetchDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "indexer",
+			Name:      "layer_fetch_duration_seconds",
+			Help:      "Duration of layer fetches, from request until the body is closed.",
+			Buckets:   []float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{"host", "code"},
+	)
+)
+
+// FetchMetrics wraps the provided RoundTripper to record the number of bytes
+// read and the time taken for each request, labeled by host.
+//
+// A request is timed until its response body is closed.
+func FetchMetrics(next http.RoundTripper) http.RoundTripper {
+	return &fetchmetrics{rt: next}
+}
+
+// Fetchmetrics implements the metrics collection.
+type fetchmetrics struct {
+	rt http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *fetchmetrics) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	start := time.Now()
+	res, err := f.rt.RoundTrip(req)
+	if err != nil {
+		fetchDuration.WithLabelValues(host, "error").Observe(time.Since(start).Seconds())
+		return nil, err
+	}
+	res.Body = &meteredBody{
+		rc:    res.Body,
+		bytes: fetchBytes.WithLabelValues(host),
+		done: func() {
+			fetchDuration.WithLabelValues(host, strconv.Itoa(res.StatusCode)).
+				Observe(time.Since(start).Seconds())
+		},
+	}
+	return res, nil
+}
+
+// MeteredBody counts bytes read and reports the request done on Close.
+type meteredBody struct {
+	rc    io.ReadCloser
+	bytes prometheus.Counter
+	done  func()
+	once  sync.Once
+}
+
+// Read implements io.Reader.
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if n > 0 {
+		b.bytes.Add(float64(n))
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *meteredBody) Close() error {
+	b.once.Do(b.done)
+	return b.rc.Close()
+}
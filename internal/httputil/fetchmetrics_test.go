@@ -0,0 +1,43 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFetchMetrics(t *testing.T) {
+	const sz = 4096
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(bytes.Repeat([]byte{'a'}, sz))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := &http.Client{Transport: FetchMetrics(srv.Client().Transport)}
+
+	before := testutil.ToFloat64(fetchBytes.WithLabelValues(u.Host))
+	res, err := cl.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		t.Error(err)
+	}
+	res.Body.Close()
+	res.Body.Close() // Closing twice shouldn't double-count.
+
+	if got, want := testutil.ToFloat64(fetchBytes.WithLabelValues(u.Host))-before, float64(sz); got != want {
+		t.Errorf("bytes: got: %v, want: %v", got, want)
+	}
+	if got, want := testutil.CollectAndCount(fetchDuration), 1; got != want {
+		t.Errorf("duration series: got: %v, want: %v", got, want)
+	}
+}
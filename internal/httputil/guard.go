@@ -0,0 +1,208 @@
+package httputil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/quay/clair/config"
+)
+
+// Blocked are networks that a Guard refuses connections to unless they're
+// explicitly allowed: link-local, unspecified, and multicast addresses, and
+// cloud metadata services outside of those.
+var blocked = mustCIDRs(
+	"0.0.0.0/8",
+	"169.254.0.0/16",
+	"224.0.0.0/4",
+	"100.100.100.200/32", // Alibaba Cloud metadata
+	"::/128",
+	"fe80::/10",
+	"ff00::/8",
+	"fd00:ec2::254/128", // AWS IMDS over IPv6
+)
+
+func mustCIDRs(ss ...string) []*net.IPNet {
+	out := make([]*net.IPNet, len(ss))
+	for i, s := range ss {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+		out[i] = n
+	}
+	return out
+}
+
+// Guard restricts the hosts and addresses requests may be sent to.
+//
+// The zero value refuses only the blocked networks.
+type Guard struct {
+	hosts []string
+	nets  []*net.IPNet
+}
+
+// NewGuard returns a Guard allowing requests to hosts matching one of
+// "hosts" and connections to addresses in one of "networks". Empty lists
+// allow any host or any address that isn't blocked.
+//
+// Host patterns are hostnames, or a "*." prefix followed by a domain to match
+// any subdomain. Networks are CIDR blocks or single addresses.
+func NewGuard(hosts, networks []string) (*Guard, error) {
+	g := Guard{
+		hosts: make([]string, len(hosts)),
+		nets:  make([]*net.IPNet, 0, len(networks)),
+	}
+	for i, h := range hosts {
+		g.hosts[i] = strings.ToLower(h)
+	}
+	for _, s := range networks {
+		n, err := parseNetwork(s)
+		if err != nil {
+			return nil, err
+		}
+		g.nets = append(g.nets, n)
+	}
+	return &g, nil
+}
+
+func parseNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("httputil: invalid address %q", s)
+		}
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("httputil: invalid network %q: %w", s, err)
+	}
+	return n, nil
+}
+
+// CheckURL reports an error if requests to "u" are disallowed.
+//
+// Literal addresses are checked as at connection time, so a URL using a
+// literal address that's blocked is refused even if a proxy is in use.
+func (g *Guard) CheckURL(u *url.URL) error {
+	host := strings.ToLower(u.Hostname())
+	if len(g.hosts) != 0 && !g.matchHost(host) {
+		return &net.AddrError{
+			Addr: host,
+			Err:  "disallowed by policy",
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return g.checkAddr(ip, u.Host)
+	}
+	return nil
+}
+
+func (g *Guard) matchHost(host string) bool {
+	for _, pat := range g.hosts {
+		if d := strings.TrimPrefix(pat, "*"); d != pat {
+			if strings.HasSuffix(host, d) && len(host) > len(d) {
+				return true
+			}
+			continue
+		}
+		if host == pat {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Guard) checkAddr(ip net.IP, addr string) error {
+	for _, n := range g.nets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if len(g.nets) != 0 {
+		return &net.AddrError{
+			Addr: addr,
+			Err:  "disallowed by policy",
+		}
+	}
+	for _, n := range blocked {
+		if n.Contains(ip) {
+			return &net.AddrError{
+				Addr: addr,
+				Err:  "disallowed by policy",
+			}
+		}
+	}
+	return nil
+}
+
+// Control is a [net.Dialer.Control] function checking the address being
+// connected to. Checking here, rather than when the URL is resolved, means a
+// DNS record can't be changed to point at a disallowed address after the
+// fact.
+func (g *Guard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &net.AddrError{
+			Addr: network + "!" + address,
+			Err:  "martian address",
+		}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return &net.AddrError{
+			Addr: network + "!" + address,
+			Err:  "martian address",
+		}
+	}
+	return g.checkAddr(ip, network+"!"+address)
+}
+
+// NewGuardedClient is like [NewOutboundClient], but requests and connections
+// are checked against "g".
+//
+// If a proxy is configured, connections may be made to the proxy rather than
+// the requested host, so "g" only checks the requested URLs and connections
+// are only checked against the blocked networks.
+func NewGuardedClient(ctx context.Context, g *Guard, p *config.Proxy, tp *config.TLSPolicy, t *config.HTTPTransport) (*http.Client, error) {
+	ctl := g.control
+	if p != nil {
+		ctl = (&Guard{}).control
+	}
+	c, err := newOutboundClient(ctx, ctl, p, tp, t)
+	if err != nil {
+		return nil, err
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+		return g.CheckURL(req.URL)
+	}
+	c.Transport = &guardedTransport{next: c.Transport, g: g}
+	return c, nil
+}
+
+type guardedTransport struct {
+	next http.RoundTripper
+	g    *Guard
+}
+
+func (t *guardedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := t.g.CheckURL(r.URL); err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,136 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGuardURL(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Hosts    []string
+		Networks []string
+		URL      string
+		OK       bool
+	}{
+		{Name: "Default", URL: "https://hooks.example.com/", OK: true},
+		{Name: "Metadata", URL: "http://169.254.169.254/latest/meta-data/"},
+		{Name: "MetadataV6", URL: "http://[fd00:ec2::254]/"},
+		{Name: "LinkLocal", URL: "http://[fe80::1]:8080/"},
+		{Name: "Loopback", URL: "http://127.0.0.1:8080/", OK: true},
+		{
+			Name:  "Host",
+			Hosts: []string{"Hooks.Example.com"},
+			URL:   "https://hooks.example.com/",
+			OK:    true,
+		},
+		{
+			Name:  "OtherHost",
+			Hosts: []string{"hooks.example.com"},
+			URL:   "https://evil.example.com/",
+		},
+		{
+			Name:  "Wildcard",
+			Hosts: []string{"*.example.com"},
+			URL:   "https://a.b.example.com/",
+			OK:    true,
+		},
+		{
+			Name:  "WildcardApex",
+			Hosts: []string{"*.example.com"},
+			URL:   "https://example.com/",
+		},
+		{
+			Name:  "WildcardSuffix",
+			Hosts: []string{"*.example.com"},
+			URL:   "https://notexample.com/",
+		},
+		{
+			Name:     "Network",
+			Networks: []string{"10.0.0.0/8"},
+			URL:      "http://10.1.2.3/",
+			OK:       true,
+		},
+		{
+			Name:     "OtherNetwork",
+			Networks: []string{"10.0.0.0/8"},
+			URL:      "http://192.168.0.1/",
+		},
+		{
+			Name:     "AllowedLinkLocal",
+			Networks: []string{"169.254.10.1"},
+			URL:      "http://169.254.10.1/",
+			OK:       true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			g, err := NewGuard(tc.Hosts, tc.Networks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(tc.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = g.CheckURL(u)
+			if got, want := err == nil, tc.OK; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			var aErr *net.AddrError
+			if err != nil && !errors.As(err, &aErr) {
+				t.Errorf("unexpected error type: %T", err)
+			}
+		})
+	}
+
+	if _, err := NewGuard(nil, []string{"10.0.0.0/33"}); err == nil {
+		t.Error("bad network: unexpected success")
+	}
+}
+
+func TestGuardedClient(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	do := func(nets []string, path string) error {
+		t.Helper()
+		g, err := NewGuard(nil, nets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewGuardedClient(ctx, g, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Get(srv.URL + path)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		return nil
+	}
+
+	if err := do(nil, "/"); err != nil {
+		t.Errorf("default: %v", err)
+	}
+	if err := do([]string{"127.0.0.0/8"}, "/"); err != nil {
+		t.Errorf("allowed network: %v", err)
+	}
+	if err := do([]string{"10.0.0.0/8"}, "/"); err == nil {
+		t.Error("disallowed network: unexpected success")
+	}
+	if err := do(nil, "/redirect"); err == nil {
+		t.Error("redirect to metadata: unexpected success")
+	}
+}
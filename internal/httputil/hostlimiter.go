@@ -0,0 +1,207 @@
+package httputil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+	"golang.org/x/time/rate"
+)
+
+// HostLimiter wraps the provided RoundTripper with per-host limits suitable
+// for downloading large blobs, and waits out throttling responses.
+//
+// Each request is limited by the entry in "hosts" for the request's host,
+// falling back to the "*" entry. Redirected requests count against the host
+// of the original request, so that downloads served from a registry's
+// storage backend are limited along with the registry.
+//
+// A 429 response, or a 503 response with a Retry-After header, pauses
+// requests to the host for as long as the server asks, up to a limit, or with
+// an exponential backoff if it doesn't say. GET and HEAD requests are then
+// retried, up to a small number of times and as long as the requested pause
+// isn't unreasonably long; otherwise the response is returned as-is.
+func HostLimiter(next http.RoundTripper, hosts []config.LayerFetchHost) http.RoundTripper {
+	h := &hostlimiter{
+		rt:    next,
+		conf:  make(map[string]config.LayerFetchHost, len(hosts)),
+		state: make(map[string]*hostState),
+	}
+	for _, c := range hosts {
+		h.conf[c.Host] = c
+	}
+	return h
+}
+
+const (
+	// ThrottleRetries is the number of times a throttled request is retried.
+	throttleRetries = 5
+	// MaxRetryAfter is the longest pause a request will wait out before being
+	// retried.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Hostlimiter implements the limiting by keeping a semaphore, Limiter, and
+// pause deadline per host.
+type hostlimiter struct {
+	rt   http.RoundTripper
+	conf map[string]config.LayerFetchHost
+
+	mu    sync.Mutex
+	state map[string]*hostState
+}
+
+type hostState struct {
+	sem chan struct{}
+	lim *rate.Limiter
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// Host returns the state for "host", creating it if needed.
+func (l *hostlimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.state[host]; ok {
+		return s
+	}
+	c, ok := l.conf[host]
+	if !ok {
+		c = l.conf["*"]
+	}
+	s := &hostState{lim: bandwidthLimiter(c.Bandwidth)}
+	if c.Concurrency > 0 {
+		s.sem = make(chan struct{}, c.Concurrency)
+	}
+	l.state[host] = s
+	return s
+}
+
+// RoundTrip implements http.RoundTripper.
+func (l *hostlimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	orig := req
+	for orig.Response != nil && orig.Response.Request != nil {
+		orig = orig.Response.Request
+	}
+	h := l.host(orig.URL.Host)
+	release := func() {}
+	if h.sem != nil {
+		select {
+		case h.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-h.sem }) }
+	}
+	retryable := (req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		(req.Body == nil || req.Body == http.NoBody)
+
+	for attempt := 0; ; attempt++ {
+		if err := h.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+		res, err := l.rt.RoundTrip(req)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		d, ok := retryAfter(res, attempt)
+		if ok {
+			p := d
+			if p > maxRetryAfter {
+				p = maxRetryAfter
+			}
+			h.pause(p)
+		}
+		if !ok || !retryable || attempt >= throttleRetries || d > maxRetryAfter {
+			res.Body = &limitedBody{
+				ctx:     ctx,
+				rc:      res.Body,
+				lim:     h.lim,
+				release: release,
+			}
+			return res, nil
+		}
+		zlog.Info(ctx).
+			Str("component", "internal/httputil/hostlimiter.RoundTrip").
+			Str("host", orig.URL.Host).
+			Str("url", req.URL.Redacted()).
+			Int("status", res.StatusCode).
+			Stringer("wait", d).
+			Int("attempt", attempt+1).
+			Msg("throttled, retrying")
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+		res.Body.Close()
+	}
+}
+
+// Wait blocks until the host's pause, if any, is over.
+func (h *hostState) wait(ctx context.Context) error {
+	h.mu.Lock()
+	d := time.Until(h.until)
+	h.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+	return nil
+}
+
+// Pause holds requests to the host for "d".
+func (h *hostState) pause(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if u := time.Now().Add(d); u.After(h.until) {
+		h.until = u
+	}
+}
+
+// RetryAfter reports whether "res" is a throttling response and how long the
+// server asked to wait, falling back to a backoff based on "attempt".
+func retryAfter(res *http.Response, attempt int) (time.Duration, bool) {
+	ra := res.Header.Get("retry-after")
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+	case res.StatusCode == http.StatusServiceUnavailable && ra != "":
+	default:
+		return 0, false
+	}
+	if s, err := strconv.Atoi(ra); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return time.Second << attempt, true
+}
+
+// BandwidthLimiter returns a Limiter allowing "bps" bytes per second, or nil
+// if "bps" is non-positive.
+func bandwidthLimiter(bps int64) *rate.Limiter {
+	if bps <= 0 {
+		return nil
+	}
+	burst := fetchChunk
+	if bps < int64(burst) {
+		burst = int(bps)
+	}
+	return rate.NewLimiter(rate.Limit(bps), burst)
+}
@@ -0,0 +1,137 @@
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+)
+
+func TestHostLimiterConcurrency(t *testing.T) {
+	const nReq = 8
+	// Track the peak concurrency seen by each server.
+	newSrv := func(peak *int32) *httptest.Server {
+		var cur int32
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			n := atomic.AddInt32(&cur, 1)
+			defer atomic.AddInt32(&cur, -1)
+			for {
+				p := atomic.LoadInt32(peak)
+				if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	var limitedPeak, otherPeak int32
+	limited, other := newSrv(&limitedPeak), newSrv(&otherPeak)
+	defer limited.Close()
+	defer other.Close()
+	u, err := url.Parse(limited.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := limited.Client()
+	cl.Transport = HostLimiter(cl.Transport, []config.LayerFetchHost{
+		{Host: u.Host, Concurrency: 1},
+		{Host: "*", Concurrency: 3},
+	})
+
+	var wg sync.WaitGroup
+	for _, srv := range []*httptest.Server{limited, other} {
+		for i := 0; i < nReq; i++ {
+			wg.Add(1)
+			go func(u string) {
+				defer wg.Done()
+				res, err := cl.Get(u)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}(srv.URL)
+		}
+	}
+	wg.Wait()
+
+	t.Logf("peaks: %d, %d", limitedPeak, otherPeak)
+	if limitedPeak > 1 {
+		t.Error("concurrency exceeded host limit")
+	}
+	if otherPeak > 3 {
+		t.Error("concurrency exceeded default limit")
+	}
+}
+
+func TestHostLimiterRetryAfter(t *testing.T) {
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/long":
+			w.Header().Set("retry-after", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		case "/unavailable":
+			// No Retry-After, so not a throttling response.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if atomic.AddInt32(&n, 1) == 1 {
+			w.Header().Set("retry-after", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	cl := srv.Client()
+	cl.Transport = HostLimiter(cl.Transport, nil)
+
+	t.Run("Retry", func(t *testing.T) {
+		begin := time.Now()
+		res, err := cl.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		if el := time.Since(begin); el < time.Second {
+			t.Errorf("retried after %v, want at least 1s", el)
+		}
+		if got, want := atomic.LoadInt32(&n), int32(2); got != want {
+			t.Errorf("got: %d requests, want: %d", got, want)
+		}
+	})
+	t.Run("Unavailable", func(t *testing.T) {
+		res, err := cl.Get(srv.URL + "/unavailable")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusServiceUnavailable; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	// This pauses the host, so it must run last.
+	t.Run("TooLong", func(t *testing.T) {
+		res, err := cl.Get(srv.URL + "/long")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusTooManyRequests; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
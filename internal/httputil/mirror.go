@@ -0,0 +1,142 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+// ErrChecksum is reported when a response from a mirror doesn't match its
+// published checksum, or a resumed download doesn't match its digest.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Mirror wraps the provided RoundTripper so that requests for URLs matching a
+// mirror's "From" prefix are sent to the mirror instead.
+//
+// If the mirror has a "ChecksumSuffix", successful GET responses are verified
+// against the published digest, and reading the body reports an error
+// wrapping ErrChecksum if they don't match.
+func Mirror(next http.RoundTripper, ms []config.UpdaterMirror) http.RoundTripper {
+	if len(ms) == 0 {
+		return next
+	}
+	return &mirror{rt: next, ms: ms}
+}
+
+type mirror struct {
+	rt http.RoundTripper
+	ms []config.UpdaterMirror
+}
+
+// RoundTrip implements http.RoundTripper.
+func (m *mirror) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := zlog.ContextWithValues(req.Context(), "component", "internal/httputil/mirror.RoundTrip")
+	orig := req.URL.String()
+	var mm *config.UpdaterMirror
+	for i := range m.ms {
+		if strings.HasPrefix(orig, m.ms[i].From) {
+			mm = &m.ms[i]
+			break
+		}
+	}
+	if mm == nil {
+		return m.rt.RoundTrip(req)
+	}
+	u, err := url.Parse(mm.To + strings.TrimPrefix(orig, mm.From))
+	if err != nil {
+		return nil, fmt.Errorf("mirror: %w", err)
+	}
+	zlog.Debug(ctx).
+		Str("from", req.URL.Redacted()).
+		Str("to", u.Redacted()).
+		Msg("using mirror")
+	r := req.Clone(req.Context())
+	r.URL = u
+	r.Host = ""
+
+	var want []byte
+	if mm.ChecksumSuffix != "" && r.Method == http.MethodGet {
+		want, err = m.checksum(r.Context(), u.String()+mm.ChecksumSuffix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := m.rt.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if want != nil && res.StatusCode == http.StatusOK {
+		res.Body = &verifiedBody{
+			rc:   res.Body,
+			h:    sha256.New(),
+			want: want,
+			url:  u.Redacted(),
+		}
+	}
+	return res, nil
+}
+
+// Checksum fetches and decodes the digest published at "u".
+func (m *mirror) checksum(ctx context.Context, u string) ([]byte, error) {
+	req, err := NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.rt.RoundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("mirror: fetching checksum: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mirror: fetching checksum %s: unexpected status: %s", req.URL.Redacted(), res.Status)
+	}
+	// A hex digest, optionally followed by whitespace and a file name.
+	b, err := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if err != nil {
+		return nil, fmt.Errorf("mirror: fetching checksum: %w", err)
+	}
+	f := strings.Fields(string(b))
+	if len(f) == 0 {
+		return nil, fmt.Errorf("mirror: empty checksum file %s", req.URL.Redacted())
+	}
+	sum, err := hex.DecodeString(f[0])
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("mirror: malformed checksum file %s", req.URL.Redacted())
+	}
+	return sum, nil
+}
+
+// VerifiedBody reports an error at EOF if the bytes read don't match the
+// expected digest.
+type verifiedBody struct {
+	rc   io.ReadCloser
+	h    hash.Hash
+	want []byte
+	url  string
+}
+
+// Read implements io.Reader.
+func (b *verifiedBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.h.Write(p[:n])
+	if errors.Is(err, io.EOF) && !bytes.Equal(b.h.Sum(nil), b.want) {
+		return n, fmt.Errorf("%w: %s", ErrChecksum, b.url)
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *verifiedBody) Close() error {
+	return b.rc.Close()
+}
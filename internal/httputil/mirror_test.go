@@ -0,0 +1,67 @@
+package httputil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/clair/config"
+)
+
+func TestMirror(t *testing.T) {
+	const body = "vulnerability data"
+	sum := sha256.Sum256([]byte(body))
+	var got []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mirror/feed.json", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path)
+		io.WriteString(w, body)
+	})
+	mux.HandleFunc("/mirror/feed.json.sha256", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, hex.EncodeToString(sum[:])+"  feed.json\n")
+	})
+	mux.HandleFunc("/mirror/bad.json", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "tampered")
+	})
+	mux.HandleFunc("/mirror/bad.json.sha256", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, hex.EncodeToString(sum[:]))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &http.Client{
+		Transport: Mirror(srv.Client().Transport, []config.UpdaterMirror{
+			{From: "https://upstream.example.com/data/", To: srv.URL + "/mirror/", ChecksumSuffix: ".sha256"},
+		}),
+	}
+
+	res, err := c.Get("https://upstream.example.com/data/feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != body {
+		t.Errorf("got: %q, want: %q", string(b), body)
+	}
+	if len(got) != 1 || got[0] != "/mirror/feed.json" {
+		t.Errorf("mirror requests: got: %v", got)
+	}
+
+	res, err = c.Get("https://upstream.example.com/data/bad.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("got: %v, want: %v", err, ErrChecksum)
+	}
+}
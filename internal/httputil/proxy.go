@@ -0,0 +1,95 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/clair/config"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyFunc returns a function for use as [http.Transport.Proxy] implementing
+// the provided configuration.
+//
+// If passed nil, [http.ProxyFromEnvironment] is returned.
+func ProxyFunc(p *config.Proxy) (func(*http.Request) (*url.URL, error), error) {
+	if p == nil {
+		return http.ProxyFromEnvironment, nil
+	}
+	var dflt *httpproxy.Config
+	if p.URL == "" {
+		dflt = httpproxy.FromEnvironment()
+		if len(p.NoProxy) != 0 {
+			dflt.NoProxy = strings.Join(append([]string{dflt.NoProxy}, p.NoProxy...), ",")
+		}
+	} else {
+		dflt = &httpproxy.Config{
+			HTTPProxy:  p.URL,
+			HTTPSProxy: p.URL,
+			NoProxy:    strings.Join(p.NoProxy, ","),
+		}
+	}
+	f := proxyFunc{
+		dflt:  dflt.ProxyFunc(),
+		local: matchFunc(nil),
+		rules: make([]proxyRule, len(p.Rules)),
+	}
+	for i, r := range p.Rules {
+		f.rules[i].match = matchFunc(r.Hosts)
+		if r.URL == "" {
+			continue
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("proxy rule %d: %w", i, err)
+		}
+		f.rules[i].proxy = u
+	}
+	return f.Proxy, nil
+}
+
+type proxyFunc struct {
+	dflt  func(*url.URL) (*url.URL, error)
+	local func(*url.URL) bool
+	rules []proxyRule
+}
+
+type proxyRule struct {
+	match func(*url.URL) bool
+	proxy *url.URL
+}
+
+// Proxy is used as an [http.Transport.Proxy] function.
+func (f *proxyFunc) Proxy(req *http.Request) (*url.URL, error) {
+	// Never proxy requests to the local host, like the standard library.
+	if f.local(req.URL) {
+		return nil, nil
+	}
+	for _, r := range f.rules {
+		if r.match(req.URL) {
+			return r.proxy, nil
+		}
+	}
+	return f.dflt(req.URL)
+}
+
+// PlaceholderProxy is handed to the httpproxy package when it's only being
+// used for its NO_PROXY matching.
+const placeholderProxy = "http://placeholder.invalid"
+
+// MatchFunc returns a function reporting whether a URL matches the provided
+// patterns, which are in NO_PROXY format. Requests to the local host always
+// match.
+func matchFunc(pats []string) func(*url.URL) bool {
+	f := (&httpproxy.Config{
+		HTTPProxy:  placeholderProxy,
+		HTTPSProxy: placeholderProxy,
+		NoProxy:    strings.Join(pats, ","),
+	}).ProxyFunc()
+	return func(u *url.URL) bool {
+		p, err := f(u)
+		return err == nil && p == nil
+	}
+}
@@ -0,0 +1,246 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+var resumeCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "http",
+		Name:      "resumed_downloads_total",
+		Help:      "Total number of attempts to resume interrupted downloads.",
+	},
+	[]string{"result"},
+)
+
+// Resumer wraps the provided RoundTripper so that GET response bodies that
+// fail partway through are resumed with range requests, up to "retries"
+// times per response, instead of failing the whole download.
+//
+// A download is only resumed if the server answers the range request with
+// the remainder of the same content, which is checked with the original
+// response's validator where there is one. If the request, or the request it
+// was redirected from, names a blob by digest, a resumed body is checked
+// against the digest, and reading it reports an error wrapping ErrChecksum if
+// they don't match. A non-positive "retries" disables resumption.
+func Resumer(next http.RoundTripper, retries int) http.RoundTripper {
+	if retries <= 0 {
+		return next
+	}
+	return &resumer{rt: next, retries: retries}
+}
+
+type resumer struct {
+	rt      http.RoundTripper
+	retries int
+}
+
+// ResumeBackoff is the delay before the first resume attempt of a body;
+// later attempts wait proportionally longer.
+var resumeBackoff = time.Second
+
+// RoundTrip implements http.RoundTripper.
+func (r *resumer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("range") != "" {
+		return r.rt.RoundTrip(req)
+	}
+	res, err := r.rt.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK || res.Uncompressed {
+		// Transparently decompressed bodies can't be resumed, as offsets
+		// into them don't correspond to offsets into the content.
+		return res, err
+	}
+	b := &resumableBody{
+		r:    r,
+		req:  req,
+		rc:   res.Body,
+		size: res.ContentLength,
+	}
+	// Weak validators can't be used with If-Range.
+	if et := res.Header.Get("etag"); et != "" && !strings.HasPrefix(et, "W/") {
+		b.validator = et
+	} else {
+		b.validator = res.Header.Get("last-modified")
+	}
+	b.h, b.want = blobDigest(req)
+	res.Body = b
+	return res, nil
+}
+
+// BlobDigest returns a hash and the expected checksum if "req", or any
+// request it was redirected from, refers to a blob by digest, as registry
+// blob URLs do.
+func blobDigest(req *http.Request) (hash.Hash, []byte) {
+	for r := req; r != nil; {
+		if d := path.Base(r.URL.Path); path.Base(path.Dir(r.URL.Path)) == "blobs" {
+			alg, sum, ok := strings.Cut(d, ":")
+			if b, err := hex.DecodeString(sum); ok && err == nil {
+				switch alg {
+				case "sha256":
+					if len(b) == sha256.Size {
+						return sha256.New(), b
+					}
+				case "sha512":
+					if len(b) == sha512.Size {
+						return sha512.New(), b
+					}
+				}
+			}
+		}
+		if r.Response == nil {
+			break
+		}
+		r = r.Response.Request
+	}
+	return nil, nil
+}
+
+// ResumableBody is a response body that resumes reading with a range request
+// if reading fails.
+type resumableBody struct {
+	r         *resumer
+	req       *http.Request
+	rc        io.ReadCloser
+	validator string
+	// Size is the expected length of the content, or -1 if unknown.
+	size  int64
+	off   int64
+	tries int
+	// H hashes the content read, if there's a digest to check it against.
+	h    hash.Hash
+	want []byte
+}
+
+// Read implements io.Reader.
+func (b *resumableBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.off += int64(n)
+	if b.h != nil {
+		b.h.Write(p[:n])
+	}
+	switch {
+	case err == nil:
+		return n, nil
+	case errors.Is(err, io.EOF) && (b.size < 0 || b.off >= b.size):
+		if b.h != nil && b.tries != 0 {
+			if !bytes.Equal(b.h.Sum(nil), b.want) {
+				return n, fmt.Errorf("%w: %s", ErrChecksum, b.req.URL.Redacted())
+			}
+		}
+		return n, err
+	case errors.Is(err, io.EOF):
+		// The body ended early.
+		err = io.ErrUnexpectedEOF
+	}
+	if rerr := b.resume(err); rerr != nil {
+		return n, rerr
+	}
+	return n, nil
+}
+
+// Resume replaces the failed body with the remainder of the content, trying
+// up to the remaining retries. If it can't, it returns an error including
+// "cause".
+func (b *resumableBody) resume(cause error) error {
+	ctx := zlog.ContextWithValues(b.req.Context(),
+		"component", "internal/httputil/resumableBody.resume",
+		"url", b.req.URL.Redacted(),
+		"offset", strconv.FormatInt(b.off, 10))
+	b.rc.Close()
+	b.rc = io.NopCloser(eofReader{cause})
+	for b.tries < b.r.retries {
+		b.tries++
+		if err := ctx.Err(); err != nil {
+			return cause
+		}
+		t := time.NewTimer(resumeBackoff * time.Duration(b.tries))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return cause
+		case <-t.C:
+		}
+		rc, err := b.request(ctx)
+		if err != nil {
+			resumeCounter.WithLabelValues("failed").Inc()
+			zlog.Info(ctx).
+				Err(err).
+				AnErr("cause", cause).
+				Int("attempt", b.tries).
+				Msg("unable to resume download")
+			var final finalErr
+			if errors.As(err, &final) {
+				break
+			}
+			continue
+		}
+		resumeCounter.WithLabelValues("resumed").Inc()
+		zlog.Info(ctx).
+			AnErr("cause", cause).
+			Int("attempt", b.tries).
+			Msg("resumed download")
+		b.rc = rc
+		return nil
+	}
+	return fmt.Errorf("download failed after %d bytes and %d resume attempts: %w", b.off, b.tries, cause)
+}
+
+// FinalErr marks an error after which resuming is pointless, such as the
+// content having changed.
+type finalErr struct{ error }
+
+func (e finalErr) Unwrap() error { return e.error }
+
+// Request makes a range request for the rest of the content.
+func (b *resumableBody) request(ctx context.Context) (io.ReadCloser, error) {
+	req := b.req.Clone(ctx)
+	req.Header.Set("range", "bytes="+strconv.FormatInt(b.off, 10)+"-")
+	if b.validator != "" {
+		req.Header.Set("if-range", b.validator)
+	}
+	res, err := b.r.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			return nil, finalErr{errors.New("server doesn't support range requests or content changed")}
+		}
+		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	cr := res.Header.Get("content-range")
+	if !strings.HasPrefix(cr, "bytes "+strconv.FormatInt(b.off, 10)+"-") {
+		res.Body.Close()
+		return nil, finalErr{fmt.Errorf("unexpected content range %q", cr)}
+	}
+	return res.Body, nil
+}
+
+// Close implements io.Closer.
+func (b *resumableBody) Close() error {
+	return b.rc.Close()
+}
+
+// EOFReader always returns its error.
+type eofReader struct{ err error }
+
+func (r eofReader) Read([]byte) (int, error) { return 0, r.err }
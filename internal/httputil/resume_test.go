@@ -0,0 +1,125 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResumer(t *testing.T) {
+	ctx := context.Background()
+	defer func(d time.Duration) { resumeBackoff = d }(resumeBackoff)
+	resumeBackoff = time.Millisecond
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	sum := sha256.Sum256(payload)
+	blob := "/v2/test/blobs/sha256:" + hex.EncodeToString(sum[:])
+
+	// Srv serves "content" with the given etag, cutting off the first
+	// "fail" responses halfway through.
+	srv := func(t *testing.T, fail int32, etag func() string, content []byte) *httptest.Server {
+		var n int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("etag", etag())
+			if atomic.AddInt32(&n, 1) <= fail {
+				// Let ServeContent work out the headers, but write only part
+				// of the body.
+				tw := &truncWriter{ResponseWriter: w, n: int64(len(content) / 4)}
+				http.ServeContent(tw, r, "", time.Time{}, bytes.NewReader(content))
+				panic(http.ErrAbortHandler)
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	get := func(t *testing.T, srv *httptest.Server, retries int) ([]byte, error) {
+		c := srv.Client()
+		c.Transport = Resumer(c.Transport, retries)
+		req, err := NewRequestWithContext(ctx, http.MethodGet, srv.URL+blob, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status: %s", res.Status)
+		}
+		return io.ReadAll(res.Body)
+	}
+	etag := func() string { return `"v1"` }
+
+	t.Run("Resume", func(t *testing.T) {
+		got, err := get(t, srv(t, 2, etag, payload), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("got %d bytes, want %d bytes", len(got), len(payload))
+		}
+	})
+	t.Run("Exhausted", func(t *testing.T) {
+		_, err := get(t, srv(t, 3, etag, payload), 2)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		t.Log(err)
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		_, err := get(t, srv(t, 1, etag, payload), 0)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		t.Log(err)
+	})
+	t.Run("Changed", func(t *testing.T) {
+		var n int32
+		etag := func() string {
+			if atomic.AddInt32(&n, 1) == 1 {
+				return `"v1"`
+			}
+			return `"v2"`
+		}
+		_, err := get(t, srv(t, 1, etag, payload), 3)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		t.Log(err)
+	})
+	t.Run("Checksum", func(t *testing.T) {
+		bad := bytes.Repeat([]byte("fedcba9876543210"), 64*1024)
+		_, err := get(t, srv(t, 1, etag, bad), 3)
+		if !errors.Is(err, ErrChecksum) {
+			t.Fatalf("got: %v, want: %v", err, ErrChecksum)
+		}
+		t.Log(err)
+	})
+}
+
+// TruncWriter writes at most "n" bytes of the body.
+type truncWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *truncWriter) Write(b []byte) (int, error) {
+	if int64(len(b)) > w.n {
+		b = b[:w.n]
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.n -= int64(n)
+	if w.n == 0 {
+		return n, io.ErrShortWrite
+	}
+	return n, err
+}
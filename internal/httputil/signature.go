@@ -0,0 +1,229 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// ErrSignature is reported when a download can't be verified against its
+// published signature.
+var ErrSignature = errors.New("signature verification failed")
+
+// Signatures wraps the provided RoundTripper so that successful GET responses
+// for URLs matching a "URL" prefix are verified against a detached OpenPGP
+// signature.
+//
+// The signature is fetched through "next" once the response has been
+// received, so a conditional request that isn't modified doesn't fetch it.
+// Reading the body reports an error wrapping ErrSignature at EOF if it doesn't
+// verify. The keyrings are read when Signatures is called.
+func Signatures(next http.RoundTripper, ss []config.UpdaterSignature) (http.RoundTripper, error) {
+	if len(ss) == 0 {
+		return next, nil
+	}
+	s := signatures{rt: next, ss: make([]signer, len(ss))}
+	for i := range ss {
+		kr, err := loadKeyring(ss[i].Keyring)
+		if err != nil {
+			return nil, fmt.Errorf("signatures: %q: %w", ss[i].URL, err)
+		}
+		s.ss[i] = signer{UpdaterSignature: ss[i], kr: kr}
+	}
+	return &s, nil
+}
+
+type signatures struct {
+	rt http.RoundTripper
+	ss []signer
+}
+
+type signer struct {
+	config.UpdaterSignature
+	kr openpgp.EntityList
+}
+
+// LoadKeyring reads an armored or binary keyring from the named file.
+func loadKeyring(name string) (openpgp.EntityList, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var kr openpgp.EntityList
+	if isArmored(b) {
+		kr, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	} else {
+		kr, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+	}
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("reading keyring %q: %w", name, err)
+	case len(kr) == 0:
+		return nil, fmt.Errorf("keyring %q has no keys", name)
+	}
+	return kr, nil
+}
+
+func isArmored(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN "))
+}
+
+// RoundTrip implements http.RoundTripper.
+func (s *signatures) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return s.rt.RoundTrip(req)
+	}
+	u := req.URL.String()
+	var sg *signer
+	for i := range s.ss {
+		if strings.HasPrefix(u, s.ss[i].URL) {
+			sg = &s.ss[i]
+			break
+		}
+	}
+	if sg == nil {
+		return s.rt.RoundTrip(req)
+	}
+	ctx := zlog.ContextWithValues(req.Context(),
+		"component", "internal/httputil/signatures.RoundTrip",
+		"signature", sg.Name,
+		"url", req.URL.Redacted())
+
+	res, err := s.rt.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	sig, err := s.signature(ctx, u+sg.Suffix)
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	if sig == nil {
+		if sg.Strict {
+			res.Body.Close()
+			return nil, fmt.Errorf("%w: %s: no signature published", ErrSignature, req.URL.Redacted())
+		}
+		zlog.Warn(ctx).Msg("no signature published, not verifying")
+		return res, nil
+	}
+	zlog.Debug(ctx).Msg("verifying signature")
+	res.Body = newSignedBody(res.Body, sg.kr, sig, req.URL.Redacted())
+	return res, nil
+}
+
+// Signature fetches the binary form of the detached signature published at
+// "u", or nil if there isn't one.
+func (s *signatures) signature(ctx context.Context, u string) ([]byte, error) {
+	req, err := NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.rt.RoundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("signatures: fetching signature: %w", err)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("signatures: fetching signature %s: unexpected status: %s", req.URL.Redacted(), res.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("signatures: fetching signature: %w", err)
+	}
+	if !isArmored(b) {
+		return b, nil
+	}
+	blk, err := armor.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSignature, req.URL.Redacted(), err)
+	}
+	b, err = io.ReadAll(blk.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSignature, req.URL.Redacted(), err)
+	}
+	return b, nil
+}
+
+// SignedBody feeds the bytes read to a signature check running in another
+// goroutine, and reports its result at EOF.
+type signedBody struct {
+	rc   io.ReadCloser
+	pw   *io.PipeWriter
+	done chan error
+	url  string
+
+	waited bool
+	err    error
+}
+
+func newSignedBody(rc io.ReadCloser, kr openpgp.KeyRing, sig []byte, url string) *signedBody {
+	pr, pw := io.Pipe()
+	b := signedBody{
+		rc:   rc,
+		pw:   pw,
+		done: make(chan error, 1),
+		url:  url,
+	}
+	go func() {
+		_, err := openpgp.CheckDetachedSignature(kr, pr, bytes.NewReader(sig))
+		// If the check returned early, unblock the writer.
+		pr.CloseWithError(ErrSignature)
+		b.done <- err
+	}()
+	return &b
+}
+
+// Wait returns the result of the signature check, blocking until it's done.
+func (b *signedBody) wait() error {
+	if !b.waited {
+		b.waited = true
+		if err := <-b.done; err != nil {
+			b.err = fmt.Errorf("%w: %s: %v", ErrSignature, b.url, err)
+		}
+	}
+	return b.err
+}
+
+// Read implements io.Reader.
+func (b *signedBody) Read(p []byte) (int, error) {
+	if b.waited {
+		if b.err != nil {
+			return 0, b.err
+		}
+		return b.rc.Read(p)
+	}
+	n, err := b.rc.Read(p)
+	if n > 0 {
+		if _, err := b.pw.Write(p[:n]); err != nil {
+			b.pw.Close()
+			return 0, b.wait()
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		b.pw.Close()
+		if err := b.wait(); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *signedBody) Close() error {
+	b.pw.CloseWithError(io.ErrUnexpectedEOF)
+	return b.rc.Close()
+}
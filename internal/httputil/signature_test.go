@@ -0,0 +1,110 @@
+package httputil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quay/clair/config"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestSignatures(t *testing.T) {
+	const body = "vulnerability data"
+	e, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(e *openpgp.Entity, armored bool) string {
+		var buf bytes.Buffer
+		f := openpgp.DetachSign
+		if armored {
+			f = openpgp.ArmoredDetachSign
+		}
+		if err := f(&buf, e, strings.NewReader(body), nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	keyring := filepath.Join(t.TempDir(), "keyring.gpg")
+	f, err := os.Create(keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	files := map[string]string{
+		"/data/armored.json":      body,
+		"/data/armored.json.asc":  sign(e, true),
+		"/data/binary.json":       body,
+		"/data/binary.json.asc":   sign(e, false),
+		"/data/tampered.json":     "tampered",
+		"/data/tampered.json.asc": sign(e, true),
+		"/data/unknown.json":      body,
+		"/data/unknown.json.asc":  sign(other, true),
+		"/data/unsigned.json":     body,
+		"/strict/unsigned.json":   body,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, b)
+	}))
+	defer srv.Close()
+
+	rt, err := Signatures(srv.Client().Transport, []config.UpdaterSignature{
+		{Name: "strict", URL: srv.URL + "/strict/", Keyring: keyring, Suffix: ".asc", Strict: true},
+		{Name: "test", URL: srv.URL + "/data/", Keyring: keyring, Suffix: ".asc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: rt}
+
+	tt := []struct {
+		Path string
+		OK   bool
+	}{
+		{"/data/armored.json", true},
+		{"/data/binary.json", true},
+		{"/data/tampered.json", false},
+		{"/data/unknown.json", false},
+		{"/data/unsigned.json", true},
+		{"/strict/unsigned.json", false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Path, func(t *testing.T) {
+			res, err := c.Get(srv.URL + tc.Path)
+			if err == nil {
+				var b []byte
+				b, err = io.ReadAll(res.Body)
+				res.Body.Close()
+				if err == nil && string(b) != files[tc.Path] {
+					t.Errorf("got: %q, want: %q", string(b), files[tc.Path])
+				}
+			}
+			switch {
+			case tc.OK && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case !tc.OK && !errors.Is(err, ErrSignature):
+				t.Errorf("got: %v, want: %v", err, ErrSignature)
+			}
+		})
+	}
+}
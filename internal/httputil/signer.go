@@ -4,6 +4,7 @@
 	"context"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/quay/clair/config"
@@ -86,6 +87,13 @@ type Signer struct {
 	signer jose.Signer
 	use    map[string]struct{}
 	claim  jwt.Claims
+	scope  string
+}
+
+// Scope sets the scopes claimed by the tokens added to requests, as a
+// space-separated "scope" claim.
+func (s *Signer) Scope(scopes ...string) {
+	s.scope = strings.Join(scopes, " ")
 }
 
 // Sign modifies the passed [http.Request] as needed.
@@ -105,7 +113,11 @@ func (s *Signer) Sign(ctx context.Context, req *http.Request) error {
 	cl.IssuedAt = jwt.NewNumericDate(now)
 	cl.NotBefore = jwt.NewNumericDate(now.Add(-jwt.DefaultLeeway))
 	cl.Expiry = jwt.NewNumericDate(now.Add(jwt.DefaultLeeway))
-	h, err := jwt.Signed(s.signer).Claims(&cl).CompactSerialize()
+	b := jwt.Signed(s.signer).Claims(&cl)
+	if s.scope != "" {
+		b = b.Claims(map[string]interface{}{"scope": s.scope})
+	}
+	h, err := b.CompactSerialize()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,97 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrTooLarge is reported when a response body exceeds a configured size
+// limit.
+var ErrTooLarge = errors.New("size limit exceeded")
+
+// SizeLimiter wraps the provided RoundTripper so that response bodies larger
+// than "max" bytes cause an error wrapping ErrTooLarge.
+//
+// Responses that report a Content-Length over the limit are rejected without
+// reading the body. Requests with a context returned by WithByteBudget are
+// additionally charged against that budget. A non-positive "max" disables the
+// per-response limit.
+func SizeLimiter(next http.RoundTripper, max int64) http.RoundTripper {
+	return &sizelimiter{rt: next, max: max}
+}
+
+type sizelimiter struct {
+	rt  http.RoundTripper
+	max int64
+}
+
+// RoundTrip implements http.RoundTripper.
+func (s *sizelimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	budget, _ := req.Context().Value(budgetKey{}).(*int64)
+	if s.max <= 0 && budget == nil {
+		return s.rt.RoundTrip(req)
+	}
+	res, err := s.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if s.max > 0 && res.ContentLength > s.max {
+		res.Body.Close()
+		return nil, fmt.Errorf("%w: %s: response of %d bytes over limit of %d bytes",
+			ErrTooLarge, req.URL.Redacted(), res.ContentLength, s.max)
+	}
+	if budget != nil && res.ContentLength > 0 && atomic.LoadInt64(budget) < res.ContentLength {
+		res.Body.Close()
+		return nil, fmt.Errorf("%w: %s: response of %d bytes over remaining budget",
+			ErrTooLarge, req.URL.Redacted(), res.ContentLength)
+	}
+	res.Body = &countedBody{
+		rc:     res.Body,
+		max:    s.max,
+		budget: budget,
+	}
+	return res, nil
+}
+
+// CountedBody reports an error once more than "max" bytes have been read or
+// the shared budget is exhausted.
+type countedBody struct {
+	rc     io.ReadCloser
+	max    int64
+	n      int64
+	budget *int64
+}
+
+// Read implements io.Reader.
+func (b *countedBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	if b.max > 0 && b.n > b.max {
+		return n, fmt.Errorf("%w: response over limit of %d bytes", ErrTooLarge, b.max)
+	}
+	if b.budget != nil && atomic.AddInt64(b.budget, -int64(n)) < 0 {
+		return n, fmt.Errorf("%w: byte budget exhausted", ErrTooLarge)
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *countedBody) Close() error {
+	return b.rc.Close()
+}
+
+type budgetKey struct{}
+
+// WithByteBudget returns a Context that limits the total number of response
+// body bytes read by requests made with it, when those requests pass through
+// a SizeLimiter. A non-positive "n" returns the Context unchanged.
+func WithByteBudget(ctx context.Context, n int64) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, budgetKey{}, &n)
+}
@@ -0,0 +1,72 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSizeLimiter(t *testing.T) {
+	const body = "0123456789"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("chunked") {
+			// Flushing before writing forces a chunked response, so there's
+			// no Content-Length to check.
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	base := srv.Client().Transport
+	fetch := func(ctx context.Context, rt http.RoundTripper, u string) error {
+		cl := &http.Client{Transport: rt}
+		req, err := NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		res, err := cl.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	}
+	ctx := context.Background()
+	tt := []struct {
+		Name    string
+		Max     int64
+		Budget  int64
+		Chunked bool
+		Err     bool
+	}{
+		{Name: "Unlimited"},
+		{Name: "Under", Max: 20},
+		{Name: "ContentLength", Max: 5, Err: true},
+		{Name: "Counted", Max: 5, Chunked: true, Err: true},
+		{Name: "Budget", Budget: 5, Err: true},
+		{Name: "BudgetChunked", Budget: 5, Chunked: true, Err: true},
+		{Name: "BudgetOK", Budget: 20, Chunked: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			u := srv.URL
+			if tc.Chunked {
+				u += "?chunked"
+			}
+			rt := SizeLimiter(base, tc.Max)
+			err := fetch(WithByteBudget(ctx, tc.Budget), rt, u)
+			t.Logf("error: %v", err)
+			switch {
+			case tc.Err && !errors.Is(err, ErrTooLarge):
+				t.Error("expected ErrTooLarge")
+			case !tc.Err && err != nil:
+				t.Error("unexpected error")
+			}
+		})
+	}
+}
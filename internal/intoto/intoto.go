@@ -0,0 +1,119 @@
+// Package intoto builds in-toto attestations of Clair's results.
+//
+// Vulnerability reports are attested with the predicate cosign uses for
+// "cosign attest --type vuln", so the predicate can be attached to an image
+// with cosign and checked by policy engines that understand it.
+package intoto
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+// Media and predicate types.
+const (
+	// MediaType is the media type of a serialized Statement, and the
+	// payload type of a DSSE envelope containing one.
+	MediaType = `application/vnd.in-toto+json`
+	// StatementType is the version of the Statement layer.
+	StatementType = `https://in-toto.io/Statement/v0.1`
+	// VulnPredicateType is the type of the predicate in a Statement
+	// returned by VulnStatement.
+	VulnPredicateType = `https://cosign.sigstore.dev/attestation/vuln/v1`
+)
+
+// Statement binds a predicate to the artifacts it's about.
+type Statement struct {
+	Type          string      `json:"_type"`
+	Subject       []Subject   `json:"subject"`
+	PredicateType string      `json:"predicateType"`
+	Predicate     interface{} `json:"predicate"`
+}
+
+// Subject is an artifact a Statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// VulnPredicate is cosign's vulnerability scan predicate.
+type VulnPredicate struct {
+	Invocation Invocation `json:"invocation"`
+	Scanner    Scanner    `json:"scanner"`
+	Metadata   Metadata   `json:"metadata"`
+}
+
+// Invocation describes what started a scan. Clair doesn't know, so it's left
+// empty.
+type Invocation struct {
+	Parameters interface{} `json:"parameters"`
+	URI        string      `json:"uri"`
+	EventID    string      `json:"event_id"`
+	BuilderID  string      `json:"builder.id"`
+}
+
+// Scanner describes the scanner, its vulnerability database, and the
+// result.
+type Scanner struct {
+	URI     string      `json:"uri"`
+	Version string      `json:"version"`
+	DB      DB          `json:"db"`
+	Result  interface{} `json:"result"`
+}
+
+// DB describes the vulnerability data a scan used.
+type DB struct {
+	URI     string `json:"uri"`
+	Version string `json:"version"`
+}
+
+// Metadata is the timing of a scan.
+type Metadata struct {
+	ScanStartedOn  time.Time `json:"scanStartedOn"`
+	ScanFinishedOn time.Time `json:"scanFinishedOn"`
+}
+
+// VulnOpts describes a scan for VulnStatement.
+type VulnOpts struct {
+	// Name is the name of the subject, typically an image reference. If
+	// empty, the manifest digest is used.
+	Name string
+	// Version is the Clair version.
+	Version string
+	// DBVersion identifies the vulnerability data, typically the latest
+	// update operation.
+	DBVersion string
+	// Started and Finished are when the report was being built.
+	Started, Finished time.Time
+}
+
+// VulnStatement returns a Statement attesting that scanning the manifest
+// "m" produced "result".
+func VulnStatement(m claircore.Digest, result interface{}, opts *VulnOpts) *Statement {
+	name := opts.Name
+	if name == "" {
+		name = m.String()
+	}
+	return &Statement{
+		Type: StatementType,
+		Subject: []Subject{{
+			Name:   name,
+			Digest: map[string]string{m.Algorithm(): hex.EncodeToString(m.Checksum())},
+		}},
+		PredicateType: VulnPredicateType,
+		Predicate: &VulnPredicate{
+			Scanner: Scanner{
+				URI:     "pkg:github/quay/clair@" + opts.Version,
+				Version: opts.Version,
+				DB:      DB{Version: opts.DBVersion},
+				Result:  result,
+			},
+			Metadata: Metadata{
+				ScanStartedOn:  opts.Started.UTC(),
+				ScanFinishedOn: opts.Finished.UTC(),
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+// Package leader implements leader election on top of a context-based lock,
+// so that background work runs on exactly one replica at a time.
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+// RetryInterval is how often a replica that isn't the leader attempts to
+// become the leader.
+var RetryInterval = 10 * time.Second
+
+var leaderGauge = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "clair",
+		Subsystem: "leader",
+		Name:      "elected",
+		Help:      "Whether this process is currently the leader for the named role.",
+	},
+	[]string{"role"},
+)
+
+// Locker is the locking API used for election.
+//
+// The Context returned by TryLock must be canceled if the lock is lost. If
+// the lock is not acquired, the returned Context must already be canceled.
+type Locker interface {
+	TryLock(context.Context, string) (context.Context, context.CancelFunc)
+}
+
+// Run calls "f" whenever this process holds the lock for "role", until "ctx"
+// is canceled.
+//
+// The Context passed to "f" is canceled if leadership is lost, and "f" is
+// expected to return promptly when that happens. If "f" returns while this
+// process is still the leader, leadership is released and another election
+// takes place after RetryInterval.
+func Run(ctx context.Context, l Locker, role string, f func(context.Context)) {
+	ctx = zlog.ContextWithValues(ctx, "component", "internal/leader/Run", "role", role)
+	key := "clair-leader-" + role
+	retry := RetryInterval
+	for {
+		lctx, done := l.TryLock(ctx, key)
+		if err := lctx.Err(); err == nil {
+			zlog.Info(ctx).Msg("elected leader")
+			leaderGauge.WithLabelValues(role).Set(1)
+			f(lctx)
+			leaderGauge.WithLabelValues(role).Set(0)
+			if lctx.Err() != nil && ctx.Err() == nil {
+				zlog.Warn(ctx).Msg("lost leadership")
+			}
+		}
+		done()
+		t := time.NewTimer(retry)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Periodic returns a function for use with Run that calls "f" immediately and
+// then every "interval", logging any errors.
+func Periodic(interval time.Duration, f func(context.Context) error) func(context.Context) {
+	return func(ctx context.Context) {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			if err := f(ctx); err != nil && ctx.Err() == nil {
+				zlog.Warn(ctx).Err(err).Msg("periodic task failed")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+)
+
+// MemLocker is an in-process Locker.
+type memLocker struct {
+	mu   sync.Mutex
+	held map[string]context.CancelFunc
+}
+
+func (m *memLocker) TryLock(ctx context.Context, key string) (context.Context, context.CancelFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, cancel := context.WithCancel(ctx)
+	if _, ok := m.held[key]; ok {
+		cancel()
+		return c, cancel
+	}
+	m.held[key] = cancel
+	return c, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.held, key)
+		cancel()
+	}
+}
+
+// Steal simulates losing the lock.
+func (m *memLocker) steal(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.held[key]()
+}
+
+func TestRun(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	prev := RetryInterval
+	RetryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { RetryInterval = prev })
+
+	l := &memLocker{held: make(map[string]context.CancelFunc)}
+	var mu sync.Mutex
+	running := 0
+	elected := make(chan struct{}, 10)
+	work := func(ctx context.Context) {
+		mu.Lock()
+		running++
+		if running > 1 {
+			t.Error("more than one leader")
+		}
+		mu.Unlock()
+		elected <- struct{}{}
+		<-ctx.Done()
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Run(ctx, l, "test", work)
+		}()
+	}
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case <-elected:
+	case <-timeout:
+		t.Fatal("no leader elected")
+	}
+	// Losing the lock should result in a new election.
+	l.steal("clair-leader-test")
+	select {
+	case <-elected:
+	case <-timeout:
+		t.Fatal("no leader re-elected")
+	}
+	done()
+	wg.Wait()
+}
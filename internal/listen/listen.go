@@ -0,0 +1,137 @@
+// Package listen creates listeners from configured addresses.
+package listen
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/quay/clair/config"
+
+	"github.com/quay/clair/v4/internal/systemd"
+)
+
+// Listen announces on the local address "addr".
+//
+// If "addr" starts with config.UnixPrefix, the remainder is used as the path
+// of a Unix domain socket. A stale socket at that path is removed first. If
+// "sc" is not nil, the socket is given the configured permissions before it
+// appears at the path, so it's never reachable with the wrong ones.
+// If "addr" starts with config.SystemdPrefix, the remainder is the name of a
+// socket passed by systemd socket activation.
+// Otherwise, "addr" is a TCP address.
+func Listen(addr string, sc *config.UnixSocket) (net.Listener, error) {
+	if name := strings.TrimPrefix(addr, config.SystemdPrefix); name != addr {
+		return systemd.Listener(name)
+	}
+	p := strings.TrimPrefix(addr, config.UnixPrefix)
+	if p == addr {
+		return net.Listen("tcp", addr)
+	}
+	if err := removeStale(p); err != nil {
+		return nil, err
+	}
+	if sc == nil || (sc.Mode == "" && sc.Group == "") {
+		return net.Listen("unix", p)
+	}
+
+	// Set up the socket under a temporary name and rename it into place.
+	tmp := p + ".tmp"
+	if err := removeStale(tmp); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	ul := l.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+	fail := func(err error) (net.Listener, error) {
+		ul.Close()
+		os.Remove(tmp)
+		return nil, &net.OpError{Op: "listen", Net: "unix", Addr: &net.UnixAddr{Name: p, Net: "unix"}, Err: err}
+	}
+	if sc.Mode != "" {
+		if err := os.Chmod(tmp, sc.FileMode()); err != nil {
+			return fail(err)
+		}
+	}
+	if sc.Group != "" {
+		gid, err := lookupGroup(sc.Group)
+		if err != nil {
+			return fail(err)
+		}
+		if err := os.Chown(tmp, -1, gid); err != nil {
+			return fail(err)
+		}
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return fail(err)
+	}
+	return &unixListener{UnixListener: ul, path: p}, nil
+}
+
+// RemoveStale removes the socket at "p", if there is one and nothing is
+// listening on it.
+//
+// A socket is only considered stale if connecting to it is refused, so a
+// second process started with the same address fails instead of taking the
+// socket away from a running one.
+func removeStale(p string) error {
+	addr := &net.UnixAddr{Name: p, Net: "unix"}
+	switch fi, err := os.Lstat(p); {
+	case errors.Is(err, nil):
+		if fi.Mode().Type() != fs.ModeSocket {
+			return &net.OpError{Op: "listen", Net: "unix", Addr: addr, Err: errors.New("path exists and is not a socket")}
+		}
+		c, err := net.Dial("unix", p)
+		switch {
+		case errors.Is(err, nil):
+			c.Close()
+			return &net.OpError{Op: "listen", Net: "unix", Addr: addr, Err: syscall.EADDRINUSE}
+		case errors.Is(err, syscall.ECONNREFUSED):
+		default:
+			return err
+		}
+		return os.Remove(p)
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	default:
+		return err
+	}
+}
+
+// LookupGroup returns the ID of the group named or numbered "g".
+func lookupGroup(g string) (int, error) {
+	if id, err := strconv.Atoi(g); err == nil {
+		return id, nil
+	}
+	grp, err := user.LookupGroup(g)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(grp.Gid)
+}
+
+// UnixListener is a Unix socket listener that was renamed into place. It
+// reports, and removes on Close, the final path.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Addr implements net.Listener.
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close implements net.Listener.
+func (l *unixListener) Close() error {
+	os.Remove(l.path)
+	return l.UnixListener.Close()
+}
@@ -0,0 +1,105 @@
+package listen
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/quay/clair/config"
+)
+
+func TestListen(t *testing.T) {
+	t.Run("TCP", func(t *testing.T) {
+		l, err := Listen("127.0.0.1:0", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		if got, want := l.Addr().Network(), "tcp"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+	t.Run("Unix", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "clair.sock")
+		// Leave a stale socket behind, as if a previous process crashed.
+		stale, err := net.Listen("unix", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		l, err := Listen(config.UnixPrefix+p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		c, err := net.Dial("unix", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	})
+	t.Run("Permissions", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "clair.sock")
+		sc := &config.UnixSocket{
+			Mode:  "0600",
+			Group: strconv.Itoa(os.Getgid()),
+		}
+		l, err := Listen(config.UnixPrefix+p, sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := l.Addr().String(), p; got != want {
+			t.Errorf("addr: got: %q, want: %q", got, want)
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fi.Mode().Perm(), fs.FileMode(0o600); got != want {
+			t.Errorf("mode: got: %v, want: %v", got, want)
+		}
+		c, err := net.Dial("unix", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		l.Close()
+		if _, err := os.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("socket not removed: %v", err)
+		}
+	})
+	t.Run("InUse", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "clair.sock")
+		live, err := net.Listen("unix", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer live.Close()
+
+		if _, err := Listen(config.UnixPrefix+p, nil); !errors.Is(err, syscall.EADDRINUSE) {
+			t.Errorf("got: %v, want: %v", err, syscall.EADDRINUSE)
+		}
+		// The running listener must keep its socket.
+		c, err := net.Dial("unix", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	})
+	t.Run("NotSocket", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Listen(config.UnixPrefix+p, nil); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package loglevel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// Config is the wire format used by the Handler.
+type Config struct {
+	Components map[string]string `json:"components,omitempty"`
+	Level      string            `json:"level"`
+}
+
+// Current reports the current levels as a Config.
+func Current() *Config {
+	g, cs := Get()
+	c := Config{
+		Level:      g.String(),
+		Components: make(map[string]string, len(cs)),
+	}
+	for p, l := range cs {
+		c.Components[p] = l.String()
+	}
+	return &c
+}
+
+// Apply parses the levels in "c" and configures them with Set.
+func Apply(c *Config) error {
+	g, err := zerolog.ParseLevel(c.Level)
+	if err != nil {
+		return err
+	}
+	cs := make(map[string]zerolog.Level, len(c.Components))
+	for p, s := range c.Components {
+		if p == "" {
+			return fmt.Errorf("component %q: empty prefix", p)
+		}
+		l, err := zerolog.ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("component %q: %w", p, err)
+		}
+		cs[p] = l
+	}
+	Set(g, cs)
+	return nil
+}
+
+// Handler serves the current levels in response to GET requests and replaces
+// them in response to PUT requests. Both respond with the current levels as a
+// JSON-encoded Config.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Cache-Control", "no-store")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var c Config
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				http.Error(w, fmt.Sprintf("malformed request: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := Apply(&c); err != nil {
+				http.Error(w, fmt.Sprintf("invalid levels: %v", err), http.StatusBadRequest)
+				return
+			}
+		default:
+			h.Set("Allow", "GET, PUT")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Current())
+	})
+}
@@ -0,0 +1,125 @@
+// Package loglevel allows for changing log levels at runtime, both globally
+// and for individual components.
+//
+// Components are matched by prefix against the "component" field that's
+// attached to most log messages, with the longest matching prefix winning.
+package loglevel
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// Levels is a snapshot of the configured levels.
+type levels struct {
+	components map[string]zerolog.Level
+	// Prefixes is the keys of components, longest first.
+	prefixes []string
+	global   zerolog.Level
+}
+
+var state atomic.Pointer[levels]
+
+func init() {
+	state.Store(&levels{global: zerolog.GlobalLevel()})
+}
+
+// Set configures the global level and any per-component overrides, replacing
+// any previous configuration.
+//
+// Overrides with a level below the global level cause messages at that level
+// to be constructed for every component, so they should be removed when no
+// longer needed.
+func Set(global zerolog.Level, components map[string]zerolog.Level) {
+	l := levels{
+		global:     global,
+		components: make(map[string]zerolog.Level, len(components)),
+		prefixes:   make([]string, 0, len(components)),
+	}
+	min := global
+	for p, lvl := range components {
+		l.components[p] = lvl
+		l.prefixes = append(l.prefixes, p)
+		if lvl < min {
+			min = lvl
+		}
+	}
+	sort.Slice(l.prefixes, func(i, j int) bool {
+		a, b := l.prefixes[i], l.prefixes[j]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	state.Store(&l)
+	// The zerolog global level gates message construction, so it needs to be
+	// the most verbose of all the configured levels. The Writer does the
+	// per-component filtering.
+	zerolog.SetGlobalLevel(min)
+}
+
+// Get reports the current global level and per-component overrides.
+func Get() (zerolog.Level, map[string]zerolog.Level) {
+	l := state.Load()
+	cs := make(map[string]zerolog.Level, len(l.components))
+	for p, lvl := range l.components {
+		cs[p] = lvl
+	}
+	return l.global, cs
+}
+
+// Writer returns a zerolog.LevelWriter that drops messages below the level
+// configured for their component.
+func Writer(w io.Writer) zerolog.LevelWriter {
+	return &writer{w: w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+// Write implements io.Writer.
+func (w *writer) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *writer) WriteLevel(lvl zerolog.Level, p []byte) (int, error) {
+	if lvl != zerolog.NoLevel && lvl < state.Load().threshold(p) {
+		return len(p), nil
+	}
+	if lw, ok := w.w.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(lvl, p)
+	}
+	return w.w.Write(p)
+}
+
+// ComponentKey is the start of the JSON-encoded component field.
+var componentKey = []byte(`"component":"`)
+
+// Threshold reports the minimum level for the encoded message "p".
+func (l *levels) threshold(p []byte) zerolog.Level {
+	if len(l.prefixes) == 0 {
+		return l.global
+	}
+	i := bytes.Index(p, componentKey)
+	if i == -1 {
+		return l.global
+	}
+	b := p[i+len(componentKey):]
+	if j := bytes.IndexByte(b, '"'); j != -1 {
+		b = b[:j]
+	}
+	c := string(b)
+	for _, pfx := range l.prefixes {
+		if strings.HasPrefix(c, pfx) {
+			return l.components[pfx]
+		}
+	}
+	return l.global
+}
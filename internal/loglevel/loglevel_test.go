@@ -0,0 +1,83 @@
+package loglevel
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWriter(t *testing.T) {
+	prev := zerolog.GlobalLevel()
+	t.Cleanup(func() { Set(prev, nil) })
+
+	var buf bytes.Buffer
+	l := zerolog.New(Writer(&buf))
+	Set(zerolog.InfoLevel, map[string]zerolog.Level{
+		"libindex/":           zerolog.DebugLevel,
+		"libindex/Libindex.S": zerolog.ErrorLevel,
+	})
+
+	l.Debug().Str("component", "libvuln/Updater").Msg("dropped")
+	l.Debug().Str("component", "libindex/Controller").Msg("kept")
+	l.Info().Str("component", "libindex/Libindex.Scan").Msg("dropped")
+	l.Error().Str("component", "libindex/Libindex.Scan").Msg("kept")
+	l.Info().Msg("kept")
+	l.Debug().Msg("dropped")
+
+	out := buf.String()
+	if got, want := strings.Count(out, "kept"), 3; got != want {
+		t.Errorf("kept: got: %d, want: %d\n%s", got, want, out)
+	}
+	if strings.Contains(out, "dropped") {
+		t.Errorf("unexpected messages:\n%s", out)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	prev := zerolog.GlobalLevel()
+	t.Cleanup(func() { Set(prev, nil) })
+	Set(zerolog.InfoLevel, nil)
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	body := `{"level":"warn","components":{"notifier/":"debug"}}`
+	req, err := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("status: got: %d, want: %d", got, want)
+	}
+	g, cs := Get()
+	if got, want := g, zerolog.WarnLevel; got != want {
+		t.Errorf("global: got: %v, want: %v", got, want)
+	}
+	if got, want := cs["notifier/"], zerolog.DebugLevel; got != want {
+		t.Errorf("component: got: %v, want: %v", got, want)
+	}
+	if got, want := zerolog.GlobalLevel(), zerolog.DebugLevel; got != want {
+		t.Errorf("zerolog global: got: %v, want: %v", got, want)
+	}
+
+	req, err = http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(`{"level":"loud"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("status: got: %d, want: %d", got, want)
+	}
+}
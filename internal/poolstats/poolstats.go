@@ -0,0 +1,73 @@
+// Package poolstats exports pgx connection pool statistics as Prometheus
+// metrics.
+package poolstats
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Register registers a collector for the provided pool with the default
+// Prometheus registry. Metrics are labeled with "name" as the "pool" label.
+//
+// Registering the same name twice reports an error.
+func Register(name string, pool *pgxpool.Pool) error {
+	return prometheus.Register(New(name, pool))
+}
+
+// New returns a Collector reporting statistics for the provided pool.
+func New(name string, pool *pgxpool.Pool) prometheus.Collector {
+	l := prometheus.Labels{"pool": name}
+	desc := func(n, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("clair", "pgxpool", n), help, nil, l)
+	}
+	return &collector{
+		pool:          pool,
+		acquired:      desc("acquired_conns", "Number of currently acquired connections in the pool."),
+		idle:          desc("idle_conns", "Number of currently idle connections in the pool."),
+		total:         desc("total_conns", "Total number of connections currently in the pool."),
+		max:           desc("max_conns", "Maximum size of the pool."),
+		acquires:      desc("acquire_total", "Cumulative count of successful acquires from the pool."),
+		acquireTime:   desc("acquire_duration_seconds_total", "Total time spent acquiring connections from the pool."),
+		emptyAcquires: desc("empty_acquire_total", "Cumulative count of acquires that waited for a connection because the pool was empty."),
+		canceled:      desc("canceled_acquire_total", "Cumulative count of acquires canceled by a context."),
+	}
+}
+
+// Collector implements prometheus.Collector by reading the pool's Stat.
+type collector struct {
+	pool          *pgxpool.Pool
+	acquired      *prometheus.Desc
+	idle          *prometheus.Desc
+	total         *prometheus.Desc
+	max           *prometheus.Desc
+	acquires      *prometheus.Desc
+	acquireTime   *prometheus.Desc
+	emptyAcquires *prometheus.Desc
+	canceled      *prometheus.Desc
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.acquireTime
+	ch <- c.emptyAcquires
+	ch <- c.canceled
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireTime, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
@@ -0,0 +1,224 @@
+// Package querystats records database query latencies and captures the plans
+// of slow read queries.
+//
+// It's implemented as a pgx.Logger, so it sees every statement run on a
+// connection regardless of which package issued it.
+package querystats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+// MaxQueries is the number of distinct queries tracked per Logger. Queries
+// beyond this are reported with the fingerprint "other".
+const maxQueries = 500
+
+// ExplainTimeout bounds how long capturing a plan may take.
+const explainTimeout = 10 * time.Second
+
+var queryDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "clair",
+		Subsystem: "database",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of database queries, by pool and query fingerprint.",
+	},
+	[]string{"pool", "query", "error"},
+)
+
+// Options configures a Logger.
+type Options struct {
+	// ExplainThreshold is the latency above which a read query's plan is
+	// captured. If 0, plans are never captured.
+	ExplainThreshold time.Duration
+	// ExplainCooldown is the minimum time between captures for the same
+	// query.
+	ExplainCooldown time.Duration
+}
+
+// Logger is a pgx.Logger that records query latencies for one pool.
+type Logger struct {
+	pool string
+	opts Options
+	db   atomic.Pointer[pgxpool.Pool]
+	// Explaining is set while a plan is being captured; at most one capture
+	// runs at a time.
+	explaining atomic.Bool
+
+	mu      sync.Mutex
+	seen    map[string]struct{}
+	explain map[string]time.Time
+}
+
+var _ pgx.Logger = (*Logger)(nil)
+
+// New returns a Logger labeling its metrics with "pool".
+func New(pool string, opts *Options) *Logger {
+	return &Logger{
+		pool:    pool,
+		opts:    *opts,
+		seen:    make(map[string]struct{}),
+		explain: make(map[string]time.Time),
+	}
+}
+
+// Configure installs the Logger in "cfg".
+func (l *Logger) Configure(cfg *pgxpool.Config) {
+	cfg.ConnConfig.Logger = l
+	cfg.ConnConfig.LogLevel = pgx.LogLevelInfo
+}
+
+// Attach sets the pool used to capture plans. Plans aren't captured until a
+// pool is attached.
+func (l *Logger) Attach(p *pgxpool.Pool) {
+	l.db.Store(p)
+}
+
+// Log implements pgx.Logger.
+func (l *Logger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	switch msg {
+	case "Query", "Exec":
+	default:
+		return
+	}
+	sql, _ := data["sql"].(string)
+	dur, ok := data["time"].(time.Duration)
+	if sql == "" || !ok || isExplain(sql) {
+		return
+	}
+	errLabel := "false"
+	if level == pgx.LogLevelError {
+		errLabel = "true"
+	}
+	fp := l.fingerprint(ctx, sql)
+	queryDuration.WithLabelValues(l.pool, fp, errLabel).Observe(dur.Seconds())
+
+	if l.opts.ExplainThreshold <= 0 || dur < l.opts.ExplainThreshold ||
+		level == pgx.LogLevelError || !isRead(sql) {
+		return
+	}
+	db := l.db.Load()
+	if db == nil || !l.due(fp) {
+		return
+	}
+	args, _ := data["args"].([]interface{})
+	for _, a := range args {
+		// The pgx logger truncates large arguments, so the query can't be
+		// planned with the values it actually ran with.
+		if s, ok := a.(string); ok && strings.Contains(s, " (truncated ") {
+			zlog.Warn(ctx).
+				Str("pool", l.pool).
+				Str("query", fp).
+				Dur("duration", dur).
+				Str("sql", sql).
+				Msg("slow query; arguments too large to capture plan")
+			return
+		}
+	}
+	if !l.explaining.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer l.explaining.Store(false)
+		l.capture(db, fp, dur, sql, args)
+	}()
+}
+
+// Capture runs EXPLAIN for the query and logs the result.
+func (l *Logger) capture(db *pgxpool.Pool, fp string, dur time.Duration, sql string, args []interface{}) {
+	// The query's Context may be canceled as soon as it returns.
+	log := zlog.ContextWithValues(context.Background(),
+		"component", "internal/querystats/Logger.capture",
+		"pool", l.pool,
+		"query", fp)
+	ctx, done := context.WithTimeout(log, explainTimeout)
+	defer done()
+	var plan []string
+	rows, err := db.Query(ctx, "EXPLAIN "+sql, args...)
+	if err == nil {
+		for rows.Next() {
+			var line string
+			if err = rows.Scan(&line); err != nil {
+				break
+			}
+			plan = append(plan, line)
+		}
+		rows.Close()
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	if err != nil {
+		zlog.Warn(log).
+			Err(err).
+			Dur("duration", dur).
+			Str("sql", sql).
+			Msg("slow query; unable to capture plan")
+		return
+	}
+	zlog.Warn(log).
+		Dur("duration", dur).
+		Str("sql", sql).
+		Str("plan", strings.Join(plan, "\n")).
+		Msg("slow query")
+}
+
+// Due reports whether a plan should be captured for the fingerprint "fp",
+// and if so notes that it's being captured.
+func (l *Logger) due(fp string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.explain[fp]; ok && now.Sub(t) < l.opts.ExplainCooldown {
+		return false
+	}
+	l.explain[fp] = now
+	return true
+}
+
+// Fingerprint returns a short identifier for the query text "sql", ignoring
+// differences in whitespace.
+//
+// The first time a fingerprint is seen, it's logged along with the query.
+func (l *Logger) fingerprint(ctx context.Context, sql string) string {
+	norm := strings.Join(strings.Fields(sql), " ")
+	sum := sha256.Sum256([]byte(norm))
+	fp := hex.EncodeToString(sum[:6])
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[fp]; ok {
+		return fp
+	}
+	if len(l.seen) >= maxQueries {
+		return "other"
+	}
+	l.seen[fp] = struct{}{}
+	zlog.Debug(ctx).
+		Str("pool", l.pool).
+		Str("query", fp).
+		Str("sql", norm).
+		Msg("new query fingerprint")
+	return fp
+}
+
+// IsRead reports whether "sql" looks like a read-only statement.
+func isRead(sql string) bool {
+	s := strings.ToUpper(strings.TrimSpace(sql))
+	return strings.HasPrefix(s, "SELECT") || strings.HasPrefix(s, "WITH")
+}
+
+func isExplain(sql string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "EXPLAIN")
+}
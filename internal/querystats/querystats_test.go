@@ -0,0 +1,60 @@
+package querystats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/quay/zlog"
+)
+
+func TestLog(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := New("test", &Options{
+		ExplainThreshold: time.Second,
+		ExplainCooldown:  time.Hour,
+	})
+
+	a := l.fingerprint(ctx, "SELECT id\n\tFROM layer WHERE hash = $1;")
+	b := l.fingerprint(ctx, "SELECT id FROM layer  WHERE hash = $1;")
+	if a != b {
+		t.Errorf("whitespace changed fingerprint: %q != %q", a, b)
+	}
+
+	l.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql":  "SELECT id FROM layer WHERE hash = $1;",
+		"time": 10 * time.Millisecond,
+	})
+	l.Log(ctx, pgx.LogLevelInfo, "Dialing PostgreSQL server", map[string]interface{}{
+		"host": "localhost",
+	})
+	if got, want := testutil.CollectAndCount(queryDuration, "clair_database_query_duration_seconds"), 1; got != want {
+		t.Errorf("series: got: %d, want: %d", got, want)
+	}
+
+	if !l.due(a) {
+		t.Error("first capture not due")
+	}
+	if l.due(a) {
+		t.Error("capture due during cooldown")
+	}
+}
+
+func TestIsRead(t *testing.T) {
+	tt := []struct {
+		SQL  string
+		Read bool
+	}{
+		{SQL: "SELECT 1;", Read: true},
+		{SQL: "\n\twith x AS (SELECT 1) SELECT * FROM x;", Read: true},
+		{SQL: "INSERT INTO notification (id) VALUES ($1);", Read: false},
+		{SQL: "DELETE FROM layer;", Read: false},
+	}
+	for _, tc := range tt {
+		if got, want := isRead(tc.SQL), tc.Read; got != want {
+			t.Errorf("%q: got: %v, want: %v", tc.SQL, got, want)
+		}
+	}
+}
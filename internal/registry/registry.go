@@ -0,0 +1,219 @@
+// Package registry resolves image references into manifests Clair can index.
+//
+// Credentials come from the default keychain: the Docker configuration file
+// named by $DOCKER_CONFIG (default ~/.docker/config.json) and any credential
+// helpers it names.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/cmd"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+// Resolve fetches the manifest named by "ref" and returns it with layer URIs
+// and headers that let the indexer fetch the layers directly.
+//
+// Image indexes are resolved to their linux/amd64 image. Images for other
+// operating systems are rejected, as Clair can't index them.
+func Resolve(ctx context.Context, next http.RoundTripper, ref string) (*claircore.Manifest, error) {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	return ResolveReference(ctx, next, r, nil)
+}
+
+// ResolveReference is like Resolve, but takes a parsed reference and uses
+// "auth" instead of the default keychain if it's not nil.
+func ResolveReference(ctx context.Context, next http.RoundTripper, r name.Reference, auth authn.Authenticator) (*claircore.Manifest, error) {
+	m, _, err := resolve(ctx, next, r, auth, false)
+	return m, err
+}
+
+// ResolveConfig is like ResolveReference, but also returns a descriptor the
+// indexer can fetch the image config blob with.
+func ResolveConfig(ctx context.Context, next http.RoundTripper, r name.Reference, auth authn.Authenticator) (*claircore.Manifest, *claircore.Layer, error) {
+	return resolve(ctx, next, r, auth, true)
+}
+
+func resolve(ctx context.Context, next http.RoundTripper, r name.Reference, auth authn.Authenticator, wantConfig bool) (*claircore.Manifest, *claircore.Layer, error) {
+	rt, img, err := image(ctx, next, r, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo := r.Context()
+	dig, err := img.Digest()
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	out := claircore.Manifest{}
+	if out.Hash, err = claircore.ParseDigest(dig.String()); err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	ls, err := img.Layers()
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	zlog.Debug(ctx).
+		Stringer("ref", r).
+		Stringer("digest", out.Hash).
+		Int("layers", len(ls)).
+		Msg("found manifest")
+
+	// Request the first byte of every blob to follow any redirects and
+	// capture the authorization the indexer needs to fetch it.
+	b := blobs{
+		c:    &http.Client{Transport: rt},
+		base: url.URL{Scheme: repo.Scheme(), Host: repo.RegistryStr()},
+		repo: strings.TrimPrefix(repo.RepositoryStr(), repo.RegistryStr()),
+	}
+	for _, l := range ls {
+		d, err := l.Digest()
+		if err != nil {
+			return nil, nil, fmt.Errorf("registry: %w", err)
+		}
+		l, err := b.locate(ctx, d)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.Layers = append(out.Layers, l)
+	}
+	if !wantConfig {
+		return &out, nil, nil
+	}
+	cd, err := img.ConfigName()
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	cfg, err := b.locate(ctx, cd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &out, cfg, nil
+}
+
+// Blobs locates the blobs of a repository.
+type blobs struct {
+	c    *http.Client
+	base url.URL
+	repo string
+}
+
+// Locate returns a descriptor for the blob "d" with the URI and headers
+// needed to fetch it.
+func (b *blobs) locate(ctx context.Context, d v1.Hash) (*claircore.Layer, error) {
+	ld, err := claircore.ParseDigest(d.String())
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	u, err := b.base.Parse(path.Join("/", "v2", b.repo, "blobs", d.String()))
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	req.Header.Add("Range", "bytes=0-0")
+	res, err := b.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("registry: blob %v: unexpected response %q", d, res.Status)
+	}
+
+	res.Request.Header.Del("User-Agent")
+	res.Request.Header.Del("Range")
+	return &claircore.Layer{
+		Hash:    ld,
+		URI:     res.Request.URL.String(),
+		Headers: res.Request.Header,
+	}, nil
+}
+
+// Digest reports the digest of the image "ref" names, resolving image
+// indexes the same way as Resolve. It's cheaper than Resolve when only the
+// digest is needed.
+func Digest(ctx context.Context, next http.RoundTripper, ref string) (claircore.Digest, error) {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return claircore.Digest{}, fmt.Errorf("registry: %w", err)
+	}
+	_, img, err := image(ctx, next, r, nil)
+	if err != nil {
+		return claircore.Digest{}, err
+	}
+	dig, err := img.Digest()
+	if err != nil {
+		return claircore.Digest{}, fmt.Errorf("registry: %w", err)
+	}
+	d, err := claircore.ParseDigest(dig.String())
+	if err != nil {
+		return claircore.Digest{}, fmt.Errorf("registry: %w", err)
+	}
+	return d, nil
+}
+
+// Image fetches the image "r" names, returning the authenticated transport
+// used. If "auth" is nil, credentials come from the default keychain.
+func image(ctx context.Context, next http.RoundTripper, r name.Reference, auth authn.Authenticator) (http.RoundTripper, v1.Image, error) {
+	repo := r.Context()
+	if auth == nil {
+		var err error
+		auth, err = authn.DefaultKeychain.Resolve(repo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("registry: %w", err)
+		}
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	rt := transport.NewUserAgent(next, `clair/`+cmd.Version)
+	rt = transport.NewRetry(rt)
+	rt, err := transport.NewWithContext(ctx, repo.Registry, auth, rt, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	desc, err := remote.Get(r, remote.WithTransport(rt), remote.WithContext(ctx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, nil, fmt.Errorf("registry: %w", err)
+	}
+	if cf.OS != "" && cf.OS != "linux" {
+		return nil, nil, fmt.Errorf("registry: %v: unsupported operating system %q", r, cf.OS)
+	}
+	return rt, img, nil
+}
+
+// Registry reports the registry host of "ref".
+func Registry(ref string) (string, error) {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("registry: %w", err)
+	}
+	return r.Context().RegistryStr(), nil
+}
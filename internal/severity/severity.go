@@ -0,0 +1,19 @@
+// Package severity parses the names of claircore's normalized severities, as
+// used in configuration and API query parameters.
+package severity
+
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Parse returns the claircore.Severity named by "s", ignoring case.
+func Parse(s string) (claircore.Severity, bool) {
+	for sev := claircore.Unknown; sev <= claircore.Critical; sev++ {
+		if strings.EqualFold(sev.String(), s) {
+			return sev, true
+		}
+	}
+	return claircore.Unknown, false
+}
@@ -0,0 +1,18 @@
+package severity
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"critical", "Critical", "CRITICAL"} {
+		if got, ok := Parse(s); !ok || got != claircore.Critical {
+			t.Errorf("%q: got: %v, %v", s, got, ok)
+		}
+	}
+	if _, ok := Parse("ow"); ok {
+		t.Error("parsed a partial severity name")
+	}
+}
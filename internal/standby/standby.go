@@ -0,0 +1,251 @@
+// Package standby implements a warm standby mode, where a process serves
+// requests and keeps its caches warm but holds its background work until it's
+// promoted.
+package standby
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/leader"
+)
+
+// These are the errors reported by users of a Gate.
+var (
+	// ErrDisabled is reported when the process isn't configured as a
+	// standby.
+	ErrDisabled = errors.New("standby: not configured")
+	// ErrLockPromotion is reported when asked to promote a process that's
+	// promoted by lock acquisition.
+	ErrLockPromotion = errors.New("standby: promotion is by lock")
+)
+
+// LockKey is the lock held by the active process when promotion is by lock.
+const LockKey = "clair-active"
+
+var activeGauge = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "clair",
+		Subsystem: "standby",
+		Name:      "active",
+		Help:      "Whether this process is currently active, as opposed to a warm standby.",
+	},
+)
+
+// Status is a snapshot of a Gate's state.
+type Status struct {
+	// Since is when the process last changed state.
+	Since time.Time `json:"since"`
+	// Reason is how the process was last promoted or demoted.
+	Reason string `json:"reason"`
+	// Active reports whether the process is running its background work.
+	Active bool `json:"active"`
+}
+
+// Gate holds background work until the process is promoted.
+//
+// A nil *Gate is always active, so callers don't need to special-case
+// processes that aren't configured as a standby.
+type Gate struct {
+	base context.Context
+
+	mu      sync.Mutex
+	term    context.Context // nil while on standby
+	cancel  context.CancelFunc
+	changed chan struct{} // closed and replaced on every state change
+	since   time.Time
+	reason  string
+	workers int        // calls to functions passed to Go that are running
+	idle    *sync.Cond // signaled when workers drops to 0
+}
+
+// New returns a Gate on standby. Promotions last at most as long as "ctx".
+func New(ctx context.Context) *Gate {
+	activeGauge.Set(0)
+	g := &Gate{
+		base:    ctx,
+		changed: make(chan struct{}),
+		since:   time.Now(),
+		reason:  "startup",
+	}
+	g.idle = sync.NewCond(&g.mu)
+	return g
+}
+
+// Status reports the Gate's current state.
+func (g *Gate) Status() Status {
+	if g == nil {
+		return Status{Active: true}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Status{
+		Active: g.term != nil,
+		Since:  g.since,
+		Reason: g.reason,
+	}
+}
+
+// Promote makes the process active until it's shut down, reporting false if
+// it already was.
+func (g *Gate) Promote(reason string) bool {
+	return g.promote(g.base, reason)
+}
+
+// Promote makes the process active until "ctx" is done.
+func (g *Gate) promote(ctx context.Context, reason string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.term != nil {
+		return false
+	}
+	term, cancel := context.WithCancel(ctx)
+	g.term, g.cancel = term, cancel
+	g.transition(reason)
+	activeGauge.Set(1)
+	zlog.Info(g.base).
+		Str("component", "internal/standby/Gate.promote").
+		Str("reason", reason).
+		Msg("promoted to active")
+	go func() {
+		<-term.Done()
+		g.demote(term)
+	}()
+	return true
+}
+
+// Demote returns the process to standby if "term" is still the current term.
+func (g *Gate) demote(term context.Context) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.term != term {
+		return
+	}
+	g.cancel()
+	g.term, g.cancel = nil, nil
+	g.transition("demoted")
+	activeGauge.Set(0)
+	if g.base.Err() == nil {
+		zlog.Warn(g.base).
+			Str("component", "internal/standby/Gate.demote").
+			Msg("returned to standby")
+	}
+}
+
+// Transition records a state change and wakes any waiters. The caller must
+// hold the lock.
+func (g *Gate) transition(reason string) {
+	g.since = time.Now()
+	g.reason = reason
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// Wait blocks until the process is active and returns the current term, or
+// returns nil if "ctx" is done first. If a term is returned, the caller counts
+// as a worker and must call g.done when finished.
+func (g *Gate) wait(ctx context.Context) context.Context {
+	for {
+		g.mu.Lock()
+		term, ch := g.term, g.changed
+		// A term that's ended is about to be demoted, which closes "ch".
+		if term != nil && term.Err() == nil {
+			g.workers++
+			g.mu.Unlock()
+			return term
+		}
+		g.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
+		}
+	}
+}
+
+// Go runs "f" in a new goroutine whenever the process is active, until "ctx"
+// is done.
+//
+// The Context passed to "f" is canceled if the process is demoted, and "f" is
+// expected to return promptly when that happens; it's called again on the
+// next promotion. If "f" returns while the process is still active, it's not
+// called again. If the Gate is nil, "f" is called once with "ctx".
+func (g *Gate) Go(ctx context.Context, f func(context.Context)) {
+	if g == nil {
+		go f(ctx)
+		return
+	}
+	go func() {
+		for {
+			term := g.wait(ctx)
+			if term == nil {
+				return
+			}
+			fctx, cancel := context.WithCancel(ctx)
+			go func() {
+				select {
+				case <-term.Done():
+				case <-fctx.Done():
+				}
+				cancel()
+			}()
+			f(fctx)
+			cancel()
+			g.done()
+			if term.Err() == nil || ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Done records a worker returning.
+func (g *Gate) done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.workers--
+	if g.workers == 0 {
+		g.idle.Broadcast()
+	}
+}
+
+// Drain blocks until no workers are running.
+func (g *Gate) drain() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.workers > 0 {
+		g.idle.Wait()
+	}
+}
+
+// Elect promotes the process whenever it holds the active lock, trying every
+// "interval" until "ctx" is done. Losing the lock returns the process to
+// standby; the lock isn't released until the background work has stopped.
+func (g *Gate) Elect(ctx context.Context, l leader.Locker, interval time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "internal/standby/Gate.Elect")
+	for {
+		lctx, done := l.TryLock(ctx, LockKey)
+		if lctx.Err() == nil {
+			g.promote(lctx, "lock")
+			<-lctx.Done()
+			if ctx.Err() == nil {
+				zlog.Warn(ctx).Msg("lost active lock")
+			}
+			g.drain()
+		}
+		done()
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package standby
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+)
+
+// MemLocker is an in-process Locker.
+type memLocker struct {
+	mu   sync.Mutex
+	held map[string]context.CancelFunc
+}
+
+func (m *memLocker) TryLock(ctx context.Context, key string) (context.Context, context.CancelFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, cancel := context.WithCancel(ctx)
+	if _, ok := m.held[key]; ok {
+		cancel()
+		return c, cancel
+	}
+	m.held[key] = cancel
+	return c, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.held, key)
+		cancel()
+	}
+}
+
+// Steal simulates losing the lock.
+func (m *memLocker) steal(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.held[key]()
+}
+
+func TestNil(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var g *Gate
+	if !g.Status().Active {
+		t.Error("nil Gate should be active")
+	}
+	ran := make(chan struct{})
+	g.Go(ctx, func(context.Context) { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("work not run")
+	}
+}
+
+func TestPromote(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	g := New(ctx)
+	started := make(chan struct{}, 1)
+	g.Go(ctx, func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+	})
+
+	select {
+	case <-started:
+		t.Fatal("work run on standby")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if g.Status().Active {
+		t.Error("new Gate should be on standby")
+	}
+	if !g.Promote("test") {
+		t.Error("first promotion reported no change")
+	}
+	if g.Promote("test") {
+		t.Error("second promotion reported a change")
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("work not run after promotion")
+	}
+	if s := g.Status(); !s.Active || s.Reason != "test" {
+		t.Errorf("got: %+v", s)
+	}
+}
+
+func TestElect(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	l := &memLocker{held: make(map[string]context.CancelFunc)}
+	var mu sync.Mutex
+	running := 0
+	started := make(chan struct{}, 10)
+	work := func(ctx context.Context) {
+		mu.Lock()
+		running++
+		if running > 1 {
+			t.Error("more than one active process")
+		}
+		mu.Unlock()
+		started <- struct{}{}
+		<-ctx.Done()
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		g := New(ctx)
+		g.Go(ctx, work)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Elect(ctx, l, 10*time.Millisecond)
+		}()
+	}
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case <-started:
+	case <-timeout:
+		t.Fatal("no process promoted")
+	}
+	// Losing the lock should demote the active process and promote the
+	// standby.
+	l.steal(LockKey)
+	select {
+	case <-started:
+	case <-timeout:
+		t.Fatal("no process promoted after failover")
+	}
+	done()
+	wg.Wait()
+}
@@ -0,0 +1,345 @@
+// Package syslog sends RFC 5424 messages to a syslog collector over TLS, TCP,
+// or UDP.
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/clair/config"
+)
+
+// Severity is a syslog severity.
+type Severity int
+
+// These are the syslog severities.
+const (
+	Emerg Severity = iota
+	Alert
+	Crit
+	Err
+	Warning
+	Notice
+	Info
+	Debug
+)
+
+var severities = map[string]Severity{
+	"emerg":   Emerg,
+	"alert":   Alert,
+	"crit":    Crit,
+	"err":     Err,
+	"warning": Warning,
+	"notice":  Notice,
+	"info":    Info,
+	"debug":   Debug,
+}
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"ntp": 12, "audit": 13, "alert": 14, "clock": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Element is an SD-ELEMENT: an identifier and its parameters, in order.
+type Element struct {
+	ID     string
+	Params []Param
+}
+
+// Param is an SD-PARAM.
+type Param struct {
+	Name, Value string
+}
+
+// Message is a message to send.
+type Message struct {
+	Time     time.Time
+	Severity Severity
+	MsgID    string
+	Data     []Element
+	Msg      string
+}
+
+// Writer sends messages to a collector, connecting on first use and
+// reconnecting after a failed write.
+type Writer struct {
+	network  string
+	addr     string
+	tls      *tls.Config
+	timeout  time.Duration
+	facility int
+	app      string
+	host     string
+	procid   string
+	mapping  map[string]Severity
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// New returns a Writer configured by "cfg".
+func New(cfg *config.Syslog) (*Writer, error) {
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("syslog: failed to parse address: %w", err)
+	}
+	w := Writer{
+		timeout: time.Duration(cfg.DialTimeout),
+		app:     cfg.AppName,
+		host:    cfg.Hostname,
+		procid:  strconv.Itoa(os.Getpid()),
+		mapping: make(map[string]Severity, len(cfg.Severities)),
+	}
+	if w.timeout <= 0 {
+		w.timeout = config.DefaultBrokerDialTimeout
+	}
+	if w.app == "" {
+		w.app = config.DefaultSyslogAppName
+	}
+	if w.host == "" {
+		w.host, _ = os.Hostname()
+	}
+	f := cfg.Facility
+	if f == "" {
+		f = config.DefaultSyslogFacility
+	}
+	var ok bool
+	if w.facility, ok = facilities[f]; !ok {
+		return nil, fmt.Errorf("syslog: unknown facility %q", f)
+	}
+	for k, v := range cfg.Severities {
+		s, ok := severities[v]
+		if !ok {
+			return nil, fmt.Errorf("syslog: unknown severity %q", v)
+		}
+		w.mapping[k] = s
+	}
+	port := u.Port()
+	switch u.Scheme {
+	case "tls":
+		w.network = "tcp"
+		if port == "" {
+			port = "6514"
+		}
+		w.tls, err = cfg.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		if w.tls.ServerName == "" {
+			w.tls.ServerName = u.Hostname()
+		}
+	case "tcp":
+		w.network = "tcp"
+		if port == "" {
+			port = "601"
+		}
+	case "udp":
+		w.network = "udp"
+		if port == "" {
+			port = "514"
+		}
+	default:
+		return nil, fmt.Errorf("syslog: unknown scheme %q", u.Scheme)
+	}
+	w.addr = net.JoinHostPort(u.Hostname(), port)
+	return &w, nil
+}
+
+// Addr reports the collector's address.
+func (w *Writer) Addr() string {
+	return w.addr
+}
+
+// Severity returns the severity configured for "kind", or "def" if there
+// isn't one.
+func (w *Writer) Severity(kind string, def Severity) Severity {
+	if s, ok := w.mapping[kind]; ok {
+		return s
+	}
+	return def
+}
+
+// Write sends the messages, in order.
+//
+// If writing to an existing connection fails, Write reconnects and tries
+// again once. Messages written before the failure may have been received.
+func (w *Writer) Write(ctx context.Context, ms ...Message) error {
+	var b []byte
+	for i := range ms {
+		b = w.frame(b, &ms[i])
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		fresh := w.conn == nil
+		if fresh {
+			c, err := w.dial(ctx)
+			if err != nil {
+				return err
+			}
+			w.conn = c
+		}
+		err := w.send(ctx, b, ms)
+		if err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		if fresh || attempt > 0 || ctx.Err() != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+	}
+}
+
+// Close closes the connection to the collector, if there is one.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Send writes the framed messages "b". UDP collectors get a datagram per
+// message instead. The caller must hold the lock.
+func (w *Writer) send(ctx context.Context, b []byte, ms []Message) error {
+	dl, ok := ctx.Deadline()
+	if !ok {
+		dl = time.Now().Add(w.timeout)
+	}
+	w.conn.SetWriteDeadline(dl)
+	if w.network != "udp" {
+		_, err := w.conn.Write(b)
+		return err
+	}
+	for i := range ms {
+		if _, err := w.conn.Write(w.format(nil, &ms[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) dial(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	var c net.Conn
+	var err error
+	if w.tls != nil {
+		d := tls.Dialer{Config: w.tls}
+		c, err = d.DialContext(ctx, w.network, w.addr)
+	} else {
+		var d net.Dialer
+		c, err = d.DialContext(ctx, w.network, w.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %w", err)
+	}
+	return c, nil
+}
+
+// Frame appends "m" to "b", with octet-counting framing (RFC 6587, section
+// 3.4.1).
+func (w *Writer) frame(b []byte, m *Message) []byte {
+	msg := w.format(nil, m)
+	b = strconv.AppendInt(b, int64(len(msg)), 10)
+	b = append(b, ' ')
+	return append(b, msg...)
+}
+
+// Format appends the RFC 5424 representation of "m" to "b".
+func (w *Writer) format(b []byte, m *Message) []byte {
+	t := m.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(w.facility*8+int(m.Severity)), 10)
+	b = append(b, ">1 "...)
+	b = t.UTC().AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, ' ')
+	b = appendHeader(b, w.host, 255)
+	b = append(b, ' ')
+	b = appendHeader(b, w.app, 48)
+	b = append(b, ' ')
+	b = appendHeader(b, w.procid, 128)
+	b = append(b, ' ')
+	b = appendHeader(b, m.MsgID, 32)
+	b = append(b, ' ')
+	if len(m.Data) == 0 {
+		b = append(b, '-')
+	}
+	for _, e := range m.Data {
+		b = append(b, '[')
+		b = appendName(b, e.ID)
+		for _, p := range e.Params {
+			b = append(b, ' ')
+			b = appendName(b, p.Name)
+			b = append(b, `="`...)
+			b = appendValue(b, p.Value)
+			b = append(b, '"')
+		}
+		b = append(b, ']')
+	}
+	if m.Msg != "" {
+		b = append(b, ' ')
+		b = append(b, m.Msg...)
+	}
+	return b
+}
+
+// AppendHeader appends a header field, replacing anything other than
+// printable US-ASCII, or "-" if "s" is empty.
+func appendHeader(b []byte, s string, max int) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// AppendName appends an SD-NAME, which additionally can't contain '=', ']',
+// '"', or ' ', and is at most 32 characters.
+func appendName(b []byte, s string) []byte {
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+var valueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// AppendValue appends a PARAM-VALUE, escaping as needed.
+func appendValue(b []byte, s string) []byte {
+	return append(b, valueEscaper.Replace(s)...)
+}
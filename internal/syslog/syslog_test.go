@@ -0,0 +1,136 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+)
+
+func TestFormat(t *testing.T) {
+	w, err := New(&config.Syslog{
+		Address:  "udp://collector.example.com",
+		Facility: "local4",
+		Hostname: "clair-0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.procid = "42"
+	m := Message{
+		Time:     time.Date(2023, 4, 5, 6, 7, 8, 9000, time.UTC),
+		Severity: Warning,
+		MsgID:    "notification",
+		Data: []Element{{
+			ID: "clair@32473",
+			Params: []Param{
+				{Name: "manifest", Value: "sha256:abc"},
+				{Name: "note", Value: `a "quoted" \ value]`},
+			},
+		}},
+		Msg: "hello",
+	}
+	got := string(w.format(nil, &m))
+	want := `<164>1 2023-04-05T06:07:08.000009Z clair-0 clair 42 notification [clair@32473 manifest="sha256:abc" note="a \"quoted\" \\ value\]"] hello`
+	if got != want {
+		t.Errorf("got:  %s\nwant: %s", got, want)
+	}
+	if got, want := w.Addr(), "collector.example.com:514"; got != want {
+		t.Errorf("addr: got: %q, want: %q", got, want)
+	}
+
+	m = Message{Severity: Info}
+	if got := string(w.format(nil, &m)); !strings.HasSuffix(got, " clair-0 clair 42 - -") {
+		t.Errorf("empty message: got: %s", got)
+	}
+}
+
+func TestSeverityMapping(t *testing.T) {
+	w, err := New(&config.Syslog{
+		Address:    "tcp://collector",
+		Severities: map[string]string{"High": "crit"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.Severity("High", Err), Crit; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := w.Severity("Low", Notice), Notice; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if _, err := New(&config.Syslog{Address: "tcp://collector", Facility: "nope"}); err == nil {
+		t.Error("expected error for unknown facility")
+	}
+}
+
+func TestWriteTCP(t *testing.T) {
+	ctx := context.Background()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got := make(chan string, 4)
+	go func() {
+		// Accept twice: the writer reconnects after the first connection
+		// is closed.
+		for i := 0; i < 2; i++ {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(c)
+			n, err := r.ReadString(' ')
+			if err != nil {
+				c.Close()
+				return
+			}
+			sz, _ := strconv.Atoi(strings.TrimSpace(n))
+			b := make([]byte, sz)
+			if _, err := io.ReadFull(r, b); err != nil {
+				c.Close()
+				return
+			}
+			got <- string(b)
+			c.Close()
+		}
+	}()
+
+	w, err := New(&config.Syslog{Address: "tcp://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Write(ctx, Message{Severity: Notice, Msg: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := <-got; !strings.HasSuffix(m, " first") {
+		t.Errorf("got: %q", m)
+	}
+	// The collector closed the connection; depending on timing the next
+	// write either fails on the old connection and is retried, or succeeds
+	// into the void. Keep writing until the new connection sees a message.
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := w.Write(ctx, Message{Severity: Notice, Msg: "second"}); err != nil {
+			t.Log(err)
+		}
+		select {
+		case m := <-got:
+			if !strings.HasSuffix(m, " second") {
+				t.Errorf("got: %q", m)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for reconnect")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
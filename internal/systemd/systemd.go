@@ -0,0 +1,141 @@
+// Package systemd implements the parts of the systemd socket activation and
+// service notification protocols Clair uses.
+//
+// See sd_listen_fds(3) and sd_notify(3) for the protocol details.
+package systemd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/zlog"
+)
+
+// ListenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+var activation struct {
+	sync.Mutex
+	once  sync.Once
+	files map[string]*os.File
+}
+
+// Listener returns the inherited socket named "name", as set with
+// FileDescriptorName= in the socket unit. Each socket may only be taken once.
+//
+// Unnamed sockets are named "unknown" by systemd.
+func Listener(name string) (net.Listener, error) {
+	activation.once.Do(func() {
+		activation.files = inherited()
+	})
+	activation.Lock()
+	f, ok := activation.files[name]
+	delete(activation.files, name)
+	activation.Unlock()
+	if !ok {
+		return nil, &net.OpError{
+			Op:  "listen",
+			Net: "systemd",
+			Err: errors.New("no inherited socket named " + strconv.Quote(name)),
+		}
+	}
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// Inherited collects the sockets passed in the environment and unsets the
+// relevant variables, so child processes don't see them.
+func inherited() map[string]*os.File {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	fs := make(map[string]*os.File, n)
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		fd := listenFDsStart + i
+		fs[name] = os.NewFile(uintptr(fd), name)
+	}
+	return fs
+}
+
+// Notify sends "state" to the service manager, e.g. "READY=1". It does nothing
+// if not running under a service manager that's expecting notifications.
+func Notify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		// Abstract socket.
+		addr = "\x00" + addr[1:]
+	}
+	c, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval reports the interval the service manager expects watchdog
+// notifications at, or 0 if the watchdog isn't enabled for this process.
+func WatchdogInterval() time.Duration {
+	if p := os.Getenv("WATCHDOG_PID"); p != "" {
+		if pid, err := strconv.Atoi(p); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+	us, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || us <= 0 {
+		return 0
+	}
+	return time.Duration(us) * time.Microsecond
+}
+
+// Watchdog sends watchdog notifications at half the interval the service
+// manager expects, for as long as "healthy" reports true and the Context isn't
+// canceled. It returns immediately if the watchdog isn't enabled.
+func Watchdog(ctx context.Context, healthy func(context.Context) bool) {
+	d := WatchdogInterval()
+	if d == 0 {
+		return
+	}
+	ctx = zlog.ContextWithValues(ctx, "component", "internal/systemd/Watchdog")
+	zlog.Info(ctx).Stringer("interval", d).Msg("systemd watchdog enabled")
+	t := time.NewTicker(d / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if !healthy(ctx) {
+			zlog.Warn(ctx).Msg("unhealthy, skipping watchdog notification")
+			continue
+		}
+		if err := Notify("WATCHDOG=1"); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to send watchdog notification")
+		}
+	}
+}
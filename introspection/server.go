@@ -6,20 +6,29 @@
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"path"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	deltapprof "github.com/pyroscope-io/godeltaprof/http/pprof"
 	"github.com/quay/clair/config"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 
 	"github.com/quay/clair/v4/health"
+	"github.com/quay/clair/v4/internal/loglevel"
+	"github.com/quay/clair/v4/middleware/auth"
 )
 
 const (
@@ -27,8 +36,10 @@
 	DefaultPromEndpoint      = "/metrics"
 	Stdout                   = "stdout"
 	Jaeger                   = "jaeger"
+	OTLP                     = "otlp"
 	HealthEndpoint           = "/healthz"
 	ReadyEndpoint            = "/readyz"
+	LogLevelEndpoint         = "/loglevel"
 	DefaultIntrospectionAddr = ":8089"
 )
 
@@ -84,17 +95,9 @@ func New(ctx context.Context, conf config.Config, health func() bool) (*Server,
 
 	// configure tracing
 	// sampler
-	var sampler sdktrace.Sampler
-	switch {
-	case i.conf.LogLevel == config.DebugLog || i.conf.LogLevel == config.DebugColorLog:
-		sampler = sdktrace.AlwaysSample()
-	case i.conf.Trace.Probability != nil:
-		p := *i.conf.Trace.Probability
-		sampler = sdktrace.ParentBased(
-			sdktrace.TraceIDRatioBased(p),
-		)
-	default:
-		sampler = sdktrace.NeverSample()
+	sampler, err := traceSampler(&i.conf)
+	if err != nil {
+		return nil, err
 	}
 
 	// trace exporter
@@ -162,9 +165,20 @@ func New(ctx context.Context, conf config.Config, health func() bool) (*Server,
 		if err != nil {
 			return nil, fmt.Errorf("error configuring jaeger tracing: %w", err)
 		}
+	case OTLP:
+		exporter, err = otlpExporter(ctx, &i.conf.Trace.OTLP)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring otlp tracing: %w", err)
+		}
 	default:
 		zlog.Info(ctx).Msg("no distributed tracing enabled")
 	}
+	// Always propagate trace context, so that a process without an exporter
+	// doesn't break traces passing through it.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 	if exporter != nil {
 		tp := sdktrace.NewTracerProvider(
 			sdktrace.WithSampler(sampler),
@@ -185,6 +199,11 @@ func New(ctx context.Context, conf config.Config, health func() bool) (*Server,
 		zlog.Info(ctx).Msg("distributed tracing configured")
 	}
 
+	// configure the profile watchdog
+	if w := i.conf.ProfileWatchdog; w != nil {
+		go watchdog(ctx, w)
+	}
+
 	// configure diagnostics
 	err = i.withDiagnostics(ctx)
 	if err != nil {
@@ -195,23 +214,35 @@ func New(ctx context.Context, conf config.Config, health func() bool) (*Server,
 	}
 
 	// attach Introspection to server, this works because we embed http.ServeMux
-	i.Server.Handler = i
+	i.Server.Handler, err = i.withAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring authentication: %v", err)
+	}
 
 	return i, nil
 }
 
-// withDiagnotics enables healthz and pprof endpoints
+// withDiagnotics enables healthz, loglevel, and pprof endpoints
+//
+// Requests to the healthz endpoint with a "detail" query parameter run every
+// probe registered with the health package and report the results.
 func (i *Server) withDiagnostics(_ context.Context) error {
-	health := i.health
+	check := i.health
+	probe := health.ProbeHandler()
 	i.HandleFunc(HealthEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["detail"]; ok {
+			probe.ServeHTTP(w, r)
+			return
+		}
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		if !health() {
+		if !check() {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 		fmt.Fprint(w, `ok`)
 	})
+	i.Handle(LogLevelEndpoint, loglevel.Handler())
 	i.HandleFunc("/debug/pprof/", pprof.Index)
 	i.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	i.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -223,11 +254,133 @@ func (i *Server) withDiagnostics(_ context.Context) error {
 	return nil
 }
 
+// WithAuth returns the Handler for the server, requiring authentication for
+// every endpoint except the configured unauthenticated paths.
+//
+// If no authentication is configured, the ServeMux is returned as-is.
+func (i *Server) withAuth(ctx context.Context) (http.Handler, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "introspection/Server.withAuth")
+	cfg := i.conf.Introspection
+	if cfg == nil {
+		return i.ServeMux, nil
+	}
+	var checks []auth.Checker
+	if cfg.TLS != nil && cfg.TLS.ClientCA != "" {
+		checks = append(checks, auth.ClientCert{})
+	}
+	if cfg.PSK != nil {
+		psk, err := auth.NewPSK(cfg.PSK.Key, cfg.PSK.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, psk)
+	}
+	if len(checks) == 0 {
+		zlog.Warn(ctx).Msg("no authentication configured")
+		return i.ServeMux, nil
+	}
+
+	open := make(map[string]struct{}, len(cfg.Unauthenticated))
+	for _, p := range cfg.Unauthenticated {
+		open[path.Clean(p)] = struct{}{}
+	}
+	protected := auth.Handler(i.ServeMux, checks...)
+	zlog.Info(ctx).
+		Int("checks", len(checks)).
+		Strs("unauthenticated", cfg.Unauthenticated).
+		Msg("authentication configured")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := open[path.Clean(r.URL.Path)]; ok {
+			i.ServeMux.ServeHTTP(w, r)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	}), nil
+}
+
 func (i *Server) withReady(_ context.Context) error {
 	i.ServeMux.Handle(ReadyEndpoint, health.ReadinessHandler())
 	return nil
 }
 
+// traceSampler returns the sampler described by the configuration.
+//
+// If no sampler is named, the sampler is picked based on the log level and
+// the configured probability.
+func traceSampler(conf *config.Config) (sdktrace.Sampler, error) {
+	ratio := 1.0
+	if conf.Trace.Probability != nil {
+		ratio = *conf.Trace.Probability
+	}
+	switch conf.Trace.Sampler {
+	case "":
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unknown trace sampler: %q", conf.Trace.Sampler)
+	}
+	switch {
+	case conf.LogLevel == config.DebugLog || conf.LogLevel == config.DebugColorLog:
+		return sdktrace.AlwaysSample(), nil
+	case conf.Trace.Probability != nil:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return sdktrace.NeverSample(), nil
+	}
+}
+
+// otlpExporter constructs an OTLP exporter using the configured protocol.
+//
+// Unset values fall back to the standard OTEL_EXPORTER_OTLP_* environment
+// variables, as handled by the exporter packages.
+func otlpExporter(ctx context.Context, conf *config.OTLP) (sdktrace.SpanExporter, error) {
+	var c otlptrace.Client
+	switch conf.Protocol {
+	case "", "grpc":
+		zlog.Info(ctx).Msg("configuring otlp exporter to push via grpc")
+		var opt []otlptracegrpc.Option
+		if conf.Endpoint != "" {
+			opt = append(opt, otlptracegrpc.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opt = append(opt, otlptracegrpc.WithInsecure())
+		}
+		if len(conf.Headers) != 0 {
+			opt = append(opt, otlptracegrpc.WithHeaders(conf.Headers))
+		}
+		c = otlptracegrpc.NewClient(opt...)
+	case "http/protobuf":
+		zlog.Info(ctx).Msg("configuring otlp exporter to push via http")
+		var opt []otlptracehttp.Option
+		if conf.Endpoint != "" {
+			opt = append(opt, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.URLPath != "" {
+			opt = append(opt, otlptracehttp.WithURLPath(conf.URLPath))
+		}
+		if conf.Insecure {
+			opt = append(opt, otlptracehttp.WithInsecure())
+		}
+		if len(conf.Headers) != 0 {
+			opt = append(opt, otlptracehttp.WithHeaders(conf.Headers))
+		}
+		c = otlptracehttp.NewClient(opt...)
+	default:
+		return nil, fmt.Errorf("unknown otlp protocol: %q", conf.Protocol)
+	}
+	return otlptrace.New(ctx, c)
+}
+
 // WithPrometheus adds the prometheus endpoint to i's ServeMux.
 func (i *Server) withPrometheus(ctx context.Context) error {
 	ctx = zlog.ContextWithValues(ctx, "component", "introspection/Server.withPrometheus")
@@ -240,6 +393,14 @@ func (i *Server) withPrometheus(ctx context.Context) error {
 		Str("server", i.Addr).
 		Msg("configuring prometheus")
 
+	// Replace the default Go collector with one that exports everything
+	// available from runtime/metrics.
+	prometheus.Unregister(collectors.NewGoCollector())
+	if err := prometheus.Register(collectors.NewGoCollector(
+		collectors.WithGoCollectorRuntimeMetrics(collectors.MetricsAll),
+	)); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to register runtime metrics")
+	}
 	i.Handle(endpoint, promhttp.Handler())
 	return nil
 }
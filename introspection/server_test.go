@@ -0,0 +1,91 @@
+package introspection
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestAuth(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	key := []byte("introspection-test-key")
+	var conf config.Config
+	conf.Introspection = &config.Introspection{
+		TLS: &config.TLS{ClientCA: "/dev/null"},
+		PSK: &config.AuthPSK{
+			Key:    config.Base64(key),
+			Issuer: []string{"prometheus"},
+		},
+		Unauthenticated: []string{HealthEndpoint, ReadyEndpoint},
+	}
+	i, err := New(ctx, conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(t *testing.T, k []byte, iss string) string {
+		t.Helper()
+		s, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: k}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		tok, err := jwt.Signed(s).Claims(jwt.Claims{
+			Issuer:   iss,
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(time.Minute)),
+		}).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	cert := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "prometheus"},
+	}
+
+	tt := []struct {
+		Name   string
+		Path   string
+		Token  string
+		Cert   bool
+		Status int
+	}{
+		{Name: "Health", Path: HealthEndpoint, Status: http.StatusOK},
+		{Name: "Metrics", Path: DefaultPromEndpoint, Status: http.StatusUnauthorized},
+		{Name: "Pprof", Path: "/debug/pprof/", Status: http.StatusUnauthorized},
+		{Name: "PprofDotDot", Path: "/healthz/../debug/pprof/", Status: http.StatusUnauthorized},
+		{Name: "PSK", Path: DefaultPromEndpoint, Token: sign(t, key, "prometheus"), Status: http.StatusOK},
+		{Name: "PSKIssuer", Path: DefaultPromEndpoint, Token: sign(t, key, "mallory"), Status: http.StatusUnauthorized},
+		{Name: "PSKKey", Path: DefaultPromEndpoint, Token: sign(t, []byte("other-key"), "prometheus"), Status: http.StatusUnauthorized},
+		{Name: "ClientCert", Path: "/debug/pprof/", Cert: true, Status: http.StatusOK},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://clair"+tc.Path, nil).WithContext(ctx)
+			if tc.Token != "" {
+				req.Header.Set("authorization", "Bearer "+tc.Token)
+			}
+			if tc.Cert {
+				req.TLS = &tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{cert}},
+				}
+			}
+			rec := httptest.NewRecorder()
+			i.Server.Handler.ServeHTTP(rec, req)
+			if got, want := rec.Code, tc.Status; got != want {
+				t.Errorf("got: %d, want: %d", got, want)
+			}
+		})
+	}
+}
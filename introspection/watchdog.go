@@ -0,0 +1,128 @@
+package introspection
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+// HeapMetric is the runtime/metrics name used to measure the live heap.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// CapturePrefix is the prefix of every directory the watchdog creates.
+const capturePrefix = "clair-profile-"
+
+// Watchdog periodically checks the configured thresholds and captures
+// profiles when they're crossed. It returns when the Context is canceled.
+func watchdog(ctx context.Context, cfg *config.ProfileWatchdog) {
+	ctx = zlog.ContextWithValues(ctx, "component", "introspection/watchdog")
+	if err := os.MkdirAll(cfg.Directory, 0o755); err != nil {
+		zlog.Error(ctx).Err(err).Msg("unable to create profile directory; watchdog disabled")
+		return
+	}
+	zlog.Info(ctx).
+		Str("directory", cfg.Directory).
+		Int64("heap_threshold", cfg.HeapThreshold).
+		Int("goroutine_threshold", cfg.GoroutineThreshold).
+		Msg("profile watchdog started")
+
+	sample := []metrics.Sample{{Name: heapMetric}}
+	var last time.Time
+	t := time.NewTicker(time.Duration(cfg.Interval))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if time.Since(last) < time.Duration(cfg.Cooldown) {
+			continue
+		}
+		metrics.Read(sample)
+		heap := int64(sample[0].Value.Uint64())
+		gs := runtime.NumGoroutine()
+		var reason string
+		switch {
+		case cfg.HeapThreshold > 0 && heap >= cfg.HeapThreshold:
+			reason = "heap"
+		case cfg.GoroutineThreshold > 0 && gs >= cfg.GoroutineThreshold:
+			reason = "goroutine"
+		default:
+			continue
+		}
+		last = time.Now()
+		dir, err := capture(cfg.Directory, last)
+		if err != nil {
+			zlog.Error(ctx).Err(err).Msg("unable to capture profiles")
+			continue
+		}
+		zlog.Warn(ctx).
+			Str("reason", reason).
+			Int64("heap", heap).
+			Int("goroutines", gs).
+			Str("path", dir).
+			Msg("resource threshold crossed; captured profiles")
+		if err := prune(cfg.Directory, cfg.Retain); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to remove old profiles")
+		}
+	}
+}
+
+// Capture writes heap and goroutine profiles to a new directory inside "root"
+// and reports its path.
+func capture(root string, now time.Time) (string, error) {
+	// This format sorts lexically.
+	dir := filepath.Join(root, capturePrefix+now.UTC().Format("20060102T150405.000Z"))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", err
+	}
+	for _, name := range []string{"heap", "goroutine"} {
+		f, err := os.Create(filepath.Join(dir, name+".pb.gz"))
+		if err != nil {
+			return dir, err
+		}
+		err = pprof.Lookup(name).WriteTo(f, 0)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return dir, fmt.Errorf("%s profile: %w", name, err)
+		}
+	}
+	return dir, nil
+}
+
+// Prune removes all but the newest "keep" captures in "root".
+func prune(root string, keep int) error {
+	ents, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	var ds []string
+	for _, e := range ents {
+		if e.IsDir() && strings.HasPrefix(e.Name(), capturePrefix) {
+			ds = append(ds, e.Name())
+		}
+	}
+	if len(ds) <= keep {
+		return nil
+	}
+	sort.Strings(ds)
+	for _, d := range ds[:len(ds)-keep] {
+		if err := os.RemoveAll(filepath.Join(root, d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package introspection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCapturePrune(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		dir, err := capture(root, now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []string{"heap.pb.gz", "goroutine.pb.gz"} {
+			fi, err := os.Stat(filepath.Join(dir, n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() == 0 {
+				t.Errorf("%s: empty profile", n)
+			}
+		}
+	}
+	// Unrelated files are left alone.
+	if err := os.Mkdir(filepath.Join(root, "other"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prune(root, 2); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range ents {
+		got = append(got, e.Name())
+	}
+	if len(got) != 3 {
+		t.Fatalf("got: %v, want 2 captures and 1 other directory", got)
+	}
+	want := capturePrefix + now.Add(3*time.Second).UTC().Format("20060102T150405.000Z")
+	if got[1] != want {
+		t.Errorf("newest capture: got: %q, want: %q", got[1], want)
+	}
+}
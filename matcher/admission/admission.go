@@ -0,0 +1,169 @@
+// Package admission decodes Kubernetes admission requests into the images they
+// would run, so they can be checked against a scan policy.
+//
+// Only the parts of the Kubernetes API needed for this are described here;
+// objects are otherwise left undecoded.
+package admission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Review is an "admission.k8s.io/v1" AdmissionReview.
+type Review struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Request    *Request  `json:"request,omitempty"`
+	Response   *Response `json:"response,omitempty"`
+}
+
+// Request is the request half of a Review.
+type Request struct {
+	UID    string          `json:"uid"`
+	Kind   GroupKind       `json:"kind"`
+	Object json.RawMessage `json:"object"`
+}
+
+// GroupKind names the kind of the object under review.
+type GroupKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// Response is the response half of a Review.
+type Response struct {
+	UID      string   `json:"uid"`
+	Allowed  bool     `json:"allowed"`
+	Status   *Status  `json:"status,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Status explains a denial.
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ImageList is the simple alternative to a Review: just a list of image
+// references.
+type ImageList struct {
+	Images []string `json:"images"`
+}
+
+// Input is a decoded request body: exactly one of the members is populated.
+type Input struct {
+	Review *Review
+	List   *ImageList
+}
+
+// Decode decodes either a Review or an ImageList.
+func Decode(b []byte) (*Input, error) {
+	var probe struct {
+		Kind   string   `json:"kind"`
+		Images []string `json:"images"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, fmt.Errorf("admission: %w", err)
+	}
+	switch {
+	case probe.Kind == "AdmissionReview":
+		var r Review
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, fmt.Errorf("admission: %w", err)
+		}
+		if r.Request == nil {
+			return nil, errors.New(`admission: AdmissionReview missing "request"`)
+		}
+		return &Input{Review: &r}, nil
+	case probe.Images != nil:
+		return &Input{List: &ImageList{Images: probe.Images}}, nil
+	}
+	return nil, errors.New(`admission: expected an AdmissionReview or an object with "images"`)
+}
+
+// Images returns the distinct images in the Input, in the order they appear.
+func (in *Input) Images() ([]string, error) {
+	if in.List != nil {
+		return dedup(in.List.Images), nil
+	}
+	return Images(in.Review.Request.Kind.Kind, in.Review.Request.Object)
+}
+
+// PodSpec is the part of a Kubernetes PodSpec naming images.
+type podSpec struct {
+	InitContainers      []container `json:"initContainers"`
+	Containers          []container `json:"containers"`
+	EphemeralContainers []container `json:"ephemeralContainers"`
+}
+
+type container struct {
+	Image string `json:"image"`
+}
+
+type podTemplate struct {
+	Spec podSpec `json:"spec"`
+}
+
+// Images returns the distinct images run by the object "obj" of kind
+// "kind". Pods, the workload kinds with a pod template, and CronJobs are
+// understood; other kinds have no images.
+func Images(kind string, obj json.RawMessage) ([]string, error) {
+	var spec podSpec
+	var err error
+	switch kind {
+	case "Pod":
+		var o struct {
+			Spec podSpec `json:"spec"`
+		}
+		err = json.Unmarshal(obj, &o)
+		spec = o.Spec
+	case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job", "ReplicationController":
+		var o struct {
+			Spec struct {
+				Template podTemplate `json:"template"`
+			} `json:"spec"`
+		}
+		err = json.Unmarshal(obj, &o)
+		spec = o.Spec.Template.Spec
+	case "CronJob":
+		var o struct {
+			Spec struct {
+				JobTemplate struct {
+					Spec struct {
+						Template podTemplate `json:"template"`
+					} `json:"spec"`
+				} `json:"jobTemplate"`
+			} `json:"spec"`
+		}
+		err = json.Unmarshal(obj, &o)
+		spec = o.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("admission: %s: %w", kind, err)
+	}
+	var out []string
+	for _, cs := range [][]container{spec.InitContainers, spec.Containers, spec.EphemeralContainers} {
+		for _, c := range cs {
+			out = append(out, c.Image)
+		}
+	}
+	return dedup(out), nil
+}
+
+func dedup(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]struct{}, len(in))
+	for _, s := range in {
+		if _, ok := seen[s]; ok || s == "" {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImages(t *testing.T) {
+	tt := []struct {
+		Name string
+		In   string
+		Want []string
+	}{
+		{
+			Name: "Pod",
+			In: `{"kind":"AdmissionReview","request":{"uid":"1","kind":{"kind":"Pod"},"object":{"spec":{` +
+				`"initContainers":[{"image":"init"}],` +
+				`"containers":[{"image":"app"},{"image":"sidecar"},{"image":"app"}]}}}}`,
+			Want: []string{"init", "app", "sidecar"},
+		},
+		{
+			Name: "Deployment",
+			In: `{"kind":"AdmissionReview","request":{"uid":"1","kind":{"group":"apps","kind":"Deployment"},"object":{"spec":{` +
+				`"template":{"spec":{"containers":[{"image":"app"}]}}}}}}`,
+			Want: []string{"app"},
+		},
+		{
+			Name: "CronJob",
+			In: `{"kind":"AdmissionReview","request":{"uid":"1","kind":{"group":"batch","kind":"CronJob"},"object":{"spec":{` +
+				`"jobTemplate":{"spec":{"template":{"spec":{"containers":[{"image":"job"}]}}}}}}}}`,
+			Want: []string{"job"},
+		},
+		{
+			Name: "ConfigMap",
+			In:   `{"kind":"AdmissionReview","request":{"uid":"1","kind":{"kind":"ConfigMap"},"object":{"data":{}}}}`,
+			Want: nil,
+		},
+		{
+			Name: "List",
+			In:   `{"images":["a","b","a"]}`,
+			Want: []string{"a", "b"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			in, err := Decode([]byte(tc.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := in.Images()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+	for _, in := range []string{`{}`, `{"kind":"AdmissionReview"}`, `[]`} {
+		if _, err := Decode([]byte(in)); err == nil {
+			t.Errorf("%s: expected error", in)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package advisory stores organization-internal advisories, for tracking
+// vulnerabilities in first-party packages that no upstream source covers.
+//
+// Advisories are managed through the API and stored in the matcher database.
+// Whenever they change, the vulnerabilities they describe are written to the
+// vulnerability store as update operations of per-version-scheme updaters,
+// so they're matched, diffed, and notified on like any other source's.
+package advisory
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/severity"
+	"github.com/quay/clair/v4/matcher/feed"
+)
+
+// UpdaterPrefix is prepended to a version scheme to name the updater
+// recording the advisories using it, such as "internal-advisories-rpm".
+const UpdaterPrefix = "internal-advisories-"
+
+// Advisory is an organization-internal advisory.
+type Advisory struct {
+	// Name identifies the advisory, such as "ACME-2023-0001".
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Severity is a normalized severity: "Unknown", "Negligible", "Low",
+	// "Medium", "High", or "Critical".
+	Severity string   `json:"severity,omitempty"`
+	Links    []string `json:"links,omitempty"`
+	// Affected lists the affected package version ranges.
+	Affected []Affected `json:"affected"`
+	// Issued defaults to when the advisory was created.
+	Issued time.Time `json:"issued,omitempty"`
+	// Updated and UpdatedBy are set by the Store.
+	Updated   time.Time `json:"updated"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	// Withdrawn is set by the Store once the advisory is withdrawn.
+	Withdrawn *time.Time `json:"withdrawn,omitempty"`
+}
+
+// Affected is a range of affected versions of a package.
+type Affected struct {
+	// Package is the package's name.
+	Package string `json:"package"`
+	// Namespace is where the package comes from, in the syntax of an
+	// updater feed's namespace: a distribution as "ID" or "ID:VERSION_ID",
+	// or a language package repository as "repo:" and its name.
+	Namespace string `json:"namespace"`
+	// VersionScheme is how versions are compared: "rpm", "dpkg", "apk",
+	// "semver", or "pep440".
+	VersionScheme string `json:"version_scheme"`
+	// Introduced is the first affected version. If empty, every version
+	// before "Fixed" is affected.
+	Introduced string `json:"introduced,omitempty"`
+	// Fixed is the first version no longer affected. If empty, every version
+	// from "Introduced" is affected.
+	Fixed string `json:"fixed,omitempty"`
+}
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:-]{0,63}$`)
+
+// ErrInvalid is returned, wrapped, for malformed Advisories.
+var ErrInvalid = errors.New("invalid advisory")
+
+// Validate reports whether the Advisory is well-formed.
+func (a *Advisory) Validate() error {
+	if !nameRegexp.MatchString(a.Name) {
+		return fmt.Errorf("%w: bad name %q", ErrInvalid, a.Name)
+	}
+	if a.Severity != "" {
+		if _, ok := severity.Parse(a.Severity); !ok {
+			return fmt.Errorf("%w: unknown severity %q", ErrInvalid, a.Severity)
+		}
+	}
+	for i, l := range a.Links {
+		u, err := url.Parse(l)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("%w: links[%d]: not an http or https url", ErrInvalid, i)
+		}
+	}
+	if len(a.Affected) == 0 {
+		return fmt.Errorf("%w: no affected packages", ErrInvalid)
+	}
+	for i := range a.Affected {
+		if err := a.Affected[i].validate(); err != nil {
+			return fmt.Errorf("%w: affected[%d]: %v", ErrInvalid, i, err)
+		}
+	}
+	return nil
+}
+
+func (r *Affected) validate() error {
+	if r.Package == "" {
+		return errors.New("missing package")
+	}
+	if _, err := feed.ParseNamespace(r.Namespace); err != nil {
+		return err
+	}
+	// Comparing the versions checks the scheme and that they parse.
+	lo, hi := r.Introduced, r.Fixed
+	switch {
+	case lo == "" && hi == "":
+		lo, hi = "0", "0"
+	case lo == "":
+		lo = hi
+	case hi == "":
+		hi = lo
+	}
+	c, err := feed.Compare(r.VersionScheme, lo, hi)
+	switch {
+	case err != nil:
+		return err
+	case c > 0:
+		return errors.New("introduced version is after fixed version")
+	case c == 0 && r.Introduced != "" && r.Fixed != "":
+		return errors.New("introduced version is the fixed version")
+	}
+	return nil
+}
+
+// Vulnerabilities returns the vulnerabilities described by the active
+// advisories in "as", keyed by the name of the updater recording them.
+//
+// A vulnerability's package version is the first affected version, and its
+// fixed-in version the first version no longer affected.
+func vulnerabilities(as []Advisory) map[string][]*claircore.Vulnerability {
+	out := make(map[string][]*claircore.Vulnerability)
+	for i := range as {
+		a := &as[i]
+		if a.Withdrawn != nil {
+			continue
+		}
+		sev, _ := severity.Parse(a.Severity)
+		links := make([]string, len(a.Links))
+		copy(links, a.Links)
+		sort.Strings(links)
+		for _, r := range a.Affected {
+			ns, err := feed.ParseNamespace(r.Namespace)
+			if err != nil {
+				// Validated when stored.
+				continue
+			}
+			name := UpdaterPrefix + r.VersionScheme
+			v := claircore.Vulnerability{
+				Updater:            name,
+				Name:               a.Name,
+				Description:        a.Description,
+				Issued:             a.Issued,
+				Links:              strings.Join(links, " "),
+				Severity:           a.Severity,
+				NormalizedSeverity: sev,
+				Package: &claircore.Package{
+					Name:    r.Package,
+					Version: r.Introduced,
+					Kind:    claircore.BINARY,
+				},
+				FixedInVersion: r.Fixed,
+				Dist: &claircore.Distribution{
+					DID:       ns.DID,
+					VersionID: ns.VersionID,
+				},
+				Repo: &claircore.Repository{
+					Name: ns.Repo,
+				},
+			}
+			out[name] = append(out[name], &v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package advisory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+func TestValidate(t *testing.T) {
+	ok := func() Advisory {
+		return Advisory{
+			Name:     "ACME-2023-0001",
+			Severity: "high",
+			Links:    []string{"https://security.example.com/ACME-2023-0001"},
+			Affected: []Affected{
+				{Package: "acme-client", Namespace: "repo:pypi", VersionScheme: "pep440", Introduced: "1.0", Fixed: "1.4.2"},
+			},
+		}
+	}
+	for _, tc := range []struct {
+		Name   string
+		Modify func(*Advisory)
+		OK     bool
+	}{
+		{Name: "OK", Modify: func(*Advisory) {}, OK: true},
+		{Name: "Unfixed", Modify: func(a *Advisory) { a.Affected[0].Fixed = "" }, OK: true},
+		{Name: "AllVersions", Modify: func(a *Advisory) { a.Affected[0].Introduced, a.Affected[0].Fixed = "", "" }, OK: true},
+		{Name: "Name", Modify: func(a *Advisory) { a.Name = "ACME 1" }},
+		{Name: "Severity", Modify: func(a *Advisory) { a.Severity = "urgent" }},
+		{Name: "Link", Modify: func(a *Advisory) { a.Links = []string{"javascript:alert(1)"} }},
+		{Name: "NoAffected", Modify: func(a *Advisory) { a.Affected = nil }},
+		{Name: "Namespace", Modify: func(a *Advisory) { a.Affected[0].Namespace = "repo:" }},
+		{Name: "Scheme", Modify: func(a *Advisory) { a.Affected[0].VersionScheme = "calver" }},
+		{Name: "Version", Modify: func(a *Advisory) { a.Affected[0].Fixed = "not a version" }},
+		{Name: "Backwards", Modify: func(a *Advisory) { a.Affected[0].Introduced = "2.0" }},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			a := ok()
+			tc.Modify(&a)
+			err := a.Validate()
+			if got, want := err == nil, tc.OK; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+			if err != nil && !errors.Is(err, ErrInvalid) {
+				t.Errorf("error doesn't wrap ErrInvalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	withdrawn := time.Now()
+	as := []Advisory{
+		{
+			Name:     "ACME-2023-0001",
+			Severity: "High",
+			Affected: []Affected{
+				{Package: "acme-client", Namespace: "repo:pypi", VersionScheme: "pep440", Introduced: "1.0", Fixed: "1.4.2"},
+				{Package: "acme-agent", Namespace: "rhel:9", VersionScheme: "rpm", Fixed: "0:2.1-3.el9"},
+			},
+		},
+		{
+			Name:      "ACME-2023-0002",
+			Withdrawn: &withdrawn,
+			Affected: []Affected{
+				{Package: "acme-tools", Namespace: "repo:pypi", VersionScheme: "pep440"},
+			},
+		},
+	}
+	vs := vulnerabilities(as)
+	if got, want := len(vs[UpdaterPrefix+"pep440"]), 1; got != want {
+		t.Fatalf("pep440: got: %d vulnerabilities, want: %d", got, want)
+	}
+	if got, want := len(vs[UpdaterPrefix+"rpm"]), 1; got != want {
+		t.Fatalf("rpm: got: %d vulnerabilities, want: %d", got, want)
+	}
+	if got, want := vs[UpdaterPrefix+"pep440"][0].NormalizedSeverity, claircore.High; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	var m Matcher
+	m.set(as)
+	for _, tc := range []struct {
+		Name   string
+		Record claircore.IndexRecord
+		Filter bool
+		Want   bool
+	}{
+		{
+			Name: "InRange",
+			Record: claircore.IndexRecord{
+				Package:    &claircore.Package{Name: "acme-client", Version: "1.2"},
+				Repository: &claircore.Repository{Name: "pypi"},
+			},
+			Filter: true,
+			Want:   true,
+		},
+		{
+			Name: "Introduced",
+			Record: claircore.IndexRecord{
+				Package:    &claircore.Package{Name: "acme-client", Version: "1.0"},
+				Repository: &claircore.Repository{Name: "pypi"},
+			},
+			Filter: true,
+			Want:   true,
+		},
+		{
+			Name: "BeforeIntroduced",
+			Record: claircore.IndexRecord{
+				Package:    &claircore.Package{Name: "acme-client", Version: "0.9"},
+				Repository: &claircore.Repository{Name: "pypi"},
+			},
+			Filter: true,
+		},
+		{
+			Name: "Fixed",
+			Record: claircore.IndexRecord{
+				Package:    &claircore.Package{Name: "acme-client", Version: "1.4.2"},
+				Repository: &claircore.Repository{Name: "pypi"},
+			},
+			Filter: true,
+		},
+		{
+			Name: "OtherNamespace",
+			Record: claircore.IndexRecord{
+				Package:    &claircore.Package{Name: "acme-client", Version: "1.2"},
+				Repository: &claircore.Repository{Name: "rubygems"},
+			},
+			Filter: true,
+		},
+		{
+			Name: "Distribution",
+			Record: claircore.IndexRecord{
+				Package:      &claircore.Package{Name: "acme-agent", Version: "2.1-2.el9"},
+				Distribution: &claircore.Distribution{DID: "rhel", VersionID: "9"},
+			},
+			Filter: true,
+			Want:   true,
+		},
+		{
+			Name: "Withdrawn",
+			Record: claircore.IndexRecord{
+				Package:    &claircore.Package{Name: "acme-tools", Version: "1.0"},
+				Repository: &claircore.Repository{Name: "pypi"},
+			},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := &tc.Record
+			if got, want := m.Filter(r), tc.Filter; got != want {
+				t.Errorf("filter: got: %v, want: %v", got, want)
+			}
+			var got bool
+			for _, v := range vs {
+				for _, v := range v {
+					if v.Package.Name != r.Package.Name {
+						continue
+					}
+					ok, err := m.Vulnerable(ctx, r, v)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = got || ok
+				}
+			}
+			if want := tc.Want; got != want {
+				t.Errorf("vulnerable: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package advisory
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/feed"
+)
+
+// Matcher matches packages against the vulnerabilities recorded for
+// internal advisories.
+//
+// The Matcher only considers packages named in an active advisory, so it
+// doesn't cost a query for every package when there are few advisories.
+type Matcher struct {
+	// Packages is the set of package names in active advisories.
+	packages atomic.Pointer[map[string]struct{}]
+}
+
+var _ driver.Matcher = (*Matcher)(nil)
+
+// Name implements driver.Matcher.
+func (m *Matcher) Name() string { return "internal-advisories" }
+
+// Filter implements driver.Matcher.
+func (m *Matcher) Filter(r *claircore.IndexRecord) bool {
+	ps := m.packages.Load()
+	if ps == nil || r.Package == nil {
+		return false
+	}
+	if _, ok := (*ps)[r.Package.Name]; ok {
+		return true
+	}
+	if r.Package.Source != nil {
+		_, ok := (*ps)[r.Package.Source.Name]
+		return ok
+	}
+	return false
+}
+
+// Query implements driver.Matcher.
+//
+// Advisories cover many namespaces, so they're checked in Vulnerable.
+func (m *Matcher) Query() []driver.MatchConstraint { return nil }
+
+// Vulnerable implements driver.Matcher.
+func (m *Matcher) Vulnerable(ctx context.Context, r *claircore.IndexRecord, v *claircore.Vulnerability) (bool, error) {
+	scheme, ok := strings.CutPrefix(v.Updater, UpdaterPrefix)
+	if !ok || v.Package == nil {
+		return false, nil
+	}
+	var ns feed.Namespace
+	if v.Repo != nil {
+		ns.Repo = v.Repo.Name
+	}
+	if v.Dist != nil {
+		ns.DID, ns.VersionID = v.Dist.DID, v.Dist.VersionID
+	}
+	if !ns.Contains(r) {
+		return false, nil
+	}
+	for _, b := range []struct {
+		Version string
+		OK      func(int) bool
+	}{
+		{Version: v.Package.Version, OK: func(c int) bool { return c >= 0 }},
+		{Version: v.FixedInVersion, OK: func(c int) bool { return c < 0 }},
+	} {
+		if b.Version == "" {
+			continue
+		}
+		c, err := feed.Compare(scheme, r.Package.Version, b.Version)
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Str("advisory", v.Name).
+				Str("package", r.Package.Name).
+				Str("version", r.Package.Version).
+				Msg("unable to compare versions")
+			return false, nil
+		}
+		if !b.OK(c) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Set records the package names in the active advisories "as".
+func (m *Matcher) set(as []Advisory) {
+	ps := make(map[string]struct{})
+	for _, a := range as {
+		if a.Withdrawn != nil {
+			continue
+		}
+		for _, r := range a.Affected {
+			ps[r.Package] = struct{}{}
+		}
+	}
+	m.packages.Store(&ps)
+}
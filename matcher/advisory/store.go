@@ -0,0 +1,239 @@
+package advisory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_advisory", "the advisory store")
+
+// Store persists Advisories and publishes the vulnerabilities they describe.
+type Store struct {
+	pool  *pgxpool.Pool
+	vulns datastore.Updater
+	m     Matcher
+}
+
+// NewStore returns a Store using the passed-in Pool, publishing
+// vulnerabilities to "vulns".
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool, vulns datastore.Updater) *Store {
+	return &Store{pool: pool, vulns: vulns}
+}
+
+// Matcher returns the Matcher for the vulnerabilities the Store publishes.
+// It matches nothing until the Store has loaded the advisories, by Run or a
+// change.
+func (s *Store) Matcher() *Matcher {
+	return &s.m
+}
+
+// Advisory returns the named Advisory, reporting false if it doesn't exist.
+func (s *Store) Advisory(ctx context.Context, name string) (_ *Advisory, ok bool, err error) {
+	const query = `SELECT advisory FROM matcher_advisory WHERE name = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var a Advisory
+	err = s.pool.QueryRow(ctx, query, name).Scan(&a)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("advisory: unable to get %q: %w", name, err)
+	}
+	return &a, true, nil
+}
+
+// Advisories returns all Advisories, including withdrawn ones, ordered by
+// name.
+func (s *Store) Advisories(ctx context.Context) (_ []Advisory, err error) {
+	const query = `SELECT advisory FROM matcher_advisory ORDER BY name;`
+	defer queryMetrics.Observe("list", &err)()
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("advisory: unable to list advisories: %w", err)
+	}
+	defer rows.Close()
+	out := []Advisory{}
+	for rows.Next() {
+		var a Advisory
+		if err = rows.Scan(&a); err != nil {
+			return nil, fmt.Errorf("advisory: unable to list advisories: %w", err)
+		}
+		out = append(out, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("advisory: unable to list advisories: %w", err)
+	}
+	return out, nil
+}
+
+// PutAdvisory creates or replaces an Advisory, republishing it if it was
+// withdrawn, and reports whether it was newly created.
+//
+// The Advisory's Updated time is set, as is its Issued time if it's zero.
+func (s *Store) PutAdvisory(ctx context.Context, a *Advisory) (created bool, err error) {
+	if err := a.Validate(); err != nil {
+		return false, err
+	}
+	prev, _, err := s.Advisory(ctx, a.Name)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	a.Updated = now
+	a.Withdrawn = nil
+	if a.Issued.IsZero() {
+		a.Issued = now
+		if prev != nil {
+			a.Issued = prev.Issued
+		}
+	}
+	created, err = s.put(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	if err := s.publish(ctx, schemes(prev, a)); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// WithdrawAdvisory withdraws the named Advisory, so it no longer matches,
+// reporting false if it doesn't exist. Withdrawn Advisories are kept.
+func (s *Store) WithdrawAdvisory(ctx context.Context, name string) (bool, error) {
+	a, ok, err := s.Advisory(ctx, name)
+	if err != nil || !ok {
+		return false, err
+	}
+	if a.Withdrawn == nil {
+		now := time.Now().UTC().Truncate(time.Second)
+		a.Updated = now
+		a.Withdrawn = &now
+		if _, err := s.put(ctx, a); err != nil {
+			return false, err
+		}
+	}
+	if err := s.publish(ctx, schemes(a)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Store) put(ctx context.Context, a *Advisory) (created bool, err error) {
+	const query = `INSERT INTO matcher_advisory (name, advisory) VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET advisory = EXCLUDED.advisory, updated = now()
+RETURNING (xmax = 0);`
+	defer queryMetrics.Observe("put", &err)()
+	if err = s.pool.QueryRow(ctx, query, a.Name, a).Scan(&created); err != nil {
+		return false, fmt.Errorf("advisory: unable to store %q: %w", a.Name, err)
+	}
+	return created, nil
+}
+
+// Schemes returns the version schemes used by the non-nil Advisories.
+func schemes(as ...*Advisory) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, a := range as {
+		if a == nil {
+			continue
+		}
+		for _, r := range a.Affected {
+			if _, ok := seen[r.VersionScheme]; !ok {
+				seen[r.VersionScheme] = struct{}{}
+				out = append(out, r.VersionScheme)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Publish writes the vulnerabilities for the version schemes "ss" to the
+// vulnerability store, as an update operation of each scheme's updater.
+//
+// The advisories are read under a database lock, so concurrent changes,
+// even by other processes, are published in order.
+func (s *Store) publish(ctx context.Context, ss []string) (err error) {
+	const (
+		lock   = `SELECT pg_advisory_lock(hashtext('matcher_advisory'));`
+		unlock = `SELECT pg_advisory_unlock(hashtext('matcher_advisory'));`
+	)
+	defer queryMetrics.Observe("publish", &err)()
+	c, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("advisory: unable to publish: %w", err)
+	}
+	defer c.Release()
+	if _, err = c.Exec(ctx, lock); err != nil {
+		return fmt.Errorf("advisory: unable to publish: %w", err)
+	}
+	defer func() {
+		if _, err := c.Exec(context.Background(), unlock); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to release publish lock")
+		}
+	}()
+	as, err := s.Advisories(ctx)
+	if err != nil {
+		return err
+	}
+	vs := vulnerabilities(as)
+	for _, scheme := range ss {
+		name := UpdaterPrefix + scheme
+		b, err := json.Marshal(vs[name])
+		if err != nil {
+			return fmt.Errorf("advisory: unable to publish: %w", err)
+		}
+		sum := sha256.Sum256(b)
+		fp := driver.Fingerprint(hex.EncodeToString(sum[:]))
+		ref, err := s.vulns.UpdateVulnerabilities(ctx, name, fp, vs[name])
+		if err != nil {
+			return fmt.Errorf("advisory: unable to publish %q: %w", name, err)
+		}
+		zlog.Info(ctx).
+			Str("updater", name).
+			Stringer("ref", ref).
+			Int("count", len(vs[name])).
+			Msg("published internal advisories")
+	}
+	s.m.set(as)
+	return nil
+}
+
+// Run keeps the Matcher's view of the advisories current, including changes
+// made by other processes, until the Context is canceled.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/advisory/Store.Run")
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		as, err := s.Advisories(ctx)
+		if err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to load advisories")
+		} else {
+			s.m.set(as)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package advisory
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+// VulnRecorder records the vulnerabilities most recently published for each
+// updater.
+type vulnRecorder struct {
+	datastore.Updater
+	got map[string][]*claircore.Vulnerability
+}
+
+func (r *vulnRecorder) UpdateVulnerabilities(_ context.Context, u string, _ driver.Fingerprint, vs []*claircore.Vulnerability) (uuid.UUID, error) {
+	r.got[u] = vs
+	return uuid.New(), nil
+}
+
+func TestStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	rec := &vulnRecorder{got: make(map[string][]*claircore.Vulnerability)}
+	s := NewStore(pool, rec)
+
+	a := Advisory{
+		Name: "ACME-2023-0001",
+		Affected: []Affected{
+			{Package: "acme-client", Namespace: "repo:pypi", VersionScheme: "pep440", Fixed: "1.4.2"},
+		},
+	}
+	created, err := s.PutAdvisory(ctx, &a)
+	if err != nil || !created {
+		t.Fatalf("create: got: (%v, %v)", created, err)
+	}
+	if got, want := len(rec.got[UpdaterPrefix+"pep440"]), 1; got != want {
+		t.Errorf("published: got: %d, want: %d", got, want)
+	}
+	if !s.Matcher().Filter(&claircore.IndexRecord{Package: &claircore.Package{Name: "acme-client"}}) {
+		t.Error("matcher doesn't know about the advisory")
+	}
+
+	// Moving the advisory to another scheme empties the old one.
+	a.Affected[0] = Affected{Package: "acme-agent", Namespace: "debian:12", VersionScheme: "dpkg"}
+	created, err = s.PutAdvisory(ctx, &a)
+	if err != nil || created {
+		t.Fatalf("replace: got: (%v, %v)", created, err)
+	}
+	if got, want := len(rec.got[UpdaterPrefix+"pep440"]), 0; got != want {
+		t.Errorf("old scheme: got: %d, want: %d", got, want)
+	}
+	if got, want := len(rec.got[UpdaterPrefix+"dpkg"]), 1; got != want {
+		t.Errorf("new scheme: got: %d, want: %d", got, want)
+	}
+
+	ok, err := s.WithdrawAdvisory(ctx, a.Name)
+	if err != nil || !ok {
+		t.Fatalf("withdraw: got: (%v, %v)", ok, err)
+	}
+	if got, want := len(rec.got[UpdaterPrefix+"dpkg"]), 0; got != want {
+		t.Errorf("withdrawn: got: %d, want: %d", got, want)
+	}
+	got, ok, err := s.Advisory(ctx, a.Name)
+	if err != nil || !ok {
+		t.Fatalf("get: got: (%v, %v)", ok, err)
+	}
+	if got.Withdrawn == nil {
+		t.Error("advisory not marked withdrawn")
+	}
+	if ok, err := s.WithdrawAdvisory(ctx, "missing"); err != nil || ok {
+		t.Errorf("withdraw missing: got: (%v, %v)", ok, err)
+	}
+	as, err := s.Advisories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(as), 1; got != want {
+		t.Errorf("list: got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+// Package backfill implements re-match backfills.
+//
+// A backfill re-runs matching for every manifest the indexer has stored, so
+// that a change to matcher logic reaches every stored manifest's
+// vulnerability report without waiting for clients to request it again.
+// Manifests are re-matched at a limited rate, in chunks, and progress is
+// saved after each chunk, so a backfill survives restarts and can be paused
+// and resumed.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// State is the state of a backfill.
+type State string
+
+// These are the states a backfill can be in.
+//
+// A backfill is created running. Running and paused backfills are "active";
+// only one backfill may be active at a time.
+const (
+	StateRunning  State = "running"
+	StatePaused   State = "paused"
+	StateCanceled State = "canceled"
+	StateFinished State = "finished"
+)
+
+// Job is a backfill.
+type Job struct {
+	// ID is assigned when the Job is started.
+	ID    uuid.UUID `json:"id"`
+	State State     `json:"state"`
+	// Rate is the number of manifests re-matched per second.
+	Rate int `json:"rate"`
+	// ChunkSize is the number of manifests re-matched between saves of the
+	// Job's progress.
+	ChunkSize int `json:"chunk_size"`
+
+	// Total is the number of manifests stored when the Job was started.
+	// Manifests indexed since are re-matched if they sort after the
+	// manifests done so far, so Done may end up larger.
+	Total int64 `json:"total"`
+	// Done is the number of manifests processed, including failures and
+	// manifests without an index report.
+	Done int64 `json:"done"`
+	// Failed is the number of manifests that couldn't be re-matched.
+	Failed int64 `json:"failed"`
+	// LastManifest is the last manifest processed. The Job resumes with the
+	// manifest after it.
+	LastManifest string `json:"last_manifest,omitempty"`
+	// LastError is the most recent failure, if there was one.
+	LastError string `json:"last_error,omitempty"`
+	// ActiveSeconds is the time spent processing manifests, excluding time
+	// spent paused.
+	ActiveSeconds float64 `json:"active_seconds"`
+
+	Created  time.Time  `json:"created"`
+	Updated  time.Time  `json:"updated"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// Progress is the fraction of Total done, from 0 to 1.
+	Progress float64 `json:"progress"`
+	// ETA is the estimated time a running Job will finish, once enough
+	// manifests are done to make an estimate.
+	ETA *time.Time `json:"eta,omitempty"`
+}
+
+// Estimate fills in the Job's Progress and ETA as of "now", from the number
+// of manifests done and the time spent doing them.
+func (j *Job) estimate(now time.Time) {
+	j.Progress, j.ETA = 0, nil
+	switch {
+	case j.State == StateFinished:
+		j.Progress = 1
+		return
+	case j.Total <= 0:
+		return
+	}
+	j.Progress = float64(j.Done) / float64(j.Total)
+	if j.Progress > 1 {
+		j.Progress = 1
+	}
+	if j.State != StateRunning || j.Done == 0 || j.ActiveSeconds <= 0 {
+		return
+	}
+	left := j.Total - j.Done
+	if left < 0 {
+		left = 0
+	}
+	per := j.ActiveSeconds / float64(j.Done)
+	eta := now.Add(time.Duration(per * float64(left) * float64(time.Second))).Truncate(time.Second)
+	j.ETA = &eta
+}
+
+var (
+	// ErrInvalid is returned, wrapped, for backfills that can't be started
+	// and for state changes that aren't allowed.
+	ErrInvalid = errors.New("invalid backfill")
+	// ErrActive is returned when a backfill is started while another is
+	// running or paused.
+	ErrActive = errors.New("a backfill is already active")
+	// ErrDisabled is returned by a nil Manager, meaning backfills aren't
+	// configured.
+	ErrDisabled = errors.New("backfills not configured")
+)
+
+// MaxChunkSize is the largest ChunkSize a Job may request.
+const MaxChunkSize = 1000
+
+// Validate reports whether the Job's parameters are usable.
+func (j *Job) Validate() error {
+	switch {
+	case j.Rate < 1:
+		return fmt.Errorf("%w: rate must be at least 1", ErrInvalid)
+	case j.ChunkSize < 1:
+		return fmt.Errorf("%w: chunk_size must be at least 1", ErrInvalid)
+	case j.ChunkSize > MaxChunkSize:
+		return fmt.Errorf("%w: chunk_size %d is over the maximum of %d",
+			ErrInvalid, j.ChunkSize, MaxChunkSize)
+	}
+	return nil
+}
+
+// Indexer is the indexer functionality a Manager needs: it counts and lists
+// the stored manifests, and fetches their index reports.
+type Indexer interface {
+	indexer.Reporter
+	indexer.UsageReporter
+	indexer.ManifestLister
+}
+
+// Scanner is the matcher functionality a Manager needs.
+type Scanner interface {
+	Scan(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+}
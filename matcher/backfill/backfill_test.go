@@ -0,0 +1,164 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+type scanFunc func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+
+func (f scanFunc) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	return f(ctx, ir)
+}
+
+// FakeIndexer stores the manifests "0" through "n-1", formatted as digests.
+// Manifests in "missing" have no index report.
+type fakeIndexer struct {
+	ds      []claircore.Digest
+	missing map[string]bool
+}
+
+func newFakeIndexer(n int) *fakeIndexer {
+	f := fakeIndexer{missing: make(map[string]bool)}
+	for i := 0; i < n; i++ {
+		f.ds = append(f.ds, claircore.MustParseDigest(fmt.Sprintf("sha256:%064x", i)))
+	}
+	return &f
+}
+
+func (f *fakeIndexer) IndexReport(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	if f.missing[d.String()] {
+		return nil, false, nil
+	}
+	return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+}
+
+func (f *fakeIndexer) Usage(_ context.Context, _ string) (*indexer.Usage, error) {
+	return &indexer.Usage{Manifests: int64(len(f.ds))}, nil
+}
+
+func (f *fakeIndexer) Manifests(_ context.Context, after string, limit int) ([]claircore.Digest, error) {
+	i := sort.Search(len(f.ds), func(i int) bool { return f.ds[i].String() > after })
+	j := i + limit
+	if j > len(f.ds) {
+		j = len(f.ds)
+	}
+	return f.ds[i:j], nil
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		Name string
+		Job  Job
+		OK   bool
+	}{
+		{Name: "OK", Job: Job{Rate: 10, ChunkSize: 100}, OK: true},
+		{Name: "NoRate", Job: Job{ChunkSize: 100}},
+		{Name: "NoChunk", Job: Job{Rate: 10}},
+		{Name: "BigChunk", Job: Job{Rate: 10, ChunkSize: MaxChunkSize + 1}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Job.Validate()
+			if tc.OK {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("got: %v, want: %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("Running", func(t *testing.T) {
+		j := Job{State: StateRunning, Total: 1000, Done: 250, ActiveSeconds: 25}
+		j.estimate(now)
+		if got, want := j.Progress, 0.25; got != want {
+			t.Errorf("progress: got: %v, want: %v", got, want)
+		}
+		// 750 left at 10 per second.
+		if want := now.Add(75 * time.Second); j.ETA == nil || !j.ETA.Equal(want) {
+			t.Errorf("eta: got: %v, want: %v", j.ETA, want)
+		}
+	})
+	t.Run("Paused", func(t *testing.T) {
+		j := Job{State: StatePaused, Total: 1000, Done: 250, ActiveSeconds: 25}
+		j.estimate(now)
+		if j.ETA != nil {
+			t.Errorf("eta: got: %v, want: nil", j.ETA)
+		}
+	})
+	t.Run("Grown", func(t *testing.T) {
+		j := Job{State: StateRunning, Total: 100, Done: 120, ActiveSeconds: 12}
+		j.estimate(now)
+		if j.Progress != 1 || j.ETA == nil || !j.ETA.Equal(now) {
+			t.Errorf("got: %v, %v", j.Progress, j.ETA)
+		}
+	})
+	t.Run("Finished", func(t *testing.T) {
+		j := Job{State: StateFinished}
+		j.estimate(now)
+		if j.Progress != 1 {
+			t.Errorf("progress: got: %v, want: 1", j.Progress)
+		}
+	})
+}
+
+func TestRunChunk(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	i := newFakeIndexer(5)
+	i.missing[i.ds[1].String()] = true
+	var scanned []string
+	s := scanFunc(func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+		scanned = append(scanned, ir.Hash.String())
+		if ir.Hash.String() == i.ds[2].String() {
+			return nil, errors.New("boom")
+		}
+		return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+	})
+	m := &Manager{opts: Options{Indexer: i, Scanner: s}}
+	j := Job{Rate: 1000, ChunkSize: 3}
+	deadline := time.Now().Add(time.Minute)
+
+	c := m.runChunk(ctx, &j, deadline)
+	if c.Done != 3 || c.Failed != 1 || c.End || c.Err == nil {
+		t.Errorf("first chunk: got: %+v", c)
+	}
+	if got, want := c.Last, i.ds[2].String(); got != want {
+		t.Errorf("first chunk: last: got: %q, want: %q", got, want)
+	}
+	// The missing manifest is skipped without scanning.
+	if got, want := len(scanned), 2; got != want {
+		t.Errorf("first chunk: scanned %d, want %d", got, want)
+	}
+
+	j.LastManifest = c.Last
+	c = m.runChunk(ctx, &j, deadline)
+	if c.Done != 2 || c.Failed != 0 || !c.End {
+		t.Errorf("second chunk: got: %+v", c)
+	}
+	if got, want := c.Last, i.ds[4].String(); got != want {
+		t.Errorf("second chunk: last: got: %q, want: %q", got, want)
+	}
+
+	// Nothing is done after the deadline.
+	j.LastManifest = ""
+	c = m.runChunk(ctx, &j, time.Now().Add(-time.Second))
+	if c.Done != 0 || c.End {
+		t.Errorf("past deadline: got: %+v", c)
+	}
+}
@@ -0,0 +1,269 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_backfill", "the backfill manager")
+
+// Options configures a Manager.
+type Options struct {
+	// Indexer is used to list manifests and fetch index reports.
+	Indexer Indexer
+	// Scanner is used to re-match manifests.
+	Scanner Scanner
+	// Rate and ChunkSize are used for Jobs started without them.
+	Rate      int
+	ChunkSize int
+}
+
+// Manager stores backfill Jobs and runs the active one.
+//
+// A nil Manager returns ErrDisabled from every method.
+type Manager struct {
+	pool *pgxpool.Pool
+	opts Options
+}
+
+// New returns a Manager using the provided pool, which must be connected to
+// the matcher's database.
+func New(pool *pgxpool.Pool, opts *Options) *Manager {
+	return &Manager{pool: pool, opts: *opts}
+}
+
+// Columns is the column list used by every query returning Jobs.
+const columns = `id, state, rate, chunk_size, total, done, failed, last_manifest,
+	last_error, active_seconds, created, updated, finished`
+
+// Scan reads a backfill row, in the column order of "columns", and fills in
+// the Job's estimates.
+func scan(row pgx.Row, j *Job) error {
+	var lastErr *string
+	err := row.Scan(&j.ID, &j.State, &j.Rate, &j.ChunkSize,
+		&j.Total, &j.Done, &j.Failed, &j.LastManifest,
+		&lastErr, &j.ActiveSeconds, &j.Created, &j.Updated, &j.Finished)
+	if err != nil {
+		return err
+	}
+	if lastErr != nil {
+		j.LastError = *lastErr
+	}
+	j.estimate(time.Now())
+	return nil
+}
+
+// Jobs returns all Jobs, newest first.
+func (m *Manager) Jobs(ctx context.Context) (_ []Job, err error) {
+	if m == nil {
+		return nil, ErrDisabled
+	}
+	const query = `SELECT ` + columns + ` FROM matcher_backfill ORDER BY created DESC, id;`
+	defer queryMetrics.Observe("list", &err)()
+	rows, err := m.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("backfill: unable to list backfills: %w", err)
+	}
+	defer rows.Close()
+	out := []Job{}
+	for rows.Next() {
+		var j Job
+		if err = scan(rows, &j); err != nil {
+			return nil, fmt.Errorf("backfill: unable to list backfills: %w", err)
+		}
+		out = append(out, j)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("backfill: unable to list backfills: %w", err)
+	}
+	return out, nil
+}
+
+// Job returns the identified Job, reporting false if it doesn't exist.
+func (m *Manager) Job(ctx context.Context, id uuid.UUID) (_ *Job, ok bool, err error) {
+	if m == nil {
+		return nil, false, ErrDisabled
+	}
+	const query = `SELECT ` + columns + ` FROM matcher_backfill WHERE id = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var j Job
+	err = scan(m.pool.QueryRow(ctx, query, id), &j)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("backfill: unable to get %v: %w", id, err)
+	}
+	return &j, true, nil
+}
+
+// Start starts a Job, assigning its ID and recording the number of manifests
+// currently stored. A zero Rate or ChunkSize is replaced with the Manager's
+// default.
+//
+// ErrActive is returned if another Job is running or paused.
+func (m *Manager) Start(ctx context.Context, j *Job) (err error) {
+	if m == nil {
+		return ErrDisabled
+	}
+	const query = `INSERT INTO matcher_backfill (id, state, rate, chunk_size, total)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING
+RETURNING ` + columns + `;`
+	if j.Rate == 0 {
+		j.Rate = m.opts.Rate
+	}
+	if j.ChunkSize == 0 {
+		j.ChunkSize = m.opts.ChunkSize
+	}
+	if err = j.Validate(); err != nil {
+		return err
+	}
+	u, err := m.opts.Indexer.Usage(ctx, "")
+	if err != nil {
+		return fmt.Errorf("backfill: unable to count manifests: %w", err)
+	}
+	defer queryMetrics.Observe("start", &err)()
+	err = scan(m.pool.QueryRow(ctx, query,
+		uuid.New(), string(StateRunning), j.Rate, j.ChunkSize, u.Manifests), j)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		// The insert conflicted with the index on active backfills.
+		err = nil
+		return ErrActive
+	default:
+		return fmt.Errorf("backfill: unable to store backfill: %w", err)
+	}
+	return nil
+}
+
+// Transitions maps the states a Job may be moved to onto the states it may be
+// moved from.
+var transitions = map[State][]string{
+	StatePaused:   {string(StateRunning)},
+	StateRunning:  {string(StatePaused)},
+	StateCanceled: {string(StateRunning), string(StatePaused)},
+}
+
+// SetState pauses, resumes, or cancels the identified Job, reporting false if
+// it doesn't exist. ErrInvalid is returned if the Job can't be moved to the
+// requested state.
+//
+// A running Job finishes its current chunk before a change takes effect.
+func (m *Manager) SetState(ctx context.Context, id uuid.UUID, to State) (_ *Job, ok bool, err error) {
+	if m == nil {
+		return nil, false, ErrDisabled
+	}
+	const query = `UPDATE matcher_backfill
+SET state = $2, updated = now(),
+	finished = CASE WHEN $2 = 'canceled' THEN now() ELSE finished END
+WHERE id = $1 AND state = ANY($3)
+RETURNING ` + columns + `;`
+	from, allowed := transitions[to]
+	if !allowed {
+		return nil, false, fmt.Errorf("%w: state can't be set to %q", ErrInvalid, to)
+	}
+	var j Job
+	err = func() (err error) {
+		defer queryMetrics.Observe("set_state", &err)()
+		return scan(m.pool.QueryRow(ctx, query, id, string(to), from), &j)
+	}()
+	switch {
+	case err == nil:
+		return &j, true, nil
+	case errors.Is(err, pgx.ErrNoRows):
+	default:
+		return nil, false, fmt.Errorf("backfill: unable to update %v: %w", id, err)
+	}
+	// Nothing was updated, so report why.
+	cur, ok, err := m.Job(ctx, id)
+	switch {
+	case err != nil:
+		return nil, false, err
+	case !ok:
+		return nil, false, nil
+	}
+	return nil, true, fmt.Errorf("%w: backfill is %s, so can't be %s", ErrInvalid, cur.State, to)
+}
+
+// Claim takes a lease on the running Job, if there is one and it isn't
+// leased by another process, and returns it. The lease lasts as long as a
+// chunk takes at the Job's rate, plus "slack".
+//
+// The lease keeps multiple matcher processes from working on the Job at
+// once, without holding a lock that would block state changes.
+func (m *Manager) claim(ctx context.Context, slack time.Duration) (_ *Job, err error) {
+	const query = `UPDATE matcher_backfill
+SET lease = now() + make_interval(secs => chunk_size::double precision / rate) + $1::interval
+WHERE id = (
+	SELECT id FROM matcher_backfill
+	WHERE state = 'running' AND (lease IS NULL OR lease < now())
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + columns + `;`
+	defer queryMetrics.Observe("claim", &err)()
+	var j Job
+	err = scan(m.pool.QueryRow(ctx, query, slack), &j)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("backfill: unable to claim backfill: %w", err)
+	}
+	return &j, nil
+}
+
+// Chunk is the outcome of processing one chunk of a Job.
+type chunk struct {
+	// Last is the last manifest processed.
+	Last   string
+	Done   int64
+	Failed int64
+	// Err is the most recent failure, if there was one.
+	Err    error
+	Active time.Duration
+	// End reports whether there are no manifests after Last.
+	End bool
+}
+
+// Record saves the progress made by a chunk and releases the Job's lease. A
+// running Job that's reached the end is marked finished.
+func (m *Manager) record(ctx context.Context, id uuid.UUID, c *chunk) (err error) {
+	const query = `UPDATE matcher_backfill
+SET last_manifest = CASE WHEN $2 = '' THEN last_manifest ELSE $2 END,
+	done = done + $3,
+	failed = failed + $4,
+	last_error = coalesce($5, last_error),
+	active_seconds = active_seconds + $6,
+	state = CASE WHEN $7 AND state = 'running' THEN 'finished' ELSE state END,
+	finished = CASE WHEN $7 AND state = 'running' THEN now() ELSE finished END,
+	updated = now(),
+	lease = NULL
+WHERE id = $1;`
+	defer queryMetrics.Observe("record", &err)()
+	var msg *string
+	if c.Err != nil {
+		s := c.Err.Error()
+		msg = &s
+	}
+	_, err = m.pool.Exec(ctx, query,
+		id, c.Last, c.Done, c.Failed, msg, c.Active.Seconds(), c.End)
+	if err != nil {
+		return fmt.Errorf("backfill: unable to record progress of %v: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestManager(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	m := New(pool, &Options{Indexer: newFakeIndexer(5), Rate: 10, ChunkSize: 2})
+
+	var j Job
+	if err := m.Start(ctx, &j); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if j.State != StateRunning || j.Total != 5 || j.ChunkSize != 2 {
+		t.Errorf("start: got: %+v", j)
+	}
+	if err := m.Start(ctx, &Job{}); !errors.Is(err, ErrActive) {
+		t.Errorf("second start: got: %v, want: %v", err, ErrActive)
+	}
+
+	if _, ok, err := m.SetState(ctx, j.ID, StatePaused); err != nil || !ok {
+		t.Fatalf("pause: got: (%v, %v)", ok, err)
+	}
+	if _, _, err := m.SetState(ctx, j.ID, StatePaused); !errors.Is(err, ErrInvalid) {
+		t.Errorf("pause again: got: %v, want: %v", err, ErrInvalid)
+	}
+	if got, err := m.claim(ctx, leaseSlack); err != nil || got != nil {
+		t.Errorf("claim paused: got: (%v, %v)", got, err)
+	}
+	if _, ok, err := m.SetState(ctx, j.ID, StateRunning); err != nil || !ok {
+		t.Fatalf("resume: got: (%v, %v)", ok, err)
+	}
+
+	got, err := m.claim(ctx, leaseSlack)
+	if err != nil || got == nil {
+		t.Fatalf("claim: got: (%v, %v)", got, err)
+	}
+	if again, err := m.claim(ctx, leaseSlack); err != nil || again != nil {
+		t.Errorf("claim leased: got: (%v, %v)", again, err)
+	}
+	c := chunk{Last: "sha256:01", Done: 5, Failed: 1, Err: errors.New("boom"), End: true}
+	if err := m.record(ctx, got.ID, &c); err != nil {
+		t.Fatal(err)
+	}
+	done, ok, err := m.Job(ctx, j.ID)
+	if err != nil || !ok {
+		t.Fatalf("get: got: (%v, %v)", ok, err)
+	}
+	if done.State != StateFinished || done.Done != 5 || done.Failed != 1 ||
+		done.LastError != "boom" || done.Progress != 1 || done.Finished == nil {
+		t.Errorf("get: got: %+v", done)
+	}
+
+	// A finished backfill isn't active, so another may be started.
+	if err := m.Start(ctx, &Job{}); err != nil {
+		t.Errorf("restart: %v", err)
+	}
+	all, err := m.Jobs(ctx)
+	if err != nil || len(all) != 2 {
+		t.Errorf("list: got: (%+v, %v)", all, err)
+	}
+	if _, ok, err := m.SetState(ctx, all[0].ID, StateCanceled); err != nil || !ok {
+		t.Errorf("cancel: got: (%v, %v)", ok, err)
+	}
+}
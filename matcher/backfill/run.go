@@ -0,0 +1,160 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+	"golang.org/x/time/rate"
+)
+
+var manifestCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "matcher_backfill",
+		Name:      "manifests_total",
+		Help:      "Total number of manifests processed by backfills",
+	},
+	[]string{"result"},
+)
+
+// These bound the work done for a chunk.
+const (
+	// ScanTimeout is how long re-matching a single manifest may take.
+	scanTimeout = 2 * time.Minute
+	// LeaseSlack is added to the time a chunk should take at the Job's rate
+	// to get the length of its lease.
+	leaseSlack = 5 * time.Minute
+)
+
+// Run works on the running Job, if there is one, every "interval" until the
+// Context is canceled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/backfill/Manager.Run")
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		if err := m.runActive(ctx); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to run backfill")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// RunActive processes chunks of the running Job until it's finished, paused,
+// or canceled, or another process holds it.
+func (m *Manager) runActive(ctx context.Context) error {
+	for {
+		// Claiming anew for every chunk picks up state changes.
+		j, err := m.claim(ctx, leaseSlack)
+		if err != nil || j == nil {
+			return err
+		}
+		// Stop the chunk well before the lease expires, in case scans are
+		// slower than the Job's rate.
+		deadline := time.Now().
+			Add(time.Duration(j.ChunkSize) * time.Second / time.Duration(j.Rate)).
+			Add(leaseSlack / 2)
+		c := m.runChunk(ctx, j, deadline)
+		zlog.Debug(ctx).
+			Stringer("backfill", j.ID).
+			Int64("done", c.Done).
+			Int64("failed", c.Failed).
+			Bool("end", c.End).
+			Msg("ran backfill chunk")
+		// Save progress even if the Context was canceled, so that the work
+		// isn't repeated.
+		if err := m.record(context.Background(), j.ID, c); err != nil {
+			return err
+		}
+		if c.Done == 0 && !c.End {
+			// Nothing could be done, so wait for the next interval rather
+			// than retrying immediately.
+			return c.Err
+		}
+		if c.End {
+			zlog.Info(ctx).
+				Stringer("backfill", j.ID).
+				Int64("done", j.Done+c.Done).
+				Int64("failed", j.Failed+c.Failed).
+				Msg("backfill finished")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil
+		}
+	}
+}
+
+// RunChunk re-matches up to one chunk of manifests after the Job's last
+// manifest, stopping early at "deadline".
+func (m *Manager) runChunk(ctx context.Context, j *Job, deadline time.Time) *chunk {
+	start := time.Now()
+	c := chunk{}
+	defer func() { c.Active = time.Since(start) }()
+	ds, err := m.opts.Indexer.Manifests(ctx, j.LastManifest, j.ChunkSize)
+	if err != nil {
+		c.Err = fmt.Errorf("unable to list manifests: %w", err)
+		return &c
+	}
+	lim := rate.NewLimiter(rate.Limit(j.Rate), 1)
+	for i := range ds {
+		if time.Now().After(deadline) {
+			return &c
+		}
+		if err := lim.Wait(ctx); err != nil {
+			return &c
+		}
+		res, err := m.rematch(ctx, ds[i])
+		if ctx.Err() != nil {
+			// Don't count a manifest interrupted by shutdown.
+			return &c
+		}
+		manifestCounter.WithLabelValues(res).Inc()
+		c.Last = ds[i].String()
+		c.Done++
+		if err != nil {
+			c.Failed++
+			c.Err = fmt.Errorf("%s: %w", ds[i], err)
+			zlog.Info(ctx).
+				Err(err).
+				Stringer("backfill", j.ID).
+				Stringer("manifest", ds[i]).
+				Msg("unable to re-match manifest")
+		}
+	}
+	c.End = len(ds) < j.ChunkSize
+	return &c
+}
+
+// Rematch builds a new vulnerability report for the manifest, returning the
+// metric label for the outcome.
+//
+// Manifests without a successful index report are skipped: there's nothing
+// to match.
+func (m *Manager) rematch(ctx context.Context, d claircore.Digest) (string, error) {
+	ctx, done := context.WithTimeout(ctx, scanTimeout)
+	defer done()
+	ir, ok, err := m.opts.Indexer.IndexReport(ctx, d)
+	switch {
+	case err != nil:
+		return "failed", fmt.Errorf("unable to fetch index report: %w", err)
+	case !ok, !ir.Success:
+		return "skipped", nil
+	}
+	if _, err := m.opts.Scanner.Scan(ctx, ir); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %v: %w", scanTimeout, err)
+		}
+		return "failed", fmt.Errorf("unable to scan: %w", err)
+	}
+	return "ok", nil
+}
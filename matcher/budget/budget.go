@@ -0,0 +1,189 @@
+// Package budget bounds the time and bytes each updater may use in one update
+// run, so one slow upstream can't starve the rest of the update window.
+//
+// The updater manager takes a lock named for each updater before running it,
+// and runs the updater with the lock's Context. The LockSource returned by
+// Enforcer.Locker attaches a meter for the updater's budget to that Context,
+// and the RoundTripper returned by Enforcer.Transport charges the updater's
+// downloads against it. Once an updater is over budget, its downloads fail
+// and it's left until the next run.
+//
+// Downloads interrupted that way are kept. The next time the same URL is
+// requested, only the rest of it is, so an updater that can't finish in one
+// run makes progress across runs instead of starting over.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+var (
+	exhaustedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "matcher_budget",
+			Name:      "exhausted_total",
+			Help:      "Total number of updater runs stopped for exceeding their budget.",
+		},
+		[]string{"updater", "budget"},
+	)
+	resumeCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "matcher_budget",
+			Name:      "resumed_downloads_total",
+			Help:      "Total number of attempts to resume downloads interrupted in an earlier run.",
+		},
+		[]string{"result"},
+	)
+)
+
+// ErrExhausted is reported by downloads made by an updater that's over its
+// budget.
+var ErrExhausted = errors.New("budget: updater over budget")
+
+// LockSource is the lock interface used by the updater manager.
+type LockSource interface {
+	TryLock(context.Context, string) (context.Context, context.CancelFunc)
+	Lock(context.Context, string) (context.Context, context.CancelFunc)
+	Close(context.Context) error
+}
+
+// Enforcer applies the configured budgets.
+//
+// A nil *Enforcer enforces nothing.
+type Enforcer struct {
+	budgets []config.UpdaterBudget
+	spool   *spool
+}
+
+// New returns an Enforcer for the budgets "cfg", or nil if there are none.
+// Interrupted downloads are kept in a temporary directory.
+func New(cfg []config.UpdaterBudget) (*Enforcer, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	s, err := newSpool()
+	if err != nil {
+		return nil, fmt.Errorf("budget: %w", err)
+	}
+	return &Enforcer{budgets: cfg, spool: s}, nil
+}
+
+// Budget returns the budget for the updater "name", reporting false if it's
+// unbounded.
+func (e *Enforcer) budget(name string) (config.UpdaterBudget, bool) {
+	for _, b := range e.budgets {
+		if strings.HasPrefix(name, b.Updater) {
+			return b, b.Time > 0 || b.Bytes > 0
+		}
+	}
+	return config.UpdaterBudget{}, false
+}
+
+// Locker wraps "next" so that the Context handed out with each updater's lock
+// carries the updater's budget.
+func (e *Enforcer) Locker(next LockSource) LockSource {
+	if e == nil {
+		return next
+	}
+	return &locker{LockSource: next, e: e}
+}
+
+type locker struct {
+	LockSource
+	e *Enforcer
+}
+
+// TryLock implements LockSource.
+func (l *locker) TryLock(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	lctx, done := l.LockSource.TryLock(ctx, name)
+	b, ok := l.e.budget(name)
+	if !ok || lctx.Err() != nil {
+		return lctx, done
+	}
+	m := &meter{name: name, limit: b.Bytes}
+	if b.Time > 0 {
+		m.deadline = time.Now().Add(time.Duration(b.Time))
+	}
+	return context.WithValue(lctx, meterKey{}, m), func() {
+		if reason := m.exhausted(); reason != "" {
+			exhaustedCounter.WithLabelValues(name, reason).Inc()
+			zlog.Info(ctx).
+				Str("updater", name).
+				Str("budget", reason).
+				Int64("bytes", m.charged()).
+				Msg("updater over budget, paused until the next run")
+		}
+		done()
+	}
+}
+
+type meterKey struct{}
+
+// MeterFrom returns the meter carried by "ctx", if any.
+func meterFrom(ctx context.Context) *meter {
+	m, _ := ctx.Value(meterKey{}).(*meter)
+	return m
+}
+
+// Meter tracks one updater run's use of its budget.
+type meter struct {
+	name     string
+	limit    int64     // 0 if unbounded
+	deadline time.Time // zero if unbounded
+
+	mu     sync.Mutex
+	used   int64
+	reason string // set once the budget's exhausted
+}
+
+// Charge records "n" bytes downloaded, reporting an error wrapping
+// ErrExhausted if the budget's exhausted.
+func (m *meter) charge(n int) error {
+	m.mu.Lock()
+	m.used += int64(n)
+	m.mu.Unlock()
+	return m.check()
+}
+
+// Check reports an error wrapping ErrExhausted if the budget's exhausted.
+func (m *meter) check() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reason == "" {
+		switch {
+		case m.limit > 0 && m.used > m.limit:
+			m.reason = "bytes"
+		case !m.deadline.IsZero() && !time.Now().Before(m.deadline):
+			m.reason = "time"
+		default:
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s: %s", ErrExhausted, m.name, m.reason)
+}
+
+// Exhausted reports which budget was exhausted, or "" if neither was.
+func (m *meter) exhausted() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reason
+}
+
+// Charged reports the bytes downloaded.
+func (m *meter) charged() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
@@ -0,0 +1,139 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+type fakeLocks struct{}
+
+func (fakeLocks) TryLock(ctx context.Context, _ string) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
+func (l fakeLocks) Lock(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	return l.TryLock(ctx, name)
+}
+
+func (fakeLocks) Close(context.Context) error { return nil }
+
+func TestLocker(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	e, err := New([]config.UpdaterBudget{
+		{Updater: "osv/", Bytes: 1024},
+		{Updater: "nvd", Time: config.Duration(time.Minute)},
+		{Updater: ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := e.Locker(fakeLocks{})
+	tt := []struct {
+		Name     string
+		Metered  bool
+		Deadline bool
+	}{
+		{Name: "osv/npm", Metered: true},
+		{Name: "nvd", Metered: true, Deadline: true},
+		{Name: "ubuntu/updater/jammy", Metered: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			lctx, done := l.TryLock(ctx, tc.Name)
+			defer done()
+			m := meterFrom(lctx)
+			if got, want := m != nil, tc.Metered; got != want {
+				t.Fatalf("metered: got: %v, want: %v", got, want)
+			}
+			if m == nil {
+				return
+			}
+			if got, want := !m.deadline.IsZero(), tc.Deadline; got != want {
+				t.Errorf("deadline: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096) // 64 KiB
+	var ranged int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("range") != "" {
+			atomic.AddInt64(&ranged, 1)
+		}
+		w.Header().Set("etag", `"v1"`)
+		http.ServeContent(w, r, "feed", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	e, err := New([]config.UpdaterBudget{{Updater: "slow", Bytes: 40 * 1024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := e.Locker(fakeLocks{})
+	c := &http.Client{Transport: e.Transport(srv.Client().Transport)}
+	fetch := func() ([]byte, error) {
+		lctx, done := l.TryLock(ctx, "slow")
+		defer done()
+		req, err := http.NewRequestWithContext(lctx, http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status: %s", res.Status)
+		}
+		return io.ReadAll(res.Body)
+	}
+
+	// The first run runs out of budget partway through.
+	if _, err := fetch(); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("first run: got: %v, want: %v", err, ErrExhausted)
+	}
+	// The second run picks up where the first left off.
+	got, err := fetch()
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("second run: got %d bytes, want the original %d", len(got), len(content))
+	}
+	if got, want := atomic.LoadInt64(&ranged), int64(1); got != want {
+		t.Errorf("range requests: got: %d, want: %d", got, want)
+	}
+	// Nothing's kept once the download finishes.
+	if p := e.spool.take(srv.URL); p != nil {
+		t.Errorf("download still kept: %+v", p)
+	}
+
+	// Requests not made by a budgeted updater aren't charged.
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var b strings.Builder
+	if _, err := io.Copy(&b, res.Body); err != nil {
+		t.Error(err)
+	}
+	if got, want := b.Len(), len(content); got != want {
+		t.Errorf("unbudgeted: got: %d bytes, want: %d", got, want)
+	}
+}
@@ -0,0 +1,320 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/quay/zlog"
+)
+
+// Transport wraps "next" so that downloads are charged against the budget of
+// the updater making them.
+//
+// Requests made after the budget's exhausted fail, and so do reads from
+// response bodies. An interrupted GET response with a strong validator is
+// kept, and the next GET for the same URL asks for the remainder with a range
+// request. If the server answers with the rest of the same content, the
+// caller sees the whole body as an ordinary response; otherwise the kept part
+// is discarded. Requests not made by a budgeted updater are passed through.
+func (e *Enforcer) Transport(next http.RoundTripper) http.RoundTripper {
+	if e == nil {
+		return next
+	}
+	return &transport{rt: next, s: e.spool}
+}
+
+type transport struct {
+	rt http.RoundTripper
+	s  *spool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m := meterFrom(req.Context())
+	if m == nil {
+		return t.rt.RoundTrip(req)
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if !m.deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, m.deadline)
+	}
+	if req.Method != http.MethodGet || req.Header.Get("range") != "" {
+		res, err := t.rt.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			cancel()
+			return nil, t.err(m, err)
+		}
+		res.Body = &body{t: t, m: m, rc: res.Body, cancel: cancel}
+		return res, nil
+	}
+
+	key := req.URL.String()
+	if p := t.s.take(key); p != nil {
+		res, err := t.resume(ctx, req, m, p, cancel)
+		if res != nil || err != nil {
+			return res, err
+		}
+	}
+	res, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, t.err(m, err)
+	}
+	b := &body{t: t, m: m, rc: res.Body, cancel: cancel}
+	if v := validator(res); res.StatusCode == http.StatusOK && v != "" {
+		// A failure to keep the download only means it can't be resumed.
+		b.p, b.f, _ = t.s.create(key, v)
+	}
+	res.Body = b
+	return res, nil
+}
+
+// Resume asks for the remainder of the kept download "p". It returns a nil
+// response and error if the download should be started over.
+func (t *transport) resume(ctx context.Context, req *http.Request, m *meter, p *partial, cancel context.CancelFunc) (*http.Response, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "matcher/budget/transport.resume",
+		"updater", m.name,
+		"url", req.URL.Redacted(),
+		"offset", strconv.FormatInt(p.size, 10))
+	rreq := req.Clone(ctx)
+	rreq.Header.Set("range", "bytes="+strconv.FormatInt(p.size, 10)+"-")
+	rreq.Header.Set("if-range", p.validator)
+	res, err := t.rt.RoundTrip(rreq)
+	if err != nil {
+		t.s.release(p)
+		cancel()
+		return nil, t.err(m, err)
+	}
+	if res.StatusCode != http.StatusPartialContent ||
+		!strings.HasPrefix(res.Header.Get("content-range"), "bytes "+strconv.FormatInt(p.size, 10)+"-") {
+		resumeCounter.WithLabelValues("restarted").Inc()
+		zlog.Info(ctx).
+			Int("status", res.StatusCode).
+			Msg("unable to resume download, starting over")
+		res.Body.Close()
+		t.s.drop(p)
+		return nil, nil
+	}
+	kept, err := os.Open(p.path)
+	if err != nil {
+		res.Body.Close()
+		t.s.drop(p)
+		return nil, nil
+	}
+	f, err := os.OpenFile(p.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		kept.Close()
+		res.Body.Close()
+		t.s.drop(p)
+		return nil, nil
+	}
+	resumeCounter.WithLabelValues("resumed").Inc()
+	zlog.Info(ctx).Msg("resuming download from an earlier run")
+	b := &body{t: t, m: m, rc: res.Body, cancel: cancel, p: p, f: f}
+	res.Body = &resumedBody{
+		Reader: io.MultiReader(io.NewSectionReader(kept, 0, p.size), b),
+		kept:   kept,
+		b:      b,
+	}
+	if res.ContentLength >= 0 {
+		res.ContentLength += p.size
+	}
+	res.StatusCode = http.StatusOK
+	res.Status = "200 OK"
+	res.Header = res.Header.Clone()
+	res.Header.Del("content-range")
+	if res.ContentLength >= 0 {
+		res.Header.Set("content-length", strconv.FormatInt(res.ContentLength, 10))
+	}
+	return res, nil
+}
+
+// Err returns the error to report for "err", which is a budget error if the
+// budget ran out.
+func (t *transport) err(m *meter, err error) error {
+	if berr := m.check(); berr != nil {
+		return berr
+	}
+	return err
+}
+
+// Validator returns the response's strong validator, if any.
+func validator(res *http.Response) string {
+	if et := res.Header.Get("etag"); et != "" && !strings.HasPrefix(et, "W/") {
+		return et
+	}
+	return res.Header.Get("last-modified")
+}
+
+// Body is a response body charged against an updater's budget, and kept in
+// the spool as it's read if "p" is not nil.
+type body struct {
+	t      *transport
+	m      *meter
+	rc     io.ReadCloser
+	cancel context.CancelFunc
+	p      *partial
+	f      *os.File
+	done   bool
+}
+
+// Read implements io.Reader.
+func (b *body) Read(buf []byte) (int, error) {
+	n, err := b.rc.Read(buf)
+	if b.f != nil && n > 0 {
+		if _, werr := b.f.Write(buf[:n]); werr != nil {
+			b.abandon()
+		} else {
+			b.p.size += int64(n)
+		}
+	}
+	if cerr := b.m.charge(n); cerr != nil {
+		err = cerr
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF):
+		b.abandon()
+	default:
+		err = b.t.err(b.m, err)
+		if errors.Is(err, ErrExhausted) {
+			b.keep()
+		} else {
+			b.abandon()
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *body) Close() error {
+	// A body closed before it's finished was abandoned by the caller.
+	b.abandon()
+	b.cancel()
+	return b.rc.Close()
+}
+
+// Keep leaves the download in the spool for a later run.
+func (b *body) keep() {
+	if b.done || b.p == nil {
+		return
+	}
+	b.done = true
+	b.f.Close()
+	b.f = nil
+	if b.p.size == 0 {
+		b.t.s.drop(b.p)
+		return
+	}
+	b.t.s.release(b.p)
+}
+
+// Abandon removes the download from the spool.
+func (b *body) abandon() {
+	if b.done || b.p == nil {
+		return
+	}
+	b.done = true
+	b.f.Close()
+	b.f = nil
+	b.t.s.drop(b.p)
+}
+
+// ResumedBody is the kept part of a download followed by the remainder.
+type resumedBody struct {
+	io.Reader
+	kept *os.File
+	b    *body
+}
+
+// Close implements io.Closer.
+func (r *resumedBody) Close() error {
+	r.kept.Close()
+	return r.b.Close()
+}
+
+// Spool holds interrupted downloads, keyed by URL.
+type spool struct {
+	dir string
+
+	mu sync.Mutex
+	m  map[string]*partial
+}
+
+// Partial is an interrupted download.
+type partial struct {
+	key       string
+	path      string
+	validator string
+	size      int64
+	busy      bool
+}
+
+func newSpool() (*spool, error) {
+	dir, err := os.MkdirTemp("", "clair-updater-spool-")
+	if err != nil {
+		return nil, err
+	}
+	return &spool{dir: dir, m: make(map[string]*partial)}, nil
+}
+
+// Take returns the kept download for "key" and marks it in use, or returns
+// nil if there's none or it's already in use.
+func (s *spool) take(key string) *partial {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.m[key]
+	if !ok || p.busy {
+		return nil
+	}
+	p.busy = true
+	return p
+}
+
+// Create starts keeping a new download for "key", replacing any kept one
+// that's not in use.
+func (s *spool) create(key, validator string) (*partial, *os.File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.m[key]; ok {
+		if p.busy {
+			return nil, nil, errors.New("download in progress")
+		}
+		os.Remove(p.path)
+	}
+	f, err := os.CreateTemp(s.dir, "partial-")
+	if err != nil {
+		delete(s.m, key)
+		return nil, nil, err
+	}
+	p := &partial{key: key, path: f.Name(), validator: validator, busy: true}
+	s.m[key] = p
+	return p, f, nil
+}
+
+// Release marks "p" as no longer in use, keeping it.
+func (s *spool) release(p *partial) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.busy = false
+}
+
+// Drop removes "p".
+func (s *spool) drop(p *partial) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m[p.key] == p {
+		delete(s.m, p.key)
+	}
+	os.Remove(p.path)
+}
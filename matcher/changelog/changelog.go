@@ -0,0 +1,221 @@
+// Package changelog records an append-only log of the changes to each
+// manifest's vulnerability report, so downstream systems can reconstruct when
+// Clair's answer for an image changed and why.
+//
+// Each time a manifest's vulnerability report is built, it's compared to the
+// last one recorded for the manifest. The first report is logged as the
+// manifest being indexed, a report whose packages, distributions, or
+// repositories differ as it being re-indexed, and any findings gained or lost
+// as events of their own. Every event carries the vulnerability data cursor:
+// the most recent update operation at the time. Reports that haven't changed
+// aren't logged.
+package changelog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+)
+
+// ErrDisabled is returned when the changelog isn't configured.
+var ErrDisabled = errors.New("changelog: changelog not enabled")
+
+// Kind is the kind of an event.
+type Kind string
+
+// These are the kinds of events.
+const (
+	// KindIndexed is logged for the first report built for a manifest.
+	KindIndexed Kind = `indexed`
+	// KindReindexed is logged when a manifest's packages, distributions, or
+	// repositories change, usually because it was indexed again by newer
+	// scanners.
+	KindReindexed Kind = `reindexed`
+	// KindFindingsAdded is logged when a report has findings the previous
+	// one didn't.
+	KindFindingsAdded Kind = `findings_added`
+	// KindFindingsRemoved is logged when a report lacks findings the
+	// previous one had.
+	KindFindingsRemoved Kind = `findings_removed`
+)
+
+// Reason explains a change in findings.
+type Reason string
+
+// These are the reasons findings change.
+const (
+	// ReasonIndex means the manifest's contents changed.
+	ReasonIndex Reason = `index`
+	// ReasonVulnerabilityData means the vulnerability data changed.
+	ReasonVulnerabilityData Reason = `vulnerability_data`
+	// ReasonMatcher means neither changed, so the way reports are built did:
+	// for example, the configured matchers.
+	ReasonMatcher Reason = `matcher`
+)
+
+// Finding is a vulnerability affecting a package.
+type Finding struct {
+	Vulnerability string `json:"vulnerability"`
+	Package       string `json:"package"`
+	Version       string `json:"version"`
+}
+
+// Event is one entry in a manifest's changelog.
+type Event struct {
+	// ID orders a manifest's events, and is what clients page with.
+	ID       int64     `json:"id"`
+	Kind     Kind      `json:"kind"`
+	Recorded time.Time `json:"recorded"`
+	// Cursor is the reference of the most recent update operation when the
+	// event was recorded.
+	Cursor uuid.UUID `json:"cursor"`
+	// Reason is reported for changes in findings.
+	Reason   Reason    `json:"reason,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Page describes a page of events.
+type Page struct {
+	// Size is the maximum number of events in a page.
+	Size int `json:"size"`
+	// Next is the ID of the first event of the next page, if there is one.
+	Next *int64 `json:"next,omitempty"`
+}
+
+// State is what's remembered of the last report recorded for a manifest.
+type State struct {
+	// Fingerprint summarizes the manifest's contents. It's empty if nothing
+	// has been recorded.
+	Fingerprint string
+	Cursor      uuid.UUID
+	// Findings are sorted.
+	Findings []Finding
+}
+
+// Observe summarizes the report "vr".
+func Observe(vr *claircore.VulnerabilityReport, cur uuid.UUID) State {
+	return State{
+		Fingerprint: fingerprint(vr),
+		Cursor:      cur,
+		Findings:    findings(vr),
+	}
+}
+
+// Compare returns the events that lead from the state "prev" to "next".
+func Compare(prev, next *State) []Event {
+	var out []Event
+	reason := ReasonMatcher
+	switch {
+	case prev.Fingerprint == "":
+		out = append(out, Event{Kind: KindIndexed, Cursor: next.Cursor})
+		reason = ReasonIndex
+	case prev.Fingerprint != next.Fingerprint:
+		out = append(out, Event{Kind: KindReindexed, Cursor: next.Cursor})
+		reason = ReasonIndex
+	case prev.Cursor != next.Cursor:
+		reason = ReasonVulnerabilityData
+	}
+	added, removed := diff(prev.Findings, next.Findings)
+	if len(added) != 0 {
+		out = append(out, Event{
+			Kind:     KindFindingsAdded,
+			Cursor:   next.Cursor,
+			Reason:   reason,
+			Findings: added,
+		})
+	}
+	if len(removed) != 0 {
+		out = append(out, Event{
+			Kind:     KindFindingsRemoved,
+			Cursor:   next.Cursor,
+			Reason:   reason,
+			Findings: removed,
+		})
+	}
+	return out
+}
+
+// Diff returns the findings only in "b" and those only in "a". Both must be
+// sorted.
+func diff(a, b []Finding) (added, removed []Finding) {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch c := compareFinding(a[i], b[j]); {
+		case c < 0:
+			removed = append(removed, a[i])
+			i++
+		case c > 0:
+			added = append(added, b[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	removed = append(removed, a[i:]...)
+	added = append(added, b[j:]...)
+	return added, removed
+}
+
+func compareFinding(a, b Finding) int {
+	if c := strings.Compare(a.Vulnerability, b.Vulnerability); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Package, b.Package); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Version, b.Version)
+}
+
+// Findings returns the distinct findings in "vr", sorted.
+func findings(vr *claircore.VulnerabilityReport) []Finding {
+	seen := make(map[Finding]struct{})
+	for pkgID, vulnIDs := range vr.PackageVulnerabilities {
+		p, ok := vr.Packages[pkgID]
+		if !ok {
+			continue
+		}
+		for _, id := range vulnIDs {
+			v, ok := vr.Vulnerabilities[id]
+			if !ok {
+				continue
+			}
+			seen[Finding{Vulnerability: v.Name, Package: p.Name, Version: p.Version}] = struct{}{}
+		}
+	}
+	out := make([]Finding, 0, len(seen))
+	for f := range seen {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return compareFinding(out[i], out[j]) < 0 })
+	return out
+}
+
+// Fingerprint summarizes the packages, distributions, and repositories in
+// "vr". Database IDs aren't used, so the same contents fingerprint the same
+// way however they were indexed.
+func fingerprint(vr *claircore.VulnerabilityReport) string {
+	var ls []string
+	for _, p := range vr.Packages {
+		ls = append(ls, "p\x00"+p.Name+"\x00"+p.Version+"\x00"+p.Kind+"\x00"+p.Arch)
+	}
+	for _, d := range vr.Distributions {
+		ls = append(ls, "d\x00"+d.DID+"\x00"+d.VersionID+"\x00"+d.CPE.String())
+	}
+	for _, r := range vr.Repositories {
+		ls = append(ls, "r\x00"+r.Name+"\x00"+r.Key+"\x00"+r.URI)
+	}
+	sort.Strings(ls)
+	h := sha256.New()
+	for _, l := range ls {
+		h.Write([]byte(l))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
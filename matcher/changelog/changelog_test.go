@@ -0,0 +1,105 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+)
+
+func report(pkgs map[string]string, vulns ...string) *claircore.VulnerabilityReport {
+	vr := &claircore.VulnerabilityReport{
+		Packages:               make(map[string]*claircore.Package),
+		Vulnerabilities:        make(map[string]*claircore.Vulnerability),
+		PackageVulnerabilities: make(map[string][]string),
+	}
+	for name, version := range pkgs {
+		vr.Packages[name] = &claircore.Package{ID: name, Name: name, Version: version}
+	}
+	// Vulnerabilities are given as "package/name".
+	for i, v := range vulns {
+		pkg, name, _ := strings.Cut(v, "/")
+		id := string(rune('a' + i))
+		vr.Vulnerabilities[id] = &claircore.Vulnerability{ID: id, Name: name}
+		vr.PackageVulnerabilities[pkg] = append(vr.PackageVulnerabilities[pkg], id)
+	}
+	return vr
+}
+
+func TestCompare(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	pkgs := map[string]string{"openssl": "1", "zlib": "1"}
+	base := Observe(report(pkgs, "openssl/CVE-1"), first)
+
+	tt := []struct {
+		Name string
+		Prev State
+		Next State
+		Want []Event
+	}{
+		{
+			Name: "Indexed",
+			Next: base,
+			Want: []Event{
+				{Kind: KindIndexed, Cursor: first},
+				{Kind: KindFindingsAdded, Cursor: first, Reason: ReasonIndex, Findings: []Finding{
+					{Vulnerability: "CVE-1", Package: "openssl", Version: "1"},
+				}},
+			},
+		},
+		{
+			Name: "Unchanged",
+			Prev: base,
+			Next: Observe(report(pkgs, "openssl/CVE-1"), first),
+		},
+		{
+			Name: "VulnerabilityData",
+			Prev: base,
+			Next: Observe(report(pkgs, "zlib/CVE-2"), second),
+			Want: []Event{
+				{Kind: KindFindingsAdded, Cursor: second, Reason: ReasonVulnerabilityData, Findings: []Finding{
+					{Vulnerability: "CVE-2", Package: "zlib", Version: "1"},
+				}},
+				{Kind: KindFindingsRemoved, Cursor: second, Reason: ReasonVulnerabilityData, Findings: []Finding{
+					{Vulnerability: "CVE-1", Package: "openssl", Version: "1"},
+				}},
+			},
+		},
+		{
+			Name: "Reindexed",
+			Prev: base,
+			Next: Observe(report(map[string]string{"openssl": "2", "zlib": "1"}), first),
+			Want: []Event{
+				{Kind: KindReindexed, Cursor: first},
+				{Kind: KindFindingsRemoved, Cursor: first, Reason: ReasonIndex, Findings: []Finding{
+					{Vulnerability: "CVE-1", Package: "openssl", Version: "1"},
+				}},
+			},
+		},
+		{
+			Name: "ReindexedSameContents",
+			Prev: base,
+			Next: Observe(report(map[string]string{"zlib": "1", "openssl": "1"}, "openssl/CVE-1"), first),
+		},
+		{
+			Name: "Matcher",
+			Prev: base,
+			Next: Observe(report(pkgs), first),
+			Want: []Event{
+				{Kind: KindFindingsRemoved, Cursor: first, Reason: ReasonMatcher, Findings: []Finding{
+					{Vulnerability: "CVE-1", Package: "openssl", Version: "1"},
+				}},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := Compare(&tc.Prev, &tc.Next)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
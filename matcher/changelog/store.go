@@ -0,0 +1,237 @@
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var (
+	queryMetrics = dbutil.NewQueryMetrics("matcher_changelog", "the changelog store")
+	eventCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "matcher_changelog",
+			Name:      "events_total",
+			Help:      "Total number of changelog events recorded, by kind",
+		},
+		[]string{"kind"},
+	)
+)
+
+// DefaultPageSize is the page size used if none is requested.
+const DefaultPageSize = 100
+
+// Store persists changelogs.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Latest is the subset of matcher.Differ needed to find the current cursor.
+type Latest interface {
+	LatestUpdateOperation(context.Context, driver.UpdateKind) (uuid.UUID, error)
+}
+
+// Observe records the changes in the report "vr" since the last one recorded
+// for its manifest, against the current cursor reported by "l".
+func (s *Store) Observe(ctx context.Context, l Latest, vr *claircore.VulnerabilityReport) error {
+	cur, err := l.LatestUpdateOperation(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		return fmt.Errorf("changelog: unable to find latest update operation: %w", err)
+	}
+	next := Observe(vr, cur)
+	_, err = s.Record(ctx, vr.Hash, &next)
+	return err
+}
+
+// Record compares "next" to the state recorded for the manifest "m", appends
+// the resulting events to its log, and stores "next" in its place. It returns
+// the events recorded.
+//
+// Concurrent calls for one manifest are serialized, so every change is logged
+// exactly once.
+func (s *Store) Record(ctx context.Context, m claircore.Digest, next *State) (_ []Event, err error) {
+	const (
+		ensure = `INSERT INTO matcher_changelog_state (manifest) VALUES ($1) ON CONFLICT DO NOTHING;`
+		lock   = `SELECT fingerprint, cursor, findings FROM matcher_changelog_state WHERE manifest = $1 FOR UPDATE;`
+		insert = `INSERT INTO matcher_changelog (manifest, kind, reason, cursor, findings)
+VALUES ($1, $2, NULLIF($3, ''), $4, $5::jsonb)
+RETURNING id, recorded;`
+		update = `UPDATE matcher_changelog_state
+SET fingerprint = $2, cursor = $3, findings = $4::jsonb, updated = now()
+WHERE manifest = $1;`
+	)
+	defer queryMetrics.Observe("record", &err)()
+	var evs []Event
+	err = s.pool.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, ensure, m.String()); err != nil {
+			return err
+		}
+		var prev State
+		var fp *string
+		var cur *uuid.UUID
+		var fs []byte
+		if err := tx.QueryRow(ctx, lock, m.String()).Scan(&fp, &cur, &fs); err != nil {
+			return err
+		}
+		if fp != nil {
+			prev.Fingerprint = *fp
+		}
+		if cur != nil {
+			prev.Cursor = *cur
+		}
+		if err := json.Unmarshal(fs, &prev.Findings); err != nil {
+			return err
+		}
+		evs = Compare(&prev, next)
+		for i := range evs {
+			ev := &evs[i]
+			var b []byte
+			if ev.Findings != nil {
+				var err error
+				if b, err = json.Marshal(ev.Findings); err != nil {
+					return err
+				}
+			}
+			if err := tx.QueryRow(ctx, insert, m.String(), string(ev.Kind), string(ev.Reason), ev.Cursor, b).
+				Scan(&ev.ID, &ev.Recorded); err != nil {
+				return err
+			}
+		}
+		fs, err := json.Marshal(next.Findings)
+		if err != nil {
+			return err
+		}
+		if next.Findings == nil {
+			fs = []byte(`[]`)
+		}
+		_, err = tx.Exec(ctx, update, m.String(), next.Fingerprint, next.Cursor, fs)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("changelog: unable to record changes for %v: %w", m, err)
+	}
+	for _, ev := range evs {
+		eventCounter.WithLabelValues(string(ev.Kind)).Inc()
+	}
+	return evs, nil
+}
+
+// Changelog returns a page of the events recorded for the manifest "m",
+// oldest first, starting at the event with ID "page.Next" if it's set. The
+// returned Page describes the page after it.
+func (s *Store) Changelog(ctx context.Context, m claircore.Digest, page *Page) (_ []Event, _ Page, err error) {
+	const query = `SELECT id, kind, recorded, cursor, reason, findings
+FROM matcher_changelog
+WHERE manifest = $1
+	AND ($2::bigint IS NULL OR id >= $2)
+ORDER BY id
+LIMIT $3;`
+	defer queryMetrics.Observe("changelog", &err)()
+	out := Page{Size: page.Size}
+	if out.Size <= 0 {
+		out.Size = DefaultPageSize
+	}
+	// Ask for one more, to find out if there's another page.
+	rows, err := s.pool.Query(ctx, query, m.String(), page.Next, out.Size+1)
+	if err != nil {
+		return nil, out, fmt.Errorf("changelog: unable to query events: %w", err)
+	}
+	defer rows.Close()
+	evs := []Event{}
+	for rows.Next() {
+		var ev Event
+		var kind string
+		var cur *uuid.UUID
+		var reason *string
+		var fs []byte
+		if err = rows.Scan(&ev.ID, &kind, &ev.Recorded, &cur, &reason, &fs); err != nil {
+			return nil, out, fmt.Errorf("changelog: unable to read events: %w", err)
+		}
+		ev.Kind = Kind(kind)
+		if cur != nil {
+			ev.Cursor = *cur
+		}
+		if reason != nil {
+			ev.Reason = Reason(*reason)
+		}
+		if fs != nil {
+			if err = json.Unmarshal(fs, &ev.Findings); err != nil {
+				return nil, out, fmt.Errorf("changelog: unable to read events: %w", err)
+			}
+		}
+		evs = append(evs, ev)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, out, fmt.Errorf("changelog: unable to read events: %w", err)
+	}
+	if len(evs) > out.Size {
+		next := evs[out.Size].ID
+		out.Next = &next
+		evs = evs[:out.Size]
+	}
+	return evs, out, nil
+}
+
+// Prune removes events recorded before "before", and the state of manifests
+// whose reports haven't been built since, reporting how many events were
+// removed.
+//
+// A manifest whose state is removed is logged as indexed the next time its
+// report is built.
+func (s *Store) Prune(ctx context.Context, before time.Time) (_ int64, err error) {
+	const (
+		events = `DELETE FROM matcher_changelog WHERE recorded < $1;`
+		state  = `DELETE FROM matcher_changelog_state WHERE updated < $1;`
+	)
+	defer queryMetrics.Observe("prune", &err)()
+	tag, err := s.pool.Exec(ctx, events, before)
+	if err != nil {
+		return 0, fmt.Errorf("changelog: unable to prune events: %w", err)
+	}
+	if _, err = s.pool.Exec(ctx, state, before); err != nil {
+		return 0, fmt.Errorf("changelog: unable to prune state: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Run prunes events older than "retention" every "interval" until the
+// Context is canceled.
+func (s *Store) Run(ctx context.Context, interval, retention time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/changelog/Store.Run")
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		n, err := s.Prune(ctx, time.Now().Add(-retention))
+		switch {
+		case err != nil:
+			zlog.Warn(ctx).Err(err).Msg("unable to prune changelogs")
+		case n != 0:
+			zlog.Debug(ctx).Int64("count", n).Msg("pruned changelog events")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package changelog
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	return NewStore(pool)
+}
+
+type latest uuid.UUID
+
+func (l latest) LatestUpdateOperation(context.Context, driver.UpdateKind) (uuid.UUID, error) {
+	return uuid.UUID(l), nil
+}
+
+func TestStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := TestingStore(ctx, t)
+	m := claircore.MustParseDigest(`sha256:` + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	pkgs := map[string]string{"openssl": "1"}
+	first, second := uuid.New(), uuid.New()
+
+	for _, step := range []struct {
+		Cursor uuid.UUID
+		Report *claircore.VulnerabilityReport
+	}{
+		{first, report(pkgs, "openssl/CVE-1")},  // indexed, added
+		{first, report(pkgs, "openssl/CVE-1")},  // nothing
+		{second, report(pkgs, "openssl/CVE-2")}, // added, removed
+	} {
+		step.Report.Hash = m
+		if err := s.Observe(ctx, latest(step.Cursor), step.Report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	evs, page, err := s.Changelog(ctx, m, &Page{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 || page.Next == nil {
+		t.Fatalf("first page: got: %+v, %+v", evs, page)
+	}
+	if got, want := evs[0].Kind, KindIndexed; got != want {
+		t.Errorf("kind: got: %v, want: %v", got, want)
+	}
+	rest, page, err := s.Changelog(ctx, m, &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 2 || page.Next != nil {
+		t.Fatalf("second page: got: %+v, %+v", rest, page)
+	}
+	for _, ev := range rest {
+		if got, want := ev.Reason, ReasonVulnerabilityData; got != want {
+			t.Errorf("reason: got: %v, want: %v", got, want)
+		}
+		if got, want := ev.Cursor, second; got != want {
+			t.Errorf("cursor: got: %v, want: %v", got, want)
+		}
+	}
+
+	n, err := s.Prune(ctx, time.Now().Add(time.Hour))
+	if err != nil || n != 4 {
+		t.Errorf("prune: got: (%d, %v)", n, err)
+	}
+}
@@ -0,0 +1,238 @@
+// Package eol reports language runtimes that have reached the end of their
+// support, using schedules in the format published by endoflife.date.
+//
+// Every release cycle past its end-of-life date becomes a vulnerability of
+// the runtime package, as detected by the indexer's runtime scanner, so
+// images running unsupported interpreters show up in vulnerability reports
+// even before a CVE is published against them.
+package eol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/config"
+
+	"github.com/quay/clair/v4/indexer/runtimes"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+// Name is the name of the Updater and Matcher.
+const Name = "runtime-eol"
+
+// Products maps runtime package names to the products their schedules are
+// published as.
+var products = []struct {
+	runtime, product string
+}{
+	{runtimes.Python, "python"},
+	{runtimes.Node, "nodejs"},
+	{runtimes.Java, "eclipse-temurin"},
+	{runtimes.Ruby, "ruby"},
+}
+
+// Cycle is a release cycle in a schedule.
+type cycle struct {
+	Cycle  string `json:"cycle"`
+	EOL    date   `json:"eol"`
+	Latest string `json:"latest"`
+}
+
+// Date is an end-of-life date, which is published as either a date or a
+// boolean.
+type date struct {
+	// Reached reports whether the end of life has been reached, if there's
+	// no date.
+	Reached bool
+	Time    time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *date) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(b, &d.Reached); err == nil {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("eol: bad date %s", string(b))
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return fmt.Errorf("eol: bad date %q: %w", s, err)
+	}
+	d.Time = t
+	return nil
+}
+
+// Past reports whether the end of life is at or before "now".
+func (d *date) past(now time.Time) bool {
+	if d.Time.IsZero() {
+		return d.Reached
+	}
+	return !d.Time.After(now)
+}
+
+// New returns the Updater and Matcher for runtime end-of-life schedules. The
+// Updater fetches the schedules with the provided client.
+func New(cfg *config.UpdaterRuntimeEOL, c *http.Client) (*Updater, *Matcher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("eol: bad url: %w", err)
+	}
+	return &Updater{base: u, client: c, now: time.Now}, &Matcher{}, nil
+}
+
+// Updater fetches runtime end-of-life schedules.
+type Updater struct {
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+var _ driver.Updater = (*Updater)(nil)
+
+// Name implements driver.Updater.
+func (*Updater) Name() string { return Name }
+
+// Fetch implements driver.Updater.
+//
+// The fingerprint includes the current date, so that cycles reaching their
+// end of life are reported even if the schedules haven't changed.
+func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/eol/Updater.Fetch")
+	doc := make(map[string]json.RawMessage, len(products))
+	for _, p := range products {
+		b, err := u.fetch(ctx, p.product)
+		if err != nil {
+			return nil, hint, err
+		}
+		doc[p.runtime] = b
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, hint, err
+	}
+	sum := sha256.Sum256(b)
+	fp := driver.Fingerprint(hex.EncodeToString(sum[:]) + "@" + u.now().UTC().Format("2006-01-02"))
+	if fp == hint {
+		return nil, hint, driver.Unchanged
+	}
+	zlog.Debug(ctx).Int("size", len(b)).Msg("fetched schedules")
+	return io.NopCloser(bytes.NewReader(b)), fp, nil
+}
+
+func (u *Updater) fetch(ctx context.Context, product string) (json.RawMessage, error) {
+	ref := u.base.ResolveReference(&url.URL{Path: product + ".json"})
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, ref.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("eol: unable to fetch %q: %w", product, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("eol: unexpected response fetching %q: %s", product, res.Status)
+	}
+	var b json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&b); err != nil {
+		return nil, fmt.Errorf("eol: unable to read %q: %w", product, err)
+	}
+	return b, nil
+}
+
+// Parse implements driver.Updater.
+func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/eol/Updater.Parse")
+	defer r.Close()
+	var doc map[string][]cycle
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("eol: unable to parse schedules: %w", err)
+	}
+	now := u.now()
+	var out []*claircore.Vulnerability
+	for _, p := range products {
+		for _, c := range doc[p.runtime] {
+			if c.Cycle == "" || !c.EOL.past(now) {
+				continue
+			}
+			out = append(out, vulnerability(p.runtime, p.product, &c))
+		}
+	}
+	zlog.Debug(ctx).Int("count", len(out)).Msg("parsed schedules")
+	return out, nil
+}
+
+// Vulnerability returns the vulnerability reporting the end of life of the
+// cycle "c" of a runtime.
+func vulnerability(runtime, product string, c *cycle) *claircore.Vulnerability {
+	desc := fmt.Sprintf("%s %s has reached the end of its life", runtime, c.Cycle)
+	if !c.EOL.Time.IsZero() {
+		desc += " on " + c.EOL.Time.Format("2006-01-02")
+	}
+	desc += " and no longer receives security fixes. Upgrade to a supported release."
+	return &claircore.Vulnerability{
+		Updater:            Name,
+		Name:               fmt.Sprintf("EOL-%s-%s", runtime, c.Cycle),
+		Description:        desc,
+		Issued:             c.EOL.Time,
+		Links:              "https://endoflife.date/" + product,
+		Severity:           "End of Life",
+		NormalizedSeverity: claircore.Medium,
+		Package: &claircore.Package{
+			Name:    runtime,
+			Version: c.Cycle,
+			Kind:    claircore.BINARY,
+		},
+		Dist: &claircore.Distribution{},
+		Repo: &claircore.Repository{Name: runtimes.RepositoryName},
+	}
+}
+
+// Matcher matches runtime packages against the end-of-life vulnerabilities
+// recorded by the Updater.
+type Matcher struct{}
+
+var _ driver.Matcher = (*Matcher)(nil)
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return Name }
+
+// Filter implements driver.Matcher.
+func (*Matcher) Filter(r *claircore.IndexRecord) bool {
+	return r.Repository != nil && r.Repository.Name == runtimes.RepositoryName
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{driver.RepositoryName}
+}
+
+// Vulnerable implements driver.Matcher.
+//
+// A runtime is vulnerable if its version is in the cycle that reached its
+// end of life. Vulnerabilities from other updaters about the runtime
+// repository, such as configured advisory feeds, have their own matchers.
+func (*Matcher) Vulnerable(_ context.Context, r *claircore.IndexRecord, v *claircore.Vulnerability) (bool, error) {
+	if v.Updater != Name || v.Package == nil || v.Package.Name != r.Package.Name {
+		return false, nil
+	}
+	c := runtimes.Cycle(r.Package.Name, r.Package.Version)
+	return c != "" && c == v.Package.Version, nil
+}
@@ -0,0 +1,142 @@
+package eol
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/config"
+
+	"github.com/quay/clair/v4/indexer/runtimes"
+)
+
+var schedules = map[string]string{
+	"python": `[
+		{"cycle": "3.12", "eol": "2028-10-31", "latest": "3.12.1"},
+		{"cycle": "3.7", "eol": "2023-06-27", "latest": "3.7.17"}
+	]`,
+	"nodejs": `[
+		{"cycle": "20", "eol": "2026-04-30", "latest": "20.10.0"},
+		{"cycle": "16", "eol": "2023-09-11", "latest": "16.20.2"}
+	]`,
+	"eclipse-temurin": `[
+		{"cycle": "17", "eol": false, "latest": "17.0.9"},
+		{"cycle": "8", "eol": "2026-11-30", "latest": "8u392"},
+		{"cycle": "16", "eol": true, "latest": "16.0.2"}
+	]`,
+	"ruby": `[
+		{"cycle": "3.2", "eol": "2026-03-31", "latest": "3.2.2"}
+	]`,
+}
+
+func newUpdater(t *testing.T) (*Updater, *Matcher) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := schedules[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/"), ".json")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		w.Write([]byte(s))
+	}))
+	t.Cleanup(srv.Close)
+	u, m, err := New(&config.UpdaterRuntimeEOL{URL: srv.URL + "/api/"}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return u, m
+}
+
+func TestUpdater(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	u, _ := newUpdater(t)
+
+	rc, fp, err := u.Fetch(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(vs))
+	for i, v := range vs {
+		got[i] = v.Package.Name + " " + v.Package.Version
+	}
+	want := []string{"python 3.7", "node 16", "java 16"}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if _, _, err := u.Fetch(ctx, fp); !errors.Is(err, driver.Unchanged) {
+		t.Errorf("same day: got: %v, want: %v", err, driver.Unchanged)
+	}
+	// A new day may pass more end-of-life dates.
+	u.now = func() time.Time { return time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC) }
+	if _, next, err := u.Fetch(ctx, fp); err != nil || next == fp {
+		t.Errorf("next day: got: %q, %v", next, err)
+	}
+}
+
+func TestVulnerable(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	u, m := newUpdater(t)
+	rc, _, err := u.Fetch(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := runtimes.Repository
+	record := func(name, v string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{
+			Package:    &claircore.Package{Name: name, Version: v},
+			Repository: &repo,
+		}
+	}
+	tt := []struct {
+		Record *claircore.IndexRecord
+		Want   []string
+	}{
+		{record(runtimes.Python, "3.7.17"), []string{"EOL-python-3.7"}},
+		{record(runtimes.Python, "3.12.1"), nil},
+		{record(runtimes.Node, "16.20.2"), []string{"EOL-node-16"}},
+		{record(runtimes.Java, "16.0.2+7"), []string{"EOL-java-16"}},
+		{record(runtimes.Java, "1.8.0_392"), nil},
+	}
+	for _, tc := range tt {
+		if !m.Filter(tc.Record) {
+			t.Errorf("%s %s: filtered out", tc.Record.Package.Name, tc.Record.Package.Version)
+			continue
+		}
+		var got []string
+		for _, v := range vs {
+			ok, err := m.Vulnerable(ctx, tc.Record, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				got = append(got, v.Name)
+			}
+		}
+		if !cmp.Equal(got, tc.Want) {
+			t.Errorf("%s %s: %s", tc.Record.Package.Name, tc.Record.Package.Version, cmp.Diff(got, tc.Want))
+		}
+	}
+	if m.Filter(&claircore.IndexRecord{Package: &claircore.Package{Name: "python"}, Repository: &claircore.Repository{}}) {
+		t.Error("matched a record outside the runtime repository")
+	}
+}
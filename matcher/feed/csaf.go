@@ -0,0 +1,294 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+// CsafDocument is the subset of a CSAF 2.0 document needed to find the
+// products each vulnerability affects.
+type csafDocument struct {
+	Document struct {
+		Tracking struct {
+			ID                 string    `json:"id"`
+			InitialReleaseDate time.Time `json:"initial_release_date"`
+		} `json:"tracking"`
+		AggregateSeverity struct {
+			Text string `json:"text"`
+		} `json:"aggregate_severity"`
+		References []csafReference `json:"references"`
+	} `json:"document"`
+	ProductTree struct {
+		Branches         []csafBranch  `json:"branches"`
+		FullProductNames []csafProduct `json:"full_product_names"`
+	} `json:"product_tree"`
+	Vulnerabilities []struct {
+		CVE   string `json:"cve"`
+		Title string `json:"title"`
+		Notes []struct {
+			Category string `json:"category"`
+			Text     string `json:"text"`
+		} `json:"notes"`
+		ReleaseDate   time.Time `json:"release_date"`
+		ProductStatus struct {
+			Fixed         []string `json:"fixed"`
+			FirstFixed    []string `json:"first_fixed"`
+			KnownAffected []string `json:"known_affected"`
+		} `json:"product_status"`
+		Threats []struct {
+			Category   string   `json:"category"`
+			Details    string   `json:"details"`
+			ProductIDs []string `json:"product_ids"`
+		} `json:"threats"`
+		References []csafReference `json:"references"`
+	} `json:"vulnerabilities"`
+}
+
+type csafReference struct {
+	URL string `json:"url"`
+}
+
+type csafBranch struct {
+	Category string       `json:"category"`
+	Name     string       `json:"name"`
+	Product  *csafProduct `json:"product"`
+	Branches []csafBranch `json:"branches"`
+}
+
+type csafProduct struct {
+	ProductID string `json:"product_id"`
+	Helper    struct {
+		PURL string `json:"purl"`
+	} `json:"product_identification_helper"`
+}
+
+// CsafPackage is the package and version a product ID refers to.
+type csafPackage struct {
+	Name, Version string
+}
+
+// Products returns the package of every product in the document's product
+// tree, keyed by product ID.
+//
+// Products are identified by their package URL if they have one, and
+// otherwise by the "product_name" and "product_version" branches leading
+// to them.
+func (d *csafDocument) products() map[string]csafPackage {
+	out := make(map[string]csafPackage)
+	add := func(p *csafProduct, fallback csafPackage) {
+		if p == nil || p.ProductID == "" {
+			return
+		}
+		if pkg, ok := parsePURL(p.Helper.PURL); ok {
+			out[p.ProductID] = pkg
+			return
+		}
+		if fallback.Name != "" {
+			out[p.ProductID] = fallback
+		}
+	}
+	var walk func([]csafBranch, csafPackage)
+	walk = func(bs []csafBranch, cur csafPackage) {
+		for _, b := range bs {
+			next := cur
+			switch b.Category {
+			case "product_name":
+				next.Name = b.Name
+			case "product_version":
+				next.Version = b.Name
+			}
+			add(b.Product, next)
+			walk(b.Branches, next)
+		}
+	}
+	walk(d.ProductTree.Branches, csafPackage{})
+	for i := range d.ProductTree.FullProductNames {
+		add(&d.ProductTree.FullProductNames[i], csafPackage{})
+	}
+	return out
+}
+
+// ParsePURL returns the package a package URL refers to, named as claircore
+// names it: Maven packages are "group:artifact", and Go modules and scoped
+// npm packages keep their namespace.
+func parsePURL(s string) (csafPackage, bool) {
+	rest, ok := strings.CutPrefix(s, "pkg:")
+	if !ok {
+		return csafPackage{}, false
+	}
+	if i := strings.IndexAny(rest, "?#"); i != -1 {
+		rest = rest[:i]
+	}
+	typ, rest, ok := strings.Cut(rest, "/")
+	if !ok {
+		return csafPackage{}, false
+	}
+	rest, ver, _ := strings.Cut(rest, "@")
+	parts := strings.Split(rest, "/")
+	for i, p := range parts {
+		if u, err := url.PathUnescape(p); err == nil {
+			parts[i] = u
+		}
+	}
+	if v, err := url.PathUnescape(ver); err == nil {
+		ver = v
+	}
+	name := parts[len(parts)-1]
+	switch strings.ToLower(typ) {
+	case "maven":
+		name = strings.Join(parts, ":")
+	case "golang", "npm":
+		name = strings.Join(parts, "/")
+	}
+	if name == "" {
+		return csafPackage{}, false
+	}
+	return csafPackage{Name: name, Version: ver}, true
+}
+
+// ParseCSAF parses a CSAF 2.0 document, or a JSON array of them.
+//
+// Products listed as "fixed" or "first_fixed" record the version fixing the
+// vulnerability. Products listed as "known_affected" record the one version
+// affected, or every version if the product has none.
+func parseCSAF(ctx context.Context, u *Updater, r io.Reader) ([]*claircore.Vulnerability, error) {
+	br := bufio.NewReader(r)
+	var docs []csafDocument
+	dec := json.NewDecoder(br)
+	b, err := peekJSON(br)
+	if err != nil {
+		return nil, err
+	}
+	if b == '[' {
+		err = dec.Decode(&docs)
+	} else {
+		docs = make([]csafDocument, 1)
+		err = dec.Decode(&docs[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode CSAF document: %w", err)
+	}
+	zlog.Debug(ctx).Int("documents", len(docs)).Msg("json decoded")
+	var out []*claircore.Vulnerability
+	for i := range docs {
+		out = append(out, u.csafVulns(ctx, &docs[i])...)
+	}
+	return out, nil
+}
+
+// PeekJSON returns the first non-space byte in "r" without consuming it.
+func peekJSON(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, fmt.Errorf("unable to decode CSAF document: %w", err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.Discard(1)
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+func (u *Updater) csafVulns(ctx context.Context, d *csafDocument) []*claircore.Vulnerability {
+	products := d.products()
+	var out []*claircore.Vulnerability
+	for _, cv := range d.Vulnerabilities {
+		proto := u.ns.protovuln()
+		proto.Updater = u.name
+		proto.Name = cv.CVE
+		if proto.Name == "" {
+			proto.Name = d.Document.Tracking.ID
+		}
+		for _, n := range cv.Notes {
+			if n.Category == "description" || (n.Category == "summary" && proto.Description == "") {
+				proto.Description = n.Text
+			}
+		}
+		if proto.Description == "" {
+			proto.Description = cv.Title
+		}
+		proto.Issued = cv.ReleaseDate
+		if proto.Issued.IsZero() {
+			proto.Issued = d.Document.Tracking.InitialReleaseDate
+		}
+		var links []string
+		for _, rs := range [][]csafReference{cv.References, d.Document.References} {
+			for _, r := range rs {
+				if r.URL != "" {
+					links = append(links, r.URL)
+				}
+			}
+		}
+		proto.Links = strings.Join(links, " ")
+		sev := make(map[string]string)
+		for _, t := range cv.Threats {
+			if t.Category != "impact" {
+				continue
+			}
+			for _, id := range t.ProductIDs {
+				sev[id] = t.Details
+			}
+			if len(t.ProductIDs) == 0 {
+				proto.Severity = t.Details
+			}
+		}
+		if proto.Severity == "" {
+			proto.Severity = d.Document.AggregateSeverity.Text
+		}
+
+		add := func(id string, fixed bool) {
+			p, ok := products[id]
+			if !ok {
+				zlog.Debug(ctx).
+					Str("vulnerability", proto.Name).
+					Str("product", id).
+					Msg("unknown product")
+				return
+			}
+			v := proto
+			v.Package = &claircore.Package{
+				Name: p.Name,
+				Kind: claircore.BINARY,
+			}
+			if fixed {
+				if p.Version == "" {
+					return
+				}
+				v.FixedInVersion = p.Version
+			} else {
+				v.Package.Version = p.Version
+			}
+			if s, ok := sev[id]; ok {
+				v.Severity = s
+			}
+			v.NormalizedSeverity = normalizeSeverity(v.Severity)
+			out = append(out, &v)
+		}
+		st := cv.ProductStatus
+		fixed := append(append([]string(nil), st.Fixed...), st.FirstFixed...)
+		sort.Strings(fixed)
+		for i, id := range fixed {
+			if i != 0 && fixed[i-1] == id {
+				continue
+			}
+			add(id, true)
+		}
+		for _, id := range st.KnownAffected {
+			add(id, false)
+		}
+	}
+	return out
+}
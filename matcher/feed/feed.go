@@ -0,0 +1,195 @@
+// Package feed ingests OVAL and CSAF advisory feeds described in the
+// configuration, so advisories published outside of the distributions and
+// ecosystems claircore knows about can be matched without writing an
+// updater.
+//
+// Each configured feed becomes an Updater, fetching and parsing the feed,
+// and a Matcher, matching the packages in the feed's namespace against the
+// vulnerabilities only that Updater recorded.
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/ovalutil"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/config"
+)
+
+// Prefix is prepended to a feed's configured name to name its Updater and
+// Matcher.
+const prefix = "feed-"
+
+// Namespace is the set of packages advisories are about: those of a
+// distribution, or those from a language package repository.
+type Namespace struct {
+	// DID and VersionID identify a distribution, as in os-release(5). An
+	// empty VersionID matches every version.
+	DID, VersionID string
+	// Repo names a package repository, such as "pypi".
+	Repo string
+}
+
+// ParseNamespace parses a namespace as written in the configuration: a
+// distribution's "ID", optionally followed by a colon and its "VERSION_ID",
+// or "repo:" followed by a repository name.
+func ParseNamespace(s string) (Namespace, error) {
+	k, v, _ := strings.Cut(s, ":")
+	switch {
+	case k == "":
+		return Namespace{}, fmt.Errorf("feed: bad namespace %q", s)
+	case k == "repo" && v == "":
+		return Namespace{}, fmt.Errorf("feed: bad namespace %q", s)
+	case k == "repo":
+		return Namespace{Repo: v}, nil
+	}
+	return Namespace{DID: k, VersionID: v}, nil
+}
+
+// Contains reports whether the record's package is in the namespace.
+func (ns Namespace) Contains(r *claircore.IndexRecord) bool {
+	if ns.Repo != "" {
+		return r.Repository != nil && r.Repository.Name == ns.Repo
+	}
+	return r.Distribution != nil &&
+		r.Distribution.DID == ns.DID &&
+		(ns.VersionID == "" || r.Distribution.VersionID == ns.VersionID)
+}
+
+// Protovuln returns a Vulnerability with the namespace's distribution or
+// repository filled in.
+func (ns Namespace) protovuln() claircore.Vulnerability {
+	v := claircore.Vulnerability{
+		Dist: &claircore.Distribution{},
+		Repo: &claircore.Repository{},
+	}
+	if ns.Repo != "" {
+		v.Repo.Name = ns.Repo
+	} else {
+		v.Dist.DID = ns.DID
+		v.Dist.VersionID = ns.VersionID
+	}
+	return v
+}
+
+// New returns the Updater and Matcher for the configured feed. The Updater
+// fetches the feed with the provided client.
+func New(cfg *config.UpdaterFeed, c *http.Client) (*Updater, *Matcher, error) {
+	ns, err := ParseNamespace(cfg.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("feed %q: bad url: %w", cfg.Name, err)
+	}
+	comp, err := ovalutil.ParseCompressor(cfg.Compression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("feed %q: %w", cfg.Name, err)
+	}
+	scheme := strings.ToLower(cfg.VersionScheme)
+	cmp, ok := schemes[scheme]
+	if !ok {
+		return nil, nil, fmt.Errorf("feed %q: unknown version scheme %q", cfg.Name, cfg.VersionScheme)
+	}
+	var parse func(context.Context, *Updater, io.Reader) ([]*claircore.Vulnerability, error)
+	switch f := strings.ToLower(cfg.Format); {
+	case f == "oval" && scheme == "rpm":
+		parse = parseRPMOVAL
+	case f == "oval" && scheme == "dpkg":
+		parse = parseDpkgOVAL
+	case f == "csaf":
+		parse = parseCSAF
+	default:
+		return nil, nil, fmt.Errorf("feed %q: unsupported format %q", cfg.Name, cfg.Format)
+	}
+	name := prefix + cfg.Name
+	up := &Updater{
+		name:  name,
+		ns:    ns,
+		parse: parse,
+		fetcher: ovalutil.Fetcher{
+			URL:         u,
+			Client:      c,
+			Compression: comp,
+		},
+	}
+	m := &Matcher{
+		name: name,
+		ns:   ns,
+		cmp:  cmp,
+	}
+	return up, m, nil
+}
+
+// Drivers returns the Updaters and Matchers for all the configured feeds.
+func Drivers(cfgs []config.UpdaterFeed, c *http.Client) ([]driver.Updater, []driver.Matcher, error) {
+	us := make([]driver.Updater, 0, len(cfgs))
+	ms := make([]driver.Matcher, 0, len(cfgs))
+	for i := range cfgs {
+		u, m, err := New(&cfgs[i], c)
+		if err != nil {
+			return nil, nil, err
+		}
+		us = append(us, u)
+		ms = append(ms, m)
+	}
+	return us, ms, nil
+}
+
+// Updater fetches and parses a feed.
+type Updater struct {
+	fetcher ovalutil.Fetcher
+	parse   func(context.Context, *Updater, io.Reader) ([]*claircore.Vulnerability, error)
+	name    string
+	ns      Namespace
+}
+
+var _ driver.Updater = (*Updater)(nil)
+
+// Name implements driver.Updater.
+func (u *Updater) Name() string { return u.name }
+
+// Fetch implements driver.Updater.
+func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/feed/Updater.Fetch", "updater", u.name)
+	return u.fetcher.Fetch(ctx, hint)
+}
+
+// Parse implements driver.Updater.
+func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/feed/Updater.Parse", "updater", u.name)
+	defer r.Close()
+	vs, err := u.parse(ctx, u, r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", u.name, err)
+	}
+	zlog.Debug(ctx).Int("count", len(vs)).Msg("parsed feed")
+	return vs, nil
+}
+
+// NormalizeSeverity maps the severities used by common advisory sources to
+// claircore's.
+func normalizeSeverity(s string) claircore.Severity {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "critical":
+		return claircore.Critical
+	case "high", "important":
+		return claircore.High
+	case "medium", "moderate":
+		return claircore.Medium
+	case "low":
+		return claircore.Low
+	case "negligible", "none", "informational":
+		return claircore.Negligible
+	}
+	return claircore.Unknown
+}
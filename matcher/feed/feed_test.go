@@ -0,0 +1,190 @@
+package feed
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/config"
+)
+
+func newFeed(t *testing.T, cfg config.UpdaterFeed) (*Updater, *Matcher) {
+	t.Helper()
+	cfg.Name = "test"
+	cfg.URL = "https://advisories.example.com/feed"
+	u, m, err := New(&cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, m
+}
+
+func parse(t *testing.T, u *Updater, name string) []*claircore.Vulnerability {
+	t.Helper()
+	ctx := zlog.Test(context.Background(), t)
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs
+}
+
+// Summary is the part of a parsed vulnerability checked by the tests.
+type summary struct {
+	Name, Package, Version, Fixed, Severity string
+	Normalized                              claircore.Severity
+}
+
+func summarize(vs []*claircore.Vulnerability) []summary {
+	out := make([]summary, len(vs))
+	for i, v := range vs {
+		out[i] = summary{
+			Name:       v.Name,
+			Package:    v.Package.Name,
+			Version:    v.Package.Version,
+			Fixed:      v.FixedInVersion,
+			Severity:   v.Severity,
+			Normalized: v.NormalizedSeverity,
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Package+out[i].Version < out[j].Package+out[j].Version })
+	return out
+}
+
+func TestOVAL(t *testing.T) {
+	u, m := newFeed(t, config.UpdaterFeed{Format: "oval", Namespace: "rhel:9", VersionScheme: "rpm"})
+	vs := parse(t, u, "testdata/oval.xml")
+	got := summarize(vs)
+	want := []summary{
+		{Name: "EXA-2023:0001", Package: "internal-agent", Fixed: "0:1.2.3-4.el9", Severity: "Important", Normalized: claircore.High},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := vs[0].Updater, "feed-test"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := vs[0].Dist.DID, "rhel"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	ctx := zlog.Test(context.Background(), t)
+	for _, tc := range []struct {
+		Version string
+		Want    bool
+	}{
+		{Version: "1.2.3-3.el9", Want: true},
+		{Version: "1.2.3-4.el9", Want: false},
+		{Version: "1.3.0-1.el9", Want: false},
+	} {
+		r := &claircore.IndexRecord{
+			Package:      &claircore.Package{Name: "internal-agent", Version: tc.Version, Arch: "x86_64"},
+			Distribution: &claircore.Distribution{DID: "rhel", VersionID: "9"},
+		}
+		if !m.Filter(r) {
+			t.Fatal("record unexpectedly filtered")
+		}
+		ok, err := m.Vulnerable(ctx, r, vs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ok, tc.Want; got != want {
+			t.Errorf("%s: got: %v, want: %v", tc.Version, got, want)
+		}
+	}
+}
+
+func TestCSAF(t *testing.T) {
+	u, m := newFeed(t, config.UpdaterFeed{Format: "csaf", Namespace: "repo:pypi", VersionScheme: "pep440"})
+	vs := parse(t, u, "testdata/csaf.json")
+	got := summarize(vs)
+	want := []summary{
+		{Name: "CVE-2023-0002", Package: "example-lib", Fixed: "1.4.0", Severity: "Moderate", Normalized: claircore.Medium},
+		{Name: "CVE-2023-0002", Package: "example-lib", Version: "1.3.2", Severity: "Moderate", Normalized: claircore.Medium},
+		{Name: "CVE-2023-0002", Package: "example-tools", Version: "2.0.0", Severity: "Critical", Normalized: claircore.Critical},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	ctx := zlog.Test(context.Background(), t)
+	for _, tc := range []struct {
+		Name, Version string
+		Want          bool
+	}{
+		{Name: "example-lib", Version: "1.3.2", Want: true},
+		{Name: "example-lib", Version: "1.3.9", Want: true},
+		{Name: "example-lib", Version: "1.4.0", Want: false},
+		{Name: "example-tools", Version: "2.0.0", Want: true},
+		{Name: "example-tools", Version: "2.0.1", Want: false},
+	} {
+		r := &claircore.IndexRecord{
+			Package:    &claircore.Package{Name: tc.Name, Version: tc.Version},
+			Repository: &claircore.Repository{Name: "pypi"},
+		}
+		if !m.Filter(r) {
+			t.Fatal("record unexpectedly filtered")
+		}
+		var found bool
+		for _, v := range vs {
+			if v.Package.Name != tc.Name {
+				continue
+			}
+			ok, err := m.Vulnerable(ctx, r, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found = found || ok
+		}
+		if got, want := found, tc.Want; got != want {
+			t.Errorf("%s %s: got: %v, want: %v", tc.Name, tc.Version, got, want)
+		}
+	}
+}
+
+func TestMatcherOtherUpdater(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	_, m := newFeed(t, config.UpdaterFeed{Format: "csaf", Namespace: "alpine", VersionScheme: "apk"})
+	r := &claircore.IndexRecord{
+		Package:      &claircore.Package{Name: "musl", Version: "1.2.3-r0"},
+		Distribution: &claircore.Distribution{DID: "alpine", VersionID: "3.18.0"},
+	}
+	if !m.Filter(r) {
+		t.Fatal("record unexpectedly filtered")
+	}
+	v := &claircore.Vulnerability{Updater: "alpine-main-v3.18-updater", Package: &claircore.Package{Name: "musl"}}
+	ok, err := m.Vulnerable(ctx, r, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("matched a vulnerability from another updater")
+	}
+}
+
+func TestParsePURL(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Want csafPackage
+		OK   bool
+	}{
+		{In: "pkg:pypi/requests@2.31.0", Want: csafPackage{Name: "requests", Version: "2.31.0"}, OK: true},
+		{In: "pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1?type=jar", Want: csafPackage{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"}, OK: true},
+		{In: "pkg:golang/github.com/example/mod@v1.0.0", Want: csafPackage{Name: "github.com/example/mod", Version: "v1.0.0"}, OK: true},
+		{In: "cpe:/a:example:lib:1.0"},
+	} {
+		got, ok := parsePURL(tc.In)
+		if ok != tc.OK || !cmp.Equal(got, tc.Want) {
+			t.Errorf("%s: got: %v, %v, want: %v, %v", tc.In, got, ok, tc.Want, tc.OK)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+)
+
+// Matcher matches packages against the vulnerabilities recorded by a feed's
+// Updater.
+type Matcher struct {
+	cmp  compareFunc
+	name string
+	ns   Namespace
+}
+
+var _ driver.Matcher = (*Matcher)(nil)
+
+// Name implements driver.Matcher.
+func (m *Matcher) Name() string { return m.name }
+
+// Filter implements driver.Matcher.
+func (m *Matcher) Filter(r *claircore.IndexRecord) bool {
+	return m.ns.Contains(r)
+}
+
+// Query implements driver.Matcher.
+func (m *Matcher) Query() []driver.MatchConstraint {
+	switch {
+	case m.ns.Repo != "":
+		return []driver.MatchConstraint{driver.RepositoryName}
+	case m.ns.VersionID != "":
+		return []driver.MatchConstraint{driver.DistributionDID, driver.DistributionVersionID}
+	}
+	return []driver.MatchConstraint{driver.DistributionDID}
+}
+
+// Vulnerable implements driver.Matcher.
+//
+// Only vulnerabilities recorded by the feed's Updater are considered; other
+// updaters covering the same namespace have their own matchers.
+func (m *Matcher) Vulnerable(ctx context.Context, r *claircore.IndexRecord, v *claircore.Vulnerability) (bool, error) {
+	if v.Updater != m.name {
+		return false, nil
+	}
+	if v.Package != nil && v.Package.Arch != "" && !v.ArchOperation.Cmp(r.Package.Arch, v.Package.Arch) {
+		return false, nil
+	}
+	var want func(int) bool
+	var against string
+	switch {
+	case v.FixedInVersion != "":
+		want = func(c int) bool { return c < 0 }
+		against = v.FixedInVersion
+	case v.Package != nil && v.Package.Version != "":
+		want = func(c int) bool { return c == 0 }
+		against = v.Package.Version
+	default:
+		// Unfixed, in every version.
+		return true, nil
+	}
+	c, err := m.cmp(r.Package.Version, against)
+	if err != nil {
+		// A version in the wrong scheme is most likely a misconfigured feed
+		// or a package the feed isn't about; don't fail the whole report.
+		zlog.Debug(ctx).
+			Err(err).
+			Str("matcher", m.name).
+			Str("package", r.Package.Name).
+			Str("version", r.Package.Version).
+			Msg("unable to compare versions")
+		return false, nil
+	}
+	return want(c), nil
+}
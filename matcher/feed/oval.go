@@ -0,0 +1,76 @@
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/ovalutil"
+	"github.com/quay/goval-parser/oval"
+	"github.com/quay/zlog"
+)
+
+func decodeOVAL(r io.Reader) (*oval.Root, error) {
+	var root oval.Root
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("unable to decode OVAL document: %w", err)
+	}
+	return &root, nil
+}
+
+// OvalProtovulns creates the vulnerability an OVAL definition describes,
+// for ovalutil to fill in the affected packages.
+func (u *Updater) ovalProtovulns(def oval.Definition) ([]*claircore.Vulnerability, error) {
+	v := u.ns.protovuln()
+	v.Updater = u.name
+	v.Name = def.Title
+	// Prefer the advisory or CVE identifier, if the definition references
+	// one; titles tend to be sentences.
+	if len(def.References) != 0 && def.References[0].RefID != "" {
+		v.Name = def.References[0].RefID
+	}
+	v.Description = def.Description
+	v.Issued = def.Advisory.Issued.Date
+	v.Links = ovalutil.Links(def)
+	v.Severity = def.Advisory.Severity
+	v.NormalizedSeverity = normalizeSeverity(def.Advisory.Severity)
+	// The dpkg conversion sets the architecture on the prototype's package.
+	v.Package = &claircore.Package{}
+	return []*claircore.Vulnerability{&v}, nil
+}
+
+func parseRPMOVAL(ctx context.Context, u *Updater, r io.Reader) ([]*claircore.Vulnerability, error) {
+	root, err := decodeOVAL(r)
+	if err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).Int("definitions", len(root.Definitions.Definitions)).Msg("xml decoded")
+	return ovalutil.RPMDefsToVulns(ctx, root, u.ovalProtovulns)
+}
+
+func parseDpkgOVAL(ctx context.Context, u *Updater, r io.Reader) ([]*claircore.Vulnerability, error) {
+	root, err := decodeOVAL(r)
+	if err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).Int("definitions", len(root.Definitions.Definitions)).Msg("xml decoded")
+	// A package name may refer to a variable listing several packages.
+	names := func(_ oval.Definition, name *oval.DpkgName) []string {
+		if name.Ref == "" {
+			return []string{name.Body}
+		}
+		_, i, err := root.Variables.Lookup(name.Ref)
+		if err != nil {
+			zlog.Warn(ctx).Err(err).Str("ref", name.Ref).Msg("unable to look up variable")
+			return nil
+		}
+		var ns []string
+		for _, v := range root.Variables.ConstantVariables[i].Values {
+			ns = append(ns, v.Body)
+		}
+		return ns
+	}
+	return ovalutil.DpkgDefsToVulns(ctx, root, u.ovalProtovulns, names)
+}
@@ -0,0 +1,78 @@
+package feed
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+	apkversion "github.com/knqyf263/go-apk-version"
+	debversion "github.com/knqyf263/go-deb-version"
+	rpmversion "github.com/knqyf263/go-rpm-version"
+	"github.com/quay/claircore/pkg/pep440"
+)
+
+// CompareFunc compares two versions, returning a negative number if "a" is
+// less than "b", zero if they're equal, and a positive number otherwise.
+type compareFunc func(a, b string) (int, error)
+
+// Schemes is the version schemes a feed can use, by their configured name.
+var schemes = map[string]compareFunc{
+	"rpm": func(a, b string) (int, error) {
+		// RPM versions always parse.
+		return rpmversion.NewVersion(a).Compare(rpmversion.NewVersion(b)), nil
+	},
+	"dpkg": func(a, b string) (int, error) {
+		va, err := debversion.NewVersion(a)
+		if err != nil {
+			return 0, err
+		}
+		vb, err := debversion.NewVersion(b)
+		if err != nil {
+			return 0, err
+		}
+		return va.Compare(vb), nil
+	},
+	"apk": func(a, b string) (int, error) {
+		va, err := apkversion.NewVersion(a)
+		if err != nil {
+			return 0, err
+		}
+		vb, err := apkversion.NewVersion(b)
+		if err != nil {
+			return 0, err
+		}
+		return va.Compare(vb), nil
+	},
+	"semver": func(a, b string) (int, error) {
+		va, err := semver.NewVersion(a)
+		if err != nil {
+			return 0, err
+		}
+		vb, err := semver.NewVersion(b)
+		if err != nil {
+			return 0, err
+		}
+		return va.Compare(vb), nil
+	},
+	"pep440": func(a, b string) (int, error) {
+		va, err := pep440.Parse(a)
+		if err != nil {
+			return 0, err
+		}
+		vb, err := pep440.Parse(b)
+		if err != nil {
+			return 0, err
+		}
+		return va.Compare(&vb), nil
+	},
+}
+
+// Compare compares the versions "a" and "b" using the named version scheme,
+// returning a negative number if "a" is less than "b", zero if they're
+// equal, and a positive number otherwise.
+func Compare(scheme, a, b string) (int, error) {
+	cmp, ok := schemes[scheme]
+	if !ok {
+		return 0, fmt.Errorf("feed: unknown version scheme %q", scheme)
+	}
+	return cmp(a, b)
+}
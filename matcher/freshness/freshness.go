@@ -0,0 +1,178 @@
+// Package freshness reports when updaters last ran successfully, and whether
+// the vulnerability data they provide should be considered stale.
+package freshness
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/health"
+)
+
+var (
+	lastSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "matcher",
+			Name:      "updater_last_success_timestamp_seconds",
+			Help:      "Unix time of each updater's last successful run.",
+		},
+		[]string{"updater"},
+	)
+	staleGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "matcher",
+			Name:      "updater_stale",
+			Help:      "Whether each updater's data is stale (1) or not (0).",
+		},
+		[]string{"updater"},
+	)
+)
+
+// Status is the update status of a single updater.
+type Status struct {
+	Updater     string     `json:"updater"`
+	LastAttempt time.Time  `json:"last_attempt"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	// LastRunSucceeded reports whether the most recent attempt succeeded.
+	LastRunSucceeded bool   `json:"last_run_succeeded"`
+	LastError        string `json:"last_error,omitempty"`
+	// Stale is only set if a staleness threshold is configured.
+	Stale bool `json:"stale"`
+}
+
+// Options configures a Tracker.
+type Options struct {
+	// StaleAfter is how long after an updater's last success its data is
+	// stale. If zero, data is never considered stale.
+	StaleAfter time.Duration
+	// Readiness blocks the process's readiness while any updater is stale.
+	Readiness bool
+}
+
+// Tracker reads updater status from the matcher's database.
+type Tracker struct {
+	pool *pgxpool.Pool
+	opts Options
+
+	mu    sync.RWMutex
+	stale []string
+}
+
+// NewTracker returns a Tracker using the provided pool, which must be
+// connected to the matcher's database.
+func NewTracker(pool *pgxpool.Pool, opts *Options) *Tracker {
+	return &Tracker{pool: pool, opts: *opts}
+}
+
+// UpdaterStatus returns the status of every updater that has run, ordered by
+// name.
+func (t *Tracker) UpdaterStatus(ctx context.Context) ([]Status, error) {
+	const query = `SELECT updater_name, last_attempt, last_success, coalesce(last_run_succeeded, false), coalesce(last_error, '')
+FROM updater_status
+ORDER BY updater_name;`
+	rows, err := t.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Status{}
+	for rows.Next() {
+		var s Status
+		if err := rows.Scan(&s.Updater, &s.LastAttempt, &s.LastSuccess, &s.LastRunSucceeded, &s.LastError); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	mark(out, time.Now(), t.opts.StaleAfter)
+	return out, nil
+}
+
+// StaleUpdaters returns the names of the updaters that were stale as of the
+// most recent Check.
+func (t *Tracker) StaleUpdaters() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.stale
+}
+
+// Check refreshes the metrics and the list of stale updaters, and updates the
+// readiness block if configured.
+func (t *Tracker) Check(ctx context.Context) error {
+	const readiness = "matcher/freshness"
+	ss, err := t.UpdaterStatus(ctx)
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for _, s := range ss {
+		if s.LastSuccess != nil {
+			lastSuccess.WithLabelValues(s.Updater).Set(float64(s.LastSuccess.Unix()))
+		}
+		v := 0.0
+		if s.Stale {
+			v = 1
+			stale = append(stale, s.Updater)
+		}
+		staleGauge.WithLabelValues(s.Updater).Set(v)
+	}
+	sort.Strings(stale)
+
+	t.mu.Lock()
+	prev := len(t.stale)
+	t.stale = stale
+	t.mu.Unlock()
+	if len(stale) != 0 && prev == 0 {
+		zlog.Warn(ctx).
+			Strs("updaters", stale).
+			Stringer("stale_after", t.opts.StaleAfter).
+			Msg("vulnerability data is stale")
+	}
+	if t.opts.Readiness {
+		if len(stale) != 0 {
+			health.Block(readiness)
+		} else {
+			health.Unblock(readiness)
+		}
+	}
+	return nil
+}
+
+// Run calls Check every "interval" until the Context is canceled.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/freshness/Tracker.Run")
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		if err := t.Check(ctx); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to check updater status")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// Mark sets the Stale member of every Status in "ss".
+func mark(ss []Status, now time.Time, after time.Duration) {
+	if after <= 0 {
+		return
+	}
+	for i := range ss {
+		s := &ss[i]
+		s.Stale = s.LastSuccess == nil || now.Sub(*s.LastSuccess) > after
+	}
+}
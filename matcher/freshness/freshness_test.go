@@ -0,0 +1,32 @@
+package freshness
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMark(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-72 * time.Hour)
+	ss := []Status{
+		{Updater: "recent", LastSuccess: &recent},
+		{Updater: "old", LastSuccess: &old},
+		{Updater: "never"},
+	}
+
+	mark(ss, now, 0)
+	for _, s := range ss {
+		if s.Stale {
+			t.Errorf("%s: stale without a threshold", s.Updater)
+		}
+	}
+
+	mark(ss, now, 24*time.Hour)
+	want := map[string]bool{"recent": false, "old": true, "never": true}
+	for _, s := range ss {
+		if got := s.Stale; got != want[s.Updater] {
+			t.Errorf("%s: got: %v, want: %v", s.Updater, got, want[s.Updater])
+		}
+	}
+}
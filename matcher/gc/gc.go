@@ -0,0 +1,270 @@
+// Package gc implements policy-based garbage collection of the matcher's
+// update operations.
+//
+// Policies select updaters by name and bound how many update operations are
+// kept and for how long. The engine only removes update operations;
+// vulnerabilities that are no longer referenced are removed by the matcher's
+// own garbage collection.
+package gc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+)
+
+// MinKeep is the number of update operations always kept for every updater,
+// so that the notifier can compute differences.
+const MinKeep = 2
+
+var (
+	deletedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "matcher_gc",
+			Name:      "deleted_total",
+			Help:      "Total number of update operations deleted by GC policies.",
+		},
+		[]string{"reason"},
+	)
+	vacuumTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "matcher_gc",
+			Name:      "vacuum_last_success_timestamp_seconds",
+			Help:      "The time of the last successful vacuum of the matcher's tables.",
+		},
+	)
+)
+
+// Store is the subset of the matcher API used by the Engine.
+type Store interface {
+	UpdateOperations(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error)
+	DeleteUpdateOperations(context.Context, ...uuid.UUID) (int64, error)
+}
+
+// Execer runs SQL statements. It's satisfied by *pgxpool.Pool.
+type Execer interface {
+	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
+}
+
+// Policy bounds the update operations kept for the updaters whose names match
+// the Updaters pattern.
+type Policy struct {
+	// Updaters is a pattern in the syntax of path.Match.
+	Updaters string
+	// Keep is the maximum number of update operations kept, if positive.
+	Keep int
+	// MaxAge is the maximum age of kept update operations, if positive.
+	MaxAge time.Duration
+}
+
+// Window is a daily period, relative to midnight UTC.
+type Window struct {
+	Start  time.Duration
+	Length time.Duration
+}
+
+// Began reports the start of the occurrence of the window containing "t", if
+// any.
+func (w *Window) Began(t time.Time) (time.Time, bool) {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	// Check the previous day's window, which may run past midnight.
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		start := day.Add(w.Start)
+		if !t.Before(start) && t.Before(start.Add(w.Length)) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Options configures an Engine.
+type Options struct {
+	Policies []Policy
+	// Vacuum, if not nil, is when the matcher's tables are vacuumed using DB.
+	Vacuum *Window
+	DB     Execer
+}
+
+// Engine evaluates GC policies against a Store.
+type Engine struct {
+	store    Store
+	policies []Policy
+	window   *Window
+	db       Execer
+	now      func() time.Time
+
+	// Mu serializes collections and protects vacuumed.
+	mu       sync.Mutex
+	vacuumed time.Time
+}
+
+// New returns an Engine for the provided Store.
+func New(s Store, opts *Options) *Engine {
+	e := Engine{
+		store:    s,
+		policies: opts.Policies,
+		now:      time.Now,
+	}
+	if opts.Vacuum != nil && opts.DB != nil {
+		e.window = opts.Vacuum
+		e.db = opts.DB
+	}
+	return &e
+}
+
+// Deletion is an update operation selected for deletion.
+type Deletion struct {
+	Ref     uuid.UUID         `json:"ref"`
+	Updater string            `json:"updater"`
+	Kind    driver.UpdateKind `json:"kind"`
+	Date    time.Time         `json:"date"`
+	// Reason is "count" or "age", depending on the limit exceeded.
+	Reason string `json:"reason"`
+}
+
+// Report describes the result of a collection.
+type Report struct {
+	DryRun    bool       `json:"dry_run"`
+	Deletions []Deletion `json:"deletions"`
+	// Deleted is the number of update operations actually deleted.
+	Deleted int64 `json:"deleted"`
+	// Vacuum reports whether the tables were, or in a dry run would have
+	// been, vacuumed.
+	Vacuum bool `json:"vacuum"`
+}
+
+// Collect evaluates the policies and deletes the selected update operations,
+// then vacuums if inside the vacuum window and it hasn't happened yet in this
+// occurrence of the window. If "dryRun" is set, the Report is computed but
+// nothing is changed.
+func (e *Engine) Collect(ctx context.Context, dryRun bool) (*Report, error) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/gc/Engine.Collect")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+
+	r := Report{
+		DryRun:    dryRun,
+		Deletions: []Deletion{},
+	}
+	for _, k := range []driver.UpdateKind{driver.VulnerabilityKind, driver.EnrichmentKind} {
+		uos, err := e.store.UpdateOperations(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("gc: unable to list update operations: %w", err)
+		}
+		r.Deletions = append(r.Deletions, e.plan(now, uos)...)
+	}
+
+	var start time.Time
+	if e.window != nil {
+		var ok bool
+		start, ok = e.window.Began(now)
+		r.Vacuum = ok && e.vacuumed.Before(start)
+	}
+	if dryRun {
+		return &r, nil
+	}
+
+	if len(r.Deletions) != 0 {
+		refs := make([]uuid.UUID, len(r.Deletions))
+		for i, d := range r.Deletions {
+			refs[i] = d.Ref
+		}
+		n, err := e.store.DeleteUpdateOperations(ctx, refs...)
+		r.Deleted = n
+		if err != nil {
+			return &r, fmt.Errorf("gc: unable to delete update operations: %w", err)
+		}
+		for _, d := range r.Deletions {
+			deletedCounter.WithLabelValues(d.Reason).Inc()
+		}
+		zlog.Info(ctx).
+			Int64("deleted", n).
+			Msg("deleted update operations")
+	}
+	if r.Vacuum {
+		if err := e.vacuum(ctx); err != nil {
+			r.Vacuum = false
+			return &r, err
+		}
+		e.vacuumed = now
+	}
+	return &r, nil
+}
+
+// Plan returns the deletions selected by the policies. "Uos" is keyed by
+// updater name.
+func (e *Engine) plan(now time.Time, uos map[string][]driver.UpdateOperation) []Deletion {
+	var ds []Deletion
+	names := make([]string, 0, len(uos))
+	for n := range uos {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		p := e.policy(n)
+		if p == nil {
+			continue
+		}
+		ops := uos[n]
+		sort.SliceStable(ops, func(i, j int) bool { return ops[i].Date.After(ops[j].Date) })
+		for i := MinKeep; i < len(ops); i++ {
+			var reason string
+			switch op := &ops[i]; {
+			case p.Keep > 0 && i >= p.Keep:
+				reason = "count"
+			case p.MaxAge > 0 && now.Sub(op.Date) > p.MaxAge:
+				reason = "age"
+			default:
+				continue
+			}
+			ds = append(ds, Deletion{
+				Ref:     ops[i].Ref,
+				Updater: n,
+				Kind:    ops[i].Kind,
+				Date:    ops[i].Date,
+				Reason:  reason,
+			})
+		}
+	}
+	return ds
+}
+
+// Policy returns the first policy matching the updater "name", or nil.
+func (e *Engine) policy(name string) *Policy {
+	for i := range e.policies {
+		p := &e.policies[i]
+		if ok, _ := path.Match(p.Updaters, name); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// Vacuum vacuums and analyzes the tables holding update operations and their
+// contents.
+func (e *Engine) vacuum(ctx context.Context) error {
+	const query = `VACUUM (ANALYZE) update_operation, uo_vuln, vuln, uo_enrich, enrichment;`
+	start := time.Now()
+	if _, err := e.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("gc: unable to vacuum: %w", err)
+	}
+	vacuumTimestamp.SetToCurrentTime()
+	zlog.Info(ctx).
+		Dur("duration", time.Since(start)).
+		Msg("vacuumed matcher tables")
+	return nil
+}
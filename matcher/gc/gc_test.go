@@ -0,0 +1,148 @@
+package gc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+)
+
+type fakeStore struct {
+	uos     map[string][]driver.UpdateOperation
+	deleted []uuid.UUID
+}
+
+func (s *fakeStore) UpdateOperations(_ context.Context, k driver.UpdateKind, _ ...string) (map[string][]driver.UpdateOperation, error) {
+	out := make(map[string][]driver.UpdateOperation)
+	for n, ops := range s.uos {
+		for _, op := range ops {
+			if op.Kind == k {
+				out[n] = append(out[n], op)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) DeleteUpdateOperations(_ context.Context, refs ...uuid.UUID) (int64, error) {
+	s.deleted = append(s.deleted, refs...)
+	return int64(len(refs)), nil
+}
+
+type fakeDB struct {
+	n int
+}
+
+func (d *fakeDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	d.n++
+	return nil, nil
+}
+
+// Ops returns "n" daily update operations for "updater", newest first, with
+// the newest at "now".
+func ops(now time.Time, updater string, n int) []driver.UpdateOperation {
+	out := make([]driver.UpdateOperation, n)
+	for i := range out {
+		out[i] = driver.UpdateOperation{
+			Ref:     uuid.New(),
+			Updater: updater,
+			Date:    now.Add(-24 * time.Hour * time.Duration(i)),
+			Kind:    driver.VulnerabilityKind,
+		}
+	}
+	return out
+}
+
+func TestCollect(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	now := time.Date(2023, 6, 1, 3, 30, 0, 0, time.UTC)
+	s := &fakeStore{uos: map[string][]driver.UpdateOperation{
+		"rhel-vex":  ops(now, "rhel-vex", 6),
+		"ubuntu/22": ops(now, "ubuntu/22", 6),
+		"osv/go":    ops(now, "osv/go", 6),
+	}}
+	db := &fakeDB{}
+	e := New(s, &Options{
+		Policies: []Policy{
+			{Updaters: "rhel-*", Keep: 3},
+			{Updaters: "ubuntu/*", MaxAge: 36 * time.Hour},
+			// Would delete everything, but the newest are always kept.
+			{Updaters: "ubuntu/*", Keep: 1},
+		},
+		Vacuum: &Window{Start: 3 * time.Hour, Length: time.Hour},
+		DB:     db,
+	})
+	e.now = func() time.Time { return now }
+
+	r, err := e.Collect(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count, age int
+	for _, d := range r.Deletions {
+		switch {
+		case d.Updater == "rhel-vex" && d.Reason == "count":
+			count++
+		case d.Updater == "ubuntu/22" && d.Reason == "age":
+			age++
+		default:
+			t.Errorf("unexpected deletion: %+v", d)
+		}
+	}
+	if got, want := count, 3; got != want {
+		t.Errorf("count deletions: got: %d, want: %d", got, want)
+	}
+	if got, want := age, 4; got != want {
+		t.Errorf("age deletions: got: %d, want: %d", got, want)
+	}
+	if !r.Vacuum {
+		t.Error("dry run: expected vacuum to be reported")
+	}
+	if len(s.deleted) != 0 || db.n != 0 {
+		t.Fatalf("dry run made changes: %d deletions, %d vacuums", len(s.deleted), db.n)
+	}
+
+	r, err = e.Collect(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Deleted, int64(len(r.Deletions)); got != want {
+		t.Errorf("deleted: got: %d, want: %d", got, want)
+	}
+	if got, want := db.n, 1; got != want {
+		t.Errorf("vacuums: got: %d, want: %d", got, want)
+	}
+
+	// Only once per window.
+	if r, err = e.Collect(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if r.Vacuum || db.n != 1 {
+		t.Errorf("vacuumed twice in one window")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w := Window{Start: 23 * time.Hour, Length: 2 * time.Hour}
+	day := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		At    time.Duration
+		In    bool
+		Start time.Time
+	}{
+		{At: 22 * time.Hour, In: false},
+		{At: 23*time.Hour + time.Minute, In: true, Start: day.Add(23 * time.Hour)},
+		{At: 30 * time.Minute, In: true, Start: day.Add(-time.Hour)},
+		{At: 1 * time.Hour, In: false},
+	}
+	for _, tc := range tt {
+		start, ok := w.Began(day.Add(tc.At))
+		if ok != tc.In || !start.Equal(tc.Start) {
+			t.Errorf("%v: got: (%v, %v), want: (%v, %v)", tc.At, start, ok, tc.Start, tc.In)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package history reconstructs vulnerability reports as they would have been
+// built from the vulnerability data of a past update operation.
+//
+// The matcher only stores the vulnerabilities of the update operations it
+// retains (see the "update_retention" configuration), so a report can only be
+// rewound as far as the oldest of those. A current report is rewound by
+// removing the vulnerabilities added since the point in question and checking
+// whether the manifest was affected by the ones removed since, the same way
+// the notifier finds the manifests affected by an update.
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+)
+
+// ErrNotRetained is returned when the update operation a report is requested
+// for isn't known to the matcher, either because it never existed or because
+// it's been garbage collected.
+var ErrNotRetained = errors.New("history: update operation not retained")
+
+// Point identifies the vulnerability data a report is reconstructed against:
+// either a specific update operation, or the latest update operations as of a
+// time.
+type Point struct {
+	Ref  uuid.UUID
+	Time time.Time
+}
+
+// ParsePoint parses an update operation reference or an RFC 3339 timestamp.
+func ParsePoint(s string) (Point, error) {
+	var p Point
+	if ref, err := uuid.Parse(s); err == nil {
+		p.Ref = ref
+		return p, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return p, fmt.Errorf("history: %q is neither an update operation reference nor an RFC 3339 timestamp", s)
+	}
+	p.Time = t
+	return p, nil
+}
+
+// String implements fmt.Stringer.
+func (p Point) String() string {
+	if p.Ref != uuid.Nil {
+		return p.Ref.String()
+	}
+	return p.Time.Format(time.RFC3339)
+}
+
+// Differ is the subset of matcher.Differ needed to rewind a report.
+type Differ interface {
+	UpdateOperations(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error)
+	UpdateDiff(_ context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error)
+}
+
+// Affected is the subset of indexer.Service needed to rewind a report.
+type Affected interface {
+	AffectedManifests(context.Context, []claircore.Vulnerability) (*claircore.AffectedManifests, error)
+}
+
+// Result is a rewound report.
+type Result struct {
+	*claircore.VulnerabilityReport
+	// Unretained lists the updaters whose data as of the requested point
+	// isn't retained. Their findings are omitted from the report.
+	Unretained []string
+}
+
+// Rewind returns a copy of the current report "vr" as of "p".
+//
+// Vulnerabilities that were removed since "p" are associated with the
+// report's packages by name, which may associate them with more packages
+// than matching against the data at the time would have.
+func Rewind(ctx context.Context, d Differ, a Affected, vr *claircore.VulnerabilityReport, p Point) (*Result, error) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "matcher/history/Rewind",
+		"as_of", p.String())
+	all, err := d.UpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		return nil, fmt.Errorf("history: unable to list update operations: %w", err)
+	}
+	at := p.Time
+	if p.Ref != uuid.Nil {
+		found := false
+	Find:
+		for _, uos := range all {
+			for _, uo := range uos {
+				if uo.Ref == p.Ref {
+					at, found = uo.Date, true
+					break Find
+				}
+			}
+		}
+		if !found {
+			return nil, ErrNotRetained
+		}
+	}
+
+	out := &Result{VulnerabilityReport: clone(vr)}
+	var removed []claircore.Vulnerability
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		uos := all[name] // Newest first.
+		if len(uos) == 0 {
+			continue
+		}
+		// Find the newest operation at or before the point.
+		var prev *driver.UpdateOperation
+		for i := range uos {
+			uo := &uos[i]
+			if uo.Ref == p.Ref || !uo.Date.After(at) {
+				prev = uo
+				break
+			}
+		}
+		if prev == nil {
+			out.Unretained = append(out.Unretained, name)
+			out.drop(func(v *claircore.Vulnerability) bool { return v.Updater == name })
+			continue
+		}
+		if prev.Ref == uos[0].Ref {
+			continue
+		}
+		diff, err := d.UpdateDiff(ctx, prev.Ref, uos[0].Ref)
+		if err != nil {
+			return nil, fmt.Errorf("history: unable to diff %q update operations: %w", name, err)
+		}
+		added := make(map[string]struct{}, len(diff.Added))
+		for _, v := range diff.Added {
+			added[v.ID] = struct{}{}
+		}
+		out.drop(func(v *claircore.Vulnerability) bool {
+			_, ok := added[v.ID]
+			return ok
+		})
+		removed = append(removed, diff.Removed...)
+		zlog.Debug(ctx).
+			Str("updater", name).
+			Stringer("prev", prev.Ref).
+			Int("added", len(diff.Added)).
+			Int("removed", len(diff.Removed)).
+			Msg("rewound updater")
+	}
+	if len(removed) != 0 {
+		if err := out.restore(ctx, a, removed); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Clone makes a copy of "vr" that can be modified without affecting it.
+func clone(vr *claircore.VulnerabilityReport) *claircore.VulnerabilityReport {
+	out := *vr
+	out.Vulnerabilities = make(map[string]*claircore.Vulnerability, len(vr.Vulnerabilities))
+	for k, v := range vr.Vulnerabilities {
+		out.Vulnerabilities[k] = v
+	}
+	out.PackageVulnerabilities = make(map[string][]string, len(vr.PackageVulnerabilities))
+	for k, v := range vr.PackageVulnerabilities {
+		out.PackageVulnerabilities[k] = append([]string(nil), v...)
+	}
+	return &out
+}
+
+// Drop removes the vulnerabilities selected by "f" from the report.
+func (r *Result) drop(f func(*claircore.Vulnerability) bool) {
+	gone := make(map[string]struct{})
+	for id, v := range r.Vulnerabilities {
+		if f(v) {
+			gone[id] = struct{}{}
+			delete(r.Vulnerabilities, id)
+		}
+	}
+	if len(gone) == 0 {
+		return
+	}
+	for pkg, ids := range r.PackageVulnerabilities {
+		keep := ids[:0]
+		for _, id := range ids {
+			if _, ok := gone[id]; !ok {
+				keep = append(keep, id)
+			}
+		}
+		if len(keep) == 0 {
+			delete(r.PackageVulnerabilities, pkg)
+			continue
+		}
+		r.PackageVulnerabilities[pkg] = keep
+	}
+}
+
+// Restore adds back the vulnerabilities in "vs" that affect the report's
+// manifest.
+func (r *Result) restore(ctx context.Context, a Affected, vs []claircore.Vulnerability) error {
+	const chunk = 1000
+	for len(vs) > 0 {
+		s := vs[:minInt(chunk, len(vs))]
+		vs = vs[len(s):]
+		am, err := a.AffectedManifests(ctx, s)
+		if err != nil {
+			return fmt.Errorf("history: unable to find affected manifests: %w", err)
+		}
+		for _, id := range am.VulnerableManifests[r.Hash.String()] {
+			v, ok := am.Vulnerabilities[id]
+			if !ok || v.Package == nil {
+				continue
+			}
+			if _, ok := r.Vulnerabilities[id]; ok {
+				continue
+			}
+			linked := false
+			for pid, pkg := range r.Packages {
+				if pkg.Name != v.Package.Name {
+					continue
+				}
+				r.PackageVulnerabilities[pid] = append(r.PackageVulnerabilities[pid], id)
+				linked = true
+			}
+			if linked {
+				r.Vulnerabilities[id] = v
+			}
+		}
+	}
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestRewind(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	day := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	old, cur, other := uuid.New(), uuid.New(), uuid.New()
+	pkg := &claircore.Package{ID: "1", Name: "openssl"}
+	var (
+		// Added by "cur".
+		vNew = &claircore.Vulnerability{ID: "2", Name: "CVE-2000-0002", Updater: "a", Package: pkg}
+		// Present in "old" and "cur".
+		vKept = &claircore.Vulnerability{ID: "1", Name: "CVE-2000-0001", Updater: "a", Package: pkg}
+		// Removed by "cur".
+		vGone = &claircore.Vulnerability{ID: "3", Name: "CVE-2000-0003", Updater: "a", Package: pkg}
+		// From an updater that first ran after "old".
+		vLate = &claircore.Vulnerability{ID: "4", Name: "CVE-2000-0004", Updater: "b", Package: pkg}
+	)
+	vr := &claircore.VulnerabilityReport{
+		Hash:     claircore.MustParseDigest(digest),
+		Packages: map[string]*claircore.Package{"1": pkg},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": vKept, "2": vNew, "4": vLate,
+		},
+		PackageVulnerabilities: map[string][]string{"1": {"1", "2", "4"}},
+	}
+	m := &matcher.Mock{
+		UpdateOperations_: func(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error) {
+			return map[string][]driver.UpdateOperation{
+				"a": {
+					{Ref: cur, Updater: "a", Date: day.Add(48 * time.Hour)},
+					{Ref: old, Updater: "a", Date: day},
+				},
+				"b": {
+					{Ref: other, Updater: "b", Date: day.Add(24 * time.Hour)},
+				},
+			}, nil
+		},
+		UpdateDiff_: func(_ context.Context, prev, next uuid.UUID) (*driver.UpdateDiff, error) {
+			if prev != old || next != cur {
+				t.Errorf("unexpected diff: %v..%v", prev, next)
+			}
+			return &driver.UpdateDiff{
+				Added:   []claircore.Vulnerability{*vNew},
+				Removed: []claircore.Vulnerability{*vGone},
+			}, nil
+		},
+	}
+	i := &indexer.Mock{
+		AffectedManifests_: func(_ context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			a := claircore.NewAffectedManifests()
+			for i := range vs {
+				v := &vs[i]
+				a.Vulnerabilities[v.ID] = v
+				a.VulnerableManifests[digest] = append(a.VulnerableManifests[digest], v.ID)
+			}
+			return &a, nil
+		},
+	}
+
+	for _, p := range []Point{{Ref: old}, {Time: day.Add(time.Hour)}} {
+		t.Run(p.String(), func(t *testing.T) {
+			res, err := Rewind(ctx, m, i, vr, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range res.Vulnerabilities {
+				got = append(got, v.Name)
+			}
+			sort.Strings(got)
+			want := []string{vKept.Name, vGone.Name}
+			if !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			ids := append([]string(nil), res.PackageVulnerabilities["1"]...)
+			sort.Strings(ids)
+			if want := []string{"1", "3"}; !cmp.Equal(ids, want) {
+				t.Error(cmp.Diff(ids, want))
+			}
+			if want := []string{"b"}; !cmp.Equal(res.Unretained, want) {
+				t.Error(cmp.Diff(res.Unretained, want))
+			}
+		})
+	}
+	if got, want := len(vr.Vulnerabilities), 3; got != want {
+		t.Errorf("original report modified: got: %d vulnerabilities, want: %d", got, want)
+	}
+
+	t.Run("NotRetained", func(t *testing.T) {
+		_, err := Rewind(ctx, m, i, vr, Point{Ref: uuid.New()})
+		if !errors.Is(err, ErrNotRetained) {
+			t.Errorf("got: %v, want: %v", err, ErrNotRetained)
+		}
+	})
+}
+
+func TestParsePoint(t *testing.T) {
+	ref := uuid.New()
+	p, err := ParsePoint(ref.String())
+	if err != nil || p.Ref != ref {
+		t.Errorf("ref: got: %v, %v", p, err)
+	}
+	p, err = ParsePoint("2000-01-01T00:00:00Z")
+	if err != nil || !p.Time.Equal(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("time: got: %v, %v", p, err)
+	}
+	if _, err := ParsePoint("yesterday"); err == nil {
+		t.Error("expected error")
+	}
+}
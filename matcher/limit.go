@@ -0,0 +1,93 @@
+package matcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/claircore"
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	scanInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "matcher",
+			Name:      "scan_in_flight",
+			Help:      "Number of vulnerability reports currently being built.",
+		},
+	)
+	scanWait = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "matcher",
+			Name:      "scan_wait_seconds",
+			Help:      "Time spent waiting for a slot to build a vulnerability report.",
+		},
+	)
+	scanDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "matcher",
+			Name:      "scan_duration_seconds",
+			Help:      "Time spent building vulnerability reports, by number of packages in the index report.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"packages"},
+	)
+)
+
+// Limit wraps the provided Service so that at most "n" Scan calls run at
+// once. Callers over the limit wait until a slot frees up or their Context is
+// canceled.
+//
+// If "n" is less than 1, Scan calls are only instrumented.
+func Limit(s Service, n int) Service {
+	l := &limited{Service: s}
+	if n > 0 {
+		l.sem = semaphore.NewWeighted(int64(n))
+	}
+	return l
+}
+
+type limited struct {
+	Service
+	sem *semaphore.Weighted
+}
+
+// Scan implements Scanner.
+func (l *limited) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	if l.sem != nil {
+		start := time.Now()
+		if err := l.sem.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		defer l.sem.Release(1)
+		scanWait.Observe(time.Since(start).Seconds())
+	}
+	scanInFlight.Inc()
+	defer scanInFlight.Dec()
+	start := time.Now()
+	defer func() {
+		scanDuration.WithLabelValues(packageBucket(len(ir.Packages))).
+			Observe(time.Since(start).Seconds())
+	}()
+	return l.Service.Scan(ctx, ir)
+}
+
+// PackageBucket returns a coarse label for the number of packages in a
+// report, to keep the label's cardinality fixed.
+func packageBucket(n int) string {
+	switch {
+	case n < 100:
+		return "<100"
+	case n < 1000:
+		return "<1000"
+	case n < 5000:
+		return "<5000"
+	default:
+		return ">=5000"
+	}
+}
@@ -0,0 +1,58 @@
+package matcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+func TestLimit(t *testing.T) {
+	const n = 2
+	var cur, max int64
+	m := &Mock{
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			c := atomic.AddInt64(&cur, 1)
+			defer atomic.AddInt64(&cur, -1)
+			for {
+				m := atomic.LoadInt64(&max)
+				if c <= m || atomic.CompareAndSwapInt64(&max, m, c) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	s := Limit(m, n)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Scan(ctx, &claircore.IndexReport{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt64(&max); got > n {
+		t.Errorf("concurrent scans: got: %d, want: <= %d", got, n)
+	}
+
+	// A canceled caller doesn't wait forever.
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	l := s.(*limited)
+	if err := l.sem.Acquire(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	defer l.sem.Release(n)
+	if _, err := s.Scan(ctx, &claircore.IndexReport{}); err == nil {
+		t.Error("expected error from canceled scan")
+	}
+}
@@ -0,0 +1,63 @@
+// Package lockfiles matches the packages found by the indexer's lockfile
+// scanners against the advisories claircore's OSV updaters record.
+//
+// OSV names the packages of ecosystems claircore has no scanner for by their
+// package URL, which the lockfile scanners record as each package's source,
+// and only records the version an advisory was fixed in. A package is
+// vulnerable if its version is lower than that, or if there's no fix.
+package lockfiles
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/indexer/lockfiles"
+)
+
+// Name is the name of the Matcher.
+const Name = "lockfiles"
+
+// Matcher matches lockfile packages against OSV advisories.
+//
+// The zero value is ready to use.
+type Matcher struct{}
+
+var _ driver.Matcher = (*Matcher)(nil)
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return Name }
+
+// Filter implements driver.Matcher.
+func (*Matcher) Filter(r *claircore.IndexRecord) bool {
+	return r.Repository != nil && lockfiles.IsRepository(r.Repository.Name) &&
+		r.Package.Source != nil && r.Package.Source.Name != ""
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{driver.RepositoryName}
+}
+
+// Vulnerable implements driver.Matcher.
+//
+// Versions that can't be compared, such as Composer's "dev-main", are never
+// vulnerable.
+func (*Matcher) Vulnerable(_ context.Context, r *claircore.IndexRecord, v *claircore.Vulnerability) (bool, error) {
+	if v.Package == nil || r.Package.Source == nil || v.Package.Name != r.Package.Source.Name {
+		return false, nil
+	}
+	have, ok := parseVersion(r.Package.Version)
+	if !ok {
+		return false, nil
+	}
+	if v.FixedInVersion == "" {
+		return true, nil
+	}
+	fixed, ok := parseVersion(v.FixedInVersion)
+	if !ok {
+		return false, nil
+	}
+	return have.compare(&fixed) < 0, nil
+}
@@ -0,0 +1,108 @@
+package lockfiles
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestCompare(t *testing.T) {
+	tt := []struct {
+		A, B string
+		Want int
+	}{
+		{"v5.4.20", "5.4.21", -1},
+		{"5.4.21", "v5.4.21", 0},
+		{"5.4", "5.4.0", 0},
+		{"5.10.0", "5.9.9", 1},
+		{"2.0.0-RC1", "2.0.0", -1},
+		{"2.0.0-beta2", "2.0.0-RC1", -1},
+		{"2.0.0-beta2", "2.0.0-beta10", -1},
+		{"2.0.0-alpha1", "2.0.0-beta1", -1},
+		{"1.2.3-p1", "1.2.3", 1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+	for _, tc := range tt {
+		a, ok := parseVersion(tc.A)
+		if !ok {
+			t.Fatalf("unable to parse %q", tc.A)
+		}
+		b, ok := parseVersion(tc.B)
+		if !ok {
+			t.Fatalf("unable to parse %q", tc.B)
+		}
+		if got := a.compare(&b); got != tc.Want {
+			t.Errorf("%q, %q: got: %d, want: %d", tc.A, tc.B, got, tc.Want)
+		}
+	}
+	for _, s := range []string{"dev-main", "", "1.x-dev", "latest"} {
+		if _, ok := parseVersion(s); ok {
+			t.Errorf("%q: unexpectedly parsed", s)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	var m Matcher
+	record := func(v string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{
+			Package: &claircore.Package{
+				Name:    "symfony/http-kernel",
+				Version: v,
+				Source:  &claircore.Package{Name: "pkg:composer/symfony/http-kernel"},
+			},
+			Repository: &claircore.Repository{Name: "packagist"},
+		}
+	}
+	vuln := func(name, fixed string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater:        "osv/packagist",
+			Package:        &claircore.Package{Name: name},
+			Repo:           &claircore.Repository{Name: "packagist"},
+			FixedInVersion: fixed,
+		}
+	}
+	if !m.Filter(record("v5.4.20")) {
+		t.Error("packagist record not filtered")
+	}
+	if !m.Filter(&claircore.IndexRecord{
+		Package: &claircore.Package{
+			Name:   "github.com/apple/swift-nio",
+			Source: &claircore.Package{Name: "pkg:swift/github.com/apple/swift-nio"},
+		},
+		Repository: &claircore.Repository{Name: "swifturl"},
+	}) {
+		t.Error("swifturl record not filtered")
+	}
+	if m.Filter(&claircore.IndexRecord{
+		Package:    &claircore.Package{Name: "requests"},
+		Repository: &claircore.Repository{Name: "pypi"},
+	}) {
+		t.Error("pypi record filtered")
+	}
+
+	tt := []struct {
+		Version string
+		Vuln    *claircore.Vulnerability
+		Want    bool
+	}{
+		{"v5.4.20", vuln("pkg:composer/symfony/http-kernel", "5.4.21"), true},
+		{"v5.4.21", vuln("pkg:composer/symfony/http-kernel", "5.4.21"), false},
+		{"v6.0.0", vuln("pkg:composer/symfony/http-kernel", "5.4.21"), false},
+		{"v5.4.20", vuln("pkg:composer/symfony/http-kernel", ""), true},
+		{"dev-main", vuln("pkg:composer/symfony/http-kernel", ""), false},
+		{"v5.4.20", vuln("pkg:composer/symfony/symfony", "5.4.21"), false},
+	}
+	for _, tc := range tt {
+		got, err := m.Vulnerable(ctx, record(tc.Version), tc.Vuln)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%s against %s fixed in %q: got: %v, want: %v",
+				tc.Version, tc.Vuln.Package.Name, tc.Vuln.FixedInVersion, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package lockfiles
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version is a version as package managers using dotted numbers with an
+// optional stability suffix write them, such as "v5.4.20", "2.0.0-RC1", or
+// "1.2.3-p1".
+type version struct {
+	nums []int
+	// Stability orders the suffixes, and N is the number following one.
+	stability int
+	n         int
+}
+
+// These are the stabilities, in order.
+const (
+	stabilityDev = iota
+	stabilityAlpha
+	stabilityBeta
+	stabilityRC
+	stabilityStable
+	stabilityPatch
+)
+
+var stabilities = map[string]int{
+	"dev":   stabilityDev,
+	"alpha": stabilityAlpha,
+	"a":     stabilityAlpha,
+	"beta":  stabilityBeta,
+	"b":     stabilityBeta,
+	"rc":    stabilityRC,
+	"patch": stabilityPatch,
+	"pl":    stabilityPatch,
+	"p":     stabilityPatch,
+}
+
+// ParseVersion parses "s", reporting whether it's a version that can be
+// compared.
+func parseVersion(s string) (version, bool) {
+	v := version{stability: stabilityStable}
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "v")
+	if i := strings.IndexByte(s, '+'); i != -1 {
+		s = s[:i]
+	}
+	num, suf := s, ""
+	if i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i != -1 {
+		num, suf = strings.TrimSuffix(s[:i], "."), strings.TrimLeft(s[i:], "-_.")
+	}
+	if num == "" {
+		return v, false
+	}
+	for _, f := range strings.Split(num, ".") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return v, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	if suf == "" {
+		return v, true
+	}
+	name := strings.TrimRight(suf, "0123456789.")
+	st, ok := stabilities[name]
+	if !ok {
+		return v, false
+	}
+	v.stability = st
+	if rest := strings.Trim(suf[len(name):], "."); rest != "" {
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return v, false
+		}
+		v.n = n
+	}
+	return v, true
+}
+
+// Compare returns -1, 0, or 1 as "v" is lower than, equal to, or higher than
+// "o". Missing trailing numbers are zero.
+func (v *version) compare(o *version) int {
+	l := len(v.nums)
+	if len(o.nums) > l {
+		l = len(o.nums)
+	}
+	for i := 0; i < l; i++ {
+		var a, b int
+		if i < len(v.nums) {
+			a = v.nums[i]
+		}
+		if i < len(o.nums) {
+			b = o.nums[i]
+		}
+		if c := cmpInt(a, b); c != 0 {
+			return c
+		}
+	}
+	if c := cmpInt(v.stability, o.stability); c != 0 {
+		return c
+	}
+	return cmpInt(v.n, o.n)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
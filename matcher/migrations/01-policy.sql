@@ -0,0 +1,6 @@
+-- named scan policies, stored as the JSON documents submitted via the API
+CREATE TABLE IF NOT EXISTS matcher_policy (
+    name text PRIMARY KEY,
+    policy jsonb NOT NULL,
+    updated timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,10 @@
+-- triage annotations on individual findings, stored as the JSON documents
+-- submitted via the API. An empty package applies to every package.
+CREATE TABLE IF NOT EXISTS matcher_triage (
+    manifest text NOT NULL,
+    vulnerability text NOT NULL,
+    package text NOT NULL DEFAULT '',
+    annotation jsonb NOT NULL,
+    updated timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (manifest, vulnerability, package)
+);
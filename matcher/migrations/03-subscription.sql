@@ -0,0 +1,13 @@
+-- re-scan subscriptions, stored as the JSON documents submitted via the API,
+-- along with when they're next due and the outcome of the last run
+CREATE TABLE IF NOT EXISTS matcher_subscription (
+    id uuid PRIMARY KEY,
+    manifest text NOT NULL,
+    subscription jsonb NOT NULL,
+    period interval NOT NULL,
+    next_run timestamptz NOT NULL,
+    last_run timestamptz,
+    last_error text,
+    created timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS matcher_subscription_next_run_idx ON matcher_subscription (next_run);
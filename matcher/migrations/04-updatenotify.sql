@@ -0,0 +1,13 @@
+-- Notify listeners when an updater records an update operation, so that
+-- notifiers don't have to wait for their next poll. The notification is only
+-- delivered once the updater's transaction commits.
+CREATE OR REPLACE FUNCTION clair_notify_update_operation() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('clair_update_operation', NEW.updater);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS clair_notify_update_operation ON update_operation;
+CREATE TRIGGER clair_notify_update_operation
+	AFTER INSERT ON update_operation
+	FOR EACH ROW EXECUTE PROCEDURE clair_notify_update_operation();
@@ -0,0 +1,8 @@
+-- updaters disabled at runtime. An updater in this table is skipped when the
+-- matcher runs updates, and its existing data is left in place.
+CREATE TABLE IF NOT EXISTS matcher_updater_disabled (
+    updater text PRIMARY KEY,
+    reason text NOT NULL DEFAULT '',
+    disabled_by text NOT NULL DEFAULT '',
+    since timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,17 @@
+-- per-severity finding counts of a manifest's vulnerability report, one row
+-- per manifest per vulnerability data cursor (the latest update operation at
+-- the time the report was built).
+CREATE TABLE IF NOT EXISTS matcher_trend (
+    manifest text NOT NULL,
+    cursor uuid NOT NULL,
+    recorded timestamptz NOT NULL DEFAULT now(),
+    unknown integer NOT NULL DEFAULT 0,
+    negligible integer NOT NULL DEFAULT 0,
+    low integer NOT NULL DEFAULT 0,
+    medium integer NOT NULL DEFAULT 0,
+    high integer NOT NULL DEFAULT 0,
+    critical integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (manifest, cursor)
+);
+CREATE INDEX IF NOT EXISTS matcher_trend_recorded_idx ON matcher_trend (recorded);
+CREATE INDEX IF NOT EXISTS matcher_trend_manifest_recorded_idx ON matcher_trend (manifest, recorded);
@@ -0,0 +1,6 @@
+-- internal advisories, stored as the JSON documents submitted via the API
+CREATE TABLE IF NOT EXISTS matcher_advisory (
+    name text PRIMARY KEY,
+    advisory jsonb NOT NULL,
+    updated timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,25 @@
+-- the append-only event log of each manifest. The id orders events and is
+-- the cursor clients page with.
+CREATE TABLE IF NOT EXISTS matcher_changelog (
+    id bigserial PRIMARY KEY,
+    manifest text NOT NULL,
+    recorded timestamptz NOT NULL DEFAULT now(),
+    kind text NOT NULL,
+    reason text,
+    cursor uuid,
+    findings jsonb
+);
+CREATE INDEX IF NOT EXISTS matcher_changelog_manifest_idx ON matcher_changelog (manifest, id);
+CREATE INDEX IF NOT EXISTS matcher_changelog_recorded_idx ON matcher_changelog (recorded);
+
+-- the state of each manifest's most recent report, which new reports are
+-- compared against. A row with a null fingerprint hasn't had a report
+-- recorded yet.
+CREATE TABLE IF NOT EXISTS matcher_changelog_state (
+    manifest text PRIMARY KEY,
+    fingerprint text,
+    cursor uuid,
+    findings jsonb NOT NULL DEFAULT '[]',
+    updated timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS matcher_changelog_state_updated_idx ON matcher_changelog_state (updated);
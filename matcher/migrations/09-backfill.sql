@@ -0,0 +1,21 @@
+-- re-match backfills, with the progress saved after each chunk so that they
+-- can be resumed
+CREATE TABLE IF NOT EXISTS matcher_backfill (
+    id uuid PRIMARY KEY,
+    state text NOT NULL,
+    rate integer NOT NULL,
+    chunk_size integer NOT NULL,
+    total bigint NOT NULL,
+    done bigint NOT NULL DEFAULT 0,
+    failed bigint NOT NULL DEFAULT 0,
+    last_manifest text NOT NULL DEFAULT '',
+    active_seconds double precision NOT NULL DEFAULT 0,
+    lease timestamptz,
+    last_error text,
+    created timestamptz NOT NULL DEFAULT now(),
+    updated timestamptz NOT NULL DEFAULT now(),
+    finished timestamptz
+);
+-- only one backfill may be running or paused at a time
+CREATE UNIQUE INDEX IF NOT EXISTS matcher_backfill_active_idx ON matcher_backfill ((true))
+    WHERE state IN ('running', 'paused');
@@ -0,0 +1,73 @@
+// Package migrations holds the schema of the tables Clair's optional matcher
+// features keep alongside claircore's, in the matcher database.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+//go:embed *.sql
+var fs embed.FS
+
+func runFile(n string) func(*sql.Tx) error {
+	return dbutil.RunFile(fs, n)
+}
+
+// MigrationTable is the table the matcher migrations are recorded in.
+const MigrationTable = "clair_matcher_migrations"
+
+// Migrations creates the tables for every optional matcher feature, whether or
+// not it's enabled.
+var Migrations = []migrate.Migration{
+	{
+		ID: 1,
+		Up: runFile("01-policy.sql"),
+	},
+	{
+		ID: 2,
+		Up: runFile("02-triage.sql"),
+	},
+	{
+		ID: 3,
+		Up: runFile("03-subscription.sql"),
+	},
+	{
+		ID: 4,
+		Up: runFile("04-updatenotify.sql"),
+	},
+	{
+		ID: 5,
+		Up: runFile("05-updaterctl.sql"),
+	},
+	{
+		ID: 6,
+		Up: runFile("06-trend.sql"),
+	},
+	{
+		ID: 7,
+		Up: runFile("07-advisory.sql"),
+	},
+	{
+		ID: 8,
+		Up: runFile("08-changelog.sql"),
+	},
+	{
+		ID: 9,
+		Up: runFile("09-backfill.sql"),
+	},
+}
+
+// Init performs the matcher migrations on the database described by "cfg".
+//
+// Claircore's matcher migrations must be performed first: the update
+// notification trigger is on claircore's update_operation table.
+func Init(ctx context.Context, cfg *pgx.ConnConfig) error {
+	return dbutil.Migrate(ctx, cfg, MigrationTable, Migrations...)
+}
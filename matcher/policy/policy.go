@@ -0,0 +1,208 @@
+// Package policy implements named scan policies evaluated against
+// vulnerability reports.
+//
+// A Policy is a set of rules; a report passes a policy if none of the rules
+// are violated. Policies are stored in the matcher database so that every
+// client gates on the same rules.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/severity"
+)
+
+// Policy is a named set of rules.
+type Policy struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// MaxSeverity is the highest normalized severity allowed. An empty value
+	// allows any severity.
+	MaxSeverity string `json:"max_severity,omitempty"`
+	// DenyVulnerabilities lists vulnerability names, such as CVE IDs, that
+	// aren't allowed regardless of severity. Compared case-insensitively.
+	DenyVulnerabilities []string `json:"deny_vulnerabilities,omitempty"`
+	// BanPackages lists packages that aren't allowed, vulnerable or not.
+	BanPackages []PackageRule `json:"ban_packages,omitempty"`
+	// MaxFixAge is how long a vulnerability with an available fix is
+	// allowed, measured from when the vulnerability was issued. A zero value
+	// disables the rule.
+	MaxFixAge Duration `json:"max_fix_age,omitempty"`
+}
+
+// PackageRule selects packages.
+type PackageRule struct {
+	// Name is a path.Match glob matched against the package name.
+	Name string `json:"name"`
+	// Version, if provided, restricts the rule to an exact version.
+	Version string `json:"version,omitempty"`
+}
+
+// Matches reports whether the rule selects the package.
+func (r *PackageRule) matches(p *claircore.Package) bool {
+	if ok, _ := path.Match(r.Name, p.Name); !ok {
+		return false
+	}
+	return r.Version == "" || r.Version == p.Version
+}
+
+// Duration is a time.Duration that's encoded as a string, as accepted by
+// time.ParseDuration.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+// ErrInvalid is returned, wrapped, for Policies that can't be evaluated.
+var ErrInvalid = errors.New("invalid policy")
+
+// Validate reports whether the Policy is well-formed.
+func (p *Policy) Validate() error {
+	if !nameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("%w: bad name %q", ErrInvalid, p.Name)
+	}
+	if p.MaxSeverity != "" {
+		if _, ok := severity.Parse(p.MaxSeverity); !ok {
+			return fmt.Errorf("%w: unknown severity %q", ErrInvalid, p.MaxSeverity)
+		}
+	}
+	for i, r := range p.BanPackages {
+		if r.Name == "" {
+			return fmt.Errorf("%w: ban_packages[%d]: missing name", ErrInvalid, i)
+		}
+		if _, err := path.Match(r.Name, ""); err != nil {
+			return fmt.Errorf("%w: ban_packages[%d]: %v", ErrInvalid, i, err)
+		}
+	}
+	if p.MaxFixAge < 0 {
+		return fmt.Errorf("%w: negative max_fix_age", ErrInvalid)
+	}
+	return nil
+}
+
+// Rule names used in Violations.
+const (
+	RuleMaxSeverity       = "max_severity"
+	RuleDenyVulnerability = "deny_vulnerabilities"
+	RuleBanPackage        = "ban_packages"
+	RuleMaxFixAge         = "max_fix_age"
+)
+
+// Violation describes a single way a report fails a Policy.
+type Violation struct {
+	Rule          string `json:"rule"`
+	Package       string `json:"package_id"`
+	Vulnerability string `json:"vulnerability_id,omitempty"`
+	Message       string `json:"message"`
+}
+
+// Result is the outcome of evaluating a report against a Policy.
+type Result struct {
+	Policy     string           `json:"policy"`
+	Manifest   claircore.Digest `json:"manifest_hash"`
+	Pass       bool             `json:"pass"`
+	Violations []Violation      `json:"violations"`
+}
+
+// Evaluate checks the report against the Policy, as of the time "now".
+//
+// The Policy is assumed to be valid.
+func (p *Policy) Evaluate(vr *claircore.VulnerabilityReport, now time.Time) *Result {
+	res := Result{
+		Policy:     p.Name,
+		Manifest:   vr.Hash,
+		Violations: []Violation{},
+	}
+	max, limitSev := severity.Parse(p.MaxSeverity)
+	deny := make(map[string]struct{}, len(p.DenyVulnerabilities))
+	for _, n := range p.DenyVulnerabilities {
+		deny[strings.ToLower(n)] = struct{}{}
+	}
+
+	// Walk packages in a fixed order so results are stable.
+	ids := make([]string, 0, len(vr.Packages))
+	for id := range vr.Packages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		pkg := vr.Packages[id]
+		if pkg == nil {
+			continue
+		}
+		for i := range p.BanPackages {
+			if p.BanPackages[i].matches(pkg) {
+				res.Violations = append(res.Violations, Violation{
+					Rule:    RuleBanPackage,
+					Package: id,
+					Message: fmt.Sprintf("package %s %s is banned", pkg.Name, pkg.Version),
+				})
+				break
+			}
+		}
+		vids := append([]string(nil), vr.PackageVulnerabilities[id]...)
+		sort.Strings(vids)
+		for _, vid := range vids {
+			v, ok := vr.Vulnerabilities[vid]
+			if !ok || v == nil {
+				continue
+			}
+			if limitSev && v.NormalizedSeverity > max {
+				res.Violations = append(res.Violations, Violation{
+					Rule:          RuleMaxSeverity,
+					Package:       id,
+					Vulnerability: vid,
+					Message: fmt.Sprintf("%s in %s is %s, above %s",
+						v.Name, pkg.Name, v.NormalizedSeverity, max),
+				})
+			}
+			if _, ok := deny[strings.ToLower(v.Name)]; ok {
+				res.Violations = append(res.Violations, Violation{
+					Rule:          RuleDenyVulnerability,
+					Package:       id,
+					Vulnerability: vid,
+					Message:       fmt.Sprintf("%s in %s is denied", v.Name, pkg.Name),
+				})
+			}
+			if age := time.Duration(p.MaxFixAge); age > 0 && v.FixedInVersion != "" &&
+				!v.Issued.IsZero() && now.Sub(v.Issued) > age {
+				res.Violations = append(res.Violations, Violation{
+					Rule:          RuleMaxFixAge,
+					Package:       id,
+					Vulnerability: vid,
+					Message: fmt.Sprintf("%s in %s has a fix (%s) and was issued %s",
+						v.Name, pkg.Name, v.FixedInVersion, v.Issued.Format("2006-01-02")),
+				})
+			}
+		}
+	}
+	res.Pass = len(res.Violations) == 0
+	return &res
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Policy Policy
+		OK     bool
+	}{
+		{Name: "OK", Policy: Policy{Name: "prod", MaxSeverity: "high"}, OK: true},
+		{Name: "BadName", Policy: Policy{Name: "a/b"}},
+		{Name: "BadSeverity", Policy: Policy{Name: "prod", MaxSeverity: "urgent"}},
+		{Name: "BadGlob", Policy: Policy{Name: "prod", BanPackages: []PackageRule{{Name: "["}}}},
+		{Name: "NegativeAge", Policy: Policy{Name: "prod", MaxFixAge: Duration(-time.Hour)}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Policy.Validate()
+			if tc.OK {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("got: %v, want: %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	in := `{"name":"prod","max_fix_age":"720h0m0s"}`
+	var p Policy
+	if err := json.Unmarshal([]byte(in), &p); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := time.Duration(p.MaxFixAge), 30*24*time.Hour; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	b, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), in; got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	vr := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+			"2": {ID: "2", Name: "telnet", Version: "0.17"},
+			"3": {ID: "3", Name: "zlib", Version: "1.2.11"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"a": {ID: "a", Name: "CVE-2023-0001", NormalizedSeverity: claircore.Critical},
+			"b": {ID: "b", Name: "CVE-2023-0002", NormalizedSeverity: claircore.Low},
+			"c": {
+				ID: "c", Name: "CVE-2022-0003", NormalizedSeverity: claircore.Medium,
+				FixedInVersion: "1.2.12", Issued: now.Add(-90 * 24 * time.Hour),
+			},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"a"},
+			"3": {"b", "c"},
+		},
+	}
+
+	p := Policy{Name: "permissive"}
+	if res := p.Evaluate(vr, now); !res.Pass || len(res.Violations) != 0 {
+		t.Errorf("empty policy: got: %+v", res)
+	}
+
+	p = Policy{
+		Name:                "strict",
+		MaxSeverity:         "high",
+		DenyVulnerabilities: []string{"cve-2023-0002"},
+		BanPackages:         []PackageRule{{Name: "tel*"}},
+		MaxFixAge:           Duration(30 * 24 * time.Hour),
+	}
+	res := p.Evaluate(vr, now)
+	if res.Pass {
+		t.Error("strict policy passed")
+	}
+	type v struct{ Rule, Package, Vulnerability string }
+	var got []v
+	for _, x := range res.Violations {
+		got = append(got, v{x.Rule, x.Package, x.Vulnerability})
+	}
+	want := []v{
+		{RuleMaxSeverity, "1", "a"},
+		{RuleBanPackage, "2", ""},
+		{RuleDenyVulnerability, "3", "b"},
+		{RuleMaxFixAge, "3", "c"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
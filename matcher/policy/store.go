@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_policy", "the policy store")
+
+// Store persists Policies.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Policy returns the named Policy, reporting false if it doesn't exist.
+func (s *Store) Policy(ctx context.Context, name string) (_ *Policy, ok bool, err error) {
+	const query = `SELECT policy FROM matcher_policy WHERE name = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var p Policy
+	err = s.pool.QueryRow(ctx, query, name).Scan(&p)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("policy: unable to get %q: %w", name, err)
+	}
+	return &p, true, nil
+}
+
+// Policies returns all Policies, ordered by name.
+func (s *Store) Policies(ctx context.Context) (_ []Policy, err error) {
+	const query = `SELECT policy FROM matcher_policy ORDER BY name;`
+	defer queryMetrics.Observe("list", &err)()
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("policy: unable to list policies: %w", err)
+	}
+	defer rows.Close()
+	out := []Policy{}
+	for rows.Next() {
+		var p Policy
+		if err = rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("policy: unable to list policies: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("policy: unable to list policies: %w", err)
+	}
+	return out, nil
+}
+
+// PutPolicy creates or replaces a Policy. It reports whether the Policy was
+// newly created.
+func (s *Store) PutPolicy(ctx context.Context, p *Policy) (created bool, err error) {
+	const query = `INSERT INTO matcher_policy (name, policy) VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET policy = EXCLUDED.policy, updated = now()
+RETURNING (xmax = 0);`
+	defer queryMetrics.Observe("put", &err)()
+	if err = p.Validate(); err != nil {
+		return false, err
+	}
+	if err = s.pool.QueryRow(ctx, query, p.Name, p).Scan(&created); err != nil {
+		return false, fmt.Errorf("policy: unable to store %q: %w", p.Name, err)
+	}
+	return created, nil
+}
+
+// DeletePolicy removes the named Policy, reporting false if it didn't exist.
+func (s *Store) DeletePolicy(ctx context.Context, name string) (_ bool, err error) {
+	const query = `DELETE FROM matcher_policy WHERE name = $1;`
+	defer queryMetrics.Observe("delete", &err)()
+	tag, err := s.pool.Exec(ctx, query, name)
+	if err != nil {
+		return false, fmt.Errorf("policy: unable to delete %q: %w", name, err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	return NewStore(pool)
+}
+
+func TestStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := TestingStore(ctx, t)
+
+	p := Policy{Name: "prod", MaxSeverity: "High"}
+	created, err := s.PutPolicy(ctx, &p)
+	if err != nil || !created {
+		t.Fatalf("create: got: (%v, %v)", created, err)
+	}
+	p.MaxSeverity = "Medium"
+	created, err = s.PutPolicy(ctx, &p)
+	if err != nil || created {
+		t.Fatalf("replace: got: (%v, %v)", created, err)
+	}
+	if _, err := s.PutPolicy(ctx, &Policy{Name: "bad", MaxSeverity: "urgent"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid: got: %v", err)
+	}
+
+	got, ok, err := s.Policy(ctx, "prod")
+	if err != nil || !ok {
+		t.Fatalf("get: got: (%v, %v)", ok, err)
+	}
+	if got.MaxSeverity != "Medium" {
+		t.Errorf("get: got: %+v", got)
+	}
+	ps, err := s.Policies(ctx)
+	if err != nil || len(ps) != 1 {
+		t.Errorf("list: got: (%v, %v)", ps, err)
+	}
+
+	if ok, err := s.DeletePolicy(ctx, "prod"); err != nil || !ok {
+		t.Errorf("delete: got: (%v, %v)", ok, err)
+	}
+	if _, ok, err := s.Policy(ctx, "prod"); err != nil || ok {
+		t.Errorf("get deleted: got: (%v, %v)", ok, err)
+	}
+}
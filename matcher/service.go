@@ -2,10 +2,26 @@
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
 	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/internal/standby"
+	"github.com/quay/clair/v4/matcher/advisory"
+	"github.com/quay/clair/v4/matcher/backfill"
+	"github.com/quay/clair/v4/matcher/changelog"
+	"github.com/quay/clair/v4/matcher/freshness"
+	"github.com/quay/clair/v4/matcher/gc"
+	"github.com/quay/clair/v4/matcher/policy"
+	"github.com/quay/clair/v4/matcher/subscription"
+	"github.com/quay/clair/v4/matcher/trend"
+	"github.com/quay/clair/v4/matcher/triage"
+	"github.com/quay/clair/v4/matcher/updaterctl"
+	"github.com/quay/clair/v4/matcher/usage"
+	"github.com/quay/clair/v4/matcher/vulnstats"
 )
 
 // Service is an aggregate interface wrapping claircore.Libvuln functionality.
@@ -39,3 +55,168 @@ type Differ interface {
 	// across all updaters.
 	LatestUpdateOperation(context.Context, driver.UpdateKind) (uuid.UUID, error)
 }
+
+// Collector is implemented by Services that run the garbage collection
+// policy engine.
+type Collector interface {
+	// CollectGarbage applies the configured GC policies, or only reports what
+	// they would delete if "dryRun" is set.
+	CollectGarbage(ctx context.Context, dryRun bool) (*gc.Report, error)
+}
+
+// Policies is implemented by Services that store scan policies.
+type Policies interface {
+	// Policy returns the named policy, reporting false if it doesn't exist.
+	Policy(ctx context.Context, name string) (*policy.Policy, bool, error)
+	// Policies returns all policies, ordered by name.
+	Policies(ctx context.Context) ([]policy.Policy, error)
+	// PutPolicy creates or replaces a policy, reporting whether it was
+	// created.
+	PutPolicy(ctx context.Context, p *policy.Policy) (bool, error)
+	// DeletePolicy removes the named policy, reporting false if it didn't
+	// exist.
+	DeletePolicy(ctx context.Context, name string) (bool, error)
+}
+
+// Advisories is implemented by Services that store internal advisories.
+type Advisories interface {
+	// Advisory returns the named advisory, reporting false if it doesn't
+	// exist.
+	Advisory(ctx context.Context, name string) (*advisory.Advisory, bool, error)
+	// Advisories returns all advisories, including withdrawn ones, ordered
+	// by name.
+	Advisories(ctx context.Context) ([]advisory.Advisory, error)
+	// PutAdvisory creates or replaces an advisory, reporting whether it was
+	// created.
+	PutAdvisory(ctx context.Context, a *advisory.Advisory) (bool, error)
+	// WithdrawAdvisory withdraws the named advisory, reporting false if it
+	// doesn't exist.
+	WithdrawAdvisory(ctx context.Context, name string) (bool, error)
+}
+
+// Triage is implemented by Services that store triage annotations on
+// findings.
+type Triage interface {
+	// Annotations returns the annotations for a manifest, including expired
+	// ones.
+	Annotations(ctx context.Context, m claircore.Digest) ([]triage.Annotation, error)
+	// PutAnnotation creates or replaces the annotation for a finding,
+	// reporting whether it was created.
+	PutAnnotation(ctx context.Context, m claircore.Digest, a *triage.Annotation) (bool, error)
+	// DeleteAnnotation removes the annotation for a finding, reporting false
+	// if it didn't exist.
+	DeleteAnnotation(ctx context.Context, m claircore.Digest, vuln, pkg string) (bool, error)
+}
+
+// Subscriptions is implemented by Services that run scheduled re-scan
+// subscriptions. Methods return subscription.ErrDisabled if subscriptions
+// aren't configured.
+type Subscriptions interface {
+	// Subscriptions returns all subscriptions.
+	Subscriptions(ctx context.Context) ([]subscription.Subscription, error)
+	// Subscription returns the identified subscription, reporting false if
+	// it doesn't exist.
+	Subscription(ctx context.Context, id uuid.UUID) (*subscription.Subscription, bool, error)
+	// AddSubscription creates a subscription, assigning its ID.
+	AddSubscription(ctx context.Context, s *subscription.Subscription) error
+	// DeleteSubscription removes the identified subscription, reporting false
+	// if it didn't exist.
+	DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// Backfills is implemented by Services that can re-match every indexed
+// manifest. Methods return backfill.ErrDisabled if backfills aren't
+// configured.
+type Backfills interface {
+	// Backfills returns all backfills, newest first.
+	Backfills(ctx context.Context) ([]backfill.Job, error)
+	// Backfill returns the identified backfill, reporting false if it
+	// doesn't exist.
+	Backfill(ctx context.Context, id uuid.UUID) (*backfill.Job, bool, error)
+	// StartBackfill starts a backfill, assigning its ID. It returns
+	// backfill.ErrActive if another is running or paused.
+	StartBackfill(ctx context.Context, j *backfill.Job) error
+	// SetBackfillState pauses, resumes, or cancels the identified backfill,
+	// reporting false if it doesn't exist.
+	SetBackfillState(ctx context.Context, id uuid.UUID, s backfill.State) (*backfill.Job, bool, error)
+}
+
+// Freshness is implemented by Services that track when updaters last ran
+// successfully.
+type Freshness interface {
+	// UpdaterStatus returns the status of every updater that has run.
+	UpdaterStatus(ctx context.Context) ([]freshness.Status, error)
+	// StaleUpdaters returns the names of updaters whose data was stale as of
+	// the most recent check. It's cheap enough to call for every request.
+	StaleUpdaters() []string
+}
+
+// UsageReporter is implemented by Services that can report how much they're
+// storing.
+type UsageReporter interface {
+	Usage(ctx context.Context) (*usage.Usage, error)
+}
+
+// VulnerabilityStats is implemented by Services that can summarize the
+// vulnerability data they store.
+type VulnerabilityStats interface {
+	// VulnerabilityStats reports the size of each updater's most recent
+	// update operations.
+	VulnerabilityStats(ctx context.Context) ([]vulnstats.Source, error)
+	// VulnerabilityDiff reports the advisories that changed between two
+	// update operations of one updater. "Prev" can be uuid.Nil to indicate
+	// "the update operation before cur."
+	VulnerabilityDiff(ctx context.Context, prev, cur uuid.UUID, limit int) (*vulnstats.Diff, error)
+}
+
+// UpdaterControl is implemented by Services that allow individual updaters to
+// be disabled at runtime.
+type UpdaterControl interface {
+	// DisabledUpdaters returns the disabled updaters, ordered by name.
+	DisabledUpdaters(ctx context.Context) ([]updaterctl.Disabled, error)
+	// DisableUpdater disables an updater, reporting whether it was newly
+	// disabled.
+	DisableUpdater(ctx context.Context, d *updaterctl.Disabled) (bool, error)
+	// EnableUpdater re-enables the named updater, reporting false if it
+	// wasn't disabled.
+	EnableUpdater(ctx context.Context, name string) (bool, error)
+}
+
+// Standby is implemented by Services in a process that may run as a warm
+// standby. Methods return standby.ErrDisabled if standby mode isn't
+// configured.
+type Standby interface {
+	// StandbyStatus reports whether the process is active or on standby.
+	StandbyStatus(ctx context.Context) (standby.Status, error)
+	// Promote makes the process active, reporting false if it already was.
+	// It returns standby.ErrLockPromotion if promotion is by lock.
+	Promote(ctx context.Context) (bool, error)
+}
+
+// Trends is implemented by Services that record finding counts for
+// manifests. Methods return trend.ErrDisabled if trends aren't configured.
+type Trends interface {
+	// Trend returns the counts recorded for a manifest between "since" and
+	// "until", oldest first. A zero time leaves that end unbounded.
+	Trend(ctx context.Context, m claircore.Digest, since, until time.Time) ([]trend.Point, error)
+}
+
+// Changelogs is implemented by Services that record a changelog for each
+// manifest. Methods return changelog.ErrDisabled if the changelog isn't
+// configured.
+type Changelogs interface {
+	// Changelog returns a page of the events recorded for a manifest, oldest
+	// first, along with a description of the next page.
+	Changelog(ctx context.Context, m claircore.Digest, page *changelog.Page) ([]changelog.Event, changelog.Page, error)
+}
+
+// Vulnstore is implemented by Services that can answer vulnerability store
+// queries on behalf of matchers without a database of their own.
+type Vulnstore interface {
+	// Vulnerabilities returns the vulnerabilities affecting each record,
+	// keyed by package ID.
+	Vulnerabilities(ctx context.Context, records []*claircore.IndexRecord, opts datastore.GetOpts) (map[string][]*claircore.Vulnerability, error)
+	// Enrichments returns the enrichment records of "kind" having any of
+	// "tags".
+	Enrichments(ctx context.Context, kind string, tags []string) ([]driver.EnrichmentRecord, error)
+}
@@ -0,0 +1,206 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_subscription", "the subscription manager")
+
+// Options configures a Manager.
+type Options struct {
+	// Indexer is used to re-index manifests and fetch index reports.
+	Indexer Indexer
+	// Scanner is used to build vulnerability reports.
+	Scanner Scanner
+	// Client is used to send callbacks. It should restrict where requests
+	// may be sent.
+	Client *http.Client
+	// CheckURL, if provided, is used to reject callback URLs when a
+	// Subscription is created.
+	CheckURL func(*url.URL) error
+	// MinInterval is the shortest interval a Subscription may request.
+	MinInterval time.Duration
+}
+
+// Manager stores Subscriptions and runs them when they're due.
+//
+// A nil Manager returns ErrDisabled from every method.
+type Manager struct {
+	pool *pgxpool.Pool
+	opts Options
+}
+
+// New returns a Manager using the provided pool, which must be connected to
+// the matcher's database.
+func New(pool *pgxpool.Pool, opts *Options) *Manager {
+	return &Manager{pool: pool, opts: *opts}
+}
+
+// Scan reads a subscription row, in the column order used by every query.
+func scan(row pgx.Row, s *Subscription) error {
+	var lastErr *string
+	if err := row.Scan(s, &s.NextRun, &s.LastRun, &lastErr); err != nil {
+		return err
+	}
+	if lastErr != nil {
+		s.LastError = *lastErr
+	}
+	return nil
+}
+
+// Subscriptions returns all Subscriptions, oldest first.
+func (m *Manager) Subscriptions(ctx context.Context) (_ []Subscription, err error) {
+	if m == nil {
+		return nil, ErrDisabled
+	}
+	const query = `SELECT subscription, next_run, last_run, last_error
+FROM matcher_subscription ORDER BY created, id;`
+	defer queryMetrics.Observe("list", &err)()
+	rows, err := m.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("subscription: unable to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+	out := []Subscription{}
+	for rows.Next() {
+		var s Subscription
+		if err = scan(rows, &s); err != nil {
+			return nil, fmt.Errorf("subscription: unable to list subscriptions: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("subscription: unable to list subscriptions: %w", err)
+	}
+	return out, nil
+}
+
+// Subscription returns the identified Subscription, reporting false if it
+// doesn't exist.
+func (m *Manager) Subscription(ctx context.Context, id uuid.UUID) (_ *Subscription, ok bool, err error) {
+	if m == nil {
+		return nil, false, ErrDisabled
+	}
+	const query = `SELECT subscription, next_run, last_run, last_error
+FROM matcher_subscription WHERE id = $1;`
+	defer queryMetrics.Observe("get", &err)()
+	var s Subscription
+	err = scan(m.pool.QueryRow(ctx, query, id), &s)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		err = nil
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("subscription: unable to get %v: %w", id, err)
+	}
+	return &s, true, nil
+}
+
+// AddSubscription creates a Subscription, assigning its ID. The first re-scan
+// is due one interval from now.
+func (m *Manager) AddSubscription(ctx context.Context, s *Subscription) (err error) {
+	if m == nil {
+		return ErrDisabled
+	}
+	const query = `INSERT INTO matcher_subscription (id, manifest, subscription, period, next_run)
+VALUES ($1, $2, $3, $4::interval, now() + $4::interval)
+RETURNING next_run;`
+	defer queryMetrics.Observe("add", &err)()
+	if err = s.Validate(m.opts.MinInterval); err != nil {
+		return err
+	}
+	if m.opts.CheckURL != nil {
+		u, _ := url.Parse(s.Callback) // Checked by Validate.
+		if err = m.opts.CheckURL(u); err != nil {
+			return fmt.Errorf("%w: callback: %v", ErrInvalid, err)
+		}
+	}
+	s.ID = uuid.New()
+	s.LastRun, s.LastError = nil, ""
+	err = m.pool.QueryRow(ctx, query,
+		s.ID, s.ManifestHash.String(), s, time.Duration(s.Interval)).
+		Scan(&s.NextRun)
+	if err != nil {
+		return fmt.Errorf("subscription: unable to store subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription removes the identified Subscription, reporting false if
+// it didn't exist.
+func (m *Manager) DeleteSubscription(ctx context.Context, id uuid.UUID) (_ bool, err error) {
+	if m == nil {
+		return false, ErrDisabled
+	}
+	const query = `DELETE FROM matcher_subscription WHERE id = $1;`
+	defer queryMetrics.Observe("delete", &err)()
+	tag, err := m.pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("subscription: unable to delete %v: %w", id, err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// Claim returns up to "n" due Subscriptions and moves their next run out by
+// their interval.
+//
+// Rows locked by another process's claim are skipped, so multiple matcher
+// processes can run Subscriptions without running any twice.
+func (m *Manager) claim(ctx context.Context, n int) (_ []Subscription, err error) {
+	const query = `UPDATE matcher_subscription s
+SET next_run = now() + s.period
+FROM (
+	SELECT id FROM matcher_subscription
+	WHERE next_run <= now()
+	ORDER BY next_run
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+) due
+WHERE s.id = due.id
+RETURNING s.subscription, s.next_run, s.last_run, s.last_error;`
+	defer queryMetrics.Observe("claim", &err)()
+	rows, err := m.pool.Query(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("subscription: unable to claim subscriptions: %w", err)
+	}
+	defer rows.Close()
+	var out []Subscription
+	for rows.Next() {
+		var s Subscription
+		if err = scan(rows, &s); err != nil {
+			return nil, fmt.Errorf("subscription: unable to claim subscriptions: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("subscription: unable to claim subscriptions: %w", err)
+	}
+	return out, nil
+}
+
+// Record notes the outcome of a re-scan.
+func (m *Manager) record(ctx context.Context, id uuid.UUID, runErr error) (err error) {
+	const query = `UPDATE matcher_subscription SET last_run = now(), last_error = $2 WHERE id = $1;`
+	defer queryMetrics.Observe("record", &err)()
+	var msg *string
+	if runErr != nil {
+		s := runErr.Error()
+		msg = &s
+	}
+	if _, err = m.pool.Exec(ctx, query, id, msg); err != nil {
+		return fmt.Errorf("subscription: unable to record run of %v: %w", id, err)
+	}
+	return nil
+}
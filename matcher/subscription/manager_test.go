@@ -0,0 +1,85 @@
+package subscription
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestManager(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	m := New(pool, &Options{MinInterval: time.Second})
+
+	s := Subscription{
+		ManifestHash: claircore.MustParseDigest(digest),
+		Interval:     Duration(time.Second),
+		Callback:     "https://example.com/hook",
+	}
+	if err := m.AddSubscription(ctx, &s); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	got, ok, err := m.Subscription(ctx, s.ID)
+	if err != nil || !ok {
+		t.Fatalf("get: got: (%v, %v)", ok, err)
+	}
+	if got.Callback != s.Callback || got.NextRun.IsZero() {
+		t.Errorf("get: got: %+v", got)
+	}
+
+	if ss, err := m.claim(ctx, claimBatch); err != nil || len(ss) != 0 {
+		t.Errorf("claim early: got: (%v, %v)", ss, err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	ss, err := m.claim(ctx, claimBatch)
+	if err != nil || len(ss) != 1 {
+		t.Fatalf("claim: got: (%v, %v)", ss, err)
+	}
+	if !ss[0].NextRun.After(got.NextRun) {
+		t.Errorf("claim: next run not moved: %v", ss[0].NextRun)
+	}
+	if err := m.record(ctx, s.ID, nil); err != nil {
+		t.Error(err)
+	}
+
+	all, err := m.Subscriptions(ctx)
+	if err != nil || len(all) != 1 || all[0].LastRun == nil {
+		t.Errorf("list: got: (%+v, %v)", all, err)
+	}
+	if ok, err := m.DeleteSubscription(ctx, s.ID); err != nil || !ok {
+		t.Errorf("delete: got: (%v, %v)", ok, err)
+	}
+}
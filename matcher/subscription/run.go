@@ -0,0 +1,131 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/dbutil"
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+var runCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "matcher_subscription",
+		Name:      "runs_total",
+		Help:      "Total number of subscription re-scans run",
+	},
+	[]string{"error"},
+)
+
+// These bound the work done for due Subscriptions.
+const (
+	// ClaimBatch is how many due Subscriptions are claimed at once.
+	claimBatch = 10
+	// RunTimeout is how long a single re-scan, including re-indexing and
+	// sending the callback, may take.
+	runTimeout = 10 * time.Minute
+)
+
+// Run runs due Subscriptions every "interval" until the Context is canceled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/subscription/Manager.Run")
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		if err := m.runDue(ctx); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to run subscriptions")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// RunDue claims and runs due Subscriptions until none are left.
+func (m *Manager) runDue(ctx context.Context) error {
+	for {
+		ss, err := m.claim(ctx, claimBatch)
+		if err != nil {
+			return err
+		}
+		for i := range ss {
+			s := &ss[i]
+			err := m.rescan(ctx, s)
+			runCounter.WithLabelValues(dbutil.ErrLabel(err)).Inc()
+			ev := zlog.Debug(ctx)
+			if err != nil {
+				ev = zlog.Info(ctx).Err(err)
+			}
+			ev.Stringer("subscription", s.ID).
+				Stringer("manifest", s.ManifestHash).
+				Msg("ran subscription")
+			if err := m.record(ctx, s.ID, err); err != nil {
+				return err
+			}
+		}
+		if len(ss) < claimBatch {
+			return nil
+		}
+	}
+}
+
+// Rescan builds a new vulnerability report for the Subscription's manifest,
+// re-indexing it first if requested, and sends it to the callback URL.
+func (m *Manager) rescan(ctx context.Context, s *Subscription) error {
+	ctx, done := context.WithTimeout(ctx, runTimeout)
+	defer done()
+	if s.Manifest != nil {
+		ir, err := m.opts.Indexer.Index(ctx, s.Manifest)
+		switch {
+		case err != nil:
+			return fmt.Errorf("unable to index manifest: %w", err)
+		case ir.Err != "":
+			return fmt.Errorf("unable to index manifest: %s", ir.Err)
+		}
+	}
+	ir, ok, err := m.opts.Indexer.IndexReport(ctx, s.ManifestHash)
+	switch {
+	case err != nil:
+		return fmt.Errorf("unable to fetch index report: %w", err)
+	case !ok:
+		return errors.New("index report not found")
+	}
+	vr, err := m.opts.Scanner.Scan(ctx, ir)
+	if err != nil {
+		return fmt.Errorf("unable to scan: %w", err)
+	}
+
+	body := codec.JSONReader(&Callback{
+		SubscriptionID:      s.ID,
+		ManifestHash:        s.ManifestHash,
+		VulnerabilityReport: vr,
+	})
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, s.Callback, body)
+	if err != nil {
+		body.Close()
+		return fmt.Errorf("unable to create callback request: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+	res, err := m.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send callback: %w", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("callback: unexpected response: %s", res.Status)
+	}
+	return nil
+}
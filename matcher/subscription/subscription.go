@@ -0,0 +1,114 @@
+// Package subscription implements scheduled re-scan subscriptions.
+//
+// A Subscription asks the matcher to periodically re-match a manifest,
+// optionally re-submitting it to the indexer first, and to send the resulting
+// vulnerability report to a callback URL. This lets clients get nightly scans
+// of their images without running something to submit requests on a
+// schedule.
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+)
+
+// Subscription is a request to periodically re-scan a manifest.
+type Subscription struct {
+	// ID is assigned when the Subscription is created.
+	ID           uuid.UUID        `json:"id"`
+	ManifestHash claircore.Digest `json:"manifest_hash"`
+	// Interval is the time between re-scans.
+	Interval Duration `json:"interval"`
+	// Callback is the URL the vulnerability report is POSTed to.
+	Callback string `json:"callback"`
+	// Manifest, if provided, is re-submitted to the indexer before each
+	// re-scan. Its hash must match ManifestHash.
+	Manifest *claircore.Manifest `json:"manifest,omitempty"`
+
+	// NextRun is when the next re-scan is due. It's set by the Manager.
+	NextRun time.Time `json:"next_run"`
+	// LastRun is when the most recent re-scan finished, if one has.
+	LastRun *time.Time `json:"last_run,omitempty"`
+	// LastError is the error from the most recent re-scan, if it failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Callback is the body sent to a Subscription's callback URL.
+type Callback struct {
+	SubscriptionID      uuid.UUID                      `json:"subscription_id"`
+	ManifestHash        claircore.Digest               `json:"manifest_hash"`
+	VulnerabilityReport *claircore.VulnerabilityReport `json:"vulnerability_report"`
+}
+
+// Duration is a time.Duration that's encoded as a string, as accepted by
+// time.ParseDuration.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+var (
+	// ErrInvalid is returned, wrapped, for Subscriptions that can't be
+	// created.
+	ErrInvalid = errors.New("invalid subscription")
+	// ErrDisabled is returned by a nil Manager, meaning subscriptions aren't
+	// configured.
+	ErrDisabled = errors.New("subscriptions not configured")
+)
+
+// Validate reports whether the Subscription is well-formed and requests an
+// interval of at least "min".
+func (s *Subscription) Validate(min time.Duration) error {
+	if s.ManifestHash.String() == "" {
+		return fmt.Errorf("%w: missing manifest_hash", ErrInvalid)
+	}
+	if time.Duration(s.Interval) < min {
+		return fmt.Errorf("%w: interval %v is under the minimum of %v",
+			ErrInvalid, time.Duration(s.Interval), min)
+	}
+	u, err := url.Parse(s.Callback)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: bad callback: %v", ErrInvalid, err)
+	case u.Scheme != "http" && u.Scheme != "https", u.Host == "":
+		return fmt.Errorf("%w: callback must be an absolute http or https URL", ErrInvalid)
+	}
+	if m := s.Manifest; m != nil && m.Hash.String() != s.ManifestHash.String() {
+		return fmt.Errorf("%w: manifest hash %q does not match manifest_hash", ErrInvalid, m.Hash)
+	}
+	return nil
+}
+
+// Indexer is the indexer functionality a Manager needs.
+type Indexer interface {
+	Index(context.Context, *claircore.Manifest) (*claircore.IndexReport, error)
+	IndexReport(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error)
+}
+
+// Scanner is the matcher functionality a Manager needs.
+type Scanner interface {
+	Scan(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+}
@@ -0,0 +1,108 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+type scanFunc func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+
+func (f scanFunc) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	return f(ctx, ir)
+}
+
+const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+func TestValidate(t *testing.T) {
+	d := claircore.MustParseDigest(digest)
+	other := claircore.MustParseDigest("sha256:1111111111111111111111111111111111111111111111111111111111111111")
+	tt := []struct {
+		Name         string
+		Subscription Subscription
+		OK           bool
+	}{
+		{Name: "OK", Subscription: Subscription{ManifestHash: d, Interval: Duration(24 * time.Hour), Callback: "https://example.com/hook"}, OK: true},
+		{Name: "Reindex", Subscription: Subscription{ManifestHash: d, Interval: Duration(24 * time.Hour), Callback: "https://example.com/hook", Manifest: &claircore.Manifest{Hash: d}}, OK: true},
+		{Name: "MissingManifest", Subscription: Subscription{Interval: Duration(24 * time.Hour), Callback: "https://example.com/hook"}},
+		{Name: "ShortInterval", Subscription: Subscription{ManifestHash: d, Interval: Duration(time.Minute), Callback: "https://example.com/hook"}},
+		{Name: "RelativeCallback", Subscription: Subscription{ManifestHash: d, Interval: Duration(24 * time.Hour), Callback: "/hook"}},
+		{Name: "BadScheme", Subscription: Subscription{ManifestHash: d, Interval: Duration(24 * time.Hour), Callback: "file:///etc/passwd"}},
+		{Name: "MismatchedManifest", Subscription: Subscription{ManifestHash: d, Interval: Duration(24 * time.Hour), Callback: "https://example.com/hook", Manifest: &claircore.Manifest{Hash: other}}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Subscription.Validate(time.Hour)
+			if tc.OK {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("got: %v, want: %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestRescan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	d := claircore.MustParseDigest(digest)
+
+	var indexed bool
+	i := &indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			indexed = true
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+		},
+	}
+	s := scanFunc(func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+		return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+	})
+	got := make(chan Callback, 1)
+	status := http.StatusNoContent
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cb Callback
+		if err := json.NewDecoder(r.Body).Decode(&cb); err != nil {
+			t.Error(err)
+		}
+		got <- cb
+		w.WriteHeader(status)
+	}))
+	srv.Config.BaseContext = func(_ net.Listener) context.Context { return ctx }
+	srv.Start()
+	defer srv.Close()
+
+	m := &Manager{opts: Options{Indexer: i, Scanner: s, Client: srv.Client()}}
+	sub := Subscription{ManifestHash: d, Callback: srv.URL, Manifest: &claircore.Manifest{Hash: d}}
+	if err := m.rescan(ctx, &sub); err != nil {
+		t.Fatal(err)
+	}
+	if !indexed {
+		t.Error("manifest not re-indexed")
+	}
+	cb := <-got
+	if cb.VulnerabilityReport == nil || cb.ManifestHash.String() != digest {
+		t.Errorf("got callback: %+v", cb)
+	}
+
+	status = http.StatusBadGateway
+	if err := m.rescan(ctx, &sub); err == nil {
+		t.Error("failed callback: unexpected success")
+	}
+	<-got
+}
@@ -0,0 +1,200 @@
+// Package trend records summarized finding counts for manifests, so
+// dashboards can show whether an image is getting better or worse without
+// archiving full vulnerability reports.
+//
+// Each time a manifest's vulnerability report is built, the number of
+// vulnerabilities of each severity is recorded against the vulnerability data
+// cursor: the most recent update operation at the time. Reports built again
+// against the same data only refresh the counts for that cursor.
+package trend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_trend", "the trend store")
+
+// ErrDisabled is returned when trends aren't configured.
+var ErrDisabled = errors.New("trend: trends not enabled")
+
+// Counts is the number of distinct vulnerabilities of each severity in a
+// vulnerability report.
+type Counts struct {
+	Unknown    int `json:"unknown"`
+	Negligible int `json:"negligible"`
+	Low        int `json:"low"`
+	Medium     int `json:"medium"`
+	High       int `json:"high"`
+	Critical   int `json:"critical"`
+}
+
+// Total is the number of vulnerabilities counted.
+func (c *Counts) Total() int {
+	return c.Unknown + c.Negligible + c.Low + c.Medium + c.High + c.Critical
+}
+
+// Summarize counts the vulnerabilities in "vr" by normalized severity.
+func Summarize(vr *claircore.VulnerabilityReport) Counts {
+	var c Counts
+	for _, v := range vr.Vulnerabilities {
+		switch v.NormalizedSeverity {
+		case claircore.Negligible:
+			c.Negligible++
+		case claircore.Low:
+			c.Low++
+		case claircore.Medium:
+			c.Medium++
+		case claircore.High:
+			c.High++
+		case claircore.Critical:
+			c.Critical++
+		default:
+			c.Unknown++
+		}
+	}
+	return c
+}
+
+// Point is the counts recorded for a manifest against one vulnerability data
+// cursor.
+type Point struct {
+	// Cursor is the reference of the most recent update operation when the
+	// counts were recorded.
+	Cursor uuid.UUID `json:"cursor"`
+	// Recorded is when a report was first built against the cursor.
+	Recorded time.Time `json:"recorded"`
+	Counts   Counts    `json:"counts"`
+	Total    int       `json:"total"`
+}
+
+// Store persists finding counts.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Latest is the subset of matcher.Differ needed to find the current cursor.
+type Latest interface {
+	LatestUpdateOperation(context.Context, driver.UpdateKind) (uuid.UUID, error)
+}
+
+// Observe records the counts for the report "vr" against the current cursor
+// reported by "l". Nothing is recorded if there's no vulnerability data yet.
+func (s *Store) Observe(ctx context.Context, l Latest, vr *claircore.VulnerabilityReport) error {
+	cur, err := l.LatestUpdateOperation(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		return fmt.Errorf("trend: unable to find latest update operation: %w", err)
+	}
+	if cur == uuid.Nil {
+		return nil
+	}
+	return s.Record(ctx, vr.Hash, cur, Summarize(vr))
+}
+
+// Record stores the counts "c" for the manifest "m" against the cursor "cur",
+// replacing any counts already recorded for the pair.
+func (s *Store) Record(ctx context.Context, m claircore.Digest, cur uuid.UUID, c Counts) (err error) {
+	const query = `INSERT INTO matcher_trend
+	(manifest, cursor, unknown, negligible, low, medium, high, critical)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (manifest, cursor) DO UPDATE
+SET unknown = EXCLUDED.unknown, negligible = EXCLUDED.negligible, low = EXCLUDED.low,
+	medium = EXCLUDED.medium, high = EXCLUDED.high, critical = EXCLUDED.critical;`
+	defer queryMetrics.Observe("record", &err)()
+	_, err = s.pool.Exec(ctx, query, m.String(), cur,
+		c.Unknown, c.Negligible, c.Low, c.Medium, c.High, c.Critical)
+	if err != nil {
+		return fmt.Errorf("trend: unable to record counts for %v: %w", m, err)
+	}
+	return nil
+}
+
+// Trend returns the counts recorded for the manifest "m" between "since" and
+// "until", oldest first. A zero time leaves that end unbounded.
+func (s *Store) Trend(ctx context.Context, m claircore.Digest, since, until time.Time) (_ []Point, err error) {
+	const query = `SELECT cursor, recorded, unknown, negligible, low, medium, high, critical
+FROM matcher_trend
+WHERE manifest = $1
+	AND ($2::timestamptz IS NULL OR recorded >= $2)
+	AND ($3::timestamptz IS NULL OR recorded <= $3)
+ORDER BY recorded, cursor;`
+	defer queryMetrics.Observe("trend", &err)()
+	var lo, hi *time.Time
+	if !since.IsZero() {
+		lo = &since
+	}
+	if !until.IsZero() {
+		hi = &until
+	}
+	rows, err := s.pool.Query(ctx, query, m.String(), lo, hi)
+	if err != nil {
+		return nil, fmt.Errorf("trend: unable to query counts: %w", err)
+	}
+	defer rows.Close()
+	out := []Point{}
+	for rows.Next() {
+		var p Point
+		c := &p.Counts
+		if err = rows.Scan(&p.Cursor, &p.Recorded,
+			&c.Unknown, &c.Negligible, &c.Low, &c.Medium, &c.High, &c.Critical); err != nil {
+			return nil, fmt.Errorf("trend: unable to read counts: %w", err)
+		}
+		p.Total = c.Total()
+		out = append(out, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("trend: unable to read counts: %w", err)
+	}
+	return out, nil
+}
+
+// Prune removes counts recorded before "before", reporting how many were
+// removed.
+func (s *Store) Prune(ctx context.Context, before time.Time) (_ int64, err error) {
+	const query = `DELETE FROM matcher_trend WHERE recorded < $1;`
+	defer queryMetrics.Observe("prune", &err)()
+	tag, err := s.pool.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("trend: unable to prune counts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Run prunes counts older than "retention" every "interval" until the Context
+// is canceled.
+func (s *Store) Run(ctx context.Context, interval, retention time.Duration) {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/trend/Store.Run")
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		n, err := s.Prune(ctx, time.Now().Add(-retention))
+		switch {
+		case err != nil:
+			zlog.Warn(ctx).Err(err).Msg("unable to prune trends")
+		case n != 0:
+			zlog.Debug(ctx).Int64("count", n).Msg("pruned trends")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package trend
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	return NewStore(pool)
+}
+
+func TestSummarize(t *testing.T) {
+	vr := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {NormalizedSeverity: claircore.Critical},
+			"2": {NormalizedSeverity: claircore.High},
+			"3": {NormalizedSeverity: claircore.High},
+			"4": {NormalizedSeverity: claircore.Low},
+			"5": {NormalizedSeverity: claircore.Unknown},
+		},
+	}
+	got := Summarize(vr)
+	want := Counts{Unknown: 1, Low: 1, High: 2, Critical: 1}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := got.Total(), 5; got != want {
+		t.Errorf("total: got: %d, want: %d", got, want)
+	}
+}
+
+type latest uuid.UUID
+
+func (l latest) LatestUpdateOperation(context.Context, driver.UpdateKind) (uuid.UUID, error) {
+	return uuid.UUID(l), nil
+}
+
+func TestStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := TestingStore(ctx, t)
+	m := claircore.MustParseDigest(`sha256:` + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	vr := &claircore.VulnerabilityReport{
+		Hash: m,
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {NormalizedSeverity: claircore.High},
+		},
+	}
+
+	// No vulnerability data yet: nothing recorded.
+	if err := s.Observe(ctx, latest(uuid.Nil), vr); err != nil {
+		t.Fatal(err)
+	}
+	first, second := uuid.New(), uuid.New()
+	if err := s.Observe(ctx, latest(first), vr); err != nil {
+		t.Fatal(err)
+	}
+	// Rebuilding against the same data replaces the counts.
+	vr.Vulnerabilities["2"] = &claircore.Vulnerability{NormalizedSeverity: claircore.Critical}
+	if err := s.Observe(ctx, latest(first), vr); err != nil {
+		t.Fatal(err)
+	}
+	delete(vr.Vulnerabilities, "1")
+	delete(vr.Vulnerabilities, "2")
+	if err := s.Observe(ctx, latest(second), vr); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := s.Trend(ctx, m, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("got: %+v", ps)
+	}
+	if got, want := ps[0].Cursor, first; got != want {
+		t.Errorf("cursor: got: %v, want: %v", got, want)
+	}
+	if got, want := ps[0].Counts, (Counts{High: 1, Critical: 1}); got != want {
+		t.Errorf("counts: got: %+v, want: %+v", got, want)
+	}
+	if got, want := ps[1].Total, 0; got != want {
+		t.Errorf("total: got: %d, want: %d", got, want)
+	}
+
+	ps, err = s.Trend(ctx, m, time.Now().Add(time.Hour), time.Time{})
+	if err != nil || len(ps) != 0 {
+		t.Errorf("since: got: (%+v, %v)", ps, err)
+	}
+
+	n, err := s.Prune(ctx, time.Now().Add(time.Hour))
+	if err != nil || n != 2 {
+		t.Errorf("prune: got: (%d, %v)", n, err)
+	}
+}
@@ -0,0 +1,88 @@
+package triage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_triage", "the triage store")
+
+// Store persists Annotations.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Annotations returns the Annotations for a manifest, ordered by
+// vulnerability and package. Expired Annotations are included.
+func (s *Store) Annotations(ctx context.Context, m claircore.Digest) (_ []Annotation, err error) {
+	const query = `SELECT annotation, updated FROM matcher_triage
+WHERE manifest = $1 ORDER BY vulnerability, package;`
+	defer queryMetrics.Observe("list", &err)()
+	rows, err := s.pool.Query(ctx, query, m.String())
+	if err != nil {
+		return nil, fmt.Errorf("triage: unable to list annotations: %w", err)
+	}
+	defer rows.Close()
+	out := []Annotation{}
+	for rows.Next() {
+		var a Annotation
+		if err = rows.Scan(&a, &a.Updated); err != nil {
+			return nil, fmt.Errorf("triage: unable to list annotations: %w", err)
+		}
+		out = append(out, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("triage: unable to list annotations: %w", err)
+	}
+	return out, nil
+}
+
+// PutAnnotation creates or replaces the Annotation for a finding. It reports
+// whether the Annotation was newly created, and sets its Updated time.
+//
+// Vulnerability names are compared case-insensitively.
+func (s *Store) PutAnnotation(ctx context.Context, m claircore.Digest, a *Annotation) (created bool, err error) {
+	const query = `INSERT INTO matcher_triage (manifest, vulnerability, package, annotation)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (manifest, vulnerability, package) DO UPDATE
+SET annotation = EXCLUDED.annotation, updated = now()
+RETURNING (xmax = 0), updated;`
+	defer queryMetrics.Observe("put", &err)()
+	if err = a.Validate(time.Now()); err != nil {
+		return false, err
+	}
+	err = s.pool.QueryRow(ctx, query,
+		m.String(), strings.ToLower(a.Vulnerability), a.Package, a).
+		Scan(&created, &a.Updated)
+	if err != nil {
+		return false, fmt.Errorf("triage: unable to store annotation for %q: %w", a.Vulnerability, err)
+	}
+	return created, nil
+}
+
+// DeleteAnnotation removes the Annotation for a finding, reporting false if it
+// didn't exist.
+func (s *Store) DeleteAnnotation(ctx context.Context, m claircore.Digest, vuln, pkg string) (_ bool, err error) {
+	const query = `DELETE FROM matcher_triage
+WHERE manifest = $1 AND vulnerability = $2 AND package = $3;`
+	defer queryMetrics.Observe("delete", &err)()
+	tag, err := s.pool.Exec(ctx, query, m.String(), strings.ToLower(vuln), pkg)
+	if err != nil {
+		return false, fmt.Errorf("triage: unable to delete annotation for %q: %w", vuln, err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
@@ -0,0 +1,80 @@
+package triage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	return NewStore(pool)
+}
+
+func TestStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := TestingStore(ctx, t)
+	m := claircore.MustParseDigest("sha256:0000000000000000000000000000000000000000000000000000000000000000")
+
+	a := Annotation{Vulnerability: "CVE-2023-0001", State: Acknowledged}
+	created, err := s.PutAnnotation(ctx, m, &a)
+	if err != nil || !created {
+		t.Fatalf("create: got: (%v, %v)", created, err)
+	}
+	a = Annotation{Vulnerability: "cve-2023-0001", State: InProgress}
+	created, err = s.PutAnnotation(ctx, m, &a)
+	if err != nil || created {
+		t.Fatalf("replace: got: (%v, %v)", created, err)
+	}
+	if _, err := s.PutAnnotation(ctx, m, &Annotation{Vulnerability: "CVE-2023-0001", State: RiskAccepted}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid: got: %v", err)
+	}
+
+	as, err := s.Annotations(ctx, m)
+	if err != nil || len(as) != 1 {
+		t.Fatalf("list: got: (%v, %v)", as, err)
+	}
+	if got := as[0]; got.State != InProgress || got.Updated.IsZero() {
+		t.Errorf("list: got: %+v", got)
+	}
+
+	if ok, err := s.DeleteAnnotation(ctx, m, "CVE-2023-0001", ""); err != nil || !ok {
+		t.Errorf("delete: got: (%v, %v)", ok, err)
+	}
+	if ok, err := s.DeleteAnnotation(ctx, m, "CVE-2023-0001", ""); err != nil || ok {
+		t.Errorf("delete again: got: (%v, %v)", ok, err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package triage implements annotations recording the triage state of
+// individual findings in a manifest's vulnerability report.
+//
+// A finding is a vulnerability, identified by name, affecting a package in a
+// manifest. Annotations are stored in the matcher database and included in
+// vulnerability reports, so that small teams can track what's been looked at
+// without a separate tool.
+package triage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/quay/claircore"
+)
+
+// State is the triage state of a finding.
+type State string
+
+// These are the known triage states.
+const (
+	// Acknowledged means the finding has been seen.
+	Acknowledged State = "acknowledged"
+	// InProgress means a fix for the finding is being worked on.
+	InProgress State = "in_progress"
+	// RiskAccepted means the finding is being tolerated until the
+	// annotation expires.
+	RiskAccepted State = "risk_accepted"
+)
+
+// MaxCommentLength is the longest comment an Annotation may carry.
+const MaxCommentLength = 4096
+
+// Annotation is the triage state of a finding.
+type Annotation struct {
+	// Vulnerability is the name of the vulnerability, such as a CVE ID.
+	Vulnerability string `json:"vulnerability"`
+	// Package, if provided, restricts the annotation to packages with this
+	// name. Otherwise, the annotation applies to every package in the
+	// manifest affected by the vulnerability.
+	Package string `json:"package,omitempty"`
+	State   State  `json:"state"`
+	Comment string `json:"comment,omitempty"`
+	// Expires, if provided, is when the annotation stops applying. It's
+	// required for the "risk_accepted" state.
+	Expires *time.Time `json:"expires,omitempty"`
+	// Updated is when the annotation was last stored. It's set by the
+	// Store.
+	Updated time.Time `json:"updated"`
+}
+
+// ErrInvalid is returned, wrapped, for Annotations that can't be stored.
+var ErrInvalid = errors.New("invalid annotation")
+
+// Validate reports whether the Annotation is well-formed, as of the time
+// "now".
+func (a *Annotation) Validate(now time.Time) error {
+	if strings.TrimSpace(a.Vulnerability) == "" {
+		return fmt.Errorf("%w: missing vulnerability", ErrInvalid)
+	}
+	switch a.State {
+	case Acknowledged, InProgress:
+	case RiskAccepted:
+		if a.Expires == nil {
+			return fmt.Errorf("%w: state %q requires an expiry", ErrInvalid, a.State)
+		}
+	default:
+		return fmt.Errorf("%w: unknown state %q", ErrInvalid, a.State)
+	}
+	if a.Expires != nil && !a.Expires.After(now) {
+		return fmt.Errorf("%w: expiry %v is in the past", ErrInvalid, a.Expires.Format(time.RFC3339))
+	}
+	if len(a.Comment) > MaxCommentLength {
+		return fmt.Errorf("%w: comment over length limit of %d", ErrInvalid, MaxCommentLength)
+	}
+	if !utf8.ValidString(a.Vulnerability) || !utf8.ValidString(a.Package) || !utf8.ValidString(a.Comment) {
+		return fmt.Errorf("%w: invalid UTF-8", ErrInvalid)
+	}
+	return nil
+}
+
+// Active reports whether the Annotation applies at the time "now".
+func (a *Annotation) Active(now time.Time) bool {
+	return a.Expires == nil || a.Expires.After(now)
+}
+
+// Apply matches the Annotations for a manifest against the findings in its
+// report, as of the time "now". The returned map is keyed by the report's
+// vulnerability IDs.
+//
+// Annotations naming a package take precedence over ones that don't. A
+// vulnerability affecting multiple packages gets the annotation found for the
+// first package, in ID order. Expired annotations are ignored.
+func Apply(vr *claircore.VulnerabilityReport, as []Annotation, now time.Time) map[string]Annotation {
+	type key struct{ vuln, pkg string }
+	idx := make(map[key]*Annotation, len(as))
+	for i := range as {
+		a := &as[i]
+		if !a.Active(now) {
+			continue
+		}
+		idx[key{strings.ToLower(a.Vulnerability), a.Package}] = a
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+
+	out := make(map[string]Annotation)
+	ids := make([]string, 0, len(vr.PackageVulnerabilities))
+	for id := range vr.PackageVulnerabilities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		pkg := vr.Packages[id]
+		if pkg == nil {
+			continue
+		}
+		for _, vid := range vr.PackageVulnerabilities[id] {
+			if _, ok := out[vid]; ok {
+				continue
+			}
+			v, ok := vr.Vulnerabilities[vid]
+			if !ok || v == nil {
+				continue
+			}
+			n := strings.ToLower(v.Name)
+			a, ok := idx[key{n, pkg.Name}]
+			if !ok {
+				a, ok = idx[key{n, ""}]
+			}
+			if ok {
+				out[vid] = *a
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package triage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	tt := []struct {
+		Name       string
+		Annotation Annotation
+		OK         bool
+	}{
+		{Name: "OK", Annotation: Annotation{Vulnerability: "CVE-2023-0001", State: Acknowledged}, OK: true},
+		{Name: "RiskAccepted", Annotation: Annotation{Vulnerability: "CVE-2023-0001", State: RiskAccepted, Expires: &later}, OK: true},
+		{Name: "MissingVulnerability", Annotation: Annotation{State: InProgress}},
+		{Name: "BadState", Annotation: Annotation{Vulnerability: "CVE-2023-0001", State: "ignored"}},
+		{Name: "NoExpiry", Annotation: Annotation{Vulnerability: "CVE-2023-0001", State: RiskAccepted}},
+		{Name: "Expired", Annotation: Annotation{Vulnerability: "CVE-2023-0001", State: RiskAccepted, Expires: &earlier}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Annotation.Validate(now)
+			if tc.OK {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("got: %v, want: %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	vr := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl"},
+			"2": {ID: "2", Name: "curl"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": {ID: "10", Name: "CVE-2023-0001"},
+			"11": {ID: "11", Name: "CVE-2023-0001"},
+			"12": {ID: "12", Name: "CVE-2023-0002"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"10", "12"},
+			"2": {"11"},
+		},
+	}
+	as := []Annotation{
+		{Vulnerability: "cve-2023-0001", State: Acknowledged},
+		{Vulnerability: "CVE-2023-0001", Package: "curl", State: InProgress},
+		{Vulnerability: "CVE-2023-0002", State: RiskAccepted, Expires: &earlier},
+	}
+	got := Apply(vr, as, now)
+	if len(got) != 2 {
+		t.Fatalf("got: %+v", got)
+	}
+	if got, want := got["10"].State, Acknowledged; got != want {
+		t.Errorf("openssl: got: %q, want: %q", got, want)
+	}
+	if got, want := got["11"].State, InProgress; got != want {
+		t.Errorf("curl: got: %q, want: %q", got, want)
+	}
+	if _, ok := got["12"]; ok {
+		t.Error("expired annotation applied")
+	}
+}
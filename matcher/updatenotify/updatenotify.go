@@ -0,0 +1,83 @@
+// Package updatenotify announces new update operations in the matcher
+// database using Postgres' LISTEN and NOTIFY.
+//
+// The matcher migrations install a trigger that notifies Channel, with the
+// updater's name as the payload, whenever an update operation is recorded.
+// Listen waits for these notifications, so a notifier can look for new update operations as
+// soon as an updater run completes instead of on its next poll.
+package updatenotify
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/quay/zlog"
+)
+
+// Channel is the notification channel update operations are announced on.
+const Channel = `clair_update_operation`
+
+// RetryDelay is how long Listen waits before reconnecting.
+const retryDelay = 10 * time.Second
+
+// Listen connects to the matcher database described by "cfg" and sends on
+// "wake" whenever an update operation is recorded, until the Context is
+// canceled. Sends don't block, so "wake" should be buffered; notifications
+// arriving while a previous one is still pending are coalesced.
+//
+// Connection failures are logged and retried. Because notifications sent
+// while disconnected are lost, Listen also sends on "wake" every time it
+// (re)connects.
+func Listen(ctx context.Context, cfg *pgx.ConnConfig, wake chan<- struct{}) error {
+	ctx = zlog.ContextWithValues(ctx, "component", "matcher/updatenotify/Listen")
+	for {
+		err := listen(ctx, cfg, wake)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		zlog.Warn(ctx).
+			Err(err).
+			Stringer("retry", retryDelay).
+			Msg("lost update notification connection")
+		t := time.NewTimer(retryDelay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Listen does one connection's worth of listening.
+func listen(ctx context.Context, cfg *pgx.ConnConfig, wake chan<- struct{}) error {
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, `LISTEN `+Channel); err != nil {
+		return err
+	}
+	zlog.Info(ctx).
+		Str("channel", Channel).
+		Msg("listening for update operations")
+	signal := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	signal()
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		zlog.Debug(ctx).
+			Str("updater", n.Payload).
+			Msg("update operation recorded")
+		signal()
+	}
+}
@@ -0,0 +1,57 @@
+package updaterctl
+
+import (
+	"context"
+
+	"github.com/quay/zlog"
+)
+
+// LockSource is the lock interface used by the updater manager.
+type LockSource interface {
+	TryLock(context.Context, string) (context.Context, context.CancelFunc)
+	Lock(context.Context, string) (context.Context, context.CancelFunc)
+	Close(context.Context) error
+}
+
+// Checker reports whether an updater is disabled.
+type checker interface {
+	IsDisabled(ctx context.Context, name string) (bool, error)
+}
+
+// Locker wraps "next" so that disabled updaters are skipped.
+//
+// The updater manager takes a lock named for each updater before running it,
+// and skips the updater if the lock can't be had. The returned LockSource
+// refuses the lock for any name disabled in the Store. The check is made on
+// every attempt, so changes take effect on the next update run. If the Store
+// can't be consulted, the lock is handed out as usual; a database problem
+// shouldn't stop updates.
+func (s *Store) Locker(next LockSource) LockSource {
+	return &gate{LockSource: next, c: s}
+}
+
+type gate struct {
+	LockSource
+	c checker
+}
+
+// TryLock implements LockSource.
+func (g *gate) TryLock(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	disabled, err := g.c.IsDisabled(ctx, name)
+	switch {
+	case err != nil:
+		zlog.Warn(ctx).
+			Err(err).
+			Str("updater", name).
+			Msg("unable to check if updater is disabled")
+	case disabled:
+		zlog.Info(ctx).
+			Str("updater", name).
+			Msg("updater disabled, skipping")
+		skipCounter.WithLabelValues(name).Inc()
+		ctx, done := context.WithCancel(ctx)
+		done()
+		return ctx, done
+	}
+	return g.LockSource.TryLock(ctx, name)
+}
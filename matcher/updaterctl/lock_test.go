@@ -0,0 +1,61 @@
+package updaterctl
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeChecker map[string]error
+
+func (f fakeChecker) IsDisabled(_ context.Context, name string) (bool, error) {
+	err, ok := f[name]
+	return ok && err == nil, err
+}
+
+type fakeLocks struct {
+	taken []string
+}
+
+func (l *fakeLocks) TryLock(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	l.taken = append(l.taken, name)
+	return context.WithCancel(ctx)
+}
+
+func (l *fakeLocks) Lock(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	return l.TryLock(ctx, name)
+}
+
+func (l *fakeLocks) Close(context.Context) error { return nil }
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	next := &fakeLocks{}
+	g := &gate{
+		LockSource: next,
+		c: fakeChecker{
+			"broken": nil,
+			"flaky":  errors.New("database unavailable"),
+		},
+	}
+	tt := []struct {
+		Name     string
+		Canceled bool
+	}{
+		{Name: "ok", Canceled: false},
+		{Name: "broken", Canceled: true},
+		{Name: "flaky", Canceled: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			lctx, done := g.TryLock(ctx, tc.Name)
+			defer done()
+			if got, want := lctx.Err() != nil, tc.Canceled; got != want {
+				t.Errorf("canceled: got: %v, want: %v", got, want)
+			}
+		})
+	}
+	if got, want := len(next.taken), 2; got != want {
+		t.Errorf("locks taken: got: %v, want: %d", next.taken, want)
+	}
+}
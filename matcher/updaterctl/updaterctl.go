@@ -0,0 +1,130 @@
+// Package updaterctl lets operators disable individual updaters at runtime,
+// so a broken upstream feed can be shut off without a redeploy.
+//
+// Disabled updaters are persisted in the matcher's database, so the setting
+// survives restarts and applies to every matcher process sharing it. A
+// disabled updater is still constructed from the configured updater sets, but
+// is skipped whenever updates run; the vulnerability data from its last
+// successful run is left in place.
+package updaterctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var (
+	queryMetrics = dbutil.NewQueryMetrics("matcher_updaterctl", "the updater control store")
+	skipCounter  = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "matcher_updaterctl",
+			Name:      "skipped_total",
+			Help:      "Total number of updater runs skipped because the updater was disabled",
+		},
+		[]string{"updater"},
+	)
+)
+
+// ErrInvalid is returned when a Disabled record is malformed.
+var ErrInvalid = errors.New("updaterctl: invalid request")
+
+// Disabled records that an updater has been disabled.
+type Disabled struct {
+	// Updater is the name of the updater, as reported in update operations.
+	Updater string `json:"updater"`
+	// Reason is a free-form note on why the updater was disabled.
+	Reason string `json:"reason,omitempty"`
+	// By identifies who disabled the updater, if known.
+	By string `json:"disabled_by,omitempty"`
+	// Since is when the updater was disabled.
+	Since time.Time `json:"since"`
+}
+
+// Store persists the set of disabled updaters.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the passed-in Pool.
+//
+// The caller should close the Pool once the Store is no longer needed.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// DisabledUpdaters returns the disabled updaters, ordered by name.
+func (s *Store) DisabledUpdaters(ctx context.Context) (_ []Disabled, err error) {
+	const query = `SELECT updater, reason, disabled_by, since FROM matcher_updater_disabled ORDER BY updater;`
+	defer queryMetrics.Observe("list", &err)()
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("updaterctl: unable to list disabled updaters: %w", err)
+	}
+	defer rows.Close()
+	out := []Disabled{}
+	for rows.Next() {
+		var d Disabled
+		if err = rows.Scan(&d.Updater, &d.Reason, &d.By, &d.Since); err != nil {
+			return nil, fmt.Errorf("updaterctl: unable to list disabled updaters: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("updaterctl: unable to list disabled updaters: %w", err)
+	}
+	return out, nil
+}
+
+// DisableUpdater disables the updater named in "d", reporting whether it was
+// newly disabled. If it was already disabled, the reason is replaced, but the
+// original time is kept. The Since member is set on return.
+func (s *Store) DisableUpdater(ctx context.Context, d *Disabled) (created bool, err error) {
+	const query = `INSERT INTO matcher_updater_disabled (updater, reason, disabled_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (updater) DO UPDATE
+SET reason = EXCLUDED.reason, disabled_by = EXCLUDED.disabled_by
+RETURNING (xmax = 0), since;`
+	defer queryMetrics.Observe("disable", &err)()
+	d.Updater = strings.TrimSpace(d.Updater)
+	if d.Updater == "" {
+		err = fmt.Errorf("%w: missing updater name", ErrInvalid)
+		return false, err
+	}
+	err = s.pool.QueryRow(ctx, query, d.Updater, d.Reason, d.By).Scan(&created, &d.Since)
+	if err != nil {
+		return false, fmt.Errorf("updaterctl: unable to disable %q: %w", d.Updater, err)
+	}
+	return created, nil
+}
+
+// EnableUpdater re-enables the named updater, reporting false if it wasn't
+// disabled.
+func (s *Store) EnableUpdater(ctx context.Context, name string) (_ bool, err error) {
+	const query = `DELETE FROM matcher_updater_disabled WHERE updater = $1;`
+	defer queryMetrics.Observe("enable", &err)()
+	tag, err := s.pool.Exec(ctx, query, name)
+	if err != nil {
+		return false, fmt.Errorf("updaterctl: unable to enable %q: %w", name, err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// IsDisabled reports whether the named updater is disabled.
+func (s *Store) IsDisabled(ctx context.Context, name string) (ok bool, err error) {
+	const query = `SELECT EXISTS(SELECT 1 FROM matcher_updater_disabled WHERE updater = $1);`
+	defer queryMetrics.Observe("check", &err)()
+	if err = s.pool.QueryRow(ctx, query, name).Scan(&ok); err != nil {
+		return false, fmt.Errorf("updaterctl: unable to check %q: %w", name, err)
+	}
+	return ok, nil
+}
@@ -0,0 +1,85 @@
+package updaterctl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore/datastore/postgres"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher/migrations"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestingStore(ctx context.Context, t testing.TB) *Store {
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := postgres.InitPostgresMatcherStore(ctx, pool, true); err != nil {
+		t.Fatalf("failed to init matcher database: %v", err)
+	}
+	if err := migrations.Init(ctx, cfg.ConnConfig); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	return NewStore(pool)
+}
+
+func TestStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := TestingStore(ctx, t)
+
+	if _, err := s.DisableUpdater(ctx, &Disabled{Updater: " "}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid: got: %v", err)
+	}
+	d := Disabled{Updater: "osv/npm", Reason: "feed truncated"}
+	created, err := s.DisableUpdater(ctx, &d)
+	if err != nil || !created || d.Since.IsZero() {
+		t.Fatalf("disable: got: (%v, %v, %+v)", created, err, d)
+	}
+	since := d.Since
+	d = Disabled{Updater: "osv/npm", Reason: "still truncated", By: "ops"}
+	created, err = s.DisableUpdater(ctx, &d)
+	if err != nil || created || !d.Since.Equal(since) {
+		t.Fatalf("disable again: got: (%v, %v, %+v)", created, err, d)
+	}
+
+	ds, err := s.DisabledUpdaters(ctx)
+	if err != nil || len(ds) != 1 {
+		t.Fatalf("list: got: (%v, %v)", ds, err)
+	}
+	if got := ds[0]; got.Reason != "still truncated" || got.By != "ops" {
+		t.Errorf("list: got: %+v", got)
+	}
+	if ok, err := s.IsDisabled(ctx, "osv/npm"); err != nil || !ok {
+		t.Errorf("check: got: (%v, %v)", ok, err)
+	}
+	if ok, err := s.IsDisabled(ctx, "alpine"); err != nil || ok {
+		t.Errorf("check other: got: (%v, %v)", ok, err)
+	}
+
+	if ok, err := s.EnableUpdater(ctx, "osv/npm"); err != nil || !ok {
+		t.Errorf("enable: got: (%v, %v)", ok, err)
+	}
+	if ok, err := s.EnableUpdater(ctx, "osv/npm"); err != nil || ok {
+		t.Errorf("enable again: got: (%v, %v)", ok, err)
+	}
+}
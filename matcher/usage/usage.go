@@ -0,0 +1,109 @@
+// Package usage reports how much a matcher is storing, for capacity planning
+// and for checking that garbage collection is keeping up.
+package usage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_usage", "the usage reporter")
+
+// Usage is a snapshot of a matcher's storage usage.
+type Usage struct {
+	// Updaters is keyed by updater name.
+	Updaters map[string]*Updater `json:"updaters"`
+	// DatabaseBytes is the size of the matcher's database.
+	DatabaseBytes int64 `json:"database_bytes"`
+}
+
+// Updater is the storage used by a single updater.
+type Updater struct {
+	Vulnerabilities  int64 `json:"vulnerabilities"`
+	Enrichments      int64 `json:"enrichments"`
+	UpdateOperations int64 `json:"update_operations"`
+}
+
+// Reporter reports a matcher's storage usage.
+type Reporter struct {
+	pool *pgxpool.Pool
+}
+
+// New returns a Reporter using the provided pool, which must be connected to
+// the matcher's database.
+func New(pool *pgxpool.Pool) *Reporter {
+	return &Reporter{pool: pool}
+}
+
+// Usage reports the matcher's storage usage.
+//
+// Vulnerabilities and enrichments no longer referenced by any update
+// operation are counted until garbage collection removes them.
+func (r *Reporter) Usage(ctx context.Context) (_ *Usage, err error) {
+	u := Usage{Updaters: make(map[string]*Updater)}
+	get := func(name string) *Updater {
+		up, ok := u.Updaters[name]
+		if !ok {
+			up = new(Updater)
+			u.Updaters[name] = up
+		}
+		return up
+	}
+	counts := []struct {
+		name  string
+		query string
+		field func(*Updater) *int64
+	}{
+		{
+			name:  "vulnerabilities",
+			query: `SELECT coalesce(updater, ''), count(*) FROM vuln GROUP BY 1;`,
+			field: func(up *Updater) *int64 { return &up.Vulnerabilities },
+		},
+		{
+			name:  "enrichments",
+			query: `SELECT coalesce(updater, ''), count(*) FROM enrichment GROUP BY 1;`,
+			field: func(up *Updater) *int64 { return &up.Enrichments },
+		},
+		{
+			name:  "update_operations",
+			query: `SELECT updater, count(*) FROM update_operation GROUP BY 1;`,
+			field: func(up *Updater) *int64 { return &up.UpdateOperations },
+		},
+	}
+	for _, c := range counts {
+		err = func() (err error) {
+			defer queryMetrics.Observe(c.name, &err)()
+			rows, err := r.pool.Query(ctx, c.query)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var name string
+				var n int64
+				if err = rows.Scan(&name, &n); err != nil {
+					return err
+				}
+				*c.field(get(name)) = n
+			}
+			return rows.Err()
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("usage: unable to count %s: %w", c.name, err)
+		}
+	}
+
+	const size = `SELECT pg_database_size(current_database());`
+	err = func() (err error) {
+		defer queryMetrics.Observe("size", &err)()
+		return r.pool.QueryRow(ctx, size).Scan(&u.DatabaseBytes)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("usage: unable to get database size: %w", err)
+	}
+	return &u, nil
+}
@@ -0,0 +1,268 @@
+// Package vulnstats summarizes the vulnerability data a matcher stores, so
+// operators can check that an updater run didn't silently lose part of its
+// feed.
+//
+// An advisory is identified by its name (e.g. a CVE or vendor advisory ID).
+// A single advisory is usually stored as several vulnerability records, one
+// per affected package and distribution.
+package vulnstats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/internal/dbutil"
+)
+
+var queryMetrics = dbutil.NewQueryMetrics("matcher_vulnstats", "the vulnerability statistics reporter")
+
+// ErrNotFound is returned when an update operation isn't known to the matcher,
+// or isn't a vulnerability update operation.
+var ErrNotFound = errors.New("vulnstats: update operation not found")
+
+// ErrMismatch is returned when the update operations being compared were
+// produced by different updaters.
+var ErrMismatch = errors.New("vulnstats: update operations are from different updaters")
+
+// DefaultLimit is the number of advisory names reported per kind of change if
+// no limit is requested.
+const DefaultLimit = 100
+
+// Snapshot describes the vulnerability data of one update operation.
+type Snapshot struct {
+	Ref  uuid.UUID `json:"ref"`
+	Date time.Time `json:"date"`
+	// Vulnerabilities is the number of vulnerability records.
+	Vulnerabilities int64 `json:"vulnerabilities"`
+	// Advisories is the number of distinct advisory names.
+	Advisories int64 `json:"advisories"`
+}
+
+// Source is the vulnerability data of a single updater.
+type Source struct {
+	Updater string   `json:"updater"`
+	Latest  Snapshot `json:"latest"`
+	// Previous is the update operation before Latest, if it's still
+	// retained.
+	Previous *Snapshot `json:"previous,omitempty"`
+}
+
+// Changes is one kind of change between two update operations.
+type Changes struct {
+	// Count is the number of advisories changed.
+	Count int64 `json:"count"`
+	// Advisories holds the names of the changed advisories, in order, up to
+	// the requested limit.
+	Advisories []string `json:"advisories"`
+}
+
+// Add records the advisory "name", keeping at most "limit" names.
+func (c *Changes) add(name string, limit int) {
+	c.Count++
+	if len(c.Advisories) < limit {
+		c.Advisories = append(c.Advisories, name)
+	}
+}
+
+// Diff describes the advisories that changed between two update operations of
+// one updater.
+type Diff struct {
+	Updater string   `json:"updater"`
+	Prev    Snapshot `json:"prev"`
+	Cur     Snapshot `json:"cur"`
+	// Added advisories are only in Cur.
+	Added Changes `json:"added"`
+	// Removed advisories are only in Prev.
+	Removed Changes `json:"removed"`
+	// Modified advisories are in both, but at least one of their records
+	// differs.
+	Modified Changes `json:"modified"`
+}
+
+// Reporter reports statistics about a matcher's vulnerability data.
+type Reporter struct {
+	pool *pgxpool.Pool
+}
+
+// New returns a Reporter using the provided pool, which must be connected to
+// the matcher's database.
+func New(pool *pgxpool.Pool) *Reporter {
+	return &Reporter{pool: pool}
+}
+
+// VulnerabilityStats reports the size of each updater's two most recent
+// vulnerability update operations, ordered by updater name.
+func (r *Reporter) VulnerabilityStats(ctx context.Context) (_ []Source, err error) {
+	const query = `WITH uo AS (
+	SELECT id, ref, updater, date, row_number() OVER (PARTITION BY updater ORDER BY id DESC) AS n
+	FROM update_operation
+	WHERE kind = 'vulnerability'
+)
+SELECT uo.updater, uo.n, uo.ref, uo.date, count(v.id), count(DISTINCT v.name)
+FROM uo
+LEFT JOIN uo_vuln ON uo_vuln.uo = uo.id
+LEFT JOIN vuln v ON v.id = uo_vuln.vuln
+WHERE uo.n <= 2
+GROUP BY uo.updater, uo.n, uo.ref, uo.date
+ORDER BY uo.updater, uo.n;`
+	defer queryMetrics.Observe("stats", &err)()
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("vulnstats: unable to query update operations: %w", err)
+	}
+	defer rows.Close()
+	var out []Source
+	for rows.Next() {
+		var name string
+		var n int
+		var s Snapshot
+		if err = rows.Scan(&name, &n, &s.Ref, &s.Date, &s.Vulnerabilities, &s.Advisories); err != nil {
+			return nil, fmt.Errorf("vulnstats: unable to read update operation: %w", err)
+		}
+		switch n {
+		case 1:
+			out = append(out, Source{Updater: name, Latest: s})
+		case 2:
+			out[len(out)-1].Previous = &s
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("vulnstats: unable to read update operations: %w", err)
+	}
+	return out, nil
+}
+
+// VulnerabilityDiff reports the advisories added, removed, and modified
+// between the update operations "prev" and "cur", listing at most "limit"
+// names of each. If "prev" is uuid.Nil, the update operation before "cur"
+// from the same updater is used.
+func (r *Reporter) VulnerabilityDiff(ctx context.Context, prev, cur uuid.UUID, limit int) (_ *Diff, err error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	var d Diff
+	var prevID, curID int64
+	if curID, d.Updater, err = r.lookup(ctx, cur, &d.Cur); err != nil {
+		return nil, err
+	}
+	if prev == uuid.Nil {
+		prevID, err = r.previous(ctx, d.Updater, curID, &d.Prev)
+	} else {
+		var name string
+		prevID, name, err = r.lookup(ctx, prev, &d.Prev)
+		if err == nil && name != d.Updater {
+			err = ErrMismatch
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range []struct {
+		id int64
+		s  *Snapshot
+	}{{prevID, &d.Prev}, {curID, &d.Cur}} {
+		if err := r.count(ctx, s.id, s.s); err != nil {
+			return nil, err
+		}
+	}
+
+	// Advisories are compared by the set of record hashes stored for them.
+	const query = `WITH
+p AS (
+	SELECT coalesce(v.name, '') AS name, array_agg(v.hash ORDER BY v.hash) AS h
+	FROM uo_vuln JOIN vuln v ON v.id = uo_vuln.vuln
+	WHERE uo_vuln.uo = $1
+	GROUP BY 1
+),
+c AS (
+	SELECT coalesce(v.name, '') AS name, array_agg(v.hash ORDER BY v.hash) AS h
+	FROM uo_vuln JOIN vuln v ON v.id = uo_vuln.vuln
+	WHERE uo_vuln.uo = $2
+	GROUP BY 1
+)
+SELECT coalesce(p.name, c.name),
+	CASE WHEN p.name IS NULL THEN 'added' WHEN c.name IS NULL THEN 'removed' ELSE 'modified' END
+FROM p FULL JOIN c ON p.name = c.name
+WHERE p.name IS NULL OR c.name IS NULL OR p.h <> c.h
+ORDER BY 1;`
+	err = func() (err error) {
+		defer queryMetrics.Observe("diff", &err)()
+		rows, err := r.pool.Query(ctx, query, prevID, curID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name, kind string
+			if err = rows.Scan(&name, &kind); err != nil {
+				return err
+			}
+			switch kind {
+			case "added":
+				d.Added.add(name, limit)
+			case "removed":
+				d.Removed.add(name, limit)
+			case "modified":
+				d.Modified.add(name, limit)
+			}
+		}
+		return rows.Err()
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("vulnstats: unable to compare update operations: %w", err)
+	}
+	return &d, nil
+}
+
+// Lookup finds the vulnerability update operation "ref", filling in the
+// Snapshot's Ref and Date.
+func (r *Reporter) lookup(ctx context.Context, ref uuid.UUID, s *Snapshot) (id int64, updater string, err error) {
+	const query = `SELECT id, updater, date FROM update_operation WHERE ref = $1 AND kind = 'vulnerability';`
+	defer queryMetrics.Observe("lookup", &err)()
+	err = r.pool.QueryRow(ctx, query, ref).Scan(&id, &updater, &s.Date)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, "", fmt.Errorf("%w: %v", ErrNotFound, ref)
+	default:
+		return 0, "", fmt.Errorf("vulnstats: unable to look up update operation: %w", err)
+	}
+	s.Ref = ref
+	return id, updater, nil
+}
+
+// Previous finds the vulnerability update operation from "updater" preceding
+// the one with ID "id".
+func (r *Reporter) previous(ctx context.Context, updater string, id int64, s *Snapshot) (prev int64, err error) {
+	const query = `SELECT id, ref, date FROM update_operation
+WHERE updater = $1 AND kind = 'vulnerability' AND id < $2
+ORDER BY id DESC LIMIT 1;`
+	defer queryMetrics.Observe("previous", &err)()
+	err = r.pool.QueryRow(ctx, query, updater, id).Scan(&prev, &s.Ref, &s.Date)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, fmt.Errorf("%w: no earlier update operation from %q", ErrNotFound, updater)
+	default:
+		return 0, fmt.Errorf("vulnstats: unable to look up update operation: %w", err)
+	}
+	return prev, nil
+}
+
+// Count fills in the Snapshot's counts for the update operation with ID "id".
+func (r *Reporter) count(ctx context.Context, id int64, s *Snapshot) (err error) {
+	const query = `SELECT count(v.id), count(DISTINCT v.name)
+FROM uo_vuln JOIN vuln v ON v.id = uo_vuln.vuln
+WHERE uo_vuln.uo = $1;`
+	defer queryMetrics.Observe("count", &err)()
+	if err = r.pool.QueryRow(ctx, query, id).Scan(&s.Vulnerabilities, &s.Advisories); err != nil {
+		return fmt.Errorf("vulnstats: unable to count vulnerabilities: %w", err)
+	}
+	return nil
+}
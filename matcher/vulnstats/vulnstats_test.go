@@ -0,0 +1,18 @@
+package vulnstats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChanges(t *testing.T) {
+	var c Changes
+	for _, n := range []string{"CVE-1", "CVE-2", "CVE-3"} {
+		c.add(n, 2)
+	}
+	want := Changes{Count: 3, Advisories: []string{"CVE-1", "CVE-2"}}
+	if !cmp.Equal(c, want) {
+		t.Error(cmp.Diff(c, want))
+	}
+}
@@ -0,0 +1,116 @@
+// Package vulnstore provides a vulnerability store backed by another
+// matcher, so matchers can be run without a database of their own.
+//
+// A matcher using a Store queries the central matcher for the vulnerabilities
+// and enrichments affecting each report it builds, and for the update
+// operations it's asked about. Updating the store is left to the central
+// matcher: everything that would write to it reports ErrReadOnly.
+package vulnstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// ErrReadOnly is reported by methods that would modify the store.
+var ErrReadOnly = errors.New("vulnstore: store is read-only")
+
+// Source is what a Store needs from the central matcher. The matcher's HTTP
+// client implements it.
+type Source interface {
+	matcher.Vulnstore
+	matcher.Differ
+}
+
+// Store is a datastore.MatcherStore that forwards queries to a Source.
+type Store struct {
+	src Source
+}
+
+var _ datastore.MatcherStore = (*Store)(nil)
+
+// New returns a Store querying "src".
+func New(src Source) *Store {
+	return &Store{src: src}
+}
+
+// Get implements datastore.Vulnerability.
+func (s *Store) Get(ctx context.Context, records []*claircore.IndexRecord, opts datastore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	return s.src.Vulnerabilities(ctx, records, opts)
+}
+
+// GetEnrichment implements datastore.Enrichment.
+func (s *Store) GetEnrichment(ctx context.Context, kind string, tags []string) ([]driver.EnrichmentRecord, error) {
+	return s.src.Enrichments(ctx, kind, tags)
+}
+
+// GetUpdateOperations implements datastore.Updater.
+func (s *Store) GetUpdateOperations(ctx context.Context, k driver.UpdateKind, updaters ...string) (map[string][]driver.UpdateOperation, error) {
+	return s.src.UpdateOperations(ctx, k, updaters...)
+}
+
+// GetLatestUpdateRefs implements datastore.Updater.
+func (s *Store) GetLatestUpdateRefs(ctx context.Context, k driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return s.src.LatestUpdateOperations(ctx, k)
+}
+
+// GetLatestUpdateRef implements datastore.Updater.
+func (s *Store) GetLatestUpdateRef(ctx context.Context, k driver.UpdateKind) (uuid.UUID, error) {
+	return s.src.LatestUpdateOperation(ctx, k)
+}
+
+// GetUpdateDiff implements datastore.Updater.
+func (s *Store) GetUpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error) {
+	return s.src.UpdateDiff(ctx, prev, cur)
+}
+
+// Initialized implements datastore.Updater.
+//
+// The central matcher answers queries whether or not it's done its first
+// update, so this always reports true.
+func (s *Store) Initialized(_ context.Context) (bool, error) {
+	return true, nil
+}
+
+// UpdateVulnerabilities implements datastore.Updater. It always reports
+// ErrReadOnly.
+func (s *Store) UpdateVulnerabilities(_ context.Context, _ string, _ driver.Fingerprint, _ []*claircore.Vulnerability) (uuid.UUID, error) {
+	return uuid.Nil, ErrReadOnly
+}
+
+// UpdateEnrichments implements datastore.Updater. It always reports
+// ErrReadOnly.
+func (s *Store) UpdateEnrichments(_ context.Context, _ string, _ driver.Fingerprint, _ []driver.EnrichmentRecord) (uuid.UUID, error) {
+	return uuid.Nil, ErrReadOnly
+}
+
+// DeleteUpdateOperations implements datastore.Updater. It always reports
+// ErrReadOnly.
+func (s *Store) DeleteUpdateOperations(_ context.Context, _ ...uuid.UUID) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+// GC implements datastore.Updater. It always reports ErrReadOnly.
+func (s *Store) GC(_ context.Context, _ int) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+// RecordUpdaterStatus implements datastore.Updater. It always reports
+// ErrReadOnly.
+func (s *Store) RecordUpdaterStatus(_ context.Context, _ string, _ time.Time, _ driver.Fingerprint, _ error) error {
+	return ErrReadOnly
+}
+
+// RecordUpdaterSetStatus implements datastore.Updater. It always reports
+// ErrReadOnly.
+func (s *Store) RecordUpdaterSetStatus(_ context.Context, _ string, _ time.Time) error {
+	return ErrReadOnly
+}
@@ -0,0 +1,187 @@
+// Package workload decodes Kubernetes manifests into the workloads they
+// describe and the images those workloads run, for reporting on what's
+// deployed to a cluster.
+package workload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quay/clair/v4/matcher/admission"
+)
+
+// Workload is a Kubernetes object that runs images.
+type Workload struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name"`
+	// Images are the distinct images run, in the order they appear.
+	Images []string `json:"images"`
+}
+
+// Image is an entry of an image list, the simple alternative to manifests:
+// an image reference and the namespace, and optionally the workload, running
+// it.
+type Image struct {
+	Image     string `json:"image"`
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload,omitempty"`
+}
+
+// Object is the part of a Kubernetes object needed to find its workloads.
+type object struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Items  []json.RawMessage `json:"items"`
+	Images []Image           `json:"images"`
+}
+
+// Decode returns the workloads described by "b", in the order they appear.
+//
+// "b" is either an object with an "images" list, or Kubernetes manifests: a
+// single object, a "List" of objects, or a stream of YAML or JSON documents
+// holding either.
+// Objects that don't run images are skipped, and objects without a
+// namespace are in "default".
+//
+// An image list groups its images by namespace and workload; images without
+// a workload are each reported as their own workload.
+func Decode(b []byte) ([]Workload, error) {
+	docs, err := documents(b)
+	if err != nil {
+		return nil, err
+	}
+	var out []Workload
+	for _, doc := range docs {
+		ws, err := decodeObject(doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ws...)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("workload: no workloads found")
+	}
+	return out, nil
+}
+
+// Documents splits "b" into JSON documents. YAML is a superset of JSON, so
+// everything is read as YAML and converted.
+func documents(b []byte) ([]json.RawMessage, error) {
+	var out []json.RawMessage
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		switch {
+		case errors.Is(err, io.EOF):
+			return out, nil
+		case err != nil:
+			return nil, fmt.Errorf("workload: %w", err)
+		case v == nil: // Empty document.
+			continue
+		}
+		doc, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("workload: %w", err)
+		}
+		out = append(out, doc)
+	}
+}
+
+func decodeObject(doc json.RawMessage) ([]Workload, error) {
+	var o object
+	if err := json.Unmarshal(doc, &o); err != nil {
+		return nil, fmt.Errorf("workload: %w", err)
+	}
+	switch {
+	case o.Images != nil:
+		return group(o.Images), nil
+	case o.Kind == "List" || (o.Items != nil && o.Kind != ""):
+		var out []Workload
+		for _, item := range o.Items {
+			ws, err := decodeObject(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ws...)
+		}
+		return out, nil
+	}
+	imgs, err := admission.Images(o.Kind, doc)
+	if err != nil {
+		return nil, fmt.Errorf("workload: %s %q: %w", o.Kind, o.Metadata.Name, err)
+	}
+	if len(imgs) == 0 {
+		return nil, nil
+	}
+	ns := o.Metadata.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	return []Workload{{
+		Namespace: ns,
+		Kind:      o.Kind,
+		Name:      o.Metadata.Name,
+		Images:    imgs,
+	}}, nil
+}
+
+// Group collects the images in an image list into workloads, ordered by
+// namespace and name.
+func group(imgs []Image) []Workload {
+	type key struct{ ns, name string }
+	idx := make(map[key]int)
+	var out []Workload
+	for _, img := range imgs {
+		if img.Image == "" {
+			continue
+		}
+		k := key{ns: img.Namespace, name: img.Workload}
+		if k.ns == "" {
+			k.ns = "default"
+		}
+		if k.name == "" {
+			k.name = img.Image
+		}
+		i, ok := idx[k]
+		if !ok {
+			i = len(out)
+			idx[k] = i
+			out = append(out, Workload{Namespace: k.ns, Name: k.name})
+		}
+		out[i].Images = append(out[i].Images, img.Image)
+	}
+	for i := range out {
+		out[i].Images = dedup(out[i].Images)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func dedup(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]struct{}, len(in))
+	for _, s := range in {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package workload
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecode(t *testing.T) {
+	tt := []struct {
+		Name string
+		In   string
+		Want []Workload
+	}{
+		{
+			Name: "Deployment",
+			In: `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"prod"},` +
+				`"spec":{"template":{"spec":{"containers":[{"image":"app"},{"image":"sidecar"}]}}}}`,
+			Want: []Workload{
+				{Namespace: "prod", Kind: "Deployment", Name: "web", Images: []string{"app", "sidecar"}},
+			},
+		},
+		{
+			Name: "YAMLStream",
+			In: `apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - image: busybox
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data: {}
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: ops
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: backup
+`,
+			Want: []Workload{
+				{Namespace: "default", Kind: "Pod", Name: "debug", Images: []string{"busybox"}},
+				{Namespace: "ops", Kind: "CronJob", Name: "backup", Images: []string{"backup"}},
+			},
+		},
+		{
+			Name: "List",
+			In: `{"apiVersion":"v1","kind":"List","items":[` +
+				`{"kind":"StatefulSet","metadata":{"name":"db","namespace":"prod"},"spec":{"template":{"spec":{"containers":[{"image":"postgres"}]}}}},` +
+				`{"kind":"Service","metadata":{"name":"db","namespace":"prod"}}]}`,
+			Want: []Workload{
+				{Namespace: "prod", Kind: "StatefulSet", Name: "db", Images: []string{"postgres"}},
+			},
+		},
+		{
+			Name: "Images",
+			In: `{"images":[` +
+				`{"image":"b","namespace":"prod","workload":"web"},` +
+				`{"image":"a","namespace":"prod","workload":"web"},` +
+				`{"image":"b","namespace":"prod","workload":"web"},` +
+				`{"image":"c","namespace":"dev"}]}`,
+			Want: []Workload{
+				{Namespace: "dev", Name: "c", Images: []string{"c"}},
+				{Namespace: "prod", Name: "web", Images: []string{"b", "a"}},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := Decode([]byte(tc.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+	for _, in := range []string{``, `{}`, `{"kind":"ConfigMap"}`, `[`} {
+		if _, err := Decode([]byte(in)); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/quay/zlog"
+)
+
+// ClientCert implements the Checker interface for requests made over a TLS
+// connection with a verified client certificate.
+//
+// The certificate chain is verified by the TLS handshake, so the server's
+// tls.Config must be set to verify client certificates for this to be
+// meaningful.
+type ClientCert struct{}
+
+// Check implements Checker.
+func (c ClientCert) Check(ctx context.Context, r *http.Request) bool {
+	_, ok := c.identify(ctx, r)
+	return ok
+}
+
+func (ClientCert) identify(ctx context.Context, r *http.Request) (*Identity, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		ctx = zlog.ContextWithValues(ctx, "component", "middleware/auth/ClientCert.identify")
+		zlog.Debug(ctx).Msg("no verified client certificate")
+		return nil, false
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	return &Identity{
+		Subject: leaf.Subject.String(),
+		Issuer:  leaf.Issuer.String(),
+	}, true
+}
@@ -18,10 +18,14 @@ type handler struct {
 }
 
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if !h.auth.Check(r.Context(), r) {
+	id, ok := check(r.Context(), h.auth, r)
+	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	if id != nil {
+		r = r.WithContext(WithIdentity(r.Context(), id))
+	}
 	h.next.ServeHTTP(w, r)
 }
 
@@ -45,12 +49,17 @@ func Handler(h http.Handler, f ...Checker) http.Handler {
 
 // Check implements Checker.
 func (a any) Check(ctx context.Context, r *http.Request) bool {
+	_, ok := a.identify(ctx, r)
+	return ok
+}
+
+func (a any) identify(ctx context.Context, r *http.Request) (*Identity, bool) {
 	for _, c := range a {
-		if ok := c.Check(ctx, r); ok {
-			return true
+		if id, ok := check(ctx, c, r); ok {
+			return id, true
 		}
 	}
-	return false
+	return nil, false
 }
 
 // Fail is a Checker that always fails.
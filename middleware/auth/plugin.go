@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/quay/zlog"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject identifies the caller.
+	Subject string `json:"sub,omitempty"`
+	// Issuer names the system that vouched for the caller.
+	Issuer string `json:"iss,omitempty"`
+	// Claims holds any other claims about the caller.
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a Context carrying "id".
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the Identity of the caller, if the request was
+// authenticated by an Authenticator.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// Authenticator is the interface implemented by authentication plugins.
+type Authenticator interface {
+	// Authenticate returns the Identity of the caller making the request.
+	//
+	// A nil Identity and nil error means the Authenticator doesn't recognize
+	// the request's credentials. An error means the credentials couldn't be
+	// checked. In either case, the request is denied unless another
+	// configured authentication method allows it.
+	Authenticate(context.Context, *http.Request) (*Identity, error)
+}
+
+// Factory constructs an Authenticator. The "cfg" function decodes the
+// plugin's configuration block into its argument.
+type Factory func(ctx context.Context, cfg func(interface{}) error) (Authenticator, error)
+
+var registry struct {
+	sync.Mutex
+	m map[string]Factory
+}
+
+// Register makes the Factory "f" available as the plugin "name".
+//
+// Register is meant to be called from the init function of a package
+// compiled into a downstream build. It panics if "name" is registered twice.
+func Register(name string, f Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.m == nil {
+		registry.m = make(map[string]Factory)
+	}
+	if _, ok := registry.m[name]; ok {
+		panic(fmt.Sprintf("auth: plugin %q registered twice", name))
+	}
+	registry.m[name] = f
+}
+
+// Plugins returns the names of the registered plugins.
+func Plugins() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	out := make([]string, 0, len(registry.m))
+	for n := range registry.m {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NewPlugin returns a Checker using the Authenticator constructed by the
+// plugin "name".
+//
+// A Handler using the returned Checker adds the caller's Identity to the
+// request Context; see IdentityFromContext.
+func NewPlugin(ctx context.Context, name string, cfg func(interface{}) error) (Checker, error) {
+	registry.Lock()
+	f, ok := registry.m[name]
+	registry.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown plugin %q", name)
+	}
+	a, err := f(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("auth: plugin %q: %w", name, err)
+	}
+	return &plugin{name: name, a: a}, nil
+}
+
+// Plugin adapts an Authenticator to a Checker.
+type plugin struct {
+	name string
+	a    Authenticator
+}
+
+// Check implements Checker.
+func (p *plugin) Check(ctx context.Context, r *http.Request) bool {
+	_, ok := p.identify(ctx, r)
+	return ok
+}
+
+func (p *plugin) identify(ctx context.Context, r *http.Request) (*Identity, bool) {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "middleware/auth/plugin.identify",
+		"plugin", p.name)
+	id, err := p.a.Authenticate(ctx, r)
+	switch {
+	case err != nil:
+		zlog.Warn(ctx).Err(err).Msg("unable to authenticate request")
+		return nil, false
+	case id == nil:
+		zlog.Debug(ctx).Msg("request not authenticated")
+		return nil, false
+	}
+	return id, true
+}
+
+// Identifier is implemented by Checkers that can report the caller's
+// Identity.
+type identifier interface {
+	identify(context.Context, *http.Request) (*Identity, bool)
+}
+
+// Check runs "c", returning the caller's Identity if "c" provides one.
+func check(ctx context.Context, c Checker, r *http.Request) (*Identity, bool) {
+	if i, ok := c.(identifier); ok {
+		return i.identify(ctx, r)
+	}
+	return nil, c.Check(ctx, r)
+}
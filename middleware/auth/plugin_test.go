@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+// HeaderAuth is a test Authenticator trusting the "x-test-user" header.
+type headerAuth struct {
+	issuer string
+}
+
+func (a *headerAuth) Authenticate(_ context.Context, r *http.Request) (*Identity, error) {
+	switch u := r.Header.Get("x-test-user"); u {
+	case "":
+		return nil, nil
+	case "broken":
+		return nil, errors.New("identity provider unavailable")
+	default:
+		return &Identity{
+			Subject: u,
+			Issuer:  a.issuer,
+			Claims:  map[string]interface{}{"groups": []string{"scanners"}},
+		}, nil
+	}
+}
+
+func init() {
+	Register("test-header", func(_ context.Context, cfg func(interface{}) error) (Authenticator, error) {
+		var c struct {
+			Issuer string `json:"issuer"`
+		}
+		if err := cfg(&c); err != nil {
+			return nil, err
+		}
+		if c.Issuer == "" {
+			return nil, errors.New("issuer required")
+		}
+		return &headerAuth{issuer: c.Issuer}, nil
+	})
+}
+
+func TestPlugin(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	decode := func(issuer string) func(interface{}) error {
+		return func(v interface{}) error {
+			v.(*struct {
+				Issuer string `json:"issuer"`
+			}).Issuer = issuer
+			return nil
+		}
+	}
+
+	if _, err := NewPlugin(ctx, "missing", decode("sso")); err == nil {
+		t.Error("unknown plugin: unexpected success")
+	}
+	if _, err := NewPlugin(ctx, "test-header", decode("")); err == nil {
+		t.Error("bad config: unexpected success")
+	}
+	p, err := NewPlugin(ctx, "test-header", decode("sso"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got *Identity
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = IdentityFromContext(r.Context())
+	}), fail{}, p)
+	tt := []struct {
+		User   string
+		Status int
+	}{
+		{User: "", Status: http.StatusUnauthorized},
+		{User: "broken", Status: http.StatusUnauthorized},
+		{User: "alice", Status: http.StatusOK},
+	}
+	for _, tc := range tt {
+		got = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if tc.User != "" {
+			req.Header.Set("x-test-user", tc.User)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.Status {
+			t.Errorf("%q: got: %d, want: %d", tc.User, rec.Code, tc.Status)
+		}
+		if tc.Status == http.StatusOK && (got == nil || got.Subject != tc.User || got.Issuer != "sso") {
+			t.Errorf("%q: got identity: %+v", tc.User, got)
+		}
+	}
+}
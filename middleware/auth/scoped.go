@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// Route is the set of requests a scope grants: requests using Method for a
+// path under Prefix.
+type Route struct {
+	Method string
+	Prefix string
+}
+
+// Scoped implements the Checker interface for tokens restricted to an
+// audience and a set of routes.
+//
+// The token's "scope" claim is a space-separated list of scope names, as in
+// RFC 8693. A request is allowed if any of the named scopes grants it.
+type Scoped struct {
+	key    []byte
+	iss    string
+	aud    []string
+	grants map[string]Route
+}
+
+// ScopedClaims are the claims in a scoped token.
+type ScopedClaims struct {
+	jwt.Claims
+	Scope string `json:"scope,omitempty"`
+}
+
+// NewScoped returns a Scoped checking tokens signed with "key" and issued by
+// "issuer". The token must name one of the audiences in "aud".
+func NewScoped(key []byte, issuer string, aud []string, grants map[string]Route) (*Scoped, error) {
+	return &Scoped{
+		key:    key,
+		iss:    issuer,
+		aud:    aud,
+		grants: grants,
+	}, nil
+}
+
+// Check implements Checker.
+func (s *Scoped) Check(_ context.Context, r *http.Request) bool {
+	ctx := zlog.ContextWithValues(r.Context(), "component", "middleware/auth/Scoped.Check")
+
+	wt, ok := fromHeader(r)
+	if !ok {
+		zlog.Debug(ctx).Msg("failed to retrieve jwt from header")
+		return false
+	}
+	tok, err := jwt.ParseSigned(wt)
+	if err != nil {
+		zlog.Debug(ctx).Err(err).Msg("failed to parse jwt")
+		return false
+	}
+	var cl ScopedClaims
+	if err := tok.Claims(s.key, &cl); err != nil {
+		zlog.Debug(ctx).Err(err).Msg("failed to parse jwt")
+		return false
+	}
+
+	ctx = zlog.ContextWithValues(ctx, "iss", cl.Issuer, "scope", cl.Scope)
+	if err := cl.ValidateWithLeeway(jwt.Expected{
+		Issuer: s.iss,
+		Time:   time.Now(),
+	}, 15*time.Second); err != nil {
+		zlog.Debug(ctx).Err(err).Msg("could not validate claims")
+		return false
+	}
+	var audOK bool
+	for _, a := range s.aud {
+		if cl.Audience.Contains(a) {
+			audOK = true
+			break
+		}
+	}
+	if !audOK {
+		zlog.Debug(ctx).Strs("aud", cl.Audience).Msg("could not verify audience")
+		return false
+	}
+
+	p := path.Clean(r.URL.Path)
+	for _, sc := range strings.Fields(cl.Scope) {
+		g, ok := s.grants[sc]
+		if !ok {
+			continue
+		}
+		root := path.Clean(g.Prefix)
+		if r.Method == g.Method && (p == root || strings.HasPrefix(p, root+"/")) {
+			return true
+		}
+	}
+	zlog.Debug(ctx).
+		Str("method", r.Method).
+		Str("path", p).
+		Msg("request not allowed by token scope")
+	return false
+}
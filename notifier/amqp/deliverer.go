@@ -2,10 +2,10 @@
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/quay/clair/config"
@@ -28,8 +28,11 @@ type Deliverer struct {
 	exchange   config.Exchange
 	rollup     int
 	direct     bool
+	ser        notifier.Serializer
 }
 
+var _ notifier.Serializable = (*Deliverer)(nil)
+
 func New(conf *config.AMQP) (*Deliverer, error) {
 	var d Deliverer
 	if err := d.load(conf); err != nil {
@@ -54,6 +57,7 @@ func (d *Deliverer) load(conf *config.AMQP) error {
 	}
 
 	// Copy everything else out of the config:
+	d.ser = notifier.JSON
 	d.direct = conf.Direct
 	d.rollup = conf.Rollup
 	d.exchange = conf.Exchange
@@ -65,6 +69,32 @@ func (d *Deliverer) load(conf *config.AMQP) error {
 			return err
 		}
 	}
+	d.fo.brokers = make(map[string]*broker, len(conf.Brokers))
+	for _, b := range conf.Brokers {
+		s := broker{
+			tls:       d.fo.tls,
+			dial:      time.Duration(b.DialTimeout),
+			handshake: time.Duration(b.HandshakeTimeout),
+		}
+		if b.TLS != nil {
+			s.tls, err = b.TLS.Config()
+			if err != nil {
+				return fmt.Errorf("broker %q: %w", b.URI, err)
+			}
+		}
+		if s.dial <= 0 {
+			s.dial = config.DefaultBrokerDialTimeout
+		}
+		if s.handshake <= 0 {
+			s.handshake = config.DefaultBrokerHandshakeTimeout
+		}
+		// Key on the normalized form, to match the parsed URIs.
+		u, err := url.Parse(b.URI)
+		if err != nil {
+			return err
+		}
+		d.fo.brokers[u.String()] = &s
+	}
 	d.fo.exchange = &d.exchange
 	return nil
 }
@@ -73,6 +103,28 @@ func (d *Deliverer) Name() string {
 	return fmt.Sprintf("amqp-%s", d.exchange.Name)
 }
 
+// Destination implements the notifier.Destinationer interface.
+func (d *Deliverer) Destination() string {
+	return d.exchange.Name + "/" + d.routingKey
+}
+
+// SetSerializer implements notifier.Serializable.
+func (d *Deliverer) SetSerializer(s notifier.Serializer) {
+	d.ser = s
+}
+
+// Probe implements the notifier.Prober interface.
+//
+// A successful probe means a broker accepted a connection and the configured
+// exchange exists.
+func (d *Deliverer) Probe(ctx context.Context) error {
+	conn, err := d.fo.Connection(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
 func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	conn, err := d.fo.Connection(ctx)
 	if err != nil {
@@ -93,12 +145,12 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 		NotificationID: nID,
 		Callback:       callback,
 	}
-	b, err := json.Marshal(&cb)
+	b, err := d.ser.Callback(ctx, &cb)
 	if err != nil {
 		return &clairerror.ErrDeliveryFailed{err}
 	}
 	msg := samqp.Publishing{
-		ContentType: "application/json",
+		ContentType: d.ser.ContentType(),
 		AppId:       "clairV4-notifier",
 		Body:        b,
 	}
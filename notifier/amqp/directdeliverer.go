@@ -1,9 +1,7 @@
 package amqp
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -78,11 +76,8 @@ func (d *DirectDeliverer) Deliver(ctx context.Context, _ uuid.UUID) error {
 		rollup++
 	}
 
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
 	var currentBlock []notifier.Notification
 	for bs, be := 0, rollup; bs < len(d.n); bs, be = be, be+rollup {
-		buf.Reset()
 		// if block-end exceeds array bounds, slice block underflow.
 		// next block-start will cause loop to exit.
 		if be > len(d.n) {
@@ -90,15 +85,15 @@ func (d *DirectDeliverer) Deliver(ctx context.Context, _ uuid.UUID) error {
 		}
 
 		currentBlock = d.n[bs:be]
-		err := enc.Encode(&currentBlock)
+		b, err := d.ser.Notifications(ctx, currentBlock)
 		if err != nil {
 			ch.TxRollback()
 			return &clairerror.ErrDeliveryFailed{err}
 		}
 		msg := samqp.Publishing{
-			ContentType: "application/json",
+			ContentType: d.ser.ContentType(),
 			AppId:       "clairV4-notifier",
-			Body:        buf.Bytes(),
+			Body:        b,
 		}
 		err = ch.Publish(
 			d.exchange.Name,
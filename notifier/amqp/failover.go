@@ -4,8 +4,10 @@
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/quay/clair/config"
 	"github.com/quay/zlog"
@@ -22,6 +24,55 @@ type failOver struct {
 	tls      *tls.Config
 	exchange *config.Exchange
 	uris     []*url.URL
+	// Brokers holds per-broker settings, keyed by the URI as configured.
+	brokers map[string]*broker
+}
+
+// Broker holds the connection settings for a single broker.
+type broker struct {
+	tls       *tls.Config
+	dial      time.Duration
+	handshake time.Duration
+}
+
+// Dial is a dial function for use in a samqp.Config.
+//
+// The returned connection has a deadline set to bound the TLS and AMQP
+// handshakes. The samqp package clears it once the connection is open.
+func (b *broker) Dial(network, addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, addr, b.dial)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(b.handshake)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Config returns the samqp.Config to use for the broker at "uri".
+func (f *failOver) config(uri *url.URL) samqp.Config {
+	b, ok := f.brokers[uri.String()]
+	if !ok {
+		b = &broker{
+			tls:       f.tls,
+			dial:      config.DefaultBrokerDialTimeout,
+			handshake: config.DefaultBrokerHandshakeTimeout,
+		}
+	}
+	cfg := samqp.Config{
+		// These match the defaults used by samqp.DialTLS.
+		Heartbeat: 10 * time.Second,
+		Locale:    "en_US",
+		Dial:      b.Dial,
+	}
+	// The samqp package modifies the TLS config to set the server name, so
+	// hand it a copy.
+	if b.tls != nil {
+		cfg.TLSClientConfig = b.tls.Clone()
+	}
+	return cfg
 }
 
 // Connection returns an AMQP connection to the first broker which successfully
@@ -41,9 +92,8 @@ func (f *failOver) Connection(ctx context.Context) (*samqp.Connection, error) {
 
 	for _, uri := range f.uris {
 		ctx := zlog.ContextWithValues(ctx, "broker", uri.String())
-		// safe to always call DialTLS per docs:
-		// 'DialTLS will use the provided tls.Config when it encounters an amqps:// scheme and will dial a plain connection when it encounters an amqp:// scheme.'
-		conn, err := samqp.DialTLS(uri.String(), f.tls)
+		// The TLS config is only used when the URI has an amqps:// scheme.
+		conn, err := samqp.DialConfig(uri.String(), f.config(uri))
 		if err != nil {
 			if conn != nil {
 				conn.Close()
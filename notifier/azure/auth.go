@@ -0,0 +1,164 @@
+package azure
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/clair/config"
+
+	"github.com/quay/clair/v4/internal/httputil"
+)
+
+// Authorizer returns the value of the Authorization header for a request to
+// "resource".
+type authorizer interface {
+	authorize(ctx context.Context, resource string) (string, error)
+}
+
+// SharedAccessKey signs requests with a shared access signature.
+type sharedAccessKey struct {
+	name string
+	key  []byte
+	// Now is overridden in tests.
+	now func() time.Time
+}
+
+// SasLifetime is how long a generated signature is valid for. Signatures are
+// generated per request, so this only needs to cover clock skew and the
+// request itself.
+const sasLifetime = time.Hour
+
+func newSharedAccessKey(cfg *config.AzureSharedAccessKey) *sharedAccessKey {
+	return &sharedAccessKey{
+		name: cfg.Name,
+		key:  []byte(cfg.Key),
+		now:  time.Now,
+	}
+}
+
+func (s *sharedAccessKey) authorize(_ context.Context, resource string) (string, error) {
+	sr := url.QueryEscape(strings.ToLower(resource))
+	exp := strconv.FormatInt(s.now().Add(sasLifetime).Unix(), 10)
+	h := hmac.New(sha256.New, s.key)
+	io.WriteString(h, sr+"\n"+exp)
+	sig := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	v := url.Values{
+		"sr":  {strings.ToLower(resource)},
+		"sig": {sig},
+		"se":  {exp},
+		"skn": {s.name},
+	}
+	return "SharedAccessSignature " + v.Encode(), nil
+}
+
+// These are the endpoints managed identity tokens are requested from.
+const (
+	// ImdsEndpoint is the instance metadata service of virtual machines and
+	// AKS nodes.
+	imdsEndpoint = `http://169.254.169.254/metadata/identity/oauth2/token`
+	// TokenAudience is the resource tokens are requested for; it's the same
+	// for Service Bus and Event Hubs.
+	tokenAudience = `https://servicebus.azure.net/`
+)
+
+// ManagedIdentity authorizes requests with tokens for a managed identity.
+//
+// Tokens are requested from the App Service and Container Apps identity
+// endpoint if the environment advertises one, and from the instance metadata
+// service otherwise. Tokens are cached until shortly before they expire.
+type managedIdentity struct {
+	// Client is used only for token requests. The token endpoints are
+	// link-local or loopback, so the notifier's guarded client can't reach
+	// them, and they must never be proxied.
+	client   *http.Client
+	clientID string
+	endpoint string
+	header   string // App Service identity header; empty for IMDS.
+	now      func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newManagedIdentity(cfg *config.AzureManagedIdentity) *managedIdentity {
+	m := managedIdentity{
+		client: &http.Client{
+			Transport: &http.Transport{Proxy: nil},
+			Timeout:   30 * time.Second,
+		},
+		clientID: cfg.ClientID,
+		endpoint: imdsEndpoint,
+		now:      time.Now,
+	}
+	if ep, h := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER"); ep != "" && h != "" {
+		m.endpoint, m.header = ep, h
+	}
+	return &m
+}
+
+func (m *managedIdentity) authorize(ctx context.Context, _ string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Refresh ahead of expiry so a token doesn't lapse mid-delivery.
+	if m.token != "" && m.now().Add(5*time.Minute).Before(m.expires) {
+		return "Bearer " + m.token, nil
+	}
+
+	v := url.Values{"resource": {tokenAudience}}
+	if m.header != "" {
+		v.Set("api-version", "2019-08-01")
+	} else {
+		v.Set("api-version", "2018-02-01")
+	}
+	if m.clientID != "" {
+		v.Set("client_id", m.clientID)
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, m.endpoint+"?"+v.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if m.header != "" {
+		req.Header.Set("x-identity-header", m.header)
+	} else {
+		req.Header.Set("metadata", "true")
+	}
+	res, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("managed identity: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+		return "", fmt.Errorf("managed identity: unexpected response: %s", res.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		// ExpiresOn is a string of seconds since the epoch.
+		ExpiresOn json.Number `json:"expires_on"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&tok); err != nil {
+		return "", fmt.Errorf("managed identity: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("managed identity: empty token")
+	}
+	exp, err := tok.ExpiresOn.Int64()
+	if err != nil {
+		return "", fmt.Errorf("managed identity: bad expiry %q", tok.ExpiresOn)
+	}
+	m.token, m.expires = tok.AccessToken, time.Unix(exp, 0)
+	return "Bearer " + m.token, nil
+}
@@ -0,0 +1,252 @@
+// Package azure sends notifications to an Azure Service Bus queue or topic, or
+// to an Azure Event Hub, using the services' REST interfaces.
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer sends the notifications in a notification set as messages.
+//
+// Consecutive notifications sharing a session ID and partition key are sent
+// in a single batch request. If any batch fails the whole set is retried, so
+// consumers may see a notification more than once; every message's ID is the
+// notification ID, so Service Bus duplicate detection can drop the repeats.
+type Deliverer struct {
+	c        *http.Client
+	auth     authorizer
+	resource string
+	send     *url.URL
+	eventHub bool
+	session  *template.Template
+	key      *template.Template
+	batch    int
+	n        []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+)
+
+// New returns a new Azure Deliverer.
+func New(conf *config.Azure, client *http.Client) (*Deliverer, error) {
+	switch {
+	case conf == nil:
+		return nil, errors.New("config not provided")
+	case client == nil:
+		return nil, errors.New("http client not provided")
+	}
+	d := Deliverer{
+		c:        client,
+		resource: fmt.Sprintf("https://%s/%s", conf.Namespace, conf.Entity),
+		eventHub: conf.EventHub,
+		batch:    conf.BatchSize,
+	}
+	if d.batch <= 0 {
+		d.batch = config.DefaultAzureBatchSize
+	}
+	var err error
+	d.send, err = url.Parse(d.resource + "/messages")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case conf.SharedAccessKey != nil:
+		d.auth = newSharedAccessKey(conf.SharedAccessKey)
+	case conf.ManagedIdentity != nil:
+		d.auth = newManagedIdentity(conf.ManagedIdentity)
+	default:
+		return nil, errors.New("no credentials configured")
+	}
+	if conf.SessionID != "" {
+		if d.session, err = template.New("session_id").Parse(conf.SessionID); err != nil {
+			return nil, fmt.Errorf("invalid session_id template: %w", err)
+		}
+	}
+	key := conf.PartitionKey
+	switch {
+	case key != "":
+	case conf.SessionID != "":
+		key = conf.SessionID
+	default:
+		key = config.DefaultAzurePartitionKey
+	}
+	if d.key, err = template.New("partition_key").Parse(key); err != nil {
+		return nil, fmt.Errorf("invalid partition_key template: %w", err)
+	}
+	return &d, nil
+}
+
+func (d *Deliverer) Name() string {
+	if d.eventHub {
+		return "azure-eventhub"
+	}
+	return "azure-servicebus"
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.resource
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Message is a message in a batch send request.
+type message struct {
+	Body             string            `json:"Body"`
+	BrokerProperties brokerProperties  `json:"BrokerProperties"`
+	UserProperties   map[string]string `json:"UserProperties,omitempty"`
+}
+
+type brokerProperties struct {
+	MessageID    string `json:"MessageId,omitempty"`
+	SessionID    string `json:"SessionId,omitempty"`
+	PartitionKey string `json:"PartitionKey,omitempty"`
+	ContentType  string `json:"ContentType,omitempty"`
+}
+
+// Deliver implements notifier.Deliverer.
+//
+// Deliver sends the buffered notifications in batches.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/azure/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	var batch []message
+	var batches int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := d.post(ctx, batch); err != nil {
+			return err
+		}
+		batches++
+		batch = batch[:0]
+		return nil
+	}
+	for i := range d.n {
+		m, err := d.message(&d.n[i], nID)
+		if err != nil {
+			return err
+		}
+		if len(batch) != 0 {
+			prev := &batch[len(batch)-1].BrokerProperties
+			if len(batch) == d.batch ||
+				prev.SessionID != m.BrokerProperties.SessionID ||
+				prev.PartitionKey != m.BrokerProperties.PartitionKey {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		batch = append(batch, m)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	zlog.Info(ctx).
+		Str("destination", d.resource).
+		Int("count", len(d.n)).
+		Int("batches", batches).
+		Msg("sent notifications")
+	return nil
+}
+
+// Message builds the message for "n".
+func (d *Deliverer) message(n *notifier.Notification, set uuid.UUID) (message, error) {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return message{}, err
+	}
+	m := message{
+		Body: string(b),
+		BrokerProperties: brokerProperties{
+			ContentType: "application/json",
+		},
+		UserProperties: map[string]string{
+			"notification_set": set.String(),
+			"reason":           string(n.Reason),
+			"severity":         n.Vulnerability.Severity,
+		},
+	}
+	if !d.eventHub {
+		// Event Hubs ignores these.
+		m.BrokerProperties.MessageID = n.ID.String()
+	}
+	var sb strings.Builder
+	if d.session != nil {
+		if err := d.session.Execute(&sb, n); err != nil {
+			return message{}, fmt.Errorf("notification %v: unable to execute session_id template: %w", n.ID, err)
+		}
+		m.BrokerProperties.SessionID = sb.String()
+		sb.Reset()
+	}
+	if err := d.key.Execute(&sb, n); err != nil {
+		return message{}, fmt.Errorf("notification %v: unable to execute partition_key template: %w", n.ID, err)
+	}
+	m.BrokerProperties.PartitionKey = sb.String()
+	return m, nil
+}
+
+// Post sends a batch of messages.
+//
+// Failed requests and unsuccessful responses are reported as
+// clairerror.ErrDeliveryFailed.
+func (d *Deliverer) post(ctx context.Context, batch []message) error {
+	auth, err := d.auth.authorize(ctx, d.resource)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, d.send.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", auth)
+	req.Header.Set("content-type", "application/vnd.microsoft.servicebus.json")
+	res, err := d.c.Do(req)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	if res.StatusCode != http.StatusCreated {
+		return &clairerror.ErrDeliveryFailed{
+			E: &clairerror.ErrRequestFail{
+				Code:   res.StatusCode,
+				Status: res.Status,
+			},
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+var (
+	manifestA = claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	manifestB = claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+)
+
+func notifications() []notifier.Notification {
+	return []notifier.Notification{
+		{ID: uuid.New(), Manifest: manifestA, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3711", Severity: "High"}},
+		{ID: uuid.New(), Manifest: manifestA, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3712", Severity: "Medium"}},
+		{ID: uuid.New(), Manifest: manifestB, Reason: notifier.Removed, Vulnerability: notifier.VulnSummary{Name: "CVE-2020-1971", Severity: "Medium"}},
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var mu sync.Mutex
+	var batches [][]message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("authorization"), "SharedAccessSignature ") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got, want := r.Header.Get("content-type"), "application/vnd.microsoft.servicebus.json"; got != want {
+			t.Errorf("content-type: got: %q, want: %q", got, want)
+		}
+		var b []message
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		batches = append(batches, b)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	d, err := New(&config.Azure{
+		Namespace:       "example.servicebus.windows.net",
+		Entity:          "clair",
+		SharedAccessKey: &config.AzureSharedAccessKey{Name: "send", Key: "secret"},
+		SessionID:       "{{.Manifest}}",
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.send, _ = url.Parse(srv.URL + "/clair/messages")
+	ns := notifications()
+	d.Notifications(ctx, ns)
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The notifications are split by manifest, as that's the session ID.
+	if got, want := len(batches), 2; got != want {
+		t.Fatalf("got: %d batches, want: %d", got, want)
+	}
+	if got, want := len(batches[0]), 2; got != want {
+		t.Errorf("first batch: got: %d messages, want: %d", got, want)
+	}
+	m := batches[1][0]
+	if got, want := m.BrokerProperties.MessageID, ns[2].ID.String(); got != want {
+		t.Errorf("message id: got: %q, want: %q", got, want)
+	}
+	if got, want := m.BrokerProperties.SessionID, manifestB.String(); got != want {
+		t.Errorf("session id: got: %q, want: %q", got, want)
+	}
+	if got, want := m.BrokerProperties.PartitionKey, manifestB.String(); got != want {
+		t.Errorf("partition key: got: %q, want: %q", got, want)
+	}
+	var n notifier.Notification
+	if err := json.Unmarshal([]byte(m.Body), &n); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n.ID, ns[2].ID; got != want {
+		t.Errorf("body: got: %v, want: %v", got, want)
+	}
+}
+
+func TestDeliverBatchSize(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	d, err := New(&config.Azure{
+		Namespace:       "example.servicebus.windows.net",
+		Entity:          "clair",
+		EventHub:        true,
+		SharedAccessKey: &config.AzureSharedAccessKey{Name: "send", Key: "secret"},
+		PartitionKey:    "clair",
+		BatchSize:       1,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.send, _ = url.Parse(srv.URL)
+	d.Notifications(ctx, notifications())
+	if err := d.Deliver(ctx, uuid.New()); err == nil {
+		t.Error("expected error")
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+}
+
+func TestSharedAccessSignature(t *testing.T) {
+	ctx := context.Background()
+	s := newSharedAccessKey(&config.AzureSharedAccessKey{Name: "send", Key: "secret"})
+	s.now = func() time.Time { return time.Unix(1000, 0) }
+	got, err := s.authorize(ctx, "https://example.servicebus.windows.net/clair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := url.ParseQuery(strings.TrimPrefix(got, "SharedAccessSignature "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.Get("se"), fmt.Sprint(1000+int64(sasLifetime/time.Second)); got != want {
+		t.Errorf("expiry: got: %q, want: %q", got, want)
+	}
+	if got, want := v.Get("skn"), "send"; got != want {
+		t.Errorf("key name: got: %q, want: %q", got, want)
+	}
+	if got, want := v.Get("sr"), "https://example.servicebus.windows.net/clair"; got != want {
+		t.Errorf("resource: got: %q, want: %q", got, want)
+	}
+	if v.Get("sig") == "" {
+		t.Error("missing signature")
+	}
+}
+
+func TestManagedIdentity(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got, want := r.URL.Query().Get("client_id"), "identity"; got != want {
+			t.Errorf("client id: got: %q, want: %q", got, want)
+		}
+		fmt.Fprintf(w, `{"access_token":"token","expires_on":"%d"}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer srv.Close()
+	m := newManagedIdentity(&config.AzureManagedIdentity{ClientID: "identity"})
+	m.endpoint, m.header = srv.URL, ""
+	for i := 0; i < 2; i++ {
+		got, err := m.authorize(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Bearer token"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("got: %d token requests, want: %d", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	deliveryBatch = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "delivery_batch_size",
+			Help:      "Maximum number of notification sets attempted per delivery run.",
+		},
+	)
+	deliveryLatency = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "delivery_latency_average_seconds",
+			Help:      "Moving average of notification delivery latency.",
+		},
+	)
+	pollPaused = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "poll_paused",
+			Help:      "Whether polling is paused because notifications are awaiting delivery.",
+		},
+	)
+)
+
+// Backpressure sizes delivery batches according to recent delivery latency,
+// and reports when polling should pause because undelivered notifications are
+// piling up.
+//
+// The batch size is halved whenever the moving average latency exceeds the
+// target and grows by one otherwise, so a slow destination quickly limits the
+// amount of work in flight.
+//
+// A Backpressure is safe for concurrent use. A nil *Backpressure never limits
+// delivery or pauses polling.
+type Backpressure struct {
+	max        int
+	maxPending int
+	target     time.Duration
+
+	mu      sync.Mutex
+	batch   int
+	latency time.Duration
+	pending int
+}
+
+// NewBackpressure returns a Backpressure attempting at most "maxBatch"
+// notification sets per delivery run, shrinking batches while deliveries
+// average longer than "target", and pausing polling while more than
+// "maxPending" notification sets are awaiting delivery.
+//
+// A non-positive "maxBatch" or "maxPending" disables the respective limit.
+func NewBackpressure(maxBatch int, target time.Duration, maxPending int) *Backpressure {
+	b := &Backpressure{
+		max:        maxBatch,
+		maxPending: maxPending,
+		target:     target,
+		batch:      maxBatch,
+	}
+	deliveryBatch.Set(float64(b.batch))
+	return b
+}
+
+// Batch reports the number of notification sets a delivery run should
+// attempt. A value of 0 means there's no limit.
+func (b *Backpressure) Batch() int {
+	if b == nil || b.max <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch
+}
+
+// Observe records the latency of a delivery attempt and resizes the batch.
+func (b *Backpressure) Observe(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Exponentially weighted, so that a few fast deliveries don't hide a
+	// destination that's falling over.
+	if b.latency == 0 {
+		b.latency = d
+	} else {
+		b.latency += (d - b.latency) / 5
+	}
+	deliveryLatency.Set(b.latency.Seconds())
+	if b.max <= 0 {
+		return
+	}
+	switch {
+	case b.target > 0 && b.latency > b.target:
+		b.batch /= 2
+		if b.batch < 1 {
+			b.batch = 1
+		}
+	case b.batch < b.max:
+		b.batch++
+	}
+	deliveryBatch.Set(float64(b.batch))
+}
+
+// SetPending records the number of notification sets awaiting delivery.
+func (b *Backpressure) SetPending(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = n
+}
+
+// Paused reports whether polling should pause.
+func (b *Backpressure) Paused() bool {
+	if b == nil || b.maxPending <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.pending > b.maxPending
+	if p {
+		pollPaused.Set(1)
+	} else {
+		pollPaused.Set(0)
+	}
+	return p
+}
@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackpressure(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var b *Backpressure
+		b.Observe(time.Hour)
+		b.SetPending(1 << 20)
+		if got := b.Batch(); got != 0 {
+			t.Errorf("batch: got: %d, want: 0", got)
+		}
+		if b.Paused() {
+			t.Error("paused: got: true, want: false")
+		}
+	})
+	t.Run("Batch", func(t *testing.T) {
+		b := NewBackpressure(8, time.Second, 0)
+		if got, want := b.Batch(), 8; got != want {
+			t.Errorf("initial: got: %d, want: %d", got, want)
+		}
+		b.Observe(10 * time.Second)
+		if got, want := b.Batch(), 4; got != want {
+			t.Errorf("slow: got: %d, want: %d", got, want)
+		}
+		for i := 0; i < 10; i++ {
+			b.Observe(10 * time.Second)
+		}
+		if got, want := b.Batch(), 1; got != want {
+			t.Errorf("very slow: got: %d, want: %d", got, want)
+		}
+		for i := 0; i < 100; i++ {
+			b.Observe(time.Millisecond)
+		}
+		if got, want := b.Batch(), 8; got != want {
+			t.Errorf("recovered: got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("Unlimited", func(t *testing.T) {
+		b := NewBackpressure(-1, time.Second, 0)
+		b.Observe(10 * time.Second)
+		if got := b.Batch(); got != 0 {
+			t.Errorf("batch: got: %d, want: 0", got)
+		}
+	})
+	t.Run("Paused", func(t *testing.T) {
+		b := NewBackpressure(8, time.Second, 10)
+		b.SetPending(10)
+		if b.Paused() {
+			t.Error("at limit: got: paused, want: not paused")
+		}
+		b.SetPending(11)
+		if !b.Paused() {
+			t.Error("over limit: got: not paused, want: paused")
+		}
+		b.SetPending(0)
+		if b.Paused() {
+			t.Error("drained: got: paused, want: not paused")
+		}
+	})
+}
@@ -26,3 +26,16 @@ type Deliverer interface {
 type DirectDeliverer interface {
 	Notifications(ctx context.Context, n []Notification) error
 }
+
+// Destinationer is an optional interface a Deliverer may implement to report
+// where notifications are sent. This is used to label delivery metrics, so it
+// must not contain credentials.
+type Destinationer interface {
+	Destination() string
+}
+
+// Prober is an optional interface a Deliverer may implement to check that its
+// destination is reachable without delivering anything.
+type Prober interface {
+	Probe(context.Context) error
+}
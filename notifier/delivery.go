@@ -6,11 +6,50 @@
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/quay/zlog"
 
 	clairerror "github.com/quay/clair/v4/clair-error"
 )
 
+var (
+	deliveryCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "delivery_total",
+			Help:      "Total number of notification delivery attempts.",
+		},
+		[]string{"deliverer", "destination", "status"},
+	)
+	deliveryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "delivery_duration_seconds",
+			Help:      "Duration of notification delivery attempts.",
+		},
+		[]string{"deliverer", "destination", "status"},
+	)
+	deliveryPending = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "delivery_pending",
+			Help:      "Number of notification sets awaiting delivery, as of the last delivery run.",
+		},
+		[]string{"deliverer", "status"},
+	)
+)
+
+// Delivery status label values.
+const (
+	deliveryOK     = "delivered"
+	deliveryFailed = "failed"
+	deliveryError  = "error"
+)
+
 // Delivery handles the business logic of delivering
 // notifications.
 type Delivery struct {
@@ -22,6 +61,18 @@ type Delivery struct {
 	locks Locker
 	// the interval at which we will attempt delivery of notifications.
 	interval time.Duration
+	// Backpressure limits the notifications attempted per run, if not nil.
+	Backpressure *Backpressure
+	// Retry delays attempting notifications that failed to be delivered, if
+	// not nil.
+	Retry *Retry
+	// Ordered delivers the notification sets mentioning a manifest in the
+	// order they were created: a set isn't attempted until every earlier set
+	// mentioning one of its manifests has been delivered. Sets that don't
+	// share manifests are still delivered concurrently.
+	//
+	// The store must be a Sequencer.
+	Ordered bool
 }
 
 func NewDelivery(store Store, l Locker, d Deliverer, interval time.Duration) *Delivery {
@@ -76,6 +127,7 @@ func (d *Delivery) RunDelivery(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	deliveryPending.WithLabelValues(d.Deliverer.Name(), "created").Set(float64(len(created)))
 	if sz := len(created); sz != 0 {
 		zlog.Info(ctx).
 			Int("created", sz).
@@ -88,37 +140,145 @@ func (d *Delivery) RunDelivery(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	deliveryPending.WithLabelValues(d.Deliverer.Name(), "failed").Set(float64(len(failed)))
 	if sz := len(failed); sz != 0 {
 		zlog.Info(ctx).
 			Int("failed", sz).
 			Msg("notification ids in failed status")
 		toDeliver = append(toDeliver, failed...)
 	}
+	var seq []Sequenced
+	if d.Ordered {
+		seq, err = d.sequence(ctx, toDeliver)
+		if err != nil {
+			return err
+		}
+		toDeliver = toDeliver[:0]
+		for i := range seq {
+			toDeliver = append(toDeliver, seq[i].ID)
+		}
+	}
+	d.Backpressure.SetPending(len(toDeliver))
+	pending := len(toDeliver)
+	toDeliver, states, err := d.Retry.due(ctx, d.store, toDeliver)
+	if err != nil {
+		return err
+	}
+	waiting := pending - len(toDeliver)
+	deliveryPending.WithLabelValues(d.Deliverer.Name(), "backoff").Set(float64(waiting))
+	if waiting != 0 {
+		zlog.Info(ctx).
+			Int("waiting", waiting).
+			Msg("notification ids waiting to be retried")
+	}
+	if n := d.Backpressure.Batch(); n > 0 && len(toDeliver) > n {
+		zlog.Info(ctx).
+			Int("pending", len(toDeliver)).
+			Int("batch", n).
+			Msg("delivery backlogged. attempting a partial batch")
+		toDeliver = toDeliver[:n]
+	}
 
+	if d.Ordered {
+		return d.runOrdered(ctx, seq, toDeliver, states)
+	}
 	for _, nID := range toDeliver {
-		var err error
-		ctx, done := d.locks.TryLock(ctx, nID.String())
-		if ok := ctx.Err(); !errors.Is(ok, nil) {
-			zlog.Debug(ctx).
-				Err(ok).
-				Stringer("notification_id", nID).
-				Msg("unable to get lock")
-		} else {
-			err = d.do(ctx, nID)
+		if _, err := d.attempt(ctx, nID, states[nID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sequence orders the notification IDs "ids" by creation and reports the
+// manifests each mentions.
+func (d *Delivery) sequence(ctx context.Context, ids []uuid.UUID) ([]Sequenced, error) {
+	s, ok := d.store.(Sequencer)
+	if !ok {
+		return nil, errors.New("store does not support ordered delivery")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.Sequence(ctx, ids)
+}
+
+// RunOrdered attempts the notification sets in "ready", in the order of
+// "seq", holding back any set mentioning a manifest mentioned by an earlier
+// set in "seq" that wasn't delivered in this run.
+//
+// A set that isn't ready because it's waiting to be retried or didn't fit in
+// the batch holds back later sets the same as one that failed.
+func (d *Delivery) runOrdered(ctx context.Context, seq []Sequenced, ready []uuid.UUID, states map[uuid.UUID]RetryState) error {
+	isReady := make(map[uuid.UUID]struct{}, len(ready))
+	for _, id := range ready {
+		isReady[id] = struct{}{}
+	}
+	h := make(holds)
+	held := 0
+	for i := range seq {
+		s := &seq[i]
+		if _, ok := isReady[s.ID]; !ok {
+			h.hold(s)
+			continue
+		}
+		if h.held(s) {
+			held++
+			h.hold(s)
+			continue
 		}
-		done()
+		delivered, err := d.attempt(ctx, s.ID, states[s.ID])
 		if err != nil {
 			return err
 		}
+		if !delivered {
+			h.hold(s)
+		}
+	}
+	deliveryPending.WithLabelValues(d.Deliverer.Name(), "held").Set(float64(held))
+	if held != 0 {
+		zlog.Info(ctx).
+			Int("held", held).
+			Msg("notification ids held behind undelivered notifications for the same manifests")
 	}
 	return nil
 }
 
+// Attempt delivers "nID" under its lock, reporting whether it was delivered.
+//
+// If the lock is held elsewhere, the notification set is skipped.
+func (d *Delivery) attempt(ctx context.Context, nID uuid.UUID, prev RetryState) (bool, error) {
+	ctx, done := d.locks.TryLock(ctx, nID.String())
+	defer done()
+	if ok := ctx.Err(); !errors.Is(ok, nil) {
+		zlog.Debug(ctx).
+			Err(ok).
+			Stringer("notification_id", nID).
+			Msg("unable to get lock")
+		return false, nil
+	}
+	if d.Ordered {
+		// Another delivery goroutine may have delivered this set since it
+		// was listed as pending. Delivering it again could land it after a
+		// later set for the same manifests.
+		r, err := d.store.Receipt(ctx, nID)
+		if err != nil {
+			return false, err
+		}
+		if r.Status != Created && r.Status != DeliveryFailed {
+			return true, nil
+		}
+	}
+	return d.do(ctx, nID, prev)
+}
+
 // do performs the delivery of notifications via the composed
 // deliverer
 //
-// do's actions should be performed under a distributed lock.
-func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
+// do's actions should be performed under a distributed lock. The RetryState
+// is the notification's state before this attempt. The returned bool reports
+// whether the notifications were delivered.
+func (d *Delivery) do(ctx context.Context, nID uuid.UUID, prev RetryState) (bool, error) {
 	ctx = zlog.ContextWithValues(ctx,
 		"notification_id", nID.String(),
 		"component", "notifier/Delivery.do",
@@ -130,16 +290,21 @@ func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
 			Msg("providing direct deliverer notifications")
 		notifications, _, err := d.store.Notifications(ctx, nID, nil)
 		if err != nil {
-			return err
+			return false, err
 		}
 		err = dd.Notifications(ctx, notifications)
 		if err != nil {
-			return err
+			d.record(ctx, nID, err, prev)
+			return false, err
 		}
 	}
 
 	// deliver the notification
+	start := time.Now()
 	err := d.Deliverer.Deliver(ctx, nID)
+	d.observe(start, err)
+	d.Backpressure.Observe(time.Since(start))
+	d.record(ctx, nID, err, prev)
 	if err != nil {
 		var dErr clairerror.ErrDeliveryFailed
 		if errors.As(err, &dErr) {
@@ -149,17 +314,17 @@ func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
 				Msg("failed to deliver notifications")
 			err := d.store.SetDeliveryFailed(ctx, nID)
 			if err != nil {
-				return err
+				return false, err
 			}
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 	err = d.store.SetDelivered(ctx, nID)
 	if err != nil {
 		// the message was delivered, but we can't ack this in our db
 		// it will be delivered again unless deleted before next interval
-		return err
+		return true, err
 	}
 
 	// if we successfully performed direct delivery
@@ -167,10 +332,64 @@ func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
 	if _, ok := d.Deliverer.(DirectDeliverer); ok {
 		err := d.store.SetDeleted(ctx, nID)
 		if err != nil {
-			return err
+			return true, err
 		}
 	}
 	zlog.Info(ctx).
 		Msg("successfully delivered notifications")
-	return nil
+	return true, nil
+}
+
+// Observe records metrics for a delivery attempt started at "start" that
+// returned "err".
+func (d *Delivery) observe(start time.Time, err error) {
+	var dErr clairerror.ErrDeliveryFailed
+	status := deliveryOK
+	switch {
+	case errors.Is(err, nil):
+	case errors.As(err, &dErr):
+		status = deliveryFailed
+	default:
+		status = deliveryError
+	}
+	name, dest := d.Deliverer.Name(), d.destination()
+	deliveryCounter.WithLabelValues(name, dest, status).Inc()
+	deliveryDuration.WithLabelValues(name, dest, status).Observe(time.Since(start).Seconds())
+}
+
+func (d *Delivery) destination() string {
+	if dd, ok := d.Deliverer.(Destinationer); ok {
+		return dd.Destination()
+	}
+	return ""
+}
+
+// Record adds a delivery attempt for "nID" that returned "err" to the store,
+// if the store keeps them.
+//
+// A failed attempt is recorded with the time of the next attempt, scheduled
+// by the Delivery's Retry after the failures recorded in "prev".
+//
+// Failing to record an attempt is logged rather than returned, so that it
+// can't cause a notification to be delivered twice.
+func (d *Delivery) record(ctx context.Context, nID uuid.UUID, err error, prev RetryState) {
+	r, ok := d.store.(AttemptRecorder)
+	if !ok {
+		return
+	}
+	a := Attempt{
+		NotificationID: nID,
+		Deliverer:      d.Deliverer.Name(),
+		Destination:    d.destination(),
+		TS:             time.Now(),
+		Err:            err,
+	}
+	if err != nil {
+		a.Next = d.Retry.next(prev.Attempts+1, a.TS)
+	}
+	if err := r.RecordAttempt(ctx, &a); err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("unable to record delivery attempt")
+	}
 }
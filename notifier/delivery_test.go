@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// SequenceStore is a MockStore that's also a Sequencer.
+type sequenceStore struct {
+	*MockStore
+	seq []Sequenced
+}
+
+func (s *sequenceStore) Sequence(_ context.Context, ids []uuid.UUID) ([]Sequenced, error) {
+	want := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	var out []Sequenced
+	for _, s := range s.seq {
+		if want[s.ID] {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// TestLocker always hands out locks.
+type testLocker struct{}
+
+func (testLocker) TryLock(ctx context.Context, _ string) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
+func (testLocker) Lock(ctx context.Context, _ string) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
+func (testLocker) Close(context.Context) error { return nil }
+
+// RecordingDeliverer records the notification sets it delivers, and fails
+// the ones in "fail".
+type recordingDeliverer struct {
+	fail      map[uuid.UUID]bool
+	delivered []uuid.UUID
+}
+
+func (*recordingDeliverer) Name() string { return "recording" }
+
+func (d *recordingDeliverer) Deliver(_ context.Context, nID uuid.UUID) error {
+	if d.fail[nID] {
+		return clairerror.ErrDeliveryFailed{E: errors.New("refused")}
+	}
+	d.delivered = append(d.delivered, nID)
+	return nil
+}
+
+func TestOrderedDelivery(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// Sets are numbered in the order they were created.
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	seq := []Sequenced{
+		{ID: ids[0], Manifests: []string{"a"}},
+		{ID: ids[1], Manifests: []string{"a"}},
+		{ID: ids[2], Manifests: []string{"b"}},
+		{ID: ids[3], Manifests: []string{"a", "b"}},
+		{ID: ids[4], Manifests: []string{"c"}},
+	}
+	status := make(map[uuid.UUID]Status)
+	for _, id := range ids {
+		status[id] = Created
+	}
+	pending := func(want Status) func(context.Context) ([]uuid.UUID, error) {
+		return func(context.Context) ([]uuid.UUID, error) {
+			var out []uuid.UUID
+			// Report in reverse, so the order has to come from the Sequencer.
+			for i := len(ids) - 1; i >= 0; i-- {
+				if status[ids[i]] == want {
+					out = append(out, ids[i])
+				}
+			}
+			return out, nil
+		}
+	}
+	set := func(s Status) func(context.Context, uuid.UUID) error {
+		return func(_ context.Context, id uuid.UUID) error {
+			status[id] = s
+			return nil
+		}
+	}
+	store := &sequenceStore{
+		MockStore: &MockStore{
+			Created_: pending(Created),
+			Failed_:  pending(DeliveryFailed),
+			Receipt_: func(_ context.Context, id uuid.UUID) (Receipt, error) {
+				return Receipt{NotificationID: id, Status: status[id]}, nil
+			},
+			SetDelivered_:       set(Delivered),
+			SetDeliveredFailed_: set(DeliveryFailed),
+		},
+		seq: seq,
+	}
+	del := &recordingDeliverer{fail: map[uuid.UUID]bool{ids[0]: true}}
+	d := NewDelivery(store, testLocker{}, del, time.Second)
+	d.Ordered = true
+
+	// The first set fails, holding back the later sets for "a", and the set
+	// for both "a" and "b".
+	if err := d.RunDelivery(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := del.delivered, []uuid.UUID{ids[2], ids[4]}; !cmp.Equal(got, want) {
+		t.Errorf("first run: got: %v, want: %v", got, want)
+	}
+
+	// Once it's delivered, the rest follow in order.
+	del.fail = nil
+	del.delivered = nil
+	if err := d.RunDelivery(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := del.delivered, []uuid.UUID{ids[0], ids[1], ids[3]}; !cmp.Equal(got, want) {
+		t.Errorf("second run: got: %v, want: %v", got, want)
+	}
+	for _, id := range ids {
+		if got, want := status[id], Delivered; got != want {
+			t.Errorf("%v: got: %v, want: %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryReporter is implemented by Services that can report on the
+// delivery of a notification.
+type DeliveryReporter interface {
+	DeliveryReport(ctx context.Context, id uuid.UUID) (*DeliveryReport, error)
+}
+
+// DeliveryReport describes the delivery of a notification: its current
+// status, and a summary of the attempts made to deliver it.
+type DeliveryReport struct {
+	NotificationID uuid.UUID `json:"notification_id"`
+	// The update operation that created the notification.
+	UpdateID uuid.UUID `json:"update_id"`
+	Status   Status    `json:"status"`
+	// The time of the last status change.
+	StatusUpdated time.Time `json:"status_updated"`
+	// Deliverer and Destination are those used for the last attempt.
+	Deliverer   string `json:"deliverer,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Attempts    int    `json:"attempts"`
+	// LastAttempt is unset if no attempt has been recorded.
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	// NextAttempt is the earliest time the next attempt will be made, if
+	// one is scheduled.
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+	// LastError is the error from the last attempt, if it failed.
+	LastError string `json:"last_error,omitempty"`
+	// Delivered is the time of the first successful attempt.
+	Delivered *time.Time `json:"delivered,omitempty"`
+}
+
+// Attempt is a single attempt to deliver a notification.
+type Attempt struct {
+	NotificationID uuid.UUID
+	Deliverer      string
+	Destination    string
+	TS             time.Time
+	// Err is nil if the attempt succeeded.
+	Err error
+	// Next is the earliest time the next attempt should be made, if the
+	// attempt failed and retries are scheduled.
+	Next time.Time
+}
+
+// AttemptRecorder is implemented by Stores that keep a record of delivery
+// attempts.
+type AttemptRecorder interface {
+	// RecordAttempt adds an Attempt to the notification's DeliveryReport.
+	RecordAttempt(ctx context.Context, a *Attempt) error
+	// DeliveryReport returns the DeliveryReport for the notification ID.
+	//
+	// An *clairerror.ErrNoReceipt is returned if the notification ID is not
+	// known. The report has no attempts if none have been recorded.
+	DeliveryReport(ctx context.Context, id uuid.UUID) (*DeliveryReport, error)
+}
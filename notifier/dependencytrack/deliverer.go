@@ -0,0 +1,273 @@
+// Package dependencytrack publishes the manifests mentioned in notifications
+// to a Dependency-Track server.
+//
+// Each manifest is uploaded as a CycloneDX SBOM that includes Clair's
+// findings, into a project named by the manifest's labels. Projects are
+// created as needed, and uploading replaces the project's previous SBOM, so
+// the project always reflects the latest vulnerability report.
+package dependencytrack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/cmd"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/cyclonedx"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// These are the Dependency-Track API endpoints used, relative to the
+// configured URL.
+const (
+	bomPath     = "api/v1/bom"
+	versionPath = "api/version"
+)
+
+// Deliverer publishes the manifests mentioned in a notification set to
+// Dependency-Track.
+//
+// If publishing any manifest fails the whole set is retried, so manifests
+// may be published more than once.
+type Deliverer struct {
+	c       *http.Client
+	api     *url.URL
+	key     string
+	project string
+	version string
+	idx     indexer.Reporter
+	scan    matcher.Scanner
+	n       []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+	_ notifier.Prober          = (*Deliverer)(nil)
+)
+
+// New returns a new Dependency-Track Deliverer, which uses "idx" and "scan"
+// to build the vulnerability reports it publishes.
+func New(conf *config.DependencyTrack, client *http.Client, idx indexer.Reporter, scan matcher.Scanner) (*Deliverer, error) {
+	switch {
+	case conf == nil:
+		return nil, errors.New("config not provided")
+	case client == nil:
+		return nil, errors.New("http client not provided")
+	case idx == nil || scan == nil:
+		return nil, errors.New("indexer and matcher not provided")
+	}
+	api, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the API paths are resolved relative to any path in the
+	// configured URL.
+	if api.Path == "" || api.Path[len(api.Path)-1] != '/' {
+		api.Path += "/"
+	}
+	d := Deliverer{
+		c:       client,
+		api:     api,
+		key:     conf.APIKey,
+		project: conf.ProjectLabel,
+		version: conf.VersionLabel,
+		idx:     idx,
+		scan:    scan,
+	}
+	if d.project == "" {
+		d.project = config.DefaultDependencyTrackProjectLabel
+	}
+	if d.version == "" {
+		d.version = config.DefaultDependencyTrackVersionLabel
+	}
+	return &d, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "dependency-track"
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.api.Redacted()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Deliver implements notifier.Deliverer.
+//
+// Deliver publishes each manifest mentioned in the buffered notifications.
+// Manifests that are no longer indexed are skipped.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/dependencytrack/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	seen := make(map[string]struct{}, len(d.n))
+	for i := range d.n {
+		n := &d.n[i]
+		k := n.Manifest.String()
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if err := d.publish(ctx, n.Manifest, n.Labels); err != nil {
+			return fmt.Errorf("manifest %q: %w", k, err)
+		}
+	}
+	zlog.Info(ctx).
+		Stringer("target", d.api).
+		Int("count", len(seen)).
+		Msg("published manifests")
+	return nil
+}
+
+// BOMUpload is the request body for uploading a BOM.
+type bomUpload struct {
+	ProjectName    string `json:"projectName"`
+	ProjectVersion string `json:"projectVersion"`
+	AutoCreate     bool   `json:"autoCreate"`
+	// BOM is base64 encoded when serialized, as the API expects.
+	BOM []byte `json:"bom"`
+}
+
+// Project returns the project name and version the manifest "m" is published
+// to.
+func (d *Deliverer) projectFor(m claircore.Digest, labels map[string]string) (name, version string) {
+	name, version = labels[d.project], labels[d.version]
+	if name == "" {
+		name = m.String()
+		version = ""
+	}
+	if version == "" {
+		version = m.String()
+	}
+	return name, version
+}
+
+// Publish builds a vulnerability report for the manifest "m" and uploads it
+// as a BOM.
+func (d *Deliverer) publish(ctx context.Context, m claircore.Digest, labels map[string]string) error {
+	ir, ok, err := d.idx.IndexReport(ctx, m)
+	switch {
+	case err != nil:
+		return fmt.Errorf("unable to fetch index report: %w", err)
+	case !ok:
+		zlog.Debug(ctx).
+			Stringer("manifest", m).
+			Msg("manifest no longer indexed, skipping")
+		return nil
+	}
+	vr, err := d.scan.Scan(ctx, ir)
+	if err != nil {
+		return fmt.Errorf("unable to create vulnerability report: %w", err)
+	}
+	// Licenses are only known if the indexer is in-process and records them.
+	var ls map[string]string
+	if lr, ok := d.idx.(indexer.LicenseReporter); ok {
+		ls, err = lr.Licenses(ctx, ir)
+		if err != nil && !errors.Is(err, indexer.ErrUnsupported) {
+			zlog.Warn(ctx).
+				Err(err).
+				Stringer("manifest", m).
+				Msg("unable to look up licenses")
+		}
+	}
+	name, version := d.projectFor(m, labels)
+	bom, err := json.Marshal(cyclonedx.FromReport(vr, &cyclonedx.Opts{
+		Name:     name,
+		Version:  version,
+		Tool:     cmd.Version,
+		Licenses: ls,
+	}))
+	if err != nil {
+		return err
+	}
+
+	u, err := d.api.Parse(bomPath)
+	if err != nil {
+		return err
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPut, u.String(), codec.JSONReader(&bomUpload{
+		ProjectName:    name,
+		ProjectVersion: version,
+		AutoCreate:     true,
+		BOM:            bom,
+	}))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-api-key", d.key)
+	res, err := d.c.Do(req)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	if res.StatusCode != http.StatusOK {
+		return &clairerror.ErrDeliveryFailed{
+			E: &clairerror.ErrRequestFail{
+				Code:   res.StatusCode,
+				Status: res.Status,
+			},
+		}
+	}
+	zlog.Debug(ctx).
+		Stringer("manifest", m).
+		Str("project", name).
+		Str("version", version).
+		Int("vulnerabilities", len(vr.Vulnerabilities)).
+		Msg("published manifest")
+	return nil
+}
+
+// Probe implements notifier.Prober.
+//
+// Probe checks that the server responds to version requests, which don't
+// require a valid API key.
+func (d *Deliverer) Probe(ctx context.Context) error {
+	u, err := d.api.Parse(versionPath)
+	if err != nil {
+		return err
+	}
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := d.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	if res.StatusCode != http.StatusOK {
+		return &clairerror.ErrRequestFail{
+			Code:   res.StatusCode,
+			Status: res.Status,
+		}
+	}
+	return nil
+}
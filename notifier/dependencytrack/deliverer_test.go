@@ -0,0 +1,131 @@
+package dependencytrack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/cyclonedx"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TestDeliverer confirms each manifest mentioned in a notification set is
+// published once, to the project named by its labels.
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const key = "secret"
+	var mu sync.Mutex
+	var got []bomUpload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dtrack/api/version" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"version":"4.8.0"}`))
+			return
+		case r.URL.Path != "/dtrack/api/v1/bom" || r.Method != http.MethodPut:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case r.Header.Get("x-api-key") != key:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var u bomUpload
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		got = append(got, u)
+		mu.Unlock()
+		w.Write([]byte(`{"token":"` + uuid.NewString() + `"}`))
+	}))
+	defer srv.Close()
+
+	tagged := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	bare := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	gone := claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))
+	idx := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			if d.String() == gone.String() {
+				return nil, false, nil
+			}
+			return &claircore.IndexReport{Hash: d, Success: true}, true, nil
+		},
+	}
+	m := &matcher.Mock{
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{
+				Hash:     ir.Hash,
+				Packages: map[string]*claircore.Package{"1": {ID: "1", Name: "openssl", Version: "1"}},
+			}, nil
+		},
+	}
+	d, err := New(&config.DependencyTrack{
+		URL:    srv.URL + "/dtrack",
+		APIKey: key,
+	}, srv.Client(), idx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Probe(ctx); err != nil {
+		t.Errorf("probe: %v", err)
+	}
+
+	labels := map[string]string{"repository": "quay.io/example/app", "tag": "v1"}
+	ns := []notifier.Notification{
+		{ID: uuid.New(), Manifest: tagged, Labels: labels},
+		{ID: uuid.New(), Manifest: tagged, Labels: labels},
+		{ID: uuid.New(), Manifest: bare},
+		{ID: uuid.New(), Manifest: gone},
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	type project struct{ Name, Version string }
+	var projects []project
+	for _, u := range got {
+		if !u.AutoCreate {
+			t.Error("autoCreate not set")
+		}
+		var b cyclonedx.BOM
+		if err := json.Unmarshal(u.BOM, &b); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(b.Components), 1; got != want {
+			t.Errorf("components: got: %d, want: %d", got, want)
+		}
+		projects = append(projects, project{u.ProjectName, u.ProjectVersion})
+	}
+	want := []project{
+		{"quay.io/example/app", "v1"},
+		{bare.String(), bare.String()},
+	}
+	if !cmp.Equal(projects, want) {
+		t.Error(cmp.Diff(projects, want))
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		d.key = "wrong"
+		err := d.Deliver(ctx, uuid.New())
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,232 @@
+// Package elasticsearch indexes notifications into Elasticsearch or
+// OpenSearch using the bulk API.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer indexes the notifications in a notification set as documents.
+//
+// Documents are created with the notification ID as the document ID, so
+// documents that already exist when a failed set is retried are left as-is.
+// This works for both indices and data streams.
+type Deliverer struct {
+	c        *http.Client
+	endpoint *url.URL
+	auth     func(*http.Request)
+	index    *template.Template
+	batch    int
+	n        []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+)
+
+// New returns a new Elasticsearch Deliverer.
+func New(conf *config.Elasticsearch, client *http.Client) (*Deliverer, error) {
+	switch {
+	case conf == nil:
+		return nil, errors.New("config not provided")
+	case client == nil:
+		return nil, errors.New("http client not provided")
+	}
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	d := Deliverer{
+		c:        client,
+		endpoint: u.JoinPath("_bulk"),
+		auth:     func(*http.Request) {},
+		batch:    conf.BatchSize,
+	}
+	if conf.Pipeline != "" {
+		d.endpoint.RawQuery = url.Values{"pipeline": {conf.Pipeline}}.Encode()
+	}
+	if d.batch <= 0 {
+		d.batch = config.DefaultElasticsearchBatchSize
+	}
+	switch {
+	case conf.APIKey != "":
+		d.auth = func(r *http.Request) { r.Header.Set("authorization", "ApiKey "+conf.APIKey) }
+	case conf.Username != "":
+		d.auth = func(r *http.Request) { r.SetBasicAuth(conf.Username, conf.Password) }
+	}
+	idx := conf.Index
+	if idx == "" {
+		idx = config.DefaultElasticsearchIndex
+	}
+	if d.index, err = template.New("index").Parse(idx); err != nil {
+		return nil, fmt.Errorf("invalid index template: %w", err)
+	}
+	return &d, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "elasticsearch"
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.endpoint.String()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Action is the action line preceding a document in a bulk request.
+type action struct {
+	Create struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	} `json:"create"`
+}
+
+// Document is the indexed form of a notification.
+type document struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Set       uuid.UUID `json:"notification_set"`
+	*notifier.Notification
+}
+
+// BulkResponse is the subset of a bulk API response needed to find failed
+// items.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Deliver implements notifier.Deliverer.
+//
+// Deliver sends the buffered notifications in batches.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/elasticsearch/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	now := time.Now().UTC()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	var sb strings.Builder
+	var batches int
+	for i := 0; i < len(d.n); i += d.batch {
+		end := i + d.batch
+		if end > len(d.n) {
+			end = len(d.n)
+		}
+		buf.Reset()
+		for j := i; j < end; j++ {
+			n := &d.n[j]
+			sb.Reset()
+			if err := d.index.Execute(&sb, n); err != nil {
+				return fmt.Errorf("notification %v: unable to execute index template: %w", n.ID, err)
+			}
+			var a action
+			a.Create.Index = strings.ToLower(sb.String())
+			a.Create.ID = n.ID.String()
+			if err := enc.Encode(&a); err != nil {
+				return err
+			}
+			if err := enc.Encode(&document{Timestamp: now, Set: nID, Notification: n}); err != nil {
+				return err
+			}
+		}
+		if err := d.post(ctx, buf.Bytes()); err != nil {
+			return err
+		}
+		batches++
+	}
+	zlog.Info(ctx).
+		Stringer("destination", d.endpoint).
+		Int("count", len(d.n)).
+		Int("batches", batches).
+		Msg("sent notifications")
+	return nil
+}
+
+// Post sends a bulk request.
+//
+// Failed requests, unsuccessful responses, and responses reporting failed
+// items are reported as clairerror.ErrDeliveryFailed. Items that failed only
+// because the document already exists are not failures.
+func (d *Deliverer) post(ctx context.Context, body []byte) error {
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, d.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	d.auth(req)
+	req.Header.Set("content-type", "application/x-ndjson")
+	res, err := d.c.Do(req)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+		return &clairerror.ErrDeliveryFailed{
+			E: &clairerror.ErrRequestFail{
+				Code:   res.StatusCode,
+				Status: res.Status,
+			},
+		}
+	}
+	var r bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: fmt.Errorf("unable to decode bulk response: %w", err)}
+	}
+	if !r.Errors {
+		return nil
+	}
+	var failed int
+	var first string
+	for _, item := range r.Items {
+		for _, res := range item {
+			if res.Status < 300 || res.Status == http.StatusConflict {
+				continue
+			}
+			failed++
+			if first == "" && res.Error != nil {
+				first = fmt.Sprintf("%s: %s", res.Error.Type, res.Error.Reason)
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return &clairerror.ErrDeliveryFailed{
+		E: fmt.Errorf("%d documents not indexed (first error: %s)", failed, first),
+	}
+}
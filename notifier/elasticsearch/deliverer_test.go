@@ -0,0 +1,138 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+func notifications() []notifier.Notification {
+	m := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	return []notifier.Notification{
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3711", Severity: "High"}},
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3712", Severity: "Medium"}},
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Removed, Vulnerability: notifier.VulnSummary{Name: "CVE-2020-1971", Severity: "Medium"}},
+	}
+}
+
+// Bulk is a fake bulk API. Documents are stored by ID, and creating a
+// document that exists is a conflict.
+type bulk struct {
+	t        *testing.T
+	docs     map[string]map[string]any
+	indices  map[string]string
+	requests int
+	fail     bool
+}
+
+func (b *bulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.requests++
+	if got, want := r.URL.Path, "/_bulk"; got != want {
+		b.t.Errorf("path: got: %q, want: %q", got, want)
+	}
+	if got, want := r.URL.Query().Get("pipeline"), "clair"; got != want {
+		b.t.Errorf("pipeline: got: %q, want: %q", got, want)
+	}
+	if got, want := r.Header.Get("authorization"), "ApiKey key"; got != want {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var items []string
+	errs := false
+	s := bufio.NewScanner(r.Body)
+	for s.Scan() {
+		var a action
+		if err := json.Unmarshal(s.Bytes(), &a); err != nil {
+			b.t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.Scan()
+		var doc map[string]any
+		if err := json.Unmarshal(s.Bytes(), &doc); err != nil {
+			b.t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status := http.StatusCreated
+		switch _, ok := b.docs[a.Create.ID]; {
+		case b.fail:
+			status = http.StatusBadRequest
+		case ok:
+			status = http.StatusConflict
+		default:
+			b.docs[a.Create.ID] = doc
+			b.indices[a.Create.ID] = a.Create.Index
+		}
+		if status >= 300 {
+			errs = true
+		}
+		items = append(items, fmt.Sprintf(`{"create":{"status":%d,"error":{"type":"t","reason":"r"}}}`, status))
+	}
+	fmt.Fprintf(w, `{"errors":%v,"items":[%s]}`, errs, strings.Join(items, ","))
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b := &bulk{t: t, docs: make(map[string]map[string]any), indices: make(map[string]string)}
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	d, err := New(&config.Elasticsearch{
+		URL:       srv.URL,
+		Index:     "clair-{{.Vulnerability.Severity}}",
+		Pipeline:  "clair",
+		APIKey:    "key",
+		BatchSize: 2,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := notifications()
+	set := uuid.New()
+	d.Notifications(ctx, ns)
+	if err := d.Deliver(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.requests, 2; got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+	if got, want := len(b.docs), 3; got != want {
+		t.Fatalf("got: %d documents, want: %d", got, want)
+	}
+	id := ns[0].ID.String()
+	if got, want := b.indices[id], "clair-high"; got != want {
+		t.Errorf("index: got: %q, want: %q", got, want)
+	}
+	doc := b.docs[id]
+	if got, want := doc["notification_set"], set.String(); got != want {
+		t.Errorf("notification_set: got: %v, want: %v", got, want)
+	}
+	if got, want := doc["id"], id; got != want {
+		t.Errorf("id: got: %v, want: %v", got, want)
+	}
+	if _, ok := doc["@timestamp"]; !ok {
+		t.Error("missing @timestamp")
+	}
+
+	// Retrying the set conflicts on every document, which isn't a failure.
+	if err := d.Deliver(ctx, set); err != nil {
+		t.Error(err)
+	}
+	b.fail = true
+	if err := d.Deliver(ctx, set); err == nil {
+		t.Error("expected error")
+	}
+}
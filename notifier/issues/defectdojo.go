@@ -0,0 +1,142 @@
+package issues
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/quay/clair/config"
+)
+
+// DefectDojo opens DefectDojo findings, using the version 2 API.
+//
+// Findings are found by their "unique_id_from_tool", which holds their key.
+// Resolving a finding marks it inactive and mitigated.
+type defectDojo struct {
+	c       *client
+	test    int
+	foundBy []int
+}
+
+func newDefectDojo(conf *config.IssuesDefectDojo, c *http.Client) (*defectDojo, error) {
+	key := conf.APIKey
+	cl, err := newClient(c, conf.URL, func(r *http.Request) { r.Header.Set("authorization", "Token "+key) })
+	if err != nil {
+		return nil, err
+	}
+	return &defectDojo{
+		c:       cl,
+		test:    conf.Test,
+		foundBy: conf.FoundBy,
+	}, nil
+}
+
+func (d *defectDojo) destination() string {
+	return d.c.base.Redacted()
+}
+
+// Find returns the ID of the finding with the key "k", or 0 if there isn't
+// one.
+func (d *defectDojo) find(ctx context.Context, k string) (int, error) {
+	v := url.Values{
+		"test":                {strconv.Itoa(d.test)},
+		"unique_id_from_tool": {k},
+		"limit":               {"1"},
+	}
+	var res struct {
+		Results []struct {
+			ID int `json:"id"`
+		} `json:"results"`
+	}
+	if err := d.c.do(ctx, http.MethodGet, "api/v2/findings/?"+v.Encode(), nil, &res); err != nil {
+		return 0, err
+	}
+	if len(res.Results) == 0 {
+		return 0, nil
+	}
+	return res.Results[0].ID, nil
+}
+
+type dojoFinding struct {
+	Test              int          `json:"test,omitempty"`
+	FoundBy           []int        `json:"found_by,omitempty"`
+	UniqueID          string       `json:"unique_id_from_tool,omitempty"`
+	VulnID            string       `json:"vuln_id_from_tool,omitempty"`
+	VulnerabilityIDs  []dojoVulnID `json:"vulnerability_ids,omitempty"`
+	Title             string       `json:"title"`
+	Description       string       `json:"description"`
+	Severity          string       `json:"severity"`
+	NumericalSeverity string       `json:"numerical_severity"`
+	Mitigation        string       `json:"mitigation,omitempty"`
+	References        string       `json:"references,omitempty"`
+	ComponentName     string       `json:"component_name,omitempty"`
+	ComponentVersion  string       `json:"component_version,omitempty"`
+	Active            bool         `json:"active"`
+	Mitigated         bool         `json:"is_mitigated"`
+	// These are only set when creating a finding, so that updates don't
+	// undo triage done in DefectDojo.
+	Verified      *bool `json:"verified,omitempty"`
+	StaticFinding *bool `json:"static_finding,omitempty"`
+}
+
+type dojoVulnID struct {
+	ID string `json:"vulnerability_id"`
+}
+
+// DojoSeverity maps a Clair severity onto DefectDojo's severities and their
+// numerical equivalents.
+func dojoSeverity(s string) (string, string) {
+	switch s {
+	case "Critical":
+		return "Critical", "S0"
+	case "High":
+		return "High", "S1"
+	case "Medium":
+		return "Medium", "S2"
+	case "Low":
+		return "Low", "S3"
+	}
+	return "Info", "S4"
+}
+
+func (d *defectDojo) open(ctx context.Context, i *Issue, title, desc string) error {
+	existing, err := d.find(ctx, i.Key)
+	if err != nil {
+		return err
+	}
+	v := &i.Vulnerability
+	f := dojoFinding{
+		Title:       title,
+		Description: desc,
+		References:  v.Links,
+		Active:      true,
+	}
+	f.Severity, f.NumericalSeverity = dojoSeverity(v.Severity)
+	if v.FixedInVersion != "" {
+		f.Mitigation = "Upgrade to " + v.FixedInVersion + " or later."
+	}
+	if existing != 0 {
+		return d.c.do(ctx, http.MethodPatch, "api/v2/findings/"+strconv.Itoa(existing)+"/", &f, nil)
+	}
+	f.Test = d.test
+	f.FoundBy = d.foundBy
+	f.UniqueID = i.Key
+	f.VulnID = v.Name
+	f.VulnerabilityIDs = []dojoVulnID{{ID: v.Name}}
+	no, yes := false, true
+	f.Verified, f.StaticFinding = &no, &yes
+	if p := v.Package; p != nil {
+		f.ComponentName, f.ComponentVersion = p.Name, p.Version
+	}
+	return d.c.do(ctx, http.MethodPost, "api/v2/findings/", &f, nil)
+}
+
+func (d *defectDojo) resolve(ctx context.Context, i *Issue) error {
+	existing, err := d.find(ctx, i.Key)
+	if err != nil || existing == 0 {
+		return err
+	}
+	return d.c.do(ctx, http.MethodPatch, "api/v2/findings/"+strconv.Itoa(existing)+"/",
+		map[string]bool{"active": false, "is_mitigated": true}, nil)
+}
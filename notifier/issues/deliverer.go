@@ -0,0 +1,306 @@
+// Package issues opens and updates issues in an issue tracker for the
+// vulnerabilities mentioned in notifications.
+//
+// Notifications are grouped by vulnerability and image, and each group maps
+// to a single issue, found again on later deliveries by its key. A group that
+// only removes the vulnerability resolves the issue; any other group opens it,
+// or updates it if it's already open.
+package issues
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/codec"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Issue is what the title and description templates are executed with: the
+// notifications about one vulnerability affecting one image.
+type Issue struct {
+	// Key identifies the issue in the tracker.
+	Key string
+	// Image is the value of the configured image label, or the manifest
+	// digest if the manifest doesn't have it.
+	Image         string
+	Vulnerability notifier.VulnSummary
+	// Reason is the reason of the first notification that wasn't a removal,
+	// or "removed" if all of them were.
+	Reason    notifier.Reason
+	Manifests []claircore.Digest
+	// Labels are the labels of the first manifest.
+	Labels map[string]string
+	// Resolved reports whether the vulnerability was only removed.
+	Resolved bool
+}
+
+// Tracker is the interface the supported issue trackers implement.
+type tracker interface {
+	// Open opens an issue for "i", or updates the existing one.
+	open(ctx context.Context, i *Issue, title, desc string) error
+	// Resolve marks the existing issue for "i" as resolved, if there is one.
+	resolve(ctx context.Context, i *Issue) error
+	// Destination reports where issues are opened, without credentials.
+	destination() string
+}
+
+// Deliverer opens and updates issues for the notifications in a notification
+// set.
+//
+// If updating any issue fails the whole set is retried. Issues are found by
+// key, so this doesn't open duplicates.
+type Deliverer struct {
+	t     tracker
+	name  string
+	image string
+	title *template.Template
+	desc  *template.Template
+	n     []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+)
+
+// New returns a new issue Deliverer.
+func New(conf *config.Issues, client *http.Client) (*Deliverer, error) {
+	switch {
+	case conf == nil:
+		return nil, errors.New("config not provided")
+	case client == nil:
+		return nil, errors.New("http client not provided")
+	}
+	d := Deliverer{
+		image: conf.ImageLabel,
+	}
+	if d.image == "" {
+		d.image = config.DefaultIssuesImageLabel
+	}
+	var err error
+	title, desc := conf.Title, conf.Description
+	if title == "" {
+		title = config.DefaultIssuesTitle
+	}
+	if desc == "" {
+		desc = config.DefaultIssuesDescription
+	}
+	if d.title, err = template.New("title").Parse(title); err != nil {
+		return nil, fmt.Errorf("invalid title template: %w", err)
+	}
+	if d.desc, err = template.New("description").Parse(desc); err != nil {
+		return nil, fmt.Errorf("invalid description template: %w", err)
+	}
+	switch {
+	case conf.Jira != nil:
+		d.name = "jira"
+		d.t, err = newJira(conf.Jira, client)
+	case conf.DefectDojo != nil:
+		d.name = "defectdojo"
+		d.t, err = newDefectDojo(conf.DefectDojo, client)
+	default:
+		err = errors.New("no issue tracker configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (d *Deliverer) Name() string {
+	return d.name
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.t.destination()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Deliver implements notifier.Deliverer.
+//
+// Deliver opens, updates, or resolves an issue for each vulnerability and
+// image in the buffered notifications.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/issues/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	is := d.group(d.n)
+	var opened, resolved int
+	for _, i := range is {
+		if i.Resolved {
+			if err := d.t.resolve(ctx, i); err != nil {
+				return fmt.Errorf("issue %q: %w", i.Key, err)
+			}
+			resolved++
+			continue
+		}
+		var title, desc strings.Builder
+		if err := d.title.Execute(&title, i); err != nil {
+			return fmt.Errorf("issue %q: unable to execute title template: %w", i.Key, err)
+		}
+		if err := d.desc.Execute(&desc, i); err != nil {
+			return fmt.Errorf("issue %q: unable to execute description template: %w", i.Key, err)
+		}
+		if err := d.t.open(ctx, i, title.String(), desc.String()); err != nil {
+			return fmt.Errorf("issue %q: %w", i.Key, err)
+		}
+		opened++
+	}
+	zlog.Info(ctx).
+		Str("target", d.t.destination()).
+		Int("opened", opened).
+		Int("resolved", resolved).
+		Msg("updated issues")
+	return nil
+}
+
+// Group collects the notifications in "ns" into Issues, in the order they're
+// first mentioned.
+func (d *Deliverer) group(ns []notifier.Notification) []*Issue {
+	var out []*Issue
+	byKey := make(map[string]*Issue)
+	for i := range ns {
+		n := &ns[i]
+		image := n.Labels[d.image]
+		if image == "" {
+			image = n.Manifest.String()
+		}
+		k := key(n.Vulnerability.Name, image)
+		is, ok := byKey[k]
+		if !ok {
+			is = &Issue{
+				Key:           k,
+				Image:         image,
+				Vulnerability: n.Vulnerability,
+				Reason:        n.Reason,
+				Labels:        n.Labels,
+				Resolved:      true,
+			}
+			byKey[k] = is
+			out = append(out, is)
+		}
+		if n.Reason != notifier.Removed && is.Resolved {
+			// Describe the issue by the first notification that keeps it
+			// open.
+			is.Resolved = false
+			is.Reason = n.Reason
+			is.Vulnerability = n.Vulnerability
+		}
+		dup := false
+		for _, m := range is.Manifests {
+			if m.String() == n.Manifest.String() {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			is.Manifests = append(is.Manifests, n.Manifest)
+		}
+	}
+	return out
+}
+
+// Key returns the key identifying the issue for the vulnerability named
+// "vuln" affecting "image". It's usable as a Jira label.
+func key(vuln, image string) string {
+	h := sha256.New()
+	io.WriteString(h, vuln)
+	h.Write([]byte{0})
+	io.WriteString(h, image)
+	return "clair-" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Client makes JSON API requests to an issue tracker.
+type client struct {
+	c    *http.Client
+	base *url.URL
+	auth func(*http.Request)
+}
+
+func newClient(c *http.Client, base string, auth func(*http.Request)) (*client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the API paths are resolved relative to any path in the
+	// configured URL.
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &client{c: c, base: u, auth: auth}, nil
+}
+
+// Do makes a request to "path", relative to the base URL, with "in" as the
+// JSON body if it's not nil, and decodes a JSON response into "out" if it's
+// not nil.
+//
+// Failed requests and unsuccessful responses are reported as
+// clairerror.ErrDeliveryFailed.
+func (c *client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	u, err := c.base.Parse(path)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		body = codec.JSONReader(in)
+	}
+	req, err := httputil.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+	req.Header.Set("accept", "application/json")
+	c.auth(req)
+	res, err := c.c.Do(req)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+		return &clairerror.ErrDeliveryFailed{
+			E: &clairerror.ErrRequestFail{
+				Code:   res.StatusCode,
+				Status: res.Status,
+			},
+		}
+	}
+	if out == nil {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+		return nil
+	}
+	dec := codec.GetDecoder(res.Body)
+	defer codec.PutDecoder(dec)
+	if err := dec.Decode(out); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: fmt.Errorf("unable to decode response: %w", err)}
+	}
+	return nil
+}
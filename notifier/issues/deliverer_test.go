@@ -0,0 +1,249 @@
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+var (
+	manifestA = claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	manifestB = claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+)
+
+// Notifications returns a set mentioning the same vulnerability for two
+// manifests of one image, and removing another vulnerability.
+func notifications() []notifier.Notification {
+	labels := map[string]string{"repository": "quay.io/example/app"}
+	pkg := &claircore.Package{Name: "openssl", Version: "1.1.1k"}
+	return []notifier.Notification{
+		{ID: uuid.New(), Manifest: manifestA, Reason: notifier.Added, Labels: labels, Vulnerability: notifier.VulnSummary{
+			Name: "CVE-2021-3711", Severity: "High", FixedInVersion: "1.1.1l", Package: pkg,
+		}},
+		{ID: uuid.New(), Manifest: manifestB, Reason: notifier.Added, Labels: labels, Vulnerability: notifier.VulnSummary{
+			Name: "CVE-2021-3711", Severity: "High", FixedInVersion: "1.1.1l", Package: pkg,
+		}},
+		{ID: uuid.New(), Manifest: manifestA, Reason: notifier.Removed, Labels: labels, Vulnerability: notifier.VulnSummary{
+			Name: "CVE-2020-1971", Severity: "Medium", Package: pkg,
+		}},
+	}
+}
+
+func TestGroup(t *testing.T) {
+	d := Deliverer{image: "repository"}
+	is := d.group(notifications())
+	if got, want := len(is), 2; got != want {
+		t.Fatalf("got: %d issues, want: %d", got, want)
+	}
+	if got, want := is[0].Manifests, []claircore.Digest{manifestA, manifestB}; !cmp.Equal(got, want, cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })) {
+		t.Errorf("manifests: got: %v, want: %v", got, want)
+	}
+	if is[0].Resolved || !is[1].Resolved {
+		t.Errorf("resolved: got: %v, %v; want: false, true", is[0].Resolved, is[1].Resolved)
+	}
+	if is[0].Key == is[1].Key {
+		t.Error("distinct vulnerabilities share a key")
+	}
+	if got, want := is[0].Key, key("CVE-2021-3711", "quay.io/example/app"); got != want {
+		t.Errorf("key: got: %q, want: %q", got, want)
+	}
+}
+
+func TestJira(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var mu sync.Mutex
+	issues := map[string]string{}     // label to key
+	comments := map[string][]string{} // key to comments
+	var created, updated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if u, p, ok := r.BasicAuth(); !ok || u != "clair" || p != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+			jql := r.URL.Query().Get("jql")
+			var res struct {
+				Issues []map[string]string `json:"issues"`
+			}
+			for l, k := range issues {
+				if strings.Contains(jql, `labels = "`+l+`"`) && strings.Contains(jql, `project = "SEC"`) {
+					res.Issues = append(res.Issues, map[string]string{"key": k})
+				}
+			}
+			json.NewEncoder(w).Encode(&res)
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+			var req struct{ Fields jiraFields }
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if req.Fields.Project == nil || req.Fields.Project.Key != "SEC" || req.Fields.IssueType.Name != "Bug" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if !strings.Contains(req.Fields.Desc, manifestB.String()) {
+				t.Errorf("description missing manifest: %q", req.Fields.Desc)
+			}
+			if got, want := req.Fields.Summary, "CVE-2021-3711 in quay.io/example/app"; got != want {
+				t.Errorf("summary: got: %q, want: %q", got, want)
+			}
+			created++
+			k := "SEC-" + string(rune('0'+created))
+			issues[req.Fields.Labels[1]] = k
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"key": k})
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/rest/api/2/issue/"):
+			updated++
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comment"):
+			k := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/rest/api/2/issue/"), "/comment")
+			var c map[string]string
+			json.NewDecoder(r.Body).Decode(&c)
+			comments[k] = append(comments[k], c["body"])
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	d, err := New(&config.Issues{
+		Jira: &config.IssuesJira{URL: srv.URL, Project: "SEC", User: "clair", Token: "token"},
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := notifications()
+	// The removed vulnerability already has an issue.
+	issues[key("CVE-2020-1971", "quay.io/example/app")] = "SEC-100"
+	for i := 0; i < 2; i++ {
+		if err := d.Notifications(ctx, ns); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Deliver(ctx, uuid.New()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if created != 1 || updated != 1 {
+		t.Errorf("got: %d created, %d updated; want: 1, 1", created, updated)
+	}
+	if got, want := len(comments["SEC-100"]), 2; got != want {
+		t.Errorf("comments: got: %d, want: %d", got, want)
+	}
+}
+
+func TestDefectDojo(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var mu sync.Mutex
+	findings := map[int]map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("authorization") != "Token key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/dojo/api/v2/findings/":
+			q := r.URL.Query()
+			var res struct {
+				Results []map[string]int `json:"results"`
+			}
+			for id, f := range findings {
+				if f["unique_id_from_tool"] == q.Get("unique_id_from_tool") && q.Get("test") == "7" {
+					res.Results = append(res.Results, map[string]int{"id": id})
+				}
+			}
+			json.NewEncoder(w).Encode(&res)
+		case r.Method == http.MethodPost && r.URL.Path == "/dojo/api/v2/findings/":
+			var f map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&f)
+			id := len(findings) + 1
+			findings[id] = f
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]int{"id": id})
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/dojo/api/v2/findings/"):
+			var id int
+			for i := range findings {
+				if r.URL.Path == "/dojo/api/v2/findings/"+string(rune('0'+i))+"/" {
+					id = i
+				}
+			}
+			if id == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var f map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&f)
+			for k, v := range f {
+				findings[id][k] = v
+			}
+			json.NewEncoder(w).Encode(findings[id])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	d, err := New(&config.Issues{
+		DefectDojo: &config.IssuesDefectDojo{URL: srv.URL + "/dojo", APIKey: "key", Test: 7, FoundBy: []int{1}},
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := notifications()
+	if err := d.Notifications(ctx, ns[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	// Now the vulnerability is removed from both manifests.
+	for i := range ns[:2] {
+		ns[i].Reason = notifier.Removed
+	}
+	if err := d.Notifications(ctx, ns[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := len(findings), 1; got != want {
+		t.Fatalf("got: %d findings, want: %d", got, want)
+	}
+	f := findings[1]
+	for k, want := range map[string]interface{}{
+		"severity":           "High",
+		"numerical_severity": "S1",
+		"vuln_id_from_tool":  "CVE-2021-3711",
+		"component_name":     "openssl",
+		"mitigation":         "Upgrade to 1.1.1l or later.",
+		"active":             false,
+		"is_mitigated":       true,
+	} {
+		if got := f[k]; !cmp.Equal(got, want) {
+			t.Errorf("%s: got: %v, want: %v", k, got, want)
+		}
+	}
+}
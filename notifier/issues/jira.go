@@ -0,0 +1,113 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/clair/config"
+)
+
+// Jira opens Jira issues, using the version 2 REST API.
+//
+// Issues are found by a label holding their key. Resolving an issue only
+// comments on it, as which transition resolves an issue depends on the
+// project's workflow.
+type jira struct {
+	c         *client
+	project   string
+	issueType string
+}
+
+func newJira(conf *config.IssuesJira, c *http.Client) (*jira, error) {
+	auth := func(r *http.Request) { r.Header.Set("authorization", "Bearer "+conf.Token) }
+	if conf.User != "" {
+		auth = func(r *http.Request) { r.SetBasicAuth(conf.User, conf.Token) }
+	}
+	cl, err := newClient(c, conf.URL, auth)
+	if err != nil {
+		return nil, err
+	}
+	j := jira{
+		c:         cl,
+		project:   conf.Project,
+		issueType: conf.IssueType,
+	}
+	if j.issueType == "" {
+		j.issueType = config.DefaultIssuesJiraIssueType
+	}
+	return &j, nil
+}
+
+func (j *jira) destination() string {
+	return j.c.base.Redacted()
+}
+
+// Find returns the key of the issue labeled with "k", or an empty string if
+// there isn't one.
+func (j *jira) find(ctx context.Context, k string) (string, error) {
+	v := url.Values{
+		"jql":        {fmt.Sprintf(`project = %q AND labels = %q`, j.project, k)},
+		"maxResults": {"1"},
+		"fields":     {"key"},
+	}
+	var res struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := j.c.do(ctx, http.MethodGet, "rest/api/2/search?"+v.Encode(), nil, &res); err != nil {
+		return "", err
+	}
+	if len(res.Issues) == 0 {
+		return "", nil
+	}
+	return res.Issues[0].Key, nil
+}
+
+type jiraFields struct {
+	Project   *jiraRef `json:"project,omitempty"`
+	IssueType *jiraRef `json:"issuetype,omitempty"`
+	Summary   string   `json:"summary"`
+	Desc      string   `json:"description"`
+	Labels    []string `json:"labels,omitempty"`
+}
+
+type jiraRef struct {
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+func (j *jira) open(ctx context.Context, i *Issue, title, desc string) error {
+	existing, err := j.find(ctx, i.Key)
+	if err != nil {
+		return err
+	}
+	f := jiraFields{Summary: title, Desc: desc}
+	if existing != "" {
+		return j.c.do(ctx, http.MethodPut, "rest/api/2/issue/"+url.PathEscape(existing),
+			map[string]interface{}{"fields": &f}, nil)
+	}
+	f.Project = &jiraRef{Key: j.project}
+	f.IssueType = &jiraRef{Name: j.issueType}
+	f.Labels = []string{"clair", i.Key}
+	return j.c.do(ctx, http.MethodPost, "rest/api/2/issue",
+		map[string]interface{}{"fields": &f}, nil)
+}
+
+func (j *jira) resolve(ctx context.Context, i *Issue) error {
+	existing, err := j.find(ctx, i.Key)
+	if err != nil || existing == "" {
+		return err
+	}
+	ms := make([]string, len(i.Manifests))
+	for n, m := range i.Manifests {
+		ms[n] = m.String()
+	}
+	body := fmt.Sprintf("%s no longer affects %s in manifests: %s",
+		i.Vulnerability.Name, i.Image, strings.Join(ms, ", "))
+	return j.c.do(ctx, http.MethodPost, "rest/api/2/issue/"+url.PathEscape(existing)+"/comment",
+		map[string]string{"body": body}, nil)
+}
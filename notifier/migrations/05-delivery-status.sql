@@ -0,0 +1,10 @@
+--- a relation summarizing the delivery attempts made for a notification
+CREATE TABLE IF NOT EXISTS delivery_status (
+    notification_id uuid PRIMARY KEY REFERENCES notification (id) ON DELETE CASCADE,
+    deliverer text NOT NULL,
+    destination text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    last_attempt timestamptz NOT NULL,
+    last_error text, -- NULL if the last attempt succeeded
+    delivered timestamptz -- the first successful attempt
+);
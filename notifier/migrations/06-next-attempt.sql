@@ -0,0 +1,3 @@
+-- the earliest time a failed notification's delivery is retried, NULL if no
+-- retry is scheduled
+ALTER TABLE delivery_status ADD COLUMN IF NOT EXISTS next_attempt timestamptz;
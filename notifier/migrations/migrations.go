@@ -45,4 +45,12 @@ func runFile(n string) func(*sql.Tx) error {
 			Up: runFile("04-drop-key.sql"),
 		},
 	*/
+	{
+		ID: 5,
+		Up: runFile("05-delivery-status.sql"),
+	},
+	{
+		ID: 6,
+		Up: runFile("06-next-attempt.sql"),
+	},
 }
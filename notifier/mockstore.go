@@ -11,7 +11,7 @@ type MockStore struct {
 	Notifications_         func(ctx context.Context, id uuid.UUID, page *Page) ([]Notification, Page, error)
 	PutNotifications_      func(ctx context.Context, opts PutOpts) error
 	PutReceipt_            func(ctx context.Context, updater string, r Receipt) error
-	CollectNotitfications_ func(ctx context.Context) error
+	CollectNotitfications_ func(ctx context.Context, opts CollectOpts) error
 	Receipt_               func(ctx context.Context, id uuid.UUID) (Receipt, error)
 	ReceiptByUOID_         func(ctx context.Context, id uuid.UUID) (Receipt, error)
 	Created_               func(ctx context.Context) ([]uuid.UUID, error)
@@ -51,8 +51,8 @@ func (m *MockStore) PutReceipt(ctx context.Context, updater string, r Receipt) e
 }
 
 // CollectNotifications garbage collects all notifications.
-func (m *MockStore) CollectNotifications(ctx context.Context) error {
-	return m.CollectNotitfications_(ctx)
+func (m *MockStore) CollectNotifications(ctx context.Context, opts CollectOpts) error {
+	return m.CollectNotitfications_(ctx, opts)
 }
 
 // Receipt returns the Receipt for a given notification id
@@ -0,0 +1,229 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// This file implements the subset of MQTT 5 needed to publish messages with
+// QoS 1: connecting, publishing, waiting for acknowledgements, and
+// disconnecting. Nothing is subscribed to, so the only packets expected from
+// the broker are CONNACK, PUBACK, and DISCONNECT.
+
+// Packet types, pre-shifted into the high nibble of the fixed header.
+const (
+	pktConnect    = 0x10
+	pktConnack    = 0x20
+	pktPublish    = 0x30
+	pktPuback     = 0x40
+	pktDisconnect = 0xe0
+)
+
+// Property identifiers used in PUBLISH packets.
+const (
+	propPayloadFormat = 0x01
+	propContentType   = 0x03
+)
+
+// MaxRemaining is the largest remaining length expressible in a fixed header.
+const maxRemaining = 268435455
+
+// Conn is an MQTT 5 client connection.
+type conn struct {
+	c      net.Conn
+	r      *bufio.Reader
+	nextID uint16
+}
+
+// ConnectOpts are the fields of a CONNECT packet.
+type connectOpts struct {
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive uint16
+}
+
+// ReasonError is a failure reason code sent by the broker.
+type reasonError struct {
+	packet string
+	code   byte
+}
+
+func (e *reasonError) Error() string {
+	return fmt.Sprintf("mqtt: %s: reason code %#02x", e.packet, e.code)
+}
+
+// Handshake sends a CONNECT packet over "c" and waits for the CONNACK.
+func handshake(c net.Conn, opts *connectOpts) (*conn, error) {
+	var b []byte
+	b = appendString(b, "MQTT")
+	b = append(b, 5)    // Protocol version.
+	flags := byte(0x02) // Clean start.
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint16(b, opts.KeepAlive)
+	b = appendVarint(b, 0) // No properties.
+	b = appendString(b, opts.ClientID)
+	if opts.Username != "" {
+		b = appendString(b, opts.Username)
+	}
+	if opts.Password != "" {
+		b = appendString(b, opts.Password)
+	}
+	cn := conn{c: c, r: bufio.NewReader(c)}
+	if err := cn.write(pktConnect, b); err != nil {
+		return nil, err
+	}
+
+	t, body, err := cn.read()
+	switch {
+	case err != nil:
+		return nil, err
+	case t == pktDisconnect:
+		return nil, disconnectError(body)
+	case t != pktConnack:
+		return nil, fmt.Errorf("mqtt: unexpected packet type %#02x awaiting CONNACK", t)
+	case len(body) < 2:
+		return nil, errors.New("mqtt: short CONNACK")
+	case body[1] >= 0x80:
+		return nil, &reasonError{packet: "CONNACK", code: body[1]}
+	}
+	return &cn, nil
+}
+
+// Publish sends "payload" to "topic" with QoS 1 and waits for the broker to
+// acknowledge it.
+func (c *conn) Publish(topic, contentType string, payload []byte) error {
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+
+	var props []byte
+	props = append(props, propPayloadFormat, 1) // UTF-8 payload.
+	props = append(props, propContentType)
+	props = appendString(props, contentType)
+
+	var b []byte
+	b = appendString(b, topic)
+	b = binary.BigEndian.AppendUint16(b, id)
+	b = appendVarint(b, len(props))
+	b = append(b, props...)
+	b = append(b, payload...)
+	if err := c.write(pktPublish|0x02, b); err != nil { // QoS 1.
+		return err
+	}
+
+	for {
+		t, body, err := c.read()
+		switch {
+		case err != nil:
+			return err
+		case t == pktDisconnect:
+			return disconnectError(body)
+		case t != pktPuback:
+			// Nothing else is expected, but nothing else is harmful either.
+			continue
+		case len(body) < 2:
+			return errors.New("mqtt: short PUBACK")
+		case binary.BigEndian.Uint16(body) != id:
+			continue
+		case len(body) > 2 && body[2] >= 0x80:
+			return &reasonError{packet: "PUBACK", code: body[2]}
+		}
+		return nil
+	}
+}
+
+// Close sends a normal DISCONNECT and closes the underlying connection.
+func (c *conn) Close() error {
+	werr := c.write(pktDisconnect, nil)
+	cerr := c.c.Close()
+	if werr != nil {
+		return werr
+	}
+	return cerr
+}
+
+// Write sends a packet with the fixed header byte "h".
+func (c *conn) write(h byte, body []byte) error {
+	if len(body) > maxRemaining {
+		return fmt.Errorf("mqtt: packet too large (%d bytes)", len(body))
+	}
+	b := make([]byte, 0, len(body)+5)
+	b = append(b, h)
+	b = appendVarint(b, len(body))
+	b = append(b, body...)
+	_, err := c.c.Write(b)
+	return err
+}
+
+// Read reads a packet, returning its type and body.
+func (c *conn) read() (byte, []byte, error) {
+	h, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := readVarint(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return h & 0xf0, body, nil
+}
+
+// DisconnectError reports a DISCONNECT sent by the broker.
+func disconnectError(body []byte) error {
+	if len(body) == 0 {
+		return errors.New("mqtt: disconnected by broker")
+	}
+	return &reasonError{packet: "DISCONNECT", code: body[0]}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendVarint(b []byte, n int) []byte {
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func readVarint(r io.ByteReader) (int, error) {
+	n, mul := 0, 1
+	for i := 0; i < 4; i++ {
+		d, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(d&0x7f) * mul
+		if d&0x80 == 0 {
+			return n, nil
+		}
+		mul *= 128
+	}
+	return 0, errors.New("mqtt: malformed remaining length")
+}
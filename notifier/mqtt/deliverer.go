@@ -0,0 +1,235 @@
+// Package mqtt publishes notifications to an MQTT 5 broker.
+//
+// Every notification is published as a message with QoS 1 to a topic
+// templated from the notification, so subscribers can pick out the severities
+// or manifests they care about with topic filters. Messages are JSON unless
+// another notifier.Serializer is configured.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer publishes the notifications in a notification set to an MQTT
+// broker.
+//
+// Messages are acknowledged individually, but if any publish fails the whole
+// set is retried, so subscribers may see a notification more than once.
+type Deliverer struct {
+	addr    string
+	host    string
+	tls     *tls.Config
+	connect connectOpts
+	topic   *template.Template
+	timeout time.Duration
+	ser     notifier.Serializer
+	n       []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+	_ notifier.Prober          = (*Deliverer)(nil)
+	_ notifier.Serializable    = (*Deliverer)(nil)
+)
+
+// New returns a new MQTT Deliverer.
+func New(conf *config.MQTT) (*Deliverer, error) {
+	if conf == nil {
+		return nil, errors.New("config not provided")
+	}
+	u, err := url.Parse(conf.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse broker: %w", err)
+	}
+	d := Deliverer{
+		host:    u.Hostname(),
+		timeout: time.Duration(conf.DialTimeout),
+		ser:     notifier.JSON,
+		connect: connectOpts{
+			ClientID: conf.ClientID,
+			Username: conf.Username,
+			Password: conf.Password,
+		},
+	}
+	if d.timeout <= 0 {
+		d.timeout = config.DefaultBrokerDialTimeout
+	}
+	port := u.Port()
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		if port == "" {
+			port = "1883"
+		}
+	case "mqtts", "ssl":
+		if port == "" {
+			port = "8883"
+		}
+		d.tls, err = conf.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		if d.tls.ServerName == "" {
+			d.tls.ServerName = d.host
+		}
+	default:
+		return nil, fmt.Errorf("broker %q: unknown scheme %q", conf.Broker, u.Scheme)
+	}
+	d.addr = net.JoinHostPort(d.host, port)
+	topic := conf.Topic
+	if topic == "" {
+		topic = config.DefaultMQTTTopic
+	}
+	if d.topic, err = template.New("topic").Parse(topic); err != nil {
+		return nil, fmt.Errorf("invalid topic template: %w", err)
+	}
+	return &d, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "mqtt"
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.addr
+}
+
+// SetSerializer implements notifier.Serializable.
+func (d *Deliverer) SetSerializer(s notifier.Serializer) {
+	d.ser = s
+}
+
+// Probe implements notifier.Prober.
+//
+// A successful probe means the broker accepted a connection.
+func (d *Deliverer) Probe(ctx context.Context) error {
+	c, err := d.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Deliver implements notifier.Deliverer.
+//
+// Deliver publishes each buffered notification and waits for the broker to
+// acknowledge it.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/mqtt/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	c, err := d.dial(ctx)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer c.Close()
+	// Unblock reads and writes if the Context is canceled.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.c.SetDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+	if dl, ok := ctx.Deadline(); ok {
+		c.c.SetDeadline(dl)
+	}
+
+	var b strings.Builder
+	for i := range d.n {
+		n := &d.n[i]
+		b.Reset()
+		if err := d.topic.Execute(&b, n); err != nil {
+			return fmt.Errorf("notification %v: unable to execute topic template: %w", n.ID, err)
+		}
+		topic := b.String()
+		if err := checkTopic(topic); err != nil {
+			return fmt.Errorf("notification %v: %w", n.ID, err)
+		}
+		payload, err := d.ser.Notification(ctx, n)
+		if err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+		if err := c.Publish(topic, d.ser.ContentType(), payload); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+	}
+	zlog.Info(ctx).
+		Str("broker", d.addr).
+		Int("count", len(d.n)).
+		Msg("published notifications")
+	return nil
+}
+
+// Dial connects to the broker, completing the TLS and MQTT handshakes within
+// the configured timeout.
+func (d *Deliverer) dial(ctx context.Context) (*conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	var nc net.Conn
+	var err error
+	if d.tls != nil {
+		td := tls.Dialer{Config: d.tls}
+		nc, err = td.DialContext(ctx, "tcp", d.addr)
+	} else {
+		var nd net.Dialer
+		nc, err = nd.DialContext(ctx, "tcp", d.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dl, _ := ctx.Deadline()
+	nc.SetDeadline(dl)
+	c, err := handshake(nc, &d.connect)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	nc.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// CheckTopic reports whether "t" may be published to: topic names must not
+// be empty or contain wildcards or NUL.
+func checkTopic(t string) error {
+	switch {
+	case t == "":
+		return errors.New("empty topic")
+	case strings.ContainsAny(t, "+#\x00"):
+		return fmt.Errorf("topic %q contains a wildcard or NUL", t)
+	case len(t) > 65535:
+		return errors.New("topic too long")
+	}
+	return nil
+}
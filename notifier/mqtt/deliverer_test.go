@@ -0,0 +1,205 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Broker is a fake MQTT broker recording the messages published to it.
+type broker struct {
+	l net.Listener
+	// Reject is the PUBACK reason code sent for every publish.
+	reject byte
+
+	mu       sync.Mutex
+	clientID string
+	username string
+	topics   []string
+	payloads [][]byte
+}
+
+func newBroker(t *testing.T) *broker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{l: l}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(t, c)
+		}
+	}()
+	return b
+}
+
+func (b *broker) serve(t *testing.T, nc net.Conn) {
+	defer nc.Close()
+	c := &conn{c: nc, r: bufio.NewReader(nc)}
+	for {
+		typ, body, err := c.read()
+		if err != nil {
+			return
+		}
+		switch typ {
+		case pktConnect:
+			// Skip the protocol name, version, flags, keep alive, and empty
+			// properties.
+			flags := body[7]
+			p := body[11:]
+			next := func() string {
+				n := binary.BigEndian.Uint16(p)
+				s := string(p[2 : 2+n])
+				p = p[2+n:]
+				return s
+			}
+			b.mu.Lock()
+			b.clientID = next()
+			if flags&0x80 != 0 {
+				b.username = next()
+			}
+			b.mu.Unlock()
+			c.write(pktConnack, []byte{0, 0, 0})
+		case pktPublish:
+			n := binary.BigEndian.Uint16(body)
+			topic := string(body[2 : 2+n])
+			rest := body[2+n:]
+			id := rest[:2]
+			// Properties are shorter than 128 bytes here, so their length
+			// takes one byte.
+			payload := rest[3+int(rest[2]):]
+			b.mu.Lock()
+			b.topics = append(b.topics, topic)
+			b.payloads = append(b.payloads, payload)
+			b.mu.Unlock()
+			c.write(pktPuback, []byte{id[0], id[1], b.reject, 0})
+		case pktDisconnect:
+			return
+		default:
+			t.Errorf("unexpected packet type: %#02x", typ)
+			return
+		}
+	}
+}
+
+func notifications() []notifier.Notification {
+	return []notifier.Notification{
+		{
+			ID:            uuid.New(),
+			Manifest:      claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+			Reason:        notifier.Added,
+			Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3711", Severity: "High"},
+		},
+		{
+			ID:            uuid.New(),
+			Manifest:      claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64)),
+			Reason:        notifier.Added,
+			Vulnerability: notifier.VulnSummary{Name: "CVE-2020-1971", Severity: "Medium"},
+		},
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b := newBroker(t)
+	d, err := New(&config.MQTT{
+		Broker:   "mqtt://" + b.l.Addr().String(),
+		ClientID: "clair-test",
+		Username: "clair",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Probe(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ns := notifications()
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if got, want := b.clientID, "clair-test"; got != want {
+		t.Errorf("client id: got: %q, want: %q", got, want)
+	}
+	if got, want := b.username, "clair"; got != want {
+		t.Errorf("username: got: %q, want: %q", got, want)
+	}
+	want := []string{
+		"clair/notifications/High/" + ns[0].Manifest.String(),
+		"clair/notifications/Medium/" + ns[1].Manifest.String(),
+	}
+	if got := b.topics; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for i, p := range b.payloads {
+		var n notifier.Notification
+		if err := json.Unmarshal(p, &n); err != nil {
+			t.Fatalf("payload %d: %v", i, err)
+		}
+		if got, want := n.ID, ns[i].ID; got != want {
+			t.Errorf("payload %d: got: %v, want: %v", i, got, want)
+		}
+	}
+}
+
+func TestDeliverRejected(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b := newBroker(t)
+	b.reject = 0x87 // Not authorized.
+	d, err := New(&config.MQTT{Broker: "tcp://" + b.l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Notifications(ctx, notifications())
+	err = d.Deliver(ctx, uuid.New())
+	var re *reasonError
+	var de *clairerror.ErrDeliveryFailed
+	switch {
+	case !errors.As(err, &de):
+		t.Errorf("got: %v, want delivery failure", err)
+	case !errors.As(err, &re) || re.code != 0x87:
+		t.Errorf("got: %v, want reason code 0x87", err)
+	}
+}
+
+func TestCheckTopic(t *testing.T) {
+	for _, tc := range []struct {
+		Topic string
+		OK    bool
+	}{
+		{"clair/High/sha256:abc", true},
+		{"", false},
+		{"clair/+/x", false},
+		{"clair/#", false},
+	} {
+		if got := checkTopic(tc.Topic) == nil; got != tc.OK {
+			t.Errorf("%q: got ok: %v, want: %v", tc.Topic, got, tc.OK)
+		}
+	}
+}
@@ -12,6 +12,12 @@
 	Added   Reason = "added"
 	Removed Reason = "removed"
 	Changed Reason = "changed"
+	// FixAvailable means a vulnerability affecting the manifest previously
+	// had no fixed version, and now does.
+	FixAvailable Reason = "fix_available"
+	// SeverityChanged means the severity of a vulnerability affecting the
+	// manifest changed.
+	SeverityChanged Reason = "severity_changed"
 )
 
 // Notification summarizes a change in the vulnerabilities affecting a manifest.
@@ -27,4 +33,7 @@ type Notification struct {
 	Manifest      claircore.Digest `json:"manifest"`
 	Reason        Reason           `json:"reason"`
 	Vulnerability VulnSummary      `json:"vulnerability"`
+	// Labels are the labels submitted with the manifest, if the indexer
+	// stores them.
+	Labels map[string]string `json:"labels,omitempty"`
 }
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Sequencer is implemented by Stores that can report the order notification
+// sets were created in and the manifests they mention, so that a Delivery can
+// deliver the sets mentioning a manifest in order.
+type Sequencer interface {
+	// Sequence returns the provided notification IDs that still exist,
+	// oldest first, along with the manifests each one mentions.
+	Sequence(ctx context.Context, ids []uuid.UUID) ([]Sequenced, error)
+}
+
+// Sequenced is a notification set and the digests of the manifests it
+// mentions.
+type Sequenced struct {
+	ID        uuid.UUID
+	Manifests []string
+}
+
+// Holds tracks the manifests mentioned by notification sets that weren't
+// delivered in a delivery run. A later set mentioning one of them must wait
+// for the next run.
+type holds map[string]struct{}
+
+// Held reports whether any of the manifests mentioned by "s" are held.
+func (h holds) held(s *Sequenced) bool {
+	for _, m := range s.Manifests {
+		if _, ok := h[m]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Hold holds all the manifests mentioned by "s".
+func (h holds) hold(s *Sequenced) {
+	for _, m := range s.Manifests {
+		h[m] = struct{}{}
+	}
+}
@@ -0,0 +1,256 @@
+package notifier
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+var pageCacheCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "clair",
+		Subsystem: "notifier",
+		Name:      "page_cache_total",
+		Help:      "Total number of notification page lookups and pre-materializations, by outcome.",
+	},
+	[]string{"result"},
+)
+
+// PageSource returns pages of notifications.
+type PageSource interface {
+	Notifications(ctx context.Context, id uuid.UUID, page *Page) ([]Notification, Page, error)
+}
+
+// PageOptions configures a PageCache.
+type PageOptions struct {
+	// Hot is the number of page requests for a notification within TTL
+	// after which its pages are kept.
+	Hot int
+	// Size is the maximum number of pages kept.
+	Size int
+	// TTL is how long pages are kept, and the window requests are counted
+	// in.
+	TTL time.Duration
+}
+
+// PageCache keeps the pages of hot notifications, so that the many clients a
+// large notification may be delivered to don't each have the pages assembled
+// from the database.
+//
+// Clients page through a notification in order, so once a notification is
+// hot, the page after each one served is materialized in the background
+// before it's asked for. Notification bodies don't change once created, so
+// kept pages only need to expire.
+type PageCache struct {
+	ctx  context.Context
+	src  PageSource
+	opts PageOptions
+
+	mu        sync.Mutex
+	hits      map[uuid.UUID]*pageHits
+	lastSweep time.Time
+	pages     map[pageKey]*list.Element
+	// Order holds the pages oldest first. Pages all live for the same TTL,
+	// so this is also expiry order.
+	order list.List
+}
+
+type pageHits struct {
+	n       int
+	expires time.Time
+}
+
+// PageKey identifies a page. The Filter is included by value, so identical
+// filters share pages.
+type pageKey struct {
+	id     uuid.UUID
+	next   uuid.UUID
+	size   int
+	filter Filter
+}
+
+type materialized struct {
+	key     pageKey
+	expires time.Time
+	// Ready is closed once the fields below are populated.
+	ready chan struct{}
+	ns    []Notification
+	out   Page
+	err   error
+}
+
+// NewPageCache returns a PageCache serving pages from "src". Pages
+// materialized in the background are fetched using "ctx".
+//
+// If "opts" is nil or its Hot member isn't positive, nil is returned; a nil
+// *PageCache can't be used.
+func NewPageCache(ctx context.Context, src PageSource, opts *PageOptions) *PageCache {
+	if opts == nil || opts.Hot < 1 {
+		return nil
+	}
+	c := &PageCache{
+		ctx:   zlog.ContextWithValues(ctx, "component", "notifier/PageCache"),
+		src:   src,
+		opts:  *opts,
+		hits:  make(map[uuid.UUID]*pageHits),
+		pages: make(map[pageKey]*list.Element),
+	}
+	if c.opts.Size < 1 {
+		c.opts.Size = 1
+	}
+	return c
+}
+
+// Notifications returns the requested page, from the cache if possible.
+//
+// Requests for all of a notification's notifications at once are passed
+// through. Kept pages are shared, so the returned slice must not be modified.
+func (c *PageCache) Notifications(ctx context.Context, id uuid.UUID, page *Page) ([]Notification, Page, error) {
+	if page == nil {
+		return c.src.Notifications(ctx, id, page)
+	}
+	key := pageKey{id: id, size: page.Size}
+	if page.Next != nil {
+		key.next = *page.Next
+	}
+	if page.Filter != nil {
+		key.filter = *page.Filter
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	c.expire(now)
+	h, ok := c.hits[id]
+	if !ok || !now.Before(h.expires) {
+		h = &pageHits{expires: now.Add(c.opts.TTL)}
+		c.hits[id] = h
+	}
+	h.n++
+	hot := h.n >= c.opts.Hot
+	el, cached := c.pages[key]
+	c.mu.Unlock()
+
+	if cached {
+		m := el.Value.(*materialized)
+		select {
+		case <-m.ready:
+		case <-ctx.Done():
+			return nil, Page{}, ctx.Err()
+		}
+		if m.err == nil {
+			pageCacheCounter.WithLabelValues("hit").Inc()
+			c.prefetch(key, &m.out, now)
+			return m.ns, m.out, nil
+		}
+		// The background fetch failed; fall back to fetching it here.
+	}
+	pageCacheCounter.WithLabelValues("miss").Inc()
+	ns, out, err := c.src.Notifications(ctx, id, page)
+	if err != nil || !hot {
+		return ns, out, err
+	}
+	m := &materialized{key: key, ready: make(chan struct{}), ns: ns, out: out}
+	close(m.ready)
+	c.mu.Lock()
+	c.insert(m, now)
+	c.mu.Unlock()
+	c.prefetch(key, &out, now)
+	return ns, out, nil
+}
+
+// Forget drops the pages kept for the notification "id".
+func (c *PageCache) Forget(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hits, id)
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*materialized).key.id == id {
+			c.evict(el)
+		}
+		el = next
+	}
+}
+
+// Prefetch materializes the page following "out" in the background, if
+// there is one and it isn't already kept.
+func (c *PageCache) prefetch(prev pageKey, out *Page, now time.Time) {
+	if out.Next == nil {
+		return
+	}
+	key := prev
+	key.next = *out.Next
+	c.mu.Lock()
+	if _, ok := c.pages[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	m := &materialized{key: key, ready: make(chan struct{})}
+	c.insert(m, now)
+	c.mu.Unlock()
+
+	pageCacheCounter.WithLabelValues("prefetch").Inc()
+	go func() {
+		defer close(m.ready)
+		next := key.next
+		p := Page{Size: key.size, Next: &next}
+		if key.filter != (Filter{}) {
+			f := key.filter
+			p.Filter = &f
+		}
+		m.ns, m.out, m.err = c.src.Notifications(c.ctx, key.id, &p)
+		if m.err != nil {
+			zlog.Debug(c.ctx).
+				Err(m.err).
+				Stringer("notification_id", key.id).
+				Msg("unable to materialize page")
+			c.mu.Lock()
+			if el, ok := c.pages[key]; ok && el.Value == m {
+				c.evict(el)
+			}
+			c.mu.Unlock()
+		}
+	}()
+}
+
+// Insert adds "m", evicting the oldest pages if the cache is full. The caller
+// must hold the lock.
+func (c *PageCache) insert(m *materialized, now time.Time) {
+	if el, ok := c.pages[m.key]; ok {
+		c.evict(el)
+	}
+	for c.order.Len() >= c.opts.Size {
+		c.evict(c.order.Front())
+	}
+	m.expires = now.Add(c.opts.TTL)
+	c.pages[m.key] = c.order.PushBack(m)
+}
+
+// Evict removes the page at "el". The caller must hold the lock.
+func (c *PageCache) evict(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.pages, el.Value.(*materialized).key)
+}
+
+// Expire removes expired pages, and periodically expired request counts. The
+// caller must hold the lock.
+func (c *PageCache) expire(now time.Time) {
+	for f := c.order.Front(); f != nil && !now.Before(f.Value.(*materialized).expires); f = c.order.Front() {
+		c.evict(f)
+	}
+	if now.Sub(c.lastSweep) < c.opts.TTL {
+		return
+	}
+	c.lastSweep = now
+	for id, h := range c.hits {
+		if !now.Before(h.expires) {
+			delete(c.hits, id)
+		}
+	}
+}
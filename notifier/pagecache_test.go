@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CountingSource serves fixed pages, counting the calls for each.
+type countingSource struct {
+	mu    sync.Mutex
+	calls map[uuid.UUID]int
+	pages [][]Notification
+}
+
+func (s *countingSource) Notifications(_ context.Context, _ uuid.UUID, p *Page) ([]Notification, Page, error) {
+	i := 0
+	if p.Next != nil && *p.Next != uuid.Nil {
+		for j, pg := range s.pages {
+			if pg[len(pg)-1].ID == *p.Next {
+				i = j + 1
+			}
+		}
+	}
+	s.mu.Lock()
+	s.calls[s.pages[i][0].ID]++
+	s.mu.Unlock()
+	out := Page{Size: p.Size}
+	if i < len(s.pages)-1 {
+		out.Next = &s.pages[i][len(s.pages[i])-1].ID
+	}
+	return s.pages[i], out, nil
+}
+
+func (s *countingSource) count(i int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[s.pages[i][0].ID]
+}
+
+func TestPageCache(t *testing.T) {
+	ctx := context.Background()
+	src := &countingSource{calls: make(map[uuid.UUID]int)}
+	for i := 0; i < 3; i++ {
+		src.pages = append(src.pages, []Notification{{ID: uuid.New()}, {ID: uuid.New()}})
+	}
+	id := uuid.New()
+	c := NewPageCache(ctx, src, &PageOptions{Hot: 2, Size: 8, TTL: time.Minute})
+
+	fetch := func() {
+		t.Helper()
+		p := &Page{Size: 2}
+		for {
+			ns, out, err := c.Notifications(ctx, id, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ns) != 2 {
+				t.Fatalf("got %d notifications, want 2", len(ns))
+			}
+			if out.Next == nil {
+				return
+			}
+			p = &Page{Size: 2, Next: out.Next}
+		}
+	}
+
+	// The second page request makes the notification hot, so the page after
+	// it is materialized ahead of the request.
+	fetch()
+	for i := 0; i < 3; i++ {
+		if got, want := src.count(i), 1; got != want {
+			t.Errorf("page %d: got: %d calls, want: %d", i, got, want)
+		}
+	}
+	// The first page wasn't kept the first time around.
+	fetch()
+	if got, want := src.count(0), 2; got != want {
+		t.Errorf("first page: got: %d calls, want: %d", got, want)
+	}
+	// Kept pages are served from the cache.
+	fetch()
+	for i, want := range []int{2, 1, 1} {
+		if got := src.count(i); got != want {
+			t.Errorf("page %d: got: %d calls, want: %d", i, got, want)
+		}
+	}
+
+	// Forgotten notifications go back to the source.
+	c.Forget(id)
+	fetch()
+	if got, want := src.count(0), 3; got != want {
+		t.Errorf("after forget: got: %d calls, want: %d", got, want)
+	}
+}
+
+func TestPageCacheDisabled(t *testing.T) {
+	if c := NewPageCache(context.Background(), nil, nil); c != nil {
+		t.Error("expected nil cache")
+	}
+	if c := NewPageCache(context.Background(), nil, &PageOptions{}); c != nil {
+		t.Error("expected nil cache")
+	}
+}
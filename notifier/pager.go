@@ -1,7 +1,12 @@
 package notifier
 
 import (
+	"strings"
+
 	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/severity"
 )
 
 // Page communicates a bare-minimum paging protocol with clients.
@@ -10,4 +15,61 @@ type Page struct {
 	Next *uuid.UUID `json:"next,omitempty"`
 	// the max number of elements returned in a page
 	Size int `json:"size"`
+	// an optional filter restricting the notifications returned.
+	//
+	// The filter is not communicated back to clients, so they must provide
+	// the same filter with every request for a consistent set of pages.
+	Filter *Filter `json:"-"`
+}
+
+// Filter restricts the notifications returned for a notification ID.
+//
+// The zero value matches every notification.
+type Filter struct {
+	// return only notifications with a vulnerability at or above this severity
+	Severity claircore.Severity
+	// return only notifications with a vulnerability for a distribution with
+	// this name or DID
+	Distribution string
+	// return only notifications with a vulnerability that has a fix available
+	FixedOnly bool
+	// return only notifications for manifests with digests starting with this
+	// string
+	ManifestPrefix string
+}
+
+// Severities reports the names of the severities at or above the Filter's
+// Severity, or nil if there's no severity restriction.
+func (f *Filter) Severities() []string {
+	if f.Severity == claircore.Unknown {
+		return nil
+	}
+	var ss []string
+	for s := f.Severity; s <= claircore.Critical; s++ {
+		ss = append(ss, s.String())
+	}
+	return ss
+}
+
+// Match reports whether the Notification passes the Filter.
+func (f *Filter) Match(n *Notification) bool {
+	v := &n.Vulnerability
+	if f.Severity != claircore.Unknown {
+		if s, ok := severity.Parse(v.Severity); !ok || s < f.Severity {
+			return false
+		}
+	}
+	if f.Distribution != "" {
+		d := v.Distribution
+		if d == nil || (d.Name != f.Distribution && d.DID != f.Distribution) {
+			return false
+		}
+	}
+	if f.FixedOnly && v.FixedInVersion == "" {
+		return false
+	}
+	if f.ManifestPrefix != "" && !strings.HasPrefix(n.Manifest.String(), f.ManifestPrefix) {
+		return false
+	}
+	return true
 }
@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestFilter(t *testing.T) {
+	d := claircore.MustParseDigest(`sha256:` + "ab00000000000000000000000000000000000000000000000000000000000000")
+	n := Notification{
+		Manifest: d,
+		Vulnerability: VulnSummary{
+			Severity:       claircore.High.String(),
+			Distribution:   &claircore.Distribution{Name: "Debian GNU/Linux", DID: "debian"},
+			FixedInVersion: "1.2.3",
+		},
+	}
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "Zero", want: true},
+		{name: "SeverityBelow", filter: Filter{Severity: claircore.Medium}, want: true},
+		{name: "SeverityEqual", filter: Filter{Severity: claircore.High}, want: true},
+		{name: "SeverityAbove", filter: Filter{Severity: claircore.Critical}, want: false},
+		{name: "DistributionDID", filter: Filter{Distribution: "debian"}, want: true},
+		{name: "DistributionName", filter: Filter{Distribution: "Debian GNU/Linux"}, want: true},
+		{name: "DistributionOther", filter: Filter{Distribution: "rhel"}, want: false},
+		{name: "FixedOnly", filter: Filter{FixedOnly: true}, want: true},
+		{name: "ManifestPrefix", filter: Filter{ManifestPrefix: "sha256:ab"}, want: true},
+		{name: "ManifestOther", filter: Filter{ManifestPrefix: "sha256:cd"}, want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, want := tc.filter.Match(&n), tc.want; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+
+	unfixed := n
+	unfixed.Vulnerability.FixedInVersion = ""
+	if (&Filter{FixedOnly: true}).Match(&unfixed) {
+		t.Error("unfixed vulnerability matched FixedOnly filter")
+	}
+	if got := (&Filter{Severity: claircore.High}).Severities(); len(got) != 2 {
+		t.Errorf("severities: got: %v", got)
+	}
+}
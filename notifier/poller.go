@@ -5,10 +5,13 @@
 	"errors"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/zlog"
 
 	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/leader"
 	"github.com/quay/clair/v4/matcher"
 )
 
@@ -17,6 +20,15 @@
 	MaxChanSize = 1024
 )
 
+var eventQueueDepth = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "clair",
+		Subsystem: "notifier",
+		Name:      "poll_queue_depth",
+		Help:      "Number of update operation events awaiting processing, as of the last poll.",
+	},
+)
+
 // PollerOpt applies a configuration to a Poller
 type PollerOpt func(*Poller) error
 
@@ -29,6 +41,11 @@ type Poller struct {
 	differ matcher.Differ
 	// the interval to poll a Matcher node.
 	interval time.Duration
+	// Backpressure pauses polling while delivery is backlogged, if not nil.
+	Backpressure *Backpressure
+	// Wake, if not nil, triggers a poll ahead of the interval whenever it
+	// receives.
+	Wake <-chan struct{}
 }
 
 func NewPoller(store Store, differ matcher.Differ, interval time.Duration) *Poller {
@@ -52,9 +69,27 @@ type Event struct {
 //
 // Cancel ctx to stop the poller.
 func (p *Poller) Poll(ctx context.Context, c chan<- Event) error {
-	ctx = zlog.ContextWithValues(ctx, "component", "notifier/Poller.poll")
+	defer close(c)
+	return p.poll(ctx, c)
+}
 
+// PollElected is like Poll, but only polls while this process holds the
+// "notifier-poller" leadership role, acquired via "l".
+//
+// This method takes ownership of the channel and is responsible for closing
+// it.
+func (p *Poller) PollElected(ctx context.Context, l Locker, c chan<- Event) error {
 	defer close(c)
+	leader.Run(ctx, l, "notifier-poller", func(ctx context.Context) {
+		p.poll(ctx, c)
+	})
+	return ctx.Err()
+}
+
+// Poll is the polling loop. It doesn't close the channel.
+func (p *Poller) poll(ctx context.Context, c chan<- Event) error {
+	ctx = zlog.ContextWithValues(ctx, "component", "notifier/Poller.poll")
+
 	if err := ctx.Err(); err != nil {
 		zlog.Info(ctx).
 			Msg("context canceled before polling began")
@@ -74,6 +109,10 @@ func (p *Poller) Poll(ctx context.Context, c chan<- Event) error {
 			zlog.Debug(ctx).
 				Msg("poll interval tick")
 			p.onTick(ctx, c)
+		case <-p.Wake:
+			zlog.Debug(ctx).
+				Msg("poll woken")
+			p.onTick(ctx, c)
 		}
 	}
 }
@@ -83,6 +122,13 @@ func (p *Poller) Poll(ctx context.Context, c chan<- Event) error {
 func (p *Poller) onTick(ctx context.Context, c chan<- Event) {
 	ctx = zlog.ContextWithValues(ctx, "component", "notifier/Poller.onTick")
 
+	eventQueueDepth.Set(float64(len(c)))
+	if p.Backpressure.Paused() {
+		zlog.Info(ctx).
+			Msg("too many notifications awaiting delivery. skipping poll until next interval")
+		return
+	}
+
 	latest, err := p.differ.LatestUpdateOperations(ctx, driver.VulnerabilityKind)
 	if err != nil {
 		zlog.Error(ctx).
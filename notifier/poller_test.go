@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestPollerWake(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	uo := driver.UpdateOperation{Ref: uuid.New(), Updater: "test"}
+	m := &matcher.Mock{
+		LatestUpdateOperations_: func(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+			return map[string][]driver.UpdateOperation{"test": {uo}}, nil
+		},
+	}
+	s := &MockStore{
+		ReceiptByUOID_: func(_ context.Context, id uuid.UUID) (Receipt, error) {
+			return Receipt{}, &clairerror.ErrNoReceipt{NotificationID: id}
+		},
+	}
+	wake := make(chan struct{}, 1)
+	// The interval is long enough that only waking can cause a poll.
+	p := NewPoller(s, m, time.Hour)
+	p.Wake = wake
+	c := make(chan Event, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Poll(ctx, c)
+	}()
+	// Wait for the poller to stop, so it doesn't log after the test ends.
+	defer func() { <-done }()
+	defer cancel()
+
+	wake <- struct{}{}
+	select {
+	case e := <-c:
+		if got, want := e.uo.Ref, uo.Ref; got != want {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for event")
+	}
+}
@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+var (
+	_ notifier.AttemptRecorder = (*Store)(nil)
+	_ notifier.RetryScheduler  = (*Store)(nil)
+)
+
+var (
+	recordAttemptCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "recordattempt_total",
+			Help:      "Total number of database queries issued in the recordAttempt method",
+		},
+		[]string{"query", "error"},
+	)
+	recordAttemptDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "recordattempt_duration_seconds",
+			Help:      "Duration of all queries issued in the recordAttempt method",
+		},
+		[]string{"query", "error"},
+	)
+	deliveryReportCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "deliveryreport_total",
+			Help:      "Total number of database queries issued in the deliveryReport method",
+		},
+		[]string{"query", "error"},
+	)
+	deliveryReportDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "deliveryreport_duration_seconds",
+			Help:      "Duration of all queries issued in the deliveryReport method",
+		},
+		[]string{"query", "error"},
+	)
+	retryStatesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "retrystates_total",
+			Help:      "Total number of database queries issued in the retryStates method",
+		},
+		[]string{"query", "error"},
+	)
+	retryStatesDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "retrystates_duration_seconds",
+			Help:      "Duration of all queries issued in the retryStates method",
+		},
+		[]string{"query", "error"},
+	)
+)
+
+// RecordAttempt implements notifier.AttemptRecorder.
+func (s *Store) RecordAttempt(ctx context.Context, a *notifier.Attempt) error {
+	const query = `
+INSERT INTO delivery_status
+	(notification_id, deliverer, destination, attempts, last_attempt, last_error, delivered, next_attempt)
+VALUES
+	($1, $2, $3, 1, $4, $5, CASE WHEN $5::text IS NULL THEN $4::timestamptz END, $6)
+ON CONFLICT (notification_id) DO UPDATE SET
+	deliverer = EXCLUDED.deliverer,
+	destination = EXCLUDED.destination,
+	attempts = delivery_status.attempts + 1,
+	last_attempt = EXCLUDED.last_attempt,
+	last_error = EXCLUDED.last_error,
+	delivered = coalesce(delivery_status.delivered, EXCLUDED.delivered),
+	next_attempt = EXCLUDED.next_attempt;`
+	var msg *string
+	if a.Err != nil {
+		m := a.Err.Error()
+		msg = &m
+	}
+	var next *time.Time
+	if !a.Next.IsZero() {
+		next = &a.Next
+	}
+	var err error
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		recordAttemptDuration.WithLabelValues("upsert", errLabel(err)).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+	_, err = s.pool.Exec(ctx, query, a.NotificationID, a.Deliverer, a.Destination, a.TS, msg, next)
+	recordAttemptCounter.WithLabelValues("upsert", errLabel(err)).Add(1)
+	if err != nil {
+		return &clairerror.ErrReceipt{
+			NotificationID: a.NotificationID,
+			E:              err,
+		}
+	}
+	return nil
+}
+
+// DeliveryReport implements notifier.AttemptRecorder.
+func (s *Store) DeliveryReport(ctx context.Context, id uuid.UUID) (*notifier.DeliveryReport, error) {
+	const query = `
+SELECT
+	r.notification_id, r.uo_id, r.status, r.ts,
+	coalesce(d.deliverer, ''), coalesce(d.destination, ''), coalesce(d.attempts, 0),
+	d.last_attempt, coalesce(d.last_error, ''), d.delivered, d.next_attempt
+FROM receipt r
+LEFT JOIN delivery_status d USING (notification_id)
+WHERE r.notification_id = $1::uuid;`
+	var r notifier.DeliveryReport
+	err := s.pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		var err error
+		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+			deliveryReportDuration.WithLabelValues("query", errLabel(err)).Observe(v)
+		}))
+		defer timer.ObserveDuration()
+		err = c.QueryRow(ctx, query, id).Scan(
+			&r.NotificationID,
+			&r.UpdateID,
+			&r.Status,
+			&r.StatusUpdated,
+			&r.Deliverer,
+			&r.Destination,
+			&r.Attempts,
+			&r.LastAttempt,
+			&r.LastError,
+			&r.Delivered,
+			&r.NextAttempt,
+		)
+		deliveryReportCounter.WithLabelValues("query", errLabel(err)).Add(1)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return &clairerror.ErrNoReceipt{
+				NotificationID: id,
+			}
+		case err != nil:
+			return &clairerror.ErrReceipt{
+				NotificationID: id,
+				E:              err,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// RetryStates implements notifier.RetryScheduler.
+func (s *Store) RetryStates(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]notifier.RetryState, error) {
+	const query = `
+SELECT notification_id, attempts, next_attempt
+FROM delivery_status
+WHERE notification_id = ANY($1::uuid[]);`
+	out := make(map[uuid.UUID]notifier.RetryState)
+	err := s.pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		var err error
+		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+			retryStatesDuration.WithLabelValues("query", errLabel(err)).Observe(v)
+		}))
+		defer timer.ObserveDuration()
+		var rows pgx.Rows
+		rows, err = c.Query(ctx, query, ids)
+		retryStatesCounter.WithLabelValues("query", errLabel(err)).Add(1)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id uuid.UUID
+			var st notifier.RetryState
+			var next *time.Time
+			if err = rows.Scan(&id, &st.Attempts, &next); err != nil {
+				return err
+			}
+			if next != nil {
+				st.Next = *next
+			}
+			out[id] = st
+		}
+		err = rows.Err()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read retry states: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+func TestDeliveryReport(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	store := TestingStore(ctx, t)
+
+	nID := uuid.New()
+	if _, err := store.DeliveryReport(ctx, nID); !errors.As(err, new(*clairerror.ErrNoReceipt)) {
+		t.Fatalf("unknown notification: got: %v", err)
+	}
+	if err := store.PutReceipt(ctx, "test-updater", notifier.Receipt{
+		NotificationID: nID,
+		UOID:           uuid.New(),
+		Status:         notifier.Created,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.DeliveryReport(ctx, nID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Attempts != 0 || r.LastAttempt != nil || r.Status != notifier.Created {
+		t.Errorf("no attempts: got: %+v", r)
+	}
+
+	first := time.Now().Truncate(time.Millisecond)
+	second := first.Add(time.Second)
+	for _, a := range []notifier.Attempt{
+		{TS: first, Err: errors.New("connection refused")},
+		{TS: second},
+		// A later failure doesn't change the delivery time.
+		{TS: second.Add(time.Second), Err: errors.New("redelivered")},
+	} {
+		a.NotificationID = nID
+		a.Deliverer = "webhook"
+		a.Destination = "http://example.com/"
+		if err := store.RecordAttempt(ctx, &a); err != nil {
+			t.Fatal(err)
+		}
+		if a.Err == nil {
+			r, err := store.DeliveryReport(ctx, nID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.LastError != "" || r.Delivered == nil || !r.Delivered.Equal(second) {
+				t.Errorf("delivered: got: %+v", r)
+			}
+		}
+	}
+	r, err = store.DeliveryReport(ctx, nID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Attempts != 3 || r.LastError != "redelivered" || r.Deliverer != "webhook" {
+		t.Errorf("attempts: got: %+v", r)
+	}
+	if r.Delivered == nil || !r.Delivered.Equal(second) {
+		t.Errorf("delivered: got: %v, want: %v", r.Delivered, second)
+	}
+
+	// A scheduled retry is reported, and cleared by the next attempt.
+	next := second.Add(time.Minute)
+	if err := store.RecordAttempt(ctx, &notifier.Attempt{
+		NotificationID: nID,
+		Deliverer:      "webhook",
+		TS:             second.Add(2 * time.Second),
+		Err:            errors.New("still down"),
+		Next:           next,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	states, err := store.RetryStates(ctx, []uuid.UUID{nID, uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(states), 1; got != want {
+		t.Errorf("states: got: %d, want: %d", got, want)
+	}
+	if st := states[nID]; st.Attempts != 4 || !st.Next.Equal(next) {
+		t.Errorf("state: got: %+v", st)
+	}
+	r, err = store.DeliveryReport(ctx, nID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NextAttempt == nil || !r.NextAttempt.Equal(next) {
+		t.Errorf("next attempt: got: %v, want: %v", r.NextAttempt, next)
+	}
+	if err := store.RecordAttempt(ctx, &notifier.Attempt{
+		NotificationID: nID,
+		Deliverer:      "webhook",
+		TS:             next,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	states, err = store.RetryStates(ctx, []uuid.UUID{nID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := states[nID]; !st.Next.IsZero() {
+		t.Errorf("cleared: got: %+v", st)
+	}
+}
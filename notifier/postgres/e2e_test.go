@@ -7,6 +7,7 @@
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -343,7 +344,8 @@ func (e *e2e) CollectNotifications(ctx context.Context) func(*testing.T) {
 			t.Error(err)
 		}
 
-		if err := e.store.CollectNotifications(ctx); err != nil {
+		opts := notifier.CollectOpts{DeliveredAge: 14 * 24 * time.Hour}
+		if err := e.store.CollectNotifications(ctx, opts); err != nil {
 			t.Error(err)
 		}
 
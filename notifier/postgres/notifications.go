@@ -11,6 +11,7 @@
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/errgroup"
 
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/notifier"
@@ -64,6 +65,14 @@
 		},
 		[]string{"query", "error"},
 	)
+	gcNotificationLast = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "collectnotification_last_success_timestamp_seconds",
+			Help:      "Time of the last completed run of the CollectNotification method, in seconds since the Unix epoch",
+		},
+	)
 
 	putNotificationsCounter = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -100,6 +109,21 @@ func (s *Store) Notifications(ctx context.Context, id uuid.UUID, page *notifier.
 	const (
 		query      = "SELECT id, body FROM notification_body WHERE notification_id = $1::uuid"
 		pagedQuery = "SELECT id, body FROM notification_body WHERE notification_id = $1::uuid AND id > $2 ORDER BY id ASC LIMIT $3"
+		// FilteredQuery is pagedQuery with additional constraints on the
+		// body. Unset filter arguments are passed as NULL, empty, or false
+		// and match every row.
+		filteredQuery = `SELECT id, body FROM notification_body
+WHERE
+	notification_id = $1::uuid
+	AND id > $2
+	AND ($4::text[] IS NULL OR body->'vulnerability'->>'severity' = ANY($4::text[]))
+	AND ($5::text = ''
+		OR body->'vulnerability'->'distribution'->>'name' = $5::text
+		OR body->'vulnerability'->'distribution'->>'did' = $5::text)
+	AND (NOT $6::boolean OR body->'vulnerability'->>'fixed_in_version' <> '')
+	AND ($7::text = '' OR starts_with(body->>'manifest', $7::text))
+ORDER BY id ASC
+LIMIT $3;`
 	)
 
 	// If no page argument, early return all notifications.
@@ -115,18 +139,11 @@ func (s *Store) Notifications(ctx context.Context, id uuid.UUID, page *notifier.
 			var rows pgx.Rows
 			rows, err = c.Query(ctx, query, id)
 			notificationsCounter.WithLabelValues(`query`, errLabel(err)).Add(1)
-			for rows.Next() {
-				ns = append(ns, notifier.Notification{})
-				n := &ns[len(ns)-1]
-				if err := rows.Scan(&n.ID, n); err != nil {
-					return err
-				}
-			}
-
-			if err := rows.Err(); err != nil {
+			if err != nil {
 				return err
 			}
-			return nil
+			ns, err = s.readNotifications(ctx, rows, ns)
+			return err
 		})
 		if err != nil {
 			return nil, p, &clairerror.ErrBadNotification{
@@ -152,27 +169,28 @@ func (s *Store) Notifications(ctx context.Context, id uuid.UUID, page *notifier.
 	// Add one to limit to determine if there is another page to fetch.
 	limit := page.Size + 1
 
+	name, q := `pagedQuery`, pagedQuery
+	args := []interface{}{id, page.Next, limit}
+	if f := page.Filter; f != nil {
+		name, q = `filteredQuery`, filteredQuery
+		args = append(args, f.Severities(), f.Distribution, f.FixedOnly, f.ManifestPrefix)
+	}
+
 	ns := make([]notifier.Notification, 0, limit)
 	err := s.pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
 		var err error
 		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-			notificationsDuration.WithLabelValues(`pagedQuery`, errLabel(err)).Observe(v)
+			notificationsDuration.WithLabelValues(name, errLabel(err)).Observe(v)
 		}))
 		defer timer.ObserveDuration()
 		var rows pgx.Rows
-		rows, err = c.Query(ctx, pagedQuery, id, page.Next, limit)
-		notificationsCounter.WithLabelValues(`pagedQuery`, errLabel(err)).Add(1)
-		for rows.Next() {
-			ns = append(ns, notifier.Notification{})
-			n := &ns[len(ns)-1]
-			if err := rows.Scan(&n.ID, n); err != nil {
-				return err
-			}
-		}
-		if err := rows.Err(); err != nil {
+		rows, err = c.Query(ctx, q, args...)
+		notificationsCounter.WithLabelValues(name, errLabel(err)).Add(1)
+		if err != nil {
 			return err
 		}
-		return nil
+		ns, err = s.readNotifications(ctx, rows, ns)
+		return err
 	})
 	if err != nil {
 		return nil, notifier.Page{}, &clairerror.ErrBadNotification{
@@ -195,6 +213,73 @@ func (s *Store) Notifications(ctx context.Context, id uuid.UUID, page *notifier.
 	return ns, outPage, nil
 }
 
+// ReadNotifications appends the notifications in "rows" to "ns".
+//
+// Decoding the bodies dominates assembling a large page, so if PageWorkers
+// allows, the raw bodies are read first and decoded in parallel.
+func (s *Store) readNotifications(ctx context.Context, rows pgx.Rows, ns []notifier.Notification) ([]notifier.Notification, error) {
+	defer rows.Close()
+	if s.PageWorkers < 2 {
+		for rows.Next() {
+			ns = append(ns, notifier.Notification{})
+			n := &ns[len(ns)-1]
+			if err := rows.Scan(&n.ID, n); err != nil {
+				return ns, err
+			}
+		}
+		return ns, rows.Err()
+	}
+
+	start := len(ns)
+	var bodies [][]byte
+	for rows.Next() {
+		ns = append(ns, notifier.Notification{})
+		n := &ns[len(ns)-1]
+		var b []byte
+		if err := rows.Scan(&n.ID, &b); err != nil {
+			return ns, err
+		}
+		bodies = append(bodies, b)
+	}
+	if err := rows.Err(); err != nil {
+		return ns, err
+	}
+	out := ns[start:]
+	w := s.PageWorkers
+	if w > len(out) {
+		w = len(out)
+	}
+	if w < 2 {
+		for i := range out {
+			if err := json.Unmarshal(bodies[i], &out[i]); err != nil {
+				return ns, err
+			}
+		}
+		return ns, nil
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	chunk := (len(out) + w - 1) / w
+	for lo := 0; lo < len(out); lo += chunk {
+		hi := lo + chunk
+		if hi > len(out) {
+			hi = len(out)
+		}
+		out, bodies := out[lo:hi], bodies[lo:hi]
+		eg.Go(func() error {
+			for i := range out {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := json.Unmarshal(bodies[i], &out[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return ns, eg.Wait()
+}
+
 // PutNotifications persists the provided notifications and associates them with
 // the provided notification ID.
 //
@@ -321,21 +406,38 @@ func (r *notificationSource) Columns() []string {
 // Normally Receipter.SetDeleted will be issued first, however application logic
 // may decide to gc notifications which have not been set deleted after some
 // period of time, thus this condition should not be checked.
-func (s *Store) CollectNotifications(ctx context.Context) error {
+//
+// Notifications are removed along with their receipts and bodies; update
+// operations no longer referenced by any receipt are removed afterwards.
+func (s *Store) CollectNotifications(ctx context.Context, opts notifier.CollectOpts) error {
 	ctx = zlog.ContextWithValues(ctx, "component", "notifier/postgres/Store.CollectNotifications")
 	const (
 		tryLock            = `SELECT pg_try_advisory_xact_lock($1, $2);`
 		deleteNotification = `DELETE FROM notification USING receipt WHERE id = receipt.notification_id AND receipt.status = 'deleted'::receiptstatus;`
-		deleteUpdateOp     = `DELETE FROM notifier_update_operation
+		deleteDelivered    = `DELETE FROM notification USING receipt
+	WHERE
+		id = receipt.notification_id
+		AND receipt.status = 'delivered'::receiptstatus
+		AND receipt.ts < (now() - make_interval(secs => $1));`
+		deleteUndelivered = `DELETE FROM notification USING receipt
+	WHERE
+		id = receipt.notification_id
+		AND receipt.status IN ('created'::receiptstatus, 'delivery_failed'::receiptstatus)
+		AND receipt.ts < (now() - make_interval(secs => $1));`
+		// The receipt's timestamp changes with its status, so excess
+		// notifications are ordered by when their update operation was
+		// recorded, which is when they were created.
+		deleteExcess = `DELETE FROM notification
+	WHERE id IN (
+		SELECT r.notification_id FROM receipt r
+		JOIN notifier_update_operation uo ON uo.uo_id = r.uo_id
+		ORDER BY uo.ts DESC, r.notification_id
+		OFFSET $1);`
+		deleteUpdateOp = `DELETE FROM notifier_update_operation
 	WHERE uo_id IN (
 		SELECT uo_id FROM notifier_update_operation
 		EXCEPT
 		SELECT uo_id FROM receipt);`
-		deleteReceipts = `DELETE FROM receipt
-	WHERE
-		ts < date_trunc('day', (now() - INTERVAL '14 days'))
-		AND
-		status <> 'created'::receiptstatus;`
 	)
 	txOpt := pgx.TxOptions{
 		IsoLevel:   pgx.ReadCommitted,
@@ -347,6 +449,7 @@ func (s *Store) CollectNotifications(ctx context.Context) error {
 		affected: gcNotificationAffected,
 	}
 
+	var ran bool
 	err := s.pool.BeginTxFunc(ctx, txOpt, func(tx pgx.Tx) error {
 		var ok bool
 		if err := tx.QueryRow(ctx, tryLock, adminKeyspace, gcLock).Scan(&ok); err != nil {
@@ -359,16 +462,37 @@ func (s *Store) CollectNotifications(ctx context.Context) error {
 		if err := txExec(ctx, metrics, tx, "deleteNotification", deleteNotification, nil); err != nil {
 			return err
 		}
-		if err := txExec(ctx, metrics, tx, "deleteReceipts", deleteReceipts, nil); err != nil {
-			return err
+		if opts.DeliveredAge > 0 {
+			args := []interface{}{opts.DeliveredAge.Seconds()}
+			if err := txExec(ctx, metrics, tx, "deleteDelivered", deleteDelivered, args); err != nil {
+				return err
+			}
+		}
+		if opts.UndeliveredAge > 0 {
+			args := []interface{}{opts.UndeliveredAge.Seconds()}
+			if err := txExec(ctx, metrics, tx, "deleteUndelivered", deleteUndelivered, args); err != nil {
+				return err
+			}
+		}
+		if opts.MaxCount > 0 {
+			args := []interface{}{opts.MaxCount}
+			if err := txExec(ctx, metrics, tx, "deleteExcess", deleteExcess, args); err != nil {
+				return err
+			}
 		}
 		if err := txExec(ctx, metrics, tx, "deleteUpdateOp", deleteUpdateOp, nil); err != nil {
 			return err
 		}
+		ran = true
 		return nil
 	})
 	if err != nil {
 		return err
 	}
+	if ran {
+		gcNotificationLast.SetToCurrentTime()
+	} else {
+		zlog.Debug(ctx).Msg("another process holds the gc lock, skipping")
+	}
 	return nil
 }
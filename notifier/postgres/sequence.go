@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+var _ notifier.Sequencer = (*Store)(nil)
+
+var (
+	sequenceCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "sequence_total",
+			Help:      "Total number of database queries issued in the sequence method",
+		},
+		[]string{"query", "error"},
+	)
+	sequenceDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "sequence_duration_seconds",
+			Help:      "Duration of all queries issued in the sequence method",
+		},
+		[]string{"query", "error"},
+	)
+)
+
+// Sequence implements notifier.Sequencer.
+//
+// Notification sets are ordered by the time their update operation was
+// recorded, which is when the set was created.
+func (s *Store) Sequence(ctx context.Context, ids []uuid.UUID) ([]notifier.Sequenced, error) {
+	const query = `
+SELECT
+	r.notification_id,
+	array_remove(array_agg(DISTINCT b.body->>'manifest'), NULL)
+FROM receipt r
+JOIN notifier_update_operation uo ON uo.uo_id = r.uo_id
+LEFT JOIN notification_body b ON b.notification_id = r.notification_id
+WHERE r.notification_id = ANY($1::uuid[])
+GROUP BY r.notification_id, uo.ts
+ORDER BY uo.ts, r.notification_id;`
+	out := make([]notifier.Sequenced, 0, len(ids))
+	err := s.pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		var err error
+		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+			sequenceDuration.WithLabelValues("query", errLabel(err)).Observe(v)
+		}))
+		defer timer.ObserveDuration()
+		var rows pgx.Rows
+		rows, err = c.Query(ctx, query, ids)
+		sequenceCounter.WithLabelValues("query", errLabel(err)).Add(1)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s notifier.Sequenced
+			if err = rows.Scan(&s.ID, &s.Manifests); err != nil {
+				return err
+			}
+			out = append(out, s)
+		}
+		err = rows.Err()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to sequence notifications: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+func TestSequence(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	store := TestingStore(ctx, t)
+
+	a := claircore.MustParseDigest(`sha256:` + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b := claircore.MustParseDigest(`sha256:` + "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	var want []notifier.Sequenced
+	for _, ms := range [][]claircore.Digest{{a, b}, {b}, {}} {
+		opts := notifier.PutOpts{
+			Updater:        "test-updater",
+			UpdateID:       uuid.New(),
+			NotificationID: uuid.New(),
+		}
+		s := notifier.Sequenced{ID: opts.NotificationID, Manifests: []string{}}
+		for _, m := range ms {
+			opts.Notifications = append(opts.Notifications, notifier.Notification{
+				ID:       uuid.New(),
+				Manifest: m,
+				Reason:   notifier.Added,
+			})
+			s.Manifests = append(s.Manifests, m.String())
+		}
+		if err := store.PutNotifications(ctx, opts); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, s)
+	}
+
+	// Ask in reverse, to make sure the order comes from the store.
+	ids := []uuid.UUID{want[2].ID, want[1].ID, want[0].ID, uuid.New()}
+	got, err := store.Sequence(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
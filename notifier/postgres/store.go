@@ -19,13 +19,17 @@
 // Store implements the notifier.Store interface.
 type Store struct {
 	pool *pgxpool.Pool
+	// PageWorkers is the number of goroutines decoding the notifications
+	// returned by the Notifications method. If less than 2, they're decoded
+	// as they're read.
+	PageWorkers int
 }
 
 // NewStore returns a Store using the passed-in Pool.
 //
 // The caller should close the Pool once the store is no longer needed.
 func NewStore(pool *pgxpool.Pool) *Store {
-	return &Store{pool}
+	return &Store{pool: pool}
 }
 
 // Init initializes the database using the specified config.
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+var _ notifier.UsageReporter = (*Store)(nil)
+
+var (
+	usageCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "usage_total",
+			Help:      "Total number of database queries issued in the usage method",
+		},
+		[]string{"query", "error"},
+	)
+	usageDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "usage_duration_seconds",
+			Help:      "Duration of all queries issued in the usage method",
+		},
+		[]string{"query", "error"},
+	)
+)
+
+// Usage implements notifier.UsageReporter.
+func (s *Store) Usage(ctx context.Context) (*notifier.Usage, error) {
+	const (
+		count = `SELECT status::text, count(*) FROM receipt GROUP BY 1;`
+		size  = `SELECT pg_database_size(current_database());`
+	)
+	u := notifier.Usage{Notifications: make(map[notifier.Status]int64)}
+	err := s.pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		var err error
+		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+			usageDuration.WithLabelValues("count", errLabel(err)).Observe(v)
+		}))
+		rows, err := c.Query(ctx, count)
+		if err == nil {
+			for rows.Next() {
+				var st notifier.Status
+				var n int64
+				if err = rows.Scan(&st, &n); err != nil {
+					break
+				}
+				u.Notifications[st] = n
+			}
+			rows.Close()
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+		timer.ObserveDuration()
+		usageCounter.WithLabelValues("count", errLabel(err)).Add(1)
+		if err != nil {
+			return fmt.Errorf("unable to count notifications: %w", err)
+		}
+
+		timer = prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+			usageDuration.WithLabelValues("size", errLabel(err)).Observe(v)
+		}))
+		err = c.QueryRow(ctx, size).Scan(&u.DatabaseBytes)
+		timer.ObserveDuration()
+		usageCounter.WithLabelValues("size", errLabel(err)).Add(1)
+		if err != nil {
+			return fmt.Errorf("unable to get database size: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.Backlog = u.Notifications[notifier.Created] + u.Notifications[notifier.DeliveryFailed]
+	return &u, nil
+}
@@ -37,6 +37,8 @@ type Processor struct {
 	//
 	// The zero value makes the default behavior to do the summary.
 	NoSummary bool
+	// Throttle paces requests for affected manifests, if not nil.
+	Throttle *Throttle
 }
 
 func NewProcessor(store Store, l Locker, indexer indexer.Service, matcher matcher.Service) *Processor {
@@ -101,36 +103,56 @@ func (p *Processor) create(ctx context.Context, e Event, prev uuid.UUID) error {
 	if err != nil {
 		return fmt.Errorf("failed to get update diff: %v", err)
 	}
+	added, removed, changes := splitDiff(diff)
 	zlog.Debug(ctx).
-		Int("removed", len(diff.Removed)).
-		Int("added", len(diff.Added)).
+		Int("removed", len(removed)).
+		Int("added", len(added)).
+		Int("changed", len(changes)).
 		Msg("diff results")
 
-	tab := notifTab{
-		N:      make([]Notification, 0),
-		lookup: make(map[string]int),
+	var fixed, severity, prior []claircore.Vulnerability
+	for _, c := range changes {
+		switch c.Reason {
+		case FixAvailable:
+			fixed = append(fixed, c.Cur)
+		case SeverityChanged:
+			severity = append(severity, c.Cur)
+		}
+		prior = append(prior, c.Prior)
 	}
+	tab := newNotifTab(!p.NoSummary)
+	// Changed records are collected separately, so that manifests affected
+	// by the prior record but not the current one can be told it was
+	// removed.
+	changedTab := newNotifTab(false)
+	priorTab := newNotifTab(false)
 	eg, wctx := errgroup.WithContext(ctx)
-	eg.Go(getAffected(wctx, p.indexer, p.NoSummary, diff.Added, Added, &tab))
-	eg.Go(getAffected(wctx, p.indexer, p.NoSummary, diff.Removed, Removed, &tab))
+	eg.SetLimit(p.Throttle.limit())
+	eg.Go(getAffected(wctx, p.indexer, p.Throttle, added, Added, tab))
+	eg.Go(getAffected(wctx, p.indexer, p.Throttle, removed, Removed, tab))
+	eg.Go(getAffected(wctx, p.indexer, p.Throttle, fixed, FixAvailable, changedTab))
+	eg.Go(getAffected(wctx, p.indexer, p.Throttle, severity, SeverityChanged, changedTab))
+	eg.Go(getAffected(wctx, p.indexer, p.Throttle, prior, Removed, priorTab))
 	if err := eg.Wait(); err != nil {
 		return fmt.Errorf("failed to get affected manifests: %v", err)
 	}
+	if err := tab.merge(changedTab, priorTab); err != nil {
+		return fmt.Errorf("failed to merge notifications: %v", err)
+	}
+
+	p.label(ctx, tab.N)
 
 	// Don't count up the affected manifests unless we're going to print it.
 	if ev := zlog.Debug(ctx); ev.Enabled() {
-		var added, removed int
+		ct := make(map[Reason]int)
 		for _, n := range tab.N {
-			switch n.Reason {
-			case Added:
-				added++
-			case Removed:
-				removed++
-			}
+			ct[n.Reason]++
 		}
 		ev.
-			Int("added", added).
-			Int("removed", removed).
+			Int("added", ct[Added]).
+			Int("removed", ct[Removed]).
+			Int("fix_available", ct[FixAvailable]).
+			Int("severity_changed", ct[SeverityChanged]).
 			Msg("affected manifest counts")
 	}
 
@@ -164,6 +186,49 @@ func (p *Processor) create(ctx context.Context, e Event, prev uuid.UUID) error {
 	return nil
 }
 
+// Label attaches the labels stored for each manifest to the notifications, if
+// the indexer stores labels.
+//
+// Labels are auxiliary, so a failed lookup is logged and the notifications are
+// created without them.
+func (p *Processor) label(ctx context.Context, ns []Notification) {
+	const chunk = 1000
+	l, ok := p.indexer.(indexer.Labeler)
+	if !ok || len(ns) == 0 {
+		return
+	}
+	seen := make(map[string]struct{})
+	var ds []claircore.Digest
+	for _, n := range ns {
+		k := n.Manifest.String()
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		ds = append(ds, n.Manifest)
+	}
+	all := make(map[string]map[string]string)
+	for len(ds) > 0 {
+		s := ds[:min(chunk, len(ds))]
+		ds = ds[len(s):]
+		ls, err := l.Labels(ctx, s...)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, indexer.ErrUnsupported):
+			return
+		default:
+			zlog.Warn(ctx).Err(err).Msg("unable to look up manifest labels")
+			return
+		}
+		for k, v := range ls {
+			all[k] = v
+		}
+	}
+	for i := range ns {
+		ns[i].Labels = all[ns[i].Manifest.String()]
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -176,25 +241,94 @@ func min(a, b int) int {
 // It has supporting structures for concurrent use and summaries.
 type notifTab struct {
 	sync.Mutex
-	lookup map[string]int // only used in "summary" mode
-	N      []Notification
+	summary bool
+	lookup  map[string]int // only used in "summary" mode
+	N       []Notification
 }
 
-// GetAffected issues AffectedManifest calls in chunks and merges the result.
+func newNotifTab(summary bool) *notifTab {
+	return &notifTab{
+		summary: summary,
+		lookup:  make(map[string]int),
+		N:       make([]Notification, 0),
+	}
+}
+
+// Add records the Notification "n".
+//
+// In summary mode, only the most severe vulnerability for a given manifest is
+// kept, along with the reason it was reported for.
+func (t *notifTab) add(n Notification) error {
+	t.Lock()
+	defer t.Unlock()
+	if !t.summary {
+		t.N = append(t.N, n)
+		return nil
+	}
+	key := n.Manifest.String()
+	i, ok := t.lookup[key]
+	if !ok {
+		// If this is the first appearance of this manifest, insert it.
+		t.lookup[key] = len(t.N)
+		t.N = append(t.N, n)
+		return nil
+	}
+	// If we've seen this before, check the severity and swap if the new vuln
+	// is more severe.
+	var cur, next claircore.Severity
+	if err := cur.UnmarshalText([]byte(t.N[i].Vulnerability.Severity)); err != nil {
+		return err
+	}
+	if err := next.UnmarshalText([]byte(n.Vulnerability.Severity)); err != nil {
+		return err
+	}
+	if cur < next {
+		t.N[i] = n
+	}
+	return nil
+}
+
+// Merge adds the notifications for changed records in "changed", and the
+// notifications in "prior" for manifests that were affected by a changed
+// record's prior version but aren't affected by the current one.
+func (t *notifTab) merge(changed, prior *notifTab) error {
+	still := make(map[string]struct{}, len(changed.N))
+	for _, n := range changed.N {
+		still[n.Manifest.String()+"/"+n.Vulnerability.Name] = struct{}{}
+		if err := t.add(n); err != nil {
+			return err
+		}
+	}
+	for _, n := range prior.N {
+		if _, ok := still[n.Manifest.String()+"/"+n.Vulnerability.Name]; ok {
+			continue
+		}
+		if err := t.add(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAffected issues AffectedManifest calls in chunks, paced by "th", and
+// merges the result.
 //
 // Its signature is weird to make use in an errgroup a little bit nicer.
-func getAffected(ctx context.Context, ic indexer.Service, nosummary bool, vs []claircore.Vulnerability, r Reason, out *notifTab) func() error {
-	const chunk = 1000
+func getAffected(ctx context.Context, ic indexer.Service, th *Throttle, vs []claircore.Vulnerability, r Reason, out *notifTab) func() error {
+	chunk := th.chunkSize()
+	affectedPending.Add(float64(len(vs)))
 	return func() error {
+		// Whatever isn't requested is no longer pending once this returns.
+		defer func() { affectedPending.Sub(float64(len(vs))) }()
 		var s []claircore.Vulnerability
-		for len(vs) > 0 {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
+		for first := true; len(vs) > 0; first = false {
+			if err := th.wait(ctx, first); err != nil {
+				return err
 			}
 			s = vs[:min(chunk, len(vs))]
 			vs = vs[len(s):]
+			affectedPending.Sub(float64(len(s)))
+			affectedRequests.WithLabelValues(string(r)).Inc()
 			a, err := ic.AffectedManifests(ctx, s)
 			if err != nil {
 				return err
@@ -206,55 +340,18 @@ func getAffected(ctx context.Context, ic indexer.Service, nosummary bool, vs []c
 				}
 				// The vulns slice is sorted most severe to lease severe, so
 				// when in summary mode, we only need to check the initial vuln.
-				if !nosummary {
-					vuln := a.Vulnerabilities[vulns[0]]
-					key := digest.String()
-					var n *Notification
-					out.Lock()
-					// First, lookup if there's a notification for this
-					// manifest.
-					i, ok := out.lookup[key]
-					if ok {
-						n = &out.N[i]
-					}
-					if n == nil {
-						// If this is the first appearance of this manifest,
-						// insert it.
-						i := len(out.N)
-						out.N = append(out.N, Notification{
-							Manifest: digest,
-							Reason:   r,
-						})
-						out.lookup[key] = i
-						n = &out.N[i]
-						n.Vulnerability.FromVulnerability(vuln)
-					} else {
-						// If we've seen this before, check the severity and
-						// swap if the new vuln is more severe.
-						var sev claircore.Severity
-						if err := sev.UnmarshalText([]byte(n.Vulnerability.Severity)); err != nil {
-							out.Unlock()
-							return err
-						}
-						if sev < vuln.NormalizedSeverity {
-							n.Vulnerability.FromVulnerability(vuln)
-						}
-					}
-					out.Unlock()
-					continue
+				if out.summary && len(vulns) > 1 {
+					vulns = vulns[:1]
 				}
-				// If not in summary, create all the notifications.
-				for idx := range vulns {
-					vuln := a.Vulnerabilities[vulns[idx]]
-					out.Lock()
-					i := len(out.N)
-					out.N = append(out.N, Notification{
+				for _, id := range vulns {
+					n := Notification{
 						Manifest: digest,
 						Reason:   r,
-					})
-					n := &out.N[i]
-					n.Vulnerability.FromVulnerability(vuln)
-					out.Unlock()
+					}
+					n.Vulnerability.FromVulnerability(a.Vulnerabilities[id])
+					if err := out.add(n); err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -74,6 +74,7 @@ func TestProcessCreate(t *testing.T) {
 	t.Run("MatcherErr", testProcessorMatcherErr)
 	t.Run("IndexerErr", testProcessorIndexerErr)
 	t.Run("StoreErr", testProcessorStoreErr)
+	t.Run("Changes", testProcessorChanges)
 }
 
 // testProcessorStoreErr confirms create fails when the store is not
@@ -266,3 +267,110 @@ func testProcessorCreate(t *testing.T) {
 		t.Fatalf("unexpected err: %v", err)
 	}
 }
+
+// testProcessorChanges confirms records replacing a prior record create
+// "fix_available" and "severity_changed" notifications.
+func testProcessorChanges(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	e := Event{
+		updater: testUpdater,
+		uo:      processorUpdateOps[testUpdater][0],
+	}
+	pkg := &claircore.Package{Name: "openssl"}
+	unfixed := claircore.Vulnerability{
+		ID: "10", Updater: testUpdater, Name: "CVE-2024-0001", Package: pkg,
+		NormalizedSeverity: claircore.High,
+	}
+	fixed := unfixed
+	fixed.ID, fixed.FixedInVersion = "11", "3.0.1"
+	low := claircore.Vulnerability{
+		ID: "20", Updater: testUpdater, Name: "CVE-2024-0002", Package: pkg,
+		NormalizedSeverity: claircore.Low,
+	}
+	critical := low
+	critical.ID, critical.NormalizedSeverity = "21", claircore.Critical
+	const (
+		manifestA = `sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`
+		// ManifestB has the fixed version installed.
+		manifestB = `sha256:fc92eec5cac70b0c324cec2933cd7db1c0eae7c9e2649e42d02e77eb6da0d15f`
+	)
+	affected := map[string][]string{
+		unfixed.ID:  {manifestA, manifestB},
+		fixed.ID:    {manifestA},
+		low.ID:      {manifestA},
+		critical.ID: {manifestA},
+	}
+	mm := &matcher.Mock{
+		UpdateDiff_: func(context.Context, uuid.UUID, uuid.UUID) (*driver.UpdateDiff, error) {
+			return &driver.UpdateDiff{
+				Added:   []claircore.Vulnerability{fixed, critical},
+				Removed: []claircore.Vulnerability{unfixed, low},
+			}, nil
+		},
+	}
+	im := &indexer.Mock{
+		AffectedManifests_: func(_ context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			out := claircore.NewAffectedManifests()
+			for i := range vs {
+				v := &vs[i]
+				for _, m := range affected[v.ID] {
+					out.Add(v, claircore.MustParseDigest(m))
+				}
+			}
+			return &out, nil
+		},
+	}
+	type result struct {
+		Manifest string
+		Reason   Reason
+		Name     string
+	}
+	// Summaries keep only the most severe change for each manifest.
+	want := map[bool][]result{
+		false: {
+			{Manifest: manifestA, Reason: SeverityChanged, Name: critical.Name},
+			{Manifest: manifestB, Reason: Removed, Name: unfixed.Name},
+		},
+		true: {
+			{Manifest: manifestA, Reason: FixAvailable, Name: unfixed.Name},
+			{Manifest: manifestA, Reason: SeverityChanged, Name: low.Name},
+			{Manifest: manifestB, Reason: Removed, Name: unfixed.Name},
+		},
+	}
+	var nosummary bool
+	sm := &MockStore{
+		PutNotifications_: func(_ context.Context, opts PutOpts) error {
+			got := make([]result, len(opts.Notifications))
+			for i, n := range opts.Notifications {
+				got[i] = result{
+					Manifest: n.Manifest.String(),
+					Reason:   n.Reason,
+					Name:     n.Vulnerability.Name,
+				}
+			}
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].Manifest != got[j].Manifest {
+					return got[i].Manifest < got[j].Manifest
+				}
+				return got[i].Reason < got[j].Reason
+			})
+			if want := want[nosummary]; !cmp.Equal(got, want) {
+				t.Errorf("nosummary %v: %s", nosummary, cmp.Diff(got, want))
+			}
+			return nil
+		},
+	}
+
+	for _, nosummary = range []bool{false, true} {
+		p := Processor{
+			store:     sm,
+			indexer:   im,
+			matcher:   mm,
+			NoSummary: nosummary,
+		}
+		if err := p.create(ctx, e, uuid.Nil); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+}
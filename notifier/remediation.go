@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Change is a vulnerability record that replaced a prior record for the same
+// advisory and package.
+type change struct {
+	Reason Reason
+	Prior  claircore.Vulnerability
+	Cur    claircore.Vulnerability
+}
+
+// SplitDiff separates the records in an update diff that replace a prior
+// record in a way downstream remediation cares about from plain additions and
+// removals.
+//
+// An updater changing a record shows up in the diff as the old record being
+// removed and the new record being added. Such a pair is reported as a change
+// if a fixed version appeared or the severity changed. Pairs that can't be
+// matched unambiguously, or that changed in some other way, are reported as
+// additions and removals, as before.
+func splitDiff(diff *driver.UpdateDiff) (added, removed []claircore.Vulnerability, cs []change) {
+	prior := make(map[string][]int, len(diff.Removed))
+	for i := range diff.Removed {
+		k := vulnKey(&diff.Removed[i])
+		prior[k] = append(prior[k], i)
+	}
+	cur := make(map[string][]int, len(diff.Added))
+	for i := range diff.Added {
+		k := vulnKey(&diff.Added[i])
+		cur[k] = append(cur[k], i)
+	}
+
+	paired := make(map[int]struct{})
+	for i := range diff.Added {
+		v := &diff.Added[i]
+		k := vulnKey(v)
+		if len(cur[k]) != 1 || len(prior[k]) != 1 {
+			added = append(added, *v)
+			continue
+		}
+		j := prior[k][0]
+		p := &diff.Removed[j]
+		var r Reason
+		switch {
+		case p.FixedInVersion == "" && v.FixedInVersion != "":
+			r = FixAvailable
+		case p.NormalizedSeverity != v.NormalizedSeverity:
+			r = SeverityChanged
+		default:
+			added = append(added, *v)
+			continue
+		}
+		paired[j] = struct{}{}
+		cs = append(cs, change{Reason: r, Prior: *p, Cur: *v})
+	}
+	for i := range diff.Removed {
+		if _, ok := paired[i]; ok {
+			continue
+		}
+		removed = append(removed, diff.Removed[i])
+	}
+	return added, removed, cs
+}
+
+// VulnKey identifies the advisory and package a vulnerability record is
+// about, independent of the record's contents.
+func vulnKey(v *claircore.Vulnerability) string {
+	var b strings.Builder
+	w := func(s ...string) {
+		for _, s := range s {
+			b.WriteString(s)
+			b.WriteByte(0)
+		}
+	}
+	w(v.Updater, v.Name)
+	if p := v.Package; p != nil {
+		w(p.Name, p.Kind, p.Module, p.Arch)
+	}
+	if d := v.Dist; d != nil {
+		w(d.DID, d.VersionID, d.Version, d.Arch)
+	}
+	if r := v.Repo; r != nil {
+		w(r.Name, r.Key, r.URI)
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetryState is the recorded delivery state of a notification set.
+type RetryState struct {
+	// Attempts is the number of delivery attempts made.
+	Attempts int
+	// Next is the earliest time the next attempt should be made. It's the
+	// zero Time if no retry is scheduled.
+	Next time.Time
+}
+
+// RetryScheduler is implemented by Stores that keep the schedule of retries
+// recorded with delivery attempts, so that a restarted notifier resumes the
+// schedule instead of retrying everything at once.
+type RetryScheduler interface {
+	// RetryStates returns the RetryState of each of the provided
+	// notification IDs that has recorded attempts.
+	RetryStates(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]RetryState, error)
+}
+
+// Retry schedules the next delivery attempt of a notification set after a
+// failed one, backing off exponentially.
+//
+// The schedule is recorded with the failed attempt, so a Retry only has an
+// effect if the Store is an AttemptRecorder and a RetryScheduler. A nil
+// *Retry attempts failed notification sets on every delivery run.
+type Retry struct {
+	backoff time.Duration
+	max     time.Duration
+	now     func() time.Time
+}
+
+// NewRetry returns a Retry waiting "backoff" after the first failed attempt,
+// doubling for each subsequent one up to "max".
+//
+// A non-positive "max" doesn't limit the wait.
+func NewRetry(backoff, max time.Duration) *Retry {
+	return &Retry{
+		backoff: backoff,
+		max:     max,
+		now:     time.Now,
+	}
+}
+
+// Next returns when to next attempt delivery after "failures" consecutive
+// failed attempts, the last made at "ts".
+func (r *Retry) next(failures int, ts time.Time) time.Time {
+	if r == nil || failures < 1 {
+		return time.Time{}
+	}
+	d := r.backoff
+	for i := 1; i < failures && (r.max <= 0 || d < r.max) && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if r.max > 0 && d > r.max {
+		d = r.max
+	}
+	return ts.Add(d)
+}
+
+// Due returns the notification IDs in "ids" whose next attempt isn't
+// scheduled after the current time, and the recorded states of all of "ids".
+//
+// If the Retry is nil or the store doesn't keep retry schedules, "ids" is
+// returned unmodified.
+func (r *Retry) due(ctx context.Context, s Store, ids []uuid.UUID) ([]uuid.UUID, map[uuid.UUID]RetryState, error) {
+	rs, ok := s.(RetryScheduler)
+	if r == nil || !ok || len(ids) == 0 {
+		return ids, nil, nil
+	}
+	states, err := rs.RetryStates(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := r.now()
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if st, ok := states[id]; ok && st.Next.After(now) {
+			continue
+		}
+		out = append(out, id)
+	}
+	return out, states, nil
+}
@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRetryNext(t *testing.T) {
+	ts := time.Unix(0, 0)
+	r := NewRetry(time.Second, time.Minute)
+	for _, tc := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{7, time.Minute},
+		{1000, time.Minute},
+	} {
+		if got := r.next(tc.failures, ts).Sub(ts); got != tc.want {
+			t.Errorf("%d failures: got: %v, want: %v", tc.failures, got, tc.want)
+		}
+	}
+	unbounded := NewRetry(time.Second, 0)
+	if got := unbounded.next(1000, ts); !got.After(ts) {
+		t.Errorf("unbounded: got: %v", got)
+	}
+	var none *Retry
+	if got := none.next(3, ts); !got.IsZero() {
+		t.Errorf("nil: got: %v", got)
+	}
+}
+
+// RetryStore is a Store keeping attempts in memory.
+type retryStore struct {
+	MockStore
+	states map[uuid.UUID]RetryState
+}
+
+func (s *retryStore) RetryStates(_ context.Context, ids []uuid.UUID) (map[uuid.UUID]RetryState, error) {
+	out := make(map[uuid.UUID]RetryState)
+	for _, id := range ids {
+		if st, ok := s.states[id]; ok {
+			out[id] = st
+		}
+	}
+	return out, nil
+}
+
+func (s *retryStore) RecordAttempt(_ context.Context, a *Attempt) error {
+	st := s.states[a.NotificationID]
+	st.Attempts++
+	st.Next = a.Next
+	s.states[a.NotificationID] = st
+	return nil
+}
+
+func (s *retryStore) DeliveryReport(context.Context, uuid.UUID) (*DeliveryReport, error) {
+	panic("unimplemented")
+}
+
+func TestRetryDue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	waiting, due, fresh := uuid.New(), uuid.New(), uuid.New()
+	s := &retryStore{states: map[uuid.UUID]RetryState{
+		// As if recorded before a restart.
+		waiting: {Attempts: 3, Next: now.Add(time.Minute)},
+		due:     {Attempts: 1, Next: now.Add(-time.Second)},
+	}}
+	r := NewRetry(time.Second, time.Minute)
+	r.now = func() time.Time { return now }
+
+	got, states, err := r.due(ctx, s, []uuid.UUID{waiting, due, fresh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != due || got[1] != fresh {
+		t.Errorf("due: got: %v, want: [%v %v]", got, due, fresh)
+	}
+	if got, want := states[waiting].Attempts, 3; got != want {
+		t.Errorf("attempts: got: %d, want: %d", got, want)
+	}
+
+	// A failed attempt of "due" schedules its next attempt after its second
+	// failure.
+	d := &Delivery{Deliverer: &selfTestDeliverer{}, store: s, Retry: r}
+	before := time.Now()
+	d.record(ctx, due, errors.New("down"), states[due])
+	st := s.states[due]
+	if got, want := st.Attempts, 2; got != want {
+		t.Errorf("attempts: got: %d, want: %d", got, want)
+	}
+	if got, want := st.Next.Sub(before), 2*time.Second; got < want || got > want+time.Minute {
+		t.Errorf("next: got: %v after the attempt, want: %v", got, want)
+	}
+
+	// Without a Retry, everything is due.
+	var none *Retry
+	if got, _, _ := none.due(ctx, s, []uuid.UUID{waiting}); len(got) != 1 {
+		t.Errorf("nil: got: %v", got)
+	}
+}
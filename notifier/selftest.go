@@ -0,0 +1,85 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SelfTester is implemented by Services that can send a test notification
+// through their configured delivery mechanism.
+type SelfTester interface {
+	SelfTest(context.Context) (*SelfTestResult, error)
+}
+
+// SelfTestResult reports the outcome of a self-test.
+type SelfTestResult struct {
+	Deliverer      string    `json:"deliverer"`
+	Destination    string    `json:"destination,omitempty"`
+	NotificationID uuid.UUID `json:"notification_id"`
+	// Probe is only populated if the Deliverer implements Prober.
+	Probe    *SelfTestStep `json:"probe,omitempty"`
+	Delivery *SelfTestStep `json:"delivery,omitempty"`
+	OK       bool          `json:"ok"`
+}
+
+// SelfTestStep is the outcome of one part of a self-test.
+type SelfTestStep struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+func step(f func() error) *SelfTestStep {
+	start := time.Now()
+	err := f()
+	s := SelfTestStep{
+		OK:       err == nil,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return &s
+}
+
+// SelfTest sends a synthetic notification through "d".
+//
+// If "d" implements Prober, its destination is probed first and delivery is
+// skipped if that fails. The notification isn't stored, so a receiver
+// following the callback will find no notifications for its ID.
+func SelfTest(ctx context.Context, d Deliverer) *SelfTestResult {
+	res := SelfTestResult{
+		Deliverer:      d.Name(),
+		NotificationID: uuid.New(),
+	}
+	if dd, ok := d.(Destinationer); ok {
+		res.Destination = dd.Destination()
+	}
+	if p, ok := d.(Prober); ok {
+		res.Probe = step(func() error { return p.Probe(ctx) })
+		if !res.Probe.OK {
+			return &res
+		}
+	}
+	res.Delivery = step(func() error {
+		if dd, ok := d.(DirectDeliverer); ok {
+			n := Notification{
+				ID:     res.NotificationID,
+				Reason: Added,
+				Vulnerability: VulnSummary{
+					Name:        "clair-self-test",
+					Description: "A test notification sent by the notifier self-test. It does not describe a real vulnerability.",
+					Severity:    "Unknown",
+				},
+			}
+			if err := dd.Notifications(ctx, []Notification{n}); err != nil {
+				return err
+			}
+		}
+		return d.Deliver(ctx, res.NotificationID)
+	})
+	res.OK = res.Delivery.OK
+	return &res
+}
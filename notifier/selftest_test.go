@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type selfTestDeliverer struct {
+	probe error
+	got   []Notification
+	sent  []uuid.UUID
+}
+
+func (d *selfTestDeliverer) Name() string { return "test" }
+
+func (d *selfTestDeliverer) Probe(context.Context) error { return d.probe }
+
+func (d *selfTestDeliverer) Notifications(_ context.Context, n []Notification) error {
+	d.got = append(d.got, n...)
+	return nil
+}
+
+func (d *selfTestDeliverer) Deliver(_ context.Context, id uuid.UUID) error {
+	d.sent = append(d.sent, id)
+	return nil
+}
+
+func TestSelfTest(t *testing.T) {
+	ctx := context.Background()
+	t.Run("OK", func(t *testing.T) {
+		d := &selfTestDeliverer{}
+		res := SelfTest(ctx, d)
+		if !res.OK || res.Probe == nil || !res.Probe.OK {
+			t.Errorf("got: %+v", res)
+		}
+		if len(d.got) != 1 || d.got[0].ID != res.NotificationID {
+			t.Errorf("notifications: got: %v", d.got)
+		}
+		if len(d.sent) != 1 || d.sent[0] != res.NotificationID {
+			t.Errorf("deliveries: got: %v", d.sent)
+		}
+	})
+	t.Run("ProbeFailure", func(t *testing.T) {
+		d := &selfTestDeliverer{probe: errors.New("connection refused")}
+		res := SelfTest(ctx, d)
+		if res.OK || res.Probe.Error != "connection refused" || res.Delivery != nil {
+			t.Errorf("got: %+v", res)
+		}
+		if len(d.sent) != 0 {
+			t.Errorf("deliveries: got: %v", d.sent)
+		}
+	})
+}
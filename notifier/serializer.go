@@ -0,0 +1,128 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Serializer encodes the messages published by the message-queue deliverers:
+// AMQP, STOMP, and MQTT.
+//
+// Serializers are plugins: a downstream build can register one producing,
+// for example, Avro messages checked against a schema registry, and select it
+// in the deliverer's configuration.
+type Serializer interface {
+	// ContentType is the media type of the encoded messages.
+	ContentType() string
+	// Callback encodes the Callback published for a notification set when
+	// delivering indirectly.
+	Callback(context.Context, *Callback) ([]byte, error)
+	// Notification encodes a single Notification, for deliverers publishing
+	// one message per notification.
+	Notification(context.Context, *Notification) ([]byte, error)
+	// Notifications encodes a batch of Notifications, for deliverers rolling
+	// notifications up into one message.
+	Notifications(context.Context, []Notification) ([]byte, error)
+}
+
+// Serializable is implemented by Deliverers whose messages are encoded by a
+// Serializer.
+type Serializable interface {
+	SetSerializer(Serializer)
+}
+
+// SerializerFactory constructs a Serializer. The "cfg" function decodes the
+// plugin's configuration block into its argument.
+type SerializerFactory func(ctx context.Context, cfg func(interface{}) error) (Serializer, error)
+
+// JSONSerializer is the name of the default Serializer.
+const JSONSerializer = "json"
+
+// JSON is the default Serializer, encoding messages as JSON.
+var JSON Serializer = jsonSerializer{}
+
+var serializers struct {
+	sync.Mutex
+	m map[string]SerializerFactory
+}
+
+func init() {
+	RegisterSerializer(JSONSerializer, func(context.Context, func(interface{}) error) (Serializer, error) {
+		return JSON, nil
+	})
+}
+
+// RegisterSerializer makes the SerializerFactory "f" available as the
+// serializer "name".
+//
+// RegisterSerializer is meant to be called from the init function of a
+// package compiled into a downstream build. It panics if "name" is registered
+// twice.
+func RegisterSerializer(name string, f SerializerFactory) {
+	serializers.Lock()
+	defer serializers.Unlock()
+	if serializers.m == nil {
+		serializers.m = make(map[string]SerializerFactory)
+	}
+	if _, ok := serializers.m[name]; ok {
+		panic(fmt.Sprintf("notifier: serializer %q registered twice", name))
+	}
+	serializers.m[name] = f
+}
+
+// Serializers returns the names of the registered serializers.
+func Serializers() []string {
+	serializers.Lock()
+	defer serializers.Unlock()
+	out := make([]string, 0, len(serializers.m))
+	for n := range serializers.m {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NewSerializer returns the Serializer constructed by the serializer "name".
+func NewSerializer(ctx context.Context, name string, cfg func(interface{}) error) (Serializer, error) {
+	serializers.Lock()
+	f, ok := serializers.m[name]
+	serializers.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("notifier: unknown serializer %q", name)
+	}
+	s, err := f(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("notifier: serializer %q: %w", name, err)
+	}
+	return s, nil
+}
+
+// JsonSerializer encodes messages as JSON.
+type jsonSerializer struct{}
+
+// ContentType implements Serializer.
+func (jsonSerializer) ContentType() string { return "application/json" }
+
+// Callback implements Serializer.
+func (jsonSerializer) Callback(_ context.Context, cb *Callback) ([]byte, error) {
+	return json.Marshal(cb)
+}
+
+// Notification implements Serializer.
+func (jsonSerializer) Notification(_ context.Context, n *Notification) ([]byte, error) {
+	return json.Marshal(n)
+}
+
+// Notifications implements Serializer.
+//
+// The array is followed by a newline, as a json.Encoder writes it.
+func (jsonSerializer) Notifications(_ context.Context, ns []Notification) ([]byte, error) {
+	b, err := json.Marshal(ns)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,137 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+)
+
+// LineSerializer is a test Serializer writing one line per notification,
+// prefixed with a configured tag.
+type lineSerializer struct {
+	tag string
+}
+
+func (*lineSerializer) ContentType() string { return "text/plain" }
+
+func (s *lineSerializer) Callback(_ context.Context, cb *Callback) ([]byte, error) {
+	return []byte(s.tag + " " + cb.NotificationID.String() + "\n"), nil
+}
+
+func (s *lineSerializer) Notification(_ context.Context, n *Notification) ([]byte, error) {
+	return []byte(s.tag + " " + n.Vulnerability.Name + "\n"), nil
+}
+
+func (s *lineSerializer) Notifications(ctx context.Context, ns []Notification) ([]byte, error) {
+	var b bytes.Buffer
+	for i := range ns {
+		l, _ := s.Notification(ctx, &ns[i])
+		b.Write(l)
+	}
+	return b.Bytes(), nil
+}
+
+func init() {
+	RegisterSerializer("test-line", func(_ context.Context, cfg func(interface{}) error) (Serializer, error) {
+		var c struct {
+			Tag string `json:"tag"`
+		}
+		if err := cfg(&c); err != nil {
+			return nil, err
+		}
+		if c.Tag == "" {
+			return nil, errors.New("tag required")
+		}
+		return &lineSerializer{tag: c.Tag}, nil
+	})
+}
+
+func TestSerializerRegistry(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	decode := func(tag string) func(interface{}) error {
+		return func(v interface{}) error {
+			return json.Unmarshal([]byte(`{"tag":"`+tag+`"}`), v)
+		}
+	}
+
+	if got, want := Serializers(), []string{"json", "test-line"}; !cmp.Equal(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if _, err := NewSerializer(ctx, "missing", decode("x")); err == nil {
+		t.Error("unknown serializer: unexpected success")
+	}
+	if _, err := NewSerializer(ctx, "test-line", decode("")); err == nil {
+		t.Error("bad config: unexpected success")
+	}
+	s, err := NewSerializer(ctx, "test-line", decode("clair"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := []Notification{
+		{Vulnerability: VulnSummary{Name: "CVE-2021-3711"}},
+		{Vulnerability: VulnSummary{Name: "CVE-2020-1971"}},
+	}
+	b, err := s.Notifications(ctx, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "clair CVE-2021-3711\nclair CVE-2020-1971\n"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate registration: expected panic")
+		}
+	}()
+	RegisterSerializer("test-line", nil)
+}
+
+// TestJSONSerializer checks that the default Serializer produces the messages
+// the deliverers have always published.
+func TestJSONSerializer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	id := uuid.New()
+	u, _ := url.Parse("https://clair.example.com/notifier/api/v1/notification/" + id.String())
+	b, err := JSON.Callback(ctx, &Callback{NotificationID: id, Callback: *u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cb Callback
+	if err := json.Unmarshal(b, &cb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cb.NotificationID, id; got != want {
+		t.Errorf("callback: got: %v, want: %v", got, want)
+	}
+
+	ns := []Notification{{ID: uuid.New(), Reason: Added}}
+	var want bytes.Buffer
+	if err := json.NewEncoder(&want).Encode(&ns); err != nil {
+		t.Fatal(err)
+	}
+	b, err = JSON.Notifications(ctx, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != want.String() {
+		t.Errorf("notifications: got: %q, want: %q", got, want.String())
+	}
+	b, err = JSON.Notification(ctx, &ns[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := "[" + string(b) + "]\n"; got != want.String() {
+		t.Errorf("notification: got: %q, want element of: %q", b, want.String())
+	}
+	if got, want := JSON.ContentType(), "application/json"; got != want {
+		t.Errorf("content type: got: %q, want: %q", got, want)
+	}
+}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -14,11 +15,20 @@
 	"github.com/quay/zlog"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/quay/clair/v4/health"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/leader"
 	"github.com/quay/clair/v4/matcher"
 	"github.com/quay/clair/v4/notifier"
 	"github.com/quay/clair/v4/notifier/amqp"
+	"github.com/quay/clair/v4/notifier/azure"
+	"github.com/quay/clair/v4/notifier/dependencytrack"
+	"github.com/quay/clair/v4/notifier/elasticsearch"
+	"github.com/quay/clair/v4/notifier/issues"
+	"github.com/quay/clair/v4/notifier/mqtt"
+	"github.com/quay/clair/v4/notifier/splunk"
 	"github.com/quay/clair/v4/notifier/stomp"
+	"github.com/quay/clair/v4/notifier/syslog"
 	"github.com/quay/clair/v4/notifier/webhook"
 )
 
@@ -27,7 +37,23 @@
 	deliveries = runtime.GOMAXPROCS(0)
 )
 
-var _ notifier.Service = (*Notifier)(nil)
+var (
+	_ notifier.Service          = (*Notifier)(nil)
+	_ notifier.SelfTester       = (*Notifier)(nil)
+	_ notifier.DeliveryReporter = (*Notifier)(nil)
+	_ notifier.UsageReporter    = (*Notifier)(nil)
+)
+
+// ErrNoDeliveryReports is returned when the configured store doesn't record
+// delivery attempts.
+var ErrNoDeliveryReports = errors.New("store does not record delivery attempts")
+
+// ErrNoUsage is returned when the configured store doesn't report its usage.
+var ErrNoUsage = errors.New("store does not report usage")
+
+// ErrNoOrderedDelivery is returned when ordered delivery is configured and
+// the store can't order notifications.
+var ErrNoOrderedDelivery = errors.New("store does not support ordered delivery")
 
 // ErrNoDelivery is returned when there's insufficient configuration for
 // notification delivery.
@@ -39,18 +65,67 @@ type Notifier struct {
 	poll  *notifier.Poller
 	proc  *notifier.Processor
 	del   *notifier.Delivery
+
+	retention  notifier.CollectOpts
+	gcInterval time.Duration
+	// NewDeliverer returns a new instance of the configured Deliverer, for
+	// use outside the delivery loop.
+	newDeliverer func() (notifier.Deliverer, error)
+	// Locks is only populated if leader election is enabled.
+	locks notifier.Locker
+	// Pages is only populated if hot notification pages are kept.
+	pages *notifier.PageCache
 }
 
 // Notifications implements notifier.Service.
 func (s *Notifier) Notifications(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error) {
+	if s.pages != nil {
+		return s.pages.Notifications(ctx, id, page)
+	}
 	return s.store.Notifications(ctx, id, page)
 }
 
 // DeleteNotifications implements notifier.Service.
 func (s *Notifier) DeleteNotifications(ctx context.Context, id uuid.UUID) error {
+	if s.pages != nil {
+		s.pages.Forget(id)
+	}
 	return s.store.SetDeleted(ctx, id)
 }
 
+// SelfTest implements notifier.SelfTester.
+//
+// A new Deliverer is used, so that the test doesn't share state with the
+// delivery loop.
+func (s *Notifier) SelfTest(ctx context.Context) (*notifier.SelfTestResult, error) {
+	d, err := s.newDeliverer()
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrNoDelivery
+	}
+	return notifier.SelfTest(ctx, d), nil
+}
+
+// DeliveryReport implements notifier.DeliveryReporter.
+func (s *Notifier) DeliveryReport(ctx context.Context, id uuid.UUID) (*notifier.DeliveryReport, error) {
+	r, ok := s.store.(notifier.AttemptRecorder)
+	if !ok {
+		return nil, ErrNoDeliveryReports
+	}
+	return r.DeliveryReport(ctx, id)
+}
+
+// Usage implements notifier.UsageReporter.
+func (s *Notifier) Usage(ctx context.Context) (*notifier.Usage, error) {
+	r, ok := s.store.(notifier.UsageReporter)
+	if !ok {
+		return nil, ErrNoUsage
+	}
+	return r.Usage(ctx)
+}
+
 // Opts configures the notifier service.
 type Opts struct {
 	Matcher          matcher.Service
@@ -60,15 +135,62 @@ type Opts struct {
 	Webhook          *config.Webhook
 	AMQP             *config.AMQP
 	STOMP            *config.STOMP
+	MQTT             *config.MQTT
+	Azure            *config.Azure
+	Syslog           *config.Syslog
+	Splunk           *config.Splunk
+	Elasticsearch    *config.Elasticsearch
+	DependencyTrack  *config.DependencyTrack
+	Issues           *config.Issues
 	PollInterval     time.Duration
 	DeliveryInterval time.Duration
 	DisableSummary   bool
+	// Wake triggers a poll ahead of the poll interval, such as when the
+	// matcher records an update operation. If nil, the poller only polls on
+	// the interval.
+	Wake <-chan struct{}
+	// Retention describes which notifications are garbage collected.
+	Retention notifier.CollectOpts
+	// GCInterval is the period between garbage collection runs. If zero,
+	// the collector runs hourly.
+	GCInterval time.Duration
+	// LeaderElection restricts polling and garbage collection to a single
+	// process at a time.
+	LeaderElection bool
+	// Backpressure sizes delivery batches and pauses polling while delivery
+	// is backlogged. If nil, every pending notification is attempted on each
+	// delivery run and polling never pauses.
+	Backpressure *notifier.Backpressure
+	// Retry delays attempting notifications that failed to be delivered. If
+	// nil, failed notifications are attempted on every delivery run.
+	Retry *notifier.Retry
+	// Throttle paces the requests for manifests affected by an update. If
+	// nil, requests are made as quickly as possible.
+	Throttle *notifier.Throttle
+	// Pages configures keeping the pages of hot notifications. If nil,
+	// every page is assembled from the store.
+	Pages *notifier.PageOptions
+	// OrderedDelivery delivers the notification sets mentioning a manifest
+	// in the order they were created. The store must be a
+	// notifier.Sequencer.
+	OrderedDelivery bool
 }
 
 // New returns a configured notifier subsystem.
 func New(ctx context.Context, store notifier.Store, locks notifier.Locker, opts Opts) (*Notifier, error) {
 	ctx = zlog.ContextWithValues(ctx, "component", "notifier/service/New")
-	srv := Notifier{store: store}
+	srv := Notifier{
+		store:      store,
+		retention:  opts.Retention,
+		gcInterval: opts.GCInterval,
+	}
+	if srv.gcInterval <= 0 {
+		srv.gcInterval = time.Hour
+	}
+	if opts.LeaderElection {
+		srv.locks = locks
+	}
+	srv.pages = notifier.NewPageCache(ctx, store, opts.Pages)
 
 	// Check for test mode.
 	if tm := os.Getenv("NOTIFIER_TEST_MODE"); tm != "" {
@@ -83,6 +205,8 @@ func New(ctx context.Context, store notifier.Store, locks notifier.Locker, opts
 		Stringer("interval", opts.PollInterval).
 		Msg("initializing poller")
 	srv.poll = notifier.NewPoller(store, opts.Matcher, opts.PollInterval)
+	srv.poll.Backpressure = opts.Backpressure
+	srv.poll.Wake = opts.Wake
 
 	// Configure the Processor.
 	zlog.Info(ctx).
@@ -90,8 +214,74 @@ func New(ctx context.Context, store notifier.Store, locks notifier.Locker, opts
 		Msg("initializing processors")
 	srv.proc = notifier.NewProcessor(store, locks, opts.Indexer, opts.Matcher)
 	srv.proc.NoSummary = opts.DisableSummary
+	srv.proc.Throttle = opts.Throttle
 
 	// Configure a Deliverer.
+	srv.newDeliverer = func() (notifier.Deliverer, error) { return newDeliverer(ctx, &opts) }
+	del, err := srv.newDeliverer()
+	if err != nil {
+		return nil, err
+	}
+	if del == nil {
+		// Report an error if configured such that no notifications are being
+		// processed.
+		return nil, ErrNoDelivery
+	}
+	if p, ok := del.(notifier.Prober); ok {
+		health.Register("notifier/"+del.Name(), p.Probe)
+	}
+	srv.del = notifier.NewDelivery(store, locks, del, opts.DeliveryInterval)
+	srv.del.Backpressure = opts.Backpressure
+	srv.del.Retry = opts.Retry
+	if opts.OrderedDelivery {
+		if _, ok := store.(notifier.Sequencer); !ok {
+			return nil, ErrNoOrderedDelivery
+		}
+		srv.del.Ordered = true
+	}
+
+	return &srv, nil
+}
+
+// SetSerializer sets the Serializer configured for the message-queue
+// deliverer "del", if it is one.
+func setSerializer(ctx context.Context, del notifier.Deliverer, opts *Opts) error {
+	s, ok := del.(notifier.Serializable)
+	if !ok {
+		return nil
+	}
+	var conf *config.Serializer
+	switch {
+	case opts.AMQP != nil:
+		conf = opts.AMQP.Serializer
+	case opts.STOMP != nil:
+		conf = opts.STOMP.Serializer
+	case opts.MQTT != nil:
+		conf = opts.MQTT.Serializer
+	}
+	if conf == nil {
+		return nil
+	}
+	ser, err := notifier.NewSerializer(ctx, conf.Name, func(v interface{}) error {
+		b, err := json.Marshal(conf.Config)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, v)
+	})
+	if err != nil {
+		return err
+	}
+	zlog.Info(ctx).
+		Str("serializer", conf.Name).
+		Msg("using notification serializer")
+	s.SetSerializer(ser)
+	return nil
+}
+
+// NewDeliverer constructs the Deliverer described by "opts", or returns nil if
+// there's insufficient configuration.
+func newDeliverer(ctx context.Context, opts *Opts) (notifier.Deliverer, error) {
 	var del notifier.Deliverer
 	var err error
 	// BUG(hank) Currently only one delivery mechanism can be configured at a
@@ -101,6 +291,13 @@ func New(ctx context.Context, store notifier.Store, locks notifier.Locker, opts
 		zlog.Info(ctx).
 			Int("count", deliveries).
 			Msg("initializing webhook deliverers")
+		if opts.Webhook.Direct {
+			del, err = webhook.NewDirectDeliverer(opts.Webhook, opts.Client, opts.Signer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create webhook direct deliverer: %v", err)
+			}
+			break
+		}
 		del, err = webhook.New(opts.Webhook, opts.Client, opts.Signer)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create webhook deliverer: %v", err)
@@ -141,15 +338,60 @@ func New(ctx context.Context, store notifier.Store, locks notifier.Locker, opts
 		if err != nil {
 			return nil, fmt.Errorf("failed to create STOMP deliverer: %v", err)
 		}
+	case opts.MQTT != nil:
+		zlog.Info(ctx).
+			Msg("initializing mqtt deliverer")
+		del, err = mqtt.New(opts.MQTT)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MQTT deliverer: %v", err)
+		}
+	case opts.Azure != nil:
+		zlog.Info(ctx).
+			Msg("initializing azure deliverer")
+		del, err = azure.New(opts.Azure, opts.Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure deliverer: %v", err)
+		}
+	case opts.Syslog != nil:
+		zlog.Info(ctx).
+			Msg("initializing syslog deliverer")
+		del, err = syslog.New(opts.Syslog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create syslog deliverer: %v", err)
+		}
+	case opts.Splunk != nil:
+		zlog.Info(ctx).
+			Msg("initializing splunk deliverer")
+		del, err = splunk.New(opts.Splunk, opts.Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Splunk deliverer: %v", err)
+		}
+	case opts.Elasticsearch != nil:
+		zlog.Info(ctx).
+			Msg("initializing elasticsearch deliverer")
+		del, err = elasticsearch.New(opts.Elasticsearch, opts.Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Elasticsearch deliverer: %v", err)
+		}
+	case opts.DependencyTrack != nil:
+		zlog.Info(ctx).
+			Msg("initializing dependency-track deliverer")
+		del, err = dependencytrack.New(opts.DependencyTrack, opts.Client, opts.Indexer, opts.Matcher)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dependency-track deliverer: %v", err)
+		}
+	case opts.Issues != nil:
+		zlog.Info(ctx).
+			Msg("initializing issue tracker deliverer")
+		del, err = issues.New(opts.Issues, opts.Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create issue tracker deliverer: %v", err)
+		}
 	}
-	if del == nil {
-		// Report an error if configured such that no notifications are being
-		// processed.
-		return nil, ErrNoDelivery
+	if err := setSerializer(ctx, del, opts); err != nil {
+		return nil, fmt.Errorf("failed to create notification serializer: %w", err)
 	}
-	srv.del = notifier.NewDelivery(store, locks, del, opts.DeliveryInterval)
-
-	return &srv, nil
+	return del, nil
 }
 
 // TestModeInit will inject a mock Indexer and Matcher into opts
@@ -173,13 +415,26 @@ func (s *Notifier) Run(ctx context.Context) error {
 	ch := make(chan notifier.Event, notifier.MaxChanSize)
 	eg, ctx := errgroup.WithContext(ctx)
 	// Poller goroutine.
-	eg.Go(func() error { return s.poll.Poll(ctx, ch) })
+	if s.locks != nil {
+		eg.Go(func() error { return s.poll.PollElected(ctx, s.locks, ch) })
+	} else {
+		eg.Go(func() error { return s.poll.Poll(ctx, ch) })
+	}
 	// Processor goroutines.
 	for i := 0; i < processors; i++ {
 		eg.Go(func() error { return s.proc.Process(ctx, ch) })
 	}
 	// Garbage collection goroutine.
-	eg.Go(s.gc(ctx))
+	if s.locks != nil {
+		eg.Go(func() error {
+			leader.Run(ctx, s.locks, "notifier-gc", func(ctx context.Context) {
+				s.gc(ctx)()
+			})
+			return ctx.Err()
+		})
+	} else {
+		eg.Go(s.gc(ctx))
+	}
 	// Delivery goroutines.
 	for i := 0; i < deliveries; i++ {
 		eg.Go(func() error { return s.del.Deliver(ctx) })
@@ -189,9 +444,14 @@ func (s *Notifier) Run(ctx context.Context) error {
 
 // Gc is the garbage collection process.
 func (s *Notifier) gc(ctx context.Context) func() error {
-	// BUG(hank) The garbage collection period is currently unconfigurable.
 	ctx = zlog.ContextWithValues(ctx, "component", "notifier/service/Notifier.gc")
-	ticker := time.NewTicker(time.Hour)
+	zlog.Info(ctx).
+		Stringer("interval", s.gcInterval).
+		Stringer("delivered_age", s.retention.DeliveredAge).
+		Stringer("undelivered_age", s.retention.UndeliveredAge).
+		Int("max_count", s.retention.MaxCount).
+		Msg("initializing garbage collection")
+	ticker := time.NewTicker(s.gcInterval)
 	return func() error {
 		defer ticker.Stop()
 		for {
@@ -199,8 +459,9 @@ func (s *Notifier) gc(ctx context.Context) func() error {
 			case <-ctx.Done():
 				return ctx.Err()
 			case <-ticker.C:
-				if err := s.store.CollectNotifications(ctx); err != nil {
+				if err := s.store.CollectNotifications(ctx, s.retention); err != nil {
 					zlog.Info(ctx).Err(err).Msg("gc errored")
+					continue
 				}
 				zlog.Info(ctx).Msg("gc done")
 			}
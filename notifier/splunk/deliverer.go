@@ -0,0 +1,220 @@
+// Package splunk sends notifications to a Splunk HTTP Event Collector.
+package splunk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/httputil"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer sends the notifications in a notification set as events.
+//
+// If any batch fails the whole set is retried, so searches may see a
+// notification more than once; every event carries the notification ID for
+// deduplication.
+type Deliverer struct {
+	c          *http.Client
+	endpoint   *url.URL
+	token      string
+	index      *template.Template
+	sourcetype *template.Template
+	source     string
+	host       string
+	batch      int
+	n          []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+)
+
+// New returns a new Splunk Deliverer.
+func New(conf *config.Splunk, client *http.Client) (*Deliverer, error) {
+	switch {
+	case conf == nil:
+		return nil, errors.New("config not provided")
+	case client == nil:
+		return nil, errors.New("http client not provided")
+	}
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	d := Deliverer{
+		c:        client,
+		endpoint: u.JoinPath("services/collector/event"),
+		token:    conf.Token,
+		source:   conf.Source,
+		host:     conf.Host,
+		batch:    conf.BatchSize,
+	}
+	if d.source == "" {
+		d.source = config.DefaultSplunkSource
+	}
+	if d.batch <= 0 {
+		d.batch = config.DefaultSplunkBatchSize
+	}
+	if conf.Index != "" {
+		if d.index, err = template.New("index").Parse(conf.Index); err != nil {
+			return nil, fmt.Errorf("invalid index template: %w", err)
+		}
+	}
+	st := conf.Sourcetype
+	if st == "" {
+		st = config.DefaultSplunkSourcetype
+	}
+	if d.sourcetype, err = template.New("sourcetype").Parse(st); err != nil {
+		return nil, fmt.Errorf("invalid sourcetype template: %w", err)
+	}
+	return &d, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "splunk"
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.endpoint.String()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Event is an event in a batch request.
+type event struct {
+	Time       float64                `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	Sourcetype string                 `json:"sourcetype,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Event      *notifier.Notification `json:"event"`
+	Fields     map[string]string      `json:"fields,omitempty"`
+}
+
+// Deliver implements notifier.Deliverer.
+//
+// Deliver sends the buffered notifications in batches.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/splunk/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	now := float64(time.Now().UnixMilli()) / 1000
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	var batches int
+	for i := 0; i < len(d.n); i += d.batch {
+		end := i + d.batch
+		if end > len(d.n) {
+			end = len(d.n)
+		}
+		buf.Reset()
+		for j := i; j < end; j++ {
+			ev, err := d.event(&d.n[j], nID, now)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(&ev); err != nil {
+				return err
+			}
+		}
+		if err := d.post(ctx, buf.Bytes()); err != nil {
+			return err
+		}
+		batches++
+	}
+	zlog.Info(ctx).
+		Stringer("destination", d.endpoint).
+		Int("count", len(d.n)).
+		Int("batches", batches).
+		Msg("sent notifications")
+	return nil
+}
+
+// Event builds the event for "n".
+func (d *Deliverer) event(n *notifier.Notification, set uuid.UUID, now float64) (event, error) {
+	ev := event{
+		Time:   now,
+		Host:   d.host,
+		Source: d.source,
+		Event:  n,
+		Fields: map[string]string{
+			"notification_set": set.String(),
+			"reason":           string(n.Reason),
+			"severity":         n.Vulnerability.Severity,
+		},
+	}
+	var sb strings.Builder
+	if d.index != nil {
+		if err := d.index.Execute(&sb, n); err != nil {
+			return event{}, fmt.Errorf("notification %v: unable to execute index template: %w", n.ID, err)
+		}
+		ev.Index = sb.String()
+		sb.Reset()
+	}
+	if err := d.sourcetype.Execute(&sb, n); err != nil {
+		return event{}, fmt.Errorf("notification %v: unable to execute sourcetype template: %w", n.ID, err)
+	}
+	ev.Sourcetype = sb.String()
+	return ev, nil
+}
+
+// Post sends a batch of events.
+//
+// Failed requests and unsuccessful responses are reported as
+// clairerror.ErrDeliveryFailed.
+func (d *Deliverer) post(ctx context.Context, body []byte) error {
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, d.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "Splunk "+d.token)
+	req.Header.Set("content-type", "application/json")
+	res, err := d.c.Do(req)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		// The collector explains rejections, such as an unknown index, in
+		// the body.
+		var r struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&r)
+		var err error = &clairerror.ErrRequestFail{
+			Code:   res.StatusCode,
+			Status: res.Status,
+		}
+		if r.Text != "" {
+			err = fmt.Errorf("%w: %s", err, r.Text)
+		}
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	return nil
+}
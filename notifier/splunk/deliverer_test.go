@@ -0,0 +1,114 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+func notifications() []notifier.Notification {
+	m := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	return []notifier.Notification{
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3711", Severity: "High"}},
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{Name: "CVE-2021-3712", Severity: "Medium"}},
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Removed, Vulnerability: notifier.VulnSummary{Name: "CVE-2020-1971", Severity: "Medium"}},
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var batches [][]event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/services/collector/event"; got != want {
+			t.Errorf("path: got: %q, want: %q", got, want)
+		}
+		if got, want := r.Header.Get("authorization"), "Splunk token"; got != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// The body is a stream of concatenated events.
+		var b []event
+		dec := json.NewDecoder(r.Body)
+		for {
+			var ev event
+			err := dec.Decode(&ev)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b = append(b, ev)
+		}
+		batches = append(batches, b)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	d, err := New(&config.Splunk{
+		URL:       srv.URL,
+		Token:     "token",
+		Index:     "clair_{{.Vulnerability.Severity}}",
+		BatchSize: 2,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := notifications()
+	set := uuid.New()
+	d.Notifications(ctx, ns)
+	if err := d.Deliver(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(batches), 2; got != want {
+		t.Fatalf("got: %d batches, want: %d", got, want)
+	}
+	ev := batches[0][0]
+	if got, want := ev.Index, "clair_High"; got != want {
+		t.Errorf("index: got: %q, want: %q", got, want)
+	}
+	if got, want := ev.Sourcetype, config.DefaultSplunkSourcetype; got != want {
+		t.Errorf("sourcetype: got: %q, want: %q", got, want)
+	}
+	if got, want := ev.Fields["notification_set"], set.String(); got != want {
+		t.Errorf("notification_set: got: %q, want: %q", got, want)
+	}
+	if got, want := batches[1][0].Event.ID, ns[2].ID; got != want {
+		t.Errorf("event: got: %v, want: %v", got, want)
+	}
+}
+
+func TestDeliverRejected(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"text":"Incorrect index","code":7}`))
+	}))
+	defer srv.Close()
+	d, err := New(&config.Splunk{URL: srv.URL, Token: "token"}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Notifications(ctx, notifications())
+	err = d.Deliver(ctx, uuid.New())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "Incorrect index") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
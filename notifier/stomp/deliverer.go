@@ -2,7 +2,6 @@
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/url"
 	"time"
@@ -22,8 +21,11 @@ type Deliverer struct {
 	destination string
 	fo          failOver
 	rollup      int
+	ser         notifier.Serializer
 }
 
+var _ notifier.Serializable = (*Deliverer)(nil)
+
 func New(conf *config.STOMP) (*Deliverer, error) {
 	var d Deliverer
 	if err := d.load(conf); err != nil {
@@ -33,10 +35,10 @@ func New(conf *config.STOMP) (*Deliverer, error) {
 }
 
 func (d *Deliverer) load(cfg *config.STOMP) error {
-	d.fo.timeout = 30 * time.Second
-	// TODO(hank) Wire up the "host" and "timeout" config somehow -- probably
-	// just make the config URIs strings actual URIs and parse them out with
-	// query parameters.
+	d.fo.timeout = config.DefaultBrokerDialTimeout
+	// TODO(hank) Wire up the "host" config somehow -- probably just make the
+	// config URIs strings actual URIs and parse them out with query
+	// parameters.
 	var err error
 	if cfg.TLS != nil {
 		d.fo.tls, err = cfg.TLS.Config()
@@ -44,6 +46,27 @@ func (d *Deliverer) load(cfg *config.STOMP) error {
 			return err
 		}
 	}
+	d.fo.brokers = make(map[string]*broker, len(cfg.Brokers))
+	for _, b := range cfg.Brokers {
+		s := broker{
+			tls:       d.fo.tls,
+			dial:      time.Duration(b.DialTimeout),
+			handshake: time.Duration(b.HandshakeTimeout),
+		}
+		if b.TLS != nil {
+			s.tls, err = b.TLS.Config()
+			if err != nil {
+				return fmt.Errorf("broker %q: %w", b.URI, err)
+			}
+		}
+		if s.dial <= 0 {
+			s.dial = config.DefaultBrokerDialTimeout
+		}
+		if s.handshake <= 0 {
+			s.handshake = config.DefaultBrokerHandshakeTimeout
+		}
+		d.fo.brokers[b.URI] = &s
+	}
 	if !cfg.Direct {
 		d.callback, err = url.Parse(cfg.Callback)
 		if err != nil {
@@ -53,8 +76,11 @@ func (d *Deliverer) load(cfg *config.STOMP) error {
 
 	d.fo.addrs = make([]string, len(cfg.URIs))
 	copy(d.fo.addrs, cfg.URIs)
+	d.fo.strategy = cfg.Failover
+	d.fo.backoff = time.Duration(cfg.FailureBackoff)
 	d.destination = cfg.Destination
 	d.rollup = cfg.Rollup
+	d.ser = notifier.JSON
 	return nil
 }
 
@@ -62,6 +88,27 @@ func (d *Deliverer) Name() string {
 	return fmt.Sprintf("stomp-%s", d.destination)
 }
 
+// Destination implements the notifier.Destinationer interface.
+func (d *Deliverer) Destination() string {
+	return d.destination
+}
+
+// SetSerializer implements notifier.Serializable.
+func (d *Deliverer) SetSerializer(s notifier.Serializer) {
+	d.ser = s
+}
+
+// Probe implements the notifier.Prober interface.
+//
+// A successful probe means a broker accepted a connection.
+func (d *Deliverer) Probe(ctx context.Context) error {
+	conn, err := d.fo.Connection(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Disconnect()
+}
+
 func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	conn, err := d.fo.Connection(ctx)
 	if err != nil {
@@ -78,12 +125,12 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 		NotificationID: nID,
 		Callback:       *u,
 	}
-	b, err := json.Marshal(&cb)
+	b, err := d.ser.Callback(ctx, &cb)
 	if err != nil {
 		return &clairerror.ErrDeliveryFailed{err}
 	}
 
-	err = conn.Send(d.destination, "application/json", b, gostomp.SendOpt.Receipt)
+	err = conn.Send(d.destination, d.ser.ContentType(), b, gostomp.SendOpt.Receipt)
 	if err != nil {
 		return &clairerror.ErrDeliveryFailed{err}
 	}
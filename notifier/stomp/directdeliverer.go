@@ -1,9 +1,7 @@
 package stomp
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -89,11 +87,11 @@ func (d *DirectDeliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 		// after queuing the send.
 		// Can't use receipts because RabbitMQ treats receipt as a thing that
 		// happens at the end of a transaction (not unreasonable, I suppose).
-		var buf bytes.Buffer
-		if err := json.NewEncoder(&buf).Encode(&currentBlock); err != nil {
+		b, err := d.ser.Notifications(ctx, currentBlock)
+		if err != nil {
 			return errDeliever(err)
 		}
-		if err := tx.Send(d.destination, "application/json", buf.Bytes(), nil); err != nil {
+		if err := tx.Send(d.destination, d.ser.ContentType(), b, nil); err != nil {
 			return errDeliever(err)
 		}
 	}
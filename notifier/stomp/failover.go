@@ -4,7 +4,9 @@
 	"context"
 	"crypto/tls"
 	"fmt"
+	"math/rand"
 	"net"
+	"sync"
 	"time"
 
 	gostomp "github.com/go-stomp/stomp/v3"
@@ -15,12 +17,107 @@
 // failOver will return the first successful connection made against the provided
 // brokers, or an existing connection if not closed.
 //
+// The order brokers are attempted in is determined by the strategy, which is
+// one of the config.Failover* constants. Brokers that fail to connect are
+// moved to the end of the order until the backoff elapses.
+//
 // failOver is safe for concurrent usage.
 type failOver struct {
-	tls     *tls.Config
-	login   *config.Login
-	addrs   []string
-	timeout time.Duration
+	tls      *tls.Config
+	login    *config.Login
+	addrs    []string
+	timeout  time.Duration
+	strategy string
+	backoff  time.Duration
+	// Brokers holds per-broker settings, overriding the above.
+	brokers map[string]*broker
+
+	mu sync.Mutex
+	// Next is the index of the broker to start with, for the round-robin and
+	// sticky strategies.
+	next int
+	// Until records when failed brokers should next be attempted.
+	until map[string]time.Time
+}
+
+// Broker holds the connection settings for a single broker.
+type broker struct {
+	tls       *tls.Config
+	dial      time.Duration
+	handshake time.Duration
+}
+
+// Settings returns the connection settings for the broker at "addr".
+func (f *failOver) settings(addr string) *broker {
+	if b, ok := f.brokers[addr]; ok {
+		return b
+	}
+	return &broker{
+		tls:       f.tls,
+		dial:      f.timeout,
+		handshake: f.timeout,
+	}
+}
+
+// Order returns the brokers in the order they should be attempted.
+func (f *failOver) order() []string {
+	n := len(f.addrs)
+	ord := make([]int, n)
+	for i := range ord {
+		ord[i] = i
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch f.strategy {
+	case config.FailoverRandom:
+		rand.Shuffle(n, func(i, j int) { ord[i], ord[j] = ord[j], ord[i] })
+	case config.FailoverRoundRobin, config.FailoverSticky:
+		start := f.next % n
+		for i := range ord {
+			ord[i] = (start + i) % n
+		}
+		if f.strategy == config.FailoverRoundRobin {
+			f.next = (start + 1) % n
+		}
+	}
+
+	now := time.Now()
+	out := make([]string, 0, n)
+	var down []string
+	for _, i := range ord {
+		a := f.addrs[i]
+		if t, ok := f.until[a]; ok && now.Before(t) {
+			down = append(down, a)
+			continue
+		}
+		out = append(out, a)
+	}
+	// Brokers in backoff are attempted as a last resort.
+	return append(out, down...)
+}
+
+// Record notes the outcome of dialing the broker at "addr".
+func (f *failOver) record(addr string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		if f.backoff > 0 {
+			if f.until == nil {
+				f.until = make(map[string]time.Time)
+			}
+			f.until[addr] = time.Now().Add(f.backoff)
+		}
+		return
+	}
+	delete(f.until, addr)
+	if f.strategy == config.FailoverSticky {
+		for i, a := range f.addrs {
+			if a == addr {
+				f.next = i
+				break
+			}
+		}
+	}
 }
 
 // Dial will dial the provided address in accordance with the provided Config.
@@ -37,21 +134,32 @@ func (f *failOver) Dial(ctx context.Context, addr string) (*gostomp.Conn, error)
 		opts = append(opts, gostomp.ConnOpt.Host(host))
 	}
 
-	var d interface {
-		DialContext(context.Context, string, string) (net.Conn, error)
-	} = &net.Dialer{
-		Timeout: f.timeout,
-	}
-	if f.tls != nil {
-		d = &tls.Dialer{
-			NetDialer: d.(*net.Dialer),
-			Config:    f.tls,
-		}
+	b := f.settings(addr)
+	d := net.Dialer{
+		Timeout: b.dial,
 	}
 	conn, err := d.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to broker @ %v: %w", addr, err)
 	}
+	// Bound the TLS and STOMP handshakes, then clear the deadline once the
+	// connection is established.
+	if err := conn.SetDeadline(time.Now().Add(b.handshake)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to broker @ %v: %w", addr, err)
+	}
+	if b.tls != nil {
+		cfg := b.tls.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("tls handshake with broker @ %v failed: %w", addr, err)
+		}
+		conn = tc
+	}
 
 	stompConn, err := gostomp.Connect(conn, opts...)
 	if err != nil {
@@ -60,20 +168,25 @@ func (f *failOver) Dial(ctx context.Context, addr string) (*gostomp.Conn, error)
 		}
 		return nil, fmt.Errorf("stomp connect handshake to broker @ %v failed: %w", addr, err)
 	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		stompConn.MustDisconnect()
+		return nil, fmt.Errorf("failed to connect to broker @ %v: %w", addr, err)
+	}
 
 	return stompConn, err
 }
 
-// Connection returns a new connection to the first broker that successfully
-// handshakes.
+// Connection returns a new connection to the first broker, in the order
+// determined by the strategy, that successfully handshakes.
 //
 // The caller MUST call conn.Disconnect() to close the underlying TCP connection
 // when finished.
 func (f *failOver) Connection(ctx context.Context) (*gostomp.Conn, error) {
 	ctx = zlog.ContextWithValues(ctx, "component", "notifier/stomp/failOver.Connection")
 
-	for _, addr := range f.addrs {
+	for _, addr := range f.order() {
 		conn, err := f.Dial(ctx, addr)
+		f.record(addr, err)
 		if err != nil {
 			zlog.Debug(ctx).
 				Str("broker", addr).
@@ -0,0 +1,93 @@
+package stomp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/clair/config"
+)
+
+func TestFailoverOrder(t *testing.T) {
+	addrs := []string{"a:1", "b:1", "c:1"}
+	errDial := errors.New("dial failed")
+
+	t.Run("Priority", func(t *testing.T) {
+		f := failOver{addrs: addrs, strategy: config.FailoverPriority}
+		for i := 0; i < 2; i++ {
+			if got, want := f.order(), addrs; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		}
+	})
+	t.Run("RoundRobin", func(t *testing.T) {
+		f := failOver{addrs: addrs, strategy: config.FailoverRoundRobin}
+		for _, want := range [][]string{
+			{"a:1", "b:1", "c:1"},
+			{"b:1", "c:1", "a:1"},
+			{"c:1", "a:1", "b:1"},
+			{"a:1", "b:1", "c:1"},
+		} {
+			if got := f.order(); !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		}
+	})
+	t.Run("Sticky", func(t *testing.T) {
+		f := failOver{addrs: addrs, strategy: config.FailoverSticky}
+		f.record("b:1", nil)
+		for i := 0; i < 2; i++ {
+			if got, want := f.order(), []string{"b:1", "c:1", "a:1"}; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		}
+	})
+	t.Run("Random", func(t *testing.T) {
+		f := failOver{addrs: addrs, strategy: config.FailoverRandom}
+		got := f.order()
+		if len(got) != len(addrs) {
+			t.Errorf("got: %v", got)
+		}
+		seen := make(map[string]bool)
+		for _, a := range got {
+			seen[a] = true
+		}
+		if len(seen) != len(addrs) {
+			t.Errorf("got: %v", got)
+		}
+	})
+	t.Run("Backoff", func(t *testing.T) {
+		f := failOver{addrs: addrs, strategy: config.FailoverPriority, backoff: time.Hour}
+		f.record("a:1", errDial)
+		if got, want := f.order(), []string{"b:1", "c:1", "a:1"}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		f.record("a:1", nil)
+		if got, want := f.order(), addrs; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("NoBackoff", func(t *testing.T) {
+		f := failOver{addrs: addrs, strategy: config.FailoverPriority}
+		f.record("a:1", errDial)
+		if got, want := f.order(), addrs; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+}
+
+func TestFailoverSettings(t *testing.T) {
+	slow := &broker{dial: time.Minute, handshake: 2 * time.Minute}
+	f := failOver{
+		addrs:   []string{"a:1", "b:1"},
+		timeout: time.Second,
+		brokers: map[string]*broker{"b:1": slow},
+	}
+	if got := f.settings("a:1"); got.dial != time.Second || got.handshake != time.Second {
+		t.Errorf("default: got: %+v", got)
+	}
+	if got := f.settings("b:1"); got != slow {
+		t.Errorf("override: got: %+v", got)
+	}
+}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -21,6 +22,20 @@ type PutOpts struct {
 	Notifications []Notification
 }
 
+// CollectOpts configures which notifications CollectNotifications removes.
+//
+// Notifications marked deleted are always removed. A zero value for any
+// member disables that criterion.
+type CollectOpts struct {
+	// notifications delivered longer ago than this are removed
+	DeliveredAge time.Duration
+	// notifications not yet delivered (created or failed) and older than
+	// this are removed
+	UndeliveredAge time.Duration
+	// keep at most this many notification sets, removing the oldest first
+	MaxCount int
+}
+
 // Store is an aggregate interface implementing all methods
 // necessary for a notifier persistence layer
 type Store interface {
@@ -64,7 +79,10 @@ type Notificationer interface {
 	// application logic may decide to GC notifications which have not been
 	// set deleted after some period of time, thus this condition should not
 	// be checked.
-	CollectNotifications(ctx context.Context) error
+	//
+	// The CollectOpts describe which notifications are eligible for
+	// collection in addition to those set deleted.
+	CollectNotifications(ctx context.Context, opts CollectOpts) error
 }
 
 // Receipter implements persistence methods for Receipt models
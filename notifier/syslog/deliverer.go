@@ -0,0 +1,130 @@
+// Package syslog sends notifications to a syslog collector as RFC 5424
+// messages, for SIEMs that ingest events through syslog.
+package syslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/syslog"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// SdID is the SD-ID of the structured data describing a notification. 32473
+// is the private enterprise number reserved for documentation (RFC 5612).
+const sdID = "clair@32473"
+
+// DefaultSeverities maps vulnerability severities to syslog severities,
+// unless configured otherwise.
+var defaultSeverities = map[string]syslog.Severity{
+	"Critical":   syslog.Crit,
+	"High":       syslog.Err,
+	"Medium":     syslog.Warning,
+	"Low":        syslog.Notice,
+	"Negligible": syslog.Info,
+	"Unknown":    syslog.Info,
+}
+
+// Deliverer sends a syslog message for each notification in a notification
+// set.
+//
+// If sending fails the whole set is retried, so the collector may see a
+// notification more than once; the notification ID is included in every
+// message for deduplication.
+type Deliverer struct {
+	w *syslog.Writer
+	n []notifier.Notification
+}
+
+var (
+	_ notifier.Deliverer       = (*Deliverer)(nil)
+	_ notifier.DirectDeliverer = (*Deliverer)(nil)
+	_ notifier.Destinationer   = (*Deliverer)(nil)
+)
+
+// New returns a new syslog Deliverer.
+func New(conf *config.Syslog) (*Deliverer, error) {
+	if conf == nil {
+		return nil, errors.New("config not provided")
+	}
+	w, err := syslog.New(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Deliverer{w: w}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "syslog"
+}
+
+// Destination implements notifier.Destinationer.
+func (d *Deliverer) Destination() string {
+	return d.w.Addr()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Deliver implements notifier.Deliverer.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/syslog/Deliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	ms := make([]syslog.Message, len(d.n))
+	for i := range d.n {
+		ms[i] = d.message(&d.n[i], nID)
+	}
+	if err := d.w.Write(ctx, ms...); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	zlog.Info(ctx).
+		Str("collector", d.w.Addr()).
+		Int("count", len(ms)).
+		Msg("sent notifications")
+	return nil
+}
+
+// Message builds the syslog message for "n".
+func (d *Deliverer) message(n *notifier.Notification, set uuid.UUID) syslog.Message {
+	v := &n.Vulnerability
+	def, ok := defaultSeverities[v.Severity]
+	if !ok {
+		def = syslog.Info
+	}
+	ps := []syslog.Param{
+		{Name: "id", Value: n.ID.String()},
+		{Name: "set", Value: set.String()},
+		{Name: "manifest", Value: n.Manifest.String()},
+		{Name: "reason", Value: string(n.Reason)},
+		{Name: "vulnerability", Value: v.Name},
+		{Name: "severity", Value: v.Severity},
+	}
+	if p := v.Package; p != nil {
+		ps = append(ps,
+			syslog.Param{Name: "package", Value: p.Name},
+			syslog.Param{Name: "version", Value: p.Version},
+		)
+	}
+	if v.FixedInVersion != "" {
+		ps = append(ps, syslog.Param{Name: "fixed_in_version", Value: v.FixedInVersion})
+	}
+	return syslog.Message{
+		Severity: d.w.Severity(v.Severity, def),
+		MsgID:    "notification",
+		Data:     []syslog.Element{{ID: sdID, Params: ps}},
+		Msg:      fmt.Sprintf("%s (%s) %s for %s", v.Name, v.Severity, n.Reason, n.Manifest),
+	}
+}
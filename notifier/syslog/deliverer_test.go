@@ -0,0 +1,62 @@
+package syslog
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+func TestDeliver(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	d, err := New(&config.Syslog{
+		Address:    "udp://" + pc.LocalAddr().String(),
+		Facility:   "local0",
+		Severities: map[string]string{"High": "alert"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	ns := []notifier.Notification{
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Added, Vulnerability: notifier.VulnSummary{
+			Name: "CVE-2021-3711", Severity: "High", FixedInVersion: "1.1.1l",
+			Package: &claircore.Package{Name: "openssl", Version: "1.1.1k"},
+		}},
+		{ID: uuid.New(), Manifest: m, Reason: notifier.Removed, Vulnerability: notifier.VulnSummary{
+			Name: "CVE-2020-1971", Severity: "Medium",
+		}},
+	}
+	d.Notifications(ctx, ns)
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Local0 is facility 16: alert is <129> and warning is <132>.
+	for i, want := range []string{"<129>1 ", "<132>1 "} {
+		b := make([]byte, 4096)
+		n, _, err := pc.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(b[:n])
+		if !strings.HasPrefix(got, want) {
+			t.Errorf("message %d: got: %q, want prefix: %q", i, got, want)
+		}
+		if !strings.Contains(got, `id="`+ns[i].ID.String()+`"`) {
+			t.Errorf("message %d: missing notification id: %q", i, got)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+)
+
+// DefaultAffectedChunk is the number of vulnerabilities sent in a single
+// AffectedManifests request when a Throttle doesn't specify one.
+const DefaultAffectedChunk = 1000
+
+var (
+	affectedRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "affected_requests_total",
+			Help:      "Number of affected manifests requests made while creating notifications.",
+		},
+		[]string{"reason"},
+	)
+	affectedPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "affected_vulnerabilities_pending",
+			Help:      "Number of changed vulnerabilities awaiting an affected manifests request.",
+		},
+	)
+	affectedThrottled = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "clair",
+			Subsystem: "notifier",
+			Name:      "affected_throttled_seconds_total",
+			Help:      "Time spent waiting to make affected manifests requests, by cause.",
+		},
+		[]string{"cause"},
+	)
+)
+
+// Window is a daily period, as offsets from midnight. A Window with an End
+// before its Start wraps past midnight.
+type Window struct {
+	Start, End time.Duration
+}
+
+// ParseWindow parses a Window in "HH:MM-HH:MM" form.
+func ParseWindow(s string) (Window, error) {
+	var w Window
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return w, fmt.Errorf("invalid window %q: missing \"-\"", s)
+	}
+	for _, p := range []struct {
+		in  string
+		out *time.Duration
+	}{
+		{start, &w.Start},
+		{end, &w.End},
+	} {
+		t, err := time.Parse("15:04", strings.TrimSpace(p.in))
+		if err != nil {
+			return w, fmt.Errorf("invalid window %q: %w", s, err)
+		}
+		*p.out = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if w.Start == w.End {
+		return w, fmt.Errorf("invalid window %q: empty", s)
+	}
+	return w, nil
+}
+
+// Contains reports whether the time of day "d" falls in the window.
+func (w Window) contains(d time.Duration) bool {
+	if w.Start < w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// Throttle paces the affected manifests requests a Processor makes, which
+// can load the indexer's database for a long time after a large update.
+//
+// Vulnerabilities are sent in chunks, with an optional delay between
+// requests and a cap on concurrent requests. If any Windows are configured,
+// requests are only started during one of them; a Processor outside every
+// window waits for the next one to open, holding the update operation's lock
+// so other Processors don't pick it up.
+//
+// A nil *Throttle uses the default chunk size and doesn't otherwise limit
+// requests.
+type Throttle struct {
+	chunk       int
+	delay       time.Duration
+	concurrency int
+	windows     []Window
+	loc         *time.Location
+	now         func() time.Time
+}
+
+// NewThrottle returns a Throttle sending at most "chunk" vulnerabilities per
+// request, waiting "delay" between requests, and making at most
+// "concurrency" requests at once, only during "windows" interpreted in "loc".
+//
+// A non-positive "chunk" uses DefaultAffectedChunk. A non-positive
+// "concurrency" doesn't limit concurrent requests. An empty "windows" allows
+// requests at any time. A nil "loc" is UTC.
+func NewThrottle(chunk int, delay time.Duration, concurrency int, loc *time.Location, windows []Window) *Throttle {
+	if chunk <= 0 {
+		chunk = DefaultAffectedChunk
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Throttle{
+		chunk:       chunk,
+		delay:       delay,
+		concurrency: concurrency,
+		windows:     windows,
+		loc:         loc,
+		now:         time.Now,
+	}
+}
+
+// ChunkSize reports the number of vulnerabilities to send per request.
+func (t *Throttle) chunkSize() int {
+	if t == nil {
+		return DefaultAffectedChunk
+	}
+	return t.chunk
+}
+
+// Limit reports the number of concurrent requests allowed, in the form
+// errgroup.Group.SetLimit expects.
+func (t *Throttle) limit() int {
+	if t == nil || t.concurrency <= 0 {
+		return -1
+	}
+	return t.concurrency
+}
+
+// Wait blocks until the next request may be made. The configured delay is
+// only waited for if "first" is false.
+func (t *Throttle) wait(ctx context.Context, first bool) error {
+	if t == nil {
+		return ctx.Err()
+	}
+	if !first && t.delay > 0 {
+		if err := sleep(ctx, t.delay); err != nil {
+			return err
+		}
+		affectedThrottled.WithLabelValues("delay").Add(t.delay.Seconds())
+	}
+	if d := t.untilOpen(t.now()); d > 0 {
+		zlog.Info(ctx).
+			Stringer("wait", d).
+			Msg("outside scheduling windows: waiting to request affected manifests")
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+		affectedThrottled.WithLabelValues("window").Add(d.Seconds())
+	}
+	return ctx.Err()
+}
+
+// UntilOpen reports how long after "now" the next window opens, or 0 if
+// "now" is in a window.
+func (t *Throttle) untilOpen(now time.Time) time.Duration {
+	if len(t.windows) == 0 {
+		return 0
+	}
+	now = now.In(t.loc)
+	y, m, d := now.Date()
+	off := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.loc))
+	next := 24 * time.Hour
+	for _, w := range t.windows {
+		if w.contains(off) {
+			return 0
+		}
+		wait := (w.Start - off + 24*time.Hour) % (24 * time.Hour)
+		if wait < next {
+			next = wait
+		}
+	}
+	return next
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	tm := time.NewTimer(d)
+	defer tm.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-tm.C:
+		return nil
+	}
+}
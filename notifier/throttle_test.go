@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+func TestParseWindow(t *testing.T) {
+	got, err := ParseWindow("22:30-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Window{Start: 22*time.Hour + 30*time.Minute, End: 6 * time.Hour}
+	if got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	for _, bad := range []string{"22:30", "22:30-", "25:00-06:00", "06:00-06:00"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestThrottleUntilOpen(t *testing.T) {
+	day := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	th := NewThrottle(0, 0, 0, nil, []Window{
+		{Start: 22 * time.Hour, End: 2 * time.Hour},
+		{Start: 12 * time.Hour, End: 13 * time.Hour},
+	})
+	tt := []struct {
+		At   time.Duration
+		Want time.Duration
+	}{
+		{At: 23 * time.Hour, Want: 0},
+		{At: time.Hour, Want: 0},
+		{At: 12*time.Hour + 30*time.Minute, Want: 0},
+		{At: 2 * time.Hour, Want: 10 * time.Hour},
+		{At: 13 * time.Hour, Want: 9 * time.Hour},
+	}
+	for _, tc := range tt {
+		if got := th.untilOpen(day.Add(tc.At)); got != tc.Want {
+			t.Errorf("%v: got: %v, want: %v", tc.At, got, tc.Want)
+		}
+	}
+	if got := NewThrottle(0, 0, 0, nil, nil).untilOpen(day); got != 0 {
+		t.Errorf("no windows: got: %v, want: 0", got)
+	}
+}
+
+func TestThrottleChunks(t *testing.T) {
+	ctx := context.Background()
+	var calls int64
+	ic := &indexer.Mock{
+		AffectedManifests_: func(_ context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			atomic.AddInt64(&calls, 1)
+			if len(vs) > 2 {
+				t.Errorf("got chunk of %d, want at most 2", len(vs))
+			}
+			a := claircore.NewAffectedManifests()
+			return &a, nil
+		},
+	}
+	const delay = 10 * time.Millisecond
+	th := NewThrottle(2, delay, 1, nil, nil)
+	start := time.Now()
+	if err := getAffected(ctx, ic, th, make([]claircore.Vulnerability, 5), Added, newNotifTab(true))(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt64(&calls), int64(3); got != want {
+		t.Errorf("calls: got: %d, want: %d", got, want)
+	}
+	if got, want := time.Since(start), 2*delay; got < want {
+		t.Errorf("elapsed: got: %v, want: >=%v", got, want)
+	}
+}
@@ -0,0 +1,21 @@
+package notifier
+
+import "context"
+
+// UsageReporter is implemented by Services and Stores that can report how
+// much they're storing.
+type UsageReporter interface {
+	Usage(ctx context.Context) (*Usage, error)
+}
+
+// Usage is a snapshot of a notifier's storage usage.
+type Usage struct {
+	// Notifications counts notifications by status. Deleted notifications
+	// are counted until garbage collection removes them.
+	Notifications map[Status]int64 `json:"notifications"`
+	// Backlog is the number of notifications that are waiting to be
+	// delivered, including those whose delivery failed and will be retried.
+	Backlog int64 `json:"backlog"`
+	// DatabaseBytes is the size of the notifier's database.
+	DatabaseBytes int64 `json:"database_bytes"`
+}
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
@@ -27,6 +28,7 @@ type Deliverer struct {
 	target   *url.URL
 	signer   Signer
 	headers  http.Header
+	retry    retryPolicy
 }
 
 type Signer interface {
@@ -52,12 +54,20 @@ func New(conf *config.Webhook, client *http.Client, signer Signer) (*Deliverer,
 	if err != nil {
 		return nil, err
 	}
+	g, err := httputil.NewGuard(conf.AllowedHosts, conf.AllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.CheckURL(d.target); err != nil {
+		return nil, fmt.Errorf("webhook target not allowed: %w", err)
+	}
 	d.headers = conf.Headers.Clone()
 	if d.headers == nil {
 		d.headers = make(map[string][]string)
 	}
 	d.headers.Set("content-type", "application/json")
 	d.signer = signer
+	d.retry = newRetryPolicy(conf.Retry)
 
 	d.c = client
 	return &d, nil
@@ -67,6 +77,11 @@ func (d *Deliverer) Name() string {
 	return "webhook"
 }
 
+// Destination implements the notifier.Destinationer interface.
+func (d *Deliverer) Destination() string {
+	return d.target.Redacted()
+}
+
 // Deliver implements the notifier.Deliverer interface.
 //
 // Deliver POSTS a webhook data structure to the configured target.
@@ -86,9 +101,41 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 		Callback:       *callback,
 	}
 
-	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, d.target.String(), codec.JSONReader(&wh))
+	zlog.Info(ctx).
+		Stringer("callback", callback).
+		Stringer("target", d.target).
+		Msg("dispatching webhook")
+	return d.post(ctx, &wh)
+}
+
+// Post sends "body" as JSON to the configured target, reporting a
+// clairerror.ErrDeliveryFailed if the request fails.
+//
+// Failed requests are retried according to the configured retry policy.
+func (d *Deliverer) post(ctx context.Context, body interface{}) error {
+	for n := 1; ; n++ {
+		res, err := d.try(ctx, body)
+		if err == nil {
+			return nil
+		}
+		var dErr *clairerror.ErrDeliveryFailed
+		if !errors.As(err, &dErr) {
+			return err
+		}
+		delay, ok := d.retry.delay(n, res)
+		if !ok || !wait(ctx, delay) {
+			return err
+		}
+	}
+}
+
+// Try makes a single request, returning the response if there was one.
+//
+// The response body is closed before returning.
+func (d *Deliverer) try(ctx context.Context, body interface{}) (*http.Response, error) {
+	req, err := httputil.NewRequestWithContext(ctx, http.MethodPost, d.target.String(), codec.JSONReader(body))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for k, vs := range d.headers {
 		for _, v := range vs {
@@ -97,27 +144,22 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	}
 	if d.signer != nil {
 		if err := d.signer.Sign(ctx, req); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	zlog.Info(ctx).
-		Stringer("callback", callback).
-		Stringer("target", d.target).
-		Msg("dispatching webhook")
-
 	resp, err := d.c.Do(req)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{E: err}
+		return nil, &clairerror.ErrDeliveryFailed{E: err}
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return &clairerror.ErrDeliveryFailed{
+		return resp, &clairerror.ErrDeliveryFailed{
 			E: &clairerror.ErrRequestFail{
 				Code:   resp.StatusCode,
 				Status: resp.Status,
 			},
 		}
 	}
-	return nil
+	return resp, nil
 }
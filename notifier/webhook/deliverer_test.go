@@ -9,6 +9,7 @@
 	"path"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
@@ -81,3 +82,146 @@ func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("got: %v, wanted: %v", got, want)
 	}
 }
+
+// TestDelivererRetry confirms failed requests are retried according to the
+// configured policy.
+func TestDelivererRetry(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name     string
+		Retry    *config.WebhookRetry
+		Statuses []int
+		Header   string
+		Requests int
+		OK       bool
+	}{
+		{
+			Name:     "NoPolicy",
+			Statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			Requests: 1,
+		},
+		{
+			Name:     "Recovers",
+			Retry:    &config.WebhookRetry{MaxAttempts: 3},
+			Statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			Requests: 3,
+			OK:       true,
+		},
+		{
+			Name:     "GivesUp",
+			Retry:    &config.WebhookRetry{MaxAttempts: 2},
+			Statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			Requests: 2,
+		},
+		{
+			Name:     "NotRetryable",
+			Retry:    &config.WebhookRetry{MaxAttempts: 3},
+			Statuses: []int{http.StatusBadRequest, http.StatusOK},
+			Requests: 1,
+		},
+		{
+			Name:     "RetryAfter",
+			Retry:    &config.WebhookRetry{MaxAttempts: 3},
+			Statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			Header:   "0",
+			Requests: 2,
+			OK:       true,
+		},
+		{
+			Name:     "RetryAfterTooLong",
+			Retry:    &config.WebhookRetry{MaxAttempts: 3},
+			Statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			Header:   "3600",
+			Requests: 1,
+		},
+		{
+			Name:     "IgnoreRetryAfter",
+			Retry:    &config.WebhookRetry{MaxAttempts: 3, IgnoreRetryAfter: true},
+			Statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			Header:   "3600",
+			Requests: 2,
+			OK:       true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var mu sync.Mutex
+			var n int
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+					if tc.Header != "" {
+						w.Header().Set("retry-after", tc.Header)
+					}
+					w.WriteHeader(tc.Statuses[n])
+					n++
+				},
+			))
+			defer server.Close()
+			if tc.Retry != nil {
+				tc.Retry.Backoff = config.Duration(time.Millisecond)
+				tc.Retry.MaxBackoff = config.Duration(time.Minute)
+				tc.Retry.StatusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable}
+			}
+			conf := config.Webhook{
+				Callback: callback,
+				Target:   server.URL,
+				Retry:    tc.Retry,
+			}
+			d, err := New(&conf, server.Client(), nil)
+			if err != nil {
+				t.Fatalf("failed to create new webhook deliverer: %v", err)
+			}
+			err = d.Deliver(ctx, noteID)
+			if got, want := err == nil, tc.OK; got != want {
+				t.Errorf("got: %v, want success: %v", err, want)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := n, tc.Requests; got != want {
+				t.Errorf("got: %d requests, want: %d", got, want)
+			}
+		})
+	}
+}
+
+// TestDelivererAllowlist confirms a deliverer isn't constructed for a target
+// the allowlist doesn't cover.
+func TestDelivererAllowlist(t *testing.T) {
+	tt := []struct {
+		Name string
+		Conf config.Webhook
+		OK   bool
+	}{
+		{
+			Name: "Metadata",
+			Conf: config.Webhook{Target: "http://169.254.169.254/latest/"},
+		},
+		{
+			Name: "Host",
+			Conf: config.Webhook{
+				Target:       "https://hooks.example.com/clair",
+				AllowedHosts: []string{"*.example.com"},
+			},
+			OK: true,
+		},
+		{
+			Name: "OtherHost",
+			Conf: config.Webhook{
+				Target:       "https://hooks.example.org/clair",
+				AllowedHosts: []string{"*.example.com"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Conf.Callback = callback
+			_, err := New(&tc.Conf, http.DefaultClient, nil)
+			if got, want := err == nil, tc.OK; got != want {
+				t.Errorf("got: %v, want ok: %v", err, want)
+			}
+		})
+	}
+}
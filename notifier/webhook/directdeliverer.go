@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DirectDeliverer is a webhook deliverer which POSTs notifications directly to
+// the target, instead of a callback.
+//
+// Notification sets larger than the configured page size are sent as multiple
+// requests. If any request fails the whole set is retried, so receivers should
+// expect to see pages more than once.
+type DirectDeliverer struct {
+	n        []notifier.Notification
+	pageSize int
+	Deliverer
+}
+
+// Payload is the body of a direct webhook delivery.
+type Payload struct {
+	NotificationID uuid.UUID `json:"notification_id"`
+	// Page is the 1-indexed page number of this payload.
+	Page int `json:"page"`
+	// Pages is the total number of pages for this notification set.
+	Pages         int                     `json:"pages"`
+	Notifications []notifier.Notification `json:"notifications"`
+}
+
+// NewDirectDeliverer returns a new webhook DirectDeliverer.
+func NewDirectDeliverer(conf *config.Webhook, client *http.Client, signer Signer) (*DirectDeliverer, error) {
+	d, err := New(conf, client, signer)
+	if err != nil {
+		return nil, err
+	}
+	dd := DirectDeliverer{
+		Deliverer: *d,
+		pageSize:  conf.PageSize,
+		n:         make([]notifier.Notification, 0, 1024),
+	}
+	return &dd, nil
+}
+
+func (d *DirectDeliverer) Name() string {
+	return "webhook-direct"
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// The provided notifications are copied into a buffer for delivery.
+func (d *DirectDeliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	// if we can reslice instead of allocate do so.
+	if len(n) <= cap(d.n) {
+		d.n = d.n[:len(n)]
+		copy(d.n, n)
+		return nil
+	}
+	tmp := make([]notifier.Notification, len(n))
+	copy(tmp, n)
+	d.n = tmp
+	return nil
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// Deliver POSTs the buffered notifications to the configured target.
+func (d *DirectDeliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	ctx = zlog.ContextWithValues(ctx,
+		"component", "notifier/webhook/DirectDeliverer.Deliver",
+		"notification_id", nID.String(),
+	)
+	sz := d.pageSize
+	if sz <= 0 || sz > len(d.n) {
+		sz = len(d.n)
+	}
+	pages := 1
+	if sz != 0 {
+		pages = (len(d.n) + sz - 1) / sz
+	}
+
+	zlog.Info(ctx).
+		Stringer("target", d.target).
+		Int("count", len(d.n)).
+		Int("pages", pages).
+		Msg("dispatching webhook")
+	for i := 0; i < pages; i++ {
+		start, end := i*sz, (i+1)*sz
+		if end > len(d.n) {
+			end = len(d.n)
+		}
+		p := Payload{
+			NotificationID: nID,
+			Page:           i + 1,
+			Pages:          pages,
+			Notifications:  d.n[start:end],
+		}
+		if err := d.post(ctx, &p); err != nil {
+			return fmt.Errorf("page %d/%d: %w", p.Page, pages, err)
+		}
+	}
+	return nil
+}
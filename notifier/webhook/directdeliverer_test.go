@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/clair/config"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TestDirectDeliverer confirms the direct deliverer pages notifications to the
+// configured target.
+func TestDirectDeliverer(t *testing.T) {
+	var mu sync.Mutex
+	var got []Payload
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var p Payload
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			got = append(got, p)
+			mu.Unlock()
+		},
+	))
+	defer server.Close()
+	ctx := zlog.Test(context.Background(), t)
+	conf := config.Webhook{
+		Target:   server.URL,
+		Direct:   true,
+		PageSize: 2,
+	}
+
+	d, err := NewDirectDeliverer(&conf, server.Client(), nil)
+	if err != nil {
+		t.Fatalf("failed to create new webhook deliverer: %v", err)
+	}
+	m, err := claircore.ParseDigest("sha256:" + strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := make([]notifier.Notification, 5)
+	for i := range ns {
+		ns[i].ID = uuid.New()
+		ns[i].Manifest = m
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, noteID); err != nil {
+		t.Fatalf("got: %v, wanted: nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 {
+		t.Fatalf("got %d requests, wanted 3", len(got))
+	}
+	var n int
+	for i, p := range got {
+		if p.NotificationID != noteID {
+			t.Errorf("got: %v, wanted: %v", p.NotificationID, noteID)
+		}
+		if p.Page != i+1 || p.Pages != 3 {
+			t.Errorf("got page %d/%d, wanted %d/3", p.Page, p.Pages, i+1)
+		}
+		for _, pn := range p.Notifications {
+			if pn.ID != ns[n].ID {
+				t.Errorf("notification %d: got: %v, wanted: %v", n, pn.ID, ns[n].ID)
+			}
+			n++
+		}
+	}
+	if n != len(ns) {
+		t.Errorf("got %d notifications, wanted %d", n, len(ns))
+	}
+}
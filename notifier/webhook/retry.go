@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/quay/clair/config"
+	"github.com/quay/zlog"
+)
+
+// RetryPolicy is the schedule for retrying a failed request.
+//
+// The zero value makes a single request.
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	max        time.Duration
+	codes      map[int]bool
+	retryAfter bool
+}
+
+func newRetryPolicy(cfg *config.WebhookRetry) retryPolicy {
+	if cfg == nil {
+		return retryPolicy{attempts: 1}
+	}
+	p := retryPolicy{
+		attempts:   cfg.MaxAttempts,
+		backoff:    time.Duration(cfg.Backoff),
+		max:        time.Duration(cfg.MaxBackoff),
+		codes:      make(map[int]bool, len(cfg.StatusCodes)),
+		retryAfter: !cfg.IgnoreRetryAfter,
+	}
+	for _, c := range cfg.StatusCodes {
+		p.codes[c] = true
+	}
+	return p
+}
+
+// Retryable reports whether a request that got the response "res" should be
+// retried. A nil response is a request that failed without one.
+func (p *retryPolicy) retryable(res *http.Response) bool {
+	return res == nil || p.codes[res.StatusCode]
+}
+
+// Delay returns how long to wait before the request following attempt "n"
+// (counting from 1), and whether to make it at all.
+func (p *retryPolicy) delay(n int, res *http.Response) (time.Duration, bool) {
+	if n >= p.attempts || !p.retryable(res) {
+		return 0, false
+	}
+	if p.retryAfter && res != nil {
+		if d, ok := retryAfter(res.Header.Get("retry-after")); ok {
+			// The receiver asked for more time than we're willing to hold up
+			// other deliveries for, so leave it to the next delivery interval.
+			return d, d <= p.max
+		}
+	}
+	d := p.backoff << (n - 1)
+	if d > p.max || d <= 0 {
+		d = p.max
+	}
+	return d, true
+}
+
+// RetryAfter parses a Retry-After header value, which is either a number of
+// seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	d := time.Until(t)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// Wait blocks for "d" or until the Context is canceled, reporting whether the
+// full duration elapsed.
+func wait(ctx context.Context, d time.Duration) bool {
+	zlog.Debug(ctx).
+		Dur("delay", d).
+		Msg("retrying webhook")
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}